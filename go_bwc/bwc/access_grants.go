@@ -0,0 +1,208 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// errNoAccessGrant is returned when a user without an active grant asks for evidence
+var errNoAccessGrant = errors.New("no active access grant for this evidence")
+
+// AccessGrant lets a user outside the agency view one item of evidence until
+// ExpiresAt. Grants are never deleted; expired and revoked grants stay on
+// record alongside the accesses made under them.
+type AccessGrant struct {
+	ID             string    `json:"id"`
+	EvidenceID     string    `json:"evidence_id"`
+	UserID         string    `json:"user_id"`
+	GrantedBy      string    `json:"granted_by"`
+	Reason         string    `json:"reason"`
+	GrantedAt      time.Time `json:"granted_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	RevokedBy      string    `json:"revoked_by,omitempty"`
+	RevokedAt      time.Time `json:"revoked_at,omitempty"`
+	AccessCount    int       `json:"access_count"`
+	LastAccessedAt time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// activeAt reports whether the grant allows access at t
+func (g *AccessGrant) activeAt(t time.Time) bool {
+	return g.RevokedAt.IsZero() && t.Before(g.ExpiresAt)
+}
+
+// GrantAccess allows userID to view evidenceID for duration, e.g. an outside
+// detective working a related case. The grant expires on its own.
+func (bwc *BWCSystem) GrantAccess(evidenceID, userID string, duration time.Duration, reason string) (*AccessGrant, error) {
+	return bwc.GrantAccessBy(evidenceID, userID, "SYSTEM", duration, reason)
+}
+
+// GrantAccessBy is GrantAccess recording grantedBy as the user who issued the grant
+func (bwc *BWCSystem) GrantAccessBy(evidenceID, userID, grantedBy string, duration time.Duration, reason string) (*AccessGrant, error) {
+	return bwc.GrantAccessByContext(context.Background(), evidenceID, userID, grantedBy, duration, reason)
+}
+
+// GrantAccessByContext grants like GrantAccessBy, as the principal ctx carries
+// when it has one
+func (bwc *BWCSystem) GrantAccessByContext(ctx context.Context, evidenceID, userID, grantedBy string, duration time.Duration, reason string) (*AccessGrant, error) {
+	grantedBy, auth := actingUser(ctx, grantedBy)
+	if err := bwc.authorize(grantedBy, PermGrantAccess, "Grant access", evidenceID); err != nil {
+		return nil, err
+	}
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	if err := ValidateOfficerID(userID); err != nil {
+		return nil, err
+	}
+	if duration <= 0 {
+		return nil, errors.New("grant duration must be positive")
+	}
+	if reason == "" {
+		return nil, errors.New("a reason is required for an access grant")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if bwc.evidenceDB.Get(evidenceID) == nil {
+		return nil, ErrEvidenceNotFound
+	}
+
+	now := time.Now()
+	bwc.accessGrantSeq++
+	grant := &AccessGrant{
+		ID:         fmt.Sprintf("AGR-%06d", bwc.accessGrantSeq),
+		EvidenceID: evidenceID,
+		UserID:     userID,
+		GrantedBy:  grantedBy,
+		Reason:     reason,
+		GrantedAt:  now,
+		ExpiresAt:  now.Add(duration),
+	}
+	bwc.accessGrants[grant.ID] = grant
+
+	bwc.logAuditAs(auth, grantedBy, "GRANT_ACCESS", evidenceID,
+		fmt.Sprintf("Access grant %s to %s until %s: %s", grant.ID, userID, grant.ExpiresAt.Format(time.RFC3339), reason), "")
+
+	g := *grant
+	return &g, nil
+}
+
+// RevokeAccess ends a grant before it expires
+func (bwc *BWCSystem) RevokeAccess(grantID, revokedBy string) error {
+	if err := bwc.authorize(revokedBy, PermGrantAccess, "Revoke access", ""); err != nil {
+		return err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	grant, exists := bwc.accessGrants[grantID]
+	if !exists {
+		return errors.New("access grant not found")
+	}
+	now := time.Now()
+	if !grant.activeAt(now) {
+		return errors.New("access grant is no longer active")
+	}
+
+	grant.RevokedBy = revokedBy
+	grant.RevokedAt = now
+
+	bwc.logAudit(revokedBy, "REVOKE_ACCESS", grant.EvidenceID,
+		fmt.Sprintf("Access grant %s for %s revoked", grant.ID, grant.UserID), "")
+	return nil
+}
+
+// AccessEvidence returns evidence to a user viewing it under an access grant.
+// Every access is audited, including those refused because no grant is
+// active.
+func (bwc *BWCSystem) AccessEvidence(evidenceID, userID, ipAddress string) (*Evidence, error) {
+	return bwc.AccessEvidenceContext(context.Background(), evidenceID, userID, ipAddress)
+}
+
+// AccessEvidenceContext reads under a grant like AccessEvidence, for the
+// principal ctx carries when it has one
+func (bwc *BWCSystem) AccessEvidenceContext(ctx context.Context, evidenceID, userID, ipAddress string) (*Evidence, error) {
+	userID, auth := actingUser(ctx, userID)
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+
+	if err := bwc.rejectIfRestrictedLocked(evidence, userID, "Access under grant"); err != nil {
+		return nil, err
+	}
+	grant := bwc.activeGrantLocked(evidenceID, userID, time.Now())
+	if grant == nil {
+		details := "Access refused: no active access grant"
+		for _, g := range bwc.accessGrants {
+			if g.EvidenceID == evidenceID && g.UserID == userID {
+				details = fmt.Sprintf("Access refused: access grant %s has expired or was revoked", g.ID)
+			}
+		}
+		bwc.logAuditAs(auth, userID, "GRANT_ACCESS_DENIED", evidenceID, details, ipAddress)
+		return nil, errNoAccessGrant
+	}
+
+	grant.AccessCount++
+	grant.LastAccessedAt = time.Now()
+
+	bwc.logAuditAs(auth, userID, "ACCESS_UNDER_GRANT", evidenceID, fmt.Sprintf("Evidence viewed under access grant %s", grant.ID), ipAddress)
+
+	ev := copyEvidence(evidence)
+	return &ev, nil
+}
+
+// activeGrantLocked returns userID's active grant for evidenceID at now, or
+// nil; the caller must hold bwc.mu
+func (bwc *BWCSystem) activeGrantLocked(evidenceID, userID string, now time.Time) *AccessGrant {
+	for _, grant := range bwc.accessGrants {
+		if grant.EvidenceID == evidenceID && grant.UserID == userID && grant.activeAt(now) {
+			return grant
+		}
+	}
+	return nil
+}
+
+// HasAccessGrant reports whether userID currently holds a grant for evidenceID
+func (bwc *BWCSystem) HasAccessGrant(evidenceID, userID string) bool {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	return bwc.activeGrantLocked(evidenceID, userID, time.Now()) != nil
+}
+
+// ActiveAccessGrants lists unexpired, unrevoked grants, optionally limited to
+// one evidence item and/or one user, soonest to expire first
+func (bwc *BWCSystem) ActiveAccessGrants(evidenceID, userID string) []AccessGrant {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	now := time.Now()
+	results := make([]AccessGrant, 0)
+	for _, grant := range bwc.accessGrants {
+		if !grant.activeAt(now) {
+			continue
+		}
+		if evidenceID != "" && grant.EvidenceID != evidenceID {
+			continue
+		}
+		if userID != "" && grant.UserID != userID {
+			continue
+		}
+		results = append(results, *grant)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if !results[i].ExpiresAt.Equal(results[j].ExpiresAt) {
+			return results[i].ExpiresAt.Before(results[j].ExpiresAt)
+		}
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
@@ -0,0 +1,94 @@
+package bwc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGrantAccess(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-AGR-001", "OFF-301", "Officer Test", "Test Location", nil)
+
+	if _, err := system.AccessEvidence(evidence.ID, "DET-EXT-1", "10.0.0.9"); !errors.Is(err, errNoAccessGrant) {
+		t.Fatalf("Expected access without a grant to be refused, got %v", err)
+	}
+
+	grant, err := system.GrantAccessBy(evidence.ID, "DET-EXT-1", "SGT-1", time.Hour, "Related robbery investigation")
+	if err != nil {
+		t.Fatalf("GrantAccessBy failed: %v", err)
+	}
+	if grant.GrantedBy != "SGT-1" || !grant.ExpiresAt.After(time.Now()) {
+		t.Errorf("Unexpected grant: %+v", grant)
+	}
+
+	viewed, err := system.AccessEvidence(evidence.ID, "DET-EXT-1", "10.0.0.9")
+	if err != nil || viewed.ID != evidence.ID {
+		t.Fatalf("Expected access under grant, got %v", err)
+	}
+	if _, err := system.AccessEvidence(evidence.ID, "DET-EXT-2", ""); !errors.Is(err, errNoAccessGrant) {
+		t.Error("Grant should not extend to other users")
+	}
+
+	active := system.ActiveAccessGrants(evidence.ID, "")
+	if len(active) != 1 || active[0].AccessCount != 1 {
+		t.Fatalf("Expected one active grant with one access, got %+v", active)
+	}
+
+	actions := make(map[string]int)
+	for _, log := range system.GetAuditLogs(evidence.ID, "") {
+		actions[log.Action]++
+	}
+	if actions["GRANT_ACCESS"] != 1 || actions["ACCESS_UNDER_GRANT"] != 1 || actions["GRANT_ACCESS_DENIED"] != 2 {
+		t.Errorf("Unexpected audit actions: %v", actions)
+	}
+
+	if _, err := system.GrantAccess(evidence.ID, "DET-EXT-1", 0, "reason"); err == nil {
+		t.Error("Expected error for non-positive duration")
+	}
+	if _, err := system.GrantAccess(evidence.ID, "DET-EXT-1", time.Hour, ""); err == nil {
+		t.Error("Expected error for missing reason")
+	}
+	if _, err := system.GrantAccess("BWC-MISSING", "DET-EXT-1", time.Hour, "reason"); err == nil {
+		t.Error("Expected error for unknown evidence")
+	}
+}
+
+func TestAccessGrantExpiresAndRevokes(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-AGR-002", "OFF-302", "Officer Test", "Test Location", nil)
+
+	expiring, _ := system.GrantAccess(evidence.ID, "DET-EXT-3", time.Hour, "Court preparation")
+	system.mu.Lock()
+	system.accessGrants[expiring.ID].ExpiresAt = time.Now().Add(-time.Minute)
+	system.mu.Unlock()
+
+	if _, err := system.AccessEvidence(evidence.ID, "DET-EXT-3", ""); !errors.Is(err, errNoAccessGrant) {
+		t.Errorf("Expected expired grant to be refused, got %v", err)
+	}
+	if len(system.ActiveAccessGrants("", "DET-EXT-3")) != 0 {
+		t.Error("Expired grant should not be listed as active")
+	}
+	logs := system.GetAuditLogs(evidence.ID, "DET-EXT-3")
+	if last := logs[len(logs)-1]; !strings.Contains(last.Details, expiring.ID) {
+		t.Errorf("Expected denial to name the expired grant, got %q", last.Details)
+	}
+
+	grant, _ := system.GrantAccess(evidence.ID, "DET-EXT-3", time.Hour, "Court preparation")
+	if err := system.RevokeAccess(grant.ID, "SGT-2"); err != nil {
+		t.Fatalf("RevokeAccess failed: %v", err)
+	}
+	if system.HasAccessGrant(evidence.ID, "DET-EXT-3") {
+		t.Error("Revoked grant should not allow access")
+	}
+	if err := system.RevokeAccess(grant.ID, "SGT-2"); err == nil {
+		t.Error("Expected error revoking an inactive grant")
+	}
+}
@@ -0,0 +1,197 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EvidenceACL restricts an item of evidence, such as an internal affairs or
+// juvenile recording, to the users it lists. Evidence without one is open to
+// everyone the roles allow.
+type EvidenceACL struct {
+	Users        []string  `json:"users"`
+	Reason       string    `json:"reason"`
+	RestrictedBy string    `json:"restricted_by"`
+	RestrictedAt time.Time `json:"restricted_at"`
+}
+
+// allows reports whether userID may see evidence the ACL is on. The system's
+// own jobs always may.
+func (a *EvidenceACL) allows(userID string) bool {
+	if a == nil || userID == systemUserID {
+		return true
+	}
+	for _, u := range a.Users {
+		if u == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectIfRestrictedLocked audits and refuses operation on evidence whose ACL
+// does not list userID. Callers must hold bwc.mu.
+func (bwc *BWCSystem) rejectIfRestrictedLocked(evidence *Evidence, userID, operation string) error {
+	if evidence.ACL.allows(userID) {
+		return nil
+	}
+
+	bwc.logAudit(userID, "RESTRICTED_ACCESS_DENIED", evidence.ID,
+		fmt.Sprintf("%s refused: evidence is restricted - %s", operation, evidence.ACL.Reason), "")
+
+	return &BWCError{Code: CodePermissionDenied,
+		Message: fmt.Sprintf("evidence %s is restricted and %s is not on its access list", evidence.ID, userID)}
+}
+
+// rejectIfRestricted is rejectIfRestrictedLocked for callers that do not
+// hold bwc.mu
+func (bwc *BWCSystem) rejectIfRestricted(evidenceID, userID, operation string) error {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	return bwc.rejectIfRestrictedLocked(evidence, userID, operation)
+}
+
+// systemReadKey marks a context as carrying a read the system makes on its
+// own behalf
+type systemReadKey struct{}
+
+// systemContext returns ctx marked as the system's own, for the operations
+// without a context and the system's jobs, which authenticate no one
+func systemContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemReadKey{}, true)
+}
+
+// readerFromContext is who a read is for: the principal ctx carries, the
+// system itself for a context from systemContext, and otherwise no one, whom
+// restricted evidence and access control refuse
+func readerFromContext(ctx context.Context) string {
+	if p := PrincipalFromContext(ctx); p != nil {
+		return p.UserID
+	}
+	if ctx.Value(systemReadKey{}) != nil {
+		return systemUserID
+	}
+	return ""
+}
+
+// canRead reports whether the principal ctx carries may see evidenceID,
+// without auditing a refusal; evidence no longer held is not restricted
+func (bwc *BWCSystem) canRead(ctx context.Context, evidenceID string) bool {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	return evidence == nil || evidence.ACL.allows(readerFromContext(ctx))
+}
+
+// RestrictEvidence limits evidenceID to users, who with restrictedBy are the
+// only ones who may then read, search for or export it. It needs
+// grant_access, and on evidence that is already restricted, a place on its
+// list.
+func (bwc *BWCSystem) RestrictEvidence(evidenceID string, users []string, reason, restrictedBy string) error {
+	return bwc.RestrictEvidenceContext(context.Background(), evidenceID, users, reason, restrictedBy)
+}
+
+// RestrictEvidenceContext restricts like RestrictEvidence, as the principal
+// ctx carries when it has one
+func (bwc *BWCSystem) RestrictEvidenceContext(ctx context.Context, evidenceID string, users []string, reason, restrictedBy string) error {
+	restrictedBy, auth := actingUser(ctx, restrictedBy)
+	if err := bwc.authorize(restrictedBy, PermGrantAccess, "Restrict evidence", evidenceID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("a reason is required to restrict evidence")
+	}
+	list := []string{restrictedBy}
+	for _, userID := range users {
+		if err := ValidateOfficerID(userID); err != nil {
+			return err
+		}
+		if userID != restrictedBy {
+			list = append(list, userID)
+		}
+	}
+	sort.Strings(list[1:])
+
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfRestrictedLocked(evidence, restrictedBy, "Restrict evidence"); err != nil {
+		return err
+	}
+
+	evidence.ACL = &EvidenceACL{
+		Users:        list,
+		Reason:       reason,
+		RestrictedBy: restrictedBy,
+		RestrictedAt: time.Now(),
+	}
+	// Who may see the evidence is not part of it: the revision and any seal
+	// are left alone
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	bwc.logAuditAs(auth, restrictedBy, "RESTRICT_EVIDENCE", evidenceID,
+		fmt.Sprintf("Restricted to %s - %s", strings.Join(list, ", "), reason), "")
+	return nil
+}
+
+// LiftRestriction removes evidenceID's ACL. Only a user on the list may
+// lift it.
+func (bwc *BWCSystem) LiftRestriction(evidenceID, reason, liftedBy string) error {
+	return bwc.LiftRestrictionContext(context.Background(), evidenceID, reason, liftedBy)
+}
+
+// LiftRestrictionContext lifts a restriction like LiftRestriction, as the
+// principal ctx carries when it has one
+func (bwc *BWCSystem) LiftRestrictionContext(ctx context.Context, evidenceID, reason, liftedBy string) error {
+	liftedBy, auth := actingUser(ctx, liftedBy)
+	if err := bwc.authorize(liftedBy, PermGrantAccess, "Lift restriction", evidenceID); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if evidence.ACL == nil {
+		return fmt.Errorf("evidence %s is not restricted", evidenceID)
+	}
+	if err := bwc.rejectIfRestrictedLocked(evidence, liftedBy, "Lift restriction"); err != nil {
+		return err
+	}
+
+	evidence.ACL = nil
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	bwc.logAuditAs(auth, liftedBy, "LIFT_RESTRICTION", evidenceID, fmt.Sprintf("Restriction lifted - %s", reason), "")
+	return nil
+}
@@ -0,0 +1,292 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEvidenceACLRestrictsReads(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-IA-001", "OFF-4001", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	open, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-IA-001", "OFF-4004", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.RestrictEvidence(evidence.ID, []string{"IA-4001"}, "", "CUS-4001"); err == nil {
+		t.Error("expected a reason to be required")
+	}
+	if err := system.RestrictEvidence(evidence.ID, []string{"IA-4001"}, "Internal affairs investigation", "CUS-4001"); err != nil {
+		t.Fatalf("RestrictEvidence failed: %v", err)
+	}
+
+	investigator := ContextWithPrincipal(context.Background(), &Principal{UserID: "IA-4001", Method: AuthPassword})
+	outsider := ContextWithPrincipal(context.Background(), &Principal{UserID: "DET-4001", Method: AuthPassword})
+
+	if _, err := system.GetEvidenceContext(investigator, evidence.ID); err != nil {
+		t.Errorf("expected a listed user to read the evidence, got %v", err)
+	}
+	if _, err := system.GetEvidenceContext(outsider, evidence.ID); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user to be refused, got %v", err)
+	}
+	if _, err := system.GetEvidence(evidence.ID); err != nil {
+		t.Errorf("expected the system itself to read the evidence, got %v", err)
+	}
+
+	results, _ := system.SearchEvidenceContext(outsider, "CASE-IA-001", "", "")
+	if len(results) != 1 || results[0].ID != open.ID {
+		t.Errorf("expected the search to leave out the restricted evidence, got %d results", len(results))
+	}
+	results, _ = system.SearchEvidenceContext(investigator, "CASE-IA-001", "", "")
+	if len(results) != 2 {
+		t.Errorf("expected a listed user to find both items, got %d", len(results))
+	}
+
+	if err := system.ExportEvidenceFor(evidence.ID, filepath.Join(tmpDir, "out.json"), "DET-4001", "Discovery"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user's export to be refused, got %v", err)
+	}
+	if err := system.ExportEvidenceFor(evidence.ID, filepath.Join(tmpDir, "out.json"), "IA-4001", "Discovery"); err != nil {
+		t.Errorf("expected a listed user to export, got %v", err)
+	}
+	if _, err := system.ExportCasePackage("CASE-IA-001", filepath.Join(tmpDir, "case.zip"), "DET-4001", "Discovery", nil); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected a case package holding restricted evidence to be refused, got %v", err)
+	}
+
+	denied := 0
+	for _, log := range system.GetAuditLogs(evidence.ID, "DET-4001") {
+		if log.Action == "RESTRICTED_ACCESS_DENIED" {
+			denied++
+		}
+	}
+	if denied != 4 {
+		t.Errorf("expected the 4 refusals to be audited, got %d", denied)
+	}
+
+	if err := system.LiftRestriction(evidence.ID, "Investigation closed", "DET-4001"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user not to lift the restriction, got %v", err)
+	}
+	if err := system.LiftRestriction(evidence.ID, "Investigation closed", "IA-4001"); err != nil {
+		t.Fatalf("LiftRestriction failed: %v", err)
+	}
+	if _, err := system.GetEvidenceContext(outsider, evidence.ID); err != nil {
+		t.Errorf("expected the evidence to be open again, got %v", err)
+	}
+}
+
+func TestEvidenceACLCoversEveryRead(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-IA-003", "OFF-4005", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if _, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-IA-003", "OFF-4006", "", "", nil); err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	session, err := system.StartViewSession(evidence.ID, "DET-4005", "")
+	if err != nil {
+		t.Fatalf("StartViewSession failed: %v", err)
+	}
+	if err := system.RestrictEvidence(evidence.ID, []string{"IA-4005"}, "Internal affairs investigation", "CUS-4005"); err != nil {
+		t.Fatalf("RestrictEvidence failed: %v", err)
+	}
+
+	if _, _, err := system.OpenEvidenceStream(session.ID, evidence.ID, "DET-4005"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected a session opened before the restriction to be refused, got %v", err)
+	}
+	if _, err := system.StartViewSession(evidence.ID, "DET-4005", ""); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user's playback to be refused, got %v", err)
+	}
+	if _, err := system.GenerateCustodyAffidavitPDF(evidence.ID, "DET-4005", ""); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user's affidavit to be refused, got %v", err)
+	}
+	if _, err := system.GenerateLabelSVG(evidence.ID, "DET-4005"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user's label to be refused, got %v", err)
+	}
+
+	// A context without a principal reads as no one
+	for name, read := range map[string]func(context.Context) error{
+		"GetEvidenceContext": func(ctx context.Context) error {
+			_, err := system.GetEvidenceContext(ctx, evidence.ID)
+			return err
+		},
+		"GetChainOfCustodyContext": func(ctx context.Context) error {
+			_, err := system.GetChainOfCustodyContext(ctx, evidence.ID)
+			return err
+		},
+		"GetTimelineContext": func(ctx context.Context) error {
+			_, err := system.GetTimelineContext(ctx, evidence.ID)
+			return err
+		},
+	} {
+		if err := read(context.Background()); !errors.Is(err, ErrPermissionDenied) {
+			t.Errorf("expected %s without a principal to be refused, got %v", name, err)
+		}
+		if err := read(ContextWithPrincipal(context.Background(), &Principal{UserID: "IA-4005"})); err != nil {
+			t.Errorf("expected %s for a listed user to succeed, got %v", name, err)
+		}
+	}
+	if system.canRead(context.Background(), evidence.ID) {
+		t.Error("expected events about the evidence to be withheld from no one in particular")
+	}
+
+	outsider := ContextWithPrincipal(context.Background(), &Principal{UserID: "DET-4005"})
+	report, err := system.GenerateCaseReportContext(outsider, "CASE-IA-003", ReportOptions{})
+	if err != nil || strings.Contains(report, evidence.ID) {
+		t.Errorf("expected the case report to leave out the restricted evidence, got %v", err)
+	}
+	html, err := system.GenerateHTMLReportContext(outsider, "CASE-IA-003", ReportOptions{})
+	if err != nil || strings.Contains(html, evidence.ID) {
+		t.Errorf("expected the HTML report to leave out the restricted evidence, got %v", err)
+	}
+	if report, _ := system.GenerateCaseReport("CASE-IA-003", ReportOptions{}); !strings.Contains(report, evidence.ID) {
+		t.Error("expected the system's own report to include the restricted evidence")
+	}
+}
+
+func TestEvidenceACLCoversSearchesScansAndBatches(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.SetTextExtractor(funcTextExtractor(func(path string) (string, error) {
+		return scannedText[filepath.Base(path)], nil
+	}))
+
+	restricted, err := system.IngestEvidence(writeTestDocument(t, tmpDir, "consent.pdf"), "CASE-IA-004", "OFF-4007", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	open, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-IA-004", "OFF-4008", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.SetPlace(restricted.ID, "OFF-4007", Place{Latitude: 40, Longitude: -75}); err != nil {
+		t.Fatalf("SetPlace failed: %v", err)
+	}
+	if err := system.RestrictEvidence(restricted.ID, []string{"IA-4007"}, "Internal affairs investigation", "CUS-4007"); err != nil {
+		t.Fatalf("RestrictEvidence failed: %v", err)
+	}
+
+	outsider := ContextWithPrincipal(context.Background(), &Principal{UserID: "DET-4007"})
+	listed := ContextWithPrincipal(context.Background(), &Principal{UserID: "IA-4007"})
+	if results, err := system.SearchTextContext(outsider, "consent"); err != nil || len(results) != 0 {
+		t.Errorf("expected the text search to leave out the restricted evidence, got %d result(s), %v", len(results), err)
+	}
+	if results, _ := system.SearchTextContext(listed, "consent"); len(results) != 1 {
+		t.Errorf("expected a listed user to find the evidence by its text, got %d result(s)", len(results))
+	}
+	if results := system.SearchNearContext(outsider, 40, -75, 1000); len(results) != 0 {
+		t.Errorf("expected the location search to leave out the restricted evidence, got %d result(s)", len(results))
+	}
+	if results := system.SearchNearContext(listed, 40, -75, 1000); len(results) != 1 {
+		t.Errorf("expected a listed user to find the evidence by its location, got %d result(s)", len(results))
+	}
+	if _, err := system.ScanLabel(restricted.ID, "DET-4007"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unlisted user's label scan to be refused, got %v", err)
+	}
+
+	// Bulk operations by case leave the restricted item alone
+	plan, err := system.AddTags(EvidenceSelection{CaseNumber: "CASE-IA-004"}, []string{"reviewed"}, "DET-4007", false)
+	if err != nil || len(plan.Items) != 1 || plan.Items[0].EvidenceID != open.ID {
+		t.Errorf("expected only the unrestricted item to be tagged, got %+v %v", plan, err)
+	}
+	plan, err = system.AddTags(EvidenceSelection{EvidenceIDs: []string{restricted.ID}}, []string{"reviewed"}, "DET-4007", false)
+	if err != nil || len(plan.Items) != 0 || len(plan.Skipped) != 1 || plan.Skipped[0].Reason != "restricted" {
+		t.Errorf("expected a listed restricted item to be skipped, got %+v %v", plan, err)
+	}
+	if _, err := system.UpdateStatusBatch(EvidenceSelection{CaseNumber: "CASE-IA-004"}, "DET-4007", StatusProcessing, "", false); err != nil {
+		t.Fatalf("UpdateStatusBatch failed: %v", err)
+	}
+	got, _ := system.GetEvidence(restricted.ID)
+	if len(got.Tags) != 0 || got.Status != StatusCollected {
+		t.Errorf("expected the restricted evidence to be unchanged, got tags %v status %s", got.Tags, got.Status)
+	}
+}
+
+func TestEvidenceACLKeepsSealValid(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-JUV-001", "OFF-4002", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if _, err := system.SealEvidence(evidence.ID, "Juvenile court order 24-118"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if err := system.RestrictEvidence(evidence.ID, []string{"JUV-4001"}, "Juvenile subject", "CUS-4002"); err != nil {
+		t.Fatalf("RestrictEvidence failed: %v", err)
+	}
+	if ok, err := system.VerifySeal(evidence.ID); err != nil || !ok {
+		t.Errorf("expected restricting access to leave the seal valid, got %v %v", ok, err)
+	}
+}
+
+func TestServerEvidenceACL(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-IA-002", "OFF-4003", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/evidence/"+evidence.ID+"/acl",
+		strings.NewReader(`{"users":["IA-4002"],"reason":"Internal affairs"}`))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT acl failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the evidence to be restricted, got %d", resp.StatusCode)
+	}
+
+	// CUS-001 restricted it, so stays on the list
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected the restricting user to read the evidence, got %d", resp.StatusCode)
+	}
+
+	if err := system.SetPlace(evidence.ID, "OFF-4003", Place{Latitude: 40, Longitude: -75}); err != nil {
+		t.Fatalf("SetPlace failed: %v", err)
+	}
+	if err := system.RestrictEvidence(evidence.ID, nil, "Internal affairs", "IA-4002"); err != nil {
+		t.Fatalf("RestrictEvidence failed: %v", err)
+	}
+	for _, path := range []string{"/api/evidence/" + evidence.ID, "/api/evidence/" + evidence.ID + "/custody", "/api/evidence/" + evidence.ID + "/timeline"} {
+		resp = authGet(t, server, path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected GET %s to be refused, got %d", path, resp.StatusCode)
+		}
+	}
+	resp = authPostJSON(t, server, "/api/evidence/"+evidence.ID+"/view", `{}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a view session to be refused, got %d", resp.StatusCode)
+	}
+	resp = authPostJSON(t, server, "/api/scan", `{"code":"`+evidence.ID+`"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a label scan to be refused, got %d", resp.StatusCode)
+	}
+	resp = authGet(t, server, "/api/evidence?near=40,-75")
+	var found []*Evidence
+	json.NewDecoder(resp.Body).Decode(&found)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(found) != 0 {
+		t.Errorf("expected the location search to leave out the restricted evidence, got %d with %d result(s)", resp.StatusCode, len(found))
+	}
+}
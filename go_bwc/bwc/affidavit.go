@@ -0,0 +1,252 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// affidavitTimeLayout is how times are written in an affidavit
+const affidavitTimeLayout = "2006-01-02 15:04:05 MST"
+
+// CustodyAffidavit is the content of a chain-of-custody affidavit for one
+// evidence item, ready to be rendered for the affiant to sign
+type CustodyAffidavit struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	System      string    `json:"system"`
+	AffiantID   string    `json:"affiant_id"`
+	AffiantName string    `json:"affiant_name,omitempty"`
+	Evidence    Evidence  `json:"evidence"`
+	// FinalCheck is the most recent integrity check, if there has been one
+	FinalCheck *IntegrityCheck `json:"final_check,omitempty"`
+	// BrokenEntries holds the indexes of custody entries whose hash or PIV
+	// signature no longer verifies
+	BrokenEntries []int `json:"broken_entries"`
+	// SealValid is set for sealed evidence
+	SealValid *bool `json:"seal_valid,omitempty"`
+}
+
+// Intact reports whether the affidavit can attest to an unbroken chain: the
+// last integrity check passed, every custody entry verifies and any seal holds
+func (a *CustodyAffidavit) Intact() bool {
+	if a.FinalCheck == nil || !a.FinalCheck.IsValid || len(a.BrokenEntries) > 0 {
+		return false
+	}
+	return a.SealValid == nil || *a.SealValid
+}
+
+// BuildCustodyAffidavit gathers the custody entries, integrity checks and final
+// integrity status of evidence for an affidavit sworn by affiantID. The
+// affiant's name may be left empty to be written in by hand.
+func (bwc *BWCSystem) BuildCustodyAffidavit(evidenceID, affiantID, affiantName string) (*CustodyAffidavit, error) {
+	if err := bwc.authorize(affiantID, PermExport, "Custody affidavit", evidenceID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(affiantID) == "" {
+		return nil, errors.New("affiant is required")
+	}
+	if err := bwc.rejectIfRestricted(evidenceID, affiantID, "Custody affidavit"); err != nil {
+		return nil, err
+	}
+
+	broken, err := bwc.VerifyCustodySignatures(evidenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	bwc.mu.RLock()
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		bwc.mu.RUnlock()
+		return nil, ErrEvidenceNotFound
+	}
+	affidavit := &CustodyAffidavit{
+		GeneratedAt:   time.Now(),
+		System:        bwc.config.System.Name,
+		AffiantID:     affiantID,
+		AffiantName:   strings.TrimSpace(affiantName),
+		Evidence:      copyEvidence(evidence),
+		BrokenEntries: broken,
+	}
+	sealed := evidence.Seal != nil
+	bwc.mu.RUnlock()
+
+	if n := len(affidavit.Evidence.IntegrityChecks); n > 0 {
+		check := affidavit.Evidence.IntegrityChecks[n-1]
+		affidavit.FinalCheck = &check
+	}
+	if sealed {
+		valid, err := bwc.VerifySeal(evidenceID)
+		if err != nil {
+			return nil, err
+		}
+		affidavit.SealValid = &valid
+	}
+	return affidavit, nil
+}
+
+// GenerateCustodyAffidavitPDF renders the chain-of-custody affidavit for
+// evidence as a PDF for affiantID to sign before a notary and file
+func (bwc *BWCSystem) GenerateCustodyAffidavitPDF(evidenceID, affiantID, affiantName string) ([]byte, error) {
+	affidavit, err := bwc.BuildCustodyAffidavit(evidenceID, affiantID, affiantName)
+	if err != nil {
+		return nil, err
+	}
+	data := affidavit.PDF()
+
+	status := "INTACT"
+	if !affidavit.Intact() {
+		status = "NOT INTACT"
+	}
+	bwc.logAudit(affiantID, "GENERATE_AFFIDAVIT", evidenceID,
+		fmt.Sprintf("Custody affidavit generated (%d custody entries, chain %s)", len(affidavit.Evidence.ChainOfCustody), status), "")
+	return data, nil
+}
+
+// PDF renders the affidavit in the standard declaration format: the
+// affiant's statement, numbered paragraphs identifying the item and
+// reciting each custody entry and integrity check, the final status, and
+// signature and notary blocks
+func (a *CustodyAffidavit) PDF() []byte {
+	ev := a.Evidence
+	p := newPDFWriter("Chain of Custody Affidavit "+ev.ID, "Affidavit of chain of custody: "+ev.ID)
+
+	p.Centered(pdfBold, 14, "AFFIDAVIT OF CHAIN OF CUSTODY")
+	if a.System != "" {
+		p.Centered(pdfRegular, 10, a.System)
+	}
+	p.Gap(8)
+	p.Text(pdfRegular, 10, 0, "STATE OF ______________________")
+	p.Text(pdfRegular, 10, 0, "COUNTY OF _____________________")
+	p.Text(pdfRegular, 10, 0, "Case No. "+ev.CaseNumber)
+	p.Gap(8)
+
+	name := a.AffiantName
+	if name == "" {
+		name = "______________________________"
+	}
+	p.Text(pdfRegular, 10, 0, fmt.Sprintf(
+		"I, %s (ID %s), being first duly sworn, depose and state as follows:", name, a.AffiantID))
+	p.Gap(6)
+
+	para := 0
+	paragraph := func(s string) {
+		para++
+		p.Text(pdfRegular, 10, 0, fmt.Sprintf("%d. %s", para, s))
+		p.Gap(4)
+	}
+
+	paragraph("I am over the age of eighteen and competent to make this affidavit. I have access to " +
+		"the evidence management system and its records, which are made and kept in the regular " +
+		"course of business at or near the time of each event recorded.")
+	paragraph(fmt.Sprintf("The evidence item identified below was recorded by %s (%s) and entered into the "+
+		"system on %s. Its SHA-256 digest was computed on entry and is stated below.",
+		ev.OfficerName, ev.OfficerID, ev.CreatedAt.Format(affidavitTimeLayout)))
+
+	p.Text(pdfMono, 8, 18, "Evidence ID:  "+ev.ID)
+	p.Text(pdfMono, 8, 18, "Case number:  "+ev.CaseNumber)
+	p.Text(pdfMono, 8, 18, fmt.Sprintf("File size:    %d bytes", ev.FileSize))
+	p.Text(pdfMono, 8, 18, "SHA-256:      "+ev.FileHash)
+	p.Text(pdfMono, 8, 18, "Location:     "+ev.Location)
+	p.Text(pdfMono, 8, 18, "Status:       "+string(ev.Status))
+	p.Gap(6)
+
+	paragraph(fmt.Sprintf("The following %d entries are the complete chain of custody of the item as recorded "+
+		"by the system, in order.", len(ev.ChainOfCustody)))
+	broken := make(map[int]bool)
+	for _, i := range a.BrokenEntries {
+		broken[i] = true
+	}
+	for i, entry := range ev.ChainOfCustody {
+		p.KeepTogether(60)
+		p.Text(pdfBold, 9, 18, fmt.Sprintf("(%d) %s  %s", i+1, entry.Timestamp.Format(affidavitTimeLayout), entry.Action))
+		from := entry.FromOfficer
+		if from == "" {
+			from = "-"
+		}
+		p.Text(pdfRegular, 9, 36, fmt.Sprintf("From: %s    To: %s", from, entry.ToOfficer))
+		if entry.Purpose != "" {
+			p.Text(pdfRegular, 9, 36, "Purpose: "+entry.Purpose)
+		}
+		p.Text(pdfMono, 8, 36, "Verified hash: "+entry.VerifiedHash)
+		if sig := entry.Signature; sig != nil {
+			p.Text(pdfRegular, 9, 36, fmt.Sprintf("Signed (%s) by %s at %s", sig.Type, sig.SignerID, sig.SignedAt.Format(affidavitTimeLayout)))
+		}
+		if broken[i] {
+			p.Text(pdfBold, 9, 36, "WARNING: this entry no longer matches its recorded hash or signature")
+		}
+		p.Gap(3)
+	}
+	p.Gap(4)
+
+	paragraph(fmt.Sprintf("The integrity of the item was verified %d time(s) by recomputing its SHA-256 digest "+
+		"and comparing it with the digest recorded on entry, with these results:", len(ev.IntegrityChecks)))
+	for _, check := range ev.IntegrityChecks {
+		result := "PASSED"
+		if !check.IsValid {
+			result = "FAILED"
+		}
+		p.Text(pdfRegular, 9, 18, fmt.Sprintf("%s  %s  by %s", check.Timestamp.Format(affidavitTimeLayout), result, check.CheckedBy))
+		if !check.IsValid && check.Notes != "" {
+			p.Text(pdfRegular, 9, 36, check.Notes)
+		}
+	}
+	p.Gap(6)
+
+	paragraph(a.finalStatement())
+	if a.SealValid != nil {
+		seal := "The record's seal signature verified."
+		if !*a.SealValid {
+			seal = "The record's seal signature did NOT verify."
+		}
+		paragraph(fmt.Sprintf("The item is sealed under %s. %s", ev.Seal.Authority, seal))
+	}
+	paragraph("I declare under penalty of perjury under the laws of the State of ______________ " +
+		"that the foregoing is true and correct.")
+
+	p.KeepTogether(200)
+	p.Gap(18)
+	p.Text(pdfRegular, 10, 0, "Executed on ________________ at ______________________________.")
+	p.Gap(24)
+	p.Text(pdfRegular, 10, 0, "______________________________________")
+	p.Text(pdfRegular, 10, 0, "Signature of affiant")
+	p.Gap(12)
+	p.Text(pdfRegular, 10, 0, "Printed name: "+name)
+	p.Text(pdfRegular, 10, 0, "ID / badge number: "+a.AffiantID)
+	p.Gap(24)
+	p.Text(pdfRegular, 10, 0, "Subscribed and sworn to before me on ________________ by the affiant named above.")
+	p.Gap(24)
+	p.Text(pdfRegular, 10, 0, "______________________________________")
+	p.Text(pdfRegular, 10, 0, "Notary Public                                       (Seal)")
+	p.Text(pdfRegular, 10, 0, "My commission expires: ________________")
+
+	p.Gap(12)
+	p.Text(pdfRegular, 7, 0, fmt.Sprintf("Generated %s from the system's records.", a.GeneratedAt.Format(affidavitTimeLayout)))
+	return p.Bytes()
+}
+
+// finalStatement is the affidavit paragraph giving the item's final integrity status
+func (a *CustodyAffidavit) finalStatement() string {
+	if a.FinalCheck == nil {
+		return "The integrity of the item has not been verified since it was entered."
+	}
+	check := a.FinalCheck
+	s := fmt.Sprintf("At the most recent verification, on %s, the digest of the item ", check.Timestamp.Format(affidavitTimeLayout))
+	if check.IsValid {
+		s += "matched the digest recorded on entry."
+	} else {
+		s += "did NOT match the digest recorded on entry."
+	}
+	if len(a.BrokenEntries) == 0 {
+		s += " Every custody entry matches its recorded hash and signature."
+	} else if len(a.BrokenEntries) == 1 {
+		s += " 1 custody entry no longer matches its recorded hash or signature."
+	} else {
+		s += fmt.Sprintf(" %d custody entries no longer match their recorded hash or signature.", len(a.BrokenEntries))
+	}
+	if a.Intact() {
+		s += " To the best of my knowledge the chain of custody is unbroken and the item is unaltered."
+	}
+	return s
+}
@@ -0,0 +1,111 @@
+package bwc
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestBuildCustodyAffidavit(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-AFF-001", "OFF-1080", "Officer Test", "Test Location", nil)
+	sig := &CustodySignature{Type: SignatureTyped, SignerID: "DET-1081", Acknowledgment: "Received by Det. Jones"}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-1080", "DET-1081", "Analysis", sig); err != nil {
+		t.Fatalf("Transfer failed: %v", err)
+	}
+
+	if _, err := system.BuildCustodyAffidavit(evidence.ID, "", ""); err == nil {
+		t.Error("Expected an affiant to be required")
+	}
+	if _, err := system.BuildCustodyAffidavit("EV-MISSING", "DET-1081", ""); err == nil {
+		t.Error("Expected an error for missing evidence")
+	}
+
+	affidavit, err := system.BuildCustodyAffidavit(evidence.ID, "DET-1081", "Dana Jones")
+	if err != nil {
+		t.Fatalf("BuildCustodyAffidavit failed: %v", err)
+	}
+	if !affidavit.Intact() || affidavit.FinalCheck == nil || len(affidavit.Evidence.ChainOfCustody) != 2 {
+		t.Errorf("Expected an intact affidavit with two custody entries, got %+v", affidavit)
+	}
+
+	// A failed verification and an altered custody entry both break the chain
+	os.WriteFile(evidence.FilePath, []byte("tampered"), 0600)
+	system.VerifyIntegrity(evidence.ID, "DET-1081")
+	system.mu.Lock()
+	evidence.ChainOfCustody[1].Purpose = "Altered"
+	system.mu.Unlock()
+
+	affidavit, _ = system.BuildCustodyAffidavit(evidence.ID, "DET-1081", "")
+	if affidavit.Intact() || affidavit.FinalCheck.IsValid || len(affidavit.BrokenEntries) != 1 || affidavit.BrokenEntries[0] != 1 {
+		t.Errorf("Expected a broken chain, got %+v", affidavit)
+	}
+}
+
+func TestCustodyAffidavitPDF(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.System.Name = "County Sheriff"
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-AFF-002", "OFF-1082", "Officer Test", "Test Location", nil)
+	system.TransferCustody(evidence.ID, "OFF-1082", "DET-1083", "Analysis (interview)")
+	if _, err := system.SealEvidence(evidence.ID, "Order 26-0412"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+
+	data, err := system.GenerateCustodyAffidavitPDF(evidence.ID, "DET-1083", "Dana Jones")
+	if err != nil {
+		t.Fatalf("GenerateCustodyAffidavitPDF failed: %v", err)
+	}
+	for _, want := range []string{
+		"%PDF-1.4",
+		"(AFFIDAVIT OF CHAIN OF CUSTODY)",
+		"(County Sheriff)",
+		"I, Dana Jones \\(ID DET-1083\\)",
+		evidence.FileHash,
+		"Purpose: Analysis \\(interview\\)",
+		"matched the digest recorded on entry.",
+		"sealed under Order 26-0412. The record's seal signature verified.",
+		"(Signature of affiant)",
+		"(Notary Public",
+	} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("Expected %q in the affidavit", want)
+		}
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "DET-1083")
+	if len(logs) != 1 || logs[0].Action != "GENERATE_AFFIDAVIT" {
+		t.Errorf("Expected GENERATE_AFFIDAVIT audit entry, got %+v", logs)
+	}
+}
+
+func TestAffidavitEndpoint(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-AFF-003", "OFF-1084", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/evidence/"+evidence.ID+"/affidavit?name=Casey+Smith")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/pdf" {
+		t.Fatalf("Expected a PDF, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	if !bytes.Contains(buf.Bytes(), []byte("I, Casey Smith \\(ID CUS-001\\)")) {
+		t.Error("Expected the caller as affiant")
+	}
+
+	resp = authGet(t, server, "/api/evidence/EV-MISSING/affidavit")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", resp.StatusCode)
+	}
+}
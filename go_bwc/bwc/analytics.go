@@ -0,0 +1,339 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// analyticsDayLayout names the days of an analytics series
+const analyticsDayLayout = "2006-01-02"
+
+// defaultAnalyticsDays and maxAnalyticsDays bound an analytics window
+const (
+	defaultAnalyticsDays = 30
+	maxAnalyticsDays     = 366
+)
+
+// AnalyticsWindow is the run of whole local days a series covers, ending
+// with the day of Through
+type AnalyticsWindow struct {
+	Days    int       `json:"days"`
+	From    time.Time `json:"from"`
+	Through time.Time `json:"through"`
+}
+
+// newAnalyticsWindow covers the days days ending with the day of now
+func newAnalyticsWindow(now time.Time, days int) (AnalyticsWindow, error) {
+	if days < 1 || days > maxAnalyticsDays {
+		return AnalyticsWindow{}, fmt.Errorf("analytics days must be between 1 and %d", maxAnalyticsDays)
+	}
+	end := startOfDay(now).AddDate(0, 0, 1)
+	return AnalyticsWindow{Days: days, From: end.AddDate(0, 0, -days), Through: now}, nil
+}
+
+// dayEnd returns the end of the window's i-th day, or now for the last one
+func (w AnalyticsWindow) dayEnd(i int) time.Time {
+	end := w.From.AddDate(0, 0, i+1)
+	if end.After(w.Through) {
+		return w.Through
+	}
+	return end
+}
+
+// day returns the name of the window's i-th day
+func (w AnalyticsWindow) day(i int) string {
+	return w.From.AddDate(0, 0, i).Format(analyticsDayLayout)
+}
+
+// dayIndex returns which day of the window t falls on, or -1 outside it
+func (w AnalyticsWindow) dayIndex(t time.Time) int {
+	if t.Before(w.From) || t.After(w.Through) {
+		return -1
+	}
+	for i := 0; i < w.Days; i++ {
+		if t.Before(w.From.AddDate(0, 0, i+1)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// startOfDay returns local midnight on t's day
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// IngestDay is one day's ingest volume
+type IngestDay struct {
+	Day   string `json:"day"`
+	Items int    `json:"items"`
+	Bytes int64  `json:"bytes"`
+}
+
+// IngestVolume is evidence ingested per day
+type IngestVolume struct {
+	AnalyticsWindow
+	Items int         `json:"items"`
+	Bytes int64       `json:"bytes"`
+	Daily []IngestDay `json:"daily"`
+}
+
+// StorageDay is the evidence held in storage at the end of one day
+type StorageDay struct {
+	Day     string `json:"day"`
+	Bytes   int64  `json:"bytes"`
+	Added   int64  `json:"added"`
+	Removed int64  `json:"removed"`
+}
+
+// StorageGrowth is stored evidence bytes per day. BytesPerDay is the
+// average net growth over the window.
+type StorageGrowth struct {
+	AnalyticsWindow
+	BytesPerDay float64      `json:"bytes_per_day"`
+	Daily       []StorageDay `json:"daily"`
+}
+
+// LatencyDay is the processing jobs that finished on one day
+type LatencyDay struct {
+	Day            string  `json:"day"`
+	Jobs           int     `json:"jobs"`
+	Failed         int     `json:"failed"`
+	AverageSeconds float64 `json:"average_seconds"`
+}
+
+// ProcessingLatency is how long processing jobs took from being queued to
+// finishing, per day they finished
+type ProcessingLatency struct {
+	AnalyticsWindow
+	Jobs           int          `json:"jobs"`
+	AverageSeconds float64      `json:"average_seconds"`
+	Daily          []LatencyDay `json:"daily"`
+}
+
+// BacklogDay is how many items were overdue for verification at the end of one day
+type BacklogDay struct {
+	Day     string `json:"day"`
+	Overdue int    `json:"overdue"`
+}
+
+// VerificationBacklog is evidence overdue for scheduled verification. Past
+// days use each item's current verification priority.
+type VerificationBacklog struct {
+	AnalyticsWindow
+	Overdue    int                          `json:"overdue"`
+	ByPriority map[VerificationPriority]int `json:"by_priority"`
+	Daily      []BacklogDay                 `json:"daily"`
+}
+
+// purgedAt returns when evidence was purged by retention, if it was
+func purgedAt(evidence *Evidence) (time.Time, bool) {
+	for i := len(evidence.ChainOfCustody) - 1; i >= 0; i-- {
+		if evidence.ChainOfCustody[i].Action == "PURGED" {
+			return evidence.ChainOfCustody[i].Timestamp, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// IngestVolume counts the evidence ingested on each of the days days ending
+// with the day of now
+func (bwc *BWCSystem) IngestVolume(now time.Time, days int) (*IngestVolume, error) {
+	window, err := newAnalyticsWindow(now, days)
+	if err != nil {
+		return nil, err
+	}
+	volume := &IngestVolume{AnalyticsWindow: window, Daily: make([]IngestDay, days)}
+	for i := range volume.Daily {
+		volume.Daily[i].Day = window.day(i)
+	}
+
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		i := window.dayIndex(evidence.CreatedAt)
+		if i < 0 {
+			continue
+		}
+		volume.Daily[i].Items++
+		volume.Daily[i].Bytes += evidence.FileSize
+		volume.Items++
+		volume.Bytes += evidence.FileSize
+	}
+	return volume, nil
+}
+
+// StorageGrowth reports the evidence bytes held at the end of each of the
+// days days ending with the day of now, counting ingests and retention purges
+func (bwc *BWCSystem) StorageGrowth(now time.Time, days int) (*StorageGrowth, error) {
+	window, err := newAnalyticsWindow(now, days)
+	if err != nil {
+		return nil, err
+	}
+	growth := &StorageGrowth{AnalyticsWindow: window, Daily: make([]StorageDay, days)}
+	for i := range growth.Daily {
+		growth.Daily[i].Day = window.day(i)
+	}
+
+	bwc.mu.RLock()
+	var before int64
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.CreatedAt.Before(window.From) {
+			before += evidence.FileSize
+		} else if i := window.dayIndex(evidence.CreatedAt); i >= 0 {
+			growth.Daily[i].Added += evidence.FileSize
+		}
+		if purged, ok := purgedAt(evidence); ok {
+			if purged.Before(window.From) {
+				before -= evidence.FileSize
+			} else if i := window.dayIndex(purged); i >= 0 {
+				growth.Daily[i].Removed += evidence.FileSize
+			}
+		}
+	}
+	bwc.mu.RUnlock()
+
+	held := before
+	for i := range growth.Daily {
+		held += growth.Daily[i].Added - growth.Daily[i].Removed
+		growth.Daily[i].Bytes = held
+	}
+	growth.BytesPerDay = float64(held-before) / float64(days)
+	return growth, nil
+}
+
+// ProcessingLatency averages the time from queueing to finishing of the
+// processing jobs that finished on each of the days days ending with the day
+// of now. Failed jobs count towards the latency.
+func (bwc *BWCSystem) ProcessingLatency(now time.Time, days int) (*ProcessingLatency, error) {
+	window, err := newAnalyticsWindow(now, days)
+	if err != nil {
+		return nil, err
+	}
+	latency := &ProcessingLatency{AnalyticsWindow: window, Daily: make([]LatencyDay, days)}
+	totals := make([]time.Duration, days)
+	for i := range latency.Daily {
+		latency.Daily[i].Day = window.day(i)
+	}
+
+	bwc.mu.RLock()
+	var total time.Duration
+	for _, job := range bwc.jobs {
+		if job.Status != JobSucceeded && job.Status != JobFailed {
+			continue
+		}
+		i := window.dayIndex(job.FinishedAt)
+		if i < 0 {
+			continue
+		}
+		took := job.FinishedAt.Sub(job.QueuedAt)
+		totals[i] += took
+		total += took
+		latency.Daily[i].Jobs++
+		latency.Jobs++
+		if job.Status == JobFailed {
+			latency.Daily[i].Failed++
+		}
+	}
+	bwc.mu.RUnlock()
+
+	for i, day := range latency.Daily {
+		if day.Jobs > 0 {
+			latency.Daily[i].AverageSeconds = totals[i].Seconds() / float64(day.Jobs)
+		}
+	}
+	if latency.Jobs > 0 {
+		latency.AverageSeconds = total.Seconds() / float64(latency.Jobs)
+	}
+	return latency, nil
+}
+
+// VerificationBacklog counts the evidence overdue for scheduled verification
+// now, by priority, and at the end of each of the days days ending with the
+// day of now. Deleted evidence is not verified on a schedule and is left
+// out.
+func (bwc *BWCSystem) VerificationBacklog(now time.Time, days int) (*VerificationBacklog, error) {
+	window, err := newAnalyticsWindow(now, days)
+	if err != nil {
+		return nil, err
+	}
+	backlog := &VerificationBacklog{
+		AnalyticsWindow: window,
+		ByPriority:      make(map[VerificationPriority]int),
+		Daily:           make([]BacklogDay, days),
+	}
+	for i := range backlog.Daily {
+		backlog.Daily[i].Day = window.day(i)
+	}
+
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted {
+			continue
+		}
+		priority := bwc.config.verificationPriority(evidence, now)
+		interval := bwc.config.verificationInterval(priority)
+		if lastVerified(evidence).Add(interval).Before(now) {
+			backlog.Overdue++
+			backlog.ByPriority[priority]++
+		}
+
+		for i := range backlog.Daily {
+			end := window.dayEnd(i)
+			if evidence.CreatedAt.After(end) {
+				continue
+			}
+			if lastVerifiedBy(evidence, end).Add(interval).Before(end) {
+				backlog.Daily[i].Overdue++
+			}
+		}
+	}
+	return backlog, nil
+}
+
+// lastVerifiedBy returns when evidence last had an integrity check at or
+// before t, or when it was ingested if it had none by then
+func lastVerifiedBy(evidence *Evidence, t time.Time) time.Time {
+	last := evidence.CreatedAt
+	for _, checks := range [][]IntegrityCheck{evidence.IntegrityChecks, evidence.SealedChecks} {
+		for _, check := range checks {
+			if check.Timestamp.After(t) {
+				break
+			}
+			if check.Timestamp.After(last) {
+				last = check.Timestamp
+			}
+		}
+	}
+	return last
+}
+
+// analyticsMetrics are the series served under /api/analytics/
+var analyticsMetrics = map[string]func(bwc *BWCSystem, now time.Time, days int) (interface{}, error){
+	"ingest": func(bwc *BWCSystem, now time.Time, days int) (interface{}, error) {
+		return bwc.IngestVolume(now, days)
+	},
+	"storage": func(bwc *BWCSystem, now time.Time, days int) (interface{}, error) {
+		return bwc.StorageGrowth(now, days)
+	},
+	"processing": func(bwc *BWCSystem, now time.Time, days int) (interface{}, error) {
+		return bwc.ProcessingLatency(now, days)
+	},
+	"verification": func(bwc *BWCSystem, now time.Time, days int) (interface{}, error) {
+		return bwc.VerificationBacklog(now, days)
+	},
+}
+
+// Analytics computes the named series over the days days ending with the day of now
+func (bwc *BWCSystem) Analytics(metric string, now time.Time, days int) (interface{}, error) {
+	compute, known := analyticsMetrics[metric]
+	if !known {
+		return nil, errors.New("metric must be ingest, storage, processing or verification")
+	}
+	return compute(bwc, now, days)
+}
@@ -0,0 +1,175 @@
+package bwc
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// analyticsNow is midday, so that hour offsets stay on the same day
+var analyticsNow = time.Date(2024, 6, 10, 12, 0, 0, 0, time.Local)
+
+func TestAnalyticsWindow(t *testing.T) {
+	window, err := newAnalyticsWindow(analyticsNow, 3)
+	if err != nil {
+		t.Fatalf("newAnalyticsWindow failed: %v", err)
+	}
+	if window.day(0) != "2024-06-08" || window.day(2) != "2024-06-10" {
+		t.Errorf("unexpected days %s to %s", window.day(0), window.day(2))
+	}
+	if i := window.dayIndex(analyticsNow.Add(-36 * time.Hour)); i != 1 {
+		t.Errorf("expected day 1, got %d", i)
+	}
+	if i := window.dayIndex(analyticsNow.AddDate(0, 0, -3)); i != -1 {
+		t.Errorf("expected a time before the window to be outside it, got %d", i)
+	}
+	if !window.dayEnd(2).Equal(analyticsNow) {
+		t.Errorf("expected the last day to end now, got %v", window.dayEnd(2))
+	}
+
+	for _, days := range []int{0, maxAnalyticsDays + 1} {
+		if _, err := newAnalyticsWindow(analyticsNow, days); err == nil {
+			t.Errorf("expected %d days to be refused", days)
+		}
+	}
+}
+
+func TestIngestVolumeAndStorageGrowth(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	file := createTestFile(t, tmpDir)
+
+	created := []time.Time{
+		analyticsNow.AddDate(0, 0, -10),
+		analyticsNow.AddDate(0, 0, -1),
+		analyticsNow.AddDate(0, 0, -1),
+		analyticsNow.Add(-time.Hour),
+	}
+	var items []*Evidence
+	for i, at := range created {
+		ev, err := system.IngestEvidence(file, "CASE-ANL-"+string(rune('A'+i)), "OFF-1106", "", "", nil)
+		if err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+		system.evidenceDB.Get(ev.ID).CreatedAt = at
+		items = append(items, system.evidenceDB.Get(ev.ID))
+	}
+	size := items[0].FileSize
+	// The oldest item was purged yesterday
+	items[0].ChainOfCustody = append(items[0].ChainOfCustody, CustodyEntry{Timestamp: analyticsNow.AddDate(0, 0, -1), Action: "PURGED"})
+
+	volume, err := system.IngestVolume(analyticsNow, 3)
+	if err != nil {
+		t.Fatalf("IngestVolume failed: %v", err)
+	}
+	if volume.Items != 3 || volume.Bytes != 3*size {
+		t.Errorf("expected 3 items in the window, got %d (%d bytes)", volume.Items, volume.Bytes)
+	}
+	if volume.Daily[0].Items != 0 || volume.Daily[1].Items != 2 || volume.Daily[2].Items != 1 {
+		t.Errorf("unexpected daily volume %+v", volume.Daily)
+	}
+
+	growth, err := system.StorageGrowth(analyticsNow, 3)
+	if err != nil {
+		t.Fatalf("StorageGrowth failed: %v", err)
+	}
+	want := []int64{size, 2 * size, 3 * size}
+	for i, day := range growth.Daily {
+		if day.Bytes != want[i] {
+			t.Errorf("day %s: expected %d bytes held, got %d", day.Day, want[i], day.Bytes)
+		}
+	}
+	if growth.Daily[1].Removed != size {
+		t.Errorf("expected the purge to be counted, got %+v", growth.Daily[1])
+	}
+	if growth.BytesPerDay != float64(2*size)/3 {
+		t.Errorf("unexpected growth rate %v", growth.BytesPerDay)
+	}
+}
+
+func TestProcessingLatency(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	finished := analyticsNow.Add(-2 * time.Hour)
+	system.jobs["JOB-1"] = &ProcessingJob{ID: "JOB-1", Status: JobSucceeded, QueuedAt: finished.Add(-10 * time.Second), FinishedAt: finished}
+	system.jobs["JOB-2"] = &ProcessingJob{ID: "JOB-2", Status: JobFailed, QueuedAt: finished.Add(-30 * time.Second), FinishedAt: finished}
+	system.jobs["JOB-3"] = &ProcessingJob{ID: "JOB-3", Status: JobRunning, QueuedAt: finished}
+	system.jobs["JOB-4"] = &ProcessingJob{ID: "JOB-4", Status: JobSucceeded, QueuedAt: finished.AddDate(0, 0, -5), FinishedAt: finished.AddDate(0, 0, -5)}
+
+	latency, err := system.ProcessingLatency(analyticsNow, 2)
+	if err != nil {
+		t.Fatalf("ProcessingLatency failed: %v", err)
+	}
+	if latency.Jobs != 2 || latency.AverageSeconds != 20 {
+		t.Errorf("expected 2 jobs averaging 20s, got %d averaging %v", latency.Jobs, latency.AverageSeconds)
+	}
+	if today := latency.Daily[1]; today.Jobs != 2 || today.Failed != 1 || today.AverageSeconds != 20 {
+		t.Errorf("unexpected day %+v", today)
+	}
+	if latency.Daily[0].Jobs != 0 || latency.Daily[0].AverageSeconds != 0 {
+		t.Errorf("expected an empty first day, got %+v", latency.Daily[0])
+	}
+}
+
+func TestVerificationBacklog(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.VerificationIntervalHours = 24
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-ANL-V", "OFF-1107", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	evidence := system.evidenceDB.Get(ev.ID)
+	evidence.CreatedAt = analyticsNow.AddDate(0, 0, -5)
+	// Verified two days ago, so overdue since yesterday midday
+	evidence.IntegrityChecks = []IntegrityCheck{{Timestamp: analyticsNow.AddDate(0, 0, -2), IsValid: true}}
+
+	backlog, err := system.VerificationBacklog(analyticsNow, 4)
+	if err != nil {
+		t.Fatalf("VerificationBacklog failed: %v", err)
+	}
+	if backlog.Overdue != 1 || backlog.ByPriority[PriorityNormal] != 1 {
+		t.Errorf("expected one NORMAL item overdue now, got %+v", backlog)
+	}
+	// Day 0 ends 2 days before the check, 4 days after ingest: overdue. Day 1
+	// ends the evening of the check: current. Days 2 and 3: overdue.
+	want := []int{1, 0, 1, 1}
+	for i, day := range backlog.Daily {
+		if day.Overdue != want[i] {
+			t.Errorf("day %s: expected %d overdue, got %d", day.Day, want[i], day.Overdue)
+		}
+	}
+}
+
+func TestAnalyticsAPI(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for _, metric := range []string{"ingest", "storage", "processing", "verification"} {
+		resp := authGet(t, server, "/api/analytics/"+metric+"?days=7")
+		var body struct {
+			Days  int               `json:"days"`
+			Daily []json.RawMessage `json:"daily"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != 200 || body.Days != 7 || len(body.Daily) != 7 {
+			t.Errorf("%s: expected 7 daily points, got status %d and %d points", metric, resp.StatusCode, len(body.Daily))
+		}
+	}
+
+	for path, status := range map[string]int{
+		"/api/analytics/weather":        404,
+		"/api/analytics/ingest?days=x":  400,
+		"/api/analytics/ingest?days=0":  400,
+		"/api/analytics/ingest?days=99": 200,
+	} {
+		resp := authGet(t, server, path)
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: expected %d, got %d", path, status, resp.StatusCode)
+		}
+	}
+}
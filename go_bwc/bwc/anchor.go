@@ -0,0 +1,454 @@
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MerkleAnchor commits to the hash of every item of evidence at one moment
+// with a single Merkle root. Kept, signed and timestamped, it is cheap
+// evidence that nothing in the archive has changed since: an inclusion proof
+// ties any one item to the root without the rest of the archive.
+type MerkleAnchor struct {
+	ID        string    `json:"id"`
+	Root      string    `json:"root"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	LeafCount int       `json:"leaf_count"`
+	// Leaves are the items anchored, ordered by evidence ID; listings leave
+	// them out
+	Leaves []AnchorLeaf `json:"leaves,omitempty"`
+	// Signature is a detached PKCS#7 signature over the anchor statement by
+	// the agency signing key, when one is configured
+	Signature []byte `json:"signature,omitempty"`
+	// Timestamp is the TSA's token over Root, when a TSA is configured
+	Timestamp *HashTimestamp `json:"timestamp,omitempty"`
+}
+
+// AnchorLeaf is one item of evidence in an anchor
+type AnchorLeaf struct {
+	EvidenceID string `json:"evidence_id"`
+	FileHash   string `json:"file_hash"`
+}
+
+// InclusionProof shows that an item's hash is one of the leaves under an
+// anchor's root: hashing the leaf up through Path yields Root
+type InclusionProof struct {
+	AnchorID   string      `json:"anchor_id"`
+	Root       string      `json:"root"`
+	AnchoredAt time.Time   `json:"anchored_at"`
+	EvidenceID string      `json:"evidence_id"`
+	FileHash   string      `json:"file_hash"`
+	Index      int         `json:"index"`
+	LeafCount  int         `json:"leaf_count"`
+	Path       []ProofStep `json:"path"`
+}
+
+// ProofStep is the sibling hashed with the running hash at one level of
+// the tree. Left is set when the sibling is the left-hand node.
+type ProofStep struct {
+	Hash string `json:"hash"`
+	Left bool   `json:"left"`
+}
+
+// AnchorVerification is the outcome of checking an anchor against itself and
+// against the evidence records as they are now
+type AnchorVerification struct {
+	AnchorID string `json:"anchor_id"`
+	// RootValid is set when the stored leaves still hash to the root
+	RootValid bool `json:"root_valid"`
+	// SignatureValid and TimestampValid are nil when the anchor has none
+	SignatureValid *bool `json:"signature_valid,omitempty"`
+	// Signer is the subject of the certificate that signed the anchor
+	Signer         string `json:"signer,omitempty"`
+	TimestampValid *bool  `json:"timestamp_valid,omitempty"`
+	// Changed lists evidence whose hash is no longer the one anchored, and
+	// Missing evidence whose record is gone
+	Changed []string `json:"changed"`
+	Missing []string `json:"missing"`
+	Valid   bool     `json:"valid"`
+}
+
+// anchorsDir holds one JSON file per anchor
+func (bwc *BWCSystem) anchorsDir() string {
+	return filepath.Join(bwc.storagePath, "anchors")
+}
+
+// Leaf and interior nodes are hashed with different prefixes, as in RFC
+// 6962, so a leaf cannot be passed off as an interior node
+func merkleLeafHash(leaf AnchorLeaf) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write([]byte(leaf.EvidenceID))
+	h.Write([]byte{0})
+	h.Write([]byte(leaf.FileHash))
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleLevels builds the tree over leaves bottom up. A node without a
+// sibling is carried up to the next level unchanged.
+func merkleLevels(leaves []AnchorLeaf) [][][]byte {
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = merkleLeafHash(leaf)
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+			} else {
+				next = append(next, merkleNodeHash(level[i], level[i+1]))
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// merkleRoot is the hex root over leaves; an empty tree's root is the
+// SHA-256 of nothing
+func merkleRoot(leaves []AnchorLeaf) string {
+	if len(leaves) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+	levels := merkleLevels(leaves)
+	return hex.EncodeToString(levels[len(levels)-1][0])
+}
+
+// merklePath is the sibling at each level from leaf index up to the root
+func merklePath(leaves []AnchorLeaf, index int) []ProofStep {
+	path := make([]ProofStep, 0)
+	levels := merkleLevels(leaves)
+	for _, level := range levels[:len(levels)-1] {
+		sibling := index ^ 1
+		if sibling < len(level) {
+			path = append(path, ProofStep{Hash: hex.EncodeToString(level[sibling]), Left: sibling < index})
+		}
+		index /= 2
+	}
+	return path
+}
+
+// Verify recomputes the root from the proof's leaf and path. It shows the
+// item was anchored; that the root itself is genuine is shown by the anchor's
+// signature and timestamp.
+func (p *InclusionProof) Verify() error {
+	node := merkleLeafHash(AnchorLeaf{EvidenceID: p.EvidenceID, FileHash: p.FileHash})
+	for _, step := range p.Path {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil || len(sibling) != sha256.Size {
+			return fmt.Errorf("malformed proof hash %q", step.Hash)
+		}
+		if step.Left {
+			node = merkleNodeHash(sibling, node)
+		} else {
+			node = merkleNodeHash(node, sibling)
+		}
+	}
+	if hex.EncodeToString(node) != strings.ToLower(p.Root) {
+		return fmt.Errorf("proof for %s does not lead to root %s", p.EvidenceID, p.Root)
+	}
+	return nil
+}
+
+// anchorStatement is what the agency key signs: the root with what the
+// anchor says about it
+func anchorStatement(anchor *MerkleAnchor) []byte {
+	return []byte(fmt.Sprintf("BWC MERKLE ANCHOR\nID: %s\nCreated: %s\nLeaves: %d\nRoot: %s\n",
+		anchor.ID, anchor.CreatedAt.UTC().Format(time.RFC3339Nano), anchor.LeafCount, anchor.Root))
+}
+
+// AnchorEvidence builds a Merkle tree over the current hash of every item of
+// evidence that is not deleted and stores its root. With an agency signing
+// key the anchor is signed, and with a TSA its root is timestamped.
+func (bwc *BWCSystem) AnchorEvidence(userID string) (*MerkleAnchor, error) {
+	return bwc.AnchorEvidenceContext(context.Background(), userID)
+}
+
+// AnchorEvidenceContext anchors like AnchorEvidence, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) AnchorEvidenceContext(ctx context.Context, userID string) (*MerkleAnchor, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermVerify, "Evidence anchoring", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	leaves := make([]AnchorLeaf, 0)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted {
+			continue
+		}
+		leaves = append(leaves, AnchorLeaf{EvidenceID: evidence.ID, FileHash: evidence.FileHash})
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].EvidenceID < leaves[j].EvidenceID })
+
+	bwc.anchorSeq++
+	anchor := &MerkleAnchor{
+		ID:        fmt.Sprintf("ANC-%06d", bwc.anchorSeq),
+		Root:      merkleRoot(leaves),
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+		LeafCount: len(leaves),
+		Leaves:    leaves,
+	}
+	if bwc.reportSigner != nil {
+		signature, err := bwc.reportSigner.sign(anchorStatement(anchor), anchor.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign anchor: %w", err)
+		}
+		anchor.Signature = signature
+	}
+	if bwc.tsa != nil {
+		stamp, err := bwc.tsa.timestamp(anchor.Root)
+		if err != nil {
+			bwc.logAudit("SYSTEM", "TIMESTAMP_FAILED", "", fmt.Sprintf("Anchor %s: %v", anchor.ID, err), "")
+		} else {
+			anchor.Timestamp = stamp
+		}
+	}
+
+	if err := os.MkdirAll(bwc.anchorsDir(), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create anchors directory: %w", err)
+	}
+	if err := writeSnapshot(filepath.Join(bwc.anchorsDir(), anchor.ID+".json"), anchor); err != nil {
+		return nil, err
+	}
+
+	bwc.logAuditAs(auth, userID, "ANCHOR_EVIDENCE", "",
+		fmt.Sprintf("Anchor %s over %d item(s), root %s", anchor.ID, anchor.LeafCount, anchor.Root), "")
+	return anchor, nil
+}
+
+// loadAnchorLocked reads anchor id from disk. The caller must hold bwc.mu.
+func (bwc *BWCSystem) loadAnchorLocked(id string) (*MerkleAnchor, error) {
+	if !strings.HasPrefix(id, "ANC-") || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("anchor %s not found", id)
+	}
+	data, err := os.ReadFile(filepath.Join(bwc.anchorsDir(), id+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("anchor %s not found", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read anchor: %w", err)
+	}
+	var anchor MerkleAnchor
+	if err := json.Unmarshal(data, &anchor); err != nil {
+		return nil, fmt.Errorf("anchor %s: %w", id, err)
+	}
+	return &anchor, nil
+}
+
+// anchorIDsLocked lists the stored anchors, oldest first. The caller must
+// hold bwc.mu.
+func (bwc *BWCSystem) anchorIDsLocked() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(bwc.anchorsDir(), "ANC-*.json"))
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(paths))
+	for i, path := range paths {
+		ids[i] = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// loadAnchorSeq continues anchor numbering after the anchors on disk
+func (bwc *BWCSystem) loadAnchorSeq() error {
+	ids, err := bwc.anchorIDsLocked()
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		var seq int
+		if _, err := fmt.Sscanf(id, "ANC-%d", &seq); err == nil && seq > bwc.anchorSeq {
+			bwc.anchorSeq = seq
+		}
+	}
+	return nil
+}
+
+// Anchors lists the stored anchors, oldest first, without their leaves
+func (bwc *BWCSystem) Anchors() ([]*MerkleAnchor, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	ids, err := bwc.anchorIDsLocked()
+	if err != nil {
+		return nil, err
+	}
+	anchors := make([]*MerkleAnchor, 0, len(ids))
+	for _, id := range ids {
+		anchor, err := bwc.loadAnchorLocked(id)
+		if err != nil {
+			return nil, err
+		}
+		anchor.Leaves = nil
+		anchors = append(anchors, anchor)
+	}
+	return anchors, nil
+}
+
+// InclusionProof proves evidenceID was in anchorID, or in the latest anchor
+// that holds it when anchorID is empty
+func (bwc *BWCSystem) InclusionProof(evidenceID, anchorID string) (*InclusionProof, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	ids := []string{anchorID}
+	if anchorID == "" {
+		var err error
+		if ids, err = bwc.anchorIDsLocked(); err != nil {
+			return nil, err
+		}
+	}
+	for i := len(ids) - 1; i >= 0; i-- {
+		anchor, err := bwc.loadAnchorLocked(ids[i])
+		if err != nil {
+			return nil, err
+		}
+		index := sort.Search(len(anchor.Leaves), func(j int) bool { return anchor.Leaves[j].EvidenceID >= evidenceID })
+		if index == len(anchor.Leaves) || anchor.Leaves[index].EvidenceID != evidenceID {
+			continue
+		}
+		return &InclusionProof{
+			AnchorID:   anchor.ID,
+			Root:       anchor.Root,
+			AnchoredAt: anchor.CreatedAt,
+			EvidenceID: evidenceID,
+			FileHash:   anchor.Leaves[index].FileHash,
+			Index:      index,
+			LeafCount:  len(anchor.Leaves),
+			Path:       merklePath(anchor.Leaves, index),
+		}, nil
+	}
+	if anchorID != "" {
+		return nil, fmt.Errorf("evidence %s is not in anchor %s", evidenceID, anchorID)
+	}
+	return nil, fmt.Errorf("evidence %s has not been anchored", evidenceID)
+}
+
+// VerifyAnchor checks that anchorID's leaves still hash to its root, that
+// its signature and timestamp hold, and which items have changed or gone
+// since it was made
+func (bwc *BWCSystem) VerifyAnchor(anchorID, userID string) (*AnchorVerification, error) {
+	return bwc.VerifyAnchorContext(context.Background(), anchorID, userID)
+}
+
+// VerifyAnchorContext verifies like VerifyAnchor, as the principal ctx carries
+// when it has one
+func (bwc *BWCSystem) VerifyAnchorContext(ctx context.Context, anchorID, userID string) (*AnchorVerification, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermVerify, "Anchor verification", ""); err != nil {
+		return nil, err
+	}
+
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	anchor, err := bwc.loadAnchorLocked(anchorID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnchorVerification{AnchorID: anchor.ID, Changed: make([]string, 0), Missing: make([]string, 0)}
+	result.RootValid = len(anchor.Leaves) == anchor.LeafCount && merkleRoot(anchor.Leaves) == anchor.Root
+	result.Valid = result.RootValid
+	if anchor.Signature != nil {
+		signature, err := VerifyReportSignature(anchorStatement(anchor), anchor.Signature, nil)
+		ok := err == nil
+		if ok {
+			result.Signer = signature.Signer
+		}
+		result.SignatureValid = &ok
+		result.Valid = result.Valid && ok
+	}
+	if anchor.Timestamp != nil {
+		var roots *x509.CertPool
+		if bwc.tsa != nil {
+			roots = bwc.tsa.roots
+		}
+		_, err := VerifyHashTimestamp(anchor.Timestamp.Token, anchor.Root, roots)
+		ok := err == nil
+		result.TimestampValid = &ok
+		result.Valid = result.Valid && ok
+	}
+
+	for _, leaf := range anchor.Leaves {
+		evidence := bwc.evidenceDB.Get(leaf.EvidenceID)
+		switch {
+		case evidence == nil:
+			result.Missing = append(result.Missing, leaf.EvidenceID)
+		case evidence.FileHash != leaf.FileHash:
+			result.Changed = append(result.Changed, leaf.EvidenceID)
+		}
+	}
+	result.Valid = result.Valid && len(result.Changed) == 0 && len(result.Missing) == 0
+
+	outcome := "passed"
+	if !result.Valid {
+		outcome = "FAILED"
+	}
+	bwc.logAuditAs(auth, userID, "VERIFY_ANCHOR", "",
+		fmt.Sprintf("Anchor %s verification %s: %d changed, %d missing", anchor.ID, outcome, len(result.Changed), len(result.Missing)), "")
+	return result, nil
+}
+
+// anchorScheduler anchors the archive every integrity.anchor_interval_hours
+type anchorScheduler struct {
+	system   *BWCSystem
+	interval time.Duration
+}
+
+func newAnchorScheduler(system *BWCSystem) *anchorScheduler {
+	return &anchorScheduler{
+		system:   system,
+		interval: time.Duration(system.config.Integrity.AnchorIntervalHours) * time.Hour,
+	}
+}
+
+// Run anchors the evidence every interval until stop is closed
+func (s *anchorScheduler) Run(stop <-chan struct{}, logf func(format string, args ...interface{})) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := s.system.AnchorEvidence(systemUserID); err != nil {
+				logf("Evidence anchoring failed: %v\n", err)
+			}
+		}
+	}
+}
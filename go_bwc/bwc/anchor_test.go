@@ -0,0 +1,150 @@
+package bwc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestMerkleProofsForEveryTreeSize(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := make([]AnchorLeaf, size)
+		for i := range leaves {
+			leaves[i] = AnchorLeaf{EvidenceID: fmt.Sprintf("BWC-%02d", i), FileHash: fmt.Sprintf("%064x", i)}
+		}
+		root := merkleRoot(leaves)
+		for i, leaf := range leaves {
+			proof := &InclusionProof{Root: root, EvidenceID: leaf.EvidenceID, FileHash: leaf.FileHash, Path: merklePath(leaves, i)}
+			if err := proof.Verify(); err != nil {
+				t.Errorf("size %d, leaf %d: %v", size, i, err)
+			}
+			proof.FileHash = fmt.Sprintf("%064x", 99)
+			if err := proof.Verify(); err == nil {
+				t.Errorf("size %d, leaf %d: expected an altered hash to fail", size, i)
+			}
+		}
+	}
+}
+
+func TestAnchorEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	certFile, keyFile, _ := writeTestAgencyCertificate(t, t.TempDir(), key)
+	if system.reportSigner, err = loadReportSigner(certFile, keyFile); err != nil {
+		t.Fatalf("loadReportSigner failed: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-ANC-001", fmt.Sprintf("OFF-500%d", i), "", "", nil)
+		if err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+		ids = append(ids, evidence.ID)
+	}
+
+	anchor, err := system.AnchorEvidence("AUD-5001")
+	if err != nil {
+		t.Fatalf("AnchorEvidence failed: %v", err)
+	}
+	if anchor.LeafCount != 3 || anchor.Signature == nil {
+		t.Errorf("expected a signed anchor over 3 items, got %d leaves, signature %v", anchor.LeafCount, anchor.Signature != nil)
+	}
+
+	for _, id := range ids {
+		proof, err := system.InclusionProof(id, "")
+		if err != nil {
+			t.Fatalf("InclusionProof failed: %v", err)
+		}
+		if proof.AnchorID != anchor.ID || proof.Root != anchor.Root {
+			t.Errorf("expected a proof against %s, got %s", anchor.ID, proof.AnchorID)
+		}
+		if err := proof.Verify(); err != nil {
+			t.Errorf("expected the proof for %s to verify: %v", id, err)
+		}
+	}
+
+	result, err := system.VerifyAnchor(anchor.ID, "AUD-5001")
+	if err != nil {
+		t.Fatalf("VerifyAnchor failed: %v", err)
+	}
+	if !result.Valid || result.SignatureValid == nil || !*result.SignatureValid {
+		t.Errorf("expected the anchor to verify, got %+v", result)
+	}
+
+	// A record altered behind the system's back no longer matches the anchor
+	system.evidenceDB.Get(ids[1]).FileHash = fmt.Sprintf("%064x", 1)
+	result, err = system.VerifyAnchor(anchor.ID, "AUD-5001")
+	if err != nil {
+		t.Fatalf("VerifyAnchor failed: %v", err)
+	}
+	if result.Valid || len(result.Changed) != 1 || result.Changed[0] != ids[1] {
+		t.Errorf("expected the altered record to be reported, got %+v", result)
+	}
+
+	// Anchors survive a restart and numbering carries on
+	reopened, err := NewBWCSystem(system.storagePath)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	next, err := reopened.AnchorEvidence("AUD-5001")
+	if err != nil {
+		t.Fatalf("AnchorEvidence failed: %v", err)
+	}
+	if next.ID == anchor.ID {
+		t.Errorf("expected a new anchor ID, got %s again", next.ID)
+	}
+	anchors, err := reopened.Anchors()
+	if err != nil || len(anchors) != 2 || anchors[0].Leaves != nil {
+		t.Errorf("expected 2 anchors listed without leaves, got %d: %v", len(anchors), err)
+	}
+}
+
+func TestServerAnchors(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-ANC-002", "OFF-5004", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	resp := authPostJSON(t, server, "/api/anchors", "")
+	var anchor MerkleAnchor
+	json.NewDecoder(resp.Body).Decode(&anchor)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || anchor.LeafCount != 1 {
+		t.Fatalf("expected an anchor over 1 item, got %d %+v", resp.StatusCode, anchor)
+	}
+
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID+"/inclusion-proof?anchor="+anchor.ID)
+	var proof InclusionProof
+	json.NewDecoder(resp.Body).Decode(&proof)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || proof.Verify() != nil {
+		t.Errorf("expected a verifiable proof, got %d %+v", resp.StatusCode, proof)
+	}
+
+	resp = authGet(t, server, "/api/anchors/"+anchor.ID+"/verify")
+	var result AnchorVerification
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !result.Valid {
+		t.Errorf("expected the anchor to verify, got %d %+v", resp.StatusCode, result)
+	}
+
+	resp = authGet(t, server, "/api/anchors/ANC-999999/verify")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected an unknown anchor to be 404, got %d", resp.StatusCode)
+	}
+}
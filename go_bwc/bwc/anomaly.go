@@ -0,0 +1,327 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Anomaly rules
+const (
+	RuleOffHoursExport = "OFF_HOURS_EXPORT"
+	RuleCaseSpread     = "CASE_SPREAD"
+	RuleFailedAccess   = "FAILED_ACCESS"
+)
+
+// exportActions are the audit actions that copy evidence out of the system
+var exportActions = map[string]bool{
+	"EXPORT_EVIDENCE":          true,
+	"EXPORT_CASE_PACKAGE":      true,
+	"EXPORT_TESTIMONY_PACKAGE": true,
+}
+
+// failedAccessActions are the audit actions recording a refused access
+var failedAccessActions = map[string]bool{
+	"LOGIN_FAILED":             true,
+	"AUTH_FAILED":              true,
+	"GRANT_ACCESS_DENIED":      true,
+	"SEALED_ACCESS_DENIED":     true,
+	"RESTRICTED_ACCESS_DENIED": true,
+}
+
+// AccessAlert is raised when a user's activity matches an anomaly rule
+type AccessAlert struct {
+	ID       string    `json:"id"`
+	Rule     string    `json:"rule"`
+	UserID   string    `json:"user_id"`
+	RaisedAt time.Time `json:"raised_at"`
+	// Count is how many audited actions within the window matched the rule
+	Count   int    `json:"count"`
+	Details string `json:"details"`
+}
+
+// FlaggedAccount is an account an alert was raised for, awaiting review
+type FlaggedAccount struct {
+	UserID     string    `json:"user_id"`
+	FlaggedAt  time.Time `json:"flagged_at"`
+	AlertIDs   []string  `json:"alert_ids"`
+	Reviewed   bool      `json:"reviewed"`
+	ReviewedBy string    `json:"reviewed_by,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+}
+
+// anomalyDetector applies the anomaly rules to the audit stream. It keeps the
+// recent matching actions of each account within the configured window.
+type anomalyDetector struct {
+	system *BWCSystem
+	cfg    AnomalyDetectionConfig
+	mail   mailer
+
+	mu sync.Mutex
+	// recent holds matching action times per rule and account
+	recent map[string][]time.Time
+	// cases holds the cases each account touched within the window
+	cases map[string]map[string]time.Time
+	// alerted suppresses repeat alerts for a rule and account within the window
+	alerted map[string]time.Time
+}
+
+func newAnomalyDetector(system *BWCSystem) *anomalyDetector {
+	return &anomalyDetector{
+		system:  system,
+		cfg:     system.config.AnomalyDetection,
+		mail:    newSMTPMailer(system.config.Notifications),
+		recent:  make(map[string][]time.Time),
+		cases:   make(map[string]map[string]time.Time),
+		alerted: make(map[string]time.Time),
+	}
+}
+
+// Run applies the rules to audit entries as they are logged until stop is closed
+func (d *anomalyDetector) Run(stop <-chan struct{}, logf func(format string, args ...interface{})) {
+	sub := d.system.Subscribe(EventFilter{Types: []EventType{EventAudit}})
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			for _, alert := range d.observe(*event.Audit) {
+				if err := d.notify(alert); err != nil {
+					logf("Anomaly alert delivery failed: %v\n", err)
+				}
+			}
+		}
+	}
+}
+
+// observe applies the rules to one audit entry and returns the alerts raised
+func (d *anomalyDetector) observe(entry AuditLog) []*AccessAlert {
+	if entry.UserID == "SYSTEM" {
+		return nil
+	}
+	if entry.UserID == "UNKNOWN" {
+		// Unauthenticated failures are attributed to their source address
+		if entry.IPAddress == "" {
+			return nil
+		}
+		entry.UserID = "ip:" + entry.IPAddress
+	}
+	window := time.Duration(d.cfg.WindowMinutes) * time.Minute
+
+	var caseNumber string
+	if entry.EvidenceID != "" && d.cfg.CaseSpreadLimit > 0 {
+		d.system.mu.RLock()
+		if evidence := d.system.evidenceDB.Get(entry.EvidenceID); evidence != nil {
+			caseNumber = evidence.CaseNumber
+		}
+		d.system.mu.RUnlock()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var raised []struct {
+		rule    string
+		count   int
+		details string
+	}
+	raise := func(rule string, count int, details string) {
+		key := rule + "|" + entry.UserID
+		if last, ok := d.alerted[key]; ok && entry.Timestamp.Sub(last) < window {
+			return
+		}
+		d.alerted[key] = entry.Timestamp
+		raised = append(raised, struct {
+			rule    string
+			count   int
+			details string
+		}{rule, count, details})
+	}
+
+	if exportActions[entry.Action] && d.cfg.OffHoursExportLimit > 0 && !d.businessHours(entry.Timestamp) {
+		if n := d.record(RuleOffHoursExport, entry, window); n >= d.cfg.OffHoursExportLimit {
+			raise(RuleOffHoursExport, n, fmt.Sprintf("%d exports outside business hours within %d minutes", n, d.cfg.WindowMinutes))
+		}
+	}
+	if failedAccessActions[entry.Action] && d.cfg.FailedAccessLimit > 0 {
+		if n := d.record(RuleFailedAccess, entry, window); n >= d.cfg.FailedAccessLimit {
+			details := fmt.Sprintf("%d failed access attempts within %d minutes", n, d.cfg.WindowMinutes)
+			if entry.IPAddress != "" {
+				details += ", last from " + entry.IPAddress
+			}
+			raise(RuleFailedAccess, n, details)
+		}
+	}
+	if caseNumber != "" {
+		touched := d.cases[entry.UserID]
+		if touched == nil {
+			touched = make(map[string]time.Time)
+			d.cases[entry.UserID] = touched
+		}
+		touched[caseNumber] = entry.Timestamp
+		for c, at := range touched {
+			if entry.Timestamp.Sub(at) >= window {
+				delete(touched, c)
+			}
+		}
+		if len(touched) >= d.cfg.CaseSpreadLimit {
+			raise(RuleCaseSpread, len(touched), fmt.Sprintf("%d different cases accessed within %d minutes", len(touched), d.cfg.WindowMinutes))
+		}
+	}
+
+	alerts := make([]*AccessAlert, 0, len(raised))
+	for _, r := range raised {
+		alerts = append(alerts, d.system.raiseAccessAlert(r.rule, entry.UserID, r.count, r.details))
+	}
+	return alerts
+}
+
+// record adds a matching action and returns how many fall within the window
+func (d *anomalyDetector) record(rule string, entry AuditLog, window time.Duration) int {
+	key := rule + "|" + entry.UserID
+	times := append(d.recent[key], entry.Timestamp)
+	kept := times[:0]
+	for _, at := range times {
+		if entry.Timestamp.Sub(at) < window {
+			kept = append(kept, at)
+		}
+	}
+	d.recent[key] = kept
+	return len(kept)
+}
+
+// businessHours reports whether t falls on a weekday within the configured hours
+func (d *anomalyDetector) businessHours(t time.Time) bool {
+	t = t.Local()
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	return t.Hour() >= d.cfg.BusinessHoursStart && t.Hour() < d.cfg.BusinessHoursEnd
+}
+
+// notify emails an alert to notifications.alert_recipients when configured
+func (d *anomalyDetector) notify(alert *AccessAlert) error {
+	notifications := d.system.config.Notifications
+	if !notifications.Enabled || len(notifications.AlertRecipients) == 0 {
+		return nil
+	}
+	subject := fmt.Sprintf("[%s] Access anomaly: %s by %s", d.system.config.System.Name, alert.Rule, alert.UserID)
+	body := fmt.Sprintf("%s\n\nAlert %s raised %s. The account is flagged for review.\n",
+		alert.Details, alert.ID, alert.RaisedAt.Format(time.RFC3339))
+	return d.mail.SendMail(notifications.AlertRecipients, subject, body)
+}
+
+// raiseAccessAlert records an alert, flags the account for review, audits
+// the alert and publishes it to subscribers
+func (bwc *BWCSystem) raiseAccessAlert(rule, userID string, count int, details string) *AccessAlert {
+	bwc.mu.Lock()
+	bwc.accessAlertSeq++
+	alert := &AccessAlert{
+		ID:       fmt.Sprintf("ANM-%06d", bwc.accessAlertSeq),
+		Rule:     rule,
+		UserID:   userID,
+		RaisedAt: time.Now(),
+		Count:    count,
+		Details:  details,
+	}
+	bwc.accessAlerts = append(bwc.accessAlerts, alert)
+
+	flag, exists := bwc.flaggedAccounts[userID]
+	if !exists || flag.Reviewed {
+		flag = &FlaggedAccount{UserID: userID, FlaggedAt: alert.RaisedAt}
+		bwc.flaggedAccounts[userID] = flag
+	}
+	flag.AlertIDs = append(flag.AlertIDs, alert.ID)
+
+	bwc.logAudit("SYSTEM", "ACCESS_ANOMALY", "", fmt.Sprintf("%s %s for %s: %s", alert.ID, rule, userID, details), "")
+	bwc.mu.Unlock()
+
+	a := *alert
+	bwc.events.publish(Event{
+		Type:      EventAccessAnomaly,
+		Timestamp: alert.RaisedAt,
+		UserID:    userID,
+		Anomaly:   &a,
+	})
+	return &a
+}
+
+// AccessAlerts returns the anomaly alerts raised since since, oldest first
+func (bwc *BWCSystem) AccessAlerts(since time.Time) []AccessAlert {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	alerts := make([]AccessAlert, 0)
+	for _, alert := range bwc.accessAlerts {
+		if !alert.RaisedAt.Before(since) {
+			alerts = append(alerts, *alert)
+		}
+	}
+	return alerts
+}
+
+// FlaggedAccounts returns the accounts awaiting review, by user ID
+func (bwc *BWCSystem) FlaggedAccounts() []FlaggedAccount {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	accounts := make([]FlaggedAccount, 0)
+	for _, flag := range bwc.flaggedAccounts {
+		if !flag.Reviewed {
+			f := *flag
+			f.AlertIDs = append([]string(nil), flag.AlertIDs...)
+			accounts = append(accounts, f)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].UserID < accounts[j].UserID
+	})
+	return accounts
+}
+
+// ReviewFlaggedAccount clears the flag on an account once its activity has
+// been reviewed. A later alert flags it again.
+func (bwc *BWCSystem) ReviewFlaggedAccount(userID, reviewerID, notes string) error {
+	return bwc.ReviewFlaggedAccountContext(context.Background(), userID, reviewerID, notes)
+}
+
+// ReviewFlaggedAccountContext records a review like ReviewFlaggedAccount, by
+// the principal ctx carries when it has one
+func (bwc *BWCSystem) ReviewFlaggedAccountContext(ctx context.Context, userID, reviewerID, notes string) error {
+	reviewerID, auth := actingUser(ctx, reviewerID)
+	if err := bwc.authorize(reviewerID, PermReviewAudit, "Flagged account review", ""); err != nil {
+		return err
+	}
+	if strings.TrimSpace(notes) == "" {
+		return errors.New("review notes are required")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	flag, exists := bwc.flaggedAccounts[userID]
+	if !exists || flag.Reviewed {
+		return fmt.Errorf("account %s is not flagged for review", userID)
+	}
+	if reviewerID == userID {
+		return errors.New("an account cannot review its own flag")
+	}
+	flag.Reviewed = true
+	flag.ReviewedBy = reviewerID
+	flag.ReviewedAt = time.Now()
+	flag.Notes = notes
+
+	bwc.logAuditAs(auth, reviewerID, "REVIEW_FLAGGED_ACCOUNT", "",
+		fmt.Sprintf("Reviewed %s (%s): %s", userID, strings.Join(flag.AlertIDs, ", "), notes), "")
+	return nil
+}
@@ -0,0 +1,303 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnomalyOffHoursExports(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AnomalyDetection.OffHoursExportLimit = 3
+	detector := newAnomalyDetector(system)
+
+	// Wednesday mid-morning is within business hours
+	day := time.Date(2026, 10, 14, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 5; i++ {
+		entry := AuditLog{Timestamp: day.Add(time.Duration(i) * time.Minute), UserID: "OFF-1040", Action: "EXPORT_EVIDENCE"}
+		if alerts := detector.observe(entry); len(alerts) != 0 {
+			t.Fatalf("Expected no alert during business hours, got %+v", alerts)
+		}
+	}
+
+	night := time.Date(2026, 10, 14, 23, 30, 0, 0, time.Local)
+	var raised []*AccessAlert
+	for i := 0; i < 5; i++ {
+		entry := AuditLog{Timestamp: night.Add(time.Duration(i) * time.Minute), UserID: "OFF-1040", Action: "EXPORT_CASE_PACKAGE"}
+		raised = append(raised, detector.observe(entry)...)
+	}
+	if len(raised) != 1 {
+		t.Fatalf("Expected one alert with repeats suppressed, got %d", len(raised))
+	}
+	if raised[0].Rule != RuleOffHoursExport || raised[0].UserID != "OFF-1040" || raised[0].Count != 3 {
+		t.Errorf("Unexpected alert: %+v", raised[0])
+	}
+
+	// Saturday counts as off hours at any time of day
+	saturday := time.Date(2026, 10, 17, 11, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		raised = append(raised, detector.observe(AuditLog{Timestamp: saturday, UserID: "OFF-1041", Action: "EXPORT_EVIDENCE"})...)
+	}
+	if len(raised) != 2 || raised[1].UserID != "OFF-1041" {
+		t.Errorf("Expected weekend exports to raise an alert, got %+v", raised)
+	}
+}
+
+func TestAnomalyWindowExpires(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AnomalyDetection.FailedAccessLimit = 3
+	detector := newAnomalyDetector(system)
+
+	start := time.Date(2026, 10, 14, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 4; i++ {
+		// Attempts 40 minutes apart never fall three to a 60 minute window
+		entry := AuditLog{Timestamp: start.Add(time.Duration(i) * 40 * time.Minute), UserID: "OFF-1042", Action: "GRANT_ACCESS_DENIED"}
+		if alerts := detector.observe(entry); len(alerts) != 0 {
+			t.Fatalf("Expected old attempts to age out, got %+v", alerts)
+		}
+	}
+}
+
+func TestAnomalyFailedAccessBySource(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AnomalyDetection.FailedAccessLimit = 3
+	detector := newAnomalyDetector(system)
+
+	now := time.Now()
+	var raised []*AccessAlert
+	for i := 0; i < 3; i++ {
+		entry := AuditLog{Timestamp: now, UserID: "UNKNOWN", Action: "LOGIN_FAILED", IPAddress: "203.0.113.9"}
+		raised = append(raised, detector.observe(entry)...)
+	}
+	// Other sources are counted separately
+	raised = append(raised, detector.observe(AuditLog{Timestamp: now, UserID: "UNKNOWN", Action: "AUTH_FAILED", IPAddress: "198.51.100.4"})...)
+
+	if len(raised) != 1 {
+		t.Fatalf("Expected one alert, got %+v", raised)
+	}
+	if raised[0].Rule != RuleFailedAccess || raised[0].UserID != "ip:203.0.113.9" {
+		t.Errorf("Unexpected alert: %+v", raised[0])
+	}
+	if !strings.Contains(raised[0].Details, "203.0.113.9") {
+		t.Errorf("Expected the source address in the details: %s", raised[0].Details)
+	}
+}
+
+func TestAnomalyCaseSpread(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AnomalyDetection.CaseSpreadLimit = 3
+	detector := newAnomalyDetector(system)
+
+	testFile := createTestFile(t, tmpDir)
+	var ids []string
+	for i := 0; i < 4; i++ {
+		ev, err := system.IngestEvidence(testFile, fmt.Sprintf("CASE-ANM-%03d", i), "OFF-1043", "Officer Test", "Test Location", nil)
+		if err != nil {
+			t.Fatalf("Ingest failed: %v", err)
+		}
+		ids = append(ids, ev.ID)
+	}
+	same, _ := system.IngestEvidence(testFile, "CASE-ANM-000", "OFF-1043", "Officer Test", "Test Location", nil)
+
+	now := time.Now()
+	view := func(id string) []*AccessAlert {
+		return detector.observe(AuditLog{Timestamp: now, UserID: "OFF-1044", Action: "VIEW_EVIDENCE", EvidenceID: id})
+	}
+	for _, id := range []string{ids[0], same.ID, ids[1]} {
+		if alerts := view(id); len(alerts) != 0 {
+			t.Fatalf("Expected evidence from the same case to count once, got %+v", alerts)
+		}
+	}
+	alerts := view(ids[2])
+	if len(alerts) != 1 || alerts[0].Rule != RuleCaseSpread || alerts[0].Count != 3 {
+		t.Fatalf("Expected a case spread alert, got %+v", alerts)
+	}
+}
+
+func TestFlaggedAccountReview(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	sub := system.Subscribe(EventFilter{Types: []EventType{EventAccessAnomaly}})
+	defer sub.Close()
+
+	alert := system.raiseAccessAlert(RuleFailedAccess, "OFF-1045", 5, "5 failed access attempts within 60 minutes")
+	if alert.ID != "ANM-000001" {
+		t.Errorf("Expected ANM-000001, got %s", alert.ID)
+	}
+	select {
+	case event := <-sub.C:
+		if event.Anomaly == nil || event.Anomaly.ID != alert.ID || event.UserID != "OFF-1045" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected an access anomaly event")
+	}
+	if logs := system.GetAuditLogs("", "SYSTEM"); len(logs) == 0 || logs[len(logs)-1].Action != "ACCESS_ANOMALY" {
+		t.Errorf("Expected ACCESS_ANOMALY audit entry, got %+v", logs)
+	}
+
+	system.raiseAccessAlert(RuleCaseSpread, "OFF-1045", 10, "10 different cases accessed within 60 minutes")
+	flagged := system.FlaggedAccounts()
+	if len(flagged) != 1 || len(flagged[0].AlertIDs) != 2 {
+		t.Fatalf("Expected one account flagged by two alerts, got %+v", flagged)
+	}
+
+	if err := system.ReviewFlaggedAccount("OFF-1045", "SUP-001", ""); err == nil {
+		t.Error("Expected review without notes to fail")
+	}
+	if err := system.ReviewFlaggedAccount("OFF-1045", "OFF-1045", "Looks fine"); err == nil {
+		t.Error("Expected self-review to fail")
+	}
+	if err := system.ReviewFlaggedAccount("OFF-1045", "SUP-001", "Bulk export approved by the DA"); err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if flagged := system.FlaggedAccounts(); len(flagged) != 0 {
+		t.Errorf("Expected no flagged accounts after review, got %+v", flagged)
+	}
+	if err := system.ReviewFlaggedAccount("OFF-1045", "SUP-001", "Again"); err == nil {
+		t.Error("Expected reviewing an unflagged account to fail")
+	}
+	if logs := system.GetAuditLogs("", "SUP-001"); len(logs) != 1 || logs[0].Action != "REVIEW_FLAGGED_ACCOUNT" {
+		t.Errorf("Expected REVIEW_FLAGGED_ACCOUNT audit entry, got %+v", logs)
+	}
+
+	// A later alert flags the account again
+	system.raiseAccessAlert(RuleFailedAccess, "OFF-1045", 5, "5 failed access attempts within 60 minutes")
+	if flagged := system.FlaggedAccounts(); len(flagged) != 1 || len(flagged[0].AlertIDs) != 1 {
+		t.Errorf("Expected the account to be flagged again, got %+v", flagged)
+	}
+	if alerts := system.AccessAlerts(time.Time{}); len(alerts) != 3 {
+		t.Errorf("Expected 3 alerts, got %d", len(alerts))
+	}
+}
+
+func TestAnomalyDetectorRun(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AnomalyDetection.FailedAccessLimit = 2
+	system.config.Notifications.Enabled = true
+	system.config.Notifications.AlertRecipients = []string{"security@example.org"}
+
+	detector := newAnomalyDetector(system)
+	mail := &fakeMailer{}
+	detector.mail = mail
+
+	sub := system.Subscribe(EventFilter{Types: []EventType{EventAccessAnomaly}})
+	defer sub.Close()
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		detector.Run(stop, t.Logf)
+		close(done)
+	}()
+	// Wait for the detector to subscribe before logging
+	deadline := time.Now().Add(time.Second)
+	for {
+		system.events.mu.Lock()
+		n := len(system.events.subscribers)
+		system.events.mu.Unlock()
+		if n == 2 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	system.logAudit("OFF-1046", "SEALED_ACCESS_DENIED", "", "Sealed evidence", "")
+	system.logAudit("OFF-1046", "SEALED_ACCESS_DENIED", "", "Sealed evidence", "")
+
+	select {
+	case event := <-sub.C:
+		if event.Anomaly.UserID != "OFF-1046" {
+			t.Errorf("Unexpected alert: %+v", event.Anomaly)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the detector to raise an alert")
+	}
+	close(stop)
+	<-done
+
+	if len(mail.sent) != 1 || mail.sent[0].to[0] != "security@example.org" || !strings.Contains(mail.sent[0].subject, "OFF-1046") {
+		t.Errorf("Unexpected mail: %+v", mail.sent)
+	}
+}
+
+func TestAnomaliesEndpoint(t *testing.T) {
+	system, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/anomalies", nil)
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", resp.StatusCode)
+	}
+	if logs := system.GetAuditLogs("", "UNKNOWN"); len(logs) != 1 || logs[0].Action != "AUTH_FAILED" || logs[0].IPAddress == "" {
+		t.Errorf("Expected AUTH_FAILED audit entry with the client address, got %+v", logs)
+	}
+
+	system.raiseAccessAlert(RuleOffHoursExport, "OFF-1047", 5, "5 exports outside business hours within 60 minutes")
+
+	resp = authGet(t, server, "/api/anomalies")
+	var listing struct {
+		Alerts  []AccessAlert    `json:"alerts"`
+		Flagged []FlaggedAccount `json:"flagged_accounts"`
+	}
+	json.NewDecoder(resp.Body).Decode(&listing)
+	resp.Body.Close()
+	if len(listing.Alerts) != 1 || len(listing.Flagged) != 1 || listing.Flagged[0].UserID != "OFF-1047" {
+		t.Fatalf("Unexpected listing: %+v", listing)
+	}
+
+	resp = authGet(t, server, "/api/anomalies?since="+time.Now().Add(time.Hour).Format(time.RFC3339))
+	listing.Alerts = nil
+	json.NewDecoder(resp.Body).Decode(&listing)
+	resp.Body.Close()
+	if len(listing.Alerts) != 0 {
+		t.Errorf("Expected no alerts after since, got %+v", listing.Alerts)
+	}
+
+	body, _ := json.Marshal(anomalyReview{UserID: "OFF-1047", Notes: "Scheduled discovery export"})
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/api/anomalies", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Review request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+	if flagged := system.FlaggedAccounts(); len(flagged) != 0 {
+		t.Errorf("Expected the flag to be cleared, got %+v", flagged)
+	}
+}
+
+func TestAnomalyDetectionConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.AnomalyDetection.Enabled = true
+	cfg.AnomalyDetection.WindowMinutes = 0
+	cfg.AnomalyDetection.BusinessHoursStart = 19
+	cfg.AnomalyDetection.BusinessHoursEnd = 7
+	cfg.AnomalyDetection.CaseSpreadLimit = -1
+
+	err := cfg.Validate()
+	cfgErr, ok := err.(*ConfigError)
+	if !ok || len(cfgErr.Problems) != 3 {
+		t.Fatalf("Expected 3 problems, got %v", err)
+	}
+}
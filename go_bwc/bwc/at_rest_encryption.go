@@ -0,0 +1,436 @@
+package bwc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Evidence files can be encrypted at rest with AES-256-GCM. Each file has its
+// own random data key, kept in the record wrapped by the system's
+// KeyProvider. The file is sealed in fixed-size segments, each with its
+// own tag, so playback can read from any offset and a damaged segment is
+// located rather than failing the whole file.
+
+const (
+	atRestAlgorithm = "AES-256-GCM"
+	// atRestSegmentSize divides stagingCheckpointSize, so a checkpoint always
+	// falls on a segment boundary
+	atRestSegmentSize = 64 << 10
+	atRestKeySize     = 32
+	atRestTagSize     = 16
+)
+
+// AtRestEncryption describes how an evidence file is encrypted in storage
+type AtRestEncryption struct {
+	Algorithm string `json:"algorithm"`
+	// KeyID names the key the data key is wrapped with
+	KeyID string `json:"key_id"`
+	// WrappedKey is the file's data key, encrypted with KeyID
+	WrappedKey []byte `json:"wrapped_key"`
+	// SegmentSize is how many bytes of the recording each sealed segment holds
+	SegmentSize int `json:"segment_size"`
+	// StoredSHA256 is the SHA-256 of the file as stored. Parity, replicas
+	// and backups hold the encrypted file and are checked against it.
+	StoredSHA256 string `json:"stored_sha256,omitempty"`
+}
+
+// errNoAtRestKey is returned for evidence encrypted at rest on a system
+// with no key to decrypt it
+var errNoAtRestKey = errors.New("evidence is encrypted at rest but no encryption key is configured")
+
+// errEncryptedAtRest is returned by features that hand the stored file to
+// other programs, which cannot read it encrypted
+var errEncryptedAtRest = errors.New("evidence is encrypted at rest and this feature reads the file directly")
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newAtRestEncryption makes a data key for evidenceID and returns it with
+// its description, ready to be kept in the record
+func (bwc *BWCSystem) newAtRestEncryption(evidenceID string) (*AtRestEncryption, error) {
+	key := make([]byte, atRestKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := bwc.atRest.WrapKey(key, evidenceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return &AtRestEncryption{
+		Algorithm:   atRestAlgorithm,
+		KeyID:       keyID,
+		WrappedKey:  wrapped,
+		SegmentSize: atRestSegmentSize,
+	}, nil
+}
+
+// atRestCipher returns the cipher of the file of evidenceID, which holds
+// plainSize bytes of recording
+func (bwc *BWCSystem) atRestCipher(enc *AtRestEncryption, evidenceID string, plainSize int64) (*segmentCipher, error) {
+	if enc.Algorithm != atRestAlgorithm {
+		return nil, fmt.Errorf("unsupported at-rest encryption %q", enc.Algorithm)
+	}
+	if bwc.atRest == nil {
+		return nil, errNoAtRestKey
+	}
+	key, err := bwc.atRest.UnwrapKey(enc.KeyID, enc.WrappedKey, evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	return newSegmentCipher(key, enc.SegmentSize, plainSize)
+}
+
+// storedHash returns the SHA-256 of evidence's file as it is kept in storage
+func storedHash(evidence *Evidence) string {
+	if evidence.Encryption != nil {
+		return evidence.Encryption.StoredSHA256
+	}
+	return evidence.FileHash
+}
+
+// storedSize returns the size of evidence's file as it is kept in storage
+func storedSize(evidence *Evidence) int64 {
+	if evidence.Encryption != nil {
+		return sealedSize(evidence.FileSize, int64(evidence.Encryption.SegmentSize))
+	}
+	return evidence.FileSize
+}
+
+// sealedSize is the size of plainSize bytes sealed in segments of segSize
+func sealedSize(plainSize, segSize int64) int64 {
+	full, rest := plainSize/segSize, plainSize%segSize
+	size := full * (segSize + atRestTagSize)
+	if rest > 0 || plainSize == 0 {
+		size += rest + atRestTagSize
+	}
+	return size
+}
+
+// segmentCipher seals and opens the segments of one file. Each segment's
+// nonce is its index, with the last segment flagged, so segments cannot be
+// reordered, dropped from the end or appended without detection.
+type segmentCipher struct {
+	aead      cipher.AEAD
+	segSize   int64
+	plainSize int64
+}
+
+func newSegmentCipher(key []byte, segSize int, plainSize int64) (*segmentCipher, error) {
+	if segSize <= 0 {
+		return nil, fmt.Errorf("invalid segment size %d", segSize)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentCipher{aead: aead, segSize: int64(segSize), plainSize: plainSize}, nil
+}
+
+// segments counts the file's segments; an empty file has one, empty
+func (c *segmentCipher) segments() int64 {
+	if c.plainSize == 0 {
+		return 1
+	}
+	return (c.plainSize + c.segSize - 1) / c.segSize
+}
+
+// plainLen is how many bytes of recording segment i holds
+func (c *segmentCipher) plainLen(i int64) int64 {
+	n := c.plainSize - i*c.segSize
+	if n > c.segSize {
+		n = c.segSize
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+func (c *segmentCipher) nonce(i int64) []byte {
+	nonce := make([]byte, c.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[3:11], uint64(i))
+	if i == c.segments()-1 {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+func (c *segmentCipher) seal(dst, plaintext []byte, i int64) []byte {
+	return c.aead.Seal(dst, c.nonce(i), plaintext, nil)
+}
+
+func (c *segmentCipher) open(dst, sealed []byte, i int64) ([]byte, error) {
+	plain, err := c.aead.Open(dst, c.nonce(i), sealed, nil)
+	if err != nil {
+		start := i * c.segSize
+		return nil, &BWCError{Code: CodeIntegrityFailure,
+			Message: fmt.Sprintf("encrypted segment %d (bytes %d-%d) failed authentication", i, start, start+c.segSize-1)}
+	}
+	return plain, nil
+}
+
+// damagedRange is the range of the recording segment i holds
+func (c *segmentCipher) damagedRange(i int64) ByteRange {
+	start := i * c.segSize
+	end := start + c.segSize - 1
+	if end > c.plainSize-1 {
+		end = c.plainSize - 1
+	}
+	return ByteRange{Start: start, End: end}
+}
+
+// segmentWriter seals what is written to it into dst, a segment at a time
+type segmentWriter struct {
+	c    *segmentCipher
+	dst  io.Writer
+	buf  []byte
+	next int64
+}
+
+// newSegmentWriter continues a file whose first offset bytes are already
+// sealed; offset is a segment boundary or the end of the file
+func newSegmentWriter(c *segmentCipher, dst io.Writer, offset int64) *segmentWriter {
+	return &segmentWriter{c: c, dst: dst, buf: make([]byte, 0, c.segSize), next: (offset + c.segSize - 1) / c.segSize}
+}
+
+func (w *segmentWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		if int64(len(w.buf)) == w.c.segSize {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *segmentWriter) flush() error {
+	if _, err := w.dst.Write(w.c.seal(nil, w.buf, w.next)); err != nil {
+		return err
+	}
+	w.buf = w.buf[:0]
+	w.next++
+	return nil
+}
+
+// Close seals what is left as the last segment; it does not close dst
+func (w *segmentWriter) Close() error {
+	if len(w.buf) > 0 || w.next == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// segmentReader opens a sealed file read as a stream. A lenient reader, used
+// to hash a file that may be damaged, reads each segment that fails
+// authentication as zeros and notes its range, so the hash still comes out,
+// and different, and later segments are still checked.
+type segmentReader struct {
+	c       *segmentCipher
+	src     io.Reader
+	lenient bool
+	damaged []ByteRange
+	next    int64
+	sealed  []byte
+	plain   []byte
+	done    bool
+}
+
+func newSegmentReader(c *segmentCipher, src io.Reader, lenient bool) *segmentReader {
+	return &segmentReader{c: c, src: src, lenient: lenient, sealed: make([]byte, c.segSize+atRestTagSize)}
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+// fill opens the next segment into r.plain
+func (r *segmentReader) fill() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	i := r.next
+	if i >= r.c.segments() {
+		// Anything past the last segment was appended
+		r.done = true
+		if n == 0 {
+			return nil
+		}
+		if !r.lenient {
+			return &BWCError{Code: CodeIntegrityFailure, Message: "data appended after the last encrypted segment"}
+		}
+		r.damaged = append(r.damaged, ByteRange{Start: r.c.plainSize, End: r.c.plainSize + int64(n) - 1})
+		r.plain = append([]byte(nil), r.sealed[:n]...)
+		return nil
+	}
+	if n == 0 {
+		// The file ends early
+		r.done = true
+		if !r.lenient {
+			return &BWCError{Code: CodeIntegrityFailure, Message: fmt.Sprintf("encrypted file ends before segment %d", i)}
+		}
+		r.damaged = append(r.damaged, ByteRange{Start: i * r.c.segSize, End: r.c.plainSize - 1})
+		return nil
+	}
+	r.next++
+	plain, openErr := r.c.open(r.sealed[:0:0], r.sealed[:n], i)
+	if openErr != nil {
+		if !r.lenient {
+			return openErr
+		}
+		r.damaged = append(r.damaged, r.c.damagedRange(i))
+		plain = make([]byte, r.c.plainLen(i))
+	}
+	r.plain = plain
+	return nil
+}
+
+// segmentReaderAt opens a sealed file at any offset, for playback
+type segmentReaderAt struct {
+	c      *segmentCipher
+	src    io.ReaderAt
+	cached int64
+	plain  []byte
+}
+
+func (r *segmentReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	read := 0
+	for len(p) > 0 {
+		if off >= r.c.plainSize {
+			return read, io.EOF
+		}
+		i := off / r.c.segSize
+		if r.plain == nil || r.cached != i {
+			sealed := make([]byte, r.c.plainLen(i)+atRestTagSize)
+			if _, err := r.src.ReadAt(sealed, i*(r.c.segSize+atRestTagSize)); err != nil && !errors.Is(err, io.EOF) {
+				return read, err
+			}
+			plain, err := r.c.open(nil, sealed, i)
+			if err != nil {
+				return read, err
+			}
+			r.cached, r.plain = i, plain
+		}
+		n := copy(p, r.plain[off-i*r.c.segSize:])
+		p = p[n:]
+		off += int64(n)
+		read += n
+	}
+	return read, nil
+}
+
+// playbackFile is the local file a recording is played back from, which may
+// be a temporary copy of a blob
+type playbackFile struct {
+	*os.File
+	done func()
+}
+
+func (f *playbackFile) Close() error {
+	err := f.File.Close()
+	f.done()
+	return err
+}
+
+// decryptedFile is an encrypted evidence file opened for reading as the
+// recording it holds
+type decryptedFile struct {
+	*io.SectionReader
+	file *playbackFile
+}
+
+func (f *decryptedFile) Close() error {
+	return f.file.Close()
+}
+
+// openStoredFile opens the file of evidence as it is kept in storage, from
+// the blob store or, for records from before blob stores, from its file path
+func (bwc *BWCSystem) openStoredFile(evidence *Evidence) (io.ReadCloser, error) {
+	if evidence.BlobKey == "" {
+		return os.Open(evidence.FilePath)
+	}
+	return bwc.blobs.Get(evidence.BlobKey)
+}
+
+// openPlayback opens the recording of evidence for reading at any offset. A
+// blob kept off local disk is read from a temporary copy.
+func (bwc *BWCSystem) openPlayback(evidence *Evidence) (io.ReadSeekCloser, error) {
+	path, done, err := bwc.localStoredFile(evidence)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	file := &playbackFile{File: f, done: done}
+	if evidence.Encryption == nil {
+		return file, nil
+	}
+	c, err := bwc.atRestCipher(evidence.Encryption, evidence.ID, evidence.FileSize)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &decryptedFile{SectionReader: io.NewSectionReader(&segmentReaderAt{c: c, src: file}, 0, evidence.FileSize), file: file}, nil
+}
+
+// hashEncrypted hashes the recording in the encrypted file of evidence,
+// reading through wrap when it is set. Segments that fail authentication
+// hash as zeros. The damaged ranges come from manifest when there is one and
+// otherwise from the segments.
+func (bwc *BWCSystem) hashEncrypted(evidence *Evidence, manifest *ChunkManifest, wrap func(io.Reader) io.Reader) (string, []ByteRange, error) {
+	c, err := bwc.atRestCipher(evidence.Encryption, evidence.ID, evidence.FileSize)
+	if err != nil {
+		return "", nil, err
+	}
+	file, err := bwc.openStoredFile(evidence)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	sr := newSegmentReader(c, file, true)
+	var r io.Reader = sr
+	if wrap != nil {
+		r = wrap(r)
+	}
+	if manifest != nil {
+		return manifest.corruptRangesIn(r, evidence.FileSize)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", nil, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), sr.damaged, nil
+}
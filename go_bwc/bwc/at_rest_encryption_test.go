@@ -0,0 +1,217 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// enableAtRestEncryption gives system a fresh key encryption key
+func enableAtRestEncryption(t *testing.T, system *BWCSystem) {
+	t.Helper()
+	provider, err := NewLocalKeyProvider(bytes.Repeat([]byte{0x42}, atRestKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	system.atRest = provider
+}
+
+// writePlainRecording writes size bytes of patterned content spanning several segments
+func writePlainRecording(t *testing.T, dir string, size int) (string, []byte) {
+	t.Helper()
+	data := bytes.Repeat([]byte("plaintext bodycam frame "), size/24+1)[:size]
+	path := filepath.Join(dir, "encrypted.mp4")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, data
+}
+
+func TestAtRestEncryptionIngestAndRead(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAtRestEncryption(t, system)
+
+	source, data := writePlainRecording(t, tmpDir, 3*atRestSegmentSize+1000)
+	evidence, err := system.IngestEvidence(source, "CASE-ENC-001", "OFF-1300", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if evidence.Encryption == nil || evidence.Encryption.Algorithm != atRestAlgorithm || evidence.Encryption.KeyID != system.atRest.KeyID() {
+		t.Fatalf("expected the record to describe its encryption, got %+v", evidence.Encryption)
+	}
+
+	stored, err := os.ReadFile(evidence.FilePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(stored, []byte("plaintext bodycam")) {
+		t.Error("expected no plaintext in storage")
+	}
+	if int64(len(stored)) != storedSize(evidence) {
+		t.Errorf("stored file is %d bytes, expected %d", len(stored), storedSize(evidence))
+	}
+	if hash, _ := calculateFileHash(evidence.FilePath); hash != evidence.Encryption.StoredSHA256 || hash == evidence.FileHash {
+		t.Errorf("expected the stored hash to be of the encrypted file, got %s", hash)
+	}
+
+	if ok, err := system.VerifyIntegrity(evidence.ID, "AUDITOR"); err != nil || !ok {
+		t.Fatalf("expected an encrypted file to verify, got %v %v", ok, err)
+	}
+
+	file, err := system.openEvidenceFile(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("expected the recording back decrypted, got %d bytes, %v", len(got), err)
+	}
+
+	playback, err := system.openPlayback(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer playback.Close()
+	offset := int64(2*atRestSegmentSize - 10)
+	if _, err := playback.Seek(offset, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	part := make([]byte, 20)
+	if _, err := io.ReadFull(playback, part); err != nil || !bytes.Equal(part, data[offset:offset+20]) {
+		t.Errorf("expected a read across segments at %d to decrypt, got %q %v", offset, part, err)
+	}
+}
+
+func TestAtRestEncryptionLocatesTampering(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAtRestEncryption(t, system)
+
+	source, _ := writePlainRecording(t, tmpDir, 3*atRestSegmentSize+1000)
+	evidence, err := system.IngestEvidence(source, "CASE-ENC-002", "OFF-1301", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	f, err := os.OpenFile(evidence.FilePath, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte{0xff}, 2*(atRestSegmentSize+atRestTagSize)+5)
+	f.Close()
+
+	ok, err := system.VerifyIntegrity(evidence.ID, "AUDITOR")
+	if err != nil || ok {
+		t.Fatalf("expected tampering to fail verification, got %v %v", ok, err)
+	}
+	stored, _ := system.GetEvidence(evidence.ID)
+	check := stored.IntegrityChecks[len(stored.IntegrityChecks)-1]
+	want := ByteRange{Start: 2 * atRestSegmentSize, End: 3*atRestSegmentSize - 1}
+	if len(check.CorruptRanges) != 1 || check.CorruptRanges[0] != want {
+		t.Errorf("expected the damaged segment %+v, got %+v", want, check.CorruptRanges)
+	}
+
+	if err := system.TransferCustody(evidence.ID, "OFF-1301", "DET-1", "Analysis"); !errors.Is(err, ErrIntegrityFailure) {
+		t.Errorf("expected a transfer of tampered evidence to fail integrity, got %v", err)
+	}
+	file, _ := system.openEvidenceFile(evidence)
+	_, err = io.ReadAll(file)
+	file.Close()
+	if !errors.Is(err, ErrIntegrityFailure) {
+		t.Errorf("expected reading a tampered segment to fail, got %v", err)
+	}
+}
+
+func TestResumeEncryptedIngest(t *testing.T) {
+	storage := t.TempDir()
+	source := writeRecording(t, t.TempDir())
+	id := "BWC-CASE-RSM-1-OFF-1120-1700000002"
+	stage := stageInterruptedIngest(t, storage, source, id, 1<<20, false)
+
+	// Rewrite the staged copy as an encrypted ingest would have left it
+	provider, _ := NewLocalKeyProvider(bytes.Repeat([]byte{0x42}, atRestKeySize))
+	key := bytes.Repeat([]byte{5}, atRestKeySize)
+	keyID, wrapped, _ := provider.WrapKey(key, id)
+	stage.Encryption = &AtRestEncryption{Algorithm: atRestAlgorithm, KeyID: keyID, WrappedKey: wrapped, SegmentSize: atRestSegmentSize}
+	journal, _ := json.Marshal(stage)
+	os.WriteFile(filepath.Join(storage, "staging", id+".json"), journal, 0600)
+	data, _ := os.ReadFile(source)
+	c, _ := newSegmentCipher(key, atRestSegmentSize, int64(len(data)))
+	var partial bytes.Buffer
+	newSegmentWriter(c, &partial, 0).Write(data[:1<<20])
+	partial.Write(bytes.Repeat([]byte{0xee}, 1000))
+	os.WriteFile(filepath.Join(storage, "staging", id+".mp4.partial"), partial.Bytes(), 0600)
+
+	system, err := NewBWCSystem(storage)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	system.atRest = provider
+	ev, err := system.ResumeIngest(id, "CUS-001")
+	if err != nil {
+		t.Fatalf("ResumeIngest failed: %v", err)
+	}
+	file, _ := system.openEvidenceFile(ev)
+	got, err := io.ReadAll(file)
+	file.Close()
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("expected the resumed copy to decrypt to the source, got %d bytes, %v", len(got), err)
+	}
+	if logs := system.GetAuditLogs(id, "CUS-001"); len(logs) == 0 || !strings.Contains(logs[0].Details, "from byte 1048576") {
+		t.Errorf("expected the copy to resume from its checkpoint, got %+v", logs)
+	}
+	if valid, err := system.VerifyIntegrity(id, "CUS-001"); err != nil || !valid {
+		t.Errorf("expected the resumed evidence to verify, got %v, %v", valid, err)
+	}
+}
+
+func TestSegmentWriterResumesAtBoundary(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, atRestKeySize)
+	data := bytes.Repeat([]byte("0123456789"), 50)
+	c, _ := newSegmentCipher(key, 64, int64(len(data)))
+
+	var whole bytes.Buffer
+	w := newSegmentWriter(c, &whole, 0)
+	w.Write(data)
+	w.Close()
+
+	var resumed bytes.Buffer
+	w = newSegmentWriter(c, &resumed, 0)
+	w.Write(data[:256])
+	w = newSegmentWriter(c, &resumed, 256)
+	w.Write(data[256:])
+	w.Close()
+	if !bytes.Equal(whole.Bytes(), resumed.Bytes()) || int64(whole.Len()) != sealedSize(int64(len(data)), 64) {
+		t.Fatal("expected a copy resumed at a segment boundary to seal the same")
+	}
+
+	// Dropping the last segment is detected, even on a segment boundary
+	r := newSegmentReader(c, bytes.NewReader(whole.Bytes()[:7*(64+atRestTagSize)]), false)
+	if _, err := io.ReadAll(r); !errors.Is(err, ErrIntegrityFailure) {
+		t.Errorf("expected a truncated file to fail, got %v", err)
+	}
+
+	empty, _ := newSegmentCipher(key, 64, 0)
+	var sealed bytes.Buffer
+	w = newSegmentWriter(empty, &sealed, 0)
+	w.Close()
+	if got, err := io.ReadAll(newSegmentReader(empty, &sealed, false)); err != nil || len(got) != 0 {
+		t.Errorf("expected an empty file to round-trip, got %q %v", got, err)
+	}
+}
+
+func TestEncryptionRequiresKeyFile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Security.EnableEncryption = true
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "security.encryption_key_file") {
+		t.Errorf("expected encryption without a key file to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// wavBytes builds a PCM WAV file; each frame holds one sample per channel
+func wavBytes(encoding, sampleRate, channels, bits int, frames [][]float64) []byte {
+	bytesPerSample := bits / 8
+	var data bytes.Buffer
+	for _, frame := range frames {
+		for _, v := range frame {
+			switch {
+			case encoding == wavFloat:
+				binary.Write(&data, binary.LittleEndian, float32(v))
+			case bits == 8:
+				data.WriteByte(byte(v*127 + 128))
+			case bits == 16:
+				binary.Write(&data, binary.LittleEndian, int16(v*32767))
+			case bits == 24:
+				s := int32(v * 8388607)
+				data.Write([]byte{byte(s), byte(s >> 8), byte(s >> 16)})
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(4+8+16+8+8+4+data.Len()))
+	b.WriteString("WAVE")
+	b.WriteString("LIST")
+	binary.Write(&b, binary.LittleEndian, uint32(4))
+	b.WriteString("INFO")
+	b.WriteString("fmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(encoding))
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate*channels*bytesPerSample))
+	binary.Write(&b, binary.LittleEndian, uint16(channels*bytesPerSample))
+	binary.Write(&b, binary.LittleEndian, uint16(bits))
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(data.Len()))
+	b.Write(data.Bytes())
+	return b.Bytes()
+}
+
+// toneFrames is one second of stereo audio: silence, then a tone at level in
+// the right channel
+func toneFrames(sampleRate int, level float64) [][]float64 {
+	frames := make([][]float64, sampleRate)
+	for i := range frames {
+		v := 0.0
+		if i >= sampleRate/2 {
+			v = level * math.Sin(float64(i)*math.Pi/2)
+		}
+		frames[i] = []float64{0, v}
+	}
+	return frames
+}
+
+func writeTestAudio(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write audio file: %v", err)
+	}
+	return path
+}
+
+func TestIngestAudioEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	wav := writeTestAudio(t, tmpDir, "interview.wav", wavBytes(wavPCM, 8000, 2, 16, toneFrames(8000, 0.5)))
+	evidence, err := system.IngestEvidence(wav, "CASE-AUD-001", "OFF-981", "Officer Test", "Interview Room 2", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	audio := evidence.Audio
+	if evidence.MediaType != MediaAudio || audio == nil || audio.SampleRate != 8000 || audio.Channels != 2 ||
+		audio.BitsPerSample != 16 || audio.DurationSeconds != 1 || evidence.Duration != 1 {
+		t.Fatalf("Unexpected audio metadata %+v", audio)
+	}
+	if len(audio.Waveform) != waveformBuckets || audio.Waveform[0] != 0 || audio.Waveform[waveformBuckets-1] != 0.5 {
+		t.Errorf("Expected silence then a tone at half level, got %v", audio.Waveform)
+	}
+
+	svg, err := system.GenerateWaveformSVG(evidence.ID)
+	if err != nil || !bytes.Contains(svg, []byte("<svg")) || !bytes.Contains(svg, []byte(evidence.ID)) {
+		t.Errorf("Unexpected waveform preview: %v\n%s", err, svg)
+	}
+
+	video, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-AUD-001", "OFF-982", "Officer Test", "Interview Room 2", nil)
+	if video.MediaType != MediaVideo || video.Audio != nil {
+		t.Errorf("Expected a video item, got %s", video.MediaType)
+	}
+	if _, err := system.GenerateWaveformSVG(video.ID); err == nil {
+		t.Error("Expected no waveform for video evidence")
+	}
+
+	report, _ := system.GenerateReport("CASE-AUD-001")
+	if !strings.Contains(report, "Audio: wav, 8000 Hz, 2 channels, 1.0 s") {
+		t.Errorf("Expected the audio details in the report:\n%s", report)
+	}
+}
+
+func TestIngestAudioWithoutMetadata(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	mp3 := writeTestAudio(t, tmpDir, "call.mp3", []byte("ID3 phone call"))
+	evidence, err := system.IngestEvidence(mp3, "CASE-AUD-002", "OFF-983", "Officer Test", "Dispatch", nil)
+	if err != nil || evidence.Audio.Format != "mp3" || evidence.Audio.SampleRate != 0 {
+		t.Fatalf("Expected an mp3 recorded by format only, got %+v, %v", evidence.Audio, err)
+	}
+
+	broken := writeTestAudio(t, tmpDir, "broken.wav", []byte("RIFF....WAVEjunk"))
+	evidence, err = system.IngestEvidence(broken, "CASE-AUD-002", "OFF-984", "Officer Test", "Dispatch", nil)
+	if err != nil {
+		t.Fatalf("Expected an unreadable WAV to be ingested: %v", err)
+	}
+	if evidence.Audio.Format != "wav" || len(evidence.Audio.Waveform) != 0 {
+		t.Errorf("Unexpected audio metadata %+v", evidence.Audio)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "SYSTEM")
+	if len(logs) != 1 || logs[0].Action != "MEDIA_PROBE_FAILED" {
+		t.Errorf("Expected MEDIA_PROBE_FAILED audit entry, got %v", logs)
+	}
+}
+
+func TestReadWAVEncodings(t *testing.T) {
+	tests := []struct {
+		encoding, bits int
+	}{
+		{wavPCM, 8},
+		{wavPCM, 24},
+		{wavFloat, 32},
+	}
+	for _, tt := range tests {
+		info, err := readWAV(bytes.NewReader(wavBytes(tt.encoding, 4000, 2, tt.bits, toneFrames(4000, 0.75))))
+		if err != nil {
+			t.Fatalf("%d-bit encoding %d: %v", tt.bits, tt.encoding, err)
+		}
+		if peak := info.Waveform[len(info.Waveform)-1]; math.Abs(peak-0.75) > 0.01 || info.Waveform[0] != 0 {
+			t.Errorf("%d-bit encoding %d: unexpected waveform %v", tt.bits, tt.encoding, info.Waveform)
+		}
+	}
+
+	if _, err := readWAV(bytes.NewReader(wavBytes(2, 4000, 1, 4, nil))); err == nil {
+		t.Error("Expected a compressed WAV encoding to be refused")
+	}
+}
+
+func TestServerAudioEvidence(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	wav := writeTestAudio(t, tmpDir, "interview.wav", wavBytes(wavPCM, 8000, 2, 16, toneFrames(8000, 0.5)))
+	evidence, _ := system.IngestEvidence(wav, "CASE-AUD-003", "OFF-985", "Officer Test", "Interview Room 1", nil)
+	system.IngestEvidence(createTestFile(t, tmpDir), "CASE-AUD-003", "OFF-986", "Officer Test", "Interview Room 1", nil)
+
+	resp := authGet(t, server, "/api/evidence/"+evidence.ID+"/waveform")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("Expected an SVG waveform, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp = authGet(t, server, "/api/evidence?case=CASE-AUD-003&media=audio")
+	var results []Evidence
+	json.NewDecoder(resp.Body).Decode(&results)
+	resp.Body.Close()
+	if len(results) != 1 || results[0].ID != evidence.ID {
+		t.Errorf("Expected only the audio item, got %d results", len(results))
+	}
+}
@@ -0,0 +1,327 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// auditExportFormat identifies the audit export layout
+const auditExportFormat = "bwc-audit-export-v1"
+
+// Files inside an audit export, beside manifest.json and manifest.sig
+const (
+	auditExportEntriesFile = "audit-log.jsonl"
+	auditExportChainFile   = "chain.json"
+	// auditExportCMSFile is the agency's X.509 signature over manifest.json,
+	// present when report signing is configured
+	auditExportCMSFile = "manifest.json.p7s"
+)
+
+// auditChainGenesis is the hash the chain starts from
+var auditChainGenesis = make([]byte, sha256.Size)
+
+var errAuditExportSignature = errors.New("audit export signature does not verify")
+
+// ExportedAuditEntry is one line of audit-log.jsonl. Seq is the entry's
+// position in the system's whole audit log, so gaps left by the filter show.
+type ExportedAuditEntry struct {
+	Seq int `json:"seq"`
+	AuditLog
+}
+
+// AuditChainLink ties one exported entry into the chain. EntryHash is the
+// SHA-256 of the entry's line in audit-log.jsonl, without its newline;
+// ChainHash is the SHA-256 of the previous link's ChainHash, then EntryHash,
+// both as raw bytes. The first link follows 32 zero bytes.
+type AuditChainLink struct {
+	Seq       int    `json:"seq"`
+	EntryHash string `json:"entry_hash"`
+	ChainHash string `json:"chain_hash"`
+}
+
+// AuditChain is chain.json in an audit export
+type AuditChain struct {
+	Algorithm string           `json:"algorithm"`
+	Links     []AuditChainLink `json:"links"`
+	Head      string           `json:"head"`
+}
+
+// AuditExportManifest is manifest.json at the root of an audit export. The
+// export is a zip holding the selected entries in audit-log.jsonl, the hash
+// chain over them in chain.json, and the manifest with its detached
+// signature in manifest.sig. The manifest pins both files and the chain head.
+type AuditExportManifest struct {
+	Format       string      `json:"format"`
+	SourceSystem string      `json:"source_system"`
+	ExportedAt   time.Time   `json:"exported_at"`
+	ExportedBy   string      `json:"exported_by"`
+	Filter       AuditFilter `json:"filter"`
+	EntryCount   int         `json:"entry_count"`
+	// LogEntries is the size of the whole audit log when it was exported
+	LogEntries int          `json:"log_entries"`
+	ChainHead  string       `json:"chain_head"`
+	Files      []BackupFile `json:"files"`
+}
+
+// auditExportSigningPayload is the message an audit export signature covers
+func auditExportSigningPayload(manifest []byte) []byte {
+	return append([]byte("BWC-AUDIT-EXPORT-v1\n"), manifest...)
+}
+
+// nextAuditChainHash extends the chain from prev by entryHash
+func nextAuditChainHash(prev, entryHash []byte) []byte {
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(entryHash)
+	return h.Sum(nil)
+}
+
+// ExportAuditLogs writes the audit entries filter selects to a zip at path,
+// with a hash chain over them and a detached signature over its manifest, so
+// an auditor can check the log offline with VerifyAuditExport and the key
+// from SealPublicKey. When report signing is configured the manifest also
+// carries the agency's PKCS#7 signature. The export itself is audited.
+func (bwc *BWCSystem) ExportAuditLogs(filter AuditFilter, path, exportedBy string) (*AuditExportManifest, error) {
+	if err := bwc.authorize(exportedBy, PermReviewAudit, "Audit log export", ""); err != nil {
+		return nil, err
+	}
+	bwc.mu.Lock()
+	if err := bwc.checkExportPathLocked(path, "", exportedBy); err != nil {
+		bwc.mu.Unlock()
+		return nil, err
+	}
+	bwc.mu.Unlock()
+
+	bwc.auditMu.Lock()
+	logEntries := len(bwc.auditLogs)
+	var selected []ExportedAuditEntry
+	for seq, log := range bwc.auditLogs {
+		if filter.matches(log) {
+			selected = append(selected, ExportedAuditEntry{Seq: seq, AuditLog: log})
+		}
+	}
+	bwc.auditMu.Unlock()
+
+	var lines bytes.Buffer
+	chain := AuditChain{Algorithm: "sha256", Links: make([]AuditChainLink, 0, len(selected))}
+	head := auditChainGenesis
+	for _, entry := range selected {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal audit entry: %w", err)
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+		sum := sha256.Sum256(line)
+		head = nextAuditChainHash(head, sum[:])
+		chain.Links = append(chain.Links, AuditChainLink{Seq: entry.Seq, EntryHash: hex.EncodeToString(sum[:]), ChainHash: hex.EncodeToString(head)})
+	}
+	chain.Head = hex.EncodeToString(head)
+	chainData, err := json.MarshalIndent(chain, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit chain: %w", err)
+	}
+
+	manifest := &AuditExportManifest{
+		Format:       auditExportFormat,
+		SourceSystem: bwc.config.System.Name,
+		ExportedAt:   time.Now().UTC(),
+		ExportedBy:   exportedBy,
+		Filter:       filter,
+		EntryCount:   len(selected),
+		LogEntries:   logEntries,
+		ChainHead:    chain.Head,
+	}
+
+	_, size, err := writeExportFile(path, nil, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		add := func(name string, data []byte) error {
+			fw, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			_, err = fw.Write(data)
+			return err
+		}
+		for _, entry := range []struct {
+			name string
+			data []byte
+		}{{auditExportEntriesFile, lines.Bytes()}, {auditExportChainFile, chainData}} {
+			if err := add(entry.name, entry.data); err != nil {
+				return err
+			}
+			sum := sha256.Sum256(entry.data)
+			manifest.Files = append(manifest.Files, BackupFile{Name: entry.name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(entry.data))})
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := add("manifest.json", data); err != nil {
+			return err
+		}
+		sig, err := json.MarshalIndent(PackageSignature{
+			KeyID:     bwc.sealer.keyID,
+			PublicKey: hex.EncodeToString(bwc.SealPublicKey()),
+			Signature: bwc.sealer.sign(auditExportSigningPayload(data)),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := add(casePackageSignatureFile, sig); err != nil {
+			return err
+		}
+		if bwc.reportSigner != nil {
+			signature, err := bwc.reportSigner.sign(data, manifest.ExportedAt)
+			if err != nil {
+				return err
+			}
+			if err := add(auditExportCMSFile, signature); err != nil {
+				return err
+			}
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audit export: %w", err)
+	}
+
+	bwc.logAudit(exportedBy, "EXPORT_AUDIT_LOG", filter.EvidenceID,
+		fmt.Sprintf("%d of %d audit entries exported to %s (%d bytes, chain head %s)", manifest.EntryCount, logEntries, path, size, chain.Head), "")
+	return manifest, nil
+}
+
+// VerifyAuditExport checks an audit export written by ExportAuditLogs
+// against key, the exporting system's SealPublicKey: the manifest signature,
+// the hashes of the files it pins, and every link of the hash chain over the
+// entries. It needs nothing from the system that wrote the export.
+func VerifyAuditExport(path string, key ed25519.PublicKey) (*AuditExportManifest, []ExportedAuditEntry, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not an audit export: %w", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		if entries[f.Name] != nil {
+			return nil, nil, fmt.Errorf("duplicate audit export entry %s", f.Name)
+		}
+		entries[f.Name] = f
+	}
+
+	manifestData, err := readPackageEntry(entries, "manifest.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	sigData, err := readPackageEntry(entries, casePackageSignatureFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sig PackageSignature
+	if err := json.Unmarshal(sigData, &sig); err != nil {
+		return nil, nil, fmt.Errorf("malformed audit export signature: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(key, auditExportSigningPayload(manifestData), signature) {
+		return nil, nil, errAuditExportSignature
+	}
+
+	var manifest AuditExportManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("malformed manifest: %w", err)
+	}
+	if manifest.Format != auditExportFormat {
+		return nil, nil, fmt.Errorf("unsupported audit export format %q", manifest.Format)
+	}
+	pinned := make(map[string]BackupFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		pinned[file.Name] = file
+	}
+
+	chainData, err := readPinnedAuditFile(entries, pinned, auditExportChainFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var chain AuditChain
+	if err := json.Unmarshal(chainData, &chain); err != nil {
+		return nil, nil, fmt.Errorf("malformed audit chain: %w", err)
+	}
+	if chain.Head != manifest.ChainHead || len(chain.Links) != manifest.EntryCount {
+		return nil, nil, errors.New("audit chain does not match the manifest")
+	}
+
+	lines, err := readPinnedAuditFile(entries, pinned, auditExportEntriesFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	exported := make([]ExportedAuditEntry, 0, len(chain.Links))
+	head := auditChainGenesis
+	scanner := bufio.NewScanner(bytes.NewReader(lines))
+	scanner.Buffer(nil, maxPackageMetadataBytes)
+	for scanner.Scan() {
+		i := len(exported)
+		if i >= len(chain.Links) {
+			return nil, nil, errors.New("audit log has more entries than its chain")
+		}
+		link := chain.Links[i]
+		sum := sha256.Sum256(scanner.Bytes())
+		head = nextAuditChainHash(head, sum[:])
+		if hex.EncodeToString(sum[:]) != link.EntryHash || hex.EncodeToString(head) != link.ChainHash {
+			return nil, nil, fmt.Errorf("audit entry %d breaks the chain", link.Seq)
+		}
+		var entry ExportedAuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, nil, fmt.Errorf("malformed audit entry %d: %w", link.Seq, err)
+		}
+		if entry.Seq != link.Seq || (i > 0 && entry.Seq <= exported[i-1].Seq) {
+			return nil, nil, fmt.Errorf("audit entry %d is out of order", link.Seq)
+		}
+		exported = append(exported, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", auditExportEntriesFile, err)
+	}
+	if len(exported) != len(chain.Links) || hex.EncodeToString(head) != chain.Head {
+		return nil, nil, errors.New("audit log is missing entries from its chain")
+	}
+	return &manifest, exported, nil
+}
+
+// readPinnedAuditFile reads name from an audit export and checks it against
+// the hash the manifest pins
+func readPinnedAuditFile(entries map[string]*zip.File, pinned map[string]BackupFile, name string) ([]byte, error) {
+	file, ok := pinned[name]
+	if !ok {
+		return nil, fmt.Errorf("manifest does not list %s", name)
+	}
+	f := entries[name]
+	if f == nil {
+		return nil, fmt.Errorf("audit export is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, file.Size+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != file.Size || hex.EncodeToString(sum[:]) != file.SHA256 {
+		return nil, fmt.Errorf("%s does not match the manifest", name)
+	}
+	return data, nil
+}
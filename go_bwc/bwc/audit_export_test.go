@@ -0,0 +1,145 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rewriteZip copies the zip at path, passing each entry through edit
+func rewriteZip(t *testing.T, path string, edit func(name string, data []byte) []byte) {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		w, _ := zw.Create(f.Name)
+		w.Write(edit(f.Name, data))
+	}
+	zr.Close()
+	zw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportAuditLogs(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	certFile, keyFile, roots := writeTestAgencyCertificate(t, t.TempDir(), key)
+	if system.reportSigner, err = loadReportSigner(certFile, keyFile); err != nil {
+		t.Fatalf("loadReportSigner failed: %v", err)
+	}
+
+	first, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-AEX-001", "OFF-8001", "", "", nil)
+	second, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-AEX-001", "OFF-8002", "", "", nil)
+	system.VerifyIntegrity(first.ID, "AUD-8001")
+	system.TransferCustody(first.ID, "OFF-8001", "DET-8001", "Analysis")
+
+	path := filepath.Join(tmpDir, "audit.zip")
+	manifest, err := system.ExportAuditLogs(AuditFilter{EvidenceID: first.ID}, path, "AUD-8001")
+	if err != nil {
+		t.Fatalf("ExportAuditLogs failed: %v", err)
+	}
+	if want := len(system.GetAuditLogs(first.ID, "")) - 1; manifest.EntryCount != want {
+		t.Errorf("expected %d entries, got %d", want, manifest.EntryCount)
+	}
+
+	verified, entries, err := VerifyAuditExport(path, system.SealPublicKey())
+	if err != nil {
+		t.Fatalf("VerifyAuditExport failed: %v", err)
+	}
+	if verified.ChainHead != manifest.ChainHead || len(entries) != manifest.EntryCount {
+		t.Errorf("expected the verified export to match, got %+v", verified)
+	}
+	for _, entry := range entries {
+		if entry.EvidenceID != first.ID {
+			t.Errorf("expected only %s, got an entry for %s", first.ID, entry.EvidenceID)
+		}
+	}
+	// The second item's ingest sits between the first two entries
+	if entries[1].Seq-entries[0].Seq < 2 {
+		t.Errorf("expected entries to keep their place in the whole log, got %d then %d", entries[0].Seq, entries[1].Seq)
+	}
+	if last := system.GetAuditLogs("", "AUD-8001"); last[len(last)-1].Action != "EXPORT_AUDIT_LOG" {
+		t.Error("expected the export to be audited")
+	}
+
+	// The agency's X.509 signature over the manifest verifies on its own
+	zr, _ := zip.OpenReader(path)
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	manifestData, _ := readPackageEntry(files, "manifest.json")
+	cms, err := readPackageEntry(files, auditExportCMSFile)
+	zr.Close()
+	if err != nil {
+		t.Fatalf("expected a PKCS#7 signature: %v", err)
+	}
+	if _, err := VerifyReportSignature(manifestData, cms, roots); err != nil {
+		t.Errorf("expected the PKCS#7 signature to verify: %v", err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, _, err := VerifyAuditExport(path, other); !errors.Is(err, errAuditExportSignature) {
+		t.Errorf("expected another key to be refused, got %v", err)
+	}
+
+	// An edited entry no longer matches the pinned file
+	rewriteZip(t, path, func(name string, data []byte) []byte {
+		if name == auditExportEntriesFile {
+			return bytes.Replace(data, []byte("DET-8001"), []byte("DET-9999"), 1)
+		}
+		return data
+	})
+	if _, _, err := VerifyAuditExport(path, system.SealPublicKey()); err == nil || !strings.Contains(err.Error(), "does not match the manifest") {
+		t.Errorf("expected an edited entry to be caught, got %v", err)
+	}
+
+	// Only entries inside the time window are exported
+	window := filepath.Join(tmpDir, "window.zip")
+	manifest, err = system.ExportAuditLogs(AuditFilter{EvidenceID: second.ID, Since: time.Now().Add(time.Hour)}, window, "AUD-8001")
+	if err != nil {
+		t.Fatalf("ExportAuditLogs failed: %v", err)
+	}
+	if _, entries, err := VerifyAuditExport(window, system.SealPublicKey()); err != nil || len(entries) != 0 || manifest.EntryCount != 0 {
+		t.Errorf("expected an empty export to verify, got %d entries: %v", len(entries), err)
+	}
+}
+
+func TestExportAuditLogsNeedsAuditPermission(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+	system.RegisterUser("OFF-8003", "", RoleOfficer, "ADM-001")
+	system.RegisterUser("AUD-8003", "", RoleAuditor, "ADM-001")
+
+	if _, err := system.ExportAuditLogs(AuditFilter{}, filepath.Join(tmpDir, "audit.zip"), "OFF-8003"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an officer to be refused, got %v", err)
+	}
+	if _, err := system.ExportAuditLogs(AuditFilter{}, filepath.Join(tmpDir, "audit.zip"), "AUD-8003"); err != nil {
+		t.Errorf("expected an auditor to export, got %v", err)
+	}
+}
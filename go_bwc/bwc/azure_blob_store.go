@@ -0,0 +1,250 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion is the Blob service version requests are made against; it
+// is the first to accept immutability policies on Put Blob
+const azureAPIVersion = "2020-10-02"
+
+// azureBlobStore keeps evidence files as block blobs in an Azure Storage
+// container, under the destination's prefix. With ObjectLock set, every blob
+// is given a locked immutability policy until its retention period ends, or a
+// legal hold when it is retained indefinitely. The container must have
+// version-level immutability enabled.
+type azureBlobStore struct {
+	client *http.Client
+	dest   ReportDestination
+}
+
+func newAzureBlobStore(dest ReportDestination) *azureBlobStore {
+	return &azureBlobStore{client: replicaHTTPClient, dest: dest}
+}
+
+// azureCredentials returns the destination's account and shared key, falling
+// back to the AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_KEY environment variables
+func azureCredentials(dest ReportDestination) (string, []byte, error) {
+	account, key := dest.Account, dest.AccountKey
+	if account == "" {
+		account, key = os.Getenv("AZURE_STORAGE_ACCOUNT"), os.Getenv("AZURE_STORAGE_KEY")
+	}
+	if account == "" {
+		return "", nil, errors.New("no Azure storage account is configured")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", nil, fmt.Errorf("the Azure account key is not base64: %w", err)
+	}
+	return account, decoded, nil
+}
+
+// url returns the URL of key in the container. A configured endpoint (e.g.
+// Azurite, with the account in its path) is used in place of the account's
+// blob endpoint.
+func (s *azureBlobStore) url(account, key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	escaped := (&url.URL{Path: "/" + s.dest.Bucket + "/" + s.dest.Prefix + key}).EscapedPath()
+	if s.dest.Endpoint != "" {
+		return strings.TrimRight(s.dest.Endpoint, "/") + escaped, nil
+	}
+	return fmt.Sprintf("https://%s.blob.core.windows.net%s", account, escaped), nil
+}
+
+// send signs and sends a request for key, returning the response, which the
+// caller must close. A missing blob is an error wrapping os.ErrNotExist, and
+// any other status but want an error too.
+func (s *azureBlobStore) send(method, key string, body io.Reader, size int64, headers map[string]string, want int) (*http.Response, error) {
+	account, accountKey, err := azureCredentials(s.dest)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.url(account, key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = size
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	signAzureRequest(req, account, accountKey, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Azure %s of %s failed: %w", method, key, err)
+	}
+	if resp.StatusCode == want {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("Azure blob %s: %w", key, os.ErrNotExist)
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("Azure %s of %s returned %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// Put stores r under key. With ObjectLock it is put under legal hold, as no
+// retention period is known.
+func (s *azureBlobStore) Put(key string, r io.Reader) (BlobInfo, error) {
+	return s.PutRetained(key, r, time.Time{})
+}
+
+// PutRetained stores r under key, immutable until retainUntil when ObjectLock
+// is set, or under legal hold when retainUntil is zero. Azure checks the body
+// against its Content-MD5, so r is spooled to a temporary file first.
+func (s *azureBlobStore) PutRetained(key string, r io.Reader, retainUntil time.Time) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+	path, size, sum, err := spoolBlob(key, r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer os.Remove(path)
+	md5Sum, err := fileMD5(path)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	headers := map[string]string{
+		"Content-Type":     "application/octet-stream",
+		"Content-MD5":      md5Sum,
+		"X-Ms-Blob-Type":   "BlockBlob",
+		"X-Ms-Meta-Sha256": sum,
+	}
+	if s.dest.ObjectLock {
+		if retainUntil.IsZero() {
+			headers["X-Ms-Legal-Hold"] = "true"
+		} else {
+			headers["X-Ms-Immutability-Policy-Mode"] = "locked"
+			headers["X-Ms-Immutability-Policy-Until-Date"] = retainUntil.UTC().Format(http.TimeFormat)
+		}
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer file.Close()
+	resp, err := s.send(http.MethodPut, key, file, size, headers, http.StatusCreated)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+	return BlobInfo{Key: key, Size: size, SHA256: sum, Modified: time.Now()}, nil
+}
+
+func (s *azureBlobStore) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.send(http.MethodGet, key, nil, 0, nil, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the blob, which Azure refuses while it is immutable
+func (s *azureBlobStore) Delete(key string) error {
+	resp, err := s.send(http.MethodDelete, key, nil, 0, nil, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *azureBlobStore) Stat(key string) (BlobInfo, error) {
+	resp, err := s.send(http.MethodHead, key, nil, 0, nil, http.StatusOK)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+	info := BlobInfo{Key: key, Size: resp.ContentLength}
+	info.Modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return info, nil
+}
+
+// HashReader streams the blob through SHA-256; nothing is written to disk
+func (s *azureBlobStore) HashReader(key string) (string, error) {
+	body, err := s.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", fmt.Errorf("Azure download of %s failed: %w", key, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// signAzureRequest adds the x-ms-date, x-ms-version and Shared Key
+// Authorization headers to req, signing every x-ms- header already set on it
+func signAzureRequest(req *http.Request, account string, key []byte, now time.Time) {
+	req.Header.Set("X-Ms-Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+
+	var names []string
+	for name := range req.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-ms-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+
+	canonicalResource := "/" + account + req.URL.EscapedPath()
+	query := req.URL.Query()
+	params := make([]string, 0, len(query))
+	for name := range query {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		values := query[name]
+		sort.Strings(values)
+		canonicalResource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+
+	length := ""
+	if req.ContentLength > 0 {
+		length = strconv.FormatInt(req.ContentLength, 10)
+	}
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		length,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, given as x-ms-date instead
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonicalHeaders.String() + canonicalResource,
+	}, "\n")
+
+	signature := base64.StdEncoding.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", "SharedKey "+account+":"+signature)
+}
@@ -0,0 +1,149 @@
+package bwc
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAzureContainer is an in-memory Azure Storage account that checks
+// Shared Key signatures and refuses to delete immutable blobs
+type fakeAzureContainer struct {
+	mu      sync.Mutex
+	account string
+	key     []byte
+	blobs   map[string][]byte
+	headers map[string]http.Header
+}
+
+func newFakeAzureContainer(account string, key []byte) (*fakeAzureContainer, *httptest.Server) {
+	c := &fakeAzureContainer{account: account, key: key, blobs: make(map[string][]byte), headers: make(map[string]http.Header)}
+	return c, httptest.NewServer(c)
+}
+
+func (c *fakeAzureContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	signed := r.Clone(r.Context())
+	signed.Header = r.Header.Clone()
+	date, _ := http.ParseTime(r.Header.Get("X-Ms-Date"))
+	signAzureRequest(signed, c.account, c.key, date)
+	if signed.Header.Get("Authorization") != r.Header.Get("Authorization") {
+		http.Error(w, "AuthenticationFailed", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			http.Error(w, "Md5Mismatch", http.StatusBadRequest)
+			return
+		}
+		c.blobs[r.URL.Path] = body
+		c.headers[r.URL.Path] = r.Header.Clone()
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		body, ok := c.blobs[r.URL.Path]
+		if !ok {
+			http.Error(w, "BlobNotFound", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Write(body)
+	case http.MethodDelete:
+		if h := c.headers[r.URL.Path]; h.Get("X-Ms-Immutability-Policy-Mode") != "" || h.Get("X-Ms-Legal-Hold") == "true" {
+			http.Error(w, "BlobImmutableDueToPolicy", http.StatusConflict)
+			return
+		}
+		delete(c.blobs, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func TestAzureBlobStoreWithImmutability(t *testing.T) {
+	accountKey := []byte("azure-account-key")
+	container, server := newFakeAzureContainer("bwcagency", accountKey)
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Storage.Blobs = &ReportDestination{Type: "azure", Bucket: "evidence", Prefix: "media/", Endpoint: server.URL,
+		Account: "bwcagency", AccountKey: base64.StdEncoding.EncodeToString(accountKey), ObjectLock: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-AZ-1", "OFF-1159", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	path := "/evidence/media/" + ev.BlobKey
+	headers := container.headers[path]
+	if ev.FilePath != "" || headers.Get("X-Ms-Meta-Sha256") != ev.FileHash {
+		t.Fatalf("expected the blob stored with the evidence hash, got %v", headers)
+	}
+	retainUntil, err := http.ParseTime(headers.Get("X-Ms-Immutability-Policy-Until-Date"))
+	if headers.Get("X-Ms-Immutability-Policy-Mode") != "locked" || err != nil {
+		t.Fatalf("expected a locked immutability policy, got %v", headers)
+	}
+	if want := time.Now().AddDate(0, 0, cfg.Storage.RetentionDays); retainUntil.Sub(want) > time.Minute || want.Sub(retainUntil) > time.Minute {
+		t.Errorf("expected the policy to last the retention period, got %s", retainUntil)
+	}
+
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Fatalf("expected the streamed blob to verify, got %v, %v", valid, err)
+	}
+	store := system.blobs.(*azureBlobStore)
+	if info, err := store.Stat(ev.BlobKey); err != nil || info.Size != ev.FileSize {
+		t.Errorf("expected the blob's size, got %+v, %v", info, err)
+	}
+	if err := store.Delete(ev.BlobKey); err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("expected an immutable blob not to be deleted, got %v", err)
+	}
+	if _, err := store.Stat("BWC-missing.mp4"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing blob to wrap os.ErrNotExist, got %v", err)
+	}
+
+	container.blobs[path] = []byte("tampered")
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || valid {
+		t.Errorf("expected the tampered blob to fail verification, got %v, %v", valid, err)
+	}
+}
+
+func TestAzureBlobStoreLegalHoldAndBadKey(t *testing.T) {
+	accountKey := []byte("azure-account-key")
+	container, server := newFakeAzureContainer("bwcagency", accountKey)
+	defer server.Close()
+	dest := ReportDestination{Type: "azure", Bucket: "evidence", Endpoint: server.URL, Account: "bwcagency",
+		AccountKey: base64.StdEncoding.EncodeToString(accountKey), ObjectLock: true}
+
+	info, err := newAzureBlobStore(dest).Put("BWC-CASE-AZ-2-OFF-1160-1700000000.mp4", strings.NewReader("footage"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if h := container.headers["/evidence/"+info.Key]; h.Get("X-Ms-Legal-Hold") != "true" {
+		t.Errorf("expected a legal hold, got %v", h)
+	}
+
+	dest.AccountKey = base64.StdEncoding.EncodeToString([]byte("wrong-key"))
+	if _, err := newAzureBlobStore(dest).HashReader(info.Key); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a request signed with the wrong key to be refused, got %v", err)
+	}
+}
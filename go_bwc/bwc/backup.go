@@ -0,0 +1,442 @@
+package bwc
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// backupFormat identifies the layout of a backup archive
+const backupFormat = "bwc-backup/v1"
+
+// errBackupSignature is returned for a backup whose manifest signature does
+// not verify or is not from a key this system trusts
+var errBackupSignature = errors.New("backup is not signed by this system or a trusted source")
+
+// BackupFile lists one entry of a backup archive with the hash it must match
+type BackupFile struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// BackupManifest is manifest.json at the root of a backup. The backup is a zip
+// holding the manifest, its signature in manifest.sig, the evidence records in
+// evidence.json, the audit log in audit.json and, when media is included,
+// each recording as media/<blob key>.
+type BackupManifest struct {
+	Format        string       `json:"format"`
+	SourceSystem  string       `json:"source_system"`
+	CreatedAt     time.Time    `json:"created_at"`
+	CreatedBy     string       `json:"created_by"`
+	IncludesMedia bool         `json:"includes_media"`
+	EvidenceCount int          `json:"evidence_count"`
+	AuditEntries  int          `json:"audit_entries"`
+	Files         []BackupFile `json:"files"`
+}
+
+// BackupRestore summarises a restored backup
+type BackupRestore struct {
+	Source        string    `json:"source"`
+	KeyID         string    `json:"key_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreatedBy     string    `json:"created_by"`
+	EvidenceCount int       `json:"evidence_count"`
+	AuditEntries  int       `json:"audit_entries"`
+	MediaRestored int       `json:"media_restored"`
+}
+
+// backupSigningPayload is the message a backup signature covers
+func backupSigningPayload(manifest []byte) []byte {
+	return append([]byte("BWC-BACKUP-v1\n"), manifest...)
+}
+
+// backupMediaName returns the archive entry evidence's recording is kept in
+func backupMediaName(evidence *Evidence) string {
+	if evidence.BlobKey != "" {
+		return "media/" + evidence.BlobKey
+	}
+	return "media/" + evidence.ID + filepath.Ext(evidence.FilePath)
+}
+
+// Backup writes the evidence records and the audit log to a single archive at
+// dst, with every recording too when includeMedia is set. The manifest pins
+// each entry by hash and is signed with the system's sealing key, so Restore
+// on new hardware configured with the same key, or trusting it as a source,
+// accepts it. Recordings are re-hashed as they are archived and the backup
+// fails if any no longer matches its recorded hash.
+func (bwc *BWCSystem) Backup(dst, userID string, includeMedia bool) (*BackupManifest, error) {
+	if err := bwc.authorize(userID, PermAdminister, "Backup", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	// Snapshot the records and the log so recordings are copied without the lock
+	bwc.mu.Lock()
+	if err := bwc.checkExportPathLocked(dst, "", userID); err != nil {
+		bwc.mu.Unlock()
+		return nil, err
+	}
+	records := bwc.evidenceDB.Search(nil)
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	evidenceData, err := json.MarshalIndent(evidenceSnapshot{Version: jsonSnapshotVersion, SavedAt: time.Now(), Evidence: records}, "", "  ")
+	if err != nil {
+		bwc.mu.Unlock()
+		return nil, fmt.Errorf("failed to marshal evidence: %w", err)
+	}
+	snapshot := make([]Evidence, len(records))
+	for i, ev := range records {
+		snapshot[i] = *ev
+	}
+	bwc.mu.Unlock()
+
+	bwc.auditMu.Lock()
+	entries := append([]AuditLog(nil), bwc.auditLogs...)
+	bwc.auditMu.Unlock()
+	auditData, err := json.MarshalIndent(auditSnapshot{Version: jsonSnapshotVersion, SavedAt: time.Now(), Entries: entries}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal audit log: %w", err)
+	}
+
+	manifest := &BackupManifest{
+		Format:        backupFormat,
+		SourceSystem:  bwc.config.System.Name,
+		CreatedAt:     time.Now().UTC(),
+		CreatedBy:     userID,
+		IncludesMedia: includeMedia,
+		EvidenceCount: len(snapshot),
+		AuditEntries:  len(entries),
+	}
+
+	_, size, err := writeExportFile(dst, nil, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		for _, entry := range []struct {
+			name string
+			data []byte
+		}{{jsonEvidenceFile, evidenceData}, {jsonAuditFile, auditData}} {
+			fw, err := zw.Create(entry.name)
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(entry.data); err != nil {
+				return err
+			}
+			sum := sha256.Sum256(entry.data)
+			manifest.Files = append(manifest.Files, BackupFile{Name: entry.name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(entry.data))})
+		}
+		if includeMedia {
+			for i := range snapshot {
+				file, err := bwc.writeBackupMedia(zw, &snapshot[i])
+				if err != nil {
+					return err
+				}
+				manifest.Files = append(manifest.Files, *file)
+			}
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		mw, err := zw.Create("manifest.json")
+		if err != nil {
+			return err
+		}
+		if _, err := mw.Write(data); err != nil {
+			return err
+		}
+		sig, err := json.MarshalIndent(PackageSignature{
+			KeyID:     bwc.sealer.keyID,
+			PublicKey: hex.EncodeToString(bwc.SealPublicKey()),
+			Signature: bwc.sealer.sign(backupSigningPayload(data)),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		sw, err := zw.Create(casePackageSignatureFile)
+		if err != nil {
+			return err
+		}
+		if _, err := sw.Write(sig); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	bwc.logAudit(userID, "BACKUP", "", fmt.Sprintf("Backup of %d records and %d audit entries written to %s (%d bytes, media included: %t)",
+		manifest.EvidenceCount, manifest.AuditEntries, dst, size, includeMedia), "")
+	return manifest, nil
+}
+
+// writeBackupMedia adds evidence's recording to zw, read from the blob store.
+// A recording encrypted at rest stays encrypted in the backup.
+func (bwc *BWCSystem) writeBackupMedia(zw *zip.Writer, evidence *Evidence) (*BackupFile, error) {
+	src, err := bwc.openStoredFile(evidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", evidence.ID, err)
+	}
+	defer src.Close()
+
+	// Recordings are already compressed
+	name := backupMediaName(evidence)
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store, Modified: evidence.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(fw, h), src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up %s: %w", evidence.ID, err)
+	}
+	file := &BackupFile{Name: name, SHA256: hex.EncodeToString(h.Sum(nil)), Size: n}
+	if file.SHA256 != storedHash(evidence) {
+		return nil, fmt.Errorf("%s no longer matches its recorded hash", evidence.ID)
+	}
+	return file, nil
+}
+
+// Restore loads a backup written by Backup into a system that holds no
+// evidence yet. Nothing is restored until the manifest signature verifies
+// against this system's sealing key or a trusted source and every entry
+// matches the hash the manifest pins. Recordings in the backup are put into
+// the blob store, retained for what is left of their retention period; a
+// backup without media expects the recordings to be in the blob store already.
+// The restored audit log is followed by a RESTORE entry.
+func (bwc *BWCSystem) Restore(src, userID string) (*BackupRestore, error) {
+	if err := bwc.authorize(userID, PermAdminister, "Restore", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opIngest); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	result, err := bwc.restoreBackup(src)
+	if err != nil {
+		bwc.logAudit(userID, "RESTORE_REJECTED", "", fmt.Sprintf("Backup %s rejected: %v", filepath.Base(src), err), "")
+		return nil, fmt.Errorf("backup rejected: %w", err)
+	}
+	bwc.logAudit(userID, "RESTORE", "", fmt.Sprintf("Restored %d records, %d audit entries and %d recordings from %s, backed up by %s at %s (key %s)",
+		result.EvidenceCount, result.AuditEntries, result.MediaRestored, filepath.Base(src), result.CreatedBy, result.CreatedAt.Format(time.RFC3339), result.KeyID), "")
+	return result, nil
+}
+
+// restoreBackup verifies the backup at src and loads it
+func (bwc *BWCSystem) restoreBackup(src string) (*BackupRestore, error) {
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup: %w", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		if entries[f.Name] != nil {
+			return nil, fmt.Errorf("duplicate backup entry %s", f.Name)
+		}
+		entries[f.Name] = f
+	}
+	manifestData, err := readPackageEntry(entries, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	sigData, err := readPackageEntry(entries, casePackageSignatureFile)
+	if err != nil {
+		return nil, err
+	}
+	var sig PackageSignature
+	if err := json.Unmarshal(sigData, &sig); err != nil {
+		return nil, fmt.Errorf("malformed backup signature: %w", err)
+	}
+	key, err := hex.DecodeString(sig.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("malformed backup signing key")
+	}
+	source := bwc.config.System.Name
+	if !ed25519.PublicKey(key).Equal(bwc.SealPublicKey()) {
+		var trusted bool
+		if source, trusted = bwc.trustedSourceFor(key); !trusted {
+			return nil, fmt.Errorf("%w (key %s)", errBackupSignature, sig.KeyID)
+		}
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(key, backupSigningPayload(manifestData), signature) {
+		return nil, errBackupSignature
+	}
+
+	var manifest BackupManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("malformed manifest: %w", err)
+	}
+	if manifest.Format != backupFormat {
+		return nil, fmt.Errorf("unsupported backup format %q", manifest.Format)
+	}
+	pinned := make(map[string]BackupFile, len(manifest.Files))
+	for _, file := range manifest.Files {
+		pinned[file.Name] = file
+	}
+
+	var evidence evidenceSnapshot
+	if err := readBackupJSON(entries, pinned, jsonEvidenceFile, &evidence, &evidence.Version); err != nil {
+		return nil, err
+	}
+	var audit auditSnapshot
+	if err := readBackupJSON(entries, pinned, jsonAuditFile, &audit, &audit.Version); err != nil {
+		return nil, err
+	}
+	if len(evidence.Evidence) != manifest.EvidenceCount || len(audit.Entries) != manifest.AuditEntries {
+		return nil, errors.New("backup contents do not match the manifest counts")
+	}
+
+	keySum := sha256.Sum256(key)
+	result := &BackupRestore{
+		Source:        source,
+		KeyID:         hex.EncodeToString(keySum[:8]),
+		CreatedAt:     manifest.CreatedAt,
+		CreatedBy:     manifest.CreatedBy,
+		EvidenceCount: manifest.EvidenceCount,
+		AuditEntries:  manifest.AuditEntries,
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	if len(bwc.evidenceDB.Search(nil)) > 0 {
+		return nil, errors.New("a backup can only be restored into a system with no evidence")
+	}
+
+	seen := make(map[string]bool)
+	for _, ev := range evidence.Evidence {
+		if ev == nil || ev.ID == "" || seen[ev.ID] {
+			return nil, errors.New("backup holds a record without an ID or one listed twice")
+		}
+		seen[ev.ID] = true
+	}
+	if manifest.IncludesMedia {
+		// Every recording is checked before any is stored
+		for _, ev := range evidence.Evidence {
+			if file, ok := pinned[backupMediaName(ev)]; !ok || file.SHA256 != storedHash(ev) || entries[file.Name] == nil {
+				return nil, fmt.Errorf("%s: recording is missing or does not match the manifest", ev.ID)
+			}
+		}
+		for _, ev := range evidence.Evidence {
+			if err := bwc.restoreBackupMediaLocked(entries[backupMediaName(ev)], ev); err != nil {
+				return nil, fmt.Errorf("%s: %w", ev.ID, err)
+			}
+			result.MediaRestored++
+		}
+	}
+
+	if err := bwc.appendAuditEntries(audit.Entries); err != nil {
+		return nil, fmt.Errorf("failed to restore the audit log: %w", err)
+	}
+
+	for _, ev := range evidence.Evidence {
+		if err := bwc.saveLocked(ev); err != nil {
+			return nil, fmt.Errorf("failed to restore %s: %w", ev.ID, err)
+		}
+	}
+	return result, nil
+}
+
+// appendAuditEntries adds entries written by another system, as they are, to
+// the end of the audit log
+func (bwc *BWCSystem) appendAuditEntries(entries []AuditLog) error {
+	bwc.auditMu.Lock()
+	defer bwc.auditMu.Unlock()
+	for _, entry := range entries {
+		bwc.auditLogs = append(bwc.auditLogs, entry)
+		if bwc.auditStore != nil {
+			if err := bwc.auditStore.AppendAudit(entry); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readBackupJSON reads a snapshot entry, checking it against the hash the
+// manifest pins, into v. version must point into v.
+func readBackupJSON(entries map[string]*zip.File, pinned map[string]BackupFile, name string, v interface{}, version *int) error {
+	data, err := readPackageEntry(entries, name)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if file, ok := pinned[name]; !ok || file.SHA256 != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("%s does not match the manifest hash", name)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("malformed %s: %w", name, err)
+	}
+	if *version > jsonSnapshotVersion {
+		return fmt.Errorf("%s: version %d is newer than this build reads (%d)", name, *version, jsonSnapshotVersion)
+	}
+	return nil
+}
+
+// restoreBackupMediaLocked copies a recording out of the backup into the blob
+// store and points the record at it. The caller must hold bwc.mu.
+func (bwc *BWCSystem) restoreBackupMediaLocked(f *zip.File, evidence *Evidence) error {
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return bwc.storeReceivedFileLocked(src, strings.TrimPrefix(f.Name, "media/"), evidence)
+}
+
+// storeReceivedFileLocked copies a recording that came from another system
+// into the blob store under key, checking it against evidence's hash as it is
+// copied, and points the record at it. The file is retained for what is left
+// of its retention period. The caller must hold bwc.mu.
+func (bwc *BWCSystem) storeReceivedFileLocked(src io.Reader, key string, evidence *Evidence) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(bwc.stagingDir(), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(bwc.stagingDir(), ".received-*")
+	if err != nil {
+		return fmt.Errorf("failed to stage recording: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), src)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stage recording: %w", err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != storedHash(evidence) {
+		return errors.New("recording does not match its recorded hash")
+	}
+
+	path, err := bwc.putBlobLocked(key, tmp.Name(), storedHash(evidence), bwc.blobRetainUntil(evidence))
+	if err != nil {
+		return err
+	}
+	evidence.BlobKey, evidence.FilePath = key, path
+	return nil
+}
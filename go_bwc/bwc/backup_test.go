@@ -0,0 +1,106 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupAndRestoreToNewHardware(t *testing.T) {
+	source, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	first, err := source.IngestEvidence(testFile, "CASE-BAK-001", "OFF-1160", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	second, _ := source.IngestEvidence(testFile, "CASE-BAK-002", "OFF-1161", "Officer Test", "Test Location", nil)
+	source.TransferCustody(first.ID, "OFF-1160", "DET-1", "Investigation")
+
+	path := filepath.Join(tmpDir, "backup.zip")
+	manifest, err := source.Backup(path, "ADMIN-1", true)
+	if err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+	if manifest.EvidenceCount != 2 || !manifest.IncludesMedia || len(manifest.Files) != 4 {
+		t.Fatalf("expected two records, the audit log and two recordings, got %+v", manifest)
+	}
+
+	// The new system is configured with the same sealing key
+	target, _, cleanupTarget := setupTestSystem(t)
+	defer cleanupTarget()
+	target.sealer = source.sealer
+
+	result, err := target.Restore(path, "ADMIN-2")
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if result.EvidenceCount != 2 || result.MediaRestored != 2 || result.AuditEntries != manifest.AuditEntries {
+		t.Errorf("unexpected restore summary: %+v", result)
+	}
+	restored, err := target.GetEvidence(first.ID)
+	if err != nil || len(restored.ChainOfCustody) != len(source.evidenceDB.Get(first.ID).ChainOfCustody) {
+		t.Fatalf("expected the record with its custody chain, got %+v, %v", restored, err)
+	}
+	if !strings.HasPrefix(restored.FilePath, target.storagePath) {
+		t.Errorf("expected the recording in the new storage, got %s", restored.FilePath)
+	}
+	for _, id := range []string{first.ID, second.ID} {
+		if valid, err := target.VerifyIntegrity(id, "AUDITOR-1"); err != nil || !valid {
+			t.Errorf("expected %s to verify after restore, got %v, %v", id, valid, err)
+		}
+	}
+
+	logs := target.GetAuditLogs("", "")
+	if logs[0].Action != source.GetAuditLogs("", "")[0].Action || logs[manifest.AuditEntries].Action != "RESTORE" {
+		t.Errorf("expected the restored log followed by a RESTORE entry, got %+v", logs[manifest.AuditEntries])
+	}
+
+	if _, err := target.Restore(path, "ADMIN-2"); err == nil || !strings.Contains(err.Error(), "no evidence") {
+		t.Errorf("expected a second restore into a populated system to be refused, got %v", err)
+	}
+}
+
+func TestRestoreRejectsTamperedOrUntrustedBackup(t *testing.T) {
+	source, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	source.IngestEvidence(createTestFile(t, tmpDir), "CASE-BAK-003", "OFF-1162", "Officer Test", "Test Location", nil)
+
+	path := filepath.Join(tmpDir, "backup.zip")
+	if _, err := source.Backup(path, "ADMIN-1", true); err != nil {
+		t.Fatalf("Backup failed: %v", err)
+	}
+
+	target, _, cleanupTarget := setupTestSystem(t)
+	defer cleanupTarget()
+	if _, err := target.Restore(path, "ADMIN-2"); !errors.Is(err, errBackupSignature) {
+		t.Errorf("expected a backup from an untrusted key to be refused, got %v", err)
+	}
+
+	trustSource(target, source, "Old Server")
+	tampered := filepath.Join(tmpDir, "tampered.zip")
+	rewritePackage(t, path, tampered, func(name string, data []byte) []byte {
+		if strings.HasPrefix(name, "media/") {
+			return []byte("overwritten")
+		}
+		return data
+	})
+	if _, err := target.Restore(tampered, "ADMIN-2"); err == nil || !strings.Contains(err.Error(), "recording") {
+		t.Errorf("expected a tampered recording to be refused, got %v", err)
+	}
+	if len(target.evidenceDB.Search(nil)) != 0 {
+		t.Error("expected nothing restored from a rejected backup")
+	}
+	entries, _ := os.ReadDir(target.stagingDir())
+	if len(entries) != 0 {
+		t.Errorf("expected no staged recordings left behind, got %v", entries)
+	}
+
+	result, err := target.Restore(path, "ADMIN-2")
+	if err != nil || result.Source != "Old Server" {
+		t.Errorf("expected a backup from a trusted source to restore, got %+v, %v", result, err)
+	}
+}
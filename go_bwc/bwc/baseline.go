@@ -0,0 +1,332 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// baselineMinDays is how many active days an account needs before its
+// activity is scored against its baseline
+const baselineMinDays = 3
+
+// reviewDayLayout is how review queue days are written
+const reviewDayLayout = "2006-01-02"
+
+// ActivityBaseline summarises an account's typical daily activity over the
+// audit history, counting only the days it was active
+type ActivityBaseline struct {
+	UserID        string         `json:"user_id"`
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	ActiveDays    int            `json:"active_days"`
+	MeanActions   float64        `json:"mean_actions"`
+	StdDevActions float64        `json:"stddev_actions"`
+	MeanCases     float64        `json:"mean_cases"`
+	StdDevCases   float64        `json:"stddev_cases"`
+	Actions       map[string]int `json:"actions"`
+}
+
+// ActivityDeviation is one way a day's activity differs from the baseline
+type ActivityDeviation struct {
+	Metric   string  `json:"metric"`
+	Observed float64 `json:"observed"`
+	Expected float64 `json:"expected"`
+	Score    float64 `json:"score"`
+	Details  string  `json:"details"`
+}
+
+// ActivityReview is one account's day of activity queued for an auditor
+type ActivityReview struct {
+	UserID     string              `json:"user_id"`
+	Day        string              `json:"day"`
+	Actions    int                 `json:"actions"`
+	Cases      int                 `json:"cases"`
+	Score      float64             `json:"score"`
+	Deviations []ActivityDeviation `json:"deviations"`
+	Reviewed   bool                `json:"reviewed"`
+	ReviewedBy string              `json:"reviewed_by,omitempty"`
+	ReviewedAt time.Time           `json:"reviewed_at,omitempty"`
+	Notes      string              `json:"notes,omitempty"`
+}
+
+// dailyActivity is one account's audited activity on one day
+type dailyActivity struct {
+	actions int
+	kinds   map[string]int
+	cases   map[string]bool
+}
+
+// dayStart returns midnight local time on t's day
+func dayStart(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// activityBetween groups the audit log between from and to by account and
+// day. System and unauthenticated entries are not attributed to an account.
+func (bwc *BWCSystem) activityBetween(from, to time.Time) map[string]map[time.Time]*dailyActivity {
+	bwc.auditMu.Lock()
+	var entries []AuditLog
+	for _, entry := range bwc.auditLogs {
+		if !entry.Timestamp.Before(from) && entry.Timestamp.Before(to) {
+			entries = append(entries, entry)
+		}
+	}
+	bwc.auditMu.Unlock()
+
+	cases := make(map[string]string)
+	bwc.mu.RLock()
+	for _, entry := range entries {
+		if evidence := bwc.evidenceDB.Get(entry.EvidenceID); evidence != nil {
+			cases[entry.EvidenceID] = evidence.CaseNumber
+		}
+	}
+	bwc.mu.RUnlock()
+
+	activity := make(map[string]map[time.Time]*dailyActivity)
+	for _, entry := range entries {
+		if entry.UserID == "SYSTEM" || entry.UserID == "UNKNOWN" || entry.UserID == "" {
+			continue
+		}
+		days := activity[entry.UserID]
+		if days == nil {
+			days = make(map[time.Time]*dailyActivity)
+			activity[entry.UserID] = days
+		}
+		day := dayStart(entry.Timestamp)
+		a := days[day]
+		if a == nil {
+			a = &dailyActivity{kinds: make(map[string]int), cases: make(map[string]bool)}
+			days[day] = a
+		}
+		a.actions++
+		a.kinds[entry.Action]++
+		if c := cases[entry.EvidenceID]; c != "" {
+			a.cases[c] = true
+		}
+	}
+	return activity
+}
+
+// meanStdDev returns the mean and population standard deviation of values
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// baselineFrom summarises days of activity
+func baselineFrom(userID string, from, to time.Time, days map[time.Time]*dailyActivity) *ActivityBaseline {
+	b := &ActivityBaseline{UserID: userID, From: from, To: to, ActiveDays: len(days), Actions: make(map[string]int)}
+	var actions, cases []float64
+	for _, a := range days {
+		actions = append(actions, float64(a.actions))
+		cases = append(cases, float64(len(a.cases)))
+		for kind, n := range a.kinds {
+			b.Actions[kind] += n
+		}
+	}
+	b.MeanActions, b.StdDevActions = meanStdDev(actions)
+	b.MeanCases, b.StdDevCases = meanStdDev(cases)
+	return b
+}
+
+// baselineWindow returns the period before day that baselines cover
+func (bwc *BWCSystem) baselineWindow(day time.Time) (time.Time, time.Time) {
+	to := dayStart(day)
+	return to.AddDate(0, 0, -bwc.config.Audit.BaselineDays), to
+}
+
+// ActivityBaselines returns the baseline of every account active in the
+// audit.baseline_days before day, by user ID
+func (bwc *BWCSystem) ActivityBaselines(day time.Time) []ActivityBaseline {
+	from, to := bwc.baselineWindow(day)
+	baselines := make([]ActivityBaseline, 0)
+	for userID, days := range bwc.activityBetween(from, to) {
+		baselines = append(baselines, *baselineFrom(userID, from, to, days))
+	}
+	sort.Slice(baselines, func(i, j int) bool {
+		return baselines[i].UserID < baselines[j].UserID
+	})
+	return baselines
+}
+
+// excessScore is how many standard deviations observed lies above mean. The
+// deviation is taken as at least 1 so a perfectly regular history does not
+// turn a single extra action into a large score.
+func excessScore(observed, mean, stddev float64) float64 {
+	if observed <= mean {
+		return 0
+	}
+	return (observed - mean) / math.Max(stddev, 1)
+}
+
+// scoreActivity compares a day of activity with the account's baseline
+func scoreActivity(b *ActivityBaseline, a *dailyActivity) []ActivityDeviation {
+	var deviations []ActivityDeviation
+	if s := excessScore(float64(a.actions), b.MeanActions, b.StdDevActions); s > 0 {
+		deviations = append(deviations, ActivityDeviation{
+			Metric: "volume", Observed: float64(a.actions), Expected: b.MeanActions, Score: s,
+			Details: fmt.Sprintf("%d actions against a typical %.1f", a.actions, b.MeanActions),
+		})
+	}
+	if s := excessScore(float64(len(a.cases)), b.MeanCases, b.StdDevCases); s > 0 {
+		deviations = append(deviations, ActivityDeviation{
+			Metric: "cases", Observed: float64(len(a.cases)), Expected: b.MeanCases, Score: s,
+			Details: fmt.Sprintf("%d cases against a typical %.1f", len(a.cases), b.MeanCases),
+		})
+	}
+
+	var unusual []string
+	for kind := range a.kinds {
+		if b.Actions[kind] == 0 {
+			unusual = append(unusual, kind)
+		}
+	}
+	if len(unusual) > 0 {
+		sort.Strings(unusual)
+		deviations = append(deviations, ActivityDeviation{
+			Metric: "new_actions", Observed: float64(len(unusual)), Score: float64(len(unusual)),
+			Details: "Actions not seen before: " + strings.Join(unusual, ", "),
+		})
+	}
+	return deviations
+}
+
+// reviewKey identifies an account's day in the review queue
+func reviewKey(userID, day string) string {
+	return userID + "|" + day
+}
+
+// ActivityReviewQueue scores each account's activity on day against its
+// baseline and returns the days scoring at least
+// audit.review_score_threshold that no auditor has reviewed yet, highest
+// score first. Accounts with fewer than three active days in the baseline
+// period are not scored.
+func (bwc *BWCSystem) ActivityReviewQueue(day time.Time) []ActivityReview {
+	from, to := bwc.baselineWindow(day)
+	history := bwc.activityBetween(from, to)
+	current := bwc.activityBetween(to, to.AddDate(0, 0, 1))
+	dayName := to.Format(reviewDayLayout)
+
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	queue := make([]ActivityReview, 0)
+	for userID, days := range current {
+		if len(history[userID]) < baselineMinDays {
+			continue
+		}
+		if _, reviewed := bwc.activityReviews[reviewKey(userID, dayName)]; reviewed {
+			continue
+		}
+		a := days[to]
+		review := ActivityReview{
+			UserID:     userID,
+			Day:        dayName,
+			Actions:    a.actions,
+			Cases:      len(a.cases),
+			Deviations: scoreActivity(baselineFrom(userID, from, to, history[userID]), a),
+		}
+		for _, d := range review.Deviations {
+			review.Score += d.Score
+		}
+		if review.Score >= bwc.config.Audit.ReviewScoreThreshold {
+			queue = append(queue, review)
+		}
+	}
+	sort.Slice(queue, func(i, j int) bool {
+		if queue[i].Score != queue[j].Score {
+			return queue[i].Score > queue[j].Score
+		}
+		return queue[i].UserID < queue[j].UserID
+	})
+	return queue
+}
+
+// ReviewActivity removes an account's day from the review queue once an
+// auditor has looked at it
+func (bwc *BWCSystem) ReviewActivity(userID string, day time.Time, auditorID, notes string) error {
+	return bwc.ReviewActivityContext(context.Background(), userID, day, auditorID, notes)
+}
+
+// ReviewActivityContext records a review like ReviewActivity, by the principal
+// ctx carries when it has one
+func (bwc *BWCSystem) ReviewActivityContext(ctx context.Context, userID string, day time.Time, auditorID, notes string) error {
+	auditorID, auth := actingUser(ctx, auditorID)
+	if err := bwc.authorize(auditorID, PermReviewAudit, "Activity review", ""); err != nil {
+		return err
+	}
+	if strings.TrimSpace(notes) == "" {
+		return errors.New("review notes are required")
+	}
+	if auditorID == userID {
+		return errors.New("an account cannot review its own activity")
+	}
+	dayName := dayStart(day).Format(reviewDayLayout)
+
+	var queued *ActivityReview
+	for _, review := range bwc.ActivityReviewQueue(day) {
+		if review.UserID == userID {
+			r := review
+			queued = &r
+			break
+		}
+	}
+	if queued == nil {
+		return fmt.Errorf("no activity by %s on %s is awaiting review", userID, dayName)
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	key := reviewKey(userID, dayName)
+	if _, reviewed := bwc.activityReviews[key]; reviewed {
+		return fmt.Errorf("activity by %s on %s has already been reviewed", userID, dayName)
+	}
+	queued.Reviewed = true
+	queued.ReviewedBy = auditorID
+	queued.ReviewedAt = time.Now()
+	queued.Notes = notes
+	bwc.activityReviews[key] = queued
+
+	bwc.logAuditAs(auth, auditorID, "REVIEW_ACTIVITY", "",
+		fmt.Sprintf("Reviewed activity by %s on %s (score %.1f): %s", userID, dayName, queued.Score, notes), "")
+	return nil
+}
+
+// ReviewedActivity returns the reviews recorded for userID, or for every
+// account when userID is empty, oldest day first
+func (bwc *BWCSystem) ReviewedActivity(userID string) []ActivityReview {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	reviews := make([]ActivityReview, 0)
+	for _, review := range bwc.activityReviews {
+		if userID == "" || review.UserID == userID {
+			reviews = append(reviews, *review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		if reviews[i].Day != reviews[j].Day {
+			return reviews[i].Day < reviews[j].Day
+		}
+		return reviews[i].UserID < reviews[j].UserID
+	})
+	return reviews
+}
@@ -0,0 +1,205 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// addActivity appends n audit entries by userID at the given time
+func addActivity(system *BWCSystem, userID, action, evidenceID string, at time.Time, n int) {
+	for i := 0; i < n; i++ {
+		system.auditLogs = append(system.auditLogs, AuditLog{
+			Timestamp:  at.Add(time.Duration(i) * time.Minute),
+			UserID:     userID,
+			Action:     action,
+			EvidenceID: evidenceID,
+		})
+	}
+}
+
+// seedBaselineHistory gives OFF-1050 and OFF-1051 five regular days before day
+// and OFF-1052 only two
+func seedBaselineHistory(t *testing.T, system *BWCSystem, tmpDir string, day time.Time) []*Evidence {
+	testFile := createTestFile(t, tmpDir)
+	var evidence []*Evidence
+	for _, c := range []string{"CASE-BL-001", "CASE-BL-002", "CASE-BL-003", "CASE-BL-004"} {
+		ev, err := system.IngestEvidence(testFile, c, "OFF-1049", "Officer Test", "Test Location", nil)
+		if err != nil {
+			t.Fatalf("Ingest failed: %v", err)
+		}
+		evidence = append(evidence, ev)
+	}
+
+	for i, n := range []int{8, 10, 12, 10, 10} {
+		at := day.AddDate(0, 0, -(i + 1)).Add(9 * time.Hour)
+		addActivity(system, "OFF-1050", "VIEW_EVIDENCE", evidence[0].ID, at, n)
+		addActivity(system, "OFF-1051", "VIEW_EVIDENCE", evidence[1].ID, at, n)
+		if i < 2 {
+			addActivity(system, "OFF-1052", "VIEW_EVIDENCE", evidence[1].ID, at, n)
+		}
+	}
+	return evidence
+}
+
+func TestActivityBaselines(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	seedBaselineHistory(t, system, tmpDir, day)
+	// Activity on the day itself is not part of its baseline
+	addActivity(system, "OFF-1050", "VIEW_EVIDENCE", "", day.Add(10*time.Hour), 50)
+
+	baselines := system.ActivityBaselines(day.Add(15 * time.Hour))
+	if len(baselines) != 3 || baselines[0].UserID != "OFF-1050" {
+		t.Fatalf("Expected three baselines by user, got %+v", baselines)
+	}
+	b := baselines[0]
+	if b.ActiveDays != 5 || b.MeanActions != 10 || b.MeanCases != 1 || b.Actions["VIEW_EVIDENCE"] != 50 {
+		t.Errorf("Unexpected baseline: %+v", b)
+	}
+	if math.Abs(b.StdDevActions-math.Sqrt(1.6)) > 1e-9 {
+		t.Errorf("Expected stddev %.3f, got %.3f", math.Sqrt(1.6), b.StdDevActions)
+	}
+	if !b.To.Equal(day) || !b.From.Equal(day.AddDate(0, 0, -30)) {
+		t.Errorf("Unexpected period %s to %s", b.From, b.To)
+	}
+}
+
+func TestActivityReviewQueue(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	evidence := seedBaselineHistory(t, system, tmpDir, day)
+
+	// OFF-1050 reads four cases in bulk and exports for the first time
+	for _, ev := range evidence {
+		addActivity(system, "OFF-1050", "VIEW_EVIDENCE", ev.ID, day.Add(9*time.Hour), 10)
+	}
+	addActivity(system, "OFF-1050", "EXPORT_EVIDENCE", evidence[0].ID, day.Add(11*time.Hour), 2)
+	// OFF-1051 has a normal day and OFF-1052 has too little history to score
+	addActivity(system, "OFF-1051", "VIEW_EVIDENCE", evidence[1].ID, day.Add(9*time.Hour), 11)
+	addActivity(system, "OFF-1052", "EXPORT_EVIDENCE", evidence[2].ID, day.Add(9*time.Hour), 60)
+
+	queue := system.ActivityReviewQueue(day.Add(12 * time.Hour))
+	if len(queue) != 1 {
+		t.Fatalf("Expected one account queued, got %+v", queue)
+	}
+	review := queue[0]
+	if review.UserID != "OFF-1050" || review.Day != "2026-10-14" || review.Actions != 42 || review.Cases != 4 {
+		t.Errorf("Unexpected review: %+v", review)
+	}
+	metrics := make(map[string]ActivityDeviation)
+	for _, d := range review.Deviations {
+		metrics[d.Metric] = d
+	}
+	if metrics["volume"].Score < 20 || metrics["cases"].Score != 3 || metrics["new_actions"].Details != "Actions not seen before: EXPORT_EVIDENCE" {
+		t.Errorf("Unexpected deviations: %+v", review.Deviations)
+	}
+
+	system.config.Audit.ReviewScoreThreshold = 1000
+	if queue := system.ActivityReviewQueue(day); len(queue) != 0 {
+		t.Errorf("Expected the threshold to empty the queue, got %+v", queue)
+	}
+}
+
+func TestReviewActivity(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	evidence := seedBaselineHistory(t, system, tmpDir, day)
+	addActivity(system, "OFF-1050", "EXPORT_EVIDENCE", evidence[0].ID, day.Add(9*time.Hour), 40)
+
+	if err := system.ReviewActivity("OFF-1050", day, "AUD-001", ""); err == nil {
+		t.Error("Expected review without notes to fail")
+	}
+	if err := system.ReviewActivity("OFF-1050", day, "OFF-1050", "Fine"); err == nil {
+		t.Error("Expected self-review to fail")
+	}
+	if err := system.ReviewActivity("OFF-1051", day, "AUD-001", "Fine"); err == nil {
+		t.Error("Expected reviewing an account that is not queued to fail")
+	}
+	if err := system.ReviewActivity("OFF-1050", day.Add(20*time.Hour), "AUD-001", "Discovery request from the DA"); err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if queue := system.ActivityReviewQueue(day); len(queue) != 0 {
+		t.Errorf("Expected reviewed activity to leave the queue, got %+v", queue)
+	}
+
+	reviews := system.ReviewedActivity("OFF-1050")
+	if len(reviews) != 1 || !reviews[0].Reviewed || reviews[0].ReviewedBy != "AUD-001" || reviews[0].Score == 0 {
+		t.Errorf("Unexpected reviews: %+v", reviews)
+	}
+	if logs := system.GetAuditLogs("", "AUD-001"); len(logs) != 1 || logs[0].Action != "REVIEW_ACTIVITY" {
+		t.Errorf("Expected REVIEW_ACTIVITY audit entry, got %+v", logs)
+	}
+}
+
+func TestActivityReviewEndpoint(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+	evidence := seedBaselineHistory(t, system, tmpDir, day)
+	addActivity(system, "OFF-1050", "EXPORT_EVIDENCE", evidence[0].ID, day.Add(9*time.Hour), 40)
+	enableAccessControl(system)
+	if _, err := system.RegisterUser("CUS-001", "", RoleEvidenceCustodian, "ADM-001"); err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+
+	for _, path := range []string{"/api/audit/review?date=2026-10-14", "/api/audit/baselines?date=2026-10-14"} {
+		resp := authGet(t, server, path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("Expected 403 for a non-auditor at %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	if err := system.SetUserRole("CUS-001", RoleAuditor, "ADM-001"); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+
+	resp := authGet(t, server, "/api/audit/review?date=14/10/2026")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad date, got %d", resp.StatusCode)
+	}
+
+	resp = authGet(t, server, "/api/audit/review?date=2026-10-14")
+	var queue []ActivityReview
+	json.NewDecoder(resp.Body).Decode(&queue)
+	resp.Body.Close()
+	if len(queue) != 1 || queue[0].UserID != "OFF-1050" {
+		t.Fatalf("Unexpected queue: %+v", queue)
+	}
+
+	resp = authGet(t, server, "/api/audit/baselines?date=2026-10-14")
+	var baselines []ActivityBaseline
+	json.NewDecoder(resp.Body).Decode(&baselines)
+	resp.Body.Close()
+	if len(baselines) != 3 {
+		t.Errorf("Expected 3 baselines, got %+v", baselines)
+	}
+
+	body, _ := json.Marshal(activityReviewRequest{UserID: "OFF-1050", Date: "2026-10-14", Notes: "Court-ordered export"})
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/audit/review", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Review request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.StatusCode)
+	}
+	if queue := system.ActivityReviewQueue(day); len(queue) != 0 {
+		t.Errorf("Expected an empty queue, got %+v", queue)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build bbolt
+
+package bwc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltEvidenceBucket = []byte("evidence")
+	boltAuditBucket    = []byte("audit")
+)
+
+func init() {
+	openBoltStore = func(path string) (EvidenceStore, error) {
+		return openBBoltStore(path)
+	}
+}
+
+// boltStore is an EvidenceStore and AuditStore in a single bbolt file, with a
+// bucket of evidence records keyed by ID and a bucket of audit entries keyed
+// by sequence. bbolt syncs each transaction before it returns, so a record
+// that Put accepted survives a crash.
+type boltStore struct {
+	*memoryStore
+	db *bolt.DB
+}
+
+// openBBoltStore opens or creates the file at path and loads the records in it
+func openBBoltStore(path string) (*boltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	s := &boltStore{memoryStore: newMemoryStore(), db: db}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltEvidenceBucket, boltAuditBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltEvidenceBucket).ForEach(func(id, record []byte) error {
+			var evidence Evidence
+			if err := json.Unmarshal(record, &evidence); err != nil {
+				return fmt.Errorf("evidence %s: invalid record: %w", id, err)
+			}
+			return s.memoryStore.Put(&evidence)
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *boltStore) Put(evidence *Evidence) error {
+	record, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltEvidenceBucket).Put([]byte(evidence.ID), record)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write evidence %s: %w", evidence.ID, err)
+	}
+	return s.memoryStore.Put(evidence)
+}
+
+func (s *boltStore) Delete(id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltEvidenceBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete evidence %s: %w", id, err)
+	}
+	return s.memoryStore.Delete(id)
+}
+
+func (s *boltStore) AppendAudit(log AuditLog) error {
+	entry, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltAuditBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		var key [8]byte
+		binary.BigEndian.PutUint64(key[:], seq)
+		return bucket.Put(key[:], entry)
+	})
+}
+
+func (s *boltStore) AuditLogs() ([]AuditLog, error) {
+	var logs []AuditLog
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// Big-endian keys iterate in the order they were appended
+		return tx.Bucket(boltAuditBucket).ForEach(func(_, entry []byte) error {
+			var log AuditLog
+			if err := json.Unmarshal(entry, &log); err != nil {
+				return fmt.Errorf("invalid audit entry: %w", err)
+			}
+			logs = append(logs, log)
+			return nil
+		})
+	})
+	return logs, err
+}
@@ -0,0 +1,50 @@
+//go:build bbolt
+
+package bwc
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStoreSurvivesRestart(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "evidence.bolt")
+	store, err := openBBoltStore(path)
+	if err != nil {
+		t.Fatalf("openBBoltStore failed: %v", err)
+	}
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-BLT-1", "OFF-1143", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1143", "DET-005", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	store.db.Close()
+
+	restarted, err := NewBWCSystem(tmpDir)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	reopened, err := openBBoltStore(path)
+	if err != nil {
+		t.Fatalf("openBBoltStore failed: %v", err)
+	}
+	defer reopened.db.Close()
+	if err := restarted.SetEvidenceStore(reopened); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+	got, err := restarted.GetEvidence(ev.ID)
+	if err != nil || len(got.ChainOfCustody) != 2 {
+		t.Fatalf("expected the record and its custody chain to survive, got %+v, %v", got, err)
+	}
+	if len(restarted.GetAuditLogs(ev.ID, "")) < 2 {
+		t.Error("expected the audit log to survive a restart")
+	}
+}
@@ -0,0 +1,46 @@
+package bwc
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestBLAKE3Vectors(t *testing.T) {
+	h, err := newDigest(HashBLAKE3)
+	if err != nil {
+		t.Fatalf("newDigest failed: %v", err)
+	}
+	// From the BLAKE3 test vectors: input byte i is i mod 251
+	vectors := map[int]string{
+		0:      "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262",
+		1:      "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213",
+		1024:   "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7",
+		1025:   "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444",
+		102400: "bc3e3d41a1146b069abffad3c0d44860cf664390afce4d9661f7902e7943e085",
+	}
+	for n, want := range vectors {
+		input := make([]byte, n)
+		for i := range input {
+			input[i] = byte(i % 251)
+		}
+
+		h.Reset()
+		h.Write(input)
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			t.Errorf("BLAKE3 of %d bytes:\n got %s\nwant %s", n, got, want)
+		}
+
+		// Written in pieces that straddle blocks and chunks
+		h.Reset()
+		for i := 0; i < n; i += 100 {
+			end := i + 100
+			if end > n {
+				end = n
+			}
+			h.Write(input[i:end])
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != want {
+			t.Errorf("BLAKE3 of %d bytes written in pieces: got %s", n, got)
+		}
+	}
+}
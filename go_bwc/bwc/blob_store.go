@@ -0,0 +1,380 @@
+package bwc
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// BlobInfo describes a stored evidence file
+type BlobInfo struct {
+	Key      string    `json:"key"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Modified time.Time `json:"modified"`
+}
+
+// BlobStore holds the files evidence records describe. Records keep the key
+// of their file in Evidence.BlobKey.
+type BlobStore interface {
+	// Put stores what r yields under key, replacing any blob there, and
+	// returns its size and SHA-256
+	Put(key string, r io.Reader) (BlobInfo, error)
+	// Get opens the blob stored under key
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the blob stored under key
+	Delete(key string) error
+	// Stat describes the blob stored under key, with an error wrapping
+	// os.ErrNotExist when there is none. SHA256 is left empty.
+	Stat(key string) (BlobInfo, error)
+	// HashReader returns the SHA-256 of the blob stored under key, read as
+	// a stream
+	HashReader(key string) (string, error)
+}
+
+// localBlobStore is implemented by blob stores whose blobs are files on local
+// disk, which the features that read recordings directly need
+type localBlobStore interface {
+	// Path returns the file the blob stored under key is kept in
+	Path(key string) string
+	// PutFile moves the file at path into the store under key
+	PutFile(key, path string) (BlobInfo, error)
+}
+
+// retainingBlobStore is implemented by blob stores that can make a blob
+// immutable for as long as the evidence must be kept
+type retainingBlobStore interface {
+	// PutRetained is Put, with the blob kept unchanged until retainUntil, or
+	// indefinitely when retainUntil is zero
+	PutRetained(key string, r io.Reader, retainUntil time.Time) (BlobInfo, error)
+}
+
+// diskBlobStore is the default BlobStore: files in a directory on local disk,
+// named by their keys
+type diskBlobStore struct {
+	dir string
+}
+
+func newDiskBlobStore(dir string) *diskBlobStore {
+	return &diskBlobStore{dir: dir}
+}
+
+// checkBlobKey refuses keys that are not a plain file name
+func checkBlobKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) || strings.ContainsRune(key, 0) {
+		return fmt.Errorf("invalid blob key %q", key)
+	}
+	return nil
+}
+
+func (s *diskBlobStore) Path(key string) string {
+	return filepath.Join(s.dir, key)
+}
+
+func (s *diskBlobStore) Put(key string, r io.Reader) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".blob-*")
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	info, err := s.PutFile(key, tmp.Name())
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	info.Size, info.SHA256 = size, hex.EncodeToString(h.Sum(nil))
+	return info, nil
+}
+
+func (s *diskBlobStore) PutFile(key, path string) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+	if err := os.Rename(path, s.Path(key)); err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to store %s: %w", key, err)
+	}
+	return s.Stat(key)
+}
+
+func (s *diskBlobStore) Get(key string) (io.ReadCloser, error) {
+	if err := checkBlobKey(key); err != nil {
+		return nil, err
+	}
+	return os.Open(s.Path(key))
+}
+
+func (s *diskBlobStore) Delete(key string) error {
+	if err := checkBlobKey(key); err != nil {
+		return err
+	}
+	return os.Remove(s.Path(key))
+}
+
+func (s *diskBlobStore) Stat(key string) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+	info, err := os.Stat(s.Path(key))
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Key: key, Size: info.Size(), Modified: info.ModTime()}, nil
+}
+
+func (s *diskBlobStore) HashReader(key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return calculateFileHash(s.Path(key))
+}
+
+// spoolBlob copies what r yields to a temporary file, for cloud stores that
+// need the length and hashes of an upload before it is sent, and returns the
+// file's path, size and SHA-256. The caller removes the file.
+func spoolBlob(key string, r io.Reader) (string, int64, string, error) {
+	tmp, err := os.CreateTemp("", "bwc-blob-*")
+	if err != nil {
+		return "", 0, "", err
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, "", fmt.Errorf("failed to spool %s: %w", key, err)
+	}
+	return tmp.Name(), size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// fileMD5 returns the base64 MD5 of the file at path, the form cloud stores
+// check uploads against
+func fileMD5(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// SetBlobStore replaces the store evidence files are kept in, by default the
+// storage directory. It must be called before any evidence is ingested.
+// Processing reads recordings as local files, so it needs a store that keeps
+// its blobs on local disk; parity, replication and playback copy a blob kept
+// elsewhere to a temporary file for as long as they need it.
+func (bwc *BWCSystem) SetBlobStore(store BlobStore) error {
+	if store == nil {
+		return errors.New("a blob store is required")
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if len(bwc.evidenceDB.Search(nil)) > 0 {
+		return errors.New("the blob store cannot be changed once evidence is held")
+	}
+	bwc.blobs = store
+	return nil
+}
+
+// openBlobStore returns the blob store dest describes
+func openBlobStore(dest ReportDestination) (BlobStore, error) {
+	switch dest.Type {
+	case "s3":
+		return newS3BlobStore(dest), nil
+	case "azure":
+		return newAzureBlobStore(dest), nil
+	case "gcs":
+		return newGCSBlobStore(dest), nil
+	}
+	return nil, fmt.Errorf("unsupported blob store type %q", dest.Type)
+}
+
+// putBlobLocked moves a staged file with the SHA-256 hash into the blob store
+// under key and returns where it is on local disk, or "" when the store is not
+// local. A store that can retain blobs keeps it until retainUntil, or
+// indefinitely when that is zero. The caller must hold bwc.mu.
+func (bwc *BWCSystem) putBlobLocked(key, stagedPath, hash string, retainUntil time.Time) (string, error) {
+	if local, ok := bwc.blobs.(localBlobStore); ok {
+		if _, err := local.PutFile(key, stagedPath); err != nil {
+			return "", err
+		}
+		return local.Path(key), nil
+	}
+	file, err := os.Open(stagedPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	var info BlobInfo
+	if retaining, ok := bwc.blobs.(retainingBlobStore); ok {
+		info, err = retaining.PutRetained(key, file, retainUntil)
+	} else {
+		info, err = bwc.blobs.Put(key, file)
+	}
+	if err != nil {
+		return "", err
+	}
+	if info.SHA256 != hash {
+		bwc.blobs.Delete(key)
+		return "", fmt.Errorf("blob %s was stored with hash %s, expected %s", key, info.SHA256, hash)
+	}
+	os.Remove(stagedPath)
+	return "", nil
+}
+
+// blobRetainUntil is how long a store that can retain blobs keeps the file
+// of evidence unchanged; zero means indefinitely
+func (bwc *BWCSystem) blobRetainUntil(evidence *Evidence) time.Time {
+	if expiry, _, ok := retentionExpiryUnder(bwc.config.RetentionPolicy(), evidence); ok {
+		return expiry.UTC().Add(time.Second - 1).Truncate(time.Second)
+	}
+	return time.Time{}
+}
+
+// storagePathFor is where the file of evidence is kept on local disk, or for
+// a blob kept elsewhere, where the files that go with it are
+func (bwc *BWCSystem) storagePathFor(evidence *Evidence) string {
+	if evidence.FilePath != "" {
+		return evidence.FilePath
+	}
+	return filepath.Join(bwc.storagePath, evidence.BlobKey)
+}
+
+// statStoredFile returns the size of the file of evidence as it is kept in
+// storage, with an error wrapping os.ErrNotExist when it is gone
+func (bwc *BWCSystem) statStoredFile(evidence *Evidence) (int64, error) {
+	if evidence.BlobKey == "" {
+		info, err := os.Stat(evidence.FilePath)
+		if err != nil {
+			return 0, err
+		}
+		return info.Size(), nil
+	}
+	info, err := bwc.blobs.Stat(evidence.BlobKey)
+	return info.Size, err
+}
+
+// hashStoredFile returns the SHA-256 of the file of evidence as it is kept in
+// storage, which storedHash records
+func (bwc *BWCSystem) hashStoredFile(evidence *Evidence) (string, error) {
+	if evidence.BlobKey == "" {
+		return calculateFileHash(evidence.FilePath)
+	}
+	return bwc.blobs.HashReader(evidence.BlobKey)
+}
+
+// localStoredFile returns a file on local disk holding the file of evidence
+// as it is kept in storage, and a func to call when done with it. A blob kept
+// off local disk is copied to a temporary file, which done removes.
+func (bwc *BWCSystem) localStoredFile(evidence *Evidence) (string, func(), error) {
+	if evidence.FilePath != "" {
+		return evidence.FilePath, func() {}, nil
+	}
+	src, err := bwc.openStoredFile(evidence)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp(bwc.storagePath, ".blob-*")
+	if err != nil {
+		return "", nil, err
+	}
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", nil, fmt.Errorf("failed to copy %s from the blob store: %w", evidence.BlobKey, err)
+	}
+	return tmp.Name(), func() { os.Remove(tmp.Name()) }, nil
+}
+
+// replaceStoredFileLocked puts the repaired file at path in place of the file
+// of evidence, in the blob store or for records from before blob stores at
+// its file path. The caller must hold bwc.mu.
+func (bwc *BWCSystem) replaceStoredFileLocked(evidence *Evidence, path string) error {
+	if evidence.BlobKey == "" {
+		return os.Rename(path, evidence.FilePath)
+	}
+	_, err := bwc.putBlobLocked(evidence.BlobKey, path, storedHash(evidence), bwc.blobRetainUntil(evidence))
+	return err
+}
+
+// openEvidenceFile opens the recording of evidence, decrypting it when it is
+// encrypted at rest
+func (bwc *BWCSystem) openEvidenceFile(evidence *Evidence) (io.ReadCloser, error) {
+	file, err := bwc.openStoredFile(evidence)
+	if err != nil || evidence.Encryption == nil {
+		return file, err
+	}
+	c, err := bwc.atRestCipher(evidence.Encryption, evidence.ID, evidence.FileSize)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{newSegmentReader(c, file, false), file}, nil
+}
+
+// hashEvidence returns the SHA-256 of the recording of evidence. Records
+// from before blob stores, or imported with a case, name the file instead.
+func (bwc *BWCSystem) hashEvidence(evidence *Evidence) (string, error) {
+	if evidence.Encryption != nil {
+		hash, _, err := bwc.hashEncrypted(evidence, nil, nil)
+		return hash, err
+	}
+	return bwc.hashStoredFile(evidence)
+}
+
+// hashEvidenceVia hashes the recording of evidence like hashEvidence,
+// reading through wrap when it is set
+func (bwc *BWCSystem) hashEvidenceVia(evidence *Evidence, wrap func(io.Reader) io.Reader) (string, error) {
+	if wrap == nil {
+		return bwc.hashEvidence(evidence)
+	}
+	if evidence.Encryption != nil {
+		hash, _, err := bwc.hashEncrypted(evidence, nil, wrap)
+		return hash, err
+	}
+	file, err := bwc.openStoredFile(evidence)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, wrap(file)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,226 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memBlobStore is a BlobStore off local disk
+type memBlobStore struct {
+	mu    sync.Mutex
+	blobs map[string][]byte
+}
+
+func newMemBlobStore() *memBlobStore {
+	return &memBlobStore{blobs: make(map[string][]byte)}
+}
+
+func (s *memBlobStore) Put(key string, r io.Reader) (BlobInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	s.mu.Lock()
+	s.blobs[key] = data
+	s.mu.Unlock()
+	sum := sha256.Sum256(data)
+	return BlobInfo{Key: key, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:]), Modified: time.Now()}, nil
+}
+
+func (s *memBlobStore) Get(key string) (io.ReadCloser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("blob %s: %w", key, os.ErrNotExist)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memBlobStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.blobs, key)
+	return nil
+}
+
+func (s *memBlobStore) Stat(key string) (BlobInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.blobs[key]
+	if !ok {
+		return BlobInfo{}, fmt.Errorf("blob %s: %w", key, os.ErrNotExist)
+	}
+	return BlobInfo{Key: key, Size: int64(len(data))}, nil
+}
+
+func (s *memBlobStore) HashReader(key string) (string, error) {
+	r, err := s.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func TestIngestIntoBlobStore(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	blobs := newMemBlobStore()
+	if err := system.SetBlobStore(nil); err == nil {
+		t.Error("expected a nil blob store to be rejected")
+	}
+	if err := system.SetBlobStore(blobs); err != nil {
+		t.Fatalf("SetBlobStore failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-BLB-1", "OFF-1150", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if ev.FilePath != "" || ev.BlobKey != ev.ID+".mp4" {
+		t.Fatalf("expected the file to be kept only in the blob store, got path %q key %q", ev.FilePath, ev.BlobKey)
+	}
+	if info, err := blobs.Stat(ev.BlobKey); err != nil || info.Size != ev.FileSize {
+		t.Fatalf("expected the blob to be stored, got %+v, %v", info, err)
+	}
+	if entries, _ := os.ReadDir(system.stagingDir()); len(entries) != 0 {
+		t.Errorf("expected the staged copy to be removed, found %d entries", len(entries))
+	}
+
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Fatalf("expected the blob to verify, got %v, %v", valid, err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1150", "DET-009", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+
+	blobs.Put(ev.BlobKey, strings.NewReader("tampered"))
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || valid {
+		t.Errorf("expected the tampered blob to fail verification, got %v, %v", valid, err)
+	}
+
+	if err := system.SetBlobStore(newMemBlobStore()); err == nil {
+		t.Error("expected the blob store not to be replaced once evidence is held")
+	}
+}
+
+func TestMaintenanceAndPlaybackOffLocalDisk(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableTestParity(system)
+	blobs := newMemBlobStore()
+	if err := system.SetBlobStore(blobs); err != nil {
+		t.Fatalf("SetBlobStore failed: %v", err)
+	}
+
+	source, original := writeParityTestFile(t, tmpDir, 50000)
+	ev, err := system.IngestEvidence(source, "CASE-BLB-3", "OFF-1152", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if ev.FilePath != "" || ev.Parity == nil {
+		t.Fatalf("expected a blob with parity kept locally, got path %q parity %+v", ev.FilePath, ev.Parity)
+	}
+	if issues := system.CheckStorage(true); len(issues) != 0 {
+		t.Errorf("expected the blob to pass the storage check, got %+v", issues)
+	}
+
+	session, err := system.StartViewSession(ev.ID, "DET-010", "")
+	if err != nil {
+		t.Fatalf("StartViewSession failed: %v", err)
+	}
+	stream, _, err := system.OpenEvidenceStream(session.ID, ev.ID, "DET-010")
+	if err != nil {
+		t.Fatalf("OpenEvidenceStream failed: %v", err)
+	}
+	stream.Seek(40000, io.SeekStart)
+	tail, _ := io.ReadAll(stream)
+	stream.Close()
+	if !bytes.Equal(tail, original[40000:]) {
+		t.Errorf("expected playback to read the blob from any offset, got %d bytes", len(tail))
+	}
+	if entries, _ := filepath.Glob(filepath.Join(system.storagePath, ".blob-*")); len(entries) != 0 {
+		t.Errorf("expected the temporary copy to be removed, found %v", entries)
+	}
+
+	damaged := append([]byte(nil), original...)
+	damaged[12345] ^= 0xff
+	blobs.Put(ev.BlobKey, bytes.NewReader(damaged))
+	if issues := system.CheckStorage(true); len(issues) != 1 || issues[0].Problem != "hash mismatch" {
+		t.Errorf("expected the damaged blob to be found, got %+v", issues)
+	}
+	if _, err := system.RepairFromParity(ev.ID, "TECH-1"); err != nil {
+		t.Fatalf("RepairFromParity failed: %v", err)
+	}
+	if hash, _ := blobs.HashReader(ev.BlobKey); hash != ev.FileHash {
+		t.Error("expected the repaired file to be put back in the blob store")
+	}
+
+	system.config.Storage.Replica = &ReportDestination{Type: "directory", Path: filepath.Join(tmpDir, "replica")}
+	replica, err := system.ReplicateEvidence(ev.ID, "ADMIN-1")
+	if err != nil {
+		t.Fatalf("ReplicateEvidence failed: %v", err)
+	}
+	if copied, _ := os.ReadFile(replica.Location); !bytes.Equal(copied, original) {
+		t.Error("expected the blob to be copied to the replica")
+	}
+
+	blobs.Delete(ev.BlobKey)
+	if issues := system.CheckStorage(false); len(issues) != 1 || !strings.Contains(issues[0].Problem, "not accessible") {
+		t.Errorf("expected the missing blob to be found, got %+v", issues)
+	}
+}
+
+func TestDiskBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	store := newDiskBlobStore(dir)
+
+	info, err := store.Put("BWC-CASE-BLB-2-OFF-1151-1700000000.mp4", strings.NewReader("footage"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	sum := sha256.Sum256([]byte("footage"))
+	if info.Size != 7 || info.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("expected size and hash of the blob, got %+v", info)
+	}
+	if hash, err := store.HashReader(info.Key); err != nil || hash != info.SHA256 {
+		t.Errorf("expected HashReader to match, got %s, %v", hash, err)
+	}
+	r, err := store.Get(info.Key)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if string(data) != "footage" {
+		t.Errorf("expected the stored bytes, got %q", data)
+	}
+	if err := store.Delete(info.Key); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Stat(info.Key); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the blob to be gone, got %v", err)
+	}
+
+	for _, key := range []string{"", "..", "../escape.mp4", `a\b`} {
+		if _, err := store.Put(key, strings.NewReader("x")); err == nil {
+			t.Errorf("expected key %q to be refused", key)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package bwc
+
+import (
+	"fmt"
+	"time"
+)
+
+// ClockCheck compares the creation time a camera wrote into a video's
+// container with when the video was uploaded, after correcting for the
+// camera's known clock offset, so that disputes over a timeline can be
+// answered from the record. Photos are checked against the incident time
+// instead; see captureTimeDiscrepancy.
+type ClockCheck struct {
+	RecordedAt time.Time `json:"recorded_at"`
+	// Device is the serial number or officer ID whose offset was applied
+	Device        string    `json:"device,omitempty"`
+	OffsetSeconds int       `json:"offset_seconds,omitempty"`
+	CorrectedAt   time.Time `json:"corrected_at"`
+	IngestedAt    time.Time `json:"ingested_at"`
+	Plausible     bool      `json:"plausible"`
+	// DriftSeconds is the least the camera clock must be wrong by to explain
+	// an implausible time: positive when it runs ahead, negative when behind
+	DriftSeconds int64  `json:"drift_seconds,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// Drift returns DriftSeconds as a duration
+func (c *ClockCheck) Drift() time.Duration {
+	return time.Duration(c.DriftSeconds) * time.Second
+}
+
+// deviceOffset returns the known clock offset of the camera that recorded
+// evidence: by its serial number if the original media is known, otherwise by
+// the officer it was issued to
+func (bwc *BWCSystem) deviceOffset(evidence *Evidence) (string, int) {
+	offsets := bwc.config.CameraClock.DeviceOffsets
+	if f := evidence.Forensics; f != nil && f.OriginalMedia != nil && f.OriginalMedia.DeviceSerial != "" {
+		if offset, ok := offsets[f.OriginalMedia.DeviceSerial]; ok {
+			return f.OriginalMedia.DeviceSerial, offset
+		}
+	}
+	if offset, ok := offsets[evidence.OfficerID]; ok {
+		return evidence.OfficerID, offset
+	}
+	return "", 0
+}
+
+// checkClock checks the recorded time of a video against ingestedAt. It
+// returns nil when the camera embedded no time.
+func (bwc *BWCSystem) checkClock(evidence *Evidence, ingestedAt time.Time) *ClockCheck {
+	if evidence.Video == nil || evidence.Video.RecordedAt == nil {
+		return nil
+	}
+	recordedAt := *evidence.Video.RecordedAt
+	check := &ClockCheck{RecordedAt: recordedAt, IngestedAt: ingestedAt, Plausible: true}
+	check.Device, check.OffsetSeconds = bwc.deviceOffset(evidence)
+	check.CorrectedAt = recordedAt.Add(-time.Duration(check.OffsetSeconds) * time.Second)
+
+	cfg := bwc.config.CameraClock
+	latest := ingestedAt.Add(time.Duration(cfg.ToleranceMinutes) * time.Minute)
+	earliest := ingestedAt.AddDate(0, 0, -cfg.MaxRecordingAgeDays)
+	end := check.CorrectedAt.Add(time.Duration(evidence.Duration) * time.Second)
+	switch {
+	case end.After(latest):
+		check.Plausible = false
+		drift := end.Sub(ingestedAt).Round(time.Second)
+		check.DriftSeconds = int64(drift / time.Second)
+		check.Reason = fmt.Sprintf("recording ends %s after its upload began at %s", drift, ingestedAt.Format(time.RFC3339))
+	case check.CorrectedAt.Before(earliest):
+		check.Plausible = false
+		check.DriftSeconds = -int64(earliest.Sub(check.CorrectedAt).Round(time.Second) / time.Second)
+		check.Reason = fmt.Sprintf("recording starts %s before its upload began at %s, more than %d days",
+			ingestedAt.Sub(check.CorrectedAt).Round(time.Second), ingestedAt.Format(time.RFC3339), cfg.MaxRecordingAgeDays)
+	}
+	return check
+}
+
+// recheckClockLocked repeats the clock check of evidence, as when its camera
+// is identified and a different offset applies. It returns the reason when
+// the clock is newly found implausible, for the caller to audit once the
+// change is saved. The caller must hold bwc.mu.
+func (bwc *BWCSystem) recheckClockLocked(evidence *Evidence) string {
+	if evidence.Clock == nil {
+		return ""
+	}
+	before := *evidence.Clock
+	check := bwc.checkClock(evidence, before.IngestedAt)
+	evidence.Clock = check
+	if !check.Plausible && (before.Plausible || before.DriftSeconds != check.DriftSeconds) {
+		return check.Reason
+	}
+	return ""
+}
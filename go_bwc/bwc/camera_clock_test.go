@@ -0,0 +1,126 @@
+package bwc
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// ingestRecordedAt ingests a bodycam video whose container says it was
+// created at recordedAt
+func ingestRecordedAt(t *testing.T, system *BWCSystem, caseNumber, officerID string, recordedAt time.Time) *Evidence {
+	t.Helper()
+	dir := t.TempDir()
+	system.config.VideoProcessing.ExtractMetadata = true
+	system.config.VideoProcessing.FFprobePath = fakeFFprobe(t, dir, `{"streams":[
+ {"codec_type":"video","codec_name":"h264","width":1920,"height":1080,"avg_frame_rate":"30/1"}],
+ "format":{"duration":"600.000000","tags":{"creation_time":"`+recordedAt.UTC().Format(time.RFC3339Nano)+`"}}}`)
+	ev, err := system.IngestEvidence(createTestFile(t, dir), caseNumber, officerID, "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	return ev
+}
+
+func TestCameraClockCheck(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	now := time.Now().Truncate(time.Second)
+	ok := ingestRecordedAt(t, system, "CASE-CLK-1", "OFF-1133", now.Add(-time.Hour))
+	if ok.Video.RecordedAt == nil || !ok.Video.RecordedAt.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expected the container creation time, got %+v", ok.Video)
+	}
+	if c := ok.Clock; c == nil || !c.Plausible || c.DriftSeconds != 0 || c.Device != "" {
+		t.Errorf("expected a plausible recording, got %+v", c)
+	}
+
+	ahead := ingestRecordedAt(t, system, "CASE-CLK-1", "OFF-1136", now.Add(3*time.Hour))
+	c := ahead.Clock
+	if c == nil || c.Plausible || !strings.Contains(c.Reason, "after its upload began") {
+		t.Fatalf("expected a recording from the future to be flagged, got %+v", c)
+	}
+	// The recording ends ten minutes after its embedded start
+	if drift := c.Drift() - 3*time.Hour - 10*time.Minute; drift < -time.Second || drift > time.Second {
+		t.Errorf("expected about 3h10m of drift, got %s", c.Drift())
+	}
+	logs := system.GetAuditLogs(ahead.ID, "SYSTEM")
+	if len(logs) != 1 || logs[0].Action != "CLOCK_DRIFT_DETECTED" || logs[0].Details != c.Reason {
+		t.Errorf("expected CLOCK_DRIFT_DETECTED, got %+v", logs)
+	}
+
+	reset := ingestRecordedAt(t, system, "CASE-CLK-5", "OFF-1133", time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	if c := reset.Clock; c.Plausible || c.DriftSeconds >= 0 || !strings.Contains(c.Reason, "more than 90 days") {
+		t.Errorf("expected a reset clock to be flagged as behind, got %+v", c)
+	}
+
+	report, _ := system.GenerateCaseReport("CASE-CLK-1", ReportOptions{})
+	for _, want := range []string{
+		"Camera Clock: Plausible, recorded " + now.Add(-time.Hour).UTC().Format(time.RFC3339),
+		"Camera Clock: Implausible, recorded " + now.Add(3*time.Hour).UTC().Format(time.RFC3339) + ", off by at least " + c.Drift().String(),
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected %q in the report:\n%s", want, report)
+		}
+	}
+
+	// A known offset for the officer's camera explains the difference
+	system.config.CameraClock.DeviceOffsets = map[string]int{"OFF-1134": 3 * 3600}
+	corrected := ingestRecordedAt(t, system, "CASE-CLK-2", "OFF-1134", now.Add(3*time.Hour))
+	if c := corrected.Clock; !c.Plausible || c.Device != "OFF-1134" || !c.CorrectedAt.Equal(now) {
+		t.Errorf("expected the device offset to be applied, got %+v", c)
+	}
+	report, _ = system.GenerateCaseReport("CASE-CLK-2", ReportOptions{Locale: "fr"})
+	if !strings.Contains(report, "Horloge de la caméra: Plausible, enregistré "+now.UTC().Format(time.RFC3339)+" après un décalage de l'appareil de +10800 s") {
+		t.Errorf("expected the corrected time in the report:\n%s", report)
+	}
+
+	// Without a time in the container there is nothing to check
+	system.config.VideoProcessing.FFprobePath = fakeFFprobe(t, t.TempDir(), ffprobeBodycam)
+	plain, _ := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-CLK-3", "OFF-1133", "Officer Test", "Patrol", nil)
+	if plain.Clock != nil {
+		t.Errorf("expected no clock check, got %+v", plain.Clock)
+	}
+}
+
+func TestCameraClockRecheckedWhenDeviceIdentified(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.CameraClock.DeviceOffsets = map[string]int{"X7000001": 2 * 3600, "X7000002": -2 * 3600}
+
+	now := time.Now()
+	ev := ingestRecordedAt(t, system, "CASE-CLK-4", "OFF-1135", now.Add(2*time.Hour))
+	if ev.Clock.Plausible {
+		t.Fatalf("expected an unexplained drift to be flagged, got %+v", ev.Clock)
+	}
+
+	updated, err := system.UpdateMetadata(ev.ID, "CUS-001", ev.Revision, MetadataUpdate{OriginalMedia: &OriginalMedia{DeviceSerial: "X7000001"}})
+	if err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if c := updated.Clock; !c.Plausible || c.Device != "X7000001" || c.OffsetSeconds != 7200 {
+		t.Errorf("expected the camera's offset to explain the drift, got %+v", c)
+	}
+
+	updated, err = system.UpdateMetadata(ev.ID, "CUS-001", updated.Revision, MetadataUpdate{OriginalMedia: &OriginalMedia{DeviceSerial: "X7000002"}})
+	if err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if c := updated.Clock; c.Plausible || c.Drift() < 4*time.Hour {
+		t.Errorf("expected the wrong offset to widen the drift, got %+v", c)
+	}
+	logs := system.GetAuditLogs(ev.ID, "CUS-001")
+	if len(logs) != 3 || logs[2].Action != "CLOCK_DRIFT_DETECTED" {
+		t.Errorf("expected the recheck to be audited, got %+v", logs)
+	}
+}
+
+func TestCameraClockConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CameraClock.ToleranceMinutes = -1
+	cfg.CameraClock.MaxRecordingAgeDays = 0
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "camera_clock.tolerance_minutes") || !strings.Contains(err.Error(), "camera_clock.max_recording_age_days") {
+		t.Errorf("expected both settings to be rejected, got %v", err)
+	}
+}
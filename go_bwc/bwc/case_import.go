@@ -0,0 +1,307 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	errPackageUntrusted = errors.New("case package is not signed by a trusted source")
+	errPackageSignature = errors.New("case package signature does not verify")
+)
+
+// maxPackageMetadataBytes bounds the manifest, signature and each record read from a package
+const maxPackageMetadataBytes = 16 << 20
+
+// CaseImport summarises an accepted case package
+type CaseImport struct {
+	Source      string    `json:"source"`
+	KeyID       string    `json:"key_id"`
+	CaseNumber  string    `json:"case_number"`
+	ExportedBy  string    `json:"exported_by"`
+	ExportedAt  time.Time `json:"exported_at"`
+	EvidenceIDs []string  `json:"evidence_ids"`
+}
+
+// stagedImport is a verified record whose recording has been copied into storage
+type stagedImport struct {
+	evidence *Evidence
+	tmpPath  string
+	destPath string
+}
+
+// ImportCasePackage imports a case package exported by another BWC instance
+// or agency. Encrypted packages are decrypted first. Nothing is accepted until
+// the manifest signature verifies against a trusted source and every record
+// and recording matches the hashes the manifest pins. Each imported item gets
+// an IMPORTED custody entry from the source system to userID.
+func (bwc *BWCSystem) ImportCasePackage(path, userID string) (*CaseImport, error) {
+	if err := bwc.authorize(userID, PermIngest, "Case package import", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opIngest); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	result, staged, err := bwc.verifyCasePackage(path)
+	if err != nil {
+		removeStaged(staged)
+		bwc.logAudit(userID, "IMPORT_REJECTED", "", fmt.Sprintf("Case package %s rejected: %v", filepath.Base(path), err), "")
+		return nil, fmt.Errorf("case package rejected: %w", err)
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	for _, s := range staged {
+		if bwc.evidenceDB.Get(s.evidence.ID) != nil {
+			removeStaged(staged)
+			bwc.logAudit(userID, "IMPORT_REJECTED", s.evidence.ID, "Evidence already exists", "")
+			return nil, fmt.Errorf("case package rejected: evidence %s already exists", s.evidence.ID)
+		}
+	}
+
+	for i, s := range staged {
+		if err := os.Rename(s.tmpPath, s.destPath); err != nil {
+			for _, moved := range staged[:i] {
+				os.Remove(moved.destPath)
+			}
+			removeStaged(staged[i:])
+			return nil, fmt.Errorf("failed to move imported file into storage: %w", err)
+		}
+	}
+
+	now := time.Now()
+	for _, s := range staged {
+		evidence := s.evidence
+		evidence.FilePath = s.destPath
+		markModified(evidence, now)
+		entry := CustodyEntry{
+			Timestamp:   now,
+			FromOfficer: result.Source,
+			ToOfficer:   userID,
+			Action:      "IMPORTED",
+			Purpose: fmt.Sprintf("Signed case package from %s (key %s), exported by %s at %s",
+				result.Source, result.KeyID, result.ExportedBy, result.ExportedAt.Format(time.RFC3339)),
+			VerifiedHash: evidence.FileHash,
+		}
+		entry.EntryHash, err = custodyEntryHash(entry)
+		if err != nil {
+			return nil, err
+		}
+		evidence.ChainOfCustody = append(evidence.ChainOfCustody, entry)
+
+		if err := bwc.saveLocked(evidence); err != nil {
+			return nil, err
+		}
+		bwc.logAudit(userID, "IMPORT_EVIDENCE", evidence.ID, entry.Purpose, "")
+		bwc.publishEvidenceChange(EventEvidenceIngested, evidence, userID, EvidenceChange{ToOfficer: userID})
+	}
+
+	return result, nil
+}
+
+// removeStaged deletes staged recordings that were not accepted
+func removeStaged(staged []stagedImport) {
+	for _, s := range staged {
+		os.Remove(s.tmpPath)
+	}
+}
+
+// trustedSourceFor returns the name of the trusted source holding key
+func (bwc *BWCSystem) trustedSourceFor(key []byte) (string, bool) {
+	for _, source := range bwc.config.ChainOfCustody.TrustedSources {
+		trusted, err := hex.DecodeString(source.PublicKey)
+		if err == nil && bytes.Equal(trusted, key) {
+			return source.Name, true
+		}
+	}
+	return "", false
+}
+
+// verifyCasePackage checks the package signature and hashes, staging each
+// recording into storage. Staged files are returned even on error so the
+// caller can remove them.
+func (bwc *BWCSystem) verifyCasePackage(path string) (*CaseImport, []stagedImport, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a case package: %w", err)
+	}
+	defer zr.Close()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		if entries[f.Name] != nil {
+			return nil, nil, fmt.Errorf("duplicate package entry %s", f.Name)
+		}
+		entries[f.Name] = f
+	}
+
+	manifestData, err := readPackageEntry(entries, "manifest.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	sigData, err := readPackageEntry(entries, casePackageSignatureFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var sig PackageSignature
+	if err := json.Unmarshal(sigData, &sig); err != nil {
+		return nil, nil, fmt.Errorf("malformed package signature: %w", err)
+	}
+	key, err := hex.DecodeString(sig.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, nil, errors.New("malformed package signing key")
+	}
+	source, trusted := bwc.trustedSourceFor(key)
+	if !trusted {
+		return nil, nil, fmt.Errorf("%w (key %s)", errPackageUntrusted, sig.KeyID)
+	}
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(key, packageSigningPayload(manifestData), signature) {
+		return nil, nil, errPackageSignature
+	}
+
+	var manifest CasePackageManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("malformed manifest: %w", err)
+	}
+	if manifest.Format != casePackageFormat {
+		return nil, nil, fmt.Errorf("unsupported package format %q", manifest.Format)
+	}
+	if len(manifest.Evidence) == 0 {
+		return nil, nil, errors.New("package contains no evidence")
+	}
+
+	keySum := sha256.Sum256(key)
+	result := &CaseImport{
+		Source:     source,
+		KeyID:      hex.EncodeToString(keySum[:8]),
+		CaseNumber: manifest.CaseNumber,
+		ExportedBy: manifest.ExportedBy,
+		ExportedAt: manifest.ExportedAt,
+	}
+
+	staged := make([]stagedImport, 0, len(manifest.Evidence))
+	seen := make(map[string]bool)
+	for _, item := range manifest.Evidence {
+		if seen[item.EvidenceID] {
+			return nil, staged, fmt.Errorf("evidence %s is listed twice", item.EvidenceID)
+		}
+		seen[item.EvidenceID] = true
+
+		s, err := bwc.stageCasePackageItem(entries, &manifest, item)
+		if s != nil {
+			staged = append(staged, *s)
+		}
+		if err != nil {
+			return nil, staged, fmt.Errorf("%s: %w", item.EvidenceID, err)
+		}
+		result.EvidenceIDs = append(result.EvidenceIDs, item.EvidenceID)
+	}
+	return result, staged, nil
+}
+
+// stageCasePackageItem verifies one record and copies its recording to a
+// temporary file in storage, checking the recording's hash as it is copied
+func (bwc *BWCSystem) stageCasePackageItem(entries map[string]*zip.File, manifest *CasePackageManifest, item CasePackageItem) (*stagedImport, error) {
+	id := item.EvidenceID
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return nil, errors.New("invalid evidence ID")
+	}
+	ext := strings.TrimPrefix(item.File, "files/"+id)
+	if item.Record != "evidence/"+id+".json" || !strings.HasPrefix(item.File, "files/"+id) || (ext != "" && ext != filepath.Ext(item.File)) {
+		return nil, errors.New("unexpected package entry names")
+	}
+
+	record, err := readPackageEntry(entries, item.Record)
+	if err != nil {
+		return nil, err
+	}
+	recordSum := sha256.Sum256(record)
+	if hex.EncodeToString(recordSum[:]) != item.RecordSHA256 {
+		return nil, errors.New("record does not match the manifest hash")
+	}
+	var evidence Evidence
+	if err := json.Unmarshal(record, &evidence); err != nil {
+		return nil, fmt.Errorf("malformed record: %w", err)
+	}
+	if evidence.ID != id || evidence.CaseNumber != manifest.CaseNumber || evidence.FileHash != item.FileSHA256 {
+		return nil, errors.New("record does not match the manifest")
+	}
+
+	bwc.mu.RLock()
+	existing := bwc.evidenceDB.Get(id)
+	bwc.mu.RUnlock()
+	if existing != nil {
+		return nil, errors.New("evidence already exists")
+	}
+
+	f := entries[item.File]
+	if f == nil {
+		return nil, fmt.Errorf("package is missing %s", item.File)
+	}
+	src, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(bwc.storagePath, ".import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage imported file: %w", err)
+	}
+	staged := &stagedImport{evidence: &evidence, tmpPath: tmp.Name(), destPath: filepath.Join(bwc.storagePath, id+ext)}
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), src)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return staged, fmt.Errorf("failed to stage imported file: %w", err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != item.FileSHA256 || n != item.FileSize || n != evidence.FileSize {
+		return staged, errors.New("recording does not match the manifest hash")
+	}
+	return staged, nil
+}
+
+// readPackageEntry reads a bounded metadata entry from a package
+func readPackageEntry(entries map[string]*zip.File, name string) ([]byte, error) {
+	f := entries[name]
+	if f == nil {
+		return nil, fmt.Errorf("package is missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxPackageMetadataBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxPackageMetadataBytes {
+		return nil, fmt.Errorf("%s is too large", name)
+	}
+	return data, nil
+}
@@ -0,0 +1,208 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// exportTestPackage exports a two-item case from a source system named "County Sheriff"
+func exportTestPackage(t *testing.T) (*BWCSystem, string, func()) {
+	t.Helper()
+	source, tmpDir, cleanup := setupTestSystem(t)
+	source.config.System.Name = "County Sheriff"
+
+	testFile := createTestFile(t, tmpDir)
+	source.IngestEvidence(testFile, "CASE-IMP-001", "OFF-801", "Officer Test", "Test Location", nil)
+	source.IngestEvidence(testFile, "CASE-IMP-001", "OFF-802", "Officer Test", "Test Location", nil)
+
+	path := filepath.Join(tmpDir, "case.zip")
+	if _, err := source.ExportCasePackage("CASE-IMP-001", path, "SO-CLERK-1", "Joint investigation", nil); err != nil {
+		cleanup()
+		t.Fatalf("ExportCasePackage failed: %v", err)
+	}
+	return source, path, cleanup
+}
+
+// trustSource makes target trust packages signed by source
+func trustSource(target, source *BWCSystem, name string) {
+	target.config.ChainOfCustody.TrustedSources = append(target.config.ChainOfCustody.TrustedSources,
+		TrustedSource{Name: name, PublicKey: hex.EncodeToString(source.SealPublicKey())})
+}
+
+// rewritePackage copies a package, passing each entry's content through edit
+func rewritePackage(t *testing.T, src, dst string, edit func(name string, data []byte) []byte) {
+	t.Helper()
+	zr, err := zip.OpenReader(src)
+	if err != nil {
+		t.Fatalf("Failed to open package: %v", err)
+	}
+	defer zr.Close()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		w, _ := zw.Create(f.Name)
+		w.Write(edit(f.Name, data))
+	}
+	zw.Close()
+	if err := os.WriteFile(dst, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write package: %v", err)
+	}
+}
+
+// stagedFiles lists leftover import staging files in dir
+func stagedFiles(dir string) []string {
+	matches, _ := filepath.Glob(filepath.Join(dir, ".import-*"))
+	return matches
+}
+
+func TestImportCasePackage(t *testing.T) {
+	source, path, cleanupSource := exportTestPackage(t)
+	defer cleanupSource()
+	target, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	trustSource(target, source, "County Sheriff")
+
+	result, err := target.ImportCasePackage(path, "PD-CLERK-9")
+	if err != nil {
+		t.Fatalf("ImportCasePackage failed: %v", err)
+	}
+	if result.Source != "County Sheriff" || result.CaseNumber != "CASE-IMP-001" || result.ExportedBy != "SO-CLERK-1" || len(result.EvidenceIDs) != 2 {
+		t.Fatalf("Unexpected import result: %+v", result)
+	}
+
+	for _, id := range result.EvidenceIDs {
+		evidence, err := target.GetEvidence(id)
+		if err != nil {
+			t.Fatalf("Imported evidence %s missing: %v", id, err)
+		}
+		if filepath.Dir(evidence.FilePath) != target.storagePath {
+			t.Errorf("Expected the recording in local storage, got %s", evidence.FilePath)
+		}
+		if valid, err := target.VerifyIntegrity(id, "PD-CLERK-9"); err != nil || !valid {
+			t.Errorf("Imported recording failed integrity: %v", err)
+		}
+
+		var imported *CustodyEntry
+		for i := range evidence.ChainOfCustody {
+			if evidence.ChainOfCustody[i].Action == "IMPORTED" {
+				imported = &evidence.ChainOfCustody[i]
+			}
+		}
+		if imported == nil || imported.FromOfficer != "County Sheriff" || imported.ToOfficer != "PD-CLERK-9" ||
+			!strings.Contains(imported.Purpose, result.KeyID) {
+			t.Errorf("Expected an IMPORTED custody entry naming the source, got %+v", evidence.ChainOfCustody)
+		}
+		if failed, _ := target.VerifyCustodySignatures(id); len(failed) != 0 {
+			t.Errorf("Custody entries failed verification: %v", failed)
+		}
+
+		logs := target.GetAuditLogs(id, "PD-CLERK-9")
+		if len(logs) == 0 || logs[0].Action != "IMPORT_EVIDENCE" {
+			t.Errorf("Expected IMPORT_EVIDENCE audit entry, got %v", logs)
+		}
+	}
+
+	if _, err := target.ImportCasePackage(path, "PD-CLERK-9"); err == nil {
+		t.Error("Expected a second import of the same evidence to be rejected")
+	}
+	if leftover := stagedFiles(target.storagePath); len(leftover) != 0 {
+		t.Errorf("Staging files left behind: %v", leftover)
+	}
+}
+
+func TestImportCasePackageRejections(t *testing.T) {
+	source, path, cleanupSource := exportTestPackage(t)
+	defer cleanupSource()
+	dir := filepath.Dir(path)
+
+	tamperedFile := filepath.Join(dir, "tampered-file.zip")
+	rewritePackage(t, path, tamperedFile, func(name string, data []byte) []byte {
+		if strings.HasPrefix(name, "files/") {
+			return append(data, '!')
+		}
+		return data
+	})
+	tamperedRecord := filepath.Join(dir, "tampered-record.zip")
+	rewritePackage(t, path, tamperedRecord, func(name string, data []byte) []byte {
+		if strings.HasPrefix(name, "evidence/") {
+			return bytes.Replace(data, []byte("Test Location"), []byte("Elsewhere"), 1)
+		}
+		return data
+	})
+	tamperedManifest := filepath.Join(dir, "tampered-manifest.zip")
+	rewritePackage(t, path, tamperedManifest, func(name string, data []byte) []byte {
+		if name == "manifest.json" {
+			return bytes.Replace(data, []byte("SO-CLERK-1"), []byte("SO-CLERK-2"), 1)
+		}
+		return data
+	})
+	unsigned := filepath.Join(dir, "unsigned.zip")
+	rewritePackage(t, path, unsigned, func(name string, data []byte) []byte {
+		if name == casePackageSignatureFile {
+			return []byte("{}")
+		}
+		return data
+	})
+
+	tests := []struct {
+		name    string
+		path    string
+		trusted bool
+		want    error
+	}{
+		{"untrusted source", path, false, errPackageUntrusted},
+		{"altered recording", tamperedFile, true, nil},
+		{"altered record", tamperedRecord, true, nil},
+		{"altered manifest", tamperedManifest, true, errPackageSignature},
+		{"missing signature", unsigned, true, nil},
+	}
+	for _, tt := range tests {
+		target, _, cleanup := setupTestSystem(t)
+		if tt.trusted {
+			trustSource(target, source, "County Sheriff")
+		}
+
+		_, err := target.ImportCasePackage(tt.path, "PD-CLERK-9")
+		if err == nil {
+			t.Errorf("%s: expected the import to be rejected", tt.name)
+		} else if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, err)
+		}
+		if n := len(target.SearchEvidence("", "", "")); n != 0 {
+			t.Errorf("%s: expected no records accepted, got %d", tt.name, n)
+		}
+		if leftover := stagedFiles(target.storagePath); len(leftover) != 0 {
+			t.Errorf("%s: staging files left behind: %v", tt.name, leftover)
+		}
+		logs := target.GetAuditLogs("", "PD-CLERK-9")
+		if len(logs) != 1 || logs[0].Action != "IMPORT_REJECTED" {
+			t.Errorf("%s: expected IMPORT_REJECTED audit entry, got %v", tt.name, logs)
+		}
+		cleanup()
+	}
+}
+
+func TestTrustedSourcesConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ChainOfCustody.TrustedSources = []TrustedSource{
+		{Name: "County Sheriff", PublicKey: strings.Repeat("ab", 32)},
+		{Name: "County Sheriff", PublicKey: "not hex"},
+		{PublicKey: strings.Repeat("ab", 31)},
+	}
+	err := cfg.Validate()
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 4 {
+		t.Fatalf("Expected 4 trusted source problems, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package bwc
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// casePackageFormat identifies the layout of a case package
+const casePackageFormat = "bwc-case-package/v1"
+
+// casePackageSignatureFile holds the PackageSignature over manifest.json
+const casePackageSignatureFile = "manifest.sig"
+
+// CasePackageItem lists one evidence item in a case package
+type CasePackageItem struct {
+	EvidenceID   string `json:"evidence_id"`
+	Record       string `json:"record"`
+	RecordSHA256 string `json:"record_sha256"`
+	File         string `json:"file"`
+	FileSHA256   string `json:"file_sha256"`
+	FileSize     int64  `json:"file_size"`
+}
+
+// CasePackageManifest is manifest.json at the root of a case package. The
+// package is a zip holding the manifest, its signature in manifest.sig, each
+// evidence record as evidence/<id>.json and each recording as files/<id><ext>.
+type CasePackageManifest struct {
+	Format       string            `json:"format"`
+	SourceSystem string            `json:"source_system"`
+	CaseNumber   string            `json:"case_number"`
+	ExportedAt   time.Time         `json:"exported_at"`
+	ExportedBy   string            `json:"exported_by"`
+	Purpose      string            `json:"purpose,omitempty"`
+	Evidence     []CasePackageItem `json:"evidence"`
+}
+
+// PackageSignature is the exporting system's Ed25519 signature over the exact
+// bytes of manifest.json. The manifest in turn pins every record and recording
+// by hash.
+type PackageSignature struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// packageSigningPayload is the message a package signature covers
+func packageSigningPayload(manifest []byte) []byte {
+	return append([]byte("BWC-PACKAGE-v1\n"), manifest...)
+}
+
+// ExportCasePackage writes every evidence record and recording in caseNumber
+// to a zip package at path, encrypted with enc when it is set. Each item is
+// registered as a copy. Recordings are re-hashed as they are packaged and the
+// export fails if any no longer matches its recorded hash. Sealed evidence
+// cannot be packaged. The manifest is signed with the system's sealing key.
+func (bwc *BWCSystem) ExportCasePackage(caseNumber, path, userID, purpose string, enc *ExportEncryption) (*CasePackageManifest, error) {
+	if err := bwc.authorize(userID, PermExport, "Case package export", ""); err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		if err := enc.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	// Snapshot the records so the recordings are copied without holding the lock
+	bwc.mu.Lock()
+	if err := bwc.checkExportPathLocked(path, "", userID); err != nil {
+		bwc.mu.Unlock()
+		return nil, err
+	}
+	evidence := make([]*Evidence, 0)
+	for _, ev := range bwc.evidenceDB.Search(nil) {
+		if ev.CaseNumber == caseNumber {
+			evidence = append(evidence, ev)
+		}
+	}
+	sort.Slice(evidence, func(i, j int) bool { return evidence[i].ID < evidence[j].ID })
+
+	records := make([][]byte, len(evidence))
+	for i, ev := range evidence {
+		if err := bwc.rejectIfSealedLocked(ev, userID, "Case package export"); err != nil {
+			bwc.mu.Unlock()
+			return nil, err
+		}
+		if err := bwc.rejectIfRestrictedLocked(ev, userID, "Case package export"); err != nil {
+			bwc.mu.Unlock()
+			return nil, err
+		}
+		// The package holds the recording decrypted; the data key is of no
+		// use to anyone else
+		packaged := *ev
+		packaged.Encryption = nil
+		data, err := json.MarshalIndent(&packaged, "", "  ")
+		if err != nil {
+			bwc.mu.Unlock()
+			return nil, fmt.Errorf("failed to marshal evidence: %w", err)
+		}
+		records[i] = data
+	}
+	snapshot := make([]Evidence, len(evidence))
+	for i, ev := range evidence {
+		snapshot[i] = *ev
+	}
+	bwc.mu.Unlock()
+
+	if len(snapshot) == 0 {
+		return nil, errors.New("case has no evidence")
+	}
+
+	manifest := &CasePackageManifest{
+		Format:       casePackageFormat,
+		SourceSystem: bwc.config.System.Name,
+		CaseNumber:   caseNumber,
+		ExportedAt:   time.Now().UTC(),
+		ExportedBy:   userID,
+		Purpose:      purpose,
+	}
+
+	hash, size, err := writeExportFile(path, enc, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		for i := range snapshot {
+			item, err := bwc.writeCasePackageItem(zw, &snapshot[i], records[i])
+			if err != nil {
+				return err
+			}
+			manifest.Evidence = append(manifest.Evidence, *item)
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		mw, err := zw.Create("manifest.json")
+		if err != nil {
+			return err
+		}
+		if _, err := mw.Write(data); err != nil {
+			return err
+		}
+
+		sig, err := json.MarshalIndent(PackageSignature{
+			KeyID:     bwc.sealer.keyID,
+			PublicKey: hex.EncodeToString(bwc.SealPublicKey()),
+			Signature: bwc.sealer.sign(packageSigningPayload(data)),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		sw, err := zw.Create(casePackageSignatureFile)
+		if err != nil {
+			return err
+		}
+		if _, err := sw.Write(sig); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write case package: %w", err)
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	for _, item := range manifest.Evidence {
+		record := bwc.registerCopyLocked(item.EvidenceID, CopyExport, userID, path, purpose, hash, size)
+		record.Encryption = enc.Describe()
+		bwc.logAudit(userID, "EXPORT_CASE_PACKAGE", item.EvidenceID, copyDetails(record), "")
+	}
+
+	return manifest, nil
+}
+
+// writeCasePackageItem adds one evidence record and its recording to zw. A
+// recording encrypted at rest is packaged decrypted.
+func (bwc *BWCSystem) writeCasePackageItem(zw *zip.Writer, ev *Evidence, record []byte) (*CasePackageItem, error) {
+	recordSum := sha256.Sum256(record)
+	item := &CasePackageItem{
+		EvidenceID:   ev.ID,
+		Record:       "evidence/" + ev.ID + ".json",
+		RecordSHA256: hex.EncodeToString(recordSum[:]),
+		File:         "files/" + ev.ID + filepath.Ext(ev.FilePath),
+	}
+
+	rw, err := zw.Create(item.Record)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rw.Write(record); err != nil {
+		return nil, err
+	}
+
+	src, err := bwc.openEvidenceFile(ev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", ev.ID, err)
+	}
+	defer src.Close()
+
+	// Recordings are already compressed
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: item.File, Method: zip.Store, Modified: ev.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(fw, h), src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to package %s: %w", ev.ID, err)
+	}
+
+	item.FileSHA256 = hex.EncodeToString(h.Sum(nil))
+	item.FileSize = n
+	if item.FileSHA256 != ev.FileHash {
+		return nil, fmt.Errorf("%s no longer matches its recorded hash", ev.ID)
+	}
+	return item, nil
+}
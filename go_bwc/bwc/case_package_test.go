@@ -0,0 +1,141 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readCasePackage opens a package and checks its manifest against the contents
+func readCasePackage(t *testing.T, data []byte) *CasePackageManifest {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Package is not a zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest CasePackageManifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	for _, item := range manifest.Evidence {
+		recordSum := sha256.Sum256(files[item.Record])
+		fileSum := sha256.Sum256(files[item.File])
+		if hex.EncodeToString(recordSum[:]) != item.RecordSHA256 || hex.EncodeToString(fileSum[:]) != item.FileSHA256 {
+			t.Errorf("Package contents of %s do not match the manifest", item.EvidenceID)
+		}
+	}
+	return &manifest
+}
+
+func TestExportCasePackage(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	first, _ := system.IngestEvidence(testFile, "CASE-PKG-001", "OFF-701", "Officer Test", "Test Location", nil)
+	second, _ := system.IngestEvidence(testFile, "CASE-PKG-001", "OFF-702", "Officer Test", "Test Location", nil)
+	system.IngestEvidence(testFile, "CASE-PKG-002", "OFF-703", "Officer Test", "Test Location", nil)
+
+	path := filepath.Join(tmpDir, "case.zip")
+	manifest, err := system.ExportCasePackage("CASE-PKG-001", path, "DA-CLERK-1", "Discovery", nil)
+	if err != nil {
+		t.Fatalf("ExportCasePackage failed: %v", err)
+	}
+	if manifest.Format != casePackageFormat || len(manifest.Evidence) != 2 {
+		t.Fatalf("Unexpected manifest: %+v", manifest)
+	}
+
+	data, _ := os.ReadFile(path)
+	read := readCasePackage(t, data)
+	if len(read.Evidence) != 2 || read.Evidence[0].FileSHA256 != first.FileHash || read.Evidence[0].FileSize != first.FileSize {
+		t.Errorf("Unexpected packaged manifest: %+v", read)
+	}
+
+	sum := sha256.Sum256(data)
+	for _, ev := range []*Evidence{first, second} {
+		copies := system.CopiesOf(ev.ID)
+		if len(copies) != 1 || copies[0].SHA256 != hex.EncodeToString(sum[:]) || copies[0].Encryption != "" {
+			t.Errorf("Unexpected copies of %s: %+v", ev.ID, copies)
+		}
+		logs := system.GetAuditLogs(ev.ID, "DA-CLERK-1")
+		if len(logs) != 1 || logs[0].Action != "EXPORT_CASE_PACKAGE" {
+			t.Errorf("Expected EXPORT_CASE_PACKAGE for %s, got %v", ev.ID, logs)
+		}
+	}
+
+	if _, err := system.ExportCasePackage("CASE-NONE", filepath.Join(tmpDir, "none.zip"), "DA-CLERK-1", "", nil); err == nil {
+		t.Error("Expected an error for a case without evidence")
+	}
+}
+
+func TestExportCasePackageEncrypted(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	defer func(w int) { ageScryptWorkFactor = w }(ageScryptWorkFactor)
+	ageScryptWorkFactor = 10
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-PKG-003", "OFF-704", "Officer Test", "Test Location", nil)
+
+	path := filepath.Join(tmpDir, "case.zip.age")
+	if _, err := system.ExportCasePackage("CASE-PKG-003", path, "DA-CLERK-1", "Courier to county", &ExportEncryption{Passphrase: "correct horse battery"}); err != nil {
+		t.Fatalf("ExportCasePackage failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	plain, err := ageDecrypt(t, data, nil, "correct horse battery")
+	if err != nil {
+		t.Fatalf("Failed to decrypt package: %v", err)
+	}
+	if manifest := readCasePackage(t, plain); len(manifest.Evidence) != 1 || manifest.Evidence[0].EvidenceID != evidence.ID {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	copies := system.CopiesOf(evidence.ID)
+	if len(copies) != 1 || copies[0].Encryption != "age passphrase" {
+		t.Errorf("Unexpected copies: %+v", copies)
+	}
+}
+
+func TestExportCasePackageRefusals(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-PKG-004", "OFF-705", "Officer Test", "Test Location", nil)
+
+	// A recording altered in storage is not shipped
+	os.WriteFile(evidence.FilePath, []byte("altered"), 0600)
+	path := filepath.Join(tmpDir, "case.zip")
+	if _, err := system.ExportCasePackage("CASE-PKG-004", path, "DA-CLERK-1", "", nil); err == nil {
+		t.Error("Expected a hash mismatch to fail the export")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected the partial package to be removed")
+	}
+
+	sealed, _ := system.IngestEvidence(testFile, "CASE-PKG-005", "OFF-706", "Officer Test", "Test Location", nil)
+	if _, err := system.SealEvidence(sealed.ID, "Court Order 2025-CR-0101"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if _, err := system.ExportCasePackage("CASE-PKG-005", path, "DA-CLERK-1", "", nil); err == nil {
+		t.Error("Expected sealed evidence to be refused")
+	}
+	if len(system.CopiesOf(evidence.ID)) != 0 || len(system.CopiesOf(sealed.ID)) != 0 {
+		t.Error("Expected no copies registered for refused exports")
+	}
+}
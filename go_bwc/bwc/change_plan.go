@@ -0,0 +1,235 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ChangePlan reports what a bulk or destructive operation changed or, for a
+// dry run, exactly what it would change. A dry run mutates nothing, not even
+// the audit log.
+type ChangePlan struct {
+	Operation string          `json:"operation"`
+	DryRun    bool            `json:"dry_run"`
+	Items     []PlannedChange `json:"items"`
+	// Skipped lists items the operation leaves alone, with the reason
+	Skipped    []PlannedChange `json:"skipped,omitempty"`
+	TotalBytes int64           `json:"total_bytes"`
+}
+
+// PlannedChange is the effect of an operation on one evidence item
+type PlannedChange struct {
+	EvidenceID string         `json:"evidence_id"`
+	CaseNumber string         `json:"case_number,omitempty"`
+	FromStatus EvidenceStatus `json:"from_status,omitempty"`
+	ToStatus   EvidenceStatus `json:"to_status,omitempty"`
+	// Files are removed from storage; Bytes is their total size
+	Files []string `json:"files,omitempty"`
+	Bytes int64    `json:"bytes,omitempty"`
+	// Tags are the tags added or removed
+	Tags []string `json:"tags,omitempty"`
+	// CustodyAction is the custody entry the change appends, if any
+	CustodyAction string `json:"custody_action,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+func (p *ChangePlan) add(change PlannedChange) {
+	p.Items = append(p.Items, change)
+	p.TotalBytes += change.Bytes
+}
+
+func (p *ChangePlan) skip(evidenceID, reason string) {
+	p.Skipped = append(p.Skipped, PlannedChange{EvidenceID: evidenceID, Reason: reason})
+}
+
+// sealedSkipLocked returns why sealed evidence is left out of a bulk
+// operation, or "" if it is not sealed. The refusal is audited unless this is
+// a dry run. The caller must hold bwc.mu.
+func (bwc *BWCSystem) sealedSkipLocked(evidence *Evidence, userID, operation string, dryRun bool) string {
+	if evidence.Seal == nil {
+		return ""
+	}
+	if !dryRun {
+		bwc.rejectIfSealedLocked(evidence, userID, operation)
+	}
+	return "sealed under " + evidence.Seal.Authority
+}
+
+// EvidenceSelection picks the items a batch operation applies to: the listed
+// IDs or, when none are listed, every item matching the search criteria
+type EvidenceSelection struct {
+	EvidenceIDs []string       `json:"evidence_ids,omitempty"`
+	CaseNumber  string         `json:"case_number,omitempty"`
+	OfficerID   string         `json:"officer_id,omitempty"`
+	Status      EvidenceStatus `json:"status,omitempty"`
+}
+
+// resolveSelection returns the evidence IDs selected by userID. A search
+// without criteria is refused rather than selecting everything, and leaves out
+// restricted evidence userID is not on the access list of.
+func (bwc *BWCSystem) resolveSelection(selection EvidenceSelection, userID string) ([]string, error) {
+	if len(selection.EvidenceIDs) > 0 {
+		return selection.EvidenceIDs, nil
+	}
+	if selection.CaseNumber == "" && selection.OfficerID == "" && selection.Status == "" {
+		return nil, errors.New("no evidence selected")
+	}
+
+	results, _ := bwc.searchEvidence(systemContext(context.Background()), selection.CaseNumber, selection.OfficerID, selection.Status)
+	ids := make([]string, 0, len(results))
+	for _, evidence := range results {
+		if evidence.ACL.allows(userID) {
+			ids = append(ids, evidence.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("no evidence matches the selection")
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// statusTransitions lists the statuses each status may move to. DELETED is
+// final; retention purges set it directly.
+var statusTransitions = map[EvidenceStatus][]EvidenceStatus{
+	StatusCollected:  {StatusProcessing, StatusAnalyzed, StatusArchived},
+	StatusProcessing: {StatusAnalyzed, StatusArchived},
+	StatusAnalyzed:   {StatusProcessing, StatusArchived},
+	StatusArchived:   {StatusAnalyzed, StatusDeleted},
+}
+
+// checkStatusTransition reports whether evidence may move from one status to another
+func checkStatusTransition(from, to EvidenceStatus) error {
+	for _, allowed := range statusTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return &BWCError{Code: CodeInvalidTransition, Message: fmt.Sprintf("cannot change status from %s to %s", from, to)}
+}
+
+// BulkUpdateStatus sets newStatus on every listed item that can take it. Items
+// that are missing, sealed or already in that status are skipped. With dryRun
+// set nothing is changed and the plan shows what would be.
+func (bwc *BWCSystem) BulkUpdateStatus(evidenceIDs []string, officerID string, newStatus EvidenceStatus, notes string, dryRun bool) (*ChangePlan, error) {
+	return bwc.UpdateStatusBatch(EvidenceSelection{EvidenceIDs: evidenceIDs}, officerID, newStatus, notes, dryRun)
+}
+
+// UpdateStatusBatch sets newStatus on the selected items, such as every item
+// in a closed case. Each item is checked on its own: the plan lists the items
+// changed and, with the reason, those that were not because they are missing,
+// sealed, restricted from officerID, already in that status or cannot move
+// to it.
+func (bwc *BWCSystem) UpdateStatusBatch(selection EvidenceSelection, officerID string, newStatus EvidenceStatus, notes string, dryRun bool) (*ChangePlan, error) {
+	if err := bwc.authorize(officerID, statusPermission(newStatus), "Batch status update", ""); err != nil {
+		return nil, err
+	}
+	if _, known := statusTransitions[newStatus]; !known && newStatus != StatusDeleted {
+		return nil, fmt.Errorf("unknown status %q", newStatus)
+	}
+	if newStatus == StatusDeleted && !dryRun {
+		if err := bwc.requireNoApproval(officerID, "Batch deletion", ""); err != nil {
+			return nil, err
+		}
+	}
+	evidenceIDs, err := bwc.resolveSelection(selection, officerID)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := bwc.beginOperation(opMutation); err != nil {
+			return nil, err
+		}
+		defer bwc.endOperation()
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	plan := &ChangePlan{Operation: "bulk status update", DryRun: dryRun, Items: make([]PlannedChange, 0)}
+	seen := make(map[string]bool)
+	for _, id := range evidenceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		evidence := bwc.evidenceDB.Get(id)
+		switch {
+		case evidence == nil:
+			plan.skip(id, "evidence not found")
+		case bwc.rejectIfRestrictedLocked(evidence, officerID, "Status update") != nil:
+			plan.skip(id, "restricted")
+		case evidence.Status == newStatus:
+			plan.skip(id, "already "+string(newStatus))
+		default:
+			if err := checkStatusTransition(evidence.Status, newStatus); err != nil {
+				plan.skip(id, err.Error())
+				continue
+			}
+			if reason := bwc.sealedSkipLocked(evidence, officerID, "Status update", dryRun); reason != "" {
+				plan.skip(id, reason)
+				continue
+			}
+			plan.add(PlannedChange{
+				EvidenceID: id,
+				CaseNumber: evidence.CaseNumber,
+				FromStatus: evidence.Status,
+				ToStatus:   newStatus,
+			})
+		}
+	}
+
+	if !dryRun {
+		for _, change := range plan.Items {
+			if err := bwc.updateStatusLocked(bwc.evidenceDB.Get(change.EvidenceID), officerID, newStatus, notes, nil); err != nil {
+				return nil, fmt.Errorf("%s: %w", change.EvidenceID, err)
+			}
+		}
+		bwc.logAudit(officerID, "BULK_UPDATE_STATUS", "",
+			fmt.Sprintf("%d items set to %s, %d skipped", len(plan.Items), newStatus, len(plan.Skipped)), "")
+	}
+	return plan, nil
+}
+
+// storedFiles lists the files kept in storage for evidence: the recording,
+// its parity file, a photo thumbnail, extracted document text and processing
+// outputs. Files that no longer exist are left out.
+func (bwc *BWCSystem) storedFiles(evidence *Evidence) ([]string, int64) {
+	candidates := []string{evidence.FilePath}
+	if evidence.Parity != nil {
+		candidates = append(candidates, evidence.Parity.Path)
+	}
+	if evidence.Photo != nil && evidence.Photo.Thumbnail != nil {
+		candidates = append(candidates, evidence.Photo.Thumbnail.Path)
+	}
+	if evidence.Document != nil && evidence.Document.Text != nil {
+		candidates = append(candidates, evidence.Document.Text.Path)
+	}
+	for _, result := range evidence.Processing {
+		for _, out := range result.Outputs {
+			candidates = append(candidates, out.Path)
+		}
+	}
+
+	files := make([]string, 0, len(candidates))
+	var total int64
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		files = append(files, path)
+		total += info.Size()
+	}
+	return files, total
+}
+
+// derivedDir is where processing outputs for evidence are kept
+func (bwc *BWCSystem) derivedDir(evidenceID string) string {
+	return filepath.Join(bwc.storagePath, "derived", evidenceID)
+}
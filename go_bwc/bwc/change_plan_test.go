@@ -0,0 +1,129 @@
+package bwc
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestBulkUpdateStatusDryRun(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	a, _ := system.IngestEvidence(testFile, "CASE-BLK-001", "OFF-941", "Officer Test", "Test Location", nil)
+	b, _ := system.IngestEvidence(testFile, "CASE-BLK-001", "OFF-942", "Officer Test", "Test Location", nil)
+	sealed, _ := system.IngestEvidence(testFile, "CASE-BLK-001", "OFF-943", "Officer Test", "Test Location", nil)
+	done, _ := system.IngestEvidence(testFile, "CASE-BLK-001", "OFF-944", "Officer Test", "Test Location", nil)
+	system.SealEvidence(sealed.ID, "Court order 24-201")
+	system.UpdateStatus(done.ID, "DET-1", StatusArchived, "Closed")
+
+	ids := []string{a.ID, b.ID, sealed.ID, done.ID, "BWC-MISSING", a.ID}
+	auditCount := len(system.GetAuditLogs("", ""))
+
+	preview, err := system.BulkUpdateStatus(ids, "DET-1", StatusArchived, "Case closed", true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if !preview.DryRun || len(preview.Items) != 2 || len(preview.Skipped) != 3 {
+		t.Fatalf("Unexpected plan %+v", preview)
+	}
+	if preview.Items[0].FromStatus != StatusCollected || preview.Items[0].ToStatus != StatusArchived {
+		t.Errorf("Unexpected planned change %+v", preview.Items[0])
+	}
+	if a.Status != StatusCollected || b.Status != StatusCollected {
+		t.Error("Expected a dry run to change nothing")
+	}
+	if n := len(system.GetAuditLogs("", "")); n != auditCount {
+		t.Errorf("Expected a dry run to write no audit entries, got %d new", n-auditCount)
+	}
+
+	plan, err := system.BulkUpdateStatus(ids, "DET-1", StatusArchived, "Case closed", false)
+	if err != nil {
+		t.Fatalf("BulkUpdateStatus failed: %v", err)
+	}
+	if !reflect.DeepEqual(plan.Items, preview.Items) || !reflect.DeepEqual(plan.Skipped, preview.Skipped) {
+		t.Errorf("Expected the run to match its dry run:\n%+v\n%+v", plan, preview)
+	}
+	if a.Status != StatusArchived || b.Status != StatusArchived || sealed.Status != StatusCollected {
+		t.Error("Expected only the unsealed items archived")
+	}
+
+	actions := make(map[string]int)
+	for _, log := range system.GetAuditLogs("", "DET-1") {
+		actions[log.Action]++
+	}
+	if actions["UPDATE_STATUS"] != 3 || actions["BULK_UPDATE_STATUS"] != 1 || actions["SEALED_ACCESS_DENIED"] != 1 {
+		t.Errorf("Unexpected audit entries %v", actions)
+	}
+}
+
+func TestUpdateStatusBatch(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	a, _ := system.IngestEvidence(testFile, "CASE-BLK-002", "OFF-951", "Officer Test", "Test Location", nil)
+	b, _ := system.IngestEvidence(testFile, "CASE-BLK-002", "OFF-952", "Officer Test", "Test Location", nil)
+	c, _ := system.IngestEvidence(testFile, "CASE-BLK-002", "OFF-953", "Officer Test", "Test Location", nil)
+	other, _ := system.IngestEvidence(testFile, "CASE-BLK-003", "OFF-954", "Officer Test", "Test Location", nil)
+	system.UpdateStatus(b.ID, "DET-1", StatusArchived, "Closed")
+
+	plan, err := system.UpdateStatusBatch(EvidenceSelection{CaseNumber: "CASE-BLK-002"}, "DET-1", StatusDeleted, "Destroyed", false)
+	if err != nil {
+		t.Fatalf("UpdateStatusBatch failed: %v", err)
+	}
+	if len(plan.Items) != 1 || plan.Items[0].EvidenceID != b.ID || len(plan.Skipped) != 2 {
+		t.Fatalf("Expected only the archived item deleted, got %+v", plan)
+	}
+	if want := "cannot change status from COLLECTED to DELETED"; plan.Skipped[0].Reason != want {
+		t.Errorf("Expected reason %q, got %q", want, plan.Skipped[0].Reason)
+	}
+	if a.Status != StatusCollected || c.Status != StatusCollected || b.Status != StatusDeleted {
+		t.Error("Expected invalid transitions to leave items unchanged")
+	}
+
+	plan, _ = system.UpdateStatusBatch(EvidenceSelection{CaseNumber: "CASE-BLK-002", Status: StatusCollected}, "DET-1", StatusArchived, "Closed", false)
+	if len(plan.Items) != 2 || plan.Items[0].EvidenceID > plan.Items[1].EvidenceID || other.Status != StatusCollected {
+		t.Errorf("Expected the matching items archived in ID order, got %+v", plan)
+	}
+	if plan, _ := system.BulkUpdateStatus([]string{b.ID}, "DET-1", StatusArchived, "Restore", false); len(plan.Skipped) != 1 {
+		t.Errorf("Expected DELETED to be final, got %+v", plan)
+	}
+
+	for _, sel := range []EvidenceSelection{{}, {CaseNumber: "CASE-NONE"}} {
+		if _, err := system.UpdateStatusBatch(sel, "DET-1", StatusArchived, "", true); err == nil {
+			t.Errorf("Expected selection %+v to be refused", sel)
+		}
+	}
+	if _, err := system.UpdateStatusBatch(EvidenceSelection{EvidenceIDs: []string{a.ID}}, "DET-1", "LOST", "", true); err == nil {
+		t.Error("Expected an unknown status to be refused")
+	}
+}
+
+func TestUpdateStatusFollowsTransitions(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-BLK-006", "OFF-961", "Officer Test", "Test Location", nil)
+	if err := system.UpdateStatus(evidence.ID, "DET-1", StatusDeleted, "Destroyed"); ErrorCodeOf(err) != CodeInvalidTransition {
+		t.Errorf("Expected COLLECTED to DELETED to be refused, got %v", err)
+	}
+	system.UpdateStatus(evidence.ID, "DET-1", StatusArchived, "Closed")
+	if err := system.UpdateStatus(evidence.ID, "DET-1", StatusDeleted, "Destroyed"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+
+	// DELETED is final
+	if err := system.UpdateStatus(evidence.ID, "DET-1", StatusCollected, "Restored"); ErrorCodeOf(err) != CodeInvalidTransition {
+		t.Errorf("Expected DELETED to COLLECTED to be refused, got %v", err)
+	}
+	resp := authPostJSON(t, server, "/api/evidence/"+evidence.ID+"/status", `{"status": "COLLECTED"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a deleted item's status change, got %d", resp.StatusCode)
+	}
+	if got, _ := system.GetEvidence(evidence.ID); got.Status != StatusDeleted {
+		t.Errorf("Expected the evidence to stay deleted, got %s", got.Status)
+	}
+}
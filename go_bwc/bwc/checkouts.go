@@ -0,0 +1,234 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Checkout records evidence temporarily released from the property room,
+// e.g. to court or a lab, that is expected back
+type Checkout struct {
+	EvidenceID   string    `json:"evidence_id"`
+	CheckedOutBy string    `json:"checked_out_by"`
+	CheckedOutTo string    `json:"checked_out_to"`
+	Purpose      string    `json:"purpose"`
+	CheckedOutAt time.Time `json:"checked_out_at"`
+}
+
+// ScanResult is what a counter operator sees after scanning an evidence label
+type ScanResult struct {
+	Evidence         *Evidence       `json:"evidence"`
+	CurrentCustodian string          `json:"current_custodian"`
+	Checkout         *Checkout       `json:"checkout,omitempty"`
+	PendingRequest   *CustodyRequest `json:"pending_request,omitempty"`
+}
+
+// ScanLabel resolves a scanned label code to its evidence record and custody state
+func (bwc *BWCSystem) ScanLabel(code, userID string) (*ScanResult, error) {
+	return bwc.ScanLabelContext(context.Background(), code, userID)
+}
+
+// ScanLabelContext resolves a label like ScanLabel for the principal ctx
+// carries, when it has one
+func (bwc *BWCSystem) ScanLabelContext(ctx context.Context, code, userID string) (*ScanResult, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermViewEvidence, "Label scan", ""); err != nil {
+		return nil, err
+	}
+	evidenceID, err := ParseLabelCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := bwc.scanState(evidenceID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bwc.logAuditAs(auth, userID, "SCAN_LABEL", evidenceID, "Evidence label scanned", "")
+
+	return result, nil
+}
+
+// scanState snapshots the custody state of evidence for userID, who must be
+// on its access list if it is restricted
+func (bwc *BWCSystem) scanState(evidenceID, userID string) (*ScanResult, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfRestrictedLocked(evidence, userID, "Scan label"); err != nil {
+		return nil, err
+	}
+
+	ev := copyEvidence(evidence)
+	result := &ScanResult{
+		Evidence:         &ev,
+		CurrentCustodian: currentCustodian(evidence),
+	}
+	if checkout, out := bwc.checkouts[evidenceID]; out {
+		c := *checkout
+		result.Checkout = &c
+	}
+	for _, req := range bwc.custodyRequests {
+		if req.EvidenceID == evidenceID && req.Status == RequestPending {
+			r := *req
+			result.PendingRequest = &r
+		}
+	}
+
+	return result, nil
+}
+
+// currentCustodian returns who holds evidence according to its chain of custody
+func currentCustodian(evidence *Evidence) string {
+	if n := len(evidence.ChainOfCustody); n > 0 {
+		return evidence.ChainOfCustody[n-1].ToOfficer
+	}
+	return evidence.OfficerID
+}
+
+// CheckOutEvidence releases evidence from custodianID to recipientID until it is checked in.
+// Integrity is verified and the release is recorded in the chain of custody.
+func (bwc *BWCSystem) CheckOutEvidence(evidenceID, custodianID, recipientID, purpose string) (*Checkout, error) {
+	return bwc.CheckOutEvidenceSigned(evidenceID, custodianID, recipientID, purpose, nil)
+}
+
+// CheckOutEvidenceSigned checks out evidence with an electronic signature on the custody entry
+func (bwc *BWCSystem) CheckOutEvidenceSigned(evidenceID, custodianID, recipientID, purpose string, sig *CustodySignature) (*Checkout, error) {
+	return bwc.CheckOutEvidenceSignedContext(context.Background(), evidenceID, custodianID, recipientID, purpose, sig)
+}
+
+// CheckOutEvidenceSignedContext checks out like CheckOutEvidenceSigned, from
+// the principal ctx carries when it has one
+func (bwc *BWCSystem) CheckOutEvidenceSignedContext(ctx context.Context, evidenceID, custodianID, recipientID, purpose string, sig *CustodySignature) (*Checkout, error) {
+	custodianID, auth := actingUser(ctx, custodianID)
+	if err := bwc.authorize(custodianID, PermTransfer, "Check out", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	if recipientID == "" || purpose == "" {
+		return nil, errors.New("recipient and purpose are required to check out evidence")
+	}
+	if err := ValidateOfficerID(recipientID); err != nil {
+		return nil, err
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, custodianID, "Check-out"); err != nil {
+		return nil, err
+	}
+	if evidence.Status == StatusDeleted {
+		return nil, errors.New("deleted evidence cannot be checked out")
+	}
+	if checkout, out := bwc.checkouts[evidenceID]; out {
+		return nil, fmt.Errorf("evidence is already checked out to %s", checkout.CheckedOutTo)
+	}
+
+	if err := bwc.recordCustodyLocked(evidence, custodianID, recipientID, "CHECKED_OUT", purpose, sig, auth); err != nil {
+		return nil, err
+	}
+
+	checkout := &Checkout{
+		EvidenceID:   evidenceID,
+		CheckedOutBy: custodianID,
+		CheckedOutTo: recipientID,
+		Purpose:      purpose,
+		CheckedOutAt: time.Now(),
+	}
+	bwc.checkouts[evidenceID] = checkout
+
+	bwc.logAuditAs(auth, custodianID, "CHECK_OUT_EVIDENCE", evidenceID,
+		fmt.Sprintf("Checked out to %s - %s", recipientID, purpose), "")
+
+	bwc.publishEvidenceChange(EventCustodyTransferred, evidence, custodianID, EvidenceChange{
+		FromOfficer: custodianID,
+		ToOfficer:   recipientID,
+		Purpose:     purpose,
+	})
+
+	c := *checkout
+	return &c, nil
+}
+
+// CheckInEvidence returns checked-out evidence to custodianID, verifying integrity on return
+func (bwc *BWCSystem) CheckInEvidence(evidenceID, custodianID string) error {
+	return bwc.CheckInEvidenceSigned(evidenceID, custodianID, nil)
+}
+
+// CheckInEvidenceSigned checks in evidence with an electronic signature on the custody entry
+func (bwc *BWCSystem) CheckInEvidenceSigned(evidenceID, custodianID string, sig *CustodySignature) error {
+	return bwc.CheckInEvidenceSignedContext(context.Background(), evidenceID, custodianID, sig)
+}
+
+// CheckInEvidenceSignedContext checks in like CheckInEvidenceSigned, to the
+// principal ctx carries when it has one
+func (bwc *BWCSystem) CheckInEvidenceSignedContext(ctx context.Context, evidenceID, custodianID string, sig *CustodySignature) error {
+	custodianID, auth := actingUser(ctx, custodianID)
+	if err := bwc.authorize(custodianID, PermTransfer, "Check in", evidenceID); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	checkout, out := bwc.checkouts[evidenceID]
+	if !out {
+		return errors.New("evidence is not checked out")
+	}
+
+	if err := bwc.recordCustodyLocked(evidence, checkout.CheckedOutTo, custodianID, "CHECKED_IN", "Returned: "+checkout.Purpose, sig, auth); err != nil {
+		return err
+	}
+	delete(bwc.checkouts, evidenceID)
+
+	bwc.logAuditAs(auth, custodianID, "CHECK_IN_EVIDENCE", evidenceID,
+		fmt.Sprintf("Checked in from %s after %s", checkout.CheckedOutTo, time.Since(checkout.CheckedOutAt).Round(time.Minute)), "")
+
+	bwc.publishEvidenceChange(EventCustodyTransferred, evidence, custodianID, EvidenceChange{
+		FromOfficer: checkout.CheckedOutTo,
+		ToOfficer:   custodianID,
+		Purpose:     "Returned: " + checkout.Purpose,
+	})
+
+	return nil
+}
+
+// CheckedOutEvidence lists outstanding checkouts, oldest first
+func (bwc *BWCSystem) CheckedOutEvidence() []Checkout {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	checkouts := make([]Checkout, 0, len(bwc.checkouts))
+	for _, c := range bwc.checkouts {
+		checkouts = append(checkouts, *c)
+	}
+	sort.Slice(checkouts, func(i, j int) bool {
+		return checkouts[i].CheckedOutAt.Before(checkouts[j].CheckedOutAt)
+	})
+	return checkouts
+}
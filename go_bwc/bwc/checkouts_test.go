@@ -0,0 +1,92 @@
+package bwc
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScanLabel(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SCAN-001", "OFF-123", "Officer Test", "Test Location", nil)
+	system.TransferCustody(evidence.ID, "OFF-123", "CUS-001", "Property room intake")
+
+	result, err := system.ScanLabel("https://evidence.example.gov/verify/"+evidence.ID, "CUS-001")
+	if err != nil {
+		t.Fatalf("ScanLabel failed: %v", err)
+	}
+	if result.Evidence.ID != evidence.ID || result.CurrentCustodian != "CUS-001" {
+		t.Errorf("Unexpected scan result: %+v", result)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "CUS-001")
+	if len(logs) != 1 || logs[0].Action != "SCAN_LABEL" {
+		t.Errorf("Expected SCAN_LABEL audit entry, got %v", logs)
+	}
+
+	if _, err := system.ScanLabel("BWC-UNKNOWN", "CUS-001"); err == nil {
+		t.Error("Expected error for unknown evidence")
+	}
+}
+
+func TestCheckOutAndCheckIn(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SCAN-002", "OFF-123", "Officer Test", "Test Location", nil)
+
+	checkout, err := system.CheckOutEvidence(evidence.ID, "CUS-001", "COURT-7", "Trial exhibit")
+	if err != nil {
+		t.Fatalf("CheckOutEvidence failed: %v", err)
+	}
+	if checkout.CheckedOutTo != "COURT-7" {
+		t.Errorf("Unexpected checkout: %+v", checkout)
+	}
+
+	if _, err := system.CheckOutEvidence(evidence.ID, "CUS-001", "LAB-1", "Analysis"); err == nil {
+		t.Error("Expected error checking out evidence twice")
+	}
+	if err := system.TransferCustody(evidence.ID, "CUS-001", "DET-456", "Analysis"); err == nil {
+		t.Error("Expected transfer of checked-out evidence to be refused")
+	}
+	if out := system.CheckedOutEvidence(); len(out) != 1 || out[0].EvidenceID != evidence.ID {
+		t.Errorf("Expected one outstanding checkout, got %v", out)
+	}
+
+	if err := system.CheckInEvidence(evidence.ID, "CUS-001"); err != nil {
+		t.Fatalf("CheckInEvidence failed: %v", err)
+	}
+	if err := system.CheckInEvidence(evidence.ID, "CUS-001"); err == nil {
+		t.Error("Expected error checking in evidence that is not checked out")
+	}
+
+	chain, _ := system.GetChainOfCustody(evidence.ID)
+	if len(chain) != 3 || chain[1].Action != "CHECKED_OUT" || chain[2].Action != "CHECKED_IN" || chain[2].FromOfficer != "COURT-7" {
+		t.Errorf("Unexpected chain of custody: %+v", chain)
+	}
+	if len(system.CheckedOutEvidence()) != 0 {
+		t.Error("Expected no outstanding checkouts")
+	}
+}
+
+func TestCheckOutRequiresIntegrity(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SCAN-003", "OFF-123", "Officer Test", "Test Location", nil)
+	os.WriteFile(evidence.FilePath, []byte("TAMPERED"), 0600)
+
+	_, err := system.CheckOutEvidence(evidence.ID, "CUS-001", "COURT-7", "Trial exhibit")
+	if err == nil || !strings.Contains(err.Error(), "integrity") {
+		t.Errorf("Expected integrity failure, got %v", err)
+	}
+
+	if _, err := system.CheckOutEvidence(evidence.ID, "CUS-001", "", ""); err == nil {
+		t.Error("Expected error without recipient and purpose")
+	}
+}
@@ -0,0 +1,165 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// ChunkManifest holds the SHA-256 of each fixed-size block of an evidence
+// file. A file hash only shows that something changed; the chunk hashes show
+// which byte ranges did.
+type ChunkManifest struct {
+	ChunkSize int64    `json:"chunk_size"`
+	Hashes    []string `json:"hashes"`
+}
+
+// ByteRange is an inclusive range of byte offsets in a file
+type ByteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// evidenceChunkSize is the configured chunk size in bytes, or 0 when chunk hashing is off
+func (bwc *BWCSystem) evidenceChunkSize() int64 {
+	return int64(bwc.config.Integrity.ChunkSizeMB) << 20
+}
+
+// hashFileChunks returns the SHA-256 of the file at path and, when chunkSize is
+// positive, its chunk manifest, reading the file once
+func hashFileChunks(path string, chunkSize int64) (string, *ChunkManifest, error) {
+	return hashFileChunksVia(path, chunkSize, nil)
+}
+
+// hashFileChunksVia is hashFileChunks reading through wrap when it is set
+func hashFileChunksVia(path string, chunkSize int64, wrap func(io.Reader) io.Reader) (string, *ChunkManifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if wrap != nil {
+		r = wrap(file)
+	}
+	total := sha256.New()
+	if chunkSize <= 0 {
+		if _, err := io.Copy(total, r); err != nil {
+			return "", nil, err
+		}
+		return hex.EncodeToString(total.Sum(nil)), nil, nil
+	}
+
+	manifest := &ChunkManifest{ChunkSize: chunkSize, Hashes: make([]string, 0)}
+	if err := readChunks(r, chunkSize, total, func(sum string) {
+		manifest.Hashes = append(manifest.Hashes, sum)
+	}); err != nil {
+		return "", nil, err
+	}
+	return hex.EncodeToString(total.Sum(nil)), manifest, nil
+}
+
+// readChunks hashes r in chunkSize blocks, also feeding every byte to total
+func readChunks(r io.Reader, chunkSize int64, total hash.Hash, chunk func(sum string)) error {
+	for {
+		h := sha256.New()
+		n, err := io.CopyN(io.MultiWriter(h, total), r, chunkSize)
+		if n > 0 {
+			chunk(hex.EncodeToString(h.Sum(nil)))
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// corruptRanges rehashes the file at path and returns its SHA-256 together
+// with the byte ranges whose chunks no longer match the manifest. Adjacent
+// corrupted chunks are merged. originalSize is the file's size at ingest, so
+// truncation and appended data are reported too.
+func (m *ChunkManifest) corruptRanges(path string, originalSize int64) (string, []ByteRange, error) {
+	return m.corruptRangesVia(path, originalSize, nil)
+}
+
+// corruptRangesVia is corruptRanges, reading through wrap when it is set
+func (m *ChunkManifest) corruptRangesVia(path string, originalSize int64, wrap func(io.Reader) io.Reader) (string, []ByteRange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if wrap != nil {
+		r = wrap(file)
+	}
+	return m.corruptRangesIn(r, originalSize)
+}
+
+// corruptRangesIn is corruptRanges for the file r yields
+func (m *ChunkManifest) corruptRangesIn(r io.Reader, originalSize int64) (string, []ByteRange, error) {
+	total := sha256.New()
+	current := make([]string, 0, len(m.Hashes))
+	var currentSize int64
+	if err := readChunks(&countingReader{r: r, n: &currentSize}, m.ChunkSize, total, func(sum string) {
+		current = append(current, sum)
+	}); err != nil {
+		return "", nil, err
+	}
+
+	size := originalSize
+	if currentSize > size {
+		size = currentSize
+	}
+	chunks := len(m.Hashes)
+	if len(current) > chunks {
+		chunks = len(current)
+	}
+
+	ranges := make([]ByteRange, 0)
+	for i := 0; i < chunks; i++ {
+		if i < len(m.Hashes) && i < len(current) && m.Hashes[i] == current[i] {
+			continue
+		}
+		start := int64(i) * m.ChunkSize
+		end := start + m.ChunkSize - 1
+		if end > size-1 {
+			end = size - 1
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].End+1 == start {
+			ranges[n-1].End = end
+		} else {
+			ranges = append(ranges, ByteRange{Start: start, End: end})
+		}
+	}
+	return hex.EncodeToString(total.Sum(nil)), ranges, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// formatByteRanges describes ranges for integrity notes, e.g. "bytes 0-8388607, 25165824-25165900"
+func formatByteRanges(ranges []ByteRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = fmt.Sprintf("%d-%d", r.Start, r.End)
+	}
+	return "bytes " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,137 @@
+package bwc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMB = 1 << 20
+
+// createChunkedTestFile writes a 3.5 MB file of varied content
+func createChunkedTestFile(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, "long_video.mp4")
+	data := bytes.Repeat([]byte("0123456789abcdefghijklmnopqrstu"), 7*testMB/2/31+1)[:7*testMB/2]
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	return path
+}
+
+func TestHashFileChunks(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := createChunkedTestFile(t, tmpDir)
+
+	want, _ := calculateFileHash(path)
+	hash, manifest, err := hashFileChunks(path, testMB)
+	if err != nil {
+		t.Fatalf("hashFileChunks failed: %v", err)
+	}
+	if hash != want {
+		t.Errorf("File hash %s does not match %s", hash, want)
+	}
+	if manifest.ChunkSize != testMB || len(manifest.Hashes) != 4 {
+		t.Errorf("Expected 4 chunks of 1 MB, got %d of %d", len(manifest.Hashes), manifest.ChunkSize)
+	}
+
+	if _, manifest, _ := hashFileChunks(path, 0); manifest != nil {
+		t.Error("Expected no manifest when chunk hashing is off")
+	}
+}
+
+func TestChunkManifestCorruptRanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := createChunkedTestFile(t, tmpDir)
+	original, _ := os.ReadFile(path)
+	size := int64(len(original))
+	_, manifest, _ := hashFileChunks(path, testMB)
+
+	tests := []struct {
+		name   string
+		modify func([]byte) []byte
+		want   []ByteRange
+	}{
+		{"unchanged", func(b []byte) []byte { return b }, []ByteRange{}},
+		{"one sector", func(b []byte) []byte { b[testMB+512] ^= 0xff; return b }, []ByteRange{{testMB, 2*testMB - 1}}},
+		{"adjacent chunks", func(b []byte) []byte { b[10] ^= 1; b[testMB+10] ^= 1; return b },
+			[]ByteRange{{0, 2*testMB - 1}}},
+		{"separate chunks", func(b []byte) []byte { b[10] ^= 1; b[3*testMB+10] ^= 1; return b },
+			[]ByteRange{{0, testMB - 1}, {3 * testMB, size - 1}}},
+		{"truncated", func(b []byte) []byte { return b[:5*testMB/2] }, []ByteRange{{2 * testMB, size - 1}}},
+		{"appended", func(b []byte) []byte { return append(b, make([]byte, testMB)...) },
+			[]ByteRange{{3 * testMB, size + testMB - 1}}},
+	}
+	for _, tt := range tests {
+		os.WriteFile(path, tt.modify(append([]byte(nil), original...)), 0600)
+		_, ranges, err := manifest.corruptRanges(path, size)
+		if err != nil {
+			t.Fatalf("%s: corruptRanges failed: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(ranges, tt.want) {
+			t.Errorf("%s: got ranges %v, want %v", tt.name, ranges, tt.want)
+		}
+	}
+}
+
+func TestVerifyIntegrityReportsCorruptRanges(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.ChunkSizeMB = 1
+
+	evidence, err := system.IngestEvidence(createChunkedTestFile(t, tmpDir), "CASE-CHK-001", "OFF-901", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if evidence.ChunkManifest == nil || len(evidence.ChunkManifest.Hashes) != 4 {
+		t.Fatalf("Expected a 4-chunk manifest, got %+v", evidence.ChunkManifest)
+	}
+
+	if valid, err := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Fatalf("Expected intact evidence to verify: %v", err)
+	}
+
+	data, _ := os.ReadFile(evidence.FilePath)
+	data[2*testMB+100] ^= 0xff
+	os.WriteFile(evidence.FilePath, data, 0600)
+
+	if valid, err := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); err != nil || valid {
+		t.Fatalf("Expected corrupted evidence to fail verification: %v", err)
+	}
+	checks := system.evidenceDB.Get(evidence.ID).IntegrityChecks
+	check := checks[len(checks)-1]
+	want := []ByteRange{{2 * testMB, 3*testMB - 1}}
+	if !reflect.DeepEqual(check.CorruptRanges, want) {
+		t.Errorf("Expected corrupt ranges %v, got %v", want, check.CorruptRanges)
+	}
+	if !strings.Contains(check.Notes, "bytes 2097152-3145727") {
+		t.Errorf("Expected the range in the notes, got %q", check.Notes)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "AUDITOR-1")
+	if last := logs[len(logs)-1]; !strings.Contains(last.Details, "bytes 2097152-3145727") {
+		t.Errorf("Expected the range in the audit entry, got %q", last.Details)
+	}
+}
+
+func TestVerifyIntegrityWithoutChunks(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-CHK-002", "OFF-902", "Officer Test", "Test Location", nil)
+	if evidence.ChunkManifest != nil {
+		t.Fatal("Expected no chunk manifest by default")
+	}
+
+	os.WriteFile(evidence.FilePath, []byte("altered"), 0600)
+	if valid, _ := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); valid {
+		t.Fatal("Expected altered evidence to fail verification")
+	}
+	checks := system.evidenceDB.Get(evidence.ID).IntegrityChecks
+	if check := checks[len(checks)-1]; len(check.CorruptRanges) != 0 {
+		t.Errorf("Expected no ranges without a manifest, got %v", check.CorruptRanges)
+	}
+}
@@ -0,0 +1,97 @@
+package bwc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// clientBindings maps the wire types of the client package to the server
+// types they decode
+var clientBindings = map[string]reflect.Type{
+	"Evidence":         reflect.TypeOf(Evidence{}),
+	"CustodyEntry":     reflect.TypeOf(CustodyEntry{}),
+	"CustodySignature": reflect.TypeOf(CustodySignature{}),
+	"IntegrityCheck":   reflect.TypeOf(IntegrityCheck{}),
+	"Seal":             reflect.TypeOf(Seal{}),
+	"MetadataUpdate":   reflect.TypeOf(MetadataUpdate{}),
+	"Forensics":        reflect.TypeOf(ForensicMetadata{}),
+	"OriginalMedia":    reflect.TypeOf(OriginalMedia{}),
+	"ForensicTool":     reflect.TypeOf(ForensicTool{}),
+	"ClockCheck":       reflect.TypeOf(ClockCheck{}),
+	"AuditLog":         reflect.TypeOf(AuditLog{}),
+	"AccessGrant":      reflect.TypeOf(AccessGrant{}),
+	"Checkout":         reflect.TypeOf(Checkout{}),
+	"CustodyRequest":   reflect.TypeOf(CustodyRequest{}),
+	"ScanResult":       reflect.TypeOf(ScanResult{}),
+	"AccessAlert":      reflect.TypeOf(AccessAlert{}),
+	"FlaggedAccount":   reflect.TypeOf(FlaggedAccount{}),
+	"Event":            reflect.TypeOf(Event{}),
+	"IntegrityAlert":   reflect.TypeOf(IntegrityAlert{}),
+	"EvidenceChange":   reflect.TypeOf(EvidenceChange{}),
+}
+
+// jsonNames returns the json field names of a struct type
+func jsonNames(t reflect.Type) map[string]bool {
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			for name := range jsonNames(f.Type) {
+				names[name] = true
+			}
+			continue
+		}
+		if name := strings.Split(f.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+			names[name] = true
+		}
+	}
+	return names
+}
+
+func TestClientBindingsMatchServerTypes(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../client/types.go", nil, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse client types: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+			server, bound := clientBindings[ts.Name.Name]
+			if !bound {
+				continue
+			}
+			names := jsonNames(server)
+			for _, field := range st.Fields.List {
+				if field.Tag == nil {
+					continue
+				}
+				tag, _ := strconv.Unquote(field.Tag.Value)
+				name := strings.Split(reflect.StructTag(tag).Get("json"), ",")[0]
+				if !names[name] {
+					t.Errorf("client.%s field %q has no counterpart in %s", ts.Name.Name, name, server)
+				}
+			}
+			found[ts.Name.Name] = true
+		}
+	}
+	for name := range clientBindings {
+		if !found[name] {
+			t.Errorf("client type %s not found", name)
+		}
+	}
+}
@@ -0,0 +1,677 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RunCommand dispatches the subcommands of the bwc-system binary and returns
+// the process exit code
+func RunCommand(args []string, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "config":
+		return runConfigCommand(args[1:], stdout, stderr)
+	case "tui":
+		return runTUICommand(args[1:], os.Stdin, stdout, stderr)
+	case "serve":
+		return runServeCommand(args[1:], stdout, stderr)
+	case "api-token":
+		return runAPITokenCommand(args[1:], stdout, stderr)
+	case "scan":
+		return runScanCommand(args[1:], os.Stdin, stdout, stderr)
+	case "ingest":
+		return runIngestCommand(args[1:], stdout, stderr)
+	case "verify":
+		return runVerifyCommand(args[1:], stdout, stderr)
+	case "transfer":
+		return runTransferCommand(args[1:], stdout, stderr)
+	case "search":
+		return runSearchCommand(args[1:], stdout, stderr)
+	case "report":
+		return runReportCommand(args[1:], stdout, stderr)
+	case "audit":
+		return runAuditCommand(args[1:], stdout, stderr)
+	case "scrub":
+		return runScrubCommand(args[1:], stdout, stderr)
+	case "backup":
+		return runBackupCommand(args[1:], stdout, stderr)
+	case "restore":
+		return runRestoreCommand(args[1:], stdout, stderr)
+	case "rotate-keys":
+		return runRotateKeysCommand(args[1:], stdout, stderr)
+	case "register-user":
+		return runRegisterUserCommand(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		PrintUsage(stdout)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "Unknown command: %s\n\n", args[0])
+		PrintUsage(stderr)
+		return 2
+	}
+}
+
+// PrintUsage lists the subcommands
+func PrintUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: bwc-system [command]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	fmt.Fprintln(w, "  config check [-config path]      Validate a configuration file with environment overrides applied")
+	fmt.Fprintln(w, "  tui -officer ID [-config path]   Interactive evidence custodian console")
+	fmt.Fprintln(w, "  serve [-config path] [-listen a] [-grpc a]")
+	fmt.Fprintln(w, "                                   Serve the API and web review UI, and gRPC with -grpc")
+	fmt.Fprintln(w, "  api-token -user ID [-report-profile p] [-grant-only]")
+	fmt.Fprintln(w, "                                   Generate an API token and its configuration entry")
+	fmt.Fprintln(w, "  scan [-server url] [-action a]   Look up or act on scanned evidence label codes read from stdin")
+	fmt.Fprintln(w, "  ingest -case c [-server url] [-local] file")
+	fmt.Fprintln(w, "                                   Upload evidence to a running server, or with -local into storage")
+	fmt.Fprintln(w, "  verify -user ID evidence-id...   Check recordings against their recorded hashes")
+	fmt.Fprintln(w, "  transfer -from ID -to ID -purpose text evidence-id")
+	fmt.Fprintln(w, "                                   Record a custody transfer")
+	fmt.Fprintln(w, "  search [-case c] [-officer id] [-status s]")
+	fmt.Fprintln(w, "                                   List matching evidence")
+	fmt.Fprintln(w, "  report -case c [-profile p]      Print a case report")
+	fmt.Fprintln(w, "  audit [-evidence id] [-user id]  Print audit log entries")
+	fmt.Fprintln(w, "  scrub [-full] [-report file]     Check storage against the evidence records on a running server")
+	fmt.Fprintln(w, "  backup -user ID [-media] file    Write a signed backup of the records and audit log")
+	fmt.Fprintln(w, "  restore -user ID file            Restore a signed backup into an empty system")
+	fmt.Fprintln(w, "  rotate-keys -user ID             Re-wrap the data keys of encrypted evidence under the current key")
+	fmt.Fprintln(w, "  register-user -user ID -role r [-name n] id")
+	fmt.Fprintln(w, "                                   Register a user in a role for access control")
+	fmt.Fprintln(w, "  help                             Show this message")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands that read the system directly take -config; ingest, verify, transfer,")
+	fmt.Fprintln(w, "search, report and audit print JSON with -json.")
+}
+
+// runConfigCommand implements "config check"
+func runConfigCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "check" {
+		fmt.Fprintln(stderr, "Usage: bwc-system config check [-config path]")
+		return 2
+	}
+
+	flags := flag.NewFlagSet("config check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file (default $BWC_CONFIG or "+DefaultConfigPath+")")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	*path = resolveConfigPath(*path)
+	if *path == "" {
+		*path = DefaultConfigPath
+	}
+
+	cfg, err := LoadRuntimeConfig(*path)
+	if err != nil {
+		var cfgErr *ConfigError
+		if errors.As(err, &cfgErr) {
+			fmt.Fprintf(stderr, "Configuration %s is invalid:\n", *path)
+			for _, problem := range cfgErr.Problems {
+				fmt.Fprintf(stderr, "  - %s\n", problem)
+			}
+			return 1
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Configuration %s is valid\n", *path)
+	fmt.Fprintf(stdout, "  Storage path:   %s\n", cfg.Storage.Path)
+	fmt.Fprintf(stdout, "  Hash algorithm: %s\n", cfg.Security.HashAlgorithm)
+	fmt.Fprintf(stdout, "  Retention:      %d days (%d rules)\n", cfg.Storage.RetentionDays, len(cfg.Storage.RetentionRules))
+	if cfg.API.Enabled {
+		fmt.Fprintf(stdout, "  API listen:     %s\n", cfg.ListenAddress())
+	} else {
+		fmt.Fprintln(stdout, "  API listen:     disabled")
+	}
+	for _, o := range cfg.Overrides() {
+		kind := "setting"
+		if o.IsSecret {
+			kind = "secret"
+		}
+		fmt.Fprintf(stdout, "  Override:       %s %s from %s\n", kind, o.Setting, o.Source)
+	}
+	return 0
+}
+
+// runTUICommand implements "tui"
+func runTUICommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	officerID := flags.String("officer", "", "officer ID of the custodian using the console")
+	retentionDays := flags.Int("retention-days", 30, "show evidence reaching retention within this many days")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *officerID == "" {
+		fmt.Fprintln(stderr, "Error: -officer is required")
+		return 2
+	}
+
+	system, err := openSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	window := time.Duration(*retentionDays) * 24 * time.Hour
+	if err := runTUI(system, *officerID, window, stdin, stdout, isTerminal(stdout)); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// resolveConfigPath picks the -config flag, then BWC_CONFIG, then config.json if present
+func resolveConfigPath(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if env := os.Getenv(EnvPrefix + "CONFIG"); env != "" {
+		return env
+	}
+	if _, err := os.Stat(DefaultConfigPath); err == nil {
+		return DefaultConfigPath
+	}
+	return ""
+}
+
+// openSystem loads the runtime configuration and creates the BWC system from it
+func openSystem(configPath string) (*BWCSystem, error) {
+	cfg, err := LoadRuntimeConfig(resolveConfigPath(configPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBWCSystemFromConfig(cfg)
+}
+
+// isTerminal reports whether w is an interactive terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// startGRPCServer serves the gRPC evidence service on addr, sending the
+// error it stops with to errCh, and returns a function that stops it
+// gracefully; it is set by builds with -tags grpc
+var startGRPCServer func(api *apiServer, addr string, errCh chan<- error) (func(), error)
+
+// runServeCommand implements "serve"
+func runServeCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	listen := flags.String("listen", "", "listen address (default from api.host and api.port)")
+	grpcListen := flags.String("grpc", "", "also serve the gRPC evidence service on this address")
+	drainTimeout := flags.Duration("drain-timeout", 30*time.Second, "time allowed for in-flight operations on shutdown")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	system, err := openSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if interrupted := system.InterruptedIngests(); len(interrupted) > 0 {
+		fmt.Fprintf(stderr, "Found %d interrupted ingests; resume or discard them at /api/ingests/interrupted\n", len(interrupted))
+	}
+
+	cfg := system.config
+	if len(cfg.API.Credentials) == 0 {
+		fmt.Fprintln(stderr, "Error: api.credentials must be configured before serving (see api-token)")
+		return 1
+	}
+
+	addr := *listen
+	if addr == "" {
+		addr = cfg.ListenAddress()
+	}
+
+	api := newAPIServer(system)
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           api,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		fmt.Fprintf(stdout, "Serving on %s\n", addr)
+		if cfg.API.EnableTLS {
+			errCh <- server.ListenAndServeTLS(cfg.API.TLSCertPath, cfg.API.TLSKeyPath)
+		} else {
+			errCh <- server.ListenAndServe()
+		}
+	}()
+	if *grpcListen != "" {
+		if startGRPCServer == nil {
+			fmt.Fprintln(stderr, "Error: -grpc needs a build with -tags grpc")
+			return 1
+		}
+		stopGRPC, err := startGRPCServer(api, *grpcListen, errCh)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer stopGRPC()
+		fmt.Fprintf(stdout, "Serving gRPC on %s\n", *grpcListen)
+	}
+
+	stopSchedulers := make(chan struct{})
+	defer close(stopSchedulers)
+	logf := func(format string, args ...interface{}) {
+		fmt.Fprintf(stderr, format, args...)
+	}
+	if cfg.Notifications.Enabled && len(cfg.Notifications.Digests) > 0 {
+		go newDigestScheduler(system).Run(stopSchedulers, logf)
+	}
+	if len(cfg.Reports.Schedules) > 0 {
+		go newReportScheduler(system).Run(stopSchedulers, logf)
+	}
+	if cfg.Integrity.ScheduledVerificationEnabled {
+		go newVerificationScheduler(system).Run(stopSchedulers, logf)
+	}
+	if cfg.Integrity.AnchorIntervalHours > 0 {
+		go newAnchorScheduler(system).Run(stopSchedulers, logf)
+	}
+	if cfg.AnomalyDetection.Enabled {
+		go newAnomalyDetector(system).Run(stopSchedulers, logf)
+	}
+	if cfg.Replication.Role == "primary" {
+		go newReplicationShipper(system).Run(stopSchedulers, logf)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	case sig := <-signals:
+		fmt.Fprintf(stdout, "Received %s, entering maintenance mode\n", sig)
+	}
+
+	// Stop new ingests and let in-flight operations finish before closing listeners
+	system.EnterMaintenance("SYSTEM", "Server shutdown")
+	if !system.WaitForDrain(*drainTimeout) {
+		fmt.Fprintln(stderr, "Warning: operations still in flight after drain timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// runAPITokenCommand implements "api-token"
+func runAPITokenCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("api-token", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	userID := flags.String("user", "", "user ID the token authenticates")
+	profileName := flags.String("report-profile", "", "report profile cap: internal, court or public")
+	grantOnly := flags.Bool("grant-only", false, "limit the user to evidence they hold an access grant for")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *userID == "" {
+		fmt.Fprintln(stderr, "Error: -user is required")
+		return 2
+	}
+	if err := ValidateOfficerID(*userID); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *profileName != "" {
+		if _, err := ParseReportProfile(*profileName); err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+	}
+
+	token, digest, err := newAPIToken()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(stdout, "Token for %s (shown once, give it to the user):\n  %s\n\n", *userID, token)
+	fmt.Fprintln(stdout, "Add to api.credentials in the configuration file:")
+	cred := APICredential{UserID: *userID, TokenSHA256: digest, ReportProfile: *profileName, GrantOnly: *grantOnly}
+	entry, _ := json.Marshal(cred)
+	fmt.Fprintf(stdout, "  %s\n", entry)
+	return 0
+}
+
+// runScanCommand implements "scan": a counter workflow driven by a barcode scanner
+func runScanCommand(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "http://localhost:8080", "base URL of the running server")
+	tokenFile := flags.String("token-file", "", "file holding the API token (default: $BWC_API_TOKEN)")
+	action := flags.String("action", "lookup", "lookup, checkout, checkin or transfer")
+	to := flags.String("to", "", "recipient for checkout or transfer")
+	purpose := flags.String("purpose", "", "purpose recorded for checkout or transfer")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	switch *action {
+	case "lookup", "checkin":
+	case "checkout", "transfer":
+		if *to == "" || *purpose == "" {
+			fmt.Fprintf(stderr, "Error: -to and -purpose are required for %s\n", *action)
+			return 2
+		}
+	default:
+		fmt.Fprintf(stderr, "Error: unknown action %q\n", *action)
+		return 2
+	}
+
+	token, code := loadAPIToken(*tokenFile, stderr)
+	if token == "" {
+		return code
+	}
+
+	client := newScanClient(*server, token)
+	template := scanRequest{Action: *action, To: *to, Purpose: *purpose}
+	if failures := runScanLoop(client, template, stdin, stdout, isTerminal(stdout)); failures > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadAPIToken reads the API token from tokenFile, or from BWC_API_TOKEN when
+// no file is given. On failure it reports the problem and returns an empty
+// token with the exit code to use.
+func loadAPIToken(tokenFile string, stderr io.Writer) (string, int) {
+	token := os.Getenv(EnvPrefix + "API_TOKEN")
+	if tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: failed to read token file: %v\n", err)
+			return "", 1
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token == "" {
+		fmt.Fprintf(stderr, "Error: set %sAPI_TOKEN or -token-file\n", EnvPrefix)
+		return "", 2
+	}
+	return token, 0
+}
+
+// runIngestCommand implements "ingest": it uploads a file to a running server,
+// showing the upload and the server's hash and copy progress on stderr
+func runIngestCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "http://localhost:8080", "base URL of the running server")
+	tokenFile := flags.String("token-file", "", "file holding the API token (default: $BWC_API_TOKEN)")
+	caseNumber := flags.String("case", "", "case number")
+	officerID := flags.String("officer", "", "recording officer (default: the token's user)")
+	officerName := flags.String("officer-name", "", "recording officer's name")
+	location := flags.String("location", "", "where the recording was made")
+	tags := flags.String("tags", "", "comma-separated tags")
+	local := flags.Bool("local", false, "ingest into the configured storage rather than through a server")
+	path := flags.String("config", "", "path to the configuration file, with -local")
+	asJSON := flags.Bool("json", false, "print the ingested record as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *caseNumber == "" || flags.NArg() != 1 || (*local && *officerID == "") {
+		fmt.Fprintln(stderr, "Usage: bwc-system ingest -case c [-officer id] [-server url] [-json] file")
+		fmt.Fprintln(stderr, "       bwc-system ingest -local -case c -officer id [-config path] [-json] file")
+		return 2
+	}
+
+	upload := ingestUpload{
+		Path:        flags.Arg(0),
+		CaseNumber:  *caseNumber,
+		OfficerID:   *officerID,
+		OfficerName: *officerName,
+		Location:    *location,
+	}
+	if *tags != "" {
+		upload.Tags = strings.Split(*tags, ",")
+	}
+
+	meter := newIngestMeter(stderr, isTerminal(stderr))
+	var evidence *Evidence
+	if *local {
+		system, err := openLocalSystem(*path)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+		evidence, err = system.ingestEvidence(ctx, upload.Path, upload.CaseNumber, upload.OfficerID,
+			upload.OfficerName, upload.Location, upload.Tags, time.Time{}, meter.Processing)
+		meter.Done()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		token, code := loadAPIToken(*tokenFile, stderr)
+		if token == "" {
+			return code
+		}
+		var err error
+		evidence, err = newIngestClient(*server, token).Upload(upload, meter.Sent, meter.Processing)
+		meter.Done()
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+	}
+	if *asJSON {
+		return writeCommandJSON(stdout, evidence)
+	}
+	fmt.Fprintf(stdout, "Ingested %s (SHA-256 %s)\n", evidence.ID, evidence.FileHash)
+	return 0
+}
+
+// runScrubCommand implements "scrub": it has a running server check its
+// storage, prints the findings and saves the JSON report for remediation.
+// It exits 1 when anything was found.
+func runScrubCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("scrub", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "http://localhost:8080", "base URL of the running server")
+	tokenFile := flags.String("token-file", "", "file holding the API token (default: $BWC_API_TOKEN)")
+	full := flags.Bool("full", false, "hash every file rather than a sample of chunks")
+	reportFile := flags.String("report", "", "write the JSON report to this file, or - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "Usage: bwc-system scrub [-full] [-report file] [-server url]")
+		return 2
+	}
+
+	token, code := loadAPIToken(*tokenFile, stderr)
+	if token == "" {
+		return code
+	}
+
+	raw, report, err := requestScrub(*server, token, *full)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	switch *reportFile {
+	case "":
+		fmt.Fprint(stdout, report.Text())
+	case "-":
+		stdout.Write(raw)
+		fmt.Fprintln(stdout)
+	default:
+		if err := os.WriteFile(*reportFile, raw, 0600); err != nil {
+			fmt.Fprintf(stderr, "Error: failed to write report: %v\n", err)
+			return 1
+		}
+		fmt.Fprint(stdout, report.Text())
+	}
+	if !report.Clean() {
+		return 1
+	}
+	return 0
+}
+
+// runBackupCommand implements "backup"
+func runBackupCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	userID := flags.String("user", "", "user taking the backup")
+	media := flags.Bool("media", false, "include every recording")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *userID == "" || flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: bwc-system backup -user ID [-media] [-config path] file")
+		return 2
+	}
+
+	system, err := openSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	manifest, err := system.Backup(flags.Arg(0), *userID, *media)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Backed up %d records and %d audit entries to %s\n", manifest.EvidenceCount, manifest.AuditEntries, flags.Arg(0))
+	return 0
+}
+
+// runRestoreCommand implements "restore"
+func runRestoreCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	userID := flags.String("user", "", "user restoring the backup")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *userID == "" || flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: bwc-system restore -user ID [-config path] file")
+		return 2
+	}
+
+	system, err := openSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	result, err := system.Restore(flags.Arg(0), *userID)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Restored %d records, %d audit entries and %d recordings backed up by %s at %s\n",
+		result.EvidenceCount, result.AuditEntries, result.MediaRestored, result.CreatedBy, result.CreatedAt.Format(time.RFC3339))
+	return 0
+}
+
+// runRotateKeysCommand implements "rotate-keys". It exits 1 when any data key
+// is still wrapped with the old key.
+func runRotateKeysCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("rotate-keys", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	userID := flags.String("user", "", "user rotating the keys")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *userID == "" || flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "Usage: bwc-system rotate-keys -user ID [-config path]")
+		return 2
+	}
+
+	system, err := openSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	rotation, err := system.RotateDataKeys(*userID)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Re-wrapped %d data keys under %s\n", rotation.Rewrapped, rotation.KeyID)
+	for _, id := range rotation.Failed {
+		fmt.Fprintf(stdout, "  failed: %s\n", id)
+	}
+	if len(rotation.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runRegisterUserCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("register-user", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	userID := flags.String("user", "", "administrator registering the user")
+	roleName := flags.String("role", "", "OFFICER, DETECTIVE, EVIDENCE_CUSTODIAN, AUDITOR or ADMIN")
+	name := flags.String("name", "", "the user's name")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *userID == "" || *roleName == "" || flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: bwc-system register-user -user ID -role r [-name n] [-config path] id")
+		return 2
+	}
+	role, err := ParseRole(*roleName)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	system, err := openSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	user, err := system.RegisterUser(flags.Arg(0), *name, role, *userID)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Registered %s as %s\n", user.ID, user.Role)
+	return 0
+}
@@ -0,0 +1,1341 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultConfigPath is the configuration file used when none is specified
+const DefaultConfigPath = "config.json"
+
+// Config holds all deployment settings for the BWC system.
+// The layout mirrors config.example.json.
+type Config struct {
+	System           SystemConfig           `json:"system"`
+	Storage          StorageConfig          `json:"storage"`
+	Security         SecurityConfig         `json:"security"`
+	Integrity        IntegrityConfig        `json:"integrity"`
+	Audit            AuditConfig            `json:"audit"`
+	ChainOfCustody   CustodyConfig          `json:"chain_of_custody"`
+	API              APIConfig              `json:"api"`
+	Database         DatabaseConfig         `json:"database"`
+	Notifications    NotificationsConfig    `json:"notifications"`
+	VideoProcessing  VideoProcessingConfig  `json:"video_processing"`
+	Compliance       ComplianceConfig       `json:"compliance"`
+	Performance      PerformanceConfig      `json:"performance"`
+	Logging          LoggingConfig          `json:"logging"`
+	Labels           LabelsConfig           `json:"labels"`
+	Tags             TagsConfig             `json:"tags"`
+	Geocoding        GeocodingConfig        `json:"geocoding"`
+	Photos           PhotosConfig           `json:"photos"`
+	CameraClock      CameraClockConfig      `json:"camera_clock"`
+	OCR              OCRConfig              `json:"ocr"`
+	AnomalyDetection AnomalyDetectionConfig `json:"anomaly_detection"`
+	Reports          ReportsConfig          `json:"reports"`
+	Identifiers      IdentifiersConfig      `json:"identifiers"`
+	Replication      ReplicationConfig      `json:"replication"`
+	Timestamping     TimestampingConfig     `json:"timestamping"`
+	AccessControl    AccessControlConfig    `json:"access_control"`
+
+	overrides []ConfigOverride
+}
+
+// SystemConfig identifies the deployment
+type SystemConfig struct {
+	Name        string `json:"name"`
+	Version     string `json:"version"`
+	Environment string `json:"environment"`
+}
+
+// StorageConfig controls where and how evidence files are stored
+type StorageConfig struct {
+	Path               string          `json:"path"`
+	MaxFileSizeMB      int64           `json:"max_file_size_mb"`
+	AllowedExtensions  []string        `json:"allowed_extensions"`
+	RetentionDays      int             `json:"retention_days"`
+	RetentionRules     []RetentionRule `json:"retention_rules"`
+	BackupEnabled      bool            `json:"backup_enabled"`
+	BackupPath         string          `json:"backup_path"`
+	CompressionEnabled bool            `json:"compression_enabled"`
+	// WriteAheadLog logs every changed evidence record to evidence.wal
+	// before it is stored, and replays the log on start
+	WriteAheadLog bool `json:"write_ahead_log"`
+	// ExportRoots confines exports and packages to these directories. When
+	// empty they may be written anywhere except over stored evidence.
+	ExportRoots []string `json:"export_roots,omitempty"`
+	// Replica is a second copy of every recording, in a directory or S3
+	// bucket, used to restore evidence that fails verification
+	Replica *ReportDestination `json:"replica,omitempty"`
+	// Blobs keeps evidence files somewhere other than Path: an s3 bucket, an
+	// azure container or a gcs bucket. Object Lock protects each file for its
+	// retention period.
+	Blobs *ReportDestination `json:"blobs,omitempty"`
+}
+
+// RetentionRule overrides the default retention period for evidence carrying a tag
+type RetentionRule struct {
+	Tag           string `json:"tag"`
+	RetentionDays int    `json:"retention_days"`
+	Indefinite    bool   `json:"indefinite"`
+}
+
+// SecurityConfig holds hashing, encryption and authentication settings
+type SecurityConfig struct {
+	HashAlgorithm         string    `json:"hash_algorithm"`
+	EnableEncryption      bool      `json:"enable_encryption"`
+	EncryptionAlgorithm   string    `json:"encryption_algorithm"`
+	Require2FA            bool      `json:"require_2fa"`
+	SessionTimeoutMinutes int       `json:"session_timeout_minutes"`
+	MaxLoginAttempts      int       `json:"max_login_attempts"`
+	PasswordMinLength     int       `json:"password_min_length"`
+	KMS                   KMSConfig `json:"kms"`
+	// EncryptionKeyFile holds the hex-encoded 32-byte key that wraps the
+	// data keys of evidence files encrypted at rest, when kms.provider is
+	// local or empty. It is required when enable_encryption is set, and
+	// must be kept for as long as the evidence is.
+	EncryptionKeyFile string `json:"encryption_key_file,omitempty"`
+	// RetiredEncryptionKeyFiles hold earlier keys, which still unwrap data
+	// keys until a rotation moves them to the current one
+	RetiredEncryptionKeyFiles []string `json:"retired_encryption_key_files,omitempty"`
+	// SealingKeyFile holds the hex-encoded Ed25519 seed that signs evidence
+	// seals and case packages. When empty a key is generated: with a
+	// persistent database it is kept as sealing.key in the storage path,
+	// otherwise it lasts until the system stops.
+	SealingKeyFile string `json:"sealing_key_file,omitempty"`
+	// PseudonymKeyFile holds the hex-encoded 32-byte key that derives the
+	// pseudonyms of research exports. When empty a key is generated at
+	// startup and earlier datasets can no longer be resolved.
+	PseudonymKeyFile string `json:"pseudonym_key_file,omitempty"`
+	// ReportSigningCertFile and ReportSigningKeyFile hold the agency's PEM
+	// X.509 certificate chain and private key. When set, reports and
+	// certificates can be issued with a detached PKCS#7 signature.
+	ReportSigningCertFile string `json:"report_signing_cert_file,omitempty"`
+	ReportSigningKeyFile  string `json:"report_signing_key_file,omitempty"`
+	// HSM holds the report signing key in a hardware security module in
+	// place of ReportSigningKeyFile
+	HSM HSMConfig `json:"hsm"`
+}
+
+// HSMConfig names a private key in a hardware security module, used through
+// the module's PKCS#11 library so it never leaves the device. Builds with
+// -tags pkcs11 support it.
+type HSMConfig struct {
+	// Module is the path of the vendor's PKCS#11 library
+	Module     string `json:"module,omitempty"`
+	TokenLabel string `json:"token_label,omitempty"`
+	PIN        string `json:"pin,omitempty"`
+	// KeyLabel is the CKA_LABEL of the private key
+	KeyLabel string `json:"key_label,omitempty"`
+}
+
+// KMSConfig identifies the key management service that wraps the data keys
+// of evidence encrypted at rest: local (key files), aws (AWS KMS) or vault
+// (HashiCorp Vault transit, at Endpoint with Token)
+type KMSConfig struct {
+	Provider        string `json:"provider"`
+	KeyID           string `json:"key_id"`
+	Region          string `json:"region"`
+	Endpoint        string `json:"endpoint"`
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	Token           string `json:"token,omitempty"`
+	// Mount is the path of Vault's transit engine, "transit" by default
+	Mount string `json:"mount,omitempty"`
+}
+
+// IntegrityConfig controls automatic verification behaviour
+type IntegrityConfig struct {
+	AutoVerifyOnAccess           bool   `json:"auto_verify_on_access"`
+	VerifyOnTransfer             bool   `json:"verify_on_transfer"`
+	ScheduledVerificationEnabled bool   `json:"scheduled_verification_enabled"`
+	VerificationIntervalHours    int    `json:"verification_interval_hours"`
+	AlertOnFailure               bool   `json:"alert_on_failure"`
+	AlertEmail                   string `json:"alert_email"`
+	// ChunkSizeMB enables per-chunk hashes of this many MB at ingest, so a
+	// failed verification reports the corrupted byte ranges. 0 disables them.
+	ChunkSizeMB int `json:"chunk_size_mb,omitempty"`
+	// HashAlgorithms are digests kept of each file besides its SHA-256:
+	// sha512 and blake3. Integrity checks verify every digest kept.
+	HashAlgorithms []string `json:"hash_algorithms,omitempty"`
+	// AnchorIntervalHours anchors the hash of every item under a signed
+	// Merkle root this often while serving. 0 disables it.
+	AnchorIntervalHours int `json:"anchor_interval_hours,omitempty"`
+	// Parity writes Reed-Solomon recovery data alongside each evidence file
+	Parity ParityConfig `json:"parity"`
+	// Priorities re-verifies important evidence more often than
+	// VerificationIntervalHours
+	Priorities VerificationPriorityConfig `json:"priorities"`
+}
+
+// VerificationPriorityConfig sets the scheduled verification interval of
+// each priority; NORMAL evidence uses integrity.verification_interval_hours.
+// Evidence tagged with one of CriticalTags or HighTags, or with a court date
+// within CourtDateWindowDays, is verified at that priority or higher.
+type VerificationPriorityConfig struct {
+	CriticalIntervalHours int      `json:"critical_interval_hours"`
+	HighIntervalHours     int      `json:"high_interval_hours"`
+	LowIntervalHours      int      `json:"low_interval_hours"`
+	CourtDateWindowDays   int      `json:"court_date_window_days"`
+	CriticalTags          []string `json:"critical_tags,omitempty"`
+	HighTags              []string `json:"high_tags,omitempty"`
+}
+
+// ParityConfig lays out the parity files that let damaged evidence be repaired.
+// Each group of DataBlocks blocks can lose ParityBlocks of them; storage
+// overhead is ParityBlocks/DataBlocks.
+type ParityConfig struct {
+	Enabled      bool `json:"enabled"`
+	BlockSizeKB  int  `json:"block_size_kb"`
+	DataBlocks   int  `json:"data_blocks"`
+	ParityBlocks int  `json:"parity_blocks"`
+}
+
+// AuditConfig controls audit logging. LogAllAccess audits reads as well as
+// changes: every record GetEvidence, SearchEvidence or GetChainOfCustody
+// returns, and their Context forms, is logged against the reader.
+type AuditConfig struct {
+	Enabled       bool   `json:"enabled"`
+	LogAllAccess  bool   `json:"log_all_access"`
+	RetentionDays int    `json:"retention_days"`
+	ExportFormat  string `json:"export_format"`
+	EnableSyslog  bool   `json:"enable_syslog"`
+	SyslogServer  string `json:"syslog_server"`
+	// BaselineDays is how much audit history each account's typical
+	// activity is computed over. A day scoring ReviewScoreThreshold or more
+	// against the baseline is queued for review.
+	BaselineDays         int     `json:"baseline_days"`
+	ReviewScoreThreshold float64 `json:"review_score_threshold"`
+}
+
+// CustodyConfig controls chain of custody requirements. PIVRootsFile is a PEM
+// bundle of CA certificates that PIV signing certificates must chain to.
+type CustodyConfig struct {
+	RequirePurpose            bool   `json:"require_purpose"`
+	RequireSignature          bool   `json:"require_signature"`
+	VerifyIntegrityOnTransfer bool   `json:"verify_integrity_on_transfer"`
+	MinCustodyNoteLength      int    `json:"min_custody_note_length"`
+	PIVRootsFile              string `json:"piv_roots_file,omitempty"`
+	// TrustedSources lists the instances and agencies whose signed case
+	// packages may be imported
+	TrustedSources []TrustedSource `json:"trusted_sources,omitempty"`
+}
+
+// TrustedSource is another BWC instance or agency, identified by the Ed25519
+// key that signs its case packages
+type TrustedSource struct {
+	Name string `json:"name"`
+	// PublicKey is the hex-encoded public half of the source's sealing key
+	PublicKey string `json:"public_key"`
+}
+
+// APIConfig controls the network API listener
+type APIConfig struct {
+	Enabled            bool            `json:"enabled"`
+	Host               string          `json:"host"`
+	Port               int             `json:"port"`
+	EnableTLS          bool            `json:"enable_tls"`
+	TLSCertPath        string          `json:"tls_cert_path"`
+	TLSKeyPath         string          `json:"tls_key_path"`
+	RateLimitPerMinute int             `json:"rate_limit_per_minute"`
+	CORSEnabled        bool            `json:"cors_enabled"`
+	CORSOrigins        []string        `json:"cors_origins"`
+	Credentials        []APICredential `json:"credentials"`
+	// IdempotencyKeyTTLHours is how long ingest idempotency keys are
+	// remembered; 24 hours when unset
+	IdempotencyKeyTTLHours int `json:"idempotency_key_ttl_hours,omitempty"`
+}
+
+// APICredential maps an API token to the user it authenticates.
+// Only the SHA-256 of the token is stored in configuration.
+// ReportProfile caps the case report content the user may download
+// and defaults to internal. GrantOnly users, such as outside detectives,
+// may only view evidence they hold an active access grant for.
+type APICredential struct {
+	UserID        string `json:"user_id"`
+	TokenSHA256   string `json:"token_sha256"`
+	ReportProfile string `json:"report_profile,omitempty"`
+	GrantOnly     bool   `json:"grant_only,omitempty"`
+}
+
+// DatabaseConfig selects and configures the evidence database
+type DatabaseConfig struct {
+	Type                     string `json:"type"`
+	Host                     string `json:"host"`
+	Port                     int    `json:"port"`
+	Name                     string `json:"name"`
+	User                     string `json:"user"`
+	SSLMode                  string `json:"ssl_mode"`
+	MaxConnections           int    `json:"max_connections"`
+	ConnectionTimeoutSeconds int    `json:"connection_timeout_seconds"`
+	Password                 string `json:"password,omitempty"`
+	DSN                      string `json:"dsn,omitempty"`
+}
+
+// NotificationsConfig configures outbound alert delivery
+type NotificationsConfig struct {
+	Enabled         bool     `json:"enabled"`
+	SMTPHost        string   `json:"smtp_host"`
+	SMTPPort        int      `json:"smtp_port"`
+	SMTPUser        string   `json:"smtp_user"`
+	SMTPUseTLS      bool     `json:"smtp_use_tls"`
+	SMTPPassword    string   `json:"smtp_password,omitempty"`
+	AlertRecipients []string `json:"alert_recipients"`
+	// DigestHour is the local hour (0-23) daily digests are sent
+	DigestHour int            `json:"digest_hour"`
+	Digests    []DigestConfig `json:"digests,omitempty"`
+}
+
+// DigestConfig sends a daily digest to one role by email, webhook or both.
+// Sections is any of ingest, integrity, custody and retention; empty means all.
+type DigestConfig struct {
+	Role       string   `json:"role"`
+	Recipients []string `json:"recipients,omitempty"`
+	WebhookURL string   `json:"webhook_url,omitempty"`
+	Sections   []string `json:"sections,omitempty"`
+}
+
+// VideoProcessingConfig configures media processing on ingest. Video
+// metadata is read with the ffprobe at FFprobePath when ExtractMetadata is on,
+// or from MP4 and QuickTime files directly when FFprobePath is unset.
+type VideoProcessingConfig struct {
+	Enabled                  bool   `json:"enabled"`
+	GenerateThumbnails       bool   `json:"generate_thumbnails"`
+	ThumbnailIntervalSeconds int    `json:"thumbnail_interval_seconds"`
+	ExtractMetadata          bool   `json:"extract_metadata"`
+	FFprobePath              string `json:"ffprobe_path,omitempty"`
+	EnableTranscoding        bool   `json:"enable_transcoding"`
+	TargetFormat             string `json:"target_format"`
+	TargetResolution         string `json:"target_resolution"`
+	// Processors are external programs run against evidence as processing jobs
+	Processors        []ProcessorConfig `json:"processors,omitempty"`
+	MaxConcurrentJobs int               `json:"max_concurrent_jobs"`
+}
+
+// ProcessorConfig runs Command with the evidence file path appended as its
+// last argument. The program prints a JSON result on stdout and may write
+// derived files to $BWC_OUTPUT_DIR. OnIngest queues a job for every new item
+// of the MediaTypes it handles, or of any type when none are listed.
+type ProcessorConfig struct {
+	Name           string      `json:"name"`
+	Command        []string    `json:"command"`
+	TimeoutSeconds int         `json:"timeout_seconds"`
+	OnIngest       bool        `json:"on_ingest"`
+	MediaTypes     []MediaType `json:"media_types,omitempty"`
+}
+
+// ComplianceConfig records jurisdictional compliance settings
+type ComplianceConfig struct {
+	Jurisdiction             string `json:"jurisdiction"`
+	CJISCompliant            bool   `json:"cjis_compliant"`
+	GDPRCompliant            bool   `json:"gdpr_compliant"`
+	RetentionPolicy          string `json:"retention_policy"`
+	AutoDeleteAfterRetention bool   `json:"auto_delete_after_retention"`
+	RequireLegalHoldCheck    bool   `json:"require_legal_hold_check"`
+	// ClassificationDeadlineDays is how long officers have to categorize an
+	// upload before the accountability report lists it as overdue
+	ClassificationDeadlineDays int `json:"classification_deadline_days"`
+}
+
+// PerformanceConfig tunes concurrency and caching
+type PerformanceConfig struct {
+	MaxConcurrentIngests       int  `json:"max_concurrent_ingests"`
+	MaxConcurrentVerifications int  `json:"max_concurrent_verifications"`
+	EnableCaching              bool `json:"enable_caching"`
+	CacheTTLMinutes            int  `json:"cache_ttl_minutes"`
+	EnableCompression          bool `json:"enable_compression"`
+}
+
+// LabelsConfig configures printed evidence labels. When VerifyBaseURL is set,
+// label QR codes encode VerifyBaseURL/<evidence-id>; otherwise the bare ID.
+type LabelsConfig struct {
+	VerifyBaseURL string `json:"verify_base_url"`
+}
+
+// TagsConfig is the managed tag vocabulary. When Vocabulary is empty tags are
+// free text; otherwise tags given at ingest or tagging time are mapped onto it
+// and anything unlisted is refused unless AllowUnlisted is set.
+type TagsConfig struct {
+	Vocabulary    []TagDefinition `json:"vocabulary,omitempty"`
+	AllowUnlisted bool            `json:"allow_unlisted"`
+}
+
+// TagDefinition is one managed tag. Synonyms are stored as Name. A deprecated
+// tag is stored as ReplacedBy, or refused when it has no replacement.
+type TagDefinition struct {
+	Name       string   `json:"name"`
+	Synonyms   []string `json:"synonyms,omitempty"`
+	Deprecated bool     `json:"deprecated,omitempty"`
+	ReplacedBy string   `json:"replaced_by,omitempty"`
+}
+
+// GeocodingConfig points ingest at a Nominatim-compatible search API that
+// resolves free-text locations to structured places. Geocoding is off when
+// URL is empty.
+type GeocodingConfig struct {
+	URL            string `json:"url"`
+	UserAgent      string `json:"user_agent"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// TimestampingConfig names an RFC 3161 time-stamping authority. When URL is
+// set, the hash found at ingest and by every integrity check is timestamped.
+// Policy is the dotted OID of the TSA policy to request, if it has several;
+// RootsFile holds the PEM CA certificates its certificate must chain to.
+type TimestampingConfig struct {
+	URL            string `json:"url"`
+	Policy         string `json:"policy,omitempty"`
+	RootsFile      string `json:"roots_file,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+}
+
+// AccessControlConfig turns on role-based access control. While it is off
+// any caller may do anything. Admins are user IDs with every permission
+// whether registered or not, so the first users can be registered.
+type AccessControlConfig struct {
+	Enabled bool     `json:"enabled"`
+	Admins  []string `json:"admins,omitempty"`
+	// DeletionApproval requires a second user to approve each deletion
+	// and retention purge
+	DeletionApproval DeletionApprovalConfig `json:"deletion_approval"`
+}
+
+// DeletionApprovalConfig is the two-person rule for destructive operations.
+// A request not approved within WindowHours, 24 by default, expires.
+type DeletionApprovalConfig struct {
+	Enabled     bool `json:"enabled"`
+	WindowHours int  `json:"window_hours"`
+}
+
+// PhotosConfig configures photo evidence: the longest side of generated
+// thumbnails, and how far a photo's EXIF capture time may be from the claimed
+// incident time before it is flagged
+type PhotosConfig struct {
+	ThumbnailSize               int `json:"thumbnail_size"`
+	CaptureTimeToleranceMinutes int `json:"capture_time_tolerance_minutes"`
+}
+
+// CameraClockConfig controls the check of the time a camera embedded in a
+// recording against when it was uploaded. A recording is implausible when it
+// ends more than ToleranceMinutes after its upload began, or was made more than
+// MaxRecordingAgeDays before. DeviceOffsets are the known errors of camera
+// clocks in seconds, positive when a clock runs fast, keyed by device serial
+// number or by the ID of the officer a camera is issued to.
+type CameraClockConfig struct {
+	ToleranceMinutes    int            `json:"tolerance_minutes"`
+	MaxRecordingAgeDays int            `json:"max_recording_age_days"`
+	DeviceOffsets       map[string]int `json:"device_offsets,omitempty"`
+}
+
+// OCRConfig runs Command with a document's path appended as its last
+// argument to read its text for the full-text index. The program prints the
+// text on stdout. Documents are not read when Command is empty.
+type OCRConfig struct {
+	Command        []string `json:"command,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds"`
+}
+
+// AnomalyDetectionConfig configures the rules applied to the audit stream.
+// Each limit is how many matching actions by one account within
+// WindowMinutes raise an alert; a limit of 0 disables that rule. Exports
+// count towards OffHoursExportLimit on weekends and outside
+// BusinessHoursStart to BusinessHoursEnd local time.
+type AnomalyDetectionConfig struct {
+	Enabled             bool `json:"enabled"`
+	WindowMinutes       int  `json:"window_minutes"`
+	BusinessHoursStart  int  `json:"business_hours_start"`
+	BusinessHoursEnd    int  `json:"business_hours_end"`
+	OffHoursExportLimit int  `json:"off_hours_export_limit"`
+	CaseSpreadLimit     int  `json:"case_spread_limit"`
+	FailedAccessLimit   int  `json:"failed_access_limit"`
+}
+
+// ReportsConfig schedules recurring reports, generated at Hour local time on
+// the day each falls due
+type ReportsConfig struct {
+	Hour      int              `json:"hour"`
+	Schedules []ReportSchedule `json:"schedules,omitempty"`
+}
+
+// ReportSchedule is one recurring report. Weekly reports run on Mondays,
+// monthly on the 1st and quarterly on the 1st of January, April, July and
+// October, each covering the period just ended.
+type ReportSchedule struct {
+	Name      string              `json:"name"`
+	Type      ScheduledReportType `json:"type"`
+	Frequency string              `json:"frequency"`
+	// Cases limits case summaries to these case numbers; by default every
+	// case with activity in the period is included
+	Cases        []string            `json:"cases,omitempty"`
+	Profile      string              `json:"profile,omitempty"`
+	Locale       string              `json:"locale,omitempty"`
+	Format       string              `json:"format,omitempty"`
+	Destinations []ReportDestination `json:"destinations"`
+}
+
+// ReportDestination is where a scheduled report is delivered: a directory,
+// email recipients or an S3 bucket. S3 credentials default to the AWS_*
+// environment variables.
+type ReportDestination struct {
+	Type            string   `json:"type"`
+	Path            string   `json:"path,omitempty"`
+	Recipients      []string `json:"recipients,omitempty"`
+	Bucket          string   `json:"bucket,omitempty"`
+	Prefix          string   `json:"prefix,omitempty"`
+	Region          string   `json:"region,omitempty"`
+	Endpoint        string   `json:"endpoint,omitempty"`
+	AccessKeyID     string   `json:"access_key_id,omitempty"`
+	SecretAccessKey string   `json:"secret_access_key,omitempty"`
+	SessionToken    string   `json:"session_token,omitempty"`
+	// Account and AccountKey authenticate to Azure Blob Storage with a
+	// shared key; Bucket names the container
+	Account    string `json:"account,omitempty"`
+	AccountKey string `json:"account_key,omitempty"`
+	// AccessToken authenticates to Google Cloud Storage. CredentialsFile is
+	// a service account key to obtain tokens with instead.
+	AccessToken     string `json:"access_token,omitempty"`
+	CredentialsFile string `json:"credentials_file,omitempty"`
+	// ObjectLock applies to an S3 storage replica and to storage.blobs only:
+	// each recording is locked until its retention period ends, or held
+	// indefinitely when it is retained indefinitely. The bucket must have
+	// Object Lock, the Azure container version-level immutability, or the GCS
+	// bucket object retention enabled.
+	ObjectLock bool `json:"object_lock,omitempty"`
+}
+
+// LoggingConfig configures application logging
+type LoggingConfig struct {
+	Level      string `json:"level"`
+	Format     string `json:"format"`
+	Output     string `json:"output"`
+	FilePath   string `json:"file_path"`
+	MaxSizeMB  int    `json:"max_size_mb"`
+	MaxBackups int    `json:"max_backups"`
+	MaxAgeDays int    `json:"max_age_days"`
+	Compress   bool   `json:"compress"`
+}
+
+// ConfigError lists every problem found while validating a configuration
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return "invalid configuration: " + strings.Join(e.Problems, "; ")
+}
+
+// DefaultConfig returns the configuration used when no file is supplied
+func DefaultConfig() *Config {
+	return &Config{
+		System: SystemConfig{
+			Name:        "Forensic BWC Management System",
+			Version:     "1.0.0",
+			Environment: "development",
+		},
+		Storage: StorageConfig{
+			Path:              "./bwc_storage",
+			MaxFileSizeMB:     5120,
+			AllowedExtensions: []string{".mp4", ".avi", ".mov", ".mkv", ".webm", ".wav", ".mp3", ".m4a", ".flac", ".pdf", ".tif", ".tiff"},
+			RetentionDays:     2555,
+		},
+		Security: SecurityConfig{
+			HashAlgorithm:         "SHA-256",
+			EncryptionAlgorithm:   "AES-256-GCM",
+			SessionTimeoutMinutes: 30,
+			MaxLoginAttempts:      5,
+			PasswordMinLength:     12,
+		},
+		Integrity: IntegrityConfig{
+			VerifyOnTransfer:          true,
+			VerificationIntervalHours: 24,
+			Priorities: VerificationPriorityConfig{
+				CriticalIntervalHours: 1,
+				HighIntervalHours:     6,
+				LowIntervalHours:      168,
+				CourtDateWindowDays:   14,
+			},
+			Parity: ParityConfig{
+				BlockSizeKB:  256,
+				DataBlocks:   32,
+				ParityBlocks: 2,
+			},
+		},
+		Audit: AuditConfig{
+			Enabled:              true,
+			RetentionDays:        2555,
+			ExportFormat:         "json",
+			BaselineDays:         30,
+			ReviewScoreThreshold: 3,
+		},
+		Compliance: ComplianceConfig{
+			ClassificationDeadlineDays: 3,
+		},
+		ChainOfCustody: CustodyConfig{
+			RequirePurpose:            true,
+			VerifyIntegrityOnTransfer: true,
+		},
+		API: APIConfig{
+			Host:               "0.0.0.0",
+			Port:               8080,
+			RateLimitPerMinute: 100,
+		},
+		Database: DatabaseConfig{
+			Type: "memory",
+		},
+		VideoProcessing: VideoProcessingConfig{
+			MaxConcurrentJobs: 2,
+		},
+		Performance: PerformanceConfig{
+			MaxConcurrentIngests:       10,
+			MaxConcurrentVerifications: 5,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+			Output: "stdout",
+		},
+		Photos: PhotosConfig{
+			ThumbnailSize:               defaultThumbnailSize,
+			CaptureTimeToleranceMinutes: 60,
+		},
+		CameraClock: CameraClockConfig{
+			ToleranceMinutes:    10,
+			MaxRecordingAgeDays: 90,
+		},
+		AnomalyDetection: AnomalyDetectionConfig{
+			WindowMinutes:       60,
+			BusinessHoursStart:  7,
+			BusinessHoursEnd:    19,
+			OffHoursExportLimit: 5,
+			CaseSpreadLimit:     10,
+			FailedAccessLimit:   5,
+		},
+	}
+}
+
+// LoadConfig reads a configuration file on top of the defaults and validates it.
+// Files are JSON unless named .yaml, .yml or .toml, which need builds with
+// -tags yaml or -tags toml. Unknown keys are rejected so typos are not
+// silently ignored.
+func LoadConfig(path string) (*Config, error) {
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// loadConfigFile decodes a configuration file over the defaults without validating it
+func loadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml", ".toml":
+		if data, err = configFileToJSON(ext, data); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	cfg := DefaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// decodeYAMLConfig and decodeTOMLConfig parse a configuration file into its
+// tree of keys; they are set by builds with -tags yaml and -tags toml
+var (
+	decodeYAMLConfig func(data []byte) (map[string]interface{}, error)
+	decodeTOMLConfig func(data []byte) (map[string]interface{}, error)
+)
+
+// configFileToJSON re-encodes a YAML or TOML configuration file as JSON, so
+// it is decoded and checked for unknown keys exactly as a JSON file is
+func configFileToJSON(ext string, data []byte) ([]byte, error) {
+	decode, tag := decodeYAMLConfig, "yaml"
+	if ext == ".toml" {
+		decode, tag = decodeTOMLConfig, "toml"
+	}
+	if decode == nil {
+		return nil, fmt.Errorf("%s configuration needs a build with -tags %s", ext, tag)
+	}
+	tree, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(tree)
+}
+
+// Validate checks the configuration for missing or inconsistent settings
+func (c *Config) Validate() error {
+	problems := make([]string, 0)
+
+	if strings.TrimSpace(c.Storage.Path) == "" {
+		problems = append(problems, "storage.path is required")
+	}
+	if c.Storage.MaxFileSizeMB < 0 {
+		problems = append(problems, "storage.max_file_size_mb must not be negative")
+	}
+	for _, ext := range c.Storage.AllowedExtensions {
+		if !strings.HasPrefix(ext, ".") {
+			problems = append(problems, fmt.Sprintf("storage.allowed_extensions entry %q must start with '.'", ext))
+		}
+	}
+	problems = c.RetentionPolicy().validate("storage.", problems)
+	for i, root := range c.Storage.ExportRoots {
+		if strings.TrimSpace(root) == "" {
+			problems = append(problems, fmt.Sprintf("storage.export_roots[%d] must not be empty", i))
+		}
+	}
+	if c.Storage.BackupEnabled && c.Storage.BackupPath == "" {
+		problems = append(problems, "storage.backup_path is required when backups are enabled")
+	}
+	if r := c.Storage.Replica; r != nil {
+		switch r.Type {
+		case "directory":
+			if r.Path == "" {
+				problems = append(problems, "storage.replica.path is required")
+			} else if filepath.Clean(r.Path) == filepath.Clean(c.Storage.Path) {
+				problems = append(problems, "storage.replica.path must differ from storage.path")
+			}
+		case "s3":
+			if r.Bucket == "" || (r.Region == "" && r.Endpoint == "") {
+				problems = append(problems, "storage.replica requires bucket and region or endpoint")
+			}
+			if r.Endpoint != "" && !isHTTPURL(r.Endpoint) {
+				problems = append(problems, "storage.replica.endpoint must be an absolute http or https URL")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("storage.replica.type %q is not one of directory, s3", r.Type))
+		}
+		if r.ObjectLock && r.Type != "s3" {
+			problems = append(problems, "storage.replica.object_lock requires an s3 replica")
+		}
+	}
+	if b := c.Storage.Blobs; b != nil {
+		switch b.Type {
+		case "s3":
+			if b.Bucket == "" || (b.Region == "" && b.Endpoint == "") {
+				problems = append(problems, "storage.blobs requires bucket and region or endpoint")
+			}
+		case "azure", "gcs":
+			if b.Bucket == "" {
+				problems = append(problems, "storage.blobs requires bucket")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("storage.blobs.type %q is not one of s3, azure, gcs", b.Type))
+		}
+		if b.Endpoint != "" && !isHTTPURL(b.Endpoint) {
+			problems = append(problems, "storage.blobs.endpoint must be an absolute http or https URL")
+		}
+	}
+
+	if !isSupportedHashAlgorithm(c.Security.HashAlgorithm) {
+		problems = append(problems, fmt.Sprintf("security.hash_algorithm %q is not supported", c.Security.HashAlgorithm))
+	}
+	if c.Security.EnableEncryption && c.Security.EncryptionAlgorithm != "AES-256-GCM" {
+		problems = append(problems, fmt.Sprintf("security.encryption_algorithm %q is not supported", c.Security.EncryptionAlgorithm))
+	}
+	if c.Security.EnableEncryption {
+		kms := c.Security.KMS
+		switch kms.Provider {
+		case "", "local":
+			if c.Security.EncryptionKeyFile == "" {
+				problems = append(problems, "security.enable_encryption requires security.encryption_key_file")
+			}
+		case "aws":
+			if kms.KeyID == "" || kms.Region == "" {
+				problems = append(problems, "security.kms provider aws requires key_id and region")
+			}
+		case "vault":
+			if kms.KeyID == "" || !isHTTPURL(kms.Endpoint) {
+				problems = append(problems, "security.kms provider vault requires key_id and an http or https endpoint")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("security.kms.provider %q is not one of local, aws, vault", kms.Provider))
+		}
+		if kms.Endpoint != "" && !isHTTPURL(kms.Endpoint) {
+			problems = append(problems, "security.kms.endpoint must be an absolute http or https URL")
+		}
+	}
+	if c.Security.SessionTimeoutMinutes <= 0 {
+		problems = append(problems, "security.session_timeout_minutes must be positive")
+	}
+	if c.Security.MaxLoginAttempts <= 0 {
+		problems = append(problems, "security.max_login_attempts must be positive")
+	}
+	if c.Security.PasswordMinLength < 8 {
+		problems = append(problems, "security.password_min_length must be at least 8")
+	}
+	if hsm := c.Security.HSM; hsm.Module != "" {
+		if hsm.TokenLabel == "" || hsm.KeyLabel == "" {
+			problems = append(problems, "security.hsm needs token_label and key_label")
+		}
+		if c.Security.ReportSigningKeyFile != "" {
+			problems = append(problems, "security.report_signing_key_file and security.hsm cannot both be set")
+		}
+		if c.Security.ReportSigningCertFile == "" {
+			problems = append(problems, "security.hsm needs security.report_signing_cert_file")
+		}
+	} else if (c.Security.ReportSigningCertFile == "") != (c.Security.ReportSigningKeyFile == "") {
+		problems = append(problems, "security.report_signing_cert_file and security.report_signing_key_file must be set together")
+	}
+
+	if c.Integrity.ScheduledVerificationEnabled && c.Integrity.VerificationIntervalHours <= 0 {
+		problems = append(problems, "integrity.verification_interval_hours must be positive when scheduled verification is enabled")
+	}
+	if p := c.Integrity.Priorities; c.Integrity.ScheduledVerificationEnabled &&
+		(p.CriticalIntervalHours <= 0 || p.HighIntervalHours <= 0 || p.LowIntervalHours <= 0) {
+		problems = append(problems, "integrity.priorities intervals must be positive when scheduled verification is enabled")
+	}
+	if c.Integrity.Priorities.CourtDateWindowDays < 0 {
+		problems = append(problems, "integrity.priorities.court_date_window_days must not be negative")
+	}
+	if c.Integrity.ChunkSizeMB < 0 {
+		problems = append(problems, "integrity.chunk_size_mb must not be negative")
+	}
+	if err := validateHashAlgorithms(c.Integrity.HashAlgorithms); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if c.Integrity.AnchorIntervalHours < 0 {
+		problems = append(problems, "integrity.anchor_interval_hours must not be negative")
+	}
+	if p := c.Integrity.Parity; p.Enabled {
+		if p.BlockSizeKB <= 0 {
+			problems = append(problems, "integrity.parity.block_size_kb must be positive")
+		}
+		if p.DataBlocks <= 0 || p.ParityBlocks <= 0 || p.DataBlocks+p.ParityBlocks > 256 {
+			problems = append(problems, "integrity.parity needs positive data_blocks and parity_blocks totalling at most 256")
+		}
+	}
+
+	if c.API.Enabled {
+		if c.API.Port <= 0 || c.API.Port > 65535 {
+			problems = append(problems, fmt.Sprintf("api.port %d is out of range", c.API.Port))
+		}
+		if c.API.EnableTLS && (c.API.TLSCertPath == "" || c.API.TLSKeyPath == "") {
+			problems = append(problems, "api.tls_cert_path and api.tls_key_path are required when TLS is enabled")
+		}
+		if len(c.API.Credentials) == 0 {
+			problems = append(problems, "api.credentials must contain at least one user when the API is enabled")
+		}
+	}
+	for i, cred := range c.API.Credentials {
+		if cred.UserID == "" {
+			problems = append(problems, fmt.Sprintf("api.credentials[%d].user_id is required", i))
+		} else if cred.UserID == systemUserID {
+			problems = append(problems, fmt.Sprintf("api.credentials[%d].user_id %s is reserved for the system", i, systemUserID))
+		}
+		if decoded, err := hex.DecodeString(cred.TokenSHA256); err != nil || len(decoded) != sha256.Size {
+			problems = append(problems, fmt.Sprintf("api.credentials[%d].token_sha256 must be a hex SHA-256 digest", i))
+		}
+		if cred.ReportProfile != "" {
+			if _, err := ParseReportProfile(cred.ReportProfile); err != nil {
+				problems = append(problems, fmt.Sprintf("api.credentials[%d].report_profile: %v", i, err))
+			}
+		}
+	}
+	if c.API.IdempotencyKeyTTLHours < 0 {
+		problems = append(problems, "api.idempotency_key_ttl_hours must not be negative")
+	}
+
+	sourceNames := make(map[string]bool)
+	for i, source := range c.ChainOfCustody.TrustedSources {
+		if source.Name == "" {
+			problems = append(problems, fmt.Sprintf("chain_of_custody.trusted_sources[%d].name is required", i))
+		} else if sourceNames[source.Name] {
+			problems = append(problems, fmt.Sprintf("chain_of_custody.trusted_sources[%d] duplicates name %q", i, source.Name))
+		}
+		sourceNames[source.Name] = true
+		if key, err := hex.DecodeString(source.PublicKey); err != nil || len(key) != ed25519.PublicKeySize {
+			problems = append(problems, fmt.Sprintf("chain_of_custody.trusted_sources[%d].public_key must be a hex Ed25519 public key", i))
+		}
+	}
+
+	switch c.Database.Type {
+	case "memory", "json", "sqlite", "bbolt":
+	case "postgres":
+		if c.Database.DSN == "" && (c.Database.Host == "" || c.Database.Name == "") {
+			problems = append(problems, "database.type postgres needs database.dsn, or database.host and database.name")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("database.type %q is not supported", c.Database.Type))
+	}
+
+	switch c.Logging.Level {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("logging.level %q is not one of debug, info, warn, error", c.Logging.Level))
+	}
+
+	if c.Notifications.DigestHour < 0 || c.Notifications.DigestHour > 23 {
+		problems = append(problems, "notifications.digest_hour must be between 0 and 23")
+	}
+	for i, dc := range c.Notifications.Digests {
+		if dc.Role == "" {
+			problems = append(problems, fmt.Sprintf("notifications.digests[%d].role is required", i))
+		}
+		if len(dc.Recipients) == 0 && dc.WebhookURL == "" {
+			problems = append(problems, fmt.Sprintf("notifications.digests[%d] needs recipients or a webhook_url", i))
+		}
+		if len(dc.Recipients) > 0 && c.Notifications.SMTPHost == "" {
+			problems = append(problems, fmt.Sprintf("notifications.digests[%d] has recipients but notifications.smtp_host is not set", i))
+		}
+		if dc.WebhookURL != "" && !isHTTPURL(dc.WebhookURL) {
+			problems = append(problems, fmt.Sprintf("notifications.digests[%d].webhook_url must be an absolute http or https URL", i))
+		}
+		if _, err := parseDigestSections(dc.Sections); err != nil {
+			problems = append(problems, fmt.Sprintf("notifications.digests[%d].sections: %v", i, err))
+		}
+	}
+
+	if c.Labels.VerifyBaseURL != "" && !isHTTPURL(c.Labels.VerifyBaseURL) {
+		problems = append(problems, "labels.verify_base_url must be an absolute http or https URL")
+	}
+
+	problems = c.validateReportSchedules(problems)
+	problems = c.validateTags(problems)
+
+	if c.Geocoding.URL != "" && !isHTTPURL(c.Geocoding.URL) {
+		problems = append(problems, "geocoding.url must be an absolute http or https URL")
+	}
+	if c.Geocoding.TimeoutSeconds < 0 {
+		problems = append(problems, "geocoding.timeout_seconds must not be negative")
+	}
+	if c.Timestamping.URL != "" && !isHTTPURL(c.Timestamping.URL) {
+		problems = append(problems, "timestamping.url must be an absolute http or https URL")
+	}
+	if c.Timestamping.Policy != "" {
+		if _, err := parseOID(c.Timestamping.Policy); err != nil {
+			problems = append(problems, "timestamping.policy: "+err.Error())
+		}
+	}
+	if c.Timestamping.TimeoutSeconds < 0 {
+		problems = append(problems, "timestamping.timeout_seconds must not be negative")
+	}
+	if c.AccessControl.Enabled && len(c.AccessControl.Admins) == 0 {
+		problems = append(problems, "access_control.admins must name at least one user when access control is enabled")
+	}
+	for _, admin := range c.AccessControl.Admins {
+		if err := ValidateOfficerID(admin); err != nil {
+			problems = append(problems, "access_control.admins: "+err.Error())
+		}
+	}
+	if c.AccessControl.DeletionApproval.WindowHours < 0 {
+		problems = append(problems, "access_control.deletion_approval.window_hours must not be negative")
+	}
+	if c.Photos.ThumbnailSize < 0 {
+		problems = append(problems, "photos.thumbnail_size must not be negative")
+	}
+	if c.Photos.CaptureTimeToleranceMinutes < 0 {
+		problems = append(problems, "photos.capture_time_tolerance_minutes must not be negative")
+	}
+	if c.CameraClock.ToleranceMinutes < 0 {
+		problems = append(problems, "camera_clock.tolerance_minutes must not be negative")
+	}
+	if c.CameraClock.MaxRecordingAgeDays < 1 {
+		problems = append(problems, "camera_clock.max_recording_age_days must be at least 1")
+	}
+	if len(c.OCR.Command) > 0 && c.OCR.Command[0] == "" {
+		problems = append(problems, "ocr.command must name a program")
+	}
+	if c.OCR.TimeoutSeconds < 0 {
+		problems = append(problems, "ocr.timeout_seconds must not be negative")
+	}
+	problems = c.validateAnomalyDetection(problems)
+	problems = c.validateIdentifiers(problems)
+	problems = c.validateReplication(problems)
+	if c.Audit.BaselineDays < 1 {
+		problems = append(problems, "audit.baseline_days must be at least 1")
+	}
+	if c.Compliance.ClassificationDeadlineDays < 1 {
+		problems = append(problems, "compliance.classification_deadline_days must be at least 1")
+	}
+	if c.Audit.ReviewScoreThreshold < 0 {
+		problems = append(problems, "audit.review_score_threshold must not be negative")
+	}
+
+	if c.VideoProcessing.MaxConcurrentJobs < 0 {
+		problems = append(problems, "video_processing.max_concurrent_jobs must not be negative")
+	}
+	seenProcessors := make(map[string]bool)
+	for i, p := range c.VideoProcessing.Processors {
+		prefix := fmt.Sprintf("video_processing.processors[%d]", i)
+		if p.Name == "" {
+			problems = append(problems, prefix+".name is required")
+		} else if seenProcessors[p.Name] {
+			problems = append(problems, fmt.Sprintf("%s duplicates name %q", prefix, p.Name))
+		}
+		seenProcessors[p.Name] = true
+		if len(p.Command) == 0 || p.Command[0] == "" {
+			problems = append(problems, prefix+".command is required")
+		}
+		if p.TimeoutSeconds < 0 {
+			problems = append(problems, prefix+".timeout_seconds must not be negative")
+		}
+		for _, t := range p.MediaTypes {
+			if !validMediaType(t) {
+				problems = append(problems, fmt.Sprintf("%s.media_types has unknown type %q", prefix, t))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+
+	return nil
+}
+
+// validateReportSchedules appends problems with scheduled report settings
+func (c *Config) validateReportSchedules(problems []string) []string {
+	if c.Reports.Hour < 0 || c.Reports.Hour > 23 {
+		problems = append(problems, "reports.hour must be between 0 and 23")
+	}
+
+	names := make(map[string]bool)
+	for i, s := range c.Reports.Schedules {
+		prefix := fmt.Sprintf("reports.schedules[%d]", i)
+		if s.Name == "" {
+			problems = append(problems, prefix+".name is required")
+		} else if names[s.Name] {
+			problems = append(problems, fmt.Sprintf("%s duplicates name %q", prefix, s.Name))
+		}
+		names[s.Name] = true
+
+		switch s.Type {
+		case ScheduledCaseSummary, ScheduledAuditExport, ScheduledRetentionPreview, ScheduledRetentionForecast, ScheduledOfficerAccountability:
+		default:
+			problems = append(problems, fmt.Sprintf("%s.type %q is not one of case_summary, audit_export, retention_preview, retention_forecast, officer_accountability", prefix, s.Type))
+		}
+		switch s.Frequency {
+		case "weekly", "monthly", "quarterly":
+		default:
+			problems = append(problems, fmt.Sprintf("%s.frequency %q is not one of weekly, monthly, quarterly", prefix, s.Frequency))
+		}
+		if s.Profile != "" {
+			if _, err := ParseReportProfile(s.Profile); err != nil {
+				problems = append(problems, fmt.Sprintf("%s.profile: %v", prefix, err))
+			}
+		}
+		if _, err := ParseLocale(s.Locale); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.locale: %v", prefix, err))
+		}
+		if !validScheduleFormat(s.Type, s.Format) {
+			problems = append(problems, fmt.Sprintf("%s.format %q is not supported for %s", prefix, s.Format, s.Type))
+		}
+
+		if len(s.Destinations) == 0 {
+			problems = append(problems, prefix+".destinations must not be empty")
+		}
+		for j, d := range s.Destinations {
+			dprefix := fmt.Sprintf("%s.destinations[%d]", prefix, j)
+			if d.ObjectLock {
+				problems = append(problems, dprefix+".object_lock is only supported for storage.replica")
+			}
+			switch d.Type {
+			case "directory":
+				if d.Path == "" {
+					problems = append(problems, dprefix+".path is required")
+				}
+			case "email":
+				if len(d.Recipients) == 0 {
+					problems = append(problems, dprefix+".recipients is required")
+				}
+				if c.Notifications.SMTPHost == "" {
+					problems = append(problems, dprefix+" sends email but notifications.smtp_host is not set")
+				}
+			case "s3":
+				if d.Bucket == "" || (d.Region == "" && d.Endpoint == "") {
+					problems = append(problems, dprefix+" requires bucket and region or endpoint")
+				}
+				if d.Endpoint != "" && !isHTTPURL(d.Endpoint) {
+					problems = append(problems, dprefix+".endpoint must be an absolute http or https URL")
+				}
+			default:
+				problems = append(problems, fmt.Sprintf("%s.type %q is not one of directory, email, s3", dprefix, d.Type))
+			}
+		}
+	}
+	return problems
+}
+
+// isHTTPURL reports whether raw is an absolute http or https URL
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ListenAddress returns the host:port the API should listen on
+// validateTags checks that tag names and synonyms are unique, ignoring case
+// and separators, and that each replacement names a current tag
+func (c *Config) validateTags(problems []string) []string {
+	owners := make(map[string]string)
+	for i, def := range c.Tags.Vocabulary {
+		prefix := fmt.Sprintf("tags.vocabulary[%d]", i)
+		if tagKey(def.Name) == "" {
+			problems = append(problems, prefix+".name is required")
+			continue
+		}
+		for _, term := range append([]string{def.Name}, def.Synonyms...) {
+			key := tagKey(term)
+			if owner, taken := owners[key]; taken {
+				problems = append(problems, fmt.Sprintf("%s: %q is already used by tag %q", prefix, term, owner))
+				continue
+			}
+			owners[key] = def.Name
+		}
+		if def.ReplacedBy != "" && !def.Deprecated {
+			problems = append(problems, prefix+".replaced_by is only allowed on a deprecated tag")
+		}
+	}
+	for i, def := range c.Tags.Vocabulary {
+		if def.ReplacedBy == "" {
+			continue
+		}
+		if replacement := c.Tags.lookup(def.ReplacedBy); replacement == nil || replacement.Deprecated || tagKey(replacement.Name) != tagKey(def.ReplacedBy) {
+			problems = append(problems, fmt.Sprintf("tags.vocabulary[%d].replaced_by must name a current tag", i))
+		}
+	}
+	return problems
+}
+
+// validateAnomalyDetection checks the anomaly window, business hours and limits
+func (c *Config) validateAnomalyDetection(problems []string) []string {
+	a := c.AnomalyDetection
+	if a.Enabled && a.WindowMinutes < 1 {
+		problems = append(problems, "anomaly_detection.window_minutes must be at least 1")
+	}
+	if a.BusinessHoursStart < 0 || a.BusinessHoursStart > 23 || a.BusinessHoursEnd < 1 || a.BusinessHoursEnd > 24 {
+		problems = append(problems, "anomaly_detection business hours must be within 0-24")
+	} else if a.BusinessHoursStart >= a.BusinessHoursEnd {
+		problems = append(problems, "anomaly_detection.business_hours_start must be before business_hours_end")
+	}
+	if a.OffHoursExportLimit < 0 || a.CaseSpreadLimit < 0 || a.FailedAccessLimit < 0 {
+		problems = append(problems, "anomaly_detection limits must not be negative")
+	}
+	return problems
+}
+
+func (c *Config) ListenAddress() string {
+	return net.JoinHostPort(c.API.Host, strconv.Itoa(c.API.Port))
+}
+
+// SessionTimeout returns the configured authentication session lifetime
+func (c *Config) SessionTimeout() time.Duration {
+	return time.Duration(c.Security.SessionTimeoutMinutes) * time.Minute
+}
+
+// RetentionPolicy is a default retention period and the rules that override
+// it for tagged evidence
+type RetentionPolicy struct {
+	RetentionDays  int             `json:"retention_days"`
+	RetentionRules []RetentionRule `json:"retention_rules"`
+}
+
+// RetentionPolicy returns the retention policy in force
+func (c *Config) RetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{RetentionDays: c.Storage.RetentionDays, RetentionRules: c.Storage.RetentionRules}
+}
+
+// validate appends the policy's problems, naming fields under prefix
+func (p RetentionPolicy) validate(prefix string, problems []string) []string {
+	if p.RetentionDays <= 0 {
+		problems = append(problems, prefix+"retention_days must be positive")
+	}
+	seenTags := make(map[string]bool)
+	for i, rule := range p.RetentionRules {
+		if rule.Tag == "" {
+			problems = append(problems, fmt.Sprintf("%sretention_rules[%d].tag is required", prefix, i))
+		} else if seenTags[rule.Tag] {
+			problems = append(problems, fmt.Sprintf("%sretention_rules[%d] duplicates tag %q", prefix, i, rule.Tag))
+		}
+		seenTags[rule.Tag] = true
+		if !rule.Indefinite && rule.RetentionDays <= 0 {
+			problems = append(problems, fmt.Sprintf("%sretention_rules[%d].retention_days must be positive unless indefinite", prefix, i))
+		}
+	}
+	return problems
+}
+
+// RetentionFor returns the retention period for evidence carrying the given tags.
+// The longest matching rule wins; indefinite retention overrides any period.
+func (c *Config) RetentionFor(tags []string) (days int, indefinite bool) {
+	return c.RetentionPolicy().RetentionFor(tags)
+}
+
+// RetentionFor returns the policy's retention period for evidence carrying
+// the given tags
+func (p RetentionPolicy) RetentionFor(tags []string) (days int, indefinite bool) {
+	days = p.RetentionDays
+
+	for _, rule := range p.RetentionRules {
+		for _, tag := range tags {
+			if !strings.EqualFold(tag, rule.Tag) {
+				continue
+			}
+			if rule.Indefinite {
+				return 0, true
+			}
+			if rule.RetentionDays > days {
+				days = rule.RetentionDays
+			}
+		}
+	}
+
+	return days, false
+}
+
+func isSupportedHashAlgorithm(name string) bool {
+	return strings.EqualFold(name, "SHA-256")
+}
+
+// NewBWCSystemFromConfig creates a BWC system using the supplied configuration
+func NewBWCSystemFromConfig(cfg *Config) (*BWCSystem, error) {
+	if cfg == nil {
+		return nil, errors.New("configuration is required")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	system, err := NewBWCSystem(cfg.Storage.Path)
+	if err != nil {
+		return nil, err
+	}
+	system.config = cfg
+
+	store, err := openEvidenceStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if store != nil {
+		if err := system.SetEvidenceStore(store); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Storage.Blobs != nil {
+		blobs, err := openBlobStore(*cfg.Storage.Blobs)
+		if err != nil {
+			return nil, err
+		}
+		if err := system.SetBlobStore(blobs); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Storage.WriteAheadLog {
+		system.mu.Lock()
+		err := system.openWriteAheadLogLocked(filepath.Join(cfg.Storage.Path, walFile))
+		system.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Replication.Role == "primary" {
+		journal, err := openReplicationJournal(filepath.Join(cfg.Storage.Path, replicationJournalFile))
+		if err != nil {
+			return nil, err
+		}
+		system.replication = journal
+	}
+
+	// A generated key is kept with a persistent store, whose seals would
+	// otherwise fail to verify after a restart
+	if cfg.Security.SealingKeyFile != "" {
+		sealer, err := loadSealSigner(cfg.Security.SealingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		system.sealer = sealer
+	} else if cfg.Database.Type != "memory" {
+		sealer, err := loadOrCreateSealSigner(filepath.Join(cfg.Storage.Path, sealingKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		system.sealer = sealer
+	}
+
+	if cfg.Security.EnableEncryption {
+		provider, err := newKeyProvider(cfg.Security)
+		if err != nil {
+			return nil, err
+		}
+		system.atRest = provider
+	}
+
+	if cfg.Security.PseudonymKeyFile != "" {
+		key, err := loadPseudonymKey(cfg.Security.PseudonymKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		system.pseudonymKey = key
+	}
+
+	if cfg.Security.HSM.Module != "" {
+		signer, err := loadHSMReportSigner(cfg.Security.ReportSigningCertFile, cfg.Security.HSM)
+		if err != nil {
+			return nil, err
+		}
+		system.reportSigner = signer
+	} else if cfg.Security.ReportSigningCertFile != "" {
+		signer, err := loadReportSigner(cfg.Security.ReportSigningCertFile, cfg.Security.ReportSigningKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		system.reportSigner = signer
+	}
+
+	if cfg.ChainOfCustody.PIVRootsFile != "" {
+		roots, err := loadPIVRoots(cfg.ChainOfCustody.PIVRootsFile)
+		if err != nil {
+			return nil, err
+		}
+		system.pivRoots = roots
+	}
+
+	for _, pc := range cfg.VideoProcessing.Processors {
+		if err := system.RegisterProcessor(newExecProcessor(pc)); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Geocoding.URL != "" {
+		system.SetGeocoder(newNominatimGeocoder(cfg.Geocoding))
+	}
+	if cfg.Timestamping.URL != "" {
+		tsa, err := newTimestampAuthority(cfg.Timestamping)
+		if err != nil {
+			return nil, err
+		}
+		system.tsa = tsa
+	}
+	if len(cfg.OCR.Command) > 0 {
+		system.SetTextExtractor(newExecTextExtractor(cfg.OCR))
+	}
+
+	return system, nil
+}
@@ -0,0 +1,173 @@
+package bwc
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTestConfig writes a configuration file into tmpDir and returns its path
+func writeTestConfig(t *testing.T, tmpDir, content string) string {
+	path := filepath.Join(tmpDir, "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadExampleConfig(t *testing.T) {
+	cfg, err := LoadConfig("../config.example.json")
+	if err != nil {
+		t.Fatalf("LoadConfig failed on example config: %v", err)
+	}
+
+	if cfg.Storage.Path != "./bwc_storage" {
+		t.Errorf("Expected storage path ./bwc_storage, got %s", cfg.Storage.Path)
+	}
+
+	if cfg.Security.HashAlgorithm != "SHA-256" {
+		t.Errorf("Expected hash algorithm SHA-256, got %s", cfg.Security.HashAlgorithm)
+	}
+
+	if cfg.ListenAddress() != "0.0.0.0:8080" {
+		t.Errorf("Expected listen address 0.0.0.0:8080, got %s", cfg.ListenAddress())
+	}
+}
+
+func TestLoadConfigAppliesDefaults(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := writeTestConfig(t, tmpDir, `{"storage": {"path": "/srv/evidence", "retention_days": 365}}`)
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	if cfg.Storage.Path != "/srv/evidence" {
+		t.Errorf("Expected storage path /srv/evidence, got %s", cfg.Storage.Path)
+	}
+
+	if cfg.Security.HashAlgorithm != "SHA-256" {
+		t.Errorf("Expected default hash algorithm, got %s", cfg.Security.HashAlgorithm)
+	}
+
+	if cfg.Security.PasswordMinLength != 12 {
+		t.Errorf("Expected default password length 12, got %d", cfg.Security.PasswordMinLength)
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := writeTestConfig(t, tmpDir, `{"storage": {"pth": "/srv/evidence"}}`)
+
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Expected error for unknown configuration key")
+	}
+}
+
+func TestLoadConfigYAMLNeedsBuildTag(t *testing.T) {
+	if decodeYAMLConfig != nil {
+		t.Skip("built with -tags yaml")
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("storage:\n  path: /srv/evidence\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadConfig(path); err == nil || !strings.Contains(err.Error(), "-tags yaml") {
+		t.Errorf("Expected an error naming the build tag, got %v", err)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.Path = ""
+	cfg.Security.HashAlgorithm = "MD5"
+	cfg.Storage.RetentionRules = []RetentionRule{{Tag: "dui"}}
+	cfg.API.Enabled = true
+	cfg.API.Port = 70000
+
+	err := cfg.Validate()
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Expected ConfigError, got %v", err)
+	}
+
+	if len(cfgErr.Problems) != 5 {
+		t.Errorf("Expected 5 problems, got %d: %v", len(cfgErr.Problems), cfgErr.Problems)
+	}
+
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("Expected default config to be valid: %v", err)
+	}
+}
+
+func TestRetentionFor(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.RetentionDays = 365
+	cfg.Storage.RetentionRules = []RetentionRule{
+		{Tag: "use-of-force", RetentionDays: 3650},
+		{Tag: "homicide", Indefinite: true},
+	}
+
+	if days, indefinite := cfg.RetentionFor([]string{"traffic-stop"}); days != 365 || indefinite {
+		t.Errorf("Expected default retention, got %d days (indefinite %v)", days, indefinite)
+	}
+
+	if days, _ := cfg.RetentionFor([]string{"Use-Of-Force"}); days != 3650 {
+		t.Errorf("Expected 3650 days for use-of-force, got %d", days)
+	}
+
+	if _, indefinite := cfg.RetentionFor([]string{"use-of-force", "homicide"}); !indefinite {
+		t.Error("Expected indefinite retention for homicide")
+	}
+}
+
+func TestNewBWCSystemFromConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(tmpDir, "storage")
+
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	if system.storagePath != cfg.Storage.Path {
+		t.Errorf("Expected storage path %s, got %s", cfg.Storage.Path, system.storagePath)
+	}
+
+	cfg.Security.HashAlgorithm = "CRC32"
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil {
+		t.Error("Expected error for invalid configuration")
+	}
+}
+
+func TestConfigCheckCommand(t *testing.T) {
+	tmpDir := t.TempDir()
+	var stdout, stderr bytes.Buffer
+
+	code := RunCommand([]string{"config", "check", "-config", "../config.example.json"}, &stdout, &stderr)
+	if code != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "is valid") {
+		t.Errorf("Unexpected output: %s", stdout.String())
+	}
+
+	path := writeTestConfig(t, tmpDir, `{"security": {"hash_algorithm": "MD5"}}`)
+	stdout.Reset()
+	stderr.Reset()
+
+	code = RunCommand([]string{"config", "check", "-config", path}, &stdout, &stderr)
+	if code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "security.hash_algorithm") {
+		t.Errorf("Expected hash algorithm problem in output: %s", stderr.String())
+	}
+}
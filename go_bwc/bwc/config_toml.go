@@ -0,0 +1,15 @@
+//go:build toml
+
+package bwc
+
+import "github.com/BurntSushi/toml"
+
+func init() {
+	decodeTOMLConfig = func(data []byte) (map[string]interface{}, error) {
+		tree := make(map[string]interface{})
+		if _, err := toml.Decode(string(data), &tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	}
+}
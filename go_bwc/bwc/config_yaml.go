@@ -0,0 +1,15 @@
+//go:build yaml
+
+package bwc
+
+import "gopkg.in/yaml.v3"
+
+func init() {
+	decodeYAMLConfig = func(data []byte) (map[string]interface{}, error) {
+		tree := make(map[string]interface{})
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		return tree, nil
+	}
+}
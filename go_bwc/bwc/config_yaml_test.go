@@ -0,0 +1,43 @@
+//go:build yaml
+
+package bwc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadYAMLConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "config.yml")
+	content := `
+storage:
+  path: /srv/evidence
+  retention_days: 365
+  allowed_extensions: [".mp4", ".mov"]
+security:
+  password_min_length: 16
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if cfg.Storage.Path != "/srv/evidence" || cfg.Storage.RetentionDays != 365 || len(cfg.Storage.AllowedExtensions) != 2 {
+		t.Errorf("Unexpected storage settings %+v", cfg.Storage)
+	}
+	if cfg.Security.PasswordMinLength != 16 || cfg.Security.HashAlgorithm != "SHA-256" {
+		t.Errorf("Expected the file over the defaults, got %+v", cfg.Security)
+	}
+
+	if err := os.WriteFile(path, []byte("storage:\n  pth: /srv/evidence\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("Expected error for unknown configuration key")
+	}
+}
@@ -0,0 +1,39 @@
+package bwc
+
+import (
+	"context"
+	"io"
+)
+
+// The Context variants of the BWCSystem operations stop when their context is
+// cancelled or its deadline passes, returning an error that wraps ctx.Err().
+// Hashing and copying check it between reads, so a 10 GB recording stops
+// within one read rather than after a full pass. Short operations check it
+// before they change anything; once a change is saved it is not undone. The
+// operations without a context run as if given context.Background(), reading
+// as the system itself; a Context variant given no principal reads as no one,
+// so restricted evidence is refused to it.
+
+// contextReader fails reads once ctx is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// readVia returns a wrap function for the ...Via helpers that stops reads
+// once ctx is done, or nil for a context that can never be
+func readVia(ctx context.Context) func(io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return nil
+	}
+	return func(r io.Reader) io.Reader {
+		return &contextReader{ctx: ctx, r: r}
+	}
+}
@@ -0,0 +1,90 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// cancelAfter is a context that reports itself cancelled once Err has been
+// asked reads times, standing in for a caller cancelling part way through
+type cancelAfter struct {
+	context.Context
+	reads int
+}
+
+func (c *cancelAfter) Done() <-chan struct{} { return make(chan struct{}) }
+
+func (c *cancelAfter) Err() error {
+	if c.reads--; c.reads < 0 {
+		return context.Canceled
+	}
+	return nil
+}
+
+func TestIngestContextStopsHashing(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "long.mp4")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("frame"), 1<<20), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := system.IngestEvidenceContext(ctx, path, "CASE-CTX-001", "OFF-1200", "", "", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled context to stop the ingest, got %v", err)
+	}
+
+	_, err := system.IngestEvidenceContext(&cancelAfter{Context: context.Background(), reads: 5}, path, "CASE-CTX-001", "OFF-1200", "", "", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the hash to stop part way, got %v", err)
+	}
+	if len(system.SearchEvidence("CASE-CTX-001", "", "")) != 0 || len(system.ActiveIngests()) != 0 {
+		t.Error("expected nothing ingested or left under way")
+	}
+	if _, err := system.IngestEvidenceContext(context.Background(), path, "CASE-CTX-001", "OFF-1200", "", "", nil); err != nil {
+		t.Errorf("expected the ingest to succeed uncancelled, got %v", err)
+	}
+}
+
+func TestVerifyIntegrityContextRecordsNothingWhenStopped(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "long.mp4")
+	if err := os.WriteFile(path, bytes.Repeat([]byte("frame"), 1<<20), 0600); err != nil {
+		t.Fatal(err)
+	}
+	evidence, err := system.IngestEvidence(path, "CASE-CTX-002", "OFF-1201", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	checks := len(evidence.IntegrityChecks)
+	valid, err := system.VerifyIntegrityContext(&cancelAfter{Context: context.Background(), reads: 3}, evidence.ID, "AUDITOR-1")
+	if valid || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the check to stop part way, got %v, %v", valid, err)
+	}
+	if stored, _ := system.GetEvidence(evidence.ID); len(stored.IntegrityChecks) != checks {
+		t.Errorf("expected a stopped check not to be recorded, got %+v", stored.IntegrityChecks)
+	}
+	for _, log := range system.GetAuditLogs(evidence.ID, "AUDITOR-1") {
+		t.Errorf("expected no audit entry for a stopped check, got %+v", log)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := system.SearchEvidenceContext(ctx, "CASE-CTX-002", "", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled search to fail, got %v", err)
+	}
+	if err := system.TransferCustodyContext(ctx, evidence.ID, "OFF-1201", "DET-1", "Analysis"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled transfer to fail, got %v", err)
+	}
+	if stored, _ := system.GetEvidence(evidence.ID); len(stored.ChainOfCustody) != 1 {
+		t.Errorf("expected no transfer recorded, got %+v", stored.ChainOfCustody)
+	}
+}
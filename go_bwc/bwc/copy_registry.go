@@ -0,0 +1,129 @@
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CopyKind says how a copy of evidence was produced
+type CopyKind string
+
+const (
+	CopyExport   CopyKind = "EXPORT"
+	CopyReport   CopyKind = "REPORT"
+	CopyDownload CopyKind = "DOWNLOAD"
+)
+
+// CopyRecord registers one copy of evidence, or of a report describing it,
+// that left the system. During litigation the registry enumerates every
+// disclosed copy.
+type CopyRecord struct {
+	ID          string    `json:"id"`
+	EvidenceID  string    `json:"evidence_id"`
+	Kind        CopyKind  `json:"kind"`
+	MadeBy      string    `json:"made_by"`
+	MadeAt      time.Time `json:"made_at"`
+	Destination string    `json:"destination"`
+	Purpose     string    `json:"purpose,omitempty"`
+	SHA256      string    `json:"sha256"`
+	Size        int64     `json:"size"`
+	// Encryption describes how the copy was encrypted for transport, if it was
+	Encryption string `json:"encryption,omitempty"`
+}
+
+// registerCopyLocked records a copy of evidenceID whose content hashes to
+// sha256Hex. The operation producing the copy is audited by the caller; the
+// caller must hold bwc.mu for writing.
+func (bwc *BWCSystem) registerCopyLocked(evidenceID string, kind CopyKind, madeBy, destination, purpose, sha256Hex string, size int64) *CopyRecord {
+	bwc.copySeq++
+	record := &CopyRecord{
+		ID:          fmt.Sprintf("CPY-%06d", bwc.copySeq),
+		EvidenceID:  evidenceID,
+		Kind:        kind,
+		MadeBy:      madeBy,
+		MadeAt:      time.Now(),
+		Destination: destination,
+		Purpose:     purpose,
+		SHA256:      sha256Hex,
+		Size:        size,
+	}
+	bwc.copies = append(bwc.copies, record)
+	return record
+}
+
+// copyDetails describes a registered copy for the audit log
+func copyDetails(record *CopyRecord) string {
+	details := fmt.Sprintf("Copy %s (%s) to %s, sha256 %s", record.ID, record.Kind, record.Destination, record.SHA256)
+	if record.Encryption != "" {
+		details += ", encrypted " + record.Encryption
+	}
+	if record.Purpose != "" {
+		details += ": " + record.Purpose
+	}
+	return details
+}
+
+// RegisterCopy records and audits a copy of evidence produced outside the
+// system's own export paths, e.g. a disc burned from the review station
+func (bwc *BWCSystem) RegisterCopy(evidenceID string, kind CopyKind, madeBy, destination, purpose, sha256Hex string, size int64) (*CopyRecord, error) {
+	return bwc.RegisterCopyContext(context.Background(), evidenceID, kind, madeBy, destination, purpose, sha256Hex, size)
+}
+
+// RegisterCopyContext records a copy like RegisterCopy, made by the principal
+// ctx carries when it has one
+func (bwc *BWCSystem) RegisterCopyContext(ctx context.Context, evidenceID string, kind CopyKind, madeBy, destination, purpose, sha256Hex string, size int64) (*CopyRecord, error) {
+	madeBy, auth := actingUser(ctx, madeBy)
+	if err := bwc.authorize(madeBy, PermExport, "Copy registration", evidenceID); err != nil {
+		return nil, err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if bwc.evidenceDB.Get(evidenceID) == nil {
+		return nil, ErrEvidenceNotFound
+	}
+
+	record := bwc.registerCopyLocked(evidenceID, kind, madeBy, destination, purpose, sha256Hex, size)
+	bwc.logAuditAs(auth, madeBy, "REGISTER_COPY", evidenceID, copyDetails(record), "")
+
+	r := *record
+	return &r, nil
+}
+
+// registerCaseCopy records a report copy against every evidence item in caseNumber
+func (bwc *BWCSystem) registerCaseCopy(caseNumber string, kind CopyKind, madeBy, destination, purpose string, data []byte) {
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	ids := make([]string, 0)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.CaseNumber == caseNumber {
+			ids = append(ids, evidence.ID)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		bwc.registerCopyLocked(id, kind, madeBy, destination, purpose, hash, int64(len(data)))
+	}
+}
+
+// CopiesOf lists the registered copies of evidenceID, oldest first
+func (bwc *BWCSystem) CopiesOf(evidenceID string) []CopyRecord {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	results := make([]CopyRecord, 0)
+	for _, record := range bwc.copies {
+		if record.EvidenceID == evidenceID {
+			results = append(results, *record)
+		}
+	}
+	return results
+}
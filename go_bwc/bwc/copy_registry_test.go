@@ -0,0 +1,66 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportRegistersCopy(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-CPY-001", "OFF-501", "Officer Test", "Test Location", nil)
+
+	exportPath := filepath.Join(tmpDir, "discovery.json")
+	if err := system.ExportEvidenceFor(evidence.ID, exportPath, "DA-CLERK-1", "Discovery for defense counsel"); err != nil {
+		t.Fatalf("ExportEvidenceFor failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(exportPath)
+	sum := sha256.Sum256(data)
+
+	copies := system.CopiesOf(evidence.ID)
+	if len(copies) != 1 {
+		t.Fatalf("Expected 1 registered copy, got %d", len(copies))
+	}
+	c := copies[0]
+	if c.Kind != CopyExport || c.MadeBy != "DA-CLERK-1" || c.Destination != exportPath ||
+		c.Purpose != "Discovery for defense counsel" || c.SHA256 != hex.EncodeToString(sum[:]) || c.Size != int64(len(data)) {
+		t.Errorf("Unexpected copy record: %+v", c)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "DA-CLERK-1")
+	if len(logs) != 1 || logs[0].Action != "EXPORT_EVIDENCE" {
+		t.Errorf("Expected EXPORT_EVIDENCE audit entry, got %v", logs)
+	}
+}
+
+func TestRegisterCopy(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	first, _ := system.IngestEvidence(testFile, "CASE-CPY-002", "OFF-502", "Officer Test", "Test Location", nil)
+	second, _ := system.IngestEvidence(testFile, "CASE-CPY-002", "OFF-503", "Officer Test", "Test Location", nil)
+
+	if _, err := system.RegisterCopy(first.ID, CopyDownload, "TECH-1", "DVD for court", "Trial exhibit 4", first.FileHash, first.FileSize); err != nil {
+		t.Fatalf("RegisterCopy failed: %v", err)
+	}
+	if _, err := system.RegisterCopy("BWC-MISSING", CopyDownload, "TECH-1", "DVD", "", "", 0); err == nil {
+		t.Error("Expected error for unknown evidence")
+	}
+
+	system.registerCaseCopy("CASE-CPY-002", CopyReport, "SGT-1", "API download to 10.0.0.1", "court report", []byte("report"))
+
+	if n := len(system.CopiesOf(first.ID)); n != 2 {
+		t.Errorf("Expected 2 copies of the first item, got %d", n)
+	}
+	copies := system.CopiesOf(second.ID)
+	if len(copies) != 1 || copies[0].Kind != CopyReport {
+		t.Errorf("Expected the case report copy on the second item, got %+v", copies)
+	}
+}
@@ -0,0 +1,228 @@
+package bwc
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TransferReceipt documents a batch custody transfer. It is signed with the
+// system sealing key so a printed or exported copy can be checked later.
+type TransferReceipt struct {
+	ID          string                `json:"id"`
+	FromOfficer string                `json:"from_officer"`
+	ToOfficer   string                `json:"to_officer"`
+	Purpose     string                `json:"purpose"`
+	Timestamp   time.Time             `json:"timestamp"`
+	Items       []TransferReceiptItem `json:"items"`
+	KeyID       string                `json:"key_id"`
+	Signature   string                `json:"signature"`
+}
+
+// TransferReceiptItem is one evidence item handed over in a batch transfer
+type TransferReceiptItem struct {
+	EvidenceID   string `json:"evidence_id"`
+	CaseNumber   string `json:"case_number"`
+	VerifiedHash string `json:"verified_hash"`
+	// EntryHash is the hash of the custody entry the transfer appended
+	EntryHash string `json:"entry_hash"`
+}
+
+// payload is the message the receipt signature covers
+func (r *TransferReceipt) payload() []byte {
+	lines := []string{
+		"BWC-TRANSFER-RECEIPT-v1",
+		r.ID,
+		r.FromOfficer,
+		r.ToOfficer,
+		r.Purpose,
+		r.Timestamp.UTC().Format(time.RFC3339Nano),
+	}
+	for _, item := range r.Items {
+		lines = append(lines, strings.Join([]string{item.EvidenceID, item.CaseNumber, item.VerifiedHash, item.EntryHash}, " "))
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// Text renders the receipt as a printable document
+func (r *TransferReceipt) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "EVIDENCE TRANSFER RECEIPT %s\n", r.ID)
+	fmt.Fprintf(&b, "Date:    %s\n", r.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(&b, "From:    %s\n", r.FromOfficer)
+	fmt.Fprintf(&b, "To:      %s\n", r.ToOfficer)
+	fmt.Fprintf(&b, "Purpose: %s\n", r.Purpose)
+	fmt.Fprintf(&b, "Items:   %d\n\n", len(r.Items))
+	for i, item := range r.Items {
+		fmt.Fprintf(&b, "%3d. %s  case %s\n", i+1, item.EvidenceID, item.CaseNumber)
+		fmt.Fprintf(&b, "     SHA-256 %s\n", item.VerifiedHash)
+		fmt.Fprintf(&b, "     Entry   %s\n", item.EntryHash)
+	}
+	fmt.Fprintf(&b, "\nSigned with key %s\n%s\n", r.KeyID, r.Signature)
+	b.WriteString("\nReleased by: ______________________   Received by: ______________________\n")
+	return b.String()
+}
+
+// TransferCustodyBatch hands every listed item from fromOfficer to toOfficer
+// in one step. Each item is checked and its integrity verified first; if any
+// item fails, nothing is transferred. On success a single signed receipt
+// covers the whole batch.
+func (bwc *BWCSystem) TransferCustodyBatch(evidenceIDs []string, fromOfficer, toOfficer, purpose string) (*TransferReceipt, error) {
+	if err := bwc.authorize(fromOfficer, PermTransfer, "Batch custody transfer", ""); err != nil {
+		return nil, err
+	}
+	if len(evidenceIDs) == 0 {
+		return nil, errors.New("no evidence selected")
+	}
+	if err := bwc.checkTransferOfficers(fromOfficer, toOfficer); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if bwc.config.ChainOfCustody.RequireSignature {
+		return nil, errors.New("a signature is required on custody hand-offs - transfer items individually")
+	}
+
+	// Check every item before changing any of them
+	batch := make([]*Evidence, 0, len(evidenceIDs))
+	hashes := make([]string, 0, len(evidenceIDs))
+	seen := make(map[string]bool)
+	for _, id := range evidenceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		evidence := bwc.evidenceDB.Get(id)
+		if evidence == nil {
+			return nil, fmt.Errorf("%s: %w", id, ErrEvidenceNotFound)
+		}
+		if err := bwc.rejectIfSealedLocked(evidence, fromOfficer, "Custody transfer"); err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		if checkout, out := bwc.checkouts[id]; out {
+			return nil, fmt.Errorf("%s: evidence is checked out to %s and must be checked in first", id, checkout.CheckedOutTo)
+		}
+		if err := bwc.preTransferHooksLocked(evidence, fromOfficer, toOfficer, purpose); err != nil {
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		currentHash, err := bwc.hashEvidence(evidence)
+		if err != nil {
+			return nil, fmt.Errorf("%s: failed to verify integrity: %w", id, err)
+		}
+		if currentHash != evidence.FileHash {
+			bwc.publishTransferIntegrityAlert(evidence, fromOfficer, currentHash)
+			bwc.logAudit(fromOfficer, "TRANSFER_BATCH_REJECTED", id,
+				fmt.Sprintf("Integrity check failed - batch of %d to %s not transferred", len(evidenceIDs), toOfficer), "")
+			return nil, fmt.Errorf("%s: %w", id, &BWCError{Code: CodeIntegrityFailure, Message: "integrity check failed - cannot transfer compromised evidence"})
+		}
+		batch = append(batch, evidence)
+		hashes = append(hashes, currentHash)
+	}
+
+	bwc.transferReceiptSeq++
+	receipt := &TransferReceipt{
+		ID:          fmt.Sprintf("TRC-%06d", bwc.transferReceiptSeq),
+		FromOfficer: fromOfficer,
+		ToOfficer:   toOfficer,
+		Purpose:     purpose,
+		Timestamp:   time.Now(),
+		Items:       make([]TransferReceiptItem, 0, len(batch)),
+		KeyID:       bwc.sealer.keyID,
+	}
+
+	// Entries are built before any is appended so a hashing error leaves
+	// every chain untouched
+	entries := make([]CustodyEntry, len(batch))
+	for i, evidence := range batch {
+		entry := CustodyEntry{
+			Timestamp:    receipt.Timestamp,
+			FromOfficer:  fromOfficer,
+			ToOfficer:    toOfficer,
+			Action:       "TRANSFERRED",
+			Purpose:      fmt.Sprintf("%s (receipt %s)", purpose, receipt.ID),
+			VerifiedHash: hashes[i],
+		}
+		var err error
+		entry.EntryHash, err = custodyEntryHash(entry)
+		if err != nil {
+			bwc.transferReceiptSeq--
+			return nil, fmt.Errorf("%s: %w", evidence.ID, err)
+		}
+		entries[i] = entry
+		receipt.Items = append(receipt.Items, TransferReceiptItem{
+			EvidenceID:   evidence.ID,
+			CaseNumber:   evidence.CaseNumber,
+			VerifiedHash: hashes[i],
+			EntryHash:    entry.EntryHash,
+		})
+	}
+	receipt.Signature = bwc.sealer.sign(receipt.payload())
+
+	// Every item is saved before any is audited. If one cannot be, the items
+	// already written are put back, so the batch moves all or nothing; a
+	// write that fails again is audited by saveLocked.
+	previous := make([]Evidence, len(batch))
+	for i, evidence := range batch {
+		previous[i] = copyEvidence(evidence)
+		evidence.ChainOfCustody = append(evidence.ChainOfCustody, entries[i])
+		markModified(evidence, receipt.Timestamp)
+		if err := bwc.saveLocked(evidence); err != nil {
+			for j := i; j >= 0; j-- {
+				*batch[j] = previous[j]
+				if j < i {
+					bwc.saveLocked(batch[j])
+				}
+			}
+			bwc.transferReceiptSeq--
+			return nil, fmt.Errorf("%s: %w", evidence.ID, err)
+		}
+	}
+	for _, evidence := range batch {
+		bwc.logAudit(fromOfficer, "TRANSFER_CUSTODY", evidence.ID,
+			fmt.Sprintf("Transferred to %s - %s (receipt %s)", toOfficer, purpose, receipt.ID), "")
+		bwc.publishEvidenceChange(EventCustodyTransferred, evidence, fromOfficer, EvidenceChange{
+			FromOfficer: fromOfficer,
+			ToOfficer:   toOfficer,
+			Purpose:     purpose,
+		})
+	}
+	bwc.transferReceipts[receipt.ID] = receipt
+	bwc.logAudit(fromOfficer, "TRANSFER_BATCH", "",
+		fmt.Sprintf("Receipt %s: %d items transferred to %s - %s", receipt.ID, len(batch), toOfficer, purpose), "")
+
+	return receipt, nil
+}
+
+// GetTransferReceipt returns a copy of a batch transfer receipt
+func (bwc *BWCSystem) GetTransferReceipt(receiptID string) (*TransferReceipt, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	receipt, exists := bwc.transferReceipts[receiptID]
+	if !exists {
+		return nil, errors.New("transfer receipt not found")
+	}
+	copied := *receipt
+	copied.Items = append([]TransferReceiptItem(nil), receipt.Items...)
+	return &copied, nil
+}
+
+// VerifyTransferReceipt reports whether a receipt's signature is valid for
+// this system's sealing key
+func (bwc *BWCSystem) VerifyTransferReceipt(receipt *TransferReceipt) bool {
+	signature, err := base64.StdEncoding.DecodeString(receipt.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(bwc.SealPublicKey(), receipt.payload(), signature)
+}
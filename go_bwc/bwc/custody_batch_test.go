@@ -0,0 +1,139 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTransferCustodyBatch(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TRB-001", fmt.Sprintf("OFF-%d", 941+i), "Officer Test", "Test Location", nil)
+		if err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+		ids = append(ids, evidence.ID)
+	}
+
+	receipt, err := system.TransferCustodyBatch(append(ids, ids[0]), "ROOM-A", "ROOM-B", "Relocation")
+	if err != nil {
+		t.Fatalf("TransferCustodyBatch failed: %v", err)
+	}
+	if receipt.ID != "TRC-000001" || len(receipt.Items) != 3 {
+		t.Fatalf("Expected one receipt for 3 items, got %+v", receipt)
+	}
+	for i, id := range ids {
+		chain := system.evidenceDB.Get(id).ChainOfCustody
+		last := chain[len(chain)-1]
+		if len(chain) != 2 || last.Action != "TRANSFERRED" || last.ToOfficer != "ROOM-B" || !strings.Contains(last.Purpose, receipt.ID) {
+			t.Errorf("%s: unexpected custody entry %+v", id, last)
+		}
+		if item := receipt.Items[i]; item.EvidenceID != id || item.EntryHash != last.EntryHash || item.VerifiedHash != system.evidenceDB.Get(id).FileHash {
+			t.Errorf("%s: receipt item does not match the chain: %+v", id, item)
+		}
+	}
+
+	if !system.VerifyTransferReceipt(receipt) {
+		t.Error("Expected the receipt signature to verify")
+	}
+	stored, err := system.GetTransferReceipt(receipt.ID)
+	if err != nil {
+		t.Fatalf("GetTransferReceipt failed: %v", err)
+	}
+	stored.ToOfficer = "ROOM-C"
+	if system.VerifyTransferReceipt(stored) {
+		t.Error("Expected an altered receipt to fail verification")
+	}
+
+	text := receipt.Text()
+	for _, want := range []string{"EVIDENCE TRANSFER RECEIPT TRC-000001", "From:    ROOM-A", "Items:   3", ids[2], receipt.Signature} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected receipt text to contain %q", want)
+		}
+	}
+
+	logs := system.GetAuditLogs("", "ROOM-A")
+	if last := logs[len(logs)-1]; len(logs) != 4 || last.Action != "TRANSFER_BATCH" || !strings.Contains(last.Details, "3 items") {
+		t.Errorf("Expected per-item and batch audit entries, got %v", logs)
+	}
+}
+
+func TestTransferCustodyBatchAllOrNothing(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TRB-002", fmt.Sprintf("OFF-%d", 944+i), "Officer Test", "Test Location", nil)
+		ids = append(ids, evidence.ID)
+	}
+	sealed, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TRB-002", "OFF-947", "Officer Test", "Test Location", nil)
+	system.SealEvidence(sealed.ID, "Court order 24-207")
+
+	os.WriteFile(system.evidenceDB.Get(ids[2]).FilePath, []byte("altered"), 0600)
+
+	tests := []struct {
+		name string
+		ids  []string
+		want string
+	}{
+		{"empty", nil, "no evidence selected"},
+		{"missing", []string{ids[0], "BWC-MISSING"}, "BWC-MISSING: evidence not found"},
+		{"sealed", []string{ids[0], sealed.ID}, "sealed"},
+		{"tampered", ids, "integrity check failed"},
+	}
+	for _, tt := range tests {
+		if _, err := system.TransferCustodyBatch(tt.ids, "ROOM-A", "ROOM-B", "Relocation"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error %q, got %v", tt.name, tt.want, err)
+		}
+	}
+	for _, id := range append(ids, sealed.ID) {
+		if n := len(system.evidenceDB.Get(id).ChainOfCustody); n != 1 {
+			t.Errorf("%s: expected no transfer, got %d custody entries", id, n)
+		}
+	}
+	if _, err := system.GetTransferReceipt("TRC-000001"); err == nil {
+		t.Error("Expected no receipt for a failed batch")
+	}
+}
+
+func TestTransferCustodyBatchFailedSave(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	store := newRecordingStore()
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TRB-003", fmt.Sprintf("OFF-%d", 948+i), "Officer Test", "Test Location", nil)
+		ids = append(ids, evidence.ID)
+	}
+
+	// The last item cannot be written, so the two before it are put back
+	store.fail, store.failID = errors.New("disk full"), ids[2]
+	if _, err := system.TransferCustodyBatch(ids, "ROOM-A", "ROOM-B", "Relocation"); err == nil || !strings.Contains(err.Error(), ids[2]) {
+		t.Fatalf("Expected the failed write to fail the batch, got %v", err)
+	}
+	for _, id := range ids {
+		if evidence := store.Get(id); len(evidence.ChainOfCustody) != 1 || evidence.Revision != 1 {
+			t.Errorf("%s: expected no transfer, got %d custody entries at revision %d", id, len(evidence.ChainOfCustody), evidence.Revision)
+		}
+	}
+	if logs := system.GetAuditLogs("", "ROOM-A"); len(logs) != 0 {
+		t.Errorf("Expected nothing audited for an unsaved batch, got %v", logs)
+	}
+
+	store.fail = nil
+	receipt, err := system.TransferCustodyBatch(ids, "ROOM-A", "ROOM-B", "Relocation")
+	if err != nil || receipt.ID != "TRC-000001" {
+		t.Fatalf("Expected the retried batch to take the first receipt, got %+v, %v", receipt, err)
+	}
+}
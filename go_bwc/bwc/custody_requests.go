@@ -0,0 +1,185 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CustodyRequestStatus represents the state of a requested custody hand-off
+type CustodyRequestStatus string
+
+const (
+	RequestPending  CustodyRequestStatus = "PENDING"
+	RequestAccepted CustodyRequestStatus = "ACCEPTED"
+	RequestDeclined CustodyRequestStatus = "DECLINED"
+)
+
+// CustodyRequest is a custody transfer awaiting acceptance by the receiving officer
+type CustodyRequest struct {
+	ID          string               `json:"id"`
+	EvidenceID  string               `json:"evidence_id"`
+	FromOfficer string               `json:"from_officer"`
+	ToOfficer   string               `json:"to_officer"`
+	Purpose     string               `json:"purpose"`
+	Status      CustodyRequestStatus `json:"status"`
+	RequestedAt time.Time            `json:"requested_at"`
+	ResolvedAt  time.Time            `json:"resolved_at"`
+	Resolution  string               `json:"resolution"`
+}
+
+// RequestCustodyTransfer records a hand-off that takes effect once the receiving officer accepts it
+func (bwc *BWCSystem) RequestCustodyTransfer(evidenceID, fromOfficer, toOfficer, purpose string) (*CustodyRequest, error) {
+	return bwc.RequestCustodyTransferContext(context.Background(), evidenceID, fromOfficer, toOfficer, purpose)
+}
+
+// RequestCustodyTransferContext asks for a transfer like
+// RequestCustodyTransfer, from the principal ctx carries when it has one
+func (bwc *BWCSystem) RequestCustodyTransferContext(ctx context.Context, evidenceID, fromOfficer, toOfficer, purpose string) (*CustodyRequest, error) {
+	fromOfficer, auth := actingUser(ctx, fromOfficer)
+	if err := bwc.authorize(fromOfficer, PermTransfer, "Custody transfer request", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := ValidateOfficerID(toOfficer); err != nil {
+		return nil, err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, fromOfficer, "Custody transfer request"); err != nil {
+		return nil, err
+	}
+
+	if fromOfficer == toOfficer {
+		return nil, errors.New("cannot request custody transfer to the current holder")
+	}
+
+	for _, req := range bwc.custodyRequests {
+		if req.EvidenceID == evidenceID && req.Status == RequestPending {
+			return nil, fmt.Errorf("custody transfer %s is already pending for this evidence", req.ID)
+		}
+	}
+
+	bwc.custodyRequestSeq++
+	req := &CustodyRequest{
+		ID:          fmt.Sprintf("CTR-%06d", bwc.custodyRequestSeq),
+		EvidenceID:  evidenceID,
+		FromOfficer: fromOfficer,
+		ToOfficer:   toOfficer,
+		Purpose:     purpose,
+		Status:      RequestPending,
+		RequestedAt: time.Now(),
+	}
+	bwc.custodyRequests[req.ID] = req
+
+	bwc.logAuditAs(auth, fromOfficer, "REQUEST_CUSTODY_TRANSFER", evidenceID,
+		fmt.Sprintf("Transfer %s to %s requested - %s", req.ID, toOfficer, purpose), "")
+
+	return req, nil
+}
+
+// AcceptCustodyTransfer completes a pending request; only the receiving officer may accept it
+func (bwc *BWCSystem) AcceptCustodyTransfer(requestID, officerID string) error {
+	return bwc.AcceptCustodyTransferSigned(requestID, officerID, nil)
+}
+
+// AcceptCustodyTransferSigned accepts a request with the receiving officer's electronic signature
+func (bwc *BWCSystem) AcceptCustodyTransferSigned(requestID, officerID string, sig *CustodySignature) error {
+	if err := bwc.authorize(officerID, PermViewEvidence, "Custody transfer acceptance", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	req, err := bwc.pendingRequestFor(requestID, officerID)
+	if err != nil {
+		return err
+	}
+
+	if err := bwc.transferCustodyLocked(req.EvidenceID, req.FromOfficer, req.ToOfficer, req.Purpose, sig, nil); err != nil {
+		return err
+	}
+
+	req.Status = RequestAccepted
+	req.ResolvedAt = time.Now()
+
+	bwc.logAudit(officerID, "ACCEPT_CUSTODY_TRANSFER", req.EvidenceID,
+		fmt.Sprintf("Transfer %s from %s accepted", req.ID, req.FromOfficer), "")
+
+	return nil
+}
+
+// DeclineCustodyTransfer rejects a pending request, leaving custody unchanged
+func (bwc *BWCSystem) DeclineCustodyTransfer(requestID, officerID, reason string) error {
+	if err := bwc.authorize(officerID, PermViewEvidence, "Custody transfer decline", ""); err != nil {
+		return err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	req, err := bwc.pendingRequestFor(requestID, officerID)
+	if err != nil {
+		return err
+	}
+
+	req.Status = RequestDeclined
+	req.ResolvedAt = time.Now()
+	req.Resolution = reason
+
+	bwc.logAudit(officerID, "DECLINE_CUSTODY_TRANSFER", req.EvidenceID,
+		fmt.Sprintf("Transfer %s from %s declined - %s", req.ID, req.FromOfficer, reason), "")
+
+	return nil
+}
+
+// PendingCustodyRequests lists requests awaiting acceptance, optionally only those addressed to officerID
+func (bwc *BWCSystem) PendingCustodyRequests(officerID string) []*CustodyRequest {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	results := make([]*CustodyRequest, 0)
+	for _, req := range bwc.custodyRequests {
+		if req.Status != RequestPending {
+			continue
+		}
+		if officerID != "" && req.ToOfficer != officerID {
+			continue
+		}
+		results = append(results, req)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+
+	return results
+}
+
+// pendingRequestFor looks up a pending request addressed to officerID; the caller must hold bwc.mu
+func (bwc *BWCSystem) pendingRequestFor(requestID, officerID string) (*CustodyRequest, error) {
+	req, exists := bwc.custodyRequests[requestID]
+	if !exists {
+		return nil, errors.New("custody request not found")
+	}
+
+	if req.Status != RequestPending {
+		return nil, fmt.Errorf("custody request is already %s", req.Status)
+	}
+
+	if req.ToOfficer != officerID {
+		return nil, errors.New("only the receiving officer may resolve a custody request")
+	}
+
+	return req, nil
+}
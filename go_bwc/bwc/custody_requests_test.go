@@ -0,0 +1,92 @@
+package bwc
+
+import "testing"
+
+func TestCustodyRequestAcceptance(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	evidence, err := system.IngestEvidence(testFile, "CASE-CTR-001", "OFF-123", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	req, err := system.RequestCustodyTransfer(evidence.ID, "OFF-123", "CUS-001", "Property room intake")
+	if err != nil {
+		t.Fatalf("RequestCustodyTransfer failed: %v", err)
+	}
+
+	if req.Status != RequestPending {
+		t.Errorf("Expected status %s, got %s", RequestPending, req.Status)
+	}
+
+	if _, err := system.RequestCustodyTransfer(evidence.ID, "OFF-123", "CUS-002", "Duplicate"); err == nil {
+		t.Error("Expected error for second pending request on the same evidence")
+	}
+
+	// Custody must not change until the receiving officer accepts
+	custody, _ := system.GetChainOfCustody(evidence.ID)
+	if len(custody) != 1 {
+		t.Errorf("Expected 1 custody entry before acceptance, got %d", len(custody))
+	}
+
+	if pending := system.PendingCustodyRequests("CUS-001"); len(pending) != 1 {
+		t.Errorf("Expected 1 pending request for CUS-001, got %d", len(pending))
+	}
+
+	if err := system.AcceptCustodyTransfer(req.ID, "CUS-999"); err == nil {
+		t.Error("Expected error when a different officer accepts")
+	}
+
+	if err := system.AcceptCustodyTransfer(req.ID, "CUS-001"); err != nil {
+		t.Fatalf("AcceptCustodyTransfer failed: %v", err)
+	}
+
+	custody, _ = system.GetChainOfCustody(evidence.ID)
+	if len(custody) != 2 || custody[1].ToOfficer != "CUS-001" {
+		t.Errorf("Expected custody to pass to CUS-001, got %v", custody)
+	}
+
+	if err := system.AcceptCustodyTransfer(req.ID, "CUS-001"); err == nil {
+		t.Error("Expected error when accepting an already resolved request")
+	}
+
+	if pending := system.PendingCustodyRequests(""); len(pending) != 0 {
+		t.Errorf("Expected no pending requests, got %d", len(pending))
+	}
+}
+
+func TestCustodyRequestDecline(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	evidence, _ := system.IngestEvidence(testFile, "CASE-CTR-002", "OFF-123", "Officer Test", "Test Location", nil)
+
+	if _, err := system.RequestCustodyTransfer("INVALID-ID", "OFF-123", "CUS-001", "Intake"); err == nil {
+		t.Error("Expected error for non-existent evidence")
+	}
+
+	req, _ := system.RequestCustodyTransfer(evidence.ID, "OFF-123", "CUS-001", "Intake")
+
+	if err := system.DeclineCustodyTransfer(req.ID, "CUS-001", "Packaging damaged"); err != nil {
+		t.Fatalf("DeclineCustodyTransfer failed: %v", err)
+	}
+
+	if req.Status != RequestDeclined || req.Resolution != "Packaging damaged" {
+		t.Errorf("Unexpected request state: %s (%s)", req.Status, req.Resolution)
+	}
+
+	custody, _ := system.GetChainOfCustody(evidence.ID)
+	if len(custody) != 1 {
+		t.Errorf("Expected custody unchanged after decline, got %d entries", len(custody))
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "CUS-001")
+	if len(logs) != 1 || logs[0].Action != "DECLINE_CUSTODY_TRANSFER" {
+		t.Errorf("Expected decline to be audited, got %v", logs)
+	}
+}
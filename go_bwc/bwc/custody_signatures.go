@@ -0,0 +1,241 @@
+package bwc
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// SignatureType identifies the kind of electronic signature on a custody entry
+type SignatureType string
+
+const (
+	// SignatureTyped is a typed acknowledgment, e.g. "I, J. Smith, received this evidence"
+	SignatureTyped SignatureType = "TYPED"
+	// SignatureImage is a captured handwritten signature image
+	SignatureImage SignatureType = "IMAGE"
+	// SignaturePIV is a signature by a PIV/CAC card over the custody signing payload
+	SignaturePIV SignatureType = "PIV"
+	// SignatureEd25519 is a signature over the custody signing payload by the
+	// signer's enrolled officer key
+	SignatureEd25519 SignatureType = "ED25519"
+)
+
+// maxSignatureImageBytes bounds signature pad captures stored on custody entries
+const maxSignatureImageBytes = 128 * 1024
+
+// CustodySignature is the signature artifact attached to a custody entry. It is
+// covered by the entry's EntryHash.
+type CustodySignature struct {
+	Type     SignatureType `json:"type"`
+	SignerID string        `json:"signer_id"`
+	SignedAt time.Time     `json:"signed_at"`
+	// Acknowledgment holds the typed statement for TYPED signatures
+	Acknowledgment string `json:"acknowledgment,omitempty"`
+	// Image holds a PNG, JPEG or GIF for IMAGE signatures
+	Image []byte `json:"image,omitempty"`
+	// Certificate (DER) and Value hold the card certificate and its signature
+	// over the custody signing payload for PIV signatures
+	Certificate []byte `json:"certificate,omitempty"`
+	Value       []byte `json:"value,omitempty"`
+	// KeyID names the officer key of ED25519 signatures, whose Value is the
+	// signature over the custody signing payload
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// custodySigningPayload is the statement a PIV card signs for a hand-off. It
+// omits the entry timestamp, which is not known until the entry is recorded.
+func custodySigningPayload(evidence *Evidence, fromOfficer, toOfficer, action, purpose string) []byte {
+	return []byte(strings.Join([]string{
+		"BWC-CUSTODY-v1",
+		evidence.ID,
+		fromOfficer,
+		toOfficer,
+		action,
+		purpose,
+		evidence.FileHash,
+	}, "\n"))
+}
+
+// CustodySigningPayload returns the bytes a PIV card must sign for a hand-off of
+// evidence with the given parties, action and purpose
+func (bwc *BWCSystem) CustodySigningPayload(evidenceID, fromOfficer, toOfficer, action, purpose string) ([]byte, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	return custodySigningPayload(evidence, fromOfficer, toOfficer, action, purpose), nil
+}
+
+// custodyEntryHash hashes a custody entry, including its signature, without its EntryHash
+func custodyEntryHash(entry CustodyEntry) (string, error) {
+	entry.EntryHash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal custody entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// validateCustodySignature checks that sig is complete, was made by one of the
+// parties to the hand-off and, for PIV signatures, verifies it over payload
+func (bwc *BWCSystem) validateCustodySignature(sig *CustodySignature, payload []byte, fromOfficer, toOfficer string) error {
+	if sig.SignerID == "" {
+		return errors.New("signature signer is required")
+	}
+	if sig.SignerID != fromOfficer && sig.SignerID != toOfficer {
+		return fmt.Errorf("signer %s is not a party to this hand-off", sig.SignerID)
+	}
+	if sig.SignedAt.IsZero() {
+		sig.SignedAt = time.Now()
+	}
+
+	switch sig.Type {
+	case SignatureTyped:
+		if strings.TrimSpace(sig.Acknowledgment) == "" {
+			return errors.New("typed signature requires an acknowledgment")
+		}
+	case SignatureImage:
+		if len(sig.Image) == 0 || len(sig.Image) > maxSignatureImageBytes {
+			return fmt.Errorf("signature image must be between 1 and %d bytes", maxSignatureImageBytes)
+		}
+		switch http.DetectContentType(sig.Image) {
+		case "image/png", "image/jpeg", "image/gif":
+		default:
+			return errors.New("signature image must be PNG, JPEG or GIF")
+		}
+	case SignaturePIV:
+		return bwc.verifyPIVSignature(sig, payload)
+	case SignatureEd25519:
+		return bwc.verifyOfficerSignatureLocked(sig, payload)
+	default:
+		return fmt.Errorf("unknown signature type %q", sig.Type)
+	}
+	return nil
+}
+
+// verifyPIVSignature checks the card certificate and its signature over payload
+func (bwc *BWCSystem) verifyPIVSignature(sig *CustodySignature, payload []byte) error {
+	cert, err := x509.ParseCertificate(sig.Certificate)
+	if err != nil {
+		return fmt.Errorf("invalid PIV certificate: %w", err)
+	}
+
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return errors.New("PIV certificate is not currently valid")
+	}
+	if bwc.pivRoots != nil {
+		opts := x509.VerifyOptions{Roots: bwc.pivRoots, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}
+		if _, err := cert.Verify(opts); err != nil {
+			return fmt.Errorf("PIV certificate is not trusted: %w", err)
+		}
+	}
+
+	if !pivSignatureMatches(cert, payload, sig.Value) {
+		return errors.New("PIV signature verification failed")
+	}
+	return nil
+}
+
+// verifyOfficerSignatureLocked checks an ED25519 signature against the
+// signer's enrolled key, recording which key it was. The caller must hold
+// bwc.mu.
+func (bwc *BWCSystem) verifyOfficerSignatureLocked(sig *CustodySignature, payload []byte) error {
+	key := bwc.activeOfficerKeyLocked(sig.SignerID)
+	if key == nil {
+		return fmt.Errorf("%s has no enrolled signing key", sig.SignerID)
+	}
+	if sig.KeyID != "" && sig.KeyID != key.KeyID {
+		return fmt.Errorf("key %s is not the enrolled signing key of %s", sig.KeyID, sig.SignerID)
+	}
+	if !ed25519.Verify(key.PublicKey, payload, sig.Value) {
+		return errors.New("Ed25519 signature verification failed")
+	}
+	sig.KeyID = key.KeyID
+	return nil
+}
+
+// loadPIVRoots reads a PEM bundle of trusted PIV issuing certificates
+func loadPIVRoots(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PIV roots: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// VerifyCustodySignatures recomputes every custody entry hash and re-verifies PIV
+// and officer signatures. An officer signature fails if its key is unknown or
+// was revoked before the entry was recorded. It returns the indexes of
+// entries that fail.
+func (bwc *BWCSystem) VerifyCustodySignatures(evidenceID string) ([]int, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+
+	failed := make([]int, 0)
+	for i, entry := range evidence.ChainOfCustody {
+		if entry.EntryHash == "" {
+			continue
+		}
+		hash, err := custodyEntryHash(entry)
+		if err != nil || hash != entry.EntryHash {
+			failed = append(failed, i)
+			continue
+		}
+		sig := entry.Signature
+		if sig == nil {
+			continue
+		}
+		payload := custodySigningPayload(evidence, entry.FromOfficer, entry.ToOfficer, entry.Action, entry.Purpose)
+		switch sig.Type {
+		case SignaturePIV:
+			cert, err := x509.ParseCertificate(sig.Certificate)
+			if err != nil || !pivSignatureMatches(cert, payload, sig.Value) {
+				failed = append(failed, i)
+			}
+		case SignatureEd25519:
+			key := bwc.officerKeys[sig.KeyID]
+			if key == nil || key.OfficerID != sig.SignerID || (key.RevokedAt != nil && key.RevokedAt.Before(entry.Timestamp)) ||
+				!ed25519.Verify(key.PublicKey, payload, sig.Value) {
+				failed = append(failed, i)
+			}
+		}
+	}
+	return failed, nil
+}
+
+// pivSignatureMatches checks a SHA-256 PIV signature without regard to certificate
+// validity, which may have lapsed since the hand-off was signed
+func pivSignatureMatches(cert *x509.Certificate, payload, value []byte) bool {
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return cert.CheckSignature(x509.ECDSAWithSHA256, payload, value) == nil
+	case *rsa.PublicKey:
+		return cert.CheckSignature(x509.SHA256WithRSA, payload, value) == nil
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"image"
+	"image/png"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testPIVCard returns a self-signed certificate and its key, standing in for a PIV card
+func testPIVCard(t *testing.T) ([]byte, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "DET-456"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der, key
+}
+
+func signPIV(t *testing.T, key *ecdsa.PrivateKey, payload []byte) []byte {
+	t.Helper()
+	digest := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestTypedSignatureOnTransfer(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SIG-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	sig := &CustodySignature{Type: SignatureTyped, SignerID: "DET-456", Acknowledgment: "Received by Det. Jones"}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-123", "DET-456", "Analysis", sig); err != nil {
+		t.Fatalf("TransferCustodySigned failed: %v", err)
+	}
+
+	chain, _ := system.GetChainOfCustody(evidence.ID)
+	entry := chain[len(chain)-1]
+	if entry.Signature == nil || entry.Signature.Acknowledgment != "Received by Det. Jones" || entry.Signature.SignedAt.IsZero() {
+		t.Errorf("Expected typed signature on custody entry, got %+v", entry.Signature)
+	}
+	if hash, _ := custodyEntryHash(entry); entry.EntryHash == "" || hash != entry.EntryHash {
+		t.Errorf("Expected entry hash %s, got %s", hash, entry.EntryHash)
+	}
+
+	sig.Acknowledgment = "changed after signing"
+	if failed, _ := system.VerifyCustodySignatures(evidence.ID); len(failed) != 0 {
+		t.Errorf("Expected stored signature to be independent of the caller's, failed %v", failed)
+	}
+
+	system.mu.Lock()
+	system.evidenceDB.Get(evidence.ID).ChainOfCustody[1].Signature.Acknowledgment = "forged"
+	system.mu.Unlock()
+	if failed, _ := system.VerifyCustodySignatures(evidence.ID); len(failed) != 1 || failed[0] != 1 {
+		t.Errorf("Expected altered entry 1 to fail verification, got %v", failed)
+	}
+}
+
+func TestSignatureValidation(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SIG-002", "OFF-124", "Officer Test", "Test Location", nil)
+
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 40, 10)))
+
+	tests := []struct {
+		name  string
+		sig   *CustodySignature
+		valid bool
+	}{
+		{"typed", &CustodySignature{Type: SignatureTyped, SignerID: "OFF-124", Acknowledgment: "Released"}, true},
+		{"image", &CustodySignature{Type: SignatureImage, SignerID: "DET-456", Image: img.Bytes()}, true},
+		{"empty acknowledgment", &CustodySignature{Type: SignatureTyped, SignerID: "OFF-124"}, false},
+		{"not an image", &CustodySignature{Type: SignatureImage, SignerID: "OFF-124", Image: []byte("plain text")}, false},
+		{"signer not a party", &CustodySignature{Type: SignatureTyped, SignerID: "OFF-999", Acknowledgment: "Hi"}, false},
+		{"unknown type", &CustodySignature{Type: "STAMP", SignerID: "OFF-124"}, false},
+	}
+
+	from, to := "OFF-124", "DET-456"
+	for _, tt := range tests {
+		err := system.TransferCustodySigned(evidence.ID, from, to, "Analysis", tt.sig)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got %v", tt.name, tt.valid, err)
+		}
+		if err == nil {
+			from, to = to, from
+		}
+	}
+}
+
+func TestPIVSignature(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SIG-003", "OFF-125", "Officer Test", "Test Location", nil)
+	cert, key := testPIVCard(t)
+
+	payload, err := system.CustodySigningPayload(evidence.ID, "OFF-125", "DET-456", "TRANSFERRED", "Analysis")
+	if err != nil {
+		t.Fatalf("CustodySigningPayload failed: %v", err)
+	}
+
+	wrong := &CustodySignature{Type: SignaturePIV, SignerID: "DET-456", Certificate: cert,
+		Value: signPIV(t, key, []byte("some other statement"))}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-125", "DET-456", "Analysis", wrong); err == nil {
+		t.Error("Expected PIV signature over the wrong payload to be rejected")
+	}
+
+	sig := &CustodySignature{Type: SignaturePIV, SignerID: "DET-456", Certificate: cert, Value: signPIV(t, key, payload)}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-125", "DET-456", "Analysis", sig); err != nil {
+		t.Fatalf("TransferCustodySigned with PIV failed: %v", err)
+	}
+	if failed, _ := system.VerifyCustodySignatures(evidence.ID); len(failed) != 0 {
+		t.Errorf("Expected PIV signature to verify, failed %v", failed)
+	}
+}
+
+func TestPIVRootsFromConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	trusted, _ := testPIVCard(t)
+	rootsFile := filepath.Join(tmpDir, "piv-roots.pem")
+	os.WriteFile(rootsFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: trusted}), 0600)
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(tmpDir, "storage")
+	cfg.ChainOfCustody.PIVRootsFile = rootsFile
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SIG-004", "OFF-126", "Officer Test", "Test Location", nil)
+	payload, _ := system.CustodySigningPayload(evidence.ID, "OFF-126", "DET-456", "TRANSFERRED", "Analysis")
+
+	untrusted, key := testPIVCard(t)
+	sig := &CustodySignature{Type: SignaturePIV, SignerID: "DET-456", Certificate: untrusted, Value: signPIV(t, key, payload)}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-126", "DET-456", "Analysis", sig); err == nil {
+		t.Error("Expected certificate outside the PIV roots to be rejected")
+	}
+
+	os.WriteFile(rootsFile, []byte("no certificates"), 0600)
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil {
+		t.Error("Expected error for PIV roots file without certificates")
+	}
+}
+
+func TestRequireSignaturePolicy(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.ChainOfCustody.RequireSignature = true
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SIG-005", "OFF-127", "Officer Test", "Test Location", nil)
+
+	if err := system.TransferCustody(evidence.ID, "OFF-127", "DET-456", "Analysis"); err == nil {
+		t.Error("Expected unsigned transfer to be refused by policy")
+	}
+	if _, err := system.CheckOutEvidence(evidence.ID, "OFF-127", "COURT-7", "Trial exhibit"); err == nil {
+		t.Error("Expected unsigned check-out to be refused by policy")
+	}
+
+	sig := &CustodySignature{Type: SignatureTyped, SignerID: "COURT-7", Acknowledgment: "Received for trial"}
+	if _, err := system.CheckOutEvidenceSigned(evidence.ID, "OFF-127", "COURT-7", "Trial exhibit", sig); err != nil {
+		t.Errorf("Expected signed check-out to succeed: %v", err)
+	}
+}
+
+func TestEd25519CustodySignatures(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SIG-006", "OFF-128", "Officer Test", "Test Location", nil)
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	payload, _ := system.CustodySigningPayload(evidence.ID, "OFF-128", "DET-456", "TRANSFERRED", "Analysis")
+
+	sig := &CustodySignature{Type: SignatureEd25519, SignerID: "OFF-128", Value: ed25519.Sign(priv, payload)}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-128", "DET-456", "Analysis", sig); err == nil {
+		t.Fatal("expected a signature by an officer without an enrolled key to be refused")
+	}
+	key, err := system.EnrollOfficerKey("OFF-128", pub, "ADMIN-1")
+	if err != nil {
+		t.Fatalf("EnrollOfficerKey failed: %v", err)
+	}
+	forged := &CustodySignature{Type: SignatureEd25519, SignerID: "OFF-128", Value: ed25519.Sign(priv, []byte("another statement"))}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-128", "DET-456", "Analysis", forged); err == nil {
+		t.Error("expected a signature over another statement to be refused")
+	}
+	if err := system.TransferCustodySigned(evidence.ID, "OFF-128", "DET-456", "Analysis", sig); err != nil {
+		t.Fatalf("TransferCustodySigned failed: %v", err)
+	}
+	chain, _ := system.GetChainOfCustody(evidence.ID)
+	if got := chain[len(chain)-1].Signature.KeyID; got != key.KeyID {
+		t.Errorf("expected the entry to name key %s, got %s", key.KeyID, got)
+	}
+
+	// A replacement key signs from now on; the entry signed before still verifies
+	newPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := system.EnrollOfficerKey("OFF-128", newPub, "ADMIN-1"); err != nil {
+		t.Fatalf("EnrollOfficerKey failed: %v", err)
+	}
+	payload, _ = system.CustodySigningPayload(evidence.ID, "DET-456", "OFF-128", "TRANSFERRED", "Return")
+	stale := &CustodySignature{Type: SignatureEd25519, SignerID: "OFF-128", Value: ed25519.Sign(priv, payload)}
+	if err := system.TransferCustodySigned(evidence.ID, "DET-456", "OFF-128", "Return", stale); err == nil {
+		t.Error("expected the replaced key to be refused")
+	}
+
+	// The keys survive a restart
+	reopened, err := NewBWCSystem(system.storagePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := reopened.OfficerKeys("OFF-128"); len(keys) != 2 || keys[0].RevokedAt == nil || keys[1].RevokedAt != nil {
+		t.Fatalf("expected the replaced and the current key, got %+v", keys)
+	}
+	if failed, _ := system.VerifyCustodySignatures(evidence.ID); len(failed) != 0 {
+		t.Errorf("expected the signed entry to verify, failed %v", failed)
+	}
+
+	// An entry altered and re-hashed after the fact no longer verifies
+	system.mu.Lock()
+	entry := &system.evidenceDB.Get(evidence.ID).ChainOfCustody[1]
+	entry.Purpose = "Routine storage"
+	entry.EntryHash, _ = custodyEntryHash(*entry)
+	system.mu.Unlock()
+	if failed, _ := system.VerifyCustodySignatures(evidence.ID); len(failed) != 1 || failed[0] != 1 {
+		t.Errorf("expected the altered entry to fail verification, got %v", failed)
+	}
+}
@@ -0,0 +1,92 @@
+package bwc
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// dashboardStatuses are the evidence queues shown to custodians, in workflow order
+var dashboardStatuses = []EvidenceStatus{StatusCollected, StatusProcessing, StatusAnalyzed, StatusArchived}
+
+// QueueSummary lists evidence currently in one status
+type QueueSummary struct {
+	Status EvidenceStatus `json:"status"`
+	Items  []*Evidence    `json:"items"`
+}
+
+// IntegrityAlert is evidence whose most recent integrity check failed
+type IntegrityAlert struct {
+	EvidenceID string    `json:"evidence_id"`
+	CaseNumber string    `json:"case_number"`
+	CheckedAt  time.Time `json:"checked_at"`
+	CheckedBy  string    `json:"checked_by"`
+	Notes      string    `json:"notes"`
+}
+
+// Dashboard is a snapshot of the work facing an evidence custodian
+type Dashboard struct {
+	GeneratedAt     time.Time         `json:"generated_at"`
+	OfficerID       string            `json:"officer_id"`
+	Queues          []QueueSummary    `json:"queues"`
+	PendingRequests []*CustodyRequest `json:"pending_requests"`
+	IntegrityAlerts []IntegrityAlert  `json:"integrity_alerts"`
+	RetentionItems  []RetentionItem   `json:"retention_items"`
+}
+
+// IntegrityAlerts lists evidence whose latest integrity check did not pass
+func (bwc *BWCSystem) IntegrityAlerts() []IntegrityAlert {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	alerts := make([]IntegrityAlert, 0)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if len(evidence.IntegrityChecks) == 0 {
+			continue
+		}
+
+		last := evidence.IntegrityChecks[len(evidence.IntegrityChecks)-1]
+		if last.IsValid {
+			continue
+		}
+
+		alerts = append(alerts, IntegrityAlert{
+			EvidenceID: evidence.ID,
+			CaseNumber: evidence.CaseNumber,
+			CheckedAt:  last.Timestamp,
+			CheckedBy:  last.CheckedBy,
+			Notes:      last.Notes,
+		})
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].CheckedAt.After(alerts[j].CheckedAt)
+	})
+
+	return alerts
+}
+
+// BuildDashboard gathers evidence queues, custody requests addressed to officerID,
+// integrity alerts and evidence reaching retention within retentionWindow
+func (bwc *BWCSystem) BuildDashboard(officerID string, retentionWindow time.Duration) *Dashboard {
+	now := time.Now()
+
+	dashboard := &Dashboard{
+		GeneratedAt:     now,
+		OfficerID:       officerID,
+		Queues:          make([]QueueSummary, 0, len(dashboardStatuses)),
+		PendingRequests: bwc.PendingCustodyRequests(officerID),
+		IntegrityAlerts: bwc.IntegrityAlerts(),
+		RetentionItems:  bwc.RetentionDue(now, retentionWindow),
+	}
+
+	for _, status := range dashboardStatuses {
+		items, _ := bwc.searchEvidence(systemContext(context.Background()), "", "", status)
+		sort.Slice(items, func(i, j int) bool {
+			return items[i].CreatedAt.Before(items[j].CreatedAt)
+		})
+		dashboard.Queues = append(dashboard.Queues, QueueSummary{Status: status, Items: items})
+	}
+
+	return dashboard
+}
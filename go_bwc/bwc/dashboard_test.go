@@ -0,0 +1,50 @@
+package bwc
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestBuildDashboard(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	ev1, _ := system.IngestEvidence(testFile, "CASE-DASH-001", "OFF-001", "Officer A", "Location A", nil)
+	ev2, _ := system.IngestEvidence(testFile, "CASE-DASH-002", "OFF-002", "Officer B", "Location B", nil)
+
+	system.UpdateStatus(ev2.ID, "OFF-002", StatusProcessing, "")
+	system.RequestCustodyTransfer(ev1.ID, "OFF-001", "CUS-001", "Intake")
+
+	// Tamper with the second item so it raises an integrity alert
+	os.WriteFile(ev2.FilePath, []byte("TAMPERED"), 0600)
+	system.VerifyIntegrity(ev2.ID, "CUS-001")
+
+	d := system.BuildDashboard("CUS-001", 30*24*time.Hour)
+
+	if len(d.Queues) != len(dashboardStatuses) {
+		t.Fatalf("Expected %d queues, got %d", len(dashboardStatuses), len(d.Queues))
+	}
+
+	if len(d.Queues[0].Items) != 1 || d.Queues[0].Items[0].ID != ev1.ID {
+		t.Errorf("Expected %s in the COLLECTED queue", ev1.ID)
+	}
+
+	if len(d.Queues[1].Items) != 1 || d.Queues[1].Items[0].ID != ev2.ID {
+		t.Errorf("Expected %s in the PROCESSING queue", ev2.ID)
+	}
+
+	if len(d.PendingRequests) != 1 {
+		t.Errorf("Expected 1 pending request, got %d", len(d.PendingRequests))
+	}
+
+	if len(d.IntegrityAlerts) != 1 || d.IntegrityAlerts[0].EvidenceID != ev2.ID {
+		t.Errorf("Expected integrity alert for %s, got %v", ev2.ID, d.IntegrityAlerts)
+	}
+
+	if other := system.BuildDashboard("CUS-999", 0); len(other.PendingRequests) != 0 {
+		t.Errorf("Expected no pending requests for another officer, got %d", len(other.PendingRequests))
+	}
+}
@@ -0,0 +1,402 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deletionRequestsFile holds every deletion and purge request, so pending
+// ones survive a restart and resolved ones stay on record
+const deletionRequestsFile = "deletion_requests.json"
+
+// defaultDeletionApprovalWindow applies when access_control.deletion_approval.window_hours is unset
+const defaultDeletionApprovalWindow = 24 * time.Hour
+
+// RequestExpired marks a deletion request no one approved in time
+const RequestExpired CustodyRequestStatus = "EXPIRED"
+
+// ErrApprovalRequired is matched by deletions and purges refused because the
+// two-person rule is on; they go through RequestDeletion or RequestPurge
+var ErrApprovalRequired = &BWCError{Code: CodeApprovalRequired, Message: "a second user must approve this operation"}
+
+// DeletionKind is what a deletion request destroys
+type DeletionKind string
+
+const (
+	// DeletionEvidence sets one item to DELETED
+	DeletionEvidence DeletionKind = "delete"
+	// DeletionPurge runs a retention purge over the items it lists
+	DeletionPurge DeletionKind = "purge"
+)
+
+// DeletionRequest is a deletion or purge awaiting approval by a second
+// authorized user. It takes effect only if approved before ExpiresAt.
+type DeletionRequest struct {
+	ID         string       `json:"id"`
+	Kind       DeletionKind `json:"kind"`
+	EvidenceID string       `json:"evidence_id,omitempty"`
+	// Plan is what a purge would do when requested; approval purges only
+	// those items, if they are still expired
+	Plan        *ChangePlan          `json:"plan,omitempty"`
+	Reason      string               `json:"reason"`
+	RequestedBy string               `json:"requested_by"`
+	RequestedAt time.Time            `json:"requested_at"`
+	ExpiresAt   time.Time            `json:"expires_at"`
+	Status      CustodyRequestStatus `json:"status"`
+	ResolvedBy  string               `json:"resolved_by,omitempty"`
+	ResolvedAt  time.Time            `json:"resolved_at"`
+	Resolution  string               `json:"resolution,omitempty"`
+}
+
+// deletionApprovalWindow is how long a deletion request stays open
+func (c *Config) deletionApprovalWindow() time.Duration {
+	if hours := c.AccessControl.DeletionApproval.WindowHours; hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultDeletionApprovalWindow
+}
+
+// requireNoApproval refuses a deletion made directly while the two-person
+// rule is on, and audits the refusal
+func (bwc *BWCSystem) requireNoApproval(userID, operation, evidenceID string) error {
+	if !bwc.config.AccessControl.DeletionApproval.Enabled {
+		return nil
+	}
+	bwc.logAudit(userID, "DELETION_APPROVAL_REQUIRED", evidenceID,
+		fmt.Sprintf("%s refused: deletions need a second approver", operation), "")
+	return &BWCError{Code: CodeApprovalRequired,
+		Message: fmt.Sprintf("%s needs a second approver: use RequestDeletion or RequestPurge", strings.ToLower(operation))}
+}
+
+// RequestDeletion queues evidenceID for deletion. A second user with
+// delete_evidence must approve it within the approval window.
+func (bwc *BWCSystem) RequestDeletion(evidenceID, reason, requestedBy string) (*DeletionRequest, error) {
+	return bwc.RequestDeletionContext(context.Background(), evidenceID, reason, requestedBy)
+}
+
+// RequestDeletionContext asks for a deletion like RequestDeletion, as the
+// principal ctx carries when it has one
+func (bwc *BWCSystem) RequestDeletionContext(ctx context.Context, evidenceID, reason, requestedBy string) (*DeletionRequest, error) {
+	requestedBy, auth := actingUser(ctx, requestedBy)
+	if err := bwc.authorize(requestedBy, PermDeleteEvidence, "Deletion request", evidenceID); err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to delete evidence")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if evidence.Status == StatusDeleted {
+		return nil, fmt.Errorf("evidence %s is already deleted", evidenceID)
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, requestedBy, "Deletion request"); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	bwc.expireDeletionRequestsLocked(now)
+	for _, req := range bwc.deletionRequests {
+		if req.Kind == DeletionEvidence && req.EvidenceID == evidenceID && req.Status == RequestPending {
+			return nil, fmt.Errorf("deletion request %s is already pending for this evidence", req.ID)
+		}
+	}
+
+	req, err := bwc.addDeletionRequestLocked(&DeletionRequest{
+		Kind: DeletionEvidence, EvidenceID: evidenceID, Reason: reason, RequestedBy: requestedBy,
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	bwc.logAuditAs(auth, requestedBy, "REQUEST_DELETION", evidenceID,
+		fmt.Sprintf("Deletion %s requested - %s; approval due by %s", req.ID, reason, req.ExpiresAt.Format(time.RFC3339)), "")
+
+	r := *req
+	return &r, nil
+}
+
+// RequestPurge queues a retention purge of the evidence expired now. The
+// request carries the plan; a second user approves exactly those items.
+func (bwc *BWCSystem) RequestPurge(reason, requestedBy string) (*DeletionRequest, error) {
+	return bwc.RequestPurgeContext(context.Background(), reason, requestedBy)
+}
+
+// RequestPurgeContext asks for a purge like RequestPurge, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) RequestPurgeContext(ctx context.Context, reason, requestedBy string) (*DeletionRequest, error) {
+	requestedBy, auth := actingUser(ctx, requestedBy)
+	if err := bwc.authorize(requestedBy, PermDeleteEvidence, "Purge request", ""); err != nil {
+		return nil, err
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required to purge evidence")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	now := time.Now()
+	plan, err := bwc.purgeExpiredLocked(now, requestedBy, true, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(plan.Items) == 0 {
+		return nil, errors.New("no evidence is due to be purged")
+	}
+
+	bwc.expireDeletionRequestsLocked(now)
+	req, err := bwc.addDeletionRequestLocked(&DeletionRequest{
+		Kind: DeletionPurge, Plan: plan, Reason: reason, RequestedBy: requestedBy,
+	}, now)
+	if err != nil {
+		return nil, err
+	}
+
+	bwc.logAuditAs(auth, requestedBy, "REQUEST_PURGE", "",
+		fmt.Sprintf("Purge %s of %d items (%d bytes) requested - %s; approval due by %s",
+			req.ID, len(plan.Items), plan.TotalBytes, reason, req.ExpiresAt.Format(time.RFC3339)), "")
+
+	r := *req
+	return &r, nil
+}
+
+// addDeletionRequestLocked files req as pending and saves it; the caller must
+// hold bwc.mu
+func (bwc *BWCSystem) addDeletionRequestLocked(req *DeletionRequest, now time.Time) (*DeletionRequest, error) {
+	bwc.deletionRequestSeq++
+	req.ID = fmt.Sprintf("DEL-%06d", bwc.deletionRequestSeq)
+	req.RequestedAt = now
+	req.ExpiresAt = now.Add(bwc.config.deletionApprovalWindow())
+	req.Status = RequestPending
+	bwc.deletionRequests[req.ID] = req
+	if err := bwc.saveDeletionRequestsLocked(); err != nil {
+		delete(bwc.deletionRequests, req.ID)
+		bwc.deletionRequestSeq--
+		return nil, err
+	}
+	return req, nil
+}
+
+// ApproveDeletion carries out a pending deletion or purge. The approver must
+// hold delete_evidence and not be the requester, and the request must not
+// have expired.
+func (bwc *BWCSystem) ApproveDeletion(requestID, approverID string) (*DeletionRequest, error) {
+	return bwc.ApproveDeletionContext(context.Background(), requestID, approverID)
+}
+
+// ApproveDeletionContext approves like ApproveDeletion, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) ApproveDeletionContext(ctx context.Context, requestID, approverID string) (*DeletionRequest, error) {
+	approverID, auth := actingUser(ctx, approverID)
+	if err := bwc.authorize(approverID, PermDeleteEvidence, "Deletion approval", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	now := time.Now()
+	req, err := bwc.pendingDeletionFor(requestID, approverID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// A purge goes ahead item by item, so one that fails part way is still
+	// approved, with the failures returned
+	var purgeErr error
+	switch req.Kind {
+	case DeletionEvidence:
+		evidence := bwc.evidenceDB.Get(req.EvidenceID)
+		if evidence == nil {
+			return nil, ErrEvidenceNotFound
+		}
+		if err := bwc.rejectIfSealedLocked(evidence, approverID, "Deletion approval"); err != nil {
+			return nil, err
+		}
+		notes := fmt.Sprintf("Deletion %s requested by %s, approved by %s - %s", req.ID, req.RequestedBy, approverID, req.Reason)
+		if err := bwc.markDeletedLocked(evidence, approverID, notes, auth); err != nil {
+			return nil, err
+		}
+	case DeletionPurge:
+		only := make(map[string]bool, len(req.Plan.Items))
+		for _, item := range req.Plan.Items {
+			only[item.EvidenceID] = true
+		}
+		req.Plan, purgeErr = bwc.purgeExpiredLocked(now, approverID, false, only)
+	}
+
+	req.Status = RequestAccepted
+	req.ResolvedBy = approverID
+	req.ResolvedAt = now
+	// The deletion has happened by now; were the request left pending on
+	// disk, approving it again after a restart finds nothing left to delete
+	if err := bwc.saveDeletionRequestsLocked(); err != nil && purgeErr == nil {
+		purgeErr = err
+	}
+
+	bwc.logAuditAs(auth, approverID, "APPROVE_DELETION", req.EvidenceID,
+		fmt.Sprintf("%s %s requested by %s approved - %s", deletionLabel(req.Kind), req.ID, req.RequestedBy, req.Reason), "")
+
+	r := *req
+	return &r, purgeErr
+}
+
+// DeclineDeletion rejects a pending request; nothing is deleted
+func (bwc *BWCSystem) DeclineDeletion(requestID, approverID, reason string) error {
+	return bwc.DeclineDeletionContext(context.Background(), requestID, approverID, reason)
+}
+
+// DeclineDeletionContext declines like DeclineDeletion, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) DeclineDeletionContext(ctx context.Context, requestID, approverID, reason string) error {
+	approverID, auth := actingUser(ctx, approverID)
+	if err := bwc.authorize(approverID, PermDeleteEvidence, "Deletion decline", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	now := time.Now()
+	req, err := bwc.pendingDeletionFor(requestID, approverID, now)
+	if err != nil {
+		return err
+	}
+
+	before := *req
+	req.Status = RequestDeclined
+	req.ResolvedBy = approverID
+	req.ResolvedAt = now
+	req.Resolution = reason
+	if err := bwc.saveDeletionRequestsLocked(); err != nil {
+		*req = before
+		return err
+	}
+
+	bwc.logAuditAs(auth, approverID, "DECLINE_DELETION", req.EvidenceID,
+		fmt.Sprintf("%s %s requested by %s declined - %s", deletionLabel(req.Kind), req.ID, req.RequestedBy, reason), "")
+	return nil
+}
+
+// PendingDeletionRequests lists deletion and purge requests awaiting a
+// second approver, oldest first
+func (bwc *BWCSystem) PendingDeletionRequests() []DeletionRequest {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	bwc.expireDeletionRequestsLocked(time.Now())
+	results := make([]DeletionRequest, 0)
+	for _, req := range bwc.deletionRequests {
+		if req.Status == RequestPending {
+			results = append(results, *req)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// pendingDeletionFor looks up a pending deletion request that approverID may
+// resolve; the caller must hold bwc.mu
+func (bwc *BWCSystem) pendingDeletionFor(requestID, approverID string, now time.Time) (*DeletionRequest, error) {
+	bwc.expireDeletionRequestsLocked(now)
+	req, exists := bwc.deletionRequests[requestID]
+	if !exists {
+		return nil, errors.New("deletion request not found")
+	}
+	if req.Status != RequestPending {
+		return nil, fmt.Errorf("deletion request is already %s", req.Status)
+	}
+	if approverID == "" || approverID == systemUserID || approverID == req.RequestedBy {
+		return nil, errors.New("a deletion request must be resolved by a second user")
+	}
+	return req, nil
+}
+
+// expireDeletionRequestsLocked closes, and audits, requests whose approval
+// window has passed; the caller must hold bwc.mu
+func (bwc *BWCSystem) expireDeletionRequestsLocked(now time.Time) {
+	expired := false
+	for _, req := range bwc.deletionRequests {
+		if req.Status != RequestPending || now.Before(req.ExpiresAt) {
+			continue
+		}
+		expired = true
+		req.Status = RequestExpired
+		req.ResolvedAt = now
+		req.Resolution = "not approved within the approval window"
+		bwc.logAudit(systemUserID, "DELETION_REQUEST_EXPIRED", req.EvidenceID,
+			fmt.Sprintf("%s %s requested by %s expired unapproved", deletionLabel(req.Kind), req.ID, req.RequestedBy), "")
+	}
+	// An expiry that fails to save is only found again after a restart
+	if expired {
+		bwc.saveDeletionRequestsLocked()
+	}
+}
+
+// loadDeletionRequests reads the deletion and purge requests from storage
+func (bwc *BWCSystem) loadDeletionRequests() error {
+	data, err := os.ReadFile(filepath.Join(bwc.storagePath, deletionRequestsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read deletion requests: %w", err)
+	}
+	var requests []*DeletionRequest
+	if err := json.Unmarshal(data, &requests); err != nil {
+		return fmt.Errorf("%s: %w", deletionRequestsFile, err)
+	}
+	for _, req := range requests {
+		bwc.deletionRequests[req.ID] = req
+		if seq, err := strconv.Atoi(strings.TrimPrefix(req.ID, "DEL-")); err == nil && seq > bwc.deletionRequestSeq {
+			bwc.deletionRequestSeq = seq
+		}
+	}
+	return nil
+}
+
+// saveDeletionRequestsLocked writes every request, oldest first. The caller
+// must hold bwc.mu.
+func (bwc *BWCSystem) saveDeletionRequestsLocked() error {
+	requests := make([]*DeletionRequest, 0, len(bwc.deletionRequests))
+	for _, req := range bwc.deletionRequests {
+		requests = append(requests, req)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].ID < requests[j].ID
+	})
+	return writeSnapshot(filepath.Join(bwc.storagePath, deletionRequestsFile), requests)
+}
+
+func deletionLabel(kind DeletionKind) string {
+	if kind == DeletionPurge {
+		return "Purge"
+	}
+	return "Deletion"
+}
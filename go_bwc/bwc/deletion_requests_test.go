@@ -0,0 +1,224 @@
+package bwc
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// auditActions lists the actions audited for evidenceID, oldest first
+func auditActions(system *BWCSystem, evidenceID string) []string {
+	actions := make([]string, 0)
+	for _, log := range system.GetAuditLogs(evidenceID, "") {
+		actions = append(actions, log.Action)
+	}
+	return actions
+}
+
+func TestDeletionNeedsSecondApprover(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AccessControl.DeletionApproval.Enabled = true
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-DEL-001", "OFF-6001", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	if err := system.UpdateStatus(evidence.ID, "SUP-6001", StatusDeleted, "Duplicate upload"); !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("expected a direct deletion to be refused, got %v", err)
+	}
+	if _, err := system.BulkUpdateStatus([]string{evidence.ID}, "SUP-6001", StatusDeleted, "Duplicate upload", false); !errors.Is(err, ErrApprovalRequired) {
+		t.Errorf("expected a batch deletion to be refused, got %v", err)
+	}
+
+	if _, err := system.RequestDeletion(evidence.ID, "", "SUP-6001"); err == nil {
+		t.Error("expected a reason to be required")
+	}
+	req, err := system.RequestDeletion(evidence.ID, "Duplicate upload", "SUP-6001")
+	if err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+	if _, err := system.RequestDeletion(evidence.ID, "Duplicate upload", "SUP-6002"); err == nil {
+		t.Error("expected a second pending request for the same evidence to be refused")
+	}
+	if pending := system.PendingDeletionRequests(); len(pending) != 1 || pending[0].ID != req.ID {
+		t.Errorf("expected the request to be pending, got %+v", pending)
+	}
+
+	if _, err := system.ApproveDeletion(req.ID, "SUP-6001"); err == nil {
+		t.Error("expected the requester not to approve their own request")
+	}
+	if got, _ := system.GetEvidence(evidence.ID); got.Status == StatusDeleted {
+		t.Fatal("expected the evidence to stay until approved")
+	}
+
+	approved, err := system.ApproveDeletion(req.ID, "SUP-6002")
+	if err != nil {
+		t.Fatalf("ApproveDeletion failed: %v", err)
+	}
+	if approved.Status != RequestAccepted || approved.ResolvedBy != "SUP-6002" {
+		t.Errorf("unexpected resolution %+v", approved)
+	}
+	if got, _ := system.GetEvidence(evidence.ID); got.Status != StatusDeleted {
+		t.Errorf("expected the evidence to be deleted, got %s", got.Status)
+	}
+
+	actions := strings.Join(auditActions(system, evidence.ID), " ")
+	for _, action := range []string{"DELETION_APPROVAL_REQUIRED", "REQUEST_DELETION", "APPROVE_DELETION", "UPDATE_STATUS"} {
+		if !strings.Contains(actions, action) {
+			t.Errorf("expected %s to be audited, got %s", action, actions)
+		}
+	}
+}
+
+func TestDeletionRequestExpires(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AccessControl.DeletionApproval = DeletionApprovalConfig{Enabled: true, WindowHours: 2}
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-DEL-002", "OFF-6002", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	req, err := system.RequestDeletion(evidence.ID, "Recorded in error", "SUP-6003")
+	if err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+	if window := req.ExpiresAt.Sub(req.RequestedAt); window != 2*time.Hour {
+		t.Errorf("expected a 2 hour window, got %s", window)
+	}
+
+	system.deletionRequests[req.ID].ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := system.ApproveDeletion(req.ID, "SUP-6004"); err == nil || !strings.Contains(err.Error(), "EXPIRED") {
+		t.Errorf("expected an expired request to be refused, got %v", err)
+	}
+	if got, _ := system.GetEvidence(evidence.ID); got.Status == StatusDeleted {
+		t.Error("expected an expired request to delete nothing")
+	}
+	if !strings.Contains(strings.Join(auditActions(system, evidence.ID), " "), "DELETION_REQUEST_EXPIRED") {
+		t.Error("expected the expiry to be audited")
+	}
+
+	// Once expired, the evidence can be requested again
+	if _, err := system.RequestDeletion(evidence.ID, "Recorded in error", "SUP-6003"); err != nil {
+		t.Errorf("expected a new request to be accepted, got %v", err)
+	}
+}
+
+func TestDeletionRequestsSurviveRestart(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.AccessControl.DeletionApproval.Enabled = true
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-DEL-005", "OFF-6005", "", "", nil)
+	req, err := system.RequestDeletion(evidence.ID, "Duplicate upload", "SUP-6005")
+	if err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+	if _, err := system.ApproveDeletion(req.ID, systemUserID); err == nil {
+		t.Error("expected SYSTEM not to count as a second approver")
+	}
+
+	reopened, err := NewBWCSystem(system.storagePath)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	if pending := reopened.PendingDeletionRequests(); len(pending) != 1 || pending[0].ID != req.ID || pending[0].EvidenceID != evidence.ID {
+		t.Fatalf("expected the request to be pending after a restart, got %+v", pending)
+	}
+	if err := reopened.DeclineDeletion(req.ID, "SUP-6006", "Not a duplicate"); err != nil {
+		t.Fatalf("DeclineDeletion failed: %v", err)
+	}
+
+	reopened, err = NewBWCSystem(system.storagePath)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	if pending := reopened.PendingDeletionRequests(); len(pending) != 0 {
+		t.Errorf("expected the decline to be kept, got %+v", pending)
+	}
+	reopened.config.AccessControl.DeletionApproval.Enabled = true
+	another, _ := reopened.IngestEvidence(createTestFile(t, tmpDir), "CASE-DEL-006", "OFF-6005", "", "", nil)
+	next, err := reopened.RequestDeletion(another.ID, "Duplicate upload", "SUP-6005")
+	if err != nil || next.ID == req.ID {
+		t.Errorf("expected request numbering to carry on, got %+v, %v", next, err)
+	}
+}
+
+func TestPurgeNeedsSecondApprover(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 365
+	system.config.AccessControl.DeletionApproval.Enabled = true
+
+	testFile := createTestFile(t, tmpDir)
+	expired, _ := system.IngestEvidence(testFile, "CASE-DEL-003", "OFF-6003", "", "", nil)
+	later, _ := system.IngestEvidence(testFile, "CASE-DEL-004", "OFF-6004", "", "", nil)
+	now := time.Now()
+	expired.CreatedAt = now.AddDate(-2, 0, 0)
+
+	if _, err := system.PurgeExpired(now, "RECORDS-6001", false); !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("expected a purge to need approval, got %v", err)
+	}
+	if _, err := system.PurgeExpired(now, "RECORDS-6001", true); err != nil {
+		t.Errorf("expected a dry run to be allowed, got %v", err)
+	}
+
+	req, err := system.RequestPurge("Quarterly retention purge", "RECORDS-6001")
+	if err != nil {
+		t.Fatalf("RequestPurge failed: %v", err)
+	}
+	if req.Kind != DeletionPurge || len(req.Plan.Items) != 1 || req.Plan.Items[0].EvidenceID != expired.ID {
+		t.Fatalf("expected a purge plan of the expired item, got %+v", req.Plan)
+	}
+
+	// Evidence that expires after the request is not swept up by its approval
+	later.CreatedAt = now.AddDate(-2, 0, 0)
+	approved, err := system.ApproveDeletion(req.ID, "RECORDS-6002")
+	if err != nil {
+		t.Fatalf("ApproveDeletion failed: %v", err)
+	}
+	if len(approved.Plan.Items) != 1 {
+		t.Errorf("expected only the requested item to be purged, got %+v", approved.Plan.Items)
+	}
+	if _, err := os.Stat(expired.FilePath); !os.IsNotExist(err) {
+		t.Error("expected the expired recording to be removed")
+	}
+	if later.Status == StatusDeleted {
+		t.Error("expected evidence outside the request to be kept")
+	}
+}
+
+func TestServerDeletions(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	system.config.AccessControl.DeletionApproval.Enabled = true
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-DEL-005", "OFF-6005", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	req, err := system.RequestDeletion(evidence.ID, "Duplicate upload", "SUP-6005")
+	if err != nil {
+		t.Fatalf("RequestDeletion failed: %v", err)
+	}
+
+	resp := authPostJSON(t, server, "/api/deletions/"+req.ID+"/approve", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the approval to succeed, got %d", resp.StatusCode)
+	}
+	if got, _ := system.GetEvidence(evidence.ID); got.Status != StatusDeleted {
+		t.Errorf("expected the evidence to be deleted, got %s", got.Status)
+	}
+
+	resp = authPostJSON(t, server, "/api/deletions", `{"evidence_id":"`+evidence.ID+`","reason":""}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected a request without a reason to be refused, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,256 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestPeriod is the window each digest summarizes
+const digestPeriod = 24 * time.Hour
+
+// digestSectionNames lists the sections a digest can include, in display order
+var digestSectionNames = []string{"ingest", "integrity", "custody", "retention"}
+
+// digestSections selects which sections a role's digest includes
+type digestSections struct {
+	Ingest    bool
+	Integrity bool
+	Custody   bool
+	Retention bool
+}
+
+// parseDigestSections converts section names to digestSections. No names selects every section.
+func parseDigestSections(names []string) (digestSections, error) {
+	if len(names) == 0 {
+		return digestSections{Ingest: true, Integrity: true, Custody: true, Retention: true}, nil
+	}
+
+	var sections digestSections
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "ingest":
+			sections.Ingest = true
+		case "integrity":
+			sections.Integrity = true
+		case "custody":
+			sections.Custody = true
+		case "retention":
+			sections.Retention = true
+		default:
+			return digestSections{}, fmt.Errorf("unknown digest section %q (expected %s)", name, strings.Join(digestSectionNames, ", "))
+		}
+	}
+	return sections, nil
+}
+
+// Digest summarizes evidence activity for one role over a 24-hour period.
+// Sections the role does not receive are left empty.
+type Digest struct {
+	Role              string            `json:"role"`
+	PeriodStart       time.Time         `json:"period_start"`
+	PeriodEnd         time.Time         `json:"period_end"`
+	Ingested          int               `json:"ingested"`
+	IngestedByCase    map[string]int    `json:"ingested_by_case,omitempty"`
+	IntegrityFailures []IntegrityAlert  `json:"integrity_failures,omitempty"`
+	PendingCustody    []*CustodyRequest `json:"pending_custody,omitempty"`
+	RetentionDue      []RetentionItem   `json:"retention_due,omitempty"`
+
+	sections digestSections
+}
+
+// BuildDigest summarizes the 24 hours ending at end: evidence ingested,
+// integrity checks that failed, custody transfers awaiting acceptance and
+// evidence whose retention period ended. sectionNames limits the digest as in
+// DigestConfig.Sections.
+func (bwc *BWCSystem) BuildDigest(role string, end time.Time, sectionNames []string) (*Digest, error) {
+	sections, err := parseDigestSections(sectionNames)
+	if err != nil {
+		return nil, err
+	}
+
+	start := end.Add(-digestPeriod)
+	digest := &Digest{Role: role, PeriodStart: start, PeriodEnd: end, sections: sections}
+
+	inPeriod := func(t time.Time) bool { return t.After(start) && !t.After(end) }
+
+	bwc.mu.RLock()
+	if sections.Ingest {
+		digest.IngestedByCase = make(map[string]int)
+	}
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if sections.Ingest && inPeriod(evidence.CreatedAt) {
+			digest.Ingested++
+			digest.IngestedByCase[evidence.CaseNumber]++
+		}
+		if sections.Integrity {
+			for _, check := range evidence.IntegrityChecks {
+				if !check.IsValid && inPeriod(check.Timestamp) {
+					digest.IntegrityFailures = append(digest.IntegrityFailures, IntegrityAlert{
+						EvidenceID: evidence.ID,
+						CaseNumber: evidence.CaseNumber,
+						CheckedAt:  check.Timestamp,
+						CheckedBy:  check.CheckedBy,
+						Notes:      check.Notes,
+					})
+				}
+			}
+		}
+	}
+	bwc.mu.RUnlock()
+
+	sort.Slice(digest.IntegrityFailures, func(i, j int) bool {
+		return digest.IntegrityFailures[i].CheckedAt.Before(digest.IntegrityFailures[j].CheckedAt)
+	})
+
+	if sections.Custody {
+		digest.PendingCustody = bwc.PendingCustodyRequests("")
+	}
+	if sections.Retention {
+		for _, item := range bwc.RetentionDue(end, 0) {
+			if inPeriod(item.ExpiresAt) {
+				digest.RetentionDue = append(digest.RetentionDue, item)
+			}
+		}
+	}
+
+	return digest, nil
+}
+
+// Subject returns the email subject line for the digest
+func (d *Digest) Subject(systemName string) string {
+	return fmt.Sprintf("[%s] Daily evidence digest for %s - %s", systemName, d.Role, d.PeriodEnd.Format("2006-01-02"))
+}
+
+// Text renders the digest as a plain-text email body
+func (d *Digest) Text() string {
+	sections := d.sections
+	var b strings.Builder
+	fmt.Fprintf(&b, "Evidence digest for %s\n", d.Role)
+	fmt.Fprintf(&b, "Period: %s to %s\n\n", d.PeriodStart.Format(time.RFC3339), d.PeriodEnd.Format(time.RFC3339))
+
+	if sections.Ingest {
+		fmt.Fprintf(&b, "Evidence ingested: %d\n", d.Ingested)
+		cases := make([]string, 0, len(d.IngestedByCase))
+		for caseNumber := range d.IngestedByCase {
+			cases = append(cases, caseNumber)
+		}
+		sort.Strings(cases)
+		for _, caseNumber := range cases {
+			fmt.Fprintf(&b, "  %s: %d\n", caseNumber, d.IngestedByCase[caseNumber])
+		}
+		b.WriteString("\n")
+	}
+
+	if sections.Integrity {
+		fmt.Fprintf(&b, "Integrity failures: %d\n", len(d.IntegrityFailures))
+		for _, alert := range d.IntegrityFailures {
+			fmt.Fprintf(&b, "  %s %s (case %s) checked by %s\n",
+				alert.CheckedAt.Format("2006-01-02 15:04"), alert.EvidenceID, alert.CaseNumber, alert.CheckedBy)
+		}
+		b.WriteString("\n")
+	}
+
+	if sections.Custody {
+		fmt.Fprintf(&b, "Custody transfers awaiting acceptance: %d\n", len(d.PendingCustody))
+		for _, req := range d.PendingCustody {
+			fmt.Fprintf(&b, "  %s %s: %s -> %s since %s\n",
+				req.ID, req.EvidenceID, req.FromOfficer, req.ToOfficer, req.RequestedAt.Format("2006-01-02 15:04"))
+		}
+		b.WriteString("\n")
+	}
+
+	if sections.Retention {
+		fmt.Fprintf(&b, "Evidence entering retention disposition: %d\n", len(d.RetentionDue))
+		for _, item := range d.RetentionDue {
+			fmt.Fprintf(&b, "  %s (case %s) retention ended %s\n",
+				item.EvidenceID, item.CaseNumber, item.ExpiresAt.Format("2006-01-02"))
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// digestScheduler sends the configured digests once a day
+type digestScheduler struct {
+	system *BWCSystem
+	mail   mailer
+	http   *http.Client
+}
+
+func newDigestScheduler(system *BWCSystem) *digestScheduler {
+	return &digestScheduler{
+		system: system,
+		mail:   newSMTPMailer(system.config.Notifications),
+		http:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SendDigests builds and delivers every configured digest for the period ending
+// at end. Delivery continues past failures, which are returned together.
+func (s *digestScheduler) SendDigests(end time.Time) error {
+	cfg := s.system.config
+	var errs []error
+
+	for _, dc := range cfg.Notifications.Digests {
+		digest, err := s.system.BuildDigest(dc.Role, end, dc.Sections)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest for %s: %w", dc.Role, err))
+			continue
+		}
+
+		var delivered []string
+		if len(dc.Recipients) > 0 {
+			if err := s.mail.SendMail(dc.Recipients, digest.Subject(cfg.System.Name), digest.Text()); err != nil {
+				errs = append(errs, fmt.Errorf("digest for %s: %w", dc.Role, err))
+			} else {
+				delivered = append(delivered, "email")
+			}
+		}
+		if dc.WebhookURL != "" {
+			if err := postWebhook(s.http, dc.WebhookURL, digest); err != nil {
+				errs = append(errs, fmt.Errorf("digest for %s: %w", dc.Role, err))
+			} else {
+				delivered = append(delivered, "webhook")
+			}
+		}
+
+		if len(delivered) > 0 {
+			s.system.logAudit("SYSTEM", "SEND_DIGEST", "",
+				fmt.Sprintf("Daily digest for %s sent by %s", dc.Role, strings.Join(delivered, " and ")), "")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run sends digests every day at notifications.digest_hour local time until stop is closed
+func (s *digestScheduler) Run(stop <-chan struct{}, logf func(format string, args ...interface{})) {
+	for {
+		next := nextDailyRun(time.Now(), s.system.config.Notifications.DigestHour)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.SendDigests(next); err != nil {
+			logf("Digest delivery failed: %v\n", err)
+		}
+	}
+}
+
+// nextDailyRun returns the first occurrence of hour:00 after now
+func nextDailyRun(now time.Time, hour int) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
@@ -0,0 +1,170 @@
+package bwc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeMailer struct {
+	sent []fakeMail
+	err  error
+}
+
+type fakeMail struct {
+	to          []string
+	subject     string
+	body        string
+	attachments []mailAttachment
+}
+
+func (m *fakeMailer) SendMail(to []string, subject, body string, attachments ...mailAttachment) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, fakeMail{to: to, subject: subject, body: body, attachments: attachments})
+	return nil
+}
+
+func TestBuildDigest(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 30
+
+	testFile := createTestFile(t, tmpDir)
+	fresh, _ := system.IngestEvidence(testFile, "CASE-DIG-001", "OFF-123", "Officer Test", "Test Location", nil)
+	tampered, _ := system.IngestEvidence(testFile, "CASE-DIG-002", "OFF-124", "Officer Test", "Test Location", nil)
+	expiring, _ := system.IngestEvidence(testFile, "CASE-DIG-003", "OFF-125", "Officer Test", "Test Location", nil)
+
+	os.WriteFile(tampered.FilePath, []byte("altered"), 0600)
+	system.VerifyIntegrity(tampered.ID, "AUDITOR-1")
+	system.RequestCustodyTransfer(fresh.ID, "OFF-123", "DET-456", "Analysis")
+
+	system.mu.Lock()
+	system.evidenceDB.Get(expiring.ID).CreatedAt = time.Now().AddDate(0, 0, -30).Add(-time.Hour)
+	system.mu.Unlock()
+
+	digest, err := system.BuildDigest("supervisor", time.Now(), nil)
+	if err != nil {
+		t.Fatalf("BuildDigest failed: %v", err)
+	}
+	if digest.Ingested != 2 || digest.IngestedByCase["CASE-DIG-001"] != 1 {
+		t.Errorf("Unexpected ingest counts: %d %v", digest.Ingested, digest.IngestedByCase)
+	}
+	if len(digest.IntegrityFailures) != 1 || digest.IntegrityFailures[0].EvidenceID != tampered.ID {
+		t.Errorf("Expected one integrity failure, got %v", digest.IntegrityFailures)
+	}
+	if len(digest.PendingCustody) != 1 {
+		t.Errorf("Expected one pending custody request, got %v", digest.PendingCustody)
+	}
+	if len(digest.RetentionDue) != 1 || digest.RetentionDue[0].EvidenceID != expiring.ID {
+		t.Errorf("Expected one item entering retention disposition, got %v", digest.RetentionDue)
+	}
+
+	text := digest.Text()
+	for _, want := range []string{"Evidence ingested: 2", "Integrity failures: 1", "awaiting acceptance: 1", "retention disposition: 1"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected digest text to contain %q:\n%s", want, text)
+		}
+	}
+
+	limited, err := system.BuildDigest("evidence-tech", time.Now(), []string{"custody"})
+	if err != nil {
+		t.Fatalf("BuildDigest failed: %v", err)
+	}
+	if limited.Ingested != 0 || len(limited.IntegrityFailures) != 0 || len(limited.PendingCustody) != 1 {
+		t.Errorf("Expected only the custody section, got %+v", limited)
+	}
+	if strings.Contains(limited.Text(), "Integrity failures") {
+		t.Error("Expected excluded sections to be omitted from the text")
+	}
+
+	if _, err := system.BuildDigest("x", time.Now(), []string{"weather"}); err == nil {
+		t.Error("Expected error for unknown section")
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(testFile, "CASE-DIG-010", "OFF-126", "Officer Test", "Test Location", nil)
+
+	var received Digest
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer hook.Close()
+
+	system.config.Notifications.Digests = []DigestConfig{
+		{Role: "supervisor", Recipients: []string{"sgt@example.gov"}},
+		{Role: "evidence-tech", WebhookURL: hook.URL, Sections: []string{"ingest"}},
+	}
+
+	mail := &fakeMailer{}
+	scheduler := newDigestScheduler(system)
+	scheduler.mail = mail
+
+	if err := scheduler.SendDigests(time.Now()); err != nil {
+		t.Fatalf("SendDigests failed: %v", err)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].to[0] != "sgt@example.gov" || !strings.Contains(mail.sent[0].subject, "supervisor") {
+		t.Errorf("Unexpected mail: %+v", mail.sent)
+	}
+	if received.Role != "evidence-tech" || received.Ingested != 1 {
+		t.Errorf("Unexpected webhook digest: %+v", received)
+	}
+
+	sent := 0
+	for _, log := range system.GetAuditLogs("", "SYSTEM") {
+		if log.Action == "SEND_DIGEST" {
+			sent++
+		}
+	}
+	if sent != 2 {
+		t.Errorf("Expected 2 SEND_DIGEST audit entries, got %d", sent)
+	}
+
+	mail.err = errors.New("relay unavailable")
+	if err := scheduler.SendDigests(time.Now()); err == nil || !strings.Contains(err.Error(), "relay unavailable") {
+		t.Errorf("Expected mail failure to be reported, got %v", err)
+	}
+}
+
+func TestNextDailyRun(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 30, 0, 0, time.UTC)
+
+	if next := nextDailyRun(now, 7); !next.Equal(time.Date(2025, 3, 11, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected tomorrow 07:00, got %v", next)
+	}
+	if next := nextDailyRun(now, 18); !next.Equal(time.Date(2025, 3, 10, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected today 18:00, got %v", next)
+	}
+}
+
+func TestDigestConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.SMTPHost = ""
+	cfg.Notifications.DigestHour = 24
+	cfg.Notifications.Digests = []DigestConfig{
+		{Role: "", Recipients: []string{"a@example.gov"}},
+		{Role: "tech", WebhookURL: "ftp://hooks.example.gov", Sections: []string{"weather"}},
+		{Role: "empty"},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{"digest_hour", "digests[0].role", "smtp_host", "digests[1].webhook_url", "digests[1].sections", "digests[2] needs"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected problem mentioning %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strings"
+
+	"lukechampine.com/blake3"
+)
+
+// HashAlgorithm names a digest kept of each evidence file. SHA-256 is always
+// kept, as the file hash; integrity.hash_algorithms adds others.
+type HashAlgorithm string
+
+const (
+	HashSHA256 HashAlgorithm = "sha256"
+	HashSHA512 HashAlgorithm = "sha512"
+	HashBLAKE3 HashAlgorithm = "blake3"
+)
+
+// newDigest returns a hash for alg
+func newDigest(alg HashAlgorithm) (hash.Hash, error) {
+	switch alg {
+	case HashSHA256:
+		return sha256.New(), nil
+	case HashSHA512:
+		return sha512.New(), nil
+	case HashBLAKE3:
+		return blake3.New(32, nil), nil
+	}
+	return nil, &ValidationError{Field: "hash algorithm", Value: string(alg), Reason: "must be sha256, sha512 or blake3"}
+}
+
+// digester computes several digests of the bytes written to it
+type digester struct {
+	hashes map[HashAlgorithm]hash.Hash
+}
+
+// newDigester returns a digester for algs, or nil when there are none
+func newDigester(algs []HashAlgorithm) (*digester, error) {
+	if len(algs) == 0 {
+		return nil, nil
+	}
+	d := &digester{hashes: make(map[HashAlgorithm]hash.Hash, len(algs))}
+	for _, alg := range algs {
+		h, err := newDigest(alg)
+		if err != nil {
+			return nil, err
+		}
+		d.hashes[alg] = h
+	}
+	return d, nil
+}
+
+func (d *digester) Write(p []byte) (int, error) {
+	for _, h := range d.hashes {
+		h.Write(p)
+	}
+	return len(p), nil
+}
+
+// sums returns the hex digests, or nil for a nil digester
+func (d *digester) sums() map[HashAlgorithm]string {
+	if d == nil {
+		return nil
+	}
+	sums := make(map[HashAlgorithm]string, len(d.hashes))
+	for alg, h := range d.hashes {
+		sums[alg] = hex.EncodeToString(h.Sum(nil))
+	}
+	return sums
+}
+
+// via returns wrap, also feeding what is read through it to d, so the
+// digests are computed in the same read as the SHA-256
+func (d *digester) via(wrap func(io.Reader) io.Reader) func(io.Reader) io.Reader {
+	if d == nil {
+		return wrap
+	}
+	return func(r io.Reader) io.Reader {
+		if wrap != nil {
+			r = wrap(r)
+		}
+		return io.TeeReader(r, d)
+	}
+}
+
+// extraDigests is the algorithms configured besides SHA-256
+func (bwc *BWCSystem) extraDigests() []HashAlgorithm {
+	algs := make([]HashAlgorithm, 0)
+	for _, name := range bwc.config.Integrity.HashAlgorithms {
+		if alg := HashAlgorithm(strings.ToLower(name)); alg != HashSHA256 {
+			algs = append(algs, alg)
+		}
+	}
+	return algs
+}
+
+// storedDigests is the algorithms evidence has digests of besides SHA-256,
+// in a stable order
+func storedDigests(evidence *Evidence) []HashAlgorithm {
+	algs := make([]HashAlgorithm, 0, len(evidence.Digests))
+	for alg := range evidence.Digests {
+		algs = append(algs, alg)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	return algs
+}
+
+// mismatchedDigests lists the algorithms whose digest in current differs
+// from the one evidence has stored
+func mismatchedDigests(evidence *Evidence, current map[HashAlgorithm]string) []HashAlgorithm {
+	var mismatched []HashAlgorithm
+	for _, alg := range storedDigests(evidence) {
+		if current[alg] != evidence.Digests[alg] {
+			mismatched = append(mismatched, alg)
+		}
+	}
+	return mismatched
+}
+
+// validateHashAlgorithms reports algorithms that are not supported
+func validateHashAlgorithms(names []string) error {
+	for _, name := range names {
+		if _, err := newDigest(HashAlgorithm(strings.ToLower(name))); err != nil {
+			return fmt.Errorf("integrity.hash_algorithms: %q must be sha256, sha512 or blake3", name)
+		}
+	}
+	return nil
+}
+
+// joinAlgorithms lists algs for a message, e.g. "sha512, blake3"
+func joinAlgorithms(algs []HashAlgorithm) string {
+	names := make([]string, len(algs))
+	for i, alg := range algs {
+		names[i] = string(alg)
+	}
+	return strings.Join(names, ", ")
+}
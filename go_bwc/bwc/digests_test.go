@@ -0,0 +1,53 @@
+package bwc
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMultipleDigests(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.HashAlgorithms = []string{"sha256", "SHA512", "blake3"}
+
+	path := createTestFile(t, tmpDir)
+	evidence, err := system.IngestEvidence(path, "CASE-DIG-001", "OFF-5001", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	sum := sha512.Sum512(data)
+	b3, _ := newDigest(HashBLAKE3)
+	b3.Write(data)
+	if len(evidence.Digests) != 2 || evidence.Digests[HashSHA512] != hex.EncodeToString(sum[:]) ||
+		evidence.Digests[HashBLAKE3] != hex.EncodeToString(b3.Sum(nil)) {
+		t.Fatalf("unexpected digests %v", evidence.Digests)
+	}
+
+	if ok, err := system.VerifyIntegrity(evidence.ID, "AUD-5001"); err != nil || !ok {
+		t.Fatalf("expected the digests to verify, got %v %v", ok, err)
+	}
+
+	// A digest that no longer matches fails the check even though the
+	// SHA-256 does
+	system.evidenceDB.Get(evidence.ID).Digests[HashBLAKE3] = strings.Repeat("0", 64)
+	ok, err := system.VerifyIntegrity(evidence.ID, "AUD-5001")
+	if err != nil || ok {
+		t.Fatalf("expected the blake3 mismatch to fail the check, got %v %v", ok, err)
+	}
+	record, _ := system.GetEvidence(evidence.ID)
+	check := record.IntegrityChecks[len(record.IntegrityChecks)-1]
+	if len(check.MismatchedDigests) != 1 || check.MismatchedDigests[0] != HashBLAKE3 || !strings.Contains(check.Notes, "blake3") {
+		t.Errorf("expected the check to name the blake3 digest, got %v %q", check.MismatchedDigests, check.Notes)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Integrity.HashAlgorithms = []string{"md5"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "hash_algorithms") {
+		t.Errorf("expected an unsupported algorithm to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+// Package bwc manages forensic body-worn camera evidence: ingest with
+// cryptographic hashing, chain of custody, integrity verification, retention,
+// reporting and an append-only audit log.
+//
+// Programs embed it by opening a BWCSystem, either with NewBWCSystem for a
+// storage directory or with LoadRuntimeConfig and NewBWCSystemFromConfig for a
+// full configuration, and calling its methods. NewAPIHandler serves the REST
+// API and web review UI from the same system. The bwc-system binary in cmd/bwc
+// is a thin wrapper around RunCommand.
+package bwc
@@ -0,0 +1,212 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// defaultOCRTimeout bounds text extraction when none is configured
+	defaultOCRTimeout = 2 * time.Minute
+	// maxDocumentText caps the text kept from one document
+	maxDocumentText = 8 << 20
+)
+
+// TextExtractor reads the text of a document, by OCR for scans. It must
+// treat the file as read-only.
+type TextExtractor interface {
+	Name() string
+	ExtractText(ctx context.Context, path string) (string, error)
+}
+
+// DocumentInfo records the text extracted from a document for the full-text
+// index
+type DocumentInfo struct {
+	Format string `json:"format"`
+	// Extractor is the text extractor that read the document
+	Extractor   string    `json:"extractor,omitempty"`
+	ExtractedAt time.Time `json:"extracted_at,omitempty"`
+	Words       int       `json:"words"`
+	// Text is the extracted text, kept with the derived files
+	Text *DerivedFile `json:"text,omitempty"`
+}
+
+func documentFormat(path string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+}
+
+// SetTextExtractor sets the extractor used for document evidence; nil turns
+// text extraction off
+func (bwc *BWCSystem) SetTextExtractor(x TextExtractor) {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	bwc.textExtractor = x
+}
+
+// extractText reads the text of the document at path with the configured
+// extractor. It returns "" without error when no extractor is set. It must
+// not be called with bwc.mu held.
+func (bwc *BWCSystem) extractText(path string) (string, string, error) {
+	bwc.mu.RLock()
+	x := bwc.textExtractor
+	bwc.mu.RUnlock()
+	if x == nil {
+		return "", "", nil
+	}
+
+	timeout := defaultOCRTimeout
+	if s := bwc.config.OCR.TimeoutSeconds; s > 0 {
+		timeout = time.Duration(s) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	text, err := x.ExtractText(ctx, path)
+	if err != nil {
+		return "", x.Name(), fmt.Errorf("text extractor %s: %w", x.Name(), err)
+	}
+	if !utf8.ValidString(text) {
+		text = strings.ToValidUTF8(text, "�")
+	}
+	return text, x.Name(), nil
+}
+
+// storeDocumentTextLocked keeps extracted text with the derived files of
+// evidence and indexes it; the caller must hold bwc.mu
+func (bwc *BWCSystem) storeDocumentTextLocked(evidence *Evidence, text, extractor string) error {
+	dir := bwc.derivedDir(evidence.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create derived directory: %w", err)
+	}
+	path := filepath.Join(dir, "text.txt")
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		return fmt.Errorf("failed to write document text: %w", err)
+	}
+	hash, err := calculateFileHash(path)
+	if err != nil {
+		return err
+	}
+
+	evidence.Document = &DocumentInfo{
+		Format:      documentFormat(evidence.FilePath),
+		Extractor:   extractor,
+		ExtractedAt: time.Now(),
+		Words:       len(strings.Fields(text)),
+		Text:        &DerivedFile{Name: "text.txt", Path: path, SHA256: hash, Size: int64(len(text))},
+	}
+	bwc.textIndex.add(evidence.ID, text)
+	return nil
+}
+
+// indexDocumentLocked adds the stored text of document evidence to the
+// full-text index, as when records are loaded from an evidence store. The
+// caller must hold bwc.mu.
+func (bwc *BWCSystem) indexDocumentLocked(evidence *Evidence) {
+	if evidence.Document == nil || evidence.Document.Text == nil {
+		return
+	}
+	if text, err := os.ReadFile(evidence.Document.Text.Path); err == nil {
+		bwc.textIndex.add(evidence.ID, string(text))
+	}
+}
+
+// ExtractDocumentText runs text extraction again for document evidence, for
+// documents ingested before an extractor was configured or read badly. The
+// new text replaces the old in the full-text index.
+func (bwc *BWCSystem) ExtractDocumentText(evidenceID, userID string) error {
+	if err := bwc.authorize(userID, PermEditMetadata, "Text extraction", evidenceID); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.RLock()
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	var path string
+	if evidence != nil {
+		path = evidence.FilePath
+	}
+	bwc.mu.RUnlock()
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if mediaTypeOf(evidence) != MediaDocument {
+		return errors.New("evidence is not a document")
+	}
+	if evidence.Encryption != nil {
+		return errEncryptedAtRest
+	}
+
+	text, extractor, err := bwc.extractText(path)
+	if err == nil && extractor == "" {
+		err = errors.New("no text extractor is configured")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Text extraction"); err != nil {
+		return err
+	}
+	if err != nil {
+		bwc.logAudit("SYSTEM", "OCR_FAILED", evidenceID, err.Error(), "")
+		return err
+	}
+	if err := bwc.storeDocumentTextLocked(evidence, text, extractor); err != nil {
+		return err
+	}
+	markModified(evidence, time.Now())
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	bwc.logAudit(userID, "EXTRACT_TEXT", evidenceID,
+		fmt.Sprintf("%d words extracted by %s", evidence.Document.Words, extractor), "")
+
+	return nil
+}
+
+// execTextExtractor runs an external OCR program that prints the text of the
+// document on stdout
+type execTextExtractor struct {
+	command []string
+}
+
+func newExecTextExtractor(cfg OCRConfig) *execTextExtractor {
+	return &execTextExtractor{command: cfg.Command}
+}
+
+func (x *execTextExtractor) Name() string {
+	return filepath.Base(x.command[0])
+}
+
+// ExtractText runs the command with the document path as its last argument
+func (x *execTextExtractor) ExtractText(ctx context.Context, path string) (string, error) {
+	args := append(append([]string(nil), x.command[1:]...), path)
+	cmd := exec.CommandContext(ctx, x.command[0], args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: maxDocumentText}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: 1024}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", errors.New("timed out")
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return "", fmt.Errorf("%v: %s", err, detail)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
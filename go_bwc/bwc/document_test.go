@@ -0,0 +1,180 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// funcTextExtractor is a TextExtractor backed by a function
+type funcTextExtractor func(string) (string, error)
+
+func (x funcTextExtractor) Name() string { return "test-ocr" }
+
+func (x funcTextExtractor) ExtractText(_ context.Context, path string) (string, error) {
+	return x(path)
+}
+
+// scannedText maps the name of a test document to the text OCR would find
+var scannedText = map[string]string{
+	"consent.pdf":  "CONSENT TO SEARCH\nI consent to a search of my vehicle, a grey Honda Civic.",
+	"property.tif": "Property receipt: one grey backpack",
+}
+
+func writeTestDocument(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("%PDF-1.4 scanned page"), 0600); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	return path
+}
+
+func TestIngestDocumentIndexesText(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.SetTextExtractor(funcTextExtractor(func(path string) (string, error) {
+		if text, ok := scannedText[filepath.Base(path)]; ok {
+			return text, nil
+		}
+		return "", errors.New("unreadable scan")
+	}))
+
+	consent, err := system.IngestEvidence(writeTestDocument(t, tmpDir, "consent.pdf"), "CASE-DOC-001", "OFF-1001", "Officer Test", "Station", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	doc := consent.Document
+	if consent.MediaType != MediaDocument || doc == nil || doc.Format != "pdf" || doc.Extractor != "test-ocr" || doc.Words != 15 || doc.Text == nil {
+		t.Fatalf("Unexpected document metadata %+v", doc)
+	}
+	if data, _ := os.ReadFile(doc.Text.Path); string(data) != scannedText["consent.pdf"] {
+		t.Errorf("Unexpected stored text %q", data)
+	}
+	if hash, _ := calculateFileHash(doc.Text.Path); hash != doc.Text.SHA256 {
+		t.Error("Stored text hash does not match")
+	}
+
+	property, _ := system.IngestEvidence(writeTestDocument(t, tmpDir, "property.tif"), "CASE-DOC-001", "OFF-1002", "Officer Test", "Station", nil)
+	system.IngestEvidence(createTestFile(t, tmpDir), "CASE-DOC-001", "OFF-1003", "Officer Test", "Station", nil)
+
+	results, err := system.SearchText("grey")
+	if err != nil || len(results) != 2 || results[0].ID > results[1].ID {
+		t.Fatalf("Expected both documents in ID order, got %d results, %v", len(results), err)
+	}
+	results, _ = system.SearchText("Honda consent")
+	if len(results) != 1 || results[0].ID != consent.ID {
+		t.Errorf("Expected only the consent form, got %d results", len(results))
+	}
+	if _, err := system.SearchText(" - "); err == nil {
+		t.Error("Expected a query without words to be refused")
+	}
+
+	unreadable, err := system.IngestEvidence(writeTestDocument(t, tmpDir, "blurred.pdf"), "CASE-DOC-001", "OFF-1004", "Officer Test", "Station", nil)
+	if err != nil {
+		t.Fatalf("Expected an OCR failure not to block ingest: %v", err)
+	}
+	if unreadable.Document == nil || unreadable.Document.Text != nil {
+		t.Errorf("Expected a document without text, got %+v", unreadable.Document)
+	}
+	logs := system.GetAuditLogs(unreadable.ID, "SYSTEM")
+	if len(logs) != 1 || logs[0].Action != "OCR_FAILED" {
+		t.Errorf("Expected OCR_FAILED audit entry, got %v", logs)
+	}
+
+	report, _ := system.GenerateReport("CASE-DOC-001")
+	if !strings.Contains(report, "Document: tif, 5 words extracted by test-ocr") {
+		t.Errorf("Expected the document details in the report:\n%s", report)
+	}
+
+	files, _ := system.storedFiles(property)
+	if len(files) != 2 || files[1] != property.Document.Text.Path {
+		t.Errorf("Expected the extracted text among the stored files, got %v", files)
+	}
+}
+
+func TestExtractDocumentText(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := writeTestDocument(t, tmpDir, "consent.pdf")
+	evidence, _ := system.IngestEvidence(path, "CASE-DOC-002", "OFF-1005", "Officer Test", "Station", nil)
+	if evidence.Document == nil || evidence.Document.Extractor != "" {
+		t.Fatalf("Expected a document without text, got %+v", evidence.Document)
+	}
+	if err := system.ExtractDocumentText(evidence.ID, "RECORDS-1"); err == nil {
+		t.Error("Expected extraction without an extractor to fail")
+	}
+
+	system.SetTextExtractor(funcTextExtractor(func(string) (string, error) {
+		return scannedText["consent.pdf"], nil
+	}))
+	if err := system.ExtractDocumentText(evidence.ID, "RECORDS-1"); err != nil {
+		t.Fatalf("ExtractDocumentText failed: %v", err)
+	}
+	if results, _ := system.SearchText("vehicle"); len(results) != 1 {
+		t.Errorf("Expected the document indexed, got %d results", len(results))
+	}
+	logs := system.GetAuditLogs(evidence.ID, "RECORDS-1")
+	if len(logs) != 1 || logs[0].Action != "EXTRACT_TEXT" || !strings.Contains(logs[0].Details, "15 words") {
+		t.Errorf("Expected EXTRACT_TEXT audit entry, got %v", logs)
+	}
+
+	video, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-DOC-002", "OFF-1006", "Officer Test", "Station", nil)
+	if err := system.ExtractDocumentText(video.ID, "RECORDS-1"); err == nil {
+		t.Error("Expected extraction from video to be refused")
+	}
+}
+
+func TestExecTextExtractor(t *testing.T) {
+	tmpDir := t.TempDir()
+	script := writeProcessorScript(t, tmpDir, "ocr.sh", `echo "text of $(basename "$1")"`+"\n")
+	failing := writeProcessorScript(t, tmpDir, "fail.sh", "echo 'no text layer' >&2\nexit 1\n")
+
+	text, err := newExecTextExtractor(OCRConfig{Command: []string{"/bin/sh", script}}).ExtractText(context.Background(), "/tmp/consent.pdf")
+	if err != nil || text != "text of consent.pdf\n" {
+		t.Errorf("Unexpected text %q, %v", text, err)
+	}
+	_, err = newExecTextExtractor(OCRConfig{Command: []string{"/bin/sh", failing}}).ExtractText(context.Background(), "/tmp/consent.pdf")
+	if err == nil || !strings.Contains(err.Error(), "no text layer") {
+		t.Errorf("Expected the extractor's error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	slow := writeProcessorScript(t, tmpDir, "slow.sh", "exec sleep 5\n")
+	if _, err := newExecTextExtractor(OCRConfig{Command: []string{"/bin/sh", slow}}).ExtractText(ctx, "/tmp/consent.pdf"); err == nil {
+		t.Error("Expected a slow extractor to time out")
+	}
+}
+
+func TestServerSearchText(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	system.SetTextExtractor(funcTextExtractor(func(string) (string, error) {
+		return scannedText["consent.pdf"], nil
+	}))
+	evidence, _ := system.IngestEvidence(writeTestDocument(t, tmpDir, "consent.pdf"), "CASE-DOC-003", "OFF-1007", "Officer Test", "Station", nil)
+
+	resp := authGet(t, server, "/api/evidence?q=honda+civic")
+	var results []Evidence
+	json.NewDecoder(resp.Body).Decode(&results)
+	resp.Body.Close()
+	if len(results) != 1 || results[0].ID != evidence.ID {
+		t.Fatalf("Expected the consent form, got %d results", len(results))
+	}
+
+	resp = authGet(t, server, "/api/evidence?q=%2A")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a query without words, got %d", resp.StatusCode)
+	}
+}
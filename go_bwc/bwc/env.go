@@ -0,0 +1,256 @@
+package bwc
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is prepended to every environment variable the system reads
+const EnvPrefix = "BWC_"
+
+// SecretFileSuffix marks a variable whose value is the path of a file holding the setting
+const SecretFileSuffix = "_FILE"
+
+// ConfigOverride records a setting replaced by the environment or a secret file
+type ConfigOverride struct {
+	Setting  string `json:"setting"`
+	Source   string `json:"source"`
+	IsSecret bool   `json:"is_secret"`
+}
+
+// envSetting maps one environment variable onto a configuration field
+type envSetting struct {
+	name    string
+	setting string
+	secret  bool
+	apply   func(cfg *Config, value string) error
+}
+
+// envSettings lists every supported variable, without the BWC_ prefix.
+// Each can also be supplied as NAME_FILE pointing at a mounted secret.
+var envSettings = []envSetting{
+	{name: "STORAGE_PATH", setting: "storage.path", apply: func(c *Config, v string) error {
+		c.Storage.Path = v
+		return nil
+	}},
+	{name: "RETENTION_DAYS", setting: "storage.retention_days", apply: func(c *Config, v string) error {
+		return setInt(&c.Storage.RetentionDays, v)
+	}},
+	{name: "HASH_ALGORITHM", setting: "security.hash_algorithm", apply: func(c *Config, v string) error {
+		c.Security.HashAlgorithm = v
+		return nil
+	}},
+	{name: "ENABLE_ENCRYPTION", setting: "security.enable_encryption", apply: func(c *Config, v string) error {
+		return setBool(&c.Security.EnableEncryption, v)
+	}},
+	{name: "ENCRYPTION_KEY_FILE", setting: "security.encryption_key_file", apply: func(c *Config, v string) error {
+		c.Security.EncryptionKeyFile = v
+		return nil
+	}},
+	{name: "KMS_PROVIDER", setting: "security.kms.provider", apply: func(c *Config, v string) error {
+		c.Security.KMS.Provider = v
+		return nil
+	}},
+	{name: "KMS_KEY_ID", setting: "security.kms.key_id", apply: func(c *Config, v string) error {
+		c.Security.KMS.KeyID = v
+		return nil
+	}},
+	{name: "KMS_REGION", setting: "security.kms.region", apply: func(c *Config, v string) error {
+		c.Security.KMS.Region = v
+		return nil
+	}},
+	{name: "KMS_ENDPOINT", setting: "security.kms.endpoint", apply: func(c *Config, v string) error {
+		c.Security.KMS.Endpoint = v
+		return nil
+	}},
+	{name: "KMS_ACCESS_KEY_ID", setting: "security.kms.access_key_id", secret: true, apply: func(c *Config, v string) error {
+		c.Security.KMS.AccessKeyID = v
+		return nil
+	}},
+	{name: "KMS_SECRET_ACCESS_KEY", setting: "security.kms.secret_access_key", secret: true, apply: func(c *Config, v string) error {
+		c.Security.KMS.SecretAccessKey = v
+		return nil
+	}},
+	{name: "KMS_MOUNT", setting: "security.kms.mount", apply: func(c *Config, v string) error {
+		c.Security.KMS.Mount = v
+		return nil
+	}},
+	{name: "TSA_URL", setting: "timestamping.url", apply: func(c *Config, v string) error {
+		c.Timestamping.URL = v
+		return nil
+	}},
+	{name: "HSM_PIN", setting: "security.hsm.pin", secret: true, apply: func(c *Config, v string) error {
+		c.Security.HSM.PIN = v
+		return nil
+	}},
+	{name: "KMS_TOKEN", setting: "security.kms.token", secret: true, apply: func(c *Config, v string) error {
+		c.Security.KMS.Token = v
+		return nil
+	}},
+	{name: "API_ENABLED", setting: "api.enabled", apply: func(c *Config, v string) error {
+		return setBool(&c.API.Enabled, v)
+	}},
+	{name: "ACCESS_CONTROL_ENABLED", setting: "access_control.enabled", apply: func(c *Config, v string) error {
+		return setBool(&c.AccessControl.Enabled, v)
+	}},
+	{name: "API_HOST", setting: "api.host", apply: func(c *Config, v string) error {
+		c.API.Host = v
+		return nil
+	}},
+	{name: "API_PORT", setting: "api.port", apply: func(c *Config, v string) error {
+		return setInt(&c.API.Port, v)
+	}},
+	{name: "TLS_CERT_PATH", setting: "api.tls_cert_path", apply: func(c *Config, v string) error {
+		c.API.TLSCertPath = v
+		return nil
+	}},
+	{name: "TLS_KEY_PATH", setting: "api.tls_key_path", apply: func(c *Config, v string) error {
+		c.API.TLSKeyPath = v
+		return nil
+	}},
+	{name: "DATABASE_TYPE", setting: "database.type", apply: func(c *Config, v string) error {
+		c.Database.Type = v
+		return nil
+	}},
+	{name: "DATABASE_DSN", setting: "database.dsn", secret: true, apply: func(c *Config, v string) error {
+		c.Database.DSN = v
+		return nil
+	}},
+	{name: "DATABASE_PASSWORD", setting: "database.password", secret: true, apply: func(c *Config, v string) error {
+		c.Database.Password = v
+		return nil
+	}},
+	{name: "SMTP_HOST", setting: "notifications.smtp_host", apply: func(c *Config, v string) error {
+		c.Notifications.SMTPHost = v
+		return nil
+	}},
+	{name: "SMTP_PORT", setting: "notifications.smtp_port", apply: func(c *Config, v string) error {
+		return setInt(&c.Notifications.SMTPPort, v)
+	}},
+	{name: "SMTP_USER", setting: "notifications.smtp_user", apply: func(c *Config, v string) error {
+		c.Notifications.SMTPUser = v
+		return nil
+	}},
+	{name: "SMTP_PASSWORD", setting: "notifications.smtp_password", secret: true, apply: func(c *Config, v string) error {
+		c.Notifications.SMTPPassword = v
+		return nil
+	}},
+	{name: "REPLICATION_TOKEN", setting: "replication.standby_token", secret: true, apply: func(c *Config, v string) error {
+		c.Replication.StandbyToken = v
+		return nil
+	}},
+	{name: "LOG_LEVEL", setting: "logging.level", apply: func(c *Config, v string) error {
+		c.Logging.Level = v
+		return nil
+	}},
+}
+
+// LoadRuntimeConfig builds the effective configuration. Later sources override earlier ones:
+//
+//  1. built-in defaults
+//  2. the config file at path (or BWC_CONFIG when path is empty; skipped when neither is set)
+//  3. BWC_* environment variables
+//  4. BWC_*_FILE secret files
+//
+// Setting both BWC_NAME and BWC_NAME_FILE is rejected as ambiguous.
+func LoadRuntimeConfig(path string) (*Config, error) {
+	return loadRuntimeConfig(path, os.LookupEnv)
+}
+
+func loadRuntimeConfig(path string, lookup func(string) (string, bool)) (*Config, error) {
+	if path == "" {
+		path, _ = lookup(EnvPrefix + "CONFIG")
+	}
+
+	cfg := DefaultConfig()
+	if path != "" {
+		fileCfg, err := loadConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		cfg = fileCfg
+	}
+
+	if err := applyEnvironment(cfg, lookup); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnvironment overlays environment variables and secret files onto cfg
+func applyEnvironment(cfg *Config, lookup func(string) (string, bool)) error {
+	problems := make([]string, 0)
+
+	for _, s := range envSettings {
+		envName := EnvPrefix + s.name
+		fileName := envName + SecretFileSuffix
+
+		value, hasValue := lookup(envName)
+		secretPath, hasFile := lookup(fileName)
+
+		if hasValue && hasFile {
+			problems = append(problems, fmt.Sprintf("both %s and %s are set", envName, fileName))
+			continue
+		}
+
+		source := "env " + envName
+		if hasFile {
+			data, err := os.ReadFile(secretPath)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: failed to read secret file: %v", fileName, err))
+				continue
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+			source = "file " + secretPath
+		} else if !hasValue {
+			continue
+		}
+
+		if err := s.apply(cfg, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", envName, err))
+			continue
+		}
+
+		cfg.overrides = append(cfg.overrides, ConfigOverride{
+			Setting:  s.setting,
+			Source:   source,
+			IsSecret: s.secret,
+		})
+	}
+
+	if len(problems) > 0 {
+		return &ConfigError{Problems: problems}
+	}
+
+	return nil
+}
+
+// Overrides lists the settings replaced by environment variables or secret files
+func (c *Config) Overrides() []ConfigOverride {
+	return c.overrides
+}
+
+func setInt(dst *int, value string) error {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", value)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, value string) error {
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", value)
+	}
+	*dst = b
+	return nil
+}
@@ -0,0 +1,117 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mapLookup returns an environment lookup function backed by a map
+func mapLookup(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func TestEnvironmentOverridesConfigFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := writeTestConfig(t, tmpDir, `{"storage": {"path": "/srv/from-file"}, "api": {"port": 9000}}`)
+
+	cfg, err := loadRuntimeConfig(path, mapLookup(map[string]string{
+		"BWC_STORAGE_PATH": "/srv/from-env",
+		"BWC_LOG_LEVEL":    "debug",
+	}))
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig failed: %v", err)
+	}
+
+	if cfg.Storage.Path != "/srv/from-env" {
+		t.Errorf("Expected environment to override storage path, got %s", cfg.Storage.Path)
+	}
+
+	if cfg.API.Port != 9000 {
+		t.Errorf("Expected file value for api.port to be kept, got %d", cfg.API.Port)
+	}
+
+	if cfg.Logging.Level != "debug" {
+		t.Errorf("Expected log level debug, got %s", cfg.Logging.Level)
+	}
+
+	if len(cfg.Overrides()) != 2 {
+		t.Errorf("Expected 2 overrides, got %d", len(cfg.Overrides()))
+	}
+}
+
+func TestSecretFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretPath := filepath.Join(tmpDir, "smtp_password")
+	if err := os.WriteFile(secretPath, []byte("s3cret-value\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	cfg, err := loadRuntimeConfig("", mapLookup(map[string]string{
+		"BWC_SMTP_PASSWORD_FILE": secretPath,
+		"BWC_DATABASE_DSN":       "postgres://bwc@db/bwc",
+	}))
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig failed: %v", err)
+	}
+
+	if cfg.Notifications.SMTPPassword != "s3cret-value" {
+		t.Errorf("Expected secret with trailing newline trimmed, got %q", cfg.Notifications.SMTPPassword)
+	}
+
+	if cfg.Database.DSN != "postgres://bwc@db/bwc" {
+		t.Errorf("Expected DSN from environment, got %s", cfg.Database.DSN)
+	}
+
+	for _, o := range cfg.Overrides() {
+		if !o.IsSecret {
+			t.Errorf("Expected %s to be marked secret", o.Setting)
+		}
+	}
+}
+
+func TestEnvironmentErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	secretPath := filepath.Join(tmpDir, "db_password")
+	os.WriteFile(secretPath, []byte("pw"), 0600)
+
+	_, err := loadRuntimeConfig("", mapLookup(map[string]string{
+		"BWC_DATABASE_PASSWORD":      "pw",
+		"BWC_DATABASE_PASSWORD_FILE": secretPath,
+		"BWC_API_PORT":               "eighty",
+		"BWC_SMTP_PASSWORD_FILE":     filepath.Join(tmpDir, "missing"),
+	}))
+
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("Expected ConfigError, got %v", err)
+	}
+
+	if len(cfgErr.Problems) != 3 {
+		t.Errorf("Expected 3 problems, got %d: %v", len(cfgErr.Problems), cfgErr.Problems)
+	}
+}
+
+func TestConfigPathFromEnvironment(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := writeTestConfig(t, tmpDir, `{"storage": {"path": "/srv/via-bwc-config"}}`)
+
+	cfg, err := loadRuntimeConfig("", mapLookup(map[string]string{"BWC_CONFIG": path}))
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig failed: %v", err)
+	}
+
+	if cfg.Storage.Path != "/srv/via-bwc-config" {
+		t.Errorf("Expected storage path from BWC_CONFIG file, got %s", cfg.Storage.Path)
+	}
+
+	// Environment overrides must still be validated
+	_, err = loadRuntimeConfig(path, mapLookup(map[string]string{"BWC_HASH_ALGORITHM": "MD5"}))
+	if err == nil {
+		t.Error("Expected validation error for unsupported hash algorithm from environment")
+	}
+}
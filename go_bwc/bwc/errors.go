@@ -0,0 +1,88 @@
+package bwc
+
+import (
+	"errors"
+)
+
+// ErrorCode classifies a failure so callers can act on it without matching
+// message text. API error responses carry it as "code".
+type ErrorCode string
+
+const (
+	CodeNotFound          ErrorCode = "NOT_FOUND"
+	CodeIntegrityFailure  ErrorCode = "INTEGRITY_FAILURE"
+	CodeInvalidTransition ErrorCode = "INVALID_TRANSITION"
+	CodeInvalidArgument   ErrorCode = "INVALID_ARGUMENT"
+	CodeRevisionConflict  ErrorCode = "REVISION_CONFLICT"
+	CodeSealed            ErrorCode = "SEALED"
+	CodeHookRejected      ErrorCode = "HOOK_REJECTED"
+	CodePermissionDenied  ErrorCode = "PERMISSION_DENIED"
+	CodeUnauthenticated   ErrorCode = "UNAUTHENTICATED"
+	CodeApprovalRequired  ErrorCode = "APPROVAL_REQUIRED"
+)
+
+// BWCError is a failure with a code. errors.Is matches it against any
+// *BWCError with the same code, so errors.Is(err, ErrIntegrityFailure) holds
+// whatever the message says.
+type BWCError struct {
+	Code    ErrorCode
+	Message string
+	// Err is the underlying cause, if any
+	Err error
+}
+
+func (e *BWCError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *BWCError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a *BWCError with the same code
+func (e *BWCError) Is(target error) bool {
+	t, ok := target.(*BWCError)
+	return ok && t.Code == e.Code
+}
+
+var (
+	// ErrEvidenceNotFound is returned for an evidence ID with no record
+	ErrEvidenceNotFound = &BWCError{Code: CodeNotFound, Message: "evidence not found"}
+	// ErrIntegrityFailure is matched by operations refused because a
+	// recording no longer matches its hash. VerifyIntegrity reports a
+	// mismatch as false rather than as an error.
+	ErrIntegrityFailure = &BWCError{Code: CodeIntegrityFailure, Message: "integrity check failed"}
+	// ErrInvalidTransition is matched by status changes the lifecycle does
+	// not allow
+	ErrInvalidTransition = &BWCError{Code: CodeInvalidTransition, Message: "invalid status transition"}
+	// ErrPermissionDenied is matched by operations the acting user's role
+	// does not allow
+	ErrPermissionDenied = &BWCError{Code: CodePermissionDenied, Message: "permission denied"}
+)
+
+// ErrorCodeOf returns the code of err, classifying the system's other error
+// types too. It returns "" for nil and for errors it cannot classify.
+func ErrorCodeOf(err error) ErrorCode {
+	var bwcErr *BWCError
+	var validation *ValidationError
+	var conflict *RevisionConflictError
+	var hook *HookRejectedError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &bwcErr):
+		return bwcErr.Code
+	case errors.As(err, &validation):
+		return CodeInvalidArgument
+	case errors.As(err, &conflict):
+		return CodeRevisionConflict
+	case errors.As(err, &hook):
+		return CodeHookRejected
+	case errors.Is(err, errEvidenceSealed):
+		return CodeSealed
+	}
+	return ""
+}
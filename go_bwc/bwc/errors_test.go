@@ -0,0 +1,66 @@
+package bwc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestErrorsCarryCodes(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	if _, err := system.GetEvidence("BWC-missing"); !errors.Is(err, ErrEvidenceNotFound) || ErrorCodeOf(err) != CodeNotFound {
+		t.Errorf("expected ErrEvidenceNotFound, got %v", err)
+	}
+	if _, err := system.TransferCustodyBatch([]string{"BWC-missing"}, "OFF-1", "DET-1", "Analysis"); !errors.Is(err, ErrEvidenceNotFound) {
+		t.Errorf("expected a batch naming missing evidence to wrap ErrEvidenceNotFound, got %v", err)
+	}
+	if err := checkStatusTransition(StatusCollected, StatusDeleted); !errors.Is(err, ErrInvalidTransition) || err.Error() != "cannot change status from COLLECTED to DELETED" {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-ERR-001", "OFF-1210", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	os.WriteFile(evidence.FilePath, []byte("tampered"), 0600)
+	err = system.TransferCustody(evidence.ID, "OFF-1210", "DET-1", "Analysis")
+	if !errors.Is(err, ErrIntegrityFailure) || errors.Is(err, ErrEvidenceNotFound) {
+		t.Errorf("expected ErrIntegrityFailure, got %v", err)
+	}
+	if _, err := system.TransferCustodyBatch([]string{evidence.ID}, "OFF-1210", "DET-1", "Analysis"); ErrorCodeOf(err) != CodeIntegrityFailure {
+		t.Errorf("expected a batch with tampered evidence to be an integrity failure, got %v", err)
+	}
+
+	for err, want := range map[error]ErrorCode{
+		nil:                             "",
+		errors.New("disk full"):         "",
+		&ValidationError{Field: "case"}: CodeInvalidArgument,
+		&RevisionConflictError{}:        CodeRevisionConflict,
+		fmt.Errorf("%w under court order", errEvidenceSealed): CodeSealed,
+	} {
+		if got := ErrorCodeOf(err); got != want {
+			t.Errorf("ErrorCodeOf(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestServerErrorsCarryCodes(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	resp := authPostJSON(t, server, "/api/evidence/BWC-missing/custody", `{"to": "DET-1", "purpose": "Analysis"}`)
+	defer resp.Body.Close()
+	var body struct {
+		Error string `json:"error"`
+		Code  string `json:"code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if resp.StatusCode != http.StatusNotFound || body.Code != string(CodeNotFound) || body.Error != "evidence not found" {
+		t.Errorf("expected a coded 404, got %d %+v", resp.StatusCode, body)
+	}
+}
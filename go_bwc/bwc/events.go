@@ -0,0 +1,199 @@
+package bwc
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType identifies the kind of event published to subscribers
+type EventType string
+
+const (
+	EventAudit          EventType = "AUDIT"
+	EventIntegrityAlert EventType = "INTEGRITY_ALERT"
+	EventAccessAnomaly  EventType = "ACCESS_ANOMALY"
+
+	EventEvidenceIngested   EventType = "EVIDENCE_INGESTED"
+	EventStatusChanged      EventType = "STATUS_CHANGED"
+	EventCustodyTransferred EventType = "CUSTODY_TRANSFERRED"
+)
+
+// lifecycleEventTypes are the evidence changes streamed to live views
+var lifecycleEventTypes = []EventType{EventEvidenceIngested, EventStatusChanged, EventCustodyTransferred}
+
+// eventBufferSize is how many undelivered events a subscriber may queue before events are dropped
+const eventBufferSize = 256
+
+// Event is a real-time notification delivered to subscribers
+type Event struct {
+	Type       EventType       `json:"type"`
+	Timestamp  time.Time       `json:"timestamp"`
+	EvidenceID string          `json:"evidence_id,omitempty"`
+	CaseNumber string          `json:"case_number,omitempty"`
+	UserID     string          `json:"user_id,omitempty"`
+	Audit      *AuditLog       `json:"audit,omitempty"`
+	Alert      *IntegrityAlert `json:"alert,omitempty"`
+	Change     *EvidenceChange `json:"change,omitempty"`
+	Anomaly    *AccessAlert    `json:"anomaly,omitempty"`
+}
+
+// EvidenceChange describes an evidence lifecycle transition
+type EvidenceChange struct {
+	Status         EvidenceStatus `json:"status"`
+	PreviousStatus EvidenceStatus `json:"previous_status,omitempty"`
+	FromOfficer    string         `json:"from_officer,omitempty"`
+	ToOfficer      string         `json:"to_officer,omitempty"`
+	Purpose        string         `json:"purpose,omitempty"`
+}
+
+// EventFilter restricts which events a subscription receives; empty fields match everything
+type EventFilter struct {
+	Types      []EventType
+	EvidenceID string
+	CaseNumber string
+	UserID     string
+}
+
+// Matches reports whether the event passes the filter
+func (f EventFilter) Matches(e Event) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.EvidenceID != "" && f.EvidenceID != e.EvidenceID {
+		return false
+	}
+	if f.CaseNumber != "" && f.CaseNumber != e.CaseNumber {
+		return false
+	}
+	if f.UserID != "" && f.UserID != e.UserID {
+		return false
+	}
+	return true
+}
+
+// Subscription receives matching events on C until Close is called
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	filter  EventFilter
+	bus     *eventBus
+	dropped int
+	once    sync.Once
+}
+
+// Dropped returns how many events were discarded because the subscriber fell behind
+func (s *Subscription) Dropped() int {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	return s.dropped
+}
+
+// Close stops delivery and closes C
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subscribers, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// eventBus fans published events out to subscribers without ever blocking publishers
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{subscribers: make(map[*Subscription]struct{})}
+}
+
+func (b *eventBus) subscribe(filter EventFilter) *Subscription {
+	ch := make(chan Event, eventBufferSize)
+	sub := &Subscription{C: ch, ch: ch, filter: filter, bus: b}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// Subscribe registers for real-time events matching filter. Slow subscribers lose
+// events rather than delaying the system; Dropped reports how many were lost.
+func (bwc *BWCSystem) Subscribe(filter EventFilter) *Subscription {
+	return bwc.events.subscribe(filter)
+}
+
+// publishIntegrityAlert notifies subscribers of a failed integrity check
+func (bwc *BWCSystem) publishIntegrityAlert(evidence *Evidence, check IntegrityCheck) {
+	alert := &IntegrityAlert{
+		EvidenceID: evidence.ID,
+		CaseNumber: evidence.CaseNumber,
+		CheckedAt:  check.Timestamp,
+		CheckedBy:  check.CheckedBy,
+		Notes:      check.Notes,
+	}
+
+	bwc.events.publish(Event{
+		Type:       EventIntegrityAlert,
+		Timestamp:  check.Timestamp,
+		EvidenceID: evidence.ID,
+		CaseNumber: evidence.CaseNumber,
+		UserID:     check.CheckedBy,
+		Alert:      alert,
+	})
+}
+
+// publishTransferIntegrityAlert notifies subscribers that a custody transfer
+// was refused because the file no longer matches its hash. The check is not
+// recorded on the evidence; the refusal is the record.
+func (bwc *BWCSystem) publishTransferIntegrityAlert(evidence *Evidence, officerID, currentHash string) {
+	bwc.publishIntegrityAlert(evidence, IntegrityCheck{
+		Timestamp: time.Now(),
+		CheckedBy: officerID,
+		HashValue: currentHash,
+		Notes:     "ALERT: File hash mismatch detected during custody transfer",
+	})
+}
+
+// publishEvidenceChange notifies subscribers of an evidence lifecycle transition
+func (bwc *BWCSystem) publishEvidenceChange(eventType EventType, evidence *Evidence, userID string, change EvidenceChange) {
+	change.Status = evidence.Status
+
+	bwc.events.publish(Event{
+		Type:       eventType,
+		Timestamp:  time.Now(),
+		EvidenceID: evidence.ID,
+		CaseNumber: evidence.CaseNumber,
+		UserID:     userID,
+		Change:     &change,
+	})
+}
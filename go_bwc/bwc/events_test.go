@@ -0,0 +1,166 @@
+package bwc
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+// nextEvent waits briefly for an event on the subscription
+func nextEvent(t *testing.T, sub *Subscription) Event {
+	select {
+	case e := <-sub.C:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for event")
+		return Event{}
+	}
+}
+
+func TestSubscribeAuditEvents(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	sub := system.Subscribe(EventFilter{Types: []EventType{EventAudit}, UserID: "OFF-123"})
+	defer sub.Close()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-EVT-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	e := nextEvent(t, sub)
+	if e.Type != EventAudit || e.Audit == nil || e.Audit.Action != "INGEST_EVIDENCE" {
+		t.Errorf("Expected INGEST_EVIDENCE audit event, got %+v", e)
+	}
+	if e.EvidenceID != evidence.ID {
+		t.Errorf("Expected evidence ID %s, got %s", evidence.ID, e.EvidenceID)
+	}
+
+	// Events from other users are filtered out
+	system.VerifyIntegrity(evidence.ID, "DET-456")
+	select {
+	case e := <-sub.C:
+		t.Errorf("Expected no event for another user, got %+v", e)
+	default:
+	}
+}
+
+func TestSubscribeIntegrityAlerts(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-EVT-002", "OFF-123", "Officer Test", "Test Location", nil)
+
+	sub := system.Subscribe(EventFilter{Types: []EventType{EventIntegrityAlert}})
+	defer sub.Close()
+
+	system.VerifyIntegrity(evidence.ID, "OFF-123")
+	os.WriteFile(evidence.FilePath, []byte("TAMPERED"), 0600)
+	system.VerifyIntegrity(evidence.ID, "OFF-123")
+
+	e := nextEvent(t, sub)
+	if e.Type != EventIntegrityAlert || e.Alert == nil {
+		t.Fatalf("Expected integrity alert, got %+v", e)
+	}
+	if e.CaseNumber != "CASE-EVT-002" || e.Alert.EvidenceID != evidence.ID {
+		t.Errorf("Unexpected alert contents: %+v", e.Alert)
+	}
+
+	select {
+	case e := <-sub.C:
+		t.Errorf("Expected only one alert, got %+v", e)
+	default:
+	}
+}
+
+func TestRefusedTransferRaisesIntegrityAlert(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-EVT-003", "OFF-123", "Officer Test", "Test Location", nil)
+
+	sub := system.Subscribe(EventFilter{Types: []EventType{EventIntegrityAlert}})
+	defer sub.Close()
+
+	os.WriteFile(evidence.FilePath, []byte("TAMPERED"), 0600)
+	if err := system.TransferCustody(evidence.ID, "OFF-123", "DET-1", "Analysis"); err == nil {
+		t.Fatal("Expected the transfer to be refused")
+	}
+	if e := nextEvent(t, sub); e.EvidenceID != evidence.ID || e.UserID != "OFF-123" || e.Alert == nil {
+		t.Errorf("Expected an alert for the refused transfer, got %+v", e)
+	}
+	system.TransferCustodyBatch([]string{evidence.ID}, "OFF-123", "DET-1", "Analysis")
+	if e := nextEvent(t, sub); e.EvidenceID != evidence.ID {
+		t.Errorf("Expected an alert for the refused batch, got %+v", e)
+	}
+	if stored, _ := system.GetEvidence(evidence.ID); len(stored.IntegrityChecks) != 1 {
+		t.Errorf("Expected refused transfers not to record checks, got %+v", stored.IntegrityChecks)
+	}
+}
+
+func TestSubscriptionDropsWhenFull(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	sub := system.Subscribe(EventFilter{})
+
+	for i := 0; i < eventBufferSize+10; i++ {
+		system.logAudit("OFF-123", "TEST", "", "filler", "")
+	}
+
+	if sub.Dropped() != 10 {
+		t.Errorf("Expected 10 dropped events, got %d", sub.Dropped())
+	}
+
+	sub.Close()
+	sub.Close()
+
+	// Publishing after close must not panic
+	system.logAudit("OFF-123", "TEST", "", "after close", "")
+
+	count := 0
+	for range sub.C {
+		count++
+	}
+	if count != eventBufferSize {
+		t.Errorf("Expected %d buffered events, got %d", eventBufferSize, count)
+	}
+}
+
+func TestEvidenceLifecycleEvents(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	sub := system.Subscribe(EventFilter{Types: lifecycleEventTypes, CaseNumber: "CASE-EVT-003"})
+	defer sub.Close()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-EVT-003", "OFF-123", "Officer Test", "Test Location", nil)
+
+	e := nextEvent(t, sub)
+	if e.Type != EventEvidenceIngested || e.Change == nil || e.Change.Status != StatusCollected {
+		t.Errorf("Expected ingest event, got %+v", e)
+	}
+
+	system.TransferCustody(evidence.ID, "OFF-123", "DET-456", "Analysis")
+	e = nextEvent(t, sub)
+	if e.Type != EventCustodyTransferred || e.Change.FromOfficer != "OFF-123" || e.Change.ToOfficer != "DET-456" {
+		t.Errorf("Expected custody transfer event, got %+v", e)
+	}
+
+	system.UpdateStatus(evidence.ID, "DET-456", StatusProcessing, "Started")
+	e = nextEvent(t, sub)
+	if e.Type != EventStatusChanged || e.Change.Status != StatusProcessing || e.Change.PreviousStatus != StatusCollected {
+		t.Errorf("Expected status change event, got %+v", e)
+	}
+
+	// Changes in other cases are filtered out
+	otherFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(otherFile, "CASE-EVT-004", "OFF-789", "Officer Other", "Test Location", nil)
+	select {
+	case e := <-sub.C:
+		t.Errorf("Expected no event for another case, got %+v", e)
+	default:
+	}
+}
@@ -0,0 +1,387 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EvidenceManager is the core evidence workflow: ingest, look-up, custody,
+// status and integrity. Integrations should depend on it rather than on
+// *BWCSystem, so their tests can run against FakeEvidenceManager.
+type EvidenceManager interface {
+	IngestEvidence(filePath, caseNumber, officerID, officerName, location string, tags []string) (*Evidence, error)
+	GetEvidence(evidenceID string) (*Evidence, error)
+	SearchEvidence(caseNumber, officerID string, status EvidenceStatus) []*Evidence
+	GetChainOfCustody(evidenceID string) ([]CustodyEntry, error)
+	TransferCustody(evidenceID, fromOfficer, toOfficer, purpose string) error
+	UpdateStatus(evidenceID, officerID string, newStatus EvidenceStatus, notes string) error
+	UpdateMetadata(evidenceID, userID string, revision int64, update MetadataUpdate) (*Evidence, error)
+	VerifyIntegrity(evidenceID, checkedBy string) (bool, error)
+	ExportEvidence(evidenceID, exportPath string) error
+	GetAuditLogs(evidenceID, userID string) []AuditLog
+}
+
+var _ EvidenceManager = (*BWCSystem)(nil)
+var _ EvidenceManager = (*FakeEvidenceManager)(nil)
+
+// fakeEpoch is where a FakeEvidenceManager's clock starts
+var fakeEpoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeEvidenceManager is an in-memory EvidenceManager for tests. Ingested
+// files are read into memory rather than copied to storage, evidence IDs are
+// FAKE-000001, FAKE-000002, ... and the clock starts at fakeEpoch and
+// advances by Step on every recorded change, so two runs of the same test
+// produce the same records. Sealing, hooks, events and media probing are not
+// modelled. It returns copies, so callers cannot change its state directly.
+type FakeEvidenceManager struct {
+	// Step is how far the clock advances for each timestamp handed out
+	Step time.Duration
+
+	mu       sync.Mutex
+	clock    time.Time
+	seq      int
+	evidence map[string]*Evidence
+	contents map[string][]byte
+	audit    []AuditLog
+}
+
+// NewFakeEvidenceManager returns an empty fake with a one-second clock step
+func NewFakeEvidenceManager() *FakeEvidenceManager {
+	return &FakeEvidenceManager{
+		Step:     time.Second,
+		clock:    fakeEpoch,
+		evidence: make(map[string]*Evidence),
+		contents: make(map[string][]byte),
+	}
+}
+
+// now returns the fake clock's time and advances it; the caller must hold f.mu
+func (f *FakeEvidenceManager) now() time.Time {
+	t := f.clock
+	f.clock = f.clock.Add(f.Step)
+	return t
+}
+
+// Now returns the time the next recorded change will carry
+func (f *FakeEvidenceManager) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.clock
+}
+
+// SetNow moves the fake clock to t
+func (f *FakeEvidenceManager) SetNow(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clock = t
+}
+
+// Corrupt alters the stored copy of evidenceID, so that the next integrity
+// check fails and custody transfers are refused
+func (f *FakeEvidenceManager) Corrupt(evidenceID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, exists := f.evidence[evidenceID]; !exists {
+		return ErrEvidenceNotFound
+	}
+	f.contents[evidenceID] = append(f.contents[evidenceID], 0)
+	return nil
+}
+
+func (f *FakeEvidenceManager) logAudit(userID, action, evidenceID, details string) {
+	f.audit = append(f.audit, AuditLog{
+		Timestamp:  f.now(),
+		UserID:     userID,
+		Action:     action,
+		EvidenceID: evidenceID,
+		Details:    details,
+	})
+}
+
+func fakeHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (f *FakeEvidenceManager) IngestEvidence(filePath, caseNumber, officerID, officerName, location string, tags []string) (*Evidence, error) {
+	for _, err := range []error{ValidateIngestPath(filePath), ValidateCaseNumber(caseNumber), ValidateOfficerID(officerID)} {
+		if err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.seq++
+	id := fmt.Sprintf("FAKE-%06d", f.seq)
+	hash := fakeHash(data)
+	now := f.now()
+	evidence := &Evidence{
+		ID:          id,
+		CaseNumber:  caseNumber,
+		OfficerID:   officerID,
+		OfficerName: officerName,
+		Timestamp:   now,
+		MediaType:   mediaTypeFor(filePath),
+		Location:    location,
+		FilePath:    filePath,
+		FileHash:    hash,
+		FileSize:    int64(len(data)),
+		Status:      StatusCollected,
+		Tags:        append([]string(nil), tags...),
+		ChainOfCustody: []CustodyEntry{{
+			Timestamp:    now,
+			FromOfficer:  "SYSTEM",
+			ToOfficer:    officerID,
+			Action:       "INGESTED",
+			Purpose:      "Initial evidence collection",
+			VerifiedHash: hash,
+		}},
+		CreatedAt:    now,
+		LastModified: now,
+		Revision:     1,
+		IntegrityChecks: []IntegrityCheck{{
+			Timestamp: now,
+			CheckedBy: "SYSTEM",
+			HashValue: hash,
+			IsValid:   true,
+			Notes:     "Initial integrity check",
+		}},
+	}
+	f.evidence[id] = evidence
+	f.contents[id] = data
+
+	f.logAudit(officerID, "INGEST_EVIDENCE", id, fmt.Sprintf("Evidence ingested from case %s", caseNumber))
+
+	c := copyEvidence(evidence)
+	return &c, nil
+}
+
+func (f *FakeEvidenceManager) GetEvidence(evidenceID string) (*Evidence, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return nil, ErrEvidenceNotFound
+	}
+	c := copyEvidence(evidence)
+	return &c, nil
+}
+
+// SearchEvidence returns the matching evidence in ID order
+func (f *FakeEvidenceManager) SearchEvidence(caseNumber, officerID string, status EvidenceStatus) []*Evidence {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results := make([]*Evidence, 0)
+	for i := 1; i <= f.seq; i++ {
+		evidence, exists := f.evidence[fmt.Sprintf("FAKE-%06d", i)]
+		if !exists {
+			continue
+		}
+		if caseNumber != "" && evidence.CaseNumber != caseNumber {
+			continue
+		}
+		if officerID != "" && evidence.OfficerID != officerID {
+			continue
+		}
+		if status != "" && evidence.Status != status {
+			continue
+		}
+		c := copyEvidence(evidence)
+		results = append(results, &c)
+	}
+	return results
+}
+
+func (f *FakeEvidenceManager) GetChainOfCustody(evidenceID string) ([]CustodyEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return nil, ErrEvidenceNotFound
+	}
+	return append([]CustodyEntry(nil), evidence.ChainOfCustody...), nil
+}
+
+func (f *FakeEvidenceManager) TransferCustody(evidenceID, fromOfficer, toOfficer, purpose string) error {
+	if err := ValidateOfficerID(fromOfficer); err != nil {
+		return err
+	}
+	if err := ValidateOfficerID(toOfficer); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return ErrEvidenceNotFound
+	}
+	currentHash := fakeHash(f.contents[evidenceID])
+	if currentHash != evidence.FileHash {
+		return errors.New("integrity check failed - cannot transfer compromised evidence")
+	}
+
+	now := f.now()
+	entry := CustodyEntry{
+		Timestamp:    now,
+		FromOfficer:  fromOfficer,
+		ToOfficer:    toOfficer,
+		Action:       "TRANSFERRED",
+		Purpose:      purpose,
+		VerifiedHash: currentHash,
+	}
+	var err error
+	if entry.EntryHash, err = custodyEntryHash(entry); err != nil {
+		return err
+	}
+	evidence.ChainOfCustody = append(evidence.ChainOfCustody, entry)
+	markModified(evidence, now)
+
+	f.logAudit(fromOfficer, "TRANSFER_CUSTODY", evidenceID, fmt.Sprintf("Transferred to %s - %s", toOfficer, purpose))
+	return nil
+}
+
+func (f *FakeEvidenceManager) UpdateStatus(evidenceID, officerID string, newStatus EvidenceStatus, notes string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return ErrEvidenceNotFound
+	}
+	oldStatus := evidence.Status
+	evidence.Status = newStatus
+	evidence.Notes = notes
+	markModified(evidence, f.now())
+
+	f.logAudit(officerID, "UPDATE_STATUS", evidenceID, fmt.Sprintf("Status changed from %s to %s", oldStatus, newStatus))
+	return nil
+}
+
+func (f *FakeEvidenceManager) UpdateMetadata(evidenceID, userID string, revision int64, update MetadataUpdate) (*Evidence, error) {
+	if revision <= 0 {
+		return nil, errors.New("the revision being edited is required")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return nil, ErrEvidenceNotFound
+	}
+	if revision != evidence.Revision {
+		f.logAudit(userID, "REVISION_CONFLICT", evidenceID,
+			fmt.Sprintf("Change against revision %d refused; current revision is %d", revision, evidence.Revision))
+		return nil, &RevisionConflictError{EvidenceID: evidenceID, Expected: revision, Current: evidence.Revision}
+	}
+
+	var changed []string
+	if update.Location != nil && *update.Location != evidence.Location {
+		evidence.Location = *update.Location
+		changed = append(changed, "location")
+	}
+	if update.OfficerName != nil && *update.OfficerName != evidence.OfficerName {
+		evidence.OfficerName = *update.OfficerName
+		changed = append(changed, "officer name")
+	}
+	if update.Notes != nil && *update.Notes != evidence.Notes {
+		evidence.Notes = *update.Notes
+		changed = append(changed, "notes")
+	}
+	if len(changed) > 0 {
+		markModified(evidence, f.now())
+		f.logAudit(userID, "UPDATE_METADATA", evidenceID,
+			fmt.Sprintf("Updated %s at revision %d", strings.Join(changed, ", "), evidence.Revision))
+	}
+
+	c := copyEvidence(evidence)
+	return &c, nil
+}
+
+func (f *FakeEvidenceManager) VerifyIntegrity(evidenceID, checkedBy string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return false, ErrEvidenceNotFound
+	}
+
+	currentHash := fakeHash(f.contents[evidenceID])
+	check := IntegrityCheck{
+		Timestamp: f.now(),
+		CheckedBy: checkedBy,
+		HashValue: currentHash,
+		IsValid:   currentHash == evidence.FileHash,
+	}
+	status := "PASSED"
+	if !check.IsValid {
+		check.Notes = "ALERT: File hash mismatch detected - possible tampering"
+		status = "FAILED"
+	}
+	evidence.IntegrityChecks = append(evidence.IntegrityChecks, check)
+	markModified(evidence, check.Timestamp)
+
+	f.logAudit(checkedBy, "VERIFY_INTEGRITY", evidenceID, "Integrity check "+status)
+	return check.IsValid, nil
+}
+
+// ExportEvidence writes the evidence record as JSON to exportPath, as BWCSystem does
+func (f *FakeEvidenceManager) ExportEvidence(evidenceID, exportPath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	evidence, exists := f.evidence[evidenceID]
+	if !exists {
+		return ErrEvidenceNotFound
+	}
+	if err := validatePath("export path", exportPath); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(evidence, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal evidence: %w", err)
+	}
+	if err := os.WriteFile(exportPath, data, 0600); err != nil {
+		return err
+	}
+
+	f.logAudit("", "EXPORT_EVIDENCE", evidenceID, "Exported to "+exportPath)
+	return nil
+}
+
+func (f *FakeEvidenceManager) GetAuditLogs(evidenceID, userID string) []AuditLog {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	logs := make([]AuditLog, 0)
+	for _, log := range f.audit {
+		if evidenceID != "" && log.EvidenceID != evidenceID {
+			continue
+		}
+		if userID != "" && log.UserID != userID {
+			continue
+		}
+		logs = append(logs, log)
+	}
+	return logs
+}
@@ -0,0 +1,172 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testEvidenceManagerContract checks the behaviour integrations rely on.
+// corrupt damages the stored copy of evidence.
+func testEvidenceManagerContract(t *testing.T, m EvidenceManager, dir string, corrupt func(id string)) {
+	t.Helper()
+	file := createTestFile(t, dir)
+
+	ev, err := m.IngestEvidence(file, "CASE-EM-1", "OFF-1094", "Officer A", "Main St", []string{"traffic"})
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	other, err := m.IngestEvidence(file, "CASE-EM-2", "OFF-1095", "Officer B", "Elm St", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if ev.ID == other.ID || ev.Status != StatusCollected || ev.Revision != 1 || ev.FileHash == "" {
+		t.Fatalf("unexpected ingested evidence %+v", ev)
+	}
+
+	if _, err := m.GetEvidence("EVD-NOPE"); err == nil {
+		t.Error("expected an error for unknown evidence")
+	}
+	if results := m.SearchEvidence("CASE-EM-1", "", ""); len(results) != 1 || results[0].ID != ev.ID {
+		t.Errorf("expected one result for CASE-EM-1, got %d", len(results))
+	}
+
+	if err := m.TransferCustody(ev.ID, "OFF-1094", "LAB-1", "Analysis"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	chain, err := m.GetChainOfCustody(ev.ID)
+	if err != nil || len(chain) != 2 || chain[1].ToOfficer != "LAB-1" || chain[1].Action != "TRANSFERRED" {
+		t.Errorf("unexpected chain of custody %+v, %v", chain, err)
+	}
+
+	if err := m.UpdateStatus(ev.ID, "LAB-1", StatusAnalyzed, "done"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	current, _ := m.GetEvidence(ev.ID)
+	if current.Status != StatusAnalyzed {
+		t.Errorf("expected ANALYZED, got %s", current.Status)
+	}
+	revision := current.Revision
+
+	location := "Oak St"
+	if _, err := m.UpdateMetadata(ev.ID, "LAB-1", 1, MetadataUpdate{Location: &location}); err == nil {
+		t.Error("expected a conflict against a stale revision")
+	} else {
+		var conflict *RevisionConflictError
+		if !errors.As(err, &conflict) || conflict.Current != revision {
+			t.Errorf("expected a *RevisionConflictError at revision %d, got %v", revision, err)
+		}
+	}
+	updated, err := m.UpdateMetadata(ev.ID, "LAB-1", revision, MetadataUpdate{Location: &location})
+	if err != nil || updated.Location != "Oak St" || updated.Revision != revision+1 {
+		t.Errorf("unexpected metadata update %+v, %v", updated, err)
+	}
+
+	if valid, err := m.VerifyIntegrity(ev.ID, "AUDITOR"); err != nil || !valid {
+		t.Errorf("expected integrity to pass, got %v, %v", valid, err)
+	}
+	corrupt(other.ID)
+	if valid, err := m.VerifyIntegrity(other.ID, "AUDITOR"); err != nil || valid {
+		t.Errorf("expected integrity to fail, got %v, %v", valid, err)
+	}
+	if err := m.TransferCustody(other.ID, "OFF-1095", "LAB-1", "Analysis"); err == nil {
+		t.Error("expected corrupted evidence to be refused a transfer")
+	}
+
+	exportPath := filepath.Join(dir, "export.json")
+	if err := m.ExportEvidence(ev.ID, exportPath); err != nil {
+		t.Fatalf("ExportEvidence failed: %v", err)
+	}
+	if _, err := os.Stat(exportPath); err != nil {
+		t.Errorf("expected the export file: %v", err)
+	}
+
+	logs := m.GetAuditLogs(ev.ID, "LAB-1")
+	actions := make(map[string]bool)
+	for _, log := range logs {
+		actions[log.Action] = true
+	}
+	for _, want := range []string{"UPDATE_STATUS", "UPDATE_METADATA", "REVISION_CONFLICT"} {
+		if !actions[want] {
+			t.Errorf("expected %s in the audit log, got %+v", want, logs)
+		}
+	}
+}
+
+func TestBWCSystemSatisfiesEvidenceManagerContract(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testEvidenceManagerContract(t, system, tmpDir, func(id string) {
+		ev, _ := system.GetEvidence(id)
+		if err := os.WriteFile(ev.FilePath, []byte("tampered"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestFakeEvidenceManagerSatisfiesContract(t *testing.T) {
+	fake := NewFakeEvidenceManager()
+	testEvidenceManagerContract(t, fake, t.TempDir(), func(id string) {
+		if err := fake.Corrupt(id); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestFakeEvidenceManagerIsDeterministic(t *testing.T) {
+	file := createTestFile(t, t.TempDir())
+
+	run := func() []*Evidence {
+		fake := NewFakeEvidenceManager()
+		fake.IngestEvidence(file, "CASE-EM-3", "OFF-1096", "Officer C", "", nil)
+		fake.IngestEvidence(file, "CASE-EM-3", "OFF-1096", "Officer C", "", nil)
+		fake.TransferCustody("FAKE-000001", "OFF-1096", "LAB-1", "Analysis")
+		return fake.SearchEvidence("CASE-EM-3", "", "")
+	}
+	first, second := run(), run()
+
+	if len(first) != 2 || first[0].ID != "FAKE-000001" || first[1].ID != "FAKE-000002" {
+		t.Fatalf("expected sequential IDs in order, got %+v", first)
+	}
+	if !first[0].Timestamp.Equal(fakeEpoch) {
+		t.Errorf("expected the first record at the fake epoch, got %s", first[0].Timestamp)
+	}
+	for i := range first {
+		if !first[i].LastModified.Equal(second[i].LastModified) || first[i].Revision != second[i].Revision {
+			t.Errorf("expected identical runs, got %+v and %+v", first[i], second[i])
+		}
+	}
+}
+
+func TestFakeEvidenceManagerClockAndCopies(t *testing.T) {
+	fake := NewFakeEvidenceManager()
+	at := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake.SetNow(at)
+	fake.Step = time.Minute
+
+	ev, err := fake.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-EM-4", "OFF-1097", "Officer D", "", []string{"a"})
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if !ev.CreatedAt.Equal(at) {
+		t.Errorf("expected the record at %s, got %s", at, ev.CreatedAt)
+	}
+	// The ingest and its audit entry each took a step
+	if want := at.Add(2 * time.Minute); !fake.Now().Equal(want) {
+		t.Errorf("expected the clock at %s, got %s", want, fake.Now())
+	}
+
+	ev.Tags[0] = "changed"
+	ev.Status = StatusDeleted
+	stored, _ := fake.GetEvidence(ev.ID)
+	if stored.Tags[0] != "a" || stored.Status != StatusCollected {
+		t.Errorf("expected the fake's state to be unaffected by callers, got %+v", stored)
+	}
+
+	if err := fake.Corrupt("FAKE-999999"); err == nil {
+		t.Error("expected an error corrupting unknown evidence")
+	}
+}
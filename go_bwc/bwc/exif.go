@@ -0,0 +1,242 @@
+package bwc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+// exifData is the subset of EXIF metadata kept for photo evidence
+type exifData struct {
+	CaptureTime *time.Time
+	Make        string
+	Model       string
+	GPS         *GeoPoint
+}
+
+// EXIF tags read from a photo
+const (
+	exifTagMake             = 0x010F
+	exifTagModel            = 0x0110
+	exifTagDateTime         = 0x0132
+	exifTagExifIFD          = 0x8769
+	exifTagGPSIFD           = 0x8825
+	exifTagDateTimeOriginal = 0x9003
+	exifTagOffsetOriginal   = 0x9011
+	gpsTagLatitudeRef       = 1
+	gpsTagLatitude          = 2
+	gpsTagLongitudeRef      = 3
+	gpsTagLongitude         = 4
+)
+
+// maxExifSegment bounds the APP1 segment read from a JPEG
+const maxExifSegment = 64 * 1024
+
+// readJPEGExif finds the EXIF segment of a JPEG stream and parses it. It
+// returns nil without error when the photo carries no EXIF data.
+func readJPEGExif(r io.Reader) (*exifData, error) {
+	br := bufio.NewReader(r)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi != [2]byte{0xFF, 0xD8} {
+		return nil, errors.New("not a JPEG file")
+	}
+
+	for {
+		var marker [4]byte
+		if _, err := io.ReadFull(br, marker[:2]); err != nil {
+			return nil, nil
+		}
+		if marker[0] != 0xFF {
+			return nil, errors.New("invalid JPEG marker")
+		}
+		// Start of scan or end of image: no EXIF before the image data
+		if marker[1] == 0xDA || marker[1] == 0xD9 {
+			return nil, nil
+		}
+		if _, err := io.ReadFull(br, marker[2:]); err != nil {
+			return nil, nil
+		}
+		length := int(binary.BigEndian.Uint16(marker[2:])) - 2
+		if length < 0 {
+			return nil, errors.New("invalid JPEG segment length")
+		}
+		if marker[1] != 0xE1 || length > maxExifSegment {
+			if _, err := br.Discard(length); err != nil {
+				return nil, nil
+			}
+			continue
+		}
+
+		segment := make([]byte, length)
+		if _, err := io.ReadFull(br, segment); err != nil {
+			return nil, errors.New("truncated JPEG segment")
+		}
+		if bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return parseExif(segment[6:])
+		}
+	}
+}
+
+// tiffReader reads IFD entries from a TIFF structure held in memory
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// ifdEntry is a raw IFD entry with its value bytes resolved
+type ifdEntry struct {
+	typ   uint16
+	count uint32
+	value []byte
+}
+
+// parseExif parses the TIFF structure of an EXIF segment
+func parseExif(data []byte) (*exifData, error) {
+	if len(data) < 8 {
+		return nil, errors.New("invalid EXIF header")
+	}
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, errors.New("invalid EXIF byte order")
+	}
+	if t.order.Uint16(data[2:4]) != 42 {
+		return nil, errors.New("invalid EXIF header")
+	}
+
+	ifd0, err := t.readIFD(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return nil, err
+	}
+	exif := &exifData{Make: t.ascii(ifd0[exifTagMake]), Model: t.ascii(ifd0[exifTagModel])}
+
+	captured, offset := t.ascii(ifd0[exifTagDateTime]), ""
+	if sub, ok := t.pointer(ifd0[exifTagExifIFD]); ok {
+		if exifIFD, err := t.readIFD(sub); err == nil {
+			if original := t.ascii(exifIFD[exifTagDateTimeOriginal]); original != "" {
+				captured = original
+			}
+			offset = t.ascii(exifIFD[exifTagOffsetOriginal])
+		}
+	}
+	if captured != "" {
+		if ts, err := parseExifTime(captured, offset); err == nil {
+			exif.CaptureTime = &ts
+		}
+	}
+
+	if sub, ok := t.pointer(ifd0[exifTagGPSIFD]); ok {
+		if gps, err := t.readIFD(sub); err == nil {
+			lat, latOK := t.degrees(gps[gpsTagLatitude], t.ascii(gps[gpsTagLatitudeRef]), "S")
+			lon, lonOK := t.degrees(gps[gpsTagLongitude], t.ascii(gps[gpsTagLongitudeRef]), "W")
+			if latOK && lonOK {
+				exif.GPS = &GeoPoint{Latitude: lat, Longitude: lon}
+			}
+		}
+	}
+
+	return exif, nil
+}
+
+// readIFD reads the entries of the IFD at offset
+func (t *tiffReader) readIFD(offset uint32) (map[uint16]ifdEntry, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, errors.New("EXIF directory out of range")
+	}
+	n := int(t.order.Uint16(t.data[offset:]))
+	start := int(offset) + 2
+	if start+n*12 > len(t.data) {
+		return nil, errors.New("EXIF directory out of range")
+	}
+
+	entries := make(map[uint16]ifdEntry, n)
+	for i := 0; i < n; i++ {
+		raw := t.data[start+i*12 : start+i*12+12]
+		entry := ifdEntry{typ: t.order.Uint16(raw[2:4]), count: t.order.Uint32(raw[4:8])}
+		size := uint64(exifTypeSize(entry.typ)) * uint64(entry.count)
+		if size == 0 {
+			continue
+		}
+		if size <= 4 {
+			entry.value = raw[8 : 8+size]
+		} else {
+			at := uint64(t.order.Uint32(raw[8:12]))
+			if at+size > uint64(len(t.data)) {
+				continue
+			}
+			entry.value = t.data[at : at+size]
+		}
+		entries[t.order.Uint16(raw[0:2])] = entry
+	}
+	return entries, nil
+}
+
+// exifTypeSize is the size in bytes of one value of an EXIF type
+func exifTypeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7:
+		return 1
+	case 3, 8:
+		return 2
+	case 4, 9, 11:
+		return 4
+	case 5, 10, 12:
+		return 8
+	}
+	return 0
+}
+
+func (t *tiffReader) ascii(e ifdEntry) string {
+	if e.typ != 2 {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimRight(string(e.value), "\x00"))
+}
+
+func (t *tiffReader) pointer(e ifdEntry) (uint32, bool) {
+	if e.typ != 4 || len(e.value) != 4 {
+		return 0, false
+	}
+	return t.order.Uint32(e.value), true
+}
+
+// degrees converts a GPS degrees/minutes/seconds triple to decimal degrees,
+// negated when ref is the negative hemisphere
+func (t *tiffReader) degrees(e ifdEntry, ref, negative string) (float64, bool) {
+	if e.typ != 5 || len(e.value) != 24 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		num := t.order.Uint32(e.value[i*8:])
+		den := t.order.Uint32(e.value[i*8+4:])
+		if den == 0 {
+			return 0, false
+		}
+		parts[i] = float64(num) / float64(den)
+	}
+	v := parts[0] + parts[1]/60 + parts[2]/3600
+	if strings.EqualFold(ref, negative) {
+		v = -v
+	}
+	return v, true
+}
+
+// parseExifTime parses an EXIF timestamp. Without an offset tag the camera's
+// clock zone is unknown and local time is assumed.
+func parseExifTime(value, offset string) (time.Time, error) {
+	if offset != "" {
+		if ts, err := time.Parse("2006:01:02 15:04:05-07:00", value+offset); err == nil {
+			return ts, nil
+		}
+	}
+	return time.ParseInLocation("2006:01:02 15:04:05", value, time.Local)
+}
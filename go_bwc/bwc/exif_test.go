@@ -0,0 +1,137 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+	"time"
+)
+
+// tiffEntry is an IFD entry written by the test EXIF builder
+type tiffEntry struct {
+	tag, typ uint16
+	count    uint32
+	value    []byte
+}
+
+func asciiEntry(tag uint16, s string) tiffEntry {
+	return tiffEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+}
+
+func longEntry(tag uint16, v uint32) tiffEntry {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return tiffEntry{tag: tag, typ: 4, count: 1, value: b}
+}
+
+func rationalEntry(tag uint16, values ...[2]uint32) tiffEntry {
+	b := make([]byte, 0, len(values)*8)
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, v[0])
+		b = binary.LittleEndian.AppendUint32(b, v[1])
+	}
+	return tiffEntry{tag: tag, typ: 5, count: uint32(len(values)), value: b}
+}
+
+// tiffIFD encodes a little-endian IFD at offset, with its out-of-line values after it
+func tiffIFD(offset int, entries []tiffEntry) []byte {
+	dataStart := offset + 2 + len(entries)*12 + 4
+	var ifd, data bytes.Buffer
+	binary.Write(&ifd, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&ifd, binary.LittleEndian, e.tag)
+		binary.Write(&ifd, binary.LittleEndian, e.typ)
+		binary.Write(&ifd, binary.LittleEndian, e.count)
+		if len(e.value) <= 4 {
+			ifd.Write(append(e.value, make([]byte, 4-len(e.value))...))
+			continue
+		}
+		binary.Write(&ifd, binary.LittleEndian, uint32(dataStart+data.Len()))
+		data.Write(e.value)
+	}
+	binary.Write(&ifd, binary.LittleEndian, uint32(0))
+	return append(ifd.Bytes(), data.Bytes()...)
+}
+
+// testExif builds an EXIF TIFF block with camera, capture time and GPS
+// position (40°26'46"N 79°58'56"W)
+func testExif(captured, offset string) []byte {
+	ifd0 := func(exifAt, gpsAt uint32) []tiffEntry {
+		return []tiffEntry{
+			asciiEntry(exifTagMake, "Canon"),
+			asciiEntry(exifTagModel, "EOS R5"),
+			longEntry(exifTagExifIFD, exifAt),
+			longEntry(exifTagGPSIFD, gpsAt),
+		}
+	}
+	exifEntries := []tiffEntry{asciiEntry(exifTagDateTimeOriginal, captured)}
+	if offset != "" {
+		exifEntries = append(exifEntries, asciiEntry(exifTagOffsetOriginal, offset))
+	}
+	gpsEntries := []tiffEntry{
+		asciiEntry(gpsTagLatitudeRef, "N"),
+		rationalEntry(gpsTagLatitude, [2]uint32{40, 1}, [2]uint32{26, 1}, [2]uint32{46, 1}),
+		asciiEntry(gpsTagLongitudeRef, "W"),
+		rationalEntry(gpsTagLongitude, [2]uint32{79, 1}, [2]uint32{58, 1}, [2]uint32{56, 1}),
+	}
+
+	exifAt := 8 + len(tiffIFD(8, ifd0(0, 0)))
+	exifIFD := tiffIFD(exifAt, exifEntries)
+	gpsAt := exifAt + len(exifIFD)
+
+	tiff := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	tiff = append(tiff, tiffIFD(8, ifd0(uint32(exifAt), uint32(gpsAt)))...)
+	tiff = append(tiff, exifIFD...)
+	return append(tiff, tiffIFD(gpsAt, gpsEntries)...)
+}
+
+// testJPEG encodes a w x h gradient, with an EXIF segment when exif is not nil
+func testJPEG(w, h int, exif []byte) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 128, 255})
+		}
+	}
+	var encoded bytes.Buffer
+	jpeg.Encode(&encoded, img, nil)
+	if exif == nil {
+		return encoded.Bytes()
+	}
+
+	segment := append([]byte("Exif\x00\x00"), exif...)
+	out := []byte{0xFF, 0xD8, 0xFF, 0xE1}
+	out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+	out = append(out, segment...)
+	return append(out, encoded.Bytes()[2:]...)
+}
+
+func TestReadJPEGExif(t *testing.T) {
+	exif, err := readJPEGExif(bytes.NewReader(testJPEG(16, 16, testExif("2024:03:09 21:15:02", "-05:00"))))
+	if err != nil || exif == nil {
+		t.Fatalf("readJPEGExif failed: %v", err)
+	}
+	want := time.Date(2024, 3, 9, 21, 15, 2, 0, time.FixedZone("", -5*3600))
+	if exif.CaptureTime == nil || !exif.CaptureTime.Equal(want) {
+		t.Errorf("Expected capture time %s, got %v", want, exif.CaptureTime)
+	}
+	if exif.Make != "Canon" || exif.Model != "EOS R5" {
+		t.Errorf("Unexpected camera %q %q", exif.Make, exif.Model)
+	}
+	if exif.GPS == nil || exif.GPS.Latitude < 40.446 || exif.GPS.Latitude > 40.447 || exif.GPS.Longitude > -79.982 || exif.GPS.Longitude < -79.983 {
+		t.Errorf("Unexpected GPS position %+v", exif.GPS)
+	}
+
+	if exif, err := readJPEGExif(bytes.NewReader(testJPEG(16, 16, nil))); err != nil || exif != nil {
+		t.Errorf("Expected no EXIF data, got %+v, %v", exif, err)
+	}
+	if _, err := readJPEGExif(bytes.NewReader([]byte("GIF89a"))); err == nil {
+		t.Error("Expected a non-JPEG to be refused")
+	}
+	if _, err := parseExif([]byte("II*\x00\xff\xff\x00\x00")); err == nil {
+		t.Error("Expected an out-of-range directory to be refused")
+	}
+}
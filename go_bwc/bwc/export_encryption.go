@@ -0,0 +1,220 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// minExportPassphraseLength is the shortest passphrase accepted for an export.
+// The passphrase travels separately from the package, often by phone.
+const minExportPassphraseLength = 12
+
+// ageScryptWorkFactor is log2 of the scrypt cost for passphrase encryption,
+// age's default
+var ageScryptWorkFactor = 18
+
+// ExportEncryption protects an export sent to an external party by email or
+// courier. Exactly one method is set:
+//
+//   - Passphrase: age passphrase (scrypt) encryption, opened with `age -d`.
+//   - AgeRecipients: age public keys ("age1..."), opened with the matching identity.
+//   - PGPPublicKeys: OpenPGP certificates, armored or binary, opened with `gpg -d`.
+//
+// Several recipients of one kind may each open the export.
+type ExportEncryption struct {
+	Passphrase    string
+	AgeRecipients []string
+	PGPPublicKeys [][]byte
+}
+
+// methods counts the encryption methods set
+func (e *ExportEncryption) methods() int {
+	n := 0
+	if e.Passphrase != "" {
+		n++
+	}
+	if len(e.AgeRecipients) > 0 {
+		n++
+	}
+	if len(e.PGPPublicKeys) > 0 {
+		n++
+	}
+	return n
+}
+
+// Validate checks that exactly one method is set and that its keys parse
+func (e *ExportEncryption) Validate() error {
+	_, _, err := e.recipients()
+	return err
+}
+
+// recipients parses the configured keys
+func (e *ExportEncryption) recipients() ([]age.Recipient, []*openpgp.Entity, error) {
+	switch e.methods() {
+	case 0:
+		return nil, nil, errors.New("no export encryption method set")
+	case 1:
+	default:
+		return nil, nil, errors.New("only one export encryption method may be set")
+	}
+
+	switch {
+	case e.Passphrase != "":
+		if len(e.Passphrase) < minExportPassphraseLength {
+			return nil, nil, fmt.Errorf("export passphrase must be at least %d characters", minExportPassphraseLength)
+		}
+		r, err := age.NewScryptRecipient(e.Passphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+		r.SetWorkFactor(ageScryptWorkFactor)
+		return []age.Recipient{r}, nil, nil
+	case len(e.AgeRecipients) > 0:
+		recipients := make([]age.Recipient, 0, len(e.AgeRecipients))
+		for _, s := range e.AgeRecipients {
+			r, err := age.ParseX25519Recipient(strings.TrimSpace(s))
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid age recipient %q: %w", s, err)
+			}
+			recipients = append(recipients, r)
+		}
+		return recipients, nil, nil
+	default:
+		keys := make([]*openpgp.Entity, 0, len(e.PGPPublicKeys))
+		for _, data := range e.PGPPublicKeys {
+			key, err := readPGPPublicKey(data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid PGP public key: %w", err)
+			}
+			keys = append(keys, key)
+		}
+		return nil, keys, nil
+	}
+}
+
+// readPGPPublicKey reads one armored or binary OpenPGP certificate and checks
+// that it has a key messages can be encrypted to
+func readPGPPublicKey(data []byte) (*openpgp.Entity, error) {
+	var keys openpgp.EntityList
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("-----BEGIN")) {
+		keys, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keys, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) != 1 {
+		return nil, fmt.Errorf("expected one certificate, found %d", len(keys))
+	}
+	if _, ok := keys[0].EncryptionKey(time.Now()); !ok {
+		return nil, errors.New("certificate has no valid encryption key")
+	}
+	return keys[0], nil
+}
+
+// pgpFingerprint is the primary key fingerprint as GnuPG prints it
+func pgpFingerprint(key *openpgp.Entity) string {
+	return strings.ToUpper(hex.EncodeToString(key.PrimaryKey.Fingerprint))
+}
+
+// Describe summarises the method and recipients, never the passphrase
+func (e *ExportEncryption) Describe() string {
+	if e == nil || e.methods() == 0 {
+		return ""
+	}
+	switch {
+	case e.Passphrase != "":
+		return "age passphrase"
+	case len(e.AgeRecipients) > 0:
+		return "age to " + strings.Join(e.AgeRecipients, ", ")
+	}
+	_, keys, err := e.recipients()
+	if err != nil {
+		return "OpenPGP"
+	}
+	fingerprints := make([]string, 0, len(keys))
+	for _, key := range keys {
+		fingerprints = append(fingerprints, pgpFingerprint(key))
+	}
+	return "OpenPGP to " + strings.Join(fingerprints, ", ")
+}
+
+// newWriter returns a writer that encrypts to dst. filename is recorded
+// inside OpenPGP messages.
+func (e *ExportEncryption) newWriter(dst io.Writer, filename string) (io.WriteCloser, error) {
+	ageRecipients, pgpKeys, err := e.recipients()
+	if err != nil {
+		return nil, err
+	}
+	if len(pgpKeys) > 0 {
+		hints := &openpgp.FileHints{IsBinary: true, FileName: filename}
+		return openpgp.Encrypt(dst, pgpKeys, nil, hints, &packet.Config{DefaultCipher: packet.CipherAES256})
+	}
+	return age.Encrypt(dst, ageRecipients...)
+}
+
+// hashingWriter hashes and counts what passes through it
+type hashingWriter struct {
+	dst  io.Writer
+	hash hash.Hash
+	size int64
+}
+
+func (w *hashingWriter) Write(p []byte) (int, error) {
+	n, err := w.dst.Write(p)
+	w.hash.Write(p[:n])
+	w.size += int64(n)
+	return n, err
+}
+
+// writeExportFile creates path with mode 0600 and fills it with write,
+// encrypting when enc is set. It returns the SHA-256 and size of the file as
+// written, i.e. of the ciphertext when encrypted, so the registered copy
+// matches what is handed over. A partial file is removed on failure.
+func writeExportFile(path string, enc *ExportEncryption, write func(io.Writer) error) (string, int64, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	hw := &hashingWriter{dst: file, hash: sha256.New()}
+
+	err = func() error {
+		if enc == nil {
+			return write(hw)
+		}
+		w, err := enc.newWriter(hw, filepath.Base(path))
+		if err != nil {
+			return err
+		}
+		if err := write(w); err != nil {
+			return err
+		}
+		return w.Close()
+	}()
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", 0, err
+	}
+	return hex.EncodeToString(hw.hash.Sum(nil)), hw.size, nil
+}
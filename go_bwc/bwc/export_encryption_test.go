@@ -0,0 +1,238 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+func testAgeIdentity(t *testing.T) (*age.X25519Identity, string) {
+	t.Helper()
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("GenerateX25519Identity failed: %v", err)
+	}
+	return identity, identity.Recipient().String()
+}
+
+// ageDecrypt opens an age file with identity or, when identity is nil, passphrase
+func ageDecrypt(t *testing.T, data []byte, identity *age.X25519Identity, passphrase string) ([]byte, error) {
+	t.Helper()
+	var id age.Identity = identity
+	if identity == nil {
+		scrypt, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		id = scrypt
+	}
+	r, err := age.Decrypt(bytes.NewReader(data), id)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// testPGPKey generates an OpenPGP key and returns it with its public
+// certificate, armored or binary
+func testPGPKey(t *testing.T, algorithm packet.PublicKeyAlgorithm, armored bool) (*openpgp.Entity, []byte) {
+	t.Helper()
+	key, err := openpgp.NewEntity("Defense Counsel", "", "counsel@example.org", &packet.Config{Algorithm: algorithm})
+	if err != nil {
+		t.Fatalf("NewEntity failed: %v", err)
+	}
+	var buf bytes.Buffer
+	w := io.WriteCloser(nopWriteCloser{&buf})
+	if armored {
+		if w, err = armor.Encode(&buf, openpgp.PublicKeyType, nil); err != nil {
+			t.Fatalf("armor.Encode failed: %v", err)
+		}
+	}
+	if err := key.Serialize(w); err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	w.Close()
+	return key, buf.Bytes()
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// pgpDecrypt opens an OpenPGP message with key
+func pgpDecrypt(data []byte, key *openpgp.Entity) ([]byte, string, error) {
+	md, err := openpgp.ReadMessage(bytes.NewReader(data), openpgp.EntityList{key}, nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	plain, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, "", err
+	}
+	return plain, md.LiteralData.FileName, nil
+}
+
+// encryptWith encrypts plaintext with enc
+func encryptWith(t *testing.T, enc *ExportEncryption, plaintext []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := enc.newWriter(&buf, "EV-1.mp4")
+	if err != nil {
+		t.Fatalf("newWriter failed: %v", err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestExportEncryptionValidate(t *testing.T) {
+	_, recipient := testAgeIdentity(t)
+	pgpEntity, pgpKey := testPGPKey(t, packet.PubKeyAlgoEdDSA, true)
+
+	valid := []ExportEncryption{
+		{Passphrase: "correct horse battery"},
+		{AgeRecipients: []string{recipient}},
+		{PGPPublicKeys: [][]byte{pgpKey}},
+	}
+	for _, enc := range valid {
+		if err := enc.Validate(); err != nil {
+			t.Errorf("Validate(%s) failed: %v", enc.Describe(), err)
+		}
+	}
+
+	invalid := map[string]ExportEncryption{
+		"none":           {},
+		"two methods":    {Passphrase: "correct horse battery", AgeRecipients: []string{recipient}},
+		"short":          {Passphrase: "hunter2"},
+		"bad recipient":  {AgeRecipients: []string{"age1nope"}},
+		"bad checksum":   {AgeRecipients: []string{"age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8q"}},
+		"bad PGP key":    {PGPPublicKeys: [][]byte{[]byte("not a key")}},
+		"bad second key": {PGPPublicKeys: [][]byte{pgpKey, {0x99}}},
+	}
+	for name, enc := range invalid {
+		if err := enc.Validate(); err == nil {
+			t.Errorf("%s: expected Validate to fail", name)
+		}
+	}
+
+	if d := valid[0].Describe(); strings.Contains(d, "horse") {
+		t.Errorf("Describe leaked the passphrase: %q", d)
+	}
+	if d := valid[2].Describe(); d != "OpenPGP to "+pgpFingerprint(pgpEntity) || len(d) != len("OpenPGP to ")+40 {
+		t.Errorf("Unexpected description %q", d)
+	}
+}
+
+func TestExportEncryptionRoundTrip(t *testing.T) {
+	defer func(w int) { ageScryptWorkFactor = w }(ageScryptWorkFactor)
+	ageScryptWorkFactor = 10
+
+	plaintext := bytes.Repeat([]byte("body camera footage "), 10000)
+
+	// age, to two recipients
+	first, firstRecipient := testAgeIdentity(t)
+	second, secondRecipient := testAgeIdentity(t)
+	data := encryptWith(t, &ExportEncryption{AgeRecipients: []string{firstRecipient, secondRecipient}}, plaintext)
+	for _, identity := range []*age.X25519Identity{first, second} {
+		if got, err := ageDecrypt(t, data, identity, ""); err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("age round trip failed: %v", err)
+		}
+	}
+	stranger, _ := testAgeIdentity(t)
+	if _, err := ageDecrypt(t, data, stranger, ""); err == nil {
+		t.Error("Expected decryption with an unrelated key to fail")
+	}
+	data[len(data)-1] ^= 1
+	if _, err := ageDecrypt(t, data, first, ""); err == nil {
+		t.Error("Expected a tampered payload to fail authentication")
+	}
+
+	// age passphrase
+	data = encryptWith(t, &ExportEncryption{Passphrase: "correct horse battery"}, plaintext)
+	if got, err := ageDecrypt(t, data, nil, "correct horse battery"); err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Passphrase round trip failed: %v", err)
+	}
+	if _, err := ageDecrypt(t, data, nil, "wrong horse battery"); err == nil {
+		t.Error("Expected the wrong passphrase to fail")
+	}
+
+	// OpenPGP, to an armored RSA certificate and a binary ECC one
+	rsaKey, rsaCert := testPGPKey(t, packet.PubKeyAlgoRSA, true)
+	eccKey, eccCert := testPGPKey(t, packet.PubKeyAlgoEdDSA, false)
+	data = encryptWith(t, &ExportEncryption{PGPPublicKeys: [][]byte{rsaCert, eccCert}}, plaintext)
+	for _, key := range []*openpgp.Entity{rsaKey, eccKey} {
+		got, filename, err := pgpDecrypt(data, key)
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("OpenPGP round trip failed: %v", err)
+		}
+		if filename != "EV-1.mp4" {
+			t.Errorf("Unexpected literal file name %q", filename)
+		}
+	}
+}
+
+func TestExportEvidenceEncrypted(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-ENC-001", "OFF-601", "Officer Test", "Test Location", nil)
+	identity, recipient := testAgeIdentity(t)
+
+	exportPath := filepath.Join(tmpDir, "discovery.json.age")
+	enc := &ExportEncryption{AgeRecipients: []string{recipient}}
+	if err := system.ExportEvidenceEncrypted(evidence.ID, exportPath, "DA-CLERK-1", "Defense discovery", enc); err != nil {
+		t.Fatalf("ExportEvidenceEncrypted failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(exportPath)
+	plain, err := ageDecrypt(t, data, identity, "")
+	if err != nil {
+		t.Fatalf("Failed to decrypt export: %v", err)
+	}
+	var exported Evidence
+	if err := json.Unmarshal(plain, &exported); err != nil || exported.ID != evidence.ID {
+		t.Errorf("Decrypted export is not the evidence record: %v", err)
+	}
+
+	// The registered copy is the ciphertext that was handed over
+	sum := sha256.Sum256(data)
+	copies := system.CopiesOf(evidence.ID)
+	if len(copies) != 1 || copies[0].SHA256 != hex.EncodeToString(sum[:]) || copies[0].Size != int64(len(data)) {
+		t.Fatalf("Unexpected copy records: %+v", copies)
+	}
+	if copies[0].Encryption != "age to "+recipient {
+		t.Errorf("Unexpected encryption %q", copies[0].Encryption)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "DA-CLERK-1")
+	if len(logs) != 1 || !strings.Contains(logs[0].Details, "encrypted age to "+recipient) {
+		t.Errorf("Expected the encryption in the audit entry, got %v", logs)
+	}
+
+	badPath := filepath.Join(tmpDir, "bad.json")
+	if err := system.ExportEvidenceEncrypted(evidence.ID, badPath, "DA-CLERK-1", "", &ExportEncryption{Passphrase: "short"}); err == nil {
+		t.Error("Expected a short passphrase to be rejected")
+	}
+	if _, err := os.Stat(badPath); !os.IsNotExist(err) {
+		t.Error("Expected no file for a rejected export")
+	}
+	if len(system.CopiesOf(evidence.ID)) != 1 {
+		t.Error("Expected a rejected export not to be registered")
+	}
+}
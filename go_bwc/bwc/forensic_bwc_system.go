@@ -0,0 +1,1297 @@
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// EvidenceStatus represents the current state of evidence
+type EvidenceStatus string
+
+const (
+	StatusCollected  EvidenceStatus = "COLLECTED"
+	StatusProcessing EvidenceStatus = "PROCESSING"
+	StatusAnalyzed   EvidenceStatus = "ANALYZED"
+	StatusArchived   EvidenceStatus = "ARCHIVED"
+	StatusDeleted    EvidenceStatus = "DELETED"
+)
+
+// Evidence represents a body-worn camera video file
+type Evidence struct {
+	ID              string         `json:"id"`
+	CaseNumber      string         `json:"case_number"`
+	OfficerID       string         `json:"officer_id"`
+	OfficerName     string         `json:"officer_name"`
+	Timestamp       time.Time      `json:"timestamp"`
+	Duration        int            `json:"duration_seconds"`
+	MediaType       MediaType      `json:"media_type,omitempty"`
+	Video           *VideoInfo     `json:"video,omitempty"`
+	Audio           *AudioInfo     `json:"audio,omitempty"`
+	Photo           *PhotoInfo     `json:"photo,omitempty"`
+	Document        *DocumentInfo  `json:"document,omitempty"`
+	IncidentTime    *time.Time     `json:"incident_time,omitempty"`
+	Severity        CaseSeverity   `json:"severity,omitempty"`
+	CourtDate       *time.Time     `json:"court_date,omitempty"`
+	Location        string         `json:"location"`
+	Place           *Place         `json:"place,omitempty"`
+	FilePath        string         `json:"file_path"`
+	// BlobKey is the key of the file in the blob store; records that predate
+	// blob stores have only FilePath
+	BlobKey         string         `json:"blob_key,omitempty"`
+	FileHash        string         `json:"file_hash"`
+	// Digests holds the file's digests under integrity.hash_algorithms
+	// besides the SHA-256 in FileHash
+	Digests         map[HashAlgorithm]string `json:"digests,omitempty"`
+	FileSize        int64          `json:"file_size"`
+	ChunkManifest   *ChunkManifest `json:"chunk_manifest,omitempty"`
+	// Encryption is set when the stored file is encrypted at rest
+	Encryption      *AtRestEncryption `json:"encryption,omitempty"`
+	Parity          *ParityInfo    `json:"parity,omitempty"`
+	Replica         *ReplicaInfo   `json:"replica,omitempty"`
+	Processing      []ProcessingResult `json:"processing,omitempty"`
+	Status          EvidenceStatus `json:"status"`
+	Tags            []string       `json:"tags"`
+	Notes           string         `json:"notes"`
+	ChainOfCustody  []CustodyEntry `json:"chain_of_custody"`
+	CreatedAt       time.Time      `json:"created_at"`
+	LastModified    time.Time      `json:"last_modified"`
+	// Revision counts changes to the record, for detecting concurrent edits
+	Revision        int64          `json:"revision"`
+	IntegrityChecks []IntegrityCheck `json:"integrity_checks"`
+	Seal            *Seal          `json:"seal,omitempty"`
+	SealHistory     []SealEvent    `json:"seal_history,omitempty"`
+	// SealedChecks are integrity checks made while sealed, which the seal
+	// does not cover
+	SealedChecks    []IntegrityCheck `json:"sealed_checks,omitempty"`
+	// ACL restricts the evidence to the users it lists
+	ACL             *EvidenceACL   `json:"acl,omitempty"`
+	Reviews         []FootageReview `json:"reviews,omitempty"`
+	Forensics       *ForensicMetadata `json:"forensics,omitempty"`
+	Clock           *ClockCheck    `json:"clock,omitempty"`
+}
+
+// CustodyEntry represents a chain of custody record
+type CustodyEntry struct {
+	Timestamp    time.Time `json:"timestamp"`
+	FromOfficer  string    `json:"from_officer"`
+	ToOfficer    string    `json:"to_officer"`
+	Action       string    `json:"action"`
+	Purpose      string    `json:"purpose"`
+	VerifiedHash string    `json:"verified_hash"`
+	Signature    *CustodySignature `json:"signature,omitempty"`
+	// Authentication is who recorded the entry, when they were authenticated
+	Authentication *Authentication `json:"authentication,omitempty"`
+	EntryHash    string    `json:"entry_hash,omitempty"`
+}
+
+// IntegrityCheck represents a file integrity verification
+type IntegrityCheck struct {
+	Timestamp  time.Time `json:"timestamp"`
+	CheckedBy  string    `json:"checked_by"`
+	HashValue  string    `json:"hash_value"`
+	IsValid    bool      `json:"is_valid"`
+	Notes      string    `json:"notes"`
+	// CorruptRanges locates the damage when the file has a chunk manifest
+	CorruptRanges []ByteRange `json:"corrupt_ranges,omitempty"`
+	// TrustedTimestamp proves HashValue existed by the time a time-stamping
+	// authority put on it, when one is configured
+	TrustedTimestamp *HashTimestamp `json:"trusted_timestamp,omitempty"`
+	// Digests are the evidence's other digests as computed by the check,
+	// and MismatchedDigests those that no longer match
+	Digests           map[HashAlgorithm]string `json:"digests,omitempty"`
+	MismatchedDigests []HashAlgorithm          `json:"mismatched_digests,omitempty"`
+}
+
+// AuditLog represents system activity logging
+type AuditLog struct {
+	Timestamp  time.Time `json:"timestamp"`
+	UserID     string    `json:"user_id"`
+	Action     string    `json:"action"`
+	EvidenceID string    `json:"evidence_id"`
+	Details    string    `json:"details"`
+	IPAddress  string    `json:"ip_address"`
+	// Authentication is who the operation was authenticated as, when it ran
+	// for an authenticated principal
+	Authentication *Authentication `json:"authentication,omitempty"`
+}
+
+// BWCSystem is the main forensic body-worn camera management system
+type BWCSystem struct {
+	evidenceDB    EvidenceStore
+	auditLogs     []AuditLog
+	// auditStore persists audit entries when the evidence store can
+	auditStore    AuditStore
+	// blobs holds the evidence files, by default in storagePath
+	blobs         BlobStore
+	// wal logs each changed record before it is stored; nil when disabled
+	wal           *writeAheadLog
+	// replication journals changes for the standby; nil unless primary
+	replication   *replicationJournal
+	storagePath   string
+	mu            sync.RWMutex
+	auditMu       sync.Mutex
+	// ingestsMu guards ingests, which are read while an ingest holds mu
+	ingestsMu     sync.Mutex
+	ingests       map[string]*ingestTracker
+	ingestSeq     int
+	// stagedIngests are interrupted ingests waiting to be resumed
+	stagedIngests map[string]*StagedIngest
+	maintenance   *maintenanceState
+	config        *Config
+
+	custodyRequests   map[string]*CustodyRequest
+	custodyRequestSeq int
+
+	checkouts map[string]*Checkout
+
+	events *eventBus
+
+	sealer           *sealSigner
+	// atRest wraps the data keys of evidence encrypted at rest; nil when
+	// encryption at rest is off
+	atRest           KeyProvider
+	pseudonymKey     []byte
+	unsealRequests   map[string]*UnsealRequest
+	unsealRequestSeq int
+
+	deletionRequests   map[string]*DeletionRequest
+	deletionRequestSeq int
+
+	replicaRepairs   map[string]*ReplicaRepairRequest
+	replicaRepairSeq int
+
+	accessGrants   map[string]*AccessGrant
+	accessGrantSeq int
+
+	viewSessions   map[string]*ViewSession
+	viewSessionSeq int
+
+	copies  []*CopyRecord
+	copySeq int
+
+	transferReceipts   map[string]*TransferReceipt
+	transferReceiptSeq int
+
+	idempotencyKeys map[string]*idempotentIngest
+
+	accessAlerts    []*AccessAlert
+	accessAlertSeq  int
+	flaggedAccounts map[string]*FlaggedAccount
+
+	activityReviews map[string]*ActivityReview
+
+	pivRoots *x509.CertPool
+	// officerKeys are the enrolled officer signing keys, by key ID
+	officerKeys map[string]*OfficerKey
+
+	// usersMu guards users and their credentials, which are checked while
+	// other locks are held
+	usersMu   sync.RWMutex
+	users     map[string]*User
+	passwords map[string]*passwordRecord
+	apiKeys   map[string]*apiKeyRecord
+
+	// reportSigner signs reports and certificates with the agency's
+	// certificate; nil when none is configured
+	reportSigner *reportSigner
+
+	hooks   []hookRegistration
+	hookSeq int
+
+	geocoder Geocoder
+
+	// tsa timestamps the hashes integrity checks find; nil when no
+	// time-stamping authority is configured
+	tsa *timestampAuthority
+
+	textExtractor TextExtractor
+	textIndex     *textIndex
+
+	processors map[string]Processor
+	jobs       map[string]*ProcessingJob
+	jobSeq     int
+	jobSlots   chan struct{}
+
+	reviewIndex map[string]string
+	reviewSeq   int
+
+	auditSamples   map[string]*AuditSample
+	auditSampleSeq int
+
+	anchorSeq int
+}
+
+// NewBWCSystem creates a new forensic BWC system instance
+func NewBWCSystem(storagePath string) (*BWCSystem, error) {
+	if err := os.MkdirAll(storagePath, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = storagePath
+
+	sealer, err := generateSealSigner()
+	if err != nil {
+		return nil, err
+	}
+	pseudonymKey, err := generatePseudonymKey()
+	if err != nil {
+		return nil, err
+	}
+
+	bwc := &BWCSystem{
+		evidenceDB:  newMemoryStore(),
+		blobs:       newDiskBlobStore(storagePath),
+		auditLogs:   make([]AuditLog, 0),
+		storagePath: storagePath,
+		maintenance: newMaintenanceState(),
+		config:      cfg,
+
+		custodyRequests: make(map[string]*CustodyRequest),
+		checkouts:       make(map[string]*Checkout),
+		events:          newEventBus(),
+		sealer:          sealer,
+		pseudonymKey:    pseudonymKey,
+		unsealRequests:  make(map[string]*UnsealRequest),
+		deletionRequests: make(map[string]*DeletionRequest),
+		replicaRepairs:  make(map[string]*ReplicaRepairRequest),
+		accessGrants:    make(map[string]*AccessGrant),
+		viewSessions:    make(map[string]*ViewSession),
+		transferReceipts: make(map[string]*TransferReceipt),
+		textIndex:       newTextIndex(),
+		idempotencyKeys: make(map[string]*idempotentIngest),
+		flaggedAccounts: make(map[string]*FlaggedAccount),
+		activityReviews: make(map[string]*ActivityReview),
+		processors:      make(map[string]Processor),
+		jobs:            make(map[string]*ProcessingJob),
+		reviewIndex:     make(map[string]string),
+		auditSamples:    make(map[string]*AuditSample),
+		ingests:         make(map[string]*ingestTracker),
+		stagedIngests:   make(map[string]*StagedIngest),
+		officerKeys:     make(map[string]*OfficerKey),
+		users:           make(map[string]*User),
+		passwords:       make(map[string]*passwordRecord),
+		apiKeys:         make(map[string]*apiKeyRecord),
+	}
+
+	// Copies cut short by a crash are found for ResumeIngest
+	if err := bwc.loadStagedIngests(); err != nil {
+		return nil, err
+	}
+	if err := bwc.loadOfficerKeys(); err != nil {
+		return nil, err
+	}
+	if err := bwc.loadUsers(); err != nil {
+		return nil, err
+	}
+	if err := bwc.loadCredentials(); err != nil {
+		return nil, err
+	}
+	if err := bwc.loadDeletionRequests(); err != nil {
+		return nil, err
+	}
+	if err := bwc.loadAnchorSeq(); err != nil {
+		return nil, err
+	}
+	return bwc, nil
+}
+
+// IngestEvidence ingests a new body-worn camera video file into the system
+func (bwc *BWCSystem) IngestEvidence(filePath, caseNumber, officerID, officerName, location string, tags []string) (*Evidence, error) {
+	return bwc.IngestEvidenceContext(context.Background(), filePath, caseNumber, officerID, officerName, location, tags)
+}
+
+// IngestEvidenceContext ingests like IngestEvidence, stopping the hash or copy
+// when ctx is done. A copy stopped part way is kept for ResumeIngest.
+func (bwc *BWCSystem) IngestEvidenceContext(ctx context.Context, filePath, caseNumber, officerID, officerName, location string, tags []string) (*Evidence, error) {
+	return bwc.ingestEvidence(ctx, filePath, caseNumber, officerID, officerName, location, tags, time.Time{}, nil)
+}
+
+// IngestEvidenceWithProgress ingests like IngestEvidence, calling progress as
+// the file is hashed and copied to storage
+func (bwc *BWCSystem) IngestEvidenceWithProgress(filePath, caseNumber, officerID, officerName, location string, tags []string, progress IngestProgressFunc) (*Evidence, error) {
+	return bwc.ingestEvidence(context.Background(), filePath, caseNumber, officerID, officerName, location, tags, time.Time{}, progress)
+}
+
+// ingestEvidence ingests a file, checking photo capture times against
+// incidentTime when it is set. progress, when set, is told how far the hash
+// and copy have got. Reads of the file stop once ctx is done.
+func (bwc *BWCSystem) ingestEvidence(ctx context.Context, filePath, caseNumber, officerID, officerName, location string, tags []string, incidentTime time.Time, progress IngestProgressFunc) (*Evidence, error) {
+	actor, auth := actingUser(ctx, officerID)
+	if err := bwc.authorize(actor, PermIngest, "Ingest", ""); err != nil {
+		return nil, err
+	}
+	ids := bwc.config.Identifiers
+	for _, err := range []error{ValidateIngestPath(filePath), ids.CheckCaseNumber(caseNumber), ids.CheckOfficerID(officerID)} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opIngest); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	lookups := bwc.lookupIngest(filePath, location)
+	evidence, err := bwc.recordIngest(ctx, filePath, caseNumber, officerID, officerName, location, tags, incidentTime, auth, lookups, progress)
+	if err != nil {
+		return nil, err
+	}
+	return bwc.timestampIngest(evidence), nil
+}
+
+// recordIngest hashes the file, copies it into storage and records the
+// evidence, all under bwc.mu
+func (bwc *BWCSystem) recordIngest(ctx context.Context, filePath, caseNumber, officerID, officerName, location string, tags []string, incidentTime time.Time, auth *Authentication, lookups ingestLookups, progress IngestProgressFunc) (*Evidence, error) {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	// Map tags onto the managed vocabulary, if one is configured
+	tags, err := bwc.config.Tags.Normalize(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify file exists
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+	if !fileInfo.Mode().IsRegular() {
+		return nil, &ValidationError{Field: "ingest path", Value: filePath, Reason: "is not a regular file"}
+	}
+
+	// Long transfers are tracked so they can be watched while mu is held
+	tracker := bwc.trackIngest(ctx, filePath, caseNumber, officerID, fileInfo.Size(), progress)
+	defer bwc.untrackIngest(tracker)
+
+	// Calculate file hash for integrity, with chunk hashes and the other
+	// digests when configured, in one read
+	digests, err := newDigester(bwc.extraDigests())
+	if err != nil {
+		return nil, err
+	}
+	hash, chunks, err := hashFileChunksVia(filePath, bwc.evidenceChunkSize(), digests.via(tracker.reader(IngestHashing)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+
+	if err := bwc.preIngestHooksLocked(IngestRequest{
+		FilePath:    filePath,
+		CaseNumber:  caseNumber,
+		OfficerID:   officerID,
+		OfficerName: officerName,
+		Location:    location,
+		Tags:        tags,
+		FileHash:    hash,
+		FileSize:    fileInfo.Size(),
+	}); err != nil {
+		return nil, err
+	}
+
+	// Generate unique evidence ID
+	evidenceID := generateEvidenceID(caseNumber, officerID)
+
+	// Copy file to secure storage through a journaled staging copy, which
+	// can be resumed if the copy is interrupted
+	stage := &StagedIngest{
+		EvidenceID:    evidenceID,
+		SourcePath:    filePath,
+		CaseNumber:    caseNumber,
+		OfficerID:     officerID,
+		OfficerName:   officerName,
+		Location:      location,
+		Tags:          tags,
+		IncidentTime:  incidentTime,
+		FileHash:      hash,
+		FileSize:      fileInfo.Size(),
+		ChunkManifest: chunks,
+		Digests:       digests.sums(),
+		StartedAt:     time.Now(),
+		Authentication: auth,
+	}
+	if bwc.atRest != nil {
+		if stage.Encryption, err = bwc.newAtRestEncryption(evidenceID); err != nil {
+			return nil, err
+		}
+	}
+	destPath, err := bwc.stageIngestLocked(stage, tracker)
+	if err != nil {
+		return nil, err
+	}
+	return bwc.completeIngestLocked(stage, destPath, lookups)
+}
+
+// ingestLookups holds what ingest looks up before taking the lock: the
+// geocoded location and, for documents, their text
+type ingestLookups struct {
+	place      *Place
+	geocodeErr error
+	text       string
+	extractor  string
+	ocrErr     error
+}
+
+// lookupIngest resolves the location and reads document text for an ingest
+func (bwc *BWCSystem) lookupIngest(filePath, location string) ingestLookups {
+	var l ingestLookups
+
+	// Resolve the location before taking the lock; a geocoder that fails
+	// does not hold up ingest
+	l.place, l.geocodeErr = bwc.geocode(location)
+
+	// Documents are read for the full-text index before taking the lock too,
+	// as OCR of a long scan can be slow
+	if mediaTypeFor(filePath) == MediaDocument {
+		l.text, l.extractor, l.ocrErr = bwc.extractText(filePath)
+	}
+	return l
+}
+
+// timestampIngest has the TSA timestamp the hash an ingest recorded, once
+// bwc.mu is released, returning the evidence with the token when one came
+func (bwc *BWCSystem) timestampIngest(evidence *Evidence) *Evidence {
+	if stamped := bwc.timestampCheck(evidence.ID, evidence.IntegrityChecks[0]); stamped != nil {
+		return stamped
+	}
+	return evidence
+}
+
+// completeIngestLocked records the evidence for a staged ingest whose file has
+// been copied into storage at destPath
+func (bwc *BWCSystem) completeIngestLocked(stage *StagedIngest, destPath string, lookups ingestLookups) (*Evidence, error) {
+	evidenceID, filePath, hash := stage.EvidenceID, stage.SourcePath, stage.FileHash
+	caseNumber, officerID, officerName, location := stage.CaseNumber, stage.OfficerID, stage.OfficerName, stage.Location
+	tags, incidentTime := stage.Tags, stage.IncidentTime
+	place, geocodeErr := lookups.place, lookups.geocodeErr
+	text, extractor, ocrErr := lookups.text, lookups.extractor, lookups.ocrErr
+
+	// Metadata that cannot be read does not hold up ingest; the file is kept
+	// and the failure audited. Files in a blob store off local disk are
+	// probed from the source, which hashed the same.
+	probePath := destPath
+	if probePath == "" || stage.Encryption != nil {
+		probePath = filePath
+	}
+	mediaType := mediaTypeFor(filePath)
+	var video *VideoInfo
+	var audio *AudioInfo
+	var photo *PhotoInfo
+	var probeErr error
+	switch mediaType {
+	case MediaVideo:
+		video, probeErr = bwc.probeVideo(probePath)
+	case MediaAudio:
+		audio, probeErr = probeAudio(probePath)
+	case MediaPhoto:
+		photo, probeErr = bwc.probePhoto(evidenceID, probePath)
+	}
+
+	// Create evidence record
+	evidence := &Evidence{
+		ID:          evidenceID,
+		CaseNumber:  caseNumber,
+		OfficerID:   officerID,
+		OfficerName: officerName,
+		Timestamp:   time.Now(),
+		MediaType:   mediaType,
+		Video:       video,
+		Audio:       audio,
+		Photo:       photo,
+		Location:    location,
+		Place:       place,
+		FilePath:    destPath,
+		BlobKey:     stageBlobKey(stage),
+		FileHash:    hash,
+		Digests:     stage.Digests,
+		FileSize:    stage.FileSize,
+		ChunkManifest: stage.ChunkManifest,
+		Encryption:  stage.Encryption,
+		Status:      StatusCollected,
+		Tags:        tags,
+		ChainOfCustody: []CustodyEntry{
+			{
+				Timestamp:    time.Now(),
+				FromOfficer:  "SYSTEM",
+				ToOfficer:    officerID,
+				Action:       "INGESTED",
+				Purpose:      "Initial evidence collection",
+				VerifiedHash: hash,
+				Authentication: stage.Authentication,
+			},
+		},
+		CreatedAt:    time.Now(),
+		LastModified: time.Now(),
+		Revision:     1,
+		IntegrityChecks: []IntegrityCheck{
+			{
+				Timestamp:  time.Now(),
+				CheckedBy:  "SYSTEM",
+				HashValue:  hash,
+				IsValid:    true,
+				Notes:      "Initial integrity check",
+			},
+		},
+	}
+
+	if video != nil {
+		evidence.Duration = int(video.DurationSeconds + 0.5)
+	}
+	if audio != nil {
+		evidence.Duration = int(audio.DurationSeconds + 0.5)
+	}
+	if !incidentTime.IsZero() {
+		evidence.IncidentTime = &incidentTime
+	}
+	if photo != nil {
+		photo.TimeDiscrepancy = bwc.captureTimeDiscrepancy(photo, incidentTime)
+	}
+
+	if bwc.config.Integrity.Parity.Enabled {
+		if err := bwc.generateParityLocked(evidence); err != nil {
+			return nil, err
+		}
+	}
+
+	if mediaType == MediaDocument {
+		if extractor == "" || ocrErr != nil {
+			evidence.Document = &DocumentInfo{Format: documentFormat(filePath)}
+		} else if err := bwc.storeDocumentTextLocked(evidence, text, extractor); err != nil {
+			return nil, err
+		}
+	}
+
+	if ocrErr != nil {
+		extractor = ""
+	}
+	evidence.Forensics = bwc.forensicMetadataLocked(stage, video != nil && probeErr == nil && video.Codec != "", extractor)
+	evidence.Clock = bwc.checkClock(evidence, stage.StartedAt)
+
+	if err := bwc.saveLocked(evidence); err != nil {
+		return nil, err
+	}
+
+	// Log audit trail
+	bwc.logAuditAs(stage.Authentication, officerID, "INGEST_EVIDENCE", evidenceID, 
+		fmt.Sprintf("Evidence ingested from case %s", caseNumber), "")
+	if geocodeErr != nil {
+		bwc.logAudit("SYSTEM", "GEOCODE_FAILED", evidenceID, geocodeErr.Error(), "")
+	}
+	if probeErr != nil {
+		bwc.logAudit("SYSTEM", "MEDIA_PROBE_FAILED", evidenceID, probeErr.Error(), "")
+	}
+	if ocrErr != nil {
+		bwc.logAudit("SYSTEM", "OCR_FAILED", evidenceID, ocrErr.Error(), "")
+	}
+	if photo != nil && photo.TimeDiscrepancy != "" {
+		bwc.logAudit("SYSTEM", "PHOTO_TIME_DISCREPANCY", evidenceID, photo.TimeDiscrepancy, "")
+	}
+	if evidence.Clock != nil && !evidence.Clock.Plausible {
+		bwc.logAudit("SYSTEM", "CLOCK_DRIFT_DETECTED", evidenceID, evidence.Clock.Reason, "")
+	}
+
+	// A replica that cannot be reached does not hold up ingest; the failure is
+	// audited and ReplicateEvidence can be retried
+	if bwc.config.Storage.Replica != nil {
+		err := bwc.replicateLocked(evidence)
+		if err == nil {
+			if err = bwc.saveLocked(evidence); err != nil {
+				evidence.Replica = nil
+			}
+		}
+		if err != nil {
+			bwc.logAudit("SYSTEM", "REPLICATION_FAILED", evidenceID, err.Error(), "")
+		}
+	}
+
+	bwc.publishEvidenceChange(EventEvidenceIngested, evidence, officerID, EvidenceChange{ToOfficer: officerID})
+	bwc.postIngestHooksLocked(evidence)
+	bwc.queueIngestJobsLocked(evidence)
+
+	return evidence, nil
+}
+
+// VerifyIntegrity verifies the integrity of evidence by comparing file hash
+func (bwc *BWCSystem) VerifyIntegrity(evidenceID, checkedBy string) (bool, error) {
+	return bwc.VerifyIntegrityContext(context.Background(), evidenceID, checkedBy)
+}
+
+// VerifyIntegrityContext verifies like VerifyIntegrity, stopping the hash when
+// ctx is done. A check that is stopped is not recorded.
+func (bwc *BWCSystem) VerifyIntegrityContext(ctx context.Context, evidenceID, checkedBy string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	actor, auth := actingUser(ctx, checkedBy)
+	if err := bwc.authorize(actor, PermVerify, "Integrity check", evidenceID); err != nil {
+		return false, err
+	}
+	// The check is recorded as the principal's, whoever checkedBy names
+	checkedBy = actor
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return false, err
+	}
+	defer bwc.endOperation()
+
+	check, err := bwc.recordIntegrityCheck(ctx, evidenceID, checkedBy, auth)
+	if err != nil {
+		return false, err
+	}
+	// The TSA is asked once bwc.mu is released
+	bwc.timestampCheck(evidenceID, check)
+	return check.IsValid, nil
+}
+
+// recordIntegrityCheck hashes the evidence and records the check under bwc.mu
+func (bwc *BWCSystem) recordIntegrityCheck(ctx context.Context, evidenceID, checkedBy string, auth *Authentication) (IntegrityCheck, error) {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return IntegrityCheck{}, ErrEvidenceNotFound
+	}
+
+	// Calculate current file hash, and in the same read every other digest
+	// kept, locating any damage when chunk hashes exist
+	digests, err := newDigester(storedDigests(evidence))
+	if err != nil {
+		return IntegrityCheck{}, err
+	}
+	wrap := digests.via(readVia(ctx))
+	var currentHash string
+	var corrupt []ByteRange
+	switch {
+	case evidence.Encryption != nil:
+		// Segments that fail authentication are located even without chunk hashes
+		currentHash, corrupt, err = bwc.hashEncrypted(evidence, evidence.ChunkManifest, wrap)
+	case evidence.ChunkManifest != nil && evidence.FilePath != "":
+		currentHash, corrupt, err = evidence.ChunkManifest.corruptRangesVia(evidence.FilePath, evidence.FileSize, wrap)
+	default:
+		currentHash, err = bwc.hashEvidenceVia(evidence, wrap)
+	}
+	if err != nil {
+		return IntegrityCheck{}, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+	current := digests.sums()
+	mismatched := mismatchedDigests(evidence, current)
+
+	isValid := currentHash == evidence.FileHash && len(mismatched) == 0
+
+	// Record integrity check
+	check := IntegrityCheck{
+		Timestamp:  time.Now(),
+		CheckedBy:  checkedBy,
+		HashValue:  currentHash,
+		IsValid:    isValid,
+		Notes:      "",
+		Digests:    current,
+		MismatchedDigests: mismatched,
+	}
+
+	if !isValid {
+		check.Notes = "ALERT: File hash mismatch detected - possible tampering"
+		if currentHash == evidence.FileHash {
+			check.Notes = fmt.Sprintf("ALERT: %s digest mismatch detected - possible tampering", joinAlgorithms(mismatched))
+		}
+		if len(corrupt) > 0 {
+			check.CorruptRanges = corrupt
+			check.Notes += "; corrupted " + formatByteRanges(corrupt)
+		}
+	}
+
+	if evidence.Seal != nil {
+		// Sealed evidence is still checked, beside the sealed state so the
+		// seal and revision are left alone
+		evidence.SealedChecks = append(evidence.SealedChecks, check)
+	} else {
+		evidence.IntegrityChecks = append(evidence.IntegrityChecks, check)
+		markModified(evidence, time.Now())
+	}
+	if err := bwc.saveLocked(evidence); err != nil {
+		return IntegrityCheck{}, err
+	}
+
+	if !isValid {
+		bwc.publishIntegrityAlert(evidence, check)
+		bwc.integrityFailureHooksLocked(evidence, check)
+	}
+
+	// Log audit trail
+	status := "PASSED"
+	if !isValid {
+		status = "FAILED"
+	}
+	details := fmt.Sprintf("Integrity check %s", status)
+	if len(check.CorruptRanges) > 0 {
+		details += ": corrupted " + formatByteRanges(check.CorruptRanges)
+	}
+	bwc.logAuditAs(auth, checkedBy, "VERIFY_INTEGRITY", evidenceID, details, "")
+
+	// Queue a restore from the replica; it still needs a person to approve it
+	if !isValid && evidence.Replica != nil {
+		bwc.requestReplicaRepairLocked(evidence, "SYSTEM",
+			fmt.Sprintf("Integrity check by %s failed", checkedBy), currentHash)
+	}
+
+	return check, nil
+}
+
+// TransferCustody transfers evidence custody from one officer to another
+func (bwc *BWCSystem) TransferCustody(evidenceID, fromOfficer, toOfficer, purpose string) error {
+	return bwc.TransferCustodySigned(evidenceID, fromOfficer, toOfficer, purpose, nil)
+}
+
+// TransferCustodyContext transfers custody unless ctx is already done
+func (bwc *BWCSystem) TransferCustodyContext(ctx context.Context, evidenceID, fromOfficer, toOfficer, purpose string) error {
+	return bwc.transferCustody(ctx, evidenceID, fromOfficer, toOfficer, purpose, nil)
+}
+
+// TransferCustodySigned transfers custody with an electronic signature attached to the custody entry
+func (bwc *BWCSystem) TransferCustodySigned(evidenceID, fromOfficer, toOfficer, purpose string, sig *CustodySignature) error {
+	return bwc.TransferCustodySignedContext(context.Background(), evidenceID, fromOfficer, toOfficer, purpose, sig)
+}
+
+// TransferCustodySignedContext transfers custody with sig, if any, attached
+// to the custody entry, unless ctx is already done
+func (bwc *BWCSystem) TransferCustodySignedContext(ctx context.Context, evidenceID, fromOfficer, toOfficer, purpose string, sig *CustodySignature) error {
+	return bwc.transferCustody(ctx, evidenceID, fromOfficer, toOfficer, purpose, sig)
+}
+
+// transferCustody transfers custody for the principal ctx carries, if any,
+// unless ctx is already done
+func (bwc *BWCSystem) transferCustody(ctx context.Context, evidenceID, fromOfficer, toOfficer, purpose string, sig *CustodySignature) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	actor, auth := actingUser(ctx, fromOfficer)
+	if err := bwc.authorize(actor, PermTransfer, "Custody transfer", evidenceID); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	return bwc.transferCustodyLocked(evidenceID, fromOfficer, toOfficer, purpose, sig, auth)
+}
+
+// transferCustodyLocked performs a custody transfer, recording auth when it
+// is for an authenticated principal; the caller must hold bwc.mu
+func (bwc *BWCSystem) transferCustodyLocked(evidenceID, fromOfficer, toOfficer, purpose string, sig *CustodySignature, auth *Authentication) error {
+	if err := bwc.checkTransferOfficers(fromOfficer, toOfficer); err != nil {
+		return err
+	}
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, fromOfficer, "Custody transfer"); err != nil {
+		return err
+	}
+
+	if checkout, out := bwc.checkouts[evidenceID]; out {
+		return fmt.Errorf("evidence is checked out to %s and must be checked in first", checkout.CheckedOutTo)
+	}
+
+	if err := bwc.preTransferHooksLocked(evidence, fromOfficer, toOfficer, purpose); err != nil {
+		return err
+	}
+
+	if err := bwc.recordCustodyLocked(evidence, fromOfficer, toOfficer, "TRANSFERRED", purpose, sig, auth); err != nil {
+		return err
+	}
+
+	// Log audit trail
+	bwc.logAuditAs(auth, fromOfficer, "TRANSFER_CUSTODY", evidenceID,
+		fmt.Sprintf("Transferred to %s - %s", toOfficer, purpose), "")
+
+	bwc.publishEvidenceChange(EventCustodyTransferred, evidence, fromOfficer, EvidenceChange{
+		FromOfficer: fromOfficer,
+		ToOfficer:   toOfficer,
+		Purpose:     purpose,
+	})
+
+	return nil
+}
+
+// UpdateStatus updates the status of evidence
+func (bwc *BWCSystem) UpdateStatus(evidenceID, officerID string, newStatus EvidenceStatus, notes string) error {
+	return bwc.UpdateStatusIfRevision(evidenceID, officerID, newStatus, notes, 0)
+}
+
+// UpdateStatusIfRevision updates the status of evidence if it is still at
+// revision, returning a *RevisionConflictError otherwise
+func (bwc *BWCSystem) UpdateStatusIfRevision(evidenceID, officerID string, newStatus EvidenceStatus, notes string, revision int64) error {
+	return bwc.UpdateStatusContext(context.Background(), evidenceID, officerID, newStatus, notes, revision)
+}
+
+// UpdateStatusContext updates the status of evidence if it is still at
+// revision, or at any revision when revision is 0, unless ctx is already done
+func (bwc *BWCSystem) UpdateStatusContext(ctx context.Context, evidenceID, officerID string, newStatus EvidenceStatus, notes string, revision int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	actor, auth := actingUser(ctx, officerID)
+	if err := bwc.authorize(actor, statusPermission(newStatus), "Status update", evidenceID); err != nil {
+		return err
+	}
+	if newStatus == StatusDeleted {
+		if err := bwc.requireNoApproval(actor, "Deletion", evidenceID); err != nil {
+			return err
+		}
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+
+	if err := bwc.rejectIfSealedLocked(evidence, officerID, "Status update"); err != nil {
+		return err
+	}
+	if err := bwc.checkRevisionLocked(evidence, officerID, revision); err != nil {
+		return err
+	}
+
+	return bwc.updateStatusLocked(evidence, officerID, newStatus, notes, auth)
+}
+
+// updateStatusLocked changes the status of evidence along statusTransitions,
+// recording auth when it is for an authenticated principal; the caller must
+// hold bwc.mu
+func (bwc *BWCSystem) updateStatusLocked(evidence *Evidence, officerID string, newStatus EvidenceStatus, notes string, auth *Authentication) error {
+	if err := checkStatusTransition(evidence.Status, newStatus); err != nil {
+		return err
+	}
+	return bwc.setStatusLocked(evidence, officerID, newStatus, notes, auth)
+}
+
+// markDeletedLocked sets DELETED on evidence that is not already deleted,
+// from whatever status it is in, for retention purges and approved
+// deletions, recording auth when it is set. The caller must hold bwc.mu.
+func (bwc *BWCSystem) markDeletedLocked(evidence *Evidence, userID, notes string, auth *Authentication) error {
+	if evidence.Status == StatusDeleted {
+		return checkStatusTransition(evidence.Status, StatusDeleted)
+	}
+	return bwc.setStatusLocked(evidence, userID, StatusDeleted, notes, auth)
+}
+
+// setStatusLocked records a status change without checking it against
+// statusTransitions; the caller must hold bwc.mu
+func (bwc *BWCSystem) setStatusLocked(evidence *Evidence, officerID string, newStatus EvidenceStatus, notes string, auth *Authentication) error {
+	before := copyEvidence(evidence)
+	oldStatus := evidence.Status
+	evidence.Status = newStatus
+	evidence.Notes = notes
+	markModified(evidence, time.Now())
+	if err := bwc.saveLocked(evidence); err != nil {
+		*evidence = before
+		return err
+	}
+
+	// Log audit trail
+	bwc.logAuditAs(auth, officerID, "UPDATE_STATUS", evidence.ID,
+		fmt.Sprintf("Status changed from %s to %s", oldStatus, newStatus), "")
+
+	bwc.publishEvidenceChange(EventStatusChanged, evidence, officerID, EvidenceChange{PreviousStatus: oldStatus})
+	bwc.postStatusChangeHooksLocked(evidence, oldStatus, officerID, notes)
+	return nil
+}
+
+// recordCustodyLocked verifies file integrity and appends a custody entry, signed
+// when sig is not nil; the caller must hold bwc.mu
+func (bwc *BWCSystem) recordCustodyLocked(evidence *Evidence, fromOfficer, toOfficer, action, purpose string, sig *CustodySignature, auth *Authentication) error {
+	if sig == nil && bwc.config.ChainOfCustody.RequireSignature {
+		return errors.New("a signature is required on custody hand-offs")
+	}
+	if sig != nil {
+		signature := *sig
+		sig = &signature
+		payload := custodySigningPayload(evidence, fromOfficer, toOfficer, action, purpose)
+		if err := bwc.validateCustodySignature(sig, payload, fromOfficer, toOfficer); err != nil {
+			return err
+		}
+	}
+
+	// Verify integrity before transfer
+	currentHash, err := bwc.hashEvidence(evidence)
+	if err != nil {
+		return fmt.Errorf("failed to verify integrity during transfer: %w", err)
+	}
+
+	if currentHash != evidence.FileHash {
+		bwc.publishTransferIntegrityAlert(evidence, fromOfficer, currentHash)
+		return &BWCError{Code: CodeIntegrityFailure, Message: "integrity check failed - cannot transfer compromised evidence"}
+	}
+
+	entry := CustodyEntry{
+		Timestamp:    time.Now(),
+		FromOfficer:  fromOfficer,
+		ToOfficer:    toOfficer,
+		Action:       action,
+		Purpose:      purpose,
+		VerifiedHash: currentHash,
+		Signature:    sig,
+		Authentication: auth,
+	}
+	entry.EntryHash, err = custodyEntryHash(entry)
+	if err != nil {
+		return err
+	}
+
+	evidence.ChainOfCustody = append(evidence.ChainOfCustody, entry)
+	markModified(evidence, time.Now())
+
+	return bwc.saveLocked(evidence)
+}
+
+// SearchEvidence searches for evidence by various criteria
+func (bwc *BWCSystem) SearchEvidence(caseNumber, officerID string, status EvidenceStatus) []*Evidence {
+	results, _ := bwc.SearchEvidenceContext(systemContext(context.Background()), caseNumber, officerID, status)
+	return results
+}
+
+// SearchEvidenceContext searches like SearchEvidence, giving up with ctx's
+// error once it is done. Restricted evidence the principal ctx carries is not
+// on the access list of is left out, and audited. With audit.log_all_access
+// on, each record returned is audited as read.
+func (bwc *BWCSystem) SearchEvidenceContext(ctx context.Context, caseNumber, officerID string, status EvidenceStatus) ([]*Evidence, error) {
+	results, err := bwc.searchEvidence(ctx, caseNumber, officerID, status)
+	if err != nil {
+		return nil, err
+	}
+	bwc.auditReads(ctx, "SEARCH_EVIDENCE", results, searchCriteria(caseNumber, officerID, status))
+	return results, nil
+}
+
+// searchEvidence is SearchEvidenceContext without read auditing, for
+// searches the system makes on its own behalf
+func (bwc *BWCSystem) searchEvidence(ctx context.Context, caseNumber, officerID string, status EvidenceStatus) ([]*Evidence, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	reader := readerFromContext(ctx)
+	results := make([]*Evidence, 0)
+
+	for i, evidence := range bwc.evidenceDB.Search(nil) {
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		match := true
+
+		if caseNumber != "" && evidence.CaseNumber != caseNumber {
+			match = false
+		}
+		if officerID != "" && evidence.OfficerID != officerID {
+			match = false
+		}
+		if status != "" && evidence.Status != status {
+			match = false
+		}
+
+		if match && bwc.rejectIfRestrictedLocked(evidence, reader, "Search") != nil {
+			match = false
+		}
+
+		if match {
+			results = append(results, evidence)
+		}
+	}
+
+	return results, nil
+}
+
+// GetEvidence retrieves evidence by ID
+func (bwc *BWCSystem) GetEvidence(evidenceID string) (*Evidence, error) {
+	return bwc.GetEvidenceContext(systemContext(context.Background()), evidenceID)
+}
+
+// GetEvidenceContext retrieves evidence by ID for the principal ctx carries,
+// refusing restricted evidence whose access list does not name them
+func (bwc *BWCSystem) GetEvidenceContext(ctx context.Context, evidenceID string) (*Evidence, error) {
+	evidence, err := bwc.readableEvidence(ctx, evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	bwc.auditReads(ctx, "VIEW_EVIDENCE", []*Evidence{evidence}, "Evidence record viewed")
+	return evidence, nil
+}
+
+// readableEvidence is GetEvidenceContext without read auditing, for checking
+// that the principal ctx carries may see evidenceID at all
+func (bwc *BWCSystem) readableEvidence(ctx context.Context, evidenceID string) (*Evidence, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfRestrictedLocked(evidence, readerFromContext(ctx), "View evidence"); err != nil {
+		return nil, err
+	}
+
+	return evidence, nil
+}
+
+// GetChainOfCustody retrieves the complete chain of custody for evidence
+func (bwc *BWCSystem) GetChainOfCustody(evidenceID string) ([]CustodyEntry, error) {
+	return bwc.GetChainOfCustodyContext(systemContext(context.Background()), evidenceID)
+}
+
+// GetChainOfCustodyContext retrieves the chain of custody for the principal
+// ctx carries, refusing restricted evidence whose access list does not name
+// them, and auditing the read when audit.log_all_access is on
+func (bwc *BWCSystem) GetChainOfCustodyContext(ctx context.Context, evidenceID string) ([]CustodyEntry, error) {
+	evidence, err := bwc.readableEvidence(ctx, evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	bwc.mu.RLock()
+	custody := evidence.ChainOfCustody
+	bwc.mu.RUnlock()
+
+	bwc.auditReads(ctx, "VIEW_CUSTODY", []*Evidence{evidence}, "Chain of custody viewed")
+	return custody, nil
+}
+
+// ExportEvidence exports evidence record to JSON
+func (bwc *BWCSystem) ExportEvidence(evidenceID, exportPath string) error {
+	return bwc.ExportEvidenceFor(evidenceID, exportPath, "", "")
+}
+
+// ExportEvidenceFor exports evidence record to JSON and registers the copy as made by userID for purpose
+func (bwc *BWCSystem) ExportEvidenceFor(evidenceID, exportPath, userID, purpose string) error {
+	return bwc.ExportEvidenceEncrypted(evidenceID, exportPath, userID, purpose, nil)
+}
+
+// ExportEvidenceEncrypted exports evidence record to JSON, encrypted with enc when it is set
+func (bwc *BWCSystem) ExportEvidenceEncrypted(evidenceID, exportPath, userID, purpose string, enc *ExportEncryption) error {
+	if err := bwc.authorize(userID, PermExport, "Export", evidenceID); err != nil {
+		return err
+	}
+	if enc != nil {
+		if err := enc.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Export"); err != nil {
+		return err
+	}
+	if err := bwc.rejectIfRestrictedLocked(evidence, userID, "Export"); err != nil {
+		return err
+	}
+	if err := bwc.checkExportPathLocked(exportPath, evidenceID, userID); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(evidence, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal evidence: %w", err)
+	}
+
+	hash, size, err := writeExportFile(exportPath, enc, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	record := bwc.registerCopyLocked(evidenceID, CopyExport, userID, exportPath, purpose, hash, size)
+	record.Encryption = enc.Describe()
+	bwc.logAudit(userID, "EXPORT_EVIDENCE", evidenceID, copyDetails(record), "")
+
+	return nil
+}
+
+// GetAuditLogsContext retrieves audit logs for a specific evidence or user
+// if the principal ctx carries may review the audit trail
+func (bwc *BWCSystem) GetAuditLogsContext(ctx context.Context, evidenceID, userID string) ([]AuditLog, error) {
+	if err := bwc.authorize(readerFromContext(ctx), PermReviewAudit, "Read audit log", evidenceID); err != nil {
+		return nil, err
+	}
+	return bwc.GetAuditLogs(evidenceID, userID), nil
+}
+
+// GetAuditLogs retrieves audit logs for a specific evidence or user without
+// checking who asks; callers acting for a user use GetAuditLogsContext
+func (bwc *BWCSystem) GetAuditLogs(evidenceID, userID string) []AuditLog {
+	bwc.auditMu.Lock()
+	defer bwc.auditMu.Unlock()
+
+	logs := make([]AuditLog, 0)
+
+	for _, log := range bwc.auditLogs {
+		match := true
+
+		if evidenceID != "" && log.EvidenceID != evidenceID {
+			match = false
+		}
+		if userID != "" && log.UserID != userID {
+			match = false
+		}
+
+		if match {
+			logs = append(logs, log)
+		}
+	}
+
+	return logs
+}
+
+// logAudit logs system activity for audit trail
+func (bwc *BWCSystem) logAudit(userID, action, evidenceID, details, ipAddress string) {
+	bwc.logAuditAs(nil, userID, action, evidenceID, details, ipAddress)
+}
+
+// logAuditAs records an audit entry for an operation run for auth, or for an
+// unauthenticated caller when auth is nil
+func (bwc *BWCSystem) logAuditAs(auth *Authentication, userID, action, evidenceID, details, ipAddress string) {
+	bwc.auditMu.Lock()
+	defer bwc.auditMu.Unlock()
+
+	log := AuditLog{
+		Timestamp:      time.Now(),
+		UserID:         userID,
+		Action:         action,
+		EvidenceID:     evidenceID,
+		Details:        details,
+		IPAddress:      ipAddress,
+		Authentication: auth,
+	}
+
+	bwc.auditLogs = append(bwc.auditLogs, log)
+	bwc.journalAudit(log)
+	if bwc.auditStore != nil {
+		if err := bwc.auditStore.AppendAudit(log); err != nil {
+			// Kept in memory only; the entry it is about was not written either
+			bwc.auditLogs = append(bwc.auditLogs, AuditLog{
+				Timestamp:  time.Now(),
+				UserID:     "SYSTEM",
+				Action:     "AUDIT_WRITE_FAILED",
+				EvidenceID: evidenceID,
+				Details:    fmt.Sprintf("%s: %v", action, err),
+			})
+		}
+	}
+
+	bwc.events.publish(Event{
+		Type:       EventAudit,
+		Timestamp:  log.Timestamp,
+		EvidenceID: evidenceID,
+		UserID:     userID,
+		Audit:      &log,
+	})
+}
+
+// GenerateReport generates a comprehensive report for a case using the internal profile
+func (bwc *BWCSystem) GenerateReport(caseNumber string) (string, error) {
+	return bwc.GenerateProfiledReport(caseNumber, ReportProfileInternal)
+}
+
+// Utility functions
+
+func calculateFileHash(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	return copyFileVia(src, dst, nil)
+}
+
+// copyFileVia copies src to dst, reading through wrap when it is set
+func copyFileVia(src, dst string, wrap func(io.Reader) io.Reader) error {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	var r io.Reader = sourceFile
+	if wrap != nil {
+		r = wrap(sourceFile)
+	}
+	if _, err := io.Copy(destFile, r); err != nil {
+		return err
+	}
+
+	return destFile.Sync()
+}
+
+// evidenceIDClock hands out the timestamps in evidence IDs. Two ingests in the
+// same second would otherwise be given the same ID, so each takes the second
+// after the last one issued when the clock has not moved on.
+var evidenceIDClock struct {
+	sync.Mutex
+	last int64
+}
+
+func generateEvidenceID(caseNumber, officerID string) string {
+	evidenceIDClock.Lock()
+	timestamp := time.Now().Unix()
+	if timestamp <= evidenceIDClock.last {
+		timestamp = evidenceIDClock.last + 1
+	}
+	evidenceIDClock.last = timestamp
+	evidenceIDClock.Unlock()
+	return fmt.Sprintf("BWC-%s-%s-%d", caseNumber, officerID, timestamp)
+}
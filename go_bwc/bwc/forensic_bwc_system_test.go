@@ -1,6 +1,7 @@
-package main
+package bwc
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
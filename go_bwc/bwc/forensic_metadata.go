@@ -0,0 +1,176 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AcquisitionMethod is how evidence was acquired from its original media
+type AcquisitionMethod string
+
+const (
+	// AcquisitionDockUpload is an upload from a camera docking station
+	AcquisitionDockUpload AcquisitionMethod = "DOCK_UPLOAD"
+	// AcquisitionFileCopy is a logical copy of the recording file
+	AcquisitionFileCopy AcquisitionMethod = "FILE_COPY"
+	// AcquisitionForensicImage is a copy taken from a bit-for-bit image of the media
+	AcquisitionForensicImage AcquisitionMethod = "FORENSIC_IMAGE"
+	// AcquisitionNetworkTransfer is a transfer over the network, such as from a cloud service
+	AcquisitionNetworkTransfer AcquisitionMethod = "NETWORK_TRANSFER"
+)
+
+func (m AcquisitionMethod) valid() bool {
+	switch m {
+	case AcquisitionDockUpload, AcquisitionFileCopy, AcquisitionForensicImage, AcquisitionNetworkTransfer:
+		return true
+	}
+	return false
+}
+
+// ForensicMetadata documents how evidence was acquired and verified, as the
+// SWGDE and ASTM E2825 guidelines for digital evidence recommend. Ingest
+// records the method, the examiner, the original file name and the tools used;
+// the original media identifiers and any write blocker are added afterwards
+// with UpdateMetadata.
+type ForensicMetadata struct {
+	AcquisitionMethod AcquisitionMethod `json:"acquisition_method"`
+	AcquiredAt        time.Time         `json:"acquired_at"`
+	// Examiner is the user who acquired the evidence
+	Examiner         string         `json:"examiner"`
+	ExaminerName     string         `json:"examiner_name,omitempty"`
+	OriginalFileName string         `json:"original_file_name"`
+	OriginalMedia    *OriginalMedia `json:"original_media,omitempty"`
+	WriteBlocker     string         `json:"write_blocker,omitempty"`
+	HashAlgorithm    string         `json:"hash_algorithm"`
+	Tools            []ForensicTool `json:"tools"`
+}
+
+// OriginalMedia identifies the device and media the evidence came from
+type OriginalMedia struct {
+	DeviceMake   string `json:"device_make,omitempty"`
+	DeviceModel  string `json:"device_model,omitempty"`
+	DeviceSerial string `json:"device_serial,omitempty"`
+	MediaSerial  string `json:"media_serial,omitempty"`
+}
+
+// ForensicTool is a program, and its version, that acquired, hashed or read
+// the evidence
+type ForensicTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Purpose string `json:"purpose"`
+}
+
+// forensicMetadataLocked records the acquisition of a staged ingest. probed
+// is set when ffprobe, or the built-in MP4 reader, read the recording's
+// metadata and extractor names the document text extractor, if one read it.
+// The caller must hold bwc.mu.
+func (bwc *BWCSystem) forensicMetadataLocked(stage *StagedIngest, probed bool, extractor string) *ForensicMetadata {
+	system := bwc.config.System
+	meta := &ForensicMetadata{
+		AcquisitionMethod: AcquisitionFileCopy,
+		AcquiredAt:        stage.StartedAt,
+		Examiner:          stage.OfficerID,
+		ExaminerName:      stage.OfficerName,
+		OriginalFileName:  filepath.Base(stage.SourcePath),
+		HashAlgorithm:     bwc.config.Security.HashAlgorithm,
+		Tools: []ForensicTool{
+			{Name: system.Name, Version: system.Version, Purpose: "acquisition and verification"},
+			{Name: "Go crypto/sha256", Version: runtime.Version(), Purpose: "hashing"},
+		},
+	}
+	if path := bwc.config.VideoProcessing.FFprobePath; probed && path != "" {
+		meta.Tools = append(meta.Tools, ForensicTool{Name: "ffprobe", Version: toolVersion(path), Purpose: "metadata extraction"})
+	} else if probed {
+		meta.Tools = append(meta.Tools, ForensicTool{Name: system.Name + " MP4 reader", Version: system.Version, Purpose: "metadata extraction"})
+	}
+	if extractor != "" {
+		meta.Tools = append(meta.Tools, ForensicTool{Name: extractor, Purpose: "text extraction"})
+	}
+	return meta
+}
+
+// toolVersions caches the version each external tool reports
+var toolVersions = struct {
+	sync.Mutex
+	byPath map[string]string
+}{byPath: make(map[string]string)}
+
+// toolVersion returns the version an ffmpeg-style tool at path reports with
+// -version, or "unknown"
+func toolVersion(path string) string {
+	toolVersions.Lock()
+	defer toolVersions.Unlock()
+	if v, ok := toolVersions.byPath[path]; ok {
+		return v
+	}
+
+	version := "unknown"
+	ctx, cancel := context.WithTimeout(context.Background(), defaultProbeTimeout)
+	defer cancel()
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, "-version")
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: 4096}
+	if cmd.Run() == nil {
+		// "ffprobe version 6.1.1-3ubuntu5 Copyright (c) ..."
+		if fields := strings.Fields(stdout.String()); len(fields) > 2 && fields[1] == "version" {
+			version = fields[2]
+		}
+	}
+	toolVersions.byPath[path] = version
+	return version
+}
+
+// validateForensicUpdate checks the forensic fields of update
+func validateForensicUpdate(update MetadataUpdate) error {
+	if update.AcquisitionMethod != nil && !update.AcquisitionMethod.valid() {
+		return fmt.Errorf("unknown acquisition method %q", *update.AcquisitionMethod)
+	}
+	return nil
+}
+
+// applyForensicUpdate applies the forensic fields of update to evidence and
+// returns the names of those that changed
+func applyForensicUpdate(evidence *Evidence, update MetadataUpdate) []string {
+	meta := evidence.Forensics
+	if meta == nil {
+		meta = &ForensicMetadata{}
+	}
+	var changed []string
+	if update.AcquisitionMethod != nil && *update.AcquisitionMethod != meta.AcquisitionMethod {
+		meta.AcquisitionMethod = *update.AcquisitionMethod
+		changed = append(changed, "acquisition method")
+	}
+	if update.Examiner != nil && *update.Examiner != meta.Examiner {
+		meta.Examiner = *update.Examiner
+		changed = append(changed, "examiner")
+		// A new examiner without a name does not keep the previous one's
+		if update.ExaminerName == nil {
+			meta.ExaminerName = ""
+		}
+	}
+	if update.ExaminerName != nil && *update.ExaminerName != meta.ExaminerName {
+		meta.ExaminerName = *update.ExaminerName
+		changed = append(changed, "examiner name")
+	}
+	if update.OriginalMedia != nil && (meta.OriginalMedia == nil || *update.OriginalMedia != *meta.OriginalMedia) {
+		media := *update.OriginalMedia
+		meta.OriginalMedia = &media
+		changed = append(changed, "original media")
+	}
+	if update.WriteBlocker != nil && *update.WriteBlocker != meta.WriteBlocker {
+		meta.WriteBlocker = *update.WriteBlocker
+		changed = append(changed, "write blocker")
+	}
+	if len(changed) > 0 {
+		evidence.Forensics = meta
+	}
+	return changed
+}
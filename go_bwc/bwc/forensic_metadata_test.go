@@ -0,0 +1,120 @@
+package bwc
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestIngestRecordsForensicMetadata(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.config.VideoProcessing.ExtractMetadata = true
+	system.config.VideoProcessing.FFprobePath = writeProcessorScript(t, tmpDir, "ffprobe",
+		"if [ \"$1\" = -version ]; then echo 'ffprobe version 6.1.1-3ubuntu5 Copyright (c) 2007-2023'; exit 0; fi\n"+
+			"cat <<'JSON'\n"+ffprobeBodycam+"\nJSON\n")
+
+	source := createTestFile(t, t.TempDir())
+	ev, err := system.IngestEvidence(source, "CASE-FOR-1", "OFF-1131", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	f := ev.Forensics
+	if f == nil {
+		t.Fatal("expected forensic metadata")
+	}
+	if f.AcquisitionMethod != AcquisitionFileCopy || f.Examiner != "OFF-1131" || f.ExaminerName != "Officer Test" || f.HashAlgorithm != "SHA-256" {
+		t.Errorf("unexpected forensic metadata %+v", f)
+	}
+	if f.OriginalFileName != "test_video.mp4" || f.AcquiredAt.IsZero() || f.AcquiredAt.After(ev.CreatedAt) {
+		t.Errorf("unexpected acquisition details %+v", f)
+	}
+	want := []ForensicTool{
+		{Name: system.config.System.Name, Version: system.config.System.Version, Purpose: "acquisition and verification"},
+		{Name: "Go crypto/sha256", Version: runtime.Version(), Purpose: "hashing"},
+		{Name: "ffprobe", Version: "6.1.1-3ubuntu5", Purpose: "metadata extraction"},
+	}
+	if len(f.Tools) != len(want) {
+		t.Fatalf("expected %d tools, got %+v", len(want), f.Tools)
+	}
+	for i := range want {
+		if f.Tools[i] != want[i] {
+			t.Errorf("expected tool %+v, got %+v", want[i], f.Tools[i])
+		}
+	}
+}
+
+func TestUpdateForensicMetadata(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-FOR-2", "OFF-1132", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	bad := AcquisitionMethod("GUESSWORK")
+	if _, err := system.UpdateMetadata(ev.ID, "CUS-001", ev.Revision, MetadataUpdate{AcquisitionMethod: &bad}); err == nil {
+		t.Error("expected an unknown acquisition method to be rejected")
+	}
+
+	method, examiner, blocker := AcquisitionForensicImage, "TECH-7", "Tableau T8u"
+	media := &OriginalMedia{DeviceMake: "Axon", DeviceModel: "Body 4", DeviceSerial: "X6001234", MediaSerial: "SD-99"}
+	updated, err := system.UpdateMetadata(ev.ID, "CUS-001", ev.Revision, MetadataUpdate{
+		AcquisitionMethod: &method, Examiner: &examiner, OriginalMedia: media, WriteBlocker: &blocker,
+	})
+	if err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	f := updated.Forensics
+	if f.AcquisitionMethod != method || f.Examiner != "TECH-7" || *f.OriginalMedia != *media || f.WriteBlocker != blocker || len(f.Tools) != 2 {
+		t.Errorf("unexpected forensic metadata %+v", f)
+	}
+	media.DeviceSerial = "changed"
+	if f.OriginalMedia.DeviceSerial != "X6001234" {
+		t.Error("expected the original media to be copied")
+	}
+
+	logs := system.GetAuditLogs(ev.ID, "CUS-001")
+	if len(logs) != 1 || logs[0].Action != "UPDATE_METADATA" || !strings.Contains(logs[0].Details, "acquisition method, examiner, original media, write blocker") {
+		t.Errorf("expected the update to be audited, got %+v", logs)
+	}
+
+	report, _ := system.GenerateCaseReport("CASE-FOR-2", ReportOptions{Profile: ReportProfileInternal})
+	for _, want := range []string{
+		"Acquisition: Forensic image by TECH-7, ",
+		"Original Media: Axon Body 4, S/N X6001234, media S/N SD-99",
+		"Write Blocker: Tableau T8u",
+		"Tools: " + system.config.System.Name + " " + system.config.System.Version + ", Go crypto/sha256 " + runtime.Version(),
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected %q in the report:\n%s", want, report)
+		}
+	}
+	public, _ := system.GenerateCaseReport("CASE-FOR-2", ReportOptions{Profile: ReportProfilePublic})
+	if !strings.Contains(public, "Acquisition: Forensic image, ") || strings.Contains(public, "TECH-7") {
+		t.Errorf("expected the public report to leave out the examiner:\n%s", public)
+	}
+
+	data, err := system.ExportCaseNIEM("CASE-FOR-2", "CUS-001", "state repository")
+	if err != nil {
+		t.Fatalf("ExportCaseNIEM failed: %v", err)
+	}
+	elements := niemElements(t, data)
+	if got := elements["AcquisitionMethodText"]; len(got) != 1 || got[0] != "FORENSIC_IMAGE" {
+		t.Errorf("expected the acquisition in the NIEM exchange, got %v", got)
+	}
+	if !strings.Contains(string(data), "<nc:ItemSerialIdentification>\n") || !strings.Contains(string(data), "<bwc:ToolVersionText>"+runtime.Version()) {
+		t.Errorf("expected the original media and tools in the NIEM exchange:\n%s", data)
+	}
+
+	if _, err := system.SealEvidence(ev.ID, "Court order 2"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if _, err := system.UpdateMetadata(ev.ID, "CUS-001", updated.Revision, MetadataUpdate{Examiner: &examiner}); !errors.Is(err, errEvidenceSealed) {
+		t.Errorf("expected sealed evidence to be refused, got %v", err)
+	}
+}
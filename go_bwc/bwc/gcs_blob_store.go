@@ -0,0 +1,327 @@
+package bwc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// gcsScope is the OAuth scope tokens minted from a service account key ask for
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// gcsBlobStore keeps evidence files as objects in a Google Cloud Storage
+// bucket, under the destination's prefix, through the JSON API. With
+// ObjectLock set, every object is given a locked retention until its
+// retention period ends, or a temporary hold when it is retained
+// indefinitely. The bucket must have object retention enabled.
+type gcsBlobStore struct {
+	client *http.Client
+	dest   ReportDestination
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func newGCSBlobStore(dest ReportDestination) *gcsBlobStore {
+	return &gcsBlobStore{client: replicaHTTPClient, dest: dest}
+}
+
+// gcsServiceAccount is the part of a service account key file tokens are
+// obtained with
+type gcsServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// accessToken returns the configured token, or one obtained with the service
+// account key in CredentialsFile or GOOGLE_APPLICATION_CREDENTIALS and kept
+// until shortly before it expires
+func (s *gcsBlobStore) accessToken() (string, error) {
+	if s.dest.AccessToken != "" {
+		return s.dest.AccessToken, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+
+	path := s.dest.CredentialsFile
+	if path == "" {
+		path = os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	}
+	if path == "" {
+		return "", errors.New("no GCS access token or credentials file is configured")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var account gcsServiceAccount
+	if err := json.Unmarshal(data, &account); err != nil {
+		return "", fmt.Errorf("failed to parse GCS credentials %s: %w", path, err)
+	}
+	token, lifetime, err := exchangeGCSAssertion(s.client, account, time.Now())
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expires = token, time.Now().Add(lifetime-time.Minute)
+	return s.token, nil
+}
+
+// exchangeGCSAssertion signs a JWT for account and exchanges it for an
+// access token, returned with how long it lasts
+func exchangeGCSAssertion(client *http.Client, account gcsServiceAccount, now time.Time) (string, time.Duration, error) {
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", 0, errors.New("the GCS service account key holds no PEM private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to parse the GCS service account key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", 0, errors.New("the GCS service account key is not an RSA key")
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": gcsScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)},
+	}
+	resp, err := client.PostForm(account.TokenURI, form)
+	if err != nil {
+		return "", 0, fmt.Errorf("GCS token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", 0, fmt.Errorf("GCS token request returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", 0, fmt.Errorf("GCS token response has no access token: %v", err)
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// endpoint returns the JSON API root; a configured endpoint (e.g.
+// fake-gcs-server) replaces storage.googleapis.com
+func (s *gcsBlobStore) endpoint() string {
+	if s.dest.Endpoint != "" {
+		return strings.TrimRight(s.dest.Endpoint, "/")
+	}
+	return "https://storage.googleapis.com"
+}
+
+// objectURL returns the JSON API URL of the object key is stored as
+func (s *gcsBlobStore) objectURL(key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return s.endpoint() + "/storage/v1/b/" + url.PathEscape(s.dest.Bucket) + "/o/" + url.PathEscape(s.dest.Prefix+key), nil
+}
+
+// send sends an authorized request, returning the response, which the caller
+// must close. A missing object is an error wrapping os.ErrNotExist, and any
+// other status but 200 or 204 an error too.
+func (s *gcsBlobStore) send(req *http.Request, key string) (*http.Response, error) {
+	token, err := s.accessToken()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GCS %s of %s failed: %w", req.Method, key, err)
+	}
+	if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusNoContent {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("GCS object %s: %w", key, os.ErrNotExist)
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("GCS %s of %s returned %s: %s", req.Method, key, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// Put stores r under key. With ObjectLock it is put under a temporary hold,
+// as no retention period is known.
+func (s *gcsBlobStore) Put(key string, r io.Reader) (BlobInfo, error) {
+	return s.PutRetained(key, r, time.Time{})
+}
+
+// PutRetained stores r under key, retained until retainUntil when ObjectLock
+// is set, or under a temporary hold when retainUntil is zero. The upload
+// carries the object's MD5, which GCS checks the body against, so r is
+// spooled to a temporary file first.
+func (s *gcsBlobStore) PutRetained(key string, r io.Reader, retainUntil time.Time) (BlobInfo, error) {
+	if err := checkBlobKey(key); err != nil {
+		return BlobInfo{}, err
+	}
+	path, size, sum, err := spoolBlob(key, r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer os.Remove(path)
+	md5Sum, err := fileMD5(path)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+
+	metadata := map[string]interface{}{
+		"name":        s.dest.Prefix + key,
+		"contentType": "application/octet-stream",
+		"md5Hash":     md5Sum,
+		"metadata":    map[string]string{"sha256": sum},
+	}
+	if s.dest.ObjectLock {
+		if retainUntil.IsZero() {
+			metadata["temporaryHold"] = true
+		} else {
+			metadata["retention"] = map[string]string{"mode": "Locked", "retainUntilTime": retainUntil.UTC().Format(time.RFC3339)}
+		}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer file.Close()
+
+	// A multipart upload sends the metadata and the file in one request
+	const boundary = "bwc-evidence-upload"
+	head := "--" + boundary + "\r\nContent-Type: application/json; charset=UTF-8\r\n\r\n" + string(encoded) +
+		"\r\n--" + boundary + "\r\nContent-Type: application/octet-stream\r\n\r\n"
+	tail := "\r\n--" + boundary + "--\r\n"
+	target := s.endpoint() + "/upload/storage/v1/b/" + url.PathEscape(s.dest.Bucket) + "/o?uploadType=multipart"
+	req, err := http.NewRequest(http.MethodPost, target, io.MultiReader(strings.NewReader(head), file, strings.NewReader(tail)))
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	req.ContentLength = int64(len(head)) + size + int64(len(tail))
+	req.Header.Set("Content-Type", "multipart/related; boundary="+boundary)
+
+	resp, err := s.send(req, key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+	return BlobInfo{Key: key, Size: size, SHA256: sum, Modified: time.Now()}, nil
+}
+
+func (s *gcsBlobStore) Get(key string) (io.ReadCloser, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodGet, target+"?alt=media", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.send(req, key)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object, which GCS refuses while it is retained or held
+func (s *gcsBlobStore) Delete(key string) error {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodDelete, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.send(req, key)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *gcsBlobStore) Stat(key string) (BlobInfo, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp, err := s.send(req, key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer resp.Body.Close()
+	var object struct {
+		Size    string    `json:"size"`
+		Updated time.Time `json:"updated"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&object); err != nil {
+		return BlobInfo{}, fmt.Errorf("failed to decode GCS object %s: %w", key, err)
+	}
+	size, err := strconv.ParseInt(object.Size, 10, 64)
+	if err != nil {
+		return BlobInfo{}, fmt.Errorf("GCS object %s has size %q", key, object.Size)
+	}
+	return BlobInfo{Key: key, Size: size, Modified: object.Updated}, nil
+}
+
+// HashReader streams the object through SHA-256; nothing is written to disk
+func (s *gcsBlobStore) HashReader(key string) (string, error) {
+	body, err := s.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", fmt.Errorf("GCS download of %s failed: %w", key, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
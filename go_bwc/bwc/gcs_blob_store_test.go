@@ -0,0 +1,183 @@
+package bwc
+
+import (
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGCSBucket is an in-memory GCS bucket behind the JSON API, with an
+// OAuth token endpoint that accepts assertions signed by key
+type fakeGCSBucket struct {
+	mu       sync.Mutex
+	key      *rsa.PublicKey
+	token    string
+	grants   int
+	objects  map[string][]byte
+	metadata map[string]map[string]interface{}
+}
+
+func newFakeGCSBucket(key *rsa.PublicKey) (*fakeGCSBucket, *httptest.Server) {
+	b := &fakeGCSBucket{key: key, token: "gcs-token", objects: make(map[string][]byte), metadata: make(map[string]map[string]interface{})}
+	return b, httptest.NewServer(b)
+}
+
+func (b *fakeGCSBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		parts := strings.Split(r.FormValue("assertion"), ".")
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[len(parts)-1])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if len(parts) != 3 || rsa.VerifyPKCS1v15(b.key, crypto.SHA256, digest[:], signature) != nil {
+			http.Error(w, "invalid_grant", http.StatusBadRequest)
+			return
+		}
+		b.grants++
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": b.token, "expires_in": 3600})
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+b.token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/evidence/o") {
+		_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		reader := multipart.NewReader(r.Body, params["boundary"])
+		part, _ := reader.NextPart()
+		var meta map[string]interface{}
+		json.NewDecoder(part).Decode(&meta)
+		part, _ = reader.NextPart()
+		body, _ := io.ReadAll(part)
+		sum := md5.Sum(body)
+		if meta["md5Hash"] != base64.StdEncoding.EncodeToString(sum[:]) {
+			http.Error(w, "md5 mismatch", http.StatusBadRequest)
+			return
+		}
+		name := meta["name"].(string)
+		b.objects[name], b.metadata[name] = body, meta
+		json.NewEncoder(w).Encode(meta)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/evidence/o/")
+	body, ok := b.objects[name]
+	if !ok {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if r.URL.Query().Get("alt") == "media" {
+			w.Write(body)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"name": name, "size": strconv.Itoa(len(body)), "updated": time.Now().UTC().Format(time.RFC3339)})
+	case http.MethodDelete:
+		if meta := b.metadata[name]; meta["retention"] != nil || meta["temporaryHold"] == true {
+			http.Error(w, "object is under retention", http.StatusForbidden)
+			return
+		}
+		delete(b.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestGCSBlobStoreWithRetention(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, server := newFakeGCSBucket(&key.PublicKey)
+	defer server.Close()
+
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	credentials, _ := json.Marshal(gcsServiceAccount{
+		ClientEmail: "bwc@agency.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    server.URL + "/token",
+	})
+	credentialsFile := filepath.Join(t.TempDir(), "service-account.json")
+	os.WriteFile(credentialsFile, credentials, 0600)
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Storage.Blobs = &ReportDestination{Type: "gcs", Bucket: "evidence", Prefix: "media/", Endpoint: server.URL,
+		CredentialsFile: credentialsFile, ObjectLock: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-GCS-1", "OFF-1161", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	meta := bucket.metadata["media/"+ev.BlobKey]
+	retention, _ := meta["retention"].(map[string]interface{})
+	if ev.FilePath != "" || retention["mode"] != "Locked" {
+		t.Fatalf("expected the object stored with a locked retention, got %v", meta)
+	}
+	retainUntil, _ := time.Parse(time.RFC3339, retention["retainUntilTime"].(string))
+	if want := time.Now().AddDate(0, 0, cfg.Storage.RetentionDays); retainUntil.Sub(want) > time.Minute || want.Sub(retainUntil) > time.Minute {
+		t.Errorf("expected the retention to last the retention period, got %s", retainUntil)
+	}
+
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Fatalf("expected the streamed object to verify, got %v, %v", valid, err)
+	}
+	store := system.blobs.(*gcsBlobStore)
+	if info, err := store.Stat(ev.BlobKey); err != nil || info.Size != ev.FileSize {
+		t.Errorf("expected the object's size, got %+v, %v", info, err)
+	}
+	if err := store.Delete(ev.BlobKey); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a retained object not to be deleted, got %v", err)
+	}
+	if _, err := store.Stat("BWC-missing.mp4"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing object to wrap os.ErrNotExist, got %v", err)
+	}
+	if bucket.grants != 1 {
+		t.Errorf("expected one token obtained and reused, got %d", bucket.grants)
+	}
+}
+
+func TestGCSBlobStoreTemporaryHold(t *testing.T) {
+	bucket, server := newFakeGCSBucket(nil)
+	defer server.Close()
+	store := newGCSBlobStore(ReportDestination{Type: "gcs", Bucket: "evidence", Endpoint: server.URL, AccessToken: "gcs-token", ObjectLock: true})
+
+	info, err := store.Put("BWC-CASE-GCS-2-OFF-1162-1700000000.mp4", strings.NewReader("footage"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if meta := bucket.metadata[info.Key]; meta["temporaryHold"] != true {
+		t.Errorf("expected a temporary hold, got %v", meta)
+	}
+	if hash, err := store.HashReader(info.Key); err != nil || hash != info.SHA256 {
+		t.Errorf("expected the stored hash, got %s, %v", hash, err)
+	}
+}
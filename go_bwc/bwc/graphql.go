@@ -0,0 +1,1115 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A small GraphQL engine for the read-only query API. It covers what query
+// tools send: selection sets, arguments, variables, aliases, named and inline
+// fragments and the @skip and @include directives. Mutations, subscriptions
+// and introspection beyond __typename are not supported; the schema is
+// published as SDL instead.
+
+// gqlMaxDepth bounds how deeply a query may nest selections
+const gqlMaxDepth = 12
+
+// gqlError is one entry of a response's errors list
+type gqlError struct {
+	Message    string            `json:"message"`
+	Locations  []gqlLocation     `json:"locations,omitempty"`
+	Path       []interface{}     `json:"path,omitempty"`
+	Extensions map[string]string `json:"extensions,omitempty"`
+}
+
+func (e *gqlError) Error() string {
+	return e.Message
+}
+
+type gqlLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// gqlResponse is the body of a GraphQL response. Data is nil when the
+// request could not be run at all.
+type gqlResponse struct {
+	Data   *gqlResult  `json:"data,omitempty"`
+	Errors []*gqlError `json:"errors,omitempty"`
+}
+
+// gqlResult is a response object, which keeps the order of the query
+type gqlResult struct {
+	keys   []string
+	values []interface{}
+}
+
+func (r *gqlResult) set(key string, value interface{}) {
+	r.keys = append(r.keys, key)
+	r.values = append(r.values, value)
+}
+
+func (r *gqlResult) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		v, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// Lexing
+
+type gqlTokenKind int
+
+const (
+	gqlEOF gqlTokenKind = iota
+	gqlPunct
+	gqlName
+	gqlInt
+	gqlFloat
+	gqlString
+)
+
+type gqlToken struct {
+	kind  gqlTokenKind
+	value string
+	loc   gqlLocation
+}
+
+func (t gqlToken) String() string {
+	switch t.kind {
+	case gqlEOF:
+		return "end of query"
+	case gqlString:
+		return strconv.Quote(t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+func gqlSyntaxError(loc gqlLocation, format string, args ...interface{}) *gqlError {
+	return &gqlError{Message: "Syntax error: " + fmt.Sprintf(format, args...), Locations: []gqlLocation{loc}}
+}
+
+func gqlNameStart(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func gqlDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// gqlLex splits a query document into tokens
+func gqlLex(src string) ([]gqlToken, error) {
+	var tokens []gqlToken
+	line, lineStart := 1, 0
+	for i := 0; i < len(src); {
+		c := src[i]
+		loc := gqlLocation{Line: line, Column: i - lineStart + 1}
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			tokens = append(tokens, gqlToken{kind: gqlPunct, value: "...", loc: loc})
+			i += 3
+		case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+			tokens = append(tokens, gqlToken{kind: gqlPunct, value: string(c), loc: loc})
+			i++
+		case gqlNameStart(c):
+			start := i
+			for i < len(src) && (gqlNameStart(src[i]) || gqlDigit(src[i])) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{kind: gqlName, value: src[start:i], loc: loc})
+		case c == '-' || gqlDigit(c):
+			start, kind := i, gqlInt
+			if c == '-' {
+				i++
+			}
+			digits := func() bool {
+				from := i
+				for i < len(src) && gqlDigit(src[i]) {
+					i++
+				}
+				return i > from
+			}
+			ok := digits()
+			if ok && i < len(src) && src[i] == '.' {
+				i++
+				kind, ok = gqlFloat, digits()
+			}
+			if ok && i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				i++
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				kind, ok = gqlFloat, digits()
+			}
+			if !ok {
+				return nil, gqlSyntaxError(loc, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, gqlToken{kind: kind, value: src[start:i], loc: loc})
+		case strings.HasPrefix(src[i:], `"""`):
+			end := strings.Index(src[i+3:], `"""`)
+			if end < 0 {
+				return nil, gqlSyntaxError(loc, "unterminated string")
+			}
+			value := src[i+3 : i+3+end]
+			line += strings.Count(value, "\n")
+			if n := strings.LastIndexByte(value, '\n'); n >= 0 {
+				lineStart = i + 3 + n + 1
+			}
+			tokens = append(tokens, gqlToken{kind: gqlString, value: strings.TrimSpace(value), loc: loc})
+			i += 3 + end + 3
+		case c == '"':
+			var b strings.Builder
+			i++
+			for {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, gqlSyntaxError(loc, "unterminated string")
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				if src[i] != '\\' {
+					b.WriteByte(src[i])
+					i++
+					continue
+				}
+				if i+1 >= len(src) {
+					return nil, gqlSyntaxError(loc, "unterminated string")
+				}
+				switch esc := src[i+1]; esc {
+				case '"', '\\', '/':
+					b.WriteByte(esc)
+				case 'b':
+					b.WriteByte('\b')
+				case 'f':
+					b.WriteByte('\f')
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case 'u':
+					if i+6 > len(src) {
+						return nil, gqlSyntaxError(loc, "invalid unicode escape")
+					}
+					r, err := strconv.ParseUint(src[i+2:i+6], 16, 32)
+					if err != nil {
+						return nil, gqlSyntaxError(loc, "invalid unicode escape")
+					}
+					var buf [utf8.UTFMax]byte
+					b.Write(buf[:utf8.EncodeRune(buf[:], rune(r))])
+					i += 4
+				default:
+					return nil, gqlSyntaxError(loc, "invalid escape \\%c", esc)
+				}
+				i += 2
+			}
+			tokens = append(tokens, gqlToken{kind: gqlString, value: b.String(), loc: loc})
+		case strings.HasPrefix(src[i:], "\ufeff"):
+			i += 3
+		default:
+			return nil, gqlSyntaxError(loc, "unexpected character %q", c)
+		}
+	}
+	return append(tokens, gqlToken{kind: gqlEOF, loc: gqlLocation{Line: line, Column: len(src) - lineStart + 1}}), nil
+}
+
+// Parsing
+
+// Values in a query are string, int64, float64, bool, nil, gqlVariable,
+// gqlEnum, []interface{} or map[string]interface{}
+type gqlVariable string
+type gqlEnum string
+
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+type gqlOperation struct {
+	kind       string
+	name       string
+	vars       []gqlVarDef
+	selections []*gqlSelection
+	loc        gqlLocation
+}
+
+type gqlVarDef struct {
+	name       string
+	typ        string
+	def        interface{}
+	hasDefault bool
+}
+
+type gqlFragment struct {
+	typeCond   string
+	selections []*gqlSelection
+	loc        gqlLocation
+}
+
+// gqlSelection is a field, a fragment spread (spread is set) or an inline
+// fragment (inline is set)
+type gqlSelection struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	directives []gqlDirective
+	selections []*gqlSelection
+	spread     string
+	inline     bool
+	typeCond   string
+	loc        gqlLocation
+}
+
+func (s *gqlSelection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type gqlDirective struct {
+	name string
+	args map[string]interface{}
+	loc  gqlLocation
+}
+
+type gqlParser struct {
+	tokens []gqlToken
+	pos    int
+}
+
+func (p *gqlParser) peek() gqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *gqlParser) next() gqlToken {
+	t := p.tokens[p.pos]
+	if t.kind != gqlEOF {
+		p.pos++
+	}
+	return t
+}
+
+// peekPunct reports whether the next token is the punctuator s
+func (p *gqlParser) peekPunct(s string) bool {
+	t := p.peek()
+	return t.kind == gqlPunct && t.value == s
+}
+
+// skip consumes the punctuator s if it is next
+func (p *gqlParser) skip(s string) bool {
+	if p.peekPunct(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *gqlParser) expect(s string) error {
+	if t := p.next(); t.kind != gqlPunct || t.value != s {
+		return gqlSyntaxError(t.loc, "expected %q, found %s", s, t)
+	}
+	return nil
+}
+
+func (p *gqlParser) name() (gqlToken, error) {
+	t := p.next()
+	if t.kind != gqlName {
+		return t, gqlSyntaxError(t.loc, "expected a name, found %s", t)
+	}
+	return t, nil
+}
+
+func gqlParse(src string) (*gqlDocument, error) {
+	tokens, err := gqlLex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &gqlParser{tokens: tokens}
+	doc := &gqlDocument{fragments: make(map[string]*gqlFragment)}
+	for p.peek().kind != gqlEOF {
+		t := p.peek()
+		switch {
+		case t.kind == gqlPunct && t.value == "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &gqlOperation{kind: "query", selections: selections, loc: t.loc})
+		case t.kind == gqlName && (t.value == "query" || t.value == "mutation" || t.value == "subscription"):
+			op, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case t.kind == gqlName && t.value == "fragment":
+			p.next()
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if name.value == "on" {
+				return nil, gqlSyntaxError(name.loc, "a fragment cannot be named \"on\"")
+			}
+			if _, dup := doc.fragments[name.value]; dup {
+				return nil, &gqlError{Message: fmt.Sprintf("There can be only one fragment named %q", name.value), Locations: []gqlLocation{name.loc}}
+			}
+			if on, err := p.name(); err != nil || on.value != "on" {
+				return nil, gqlSyntaxError(on.loc, "expected \"on\", found %s", on)
+			}
+			typeCond, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name.value] = &gqlFragment{typeCond: typeCond.value, selections: selections, loc: name.loc}
+		default:
+			return nil, gqlSyntaxError(t.loc, "unexpected %s", t)
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, &gqlError{Message: "the document has no operation"}
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	kind := p.next()
+	op := &gqlOperation{kind: kind.value, loc: kind.loc}
+	if p.peek().kind == gqlName {
+		op.name = p.next().value
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			def := gqlVarDef{name: name.value}
+			if def.typ, err = p.typeRef(); err != nil {
+				return nil, err
+			}
+			if p.skip("=") {
+				if def.def, err = p.value(true); err != nil {
+					return nil, err
+				}
+				def.hasDefault = true
+			}
+			op.vars = append(op.vars, def)
+		}
+	}
+	if p.peekPunct("@") {
+		return nil, gqlSyntaxError(p.peek().loc, "directives on operations are not supported")
+	}
+	var err error
+	op.selections, err = p.selectionSet()
+	return op, err
+}
+
+// typeRef reads a type such as [String!]! back as text
+func (p *gqlParser) typeRef() (string, error) {
+	var typ string
+	if p.skip("[") {
+		inner, err := p.typeRef()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect("]"); err != nil {
+			return "", err
+		}
+		typ = "[" + inner + "]"
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return "", err
+		}
+		typ = name.value
+	}
+	if p.skip("!") {
+		typ += "!"
+	}
+	return typ, nil
+}
+
+func (p *gqlParser) selectionSet() ([]*gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*gqlSelection
+	for !p.skip("}") {
+		sel, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, gqlSyntaxError(p.tokens[p.pos-1].loc, "a selection set cannot be empty")
+	}
+	return selections, nil
+}
+
+func (p *gqlParser) selection() (*gqlSelection, error) {
+	start := p.peek()
+	sel := &gqlSelection{loc: start.loc}
+	var err error
+	if p.skip("...") {
+		if t := p.peek(); t.kind == gqlName && t.value != "on" {
+			sel.spread = p.next().value
+			sel.directives, err = p.directives()
+			return sel, err
+		}
+		sel.inline = true
+		if t := p.peek(); t.kind == gqlName && t.value == "on" {
+			p.next()
+			typeCond, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			sel.typeCond = typeCond.value
+		}
+		if sel.directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		sel.selections, err = p.selectionSet()
+		return sel, err
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	sel.name = name.value
+	if p.skip(":") {
+		if name, err = p.name(); err != nil {
+			return nil, err
+		}
+		sel.alias, sel.name = sel.name, name.value
+	}
+	if sel.args, err = p.arguments(); err != nil {
+		return nil, err
+	}
+	if sel.directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		sel.selections, err = p.selectionSet()
+	}
+	return sel, err
+}
+
+func (p *gqlParser) arguments() (map[string]interface{}, error) {
+	if !p.skip("(") {
+		return nil, nil
+	}
+	args := make(map[string]interface{})
+	for !p.skip(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := args[name.value]; dup {
+			return nil, &gqlError{Message: fmt.Sprintf("There can be only one argument named %q", name.value), Locations: []gqlLocation{name.loc}}
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if args[name.value], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+func (p *gqlParser) directives() ([]gqlDirective, error) {
+	var directives []gqlDirective
+	for p.peekPunct("@") {
+		at := p.next()
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.arguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, gqlDirective{name: name.value, args: args, loc: at.loc})
+	}
+	return directives, nil
+}
+
+// value reads a value; constant values, such as variable defaults, may not
+// refer to variables
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	t := p.next()
+	switch t.kind {
+	case gqlInt:
+		n, err := strconv.ParseInt(t.value, 10, 64)
+		if err != nil {
+			return nil, gqlSyntaxError(t.loc, "integer %s out of range", t.value)
+		}
+		return n, nil
+	case gqlFloat:
+		f, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, gqlSyntaxError(t.loc, "invalid number %s", t.value)
+		}
+		return f, nil
+	case gqlString:
+		return t.value, nil
+	case gqlName:
+		switch t.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return gqlEnum(t.value), nil
+	case gqlPunct:
+		switch t.value {
+		case "$":
+			if constant {
+				return nil, gqlSyntaxError(t.loc, "variables are not allowed here")
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			return gqlVariable(name.value), nil
+		case "[":
+			list := []interface{}{}
+			for !p.skip("]") {
+				v, err := p.value(constant)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			return list, nil
+		case "{":
+			obj := map[string]interface{}{}
+			for !p.skip("}") {
+				name, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect(":"); err != nil {
+					return nil, err
+				}
+				if obj[name.value], err = p.value(constant); err != nil {
+					return nil, err
+				}
+			}
+			return obj, nil
+		}
+	}
+	return nil, gqlSyntaxError(t.loc, "expected a value, found %s", t)
+}
+
+// Schema
+
+// gqlRequest is the caller a query runs for
+type gqlRequest struct {
+	ctx    context.Context
+	system *BWCSystem
+	userID string
+}
+
+// gqlResolver returns the value of a field of source, given its coerced
+// arguments. Arguments that were not given, or given as null, are absent.
+type gqlResolver func(req *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// gqlField is a field of an object type. Types are written as in SDL, such
+// as [Evidence!]!; a field whose named type is an object has subfields.
+type gqlField struct {
+	typ     string
+	doc     string
+	args    []gqlArgDef
+	resolve gqlResolver
+}
+
+type gqlArgDef struct {
+	name string
+	typ  string
+}
+
+type gqlSchema struct {
+	// objects holds the object types; Query is the root
+	objects map[string]map[string]*gqlField
+	enums   map[string][]string
+	// scalars are the custom scalars, serialized as JSON
+	scalars []string
+}
+
+// gqlNamedType strips the list and non-null wrappers from a type
+func gqlNamedType(typ string) string {
+	return strings.Trim(typ, "[]!")
+}
+
+// execute runs a query document. A document that cannot be run at all gets a
+// response without data, and the second result is false.
+func (s *gqlSchema) execute(req *gqlRequest, query, operationName string, variables map[string]interface{}) (*gqlResponse, bool) {
+	fail := func(err error) (*gqlResponse, bool) {
+		gerr, ok := err.(*gqlError)
+		if !ok {
+			gerr = &gqlError{Message: err.Error()}
+		}
+		return &gqlResponse{Errors: []*gqlError{gerr}}, false
+	}
+
+	doc, err := gqlParse(query)
+	if err != nil {
+		return fail(err)
+	}
+
+	var op *gqlOperation
+	for _, candidate := range doc.operations {
+		if operationName == "" && len(doc.operations) > 1 {
+			return fail(&gqlError{Message: "operationName is required when the document has several operations"})
+		}
+		if operationName == "" || candidate.name == operationName {
+			op = candidate
+			break
+		}
+	}
+	if op == nil {
+		return fail(&gqlError{Message: fmt.Sprintf("Unknown operation named %q", operationName)})
+	}
+	if op.kind != "query" {
+		return fail(&gqlError{Message: "only queries are supported; make changes through the REST API", Locations: []gqlLocation{op.loc}})
+	}
+
+	e := &gqlExecutor{schema: s, doc: doc, req: req, vars: make(map[string]interface{}), defined: make(map[string]string)}
+	for _, def := range op.vars {
+		if _, dup := e.defined[def.name]; dup {
+			return fail(&gqlError{Message: fmt.Sprintf("There can be only one variable named \"$%s\"", def.name), Locations: []gqlLocation{op.loc}})
+		}
+		e.defined[def.name] = def.typ
+		value, given := variables[def.name]
+		if !given && def.hasDefault {
+			value, given = def.def, true
+		}
+		if value == nil && strings.HasSuffix(def.typ, "!") {
+			return fail(&gqlError{Message: fmt.Sprintf("Variable \"$%s\" of required type %q was not provided", def.name, def.typ)})
+		}
+		if given {
+			e.vars[def.name] = value
+		}
+	}
+
+	if err := e.validate("Query", op.selections, 1, nil); err != nil {
+		return fail(err)
+	}
+	data := e.executeFields("Query", op.selections, nil, nil)
+	return &gqlResponse{Data: data, Errors: e.errors}, true
+}
+
+type gqlExecutor struct {
+	schema *gqlSchema
+	doc    *gqlDocument
+	req    *gqlRequest
+	vars   map[string]interface{}
+	// defined maps the operation's variables to their types
+	defined map[string]string
+	errors  []*gqlError
+}
+
+func (e *gqlExecutor) validationError(loc gqlLocation, format string, args ...interface{}) *gqlError {
+	return &gqlError{Message: fmt.Sprintf(format, args...), Locations: []gqlLocation{loc}}
+}
+
+// validate checks selections against the type they select from before any
+// resolver runs, so a query fails as a whole rather than field by field
+func (e *gqlExecutor) validate(typeName string, selections []*gqlSelection, depth int, fragments []string) error {
+	if depth > gqlMaxDepth {
+		return e.validationError(selections[0].loc, "the query nests deeper than %d levels", gqlMaxDepth)
+	}
+	fields := e.schema.objects[typeName]
+	for _, sel := range selections {
+		for _, d := range sel.directives {
+			if d.name != "skip" && d.name != "include" {
+				return e.validationError(d.loc, "Unknown directive \"@%s\"", d.name)
+			}
+			if err := e.checkArgs(d.loc, "@"+d.name, []gqlArgDef{{name: "if", typ: "Boolean!"}}, d.args); err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case sel.spread != "":
+			frag, ok := e.doc.fragments[sel.spread]
+			if !ok {
+				return e.validationError(sel.loc, "Unknown fragment %q", sel.spread)
+			}
+			for _, seen := range fragments {
+				if seen == sel.spread {
+					return e.validationError(sel.loc, "Cannot spread fragment %q within itself", sel.spread)
+				}
+			}
+			if frag.typeCond != typeName {
+				return e.validationError(sel.loc, "Fragment %q on %q cannot be spread on %q", sel.spread, frag.typeCond, typeName)
+			}
+			if err := e.validate(typeName, frag.selections, depth, append(fragments, sel.spread)); err != nil {
+				return err
+			}
+		case sel.inline:
+			if sel.typeCond != "" && sel.typeCond != typeName {
+				return e.validationError(sel.loc, "Fragment on %q cannot be spread on %q", sel.typeCond, typeName)
+			}
+			if err := e.validate(typeName, sel.selections, depth, fragments); err != nil {
+				return err
+			}
+		case sel.name == "__typename":
+			if len(sel.args) > 0 || sel.selections != nil {
+				return e.validationError(sel.loc, "__typename takes no arguments or subfields")
+			}
+		default:
+			field, ok := fields[sel.name]
+			if !ok {
+				return e.validationError(sel.loc, "Cannot query field %q on type %q", sel.name, typeName)
+			}
+			if err := e.checkArgs(sel.loc, sel.name, field.args, sel.args); err != nil {
+				return err
+			}
+			named := gqlNamedType(field.typ)
+			if _, object := e.schema.objects[named]; !object {
+				if sel.selections != nil {
+					return e.validationError(sel.loc, "Field %q of type %q has no subfields", sel.name, field.typ)
+				}
+				continue
+			}
+			if sel.selections == nil {
+				return e.validationError(sel.loc, "Field %q of type %q must have a selection of subfields", sel.name, field.typ)
+			}
+			if err := e.validate(named, sel.selections, depth+1, fragments); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkArgs checks that given names only known arguments, supplies the
+// required ones, has literals of the right type and uses only defined
+// variables
+func (e *gqlExecutor) checkArgs(loc gqlLocation, name string, defs []gqlArgDef, given map[string]interface{}) error {
+	for arg, value := range given {
+		var def *gqlArgDef
+		for i := range defs {
+			if defs[i].name == arg {
+				def = &defs[i]
+			}
+		}
+		if def == nil {
+			return e.validationError(loc, "Unknown argument %q on %q", arg, name)
+		}
+		if err := e.checkVariables(loc, value); err != nil {
+			return err
+		}
+		// Literals can be checked now; variables are checked as they are used
+		if _, variable := value.(gqlVariable); !variable {
+			if _, err := e.coerce(def.typ, value); err != nil {
+				return e.validationError(loc, "Argument %q on %q %v", arg, name, err)
+			}
+		}
+	}
+	for _, def := range defs {
+		if value, ok := given[def.name]; strings.HasSuffix(def.typ, "!") && (!ok || value == nil) {
+			return e.validationError(loc, "Argument %q of type %q is required on %q", def.name, def.typ, name)
+		}
+	}
+	return nil
+}
+
+func (e *gqlExecutor) checkVariables(loc gqlLocation, value interface{}) error {
+	switch v := value.(type) {
+	case gqlVariable:
+		if _, ok := e.defined[string(v)]; !ok {
+			return e.validationError(loc, "Variable \"$%s\" is not defined", v)
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := e.checkVariables(loc, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := e.checkVariables(loc, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// included applies the @skip and @include directives
+func (e *gqlExecutor) included(directives []gqlDirective) bool {
+	for _, d := range directives {
+		cond, _ := e.coerce("Boolean!", d.args["if"])
+		if (d.name == "skip") == (cond == true) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectFields flattens fragments and groups the fields of a selection by
+// the key they appear under in the response, in query order
+func (e *gqlExecutor) collectFields(selections []*gqlSelection, keys *[]string, groups map[string][]*gqlSelection) {
+	for _, sel := range selections {
+		if !e.included(sel.directives) {
+			continue
+		}
+		switch {
+		case sel.spread != "":
+			e.collectFields(e.doc.fragments[sel.spread].selections, keys, groups)
+		case sel.inline:
+			e.collectFields(sel.selections, keys, groups)
+		default:
+			key := sel.key()
+			if _, seen := groups[key]; !seen {
+				*keys = append(*keys, key)
+			}
+			groups[key] = append(groups[key], sel)
+		}
+	}
+}
+
+func (e *gqlExecutor) executeFields(typeName string, selections []*gqlSelection, source interface{}, path []interface{}) *gqlResult {
+	var keys []string
+	groups := make(map[string][]*gqlSelection)
+	e.collectFields(selections, &keys, groups)
+
+	result := &gqlResult{}
+	for _, key := range keys {
+		group := groups[key]
+		sel := group[0]
+		if sel.name == "__typename" {
+			result.set(key, typeName)
+			continue
+		}
+		fieldPath := append(append([]interface{}{}, path...), key)
+		field := e.schema.objects[typeName][sel.name]
+		args, err := e.coerceArgs(field.args, sel.args)
+		var value interface{}
+		if err == nil {
+			value, err = field.resolve(e.req, source, args)
+		}
+		if err != nil {
+			e.fieldError(err, sel.loc, fieldPath)
+			result.set(key, nil)
+			continue
+		}
+		var sub []*gqlSelection
+		for _, s := range group {
+			sub = append(sub, s.selections...)
+		}
+		result.set(key, e.complete(field.typ, sub, value, fieldPath))
+	}
+	return result
+}
+
+func (e *gqlExecutor) fieldError(err error, loc gqlLocation, path []interface{}) {
+	gerr := &gqlError{Message: err.Error(), Locations: []gqlLocation{loc}, Path: path}
+	if code := ErrorCodeOf(err); code != "" {
+		gerr.Extensions = map[string]string{"code": string(code)}
+	}
+	e.errors = append(e.errors, gerr)
+}
+
+// complete turns a resolved value into response data of type typ
+func (e *gqlExecutor) complete(typ string, selections []*gqlSelection, value interface{}, path []interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(value)
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Slice) && rv.IsNil() && !strings.HasPrefix(typ, "[") {
+		return nil
+	}
+	typ = strings.TrimSuffix(typ, "!")
+	if strings.HasPrefix(typ, "[") {
+		inner := typ[1 : len(typ)-1]
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = e.complete(inner, selections, rv.Index(i).Interface(), append(append([]interface{}{}, path...), i))
+		}
+		return list
+	}
+	if _, object := e.schema.objects[typ]; object {
+		return e.executeFields(typ, selections, value, path)
+	}
+	return value
+}
+
+func (e *gqlExecutor) coerceArgs(defs []gqlArgDef, given map[string]interface{}) (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	for _, def := range defs {
+		value, err := e.coerce(def.typ, given[def.name])
+		if err != nil {
+			return nil, &ValidationError{Field: def.name, Value: fmt.Sprint(given[def.name]), Reason: err.Error()}
+		}
+		if value != nil {
+			args[def.name] = value
+		}
+	}
+	return args, nil
+}
+
+// coerce converts an argument value, substituting variables, to the Go
+// value for typ: string for String, ID, enums and custom scalars, int for
+// Int and bool for Boolean
+func (e *gqlExecutor) coerce(typ string, value interface{}) (interface{}, error) {
+	if v, ok := value.(gqlVariable); ok {
+		value = e.vars[string(v)]
+	}
+	base := strings.TrimSuffix(typ, "!")
+	if value == nil {
+		if base != typ {
+			return nil, fmt.Errorf("must not be null")
+		}
+		return nil, nil
+	}
+	switch base {
+	case "String", "Time":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int64:
+			return strconv.FormatInt(v, 10), nil
+		}
+	case "Int":
+		switch v := value.(type) {
+		case int64:
+			return int(v), nil
+		case float64:
+			// Variables arrive as JSON numbers
+			if v == float64(int(v)) {
+				return int(v), nil
+			}
+		}
+	case "Boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	default:
+		values, ok := e.schema.enums[base]
+		if !ok {
+			return nil, fmt.Errorf("has unsupported type %s", typ)
+		}
+		var name string
+		switch v := value.(type) {
+		case gqlEnum:
+			name = string(v)
+		case string:
+			name = v
+		}
+		for _, allowed := range values {
+			if name == allowed {
+				return name, nil
+			}
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+	return nil, fmt.Errorf("must be %s", strings.ToLower(base))
+}
+
+// sdl describes the schema in the GraphQL schema definition language
+func (s *gqlSchema) sdl() string {
+	var b strings.Builder
+	for _, scalar := range s.scalars {
+		fmt.Fprintf(&b, "scalar %s\n", scalar)
+	}
+
+	enums := make([]string, 0, len(s.enums))
+	for name := range s.enums {
+		enums = append(enums, name)
+	}
+	sort.Strings(enums)
+	for _, name := range enums {
+		fmt.Fprintf(&b, "\nenum %s {\n", name)
+		for _, value := range s.enums[name] {
+			fmt.Fprintf(&b, "  %s\n", value)
+		}
+		b.WriteString("}\n")
+	}
+
+	types := make([]string, 0, len(s.objects))
+	for name := range s.objects {
+		if name != "Query" {
+			types = append(types, name)
+		}
+	}
+	sort.Strings(types)
+	for _, name := range append([]string{"Query"}, types...) {
+		fmt.Fprintf(&b, "\ntype %s {\n", name)
+		fields := make([]string, 0, len(s.objects[name]))
+		for field := range s.objects[name] {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, fieldName := range fields {
+			field := s.objects[name][fieldName]
+			if field.doc != "" {
+				fmt.Fprintf(&b, "  %s\n", strconv.Quote(field.doc))
+			}
+			b.WriteString("  " + fieldName)
+			if len(field.args) > 0 {
+				args := make([]string, len(field.args))
+				for i, arg := range field.args {
+					args[i] = arg.name + ": " + arg.typ
+				}
+				b.WriteString("(" + strings.Join(args, ", ") + ")")
+			}
+			b.WriteString(": " + field.typ + "\n")
+		}
+		b.WriteString("}\n")
+	}
+	return b.String()
+}
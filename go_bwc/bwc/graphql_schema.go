@@ -0,0 +1,309 @@
+package bwc
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The GraphQL schema over the evidence store. It is read-only; changes go
+// through the REST API, where each one is checked and audited on its own.
+
+// gqlCase is the source of the Case type: a case's evidence, in recording order
+type gqlCase struct {
+	number   string
+	evidence []*Evidence
+}
+
+// gqlStatusCount is the source of the StatusCount type
+type gqlStatusCount struct {
+	status EvidenceStatus
+	count  int
+}
+
+// gqlLeaf is a field computed from its source alone
+func gqlLeaf(typ string, get func(source interface{}) interface{}) *gqlField {
+	return &gqlField{typ: typ, resolve: func(_ *gqlRequest, source interface{}, _ map[string]interface{}) (interface{}, error) {
+		return get(source), nil
+	}}
+}
+
+func gqlStringArg(args map[string]interface{}, name string) string {
+	s, _ := args[name].(string)
+	return s
+}
+
+func gqlIntArg(args map[string]interface{}, name string) int {
+	n, _ := args[name].(int)
+	return n
+}
+
+// graphQLSchema is the schema served at /api/graphql
+var graphQLSchema = &gqlSchema{
+	scalars: []string{"Time", "Long"},
+	enums: map[string][]string{
+		"EvidenceStatus": {string(StatusCollected), string(StatusProcessing), string(StatusAnalyzed), string(StatusArchived), string(StatusDeleted)},
+		"MediaType":      {string(MediaVideo), string(MediaAudio), string(MediaPhoto), string(MediaDocument), string(MediaOther)},
+		"SortField":      {"TIMESTAMP", "CASE_NUMBER", "STATUS"},
+	},
+	objects: map[string]map[string]*gqlField{
+		"Query": {
+			"evidence": {
+				typ:  "Evidence",
+				doc:  "One evidence record by ID",
+				args: []gqlArgDef{{name: "id", typ: "ID!"}},
+				resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					return req.system.GetEvidenceContext(req.ctx, gqlStringArg(args, "id"))
+				},
+			},
+			"search": {
+				typ: "EvidencePage!",
+				doc: "A page of the evidence matching every filter given, as in GET /api/evidence",
+				args: []gqlArgDef{
+					{name: "case", typ: "String"},
+					{name: "officer", typ: "String"},
+					{name: "status", typ: "EvidenceStatus"},
+					{name: "mediaType", typ: "MediaType"},
+					{name: "sort", typ: "SortField"},
+					{name: "descending", typ: "Boolean"},
+					{name: "limit", typ: "Int"},
+					{name: "offset", typ: "Int"},
+					{name: "after", typ: "String"},
+				},
+				resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					descending, _ := args["descending"].(bool)
+					return req.system.SearchEvidencePage(req.ctx, EvidenceFilter{
+						CaseNumber: gqlStringArg(args, "case"),
+						OfficerID:  gqlStringArg(args, "officer"),
+						Status:     EvidenceStatus(gqlStringArg(args, "status")),
+						MediaType:  MediaType(gqlStringArg(args, "mediaType")),
+					}, QueryOptions{
+						Limit:      gqlIntArg(args, "limit"),
+						Offset:     gqlIntArg(args, "offset"),
+						Cursor:     gqlStringArg(args, "after"),
+						SortBy:     SortField(strings.ToLower(gqlStringArg(args, "sort"))),
+						Descending: descending,
+					})
+				},
+			},
+			"case": {
+				typ:  "Case",
+				doc:  "A case and the rollups of its evidence",
+				args: []gqlArgDef{{name: "number", typ: "String!"}},
+				resolve: func(req *gqlRequest, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					return req.system.graphQLCase(req.ctx, gqlStringArg(args, "number"))
+				},
+			},
+		},
+
+		"EvidencePage": {
+			"evidence":   gqlLeaf("[Evidence!]!", func(s interface{}) interface{} { return s.(*EvidencePage).Evidence }),
+			"total":      gqlLeaf("Int!", func(s interface{}) interface{} { return s.(*EvidencePage).Total }),
+			"nextCursor": gqlLeaf("String", func(s interface{}) interface{} { return gqlOptional(s.(*EvidencePage).NextCursor) }),
+		},
+
+		"Evidence": {
+			"id":              gqlLeaf("ID!", func(s interface{}) interface{} { return s.(*Evidence).ID }),
+			"caseNumber":      gqlLeaf("String!", func(s interface{}) interface{} { return s.(*Evidence).CaseNumber }),
+			"officerId":       gqlLeaf("String!", func(s interface{}) interface{} { return s.(*Evidence).OfficerID }),
+			"officerName":     gqlLeaf("String!", func(s interface{}) interface{} { return s.(*Evidence).OfficerName }),
+			"timestamp":       gqlLeaf("Time!", func(s interface{}) interface{} { return s.(*Evidence).Timestamp }),
+			"durationSeconds": gqlLeaf("Int!", func(s interface{}) interface{} { return s.(*Evidence).Duration }),
+			"mediaType":       gqlLeaf("MediaType!", func(s interface{}) interface{} { return mediaTypeOf(s.(*Evidence)) }),
+			"location":        gqlLeaf("String!", func(s interface{}) interface{} { return s.(*Evidence).Location }),
+			"fileHash":        gqlLeaf("String!", func(s interface{}) interface{} { return s.(*Evidence).FileHash }),
+			"fileSize":        gqlLeaf("Long!", func(s interface{}) interface{} { return s.(*Evidence).FileSize }),
+			"status":          gqlLeaf("EvidenceStatus!", func(s interface{}) interface{} { return s.(*Evidence).Status }),
+			"tags":            gqlLeaf("[String!]!", func(s interface{}) interface{} { return s.(*Evidence).Tags }),
+			"notes":           gqlLeaf("String!", func(s interface{}) interface{} { return s.(*Evidence).Notes }),
+			"createdAt":       gqlLeaf("Time!", func(s interface{}) interface{} { return s.(*Evidence).CreatedAt }),
+			"lastModified":    gqlLeaf("Time!", func(s interface{}) interface{} { return s.(*Evidence).LastModified }),
+			"revision":        gqlLeaf("Long!", func(s interface{}) interface{} { return s.(*Evidence).Revision }),
+			"sealed":          gqlLeaf("Boolean!", func(s interface{}) interface{} { return s.(*Evidence).Seal != nil }),
+			"custody":         gqlLeaf("[CustodyEntry!]!", func(s interface{}) interface{} { return s.(*Evidence).ChainOfCustody }),
+			"integrityChecks": {
+				typ:  "[IntegrityCheck!]!",
+				doc:  "Integrity checks, oldest first, limited to passes or failures by valid and to those at or after since",
+				args: []gqlArgDef{{name: "valid", typ: "Boolean"}, {name: "since", typ: "Time"}},
+				resolve: func(_ *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+					var since time.Time
+					if s := gqlStringArg(args, "since"); s != "" {
+						var err error
+						if since, err = time.Parse(time.RFC3339, s); err != nil {
+							return nil, &ValidationError{Field: "since", Value: s, Reason: "must be an RFC 3339 time"}
+						}
+					}
+					valid, filterValid := args["valid"].(bool)
+					checks := []IntegrityCheck{}
+					for _, check := range source.(*Evidence).IntegrityChecks {
+						if (!filterValid || check.IsValid == valid) && !check.Timestamp.Before(since) {
+							checks = append(checks, check)
+						}
+					}
+					return checks, nil
+				},
+			},
+			"lastIntegrityCheck": gqlLeaf("IntegrityCheck", func(s interface{}) interface{} {
+				checks := s.(*Evidence).IntegrityChecks
+				if len(checks) == 0 {
+					return nil
+				}
+				return checks[len(checks)-1]
+			}),
+			"auditLog": {
+				typ:  "[AuditLog!]!",
+				doc:  "The audit entries for the evidence, oldest first, limited to one action when given",
+				args: []gqlArgDef{{name: "action", typ: "String"}},
+				resolve: func(req *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+					page, err := req.system.GetAuditLogsPageContext(req.ctx, AuditFilter{EvidenceID: source.(*Evidence).ID, Action: gqlStringArg(args, "action")}, QueryOptions{})
+					if err != nil {
+						return nil, err
+					}
+					return page.Entries, nil
+				},
+			},
+		},
+
+		"CustodyEntry": {
+			"timestamp":    gqlLeaf("Time!", func(s interface{}) interface{} { return s.(CustodyEntry).Timestamp }),
+			"fromOfficer":  gqlLeaf("String!", func(s interface{}) interface{} { return s.(CustodyEntry).FromOfficer }),
+			"toOfficer":    gqlLeaf("String!", func(s interface{}) interface{} { return s.(CustodyEntry).ToOfficer }),
+			"action":       gqlLeaf("String!", func(s interface{}) interface{} { return s.(CustodyEntry).Action }),
+			"purpose":      gqlLeaf("String!", func(s interface{}) interface{} { return s.(CustodyEntry).Purpose }),
+			"verifiedHash": gqlLeaf("String!", func(s interface{}) interface{} { return s.(CustodyEntry).VerifiedHash }),
+			"signed":       gqlLeaf("Boolean!", func(s interface{}) interface{} { return s.(CustodyEntry).Signature != nil }),
+		},
+
+		"IntegrityCheck": {
+			"timestamp": gqlLeaf("Time!", func(s interface{}) interface{} { return s.(IntegrityCheck).Timestamp }),
+			"checkedBy": gqlLeaf("String!", func(s interface{}) interface{} { return s.(IntegrityCheck).CheckedBy }),
+			"hashValue": gqlLeaf("String!", func(s interface{}) interface{} { return s.(IntegrityCheck).HashValue }),
+			"isValid":   gqlLeaf("Boolean!", func(s interface{}) interface{} { return s.(IntegrityCheck).IsValid }),
+			"notes":     gqlLeaf("String!", func(s interface{}) interface{} { return s.(IntegrityCheck).Notes }),
+		},
+
+		"AuditLog": {
+			"timestamp":  gqlLeaf("Time!", func(s interface{}) interface{} { return s.(AuditLog).Timestamp }),
+			"userId":     gqlLeaf("String!", func(s interface{}) interface{} { return s.(AuditLog).UserID }),
+			"action":     gqlLeaf("String!", func(s interface{}) interface{} { return s.(AuditLog).Action }),
+			"evidenceId": gqlLeaf("String!", func(s interface{}) interface{} { return s.(AuditLog).EvidenceID }),
+			"details":    gqlLeaf("String!", func(s interface{}) interface{} { return s.(AuditLog).Details }),
+		},
+
+		"Case": {
+			"number":        gqlLeaf("String!", func(s interface{}) interface{} { return s.(*gqlCase).number }),
+			"evidenceCount": gqlLeaf("Int!", func(s interface{}) interface{} { return len(s.(*gqlCase).evidence) }),
+			"totalSize": gqlLeaf("Long!", func(s interface{}) interface{} {
+				var total int64
+				for _, ev := range s.(*gqlCase).evidence {
+					total += ev.FileSize
+				}
+				return total
+			}),
+			"firstRecorded": gqlLeaf("Time!", func(s interface{}) interface{} { return s.(*gqlCase).evidence[0].Timestamp }),
+			"lastRecorded": gqlLeaf("Time!", func(s interface{}) interface{} {
+				evidence := s.(*gqlCase).evidence
+				return evidence[len(evidence)-1].Timestamp
+			}),
+			"officers": gqlLeaf("[String!]!", func(s interface{}) interface{} {
+				seen := map[string]bool{}
+				officers := []string{}
+				for _, ev := range s.(*gqlCase).evidence {
+					if !seen[ev.OfficerID] {
+						seen[ev.OfficerID] = true
+						officers = append(officers, ev.OfficerID)
+					}
+				}
+				sort.Strings(officers)
+				return officers
+			}),
+			"statusCounts": {
+				typ: "[StatusCount!]!",
+				doc: "How many items are in each status, for the statuses with any",
+				resolve: func(_ *gqlRequest, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					counts := map[EvidenceStatus]int{}
+					for _, ev := range source.(*gqlCase).evidence {
+						counts[ev.Status]++
+					}
+					result := []gqlStatusCount{}
+					for _, status := range graphQLSchemaStatuses {
+						if counts[status] > 0 {
+							result = append(result, gqlStatusCount{status: status, count: counts[status]})
+						}
+					}
+					return result, nil
+				},
+			},
+			"integrityFailures": {
+				typ: "Int!",
+				doc: "How many items failed their most recent integrity check",
+				resolve: func(_ *gqlRequest, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					failures := 0
+					for _, ev := range source.(*gqlCase).evidence {
+						if n := len(ev.IntegrityChecks); n > 0 && !ev.IntegrityChecks[n-1].IsValid {
+							failures++
+						}
+					}
+					return failures, nil
+				},
+			},
+			"sealedCount": gqlLeaf("Int!", func(s interface{}) interface{} {
+				sealed := 0
+				for _, ev := range s.(*gqlCase).evidence {
+					if ev.Seal != nil {
+						sealed++
+					}
+				}
+				return sealed
+			}),
+			"evidence": {
+				typ:  "[Evidence!]!",
+				doc:  "The case's evidence in recording order, limited to one status when given",
+				args: []gqlArgDef{{name: "status", typ: "EvidenceStatus"}},
+				resolve: func(_ *gqlRequest, source interface{}, args map[string]interface{}) (interface{}, error) {
+					status := EvidenceStatus(gqlStringArg(args, "status"))
+					evidence := []*Evidence{}
+					for _, ev := range source.(*gqlCase).evidence {
+						if status == "" || ev.Status == status {
+							evidence = append(evidence, ev)
+						}
+					}
+					return evidence, nil
+				},
+			},
+		},
+
+		"StatusCount": {
+			"status": gqlLeaf("EvidenceStatus!", func(s interface{}) interface{} { return s.(gqlStatusCount).status }),
+			"count":  gqlLeaf("Int!", func(s interface{}) interface{} { return s.(gqlStatusCount).count }),
+		},
+	},
+}
+
+// graphQLSchemaStatuses orders status rollups along the lifecycle
+var graphQLSchemaStatuses = []EvidenceStatus{StatusCollected, StatusProcessing, StatusAnalyzed, StatusArchived, StatusDeleted}
+
+// gqlOptional renders an empty string as null
+func gqlOptional(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// graphQLCase gathers a case's evidence for the Case type
+func (bwc *BWCSystem) graphQLCase(ctx context.Context, caseNumber string) (*gqlCase, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	evidence, err := bwc.caseEvidence(ctx, caseNumber)
+	if err != nil {
+		return nil, &BWCError{Code: CodeNotFound, Message: "no evidence found for case " + caseNumber}
+	}
+	c := &gqlCase{number: caseNumber, evidence: make([]*Evidence, len(evidence))}
+	for i := range evidence {
+		c.evidence[i] = &evidence[i]
+	}
+	return c, nil
+}
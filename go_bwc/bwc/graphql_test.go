@@ -0,0 +1,160 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runGraphQL executes a query against system and returns the response as
+// generic JSON
+func runGraphQL(t *testing.T, system *BWCSystem, query string, variables map[string]interface{}) (map[string]interface{}, bool) {
+	t.Helper()
+	resp, ok := graphQLSchema.execute(&gqlRequest{ctx: context.Background(), system: system, userID: "CUS-001"}, query, "", variables)
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to encode response: %v", err)
+	}
+	var out map[string]interface{}
+	json.Unmarshal(data, &out)
+	return out, ok
+}
+
+func TestGraphQLEvidenceWithCustodyAndChecks(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-GQL-001", "OFF-1400", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	system.TransferCustody(evidence.ID, "OFF-1400", "DET-1", "Analysis")
+	os.WriteFile(evidence.FilePath, []byte("TAMPERED"), 0600)
+	system.VerifyIntegrity(evidence.ID, "AUDITOR-1")
+
+	query := `
+		query Item($id: ID!, $withNotes: Boolean = false) {
+			item: evidence(id: $id) {
+				...Basics
+				custody { toOfficer purpose }
+				failed: integrityChecks(valid: false) { checkedBy notes @include(if: $withNotes) }
+				__typename
+			}
+		}
+		fragment Basics on Evidence { id caseNumber status }`
+	resp, ok := graphQLSchema.execute(&gqlRequest{ctx: context.Background(), system: system, userID: "CUS-001"}, query, "", map[string]interface{}{"id": evidence.ID})
+	if !ok || resp.Errors != nil {
+		t.Fatalf("Expected the query to succeed, got %+v", resp.Errors)
+	}
+	// Fields come back in the order the query asks for them
+	data, _ := json.Marshal(resp.Data)
+	want := `{"item":{"id":"` + evidence.ID + `","caseNumber":"CASE-GQL-001","status":"COLLECTED",` +
+		`"custody":[{"toOfficer":"OFF-1400","purpose":"Initial evidence collection"},{"toOfficer":"DET-1","purpose":"Analysis"}],` +
+		`"failed":[{"checkedBy":"AUDITOR-1"}],"__typename":"Evidence"}}`
+	if string(data) != want {
+		t.Errorf("Unexpected data\n got %s\nwant %s", data, want)
+	}
+}
+
+func TestGraphQLCaseRollupAndSearch(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		if _, err := system.IngestEvidence(writeDistinctClip(t, tmpDir, i), "CASE-GQL-002", "OFF-141"+string(rune('0'+i)), "", "", nil); err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+	}
+	first := system.SearchEvidence("CASE-GQL-002", "OFF-1410", "")[0]
+	system.UpdateStatus(first.ID, "OFF-1410", StatusProcessing, "Review")
+
+	out, ok := runGraphQL(t, system, `{
+		case(number: "CASE-GQL-002") { evidenceCount officers statusCounts { status count } integrityFailures processing: evidence(status: PROCESSING) { id } }
+		search(case: "CASE-GQL-002", sort: STATUS, limit: $limit) { total nextCursor evidence { status } }
+	}`, nil)
+	if ok {
+		t.Fatalf("Expected an undefined variable to be refused, got %v", out)
+	}
+
+	out, ok = runGraphQL(t, system, `query($limit: Int) {
+		case(number: "CASE-GQL-002") { evidenceCount officers statusCounts { status count } integrityFailures processing: evidence(status: PROCESSING) { id } }
+		search(case: "CASE-GQL-002", sort: STATUS, limit: $limit) { total nextCursor evidence { status } }
+	}`, map[string]interface{}{"limit": 2.0})
+	if !ok || out["errors"] != nil {
+		t.Fatalf("Expected the query to succeed, got %v", out)
+	}
+	data := out["data"].(map[string]interface{})
+	c := data["case"].(map[string]interface{})
+	if c["evidenceCount"] != 3.0 || len(c["officers"].([]interface{})) != 3 || c["integrityFailures"] != 0.0 {
+		t.Errorf("Unexpected case rollup %v", c)
+	}
+	if counts, _ := json.Marshal(c["statusCounts"]); string(counts) != `[{"count":2,"status":"COLLECTED"},{"count":1,"status":"PROCESSING"}]` {
+		t.Errorf("Unexpected status counts %s", counts)
+	}
+	if processing := c["processing"].([]interface{}); len(processing) != 1 || processing[0].(map[string]interface{})["id"] != first.ID {
+		t.Errorf("Expected only %s in processing, got %v", first.ID, processing)
+	}
+	page := data["search"].(map[string]interface{})
+	if page["total"] != 3.0 || page["nextCursor"] == nil || len(page["evidence"].([]interface{})) != 2 {
+		t.Errorf("Unexpected search page %v", page)
+	}
+}
+
+func TestGraphQLErrors(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	out, ok := runGraphQL(t, system, `{ evidence(id: "BWC-missing") { id } }`, nil)
+	errs, _ := out["errors"].([]interface{})
+	if !ok || len(errs) != 1 || out["data"].(map[string]interface{})["evidence"] != nil {
+		t.Fatalf("Expected null evidence with an error, got %v", out)
+	}
+	if ext := errs[0].(map[string]interface{})["extensions"].(map[string]interface{}); ext["code"] != string(CodeNotFound) {
+		t.Errorf("Expected a NOT_FOUND code, got %v", ext)
+	}
+
+	for _, query := range []string{
+		`{ evidence(id: "x") { password } }`,
+		`{ evidence { id } }`,
+		`{ evidence(id: "x") }`,
+		`{ search(status: LOST) { total } }`,
+		`mutation { evidence(id: "x") { id } }`,
+		`{ evidence(id: "x") { ...Loop } } fragment Loop on Evidence { ...Loop }`,
+		`{ evidence(id: "x") { id }`,
+	} {
+		if out, ok := runGraphQL(t, system, query, nil); ok || out["data"] != nil {
+			t.Errorf("Expected %s to be refused, got %v", query, out)
+		}
+	}
+}
+
+func TestServerGraphQL(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-GQL-003", "OFF-1420", "", "", nil)
+
+	resp := authPostJSON(t, server, "/api/graphql", `{"query": "query Q($id: ID!) { evidence(id: $id) { caseNumber } }", "variables": {"id": "`+evidence.ID+`"}}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `{"data":{"evidence":{"caseNumber":"CASE-GQL-003"}}}`+"\n" {
+		t.Errorf("Unexpected response %d %s", resp.StatusCode, body)
+	}
+
+	resp = authPostJSON(t, server, "/api/graphql", `{"query": "{ nothing }"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid query, got %d", resp.StatusCode)
+	}
+
+	resp = authGet(t, server, "/api/graphql")
+	sdl, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(sdl), "type Query {") || !strings.Contains(string(sdl), "case(number: String!): Case") {
+		t.Errorf("Expected the schema, got %s", sdl)
+	}
+}
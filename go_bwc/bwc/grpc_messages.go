@@ -0,0 +1,521 @@
+//go:build grpc
+
+package bwc
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of proto/evidence.proto, encoded by hand with protowire so the
+// build needs no generated code. Each marshals to and from the protobuf wire
+// format; unknown fields are skipped.
+
+// wireMessage is a message grpcCodec can carry
+type wireMessage interface {
+	marshalWire() []byte
+	unmarshalWire(b []byte) error
+}
+
+// grpcCodec is the gRPC codec for wireMessages. It takes the name of the
+// protobuf codec, as clients of the .proto send that content subtype.
+type grpcCodec struct{}
+
+func (grpcCodec) Name() string { return "proto" }
+
+func (grpcCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return nil, fmt.Errorf("cannot marshal %T", v)
+	}
+	return m.marshalWire(), nil
+}
+
+func (grpcCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(wireMessage)
+	if !ok {
+		return fmt.Errorf("cannot unmarshal into %T", v)
+	}
+	return m.unmarshalWire(data)
+}
+
+func appendWireString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendWireBytes(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+func appendWireInt64(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+func appendWireBool(b []byte, num protowire.Number, v bool) []byte {
+	if !v {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, 1)
+}
+
+func appendWireMessage(b []byte, num protowire.Number, m wireMessage) []byte {
+	return appendWireBytes(b, num, m.marshalWire())
+}
+
+// wireField is one field read by consumeWireFields: bytes for length-delimited
+// fields, varint for varints
+type wireField struct {
+	num    protowire.Number
+	bytes  []byte
+	varint uint64
+}
+
+// consumeWireFields calls fn for each length-delimited or varint field of b,
+// skipping fields of other types
+func consumeWireFields(b []byte, fn func(f wireField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := wireField{num: num}
+		switch typ {
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pbIngestMetadata struct {
+	Filename       string
+	CaseNumber     string
+	OfficerID      string
+	OfficerName    string
+	Location       string
+	Tags           []string
+	IdempotencyKey string
+	ExpectedSHA256 string
+}
+
+func (m *pbIngestMetadata) marshalWire() []byte {
+	var b []byte
+	b = appendWireString(b, 1, m.Filename)
+	b = appendWireString(b, 2, m.CaseNumber)
+	b = appendWireString(b, 3, m.OfficerID)
+	b = appendWireString(b, 4, m.OfficerName)
+	b = appendWireString(b, 5, m.Location)
+	for _, tag := range m.Tags {
+		b = appendWireBytes(b, 6, []byte(tag))
+	}
+	b = appendWireString(b, 7, m.IdempotencyKey)
+	b = appendWireString(b, 8, m.ExpectedSHA256)
+	return b
+}
+
+func (m *pbIngestMetadata) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.Filename = string(f.bytes)
+		case 2:
+			m.CaseNumber = string(f.bytes)
+		case 3:
+			m.OfficerID = string(f.bytes)
+		case 4:
+			m.OfficerName = string(f.bytes)
+		case 5:
+			m.Location = string(f.bytes)
+		case 6:
+			m.Tags = append(m.Tags, string(f.bytes))
+		case 7:
+			m.IdempotencyKey = string(f.bytes)
+		case 8:
+			m.ExpectedSHA256 = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+// pbIngestRequest holds either Metadata or a Chunk of the file
+type pbIngestRequest struct {
+	Metadata *pbIngestMetadata
+	Chunk    []byte
+}
+
+func (m *pbIngestRequest) marshalWire() []byte {
+	if m.Metadata != nil {
+		return appendWireMessage(nil, 1, m.Metadata)
+	}
+	return appendWireBytes(nil, 2, m.Chunk)
+}
+
+func (m *pbIngestRequest) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.Metadata, m.Chunk = &pbIngestMetadata{}, nil
+			return m.Metadata.unmarshalWire(f.bytes)
+		case 2:
+			// The codec's buffer may be reused once the message is read
+			m.Metadata, m.Chunk = nil, append([]byte(nil), f.bytes...)
+		}
+		return nil
+	})
+}
+
+type pbIngestResponse struct {
+	Evidence       *pbEvidence
+	ReceivedSHA256 string
+	ReceivedBytes  int64
+	Replayed       bool
+}
+
+func (m *pbIngestResponse) marshalWire() []byte {
+	var b []byte
+	if m.Evidence != nil {
+		b = appendWireMessage(b, 1, m.Evidence)
+	}
+	b = appendWireString(b, 2, m.ReceivedSHA256)
+	b = appendWireInt64(b, 3, m.ReceivedBytes)
+	return appendWireBool(b, 4, m.Replayed)
+}
+
+func (m *pbIngestResponse) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.Evidence = &pbEvidence{}
+			return m.Evidence.unmarshalWire(f.bytes)
+		case 2:
+			m.ReceivedSHA256 = string(f.bytes)
+		case 3:
+			m.ReceivedBytes = int64(f.varint)
+		case 4:
+			m.Replayed = f.varint != 0
+		}
+		return nil
+	})
+}
+
+type pbCustodyEntry struct {
+	Timestamp   string
+	FromOfficer string
+	ToOfficer   string
+	Action      string
+	Purpose     string
+}
+
+func (m *pbCustodyEntry) marshalWire() []byte {
+	var b []byte
+	b = appendWireString(b, 1, m.Timestamp)
+	b = appendWireString(b, 2, m.FromOfficer)
+	b = appendWireString(b, 3, m.ToOfficer)
+	b = appendWireString(b, 4, m.Action)
+	return appendWireString(b, 5, m.Purpose)
+}
+
+func (m *pbCustodyEntry) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.Timestamp = string(f.bytes)
+		case 2:
+			m.FromOfficer = string(f.bytes)
+		case 3:
+			m.ToOfficer = string(f.bytes)
+		case 4:
+			m.Action = string(f.bytes)
+		case 5:
+			m.Purpose = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type pbEvidence struct {
+	ID             string
+	CaseNumber     string
+	OfficerID      string
+	OfficerName    string
+	Status         string
+	FileHash       string
+	FileSize       int64
+	Revision       int64
+	Location       string
+	Tags           []string
+	ChainOfCustody []*pbCustodyEntry
+	Timestamp      string
+}
+
+// newPBEvidence returns the wire form of evidence
+func newPBEvidence(evidence *Evidence) *pbEvidence {
+	m := &pbEvidence{
+		ID:          evidence.ID,
+		CaseNumber:  evidence.CaseNumber,
+		OfficerID:   evidence.OfficerID,
+		OfficerName: evidence.OfficerName,
+		Status:      string(evidence.Status),
+		FileHash:    evidence.FileHash,
+		FileSize:    evidence.FileSize,
+		Revision:    evidence.Revision,
+		Location:    evidence.Location,
+		Tags:        evidence.Tags,
+		Timestamp:   evidence.Timestamp.Format(time.RFC3339Nano),
+	}
+	for _, entry := range evidence.ChainOfCustody {
+		m.ChainOfCustody = append(m.ChainOfCustody, &pbCustodyEntry{
+			Timestamp:   entry.Timestamp.Format(time.RFC3339Nano),
+			FromOfficer: entry.FromOfficer,
+			ToOfficer:   entry.ToOfficer,
+			Action:      entry.Action,
+			Purpose:     entry.Purpose,
+		})
+	}
+	return m
+}
+
+func (m *pbEvidence) marshalWire() []byte {
+	var b []byte
+	b = appendWireString(b, 1, m.ID)
+	b = appendWireString(b, 2, m.CaseNumber)
+	b = appendWireString(b, 3, m.OfficerID)
+	b = appendWireString(b, 4, m.OfficerName)
+	b = appendWireString(b, 5, m.Status)
+	b = appendWireString(b, 6, m.FileHash)
+	b = appendWireInt64(b, 7, m.FileSize)
+	b = appendWireInt64(b, 8, m.Revision)
+	b = appendWireString(b, 9, m.Location)
+	for _, tag := range m.Tags {
+		b = appendWireBytes(b, 10, []byte(tag))
+	}
+	for _, entry := range m.ChainOfCustody {
+		b = appendWireMessage(b, 11, entry)
+	}
+	return appendWireString(b, 12, m.Timestamp)
+}
+
+func (m *pbEvidence) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.ID = string(f.bytes)
+		case 2:
+			m.CaseNumber = string(f.bytes)
+		case 3:
+			m.OfficerID = string(f.bytes)
+		case 4:
+			m.OfficerName = string(f.bytes)
+		case 5:
+			m.Status = string(f.bytes)
+		case 6:
+			m.FileHash = string(f.bytes)
+		case 7:
+			m.FileSize = int64(f.varint)
+		case 8:
+			m.Revision = int64(f.varint)
+		case 9:
+			m.Location = string(f.bytes)
+		case 10:
+			m.Tags = append(m.Tags, string(f.bytes))
+		case 11:
+			entry := &pbCustodyEntry{}
+			m.ChainOfCustody = append(m.ChainOfCustody, entry)
+			return entry.unmarshalWire(f.bytes)
+		case 12:
+			m.Timestamp = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type pbGetEvidenceRequest struct {
+	ID string
+}
+
+func (m *pbGetEvidenceRequest) marshalWire() []byte { return appendWireString(nil, 1, m.ID) }
+
+func (m *pbGetEvidenceRequest) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		if f.num == 1 {
+			m.ID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type pbSearchEvidenceRequest struct {
+	CaseNumber string
+	OfficerID  string
+	Status     string
+}
+
+func (m *pbSearchEvidenceRequest) marshalWire() []byte {
+	b := appendWireString(nil, 1, m.CaseNumber)
+	b = appendWireString(b, 2, m.OfficerID)
+	return appendWireString(b, 3, m.Status)
+}
+
+func (m *pbSearchEvidenceRequest) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.CaseNumber = string(f.bytes)
+		case 2:
+			m.OfficerID = string(f.bytes)
+		case 3:
+			m.Status = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type pbSearchEvidenceResponse struct {
+	Evidence []*pbEvidence
+}
+
+func (m *pbSearchEvidenceResponse) marshalWire() []byte {
+	var b []byte
+	for _, ev := range m.Evidence {
+		b = appendWireMessage(b, 1, ev)
+	}
+	return b
+}
+
+func (m *pbSearchEvidenceResponse) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		if f.num != 1 {
+			return nil
+		}
+		ev := &pbEvidence{}
+		m.Evidence = append(m.Evidence, ev)
+		return ev.unmarshalWire(f.bytes)
+	})
+}
+
+type pbTransferCustodyRequest struct {
+	EvidenceID  string
+	FromOfficer string
+	ToOfficer   string
+	Purpose     string
+}
+
+func (m *pbTransferCustodyRequest) marshalWire() []byte {
+	b := appendWireString(nil, 1, m.EvidenceID)
+	b = appendWireString(b, 2, m.FromOfficer)
+	b = appendWireString(b, 3, m.ToOfficer)
+	return appendWireString(b, 4, m.Purpose)
+}
+
+func (m *pbTransferCustodyRequest) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.EvidenceID = string(f.bytes)
+		case 2:
+			m.FromOfficer = string(f.bytes)
+		case 3:
+			m.ToOfficer = string(f.bytes)
+		case 4:
+			m.Purpose = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type pbUpdateStatusRequest struct {
+	EvidenceID string
+	Status     string
+	Notes      string
+	Revision   int64
+}
+
+func (m *pbUpdateStatusRequest) marshalWire() []byte {
+	b := appendWireString(nil, 1, m.EvidenceID)
+	b = appendWireString(b, 2, m.Status)
+	b = appendWireString(b, 3, m.Notes)
+	return appendWireInt64(b, 4, m.Revision)
+}
+
+func (m *pbUpdateStatusRequest) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.EvidenceID = string(f.bytes)
+		case 2:
+			m.Status = string(f.bytes)
+		case 3:
+			m.Notes = string(f.bytes)
+		case 4:
+			m.Revision = int64(f.varint)
+		}
+		return nil
+	})
+}
+
+type pbVerifyIntegrityRequest struct {
+	EvidenceID string
+}
+
+func (m *pbVerifyIntegrityRequest) marshalWire() []byte {
+	return appendWireString(nil, 1, m.EvidenceID)
+}
+
+func (m *pbVerifyIntegrityRequest) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		if f.num == 1 {
+			m.EvidenceID = string(f.bytes)
+		}
+		return nil
+	})
+}
+
+type pbVerifyIntegrityResponse struct {
+	EvidenceID string
+	Valid      bool
+}
+
+func (m *pbVerifyIntegrityResponse) marshalWire() []byte {
+	return appendWireBool(appendWireString(nil, 1, m.EvidenceID), 2, m.Valid)
+}
+
+func (m *pbVerifyIntegrityResponse) unmarshalWire(b []byte) error {
+	return consumeWireFields(b, func(f wireField) error {
+		switch f.num {
+		case 1:
+			m.EvidenceID = string(f.bytes)
+		case 2:
+			m.Valid = f.varint != 0
+		}
+		return nil
+	})
+}
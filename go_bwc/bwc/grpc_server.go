@@ -0,0 +1,361 @@
+//go:build grpc
+
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Builds with -tags grpc serve proto/evidence.proto with "serve -grpc"
+func init() {
+	startGRPCServer = func(api *apiServer, addr string, errCh chan<- error) (func(), error) {
+		server, err := newGRPCServer(api)
+		if err != nil {
+			return nil, err
+		}
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+		go func() { errCh <- server.Serve(lis) }()
+		return server.GracefulStop, nil
+	}
+}
+
+const grpcServiceName = "bwc.v1.EvidenceService"
+
+// grpcUserKey carries the authenticated user ID in a call's context
+type grpcUserKey struct{}
+
+// grpcEvidenceService implements EvidenceService over the API's system and
+// credentials
+type grpcEvidenceService struct {
+	api *apiServer
+}
+
+// newGRPCServer returns a gRPC server for the evidence service, over TLS when
+// the API is configured for it
+func newGRPCServer(api *apiServer) (*grpc.Server, error) {
+	svc := &grpcEvidenceService{api: api}
+	opts := []grpc.ServerOption{
+		grpc.ForceServerCodec(grpcCodec{}),
+		grpc.UnaryInterceptor(svc.authenticateUnary),
+		grpc.StreamInterceptor(svc.authenticateStream),
+	}
+	if cfg := api.config.API; cfg.EnableTLS {
+		creds, err := credentials.NewServerTLSFromFile(cfg.TLSCertPath, cfg.TLSKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the gRPC TLS certificate: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	server := grpc.NewServer(opts...)
+	server.RegisterService(&grpcServiceDesc, svc)
+	return server, nil
+}
+
+// grpcServiceDesc describes EvidenceService to the gRPC server in place of
+// generated code
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetEvidence", Handler: grpcUnaryHandler("GetEvidence", func(svc *grpcEvidenceService, ctx context.Context, req *pbGetEvidenceRequest) (wireMessage, error) {
+			return svc.getEvidence(ctx, req)
+		})},
+		{MethodName: "SearchEvidence", Handler: grpcUnaryHandler("SearchEvidence", func(svc *grpcEvidenceService, ctx context.Context, req *pbSearchEvidenceRequest) (wireMessage, error) {
+			return svc.searchEvidence(ctx, req)
+		})},
+		{MethodName: "TransferCustody", Handler: grpcUnaryHandler("TransferCustody", func(svc *grpcEvidenceService, ctx context.Context, req *pbTransferCustodyRequest) (wireMessage, error) {
+			return svc.transferCustody(ctx, req)
+		})},
+		{MethodName: "UpdateStatus", Handler: grpcUnaryHandler("UpdateStatus", func(svc *grpcEvidenceService, ctx context.Context, req *pbUpdateStatusRequest) (wireMessage, error) {
+			return svc.updateStatus(ctx, req)
+		})},
+		{MethodName: "VerifyIntegrity", Handler: grpcUnaryHandler("VerifyIntegrity", func(svc *grpcEvidenceService, ctx context.Context, req *pbVerifyIntegrityRequest) (wireMessage, error) {
+			return svc.verifyIntegrity(ctx, req)
+		})},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Ingest", ClientStreams: true, Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			return srv.(*grpcEvidenceService).ingest(stream)
+		}},
+	},
+	Metadata: "proto/evidence.proto",
+}
+
+// grpcUnaryHandler adapts a typed method to the gRPC unary handler signature
+func grpcUnaryHandler[Req any, PReq interface {
+	*Req
+	wireMessage
+}](method string, fn func(*grpcEvidenceService, context.Context, PReq) (wireMessage, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		req := PReq(new(Req))
+		if err := dec(req); err != nil {
+			return nil, err
+		}
+		svc := srv.(*grpcEvidenceService)
+		if interceptor == nil {
+			return fn(svc, ctx, req)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + grpcServiceName + "/" + method}
+		return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fn(svc, ctx, req.(PReq))
+		})
+	}
+}
+
+// authenticate resolves the caller from the API token in the call's
+// metadata, refusing grant-only users as the REST API does
+func (svc *grpcEvidenceService) authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 && strings.HasPrefix(values[0], "Bearer ") {
+		token = strings.TrimPrefix(values[0], "Bearer ")
+	}
+	principal, ok := svc.api.authenticateToken(token)
+	if !ok {
+		if token != "" {
+			svc.api.system.logAudit("UNKNOWN", "AUTH_FAILED", "", "API token rejected for gRPC", grpcPeerIP(ctx))
+		}
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if svc.api.grantOnly(principal.UserID) {
+		return nil, status.Error(codes.PermissionDenied, "access is limited to granted evidence")
+	}
+	ctx = ContextWithPrincipal(ctx, principal)
+	return context.WithValue(ctx, grpcUserKey{}, principal.UserID), nil
+}
+
+func (svc *grpcEvidenceService) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := svc.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (svc *grpcEvidenceService) authenticateStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := svc.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &grpcAuthenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+type grpcAuthenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcAuthenticatedStream) Context() context.Context { return s.ctx }
+
+func grpcUser(ctx context.Context) string {
+	userID, _ := ctx.Value(grpcUserKey{}).(string)
+	return userID
+}
+
+// grpcPeerIP returns the caller's address without the port
+func grpcPeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// grpcError maps an error from the system to a gRPC status, with code for
+// errors not otherwise told apart
+func grpcError(err error, code codes.Code) error {
+	var conflict *RevisionConflictError
+	if errors.As(err, &conflict) {
+		return status.Error(codes.Aborted, err.Error())
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	switch ErrorCodeOf(err) {
+	case CodeNotFound:
+		code = codes.NotFound
+	case CodeInvalidArgument:
+		code = codes.InvalidArgument
+	case CodeIntegrityFailure, CodeInvalidTransition, CodeSealed, CodeApprovalRequired:
+		code = codes.FailedPrecondition
+	case CodePermissionDenied:
+		code = codes.PermissionDenied
+	case CodeUnauthenticated:
+		code = codes.Unauthenticated
+	}
+	return status.Error(code, err.Error())
+}
+
+// grpcChunkReader reads the file chunks of an Ingest stream
+type grpcChunkReader struct {
+	stream grpc.ServerStream
+	buf    []byte
+}
+
+func (r *grpcChunkReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		var req pbIngestRequest
+		if err := r.stream.RecvMsg(&req); err != nil {
+			return 0, err
+		}
+		if req.Metadata != nil {
+			return 0, status.Error(codes.InvalidArgument, "metadata must only be sent first")
+		}
+		r.buf = req.Chunk
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// grpcSizeLimitReader fails once more than limit bytes have been read
+type grpcSizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (r *grpcSizeLimitReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.read += int64(n)
+	if r.limit > 0 && r.read > r.limit {
+		return n, status.Error(codes.ResourceExhausted, "file exceeds storage.max_file_size_mb")
+	}
+	return n, err
+}
+
+// ingest receives the metadata and then the file in chunks, hashing each
+// chunk as it arrives, and ingests the file once the client closes the stream
+func (svc *grpcEvidenceService) ingest(stream grpc.ServerStream) error {
+	userID := grpcUser(stream.Context())
+	var first pbIngestRequest
+	if err := stream.RecvMsg(&first); err != nil {
+		return err
+	}
+	meta := first.Metadata
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first message must carry the metadata")
+	}
+	name := filepath.Base(meta.Filename)
+	if meta.Filename == "" || name == "." || name == string(filepath.Separator) {
+		return status.Error(codes.InvalidArgument, "filename is required")
+	}
+
+	h := sha256.New()
+	body := &grpcSizeLimitReader{r: &grpcChunkReader{stream: stream}, limit: svc.api.config.Storage.MaxFileSizeMB << 20}
+	path, err := svc.api.system.receiveUpload(io.TeeReader(body, h), filepath.Ext(name))
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer os.Remove(path)
+
+	received := hex.EncodeToString(h.Sum(nil))
+	if meta.ExpectedSHA256 != "" && !strings.EqualFold(meta.ExpectedSHA256, received) {
+		return status.Errorf(codes.DataLoss, "received file hashes to %s, not the expected %s", received, meta.ExpectedSHA256)
+	}
+
+	officerID := meta.OfficerID
+	if officerID == "" {
+		officerID = userID
+	}
+	var evidence *Evidence
+	replayed := false
+	if meta.IdempotencyKey != "" {
+		evidence, replayed, err = svc.api.system.IngestEvidenceIdempotentContext(stream.Context(), meta.IdempotencyKey, path, meta.CaseNumber, officerID, meta.OfficerName, meta.Location, meta.Tags)
+	} else {
+		evidence, err = svc.api.system.IngestEvidenceContext(stream.Context(), path, meta.CaseNumber, officerID, meta.OfficerName, meta.Location, meta.Tags)
+	}
+	switch {
+	case errors.Is(err, errIngestInProgress), errors.Is(err, errIdempotencyKeyReused):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return grpcError(err, codes.InvalidArgument)
+	}
+	return stream.SendMsg(&pbIngestResponse{
+		Evidence:       newPBEvidence(evidence),
+		ReceivedSHA256: received,
+		ReceivedBytes:  body.read,
+		Replayed:       replayed,
+	})
+}
+
+func (svc *grpcEvidenceService) getEvidence(ctx context.Context, req *pbGetEvidenceRequest) (wireMessage, error) {
+	evidence, err := svc.api.system.GetEvidenceContext(ctx, req.ID)
+	if err != nil {
+		return nil, grpcError(err, codes.NotFound)
+	}
+	return newPBEvidence(evidence), nil
+}
+
+func (svc *grpcEvidenceService) searchEvidence(ctx context.Context, req *pbSearchEvidenceRequest) (wireMessage, error) {
+	resp := &pbSearchEvidenceResponse{}
+	results, err := svc.api.system.SearchEvidenceContext(ctx, req.CaseNumber, req.OfficerID, EvidenceStatus(req.Status))
+	if err != nil {
+		return nil, grpcError(err, codes.Internal)
+	}
+	for _, evidence := range results {
+		resp.Evidence = append(resp.Evidence, newPBEvidence(evidence))
+	}
+	return resp, nil
+}
+
+func (svc *grpcEvidenceService) transferCustody(ctx context.Context, req *pbTransferCustodyRequest) (wireMessage, error) {
+	from := req.FromOfficer
+	if from == "" {
+		from = grpcUser(ctx)
+	}
+	if req.ToOfficer == "" {
+		return nil, status.Error(codes.InvalidArgument, "to_officer is required")
+	}
+	if err := svc.api.system.TransferCustodyContext(ctx, req.EvidenceID, from, req.ToOfficer, req.Purpose); err != nil {
+		return nil, grpcError(err, codes.FailedPrecondition)
+	}
+	return svc.getEvidence(ctx, &pbGetEvidenceRequest{ID: req.EvidenceID})
+}
+
+func (svc *grpcEvidenceService) updateStatus(ctx context.Context, req *pbUpdateStatusRequest) (wireMessage, error) {
+	newStatus := EvidenceStatus(req.Status)
+	switch newStatus {
+	case StatusCollected, StatusProcessing, StatusAnalyzed, StatusArchived:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "status %q is not one of COLLECTED, PROCESSING, ANALYZED, ARCHIVED", req.Status)
+	}
+	if err := svc.api.system.UpdateStatusContext(ctx, req.EvidenceID, grpcUser(ctx), newStatus, req.Notes, req.Revision); err != nil {
+		return nil, grpcError(err, codes.FailedPrecondition)
+	}
+	return svc.getEvidence(ctx, &pbGetEvidenceRequest{ID: req.EvidenceID})
+}
+
+func (svc *grpcEvidenceService) verifyIntegrity(ctx context.Context, req *pbVerifyIntegrityRequest) (wireMessage, error) {
+	valid, err := svc.api.system.VerifyIntegrityContext(ctx, req.EvidenceID, grpcUser(ctx))
+	if err != nil {
+		return nil, grpcError(err, codes.NotFound)
+	}
+	return &pbVerifyIntegrityResponse{EvidenceID: req.EvidenceID, Valid: valid}, nil
+}
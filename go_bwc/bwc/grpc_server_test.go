@@ -0,0 +1,153 @@
+//go:build grpc
+
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// setupGRPCServer serves the evidence service for a test system and returns a
+// client connection and a context carrying the test API token
+func setupGRPCServer(t *testing.T) (*BWCSystem, *grpc.ClientConn, context.Context, func()) {
+	system, _, _, cleanup := setupTestServer(t)
+	server, err := newGRPCServer(newAPIServer(system))
+	if err != nil {
+		t.Fatalf("newGRPCServer failed: %v", err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(lis)
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(grpcCodec{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+testAPIToken)
+	return system, conn, ctx, func() {
+		conn.Close()
+		server.Stop()
+		cleanup()
+	}
+}
+
+// grpcIngest uploads data in chunks of size over the Ingest stream. A send
+// fails with io.EOF once the server has ended the call, whose status is then
+// read by RecvMsg.
+func grpcIngest(ctx context.Context, conn *grpc.ClientConn, meta *pbIngestMetadata, data []byte, size int) (*pbIngestResponse, error) {
+	stream, err := conn.NewStream(ctx, &grpcServiceDesc.Streams[0], "/"+grpcServiceName+"/Ingest")
+	if err != nil {
+		return nil, err
+	}
+	err = stream.SendMsg(&pbIngestRequest{Metadata: meta})
+	for err == nil && len(data) > 0 {
+		n := size
+		if n > len(data) {
+			n = len(data)
+		}
+		err = stream.SendMsg(&pbIngestRequest{Chunk: data[:n]})
+		data = data[n:]
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	resp := &pbIngestResponse{}
+	return resp, stream.RecvMsg(resp)
+}
+
+func TestGRPCIngestStreamsChunks(t *testing.T) {
+	system, conn, ctx, cleanup := setupGRPCServer(t)
+	defer cleanup()
+
+	data := []byte(strings.Repeat("body-worn camera footage ", 20000))
+	sum := sha256.Sum256(data)
+	meta := &pbIngestMetadata{Filename: "clip.mp4", CaseNumber: "CASE-GRPC-001", OfficerName: "Officer Test",
+		Location: "Patrol", Tags: []string{"traffic"}, ExpectedSHA256: hex.EncodeToString(sum[:])}
+	resp, err := grpcIngest(ctx, conn, meta, data, 64*1024)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if resp.ReceivedBytes != int64(len(data)) || resp.ReceivedSHA256 != meta.ExpectedSHA256 || resp.Evidence.FileHash != meta.ExpectedSHA256 {
+		t.Fatalf("expected the file hashed as it arrived, got %+v", resp)
+	}
+	if resp.Evidence.OfficerID != "CUS-001" || len(resp.Evidence.Tags) != 1 {
+		t.Errorf("expected the caller as officer and the tags kept, got %+v", resp.Evidence)
+	}
+	entries, _ := os.ReadDir(filepath.Join(system.storagePath, "uploads"))
+	if len(entries) != 0 {
+		t.Errorf("expected the upload removed once ingested, got %v", entries)
+	}
+
+	meta.ExpectedSHA256 = strings.Repeat("0", 64)
+	if _, err := grpcIngest(ctx, conn, meta, data, 64*1024); status.Code(err) != codes.DataLoss {
+		t.Errorf("expected a hash mismatch to be refused, got %v", err)
+	}
+	if _, err := grpcIngest(ctx, conn, &pbIngestMetadata{CaseNumber: "CASE-GRPC-001"}, data, 1024); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected an ingest without a filename to be refused, got %v", err)
+	}
+	if len(system.SearchEvidence("CASE-GRPC-001", "", "")) != 1 {
+		t.Error("expected nothing ingested from refused uploads")
+	}
+}
+
+func TestGRPCEvidenceCalls(t *testing.T) {
+	_, conn, ctx, cleanup := setupGRPCServer(t)
+	defer cleanup()
+
+	resp, err := grpcIngest(ctx, conn, &pbIngestMetadata{Filename: "clip.mp4", CaseNumber: "CASE-GRPC-002", OfficerID: "OFF-1180"}, []byte("footage"), 3)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	id := resp.Evidence.ID
+
+	var ev pbEvidence
+	if err := conn.Invoke(ctx, "/"+grpcServiceName+"/TransferCustody", &pbTransferCustodyRequest{EvidenceID: id, FromOfficer: "OFF-1180", ToOfficer: "CUS-001", Purpose: "Property room"}, &ev); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	if len(ev.ChainOfCustody) != 2 || ev.ChainOfCustody[1].ToOfficer != "CUS-001" {
+		t.Errorf("expected the transfer in the returned record, got %+v", ev.ChainOfCustody)
+	}
+	err = conn.Invoke(ctx, "/"+grpcServiceName+"/UpdateStatus", &pbUpdateStatusRequest{EvidenceID: id, Status: "ANALYZED", Revision: 1}, &ev)
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("expected a stale revision to abort, got %v", err)
+	}
+	if err := conn.Invoke(ctx, "/"+grpcServiceName+"/UpdateStatus", &pbUpdateStatusRequest{EvidenceID: id, Status: "ANALYZED", Revision: ev.Revision}, &ev); err != nil || ev.Status != "ANALYZED" {
+		t.Errorf("expected the status changed, got %+v, %v", ev.Status, err)
+	}
+
+	var verified pbVerifyIntegrityResponse
+	if err := conn.Invoke(ctx, "/"+grpcServiceName+"/VerifyIntegrity", &pbVerifyIntegrityRequest{EvidenceID: id}, &verified); err != nil || !verified.Valid {
+		t.Errorf("expected the evidence to verify, got %+v, %v", verified, err)
+	}
+	var results pbSearchEvidenceResponse
+	if err := conn.Invoke(ctx, "/"+grpcServiceName+"/SearchEvidence", &pbSearchEvidenceRequest{CaseNumber: "CASE-GRPC-002"}, &results); err != nil || len(results.Evidence) != 1 {
+		t.Errorf("expected one search result, got %d, %v", len(results.Evidence), err)
+	}
+	if err := conn.Invoke(ctx, "/"+grpcServiceName+"/GetEvidence", &pbGetEvidenceRequest{ID: "BWC-missing"}, &ev); status.Code(err) != codes.NotFound {
+		t.Errorf("expected unknown evidence not to be found, got %v", err)
+	}
+
+	if err := conn.Invoke(context.Background(), "/"+grpcServiceName+"/GetEvidence", &pbGetEvidenceRequest{ID: id}, &ev); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected a call without a token to be refused, got %v", err)
+	}
+}
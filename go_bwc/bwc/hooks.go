@@ -0,0 +1,197 @@
+package bwc
+
+import (
+	"fmt"
+)
+
+// Hooks let an agency add policy to the evidence lifecycle without changing
+// this package. Any field may be nil. Pre-hooks run before the operation and
+// stop it by returning an error; the other hooks run after it has happened and
+// their errors are audited but do not undo it.
+//
+// Hooks run synchronously while the system lock is held, in the order they were
+// registered. They receive copies and must not call back into the BWCSystem.
+type Hooks struct {
+	PreIngest          func(IngestRequest) error
+	PostIngest         func(Evidence) error
+	PreTransfer        func(TransferRequest) error
+	PostStatusChange   func(StatusChange) error
+	OnIntegrityFailure func(IntegrityFailure) error
+}
+
+// IngestRequest describes a recording about to be ingested. FileHash and
+// FileSize are of the source file.
+type IngestRequest struct {
+	FilePath    string
+	CaseNumber  string
+	OfficerID   string
+	OfficerName string
+	Location    string
+	Tags        []string
+	FileHash    string
+	FileSize    int64
+}
+
+// TransferRequest describes a custody transfer about to be recorded
+type TransferRequest struct {
+	Evidence    Evidence
+	FromOfficer string
+	ToOfficer   string
+	Purpose     string
+}
+
+// StatusChange describes a completed evidence status change
+type StatusChange struct {
+	Evidence       Evidence
+	PreviousStatus EvidenceStatus
+	ChangedBy      string
+	Notes          string
+}
+
+// IntegrityFailure describes an integrity check that found the file altered
+type IntegrityFailure struct {
+	Evidence Evidence
+	Check    IntegrityCheck
+}
+
+// hookRegistration is one set of registered hooks
+type hookRegistration struct {
+	id    int
+	name  string
+	hooks Hooks
+}
+
+// RegisterHooks adds hooks under name, which identifies them in errors and
+// audit entries. The returned function removes them again.
+func (bwc *BWCSystem) RegisterHooks(name string, hooks Hooks) (unregister func()) {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	bwc.hookSeq++
+	id := bwc.hookSeq
+	bwc.hooks = append(bwc.hooks, hookRegistration{id: id, name: name, hooks: hooks})
+
+	return func() {
+		bwc.mu.Lock()
+		defer bwc.mu.Unlock()
+
+		for i, reg := range bwc.hooks {
+			if reg.id == id {
+				bwc.hooks = append(bwc.hooks[:i:i], bwc.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// HookRejectedError is returned when a pre-hook stops an operation
+type HookRejectedError struct {
+	Hook  string
+	Point string
+	Err   error
+}
+
+func (e *HookRejectedError) Error() string {
+	return fmt.Sprintf("%s hook %s rejected the operation: %v", e.Point, e.Hook, e.Err)
+}
+
+func (e *HookRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// callHook runs one hook, turning a panic into an error so that a faulty hook
+// cannot take the system down
+func callHook(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hook panicked: %v", r)
+		}
+	}()
+	return fn()
+}
+
+// runPreHooksLocked runs a pre-hook from every registration, stopping at the
+// first rejection, which is audited; the caller must hold bwc.mu
+func (bwc *BWCSystem) runPreHooksLocked(point, userID, evidenceID string, call func(Hooks) func() error) error {
+	for _, reg := range bwc.hooks {
+		fn := call(reg.hooks)
+		if fn == nil {
+			continue
+		}
+		if err := callHook(fn); err != nil {
+			rejected := &HookRejectedError{Hook: reg.name, Point: point, Err: err}
+			bwc.logAudit(userID, "HOOK_REJECTED", evidenceID, rejected.Error(), "")
+			return rejected
+		}
+	}
+	return nil
+}
+
+// runPostHooksLocked runs a post-hook from every registration, auditing any
+// that fail; the caller must hold bwc.mu
+func (bwc *BWCSystem) runPostHooksLocked(point, userID, evidenceID string, call func(Hooks) func() error) {
+	for _, reg := range bwc.hooks {
+		fn := call(reg.hooks)
+		if fn == nil {
+			continue
+		}
+		if err := callHook(fn); err != nil {
+			bwc.logAudit(userID, "HOOK_FAILED", evidenceID, fmt.Sprintf("%s hook %s failed: %v", point, reg.name, err), "")
+		}
+	}
+}
+
+func (bwc *BWCSystem) preIngestHooksLocked(req IngestRequest) error {
+	return bwc.runPreHooksLocked("pre-ingest", req.OfficerID, "", func(h Hooks) func() error {
+		if h.PreIngest == nil {
+			return nil
+		}
+		return func() error {
+			r := req
+			r.Tags = append([]string(nil), req.Tags...)
+			return h.PreIngest(r)
+		}
+	})
+}
+
+func (bwc *BWCSystem) postIngestHooksLocked(evidence *Evidence) {
+	bwc.runPostHooksLocked("post-ingest", evidence.OfficerID, evidence.ID, func(h Hooks) func() error {
+		if h.PostIngest == nil {
+			return nil
+		}
+		return func() error { return h.PostIngest(copyEvidence(evidence)) }
+	})
+}
+
+func (bwc *BWCSystem) preTransferHooksLocked(evidence *Evidence, fromOfficer, toOfficer, purpose string) error {
+	return bwc.runPreHooksLocked("pre-transfer", fromOfficer, evidence.ID, func(h Hooks) func() error {
+		if h.PreTransfer == nil {
+			return nil
+		}
+		return func() error {
+			return h.PreTransfer(TransferRequest{Evidence: copyEvidence(evidence), FromOfficer: fromOfficer, ToOfficer: toOfficer, Purpose: purpose})
+		}
+	})
+}
+
+func (bwc *BWCSystem) postStatusChangeHooksLocked(evidence *Evidence, previous EvidenceStatus, changedBy, notes string) {
+	bwc.runPostHooksLocked("post-status-change", changedBy, evidence.ID, func(h Hooks) func() error {
+		if h.PostStatusChange == nil {
+			return nil
+		}
+		return func() error {
+			return h.PostStatusChange(StatusChange{Evidence: copyEvidence(evidence), PreviousStatus: previous, ChangedBy: changedBy, Notes: notes})
+		}
+	})
+}
+
+func (bwc *BWCSystem) integrityFailureHooksLocked(evidence *Evidence, check IntegrityCheck) {
+	bwc.runPostHooksLocked("on-integrity-failure", check.CheckedBy, evidence.ID, func(h Hooks) func() error {
+		if h.OnIntegrityFailure == nil {
+			return nil
+		}
+		return func() error {
+			return h.OnIntegrityFailure(IntegrityFailure{Evidence: copyEvidence(evidence), Check: check})
+		}
+	})
+}
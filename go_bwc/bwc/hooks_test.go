@@ -0,0 +1,162 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPreIngestHookRejects(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	errNoCase := errors.New("case number must start with CASE-")
+	var seen IngestRequest
+	system.RegisterHooks("case-format", Hooks{
+		PreIngest: func(req IngestRequest) error {
+			seen = req
+			if !strings.HasPrefix(req.CaseNumber, "CASE-") {
+				return errNoCase
+			}
+			return nil
+		},
+	})
+
+	testFile := createTestFile(t, tmpDir)
+	_, err := system.IngestEvidence(testFile, "2024-17", "OFF-921", "Officer Test", "Test Location", []string{"traffic"})
+	var rejected *HookRejectedError
+	if !errors.As(err, &rejected) || rejected.Hook != "case-format" || rejected.Point != "pre-ingest" || !errors.Is(err, errNoCase) {
+		t.Fatalf("Expected the hook to reject ingest, got %v", err)
+	}
+	want, _ := calculateFileHash(testFile)
+	if seen.FileHash != want || seen.OfficerID != "OFF-921" || len(seen.Tags) != 1 {
+		t.Errorf("Unexpected ingest request passed to hook: %+v", seen)
+	}
+	if n := len(system.SearchEvidence("", "", "")); n != 0 {
+		t.Errorf("Expected nothing ingested, got %d", n)
+	}
+	// Storage holds only the source file
+	if entries, _ := os.ReadDir(system.storagePath); len(entries) != 1 {
+		t.Errorf("Expected nothing copied to storage, got %d files", len(entries))
+	}
+	logs := system.GetAuditLogs("", "OFF-921")
+	if len(logs) != 1 || logs[0].Action != "HOOK_REJECTED" || !strings.Contains(logs[0].Details, "case-format") {
+		t.Errorf("Expected HOOK_REJECTED audit entry, got %v", logs)
+	}
+
+	if _, err := system.IngestEvidence(testFile, "CASE-HK-001", "OFF-922", "Officer Test", "Test Location", nil); err != nil {
+		t.Errorf("Expected a conforming ingest to pass: %v", err)
+	}
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	var calls []string
+	var ingested Evidence
+	var change StatusChange
+	var failure IntegrityFailure
+	system.RegisterHooks("recorder", Hooks{
+		PostIngest: func(ev Evidence) error {
+			calls = append(calls, "post-ingest")
+			ingested = ev
+			return nil
+		},
+		PreTransfer: func(req TransferRequest) error {
+			calls = append(calls, "pre-transfer")
+			if req.ToOfficer == "CIVILIAN-1" {
+				return errors.New("transfers must stay within the agency")
+			}
+			return nil
+		},
+		PostStatusChange: func(c StatusChange) error {
+			calls = append(calls, "post-status-change")
+			change = c
+			return nil
+		},
+		OnIntegrityFailure: func(f IntegrityFailure) error {
+			calls = append(calls, "on-integrity-failure")
+			failure = f
+			return errors.New("pager unreachable")
+		},
+	})
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-HK-002", "OFF-923", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if ingested.ID != evidence.ID {
+		t.Errorf("Expected post-ingest to see %s, got %q", evidence.ID, ingested.ID)
+	}
+	ingested.ChainOfCustody[0].Action = "ALTERED"
+	if evidence.ChainOfCustody[0].Action != "INGESTED" {
+		t.Error("Expected hooks to receive a copy of the evidence")
+	}
+
+	if err := system.TransferCustody(evidence.ID, "OFF-923", "CIVILIAN-1", "Review"); err == nil {
+		t.Error("Expected pre-transfer hook to block the transfer")
+	}
+	if len(evidence.ChainOfCustody) != 1 {
+		t.Error("Expected no custody entry for a rejected transfer")
+	}
+	if err := system.TransferCustody(evidence.ID, "OFF-923", "DET-1", "Review"); err != nil {
+		t.Errorf("Expected transfer to pass: %v", err)
+	}
+
+	system.UpdateStatus(evidence.ID, "DET-1", StatusAnalyzed, "Reviewed")
+	if change.PreviousStatus != StatusCollected || change.Evidence.Status != StatusAnalyzed || change.ChangedBy != "DET-1" {
+		t.Errorf("Unexpected status change: %+v", change)
+	}
+
+	os.WriteFile(evidence.FilePath, []byte("altered"), 0600)
+	system.VerifyIntegrity(evidence.ID, "AUDITOR-1")
+	if failure.Evidence.ID != evidence.ID || failure.Check.IsValid {
+		t.Errorf("Unexpected integrity failure: %+v", failure)
+	}
+	audited := false
+	for _, log := range system.GetAuditLogs(evidence.ID, "AUDITOR-1") {
+		if log.Action == "HOOK_FAILED" && strings.Contains(log.Details, "on-integrity-failure hook recorder failed: pager unreachable") {
+			audited = true
+		}
+	}
+	if !audited {
+		t.Error("Expected the failing post-hook audited as HOOK_FAILED")
+	}
+
+	want := "post-ingest pre-transfer pre-transfer post-status-change on-integrity-failure"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Expected hooks %q, got %q", want, got)
+	}
+}
+
+func TestHookPanicAndUnregister(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	var order []string
+	system.RegisterHooks("first", Hooks{PreIngest: func(IngestRequest) error {
+		order = append(order, "first")
+		return nil
+	}})
+	unregister := system.RegisterHooks("faulty", Hooks{PreIngest: func(IngestRequest) error {
+		order = append(order, "faulty")
+		panic("nil map")
+	}})
+
+	testFile := createTestFile(t, tmpDir)
+	_, err := system.IngestEvidence(testFile, "CASE-HK-003", "OFF-924", "Officer Test", "Test Location", nil)
+	if err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Fatalf("Expected the panic reported as a rejection, got %v", err)
+	}
+
+	unregister()
+	unregister()
+	if _, err := system.IngestEvidence(testFile, "CASE-HK-004", "OFF-925", "Officer Test", "Test Location", nil); err != nil {
+		t.Fatalf("Expected ingest to pass once the hook is removed: %v", err)
+	}
+	if got := strings.Join(order, " "); got != "first faulty first" {
+		t.Errorf("Unexpected hook order %q", got)
+	}
+}
@@ -0,0 +1,60 @@
+package bwc
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// openHSMKey returns a signer for the private key cfg names, whose public
+// half is public; it is set by builds with -tags pkcs11
+var openHSMKey func(cfg HSMConfig, public crypto.PublicKey) (crypto.Signer, error)
+
+// loadHSMReportSigner reads the agency's PEM certificate chain from certFile
+// and signs with the key for it that the hardware security module holds
+func loadHSMReportSigner(certFile string, cfg HSMConfig) (*reportSigner, error) {
+	if openHSMKey == nil {
+		return nil, errors.New("security.hsm needs a build with -tags pkcs11")
+	}
+	pair, err := loadCertificateChain(certFile)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid report signing certificate: %w", err)
+	}
+	key, err := openHSMKey(cfg, cert.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open report signing key in HSM: %w", err)
+	}
+	pair.PrivateKey = key
+	return newReportSigner(pair)
+}
+
+// loadCertificateChain reads a PEM certificate chain without its key
+func loadCertificateChain(certFile string) (tls.Certificate, error) {
+	var pair tls.Certificate
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return pair, fmt.Errorf("failed to load report signing certificate: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			pair.Certificate = append(pair.Certificate, block.Bytes)
+		}
+	}
+	if len(pair.Certificate) == 0 {
+		return pair, fmt.Errorf("no certificate found in %s", certFile)
+	}
+	return pair, nil
+}
@@ -0,0 +1,105 @@
+package bwc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeHSMKey stands in for a key held in a token: only the signer
+// interface is exposed, not the private key itself
+type fakeHSMKey struct {
+	key   crypto.Signer
+	signs int
+}
+
+func (k *fakeHSMKey) Public() crypto.PublicKey { return k.key.Public() }
+
+func (k *fakeHSMKey) Sign(r io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	k.signs++
+	return k.key.Sign(r, digest, opts)
+}
+
+func TestHSMConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Security.HSM = HSMConfig{Module: "/usr/lib/softhsm/libsofthsm2.so"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "security.hsm needs token_label and key_label") ||
+		!strings.Contains(err.Error(), "security.hsm needs security.report_signing_cert_file") {
+		t.Errorf("expected an incomplete HSM configuration to be refused, got %v", err)
+	}
+
+	cfg.Security.HSM.TokenLabel, cfg.Security.HSM.KeyLabel = "evidence", "report-signing"
+	cfg.Security.ReportSigningCertFile = "agency.pem"
+	cfg.Security.ReportSigningKeyFile = "agency.key"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "cannot both be set") {
+		t.Errorf("expected a key file alongside the HSM to be refused, got %v", err)
+	}
+
+	cfg.Security.ReportSigningKeyFile = ""
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected the HSM configuration to be valid, got %v", err)
+	}
+}
+
+func TestHSMReportSigning(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	certFile, keyFile, roots := writeTestAgencyCertificate(t, dir, key)
+	// The key lives only in the "token"
+	os.Remove(keyFile)
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(dir, "storage")
+	cfg.Security.ReportSigningCertFile = certFile
+	cfg.Security.HSM = HSMConfig{Module: "libtoken.so", TokenLabel: "evidence", PIN: "1234", KeyLabel: "report-signing"}
+
+	saved := openHSMKey
+	defer func() { openHSMKey = saved }()
+
+	openHSMKey = nil
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil || !strings.Contains(err.Error(), "-tags pkcs11") {
+		t.Errorf("expected a build without PKCS#11 to refuse the HSM, got %v", err)
+	}
+
+	hsmKey := &fakeHSMKey{key: key}
+	var opened HSMConfig
+	openHSMKey = func(cfg HSMConfig, public crypto.PublicKey) (crypto.Signer, error) {
+		opened = cfg
+		if !key.PublicKey.Equal(public) {
+			return nil, errors.New("no private key for the certificate")
+		}
+		return hsmKey, nil
+	}
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	if opened.PIN != "1234" || opened.KeyLabel != "report-signing" {
+		t.Errorf("expected the HSM to be opened with the configured token and key, got %+v", opened)
+	}
+
+	report := []byte("CHAIN OF CUSTODY REPORT\nCase: CASE-HSM-1\n")
+	signature, err := system.reportSigner.sign(report, time.Now())
+	if err != nil {
+		t.Fatalf("sign failed: %v", err)
+	}
+	if hsmKey.signs != 1 {
+		t.Errorf("expected the HSM to sign once, got %d", hsmKey.signs)
+	}
+	if _, err := VerifyReportSignature(report, signature, roots); err != nil {
+		t.Errorf("expected the HSM signature to verify: %v", err)
+	}
+}
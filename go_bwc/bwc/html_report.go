@@ -0,0 +1,239 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxThumbnailBytes bounds images embedded into HTML reports
+const maxThumbnailBytes = 2 * 1024 * 1024
+
+// thumbnailSuffixes are sidecar images looked up next to a stored evidence file.
+// Video frames cannot be extracted with the standard library, so capture or
+// ingest tooling drops a still alongside the file (e.g. BWC-...mp4.thumb.jpg).
+var thumbnailSuffixes = []string{".thumb.jpg", ".thumb.jpeg", ".thumb.png"}
+
+// htmlReport is the data passed to the HTML report template
+type htmlReport struct {
+	Title       string
+	Lang        Locale
+	CaseNumber  string
+	Profile     ReportProfile
+	GeneratedAt time.Time
+	Sections    reportSections
+	Items       []htmlReportItem
+	Tr          *translator
+}
+
+// htmlReportItem is one evidence record in an HTML report
+type htmlReportItem struct {
+	Evidence
+	// MediaType shadows the stored type so untyped records show as video
+	MediaType MediaType
+	Thumbnail template.URL
+}
+
+// GenerateHTMLReport renders a self-contained HTML case report with embedded
+// thumbnails and collapsible custody chains
+func (bwc *BWCSystem) GenerateHTMLReport(caseNumber string, opts ReportOptions) (string, error) {
+	return bwc.GenerateHTMLReportContext(systemContext(context.Background()), caseNumber, opts)
+}
+
+// GenerateHTMLReportContext renders the HTML case report of the evidence the
+// principal ctx carries may see
+func (bwc *BWCSystem) GenerateHTMLReportContext(ctx context.Context, caseNumber string, opts ReportOptions) (string, error) {
+	evidence, err := bwc.caseEvidence(ctx, caseNumber)
+	if err != nil {
+		return "", err
+	}
+
+	return renderHTMLReport("report.case_title", caseNumber, caseNumber, opts, evidence)
+}
+
+// GenerateCustodyHTMLReport renders a self-contained HTML custody report for one evidence item
+func (bwc *BWCSystem) GenerateCustodyHTMLReport(evidenceID string, opts ReportOptions) (string, error) {
+	bwc.mu.RLock()
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		bwc.mu.RUnlock()
+		return "", ErrEvidenceNotFound
+	}
+	ev := copyEvidence(evidence)
+	bwc.mu.RUnlock()
+
+	return renderHTMLReport("report.custody_title", ev.ID, ev.CaseNumber, opts, []Evidence{ev})
+}
+
+// renderHTMLReport renders evidence under the localized title titleKey formatted with subject
+func renderHTMLReport(titleKey, subject, caseNumber string, opts ReportOptions, evidence []Evidence) (string, error) {
+	profile, sections, tr, err := opts.resolve()
+	if err != nil {
+		return "", err
+	}
+
+	report := htmlReport{
+		Title:       tr.T(titleKey, subject),
+		Lang:        tr.locale,
+		CaseNumber:  caseNumber,
+		Profile:     profile,
+		GeneratedAt: time.Now(),
+		Sections:    sections,
+		Tr:          tr,
+	}
+	for _, ev := range evidence {
+		source := ev.FilePath
+		if ev.Photo != nil && ev.Photo.Thumbnail != nil {
+			source = ev.Photo.Thumbnail.Path
+		}
+		report.Items = append(report.Items, htmlReportItem{Evidence: ev, MediaType: mediaTypeOf(&ev), Thumbnail: thumbnailDataURL(source)})
+	}
+
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.Execute(&buf, report); err != nil {
+		return "", fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// thumbnailDataURL returns an embeddable data URL for the evidence file if it is
+// an image, otherwise for a sidecar thumbnail, or "" when neither exists
+func thumbnailDataURL(filePath string) template.URL {
+	candidates := []string{filePath}
+	for _, suffix := range thumbnailSuffixes {
+		candidates = append(candidates, filePath+suffix)
+	}
+
+	for _, path := range candidates {
+		data, ok := readImage(path)
+		if !ok {
+			continue
+		}
+		contentType := http.DetectContentType(data)
+		return template.URL("data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data))
+	}
+	return ""
+}
+
+// readImage reads path if it is a JPEG, PNG or GIF no larger than maxThumbnailBytes
+func readImage(path string) ([]byte, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxThumbnailBytes+1))
+	if err != nil || len(data) > maxThumbnailBytes {
+		return nil, false
+	}
+
+	switch http.DetectContentType(data) {
+	case "image/jpeg", "image/png", "image/gif":
+		return data, true
+	}
+	return nil, false
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"timestamp": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"join":      strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; color: #1d2330; margin: 2rem; }
+h1 { font-size: 1.4rem; margin-bottom: 0.2rem; }
+.meta { color: #5a6270; font-size: 0.85rem; margin-bottom: 1.5rem; }
+.item { border: 1px solid #d5d9e0; border-radius: 4px; padding: 1rem; margin-bottom: 1rem; page-break-inside: avoid; }
+.item h2 { font-size: 1.1rem; margin: 0 0 0.5rem; }
+.thumb { float: right; max-width: 240px; max-height: 180px; margin-left: 1rem; border: 1px solid #d5d9e0; }
+dl { display: grid; grid-template-columns: 10rem 1fr; gap: 0.2rem 1rem; margin: 0; }
+dt { font-weight: bold; }
+dd { margin: 0; word-break: break-all; }
+table { border-collapse: collapse; width: 100%; margin-top: 0.5rem; font-size: 0.85rem; }
+th, td { border: 1px solid #d5d9e0; padding: 0.3rem 0.5rem; text-align: left; }
+summary { cursor: pointer; margin-top: 0.8rem; font-weight: bold; }
+.failed { color: #b42318; font-weight: bold; }
+.sealed { background: #b42318; color: #fff; font-size: 0.75rem; padding: 0.1rem 0.4rem; border-radius: 3px; vertical-align: middle; }
+.certificate { margin-top: 2rem; font-size: 0.9rem; }
+.certificate h2 { font-size: 1rem; }
+@media print { details { display: block; } details > summary { display: none; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">{{.Tr.T "report.case_number"}}: {{.CaseNumber}} &middot; {{.Tr.T "report.profile"}}: {{.Profile}} &middot; {{.Tr.T "report.generated"}}: {{timestamp .GeneratedAt}} &middot; {{.Tr.T "report.total_items"}}: {{len .Items}}</div>
+{{range .Items}}
+<section class="item">
+{{if .Thumbnail}}<img class="thumb" src="{{.Thumbnail}}" alt="{{$.Tr.T "report.thumbnail" .ID}}">{{end}}
+<h2>{{.ID}}{{if .Seal}} <span class="sealed">{{$.Tr.T "report.sealed"}}</span>{{end}}</h2>
+<dl>
+{{with .Seal}}<dt>{{$.Tr.T "report.sealed"}}</dt><dd>{{$.Tr.T "report.sealed_detail" .Authority (timestamp .SealedAt)}}</dd>{{end}}
+{{if $.Sections.OfficerDetails}}<dt>{{$.Tr.T "report.officer"}}</dt><dd>{{.OfficerName}} ({{.OfficerID}})</dd>{{end}}
+<dt>{{$.Tr.T "report.timestamp"}}</dt><dd>{{timestamp .Timestamp}}</dd>
+<dt>{{$.Tr.T "report.location"}}</dt><dd>{{.Location}}</dd>
+<dt>{{$.Tr.T "report.media_type"}}</dt><dd>{{$.Tr.MediaType .MediaType}}</dd>
+{{with .Video}}<dt>{{$.Tr.T "report.video"}}</dt><dd>{{$.Tr.Video .}}</dd>{{end}}
+{{with .Audio}}<dt>{{$.Tr.T "report.audio"}}</dt><dd>{{$.Tr.Audio .}}</dd>{{end}}
+{{with .Photo}}<dt>{{$.Tr.T "report.photo"}}</dt><dd>{{$.Tr.Photo .}}</dd>
+{{if .TimeDiscrepancy}}<dt>{{$.Tr.T "report.time_discrepancy"}}</dt><dd>{{.TimeDiscrepancy}}</dd>{{end}}{{end}}
+{{with .Document}}<dt>{{$.Tr.T "report.document"}}</dt><dd>{{$.Tr.Document .}}</dd>{{end}}
+<dt>{{$.Tr.T "report.status"}}</dt><dd>{{$.Tr.Status .Status}}</dd>
+{{if .Tags}}<dt>{{$.Tr.T "report.tags"}}</dt><dd>{{join .Tags ", "}}</dd>{{end}}
+{{if $.Sections.FilePaths}}<dt>{{$.Tr.T "report.file_path"}}</dt><dd>{{.FilePath}}</dd>{{end}}
+<dt>{{$.Tr.T "report.file_hash"}}</dt><dd>{{.FileHash}}</dd>
+<dt>{{$.Tr.T "report.file_size"}}</dt><dd>{{$.Tr.T "report.bytes" .FileSize}}</dd>
+{{with .Forensics}}<dt>{{$.Tr.T "report.acquisition"}}</dt><dd>{{$.Tr.Acquisition . $.Sections.OfficerDetails}}</dd>
+{{with .OriginalMedia}}<dt>{{$.Tr.T "report.original_media"}}</dt><dd>{{$.Tr.OriginalMedia .}}</dd>{{end}}
+{{if .WriteBlocker}}<dt>{{$.Tr.T "report.write_blocker"}}</dt><dd>{{.WriteBlocker}}</dd>{{end}}
+<dt>{{$.Tr.T "report.tools"}}</dt><dd>{{$.Tr.Tools .Tools}}</dd>{{end}}
+{{with .Clock}}<dt>{{$.Tr.T "report.clock"}}</dt><dd>{{$.Tr.Clock .}}</dd>{{end}}
+{{if and $.Sections.Notes .Notes}}<dt>{{$.Tr.T "report.notes"}}</dt><dd>{{.Notes}}</dd>{{end}}
+</dl>
+<details>
+<summary>{{$.Tr.T "report.chain" (len .ChainOfCustody)}}</summary>
+<table>
+<tr><th>{{$.Tr.T "report.time"}}</th><th>{{$.Tr.T "report.action"}}</th>{{if $.Sections.OfficerDetails}}<th>{{$.Tr.T "report.from"}}</th><th>{{$.Tr.T "report.to"}}</th>{{end}}{{if $.Sections.Notes}}<th>{{$.Tr.T "report.purpose"}}</th>{{end}}<th>{{$.Tr.T "report.verified_hash"}}</th><th>{{$.Tr.T "report.signature"}}</th></tr>
+{{range .ChainOfCustody}}<tr><td>{{timestamp .Timestamp}}</td><td>{{$.Tr.Action .Action}}</td>{{if $.Sections.OfficerDetails}}<td>{{.FromOfficer}}</td><td>{{.ToOfficer}}</td>{{end}}{{if $.Sections.Notes}}<td>{{.Purpose}}</td>{{end}}<td>{{.VerifiedHash}}</td><td>{{with .Signature}}{{$.Tr.T (print "signature." .Type)}}{{if $.Sections.OfficerDetails}} ({{.SignerID}}){{end}}{{end}}</td></tr>
+{{end}}</table>
+</details>
+{{if .IntegrityChecks}}<details>
+<summary>{{$.Tr.T "report.checks" (len .IntegrityChecks)}}</summary>
+<table>
+<tr><th>{{$.Tr.T "report.time"}}</th>{{if $.Sections.OfficerDetails}}<th>{{$.Tr.T "report.checked_by"}}</th>{{end}}<th>{{$.Tr.T "report.result"}}</th>{{if $.Sections.Notes}}<th>{{$.Tr.T "report.notes"}}</th>{{end}}</tr>
+{{range .IntegrityChecks}}<tr><td>{{timestamp .Timestamp}}</td>{{if $.Sections.OfficerDetails}}<td>{{.CheckedBy}}</td>{{end}}<td>{{if .IsValid}}{{$.Tr.T "report.passed"}}{{else}}<span class="failed">{{$.Tr.T "report.failed"}}</span>{{end}}</td>{{if $.Sections.Notes}}<td>{{.Notes}}</td>{{end}}</tr>
+{{end}}</table>
+</details>{{end}}
+{{if .SealHistory}}<details>
+<summary>{{$.Tr.T "report.seal_history" (len .SealHistory)}}</summary>
+<table>
+<tr><th>{{$.Tr.T "report.time"}}</th><th>{{$.Tr.T "report.action"}}</th><th>{{$.Tr.T "report.authority"}}</th>{{if $.Sections.OfficerDetails}}<th>{{$.Tr.T "report.approved_by"}}</th>{{end}}</tr>
+{{range .SealHistory}}<tr><td>{{timestamp .Timestamp}}</td><td>{{$.Tr.Action .Action}}</td><td>{{.Authority}}</td>{{if $.Sections.OfficerDetails}}<td>{{if .RequestedBy}}{{.RequestedBy}} / {{.ApprovedBy}}{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</details>{{end}}
+{{if and $.Sections.Reviews .Reviews}}<details>
+<summary>{{$.Tr.T "report.reviews" (len .Reviews)}}</summary>
+<table>
+<tr><th>{{$.Tr.T "report.review"}}</th><th>{{$.Tr.T "report.reason"}}</th><th>{{$.Tr.T "report.state"}}</th><th>{{$.Tr.T "report.supervisor"}}</th><th>{{$.Tr.T "report.outcome"}}</th><th>{{$.Tr.T "report.findings"}}</th></tr>
+{{range .Reviews}}<tr><td>{{.ID}}</td><td>{{$.Tr.ReviewReason .Reason}}</td><td>{{$.Tr.ReviewState .State}}</td><td>{{.Supervisor}}</td><td>{{if .Outcome}}{{$.Tr.ReviewOutcome .Outcome}}{{end}}</td><td>{{.Findings}}</td></tr>
+{{end}}</table>
+</details>{{end}}
+</section>
+{{end}}
+<section class="certificate">
+<h2>{{.Tr.T "report.certificate_heading"}}</h2>
+<p>{{.Tr.T "report.certificate" .CaseNumber}}</p>
+</section>
+</body>
+</html>
+`))
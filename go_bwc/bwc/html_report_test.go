@@ -0,0 +1,71 @@
+package bwc
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateHTMLReport(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-HTML-001", "OFF-123", "Officer Test", "<script>alert(1)</script>", nil)
+	system.TransferCustody(evidence.ID, "OFF-123", "DET-456", "Analysis")
+
+	var thumb bytes.Buffer
+	png.Encode(&thumb, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	if err := os.WriteFile(evidence.FilePath+".thumb.png", thumb.Bytes(), 0600); err != nil {
+		t.Fatalf("Failed to write thumbnail: %v", err)
+	}
+
+	report, err := system.GenerateHTMLReport("CASE-HTML-001", ReportOptions{Profile: ReportProfileCourt})
+	if err != nil {
+		t.Fatalf("GenerateHTMLReport failed: %v", err)
+	}
+
+	for _, want := range []string{"<details>", "Chain of custody (2 entries)", "DET-456", "data:image/png;base64,", evidence.FileHash} {
+		if !strings.Contains(report, want) {
+			t.Errorf("HTML report should contain %q", want)
+		}
+	}
+	if strings.Contains(report, "<script>") {
+		t.Error("HTML report must escape evidence fields")
+	}
+	if strings.Contains(report, evidence.FilePath) {
+		t.Error("Court HTML report should not contain file paths")
+	}
+
+	public, _ := system.GenerateHTMLReport("CASE-HTML-001", ReportOptions{Profile: ReportProfilePublic})
+	if strings.Contains(public, "DET-456") || strings.Contains(public, "Analysis") {
+		t.Error("Public HTML report should omit officers and purposes from the custody chain")
+	}
+
+	if _, err := system.GenerateHTMLReport("CASE-NONEXISTENT", ReportOptions{Profile: ReportProfileCourt}); err == nil {
+		t.Error("Expected error for non-existent case")
+	}
+}
+
+func TestGenerateCustodyHTMLReport(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-HTML-002", "OFF-123", "Officer Test", "Test Location", nil)
+
+	report, err := system.GenerateCustodyHTMLReport(evidence.ID, ReportOptions{})
+	if err != nil {
+		t.Fatalf("GenerateCustodyHTMLReport failed: %v", err)
+	}
+	if !strings.Contains(report, "Custody Report "+evidence.ID) || strings.Contains(report, "<img") {
+		t.Error("Expected custody report without a thumbnail for non-image evidence")
+	}
+
+	if _, err := system.GenerateCustodyHTMLReport("INVALID-ID", ReportOptions{}); err == nil {
+		t.Error("Expected error for unknown evidence")
+	}
+}
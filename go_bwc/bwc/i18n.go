@@ -0,0 +1,563 @@
+package bwc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Locale identifies the language reports are rendered in
+type Locale string
+
+const (
+	LocaleEnglish Locale = "en"
+	LocaleSpanish Locale = "es"
+	LocaleFrench  Locale = "fr"
+)
+
+// DefaultLocale is used when a report does not select one
+const DefaultLocale = LocaleEnglish
+
+// reportMessages holds report text per locale. English is complete and is the
+// fallback for any key missing from another catalog.
+var reportMessages = map[Locale]map[string]string{
+	LocaleEnglish: {
+		"report.title":               "FORENSIC BWC EVIDENCE REPORT",
+		"report.case_title":          "Case Report %s",
+		"report.custody_title":       "Custody Report %s",
+		"report.case_number":         "Case Number",
+		"report.profile":             "Report Profile",
+		"report.generated":           "Report Generated",
+		"report.total_items":         "Total Evidence Items",
+		"report.evidence_id":         "Evidence ID",
+		"report.officer":             "Officer",
+		"report.timestamp":           "Timestamp",
+		"report.location":            "Location",
+		"report.audio":               "Audio",
+		"report.audio_detail":        "%s, %d Hz, %d channels, %.1f s",
+		"report.photo":               "Photo",
+		"report.time_discrepancy":    "Time discrepancy",
+		"report.media_type":          "Type",
+		"report.video":               "Video",
+		"report.video_detail":        "%s, %s %dx%d, %.2f fps, %.1f s",
+		"report.document":            "Document",
+		"report.document_words":      "%s, %d words extracted by %s",
+		"report.acquisition":         "Acquisition",
+		"report.acquisition_detail":  "%s by %s, %s",
+		"report.acquisition_time":    "%s, %s",
+		"report.original_media":      "Original Media",
+		"report.write_blocker":       "Write Blocker",
+		"report.tools":               "Tools",
+		"report.clock":               "Camera Clock",
+		"report.clock_ok":            "Plausible, recorded %s",
+		"report.clock_implausible":   "Implausible, recorded %s, off by at least %s",
+		"report.clock_corrected":     "%s after a device offset of %+d s",
+		"report.status":              "Status",
+		"report.tags":                "Tags",
+		"report.file_path":           "File Path",
+		"report.file_hash":           "File Hash",
+		"report.file_size":           "File Size",
+		"report.bytes":               "%d bytes",
+		"report.integrity":           "Integrity Checks",
+		"report.custody_entries":     "Chain of Custody Entries",
+		"report.notes":               "Notes",
+		"report.thumbnail":           "Thumbnail of %s",
+		"report.chain":               "Chain of custody (%d entries)",
+		"report.checks":              "Integrity checks (%d)",
+		"report.time":                "Time",
+		"report.action":              "Action",
+		"report.from":                "From",
+		"report.to":                  "To",
+		"report.purpose":             "Purpose",
+		"report.verified_hash":       "Verified hash",
+		"report.checked_by":          "Checked by",
+		"report.result":              "Result",
+		"report.passed":              "Passed",
+		"report.failed":              "FAILED",
+		"report.sealed":              "SEALED",
+		"report.sealed_detail":       "%s, %s",
+		"report.seal_history":        "Seal history (%d)",
+		"report.authority":           "Authority",
+		"report.approved_by":         "Requested / approved by",
+		"report.signature":           "Signature",
+		"report.certificate_heading": "Certification",
+		"report.certificate": "This report was generated by the evidence management system from its " +
+			"records for case %s. The SHA-256 hashes listed were recorded at ingest and identify the " +
+			"original files; any alteration of a file changes its hash.",
+
+		"media.VIDEO":    "Video",
+		"media.AUDIO":    "Audio",
+		"media.PHOTO":    "Photo",
+		"media.DOCUMENT": "Document",
+		"media.OTHER":    "Other",
+
+		"status.COLLECTED":  "Collected",
+		"status.PROCESSING": "Processing",
+		"status.ANALYZED":   "Analyzed",
+		"status.ARCHIVED":   "Archived",
+		"status.DELETED":    "Deleted",
+
+		"action.INGESTED":    "Ingested",
+		"action.TRANSFERRED": "Transferred",
+		"action.VERIFIED":    "Verified",
+		"action.ACCESSED":    "Accessed",
+		"action.EXPORTED":    "Exported",
+		"action.CHECKED_OUT": "Checked out",
+		"action.CHECKED_IN":  "Checked in",
+		"action.SEALED":      "Sealed",
+		"action.UNSEALED":    "Unsealed",
+
+		"signature.TYPED": "Typed acknowledgment",
+		"signature.IMAGE": "Signature image",
+		"signature.PIV":   "PIV card",
+
+		"report.reviews":    "Supervisor reviews (%d)",
+		"report.review":     "Review",
+		"report.reason":     "Reason",
+		"report.state":      "State",
+		"report.supervisor": "Supervisor",
+		"report.outcome":    "Outcome",
+		"report.findings":   "Findings",
+
+		"review.USE_OF_FORCE": "Use of force",
+		"review.COMPLAINT":    "Complaint",
+		"review.PURSUIT":      "Pursuit",
+		"review.RANDOM_AUDIT": "Random audit",
+
+		"review_state.FLAGGED":   "Flagged",
+		"review_state.ASSIGNED":  "Assigned",
+		"review_state.IN_REVIEW": "In review",
+		"review_state.CLOSED":    "Closed",
+
+		"outcome.WITHIN_POLICY":    "Within policy",
+		"outcome.POLICY_VIOLATION": "Policy violation",
+		"outcome.TRAINING_NEEDED":  "Training needed",
+		"outcome.UNFOUNDED":        "Unfounded",
+
+		"acquisition.DOCK_UPLOAD":      "Dock upload",
+		"acquisition.FILE_COPY":        "File copy",
+		"acquisition.FORENSIC_IMAGE":   "Forensic image",
+		"acquisition.NETWORK_TRANSFER": "Network transfer",
+	},
+	LocaleSpanish: {
+		"report.title":               "INFORME FORENSE DE EVIDENCIA BWC",
+		"report.case_title":          "Informe del caso %s",
+		"report.custody_title":       "Informe de custodia %s",
+		"report.case_number":         "Número de caso",
+		"report.profile":             "Perfil del informe",
+		"report.generated":           "Informe generado",
+		"report.total_items":         "Total de elementos de evidencia",
+		"report.evidence_id":         "ID de evidencia",
+		"report.officer":             "Agente",
+		"report.timestamp":           "Fecha y hora",
+		"report.location":            "Ubicación",
+		"report.audio":               "Audio",
+		"report.audio_detail":        "%s, %d Hz, %d canales, %.1f s",
+		"report.photo":               "Foto",
+		"report.time_discrepancy":    "Discrepancia horaria",
+		"report.media_type":          "Tipo",
+		"report.video":               "Vídeo",
+		"report.video_detail":        "%s, %s %dx%d, %.2f fps, %.1f s",
+		"report.document":            "Documento",
+		"report.document_words":      "%s, %d palabras extraídas por %s",
+		"report.acquisition":         "Adquisición",
+		"report.acquisition_detail":  "%s por %s, %s",
+		"report.acquisition_time":    "%s, %s",
+		"report.original_media":      "Soporte original",
+		"report.write_blocker":       "Bloqueador de escritura",
+		"report.tools":               "Herramientas",
+		"report.clock":               "Reloj de la cámara",
+		"report.clock_ok":            "Verosímil, grabado %s",
+		"report.clock_implausible":   "Inverosímil, grabado %s, desfasado al menos %s",
+		"report.clock_corrected":     "%s tras un desfase del dispositivo de %+d s",
+		"report.status":              "Estado",
+		"report.tags":                "Etiquetas",
+		"report.file_path":           "Ruta del archivo",
+		"report.file_hash":           "Hash del archivo",
+		"report.file_size":           "Tamaño del archivo",
+		"report.bytes":               "%d bytes",
+		"report.integrity":           "Verificaciones de integridad",
+		"report.custody_entries":     "Entradas de cadena de custodia",
+		"report.notes":               "Notas",
+		"report.thumbnail":           "Miniatura de %s",
+		"report.chain":               "Cadena de custodia (%d entradas)",
+		"report.checks":              "Verificaciones de integridad (%d)",
+		"report.time":                "Hora",
+		"report.action":              "Acción",
+		"report.from":                "De",
+		"report.to":                  "A",
+		"report.purpose":             "Propósito",
+		"report.verified_hash":       "Hash verificado",
+		"report.checked_by":          "Verificado por",
+		"report.result":              "Resultado",
+		"report.passed":              "Correcta",
+		"report.failed":              "FALLIDA",
+		"report.sealed":              "SELLADA",
+		"report.sealed_detail":       "%s, %s",
+		"report.seal_history":        "Historial de sellado (%d)",
+		"report.authority":           "Autoridad",
+		"report.approved_by":         "Solicitado / aprobado por",
+		"report.signature":           "Firma",
+		"report.certificate_heading": "Certificación",
+		"report.certificate": "Este informe fue generado por el sistema de gestión de evidencias a partir " +
+			"de sus registros del caso %s. Los hashes SHA-256 indicados se registraron al ingresar la " +
+			"evidencia e identifican los archivos originales; cualquier alteración de un archivo cambia su hash.",
+
+		"media.VIDEO":    "Vídeo",
+		"media.AUDIO":    "Audio",
+		"media.PHOTO":    "Foto",
+		"media.DOCUMENT": "Documento",
+		"media.OTHER":    "Otro",
+
+		"status.COLLECTED":  "Recolectada",
+		"status.PROCESSING": "En procesamiento",
+		"status.ANALYZED":   "Analizada",
+		"status.ARCHIVED":   "Archivada",
+		"status.DELETED":    "Eliminada",
+
+		"action.INGESTED":    "Ingresada",
+		"action.TRANSFERRED": "Transferida",
+		"action.VERIFIED":    "Verificada",
+		"action.ACCESSED":    "Consultada",
+		"action.EXPORTED":    "Exportada",
+		"action.CHECKED_OUT": "Prestada",
+		"action.CHECKED_IN":  "Devuelta",
+		"action.SEALED":      "Sellada",
+		"action.UNSEALED":    "Desellada",
+
+		"signature.TYPED": "Reconocimiento escrito",
+		"signature.IMAGE": "Imagen de firma",
+		"signature.PIV":   "Tarjeta PIV",
+
+		"report.reviews":    "Revisiones de supervisión (%d)",
+		"report.review":     "Revisión",
+		"report.reason":     "Motivo",
+		"report.state":      "Estado",
+		"report.supervisor": "Supervisor",
+		"report.outcome":    "Resultado",
+		"report.findings":   "Conclusiones",
+
+		"review.USE_OF_FORCE": "Uso de la fuerza",
+		"review.COMPLAINT":    "Queja",
+		"review.PURSUIT":      "Persecución",
+		"review.RANDOM_AUDIT": "Auditoría aleatoria",
+
+		"review_state.FLAGGED":   "Señalada",
+		"review_state.ASSIGNED":  "Asignada",
+		"review_state.IN_REVIEW": "En revisión",
+		"review_state.CLOSED":    "Cerrada",
+
+		"outcome.WITHIN_POLICY":    "Conforme a la política",
+		"outcome.POLICY_VIOLATION": "Infracción de la política",
+		"outcome.TRAINING_NEEDED":  "Requiere formación",
+		"outcome.UNFOUNDED":        "Infundada",
+
+		"acquisition.DOCK_UPLOAD":      "Descarga desde base",
+		"acquisition.FILE_COPY":        "Copia de archivo",
+		"acquisition.FORENSIC_IMAGE":   "Imagen forense",
+		"acquisition.NETWORK_TRANSFER": "Transferencia por red",
+	},
+	LocaleFrench: {
+		"report.title":               "RAPPORT MÉDICO-LÉGAL DE PREUVES BWC",
+		"report.case_title":          "Rapport de l'affaire %s",
+		"report.custody_title":       "Rapport de garde %s",
+		"report.case_number":         "Numéro d'affaire",
+		"report.profile":             "Profil du rapport",
+		"report.generated":           "Rapport généré",
+		"report.total_items":         "Nombre total de pièces",
+		"report.evidence_id":         "Identifiant de la pièce",
+		"report.officer":             "Agent",
+		"report.timestamp":           "Horodatage",
+		"report.location":            "Lieu",
+		"report.audio":               "Audio",
+		"report.audio_detail":        "%s, %d Hz, %d canaux, %.1f s",
+		"report.photo":               "Photo",
+		"report.time_discrepancy":    "Écart horaire",
+		"report.media_type":          "Type",
+		"report.video":               "Vidéo",
+		"report.video_detail":        "%s, %s %dx%d, %.2f i/s, %.1f s",
+		"report.document":            "Document",
+		"report.document_words":      "%s, %d mots extraits par %s",
+		"report.acquisition":         "Acquisition",
+		"report.acquisition_detail":  "%s par %s, %s",
+		"report.acquisition_time":    "%s, %s",
+		"report.original_media":      "Support d'origine",
+		"report.write_blocker":       "Bloqueur d'écriture",
+		"report.tools":               "Outils",
+		"report.clock":               "Horloge de la caméra",
+		"report.clock_ok":            "Plausible, enregistré %s",
+		"report.clock_implausible":   "Invraisemblable, enregistré %s, décalé d'au moins %s",
+		"report.clock_corrected":     "%s après un décalage de l'appareil de %+d s",
+		"report.status":              "Statut",
+		"report.tags":                "Étiquettes",
+		"report.file_path":           "Chemin du fichier",
+		"report.file_hash":           "Empreinte du fichier",
+		"report.file_size":           "Taille du fichier",
+		"report.bytes":               "%d octets",
+		"report.integrity":           "Contrôles d'intégrité",
+		"report.custody_entries":     "Entrées de la chaîne de garde",
+		"report.notes":               "Notes",
+		"report.thumbnail":           "Miniature de %s",
+		"report.chain":               "Chaîne de garde (%d entrées)",
+		"report.checks":              "Contrôles d'intégrité (%d)",
+		"report.time":                "Heure",
+		"report.action":              "Action",
+		"report.from":                "De",
+		"report.to":                  "À",
+		"report.purpose":             "Motif",
+		"report.verified_hash":       "Empreinte vérifiée",
+		"report.checked_by":          "Contrôlé par",
+		"report.result":              "Résultat",
+		"report.passed":              "Réussi",
+		"report.failed":              "ÉCHEC",
+		"report.sealed":              "SCELLÉE",
+		"report.sealed_detail":       "%s, %s",
+		"report.seal_history":        "Historique des scellés (%d)",
+		"report.authority":           "Autorité",
+		"report.approved_by":         "Demandé / approuvé par",
+		"report.signature":           "Signature",
+		"report.certificate_heading": "Certification",
+		"report.certificate": "Ce rapport a été généré par le système de gestion des preuves à partir de " +
+			"ses registres pour l'affaire %s. Les empreintes SHA-256 indiquées ont été enregistrées lors " +
+			"de l'intégration et identifient les fichiers originaux ; toute modification d'un fichier " +
+			"change son empreinte.",
+
+		"media.VIDEO":    "Vidéo",
+		"media.AUDIO":    "Audio",
+		"media.PHOTO":    "Photo",
+		"media.DOCUMENT": "Document",
+		"media.OTHER":    "Autre",
+
+		"status.COLLECTED":  "Collectée",
+		"status.PROCESSING": "En traitement",
+		"status.ANALYZED":   "Analysée",
+		"status.ARCHIVED":   "Archivée",
+		"status.DELETED":    "Supprimée",
+
+		"action.INGESTED":    "Intégrée",
+		"action.TRANSFERRED": "Transférée",
+		"action.VERIFIED":    "Vérifiée",
+		"action.ACCESSED":    "Consultée",
+		"action.EXPORTED":    "Exportée",
+		"action.CHECKED_OUT": "Sortie",
+		"action.CHECKED_IN":  "Restituée",
+		"action.SEALED":      "Scellée",
+		"action.UNSEALED":    "Descellée",
+
+		"signature.TYPED": "Accusé de réception saisi",
+		"signature.IMAGE": "Image de signature",
+		"signature.PIV":   "Carte PIV",
+
+		"report.reviews":    "Examens hiérarchiques (%d)",
+		"report.review":     "Examen",
+		"report.reason":     "Motif",
+		"report.state":      "État",
+		"report.supervisor": "Superviseur",
+		"report.outcome":    "Conclusion",
+		"report.findings":   "Constatations",
+
+		"review.USE_OF_FORCE": "Usage de la force",
+		"review.COMPLAINT":    "Plainte",
+		"review.PURSUIT":      "Poursuite",
+		"review.RANDOM_AUDIT": "Audit aléatoire",
+
+		"review_state.FLAGGED":   "Signalé",
+		"review_state.ASSIGNED":  "Attribué",
+		"review_state.IN_REVIEW": "En cours d'examen",
+		"review_state.CLOSED":    "Clos",
+
+		"outcome.WITHIN_POLICY":    "Conforme aux règles",
+		"outcome.POLICY_VIOLATION": "Manquement aux règles",
+		"outcome.TRAINING_NEEDED":  "Formation nécessaire",
+		"outcome.UNFOUNDED":        "Non fondé",
+
+		"acquisition.DOCK_UPLOAD":      "Téléversement depuis la station",
+		"acquisition.FILE_COPY":        "Copie de fichier",
+		"acquisition.FORENSIC_IMAGE":   "Image légale",
+		"acquisition.NETWORK_TRANSFER": "Transfert réseau",
+	},
+}
+
+// SupportedLocales returns the locales reports can be rendered in
+func SupportedLocales() []Locale {
+	locales := make([]Locale, 0, len(reportMessages))
+	for locale := range reportMessages {
+		locales = append(locales, locale)
+	}
+	sort.Slice(locales, func(i, j int) bool { return locales[i] < locales[j] })
+	return locales
+}
+
+// ParseLocale converts a language tag such as "es" or "fr-CA" to a supported locale.
+// An empty tag selects DefaultLocale.
+func ParseLocale(tag string) (Locale, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return DefaultLocale, nil
+	}
+
+	if i := strings.IndexAny(tag, "-_"); i > 0 {
+		tag = tag[:i]
+	}
+	if _, ok := reportMessages[Locale(tag)]; !ok {
+		return "", fmt.Errorf("unsupported locale %q (supported: %v)", tag, SupportedLocales())
+	}
+	return Locale(tag), nil
+}
+
+// translator renders report text in one locale
+type translator struct {
+	locale   Locale
+	messages map[string]string
+}
+
+func newTranslator(locale Locale) *translator {
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	return &translator{locale: locale, messages: reportMessages[locale]}
+}
+
+// T returns the message for key, formatted with args when given. Keys missing
+// from the locale fall back to English, then to the key itself.
+func (tr *translator) T(key string, args ...interface{}) string {
+	msg, ok := tr.messages[key]
+	if !ok {
+		if msg, ok = reportMessages[DefaultLocale][key]; !ok {
+			msg = key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(msg, args...)
+	}
+	return msg
+}
+
+// Status returns the localized name of an evidence status
+func (tr *translator) Status(status EvidenceStatus) string {
+	return tr.T("status." + string(status))
+}
+
+// MediaType returns the localized name of a media type
+func (tr *translator) MediaType(t MediaType) string {
+	return tr.T("media." + string(t))
+}
+
+// Video describes a video recording's format, codec, frame size, frame rate
+// and duration, or only its format when the details could not be read
+func (tr *translator) Video(video *VideoInfo) string {
+	if video.Codec == "" {
+		return video.Format
+	}
+	return tr.T("report.video_detail", video.Format, video.Codec, video.Width, video.Height, video.FrameRate, video.DurationSeconds)
+}
+
+// Audio describes an audio recording's format, or only its format when the
+// details could not be read
+func (tr *translator) Audio(audio *AudioInfo) string {
+	if audio.SampleRate == 0 {
+		return audio.Format
+	}
+	return tr.T("report.audio_detail", audio.Format, audio.SampleRate, audio.Channels, audio.DurationSeconds)
+}
+
+// Photo describes a photo's format, size, camera and EXIF capture time,
+// leaving out whatever is unknown
+func (tr *translator) Photo(photo *PhotoInfo) string {
+	parts := []string{photo.Format}
+	if photo.Width > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", photo.Width, photo.Height))
+	}
+	if camera := strings.TrimSpace(photo.CameraMake + " " + photo.CameraModel); camera != "" {
+		parts = append(parts, camera)
+	}
+	if photo.CaptureTime != nil {
+		parts = append(parts, photo.CaptureTime.Format(time.RFC3339))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Document describes a document's format and the text read from it, or only
+// its format when no text was extracted
+func (tr *translator) Document(document *DocumentInfo) string {
+	if document.Extractor == "" {
+		return document.Format
+	}
+	return tr.T("report.document_words", document.Format, document.Words, document.Extractor)
+}
+
+// Acquisition describes how evidence was acquired and when, and by whom when
+// withExaminer is set
+func (tr *translator) Acquisition(meta *ForensicMetadata, withExaminer bool) string {
+	method, at := tr.T("acquisition."+string(meta.AcquisitionMethod)), meta.AcquiredAt.Format(time.RFC3339)
+	if !withExaminer {
+		return tr.T("report.acquisition_time", method, at)
+	}
+	examiner := meta.Examiner
+	if meta.ExaminerName != "" {
+		examiner = meta.ExaminerName + " (" + meta.Examiner + ")"
+	}
+	return tr.T("report.acquisition_detail", method, examiner, at)
+}
+
+// OriginalMedia lists the known identifiers of the original device and media
+func (tr *translator) OriginalMedia(media *OriginalMedia) string {
+	parts := make([]string, 0, 3)
+	if device := strings.TrimSpace(media.DeviceMake + " " + media.DeviceModel); device != "" {
+		parts = append(parts, device)
+	}
+	if media.DeviceSerial != "" {
+		parts = append(parts, "S/N "+media.DeviceSerial)
+	}
+	if media.MediaSerial != "" {
+		parts = append(parts, "media S/N "+media.MediaSerial)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Tools lists the tools that acquired and verified evidence, with their versions
+func (tr *translator) Tools(tools []ForensicTool) string {
+	parts := make([]string, len(tools))
+	for i, tool := range tools {
+		parts[i] = strings.TrimSpace(tool.Name + " " + tool.Version)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Clock describes the outcome of a camera clock check, with the recorded time
+// corrected for any known device offset
+func (tr *translator) Clock(check *ClockCheck) string {
+	recorded := check.CorrectedAt.Format(time.RFC3339)
+	if check.OffsetSeconds != 0 {
+		recorded = tr.T("report.clock_corrected", recorded, check.OffsetSeconds)
+	}
+	if check.Plausible {
+		return tr.T("report.clock_ok", recorded)
+	}
+	drift := check.Drift()
+	if drift < 0 {
+		drift = -drift
+	}
+	return tr.T("report.clock_implausible", recorded, drift)
+}
+
+// ReviewReason returns the localized name of a review reason
+func (tr *translator) ReviewReason(reason ReviewReason) string {
+	return tr.T("review." + string(reason))
+}
+
+// ReviewState returns the localized name of a review state
+func (tr *translator) ReviewState(state ReviewState) string {
+	return tr.T("review_state." + string(state))
+}
+
+// ReviewOutcome returns the localized name of a review outcome
+func (tr *translator) ReviewOutcome(outcome ReviewOutcome) string {
+	return tr.T("outcome." + string(outcome))
+}
+
+// Action returns the localized name of a custody action
+func (tr *translator) Action(action string) string {
+	return tr.T("action." + action)
+}
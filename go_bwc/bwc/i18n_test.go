@@ -0,0 +1,86 @@
+package bwc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		tag     string
+		want    Locale
+		wantErr bool
+	}{
+		{"", LocaleEnglish, false},
+		{"es", LocaleSpanish, false},
+		{"fr-CA", LocaleFrench, false},
+		{" EN_gb ", LocaleEnglish, false},
+		{"de", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLocale(tt.tag)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLocale(%q) = %q, %v; want %q (error %v)", tt.tag, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTranslatorFallback(t *testing.T) {
+	tr := newTranslator(LocaleSpanish)
+	if got := tr.Status(StatusArchived); got != "Archivada" {
+		t.Errorf("Expected Spanish status name, got %q", got)
+	}
+
+	delete(reportMessages[LocaleSpanish], "report.tags")
+	defer func() { reportMessages[LocaleSpanish]["report.tags"] = "Etiquetas" }()
+	if got := tr.T("report.tags"); got != "Tags" {
+		t.Errorf("Expected English fallback, got %q", got)
+	}
+
+	if got := tr.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("Expected key as last resort, got %q", got)
+	}
+}
+
+func TestCatalogsCoverEnglishKeys(t *testing.T) {
+	for locale, messages := range reportMessages {
+		for key := range reportMessages[LocaleEnglish] {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("Locale %s is missing %s", locale, key)
+			}
+		}
+	}
+}
+
+func TestLocalizedReports(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-I18N-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	report, err := system.GenerateCaseReport("CASE-I18N-001", ReportOptions{Profile: ReportProfileCourt, Locale: LocaleSpanish})
+	if err != nil {
+		t.Fatalf("GenerateCaseReport failed: %v", err)
+	}
+	for _, want := range []string{"INFORME FORENSE DE EVIDENCIA BWC", "Número de caso: CASE-I18N-001", "Estado: Recolectada", "Certificación"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Spanish report should contain %q:\n%s", want, report)
+		}
+	}
+
+	html, err := system.GenerateCustodyHTMLReport(evidence.ID, ReportOptions{Locale: LocaleFrench})
+	if err != nil {
+		t.Fatalf("GenerateCustodyHTMLReport failed: %v", err)
+	}
+	for _, want := range []string{`<html lang="fr">`, "Rapport de garde " + evidence.ID, "Chaîne de garde (1 entrées)", "Intégrée"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("French HTML report should contain %q", want)
+		}
+	}
+
+	if _, err := system.GenerateCaseReport("CASE-I18N-001", ReportOptions{Locale: "de"}); err == nil {
+		t.Error("Expected error for unsupported locale")
+	}
+}
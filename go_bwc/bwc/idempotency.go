@@ -0,0 +1,172 @@
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// defaultIdempotencyTTL is how long an idempotency key is remembered
+	// when api.idempotency_key_ttl_hours is not set
+	defaultIdempotencyTTL = 24 * time.Hour
+	maxIdempotencyKeyLen  = 255
+)
+
+var (
+	errIdempotencyKeyReused = errors.New("idempotency key was already used for a different ingest")
+	errIngestInProgress     = errors.New("an ingest with this idempotency key is in progress")
+)
+
+// idempotentIngest remembers the ingest made under an idempotency key. The
+// evidence ID is empty while the first request is still running.
+type idempotentIngest struct {
+	fingerprint string
+	evidenceID  string
+	fileHash    string
+	createdAt   time.Time
+}
+
+// IngestEvidenceIdempotent ingests evidence under a client-supplied key, so a
+// dock that retries a request it saw fail does not create a second record
+// and copy. A retry with the same key, metadata and file content returns the
+// evidence from the first request with replayed set. Reusing a key for a
+// different ingest is refused, as is a retry while the first request is
+// still running. A failed ingest releases its key.
+func (bwc *BWCSystem) IngestEvidenceIdempotent(key, filePath, caseNumber, officerID, officerName, location string, tags []string) (evidence *Evidence, replayed bool, err error) {
+	return bwc.IngestEvidenceIdempotentContext(context.Background(), key, filePath, caseNumber, officerID, officerName, location, tags)
+}
+
+// IngestEvidenceIdempotentContext is IngestEvidenceIdempotent, stopping the
+// hash or copy when ctx is done. A stopped ingest releases its key.
+func (bwc *BWCSystem) IngestEvidenceIdempotentContext(ctx context.Context, key, filePath, caseNumber, officerID, officerName, location string, tags []string) (evidence *Evidence, replayed bool, err error) {
+	if err := validateIdempotencyKey(key); err != nil {
+		return nil, false, err
+	}
+	fingerprint := ingestFingerprint(caseNumber, officerID, officerName, location, tags)
+
+	bwc.mu.Lock()
+	bwc.expireIdempotencyKeysLocked()
+	rec, exists := bwc.idempotencyKeys[key]
+	if !exists {
+		bwc.idempotencyKeys[key] = &idempotentIngest{fingerprint: fingerprint, createdAt: time.Now()}
+	}
+	bwc.mu.Unlock()
+
+	if exists {
+		evidence, err := bwc.replayIngest(key, rec, fingerprint, filePath, officerID)
+		return evidence, err == nil, err
+	}
+
+	evidence, err = bwc.IngestEvidenceContext(ctx, filePath, caseNumber, officerID, officerName, location, tags)
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	if err != nil {
+		delete(bwc.idempotencyKeys, key)
+		return nil, false, err
+	}
+	rec = bwc.idempotencyKeys[key]
+	rec.evidenceID, rec.fileHash = evidence.ID, evidence.FileHash
+	return evidence, false, nil
+}
+
+// replayIngest answers a retried request from the record of the first one
+func (bwc *BWCSystem) replayIngest(key string, rec *idempotentIngest, fingerprint, filePath, officerID string) (*Evidence, error) {
+	bwc.mu.RLock()
+	evidenceID, fileHash, recorded := rec.evidenceID, rec.fileHash, rec.fingerprint
+	bwc.mu.RUnlock()
+	if evidenceID == "" {
+		return nil, errIngestInProgress
+	}
+	if recorded != fingerprint {
+		return nil, errIdempotencyKeyReused
+	}
+	// The file is hashed outside the lock; a retry carries the same content
+	hash, err := calculateFileHash(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file not found: %w", err)
+	}
+	if hash != fileHash {
+		return nil, errIdempotencyKeyReused
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	bwc.logAudit(officerID, "INGEST_REPLAYED", evidenceID,
+		fmt.Sprintf("Retried ingest with idempotency key %q returned the original record", key), "")
+	return evidence, nil
+}
+
+// expireIdempotencyKeysLocked forgets completed keys older than the
+// configured TTL; the caller must hold bwc.mu
+func (bwc *BWCSystem) expireIdempotencyKeysLocked() {
+	ttl := time.Duration(bwc.config.API.IdempotencyKeyTTLHours) * time.Hour
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	cutoff := time.Now().Add(-ttl)
+	for key, rec := range bwc.idempotencyKeys {
+		if rec.evidenceID != "" && rec.createdAt.Before(cutoff) {
+			delete(bwc.idempotencyKeys, key)
+		}
+	}
+}
+
+// receiveUpload writes an uploaded file to the uploads directory under
+// storage, keeping ext so the media type can be told. The caller removes it
+// once ingested.
+func (bwc *BWCSystem) receiveUpload(body io.Reader, ext string) (string, error) {
+	dir := filepath.Join(bwc.storagePath, "uploads")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, "upload-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to store upload: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// ingestFingerprint identifies the metadata of an ingest request
+func ingestFingerprint(caseNumber, officerID, officerName, location string, tags []string) string {
+	h := sha256.New()
+	for _, field := range append([]string{caseNumber, officerID, officerName, location}, tags...) {
+		fmt.Fprintf(h, "%d:%s\n", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func validateIdempotencyKey(key string) error {
+	if key == "" {
+		return errors.New("idempotency key is required")
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		return fmt.Errorf("idempotency key is longer than %d bytes", maxIdempotencyKeyLen)
+	}
+	if strings.IndexFunc(key, func(r rune) bool { return !unicode.IsPrint(r) || r == ' ' }) >= 0 {
+		return errors.New("idempotency key must be printable without spaces")
+	}
+	return nil
+}
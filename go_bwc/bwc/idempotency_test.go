@@ -0,0 +1,132 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIngestEvidenceIdempotent(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	first, replayed, err := system.IngestEvidenceIdempotent("dock-7-clip-0412", testFile, "CASE-IDM-001", "OFF-1021", "Officer Test", "Dock 7", []string{"patrol"})
+	if err != nil || replayed {
+		t.Fatalf("IngestEvidenceIdempotent failed: %v (replayed %v)", err, replayed)
+	}
+
+	retry, replayed, err := system.IngestEvidenceIdempotent("dock-7-clip-0412", testFile, "CASE-IDM-001", "OFF-1021", "Officer Test", "Dock 7", []string{"patrol"})
+	if err != nil || !replayed || retry.ID != first.ID {
+		t.Fatalf("Expected the original evidence replayed, got %v, %v", retry, err)
+	}
+	if n := len(system.SearchEvidence("CASE-IDM-001", "", "")); n != 1 {
+		t.Errorf("Expected one record after a retry, got %d", n)
+	}
+	logs := system.GetAuditLogs(first.ID, "OFF-1021")
+	if len(logs) != 2 || logs[1].Action != "INGEST_REPLAYED" {
+		t.Errorf("Expected INGEST_REPLAYED audit entry, got %v", logs)
+	}
+
+	if _, _, err := system.IngestEvidenceIdempotent("dock-7-clip-0412", testFile, "CASE-IDM-002", "OFF-1021", "Officer Test", "Dock 7", []string{"patrol"}); !errors.Is(err, errIdempotencyKeyReused) {
+		t.Errorf("Expected different metadata under the key to be refused, got %v", err)
+	}
+	other := filepath.Join(tmpDir, "other.mp4")
+	os.WriteFile(other, []byte("different footage"), 0600)
+	if _, _, err := system.IngestEvidenceIdempotent("dock-7-clip-0412", other, "CASE-IDM-001", "OFF-1021", "Officer Test", "Dock 7", []string{"patrol"}); !errors.Is(err, errIdempotencyKeyReused) {
+		t.Errorf("Expected a different file under the key to be refused, got %v", err)
+	}
+
+	for _, key := range []string{"", "has space", string(make([]byte, maxIdempotencyKeyLen+1))} {
+		if _, _, err := system.IngestEvidenceIdempotent(key, testFile, "CASE-IDM-001", "OFF-1022", "Officer Test", "Dock 7", nil); err == nil {
+			t.Errorf("Expected key %q to be refused", key)
+		}
+	}
+}
+
+func TestIdempotencyKeyLifecycle(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	if _, _, err := system.IngestEvidenceIdempotent("retry-after-failure", filepath.Join(tmpDir, "missing.mp4"), "CASE-IDM-003", "OFF-1023", "Officer Test", "Dock 7", nil); err == nil {
+		t.Fatal("Expected a missing file to fail")
+	}
+	testFile := createTestFile(t, tmpDir)
+	first, replayed, err := system.IngestEvidenceIdempotent("retry-after-failure", testFile, "CASE-IDM-003", "OFF-1023", "Officer Test", "Dock 7", nil)
+	if err != nil || replayed {
+		t.Fatalf("Expected a failed ingest to release its key: %v", err)
+	}
+
+	system.idempotencyKeys["in-flight"] = &idempotentIngest{fingerprint: "x", createdAt: time.Now()}
+	if _, _, err := system.IngestEvidenceIdempotent("in-flight", testFile, "CASE-IDM-003", "OFF-1024", "Officer Test", "Dock 7", nil); !errors.Is(err, errIngestInProgress) {
+		t.Errorf("Expected errIngestInProgress, got %v", err)
+	}
+
+	system.idempotencyKeys["retry-after-failure"].createdAt = time.Now().Add(-25 * time.Hour)
+	second, replayed, err := system.IngestEvidenceIdempotent("retry-after-failure", testFile, "CASE-IDM-003", "OFF-1025", "Officer Test", "Dock 7", nil)
+	if err != nil || replayed || second.ID == first.ID {
+		t.Errorf("Expected an expired key to ingest again, got %v, %v", second, err)
+	}
+}
+
+func TestServerIngestIdempotent(t *testing.T) {
+	system, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	post := func(key, query string, body []byte) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/evidence?"+query, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testAPIToken)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		return resp
+	}
+	query := "filename=clip.mp4&case=CASE-IDM-004&officer=OFF-1026&officer_name=Officer+Test&location=Dock+7&tags=patrol,night"
+	footage := []byte("dock upload footage")
+
+	var first, retry Evidence
+	resp := post("dock-7-upload-1", query, footage)
+	json.NewDecoder(resp.Body).Decode(&first)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || first.MediaType != MediaVideo || len(first.Tags) != 2 {
+		t.Fatalf("Expected the upload ingested, got %d %+v", resp.StatusCode, first)
+	}
+
+	resp = post("dock-7-upload-1", query, footage)
+	json.NewDecoder(resp.Body).Decode(&retry)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Idempotent-Replayed") != "true" || retry.ID != first.ID {
+		t.Errorf("Expected the original evidence replayed, got %d %s", resp.StatusCode, retry.ID)
+	}
+
+	resp = post("dock-7-upload-1", query, []byte("other footage"))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for a reused key, got %d", resp.StatusCode)
+	}
+	resp = post("", "case=CASE-IDM-004", footage)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a filename, got %d", resp.StatusCode)
+	}
+
+	system.config.Storage.MaxFileSizeMB = 1
+	resp = post("", query, make([]byte, 2<<20))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for an oversized upload, got %d", resp.StatusCode)
+	}
+
+	if uploads, _ := os.ReadDir(filepath.Join(system.storagePath, "uploads")); len(uploads) != 0 {
+		t.Errorf("Expected uploads to be cleaned up, found %d", len(uploads))
+	}
+}
@@ -0,0 +1,121 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// IdentifiersConfig holds the agency's numbering schemes. A policy left
+// empty accepts any identifier that passes the built-in validation.
+type IdentifiersConfig struct {
+	CaseNumber IdentifierPolicy `json:"case_number"`
+	OfficerID  IdentifierPolicy `json:"officer_id"`
+}
+
+// IdentifierPolicy is a numbering scheme, given either as a Format such as
+// CASE-YYYY-NNNN or as a regular expression Pattern. In a Format, YYYY is a
+// four-digit year, YY a two-digit year, each run of N that many digits and
+// anything else literal. A Pattern must match the whole identifier;
+// Description explains it in error messages.
+type IdentifierPolicy struct {
+	Format      string `json:"format,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// formatToken matches the placeholders of a policy Format
+var formatToken = regexp.MustCompile(`YYYY|YY|N+`)
+
+// compile returns the expression that identifiers must match, or nil when
+// the policy is empty
+func (p IdentifierPolicy) compile() (*regexp.Regexp, error) {
+	switch {
+	case p.Format != "" && p.Pattern != "":
+		return nil, errors.New("set format or pattern, not both")
+	case p.Pattern != "":
+		return regexp.Compile(`^(?:` + p.Pattern + `)$`)
+	case p.Format == "":
+		return nil, nil
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	last := 0
+	for _, loc := range formatToken.FindAllStringIndex(p.Format, -1) {
+		expr.WriteString(regexp.QuoteMeta(p.Format[last:loc[0]]))
+		switch token := p.Format[loc[0]:loc[1]]; token {
+		case "YYYY":
+			expr.WriteString(`(?:19|20)\d{2}`)
+		case "YY":
+			expr.WriteString(`\d{2}`)
+		default:
+			fmt.Fprintf(&expr, `\d{%d}`, len(token))
+		}
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(p.Format[last:]))
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+// describe names the scheme for error messages
+func (p IdentifierPolicy) describe() string {
+	switch {
+	case p.Description != "":
+		return p.Description
+	case p.Format != "":
+		return "the format " + p.Format
+	}
+	return "the pattern " + p.Pattern
+}
+
+// check refuses value when it does not follow the policy
+func (p IdentifierPolicy) check(field, value string) error {
+	re, err := p.compile()
+	if err != nil {
+		return fmt.Errorf("%s policy: %w", field, err)
+	}
+	if re != nil && !re.MatchString(value) {
+		return &ValidationError{Field: field, Value: value, Reason: "does not match " + p.describe()}
+	}
+	return nil
+}
+
+// CheckCaseNumber validates a case number and applies the case number policy
+func (c IdentifiersConfig) CheckCaseNumber(caseNumber string) error {
+	if err := ValidateCaseNumber(caseNumber); err != nil {
+		return err
+	}
+	return c.CaseNumber.check("case number", caseNumber)
+}
+
+// CheckOfficerID validates an officer ID and applies the officer ID policy
+func (c IdentifiersConfig) CheckOfficerID(officerID string) error {
+	if err := ValidateOfficerID(officerID); err != nil {
+		return err
+	}
+	return c.OfficerID.check("officer ID", officerID)
+}
+
+// checkTransferOfficers validates both parties to a custody hand-off and
+// applies the officer ID policy to the recipient. The releasing officer may
+// hold an ID recorded before the policy was introduced.
+func (bwc *BWCSystem) checkTransferOfficers(fromOfficer, toOfficer string) error {
+	if err := ValidateOfficerID(fromOfficer); err != nil {
+		return err
+	}
+	return bwc.config.Identifiers.CheckOfficerID(toOfficer)
+}
+
+// validateIdentifiers checks that the identifier policies compile
+func (c *Config) validateIdentifiers(problems []string) []string {
+	if _, err := c.Identifiers.CaseNumber.compile(); err != nil {
+		problems = append(problems, fmt.Sprintf("identifiers.case_number: %v", err))
+	}
+	if _, err := c.Identifiers.OfficerID.compile(); err != nil {
+		problems = append(problems, fmt.Sprintf("identifiers.officer_id: %v", err))
+	}
+	return problems
+}
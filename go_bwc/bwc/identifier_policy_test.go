@@ -0,0 +1,103 @@
+package bwc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIdentifierPolicyFormat(t *testing.T) {
+	policy := IdentifierPolicy{Format: "CASE-YYYY-NNNN"}
+
+	for _, v := range []string{"CASE-2024-0001", "CASE-1999-9999"} {
+		if err := policy.check("case number", v); err != nil {
+			t.Errorf("expected %q to match, got %v", v, err)
+		}
+	}
+	for _, v := range []string{"CASE-24-0001", "CASE-2024-001", "CASE-2024-00011", "case-2024-0001", "CASE-3024-0001", "XCASE-2024-0001"} {
+		err := policy.check("case number", v)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !strings.Contains(err.Error(), "does not match the format CASE-YYYY-NNNN") {
+			t.Errorf("expected %q to be refused with the format named, got %v", v, err)
+		}
+	}
+
+	// Literal regexp characters in a format are matched as written
+	dotted := IdentifierPolicy{Format: "RPT.YY/NNN"}
+	if err := dotted.check("case number", "RPT.24/123"); err != nil {
+		t.Errorf("expected a match, got %v", err)
+	}
+	if err := dotted.check("case number", "RPTx24/123"); err == nil {
+		t.Error("expected '.' to be literal")
+	}
+}
+
+func TestIdentifierPolicyPattern(t *testing.T) {
+	policy := IdentifierPolicy{Pattern: "OFF-[0-9]{3,6}", Description: "OFF- followed by 3 to 6 digits"}
+
+	if err := policy.check("officer ID", "OFF-1100"); err != nil {
+		t.Errorf("expected a match, got %v", err)
+	}
+	// The pattern is anchored to the whole identifier
+	err := policy.check("officer ID", "XOFF-1100Y")
+	if err == nil || !strings.Contains(err.Error(), "does not match OFF- followed by 3 to 6 digits") {
+		t.Errorf("expected the description in the error, got %v", err)
+	}
+
+	if err := (IdentifierPolicy{}).check("officer ID", "anything"); err != nil {
+		t.Errorf("expected an empty policy to accept anything, got %v", err)
+	}
+}
+
+func TestIdentifierPolicyConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Identifiers.CaseNumber = IdentifierPolicy{Format: "CASE-NNNN", Pattern: "CASE-.*"}
+	cfg.Identifiers.OfficerID = IdentifierPolicy{Pattern: "OFF-[0-9"}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected invalid policies to be reported")
+	}
+	for _, want := range []string{"identifiers.case_number: set format or pattern, not both", "identifiers.officer_id:"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestIdentifierPoliciesEnforcedAtIngestAndTransfer(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Identifiers = IdentifiersConfig{
+		CaseNumber: IdentifierPolicy{Format: "CASE-YYYY-NNNN"},
+		OfficerID:  IdentifierPolicy{Pattern: "(OFF|LAB)-[0-9]{4}"},
+	}
+	file := createTestFile(t, tmpDir)
+
+	if _, err := system.IngestEvidence(file, "2024/17", "OFF-1100", "", "", nil); err == nil {
+		t.Error("expected a non-conforming case number to be refused")
+	}
+	if _, err := system.IngestEvidence(file, "CASE-2024-0017", "badge 12", "", "", nil); err == nil || !strings.Contains(err.Error(), "officer ID") {
+		t.Errorf("expected a non-conforming officer ID to be refused, got %v", err)
+	}
+
+	ev, err := system.IngestEvidence(file, "CASE-2024-0017", "OFF-1100", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	if err := system.TransferCustody(ev.ID, "OFF-1100", "Crime Lab", "Analysis"); err == nil {
+		t.Error("expected a transfer to a non-conforming officer ID to be refused")
+	}
+	if _, err := system.TransferCustodyBatch([]string{ev.ID}, "OFF-1100", "Crime Lab", "Analysis"); err == nil {
+		t.Error("expected a batch transfer to a non-conforming officer ID to be refused")
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1100", "LAB-0001", "Analysis"); err != nil {
+		t.Errorf("expected a conforming transfer to succeed, got %v", err)
+	}
+
+	// Custody held under an ID from before the policy can still be handed on
+	if err := system.TransferCustody(ev.ID, "legacy-7", "OFF-1100", "Return"); err != nil {
+		t.Errorf("expected a hand-off from a legacy ID to succeed, got %v", err)
+	}
+}
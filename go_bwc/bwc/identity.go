@@ -0,0 +1,430 @@
+package bwc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// credentialsFile holds the users' password hashes and API key digests. It
+// is kept apart from users.json so listing users never touches them.
+const credentialsFile = "credentials.json"
+
+const (
+	// passwordScryptN is the scrypt cost of new password hashes
+	passwordScryptN = 1 << 15
+	// loginLockout is how long an account is locked after
+	// security.max_login_attempts failed passwords in a row
+	loginLockout = 15 * time.Minute
+)
+
+// AuthMethod is how a principal proved who they are
+type AuthMethod string
+
+const (
+	AuthPassword AuthMethod = "password"
+	AuthAPIKey   AuthMethod = "api_key"
+	// AuthConfigToken is an API token listed in api.credentials
+	AuthConfigToken AuthMethod = "config_token"
+)
+
+// Authentication is recorded on the custody and audit entries of an
+// operation run for an authenticated principal
+type Authentication struct {
+	UserID string     `json:"user_id"`
+	Method AuthMethod `json:"method"`
+	// CredentialID names the API key used, when one was
+	CredentialID string `json:"credential_id,omitempty"`
+}
+
+// Principal is a user the system has authenticated
+type Principal struct {
+	UserID          string     `json:"user_id"`
+	Role            Role       `json:"role,omitempty"`
+	Method          AuthMethod `json:"method"`
+	CredentialID    string     `json:"credential_id,omitempty"`
+	AuthenticatedAt time.Time  `json:"authenticated_at"`
+}
+
+// authentication is what entries record of p; nil for no principal
+func (p *Principal) authentication() *Authentication {
+	if p == nil {
+		return nil
+	}
+	return &Authentication{UserID: p.UserID, Method: p.Method, CredentialID: p.CredentialID}
+}
+
+// APIKey is a key a user authenticates to the API with. Only its digest is
+// kept; the token is shown once, when it is issued.
+type APIKey struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  string     `json:"created_by"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  string     `json:"revoked_by,omitempty"`
+}
+
+// apiKeyRecord is an API key with the digest of its token
+type apiKeyRecord struct {
+	APIKey
+	TokenSHA256 string `json:"token_sha256"`
+}
+
+// passwordRecord is a user's scrypt password hash and recent failures
+type passwordRecord struct {
+	Salt         []byte     `json:"salt"`
+	Hash         []byte     `json:"hash"`
+	ScryptN      int        `json:"scrypt_n"`
+	SetAt        time.Time  `json:"set_at"`
+	FailedLogins int        `json:"failed_logins,omitempty"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+// storedCredentials is the content of credentials.json
+type storedCredentials struct {
+	Passwords map[string]*passwordRecord `json:"passwords"`
+	APIKeys   []*apiKeyRecord            `json:"api_keys"`
+}
+
+// dummyPassword is hashed in place of the password of a user who has none,
+// so they are refused no faster than a wrong password
+var dummyPassword = &passwordRecord{Salt: make([]byte, 16), Hash: make([]byte, 32), ScryptN: passwordScryptN}
+
+// ErrUnauthenticated is matched by credentials the system does not accept.
+// The message never says which part was wrong.
+var ErrUnauthenticated = &BWCError{Code: CodeUnauthenticated, Message: "invalid credentials"}
+
+// principalKey carries a *Principal in a context
+type principalKey struct{}
+
+// ContextWithPrincipal returns ctx carrying p. The Context methods check
+// permissions against it and record it on the entries they make.
+func ContextWithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal ctx carries, or nil
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// actingUser returns who an operation naming userID acts as: the principal
+// ctx carries if it has one, and what to record of them
+func actingUser(ctx context.Context, userID string) (string, *Authentication) {
+	if p := PrincipalFromContext(ctx); p != nil {
+		return p.UserID, p.authentication()
+	}
+	return userID, nil
+}
+
+// loadCredentials reads the password hashes and API keys from storage
+func (bwc *BWCSystem) loadCredentials() error {
+	data, err := os.ReadFile(filepath.Join(bwc.storagePath, credentialsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	var stored storedCredentials
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("%s: %w", credentialsFile, err)
+	}
+	for userID, record := range stored.Passwords {
+		bwc.passwords[userID] = record
+	}
+	for _, key := range stored.APIKeys {
+		bwc.apiKeys[key.ID] = key
+	}
+	return nil
+}
+
+// saveCredentialsLocked writes the password hashes and API keys. The caller
+// must hold bwc.usersMu.
+func (bwc *BWCSystem) saveCredentialsLocked() error {
+	stored := storedCredentials{Passwords: bwc.passwords, APIKeys: make([]*apiKeyRecord, 0, len(bwc.apiKeys))}
+	for _, key := range bwc.apiKeys {
+		stored.APIKeys = append(stored.APIKeys, key)
+	}
+	sort.Slice(stored.APIKeys, func(i, j int) bool { return stored.APIKeys[i].ID < stored.APIKeys[j].ID })
+	return writeSnapshot(filepath.Join(bwc.storagePath, credentialsFile), stored)
+}
+
+// authorizeSelfOr allows userID to act on their own credentials, and anyone
+// else only with manage_users
+func (bwc *BWCSystem) authorizeSelfOr(actorID, userID, operation string) error {
+	if actorID == userID {
+		return nil
+	}
+	return bwc.authorize(actorID, PermManageUsers, operation, "")
+}
+
+// SetPassword sets the password userID signs in with. Users may set their
+// own, giving their current password if they have one; setting another's
+// needs manage_users. It also clears a lockout.
+func (bwc *BWCSystem) SetPassword(userID, currentPassword, password, setBy string) error {
+	return bwc.SetPasswordContext(context.Background(), userID, currentPassword, password, setBy)
+}
+
+// SetPasswordContext sets a password like SetPassword, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) SetPasswordContext(ctx context.Context, userID, currentPassword, password, setBy string) error {
+	setBy, auth := actingUser(ctx, setBy)
+	if min := bwc.config.Security.PasswordMinLength; len(password) < min {
+		return &ValidationError{Field: "password", Reason: fmt.Sprintf("must be at least %d characters", min)}
+	}
+	if err := bwc.authorizeSelfOr(setBy, userID, "Set password"); err != nil {
+		return err
+	}
+	if setBy == userID {
+		bwc.usersMu.RLock()
+		_, hasPassword := bwc.passwords[userID]
+		bwc.usersMu.RUnlock()
+		// A wrong current password counts towards the lockout like a failed login
+		if hasPassword {
+			if _, err := bwc.AuthenticatePassword(userID, currentPassword, ""); err != nil {
+				return err
+			}
+		}
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	hash, err := scrypt.Key([]byte(password), salt, passwordScryptN, 8, 1, 32)
+	if err != nil {
+		return err
+	}
+
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+	if bwc.users[userID] == nil {
+		return &BWCError{Code: CodeNotFound, Message: fmt.Sprintf("user %s is not registered", userID)}
+	}
+	previous := bwc.passwords[userID]
+	bwc.passwords[userID] = &passwordRecord{Salt: salt, Hash: hash, ScryptN: passwordScryptN, SetAt: time.Now()}
+	if err := bwc.saveCredentialsLocked(); err != nil {
+		bwc.passwords[userID] = previous
+		return err
+	}
+
+	bwc.logAuditAs(auth, setBy, "SET_PASSWORD", "", fmt.Sprintf("Password set for %s", userID), "")
+	return nil
+}
+
+// IssueAPIKey issues userID a new API key and returns its token, which is
+// not kept and cannot be shown again. Users may issue their own; issuing
+// for another needs manage_users.
+func (bwc *BWCSystem) IssueAPIKey(userID, name, issuedBy string) (string, *APIKey, error) {
+	return bwc.IssueAPIKeyContext(context.Background(), userID, name, issuedBy)
+}
+
+// IssueAPIKeyContext issues like IssueAPIKey, as the principal ctx carries
+// when it has one
+func (bwc *BWCSystem) IssueAPIKeyContext(ctx context.Context, userID, name, issuedBy string) (string, *APIKey, error) {
+	issuedBy, auth := actingUser(ctx, issuedBy)
+	if err := bwc.authorizeSelfOr(issuedBy, userID, "Issue API key"); err != nil {
+		return "", nil, err
+	}
+	token, digest, err := newAPIToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+	user := bwc.users[userID]
+	if user == nil {
+		return "", nil, &BWCError{Code: CodeNotFound, Message: fmt.Sprintf("user %s is not registered", userID)}
+	}
+	if !user.Active {
+		return "", nil, fmt.Errorf("user %s is deactivated", userID)
+	}
+
+	key := &apiKeyRecord{
+		APIKey: APIKey{
+			ID:        "ak-" + digest[:12],
+			UserID:    userID,
+			Name:      name,
+			CreatedAt: time.Now(),
+			CreatedBy: issuedBy,
+		},
+		TokenSHA256: digest,
+	}
+	bwc.apiKeys[key.ID] = key
+	if err := bwc.saveCredentialsLocked(); err != nil {
+		delete(bwc.apiKeys, key.ID)
+		return "", nil, err
+	}
+
+	bwc.logAuditAs(auth, issuedBy, "ISSUE_API_KEY", "", fmt.Sprintf("API key %s issued to %s", key.ID, userID), "")
+	issued := key.APIKey
+	return token, &issued, nil
+}
+
+// RevokeAPIKey stops an API key authenticating. Users may revoke their own;
+// revoking another's needs manage_users.
+func (bwc *BWCSystem) RevokeAPIKey(keyID, revokedBy string) error {
+	return bwc.RevokeAPIKeyContext(context.Background(), keyID, revokedBy)
+}
+
+// RevokeAPIKeyContext revokes like RevokeAPIKey, as the principal ctx carries
+// when it has one
+func (bwc *BWCSystem) RevokeAPIKeyContext(ctx context.Context, keyID, revokedBy string) error {
+	revokedBy, auth := actingUser(ctx, revokedBy)
+	bwc.usersMu.RLock()
+	key := bwc.apiKeys[keyID]
+	bwc.usersMu.RUnlock()
+	if key == nil {
+		return &BWCError{Code: CodeNotFound, Message: fmt.Sprintf("API key %s not found", keyID)}
+	}
+	if err := bwc.authorizeSelfOr(revokedBy, key.UserID, "Revoke API key"); err != nil {
+		return err
+	}
+
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+	if key.RevokedAt != nil {
+		return fmt.Errorf("API key %s is already revoked", keyID)
+	}
+	now := time.Now()
+	key.RevokedAt, key.RevokedBy = &now, revokedBy
+	if err := bwc.saveCredentialsLocked(); err != nil {
+		key.RevokedAt, key.RevokedBy = nil, ""
+		return err
+	}
+
+	bwc.logAuditAs(auth, revokedBy, "REVOKE_API_KEY", "", fmt.Sprintf("API key %s of %s revoked", keyID, key.UserID), "")
+	return nil
+}
+
+// APIKeys lists the API keys issued to userID, revoked ones included
+func (bwc *BWCSystem) APIKeys(userID string) []APIKey {
+	bwc.usersMu.RLock()
+	defer bwc.usersMu.RUnlock()
+
+	keys := make([]APIKey, 0)
+	for _, key := range bwc.apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, key.APIKey)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// principalLocked returns the principal for an active userID. The caller
+// must hold bwc.usersMu.
+func (bwc *BWCSystem) principalLocked(userID string, method AuthMethod, credentialID string) (*Principal, error) {
+	user := bwc.users[userID]
+	if user == nil || !user.Active {
+		return nil, ErrUnauthenticated
+	}
+	return &Principal{
+		UserID:          userID,
+		Role:            user.Role,
+		Method:          method,
+		CredentialID:    credentialID,
+		AuthenticatedAt: time.Now(),
+	}, nil
+}
+
+// AuthenticatePassword checks userID's password. After
+// security.max_login_attempts failures in a row the account is locked for
+// 15 minutes, which is audited as ACCOUNT_LOCKED. Callers audit other
+// failures, as the API server does.
+func (bwc *BWCSystem) AuthenticatePassword(userID, password, ipAddress string) (*Principal, error) {
+	// scrypt is slow on purpose, so it runs without usersMu held. A record
+	// is replaced, never changed, when a password is set.
+	bwc.usersMu.RLock()
+	record := bwc.passwords[userID]
+	bwc.usersMu.RUnlock()
+	hashed := record
+	if hashed == nil {
+		hashed = dummyPassword
+	}
+	hash, err := scrypt.Key([]byte(password), hashed.Salt, hashed.ScryptN, 8, 1, len(hashed.Hash))
+	if err != nil {
+		return nil, err
+	}
+
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+
+	now := time.Now()
+	// The password may have been set again while it was hashed
+	if record == nil || bwc.passwords[userID] != record || (record.LockedUntil != nil && now.Before(*record.LockedUntil)) {
+		return nil, ErrUnauthenticated
+	}
+	if subtle.ConstantTimeCompare(hash, record.Hash) != 1 {
+		record.FailedLogins++
+		if max := bwc.config.Security.MaxLoginAttempts; max > 0 && record.FailedLogins >= max {
+			until := now.Add(loginLockout)
+			record.LockedUntil, record.FailedLogins = &until, 0
+			bwc.logAudit(userID, "ACCOUNT_LOCKED", "",
+				fmt.Sprintf("%d failed passwords in a row; locked until %s", max, until.Format(time.RFC3339)), ipAddress)
+		}
+		// The count is kept in memory even if it cannot be saved
+		if err := bwc.saveCredentialsLocked(); err != nil {
+			return nil, err
+		}
+		return nil, ErrUnauthenticated
+	}
+
+	principal, err := bwc.principalLocked(userID, AuthPassword, "")
+	if err != nil {
+		return nil, err
+	}
+	if record.FailedLogins > 0 || record.LockedUntil != nil {
+		failed, locked := record.FailedLogins, record.LockedUntil
+		record.FailedLogins, record.LockedUntil = 0, nil
+		if err := bwc.saveCredentialsLocked(); err != nil {
+			record.FailedLogins, record.LockedUntil = failed, locked
+			return nil, err
+		}
+	}
+	return principal, nil
+}
+
+// AuthenticateAPIKey returns the principal an API key token belongs to, if
+// the key is not revoked and its user is active
+func (bwc *BWCSystem) AuthenticateAPIKey(token string) (*Principal, error) {
+	sum := sha256.Sum256([]byte(token))
+	digest := hex.EncodeToString(sum[:])
+
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+
+	for _, key := range bwc.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(digest), []byte(key.TokenSHA256)) != 1 {
+			continue
+		}
+		if key.RevokedAt != nil {
+			break
+		}
+		principal, err := bwc.principalLocked(key.UserID, AuthAPIKey, key.ID)
+		if err != nil {
+			break
+		}
+		now := time.Now()
+		key.LastUsedAt = &now
+		return principal, nil
+	}
+	return nil, ErrUnauthenticated
+}
@@ -0,0 +1,309 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordAuthenticationAndLockout(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	if _, err := system.RegisterUser("DET-3001", "Jordan Lee", RoleDetective, "ADM-001"); err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+	if err := system.SetPassword("DET-3001", "", "short", "DET-3001"); err == nil {
+		t.Error("expected a short password to be refused")
+	}
+	if err := system.SetPassword("DET-3001", "", "correct horse battery", "DET-3002"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected another user to need manage_users, got %v", err)
+	}
+	if err := system.SetPassword("DET-3001", "", "correct horse battery", "DET-3001"); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+
+	principal, err := system.AuthenticatePassword("DET-3001", "correct horse battery", "10.0.0.1")
+	if err != nil || principal.UserID != "DET-3001" || principal.Role != RoleDetective || principal.Method != AuthPassword {
+		t.Fatalf("unexpected principal %+v %v", principal, err)
+	}
+	if _, err := system.AuthenticatePassword("DET-3001", "wrong", ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected a wrong password to be refused, got %v", err)
+	}
+	if _, err := system.AuthenticatePassword("DET-3999", "correct horse battery", ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected an unknown user to be refused, got %v", err)
+	}
+	if err := system.SetPassword("DET-3001", "wrong", "a new long passphrase", "DET-3001"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected changing one's own password to need the current one, got %v", err)
+	}
+	if err := system.SetPassword("DET-3001", "correct horse battery", "a new long passphrase", "DET-3001"); err != nil {
+		t.Fatalf("SetPassword with the current password failed: %v", err)
+	}
+	if _, err := system.AuthenticatePassword("DET-3001", "correct horse battery", ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected the old password to stop working, got %v", err)
+	}
+
+	for i := 0; i < system.config.Security.MaxLoginAttempts; i++ {
+		system.AuthenticatePassword("DET-3001", "wrong", "10.0.0.2")
+	}
+	if _, err := system.AuthenticatePassword("DET-3001", "a new long passphrase", ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected the account to be locked, got %v", err)
+	}
+	if logs := system.GetAuditLogs("", "DET-3001"); len(logs) == 0 || logs[len(logs)-1].Action != "ACCOUNT_LOCKED" {
+		t.Error("expected the lockout to be audited")
+	}
+
+	reopened, err := NewBWCSystem(tmpDir)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	if _, err := reopened.AuthenticatePassword("DET-3001", "a new long passphrase", ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected the lockout to persist, got %v", err)
+	}
+	if err := reopened.SetPassword("DET-3001", "", "another long passphrase", "ADM-001"); err != nil {
+		t.Fatalf("SetPassword failed: %v", err)
+	}
+	if _, err := reopened.AuthenticatePassword("DET-3001", "another long passphrase", ""); err != nil {
+		t.Errorf("expected resetting the password to clear the lockout, got %v", err)
+	}
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	if _, err := system.RegisterUser("CUS-3001", "", RoleEvidenceCustodian, "ADM-001"); err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+	token, key, err := system.IssueAPIKey("CUS-3001", "property room kiosk", "CUS-3001")
+	if err != nil {
+		t.Fatalf("IssueAPIKey failed: %v", err)
+	}
+	if strings.Contains(string(mustReadFile(t, filepath.Join(tmpDir, credentialsFile))), token) {
+		t.Error("expected only the token's digest to be stored")
+	}
+
+	principal, err := system.AuthenticateAPIKey(token)
+	if err != nil || principal.UserID != "CUS-3001" || principal.Method != AuthAPIKey || principal.CredentialID != key.ID {
+		t.Fatalf("unexpected principal %+v %v", principal, err)
+	}
+	if keys := system.APIKeys("CUS-3001"); len(keys) != 1 || keys[0].LastUsedAt == nil {
+		t.Errorf("expected the key's use to be recorded, got %+v", keys)
+	}
+	if _, err := system.AuthenticateAPIKey("not-a-key"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected an unknown token to be refused, got %v", err)
+	}
+
+	reopened, err := NewBWCSystem(tmpDir)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	enableAccessControl(reopened)
+	if _, err := reopened.AuthenticateAPIKey(token); err != nil {
+		t.Errorf("expected the key to persist, got %v", err)
+	}
+	if err := reopened.RevokeAPIKey(key.ID, "CUS-3002"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected another user to need manage_users, got %v", err)
+	}
+	if err := reopened.RevokeAPIKey(key.ID, "CUS-3001"); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if _, err := reopened.AuthenticateAPIKey(token); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected a revoked key to be refused, got %v", err)
+	}
+}
+
+func TestPrincipalRecordedOnEntries(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	for id, role := range map[string]Role{"OFF-3001": RoleOfficer, "CUS-3001": RoleEvidenceCustodian} {
+		if _, err := system.RegisterUser(id, "", role, "ADM-001"); err != nil {
+			t.Fatalf("RegisterUser %s failed: %v", id, err)
+		}
+	}
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-ID-001", "OFF-3001", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	token, key, err := system.IssueAPIKey("CUS-3001", "", "CUS-3001")
+	if err != nil {
+		t.Fatalf("IssueAPIKey failed: %v", err)
+	}
+	principal, err := system.AuthenticateAPIKey(token)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey failed: %v", err)
+	}
+
+	// The principal is who acts, whatever the operation names
+	ctx := ContextWithPrincipal(context.Background(), principal)
+	if err := system.TransferCustodyContext(ctx, evidence.ID, "OFF-3001", "CUS-3001", "Booking"); err != nil {
+		t.Fatalf("TransferCustodyContext failed: %v", err)
+	}
+
+	chain, _ := system.GetChainOfCustody(evidence.ID)
+	auth := chain[len(chain)-1].Authentication
+	if auth == nil || auth.UserID != "CUS-3001" || auth.Method != AuthAPIKey || auth.CredentialID != key.ID {
+		t.Errorf("expected the custody entry to record the principal, got %+v", auth)
+	}
+	if chain[0].Authentication != nil {
+		t.Error("expected an entry made without a principal to record none")
+	}
+	if bad, err := system.VerifyCustodySignatures(evidence.ID); err != nil || len(bad) != 0 {
+		t.Errorf("expected the entry hashes to cover the principal, got %v %v", bad, err)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "OFF-3001")
+	if len(logs) == 0 || logs[len(logs)-1].Authentication == nil || logs[len(logs)-1].Authentication.CredentialID != key.ID {
+		t.Error("expected the audit entry to record the principal")
+	}
+
+	if _, err := system.VerifyIntegrityContext(ctx, evidence.ID, "OFF-3001"); err != nil {
+		t.Fatalf("VerifyIntegrityContext failed: %v", err)
+	}
+	verified, _ := system.GetEvidence(evidence.ID)
+	if check := verified.IntegrityChecks[len(verified.IntegrityChecks)-1]; check.CheckedBy != "CUS-3001" {
+		t.Errorf("expected the check recorded as the principal's, got %q", check.CheckedBy)
+	}
+
+	officer := &Principal{UserID: "OFF-3001", Method: AuthPassword}
+	ctx = ContextWithPrincipal(context.Background(), officer)
+	if err := system.TransferCustodyContext(ctx, evidence.ID, "CUS-3001", "CUS-3002", "Storage"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected the principal's permissions to apply, got %v", err)
+	}
+}
+
+func TestContextVariantsActAsPrincipal(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	for id, role := range map[string]Role{"OFF-3201": RoleOfficer, "CUS-3201": RoleEvidenceCustodian} {
+		if _, err := system.RegisterUser(id, "", role, "ADM-001"); err != nil {
+			t.Fatalf("RegisterUser %s failed: %v", id, err)
+		}
+	}
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-ID-002", "OFF-3201", "", "", nil)
+	token, key, _ := system.IssueAPIKey("CUS-3201", "", "CUS-3201")
+	principal, err := system.AuthenticateAPIKey(token)
+	if err != nil {
+		t.Fatalf("AuthenticateAPIKey failed: %v", err)
+	}
+	ctx := ContextWithPrincipal(context.Background(), principal)
+
+	// The custodian acts, whoever the check-out names
+	if _, err := system.CheckOutEvidenceSignedContext(ctx, evidence.ID, "OFF-3201", "LAB-3201", "Lab analysis", nil); err != nil {
+		t.Fatalf("CheckOutEvidenceSignedContext failed: %v", err)
+	}
+	chain, _ := system.GetChainOfCustody(evidence.ID)
+	if entry := chain[len(chain)-1]; entry.FromOfficer != "CUS-3201" || entry.Authentication == nil || entry.Authentication.CredentialID != key.ID {
+		t.Errorf("expected the check-out recorded as the principal's, got %+v", entry)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "CUS-3201")
+	if len(logs) == 0 || logs[len(logs)-1].Action != "CHECK_OUT_EVIDENCE" || logs[len(logs)-1].Authentication == nil {
+		t.Errorf("expected the check-out audited with the principal, got %+v", logs)
+	}
+	if logs := system.GetAuditLogs(evidence.ID, "OFF-3201"); len(logs) != 1 {
+		t.Errorf("expected nothing recorded as the officer named, got %+v", logs)
+	}
+
+	// An officer naming an administrator still has only an officer's permissions
+	officer := ContextWithPrincipal(context.Background(), &Principal{UserID: "OFF-3201", Method: AuthPassword})
+	if _, err := system.RegisterUserContext(officer, "OFF-3202", "", RoleOfficer, "ADM-001"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected the principal's permissions to apply, got %v", err)
+	}
+	if err := system.RestrictEvidenceContext(officer, evidence.ID, []string{"OFF-3201"}, "Internal affairs", "ADM-001"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected the principal's permissions to apply, got %v", err)
+	}
+}
+
+func TestServerPasswordLoginAndAPIKeys(t *testing.T) {
+	system, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	enableAccessControl(system)
+	system.config.AccessControl.Admins = []string{"CUS-001"}
+
+	if _, err := system.RegisterUser("DET-3101", "", RoleDetective, "CUS-001"); err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/api/users/DET-3101/password",
+		strings.NewReader(`{"password":"correct horse battery"}`))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("PUT password failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected the password to be set, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.PostForm(server.URL+"/login", url.Values{"user_id": {"DET-3101"}, "password": {"wrong"}})
+	if err != nil {
+		t.Fatalf("POST /login failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 for a wrong password, got %d", resp.StatusCode)
+	}
+	resp, err = http.PostForm(server.URL+"/login", url.Values{"user_id": {"DET-3101"}, "password": {"correct horse battery"}})
+	if err != nil {
+		t.Fatalf("POST /login failed: %v", err)
+	}
+	resp.Body.Close()
+	var session *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil {
+		t.Fatal("expected a session for the password login")
+	}
+
+	resp = authPostJSON(t, server, "/api/users/DET-3101/keys", `{"name":"laptop"}`)
+	var issued struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	json.NewDecoder(resp.Body).Decode(&issued)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || issued.Token == "" {
+		t.Fatalf("expected a key to be issued, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/session", nil)
+	req.Header.Set("Authorization", "Bearer "+issued.Token)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/session failed: %v", err)
+	}
+	var who map[string]string
+	json.NewDecoder(resp.Body).Decode(&who)
+	resp.Body.Close()
+	if who["user_id"] != "DET-3101" {
+		t.Errorf("expected the API key to authenticate DET-3101, got %v", who)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/api/users/DET-3101/keys/"+issued.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("DELETE key failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("expected the key to be revoked, got %d", resp.StatusCode)
+	}
+	if _, err := system.AuthenticateAPIKey(issued.Token); err == nil {
+		t.Error("expected the revoked key to be refused")
+	}
+}
@@ -0,0 +1,282 @@
+package bwc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ingestPollInterval is how often the server's progress is checked once an
+// upload has been sent
+const ingestPollInterval = time.Second
+
+// ingestClient uploads evidence to a running server's /api/evidence endpoint
+type ingestClient struct {
+	baseURL string
+	token   string
+	// http has no timeout: multi-gigabyte uploads take as long as they take
+	http *http.Client
+}
+
+func newIngestClient(baseURL, token string) *ingestClient {
+	return &ingestClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{},
+	}
+}
+
+// ingestUpload describes a file to upload and its metadata
+type ingestUpload struct {
+	Path        string
+	CaseNumber  string
+	OfficerID   string
+	OfficerName string
+	Location    string
+	Tags        []string
+}
+
+// Upload sends the file, calling sent as bytes leave and processing with the
+// server's progress once the whole file has been sent
+func (c *ingestClient) Upload(upload ingestUpload, sent func(done, total int64), processing func(IngestProgress)) (*Evidence, error) {
+	file, err := os.Open(upload.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("filename", filepath.Base(upload.Path))
+	q.Set("case", upload.CaseNumber)
+	for name, value := range map[string]string{"officer": upload.OfficerID, "officer_name": upload.OfficerName, "location": upload.Location} {
+		if value != "" {
+			q.Set(name, value)
+		}
+	}
+	if len(upload.Tags) > 0 {
+		q.Set("tags", strings.Join(upload.Tags, ","))
+	}
+
+	// Once the body is sent the server hashes and copies the file, which it
+	// reports through /api/ingests
+	uploaded := make(chan struct{})
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-uploaded:
+		case <-done:
+			return
+		}
+		ticker := time.NewTicker(ingestPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			active, err := c.ActiveIngests()
+			if err != nil {
+				continue
+			}
+			for _, p := range active {
+				if p.CaseNumber == upload.CaseNumber && (upload.OfficerID == "" || p.OfficerID == upload.OfficerID) {
+					processing(p)
+					break
+				}
+			}
+		}
+	}()
+	defer wg.Wait()
+	defer close(done)
+
+	body := &uploadReader{r: file, total: info.Size(), sent: sent, uploaded: uploaded}
+	req, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/evidence?"+q.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp)
+	}
+	var evidence Evidence
+	if err := json.NewDecoder(resp.Body).Decode(&evidence); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &evidence, nil
+}
+
+// ActiveIngests fetches the progress of every ingest under way on the server
+func (c *ingestClient) ActiveIngests() ([]IngestProgress, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+"/api/ingests", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp)
+	}
+	var active []IngestProgress
+	if err := json.NewDecoder(resp.Body).Decode(&active); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return active, nil
+}
+
+// apiError reads the error message from a failed API response
+func apiError(resp *http.Response) error {
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+	if apiErr.Error == "" {
+		apiErr.Error = resp.Status
+	}
+	return errors.New(apiErr.Error)
+}
+
+// uploadReader reports bytes sent and closes uploaded at the end of the file
+type uploadReader struct {
+	r        io.Reader
+	total    int64
+	done     int64
+	sent     func(done, total int64)
+	uploaded chan struct{}
+	closed   bool
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.done += int64(n)
+	if n > 0 {
+		u.sent(u.done, u.total)
+	}
+	if err == io.EOF && !u.closed {
+		u.closed = true
+		close(u.uploaded)
+	}
+	return n, err
+}
+
+// ingestMeter renders transfer progress: a self-updating line on a terminal,
+// otherwise a line at every tenth of each phase so logs stay short. Upload and
+// server progress arrive on different goroutines.
+type ingestMeter struct {
+	mu        sync.Mutex
+	out       io.Writer
+	live      bool
+	started   time.Time
+	lastPhase string
+	lastStep  int
+	lastDraw  time.Time
+}
+
+func newIngestMeter(out io.Writer, live bool) *ingestMeter {
+	return &ingestMeter{out: out, live: live, started: time.Now(), lastStep: -1}
+}
+
+// Sent reports upload progress
+func (m *ingestMeter) Sent(done, total int64) {
+	elapsed := time.Since(m.started).Seconds()
+	rate, eta := 0.0, time.Duration(-1)
+	if elapsed > 0 && done > 0 {
+		rate = float64(done) / elapsed
+		eta = time.Duration(float64(total-done)/rate) * time.Second
+	}
+	percent := 100.0
+	if total > 0 {
+		percent = float64(done) * 100 / float64(total)
+	}
+	m.show("UPLOADING", fmt.Sprintf("%s / %s", formatByteCount(done), formatByteCount(total)), percent, rate, eta)
+}
+
+// Processing reports the server's hash and copy progress
+func (m *ingestMeter) Processing(p IngestProgress) {
+	done := p.HashedBytes
+	if p.Phase == IngestCopying {
+		done = p.CopiedBytes
+	}
+	m.show(string(p.Phase), fmt.Sprintf("%s / %s", formatByteCount(done), formatByteCount(p.TotalBytes)), p.Percent, p.BytesPerSecond, p.ETA())
+}
+
+func (m *ingestMeter) show(phase, bytes string, percent, rate float64, eta time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	step := int(percent / 10)
+	if m.live {
+		if phase == m.lastPhase && percent < 100 && time.Since(m.lastDraw) < ingestProgressInterval {
+			return
+		}
+		m.lastDraw = time.Now()
+	} else if phase == m.lastPhase && step == m.lastStep {
+		return
+	}
+	m.lastPhase, m.lastStep = phase, step
+
+	line := fmt.Sprintf("%-9s %s  %5.1f%%  %s/s  ETA %s", phase, bytes, percent, formatByteCount(int64(rate)), formatETA(eta))
+	if m.live {
+		fmt.Fprintf(m.out, "\r%-72s", line)
+	} else {
+		fmt.Fprintln(m.out, line)
+	}
+}
+
+// Done ends a self-updating line
+func (m *ingestMeter) Done() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.live && m.lastPhase != "" {
+		fmt.Fprintln(m.out)
+	}
+}
+
+// formatByteCount renders n in binary units, e.g. "1.5 GiB"
+func formatByteCount(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatETA renders d as h:mm:ss, or "--" when unknown
+func formatETA(d time.Duration) string {
+	if d < 0 {
+		return "--"
+	}
+	s := int64(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
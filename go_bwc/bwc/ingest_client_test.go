@@ -0,0 +1,65 @@
+package bwc
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIngestClientUpload(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "upload.mp4")
+	if err := os.WriteFile(path, make([]byte, 1<<20), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	var log bytes.Buffer
+	meter := newIngestMeter(&log, false)
+	client := newIngestClient(server.URL, testAPIToken)
+	evidence, err := client.Upload(ingestUpload{Path: path, CaseNumber: "CASE-PRG-2", Tags: []string{"traffic"}}, meter.Sent, meter.Processing)
+	meter.Done()
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if evidence.CaseNumber != "CASE-PRG-2" || evidence.FileSize != 1<<20 || evidence.OfficerID != "CUS-001" {
+		t.Errorf("unexpected evidence %+v", evidence)
+	}
+	if _, err := system.GetEvidence(evidence.ID); err != nil {
+		t.Errorf("expected the evidence on the server: %v", err)
+	}
+
+	out := log.String()
+	if !strings.Contains(out, "UPLOADING") || !strings.Contains(out, "1.0 MiB / 1.0 MiB  100.0%") {
+		t.Errorf("expected upload progress lines, got:\n%s", out)
+	}
+	if lines := strings.Count(out, "\n"); lines > 11 {
+		t.Errorf("expected at most one line per tenth, got %d:\n%s", lines, out)
+	}
+
+	active, err := client.ActiveIngests()
+	if err != nil || len(active) != 0 {
+		t.Errorf("expected no active ingests, got %v, %v", active, err)
+	}
+
+	if _, err := newIngestClient(server.URL, "wrong-token").Upload(ingestUpload{Path: path, CaseNumber: "CASE-PRG-2"}, meter.Sent, meter.Processing); err == nil {
+		t.Error("expected a rejected token to fail the upload")
+	}
+}
+
+func TestIngestProgressFormatting(t *testing.T) {
+	for n, want := range map[int64]string{512: "512 B", 1536: "1.5 KiB", 5 << 30: "5.0 GiB"} {
+		if got := formatByteCount(n); got != want {
+			t.Errorf("formatByteCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+	for d, want := range map[time.Duration]string{-1: "--", 0: "0:00:00", 3723 * time.Second: "1:02:03"} {
+		if got := formatETA(d); got != want {
+			t.Errorf("formatETA(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
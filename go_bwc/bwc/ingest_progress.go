@@ -0,0 +1,183 @@
+package bwc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// IngestPhase is the pass an ingest is making over its file
+type IngestPhase string
+
+const (
+	IngestHashing IngestPhase = "HASHING"
+	IngestCopying IngestPhase = "COPYING"
+)
+
+// ingestProgressInterval is the least time between progress reports, so a
+// fast local copy does not flood the callback
+const ingestProgressInterval = 250 * time.Millisecond
+
+// IngestProgress reports how far an ingest has got. The file is read twice,
+// once to hash it and once to copy it to storage, so Percent, the rate and
+// the ETA cover both passes.
+type IngestProgress struct {
+	ID         string      `json:"id"`
+	FileName   string      `json:"file_name"`
+	CaseNumber string      `json:"case_number"`
+	OfficerID  string      `json:"officer_id"`
+	Phase      IngestPhase `json:"phase"`
+
+	TotalBytes  int64 `json:"total_bytes"`
+	HashedBytes int64 `json:"hashed_bytes"`
+	CopiedBytes int64 `json:"copied_bytes"`
+
+	StartedAt      time.Time `json:"started_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Percent        float64   `json:"percent"`
+	BytesPerSecond float64   `json:"bytes_per_second"`
+	// ETASeconds is the estimated time left, or -1 before a rate is known
+	ETASeconds int64 `json:"eta_seconds"`
+}
+
+// ETA is the estimated time left, or -1 before a rate is known
+func (p IngestProgress) ETA() time.Duration {
+	if p.ETASeconds < 0 {
+		return -1
+	}
+	return time.Duration(p.ETASeconds) * time.Second
+}
+
+// IngestProgressFunc receives progress reports while an ingest runs. It is
+// called with the system locked, so apart from ActiveIngests it must not call
+// back into it.
+type IngestProgressFunc func(IngestProgress)
+
+// ingestTracker follows one ingest in bwc.ingests
+type ingestTracker struct {
+	bwc        *BWCSystem
+	ctx        context.Context // stops the ingest's reads once done
+	progress   IngestProgress  // guarded by bwc.ingestsMu
+	notify     IngestProgressFunc
+	lastReport time.Time
+	// skipped counts bytes a resumed ingest did not need to read again; they
+	// are left out of the rate
+	skipped int64
+}
+
+// trackIngest registers an ingest of size bytes so ActiveIngests can report it
+func (bwc *BWCSystem) trackIngest(ctx context.Context, filePath, caseNumber, officerID string, size int64, notify IngestProgressFunc) *ingestTracker {
+	now := time.Now()
+	bwc.ingestsMu.Lock()
+	defer bwc.ingestsMu.Unlock()
+	bwc.ingestSeq++
+	t := &ingestTracker{
+		bwc:    bwc,
+		ctx:    ctx,
+		notify: notify,
+		progress: IngestProgress{
+			ID:         fmt.Sprintf("ING-%06d", bwc.ingestSeq),
+			FileName:   filepath.Base(filePath),
+			CaseNumber: caseNumber,
+			OfficerID:  officerID,
+			Phase:      IngestHashing,
+			TotalBytes: size,
+			StartedAt:  now,
+			UpdatedAt:  now,
+			ETASeconds: -1,
+		},
+	}
+	bwc.ingests[t.progress.ID] = t
+	return t
+}
+
+// untrackIngest removes a finished or failed ingest
+func (bwc *BWCSystem) untrackIngest(t *ingestTracker) {
+	bwc.ingestsMu.Lock()
+	delete(bwc.ingests, t.progress.ID)
+	bwc.ingestsMu.Unlock()
+}
+
+// reader returns a wrapper that counts bytes read in phase
+func (t *ingestTracker) reader(phase IngestPhase) func(io.Reader) io.Reader {
+	return func(r io.Reader) io.Reader {
+		return &progressReader{r: r, tracker: t, phase: phase}
+	}
+}
+
+// skip counts n bytes of phase as done without reading them, as when a
+// resumed ingest picks up from its last checkpoint
+func (t *ingestTracker) skip(phase IngestPhase, n int64) {
+	t.bwc.ingestsMu.Lock()
+	t.skipped += n
+	t.bwc.ingestsMu.Unlock()
+	t.advance(phase, n)
+}
+
+// advance records n more bytes read in phase and reports progress when due
+func (t *ingestTracker) advance(phase IngestPhase, n int64) {
+	now := time.Now()
+	t.bwc.ingestsMu.Lock()
+	p := &t.progress
+	p.Phase = phase
+	if phase == IngestHashing {
+		p.HashedBytes += n
+	} else {
+		p.CopiedBytes += n
+	}
+	p.UpdatedAt = now
+
+	total := 2 * p.TotalBytes
+	done := p.HashedBytes + p.CopiedBytes
+	if total > 0 {
+		p.Percent = float64(done) * 100 / float64(total)
+	}
+	if elapsed := now.Sub(p.StartedAt).Seconds(); elapsed > 0 && done > t.skipped {
+		p.BytesPerSecond = float64(done-t.skipped) / elapsed
+		p.ETASeconds = int64(float64(total-done)/p.BytesPerSecond + 0.5)
+	}
+	finished := p.TotalBytes == p.HashedBytes && (phase == IngestHashing || p.TotalBytes == p.CopiedBytes)
+	due := t.notify != nil && (finished || now.Sub(t.lastReport) >= ingestProgressInterval)
+	if due {
+		t.lastReport = now
+	}
+	snapshot := *p
+	t.bwc.ingestsMu.Unlock()
+
+	if due {
+		t.notify(snapshot)
+	}
+}
+
+// progressReader counts bytes read through it into an ingest tracker
+type progressReader struct {
+	r       io.Reader
+	tracker *ingestTracker
+	phase   IngestPhase
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	if err := p.tracker.ctx.Err(); err != nil {
+		return 0, err
+	}
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.tracker.advance(p.phase, int64(n))
+	}
+	return n, err
+}
+
+// ActiveIngests reports the progress of every ingest under way, oldest first
+func (bwc *BWCSystem) ActiveIngests() []IngestProgress {
+	bwc.ingestsMu.Lock()
+	defer bwc.ingestsMu.Unlock()
+	active := make([]IngestProgress, 0, len(bwc.ingests))
+	for _, t := range bwc.ingests {
+		active = append(active, t.progress)
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].ID < active[j].ID })
+	return active
+}
@@ -0,0 +1,55 @@
+package bwc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIngestEvidenceWithProgress(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "long_recording.mp4")
+	if err := os.WriteFile(path, make([]byte, 3<<20), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	var reports []IngestProgress
+	var active []IngestProgress
+	ev, err := system.IngestEvidenceWithProgress(path, "CASE-PRG-1", "OFF-1119", "", "", nil, func(p IngestProgress) {
+		reports = append(reports, p)
+		active = system.ActiveIngests()
+	})
+	if err != nil {
+		t.Fatalf("IngestEvidenceWithProgress failed: %v", err)
+	}
+
+	if len(reports) < 2 {
+		t.Fatalf("expected a report at the end of each pass, got %d", len(reports))
+	}
+	hashed := false
+	for _, p := range reports {
+		if p.Phase == IngestHashing && p.HashedBytes == p.TotalBytes {
+			hashed = true
+		}
+		if p.ID != reports[0].ID || p.TotalBytes != ev.FileSize || p.FileName != "long_recording.mp4" {
+			t.Errorf("unexpected report %+v", p)
+		}
+	}
+	if !hashed {
+		t.Error("expected a report when hashing finished")
+	}
+	last := reports[len(reports)-1]
+	if last.Phase != IngestCopying || last.CopiedBytes != ev.FileSize || last.Percent != 100 || last.ETASeconds != 0 {
+		t.Errorf("expected the last report to show the copy complete, got %+v", last)
+	}
+
+	if len(active) != 1 || active[0].ID != last.ID || active[0].CaseNumber != "CASE-PRG-1" {
+		t.Errorf("expected the ingest to be listed while it ran, got %+v", active)
+	}
+	if len(system.ActiveIngests()) != 0 {
+		t.Error("expected the ingest to be removed once finished")
+	}
+
+}
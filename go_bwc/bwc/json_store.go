@@ -0,0 +1,187 @@
+package bwc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jsonSnapshotVersion is the version of the snapshot files written; files of
+// a later version are refused rather than misread
+const jsonSnapshotVersion = 1
+
+const (
+	jsonEvidenceFile = "evidence.json"
+	jsonAuditFile    = "audit.json"
+)
+
+// evidenceSnapshot is the content of evidence.json
+type evidenceSnapshot struct {
+	Version  int         `json:"version"`
+	SavedAt  time.Time   `json:"saved_at"`
+	Evidence []*Evidence `json:"evidence"`
+}
+
+// auditSnapshot is the content of audit.json
+type auditSnapshot struct {
+	Version int        `json:"version"`
+	SavedAt time.Time  `json:"saved_at"`
+	Entries []AuditLog `json:"entries"`
+}
+
+// jsonStore is an EvidenceStore and AuditStore kept as two JSON snapshot
+// files in a directory. Every change rewrites the snapshot it touches, so it
+// suits small deployments that need nothing installed.
+type jsonStore struct {
+	*memoryStore
+	dir string
+
+	// mu serializes snapshot writes and guards audit
+	mu    sync.Mutex
+	audit []AuditLog
+}
+
+// openJSONStore loads the snapshots in dir, which may not exist yet
+func openJSONStore(dir string) (*jsonStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	s := &jsonStore{memoryStore: newMemoryStore(), dir: dir}
+
+	var evidence evidenceSnapshot
+	if err := readSnapshot(filepath.Join(dir, jsonEvidenceFile), &evidence, &evidence.Version); err != nil {
+		return nil, err
+	}
+	for _, ev := range evidence.Evidence {
+		if err := s.memoryStore.Put(ev); err != nil {
+			return nil, fmt.Errorf("%s: %w", jsonEvidenceFile, err)
+		}
+	}
+	var audit auditSnapshot
+	if err := readSnapshot(filepath.Join(dir, jsonAuditFile), &audit, &audit.Version); err != nil {
+		return nil, err
+	}
+	s.audit = audit.Entries
+	return s, nil
+}
+
+// readSnapshot decodes the snapshot at path into v, leaving v empty when
+// there is none. version must point into v.
+func readSnapshot(path string, v interface{}, version *int) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: invalid snapshot: %w", filepath.Base(path), err)
+	}
+	if *version > jsonSnapshotVersion {
+		return fmt.Errorf("%s: snapshot version %d is newer than this build reads (%d)", filepath.Base(path), *version, jsonSnapshotVersion)
+	}
+	return nil
+}
+
+// writeSnapshot replaces the snapshot at path, so a crash mid-write leaves
+// the previous snapshot in place
+func writeSnapshot(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal snapshot: %w", err)
+	}
+	if err := replaceFile(path, data); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return nil
+}
+
+// replaceFile replaces the file at path with data, synced before it takes
+// the old file's place
+func replaceFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// writeEvidence writes the records held, with put in place of the record
+// with its ID and without the record with id skip
+func (s *jsonStore) writeEvidence(put *Evidence, skip string) error {
+	records := s.memoryStore.Search(func(ev *Evidence) bool {
+		return ev.ID != skip && (put == nil || ev.ID != put.ID)
+	})
+	if put != nil {
+		records = append(records, put)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return writeSnapshot(filepath.Join(s.dir, jsonEvidenceFile), evidenceSnapshot{
+		Version:  jsonSnapshotVersion,
+		SavedAt:  time.Now(),
+		Evidence: records,
+	})
+}
+
+func (s *jsonStore) Put(evidence *Evidence) error {
+	if evidence == nil || evidence.ID == "" {
+		return errors.New("evidence ID is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeEvidence(evidence, ""); err != nil {
+		return err
+	}
+	return s.memoryStore.Put(evidence)
+}
+
+func (s *jsonStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writeEvidence(nil, id); err != nil {
+		return err
+	}
+	return s.memoryStore.Delete(id)
+}
+
+func (s *jsonStore) AppendAudit(log AuditLog) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries := append(s.audit, log)
+	err := writeSnapshot(filepath.Join(s.dir, jsonAuditFile), auditSnapshot{
+		Version: jsonSnapshotVersion,
+		SavedAt: time.Now(),
+		Entries: entries,
+	})
+	if err != nil {
+		return err
+	}
+	s.audit = entries
+	return nil
+}
+
+func (s *jsonStore) AuditLogs() ([]AuditLog, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuditLog(nil), s.audit...), nil
+}
@@ -0,0 +1,73 @@
+package bwc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONStoreSurvivesRestart(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Database.Type = "json"
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-JSN-1", "OFF-1145", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1145", "DET-007", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+
+	restarted, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	got, err := restarted.GetEvidence(ev.ID)
+	if err != nil {
+		t.Fatalf("expected the record to survive a restart: %v", err)
+	}
+	if got.FileHash != ev.FileHash || len(got.ChainOfCustody) != 2 || got.ChainOfCustody[1].ToOfficer != "DET-007" {
+		t.Errorf("expected the custody chain to survive, got %+v", got.ChainOfCustody)
+	}
+	logs := restarted.GetAuditLogs(ev.ID, "")
+	if len(logs) < 2 || logs[0].Action != "INGEST_EVIDENCE" {
+		t.Errorf("expected the audit log to survive, got %+v", logs)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Storage.Path, jsonEvidenceFile+".tmp")); !os.IsNotExist(err) {
+		t.Error("expected no temporary snapshot to be left behind")
+	}
+}
+
+func TestJSONStoreRefusesNewerSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, jsonEvidenceFile), []byte(`{"version": 99, "evidence": []}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := openJSONStore(dir); err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Errorf("expected a newer snapshot to be refused, got %v", err)
+	}
+}
+
+func TestJSONStoreFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openJSONStore(dir)
+	if err != nil {
+		t.Fatalf("openJSONStore failed: %v", err)
+	}
+	// A directory in the way of the snapshot makes the rename fail
+	if err := os.Mkdir(filepath.Join(dir, jsonEvidenceFile), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(&Evidence{ID: "BWC-CASE-JSN-2-OFF-1146-1700000000"}); err == nil {
+		t.Fatal("expected the write to fail")
+	}
+	if store.Get("BWC-CASE-JSN-2-OFF-1146-1700000000") != nil {
+		t.Error("expected a record that was not written not to be held")
+	}
+}
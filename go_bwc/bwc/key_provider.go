@@ -0,0 +1,482 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeyProvider wraps the data keys of evidence encrypted at rest. The key that
+// wraps them never leaves the provider, so rotating it means wrapping each
+// data key again rather than re-encrypting any recording. Every wrapped key is
+// bound to its evidence ID and cannot be moved to another record.
+type KeyProvider interface {
+	// KeyID names the key new data keys are wrapped with
+	KeyID() string
+	// WrapKey encrypts dataKey and returns it with the ID of the key used
+	WrapKey(dataKey []byte, evidenceID string) (keyID string, wrapped []byte, err error)
+	// UnwrapKey recovers a data key wrapped by WrapKey or RewrapKey
+	UnwrapKey(keyID string, wrapped []byte, evidenceID string) ([]byte, error)
+	// RewrapKey wraps a data key again under the current key, without
+	// revealing it where the provider allows
+	RewrapKey(keyID string, wrapped []byte, evidenceID string) (newKeyID string, rewrapped []byte, err error)
+}
+
+// keyProviderHTTPClient calls remote key management services
+var keyProviderHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// newKeyProvider returns the key provider sec configures
+func newKeyProvider(sec SecurityConfig) (KeyProvider, error) {
+	switch sec.KMS.Provider {
+	case "", "local":
+		return loadLocalKeyProvider(sec.EncryptionKeyFile, sec.RetiredEncryptionKeyFiles)
+	case "aws":
+		return newAWSKMSProvider(sec.KMS), nil
+	case "vault":
+		return newVaultTransitProvider(sec.KMS), nil
+	}
+	return nil, fmt.Errorf("unsupported key provider %q", sec.KMS.Provider)
+}
+
+// SetKeyProvider replaces the provider that wraps the data keys of evidence
+// encrypted at rest, and encrypts evidence ingested from now on. Evidence
+// already held must still be readable by the new provider.
+func (bwc *BWCSystem) SetKeyProvider(provider KeyProvider) error {
+	if provider == nil {
+		return errors.New("a key provider is required")
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	bwc.atRest = provider
+	return nil
+}
+
+// LocalKeyProvider wraps data keys with AES-256-GCM under keys held in
+// memory, read from key files. Retired keys only unwrap, until RotateDataKeys
+// has moved every data key off them.
+type LocalKeyProvider struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewLocalKeyProvider returns a provider that wraps with current and can
+// still unwrap with the retired keys. Each key is 32 bytes.
+func NewLocalKeyProvider(current []byte, retired ...[]byte) (*LocalKeyProvider, error) {
+	p := &LocalKeyProvider{keys: make(map[string]cipher.AEAD)}
+	for i, key := range append([][]byte{current}, retired...) {
+		if len(key) != atRestKeySize {
+			return nil, fmt.Errorf("key encryption keys must be %d bytes", atRestKeySize)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(key)
+		id := "local:" + hex.EncodeToString(sum[:8])
+		p.keys[id] = aead
+		if i == 0 {
+			p.current = id
+		}
+	}
+	return p, nil
+}
+
+// loadLocalKeyProvider reads the hex-encoded keys at currentPath and
+// retiredPaths
+func loadLocalKeyProvider(currentPath string, retiredPaths []string) (*LocalKeyProvider, error) {
+	var keys [][]byte
+	for _, path := range append([]string{currentPath}, retiredPaths...) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption key: %w", err)
+		}
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != atRestKeySize {
+			return nil, fmt.Errorf("encryption key %s must contain a hex-encoded %d-byte key", path, atRestKeySize)
+		}
+		keys = append(keys, key)
+	}
+	return NewLocalKeyProvider(keys[0], keys[1:]...)
+}
+
+func (p *LocalKeyProvider) KeyID() string {
+	return p.current
+}
+
+// dataKeyAAD binds a wrapped data key to its evidence
+func dataKeyAAD(evidenceID string) []byte {
+	return []byte("BWC-DATA-KEY-v1\n" + evidenceID)
+}
+
+func (p *LocalKeyProvider) WrapKey(dataKey []byte, evidenceID string) (string, []byte, error) {
+	aead := p.keys[p.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return p.current, aead.Seal(nonce, nonce, dataKey, dataKeyAAD(evidenceID)), nil
+}
+
+func (p *LocalKeyProvider) UnwrapKey(keyID string, wrapped []byte, evidenceID string) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("data key is wrapped with key %s, which is not configured", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped data key is too short")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	key, err := aead.Open(nil, nonce, sealed, dataKeyAAD(evidenceID))
+	if err != nil {
+		return nil, fmt.Errorf("wrapped data key does not open with key %s", keyID)
+	}
+	return key, nil
+}
+
+func (p *LocalKeyProvider) RewrapKey(keyID string, wrapped []byte, evidenceID string) (string, []byte, error) {
+	key, err := p.UnwrapKey(keyID, wrapped, evidenceID)
+	if err != nil {
+		return "", nil, err
+	}
+	return p.WrapKey(key, evidenceID)
+}
+
+// awsKMSProvider wraps data keys with a symmetric AWS KMS key. The evidence ID
+// is the encryption context, which KMS requires again to decrypt.
+type awsKMSProvider struct {
+	keyID    string
+	region   string
+	endpoint string
+	creds    s3Credentials
+}
+
+func newAWSKMSProvider(cfg KMSConfig) *awsKMSProvider {
+	p := &awsKMSProvider{keyID: cfg.KeyID, region: cfg.Region, endpoint: strings.TrimRight(cfg.Endpoint, "/")}
+	if p.endpoint == "" {
+		p.endpoint = "https://kms." + cfg.Region + ".amazonaws.com"
+	}
+	p.creds = s3Credentials{AccessKeyID: cfg.AccessKeyID, SecretAccessKey: cfg.SecretAccessKey, SessionToken: cfg.Token}
+	if p.creds.AccessKeyID == "" {
+		p.creds = s3Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+	}
+	return p
+}
+
+func (p *awsKMSProvider) KeyID() string {
+	return p.keyID
+}
+
+// call sends one KMS API action; []byte fields travel base64-encoded
+func (p *awsKMSProvider) call(action string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	sum := sha256.Sum256(body)
+	signAWSRequest(req, hex.EncodeToString(sum[:]), p.region, "kms", p.creds, time.Now())
+
+	resp, err := keyProviderHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("KMS %s failed: %w", action, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&kmsErr)
+		return fmt.Errorf("KMS %s returned %s: %s %s", action, resp.Status, kmsErr.Type, kmsErr.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func kmsContext(evidenceID string) map[string]string {
+	return map[string]string{"evidence_id": evidenceID}
+}
+
+func (p *awsKMSProvider) WrapKey(dataKey []byte, evidenceID string) (string, []byte, error) {
+	var out struct {
+		CiphertextBlob []byte
+		KeyId          string
+	}
+	err := p.call("Encrypt", map[string]interface{}{
+		"KeyId": p.keyID, "Plaintext": dataKey, "EncryptionContext": kmsContext(evidenceID),
+	}, &out)
+	return out.KeyId, out.CiphertextBlob, err
+}
+
+func (p *awsKMSProvider) UnwrapKey(keyID string, wrapped []byte, evidenceID string) ([]byte, error) {
+	var out struct {
+		Plaintext []byte
+	}
+	err := p.call("Decrypt", map[string]interface{}{
+		"KeyId": keyID, "CiphertextBlob": wrapped, "EncryptionContext": kmsContext(evidenceID),
+	}, &out)
+	return out.Plaintext, err
+}
+
+// RewrapKey uses ReEncrypt, so the data key is never sent back to the system
+func (p *awsKMSProvider) RewrapKey(keyID string, wrapped []byte, evidenceID string) (string, []byte, error) {
+	var out struct {
+		CiphertextBlob []byte
+		KeyId          string
+	}
+	err := p.call("ReEncrypt", map[string]interface{}{
+		"CiphertextBlob": wrapped, "SourceKeyId": keyID, "SourceEncryptionContext": kmsContext(evidenceID),
+		"DestinationKeyId": p.keyID, "DestinationEncryptionContext": kmsContext(evidenceID),
+	}, &out)
+	return out.KeyId, out.CiphertextBlob, err
+}
+
+// vaultTransitProvider wraps data keys with a HashiCorp Vault transit key.
+// Rotating the key in Vault adds a version; RewrapKey moves a data key to the
+// newest. The evidence ID is sealed with the data key, as transit keys are
+// not usually created for associated data.
+type vaultTransitProvider struct {
+	addr  string
+	mount string
+	key   string
+	token string
+}
+
+func newVaultTransitProvider(cfg KMSConfig) *vaultTransitProvider {
+	p := &vaultTransitProvider{addr: strings.TrimRight(cfg.Endpoint, "/"), mount: cfg.Mount, key: cfg.KeyID, token: cfg.Token}
+	if p.mount == "" {
+		p.mount = "transit"
+	}
+	if p.token == "" {
+		p.token = os.Getenv("VAULT_TOKEN")
+	}
+	return p
+}
+
+func (p *vaultTransitProvider) KeyID() string {
+	return "vault:" + p.mount + "/" + p.key
+}
+
+// call posts in to a transit endpoint and decodes the response's data
+func (p *vaultTransitProvider) call(op string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	u := p.addr + "/v1/" + p.mount + "/" + op + "/" + url.PathEscape(p.key)
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := keyProviderHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault %s failed: %w", op, err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []string        `json:"errors"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&result)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault %s returned %s: %s", op, resp.Status, strings.Join(result.Errors, "; "))
+	}
+	return json.Unmarshal(result.Data, out)
+}
+
+// keyID names the key version a transit ciphertext ("vault:v2:...") was made with
+func (p *vaultTransitProvider) keyID(ciphertext string) string {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) != 3 {
+		return p.KeyID()
+	}
+	return p.KeyID() + ":" + parts[1]
+}
+
+// vaultBinding is sealed after the data key so it opens only for its evidence
+func vaultBinding(evidenceID string) []byte {
+	return []byte("\nBWC-DATA-KEY-v1\n" + evidenceID)
+}
+
+func (p *vaultTransitProvider) WrapKey(dataKey []byte, evidenceID string) (string, []byte, error) {
+	plaintext := append(append([]byte(nil), dataKey...), vaultBinding(evidenceID)...)
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := p.call("encrypt", map[string]string{"plaintext": base64.StdEncoding.EncodeToString(plaintext)}, &out); err != nil {
+		return "", nil, err
+	}
+	return p.keyID(out.Ciphertext), []byte(out.Ciphertext), nil
+}
+
+func (p *vaultTransitProvider) UnwrapKey(keyID string, wrapped []byte, evidenceID string) ([]byte, error) {
+	var out struct {
+		Plaintext string `json:"plaintext"`
+	}
+	if err := p.call("decrypt", map[string]string{"ciphertext": string(wrapped)}, &out); err != nil {
+		return nil, err
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(out.Plaintext)
+	if err != nil || !bytes.HasSuffix(plaintext, vaultBinding(evidenceID)) {
+		return nil, errors.New("wrapped data key does not belong to this evidence")
+	}
+	return plaintext[:len(plaintext)-len(vaultBinding(evidenceID))], nil
+}
+
+// RewrapKey uses transit's rewrap, so the data key is never sent back to the
+// system
+func (p *vaultTransitProvider) RewrapKey(keyID string, wrapped []byte, evidenceID string) (string, []byte, error) {
+	var out struct {
+		Ciphertext string `json:"ciphertext"`
+	}
+	if err := p.call("rewrap", map[string]string{"ciphertext": string(wrapped)}, &out); err != nil {
+		return "", nil, err
+	}
+	return p.keyID(out.Ciphertext), []byte(out.Ciphertext), nil
+}
+
+// KeyRotation is the outcome of RotateDataKeys
+type KeyRotation struct {
+	// KeyID is the provider's current key
+	KeyID string `json:"key_id"`
+	// Rewrapped counts the data keys wrapped again
+	Rewrapped int `json:"rewrapped"`
+	// Failed lists the evidence, or interrupted ingests, whose data key is
+	// still wrapped as before
+	Failed []string `json:"failed,omitempty"`
+}
+
+// RotateDataKeys wraps the data key of every evidence file encrypted at rest,
+// and of every interrupted ingest, again under the key provider's current
+// key. No recording is read or rewritten, so it takes moments however much
+// video is held. A key it has moved everything off can then be retired. The
+// wrapping is not part of the record's content: revisions and seals are
+// unchanged.
+func (bwc *BWCSystem) RotateDataKeys(userID string) (*KeyRotation, error) {
+	return bwc.RotateDataKeysContext(context.Background(), userID)
+}
+
+// RotateDataKeysContext rotates like RotateDataKeys, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) RotateDataKeysContext(ctx context.Context, userID string) (*KeyRotation, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermAdminister, "Data key rotation", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.RLock()
+	provider := bwc.atRest
+	var ids []string
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Encryption != nil {
+			ids = append(ids, evidence.ID)
+		}
+	}
+	bwc.mu.RUnlock()
+	if provider == nil {
+		return nil, errNoAtRestKey
+	}
+	sort.Strings(ids)
+
+	// The provider is called without the lock, as each call may be a round
+	// trip to a key management service
+	rotation := &KeyRotation{KeyID: provider.KeyID()}
+	for _, id := range ids {
+		if err := bwc.rewrapEvidenceKey(provider, id, userID); err != nil {
+			rotation.Failed = append(rotation.Failed, id)
+			bwc.logAuditAs(auth, userID, "REWRAP_DATA_KEY_FAILED", id, err.Error(), "")
+			continue
+		}
+		rotation.Rewrapped++
+	}
+
+	bwc.mu.Lock()
+	for id, stage := range bwc.stagedIngests {
+		if stage.Encryption == nil {
+			continue
+		}
+		keyID, wrapped, err := provider.RewrapKey(stage.Encryption.KeyID, stage.Encryption.WrappedKey, id)
+		if err == nil {
+			previous := *stage.Encryption
+			stage.Encryption.KeyID, stage.Encryption.WrappedKey = keyID, wrapped
+			if err = bwc.writeStageJournal(stage); err != nil {
+				*stage.Encryption = previous
+			}
+		}
+		if err != nil {
+			rotation.Failed = append(rotation.Failed, id)
+			bwc.logAuditAs(auth, userID, "REWRAP_DATA_KEY_FAILED", id, err.Error(), "")
+			continue
+		}
+		rotation.Rewrapped++
+	}
+	bwc.mu.Unlock()
+
+	bwc.logAuditAs(auth, userID, "ROTATE_DATA_KEYS", "", fmt.Sprintf("%d data keys re-wrapped under %s, %d failed",
+		rotation.Rewrapped, rotation.KeyID, len(rotation.Failed)), "")
+	return rotation, nil
+}
+
+// rewrapEvidenceKey wraps the data key of one record again under provider
+func (bwc *BWCSystem) rewrapEvidenceKey(provider KeyProvider, evidenceID, userID string) error {
+	bwc.mu.RLock()
+	var enc AtRestEncryption
+	if evidence := bwc.evidenceDB.Get(evidenceID); evidence != nil && evidence.Encryption != nil {
+		enc = *evidence.Encryption
+	}
+	bwc.mu.RUnlock()
+	if enc.WrappedKey == nil {
+		return ErrEvidenceNotFound
+	}
+
+	keyID, wrapped, err := provider.RewrapKey(enc.KeyID, enc.WrappedKey, evidenceID)
+	if err != nil {
+		return err
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil || evidence.Encryption == nil || !bytes.Equal(evidence.Encryption.WrappedKey, enc.WrappedKey) {
+		return errors.New("data key changed during rotation")
+	}
+	previous := evidence.Encryption
+	updated := *previous
+	updated.KeyID, updated.WrappedKey = keyID, wrapped
+	evidence.Encryption = &updated
+	if err := bwc.saveLocked(evidence); err != nil {
+		evidence.Encryption = previous
+		return err
+	}
+	bwc.logAudit(userID, "REWRAP_DATA_KEY", evidenceID, fmt.Sprintf("Data key re-wrapped from %s to %s", previous.KeyID, keyID), "")
+	return nil
+}
@@ -0,0 +1,225 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLocalKeyProviderRetiresKeys(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, atRestKeySize), bytes.Repeat([]byte{2}, atRestKeySize)
+	old, _ := NewLocalKeyProvider(oldKey)
+	keyID, wrapped, err := old.WrapKey(bytes.Repeat([]byte{9}, atRestKeySize), "BWC-A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.UnwrapKey(keyID, wrapped, "BWC-B"); err == nil {
+		t.Error("expected a data key moved to other evidence not to unwrap")
+	}
+
+	current, _ := NewLocalKeyProvider(newKey)
+	if _, err := current.UnwrapKey(keyID, wrapped, "BWC-A"); err == nil {
+		t.Error("expected another key not to unwrap")
+	}
+	rotated, _ := NewLocalKeyProvider(newKey, oldKey)
+	newID, rewrapped, err := rotated.RewrapKey(keyID, wrapped, "BWC-A")
+	if err != nil || newID != current.KeyID() || newID == keyID {
+		t.Fatalf("expected the data key to move to the current key, got %s %v", newID, err)
+	}
+	if key, err := current.UnwrapKey(newID, rewrapped, "BWC-A"); err != nil || !bytes.Equal(key, bytes.Repeat([]byte{9}, atRestKeySize)) {
+		t.Errorf("expected the re-wrapped key to open without the retired key, got %v", err)
+	}
+}
+
+func TestRotateDataKeys(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	oldKey, newKey := bytes.Repeat([]byte{1}, atRestKeySize), bytes.Repeat([]byte{2}, atRestKeySize)
+	old, _ := NewLocalKeyProvider(oldKey)
+	system.SetKeyProvider(old)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		ev, err := system.IngestEvidence(writeDistinctClip(t, tmpDir, i), "CASE-ROT-001", "OFF-140"+string(rune('0'+i)), "", "", nil)
+		if err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+		ids = append(ids, ev.ID)
+	}
+	if _, err := system.SealEvidence(ids[1], "Court order 26-114"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	before, _ := system.GetEvidence(ids[0])
+	revision := before.Revision
+
+	rotating, _ := NewLocalKeyProvider(newKey, oldKey)
+	system.SetKeyProvider(rotating)
+	rotation, err := system.RotateDataKeys("CUS-001")
+	if err != nil || rotation.Rewrapped != 2 || len(rotation.Failed) != 0 || rotation.KeyID != rotating.KeyID() {
+		t.Fatalf("expected both data keys re-wrapped, got %+v %v", rotation, err)
+	}
+
+	current, _ := NewLocalKeyProvider(newKey)
+	system.SetKeyProvider(current)
+	after, _ := system.GetEvidence(ids[0])
+	if after.Encryption.KeyID != current.KeyID() || after.Revision != revision {
+		t.Errorf("expected the new key and an unchanged revision, got %s revision %d", after.Encryption.KeyID, after.Revision)
+	}
+	if ok, err := system.VerifyIntegrity(ids[0], "CUS-001"); err != nil || !ok {
+		t.Errorf("expected the recording to read under the new key alone, got %v %v", ok, err)
+	}
+	if ok, err := system.VerifySeal(ids[1]); err != nil || !ok {
+		t.Errorf("expected the seal to survive rotation, got %v %v", ok, err)
+	}
+	audited := false
+	for _, log := range system.GetAuditLogs(ids[1], "CUS-001") {
+		audited = audited || log.Action == "REWRAP_DATA_KEY"
+	}
+	if !audited {
+		t.Error("expected the re-wrap to be audited")
+	}
+}
+
+// fakeKMS emulates the AWS KMS actions the provider uses. Its ciphertext is
+// the key ID, context and plaintext in JSON.
+func fakeKMS(t *testing.T) *httptest.Server {
+	type blob struct {
+		KeyID   string
+		Context map[string]string
+		Data    []byte
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-gov-west-1/kms/aws4_request") {
+			t.Errorf("expected a SigV4 signature for kms, got %q", r.Header.Get("Authorization"))
+		}
+		var in struct {
+			KeyId, SourceKeyId, DestinationKeyId                                     string
+			Plaintext, CiphertextBlob                                                []byte
+			EncryptionContext, SourceEncryptionContext, DestinationEncryptionContext map[string]string
+		}
+		json.NewDecoder(r.Body).Decode(&in)
+		seal := func(keyID string, ctx map[string]string, data []byte) map[string]interface{} {
+			sealed, _ := json.Marshal(blob{KeyID: keyID, Context: ctx, Data: data})
+			return map[string]interface{}{"CiphertextBlob": sealed, "KeyId": "arn:aws:kms:key/" + keyID}
+		}
+		open := func(ctx map[string]string) (*blob, bool) {
+			var b blob
+			if json.Unmarshal(in.CiphertextBlob, &b) != nil || b.Context["evidence_id"] != ctx["evidence_id"] {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"__type": "InvalidCiphertextException", "message": "context mismatch"})
+				return nil, false
+			}
+			return &b, true
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.NewEncoder(w).Encode(seal(in.KeyId, in.EncryptionContext, in.Plaintext))
+		case "TrentService.Decrypt":
+			if b, ok := open(in.EncryptionContext); ok {
+				json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": b.Data})
+			}
+		case "TrentService.ReEncrypt":
+			if b, ok := open(in.SourceEncryptionContext); ok {
+				json.NewEncoder(w).Encode(seal(in.DestinationKeyId, in.DestinationEncryptionContext, b.Data))
+			}
+		default:
+			t.Errorf("unexpected KMS action %q", r.Header.Get("X-Amz-Target"))
+		}
+	}))
+}
+
+func TestAWSKMSProvider(t *testing.T) {
+	server := fakeKMS(t)
+	defer server.Close()
+	cfg := KMSConfig{Provider: "aws", KeyID: "alias/bwc", Region: "us-gov-west-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	provider := newAWSKMSProvider(cfg)
+
+	dataKey := bytes.Repeat([]byte{3}, atRestKeySize)
+	keyID, wrapped, err := provider.WrapKey(dataKey, "BWC-A")
+	if err != nil || keyID != "arn:aws:kms:key/alias/bwc" {
+		t.Fatalf("WrapKey failed: %s %v", keyID, err)
+	}
+	if key, err := provider.UnwrapKey(keyID, wrapped, "BWC-A"); err != nil || !bytes.Equal(key, dataKey) {
+		t.Errorf("expected the data key back, got %v", err)
+	}
+	if _, err := provider.UnwrapKey(keyID, wrapped, "BWC-B"); err == nil || !strings.Contains(err.Error(), "InvalidCiphertextException") {
+		t.Errorf("expected KMS to refuse another evidence's context, got %v", err)
+	}
+
+	cfg.KeyID = "alias/bwc-2026"
+	newID, rewrapped, err := newAWSKMSProvider(cfg).RewrapKey(keyID, wrapped, "BWC-A")
+	if err != nil || newID != "arn:aws:kms:key/alias/bwc-2026" {
+		t.Fatalf("RewrapKey failed: %s %v", newID, err)
+	}
+	if key, err := provider.UnwrapKey(newID, rewrapped, "BWC-A"); err != nil || !bytes.Equal(key, dataKey) {
+		t.Errorf("expected the re-wrapped data key to unwrap, got %v", err)
+	}
+}
+
+func TestVaultTransitProvider(t *testing.T) {
+	version := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string][]string{"errors": {"permission denied"}})
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		data := map[string]string{}
+		switch r.URL.Path {
+		case "/v1/keys/encrypt/bwc":
+			data["ciphertext"] = "vault:v" + string(rune('0'+version)) + ":" + in["plaintext"]
+		case "/v1/keys/decrypt/bwc":
+			data["plaintext"] = in["ciphertext"][strings.LastIndex(in["ciphertext"], ":")+1:]
+		case "/v1/keys/rewrap/bwc":
+			data["ciphertext"] = "vault:v" + string(rune('0'+version)) + in["ciphertext"][strings.LastIndex(in["ciphertext"], ":"):]
+		default:
+			t.Errorf("unexpected Vault path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer server.Close()
+	provider := newVaultTransitProvider(KMSConfig{Provider: "vault", KeyID: "bwc", Endpoint: server.URL, Token: "s.token", Mount: "keys"})
+
+	dataKey := bytes.Repeat([]byte{4}, atRestKeySize)
+	keyID, wrapped, err := provider.WrapKey(dataKey, "BWC-A")
+	if err != nil || keyID != "vault:keys/bwc:v1" {
+		t.Fatalf("WrapKey failed: %s %v", keyID, err)
+	}
+	if key, err := provider.UnwrapKey(keyID, wrapped, "BWC-A"); err != nil || !bytes.Equal(key, dataKey) {
+		t.Errorf("expected the data key back, got %v", err)
+	}
+	if _, err := provider.UnwrapKey(keyID, wrapped, "BWC-B"); err == nil {
+		t.Error("expected a data key moved to other evidence not to unwrap")
+	}
+
+	version = 2
+	newID, rewrapped, err := provider.RewrapKey(keyID, wrapped, "BWC-A")
+	if err != nil || newID != "vault:keys/bwc:v2" || !strings.HasPrefix(string(rewrapped), "vault:v2:") {
+		t.Fatalf("RewrapKey failed: %s %q %v", newID, rewrapped, err)
+	}
+
+	provider.token = "wrong"
+	if _, _, err := provider.WrapKey(dataKey, "BWC-A"); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected Vault's error to be reported, got %v", err)
+	}
+}
+
+func TestKeyProviderConfigValidation(t *testing.T) {
+	for provider, want := range map[string]string{
+		"vault": "security.kms provider vault requires key_id",
+		"aws":   "security.kms provider aws requires key_id and region",
+		"hsm":   `security.kms.provider "hsm" is not one of local, aws, vault`,
+	} {
+		cfg := DefaultConfig()
+		cfg.Security.EnableEncryption = true
+		cfg.Security.KMS.Provider = provider
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected %q, got %v", provider, want, err)
+		}
+	}
+}
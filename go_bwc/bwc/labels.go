@@ -0,0 +1,171 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image/png"
+	"net/url"
+	"strings"
+)
+
+// labelHashPrefixLen is how many hash characters are printed on a label
+const labelHashPrefixLen = 16
+
+// EvidenceLabel holds the fields printed on a physical evidence label
+type EvidenceLabel struct {
+	EvidenceID string `json:"evidence_id"`
+	CaseNumber string `json:"case_number"`
+	HashPrefix string `json:"hash_prefix"`
+	// Code is the content of the label's QR code
+	Code string `json:"code"`
+}
+
+// EvidenceLabel returns the label fields for evidence
+func (bwc *BWCSystem) EvidenceLabel(evidenceID string) (*EvidenceLabel, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+
+	hashPrefix := evidence.FileHash
+	if len(hashPrefix) > labelHashPrefixLen {
+		hashPrefix = hashPrefix[:labelHashPrefixLen]
+	}
+
+	return &EvidenceLabel{
+		EvidenceID: evidence.ID,
+		CaseNumber: evidence.CaseNumber,
+		HashPrefix: hashPrefix,
+		Code:       labelCode(bwc.config.Labels.VerifyBaseURL, evidence.ID),
+	}, nil
+}
+
+// GenerateLabelSVG renders a printable 3.5in x 1.5in SVG label for evidence
+func (bwc *BWCSystem) GenerateLabelSVG(evidenceID, requestedBy string) ([]byte, error) {
+	if err := bwc.authorize(requestedBy, PermViewEvidence, "Label", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.rejectIfRestricted(evidenceID, requestedBy, "Label"); err != nil {
+		return nil, err
+	}
+	label, err := bwc.EvidenceLabel(evidenceID)
+	if err != nil {
+		return nil, err
+	}
+
+	qr, err := encodeQR([]byte(label.Code))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode label QR code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprint(&buf, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprint(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="3.5in" height="1.5in" viewBox="0 0 350 150">`+"\n")
+	fmt.Fprint(&buf, `<rect width="350" height="150" fill="#fff"/>`+"\n")
+
+	// QR code with its quiet zone in a 150x150 square
+	module := 150.0 / float64(qr.Size+8)
+	fmt.Fprintf(&buf, `<g transform="translate(%.3f %.3f) scale(%.4f)" fill="#000">`+"\n", 4*module, 4*module, module)
+	var path strings.Builder
+	for y := 0; y < qr.Size; y++ {
+		for x := 0; x < qr.Size; x++ {
+			if qr.Modules[y][x] {
+				fmt.Fprintf(&path, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	fmt.Fprintf(&buf, `<path d="%s" shape-rendering="crispEdges"/>`+"\n</g>\n", path.String())
+
+	fmt.Fprint(&buf, `<g font-family="monospace" fill="#000">`+"\n")
+	lines := []struct {
+		y    int
+		size int
+		bold bool
+		text string
+	}{
+		{28, 12, true, "EVIDENCE"},
+		{50, 8, true, label.EvidenceID},
+		{72, 10, false, "Case: " + label.CaseNumber},
+		{92, 10, false, "SHA-256: " + label.HashPrefix},
+		{130, 7, false, bwc.config.System.Name},
+	}
+	for _, line := range lines {
+		weight := "normal"
+		if line.bold {
+			weight = "bold"
+		}
+		fmt.Fprintf(&buf, `<text x="150" y="%d" font-size="%d" font-weight="%s">%s</text>`+"\n",
+			line.y, line.size, weight, xmlEscape(line.text))
+	}
+	fmt.Fprint(&buf, "</g>\n</svg>\n")
+
+	bwc.logAudit(requestedBy, "GENERATE_LABEL", evidenceID, "Evidence label generated", "")
+
+	return buf.Bytes(), nil
+}
+
+// GenerateLabelQRPNG renders the label's QR code alone as a PNG with the given module size
+func (bwc *BWCSystem) GenerateLabelQRPNG(evidenceID string, scale int) ([]byte, error) {
+	label, err := bwc.EvidenceLabel(evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	if scale < 1 {
+		scale = 1
+	}
+
+	qr, err := encodeQR([]byte(label.Code))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode label QR code: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, qr.Image(scale)); err != nil {
+		return nil, fmt.Errorf("failed to encode QR image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// labelCode returns the QR content for an evidence ID
+func labelCode(verifyBaseURL, evidenceID string) string {
+	if verifyBaseURL == "" {
+		return evidenceID
+	}
+	return strings.TrimRight(verifyBaseURL, "/") + "/" + url.PathEscape(evidenceID)
+}
+
+// ParseLabelCode extracts the evidence ID from a scanned label code, which is
+// either a bare evidence ID or a verification URL ending in one
+func ParseLabelCode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return "", errors.New("empty label code")
+	}
+
+	if !strings.Contains(code, "://") {
+		return code, nil
+	}
+
+	u, err := url.Parse(code)
+	if err != nil {
+		return "", fmt.Errorf("invalid label URL: %w", err)
+	}
+	segment := u.EscapedPath()
+	segment = segment[strings.LastIndex(segment, "/")+1:]
+	evidenceID, err := url.PathUnescape(segment)
+	if err != nil || evidenceID == "" {
+		return "", errors.New("label URL does not contain an evidence ID")
+	}
+	return evidenceID, nil
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -0,0 +1,119 @@
+package bwc
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestEvidenceLabel(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-LBL-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	label, err := system.EvidenceLabel(evidence.ID)
+	if err != nil {
+		t.Fatalf("EvidenceLabel failed: %v", err)
+	}
+	if label.Code != evidence.ID || label.HashPrefix != evidence.FileHash[:labelHashPrefixLen] {
+		t.Errorf("Unexpected label without verify URL: %+v", label)
+	}
+
+	system.config.Labels.VerifyBaseURL = "https://evidence.example.gov/verify/"
+	label, _ = system.EvidenceLabel(evidence.ID)
+	if label.Code != "https://evidence.example.gov/verify/"+evidence.ID {
+		t.Errorf("Unexpected verification URL %s", label.Code)
+	}
+
+	if _, err := system.EvidenceLabel("INVALID-ID"); err == nil {
+		t.Error("Expected error for unknown evidence")
+	}
+}
+
+func TestGenerateLabelSVG(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-<LBL>&002", "OFF-123", "Officer Test", "Test Location", nil)
+
+	svg, err := system.GenerateLabelSVG(evidence.ID, "CUS-001")
+	if err != nil {
+		t.Fatalf("GenerateLabelSVG failed: %v", err)
+	}
+
+	out := string(svg)
+	if !strings.HasPrefix(out, "<?xml") || !strings.Contains(out, "<path d=\"M") {
+		t.Error("Expected SVG document with QR path")
+	}
+	if !strings.Contains(out, "Case: CASE-&lt;LBL&gt;&amp;002") {
+		t.Error("Expected escaped case number on label")
+	}
+	if !strings.Contains(out, "SHA-256: "+evidence.FileHash[:labelHashPrefixLen]) {
+		t.Error("Expected hash prefix on label")
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "CUS-001")
+	if len(logs) != 1 || logs[0].Action != "GENERATE_LABEL" {
+		t.Errorf("Expected GENERATE_LABEL audit entry, got %v", logs)
+	}
+}
+
+func TestGenerateLabelQRPNG(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-LBL-003", "OFF-123", "Officer Test", "Test Location", nil)
+
+	data, err := system.GenerateLabelQRPNG(evidence.ID, 4)
+	if err != nil {
+		t.Fatalf("GenerateLabelQRPNG failed: %v", err)
+	}
+	if _, err := png.Decode(bytes.NewReader(data)); err != nil {
+		t.Errorf("Expected valid PNG: %v", err)
+	}
+}
+
+func TestParseLabelCode(t *testing.T) {
+	tests := []struct {
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{"BWC-CASE-1-OFF-1-1700000000", "BWC-CASE-1-OFF-1-1700000000", false},
+		{" BWC-CASE-1-OFF-1-1700000000\n", "BWC-CASE-1-OFF-1-1700000000", false},
+		{"https://evidence.example.gov/verify/BWC-CASE%2F1-OFF-1-1", "BWC-CASE/1-OFF-1-1", false},
+		{"https://evidence.example.gov/verify/", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLabelCode(tt.code)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLabelCode(%q) = %q, %v; want %q (error %v)", tt.code, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// Labels round-trip through their own codes
+	code := labelCode("https://evidence.example.gov/verify", "BWC-CASE/1-OFF-1-1")
+	if got, _ := ParseLabelCode(code); got != "BWC-CASE/1-OFF-1-1" {
+		t.Errorf("Expected label code to round trip, got %q", got)
+	}
+}
+
+func TestLabelsConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Labels.VerifyBaseURL = "evidence.example.gov/verify"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected error for relative verify_base_url")
+	}
+
+	cfg.Labels.VerifyBaseURL = "https://evidence.example.gov/verify"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected valid config: %v", err)
+	}
+}
@@ -0,0 +1,295 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// The commands in this file work directly on the storage named by the
+// configuration, so they are for an administrator on the evidence host rather
+// than a workstation talking to a server. Each takes -json to print a
+// machine-readable result instead of text.
+
+// openLocalSystem opens the configured system for a local command, which
+// would see nothing of earlier runs with an in-memory database
+func openLocalSystem(configPath string) (*BWCSystem, error) {
+	cfg, err := LoadRuntimeConfig(resolveConfigPath(configPath))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Database.Type == "memory" {
+		return nil, fmt.Errorf("the configured database is in memory; set database.type to json, sqlite, bbolt or postgres")
+	}
+	return NewBWCSystemFromConfig(cfg)
+}
+
+// interruptContext returns a context that is cancelled by an interrupt or
+// SIGTERM, so a long hash or copy can be stopped cleanly
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// pageFlags adds the paging flags of search and audit to flags
+func pageFlags(flags *flag.FlagSet) *QueryOptions {
+	opts := &QueryOptions{}
+	flags.IntVar(&opts.Limit, "limit", 0, "return at most this many results")
+	flags.IntVar(&opts.Offset, "offset", 0, "skip this many results")
+	flags.StringVar(&opts.Cursor, "cursor", "", "continue from the cursor printed after a previous page")
+	flags.BoolVar(&opts.Descending, "desc", false, "newest first")
+	return opts
+}
+
+// printNextCursor tells the user how to fetch the page after this one
+func printNextCursor(stderr io.Writer, next string) {
+	if next != "" {
+		fmt.Fprintf(stderr, "More results: -cursor %s\n", next)
+	}
+}
+
+// writeCommandJSON prints v as indented JSON for -json output
+func writeCommandJSON(w io.Writer, v interface{}) int {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// verifyResult is one line of "verify -json" output
+type verifyResult struct {
+	EvidenceID string `json:"evidence_id"`
+	Valid      bool   `json:"valid"`
+	Error      string `json:"error,omitempty"`
+}
+
+// runVerifyCommand implements "verify": it checks each evidence item's
+// recording against its recorded hash and exits 1 if any fails
+func runVerifyCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	userID := flags.String("user", "", "user performing the check")
+	asJSON := flags.Bool("json", false, "print the results as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *userID == "" || flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "Usage: bwc-system verify -user ID [-json] [-config path] evidence-id...")
+		return 2
+	}
+
+	system, err := openLocalSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	code := 0
+	results := make([]verifyResult, 0, flags.NArg())
+	for _, id := range flags.Args() {
+		if ctx.Err() != nil {
+			fmt.Fprintln(stderr, "Interrupted")
+			return 1
+		}
+		result := verifyResult{EvidenceID: id}
+		result.Valid, err = system.VerifyIntegrityContext(ctx, id, *userID)
+		if err != nil {
+			result.Error = err.Error()
+		}
+		if !result.Valid {
+			code = 1
+		}
+		results = append(results, result)
+	}
+
+	if *asJSON {
+		if writeCommandJSON(stdout, results) != 0 {
+			return 1
+		}
+		return code
+	}
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(stdout, "%s: error: %s\n", result.EvidenceID, result.Error)
+		case result.Valid:
+			fmt.Fprintf(stdout, "%s: valid\n", result.EvidenceID)
+		default:
+			fmt.Fprintf(stdout, "%s: INTEGRITY FAILURE\n", result.EvidenceID)
+		}
+	}
+	return code
+}
+
+// runTransferCommand implements "transfer"
+func runTransferCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("transfer", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	from := flags.String("from", "", "officer handing over the evidence")
+	to := flags.String("to", "", "officer receiving the evidence")
+	purpose := flags.String("purpose", "", "reason for the transfer")
+	asJSON := flags.Bool("json", false, "print the updated record as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *from == "" || *to == "" || *purpose == "" || flags.NArg() != 1 {
+		fmt.Fprintln(stderr, "Usage: bwc-system transfer -from ID -to ID -purpose text [-json] [-config path] evidence-id")
+		return 2
+	}
+
+	system, err := openLocalSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := system.TransferCustody(flags.Arg(0), *from, *to, *purpose); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	evidence, err := system.GetEvidence(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return writeCommandJSON(stdout, evidence)
+	}
+	fmt.Fprintf(stdout, "Transferred %s from %s to %s (%d custody entries)\n", evidence.ID, *from, *to, len(evidence.ChainOfCustody))
+	return 0
+}
+
+// runSearchCommand implements "search"
+func runSearchCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	caseNumber := flags.String("case", "", "case number")
+	officerID := flags.String("officer", "", "recording officer")
+	status := flags.String("status", "", "evidence status")
+	sortBy := flags.String("sort", string(SortByTimestamp), "order by timestamp, case_number or status")
+	opts := pageFlags(flags)
+	asJSON := flags.Bool("json", false, "print the matching records as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "Usage: bwc-system search [-case c] [-officer id] [-status s] [-sort f] [-limit n] [-cursor c] [-json] [-config path]")
+		return 2
+	}
+
+	system, err := openLocalSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	opts.SortBy = SortField(*sortBy)
+	filter := EvidenceFilter{CaseNumber: *caseNumber, OfficerID: *officerID, Status: EvidenceStatus(strings.ToUpper(*status))}
+	results, err := system.SearchEvidencePage(systemContext(context.Background()), filter, *opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	printNextCursor(stderr, results.NextCursor)
+	if *asJSON {
+		return writeCommandJSON(stdout, results.Evidence)
+	}
+	for _, evidence := range results.Evidence {
+		fmt.Fprintf(stdout, "%s  %-12s  %-10s  %s  %s\n", evidence.ID, evidence.CaseNumber, evidence.Status,
+			evidence.OfficerID, evidence.Timestamp.Format(time.RFC3339))
+	}
+	fmt.Fprintf(stdout, "%d of %d evidence items\n", len(results.Evidence), results.Total)
+	return 0
+}
+
+// runReportCommand implements "report"
+func runReportCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	caseNumber := flags.String("case", "", "case number")
+	profileName := flags.String("profile", string(ReportProfileInternal), "report profile: internal, court or public")
+	asJSON := flags.Bool("json", false, "wrap the report in a JSON object")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *caseNumber == "" || flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "Usage: bwc-system report -case c [-profile p] [-json] [-config path]")
+		return 2
+	}
+	profile, err := ParseReportProfile(*profileName)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	system, err := openLocalSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	report, err := system.GenerateProfiledReport(*caseNumber, profile)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		return writeCommandJSON(stdout, map[string]string{
+			"case_number": *caseNumber,
+			"profile":     string(profile),
+			"report":      report,
+		})
+	}
+	fmt.Fprint(stdout, report)
+	return 0
+}
+
+// runAuditCommand implements "audit"
+func runAuditCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("audit", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("config", "", "path to the configuration file")
+	evidenceID := flags.String("evidence", "", "only entries for this evidence item")
+	userID := flags.String("user", "", "only entries by this user")
+	action := flags.String("action", "", "only entries with this action")
+	opts := pageFlags(flags)
+	asJSON := flags.Bool("json", false, "print the entries as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		fmt.Fprintln(stderr, "Usage: bwc-system audit [-evidence id] [-user id] [-action a] [-limit n] [-cursor c] [-json] [-config path]")
+		return 2
+	}
+
+	system, err := openLocalSystem(*path)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	filter := AuditFilter{EvidenceID: *evidenceID, UserID: *userID, Action: strings.ToUpper(*action)}
+	logs, err := system.GetAuditLogsPage(filter, *opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 2
+	}
+	printNextCursor(stderr, logs.NextCursor)
+	if *asJSON {
+		return writeCommandJSON(stdout, logs.Entries)
+	}
+	for _, log := range logs.Entries {
+		fmt.Fprintf(stdout, "%s  %-20s  %-10s  %s  %s\n", log.Timestamp.Format(time.RFC3339), log.Action,
+			log.UserID, log.EvidenceID, log.Details)
+	}
+	return 0
+}
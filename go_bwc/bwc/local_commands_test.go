@@ -0,0 +1,87 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTestCommand runs a command line and returns its exit code and output
+func runTestCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := RunCommand(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestLocalCommandsWorkOnConfiguredStorage(t *testing.T) {
+	tmpDir := t.TempDir()
+	config := writeTestConfig(t, tmpDir, fmt.Sprintf(`{"storage": {"path": %q}, "database": {"type": "json"}}`, filepath.Join(tmpDir, "storage")))
+
+	code, out, errOut := runTestCommand("ingest", "-local", "-config", config, "-case", "CASE-CLI-001",
+		"-officer", "OFF-1190", "-tags", "traffic", "-json", createTestFile(t, tmpDir))
+	if code != 0 {
+		t.Fatalf("ingest exited %d: %s", code, errOut)
+	}
+	var evidence Evidence
+	if err := json.Unmarshal([]byte(out), &evidence); err != nil || evidence.ID == "" || len(evidence.Tags) != 1 {
+		t.Fatalf("expected the ingested record as JSON, got %q, %v", out, err)
+	}
+
+	code, out, errOut = runTestCommand("transfer", "-config", config, "-from", "OFF-1190", "-to", "DET-1", "-purpose", "Analysis", evidence.ID)
+	if code != 0 || !strings.Contains(out, "Transferred "+evidence.ID) {
+		t.Fatalf("transfer exited %d: %s%s", code, out, errOut)
+	}
+
+	code, out, _ = runTestCommand("search", "-config", config, "-case", "CASE-CLI-001", "-json")
+	var results []Evidence
+	if err := json.Unmarshal([]byte(out), &results); code != 0 || err != nil || len(results) != 1 || len(results[0].ChainOfCustody) != 2 {
+		t.Fatalf("expected the transferred record found, got %d %q, %v", code, out, err)
+	}
+	if _, out, _ = runTestCommand("search", "-config", config, "-case", "CASE-NONE", "-json"); strings.TrimSpace(out) != "[]" {
+		t.Errorf("expected an empty JSON list for no matches, got %q", out)
+	}
+
+	code, out, _ = runTestCommand("verify", "-config", config, "-user", "AUDITOR-1", "-json", evidence.ID, "BWC-missing")
+	var verified []verifyResult
+	if err := json.Unmarshal([]byte(out), &verified); err != nil || len(verified) != 2 {
+		t.Fatalf("expected a result per item, got %q, %v", out, err)
+	}
+	if code != 1 || !verified[0].Valid || verified[1].Valid || verified[1].Error == "" {
+		t.Errorf("expected the missing item to fail the check, got %d %+v", code, verified)
+	}
+
+	code, out, _ = runTestCommand("audit", "-config", config, "-evidence", evidence.ID, "-action", "transfer_custody", "-json")
+	var logs []AuditLog
+	if err := json.Unmarshal([]byte(out), &logs); code != 0 || err != nil || len(logs) != 1 || logs[0].Action != "TRANSFER_CUSTODY" {
+		t.Errorf("expected the transfer in the audit log, got %q, %v", out, err)
+	}
+
+	code, out, _ = runTestCommand("report", "-config", config, "-case", "CASE-CLI-001", "-profile", "court", "-json")
+	var report map[string]string
+	if err := json.Unmarshal([]byte(out), &report); code != 0 || err != nil || report["profile"] != "court" || !strings.Contains(report["report"], evidence.ID) {
+		t.Errorf("expected the court report as JSON, got %q, %v", out, err)
+	}
+}
+
+func TestLocalCommandsRejectBadUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"ingest", "-local", "-case", "CASE-1", "clip.mp4"},
+		{"verify", "BWC-1"},
+		{"transfer", "-from", "A", "-to", "B", "BWC-1"},
+		{"report"},
+		{"report", "-case", "CASE-1", "-profile", "press"},
+		{"audit", "extra"},
+	} {
+		if code, _, _ := runTestCommand(args...); code != 2 {
+			t.Errorf("expected %v to be refused as a usage error, got %d", args, code)
+		}
+	}
+
+	config := writeTestConfig(t, t.TempDir(), `{"database": {"type": "memory"}}`)
+	if code, _, errOut := runTestCommand("search", "-config", config); code != 1 || !strings.Contains(errOut, "in memory") {
+		t.Errorf("expected an in-memory database to be refused, got %d %q", code, errOut)
+	}
+}
@@ -0,0 +1,299 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Place is the structured form of an evidence location
+type Place struct {
+	Street     string  `json:"street,omitempty"`
+	City       string  `json:"city,omitempty"`
+	Region     string  `json:"region,omitempty"`
+	PostalCode string  `json:"postal_code,omitempty"`
+	Country    string  `json:"country,omitempty"`
+	Latitude   float64 `json:"latitude"`
+	Longitude  float64 `json:"longitude"`
+	// Formatted is the full address as the geocoder or user gave it
+	Formatted string `json:"formatted,omitempty"`
+	// Source is the geocoder that resolved the place, or "manual"
+	Source     string    `json:"source"`
+	ResolvedAt time.Time `json:"resolved_at"`
+}
+
+func (p *Place) validate() error {
+	if math.IsNaN(p.Latitude) || p.Latitude < -90 || p.Latitude > 90 {
+		return fmt.Errorf("latitude %v is out of range", p.Latitude)
+	}
+	if math.IsNaN(p.Longitude) || p.Longitude < -180 || p.Longitude > 180 {
+		return fmt.Errorf("longitude %v is out of range", p.Longitude)
+	}
+	return nil
+}
+
+// Geocoder resolves a free-text location to a place. It returns
+// errLocationNotFound when nothing matches.
+type Geocoder interface {
+	Name() string
+	Geocode(ctx context.Context, location string) (*Place, error)
+}
+
+var errLocationNotFound = errors.New("location not found")
+
+// defaultGeocodeTimeout bounds a geocoding lookup when none is configured
+const defaultGeocodeTimeout = 5 * time.Second
+
+// SetGeocoder sets the geocoder used at ingest; nil turns geocoding off
+func (bwc *BWCSystem) SetGeocoder(g Geocoder) {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	bwc.geocoder = g
+}
+
+// geocode resolves location with the configured geocoder. It returns nil
+// without error when no geocoder is set or the location is empty. It must
+// not be called with bwc.mu held.
+func (bwc *BWCSystem) geocode(location string) (*Place, error) {
+	bwc.mu.RLock()
+	g := bwc.geocoder
+	bwc.mu.RUnlock()
+	if g == nil || strings.TrimSpace(location) == "" {
+		return nil, nil
+	}
+
+	timeout := defaultGeocodeTimeout
+	if s := bwc.config.Geocoding.TimeoutSeconds; s > 0 {
+		timeout = time.Duration(s) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	place, err := g.Geocode(ctx, location)
+	if err == nil && place == nil {
+		err = errLocationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("geocoder %s: %w", g.Name(), err)
+	}
+	if err := place.validate(); err != nil {
+		return nil, fmt.Errorf("geocoder %s: %w", g.Name(), err)
+	}
+	resolved := *place
+	resolved.Source = g.Name()
+	resolved.ResolvedAt = time.Now()
+	return &resolved, nil
+}
+
+// SetPlace records or corrects the structured location of evidence. The
+// free-text location is kept as entered.
+func (bwc *BWCSystem) SetPlace(evidenceID, userID string, place Place) error {
+	return bwc.SetPlaceIfRevision(evidenceID, userID, place, 0)
+}
+
+// SetPlaceIfRevision sets the structured location of evidence if it is still
+// at revision, returning a *RevisionConflictError otherwise
+func (bwc *BWCSystem) SetPlaceIfRevision(evidenceID, userID string, place Place, revision int64) error {
+	if err := bwc.authorize(userID, PermEditMetadata, "Place update", evidenceID); err != nil {
+		return err
+	}
+	if err := place.validate(); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Location update"); err != nil {
+		return err
+	}
+	if err := bwc.checkRevisionLocked(evidence, userID, revision); err != nil {
+		return err
+	}
+
+	place.Source = "manual"
+	place.ResolvedAt = time.Now()
+	evidence.Place = &place
+	markModified(evidence, time.Now())
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	bwc.logAudit(userID, "UPDATE_LOCATION", evidenceID,
+		fmt.Sprintf("Location set to %.6f,%.6f %s", place.Latitude, place.Longitude, place.Formatted), "")
+
+	return nil
+}
+
+// SearchNear returns evidence with a structured location within radiusMeters
+// of the given point, nearest first
+func (bwc *BWCSystem) SearchNear(latitude, longitude, radiusMeters float64) []*Evidence {
+	return bwc.SearchNearContext(systemContext(context.Background()), latitude, longitude, radiusMeters)
+}
+
+// SearchNearContext searches like SearchNear, leaving out restricted evidence
+// the principal ctx carries is not on the access list of. With
+// audit.log_all_access on, each record returned is audited as read.
+func (bwc *BWCSystem) SearchNearContext(ctx context.Context, latitude, longitude, radiusMeters float64) []*Evidence {
+	bwc.mu.RLock()
+	reader := readerFromContext(ctx)
+	results := make([]*Evidence, 0)
+	distances := make(map[string]float64)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Place == nil || !evidence.ACL.allows(reader) {
+			continue
+		}
+		d := distanceMeters(latitude, longitude, evidence.Place.Latitude, evidence.Place.Longitude)
+		if d <= radiusMeters {
+			results = append(results, evidence)
+			distances[evidence.ID] = d
+		}
+	}
+	bwc.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		return distances[results[i].ID] < distances[results[j].ID]
+	})
+	bwc.auditReads(ctx, "SEARCH_EVIDENCE", results,
+		fmt.Sprintf("Returned by a search within %.0fm of %.6f,%.6f", radiusMeters, latitude, longitude))
+	return results
+}
+
+// distanceMeters is the great-circle distance between two points
+func distanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// nominatimGeocoder queries a Nominatim-compatible search API, such as a
+// self-hosted OpenStreetMap instance
+type nominatimGeocoder struct {
+	url       string
+	userAgent string
+	client    *http.Client
+}
+
+func newNominatimGeocoder(cfg GeocodingConfig) *nominatimGeocoder {
+	return &nominatimGeocoder{url: cfg.URL, userAgent: cfg.UserAgent, client: &http.Client{}}
+}
+
+func (g *nominatimGeocoder) Name() string { return "nominatim" }
+
+func (g *nominatimGeocoder) Geocode(ctx context.Context, location string) (*Place, error) {
+	query := url.Values{
+		"q":              {location},
+		"format":         {"jsonv2"},
+		"addressdetails": {"1"},
+		"limit":          {"1"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if g.userAgent != "" {
+		req.Header.Set("User-Agent", g.userAgent)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	var matches []struct {
+		Lat         string            `json:"lat"`
+		Lon         string            `json:"lon"`
+		DisplayName string            `json:"display_name"`
+		Address     map[string]string `json:"address"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&matches); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	if len(matches) == 0 {
+		return nil, errLocationNotFound
+	}
+
+	m := matches[0]
+	lat, errLat := strconv.ParseFloat(m.Lat, 64)
+	lon, errLon := strconv.ParseFloat(m.Lon, 64)
+	if errLat != nil || errLon != nil {
+		return nil, fmt.Errorf("invalid coordinates %q,%q", m.Lat, m.Lon)
+	}
+	street := strings.TrimSpace(m.Address["house_number"] + " " + m.Address["road"])
+	return &Place{
+		Street:     street,
+		City:       firstNonEmpty(m.Address["city"], m.Address["town"], m.Address["village"]),
+		Region:     m.Address["state"],
+		PostalCode: m.Address["postcode"],
+		Country:    strings.ToUpper(m.Address["country_code"]),
+		Latitude:   lat,
+		Longitude:  lon,
+		Formatted:  m.DisplayName,
+	}, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// defaultSearchRadius is used by the API when a near search gives no radius
+const defaultSearchRadius = 1000.0
+
+// parseNearQuery parses the "near=lat,lon" and "radius=meters" search parameters
+func parseNearQuery(near, radius string) (lat, lon, meters float64, err error) {
+	parts := strings.Split(near, ",")
+	if len(parts) != 2 {
+		return 0, 0, 0, errors.New("near must be latitude,longitude")
+	}
+	place := Place{}
+	place.Latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err == nil {
+		place.Longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	}
+	if err != nil {
+		return 0, 0, 0, errors.New("near must be latitude,longitude")
+	}
+	if err := place.validate(); err != nil {
+		return 0, 0, 0, err
+	}
+
+	meters = defaultSearchRadius
+	if radius != "" {
+		meters, err = strconv.ParseFloat(radius, 64)
+		if err != nil || meters <= 0 || math.IsInf(meters, 0) {
+			return 0, 0, 0, errors.New("radius must be a positive number of meters")
+		}
+	}
+	return place.Latitude, place.Longitude, meters, nil
+}
@@ -0,0 +1,145 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// funcGeocoder is a Geocoder backed by a function
+type funcGeocoder func(string) (*Place, error)
+
+func (g funcGeocoder) Name() string { return "test" }
+
+func (g funcGeocoder) Geocode(_ context.Context, location string) (*Place, error) {
+	return g(location)
+}
+
+func TestGeocodeAtIngest(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.SetGeocoder(funcGeocoder(func(location string) (*Place, error) {
+		switch location {
+		case "123 Main St, Springfield":
+			return &Place{Street: "123 Main St", City: "Springfield", Latitude: 39.7817, Longitude: -89.6501}, nil
+		case "Somewhere":
+			return nil, errLocationNotFound
+		}
+		return &Place{Latitude: 120}, nil
+	}))
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, err := system.IngestEvidence(testFile, "CASE-GEO-001", "OFF-971", "Officer Test", "123 Main St, Springfield", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if p := evidence.Place; p == nil || p.City != "Springfield" || p.Source != "test" || p.ResolvedAt.IsZero() {
+		t.Fatalf("Expected the location geocoded, got %+v", p)
+	}
+
+	for i, location := range []string{"Somewhere", "Off the map"} {
+		evidence, err := system.IngestEvidence(testFile, "CASE-GEO-001", fmt.Sprintf("OFF-%d", 972+i), "Officer Test", location, nil)
+		if err != nil {
+			t.Fatalf("Expected a geocoding failure not to block ingest: %v", err)
+		}
+		if evidence.Place != nil || evidence.Location != location {
+			t.Errorf("Expected only the free-text location, got %+v", evidence.Place)
+		}
+		logs := system.GetAuditLogs(evidence.ID, "SYSTEM")
+		if len(logs) != 1 || logs[0].Action != "GEOCODE_FAILED" {
+			t.Errorf("Expected GEOCODE_FAILED audit entry, got %v", logs)
+		}
+	}
+}
+
+func TestSearchNear(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	near, _ := system.IngestEvidence(testFile, "CASE-GEO-002", "OFF-974", "Officer Test", "City Hall", nil)
+	nearer, _ := system.IngestEvidence(testFile, "CASE-GEO-002", "OFF-975", "Officer Test", "Main and 5th", nil)
+	far, _ := system.IngestEvidence(testFile, "CASE-GEO-002", "OFF-976", "Officer Test", "Airport", nil)
+	system.IngestEvidence(testFile, "CASE-GEO-002", "OFF-977", "Officer Test", "Unknown", nil)
+
+	system.SetPlace(near.ID, "RECORDS-1", Place{Latitude: 39.8017, Longitude: -89.6436, Formatted: "City Hall"})
+	system.SetPlace(nearer.ID, "RECORDS-1", Place{Latitude: 39.7830, Longitude: -89.6500})
+	system.SetPlace(far.ID, "RECORDS-1", Place{Latitude: 39.8441, Longitude: -89.6779})
+	if err := system.SetPlace(far.ID, "RECORDS-1", Place{Latitude: 91}); err == nil {
+		t.Error("Expected an out-of-range latitude to be refused")
+	}
+
+	results := system.SearchNear(39.7817, -89.6501, 3000)
+	if len(results) != 2 || results[0].ID != nearer.ID || results[1].ID != near.ID {
+		t.Fatalf("Expected the two nearby items nearest first, got %d results", len(results))
+	}
+	if near.Place.Source != "manual" {
+		t.Errorf("Expected a manual place, got %+v", near.Place)
+	}
+	if d := distanceMeters(39.7817, -89.6501, 39.8017, -89.6436); d < 2200 || d > 2300 {
+		t.Errorf("Unexpected distance %.0f", d)
+	}
+
+	logs := system.GetAuditLogs(near.ID, "RECORDS-1")
+	if len(logs) != 1 || logs[0].Action != "UPDATE_LOCATION" || !strings.Contains(logs[0].Details, "City Hall") {
+		t.Errorf("Expected UPDATE_LOCATION audit entry, got %v", logs)
+	}
+}
+
+func TestNominatimGeocoder(t *testing.T) {
+	var query, agent string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, agent = r.URL.Query().Get("q"), r.Header.Get("User-Agent")
+		if query == "nowhere" {
+			w.Write([]byte("[]"))
+			return
+		}
+		w.Write([]byte(`[{"lat":"39.7817","lon":"-89.6501","display_name":"123, Main Street, Springfield",
+			"address":{"house_number":"123","road":"Main Street","town":"Springfield","state":"Illinois","postcode":"62701","country_code":"us"}}]`))
+	}))
+	defer backend.Close()
+
+	g := newNominatimGeocoder(GeocodingConfig{URL: backend.URL, UserAgent: "bwc-test"})
+	place, err := g.Geocode(context.Background(), "123 Main St")
+	if err != nil {
+		t.Fatalf("Geocode failed: %v", err)
+	}
+	want := Place{Street: "123 Main Street", City: "Springfield", Region: "Illinois", PostalCode: "62701", Country: "US",
+		Latitude: 39.7817, Longitude: -89.6501, Formatted: "123, Main Street, Springfield"}
+	if *place != want || query != "123 Main St" || agent != "bwc-test" {
+		t.Errorf("Unexpected place %+v (query %q, agent %q)", place, query, agent)
+	}
+	if _, err := g.Geocode(context.Background(), "nowhere"); !errors.Is(err, errLocationNotFound) {
+		t.Errorf("Expected errLocationNotFound, got %v", err)
+	}
+}
+
+func TestServerSearchNear(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-GEO-003", "OFF-978", "Officer Test", "City Hall", nil)
+	system.SetPlace(evidence.ID, "RECORDS-1", Place{Latitude: 39.8017, Longitude: -89.6436})
+
+	resp := authGet(t, server, "/api/evidence?near=39.8,-89.64&radius=500")
+	var results []Evidence
+	json.NewDecoder(resp.Body).Decode(&results)
+	resp.Body.Close()
+	if len(results) != 1 || results[0].Place == nil {
+		t.Fatalf("Expected one result with its place, got %+v", results)
+	}
+
+	for _, path := range []string{"/api/evidence?near=39.8", "/api/evidence?near=95,0", "/api/evidence?near=39.8,-89.64&radius=-1"} {
+		resp := authGet(t, server, path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// operationKind identifies the class of operation being tracked for draining
+type operationKind int
+
+const (
+	opIngest operationKind = iota
+	opMutation
+	// opProcessing is a processing job, tracked from submission until it finishes
+	opProcessing
+)
+
+// StorageIssue describes a problem found while checking evidence storage
+type StorageIssue struct {
+	EvidenceID string `json:"evidence_id"`
+	FilePath   string `json:"file_path"`
+	Problem    string `json:"problem"`
+}
+
+// MaintenanceReport summarizes maintenance state and shutdown readiness
+type MaintenanceReport struct {
+	Active         bool           `json:"active"`
+	Since          time.Time      `json:"since"`
+	EnteredBy      string         `json:"entered_by"`
+	Reason         string         `json:"reason"`
+	InFlight       int            `json:"in_flight"`
+	Drained        bool           `json:"drained"`
+	StorageChecked bool           `json:"storage_checked"`
+	StorageIssues  []StorageIssue `json:"storage_issues"`
+	SafeToShutdown bool           `json:"safe_to_shutdown"`
+	GeneratedAt    time.Time      `json:"generated_at"`
+}
+
+// maintenanceState tracks maintenance mode and in-flight operations
+type maintenanceState struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	active    bool
+	since     time.Time
+	enteredBy string
+	reason    string
+	inFlight  int
+}
+
+func newMaintenanceState() *maintenanceState {
+	m := &maintenanceState{}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// beginOperation registers an in-flight operation, rejecting new ingests
+// while the system is in maintenance mode
+func (bwc *BWCSystem) beginOperation(kind operationKind) error {
+	m := bwc.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.active && kind == opIngest {
+		return errors.New("system is in maintenance mode - new ingests are not accepted")
+	}
+	if m.active && kind == opProcessing {
+		return errors.New("system is in maintenance mode - new processing jobs are not accepted")
+	}
+
+	m.inFlight++
+	return nil
+}
+
+// endOperation marks an in-flight operation as complete
+func (bwc *BWCSystem) endOperation() {
+	m := bwc.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.inFlight--
+	if m.inFlight == 0 {
+		m.cond.Broadcast()
+	}
+}
+
+// EnterMaintenance puts the system into maintenance mode so no new ingests are accepted
+func (bwc *BWCSystem) EnterMaintenance(officerID, reason string) error {
+	if err := bwc.authorize(officerID, PermAdminister, "Enter maintenance", ""); err != nil {
+		return err
+	}
+	m := bwc.maintenance
+	m.mu.Lock()
+	if m.active {
+		m.mu.Unlock()
+		return errors.New("system is already in maintenance mode")
+	}
+	m.active = true
+	m.since = time.Now()
+	m.enteredBy = officerID
+	m.reason = reason
+	inFlight := m.inFlight
+	m.mu.Unlock()
+
+	bwc.logAudit(officerID, "ENTER_MAINTENANCE", "",
+		fmt.Sprintf("Maintenance mode entered (%d operations in flight) - %s", inFlight, reason), "")
+
+	return nil
+}
+
+// ExitMaintenance returns the system to normal operation
+func (bwc *BWCSystem) ExitMaintenance(officerID string) error {
+	if err := bwc.authorize(officerID, PermAdminister, "Exit maintenance", ""); err != nil {
+		return err
+	}
+	m := bwc.maintenance
+	m.mu.Lock()
+	if !m.active {
+		m.mu.Unlock()
+		return errors.New("system is not in maintenance mode")
+	}
+	duration := time.Since(m.since)
+	m.active = false
+	m.since = time.Time{}
+	m.enteredBy = ""
+	m.reason = ""
+	m.mu.Unlock()
+
+	bwc.logAudit(officerID, "EXIT_MAINTENANCE", "",
+		fmt.Sprintf("Maintenance mode exited after %s", duration.Round(time.Second)), "")
+
+	return nil
+}
+
+// InMaintenance reports whether the system is currently in maintenance mode
+func (bwc *BWCSystem) InMaintenance() bool {
+	m := bwc.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.active
+}
+
+// WaitForDrain blocks until no operations are in flight or the timeout elapses.
+// It returns true if the system drained within the timeout.
+func (bwc *BWCSystem) WaitForDrain(timeout time.Duration) bool {
+	m := bwc.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	timedOut := false
+	timer := time.AfterFunc(timeout, func() {
+		m.mu.Lock()
+		timedOut = true
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	for m.inFlight > 0 && !timedOut {
+		m.cond.Wait()
+	}
+
+	return m.inFlight == 0
+}
+
+// CheckStorage confirms every evidence file exists with the recorded size.
+// When fullHash is set, file contents are also re-hashed against the stored hash.
+func (bwc *BWCSystem) CheckStorage(fullHash bool) []StorageIssue {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	issues := make([]StorageIssue, 0)
+
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted {
+			continue
+		}
+
+		size, err := bwc.statStoredFile(evidence)
+		if err != nil {
+			issues = append(issues, StorageIssue{
+				EvidenceID: evidence.ID,
+				FilePath:   evidence.FilePath,
+				Problem:    fmt.Sprintf("file not accessible: %v", err),
+			})
+			continue
+		}
+
+		if size != storedSize(evidence) {
+			issues = append(issues, StorageIssue{
+				EvidenceID: evidence.ID,
+				FilePath:   evidence.FilePath,
+				Problem:    fmt.Sprintf("size mismatch: expected %d bytes, found %d", storedSize(evidence), size),
+			})
+			continue
+		}
+
+		if fullHash {
+			hash, err := bwc.hashStoredFile(evidence)
+			if err != nil {
+				issues = append(issues, StorageIssue{
+					EvidenceID: evidence.ID,
+					FilePath:   evidence.FilePath,
+					Problem:    fmt.Sprintf("failed to calculate file hash: %v", err),
+				})
+				continue
+			}
+			if hash != storedHash(evidence) {
+				issues = append(issues, StorageIssue{
+					EvidenceID: evidence.ID,
+					FilePath:   evidence.FilePath,
+					Problem:    "hash mismatch",
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// MaintenanceStatus reports the current maintenance state without draining or checking storage
+func (bwc *BWCSystem) MaintenanceStatus() *MaintenanceReport {
+	m := bwc.maintenance
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return &MaintenanceReport{
+		Active:        m.active,
+		Since:         m.since,
+		EnteredBy:     m.enteredBy,
+		Reason:        m.reason,
+		InFlight:      m.inFlight,
+		Drained:       m.inFlight == 0,
+		StorageIssues: make([]StorageIssue, 0),
+		GeneratedAt:   time.Now(),
+	}
+}
+
+// PrepareForShutdown drains in-flight operations, runs storage checks and
+// reports whether it is safe to take the system down. The system must
+// already be in maintenance mode.
+func (bwc *BWCSystem) PrepareForShutdown(officerID string, drainTimeout time.Duration, fullHash bool) (*MaintenanceReport, error) {
+	if err := bwc.authorize(officerID, PermAdminister, "Shutdown preparation", ""); err != nil {
+		return nil, err
+	}
+	if !bwc.InMaintenance() {
+		return nil, errors.New("system must be in maintenance mode before shutdown")
+	}
+
+	drained := bwc.WaitForDrain(drainTimeout)
+
+	report := bwc.MaintenanceStatus()
+	report.Drained = drained
+	if drained {
+		report.StorageIssues = bwc.CheckStorage(fullHash)
+		report.StorageChecked = true
+	}
+	report.SafeToShutdown = report.Active && drained && len(report.StorageIssues) == 0
+
+	status := "SAFE"
+	if !report.SafeToShutdown {
+		status = "NOT SAFE"
+	}
+	bwc.logAudit(officerID, "MAINTENANCE_CHECK", "",
+		fmt.Sprintf("Shutdown readiness %s (in flight: %d, storage issues: %d)",
+			status, report.InFlight, len(report.StorageIssues)), "")
+
+	return report, nil
+}
@@ -0,0 +1,145 @@
+package bwc
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceModeRejectsIngest(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	if err := system.EnterMaintenance("ADM-001", "Storage migration"); err != nil {
+		t.Fatalf("EnterMaintenance failed: %v", err)
+	}
+
+	if !system.InMaintenance() {
+		t.Error("Expected system to be in maintenance mode")
+	}
+
+	if err := system.EnterMaintenance("ADM-001", "Again"); err == nil {
+		t.Error("Expected error when entering maintenance twice")
+	}
+
+	_, err := system.IngestEvidence(testFile, "CASE-MAINT-001", "OFF-123", "Officer Test", "Test Location", nil)
+	if err == nil {
+		t.Error("Expected ingest to be rejected during maintenance")
+	}
+
+	if err := system.ExitMaintenance("ADM-001"); err != nil {
+		t.Fatalf("ExitMaintenance failed: %v", err)
+	}
+
+	if _, err := system.IngestEvidence(testFile, "CASE-MAINT-001", "OFF-123", "Officer Test", "Test Location", nil); err != nil {
+		t.Errorf("Expected ingest to succeed after maintenance: %v", err)
+	}
+
+	if err := system.ExitMaintenance("ADM-001"); err == nil {
+		t.Error("Expected error when exiting maintenance that is not active")
+	}
+}
+
+func TestMaintenanceAllowsExistingEvidenceOperations(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	evidence, err := system.IngestEvidence(testFile, "CASE-MAINT-002", "OFF-123", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	system.EnterMaintenance("ADM-001", "Nightly checks")
+
+	if _, err := system.VerifyIntegrity(evidence.ID, "OFF-123"); err != nil {
+		t.Errorf("Expected integrity verification to be allowed during maintenance: %v", err)
+	}
+
+	if err := system.TransferCustody(evidence.ID, "OFF-123", "DET-456", "Analysis"); err != nil {
+		t.Errorf("Expected custody transfer to be allowed during maintenance: %v", err)
+	}
+}
+
+func TestWaitForDrain(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	if !system.WaitForDrain(10 * time.Millisecond) {
+		t.Error("Expected idle system to be drained")
+	}
+
+	if err := system.beginOperation(opMutation); err != nil {
+		t.Fatalf("beginOperation failed: %v", err)
+	}
+
+	if system.WaitForDrain(20 * time.Millisecond) {
+		t.Error("Expected drain to time out with an operation in flight")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		system.endOperation()
+	}()
+
+	if !system.WaitForDrain(time.Second) {
+		t.Error("Expected system to drain once the operation completed")
+	}
+}
+
+func TestPrepareForShutdown(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	evidence, err := system.IngestEvidence(testFile, "CASE-MAINT-003", "OFF-123", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	if _, err := system.PrepareForShutdown("ADM-001", time.Second, true); err == nil {
+		t.Error("Expected error when preparing shutdown outside maintenance mode")
+	}
+
+	system.EnterMaintenance("ADM-001", "Hardware replacement")
+
+	report, err := system.PrepareForShutdown("ADM-001", time.Second, true)
+	if err != nil {
+		t.Fatalf("PrepareForShutdown failed: %v", err)
+	}
+
+	if !report.SafeToShutdown {
+		t.Errorf("Expected system to be safe to shut down, issues: %v", report.StorageIssues)
+	}
+
+	if report.EnteredBy != "ADM-001" || report.Reason != "Hardware replacement" {
+		t.Errorf("Unexpected maintenance details: %s / %s", report.EnteredBy, report.Reason)
+	}
+
+	// Corrupt stored evidence and confirm the storage check catches it
+	if err := os.WriteFile(evidence.FilePath, []byte("TAMPERED"), 0600); err != nil {
+		t.Fatalf("Failed to modify evidence file: %v", err)
+	}
+
+	report, err = system.PrepareForShutdown("ADM-001", time.Second, true)
+	if err != nil {
+		t.Fatalf("PrepareForShutdown failed: %v", err)
+	}
+
+	if report.SafeToShutdown {
+		t.Error("Expected shutdown to be unsafe with storage issues")
+	}
+
+	if len(report.StorageIssues) != 1 || report.StorageIssues[0].EvidenceID != evidence.ID {
+		t.Errorf("Expected one storage issue for %s, got %v", evidence.ID, report.StorageIssues)
+	}
+
+	logs := system.GetAuditLogs("", "ADM-001")
+	if len(logs) != 3 {
+		t.Errorf("Expected 3 maintenance audit logs, got %d", len(logs))
+	}
+}
@@ -0,0 +1,98 @@
+package bwc
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MediaType is the kind of evidence an item holds. It is set at ingest and
+// decides which metadata is read, which processors run and which report
+// sections are shown.
+type MediaType string
+
+const (
+	MediaVideo    MediaType = "VIDEO"
+	MediaAudio    MediaType = "AUDIO"
+	MediaPhoto    MediaType = "PHOTO"
+	MediaDocument MediaType = "DOCUMENT"
+	MediaOther    MediaType = "OTHER"
+)
+
+// mediaTypes are the media types in the order they are listed
+var mediaTypes = []MediaType{MediaVideo, MediaAudio, MediaPhoto, MediaDocument, MediaOther}
+
+// validMediaType reports whether t is a known media type
+func validMediaType(t MediaType) bool {
+	for _, known := range mediaTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// videoExtensions are the file extensions ingested as video evidence
+var videoExtensions = map[string]bool{
+	".mp4":  true,
+	".m4v":  true,
+	".mov":  true,
+	".avi":  true,
+	".mkv":  true,
+	".webm": true,
+	".mts":  true,
+	".wmv":  true,
+	".3gp":  true,
+}
+
+// audioExtensions are the file extensions ingested as audio evidence
+var audioExtensions = map[string]bool{
+	".wav":  true,
+	".mp3":  true,
+	".m4a":  true,
+	".aac":  true,
+	".flac": true,
+	".ogg":  true,
+}
+
+// photoExtensions are the file extensions ingested as photo evidence
+var photoExtensions = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".heic": true,
+}
+
+// documentExtensions are the file extensions ingested as document evidence:
+// PDFs and scanned pages
+var documentExtensions = map[string]bool{
+	".pdf":  true,
+	".tif":  true,
+	".tiff": true,
+}
+
+// mediaTypeFor classifies a file by its extension. Anything that is not
+// recognised is OTHER and is stored without type-specific processing.
+func mediaTypeFor(path string) MediaType {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case videoExtensions[ext]:
+		return MediaVideo
+	case audioExtensions[ext]:
+		return MediaAudio
+	case photoExtensions[ext]:
+		return MediaPhoto
+	case documentExtensions[ext]:
+		return MediaDocument
+	}
+	return MediaOther
+}
+
+// mediaTypeOf returns the media type of evidence; records from before media
+// types were tracked are body-worn camera video
+func mediaTypeOf(evidence *Evidence) MediaType {
+	if evidence.MediaType == "" {
+		return MediaVideo
+	}
+	return evidence.MediaType
+}
@@ -0,0 +1,26 @@
+package bwc
+
+import "testing"
+
+func TestMediaTypeFor(t *testing.T) {
+	tests := map[string]MediaType{
+		"interview.WAV":     MediaAudio,
+		"call.m4a":          MediaAudio,
+		"bodycam.mp4":       MediaVideo,
+		"dashcam.mkv":       MediaVideo,
+		"no-extension":      MediaOther,
+		"export.zip":        MediaOther,
+		"clip.MTS":          MediaVideo,
+		"archive/voice.ogg": MediaAudio,
+		"scene.JPG":         MediaPhoto,
+		"scene.png":         MediaPhoto,
+	}
+	for path, want := range tests {
+		if got := mediaTypeFor(path); got != want {
+			t.Errorf("%s: expected %s, got %s", path, want, got)
+		}
+	}
+	if got := mediaTypeOf(&Evidence{}); got != MediaVideo {
+		t.Errorf("Expected untyped evidence to be video, got %s", got)
+	}
+}
@@ -0,0 +1,267 @@
+package bwc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+// mp4Extensions are the video formats held in an ISO base media file, which
+// readMP4 reads without ffprobe
+var mp4Extensions = map[string]bool{
+	"mp4": true,
+	"m4v": true,
+	"mov": true,
+	"3gp": true,
+}
+
+// maxMP4BoxBytes bounds the metadata box read into memory; sample tables of
+// variable frame rate recordings are the largest
+const maxMP4BoxBytes = 16 << 20
+
+// mp4Epoch is where MP4 and QuickTime timestamps count from
+var mp4Epoch = time.Date(1904, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// mp4Codecs maps sample entry types to the codec names ffprobe reports
+var mp4Codecs = map[string]string{
+	"avc1": "h264",
+	"avc3": "h264",
+	"hvc1": "hevc",
+	"hev1": "hevc",
+	"mp4v": "mpeg4",
+	"av01": "av1",
+	"vp09": "vp9",
+	"s263": "h263",
+	"jpeg": "mjpeg",
+}
+
+// mp4Track is what readMP4 keeps of one track
+type mp4Track struct {
+	handler   string
+	codec     string
+	width     int
+	height    int
+	timescale uint32
+	duration  uint64
+	samples   uint64
+}
+
+// probeMP4 reads the metadata of the MP4 or QuickTime file at path
+func probeMP4(path, format string) (*VideoInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return readMP4(f, stat.Size(), format)
+}
+
+// readMP4 reads the codec, frame size, frame rate, duration and creation time
+// of the first video track from the movie header and sample tables. The
+// media data is skipped, so the movie box may come before or after it.
+func readMP4(r io.ReaderAt, size int64, format string) (*VideoInfo, error) {
+	info := &VideoInfo{Format: format}
+	var movie []byte
+	var tracks []*mp4Track
+	first := true
+	err := walkMP4(r, 0, size, func(typ string, off, n int64) error {
+		if first && !knownMP4TopLevel(typ) {
+			return errors.New("mp4: not an MP4 or QuickTime file")
+		}
+		first = false
+		if typ != "moov" {
+			return nil
+		}
+		return walkMP4(r, off, off+n, func(typ string, off, n int64) error {
+			switch typ {
+			case "mvhd":
+				data, err := readMP4Box(r, off, n)
+				movie = data
+				return err
+			case "trak":
+				track := &mp4Track{}
+				tracks = append(tracks, track)
+				return readMP4Track(r, off, off+n, track)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return info, err
+	}
+	if movie == nil {
+		return info, errors.New("mp4: no movie header")
+	}
+
+	var video *mp4Track
+	for _, track := range tracks {
+		switch track.handler {
+		case "vide":
+			if video == nil {
+				video = track
+			}
+		case "soun":
+			info.HasAudio = true
+		}
+	}
+	if video == nil {
+		return info, errors.New("mp4: no video track")
+	}
+	info.Codec = video.codec
+	info.Width, info.Height = video.width, video.height
+	if video.timescale > 0 && video.duration > 0 && video.samples > 0 {
+		info.FrameRate = math.Round(float64(video.samples)*float64(video.timescale)/float64(video.duration)*100) / 100
+	}
+
+	created, timescale, duration, ok := parseMP4MediaHeader(movie)
+	if !ok {
+		return info, errors.New("mp4: malformed movie header")
+	}
+	seconds := 0.0
+	if timescale > 0 {
+		seconds = float64(duration) / float64(timescale)
+	}
+	if seconds == 0 && video.timescale > 0 {
+		seconds = float64(video.duration) / float64(video.timescale)
+	}
+	info.DurationSeconds = math.Round(seconds*1000) / 1000
+	// Zero means the camera did not set a time
+	if created > 0 && created < math.MaxInt64/uint64(time.Second) {
+		t := mp4Epoch.Add(time.Duration(created) * time.Second)
+		info.RecordedAt = &t
+	}
+	return info, nil
+}
+
+// readMP4Track reads the boxes of one trak that readMP4 uses
+func readMP4Track(r io.ReaderAt, start, end int64, track *mp4Track) error {
+	return walkMP4(r, start, end, func(typ string, off, n int64) error {
+		switch typ {
+		case "mdia", "minf", "stbl":
+			return readMP4Track(r, off, off+n, track)
+		case "tkhd", "mdhd", "hdlr", "stsd", "stts":
+		default:
+			return nil
+		}
+
+		data, err := readMP4Box(r, off, n)
+		if err != nil {
+			return err
+		}
+		switch typ {
+		case "tkhd":
+			// Width and height are 16.16 fixed point at the end of the header
+			at := 76
+			if len(data) > 0 && data[0] == 1 {
+				at = 88
+			}
+			if len(data) >= at+8 {
+				track.width = int(binary.BigEndian.Uint32(data[at:]) >> 16)
+				track.height = int(binary.BigEndian.Uint32(data[at+4:]) >> 16)
+			}
+		case "mdhd":
+			_, track.timescale, track.duration, _ = parseMP4MediaHeader(data)
+		case "hdlr":
+			if len(data) >= 12 {
+				track.handler = string(data[8:12])
+			}
+		case "stsd":
+			// The first sample entry's type names the codec
+			if len(data) >= 16 {
+				entry := string(data[12:16])
+				if codec, ok := mp4Codecs[entry]; ok {
+					track.codec = codec
+				} else {
+					track.codec = strings.ToLower(strings.TrimSpace(entry))
+				}
+			}
+		case "stts":
+			if len(data) >= 8 {
+				count := int(binary.BigEndian.Uint32(data[4:]))
+				for i := 0; i < count && 16+i*8 <= len(data); i++ {
+					track.samples += uint64(binary.BigEndian.Uint32(data[8+i*8:]))
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// parseMP4MediaHeader reads the creation time, timescale and duration that
+// an mvhd or mdhd box starts with, in either version's field sizes
+func parseMP4MediaHeader(data []byte) (created uint64, timescale uint32, duration uint64, ok bool) {
+	if len(data) < 4 {
+		return 0, 0, 0, false
+	}
+	if data[0] == 1 {
+		if len(data) < 32 {
+			return 0, 0, 0, false
+		}
+		return binary.BigEndian.Uint64(data[4:]), binary.BigEndian.Uint32(data[20:]), binary.BigEndian.Uint64(data[24:]), true
+	}
+	if len(data) < 20 {
+		return 0, 0, 0, false
+	}
+	return uint64(binary.BigEndian.Uint32(data[4:])), binary.BigEndian.Uint32(data[12:]), uint64(binary.BigEndian.Uint32(data[16:])), true
+}
+
+// knownMP4TopLevel reports whether typ can open an MP4 or QuickTime file
+func knownMP4TopLevel(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// walkMP4 calls visit with the type, payload offset and payload size of each
+// box between start and end
+func walkMP4(r io.ReaderAt, start, end int64, visit func(typ string, off, n int64) error) error {
+	for end-start >= 8 {
+		var header [16]byte
+		if _, err := r.ReadAt(header[:8], start); err != nil {
+			return fmt.Errorf("mp4: %w", err)
+		}
+		typ := string(header[4:8])
+		size, headerLen := uint64(binary.BigEndian.Uint32(header[:4])), int64(8)
+		switch size {
+		case 0:
+			// The box runs to the end of its parent
+			size = uint64(end - start)
+		case 1:
+			if _, err := r.ReadAt(header[8:16], start+8); err != nil {
+				return fmt.Errorf("mp4: %w", err)
+			}
+			size, headerLen = binary.BigEndian.Uint64(header[8:16]), 16
+		}
+		if size < uint64(headerLen) || size > uint64(end-start) {
+			return fmt.Errorf("mp4: malformed %q box", typ)
+		}
+		if err := visit(typ, start+headerLen, int64(size)-headerLen); err != nil {
+			return err
+		}
+		start += int64(size)
+	}
+	return nil
+}
+
+// readMP4Box reads a box payload into memory
+func readMP4Box(r io.ReaderAt, off, n int64) ([]byte, error) {
+	if n > maxMP4BoxBytes {
+		return nil, fmt.Errorf("mp4: %d byte metadata box is too large", n)
+	}
+	data := make([]byte, n)
+	if _, err := r.ReadAt(data, off); err != nil {
+		return nil, fmt.Errorf("mp4: %w", err)
+	}
+	return data, nil
+}
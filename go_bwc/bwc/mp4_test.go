@@ -0,0 +1,116 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testBox builds an MP4 box of typ around payload
+func testBox(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	box := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(box, uint32(8+len(body)))
+	copy(box[4:], typ)
+	return append(box, body...)
+}
+
+// be32 encodes the big-endian fields of a box
+func be32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// testMP4 is a 1920x1080 H.264 recording at 29.97 fps with an audio track,
+// 754.321 s long and created at recordedAt. The movie box follows the media
+// data, as cameras that write it on stop leave it.
+func testMP4(recordedAt time.Time) []byte {
+	created := uint32(recordedAt.Sub(mp4Epoch) / time.Second)
+	mvhd := testBox("mvhd", be32(0, created, created, 1000, 754321), make([]byte, 80))
+	tkhd := testBox("tkhd", be32(0, created, created, 1, 0, 754321), make([]byte, 52), be32(1920<<16, 1080<<16))
+	video := testBox("trak", tkhd, testBox("mdia",
+		testBox("mdhd", be32(0, created, created, 30000, 22610*1001), make([]byte, 4)),
+		testBox("hdlr", be32(0, 0), []byte("vide"), make([]byte, 12)),
+		testBox("minf", testBox("stbl",
+			testBox("stsd", be32(0, 1), testBox("avc1", make([]byte, 78))),
+			testBox("stts", be32(0, 1, 22610, 1001)),
+		)),
+	))
+	audio := testBox("trak", testBox("mdia", testBox("hdlr", be32(0, 0), []byte("soun"), make([]byte, 12))))
+	return bytes.Join([][]byte{
+		testBox("ftyp", []byte("isom"), be32(512), []byte("isomavc1")),
+		testBox("mdat", bytes.Repeat([]byte{0xAB}, 4096)),
+		testBox("moov", mvhd, video, audio),
+	}, nil)
+}
+
+func TestReadMP4(t *testing.T) {
+	recordedAt := time.Date(2026, time.October, 14, 8, 30, 0, 0, time.UTC)
+	data := testMP4(recordedAt)
+
+	info, err := readMP4(bytes.NewReader(data), int64(len(data)), "mp4")
+	if err != nil {
+		t.Fatalf("readMP4 failed: %v", err)
+	}
+	want := VideoInfo{Format: "mp4", Codec: "h264", Width: 1920, Height: 1080, FrameRate: 29.97, DurationSeconds: 754.321, HasAudio: true}
+	got := *info
+	got.RecordedAt = nil
+	if got != want {
+		t.Errorf("Unexpected metadata %+v", got)
+	}
+	if info.RecordedAt == nil || !info.RecordedAt.Equal(recordedAt) {
+		t.Errorf("Expected creation time %s, got %v", recordedAt, info.RecordedAt)
+	}
+
+	if _, err := readMP4(strings.NewReader("This is test video content"), 26, "mp4"); err == nil {
+		t.Error("Expected an error for a file that is not MP4")
+	}
+	truncated := data[:len(data)-10]
+	if _, err := readMP4(bytes.NewReader(truncated), int64(len(truncated)), "mp4"); err == nil {
+		t.Error("Expected an error for a truncated movie box")
+	}
+}
+
+func TestIngestVideoMetadataWithoutFFprobe(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.VideoProcessing.ExtractMetadata = true
+
+	path := filepath.Join(tmpDir, "bodycam.mp4")
+	if err := os.WriteFile(path, testMP4(time.Now().Add(-time.Hour).Truncate(time.Second)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	evidence, err := system.IngestEvidence(path, "CASE-MP4-001", "OFF-9001", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if evidence.Video == nil || evidence.Video.Codec != "h264" || evidence.Video.Width != 1920 || evidence.Duration != 754 {
+		t.Fatalf("Expected the metadata to be read without ffprobe, got %+v", evidence.Video)
+	}
+	if evidence.Video.RecordedAt == nil {
+		t.Error("Expected the creation time to be recorded")
+	}
+	tools := evidence.Forensics.Tools
+	if last := tools[len(tools)-1]; !strings.HasSuffix(last.Name, "MP4 reader") {
+		t.Errorf("Expected the MP4 reader among the forensic tools, got %+v", tools)
+	}
+
+	// A file that is not really MP4 is still ingested, and the failure audited
+	evidence, err = system.IngestEvidence(createTestFile(t, tmpDir), "CASE-MP4-001", "OFF-9002", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("Expected a failed probe not to block ingest: %v", err)
+	}
+	if evidence.Video == nil || evidence.Video.Format != "mp4" || evidence.Duration != 0 {
+		t.Errorf("Expected a video recorded by format only, got %+v", evidence.Video)
+	}
+	if !strings.Contains(strings.Join(auditActions(system, evidence.ID), " "), "MEDIA_PROBE_FAILED") {
+		t.Error("Expected MEDIA_PROBE_FAILED to be audited")
+	}
+}
@@ -0,0 +1,300 @@
+package bwc
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// NIEM namespaces used by the case exchange. Elements with no NIEM
+// equivalent, such as hashes and custody signatures, are in the bwc
+// extension namespace.
+const (
+	niemCoreNamespace       = "http://release.niem.gov/niem/niem-core/5.0/"
+	niemJusticeNamespace    = "http://release.niem.gov/niem/domains/jxdm/7.0/"
+	niemStructuresNamespace = "http://release.niem.gov/niem/structures/5.0/"
+	niemExtensionNamespace  = "urn:bwc:niem:exchange:1.0"
+)
+
+// niemExchange is the root of a NIEM case exchange document. encoding/xml
+// writes the prefixed names as given, so every prefix is declared here.
+type niemExchange struct {
+	XMLName         xml.Name             `xml:"bwc:EvidenceExchange"`
+	NC              string               `xml:"xmlns:nc,attr"`
+	J               string               `xml:"xmlns:j,attr"`
+	Structures      string               `xml:"xmlns:structures,attr"`
+	BWC             string               `xml:"xmlns:bwc,attr"`
+	CreationDate    niemDateTime         `xml:"nc:DocumentCreationDate"`
+	Submitter       string               `xml:"nc:DocumentSubmitter>nc:EntityOrganization>nc:OrganizationName"`
+	SubmittedBy     string               `xml:"bwc:DocumentSubmitterIdentification>nc:IdentificationID"`
+	Case            niemCase             `xml:"nc:Case"`
+	Evidence        []niemEvidence       `xml:"j:Evidence"`
+	Officials       []niemOfficial       `xml:"j:EnforcementOfficial"`
+	Recordings      []niemAssociation    `xml:"bwc:EvidenceRecordingOfficialAssociation"`
+	CaseAssociation []niemCaseAssociated `xml:"bwc:CaseEvidenceAssociation"`
+}
+
+type niemDateTime struct {
+	DateTime string `xml:"nc:DateTime"`
+}
+
+func newNIEMDateTime(t time.Time) *niemDateTime {
+	if t.IsZero() {
+		return nil
+	}
+	return &niemDateTime{DateTime: t.UTC().Format(time.RFC3339)}
+}
+
+type niemCase struct {
+	ID         string        `xml:"structures:id,attr"`
+	TrackingID string        `xml:"nc:CaseTrackingID"`
+	Severity   string        `xml:"bwc:CaseSeverityText,omitempty"`
+	CourtDate  *niemDateTime `xml:"j:CaseAugmentation>j:CaseCourtEvent>nc:ActivityDate,omitempty"`
+}
+
+type niemEvidence struct {
+	ID           string           `xml:"structures:id,attr"`
+	EvidenceID   string           `xml:"nc:ActivityIdentification>nc:IdentificationID"`
+	RecordedAt   *niemDateTime    `xml:"nc:ActivityDate,omitempty"`
+	Status       string           `xml:"nc:ActivityStatus>nc:StatusText"`
+	Description  string           `xml:"nc:ActivityDescriptionText,omitempty"`
+	Category     string           `xml:"j:EvidenceItem>nc:ItemCategoryText"`
+	Location     string           `xml:"nc:ActivityLocation>nc:LocationDescriptionText,omitempty"`
+	Tags         []string         `xml:"bwc:EvidenceCategoryText"`
+	Recording    niemBinary       `xml:"bwc:EvidenceRecording"`
+	Custody      []niemCustody    `xml:"bwc:EvidenceCustodyTransfer"`
+	IngestedAt   *niemDateTime    `xml:"bwc:EvidenceIngestDate"`
+	Integrity    *niemIntegrity   `xml:"bwc:EvidenceIntegrityVerification,omitempty"`
+	RetentionEnd *niemDateTime    `xml:"bwc:EvidenceRetentionEndDate,omitempty"`
+	Acquisition  *niemAcquisition `xml:"bwc:EvidenceAcquisition,omitempty"`
+}
+
+type niemAcquisition struct {
+	Date         niemDateTime `xml:"nc:ActivityDate"`
+	Method       string       `xml:"bwc:AcquisitionMethodText"`
+	Examiner     string       `xml:"bwc:AcquisitionExaminerIdentification>nc:IdentificationID"`
+	ExaminerName string       `xml:"bwc:AcquisitionExaminerName>nc:PersonFullName,omitempty"`
+	FileName     string       `xml:"bwc:OriginalFileNameText"`
+	DeviceMake   string       `xml:"bwc:OriginalDevice>nc:ItemMakeName,omitempty"`
+	DeviceModel  string       `xml:"bwc:OriginalDevice>nc:ItemModelName,omitempty"`
+	DeviceSerial string       `xml:"bwc:OriginalDevice>nc:ItemSerialIdentification>nc:IdentificationID,omitempty"`
+	MediaSerial  string       `xml:"bwc:OriginalMediaSerialIdentification>nc:IdentificationID,omitempty"`
+	WriteBlocker string       `xml:"bwc:WriteBlockerText,omitempty"`
+	Tools        []niemTool   `xml:"bwc:AcquisitionTool"`
+}
+
+type niemTool struct {
+	Name    string `xml:"bwc:ToolName"`
+	Version string `xml:"bwc:ToolVersionText,omitempty"`
+	Purpose string `xml:"bwc:ToolPurposeText,omitempty"`
+}
+
+type niemBinary struct {
+	Format   string        `xml:"nc:BinaryFormatText,omitempty"`
+	Size     int64         `xml:"nc:BinarySizeValue"`
+	Hash     niemHash      `xml:"bwc:BinaryHashValue"`
+	Duration string        `xml:"bwc:BinaryDurationSecondsValue,omitempty"`
+	Captured *niemDateTime `xml:"nc:BinaryCaptureDate,omitempty"`
+}
+
+type niemHash struct {
+	Algorithm string `xml:"bwc:hashAlgorithmText,attr"`
+	Value     string `xml:",chardata"`
+}
+
+type niemCustody struct {
+	Date      niemDateTime `xml:"nc:ActivityDate"`
+	Action    string       `xml:"nc:ActivityDescriptionText"`
+	Purpose   string       `xml:"nc:ActivityReasonText,omitempty"`
+	From      string       `xml:"bwc:CustodyReleasingIdentification>nc:IdentificationID,omitempty"`
+	To        string       `xml:"bwc:CustodyReceivingIdentification>nc:IdentificationID,omitempty"`
+	Hash      string       `xml:"bwc:CustodyVerifiedHashValue,omitempty"`
+	EntryHash string       `xml:"bwc:CustodyEntryHashValue,omitempty"`
+	Signer    string       `xml:"bwc:CustodySignerText,omitempty"`
+}
+
+type niemIntegrity struct {
+	Date   niemDateTime `xml:"nc:ActivityDate"`
+	Result string       `xml:"nc:ActivityStatus>nc:StatusText"`
+}
+
+type niemOfficial struct {
+	ID      string `xml:"structures:id,attr"`
+	Name    string `xml:"nc:RoleOfPerson>nc:PersonName>nc:PersonFullName,omitempty"`
+	BadgeID string `xml:"j:EnforcementOfficialBadgeIdentification>nc:IdentificationID"`
+}
+
+type niemAssociation struct {
+	Evidence niemRef `xml:"j:Evidence"`
+	Official niemRef `xml:"j:EnforcementOfficial"`
+}
+
+type niemCaseAssociated struct {
+	Case     niemRef `xml:"nc:Case"`
+	Evidence niemRef `xml:"j:Evidence"`
+}
+
+type niemRef struct {
+	Ref string `xml:"structures:ref,attr"`
+}
+
+// ExportCaseNIEM renders the evidence, custody and case data of caseNumber as
+// a NIEM XML exchange document for justice information sharing systems. The
+// recordings themselves are not included; each is identified by its hash. The
+// document is registered as a copy against every item, and sealed evidence
+// cannot be exported.
+func (bwc *BWCSystem) ExportCaseNIEM(caseNumber, userID, destination string) ([]byte, error) {
+	if err := bwc.authorize(userID, PermExport, "NIEM export", ""); err != nil {
+		return nil, err
+	}
+	bwc.mu.Lock()
+	evidence := make([]*Evidence, 0)
+	for _, ev := range bwc.evidenceDB.Search(nil) {
+		if ev.CaseNumber == caseNumber {
+			evidence = append(evidence, ev)
+		}
+	}
+	sort.Slice(evidence, func(i, j int) bool { return evidence[i].ID < evidence[j].ID })
+	for _, ev := range evidence {
+		if err := bwc.rejectIfSealedLocked(ev, userID, "NIEM export"); err != nil {
+			bwc.mu.Unlock()
+			return nil, err
+		}
+		if err := bwc.rejectIfRestrictedLocked(ev, userID, "NIEM export"); err != nil {
+			bwc.mu.Unlock()
+			return nil, err
+		}
+	}
+	doc := bwc.niemExchangeLocked(caseNumber, userID, evidence)
+	bwc.mu.Unlock()
+
+	if len(evidence) == 0 {
+		return nil, errors.New("case has no evidence")
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal NIEM exchange: %w", err)
+	}
+	data := append([]byte(xml.Header), body...)
+	data = append(data, '\n')
+
+	bwc.registerCaseCopy(caseNumber, CopyExport, userID, destination, "NIEM exchange", data)
+	bwc.logAudit(userID, "EXPORT_NIEM", "", fmt.Sprintf("NIEM exchange of case %s (%d items) to %s", caseNumber, len(evidence), destination), "")
+	return data, nil
+}
+
+// niemExchangeLocked maps the case's evidence to the exchange document. The
+// caller must hold bwc.mu.
+func (bwc *BWCSystem) niemExchangeLocked(caseNumber, userID string, evidence []*Evidence) *niemExchange {
+	doc := &niemExchange{
+		NC:           niemCoreNamespace,
+		J:            niemJusticeNamespace,
+		Structures:   niemStructuresNamespace,
+		BWC:          niemExtensionNamespace,
+		CreationDate: *newNIEMDateTime(time.Now()),
+		Submitter:    bwc.config.System.Name,
+		SubmittedBy:  userID,
+		Case:         niemCase{ID: "case", TrackingID: caseNumber},
+	}
+
+	officials := make(map[string]string)
+	var courtDate time.Time
+	for i, ev := range evidence {
+		evRef := "ev" + strconv.Itoa(i+1)
+
+		caseSeverity := CaseSeverity(doc.Case.Severity)
+		if ev.Severity != "" && (caseSeverity == "" || priorityRank[severityPriority[ev.Severity]] > priorityRank[severityPriority[caseSeverity]]) {
+			doc.Case.Severity = string(ev.Severity)
+		}
+		if ev.CourtDate != nil && (courtDate.IsZero() || ev.CourtDate.Before(courtDate)) {
+			courtDate = *ev.CourtDate
+		}
+
+		item := niemEvidence{
+			ID:          evRef,
+			EvidenceID:  ev.ID,
+			RecordedAt:  newNIEMDateTime(ev.Timestamp),
+			Status:      string(ev.Status),
+			Description: ev.Notes,
+			Category:    string(ev.MediaType),
+			Location:    ev.Location,
+			Tags:        ev.Tags,
+			IngestedAt:  newNIEMDateTime(ev.CreatedAt),
+			Recording: niemBinary{
+				Size: ev.FileSize,
+				Hash: niemHash{Algorithm: "SHA-256", Value: ev.FileHash},
+			},
+		}
+		if ev.Place != nil && ev.Place.Formatted != "" {
+			item.Location = ev.Place.Formatted
+		}
+		if ev.Video != nil {
+			item.Recording.Format = ev.Video.Format
+		}
+		if ev.Duration > 0 {
+			item.Recording.Duration = strconv.Itoa(ev.Duration)
+		}
+		if ev.IncidentTime != nil {
+			item.Recording.Captured = newNIEMDateTime(*ev.IncidentTime)
+		}
+		for _, entry := range ev.ChainOfCustody {
+			c := niemCustody{
+				Date:      *newNIEMDateTime(entry.Timestamp),
+				Action:    entry.Action,
+				Purpose:   entry.Purpose,
+				From:      entry.FromOfficer,
+				To:        entry.ToOfficer,
+				Hash:      entry.VerifiedHash,
+				EntryHash: entry.EntryHash,
+			}
+			if entry.Signature != nil {
+				c.Signer = entry.Signature.SignerID
+			}
+			item.Custody = append(item.Custody, c)
+		}
+		if n := len(ev.IntegrityChecks); n > 0 {
+			last := ev.IntegrityChecks[n-1]
+			result := "FAILED"
+			if last.IsValid {
+				result = "VERIFIED"
+			}
+			item.Integrity = &niemIntegrity{Date: *newNIEMDateTime(last.Timestamp), Result: result}
+		}
+		if f := ev.Forensics; f != nil {
+			acq := &niemAcquisition{
+				Date:         niemDateTime{DateTime: f.AcquiredAt.UTC().Format(time.RFC3339)},
+				Method:       string(f.AcquisitionMethod),
+				Examiner:     f.Examiner,
+				ExaminerName: f.ExaminerName,
+				FileName:     f.OriginalFileName,
+				WriteBlocker: f.WriteBlocker,
+			}
+			if m := f.OriginalMedia; m != nil {
+				acq.DeviceMake, acq.DeviceModel, acq.DeviceSerial, acq.MediaSerial = m.DeviceMake, m.DeviceModel, m.DeviceSerial, m.MediaSerial
+			}
+			for _, tool := range f.Tools {
+				acq.Tools = append(acq.Tools, niemTool{Name: tool.Name, Version: tool.Version, Purpose: tool.Purpose})
+			}
+			item.Acquisition = acq
+		}
+		if expires, _, ok := bwc.retentionExpiry(ev); ok {
+			item.RetentionEnd = newNIEMDateTime(expires)
+		}
+		doc.Evidence = append(doc.Evidence, item)
+
+		offRef, seen := officials[ev.OfficerID]
+		if !seen {
+			offRef = "off" + strconv.Itoa(len(officials)+1)
+			officials[ev.OfficerID] = offRef
+			doc.Officials = append(doc.Officials, niemOfficial{ID: offRef, Name: ev.OfficerName, BadgeID: ev.OfficerID})
+		}
+		doc.Recordings = append(doc.Recordings, niemAssociation{Evidence: niemRef{evRef}, Official: niemRef{offRef}})
+		doc.CaseAssociation = append(doc.CaseAssociation, niemCaseAssociated{Case: niemRef{"case"}, Evidence: niemRef{evRef}})
+	}
+	doc.Case.CourtDate = newNIEMDateTime(courtDate)
+	return doc
+}
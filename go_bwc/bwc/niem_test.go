@@ -0,0 +1,143 @@
+package bwc
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// niemElements returns the text of every element in an exchange document,
+// keyed by local name and in document order
+func niemElements(t *testing.T, data []byte) map[string][]string {
+	t.Helper()
+	elements := make(map[string][]string)
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	var stack []string
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v\n%s", err, data)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, tok.Name.Local)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if text := strings.TrimSpace(string(tok)); text != "" && len(stack) > 0 {
+				elements[stack[len(stack)-1]] = append(elements[stack[len(stack)-1]], text)
+			}
+		}
+	}
+	return elements
+}
+
+func TestExportCaseNIEM(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	first, err := system.IngestEvidence(testFile, "CASE-NIEM-1", "OFF-1128", "Officer Ada Park", "5th & Main", []string{"felony"})
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	second, err := system.IngestEvidence(testFile, "CASE-NIEM-1", "OFF-1129", "Officer Ben Cole", "Elm St", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(first.ID, "OFF-1128", "DET-001", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	court := time.Date(2027, 3, 1, 9, 0, 0, 0, time.UTC)
+	if err := system.SetVerificationFactors(second.ID, "CUS-001", SeverityFelony, &court); err != nil {
+		t.Fatalf("SetVerificationFactors failed: %v", err)
+	}
+
+	data, err := system.ExportCaseNIEM("CASE-NIEM-1", "CUS-001", "state repository")
+	if err != nil {
+		t.Fatalf("ExportCaseNIEM failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Errorf("expected an XML declaration, got %.60s", data)
+	}
+	for _, want := range []string{
+		`xmlns:nc="` + niemCoreNamespace + `"`,
+		`xmlns:j="` + niemJusticeNamespace + `"`,
+		`<j:Evidence structures:id="ev1">`,
+		`<j:EnforcementOfficial structures:id="off2">`,
+		`<bwc:BinaryHashValue bwc:hashAlgorithmText="SHA-256">` + first.FileHash + `</bwc:BinaryHashValue>`,
+		`<j:Evidence structures:ref="ev2"></j:Evidence>`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in exchange:\n%s", want, data)
+		}
+	}
+
+	elements := niemElements(t, data)
+	if ids := elements["IdentificationID"]; len(ids) < 2 || ids[1] != first.ID {
+		t.Errorf("unexpected identifiers %v", ids)
+	}
+	if got := elements["CaseTrackingID"]; len(got) != 1 || got[0] != "CASE-NIEM-1" {
+		t.Errorf("unexpected case %v", got)
+	}
+	if got := elements["CaseSeverityText"]; len(got) != 1 || got[0] != "FELONY" {
+		t.Errorf("expected the case severity, got %v", got)
+	}
+	// Examiners come first, in each item's acquisition, then the officials
+	if got := elements["PersonFullName"]; len(got) != 4 || strings.Join(got[2:], ",") != "Officer Ada Park,Officer Ben Cole" {
+		t.Errorf("unexpected officials %v", got)
+	}
+	if got := elements["ActivityReasonText"]; len(got) != 3 || got[1] != "Investigation" {
+		t.Errorf("expected the custody transfer, got %v", got)
+	}
+	if got := elements["EvidenceCategoryText"]; len(got) != 1 || got[0] != "felony" {
+		t.Errorf("expected the tags, got %v", got)
+	}
+
+	if copies := system.CopiesOf(second.ID); len(copies) != 1 || copies[0].Kind != CopyExport || copies[0].Destination != "state repository" {
+		t.Errorf("expected the exchange to be registered as a copy, got %+v", copies)
+	}
+	if logs := system.GetAuditLogs("", "CUS-001"); len(logs) == 0 || logs[len(logs)-1].Action != "EXPORT_NIEM" {
+		t.Errorf("expected an EXPORT_NIEM audit entry, got %+v", logs)
+	}
+
+	if _, err := system.ExportCaseNIEM("CASE-NIEM-NONE", "CUS-001", "state repository"); err == nil {
+		t.Error("expected a case without evidence to be rejected")
+	}
+	if _, err := system.SealEvidence(first.ID, "Court order 1"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if _, err := system.ExportCaseNIEM("CASE-NIEM-1", "CUS-001", "state repository"); !errors.Is(err, errEvidenceSealed) {
+		t.Errorf("expected sealed evidence to be refused, got %v", err)
+	}
+}
+
+func TestNIEMExchangeEndpoint(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ev, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-NIEM-2", "OFF-1130", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/exchange/niem/CASE-NIEM-2")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/xml" {
+		t.Fatalf("expected an XML document, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), ev.ID) {
+		t.Errorf("expected the evidence in the exchange:\n%s", body)
+	}
+
+	resp = authGet(t, server, "/api/exchange/niem/CASE-NIEM-NONE")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown case, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,208 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OfficerAccountabilityReport shows, per officer, the evidence uploaded in a
+// period and how much of it has been classified. Uncategorized evidence falls
+// to the default retention period and may be purged too early or kept too
+// long; it is overdue once it has gone unclassified for DeadlineDays.
+type OfficerAccountabilityReport struct {
+	GeneratedAt  time.Time               `json:"generated_at"`
+	From         time.Time               `json:"from"`
+	To           time.Time               `json:"to"`
+	DeadlineDays int                     `json:"deadline_days"`
+	Categories   []string                `json:"categories"`
+	Officers     []OfficerAccountability `json:"officers"`
+	Totals       OfficerAccountability   `json:"totals"`
+}
+
+// OfficerAccountability is one officer's row of the report. Overdue lists
+// the evidence IDs past the classification deadline, oldest first.
+type OfficerAccountability struct {
+	OfficerID       string   `json:"officer_id"`
+	OfficerName     string   `json:"officer_name,omitempty"`
+	Recorded        int      `json:"recorded"`
+	Bytes           int64    `json:"bytes"`
+	DurationSeconds int      `json:"duration_seconds"`
+	Categorized     int      `json:"categorized"`
+	Uncategorized   int      `json:"uncategorized"`
+	OverdueCount    int      `json:"overdue"`
+	Overdue         []string `json:"overdue_evidence,omitempty"`
+}
+
+// CategorizedPercent is the share of recorded evidence that is categorized
+func (a OfficerAccountability) CategorizedPercent() float64 {
+	if a.Recorded == 0 {
+		return 100
+	}
+	return float64(a.Categorized) * 100 / float64(a.Recorded)
+}
+
+func (a *OfficerAccountability) add(b OfficerAccountability) {
+	a.Recorded += b.Recorded
+	a.Bytes += b.Bytes
+	a.DurationSeconds += b.DurationSeconds
+	a.Categorized += b.Categorized
+	a.Uncategorized += b.Uncategorized
+	a.OverdueCount += b.OverdueCount
+}
+
+// isCategorized reports whether evidence carries a category tag: one named by
+// a retention rule, or any tag when no rules are configured
+func isCategorized(policy RetentionPolicy, tags []string) bool {
+	if len(policy.RetentionRules) == 0 {
+		return len(tags) > 0
+	}
+	for _, rule := range policy.RetentionRules {
+		for _, tag := range tags {
+			if strings.EqualFold(tag, rule.Tag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// OfficerAccountability reports on evidence uploaded in [from, to), judging
+// classification deadlines as of now. Deleted evidence is left out.
+func (bwc *BWCSystem) OfficerAccountability(from, to, now time.Time) (*OfficerAccountabilityReport, error) {
+	if !to.After(from) {
+		return nil, errors.New("report period must end after it starts")
+	}
+	policy := bwc.config.RetentionPolicy()
+	deadlineDays := bwc.config.Compliance.ClassificationDeadlineDays
+
+	report := &OfficerAccountabilityReport{
+		GeneratedAt:  now,
+		From:         from,
+		To:           to,
+		DeadlineDays: deadlineDays,
+		Categories:   make([]string, 0, len(policy.RetentionRules)),
+		Officers:     make([]OfficerAccountability, 0),
+	}
+	for _, rule := range policy.RetentionRules {
+		report.Categories = append(report.Categories, rule.Tag)
+	}
+
+	type overdueItem struct {
+		id        string
+		createdAt time.Time
+	}
+	officers := make(map[string]*OfficerAccountability)
+	overdue := make(map[string][]overdueItem)
+
+	bwc.mu.RLock()
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted || evidence.CreatedAt.Before(from) || !evidence.CreatedAt.Before(to) {
+			continue
+		}
+		row := officers[evidence.OfficerID]
+		if row == nil {
+			row = &OfficerAccountability{OfficerID: evidence.OfficerID}
+			officers[evidence.OfficerID] = row
+		}
+		if row.OfficerName == "" {
+			row.OfficerName = evidence.OfficerName
+		}
+		row.Recorded++
+		row.Bytes += evidence.FileSize
+		row.DurationSeconds += evidence.Duration
+
+		if isCategorized(policy, evidence.Tags) {
+			row.Categorized++
+			continue
+		}
+		row.Uncategorized++
+		if !now.Before(evidence.CreatedAt.AddDate(0, 0, deadlineDays)) {
+			row.OverdueCount++
+			overdue[evidence.OfficerID] = append(overdue[evidence.OfficerID], overdueItem{evidence.ID, evidence.CreatedAt})
+		}
+	}
+	bwc.mu.RUnlock()
+
+	for officerID, row := range officers {
+		items := overdue[officerID]
+		sort.Slice(items, func(i, j int) bool {
+			if !items[i].createdAt.Equal(items[j].createdAt) {
+				return items[i].createdAt.Before(items[j].createdAt)
+			}
+			return items[i].id < items[j].id
+		})
+		for _, item := range items {
+			row.Overdue = append(row.Overdue, item.id)
+		}
+		report.Totals.add(*row)
+		report.Officers = append(report.Officers, *row)
+	}
+	report.Totals.OfficerID = "TOTAL"
+
+	// Officers with the most overdue evidence come first, for supervisors
+	sort.Slice(report.Officers, func(i, j int) bool {
+		a, b := report.Officers[i], report.Officers[j]
+		if a.OverdueCount != b.OverdueCount {
+			return a.OverdueCount > b.OverdueCount
+		}
+		if a.Uncategorized != b.Uncategorized {
+			return a.Uncategorized > b.Uncategorized
+		}
+		return a.OfficerID < b.OfficerID
+	})
+	return report, nil
+}
+
+// Text renders the report as a plain-text table
+func (r *OfficerAccountabilityReport) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "OFFICER CLASSIFICATION ACCOUNTABILITY\n")
+	fmt.Fprintf(&b, "Generated: %s\n", r.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Period: %s to %s\n", r.From.Format("2006-01-02"), r.To.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Classification deadline: %d days\n", r.DeadlineDays)
+	if len(r.Categories) > 0 {
+		fmt.Fprintf(&b, "Categories: %s\n", strings.Join(r.Categories, ", "))
+	} else {
+		fmt.Fprintf(&b, "Categories: any tag (no retention rules configured)\n")
+	}
+
+	fmt.Fprintf(&b, "\n%-12s %-24s %8s %10s %10s %12s %8s %8s\n", "Officer", "Name", "Recorded", "Hours", "Size MB", "Categorized", "Uncat.", "Overdue")
+	row := func(a OfficerAccountability) {
+		fmt.Fprintf(&b, "%-12s %-24s %8d %10.1f %10.1f %11.0f%% %8d %8d\n", a.OfficerID, a.OfficerName, a.Recorded,
+			float64(a.DurationSeconds)/3600, float64(a.Bytes)/(1<<20), a.CategorizedPercent(), a.Uncategorized, a.OverdueCount)
+	}
+	for _, a := range r.Officers {
+		row(a)
+	}
+	row(r.Totals)
+
+	for _, a := range r.Officers {
+		if len(a.Overdue) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nOverdue for classification, %s\n", a.OfficerID)
+		for _, id := range a.Overdue {
+			fmt.Fprintf(&b, "  %s\n", id)
+		}
+	}
+	return b.String()
+}
+
+// CSV renders the report with one row per officer
+func (r *OfficerAccountabilityReport) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"officer_id", "officer_name", "recorded", "bytes", "duration_seconds", "categorized", "uncategorized", "overdue", "overdue_evidence"})
+	for _, a := range r.Officers {
+		w.Write([]string{a.OfficerID, a.OfficerName, strconv.Itoa(a.Recorded), strconv.FormatInt(a.Bytes, 10), strconv.Itoa(a.DurationSeconds),
+			strconv.Itoa(a.Categorized), strconv.Itoa(a.Uncategorized), strconv.Itoa(a.OverdueCount), strings.Join(a.Overdue, " ")})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
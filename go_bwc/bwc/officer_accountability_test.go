@@ -0,0 +1,141 @@
+package bwc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOfficerAccountability(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionRules = []RetentionRule{
+		{Tag: "felony", RetentionDays: 3650},
+		{Tag: "homicide", Indefinite: true},
+	}
+
+	testFile := createTestFile(t, tmpDir)
+	ingest := func(caseNumber, officer string, createdDaysAgo int, tags []string) *Evidence {
+		ev, err := system.IngestEvidence(testFile, caseNumber, officer, "Officer "+officer, "Test Location", tags)
+		if err != nil {
+			t.Fatalf("Ingest failed: %v", err)
+		}
+		ev.CreatedAt = ev.CreatedAt.AddDate(0, 0, -createdDaysAgo)
+		return ev
+	}
+	ingest("CASE-OA-001", "OFF-1124", 10, []string{"Felony"})
+	oldest := ingest("CASE-OA-002", "OFF-1124", 9, nil)
+	overdue := ingest("CASE-OA-003", "OFF-1124", 5, []string{"traffic"})
+	ingest("CASE-OA-004", "OFF-1124", 1, nil)
+	ingest("CASE-OA-005", "OFF-1125", 8, []string{"homicide"})
+	ingest("CASE-OA-006", "OFF-1125", 40, nil)
+	deleted := ingest("CASE-OA-007", "OFF-1125", 6, nil)
+	deleted.Status = StatusDeleted
+
+	now := time.Now()
+	if _, err := system.OfficerAccountability(now, now, now); err == nil {
+		t.Error("Expected an empty period to be rejected")
+	}
+
+	report, err := system.OfficerAccountability(now.AddDate(0, 0, -30), now, now)
+	if err != nil {
+		t.Fatalf("OfficerAccountability failed: %v", err)
+	}
+	if report.DeadlineDays != 3 || strings.Join(report.Categories, ",") != "felony,homicide" || len(report.Officers) != 2 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	first := report.Officers[0]
+	if first.OfficerID != "OFF-1124" || first.OfficerName != "Officer OFF-1124" || first.Recorded != 4 || first.Categorized != 1 || first.Uncategorized != 3 {
+		t.Errorf("Unexpected row: %+v", first)
+	}
+	if first.OverdueCount != 2 || strings.Join(first.Overdue, ",") != oldest.ID+","+overdue.ID {
+		t.Errorf("Expected two overdue items, oldest first, got %+v", first.Overdue)
+	}
+	if second := report.Officers[1]; second.OfficerID != "OFF-1125" || second.Recorded != 1 || second.OverdueCount != 0 || second.CategorizedPercent() != 100 {
+		t.Errorf("Expected only the period's undeleted evidence, got %+v", second)
+	}
+	if report.Totals.Recorded != 5 || report.Totals.OverdueCount != 2 || report.Totals.Bytes != 5*oldest.FileSize {
+		t.Errorf("Unexpected totals: %+v", report.Totals)
+	}
+
+	text := report.Text()
+	for _, want := range []string{"Classification deadline: 3 days", "Categories: felony, homicide", "25%", "Overdue for classification, OFF-1124\n  " + oldest.ID} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in report:\n%s", want, text)
+		}
+	}
+	data, err := report.CSV()
+	if err != nil || strings.Count(string(data), "\n") != 3 || !strings.Contains(string(data), ",3,2,"+oldest.ID+" "+overdue.ID) {
+		t.Errorf("Unexpected CSV (%v):\n%s", err, data)
+	}
+
+	// Without retention rules any tag categorizes evidence
+	system.config.Storage.RetentionRules = nil
+	report, _ = system.OfficerAccountability(now.AddDate(0, 0, -30), now, now)
+	if report.Officers[0].Uncategorized != 2 || report.Officers[0].OverdueCount != 1 {
+		t.Errorf("Expected any tag to count, got %+v", report.Officers[0])
+	}
+}
+
+func TestScheduledOfficerAccountability(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(testFile, "CASE-OA-010", "OFF-1126", "Officer Test", "Test Location", nil)
+
+	end := time.Now().Add(24 * time.Hour)
+	schedule := ReportSchedule{Name: "officers", Type: ScheduledOfficerAccountability, Format: "csv"}
+	artifacts, err := system.GenerateScheduledReport(schedule, end.AddDate(0, 0, -7), end)
+	if err != nil {
+		t.Fatalf("Scheduled report failed: %v", err)
+	}
+	if len(artifacts) != 1 || !strings.HasSuffix(artifacts[0].Name, ".csv") || !strings.Contains(string(artifacts[0].Data), "OFF-1126,Officer Test,1,") {
+		t.Errorf("Unexpected artifacts: %+v", artifacts)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Reports.Schedules = []ReportSchedule{schedule}
+	cfg.Reports.Schedules[0].Frequency = "weekly"
+	cfg.Compliance.ClassificationDeadlineDays = 0
+	err = cfg.Validate()
+	if err == nil || strings.Contains(err.Error(), "officer_accountability\" is not one of") || !strings.Contains(err.Error(), "classification_deadline_days") {
+		t.Errorf("Expected only the deadline to be rejected, got %v", err)
+	}
+}
+
+func TestOfficerAccountabilityEndpoint(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	ev, _ := system.IngestEvidence(testFile, "CASE-OA-020", "OFF-1127", "Officer Test", "Test Location", nil)
+	ev.CreatedAt = ev.CreatedAt.AddDate(0, 0, -5)
+
+	resp := authGet(t, server, "/api/retention/officers?days=0")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for days=0, got %d", resp.StatusCode)
+	}
+
+	resp = authGet(t, server, "/api/retention/officers?days=7")
+	var report OfficerAccountabilityReport
+	json.NewDecoder(resp.Body).Decode(&report)
+	resp.Body.Close()
+	if len(report.Officers) != 1 || report.Officers[0].Overdue[0] != ev.ID {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	resp = authGet(t, server, "/api/retention/officers?format=text")
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text, got %s", ct)
+	}
+
+	if logs := system.GetAuditLogs("", "CUS-001"); len(logs) != 2 || logs[0].Action != "OFFICER_ACCOUNTABILITY_REPORT" {
+		t.Errorf("Expected OFFICER_ACCOUNTABILITY_REPORT audit entries, got %+v", logs)
+	}
+}
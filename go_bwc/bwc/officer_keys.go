@@ -0,0 +1,205 @@
+package bwc
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// officerKeysFile holds every officer signing key ever enrolled, so entries
+// signed with a key since replaced still verify
+const officerKeysFile = "officer_keys.json"
+
+// OfficerKey is an officer's enrolled Ed25519 public key. The private key
+// stays with the officer; custody entries they sign carry the key's ID.
+type OfficerKey struct {
+	KeyID      string            `json:"key_id"`
+	OfficerID  string            `json:"officer_id"`
+	PublicKey  ed25519.PublicKey `json:"public_key"`
+	EnrolledAt time.Time         `json:"enrolled_at"`
+	EnrolledBy string            `json:"enrolled_by"`
+	// RevokedAt is set once the key is replaced or revoked. Entries signed
+	// before then still verify; it signs nothing after.
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy     string     `json:"revoked_by,omitempty"`
+	RevokedReason string     `json:"revoked_reason,omitempty"`
+}
+
+// officerKeyID names an Ed25519 public key by its fingerprint
+func officerKeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return "ed25519:" + hex.EncodeToString(sum[:8])
+}
+
+// loadOfficerKeys reads the enrolled keys from storage
+func (bwc *BWCSystem) loadOfficerKeys() error {
+	data, err := os.ReadFile(filepath.Join(bwc.storagePath, officerKeysFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read officer keys: %w", err)
+	}
+	var keys []*OfficerKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("%s: %w", officerKeysFile, err)
+	}
+	for _, key := range keys {
+		bwc.officerKeys[key.KeyID] = key
+	}
+	return nil
+}
+
+// saveOfficerKeysLocked writes the enrolled keys. The caller must hold bwc.mu.
+func (bwc *BWCSystem) saveOfficerKeysLocked() error {
+	return writeSnapshot(filepath.Join(bwc.storagePath, officerKeysFile), bwc.officerKeysLocked(""))
+}
+
+// officerKeysLocked lists the keys of officerID, or of every officer when it
+// is empty, oldest first. The caller must hold bwc.mu.
+func (bwc *BWCSystem) officerKeysLocked(officerID string) []*OfficerKey {
+	keys := make([]*OfficerKey, 0)
+	for _, key := range bwc.officerKeys {
+		if officerID == "" || key.OfficerID == officerID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].EnrolledAt.Equal(keys[j].EnrolledAt) {
+			return keys[i].EnrolledAt.Before(keys[j].EnrolledAt)
+		}
+		return keys[i].KeyID < keys[j].KeyID
+	})
+	return keys
+}
+
+// activeOfficerKeyLocked returns the key officerID signs with now, or nil.
+// The caller must hold bwc.mu.
+func (bwc *BWCSystem) activeOfficerKeyLocked(officerID string) *OfficerKey {
+	for _, key := range bwc.officerKeys {
+		if key.OfficerID == officerID && key.RevokedAt == nil {
+			return key
+		}
+	}
+	return nil
+}
+
+// EnrollOfficerKey enrolls publicKey as the key officerID signs custody
+// entries with. A key the officer had before is revoked from now on.
+func (bwc *BWCSystem) EnrollOfficerKey(officerID string, publicKey ed25519.PublicKey, enrolledBy string) (*OfficerKey, error) {
+	return bwc.EnrollOfficerKeyContext(context.Background(), officerID, publicKey, enrolledBy)
+}
+
+// EnrollOfficerKeyContext enrolls like EnrollOfficerKey, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) EnrollOfficerKeyContext(ctx context.Context, officerID string, publicKey ed25519.PublicKey, enrolledBy string) (*OfficerKey, error) {
+	enrolledBy, auth := actingUser(ctx, enrolledBy)
+	if err := bwc.authorize(enrolledBy, PermManageUsers, "Officer key enrollment", ""); err != nil {
+		return nil, err
+	}
+	if err := ValidateOfficerID(officerID); err != nil {
+		return nil, err
+	}
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, &ValidationError{Field: "public key", Reason: fmt.Sprintf("must be a %d-byte Ed25519 key", ed25519.PublicKeySize)}
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	keyID := officerKeyID(publicKey)
+	if existing := bwc.officerKeys[keyID]; existing != nil {
+		return nil, fmt.Errorf("key %s is already enrolled for %s", keyID, existing.OfficerID)
+	}
+
+	now := time.Now()
+	previous := bwc.activeOfficerKeyLocked(officerID)
+	if previous != nil {
+		previous.RevokedAt, previous.RevokedBy, previous.RevokedReason = &now, enrolledBy, "Replaced by "+keyID
+	}
+	key := &OfficerKey{
+		KeyID:      keyID,
+		OfficerID:  officerID,
+		PublicKey:  append(ed25519.PublicKey(nil), publicKey...),
+		EnrolledAt: now,
+		EnrolledBy: enrolledBy,
+	}
+	bwc.officerKeys[keyID] = key
+	if err := bwc.saveOfficerKeysLocked(); err != nil {
+		delete(bwc.officerKeys, keyID)
+		if previous != nil {
+			previous.RevokedAt, previous.RevokedBy, previous.RevokedReason = nil, "", ""
+		}
+		return nil, err
+	}
+
+	details := fmt.Sprintf("Signing key %s enrolled for %s", keyID, officerID)
+	if previous != nil {
+		details += ", replacing " + previous.KeyID
+	}
+	bwc.logAuditAs(auth, enrolledBy, "ENROLL_OFFICER_KEY", "", details, "")
+	copied := *key
+	return &copied, nil
+}
+
+// RevokeOfficerKey stops the officer's key signing custody entries, e.g.
+// when the private key is lost. Entries it signed before still verify.
+func (bwc *BWCSystem) RevokeOfficerKey(keyID, revokedBy, reason string) error {
+	return bwc.RevokeOfficerKeyContext(context.Background(), keyID, revokedBy, reason)
+}
+
+// RevokeOfficerKeyContext revokes like RevokeOfficerKey, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) RevokeOfficerKeyContext(ctx context.Context, keyID, revokedBy, reason string) error {
+	revokedBy, auth := actingUser(ctx, revokedBy)
+	if err := bwc.authorize(revokedBy, PermManageUsers, "Officer key revocation", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	key := bwc.officerKeys[keyID]
+	if key == nil {
+		return fmt.Errorf("officer key %s is not enrolled", keyID)
+	}
+	if key.RevokedAt != nil {
+		return fmt.Errorf("officer key %s is already revoked", keyID)
+	}
+	now := time.Now()
+	key.RevokedAt, key.RevokedBy, key.RevokedReason = &now, revokedBy, reason
+	if err := bwc.saveOfficerKeysLocked(); err != nil {
+		key.RevokedAt, key.RevokedBy, key.RevokedReason = nil, "", ""
+		return err
+	}
+	bwc.logAuditAs(auth, revokedBy, "REVOKE_OFFICER_KEY", "", fmt.Sprintf("Signing key %s of %s revoked - %s", keyID, key.OfficerID, reason), "")
+	return nil
+}
+
+// OfficerKeys lists the keys enrolled for officerID, revoked ones included,
+// or every officer's keys when it is empty
+func (bwc *BWCSystem) OfficerKeys(officerID string) []OfficerKey {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	keys := bwc.officerKeysLocked(officerID)
+	result := make([]OfficerKey, len(keys))
+	for i, key := range keys {
+		result[i] = *key
+	}
+	return result
+}
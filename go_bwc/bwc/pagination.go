@@ -0,0 +1,258 @@
+package bwc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SortField names the order of a paged query
+type SortField string
+
+const (
+	SortByTimestamp  SortField = "timestamp"
+	SortByCaseNumber SortField = "case_number"
+	SortByStatus     SortField = "status"
+)
+
+// QueryOptions pages and orders SearchEvidencePage and GetAuditLogsPage.
+// Results are in a total order, with ties broken by evidence ID or by log
+// position, so pages do not overlap or skip.
+type QueryOptions struct {
+	// Limit is the most results to return; 0 returns the rest
+	Limit int
+	// Offset skips results, counted from the cursor when there is one
+	Offset int
+	// Cursor is the NextCursor of the previous page. Paging by cursor is
+	// unaffected by records added or removed in between.
+	Cursor string
+	// SortBy defaults to timestamp; audit logs sort only by timestamp
+	SortBy SortField
+	// Descending puts the newest, or the last in the sort, first
+	Descending bool
+}
+
+// EvidenceFilter selects the evidence SearchEvidencePage returns; empty
+// fields match everything
+type EvidenceFilter struct {
+	CaseNumber string
+	OfficerID  string
+	Status     EvidenceStatus
+	MediaType  MediaType
+}
+
+// AuditFilter selects the entries GetAuditLogsPage or ExportAuditLogs
+// returns; empty fields match everything
+type AuditFilter struct {
+	EvidenceID string `json:"evidence_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	Action     string `json:"action,omitempty"`
+	// Since and Until bound the entries' timestamps, Until exclusive
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+}
+
+// matches reports whether f selects log
+func (f AuditFilter) matches(log AuditLog) bool {
+	return (f.EvidenceID == "" || log.EvidenceID == f.EvidenceID) && (f.UserID == "" || log.UserID == f.UserID) &&
+		(f.Action == "" || log.Action == f.Action) &&
+		(f.Since.IsZero() || !log.Timestamp.Before(f.Since)) && (f.Until.IsZero() || log.Timestamp.Before(f.Until))
+}
+
+// EvidencePage is one page of a search
+type EvidencePage struct {
+	Evidence []*Evidence `json:"evidence"`
+	// Total counts every match, on all pages
+	Total int `json:"total"`
+	// NextCursor continues after this page; it is empty on the last
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// AuditLogPage is one page of the audit log
+type AuditLogPage struct {
+	Entries    []AuditLog `json:"entries"`
+	Total      int        `json:"total"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// pageKey places a result in the order of a paged query
+type pageKey struct {
+	Key string `json:"k"`
+	ID  string `json:"i"`
+}
+
+func (a pageKey) less(b pageKey) bool {
+	if a.Key != b.Key {
+		return a.Key < b.Key
+	}
+	return a.ID < b.ID
+}
+
+// pageCursor is the decoded form of a NextCursor. It records the order it
+// was made for so it is not applied to another.
+type pageCursor struct {
+	SortBy     SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	After      pageKey   `json:"a"`
+}
+
+func encodeCursor(c pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return c, &ValidationError{Field: "cursor", Value: s, Reason: "is not a cursor from a previous page"}
+	}
+	return c, nil
+}
+
+// timestampKey orders times as strings
+func timestampKey(unixNano int64) string {
+	return fmt.Sprintf("%020d", unixNano)
+}
+
+// page sorts keys in the order opts asks for and returns the range of the
+// sorted indexes that makes up the page, with the cursor for the next
+func page(keys []pageKey, sortBy SortField, opts QueryOptions) (order []int, next string, err error) {
+	if opts.Limit < 0 {
+		return nil, "", &ValidationError{Field: "limit", Value: fmt.Sprint(opts.Limit), Reason: "must not be negative"}
+	}
+	if opts.Offset < 0 {
+		return nil, "", &ValidationError{Field: "offset", Value: fmt.Sprint(opts.Offset), Reason: "must not be negative"}
+	}
+
+	order = make([]int, len(keys))
+	for i := range order {
+		order[i] = i
+	}
+	before := func(a, b pageKey) bool {
+		if opts.Descending {
+			return b.less(a)
+		}
+		return a.less(b)
+	}
+	sort.Slice(order, func(i, j int) bool { return before(keys[order[i]], keys[order[j]]) })
+
+	start := 0
+	if opts.Cursor != "" {
+		cursor, err := decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.SortBy != sortBy || cursor.Descending != opts.Descending {
+			return nil, "", &ValidationError{Field: "cursor", Value: opts.Cursor, Reason: "was made for a different sort"}
+		}
+		start = sort.Search(len(order), func(i int) bool { return before(cursor.After, keys[order[i]]) })
+	}
+	start += opts.Offset
+	if start > len(order) {
+		start = len(order)
+	}
+	end := len(order)
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+		next = encodeCursor(pageCursor{SortBy: sortBy, Descending: opts.Descending, After: keys[order[end-1]]})
+	}
+	return order[start:end], next, nil
+}
+
+// SearchEvidencePage returns one page of the evidence filter selects, in the
+// order opts asks for. Read auditing covers the records on the page.
+func (bwc *BWCSystem) SearchEvidencePage(ctx context.Context, filter EvidenceFilter, opts QueryOptions) (*EvidencePage, error) {
+	sortBy := opts.SortBy
+	if sortBy == "" {
+		sortBy = SortByTimestamp
+	}
+	switch sortBy {
+	case SortByTimestamp, SortByCaseNumber, SortByStatus:
+	default:
+		return nil, &ValidationError{Field: "sort", Value: string(sortBy), Reason: "must be timestamp, case_number or status"}
+	}
+
+	results, err := bwc.searchEvidence(ctx, filter.CaseNumber, filter.OfficerID, filter.Status)
+	if err != nil {
+		return nil, err
+	}
+	if filter.MediaType != "" {
+		matched := results[:0]
+		for _, evidence := range results {
+			if mediaTypeOf(evidence) == filter.MediaType {
+				matched = append(matched, evidence)
+			}
+		}
+		results = matched
+	}
+
+	keys := make([]pageKey, len(results))
+	for i, evidence := range results {
+		keys[i].ID = evidence.ID
+		switch sortBy {
+		case SortByTimestamp:
+			keys[i].Key = timestampKey(evidence.Timestamp.UnixNano())
+		case SortByCaseNumber:
+			keys[i].Key = evidence.CaseNumber
+		case SortByStatus:
+			keys[i].Key = string(evidence.Status)
+		}
+	}
+	order, next, err := page(keys, sortBy, opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &EvidencePage{Evidence: make([]*Evidence, 0, len(order)), Total: len(results), NextCursor: next}
+	for _, i := range order {
+		p.Evidence = append(p.Evidence, results[i])
+	}
+	// Only the page is returned, so only the page is audited as read
+	bwc.auditReads(ctx, "SEARCH_EVIDENCE", p.Evidence, searchCriteria(filter.CaseNumber, filter.OfficerID, filter.Status))
+	return p, nil
+}
+
+// GetAuditLogsPageContext returns one page of the audit entries filter
+// selects if the principal ctx carries may review the audit trail
+func (bwc *BWCSystem) GetAuditLogsPageContext(ctx context.Context, filter AuditFilter, opts QueryOptions) (*AuditLogPage, error) {
+	if err := bwc.authorize(readerFromContext(ctx), PermReviewAudit, "Read audit log", filter.EvidenceID); err != nil {
+		return nil, err
+	}
+	return bwc.GetAuditLogsPage(filter, opts)
+}
+
+// GetAuditLogsPage returns one page of the audit entries filter selects,
+// ordered by time, without checking who asks
+func (bwc *BWCSystem) GetAuditLogsPage(filter AuditFilter, opts QueryOptions) (*AuditLogPage, error) {
+	if opts.SortBy != "" && opts.SortBy != SortByTimestamp {
+		return nil, &ValidationError{Field: "sort", Value: string(opts.SortBy), Reason: "audit logs sort only by timestamp"}
+	}
+
+	bwc.auditMu.Lock()
+	var logs []AuditLog
+	var keys []pageKey
+	for seq, log := range bwc.auditLogs {
+		if filter.matches(log) {
+			logs = append(logs, log)
+			// The log is append-only, so an entry's position identifies it
+			keys = append(keys, pageKey{Key: timestampKey(log.Timestamp.UnixNano()), ID: fmt.Sprintf("%012d", seq)})
+		}
+	}
+	bwc.auditMu.Unlock()
+
+	order, next, err := page(keys, SortByTimestamp, opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &AuditLogPage{Entries: make([]AuditLog, 0, len(order)), Total: len(logs), NextCursor: next}
+	for _, i := range order {
+		p.Entries = append(p.Entries, logs[i])
+	}
+	return p, nil
+}
@@ -0,0 +1,137 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDistinctClip writes a recording unlike any other in the test, so
+// repeated ingests are not deduplicated. Evidence IDs are made from the case,
+// officer and second, so callers vary the officer too.
+func writeDistinctClip(t *testing.T, dir string, n int) string {
+	path := filepath.Join(dir, fmt.Sprintf("clip-%d.mp4", n))
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("recording %d", n)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSearchEvidencePageCursorAndSort(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	for i, caseNumber := range []string{"CASE-PG-003", "CASE-PG-001", "CASE-PG-002", "CASE-PG-001", "CASE-PG-004"} {
+		if _, err := system.IngestEvidence(writeDistinctClip(t, tmpDir, i), caseNumber, fmt.Sprintf("OFF-130%d", i), "", "", nil); err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	seen := map[string]bool{}
+	opts := QueryOptions{Limit: 2, SortBy: SortByCaseNumber}
+	var cases []string
+	for pages := 0; ; pages++ {
+		page, err := system.SearchEvidencePage(ctx, EvidenceFilter{}, opts)
+		if err != nil {
+			t.Fatalf("SearchEvidencePage failed: %v", err)
+		}
+		if page.Total != 5 || len(page.Evidence) > 2 || pages > 3 {
+			t.Fatalf("unexpected page %d: total %d, %d items", pages, page.Total, len(page.Evidence))
+		}
+		for _, evidence := range page.Evidence {
+			if seen[evidence.ID] {
+				t.Errorf("expected pages not to overlap, %s seen twice", evidence.ID)
+			}
+			seen[evidence.ID] = true
+			cases = append(cases, evidence.CaseNumber)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+	want := []string{"CASE-PG-001", "CASE-PG-001", "CASE-PG-002", "CASE-PG-003", "CASE-PG-004"}
+	if len(cases) != len(want) {
+		t.Fatalf("expected every item once, got %v", cases)
+	}
+	for i := range want {
+		if cases[i] != want[i] {
+			t.Fatalf("expected case order %v, got %v", want, cases)
+		}
+	}
+
+	desc, err := system.SearchEvidencePage(ctx, EvidenceFilter{}, QueryOptions{Offset: 1, Limit: 1, SortBy: SortByCaseNumber, Descending: true})
+	if err != nil || len(desc.Evidence) != 1 || desc.Evidence[0].CaseNumber != "CASE-PG-003" {
+		t.Errorf("expected the second case from the end, got %+v, %v", desc, err)
+	}
+
+	for _, opts := range []QueryOptions{
+		{SortBy: "officer"},
+		{Cursor: "not-a-cursor"},
+		{SortBy: SortByStatus, Cursor: desc.NextCursor},
+		{Limit: -1},
+	} {
+		if _, err := system.SearchEvidencePage(ctx, EvidenceFilter{}, opts); ErrorCodeOf(err) != CodeInvalidArgument {
+			t.Errorf("expected %+v to be refused, got %v", opts, err)
+		}
+	}
+}
+
+func TestAuditLogsPage(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PG-010", "OFF-1301", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		system.VerifyIntegrity(evidence.ID, "AUDITOR-1")
+	}
+
+	filter := AuditFilter{EvidenceID: evidence.ID, Action: "VERIFY_INTEGRITY"}
+	first, err := system.GetAuditLogsPage(filter, QueryOptions{Limit: 2})
+	if err != nil || first.Total != 3 || len(first.Entries) != 2 || first.NextCursor == "" {
+		t.Fatalf("expected the first two of three checks, got %+v, %v", first, err)
+	}
+	rest, err := system.GetAuditLogsPage(filter, QueryOptions{Limit: 2, Cursor: first.NextCursor})
+	if err != nil || len(rest.Entries) != 1 || rest.NextCursor != "" {
+		t.Errorf("expected the last check alone, got %+v, %v", rest, err)
+	}
+	if _, err := system.GetAuditLogsPage(filter, QueryOptions{SortBy: SortByStatus}); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Errorf("expected audit logs to sort only by time, got %v", err)
+	}
+}
+
+func TestServerPagesSearch(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		if _, err := system.IngestEvidence(writeDistinctClip(t, tmpDir, i), "CASE-PG-020", fmt.Sprintf("OFF-131%d", i), "", "", nil); err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+	}
+
+	resp := authGet(t, server, "/api/evidence?case=CASE-PG-020&limit=2&order=desc")
+	defer resp.Body.Close()
+	var results []Evidence
+	json.NewDecoder(resp.Body).Decode(&results)
+	if resp.StatusCode != http.StatusOK || len(results) != 2 || resp.Header.Get("X-Total-Count") != "3" || resp.Header.Get("X-Next-Cursor") == "" {
+		t.Errorf("expected a page of 2 of 3 with a cursor, got %d, %d items, headers %v", resp.StatusCode, len(results), resp.Header)
+	}
+	if len(results) == 2 && results[0].Timestamp.Before(results[1].Timestamp) {
+		t.Error("expected newest first")
+	}
+
+	bad := authGet(t, server, "/api/evidence?order=sideways")
+	bad.Body.Close()
+	if bad.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for a bad order, got %d", bad.StatusCode)
+	}
+}
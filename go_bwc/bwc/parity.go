@@ -0,0 +1,641 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Reed-Solomon parity files, in the spirit of par2. The evidence file is cut
+// into fixed-size blocks and block i joins group i mod G, so a burst of
+// damage is spread over many groups. Each group of up to DataBlocks blocks
+// gets ParityBlocks recovery blocks over GF(2^8), and any ParityBlocks damaged
+// blocks in a group can be rebuilt. The parity file records the SHA-256 of
+// every block, so damaged blocks are found without trusting the parity itself;
+// a repair is only accepted if the rebuilt file matches the evidence hash.
+
+// parityMagic starts every parity file
+const parityMagic = "BWCPAR1\n"
+
+var errNoParity = errors.New("evidence has no parity data")
+
+// ParityInfo describes the parity file kept alongside an evidence file
+type ParityInfo struct {
+	Path         string    `json:"path"`
+	SHA256       string    `json:"sha256"`
+	BlockSize    int       `json:"block_size"`
+	DataBlocks   int       `json:"data_blocks"`
+	ParityBlocks int       `json:"parity_blocks"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// parityHeader is the JSON trailer of a parity file, followed by its length
+// as a big-endian uint32
+type parityHeader struct {
+	BlockSize    int      `json:"block_size"`
+	DataBlocks   int      `json:"data_blocks"`
+	ParityBlocks int      `json:"parity_blocks"`
+	FileSize     int64    `json:"file_size"`
+	FileSHA256   string   `json:"file_sha256"`
+	BlockHashes  []string `json:"block_hashes"`
+	ParityHashes []string `json:"parity_hashes"`
+}
+
+// blocks is the number of data blocks in the file
+func (h *parityHeader) blocks() int {
+	return int((h.FileSize + int64(h.BlockSize) - 1) / int64(h.BlockSize))
+}
+
+// groups is the number of parity groups
+func (h *parityHeader) groups() int {
+	return (h.blocks() + h.DataBlocks - 1) / h.DataBlocks
+}
+
+// gfExp and gfLog are exponent and logarithm tables for GF(2^8) with the
+// polynomial x^8+x^4+x^3+x^2+1
+var gfExp, gfLog = gfTables()
+
+func gfTables() ([510]byte, [256]byte) {
+	var exp [510]byte
+	var log [256]byte
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		exp[i+255] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// gfMulAdd adds c*src to dst
+func gfMulAdd(dst, src []byte, c byte) {
+	if c == 0 {
+		return
+	}
+	var table [256]byte
+	for i := 1; i < 256; i++ {
+		table[i] = gfMul(byte(i), c)
+	}
+	for i, s := range src {
+		dst[i] ^= table[s]
+	}
+}
+
+// parityCoefficient is entry (j, i) of the Cauchy matrix that produces parity
+// block j from data block i. With the identity rows of the data blocks, any
+// DataBlocks rows form an invertible matrix.
+func parityCoefficient(dataBlocks, j, i int) byte {
+	return gfInv(byte(dataBlocks+j) ^ byte(i))
+}
+
+// gfInvert inverts a square matrix in place, returning false if it is singular
+func gfInvert(m [][]byte) ([][]byte, bool) {
+	n := len(m)
+	inv := make([][]byte, n)
+	for i := range inv {
+		inv[i] = make([]byte, n)
+		inv[i][i] = 1
+	}
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for row := col; row < n; row++ {
+			if m[row][col] != 0 {
+				pivot = row
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, false
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+		inv[col], inv[pivot] = inv[pivot], inv[col]
+
+		scale := gfInv(m[col][col])
+		for k := 0; k < n; k++ {
+			m[col][k] = gfMul(m[col][k], scale)
+			inv[col][k] = gfMul(inv[col][k], scale)
+		}
+		for row := 0; row < n; row++ {
+			if row == col || m[row][col] == 0 {
+				continue
+			}
+			f := m[row][col]
+			for k := 0; k < n; k++ {
+				m[row][k] ^= gfMul(f, m[col][k])
+				inv[row][k] ^= gfMul(f, inv[col][k])
+			}
+		}
+	}
+	return inv, true
+}
+
+// readBlock reads data block index of the file, zero-padded to the block size
+func (h *parityHeader) readBlock(f *os.File, index int, buf []byte) error {
+	for i := range buf {
+		buf[i] = 0
+	}
+	if index >= h.blocks() {
+		return nil
+	}
+	off := int64(index) * int64(h.BlockSize)
+	n := int64(h.BlockSize)
+	if off+n > h.FileSize {
+		n = h.FileSize - off
+	}
+	_, err := f.ReadAt(buf[:n], off)
+	if errors.Is(err, io.EOF) {
+		// A truncated file reads as zeros; the block hash exposes it
+		err = nil
+	}
+	return err
+}
+
+// blockLength is the unpadded length of data block index
+func (h *parityHeader) blockLength(index int) int64 {
+	off := int64(index) * int64(h.BlockSize)
+	if off+int64(h.BlockSize) > h.FileSize {
+		return h.FileSize - off
+	}
+	return int64(h.BlockSize)
+}
+
+// writeParityFile writes the parity file for the evidence file at src to dst
+// and returns its SHA-256
+func writeParityFile(src, dst string, fileSHA256 string, blockSize, dataBlocks, parityBlocks int) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	header := &parityHeader{
+		BlockSize:    blockSize,
+		DataBlocks:   dataBlocks,
+		ParityBlocks: parityBlocks,
+		FileSize:     info.Size(),
+		FileSHA256:   fileSHA256,
+	}
+	header.BlockHashes = make([]string, header.blocks())
+	header.ParityHashes = make([]string, 0, header.groups()*parityBlocks)
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return "", err
+	}
+	hw := &hashingWriter{dst: out, hash: sha256.New()}
+
+	err = func() error {
+		if _, err := hw.Write([]byte(parityMagic)); err != nil {
+			return err
+		}
+		groups := header.groups()
+		data := make([]byte, blockSize)
+		parity := make([][]byte, parityBlocks)
+		for j := range parity {
+			parity[j] = make([]byte, blockSize)
+		}
+
+		for g := 0; g < groups; g++ {
+			for j := range parity {
+				for k := range parity[j] {
+					parity[j][k] = 0
+				}
+			}
+			for i := 0; i < dataBlocks; i++ {
+				index := g + i*groups
+				if index >= len(header.BlockHashes) {
+					break
+				}
+				if err := header.readBlock(in, index, data); err != nil {
+					return err
+				}
+				sum := sha256.Sum256(data[:header.blockLength(index)])
+				header.BlockHashes[index] = hex.EncodeToString(sum[:])
+				for j := range parity {
+					gfMulAdd(parity[j], data, parityCoefficient(dataBlocks, j, i))
+				}
+			}
+			for j := range parity {
+				sum := sha256.Sum256(parity[j])
+				header.ParityHashes = append(header.ParityHashes, hex.EncodeToString(sum[:]))
+				if _, err := hw.Write(parity[j]); err != nil {
+					return err
+				}
+			}
+		}
+
+		trailer, err := json.Marshal(header)
+		if err != nil {
+			return err
+		}
+		if _, err := hw.Write(trailer); err != nil {
+			return err
+		}
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(trailer)))
+		if _, err := hw.Write(length[:]); err != nil {
+			return err
+		}
+		return out.Sync()
+	}()
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	return hex.EncodeToString(hw.hash.Sum(nil)), nil
+}
+
+// readParityHeader reads the trailer of an open parity file
+func readParityHeader(f *os.File) (*parityHeader, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(parityMagic))
+	if _, err := f.ReadAt(magic, 0); err != nil || string(magic) != parityMagic {
+		return nil, errors.New("not a parity file")
+	}
+
+	var length [4]byte
+	if _, err := f.ReadAt(length[:], info.Size()-4); err != nil {
+		return nil, errors.New("truncated parity file")
+	}
+	n := int64(binary.BigEndian.Uint32(length[:]))
+	if n > info.Size()-4-int64(len(parityMagic)) {
+		return nil, errors.New("truncated parity file")
+	}
+	trailer := make([]byte, n)
+	if _, err := f.ReadAt(trailer, info.Size()-4-n); err != nil {
+		return nil, err
+	}
+
+	var header parityHeader
+	if err := json.Unmarshal(trailer, &header); err != nil {
+		return nil, fmt.Errorf("malformed parity file: %w", err)
+	}
+	if header.BlockSize <= 0 || header.DataBlocks <= 0 || header.ParityBlocks <= 0 ||
+		len(header.BlockHashes) != header.blocks() || len(header.ParityHashes) != header.groups()*header.ParityBlocks {
+		return nil, errors.New("malformed parity file")
+	}
+	return &header, nil
+}
+
+// ParityRepair reports what a repair found and rebuilt
+type ParityRepair struct {
+	DamagedSHA256  string      `json:"damaged_sha256"`
+	RepairedRanges []ByteRange `json:"repaired_ranges"`
+}
+
+// rebuildFromParity writes a repaired copy of the damaged file src to dst
+// using the parity file at parityPath
+func rebuildFromParity(src, parityPath, dst string) ([]ByteRange, error) {
+	pf, err := os.Open(parityPath)
+	if err != nil {
+		return nil, err
+	}
+	defer pf.Close()
+	header, err := readParityHeader(pf)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	if err := copyFile(src, dst); err != nil {
+		return nil, err
+	}
+	out, err := os.OpenFile(dst, os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Close()
+	if err := out.Truncate(header.FileSize); err != nil {
+		return nil, err
+	}
+
+	groups := header.groups()
+	k, m, size := header.DataBlocks, header.ParityBlocks, header.BlockSize
+	repaired := make([]ByteRange, 0)
+	buf := make([]byte, size)
+
+	for g := 0; g < groups; g++ {
+		// Sort the group's rows into intact and damaged
+		blocks := make([][]byte, k)
+		damaged := make([]int, 0)
+		for i := 0; i < k; i++ {
+			index := g + i*groups
+			if index >= len(header.BlockHashes) {
+				blocks[i] = make([]byte, size)
+				continue
+			}
+			if err := header.readBlock(in, index, buf); err != nil {
+				return nil, err
+			}
+			sum := sha256.Sum256(buf[:header.blockLength(index)])
+			if hex.EncodeToString(sum[:]) != header.BlockHashes[index] {
+				damaged = append(damaged, i)
+				continue
+			}
+			blocks[i] = append([]byte(nil), buf...)
+		}
+		if len(damaged) == 0 {
+			continue
+		}
+
+		parity := make([][]byte, 0, m)
+		parityRows := make([]int, 0, m)
+		for j := 0; j < m && len(parity) < len(damaged); j++ {
+			block := make([]byte, size)
+			off := int64(len(parityMagic)) + int64(g*m+j)*int64(size)
+			if _, err := pf.ReadAt(block, off); err != nil {
+				return nil, fmt.Errorf("failed to read parity: %w", err)
+			}
+			sum := sha256.Sum256(block)
+			if hex.EncodeToString(sum[:]) != header.ParityHashes[g*m+j] {
+				continue
+			}
+			parity = append(parity, block)
+			parityRows = append(parityRows, j)
+		}
+		if len(parity) < len(damaged) {
+			return nil, fmt.Errorf("%d damaged blocks in parity group %d exceed the %d usable parity blocks", len(damaged), g, len(parity))
+		}
+
+		// Solve for the data from the intact data rows and enough parity rows
+		matrix := make([][]byte, 0, k)
+		values := make([][]byte, 0, k)
+		p := 0
+		for i := 0; i < k; i++ {
+			row := make([]byte, k)
+			if blocks[i] != nil {
+				row[i] = 1
+				values = append(values, blocks[i])
+			} else {
+				for c := 0; c < k; c++ {
+					row[c] = parityCoefficient(k, parityRows[p], c)
+				}
+				values = append(values, parity[p])
+				p++
+			}
+			matrix = append(matrix, row)
+		}
+		inv, ok := gfInvert(matrix)
+		if !ok {
+			return nil, errors.New("parity matrix is singular")
+		}
+
+		for _, i := range damaged {
+			rebuilt := make([]byte, size)
+			for c := 0; c < k; c++ {
+				gfMulAdd(rebuilt, values[c], inv[i][c])
+			}
+			index := g + i*groups
+			off := int64(index) * int64(size)
+			n := header.blockLength(index)
+			if _, err := out.WriteAt(rebuilt[:n], off); err != nil {
+				return nil, err
+			}
+			repaired = append(repaired, ByteRange{Start: off, End: off + n - 1})
+		}
+	}
+
+	if err := out.Sync(); err != nil {
+		return nil, err
+	}
+	return mergeByteRanges(repaired), nil
+}
+
+// mergeByteRanges sorts ranges and merges adjacent ones
+func mergeByteRanges(ranges []ByteRange) []ByteRange {
+	for i := 1; i < len(ranges); i++ {
+		for j := i; j > 0 && ranges[j].Start < ranges[j-1].Start; j-- {
+			ranges[j], ranges[j-1] = ranges[j-1], ranges[j]
+		}
+	}
+	merged := make([]ByteRange, 0, len(ranges))
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && merged[n-1].End+1 >= r.Start {
+			if r.End > merged[n-1].End {
+				merged[n-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// parityPath is where the parity file of an evidence file is kept
+func parityPath(evidencePath string) string {
+	return evidencePath + ".par"
+}
+
+// generateParityLocked writes the parity file for evidence using the
+// configured layout. The caller must hold bwc.mu for writing.
+func (bwc *BWCSystem) generateParityLocked(evidence *Evidence) error {
+	cfg := bwc.config.Integrity.Parity
+	src, done, err := bwc.localStoredFile(evidence)
+	if err != nil {
+		return fmt.Errorf("failed to write parity: %w", err)
+	}
+	defer done()
+	path := parityPath(bwc.storagePathFor(evidence))
+	blockSize := cfg.BlockSizeKB << 10
+	sum, err := writeParityFile(src, path, storedHash(evidence), blockSize, cfg.DataBlocks, cfg.ParityBlocks)
+	if err != nil {
+		return fmt.Errorf("failed to write parity: %w", err)
+	}
+	evidence.Parity = &ParityInfo{
+		Path:         path,
+		SHA256:       sum,
+		BlockSize:    blockSize,
+		DataBlocks:   cfg.DataBlocks,
+		ParityBlocks: cfg.ParityBlocks,
+		CreatedAt:    time.Now(),
+	}
+	return nil
+}
+
+// GenerateParity writes a parity file for evidence ingested before parity was
+// enabled, or replaces a lost one
+func (bwc *BWCSystem) GenerateParity(evidenceID, userID string) (*ParityInfo, error) {
+	if err := bwc.authorize(userID, PermAdminister, "Parity generation", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	cfg := bwc.config.Integrity.Parity
+	if !cfg.Enabled {
+		return nil, errors.New("parity is not enabled")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Parity generation"); err != nil {
+		return nil, err
+	}
+
+	// Parity of a damaged file would preserve the damage
+	currentHash, err := bwc.hashStoredFile(evidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+	if currentHash != storedHash(evidence) {
+		return nil, errors.New("evidence fails integrity verification; parity not generated")
+	}
+
+	if err := bwc.generateParityLocked(evidence); err != nil {
+		return nil, err
+	}
+	markModified(evidence, time.Now())
+	if err := bwc.saveLocked(evidence); err != nil {
+		return nil, err
+	}
+	bwc.logAudit(userID, "GENERATE_PARITY", evidenceID,
+		fmt.Sprintf("Parity %d+%d blocks of %d KB written, sha256 %s", cfg.DataBlocks, cfg.ParityBlocks, cfg.BlockSizeKB, evidence.Parity.SHA256), "")
+
+	info := *evidence.Parity
+	return &info, nil
+}
+
+// RepairFromParity rebuilds a damaged evidence file from its parity file. The
+// rebuilt file replaces the damaged one only if it matches the evidence hash
+// recorded at ingest. The repair is documented in the integrity checks, the
+// chain of custody and the audit log, as is a failed attempt.
+func (bwc *BWCSystem) RepairFromParity(evidenceID, userID string) (*ParityRepair, error) {
+	if err := bwc.authorize(userID, PermAdminister, "Parity repair", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Parity repair"); err != nil {
+		return nil, err
+	}
+	if evidence.Parity == nil {
+		return nil, errNoParity
+	}
+
+	damagedHash, err := bwc.hashStoredFile(evidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+	if damagedHash == storedHash(evidence) {
+		return nil, errors.New("evidence is intact; nothing to repair")
+	}
+
+	fail := func(err error) (*ParityRepair, error) {
+		bwc.logAudit(userID, "REPAIR_FAILED", evidenceID,
+			fmt.Sprintf("Parity repair of damaged file sha256 %s failed: %v", damagedHash, err), "")
+		return nil, fmt.Errorf("parity repair failed: %w", err)
+	}
+
+	src, done, err := bwc.localStoredFile(evidence)
+	if err != nil {
+		return fail(err)
+	}
+	defer done()
+	local := bwc.storagePathFor(evidence)
+	tmp := filepath.Join(filepath.Dir(local), "."+filepath.Base(local)+".repair")
+	ranges, err := rebuildFromParity(src, evidence.Parity.Path, tmp)
+	if err == nil {
+		var rebuiltHash string
+		rebuiltHash, err = calculateFileHash(tmp)
+		if err == nil && rebuiltHash != storedHash(evidence) {
+			err = errors.New("rebuilt file does not match the evidence hash")
+		}
+	}
+	if err == nil {
+		err = bwc.replaceStoredFileLocked(evidence, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fail(err)
+	}
+
+	now := time.Now()
+	details := fmt.Sprintf("Repaired from parity: damaged sha256 %s, ", damagedHash)
+	if len(ranges) > 0 {
+		details += "rebuilt " + formatByteRanges(ranges)
+	} else {
+		details += fmt.Sprintf("removed data appended after byte %d", evidence.FileSize-1)
+	}
+	evidence.IntegrityChecks = append(evidence.IntegrityChecks, IntegrityCheck{
+		Timestamp: now,
+		CheckedBy: userID,
+		HashValue: evidence.FileHash,
+		IsValid:   true,
+		Notes:     details,
+	})
+	entry := CustodyEntry{
+		Timestamp:    now,
+		FromOfficer:  userID,
+		ToOfficer:    userID,
+		Action:       "REPAIRED",
+		Purpose:      details,
+		VerifiedHash: evidence.FileHash,
+	}
+	entry.EntryHash, err = custodyEntryHash(entry)
+	if err != nil {
+		return nil, err
+	}
+	evidence.ChainOfCustody = append(evidence.ChainOfCustody, entry)
+	markModified(evidence, now)
+	if err := bwc.saveLocked(evidence); err != nil {
+		return nil, err
+	}
+
+	bwc.logAudit(userID, "REPAIR_EVIDENCE", evidenceID, details, "")
+
+	return &ParityRepair{DamagedSHA256: damagedHash, RepairedRanges: ranges}, nil
+}
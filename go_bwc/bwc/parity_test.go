@@ -0,0 +1,235 @@
+package bwc
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testParityBlock = 4096
+
+// writeParityTestFile writes size bytes of pseudo-random content and its parity
+// with 4 data and 2 parity blocks per group
+func writeParityTestFile(t *testing.T, dir string, size int) (string, []byte) {
+	t.Helper()
+	data := make([]byte, size)
+	rand.New(rand.NewSource(int64(size))).Read(data)
+	path := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Failed to write test file: %v", err)
+	}
+	hash, _ := calculateFileHash(path)
+	if _, err := writeParityFile(path, parityPath(path), hash, testParityBlock, 4, 2); err != nil {
+		t.Fatalf("writeParityFile failed: %v", err)
+	}
+	return path, data
+}
+
+func TestGFInvert(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if gfMul(byte(a), gfInv(byte(a))) != 1 {
+			t.Fatalf("%d * inv(%d) != 1", a, a)
+		}
+	}
+	m := [][]byte{{1, 0}, {parityCoefficient(2, 0, 0), parityCoefficient(2, 0, 1)}}
+	if _, ok := gfInvert(m); !ok {
+		t.Error("Expected a systematic Cauchy matrix to be invertible")
+	}
+	if _, ok := gfInvert([][]byte{{1, 1}, {1, 1}}); ok {
+		t.Error("Expected a singular matrix to be reported")
+	}
+}
+
+func TestRebuildFromParity(t *testing.T) {
+	// 25 blocks in 7 groups; block i belongs to group i mod 7
+	const size = 24*testParityBlock + 1000
+	tests := []struct {
+		name    string
+		damage  func(data []byte) []byte
+		want    []ByteRange
+		wantErr bool
+	}{
+		{"single sector", func(d []byte) []byte { d[5*testParityBlock+17] ^= 0xff; return d },
+			[]ByteRange{{5 * testParityBlock, 6*testParityBlock - 1}}, false},
+		{"burst across groups", func(d []byte) []byte {
+			for i := 2 * testParityBlock; i < 9*testParityBlock; i++ {
+				d[i] = 0
+			}
+			return d
+		}, []ByteRange{{2 * testParityBlock, 9*testParityBlock - 1}}, false},
+		{"two in one group", func(d []byte) []byte { d[1] ^= 1; d[7*testParityBlock+1] ^= 1; return d },
+			[]ByteRange{{0, testParityBlock - 1}, {7 * testParityBlock, 8*testParityBlock - 1}}, false},
+		{"short last block", func(d []byte) []byte { d[size-1] ^= 1; return d },
+			[]ByteRange{{24 * testParityBlock, size - 1}}, false},
+		{"truncated", func(d []byte) []byte { return d[:20*testParityBlock] },
+			[]ByteRange{{20 * testParityBlock, size - 1}}, false},
+		{"three in one group", func(d []byte) []byte {
+			d[1] ^= 1
+			d[7*testParityBlock+1] ^= 1
+			d[14*testParityBlock+1] ^= 1
+			return d
+		}, nil, true},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		path, original := writeParityTestFile(t, dir, size)
+		os.WriteFile(path, tt.damage(append([]byte(nil), original...)), 0600)
+
+		out := filepath.Join(dir, "rebuilt")
+		ranges, err := rebuildFromParity(path, parityPath(path), out)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected the damage to exceed the parity", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: rebuildFromParity failed: %v", tt.name, err)
+		}
+		if rebuilt, _ := os.ReadFile(out); !bytes.Equal(rebuilt, original) {
+			t.Errorf("%s: rebuilt file differs from the original", tt.name)
+		}
+		if !reflect.DeepEqual(ranges, tt.want) {
+			t.Errorf("%s: got ranges %v, want %v", tt.name, ranges, tt.want)
+		}
+	}
+}
+
+func TestRebuildWithDamagedParity(t *testing.T) {
+	dir := t.TempDir()
+	path, original := writeParityTestFile(t, dir, 10*testParityBlock)
+
+	// Damage the first parity block of group 0 and one data block of that group
+	par, _ := os.ReadFile(parityPath(path))
+	par[len(parityMagic)+3] ^= 1
+	os.WriteFile(parityPath(path), par, 0600)
+	damaged := append([]byte(nil), original...)
+	damaged[9] ^= 1
+	os.WriteFile(path, damaged, 0600)
+
+	out := filepath.Join(dir, "rebuilt")
+	if _, err := rebuildFromParity(path, parityPath(path), out); err != nil {
+		t.Fatalf("Expected the intact parity block to suffice: %v", err)
+	}
+	if rebuilt, _ := os.ReadFile(out); !bytes.Equal(rebuilt, original) {
+		t.Error("Rebuilt file differs from the original")
+	}
+}
+
+func enableTestParity(system *BWCSystem) {
+	system.config.Integrity.Parity = ParityConfig{Enabled: true, BlockSizeKB: 4, DataBlocks: 4, ParityBlocks: 2}
+}
+
+func TestRepairFromParity(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableTestParity(system)
+
+	source, original := writeParityTestFile(t, tmpDir, 50000)
+	evidence, err := system.IngestEvidence(source, "CASE-PAR-001", "OFF-911", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if evidence.Parity == nil || evidence.Parity.Path != parityPath(evidence.FilePath) {
+		t.Fatalf("Expected parity at ingest, got %+v", evidence.Parity)
+	}
+
+	if _, err := system.RepairFromParity(evidence.ID, "TECH-1"); err == nil {
+		t.Error("Expected intact evidence to need no repair")
+	}
+
+	damaged := append([]byte(nil), original...)
+	damaged[12345] ^= 0xff
+	os.WriteFile(evidence.FilePath, damaged, 0600)
+
+	repair, err := system.RepairFromParity(evidence.ID, "TECH-1")
+	if err != nil {
+		t.Fatalf("RepairFromParity failed: %v", err)
+	}
+	want := []ByteRange{{3 * testParityBlock, 4*testParityBlock - 1}}
+	if !reflect.DeepEqual(repair.RepairedRanges, want) || repair.DamagedSHA256 == evidence.FileHash {
+		t.Errorf("Unexpected repair: %+v", repair)
+	}
+	if valid, _ := system.VerifyIntegrity(evidence.ID, "TECH-1"); !valid {
+		t.Error("Expected repaired evidence to verify")
+	}
+
+	custody := system.evidenceDB.Get(evidence.ID).ChainOfCustody
+	if last := custody[len(custody)-1]; last.Action != "REPAIRED" || !strings.Contains(last.Purpose, repair.DamagedSHA256) {
+		t.Errorf("Expected a REPAIRED custody entry, got %+v", last)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "TECH-1")
+	found := false
+	for _, log := range logs {
+		if log.Action == "REPAIR_EVIDENCE" && strings.Contains(log.Details, "bytes 12288-16383") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected a REPAIR_EVIDENCE audit entry, got %v", logs)
+	}
+}
+
+func TestRepairFromParityFailure(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableTestParity(system)
+
+	source, original := writeParityTestFile(t, tmpDir, 50000)
+	evidence, _ := system.IngestEvidence(source, "CASE-PAR-002", "OFF-912", "Officer Test", "Test Location", nil)
+
+	// 13 blocks in 4 groups: blocks 0, 4 and 8 of group 0 exceed its two parity blocks
+	damaged := append([]byte(nil), original...)
+	for i := 0; i < 3; i++ {
+		damaged[i*4*testParityBlock] ^= 1
+	}
+	os.WriteFile(evidence.FilePath, damaged, 0600)
+
+	if _, err := system.RepairFromParity(evidence.ID, "TECH-1"); err == nil {
+		t.Fatal("Expected the repair to fail")
+	}
+	if current, _ := os.ReadFile(evidence.FilePath); !bytes.Equal(current, damaged) {
+		t.Error("Expected a failed repair to leave the file untouched")
+	}
+	logs := system.GetAuditLogs(evidence.ID, "TECH-1")
+	if len(logs) != 1 || logs[0].Action != "REPAIR_FAILED" {
+		t.Errorf("Expected a REPAIR_FAILED audit entry, got %v", logs)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(system.storagePath, ".*.repair")); len(leftovers) != 0 {
+		t.Errorf("Repair files left behind: %v", leftovers)
+	}
+}
+
+func TestGenerateParity(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PAR-003", "OFF-913", "Officer Test", "Test Location", nil)
+	if evidence.Parity != nil {
+		t.Fatal("Expected no parity by default")
+	}
+	if _, err := system.RepairFromParity(evidence.ID, "TECH-1"); err == nil {
+		t.Error("Expected repair without parity to fail")
+	}
+	if _, err := system.GenerateParity(evidence.ID, "TECH-1"); err == nil {
+		t.Error("Expected GenerateParity to require parity to be enabled")
+	}
+
+	enableTestParity(system)
+	info, err := system.GenerateParity(evidence.ID, "TECH-1")
+	if err != nil {
+		t.Fatalf("GenerateParity failed: %v", err)
+	}
+	if _, err := os.Stat(info.Path); err != nil {
+		t.Errorf("Parity file missing: %v", err)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "TECH-1")
+	if last := logs[len(logs)-1]; last.Action != "GENERATE_PARITY" {
+		t.Errorf("Expected GENERATE_PARITY audit entry, got %v", last)
+	}
+}
@@ -0,0 +1,203 @@
+package bwc
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// US Letter in points, with one-inch side margins
+const (
+	pdfPageWidth  = 612.0
+	pdfPageHeight = 792.0
+	pdfMargin     = 72.0
+	pdfLineFactor = 1.35
+)
+
+// pdfFont selects one of the standard fonts every PDF reader provides
+type pdfFont int
+
+const (
+	pdfRegular pdfFont = iota
+	pdfBold
+	pdfMono
+)
+
+// pdfFontNames are the base fonts behind F1, F2 and F3
+var pdfFontNames = []string{"Helvetica", "Helvetica-Bold", "Courier"}
+
+// pdfWriter lays out text top to bottom over as many pages as it needs and
+// writes a minimal PDF 1.4 file. Only the standard fonts are used, so text
+// outside WinAnsi is replaced with '?'.
+type pdfWriter struct {
+	title  string
+	footer string
+	pages  []*bytes.Buffer
+	y      float64
+}
+
+func newPDFWriter(title, footer string) *pdfWriter {
+	p := &pdfWriter{title: title, footer: footer}
+	p.newPage()
+	return p
+}
+
+func (p *pdfWriter) newPage() {
+	p.pages = append(p.pages, &bytes.Buffer{})
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// charWidth approximates a glyph's width as a fraction of the font size.
+// Courier is exactly 0.6; Helvetica averages less, so wrapping is conservative.
+func (f pdfFont) charWidth() float64 {
+	if f == pdfMono {
+		return 0.6
+	}
+	return 0.55
+}
+
+// wrap splits s into lines that fit width at size, breaking at spaces where it can
+func wrap(s string, font pdfFont, size, width float64) []string {
+	limit := int(width / (font.charWidth() * size))
+	if limit < 1 {
+		limit = 1
+	}
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			for len(word) > limit {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, word[:limit])
+				word = word[limit:]
+			}
+			switch {
+			case line == "":
+				line = word
+			case len(line)+1+len(word) <= limit:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Text writes s wrapped to the page width, indented by indent points
+func (p *pdfWriter) Text(font pdfFont, size, indent float64, s string) {
+	for _, line := range wrap(s, font, size, pdfPageWidth-2*pdfMargin-indent) {
+		p.ensure(size * pdfLineFactor)
+		p.y -= size * pdfLineFactor
+		if line != "" {
+			fmt.Fprintf(p.pages[len(p.pages)-1], "BT /F%d %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+				font+1, size, pdfMargin+indent, p.y, pdfEscape(line))
+		}
+	}
+}
+
+// Centered writes a single line centred on the page
+func (p *pdfWriter) Centered(font pdfFont, size float64, s string) {
+	p.ensure(size * pdfLineFactor)
+	p.y -= size * pdfLineFactor
+	x := (pdfPageWidth - float64(len(s))*font.charWidth()*size) / 2
+	fmt.Fprintf(p.pages[len(p.pages)-1], "BT /F%d %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		font+1, size, x, p.y, pdfEscape(s))
+}
+
+// Gap leaves h points of vertical space
+func (p *pdfWriter) Gap(h float64) {
+	p.y -= h
+	if p.y < pdfMargin {
+		p.newPage()
+	}
+}
+
+// Rule draws a horizontal line across the text width
+func (p *pdfWriter) Rule() {
+	p.ensure(6)
+	p.y -= 6
+	fmt.Fprintf(p.pages[len(p.pages)-1], "0.5 w %.2f %.2f m %.2f %.2f l S\n",
+		pdfMargin, p.y, pdfPageWidth-pdfMargin, p.y)
+}
+
+// KeepTogether starts a new page unless h points remain on this one
+func (p *pdfWriter) KeepTogether(h float64) {
+	p.ensure(h)
+}
+
+func (p *pdfWriter) ensure(h float64) {
+	if p.y-h < pdfMargin {
+		p.newPage()
+	}
+}
+
+// pdfEscape encodes s as a WinAnsi PDF string body
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// Bytes writes the PDF, numbering pages in the footer
+func (p *pdfWriter) Bytes() []byte {
+	var buf bytes.Buffer
+	offsets := []int{0}
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets)-1, body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1 catalog, 2 page tree, 3-5 fonts, 6 info, then a page and its content per page
+	n := len(p.pages)
+	kids := make([]string, n)
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 7+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), n))
+	for _, name := range pdfFontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+	object(fmt.Sprintf("<< /Title (%s) /Producer (%s) >>", pdfEscape(p.title), pdfEscape("bwc-system")))
+
+	for i, page := range p.pages {
+		content := page.String()
+		footer := fmt.Sprintf("Page %d of %d", i+1, n)
+		if p.footer != "" {
+			footer = p.footer + "    " + footer
+		}
+		content += fmt.Sprintf("BT /F1 8.0 Tf %.2f %.2f Td (%s) Tj ET\n", pdfMargin, pdfMargin/2, pdfEscape(footer))
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 8+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets))
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 6 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets), xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,88 @@
+package bwc
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFWriterStructure(t *testing.T) {
+	p := newPDFWriter("Test (1)", "Footer")
+	for i := 0; i < 80; i++ {
+		p.Text(pdfRegular, 10, 0, fmt.Sprintf("Line %d", i))
+	}
+	data := p.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatal("Expected a PDF header and trailer")
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Error("Expected 80 lines to need two pages")
+	}
+	if !bytes.Contains(data, []byte("(Footer    Page 2 of 2)")) {
+		t.Error("Expected numbered page footers")
+	}
+	if !bytes.Contains(data, []byte("/Title (Test \\(1\\))")) {
+		t.Error("Expected the title to be escaped")
+	}
+
+	// Every xref offset must point at its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("Expected startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(data[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref does not point at the xref table: %q", lines[0])
+	}
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for obj := 1; obj < count; obj++ {
+		off, _ := strconv.Atoi(strings.Fields(lines[2+obj])[0])
+		if want := fmt.Sprintf("%d 0 obj", obj); !bytes.HasPrefix(data[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", obj, data[off:off+10])
+		}
+	}
+
+	// Stream lengths must match their content
+	for _, m := range regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)endstream`).FindAllSubmatch(data, -1) {
+		if n, _ := strconv.Atoi(string(m[1])); n != len(m[2]) {
+			t.Errorf("Stream length %d, content %d bytes", n, len(m[2]))
+		}
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	tests := map[string]string{
+		"plain":      "plain",
+		`a(b)c\d`:    `a\(b\)c\\d`,
+		"café":       `caf\351`,
+		"check ✓ ok": "check ? ok",
+	}
+	for in, want := range tests {
+		if got := pdfEscape(in); got != want {
+			t.Errorf("pdfEscape(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	// 10 points of Courier is 6 points a character, so 60 points holds 10
+	lines := wrap("the quick brown fox jumps", pdfMono, 10, 60)
+	want := []string{"the quick", "brown fox", "jumps"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %q, got %q", want, lines)
+	}
+
+	lines = wrap("0123456789abcdef", pdfMono, 10, 60)
+	if strings.Join(lines, "|") != "0123456789|abcdef" {
+		t.Errorf("Expected a long word to be broken, got %q", lines)
+	}
+
+	if lines := wrap("one\n\ntwo", pdfMono, 10, 60); len(lines) != 3 || lines[1] != "" {
+		t.Errorf("Expected blank lines to be kept, got %q", lines)
+	}
+}
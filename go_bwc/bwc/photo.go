@@ -0,0 +1,194 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// maxThumbnailSourcePixels bounds the photos decoded for a thumbnail
+	maxThumbnailSourcePixels = 50_000_000
+	defaultThumbnailSize     = 320
+	// defaultCaptureTimeTolerance is how far EXIF capture time may be from
+	// the claimed incident time before it is flagged
+	defaultCaptureTimeTolerance = time.Hour
+)
+
+// GeoPoint is a latitude and longitude in decimal degrees
+type GeoPoint struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// PhotoInfo is the metadata of a photo, read from its EXIF data where present
+type PhotoInfo struct {
+	Format      string     `json:"format"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	CaptureTime *time.Time `json:"capture_time,omitempty"`
+	CameraMake  string     `json:"camera_make,omitempty"`
+	CameraModel string     `json:"camera_model,omitempty"`
+	GPS         *GeoPoint  `json:"gps,omitempty"`
+	// Thumbnail is a reduced JPEG copy kept with the derived files
+	Thumbnail *DerivedFile `json:"thumbnail,omitempty"`
+	// TimeDiscrepancy explains how the capture time disagrees with the
+	// claimed incident time, when it does
+	TimeDiscrepancy string `json:"time_discrepancy,omitempty"`
+}
+
+// IngestPhoto ingests a photo taken at a claimed incident time. The photo's
+// EXIF capture time is compared against it and a disagreement beyond
+// photos.capture_time_tolerance_minutes is flagged on the record and audited.
+func (bwc *BWCSystem) IngestPhoto(filePath, caseNumber, officerID, officerName, location string, tags []string, incidentTime time.Time) (*Evidence, error) {
+	if mediaTypeFor(filePath) != MediaPhoto {
+		return nil, fmt.Errorf("%s is not a supported photo format", filepath.Base(filePath))
+	}
+	if incidentTime.IsZero() {
+		return nil, errors.New("incident time is required")
+	}
+	return bwc.ingestEvidence(context.Background(), filePath, caseNumber, officerID, officerName, location, tags, incidentTime, nil)
+}
+
+// probePhoto reads the dimensions and EXIF data of a stored photo and writes
+// its thumbnail. Whatever could be read is returned along with any error.
+func (bwc *BWCSystem) probePhoto(evidenceID, path string) (*PhotoInfo, error) {
+	info := &PhotoInfo{Format: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return info, err
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return info, fmt.Errorf("unreadable photo: %w", err)
+	}
+	info.Width, info.Height = cfg.Width, cfg.Height
+
+	if format == "jpeg" {
+		if _, err := f.Seek(0, 0); err != nil {
+			return info, err
+		}
+		exif, err := readJPEGExif(f)
+		if err != nil {
+			return info, fmt.Errorf("unreadable EXIF data: %w", err)
+		}
+		if exif != nil {
+			info.CaptureTime = exif.CaptureTime
+			info.CameraMake = exif.Make
+			info.CameraModel = exif.Model
+			info.GPS = exif.GPS
+		}
+	}
+
+	if cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return info, fmt.Errorf("photo of %dx%d is too large for a thumbnail", cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return info, err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return info, fmt.Errorf("unreadable photo: %w", err)
+	}
+	thumbnail, err := bwc.writeThumbnail(evidenceID, img)
+	if err != nil {
+		return info, err
+	}
+	info.Thumbnail = thumbnail
+	return info, nil
+}
+
+// writeThumbnail scales img to fit photos.thumbnail_size and stores it as a
+// derived file of the evidence
+func (bwc *BWCSystem) writeThumbnail(evidenceID string, img image.Image) (*DerivedFile, error) {
+	size := bwc.config.Photos.ThumbnailSize
+	if size <= 0 {
+		size = defaultThumbnailSize
+	}
+	src := img.Bounds()
+	w, h := src.Dx(), src.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max1(src.Dy()*size/src.Dx())
+		} else {
+			w, h = max1(src.Dx()*size/src.Dy()), size
+		}
+	}
+
+	// Nearest-neighbour scaling is enough for a preview
+	thumb := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			thumb.Set(x, y, img.At(src.Min.X+x*src.Dx()/w, src.Min.Y+y*src.Dy()/h))
+		}
+	}
+
+	dir := bwc.derivedDir(evidenceID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create derived directory: %w", err)
+	}
+	path := filepath.Join(dir, "thumbnail.jpg")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := jpeg.Encode(f, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write thumbnail: %w", err)
+	}
+
+	hash, err := calculateFileHash(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DerivedFile{Name: "thumbnail.jpg", Path: path, SHA256: hash, Size: stat.Size()}, nil
+}
+
+func max1(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// captureTimeDiscrepancy describes how far a photo's capture time is from the
+// claimed incident time, or returns "" when it is within tolerance or either
+// time is unknown
+func (bwc *BWCSystem) captureTimeDiscrepancy(photo *PhotoInfo, incidentTime time.Time) string {
+	if photo == nil || photo.CaptureTime == nil || incidentTime.IsZero() {
+		return ""
+	}
+	tolerance := time.Duration(bwc.config.Photos.CaptureTimeToleranceMinutes) * time.Minute
+	if tolerance <= 0 {
+		tolerance = defaultCaptureTimeTolerance
+	}
+
+	diff := photo.CaptureTime.Sub(incidentTime)
+	direction := "after"
+	if diff < 0 {
+		diff, direction = -diff, "before"
+	}
+	if diff <= tolerance {
+		return ""
+	}
+	return fmt.Sprintf("EXIF capture time %s is %s %s the claimed incident time %s",
+		photo.CaptureTime.Format(time.RFC3339), diff.Round(time.Minute), direction, incidentTime.Format(time.RFC3339))
+}
@@ -0,0 +1,92 @@
+package bwc
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIngestPhoto(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := writeTestAudio(t, tmpDir, "scene.jpg", testJPEG(640, 480, testExif("2024:03:09 21:15:02", "-05:00")))
+	incident := time.Date(2024, 3, 10, 2, 0, 0, 0, time.UTC)
+	evidence, err := system.IngestPhoto(path, "CASE-PHO-001", "OFF-991", "Officer Test", "Scene", nil, incident)
+	if err != nil {
+		t.Fatalf("IngestPhoto failed: %v", err)
+	}
+	photo := evidence.Photo
+	if evidence.MediaType != MediaPhoto || photo == nil || photo.Width != 640 || photo.Height != 480 ||
+		photo.CameraMake != "Canon" || photo.GPS == nil {
+		t.Fatalf("Unexpected photo metadata %+v", photo)
+	}
+	if photo.TimeDiscrepancy != "" || evidence.IncidentTime == nil || !evidence.IncidentTime.Equal(incident) {
+		t.Errorf("Expected a capture time within tolerance, got %q", photo.TimeDiscrepancy)
+	}
+
+	thumb := photo.Thumbnail
+	if thumb == nil {
+		t.Fatal("Expected a thumbnail")
+	}
+	data, err := os.ReadFile(thumb.Path)
+	if err != nil {
+		t.Fatalf("Failed to read thumbnail: %v", err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width != defaultThumbnailSize || cfg.Height != 240 {
+		t.Errorf("Expected a %dx240 thumbnail, got %dx%d (%v)", defaultThumbnailSize, cfg.Width, cfg.Height, err)
+	}
+	if hash, _ := calculateFileHash(thumb.Path); hash != thumb.SHA256 {
+		t.Error("Thumbnail hash does not match")
+	}
+
+	late := incident.Add(-3 * time.Hour)
+	flagged, err := system.IngestPhoto(path, "CASE-PHO-001", "OFF-992", "Officer Test", "Scene", nil, late)
+	if err != nil {
+		t.Fatalf("IngestPhoto failed: %v", err)
+	}
+	if !strings.Contains(flagged.Photo.TimeDiscrepancy, "3h15m0s after") {
+		t.Errorf("Expected the capture time flagged, got %q", flagged.Photo.TimeDiscrepancy)
+	}
+	logs := system.GetAuditLogs(flagged.ID, "SYSTEM")
+	if len(logs) != 1 || logs[0].Action != "PHOTO_TIME_DISCREPANCY" {
+		t.Errorf("Expected PHOTO_TIME_DISCREPANCY audit entry, got %v", logs)
+	}
+
+	report, _ := system.GenerateReport("CASE-PHO-001")
+	if !strings.Contains(report, "Canon EOS R5") || !strings.Contains(report, flagged.Photo.TimeDiscrepancy) {
+		t.Errorf("Expected the photo details in the report:\n%s", report)
+	}
+}
+
+func TestIngestPhotoWithoutExif(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	img := image.NewGray(image.Rect(0, 0, 100, 400))
+	img.Set(50, 200, color.White)
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	path := writeTestAudio(t, tmpDir, "diagram.png", buf.Bytes())
+
+	evidence, err := system.IngestPhoto(path, "CASE-PHO-002", "OFF-993", "Officer Test", "Station", nil, time.Now())
+	if err != nil {
+		t.Fatalf("IngestPhoto failed: %v", err)
+	}
+	if p := evidence.Photo; p.Format != "png" || p.CaptureTime != nil || p.TimeDiscrepancy != "" || p.Thumbnail == nil {
+		t.Errorf("Unexpected photo metadata %+v", p)
+	}
+
+	if _, err := system.IngestPhoto(createTestFile(t, tmpDir), "CASE-PHO-002", "OFF-994", "Officer Test", "Station", nil, time.Now()); err == nil {
+		t.Error("Expected a video file to be refused")
+	}
+	if _, err := system.IngestPhoto(path, "CASE-PHO-002", "OFF-995", "Officer Test", "Station", nil, time.Time{}); err == nil {
+		t.Error("Expected a missing incident time to be refused")
+	}
+}
@@ -0,0 +1,167 @@
+//go:build pkcs11
+
+package bwc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+
+	"github.com/miekg/pkcs11"
+)
+
+func init() {
+	openHSMKey = openPKCS11Key
+}
+
+// sha256DigestInfo is the DER DigestInfo prefix PKCS #1 v1.5 puts before a
+// SHA-256 digest; CKM_RSA_PKCS signs what it is given as is
+var sha256DigestInfo = []byte{0x30, 0x31, 0x30, 0x0d, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x01, 0x05, 0x00, 0x04, 0x20}
+
+// pkcs11Key is a private key in a PKCS#11 token. A session is not safe for
+// concurrent use, so signing is serialized.
+type pkcs11Key struct {
+	mu      sync.Mutex
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	handle  pkcs11.ObjectHandle
+	public  crypto.PublicKey
+}
+
+func openPKCS11Key(cfg HSMConfig, public crypto.PublicKey) (crypto.Signer, error) {
+	switch public.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, errors.New("HSM key must be RSA or ECDSA")
+	}
+
+	ctx := pkcs11.New(cfg.Module)
+	if ctx == nil {
+		return nil, fmt.Errorf("failed to load PKCS#11 module %s", cfg.Module)
+	}
+	if err := ctx.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize PKCS#11 module: %w", err)
+	}
+
+	slot, err := findPKCS11Slot(ctx, cfg.TokenLabel)
+	if err != nil {
+		ctx.Finalize()
+		return nil, err
+	}
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION)
+	if err != nil {
+		ctx.Finalize()
+		return nil, fmt.Errorf("failed to open PKCS#11 session: %w", err)
+	}
+	if err := ctx.Login(session, pkcs11.CKU_USER, cfg.PIN); err != nil {
+		var perr pkcs11.Error
+		if !errors.As(err, &perr) || perr != pkcs11.CKR_USER_ALREADY_LOGGED_IN {
+			ctx.CloseSession(session)
+			ctx.Finalize()
+			return nil, fmt.Errorf("failed to log in to token %s: %w", cfg.TokenLabel, err)
+		}
+	}
+
+	handle, err := findPKCS11PrivateKey(ctx, session, cfg.KeyLabel)
+	if err != nil {
+		ctx.CloseSession(session)
+		ctx.Finalize()
+		return nil, err
+	}
+	return &pkcs11Key{ctx: ctx, session: session, handle: handle, public: public}, nil
+}
+
+// findPKCS11Slot is the slot holding the token labelled label
+func findPKCS11Slot(ctx *pkcs11.Ctx, label string) (uint, error) {
+	slots, err := ctx.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list PKCS#11 slots: %w", err)
+	}
+	for _, slot := range slots {
+		info, err := ctx.GetTokenInfo(slot)
+		if err == nil && info.Label == label {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("no PKCS#11 token labelled %q", label)
+}
+
+// findPKCS11PrivateKey is the one private key labelled label
+func findPKCS11PrivateKey(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, label string) (pkcs11.ObjectHandle, error) {
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_PRIVATE_KEY),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := ctx.FindObjectsInit(session, template); err != nil {
+		return 0, fmt.Errorf("failed to search for HSM key: %w", err)
+	}
+	handles, _, err := ctx.FindObjects(session, 2)
+	ctx.FindObjectsFinal(session)
+	if err != nil {
+		return 0, fmt.Errorf("failed to search for HSM key: %w", err)
+	}
+	switch len(handles) {
+	case 0:
+		return 0, fmt.Errorf("no private key labelled %q in the HSM", label)
+	case 1:
+		return handles[0], nil
+	}
+	return 0, fmt.Errorf("more than one private key labelled %q in the HSM", label)
+}
+
+func (k *pkcs11Key) Public() crypto.PublicKey {
+	return k.public
+}
+
+// Sign signs a SHA-256 digest, the only one report signing uses
+func (k *pkcs11Key) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts.HashFunc() != crypto.SHA256 {
+		return nil, fmt.Errorf("HSM signing supports SHA-256 only, not %v", opts.HashFunc())
+	}
+	if _, ok := opts.(*rsa.PSSOptions); ok {
+		return nil, errors.New("HSM signing does not support RSA-PSS")
+	}
+
+	var mechanism uint
+	input := digest
+	switch k.public.(type) {
+	case *rsa.PublicKey:
+		mechanism = pkcs11.CKM_RSA_PKCS
+		input = append(append([]byte{}, sha256DigestInfo...), digest...)
+	case *ecdsa.PublicKey:
+		mechanism = pkcs11.CKM_ECDSA
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.ctx.SignInit(k.session, []*pkcs11.Mechanism{pkcs11.NewMechanism(mechanism, nil)}, k.handle); err != nil {
+		return nil, fmt.Errorf("HSM signing failed: %w", err)
+	}
+	signature, err := k.ctx.Sign(k.session, input)
+	if err != nil {
+		return nil, fmt.Errorf("HSM signing failed: %w", err)
+	}
+	if mechanism == pkcs11.CKM_ECDSA {
+		return ecdsaRawToDER(signature)
+	}
+	return signature, nil
+}
+
+// ecdsaRawToDER converts the r || s a token returns to the ASN.1 form X.509
+// and CMS use
+func ecdsaRawToDER(raw []byte) ([]byte, error) {
+	if len(raw) == 0 || len(raw)%2 != 0 {
+		return nil, errors.New("HSM returned a malformed ECDSA signature")
+	}
+	half := len(raw) / 2
+	return asn1.Marshal(struct{ R, S *big.Int }{
+		R: new(big.Int).SetBytes(raw[:half]),
+		S: new(big.Int).SetBytes(raw[half:]),
+	})
+}
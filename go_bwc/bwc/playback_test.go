@@ -0,0 +1,59 @@
+package bwc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestViewSessionRecordsRanges(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-VS-001", "OFF-401", "Officer Test", "Test Location", nil)
+
+	session, err := system.StartViewSession(evidence.ID, "REV-1", "10.0.0.5")
+	if err != nil {
+		t.Fatalf("StartViewSession failed: %v", err)
+	}
+
+	file, ev, err := system.OpenEvidenceStream(session.ID, evidence.ID, "REV-1")
+	if err != nil {
+		t.Fatalf("OpenEvidenceStream failed: %v", err)
+	}
+	file.Close()
+	if ev.ID != evidence.ID {
+		t.Errorf("Expected evidence %s, got %s", evidence.ID, ev.ID)
+	}
+
+	if _, _, err := system.OpenEvidenceStream(session.ID, evidence.ID, "REV-2"); !errors.Is(err, errViewSessionInvalid) {
+		t.Errorf("Expected another user's session to be rejected, got %v", err)
+	}
+	if _, _, err := system.OpenEvidenceStream("VS-999999", evidence.ID, "REV-1"); !errors.Is(err, errViewSessionInvalid) {
+		t.Errorf("Expected unknown session to be rejected, got %v", err)
+	}
+
+	system.RecordServedRange(session.ID, 0, 9, "10.0.0.5")
+	system.RecordServedRange(session.ID, 100, 149, "10.0.0.5")
+
+	sessions := system.ViewSessions(evidence.ID)
+	if len(sessions) != 1 || len(sessions[0].Ranges) != 2 || sessions[0].BytesServed != 60 {
+		t.Fatalf("Unexpected view sessions: %+v", sessions)
+	}
+
+	actions := make(map[string]int)
+	for _, log := range system.GetAuditLogs(evidence.ID, "REV-1") {
+		actions[log.Action]++
+	}
+	if actions["VIEW_SESSION_START"] != 1 || actions["STREAM_RANGE"] != 2 {
+		t.Errorf("Unexpected audit actions: %v", actions)
+	}
+
+	system.mu.Lock()
+	system.viewSessions[session.ID].LastActivity = time.Now().Add(-viewSessionIdleTimeout - time.Minute)
+	system.mu.Unlock()
+	if _, _, err := system.OpenEvidenceStream(session.ID, evidence.ID, "REV-1"); !errors.Is(err, errViewSessionInvalid) {
+		t.Errorf("Expected idle session to expire, got %v", err)
+	}
+}
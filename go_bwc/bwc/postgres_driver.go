@@ -0,0 +1,6 @@
+//go:build postgres
+
+package bwc
+
+// Builds with -tags postgres link in the pgx driver for database.type postgres
+import _ "github.com/jackc/pgx/v5/stdlib"
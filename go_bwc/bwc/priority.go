@@ -0,0 +1,278 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VerificationPriority sets how often scheduled verification re-checks evidence
+type VerificationPriority string
+
+const (
+	PriorityLow      VerificationPriority = "LOW"
+	PriorityNormal   VerificationPriority = "NORMAL"
+	PriorityHigh     VerificationPriority = "HIGH"
+	PriorityCritical VerificationPriority = "CRITICAL"
+)
+
+// priorityRank orders priorities from lowest to highest
+var priorityRank = map[VerificationPriority]int{
+	PriorityLow:      0,
+	PriorityNormal:   1,
+	PriorityHigh:     2,
+	PriorityCritical: 3,
+}
+
+// CaseSeverity is the seriousness of the offence evidence relates to
+type CaseSeverity string
+
+const (
+	SeverityInfraction  CaseSeverity = "INFRACTION"
+	SeverityMisdemeanor CaseSeverity = "MISDEMEANOR"
+	SeverityFelony      CaseSeverity = "FELONY"
+)
+
+// severityPriority is the priority each severity sets on its own
+var severityPriority = map[CaseSeverity]VerificationPriority{
+	SeverityInfraction:  PriorityLow,
+	SeverityMisdemeanor: PriorityNormal,
+	SeverityFelony:      PriorityHigh,
+}
+
+// ScheduledVerification is when evidence is next due for verification
+type ScheduledVerification struct {
+	EvidenceID   string               `json:"evidence_id"`
+	CaseNumber   string               `json:"case_number"`
+	Priority     VerificationPriority `json:"priority"`
+	LastVerified time.Time            `json:"last_verified"`
+	NextDue      time.Time            `json:"next_due"`
+}
+
+// raisePriority returns the higher of two priorities
+func raisePriority(p, q VerificationPriority) VerificationPriority {
+	if priorityRank[q] > priorityRank[p] {
+		return q
+	}
+	return p
+}
+
+// hasTag reports whether tags holds any of want, ignoring case
+func hasTag(tags, want []string) bool {
+	for _, tag := range tags {
+		for _, w := range want {
+			if strings.EqualFold(tag, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verificationPriority derives evidence's priority at now from its case
+// severity, its classification tags and how close its court date is. The
+// highest of them applies; evidence with none of them is NORMAL.
+func (c *Config) verificationPriority(evidence *Evidence, now time.Time) VerificationPriority {
+	p := c.Integrity.Priorities
+	priority := PriorityNormal
+	if evidence.Severity != "" {
+		priority = severityPriority[evidence.Severity]
+	}
+	if hasTag(evidence.Tags, p.HighTags) {
+		priority = raisePriority(priority, PriorityHigh)
+	}
+	if hasTag(evidence.Tags, p.CriticalTags) {
+		priority = PriorityCritical
+	}
+	if evidence.CourtDate != nil && p.CourtDateWindowDays > 0 {
+		// The court date counts until the end of its day
+		until := evidence.CourtDate.Sub(now)
+		if until > -24*time.Hour && until <= time.Duration(p.CourtDateWindowDays)*24*time.Hour {
+			priority = PriorityCritical
+		}
+	}
+	return priority
+}
+
+// verificationInterval returns how long evidence at a priority may go
+// between scheduled verifications
+func (c *Config) verificationInterval(priority VerificationPriority) time.Duration {
+	hours := c.Integrity.VerificationIntervalHours
+	switch priority {
+	case PriorityCritical:
+		hours = c.Integrity.Priorities.CriticalIntervalHours
+	case PriorityHigh:
+		hours = c.Integrity.Priorities.HighIntervalHours
+	case PriorityLow:
+		hours = c.Integrity.Priorities.LowIntervalHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// lastVerified returns when evidence last had an integrity check, or when it
+// was ingested if it has had none
+func lastVerified(evidence *Evidence) time.Time {
+	if n := len(evidence.SealedChecks); n > 0 {
+		return evidence.SealedChecks[n-1].Timestamp
+	}
+	if n := len(evidence.IntegrityChecks); n > 0 {
+		return evidence.IntegrityChecks[n-1].Timestamp
+	}
+	return evidence.CreatedAt
+}
+
+// VerificationPriority returns evidence's current verification priority
+func (bwc *BWCSystem) VerificationPriority(evidenceID string) (VerificationPriority, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return "", ErrEvidenceNotFound
+	}
+	return bwc.config.verificationPriority(evidence, time.Now()), nil
+}
+
+// SetVerificationFactors records the severity of the case evidence belongs to
+// and its next court date, which raise or lower its verification priority. A
+// nil courtDate clears it.
+func (bwc *BWCSystem) SetVerificationFactors(evidenceID, userID string, severity CaseSeverity, courtDate *time.Time) error {
+	if err := bwc.authorize(userID, PermEditMetadata, "Verification factor update", evidenceID); err != nil {
+		return err
+	}
+	if _, known := severityPriority[severity]; !known && severity != "" {
+		return fmt.Errorf("unknown case severity %q", severity)
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Verification priority change"); err != nil {
+		return err
+	}
+
+	evidence.Severity = severity
+	evidence.CourtDate = nil
+	court := "none"
+	if courtDate != nil {
+		date := *courtDate
+		evidence.CourtDate = &date
+		court = date.Format("2006-01-02")
+	}
+	markModified(evidence, time.Now())
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	bwc.logAudit(userID, "SET_VERIFICATION_PRIORITY", evidenceID,
+		fmt.Sprintf("Severity %s, court date %s: priority %s", severity, court,
+			bwc.config.verificationPriority(evidence, time.Now())), "")
+	return nil
+}
+
+// VerificationSchedule returns when each item is next due for scheduled
+// verification, soonest first. Deleted evidence is not verified on a
+// schedule and is left out.
+func (bwc *BWCSystem) VerificationSchedule(now time.Time) []ScheduledVerification {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	schedule := make([]ScheduledVerification, 0)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted {
+			continue
+		}
+		priority := bwc.config.verificationPriority(evidence, now)
+		last := lastVerified(evidence)
+		schedule = append(schedule, ScheduledVerification{
+			EvidenceID:   evidence.ID,
+			CaseNumber:   evidence.CaseNumber,
+			Priority:     priority,
+			LastVerified: last,
+			NextDue:      last.Add(bwc.config.verificationInterval(priority)),
+		})
+	}
+	sort.Slice(schedule, func(i, j int) bool {
+		if !schedule[i].NextDue.Equal(schedule[j].NextDue) {
+			return schedule[i].NextDue.Before(schedule[j].NextDue)
+		}
+		if schedule[i].Priority != schedule[j].Priority {
+			return priorityRank[schedule[i].Priority] > priorityRank[schedule[j].Priority]
+		}
+		return schedule[i].EvidenceID < schedule[j].EvidenceID
+	})
+	return schedule
+}
+
+// VerifyDue verifies every item due at now, highest priority first, and
+// returns how many were checked and how many failed
+func (bwc *BWCSystem) VerifyDue(now time.Time) (checked, failed int, err error) {
+	var due []ScheduledVerification
+	for _, item := range bwc.VerificationSchedule(now) {
+		if !item.NextDue.After(now) {
+			due = append(due, item)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		return priorityRank[due[i].Priority] > priorityRank[due[j].Priority]
+	})
+
+	var errs []error
+	for _, item := range due {
+		valid, err := bwc.VerifyIntegrity(item.EvidenceID, "SYSTEM")
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", item.EvidenceID, err))
+			continue
+		}
+		checked++
+		if !valid {
+			failed++
+		}
+	}
+	if checked > 0 {
+		bwc.logAudit("SYSTEM", "SCHEDULED_VERIFICATION", "",
+			fmt.Sprintf("Verified %d due item(s), %d failed", checked, failed), "")
+	}
+	return checked, failed, errors.Join(errs...)
+}
+
+// verificationTick is how often the scheduler looks for evidence that is due
+const verificationTick = 5 * time.Minute
+
+// verificationScheduler re-verifies evidence as it falls due
+type verificationScheduler struct {
+	system *BWCSystem
+}
+
+func newVerificationScheduler(system *BWCSystem) *verificationScheduler {
+	return &verificationScheduler{system: system}
+}
+
+// Run verifies due evidence every few minutes until stop is closed
+func (s *verificationScheduler) Run(stop <-chan struct{}, logf func(format string, args ...interface{})) {
+	ticker := time.NewTicker(verificationTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, failed, err := s.system.VerifyDue(time.Now()); err != nil {
+				logf("Scheduled verification failed: %v\n", err)
+			} else if failed > 0 {
+				logf("Scheduled verification: %d item(s) failed their integrity check\n", failed)
+			}
+		}
+	}
+}
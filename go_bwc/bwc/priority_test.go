@@ -0,0 +1,142 @@
+package bwc
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestVerificationPriority(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Integrity.Priorities.CriticalTags = []string{"homicide"}
+	cfg.Integrity.Priorities.HighTags = []string{"use-of-force"}
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+	soon := now.AddDate(0, 0, 10)
+	later := now.AddDate(0, 0, 30)
+	yesterday := now.Add(-20 * time.Hour)
+
+	tests := []struct {
+		name     string
+		evidence Evidence
+		want     VerificationPriority
+	}{
+		{"default", Evidence{}, PriorityNormal},
+		{"infraction", Evidence{Severity: SeverityInfraction}, PriorityLow},
+		{"felony", Evidence{Severity: SeverityFelony}, PriorityHigh},
+		{"high tag", Evidence{Severity: SeverityInfraction, Tags: []string{"Use-Of-Force"}}, PriorityHigh},
+		{"critical tag", Evidence{Tags: []string{"homicide"}}, PriorityCritical},
+		{"court date soon", Evidence{Severity: SeverityInfraction, CourtDate: &soon}, PriorityCritical},
+		{"court date later", Evidence{Severity: SeverityFelony, CourtDate: &later}, PriorityHigh},
+		{"court date today", Evidence{CourtDate: &yesterday}, PriorityCritical},
+	}
+	for _, tt := range tests {
+		if got := cfg.verificationPriority(&tt.evidence, now); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+
+	if got := cfg.verificationInterval(PriorityCritical); got != time.Hour {
+		t.Errorf("Expected a 1h critical interval, got %s", got)
+	}
+	if got := cfg.verificationInterval(PriorityNormal); got != 24*time.Hour {
+		t.Errorf("Expected normal evidence to use verification_interval_hours, got %s", got)
+	}
+}
+
+func TestSetVerificationFactors(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-PRI-001", "OFF-1060", "Officer Test", "Test Location", nil)
+
+	if err := system.SetVerificationFactors(evidence.ID, "DET-001", "ARSON", nil); err == nil {
+		t.Error("Expected an unknown severity to be rejected")
+	}
+
+	court := time.Now().AddDate(0, 0, 3)
+	if err := system.SetVerificationFactors(evidence.ID, "DET-001", SeverityFelony, &court); err != nil {
+		t.Fatalf("SetVerificationFactors failed: %v", err)
+	}
+	if priority, _ := system.VerificationPriority(evidence.ID); priority != PriorityCritical {
+		t.Errorf("Expected CRITICAL ahead of the court date, got %s", priority)
+	}
+	if evidence.Revision != 2 {
+		t.Errorf("Expected the revision to be bumped, got %d", evidence.Revision)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "DET-001")
+	if len(logs) != 1 || logs[0].Action != "SET_VERIFICATION_PRIORITY" {
+		t.Errorf("Expected SET_VERIFICATION_PRIORITY audit entry, got %+v", logs)
+	}
+
+	if err := system.SetVerificationFactors(evidence.ID, "DET-001", SeverityFelony, nil); err != nil {
+		t.Fatalf("SetVerificationFactors failed: %v", err)
+	}
+	if priority, _ := system.VerificationPriority(evidence.ID); priority != PriorityHigh || evidence.CourtDate != nil {
+		t.Errorf("Expected HIGH once the court date is cleared, got %s", priority)
+	}
+
+	if _, err := system.VerificationPriority("EV-MISSING"); err == nil {
+		t.Error("Expected an error for missing evidence")
+	}
+}
+
+func TestVerifyDue(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.Priorities.CriticalTags = []string{"homicide"}
+
+	testFile := createTestFile(t, tmpDir)
+	critical, _ := system.IngestEvidence(testFile, "CASE-PRI-002", "OFF-1061", "Officer Test", "Test Location", []string{"homicide"})
+	normal, _ := system.IngestEvidence(testFile, "CASE-PRI-003", "OFF-1062", "Officer Test", "Test Location", nil)
+	low, _ := system.IngestEvidence(testFile, "CASE-PRI-004", "OFF-1063", "Officer Test", "Test Location", nil)
+	system.SetVerificationFactors(low.ID, "DET-001", SeverityInfraction, nil)
+	sealed, _ := system.IngestEvidence(testFile, "CASE-PRI-005", "OFF-1064", "Officer Test", "Test Location", nil)
+	if _, err := system.SealEvidence(sealed.ID, "Court order 26-118"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+
+	// Sealed evidence is verified on a schedule too
+	schedule := system.VerificationSchedule(time.Now())
+	if len(schedule) != 4 || schedule[0].EvidenceID != critical.ID || schedule[3].EvidenceID != low.ID {
+		t.Fatalf("Unexpected schedule: %+v", schedule)
+	}
+
+	// Two hours on, only the critical item is due
+	checked, failed, err := system.VerifyDue(time.Now().Add(2 * time.Hour))
+	if err != nil || checked != 1 || failed != 0 {
+		t.Fatalf("Expected one item checked, got %d checked, %d failed, %v", checked, failed, err)
+	}
+	// Ingest records the first check
+	if len(critical.IntegrityChecks) != 2 || len(normal.IntegrityChecks) != 1 {
+		t.Errorf("Expected only the critical item to be verified")
+	}
+
+	// Two days on, the normal and sealed items are due too and the normal
+	// one has been tampered with; the critical item was checked just now but
+	// is due again by then
+	os.WriteFile(normal.FilePath, []byte("tampered"), 0600)
+	checked, failed, err = system.VerifyDue(time.Now().Add(48 * time.Hour))
+	if err != nil || checked != 3 || failed != 1 {
+		t.Fatalf("Expected three checked and one failed, got %d checked, %d failed, %v", checked, failed, err)
+	}
+	if got, _ := system.GetEvidence(sealed.ID); len(got.SealedChecks) != 1 {
+		t.Errorf("Expected the sealed item to be verified, got %+v", got.SealedChecks)
+	}
+	if ok, err := system.VerifySeal(sealed.ID); err != nil || !ok {
+		t.Errorf("Expected the seal to remain valid, got %v, %v", ok, err)
+	}
+	if len(low.IntegrityChecks) != 1 {
+		t.Error("Expected the low priority item not to be due yet")
+	}
+	logs := system.GetAuditLogs("", "SYSTEM")
+	var runs int
+	for _, log := range logs {
+		if log.Action == "SCHEDULED_VERIFICATION" {
+			runs++
+		}
+	}
+	if runs != 2 {
+		t.Errorf("Expected 2 SCHEDULED_VERIFICATION entries, got %d", runs)
+	}
+}
@@ -0,0 +1,446 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Processor analyzes or converts evidence: a transcoder, an analyzer or a
+// classifier. It must treat FilePath as read-only and write anything it
+// produces to OutputDir.
+type Processor interface {
+	Name() string
+	Process(ctx context.Context, input ProcessorInput) (*ProcessorResult, error)
+}
+
+// MediaFilter is implemented by processors that handle only some media types,
+// e.g. ffprobe for video or OCR for documents. Processors without it handle
+// every type.
+type MediaFilter interface {
+	HandlesMediaType(t MediaType) bool
+}
+
+// ProcessorInput is the evidence a processing job runs against
+type ProcessorInput struct {
+	JobID      string
+	EvidenceID string
+	CaseNumber string
+	MediaType  MediaType
+	FilePath   string
+	FileHash   string
+	Tags       []string
+	OutputDir  string
+}
+
+// ProcessorResult is what a processor reports back onto the evidence record
+type ProcessorResult struct {
+	Summary    string            `json:"summary"`
+	Labels     []string          `json:"labels,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// DerivedFile is a file a processor produced from evidence, e.g. a transcode
+type DerivedFile struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// ProcessingResult is a completed job's result, kept on the evidence record
+type ProcessingResult struct {
+	JobID       string            `json:"job_id"`
+	Processor   string            `json:"processor"`
+	CompletedAt time.Time         `json:"completed_at"`
+	Summary     string            `json:"summary"`
+	Labels      []string          `json:"labels,omitempty"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Outputs     []DerivedFile     `json:"outputs,omitempty"`
+}
+
+// JobStatus is the state of a processing job
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "QUEUED"
+	JobRunning   JobStatus = "RUNNING"
+	JobSucceeded JobStatus = "SUCCEEDED"
+	JobFailed    JobStatus = "FAILED"
+)
+
+// ProcessingJob tracks one processor run against one evidence item
+type ProcessingJob struct {
+	ID          string            `json:"id"`
+	EvidenceID  string            `json:"evidence_id"`
+	Processor   string            `json:"processor"`
+	RequestedBy string            `json:"requested_by"`
+	Status      JobStatus         `json:"status"`
+	QueuedAt    time.Time         `json:"queued_at"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	Error       string            `json:"error,omitempty"`
+	Result      *ProcessingResult `json:"result,omitempty"`
+}
+
+// RegisterProcessor makes a processor available to SubmitProcessingJob
+func (bwc *BWCSystem) RegisterProcessor(p Processor) error {
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	name := p.Name()
+	if name == "" {
+		return errors.New("processor name is required")
+	}
+	if _, exists := bwc.processors[name]; exists {
+		return fmt.Errorf("processor %s is already registered", name)
+	}
+	bwc.processors[name] = p
+	return nil
+}
+
+// SubmitProcessingJob queues processor to run against evidence in the
+// background. The job's progress and result are read with ProcessingJobs.
+func (bwc *BWCSystem) SubmitProcessingJob(evidenceID, processor, userID string) (*ProcessingJob, error) {
+	if err := bwc.authorize(userID, PermEditMetadata, "Processing job", evidenceID); err != nil {
+		return nil, err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Processing"); err != nil {
+		return nil, err
+	}
+	return bwc.submitJobLocked(evidence, processor, userID)
+}
+
+// submitJobLocked queues a job and starts it; the caller must hold bwc.mu
+func (bwc *BWCSystem) submitJobLocked(evidence *Evidence, processor, userID string) (*ProcessingJob, error) {
+	p, exists := bwc.processors[processor]
+	if !exists {
+		return nil, fmt.Errorf("processor %s is not registered", processor)
+	}
+	if f, ok := p.(MediaFilter); ok && !f.HandlesMediaType(mediaTypeOf(evidence)) {
+		return nil, fmt.Errorf("processor %s does not handle %s evidence", processor, mediaTypeOf(evidence))
+	}
+	if evidence.Encryption != nil {
+		return nil, fmt.Errorf("processor %s cannot run: %w", processor, errEncryptedAtRest)
+	}
+	// The job counts as in flight until it finishes so maintenance drains it
+	if err := bwc.beginOperation(opProcessing); err != nil {
+		return nil, err
+	}
+
+	if bwc.jobSlots == nil {
+		slots := bwc.config.VideoProcessing.MaxConcurrentJobs
+		if slots <= 0 {
+			slots = 1
+		}
+		bwc.jobSlots = make(chan struct{}, slots)
+	}
+
+	bwc.jobSeq++
+	job := &ProcessingJob{
+		ID:          fmt.Sprintf("JOB-%06d", bwc.jobSeq),
+		EvidenceID:  evidence.ID,
+		Processor:   processor,
+		RequestedBy: userID,
+		Status:      JobQueued,
+		QueuedAt:    time.Now(),
+	}
+	bwc.jobs[job.ID] = job
+
+	bwc.logAudit(userID, "PROCESSING_QUEUED", evidence.ID, fmt.Sprintf("%s queued %s", job.ID, processor), "")
+
+	go bwc.runJob(job, p)
+
+	j := *job
+	return &j, nil
+}
+
+// runJob waits for a free slot, runs the processor and records the outcome
+func (bwc *BWCSystem) runJob(job *ProcessingJob, p Processor) {
+	defer bwc.endOperation()
+
+	bwc.jobSlots <- struct{}{}
+	defer func() { <-bwc.jobSlots }()
+
+	bwc.mu.Lock()
+	evidence := bwc.evidenceDB.Get(job.EvidenceID)
+	if evidence == nil {
+		bwc.finishJobLocked(job, nil, nil, ErrEvidenceNotFound)
+		bwc.mu.Unlock()
+		return
+	}
+	job.Status = JobRunning
+	job.StartedAt = time.Now()
+	input := ProcessorInput{
+		JobID:      job.ID,
+		EvidenceID: evidence.ID,
+		CaseNumber: evidence.CaseNumber,
+		MediaType:  mediaTypeOf(evidence),
+		FilePath:   evidence.FilePath,
+		FileHash:   evidence.FileHash,
+		Tags:       append([]string(nil), evidence.Tags...),
+		OutputDir:  filepath.Join(bwc.derivedDir(evidence.ID), job.ID),
+	}
+	bwc.mu.Unlock()
+
+	result, outputs, err := runProcessor(p, input)
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	bwc.finishJobLocked(job, result, outputs, err)
+}
+
+// runProcessor runs p and checks that it left the evidence file unchanged
+func runProcessor(p Processor, input ProcessorInput) (*ProcessorResult, []DerivedFile, error) {
+	if err := os.MkdirAll(input.OutputDir, 0700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	result, err := callProcessor(p, input)
+	if err == nil && result == nil {
+		err = errors.New("processor returned no result")
+	}
+
+	// A processor must never alter the recording it was given
+	if hash, hashErr := calculateFileHash(input.FilePath); hashErr != nil || hash != input.FileHash {
+		err = errors.New("evidence file changed during processing")
+	}
+
+	var outputs []DerivedFile
+	if err == nil {
+		outputs, err = collectDerivedFiles(input.OutputDir)
+	}
+	if err != nil || len(outputs) == 0 {
+		os.RemoveAll(input.OutputDir)
+		// Fails, harmlessly, while other jobs' outputs are in it
+		os.Remove(filepath.Dir(input.OutputDir))
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, outputs, nil
+}
+
+// callProcessor runs p, turning a panic into an error
+func callProcessor(p Processor, input ProcessorInput) (result *ProcessorResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("processor panicked: %v", r)
+		}
+	}()
+	return p.Process(context.Background(), input)
+}
+
+// collectDerivedFiles hashes the files a processor wrote to dir
+func collectDerivedFiles(dir string) ([]DerivedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]DerivedFile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		hash, err := calculateFileHash(path)
+		if err != nil {
+			return nil, err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, DerivedFile{Name: entry.Name(), Path: path, SHA256: hash, Size: info.Size()})
+	}
+	return outputs, nil
+}
+
+// finishJobLocked records a job's outcome on the job and the evidence; the
+// caller must hold bwc.mu
+func (bwc *BWCSystem) finishJobLocked(job *ProcessingJob, result *ProcessorResult, outputs []DerivedFile, err error) {
+	job.FinishedAt = time.Now()
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		bwc.logAudit(job.RequestedBy, "PROCESSING_FAILED", job.EvidenceID,
+			fmt.Sprintf("%s (%s) failed: %v", job.ID, job.Processor, err), "")
+		return
+	}
+
+	processed := ProcessingResult{
+		JobID:       job.ID,
+		Processor:   job.Processor,
+		CompletedAt: job.FinishedAt,
+		Summary:     result.Summary,
+		Labels:      result.Labels,
+		Attributes:  result.Attributes,
+		Outputs:     outputs,
+	}
+	if evidence := bwc.evidenceDB.Get(job.EvidenceID); evidence != nil {
+		before := copyEvidence(evidence)
+		evidence.Processing = append(evidence.Processing, processed)
+		markModified(evidence, job.FinishedAt)
+		if err := bwc.saveLocked(evidence); err != nil {
+			*evidence = before
+			bwc.finishJobLocked(job, nil, nil, fmt.Errorf("failed to save the result: %w", err))
+			return
+		}
+	}
+	job.Status = JobSucceeded
+	job.Result = &processed
+
+	details := fmt.Sprintf("%s (%s) completed", job.ID, job.Processor)
+	if result.Summary != "" {
+		details += ": " + result.Summary
+	}
+	if len(outputs) > 0 {
+		details += fmt.Sprintf(" (%d derived files)", len(outputs))
+	}
+	bwc.logAudit(job.RequestedBy, "PROCESSING_COMPLETED", job.EvidenceID, details, "")
+}
+
+// ProcessingJobs lists jobs for evidenceID, or every job when it is empty
+func (bwc *BWCSystem) ProcessingJobs(evidenceID string) []ProcessingJob {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	results := make([]ProcessingJob, 0)
+	for _, job := range bwc.jobs {
+		if evidenceID == "" || job.EvidenceID == evidenceID {
+			results = append(results, *job)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// queueIngestJobsLocked queues the processors configured to run on every new
+// item of its media type; the caller must hold bwc.mu
+func (bwc *BWCSystem) queueIngestJobsLocked(evidence *Evidence) {
+	for _, pc := range bwc.config.VideoProcessing.Processors {
+		if !pc.OnIngest {
+			continue
+		}
+		if f, ok := bwc.processors[pc.Name].(MediaFilter); ok && !f.HandlesMediaType(mediaTypeOf(evidence)) {
+			continue
+		}
+		if _, err := bwc.submitJobLocked(evidence, pc.Name, "SYSTEM"); err != nil {
+			bwc.logAudit("SYSTEM", "PROCESSING_FAILED", evidence.ID,
+				fmt.Sprintf("%s could not be queued: %v", pc.Name, err), "")
+		}
+	}
+}
+
+// execOutputLimit caps how much an external processor may print
+const execOutputLimit = 1 << 20
+
+// execProcessor runs an external program as a processor
+type execProcessor struct {
+	name       string
+	command    []string
+	timeout    time.Duration
+	mediaTypes []MediaType
+}
+
+func newExecProcessor(cfg ProcessorConfig) *execProcessor {
+	return &execProcessor{
+		name:       cfg.Name,
+		command:    cfg.Command,
+		timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+		mediaTypes: cfg.MediaTypes,
+	}
+}
+
+func (p *execProcessor) Name() string {
+	return p.name
+}
+
+// HandlesMediaType reports whether t is one of the configured media types,
+// or true when none are configured
+func (p *execProcessor) HandlesMediaType(t MediaType) bool {
+	if len(p.mediaTypes) == 0 {
+		return true
+	}
+	for _, handled := range p.mediaTypes {
+		if handled == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Process runs the command with the evidence path as its last argument and
+// parses the JSON result it prints
+func (p *execProcessor) Process(ctx context.Context, input ProcessorInput) (*ProcessorResult, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	args := append(append([]string(nil), p.command[1:]...), input.FilePath)
+	cmd := exec.CommandContext(ctx, p.command[0], args...)
+	cmd.Env = append(os.Environ(),
+		"BWC_JOB_ID="+input.JobID,
+		"BWC_EVIDENCE_ID="+input.EvidenceID,
+		"BWC_CASE_NUMBER="+input.CaseNumber,
+		"BWC_MEDIA_TYPE="+string(input.MediaType),
+		"BWC_FILE_HASH="+input.FileHash,
+		"BWC_TAGS="+strings.Join(input.Tags, ","),
+		"BWC_OUTPUT_DIR="+input.OutputDir,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: execOutputLimit}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: 1024}
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("timed out after %s", p.timeout)
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return nil, fmt.Errorf("%v: %s", err, detail)
+		}
+		return nil, err
+	}
+
+	var result ProcessorResult
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		return nil, fmt.Errorf("invalid result on stdout: %w", err)
+	}
+	return &result, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); room > 0 {
+		if len(p) > room {
+			b.buf.Write(p[:room])
+		} else {
+			b.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,251 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// funcProcessor is a Processor backed by a function
+type funcProcessor struct {
+	name string
+	fn   func(ProcessorInput) (*ProcessorResult, error)
+}
+
+func (p funcProcessor) Name() string { return p.name }
+
+func (p funcProcessor) Process(_ context.Context, input ProcessorInput) (*ProcessorResult, error) {
+	return p.fn(input)
+}
+
+// waitForJobs waits for every queued processing job to finish
+func waitForJobs(t *testing.T, system *BWCSystem) {
+	t.Helper()
+	if !system.WaitForDrain(10 * time.Second) {
+		t.Fatal("Processing jobs did not finish")
+	}
+}
+
+// writeProcessorScript writes an executable shell script for exec processor tests
+func writeProcessorScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0700); err != nil {
+		t.Fatalf("Failed to write script: %v", err)
+	}
+	return path
+}
+
+func TestProcessingJob(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.RegisterProcessor(funcProcessor{name: "classifier", fn: func(in ProcessorInput) (*ProcessorResult, error) {
+		os.WriteFile(filepath.Join(in.OutputDir, "frames.json"), []byte(`{"faces":2}`), 0600)
+		return &ProcessorResult{Summary: "2 faces", Labels: []string{"faces"}, Attributes: map[string]string{"model": "v3"}}, nil
+	}})
+	if err := system.RegisterProcessor(funcProcessor{name: "classifier"}); err == nil {
+		t.Error("Expected a duplicate processor name to be rejected")
+	}
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PRC-001", "OFF-931", "Officer Test", "Test Location", nil)
+	if _, err := system.SubmitProcessingJob(evidence.ID, "transcoder", "TECH-1"); err == nil {
+		t.Error("Expected an unknown processor to be rejected")
+	}
+	job, err := system.SubmitProcessingJob(evidence.ID, "classifier", "TECH-1")
+	if err != nil {
+		t.Fatalf("SubmitProcessingJob failed: %v", err)
+	}
+	waitForJobs(t, system)
+
+	jobs := system.ProcessingJobs(evidence.ID)
+	if len(jobs) != 1 || jobs[0].ID != job.ID || jobs[0].Status != JobSucceeded || jobs[0].StartedAt.IsZero() {
+		t.Fatalf("Expected the job to succeed, got %+v", jobs)
+	}
+
+	results := system.evidenceDB.Get(evidence.ID).Processing
+	if len(results) != 1 || results[0].Summary != "2 faces" || results[0].Attributes["model"] != "v3" || len(results[0].Outputs) != 1 {
+		t.Fatalf("Expected the result on the record, got %+v", results)
+	}
+	out := results[0].Outputs[0]
+	if hash, _ := calculateFileHash(out.Path); hash != out.SHA256 || out.Name != "frames.json" ||
+		!strings.HasPrefix(out.Path, filepath.Join(system.storagePath, "derived", evidence.ID)) {
+		t.Errorf("Unexpected derived file %+v", out)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "TECH-1")
+	if len(logs) != 2 || logs[0].Action != "PROCESSING_QUEUED" || logs[1].Action != "PROCESSING_COMPLETED" ||
+		!strings.Contains(logs[1].Details, "2 faces") {
+		t.Errorf("Expected queued and completed audit entries, got %v", logs)
+	}
+}
+
+func TestProcessingJobFailures(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.RegisterProcessor(funcProcessor{name: "broken", fn: func(ProcessorInput) (*ProcessorResult, error) {
+		return nil, errors.New("unsupported codec")
+	}})
+	system.RegisterProcessor(funcProcessor{name: "crashing", fn: func(ProcessorInput) (*ProcessorResult, error) {
+		panic("index out of range")
+	}})
+	system.RegisterProcessor(funcProcessor{name: "careless", fn: func(in ProcessorInput) (*ProcessorResult, error) {
+		os.WriteFile(in.FilePath, []byte("transcoded in place"), 0600)
+		return &ProcessorResult{Summary: "done"}, nil
+	}})
+
+	tests := []struct {
+		processor string
+		want      string
+	}{
+		{"broken", "unsupported codec"},
+		{"crashing", "panicked"},
+		{"careless", "evidence file changed during processing"},
+	}
+	for i, tt := range tests {
+		evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PRC-002", fmt.Sprintf("OFF-%d", 932+i), "Officer Test", "Test Location", nil)
+		system.SubmitProcessingJob(evidence.ID, tt.processor, "TECH-1")
+		waitForJobs(t, system)
+
+		jobs := system.ProcessingJobs(evidence.ID)
+		if len(jobs) != 1 || jobs[0].Status != JobFailed || !strings.Contains(jobs[0].Error, tt.want) {
+			t.Errorf("%s: expected failure %q, got %+v", tt.processor, tt.want, jobs)
+		}
+		if len(system.evidenceDB.Get(evidence.ID).Processing) != 0 {
+			t.Errorf("%s: expected no result on the record", tt.processor)
+		}
+		logs := system.GetAuditLogs(evidence.ID, "TECH-1")
+		if last := logs[len(logs)-1]; last.Action != "PROCESSING_FAILED" {
+			t.Errorf("%s: expected PROCESSING_FAILED audit entry, got %+v", tt.processor, last)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(system.storagePath, "derived")); err == nil {
+		if entries, _ := os.ReadDir(filepath.Join(system.storagePath, "derived")); len(entries) != 0 {
+			t.Errorf("Expected failed jobs to leave no output, got %d entries", len(entries))
+		}
+	}
+}
+
+func TestProcessingJobRefusals(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.RegisterProcessor(funcProcessor{name: "noop", fn: func(ProcessorInput) (*ProcessorResult, error) {
+		return &ProcessorResult{}, nil
+	}})
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PRC-003", "OFF-936", "Officer Test", "Test Location", nil)
+	system.EnterMaintenance("ADMIN-1", "Disk swap")
+	if _, err := system.SubmitProcessingJob(evidence.ID, "noop", "TECH-1"); err == nil {
+		t.Error("Expected jobs to be refused in maintenance mode")
+	}
+	system.ExitMaintenance("ADMIN-1")
+
+	if _, err := system.SealEvidence(evidence.ID, "Court order 24-118"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if _, err := system.SubmitProcessingJob(evidence.ID, "noop", "TECH-1"); err == nil {
+		t.Error("Expected sealed evidence to be refused")
+	}
+	if jobs := system.ProcessingJobs(""); len(jobs) != 0 {
+		t.Errorf("Expected no jobs, got %+v", jobs)
+	}
+}
+
+func TestExecProcessor(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	probe := writeProcessorScript(t, tmpDir, "probe.sh", `echo thumbnail > "$BWC_OUTPUT_DIR/thumb.txt"
+printf '{"summary":"probed %s","attributes":{"case":"%s"}}' "$(basename "$1")" "$BWC_CASE_NUMBER"
+`)
+	failing := writeProcessorScript(t, tmpDir, "fail.sh", "echo 'codec not supported' >&2\nexit 3\n")
+	slow := writeProcessorScript(t, tmpDir, "slow.sh", "exec sleep 5\n")
+	garbled := writeProcessorScript(t, tmpDir, "garbled.sh", "echo not json\n")
+
+	system.config.VideoProcessing.Processors = []ProcessorConfig{
+		{Name: "probe", Command: []string{"/bin/sh", probe}, OnIngest: true},
+		{Name: "fail", Command: []string{failing}},
+		{Name: "slow", Command: []string{slow}, TimeoutSeconds: 1},
+		{Name: "garbled", Command: []string{garbled}},
+	}
+	for _, pc := range system.config.VideoProcessing.Processors {
+		system.RegisterProcessor(newExecProcessor(pc))
+	}
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PRC-004", "OFF-937", "Officer Test", "Test Location", nil)
+	for _, name := range []string{"fail", "slow", "garbled"} {
+		system.SubmitProcessingJob(evidence.ID, name, "TECH-1")
+	}
+	waitForJobs(t, system)
+
+	jobs := system.ProcessingJobs(evidence.ID)
+	if len(jobs) != 4 {
+		t.Fatalf("Expected 4 jobs, got %+v", jobs)
+	}
+	if jobs[0].Processor != "probe" || jobs[0].RequestedBy != "SYSTEM" || jobs[0].Status != JobSucceeded {
+		t.Fatalf("Expected the on-ingest probe to succeed, got %+v", jobs[0])
+	}
+	result := jobs[0].Result
+	if result.Summary != "probed "+filepath.Base(evidence.FilePath) || result.Attributes["case"] != "CASE-PRC-004" ||
+		len(result.Outputs) != 1 || result.Outputs[0].Name != "thumb.txt" {
+		t.Errorf("Unexpected probe result %+v", result)
+	}
+
+	wants := map[string]string{"fail": "codec not supported", "slow": "timed out", "garbled": "invalid result"}
+	for _, job := range jobs[1:] {
+		if job.Status != JobFailed || !strings.Contains(job.Error, wants[job.Processor]) {
+			t.Errorf("%s: expected failure %q, got %q", job.Processor, wants[job.Processor], job.Error)
+		}
+	}
+}
+
+func TestProcessorConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.VideoProcessing.Processors = []ProcessorConfig{
+		{Name: "probe", Command: []string{"/usr/bin/probe"}},
+		{Name: "probe", Command: []string{"/usr/bin/probe"}},
+		{Command: []string{""}, TimeoutSeconds: -1},
+		{Name: "ocr", Command: []string{"/usr/bin/ocr"}, MediaTypes: []MediaType{MediaDocument, "IMAGE"}},
+	}
+	err := cfg.Validate()
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 5 {
+		t.Fatalf("Expected 5 processor problems, got %v", err)
+	}
+}
+
+func TestProcessorMediaTypes(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	script := writeProcessorScript(t, tmpDir, "kind.sh", `printf '{"summary":"%s"}' "$BWC_MEDIA_TYPE"`+"\n")
+	system.config.VideoProcessing.Processors = []ProcessorConfig{
+		{Name: "video-probe", Command: []string{"/bin/sh", script}, OnIngest: true, MediaTypes: []MediaType{MediaVideo}},
+		{Name: "audio-probe", Command: []string{"/bin/sh", script}, OnIngest: true, MediaTypes: []MediaType{MediaAudio}},
+		{Name: "any", Command: []string{"/bin/sh", script}, OnIngest: true},
+	}
+	for _, pc := range system.config.VideoProcessing.Processors {
+		system.RegisterProcessor(newExecProcessor(pc))
+	}
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-PRC-005", "OFF-938", "Officer Test", "Test Location", nil)
+	waitForJobs(t, system)
+
+	jobs := system.ProcessingJobs(evidence.ID)
+	if len(jobs) != 2 || jobs[0].Processor != "video-probe" || jobs[1].Processor != "any" {
+		t.Fatalf("Expected only the video and untyped processors to run, got %+v", jobs)
+	}
+	if jobs[0].Result == nil || jobs[0].Result.Summary != "VIDEO" {
+		t.Errorf("Expected the media type passed to the processor, got %+v", jobs[0].Result)
+	}
+	if _, err := system.SubmitProcessingJob(evidence.ID, "audio-probe", "TECH-1"); err == nil ||
+		!strings.Contains(err.Error(), "does not handle VIDEO evidence") {
+		t.Errorf("Expected an audio processor to refuse video, got %v", err)
+	}
+}
@@ -0,0 +1,387 @@
+package bwc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// researchDatasetFormat identifies the layout of a pseudonymized export
+const researchDatasetFormat = "bwc-research-dataset/v1"
+
+// pseudonymKeySize is the length of the pseudonymization key in bytes
+const pseudonymKeySize = 32
+
+// maxLocationDecimals is the finest coordinate precision a research export
+// keeps: three decimal places is about 110 m
+const maxLocationDecimals = 3
+
+// Pseudonym kinds prefix each pseudonym with what it stands for
+const (
+	pseudonymOfficer  = "OFF"
+	pseudonymCase     = "CASE"
+	pseudonymEvidence = "EV"
+)
+
+// generatePseudonymKey creates a random key, used when no key is configured.
+// Its pseudonyms cannot be resolved after a restart.
+func generatePseudonymKey() ([]byte, error) {
+	key := make([]byte, pseudonymKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate pseudonym key: %w", err)
+	}
+	return key, nil
+}
+
+// loadPseudonymKey reads a hex-encoded pseudonymization key from path
+func loadPseudonymKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pseudonym key: %w", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != pseudonymKeySize {
+		return nil, fmt.Errorf("pseudonym key %s must contain a hex-encoded %d-byte key", path, pseudonymKeySize)
+	}
+	return key, nil
+}
+
+// pseudonymizer maps identifiers to the pseudonyms of one study. Each study
+// has its own key, derived from the system key, so datasets released to
+// different studies cannot be linked to each other.
+type pseudonymizer struct {
+	key []byte
+}
+
+func newPseudonymizer(systemKey []byte, study string) *pseudonymizer {
+	mac := hmac.New(sha256.New, systemKey)
+	mac.Write([]byte("BWC-PSEUDONYM-v1\n" + study))
+	return &pseudonymizer{key: mac.Sum(nil)}
+}
+
+// pseudonym returns the pseudonym of value, or "" for an empty value
+func (p *pseudonymizer) pseudonym(kind, value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(kind + "\n" + value))
+	return kind + "-" + strings.ToUpper(hex.EncodeToString(mac.Sum(nil)[:6]))
+}
+
+// ResearchExportOptions controls a pseudonymized export. Study names the
+// research project; pseudonyms are stable within a study and differ between
+// studies. LocationDecimals is how many decimal places of latitude and
+// longitude are kept, from 0 to 3.
+type ResearchExportOptions struct {
+	Study            string
+	Selection        EvidenceSelection
+	LocationDecimals int
+	Purpose          string
+}
+
+// ResearchDataset is a pseudonymized export. Officer IDs, case numbers and
+// evidence IDs are replaced by pseudonyms. Names, file paths, notes and other
+// free text are left out and coordinates are rounded. File hashes, custody
+// entry hashes and the order and timing of custody events are kept.
+type ResearchDataset struct {
+	Format           string           `json:"format"`
+	Study            string           `json:"study"`
+	ExportedAt       time.Time        `json:"exported_at"`
+	LocationDecimals int              `json:"location_decimals"`
+	Records          []ResearchRecord `json:"records"`
+}
+
+// ResearchRecord is one evidence item in a research dataset
+type ResearchRecord struct {
+	ID           string            `json:"id"`
+	Case         string            `json:"case"`
+	Officer      string            `json:"officer"`
+	Timestamp    time.Time         `json:"timestamp"`
+	IncidentTime *time.Time        `json:"incident_time,omitempty"`
+	Duration     int               `json:"duration_seconds"`
+	MediaType    MediaType         `json:"media_type,omitempty"`
+	Severity     CaseSeverity      `json:"severity,omitempty"`
+	Status       EvidenceStatus    `json:"status"`
+	Tags         []string          `json:"tags"`
+	Area         *ResearchArea     `json:"area,omitempty"`
+	FileHash     string            `json:"file_hash"`
+	FileSize     int64             `json:"file_size"`
+	Custody      []ResearchCustody `json:"custody"`
+	Checks       []ResearchCheck   `json:"integrity_checks"`
+	Reviews      []ResearchReview  `json:"reviews,omitempty"`
+}
+
+// ResearchArea is where a recording was made, at reduced precision
+type ResearchArea struct {
+	City      string  `json:"city,omitempty"`
+	Region    string  `json:"region,omitempty"`
+	Country   string  `json:"country,omitempty"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// ResearchCustody is a custody entry with its parties pseudonymized
+type ResearchCustody struct {
+	Timestamp     time.Time     `json:"timestamp"`
+	Action        string        `json:"action"`
+	From          string        `json:"from"`
+	To            string        `json:"to"`
+	VerifiedHash  string        `json:"verified_hash"`
+	EntryHash     string        `json:"entry_hash,omitempty"`
+	SignatureType SignatureType `json:"signature_type,omitempty"`
+}
+
+// ResearchCheck is an integrity check with its checker pseudonymized
+type ResearchCheck struct {
+	Timestamp time.Time `json:"timestamp"`
+	CheckedBy string    `json:"checked_by"`
+	IsValid   bool      `json:"is_valid"`
+}
+
+// ResearchReview is a supervisor review without its summary or findings
+type ResearchReview struct {
+	Reason     ReviewReason  `json:"reason"`
+	State      ReviewState   `json:"state"`
+	Outcome    ReviewOutcome `json:"outcome,omitempty"`
+	Supervisor string        `json:"supervisor,omitempty"`
+	FlaggedAt  time.Time     `json:"flagged_at"`
+	ClosedAt   time.Time     `json:"closed_at,omitempty"`
+}
+
+// ResearchExportSummary reports a pseudonymized export. Skipped gives the
+// reason each selected item was left out.
+type ResearchExportSummary struct {
+	Study   string            `json:"study"`
+	Items   int               `json:"items"`
+	Skipped map[string]string `json:"skipped,omitempty"`
+	SHA256  string            `json:"sha256"`
+	Size    int64             `json:"size"`
+}
+
+// roundTo rounds v to decimals decimal places
+func roundTo(v float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(v*scale) / scale
+}
+
+// researchRecord builds the pseudonymized form of evidence
+func researchRecord(evidence *Evidence, p *pseudonymizer, decimals int) ResearchRecord {
+	record := ResearchRecord{
+		ID:           p.pseudonym(pseudonymEvidence, evidence.ID),
+		Case:         p.pseudonym(pseudonymCase, evidence.CaseNumber),
+		Officer:      p.pseudonym(pseudonymOfficer, evidence.OfficerID),
+		Timestamp:    evidence.Timestamp,
+		IncidentTime: evidence.IncidentTime,
+		Duration:     evidence.Duration,
+		MediaType:    evidence.MediaType,
+		Severity:     evidence.Severity,
+		Status:       evidence.Status,
+		Tags:         append([]string{}, evidence.Tags...),
+		FileHash:     evidence.FileHash,
+		FileSize:     evidence.FileSize,
+		Custody:      make([]ResearchCustody, 0, len(evidence.ChainOfCustody)),
+		Checks:       make([]ResearchCheck, 0, len(evidence.IntegrityChecks)),
+	}
+	if place := evidence.Place; place != nil {
+		record.Area = &ResearchArea{
+			City:      place.City,
+			Region:    place.Region,
+			Country:   place.Country,
+			Latitude:  roundTo(place.Latitude, decimals),
+			Longitude: roundTo(place.Longitude, decimals),
+		}
+	}
+	for _, entry := range evidence.ChainOfCustody {
+		custody := ResearchCustody{
+			Timestamp:    entry.Timestamp,
+			Action:       entry.Action,
+			From:         p.pseudonym(pseudonymOfficer, entry.FromOfficer),
+			To:           p.pseudonym(pseudonymOfficer, entry.ToOfficer),
+			VerifiedHash: entry.VerifiedHash,
+			EntryHash:    entry.EntryHash,
+		}
+		if entry.Signature != nil {
+			custody.SignatureType = entry.Signature.Type
+		}
+		record.Custody = append(record.Custody, custody)
+	}
+	for _, check := range evidence.IntegrityChecks {
+		record.Checks = append(record.Checks, ResearchCheck{
+			Timestamp: check.Timestamp,
+			CheckedBy: p.pseudonym(pseudonymOfficer, check.CheckedBy),
+			IsValid:   check.IsValid,
+		})
+	}
+	for _, review := range evidence.Reviews {
+		record.Reviews = append(record.Reviews, ResearchReview{
+			Reason:     review.Reason,
+			State:      review.State,
+			Outcome:    review.Outcome,
+			Supervisor: p.pseudonym(pseudonymOfficer, review.Supervisor),
+			FlaggedAt:  review.FlaggedAt,
+			ClosedAt:   review.ClosedAt,
+		})
+	}
+	return record
+}
+
+// ExportPseudonymized writes the selected evidence records to path as a
+// pseudonymized research dataset, encrypted with enc when it is set. Sealed
+// and deleted evidence is skipped. Recordings are not included. Each exported
+// item is registered as a copy.
+func (bwc *BWCSystem) ExportPseudonymized(opts ResearchExportOptions, path, userID string, enc *ExportEncryption) (*ResearchExportSummary, error) {
+	if err := bwc.authorize(userID, PermExport, "Research export", ""); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(opts.Study) == "" {
+		return nil, errors.New("study name is required")
+	}
+	if opts.LocationDecimals < 0 || opts.LocationDecimals > maxLocationDecimals {
+		return nil, fmt.Errorf("location decimals must be between 0 and %d", maxLocationDecimals)
+	}
+	if enc != nil {
+		if err := enc.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	ids, err := bwc.resolveSelection(opts.Selection, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if err := bwc.checkExportPathLocked(path, "", userID); err != nil {
+		return nil, err
+	}
+
+	p := newPseudonymizer(bwc.pseudonymKey, opts.Study)
+	dataset := ResearchDataset{
+		Format:           researchDatasetFormat,
+		Study:            opts.Study,
+		ExportedAt:       time.Now().UTC(),
+		LocationDecimals: opts.LocationDecimals,
+		Records:          make([]ResearchRecord, 0, len(ids)),
+	}
+	summary := &ResearchExportSummary{Study: opts.Study, Skipped: make(map[string]string)}
+	exported := make([]string, 0, len(ids))
+	for _, id := range ids {
+		evidence := bwc.evidenceDB.Get(id)
+		switch {
+		case evidence == nil:
+			summary.Skipped[id] = "evidence not found"
+		case evidence.Status == StatusDeleted:
+			summary.Skipped[id] = "deleted"
+		case evidence.Seal != nil:
+			summary.Skipped[id] = bwc.sealedSkipLocked(evidence, userID, "Pseudonymized export", false)
+		case bwc.rejectIfRestrictedLocked(evidence, userID, "Pseudonymized export") != nil:
+			summary.Skipped[id] = "restricted"
+		default:
+			dataset.Records = append(dataset.Records, researchRecord(evidence, p, opts.LocationDecimals))
+			exported = append(exported, id)
+		}
+	}
+	if len(exported) == 0 {
+		return nil, errors.New("no selected evidence can be exported")
+	}
+	// Order by pseudonym so the order does not reveal the evidence IDs
+	sort.Slice(dataset.Records, func(i, j int) bool { return dataset.Records[i].ID < dataset.Records[j].ID })
+
+	data, err := json.MarshalIndent(dataset, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal dataset: %w", err)
+	}
+	hash, size, err := writeExportFile(path, enc, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	summary.Items, summary.SHA256, summary.Size = len(exported), hash, size
+
+	for _, id := range exported {
+		record := bwc.registerCopyLocked(id, CopyExport, userID, path, opts.Purpose, hash, size)
+		record.Encryption = enc.Describe()
+		bwc.logAudit(userID, "EXPORT_PSEUDONYMIZED", id,
+			fmt.Sprintf("Study %s: %s", opts.Study, copyDetails(record)), "")
+	}
+	return summary, nil
+}
+
+// ResolvePseudonym re-identifies a pseudonym from a study's dataset. Only
+// identifiers still held by the system can be resolved. Every attempt is
+// audited with its reason.
+func (bwc *BWCSystem) ResolvePseudonym(study, pseudonym, userID, reason string) (string, error) {
+	if err := bwc.authorize(userID, PermAdminister, "Pseudonym resolution", ""); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return "", errors.New("a reason is required to resolve a pseudonym")
+	}
+	kind, _, ok := strings.Cut(pseudonym, "-")
+	if !ok || (kind != pseudonymOfficer && kind != pseudonymCase && kind != pseudonymEvidence) {
+		return "", fmt.Errorf("%q is not a pseudonym", pseudonym)
+	}
+
+	bwc.mu.RLock()
+	p := newPseudonymizer(bwc.pseudonymKey, study)
+	var found string
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		for _, value := range pseudonymCandidates(evidence, kind) {
+			if p.pseudonym(kind, value) == pseudonym {
+				found = value
+				break
+			}
+		}
+		if found != "" {
+			break
+		}
+	}
+	bwc.mu.RUnlock()
+
+	if found == "" {
+		bwc.logAudit(userID, "RESOLVE_PSEUDONYM", "", fmt.Sprintf("Study %s: %s not resolved - %s", study, pseudonym, reason), "")
+		return "", errors.New("pseudonym not found")
+	}
+	bwc.logAudit(userID, "RESOLVE_PSEUDONYM", "", fmt.Sprintf("Study %s: %s resolved - %s", study, pseudonym, reason), "")
+	return found, nil
+}
+
+// pseudonymCandidates lists the identifiers of a kind that evidence holds
+func pseudonymCandidates(evidence *Evidence, kind string) []string {
+	switch kind {
+	case pseudonymEvidence:
+		return []string{evidence.ID}
+	case pseudonymCase:
+		return []string{evidence.CaseNumber}
+	}
+	candidates := []string{evidence.OfficerID}
+	for _, entry := range evidence.ChainOfCustody {
+		candidates = append(candidates, entry.FromOfficer, entry.ToOfficer)
+	}
+	for _, check := range evidence.IntegrityChecks {
+		candidates = append(candidates, check.CheckedBy)
+	}
+	for _, review := range evidence.Reviews {
+		candidates = append(candidates, review.Supervisor)
+	}
+	return candidates
+}
@@ -0,0 +1,154 @@
+package bwc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPseudonymsAreStablePerStudy(t *testing.T) {
+	key := make([]byte, pseudonymKeySize)
+	a := newPseudonymizer(key, "study-a")
+
+	first := a.pseudonym(pseudonymOfficer, "OFF-1115")
+	if !strings.HasPrefix(first, "OFF-") || len(first) != len("OFF-")+12 {
+		t.Fatalf("unexpected pseudonym %q", first)
+	}
+	if again := newPseudonymizer(key, "study-a").pseudonym(pseudonymOfficer, "OFF-1115"); again != first {
+		t.Error("expected the same pseudonym within a study")
+	}
+	if other := newPseudonymizer(key, "study-b").pseudonym(pseudonymOfficer, "OFF-1115"); other == first {
+		t.Error("expected studies to have different pseudonyms")
+	}
+	if a.pseudonym(pseudonymOfficer, "OFF-1116") == first {
+		t.Error("expected officers to have different pseudonyms")
+	}
+	if a.pseudonym(pseudonymOfficer, "") != "" {
+		t.Error("expected an empty identifier to stay empty")
+	}
+}
+
+func TestExportPseudonymized(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	file := createTestFile(t, tmpDir)
+
+	ev, err := system.IngestEvidence(file, "CASE-PSN-1", "OFF-1115", "Jane Officer", "1200 Main St", []string{"traffic"})
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	system.SetPlace(ev.ID, "RECORDS-1", Place{Street: "1200 Main St", City: "Springfield", Latitude: 39.801734, Longitude: -89.643604, Formatted: "1200 Main St, Springfield"})
+	if err := system.TransferCustody(ev.ID, "OFF-1115", "LAB-7", "Analysis"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	notes := "Witness Bob Smith interviewed"
+	if _, err := system.UpdateMetadata(ev.ID, "OFF-1115", ev.Revision, MetadataUpdate{Notes: &notes}); err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+
+	sealed, err := system.IngestEvidence(file, "CASE-PSN-1", "OFF-1116", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if _, err := system.SealEvidence(sealed.ID, "Court order 24-201"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+
+	out := filepath.Join(t.TempDir(), "dataset.json")
+	opts := ResearchExportOptions{Study: "use-of-force-2024", Selection: EvidenceSelection{CaseNumber: "CASE-PSN-1"}, LocationDecimals: 2, Purpose: "University study"}
+	summary, err := system.ExportPseudonymized(opts, out, "ANALYST-1", nil)
+	if err != nil {
+		t.Fatalf("ExportPseudonymized failed: %v", err)
+	}
+	if summary.Items != 1 || !strings.Contains(summary.Skipped[sealed.ID], "sealed") {
+		t.Errorf("expected one item exported and the sealed one skipped, got %+v", summary)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("failed to read dataset: %v", err)
+	}
+	for _, secret := range []string{"OFF-1115", "LAB-7", "CASE-PSN-1", ev.ID, "Jane Officer", "Main St", "Bob Smith", tmpDir, "39.8017"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("dataset leaks %q", secret)
+		}
+	}
+
+	var dataset ResearchDataset
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		t.Fatalf("failed to parse dataset: %v", err)
+	}
+	record := dataset.Records[0]
+	if record.FileHash != ev.FileHash || len(record.Custody) != len(ev.ChainOfCustody) {
+		t.Error("expected the file hash and custody shape to be preserved")
+	}
+	if record.Custody[len(record.Custody)-1].EntryHash != ev.ChainOfCustody[len(ev.ChainOfCustody)-1].EntryHash {
+		t.Error("expected custody entry hashes to be preserved")
+	}
+	if record.Officer != record.Custody[0].To || record.Officer != record.Custody[1].From {
+		t.Error("expected the officer's pseudonym to be consistent across the record")
+	}
+	if record.Area == nil || record.Area.Latitude != 39.8 || record.Area.Longitude != -89.64 || record.Area.City != "Springfield" {
+		t.Errorf("expected a coarse area, got %+v", record.Area)
+	}
+
+	// Re-identification needs a reason and is audited
+	if _, err := system.ResolvePseudonym(opts.Study, record.Officer, "ANALYST-1", ""); err == nil {
+		t.Error("expected a reason to be required")
+	}
+	officer, err := system.ResolvePseudonym(opts.Study, record.Officer, "IA-1", "Follow-up on policy violation")
+	if err != nil || officer != "OFF-1115" {
+		t.Errorf("expected OFF-1115, got %q, %v", officer, err)
+	}
+	if _, err := system.ResolvePseudonym("another-study", record.Officer, "IA-1", "Check"); err == nil {
+		t.Error("expected a pseudonym not to resolve in another study")
+	}
+	resolutions := 0
+	for _, log := range system.GetAuditLogs("", "IA-1") {
+		if log.Action == "RESOLVE_PSEUDONYM" {
+			resolutions++
+		}
+	}
+	if resolutions != 2 {
+		t.Errorf("expected 2 RESOLVE_PSEUDONYM audit entries, got %d", resolutions)
+	}
+
+	if copies := system.CopiesOf(ev.ID); len(copies) != 1 || copies[0].Purpose != "University study" {
+		t.Errorf("expected the export to be registered as a copy, got %+v", copies)
+	}
+
+	for _, bad := range []ResearchExportOptions{
+		{Selection: opts.Selection},
+		{Study: "s", Selection: opts.Selection, LocationDecimals: 4},
+		{Study: "s", Selection: EvidenceSelection{EvidenceIDs: []string{sealed.ID}}},
+	} {
+		if _, err := system.ExportPseudonymized(bad, filepath.Join(t.TempDir(), "bad.json"), "ANALYST-1", nil); err == nil {
+			t.Errorf("expected %+v to be refused", bad)
+		}
+	}
+}
+
+func TestPseudonymKeyFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "pseudonym.key")
+	os.WriteFile(keyFile, []byte(hex.EncodeToString(make([]byte, pseudonymKeySize))+"\n"), 0600)
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(dir, "storage")
+	cfg.Security.PseudonymKeyFile = keyFile
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	if string(system.pseudonymKey) != string(make([]byte, pseudonymKeySize)) {
+		t.Error("expected the configured key to be loaded")
+	}
+
+	os.WriteFile(keyFile, []byte("abcd"), 0600)
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil {
+		t.Error("expected a short key to be refused")
+	}
+}
@@ -0,0 +1,215 @@
+package bwc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// decodeTestQR reads a symbol back to its payload, checking the format
+// information copies and the Reed-Solomon syndromes of every block
+func decodeTestQR(t *testing.T, q *qrCode) []byte {
+	get := func(x, y int) int {
+		if q.Modules[y][x] {
+			return 1
+		}
+		return 0
+	}
+
+	format, second := 0, 0
+	for i := 0; i <= 5; i++ {
+		format |= get(8, i) << i
+	}
+	format |= get(8, 7)<<6 | get(8, 8)<<7 | get(7, 8)<<8
+	for i := 9; i < 15; i++ {
+		format |= get(14-i, 8) << i
+	}
+	for i := 0; i < 8; i++ {
+		second |= get(q.Size-1-i, 8) << i
+	}
+	for i := 8; i < 15; i++ {
+		second |= get(8, q.Size-15+i) << i
+	}
+	if format != second {
+		t.Fatalf("Format information copies differ: %015b vs %015b", format, second)
+	}
+	format ^= 0x5412
+	if format>>13 != 0 {
+		t.Fatalf("Expected error correction level M, got %02b", format>>13)
+	}
+
+	mask := (format >> 10) & 7
+	q.applyMask(mask)
+	defer q.applyMask(mask)
+
+	var codewords []byte
+	var current byte
+	n := 0
+	for right := q.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.Size; vert++ {
+			for j := 0; j < 2; j++ {
+				x, y := right-j, vert
+				if (right+1)&2 == 0 {
+					y = q.Size - 1 - vert
+				}
+				if q.function[y][x] {
+					continue
+				}
+				current = current<<1 | byte(get(x, y))
+				if n++; n == 8 {
+					codewords = append(codewords, current)
+					current, n = 0, 0
+				}
+			}
+		}
+	}
+
+	v := qrVersionsM[q.Version]
+	numBlocks := v.g1Blocks + v.g2Blocks
+	blocks := make([][]byte, numBlocks)
+	idx := 0
+	for i := 0; i < qrMax(v.g1DataWords, v.g2DataWords); i++ {
+		for b := range blocks {
+			length := v.g1DataWords
+			if b >= v.g1Blocks {
+				length = v.g2DataWords
+			}
+			if i < length {
+				blocks[b] = append(blocks[b], codewords[idx])
+				idx++
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[idx])
+			idx++
+		}
+	}
+
+	var data []byte
+	for b, block := range blocks {
+		alpha := byte(1)
+		for i := 0; i < v.ecPerBlock; i++ {
+			var syndrome byte
+			for _, c := range block {
+				syndrome = gfMultiply(syndrome, alpha) ^ c
+			}
+			if syndrome != 0 {
+				t.Fatalf("Block %d has non-zero syndrome %d", b, i)
+			}
+			alpha = gfMultiply(alpha, 2)
+		}
+		data = append(data, block[:len(block)-v.ecPerBlock]...)
+	}
+
+	pos := 0
+	read := func(bits int) int {
+		value := 0
+		for i := 0; i < bits; i++ {
+			value = value<<1 | int(data[pos/8]>>(7-uint(pos%8))&1)
+			pos++
+		}
+		return value
+	}
+	if mode := read(4); mode != 0x4 {
+		t.Fatalf("Expected byte mode, got %04b", mode)
+	}
+	countBits := 8
+	if q.Version >= 10 {
+		countBits = 16
+	}
+	payload := make([]byte, read(countBits))
+	for i := range payload {
+		payload[i] = byte(read(8))
+	}
+	return payload
+}
+
+func TestReedSolomonKnownAnswer(t *testing.T) {
+	// "HELLO WORLD" at 1-M from the ISO/IEC 18004 worked example
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("Expected EC codewords %v, got %v", want, got)
+	}
+}
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	q := newQRCode(7)
+	q.drawFormatBits(0)
+	q.drawVersionBits()
+
+	format := ""
+	for i := 14; i >= 9; i-- {
+		format += map[bool]string{true: "1", false: "0"}[q.Modules[8][14-i]]
+	}
+	format += map[bool]string{true: "1", false: "0"}[q.Modules[8][7]]
+	format += map[bool]string{true: "1", false: "0"}[q.Modules[8][8]]
+	format += map[bool]string{true: "1", false: "0"}[q.Modules[7][8]]
+	for i := 5; i >= 0; i-- {
+		format += map[bool]string{true: "1", false: "0"}[q.Modules[i][8]]
+	}
+	if format != "101010000010010" {
+		t.Errorf("Expected M/mask 0 format bits 101010000010010, got %s", format)
+	}
+
+	version := ""
+	for i := 17; i >= 0; i-- {
+		version += map[bool]string{true: "1", false: "0"}[q.Modules[i/3][q.Size-11+i%3]]
+	}
+	if version != "000111110010010100" {
+		t.Errorf("Expected version 7 bits 000111110010010100, got %s", version)
+	}
+}
+
+func TestEncodeQRRoundTrip(t *testing.T) {
+	source := strings.Repeat("https://evidence.example.gov/verify/BWC-CASE-2025-001-OFF-123-", 4)
+
+	// Lengths at version capacity boundaries for level M
+	tests := []struct {
+		length  int
+		version int
+	}{
+		{1, 1}, {14, 1}, {15, 2}, {84, 5}, {100, 6}, {152, 8}, {180, 9}, {181, 10}, {213, 10},
+	}
+
+	for _, tt := range tests {
+		payload := source[:tt.length]
+		q, err := encodeQR([]byte(payload))
+		if err != nil {
+			t.Fatalf("encodeQR(%d bytes) failed: %v", tt.length, err)
+		}
+		if q.Version != tt.version {
+			t.Errorf("Expected version %d for %d bytes, got %d", tt.version, tt.length, q.Version)
+		}
+		if got := string(decodeTestQR(t, q)); got != payload {
+			t.Errorf("Round trip of %d bytes returned %q", tt.length, got)
+		}
+	}
+
+	if _, err := encodeQR(make([]byte, 214)); err != errQRTooLong {
+		t.Errorf("Expected errQRTooLong, got %v", err)
+	}
+}
+
+func TestQRImage(t *testing.T) {
+	q, _ := encodeQR([]byte("BWC-TEST"))
+	img := q.Image(2)
+
+	if want := (q.Size + 8) * 2; img.Bounds().Dx() != want {
+		t.Errorf("Expected %dpx image, got %d", want, img.Bounds().Dx())
+	}
+
+	// Top-left finder pattern corner is dark, quiet zone is light
+	if r, _, _, _ := img.At(8, 8).RGBA(); r != 0 {
+		t.Error("Expected dark finder module")
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Error("Expected light quiet zone")
+	}
+}
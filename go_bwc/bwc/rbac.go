@@ -0,0 +1,337 @@
+package bwc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// usersFile holds the registered users and their roles
+const usersFile = "users.json"
+
+// systemUserID is the principal the system's own jobs act as. It is never
+// refused a permission.
+const systemUserID = "SYSTEM"
+
+// Role is the set of permissions a user holds
+type Role string
+
+const (
+	RoleOfficer           Role = "OFFICER"
+	RoleDetective         Role = "DETECTIVE"
+	RoleEvidenceCustodian Role = "EVIDENCE_CUSTODIAN"
+	RoleAuditor           Role = "AUDITOR"
+	RoleAdmin             Role = "ADMIN"
+)
+
+// Permission is an operation a role may perform
+type Permission string
+
+const (
+	PermIngest         Permission = "ingest"
+	PermViewEvidence   Permission = "view_evidence"
+	PermVerify         Permission = "verify_integrity"
+	PermTransfer       Permission = "transfer_custody"
+	PermUpdateStatus   Permission = "update_status"
+	PermDeleteEvidence Permission = "delete_evidence"
+	PermEditMetadata   Permission = "edit_metadata"
+	PermExport         Permission = "export"
+	PermGrantAccess    Permission = "grant_access"
+	PermSeal           Permission = "seal"
+	PermReviewAudit    Permission = "review_audit"
+	PermManageUsers    Permission = "manage_users"
+	PermAdminister     Permission = "administer"
+)
+
+// rolePermissions lists what each role may do. Admins may do everything.
+var rolePermissions = map[Role][]Permission{
+	RoleOfficer: {PermIngest, PermViewEvidence, PermVerify},
+	RoleDetective: {PermIngest, PermViewEvidence, PermVerify, PermEditMetadata,
+		PermExport},
+	RoleEvidenceCustodian: {PermIngest, PermViewEvidence, PermVerify, PermTransfer,
+		PermUpdateStatus, PermEditMetadata, PermExport, PermGrantAccess, PermSeal},
+	RoleAuditor: {PermViewEvidence, PermVerify, PermReviewAudit},
+	RoleAdmin: {PermIngest, PermViewEvidence, PermVerify, PermTransfer,
+		PermUpdateStatus, PermDeleteEvidence, PermEditMetadata, PermExport,
+		PermGrantAccess, PermSeal, PermReviewAudit, PermManageUsers, PermAdminister},
+}
+
+// ParseRole returns the role named s, ignoring case
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToUpper(strings.TrimSpace(s)))
+	if _, ok := rolePermissions[role]; !ok {
+		return "", &ValidationError{Field: "role", Value: s,
+			Reason: "must be OFFICER, DETECTIVE, EVIDENCE_CUSTODIAN, AUDITOR or ADMIN"}
+	}
+	return role, nil
+}
+
+// Permissions lists what the role may do
+func (r Role) Permissions() []Permission {
+	return append([]Permission(nil), rolePermissions[r]...)
+}
+
+// Allows reports whether the role holds perm
+func (r Role) Allows(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// statusPermission is the permission needed to move evidence to status
+func statusPermission(status EvidenceStatus) Permission {
+	if status == StatusDeleted {
+		return PermDeleteEvidence
+	}
+	return PermUpdateStatus
+}
+
+// User is a person who may act on the system, and the role they act in
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	// DeactivatedAt is set once the user may no longer act
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	DeactivatedBy string     `json:"deactivated_by,omitempty"`
+}
+
+// loadUsers reads the registered users from storage
+func (bwc *BWCSystem) loadUsers() error {
+	data, err := os.ReadFile(filepath.Join(bwc.storagePath, usersFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read users: %w", err)
+	}
+	var users []*User
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("%s: %w", usersFile, err)
+	}
+	for _, user := range users {
+		bwc.users[user.ID] = user
+	}
+	return nil
+}
+
+// saveUsersLocked writes the registered users. The caller must hold
+// bwc.usersMu.
+func (bwc *BWCSystem) saveUsersLocked() error {
+	users := make([]*User, 0, len(bwc.users))
+	for _, user := range bwc.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return writeSnapshot(filepath.Join(bwc.storagePath, usersFile), users)
+}
+
+// accessControlEnabled reports whether permissions are enforced
+func (bwc *BWCSystem) accessControlEnabled() bool {
+	return bwc.config != nil && bwc.config.AccessControl.Enabled
+}
+
+// permits reports whether userID may perform perm, and why not
+func (bwc *BWCSystem) permits(userID string, perm Permission) (bool, string) {
+	if !bwc.accessControlEnabled() || userID == systemUserID {
+		return true, ""
+	}
+	for _, admin := range bwc.config.AccessControl.Admins {
+		if admin == userID {
+			return true, ""
+		}
+	}
+
+	bwc.usersMu.RLock()
+	defer bwc.usersMu.RUnlock()
+	user := bwc.users[userID]
+	switch {
+	case user == nil:
+		return false, "not a registered user"
+	case !user.Active:
+		return false, "user is deactivated"
+	case !user.Role.Allows(perm):
+		return false, fmt.Sprintf("role %s lacks %s", user.Role, perm)
+	}
+	return true, ""
+}
+
+// authorize refuses operation, auditing the refusal, unless userID holds
+// perm. Every permission is granted while access control is disabled.
+func (bwc *BWCSystem) authorize(userID string, perm Permission, operation, evidenceID string) error {
+	ok, reason := bwc.permits(userID, perm)
+	if ok {
+		return nil
+	}
+	bwc.logAudit(userID, "PERMISSION_DENIED", evidenceID,
+		fmt.Sprintf("%s refused: %s", operation, reason), "")
+	return &BWCError{Code: CodePermissionDenied,
+		Message: fmt.Sprintf("%s may not %s: %s", userID, strings.ToLower(operation), reason)}
+}
+
+// Authorize returns an error matching ErrPermissionDenied, and audits it,
+// unless userID may perform perm. The API server checks reads with it.
+func (bwc *BWCSystem) Authorize(userID string, perm Permission) error {
+	return bwc.authorize(userID, perm, string(perm), "")
+}
+
+// RegisterUser adds a user with role. The first administrators are those
+// the configuration names in access_control.admins.
+func (bwc *BWCSystem) RegisterUser(userID, name string, role Role, registeredBy string) (*User, error) {
+	return bwc.RegisterUserContext(context.Background(), userID, name, role, registeredBy)
+}
+
+// RegisterUserContext registers like RegisterUser, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) RegisterUserContext(ctx context.Context, userID, name string, role Role, registeredBy string) (*User, error) {
+	registeredBy, auth := actingUser(ctx, registeredBy)
+	if err := ValidateOfficerID(userID); err != nil {
+		return nil, err
+	}
+	if _, err := ParseRole(string(role)); err != nil {
+		return nil, err
+	}
+	if err := bwc.authorize(registeredBy, PermManageUsers, "Register user", ""); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+
+	if existing := bwc.users[userID]; existing != nil {
+		return nil, fmt.Errorf("user %s is already registered as %s", userID, existing.Role)
+	}
+	user := &User{
+		ID:        userID,
+		Name:      name,
+		Role:      role,
+		Active:    true,
+		CreatedAt: time.Now(),
+		CreatedBy: registeredBy,
+	}
+	bwc.users[userID] = user
+	if err := bwc.saveUsersLocked(); err != nil {
+		delete(bwc.users, userID)
+		return nil, err
+	}
+
+	bwc.logAuditAs(auth, registeredBy, "REGISTER_USER", "", fmt.Sprintf("User %s registered as %s", userID, role), "")
+	copied := *user
+	return &copied, nil
+}
+
+// SetUserRole changes the role userID acts in
+func (bwc *BWCSystem) SetUserRole(userID string, role Role, changedBy string) error {
+	return bwc.SetUserRoleContext(context.Background(), userID, role, changedBy)
+}
+
+// SetUserRoleContext changes a role like SetUserRole, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) SetUserRoleContext(ctx context.Context, userID string, role Role, changedBy string) error {
+	changedBy, auth := actingUser(ctx, changedBy)
+	if _, err := ParseRole(string(role)); err != nil {
+		return err
+	}
+	if err := bwc.authorize(changedBy, PermManageUsers, "Change user role", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+
+	user := bwc.users[userID]
+	if user == nil {
+		return &BWCError{Code: CodeNotFound, Message: fmt.Sprintf("user %s is not registered", userID)}
+	}
+	previous := user.Role
+	user.Role = role
+	if err := bwc.saveUsersLocked(); err != nil {
+		user.Role = previous
+		return err
+	}
+
+	bwc.logAuditAs(auth, changedBy, "CHANGE_USER_ROLE", "", fmt.Sprintf("User %s changed from %s to %s", userID, previous, role), "")
+	return nil
+}
+
+// DeactivateUser stops userID acting on the system. Their record is kept so
+// the entries they made still name them.
+func (bwc *BWCSystem) DeactivateUser(userID, deactivatedBy, reason string) error {
+	return bwc.DeactivateUserContext(context.Background(), userID, deactivatedBy, reason)
+}
+
+// DeactivateUserContext deactivates like DeactivateUser, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) DeactivateUserContext(ctx context.Context, userID, deactivatedBy, reason string) error {
+	deactivatedBy, auth := actingUser(ctx, deactivatedBy)
+	if err := bwc.authorize(deactivatedBy, PermManageUsers, "Deactivate user", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+	bwc.usersMu.Lock()
+	defer bwc.usersMu.Unlock()
+
+	user := bwc.users[userID]
+	if user == nil {
+		return &BWCError{Code: CodeNotFound, Message: fmt.Sprintf("user %s is not registered", userID)}
+	}
+	if !user.Active {
+		return fmt.Errorf("user %s is already deactivated", userID)
+	}
+	now := time.Now()
+	user.Active, user.DeactivatedAt, user.DeactivatedBy = false, &now, deactivatedBy
+	if err := bwc.saveUsersLocked(); err != nil {
+		user.Active, user.DeactivatedAt, user.DeactivatedBy = true, nil, ""
+		return err
+	}
+
+	bwc.logAuditAs(auth, deactivatedBy, "DEACTIVATE_USER", "", fmt.Sprintf("User %s deactivated - %s", userID, reason), "")
+	return nil
+}
+
+// GetUser returns the registered user userID
+func (bwc *BWCSystem) GetUser(userID string) (*User, error) {
+	bwc.usersMu.RLock()
+	defer bwc.usersMu.RUnlock()
+
+	user := bwc.users[userID]
+	if user == nil {
+		return nil, &BWCError{Code: CodeNotFound, Message: fmt.Sprintf("user %s is not registered", userID)}
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// Users lists the registered users, deactivated ones included, by ID
+func (bwc *BWCSystem) Users() []User {
+	bwc.usersMu.RLock()
+	defer bwc.usersMu.RUnlock()
+
+	users := make([]User, 0, len(bwc.users))
+	for _, user := range bwc.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users
+}
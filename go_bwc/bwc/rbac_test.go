@@ -0,0 +1,196 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// enableAccessControl turns on role-based access control with ADM-001 as
+// the configured administrator
+func enableAccessControl(system *BWCSystem) {
+	system.config.AccessControl = AccessControlConfig{Enabled: true, Admins: []string{"ADM-001"}}
+}
+
+func TestRolePermissionsAreEnforced(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	for id, role := range map[string]Role{"OFF-2001": RoleOfficer, "CUS-2001": RoleEvidenceCustodian, "AUD-2001": RoleAuditor} {
+		if _, err := system.RegisterUser(id, "", role, "ADM-001"); err != nil {
+			t.Fatalf("RegisterUser %s failed: %v", id, err)
+		}
+	}
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-RBAC-001", "OFF-2001", "", "", nil)
+	if err != nil {
+		t.Fatalf("expected an officer to ingest, got %v", err)
+	}
+	if _, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-RBAC-001", "OFF-2999", "", "", nil); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected an unregistered user to be refused, got %v", err)
+	}
+
+	err = system.TransferCustody(evidence.ID, "OFF-2001", "CUS-2001", "Booking")
+	if !errors.Is(err, ErrPermissionDenied) || ErrorCodeOf(err) != CodePermissionDenied {
+		t.Errorf("expected an officer to be refused a transfer, got %v", err)
+	}
+	if err := system.TransferCustody(evidence.ID, "CUS-2001", "CUS-2002", "Storage"); err != nil {
+		t.Errorf("expected a custodian to transfer, got %v", err)
+	}
+	if err := system.UpdateStatus(evidence.ID, "CUS-2001", StatusArchived, "Closed"); err != nil {
+		t.Fatalf("expected a custodian to archive, got %v", err)
+	}
+	if err := system.UpdateStatus(evidence.ID, "CUS-2001", StatusDeleted, "Expired"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected a custodian to be refused deletion, got %v", err)
+	}
+	if ok, err := system.VerifyIntegrity(evidence.ID, "AUD-2001"); err != nil || !ok {
+		t.Errorf("expected an auditor to verify, got %v %v", ok, err)
+	}
+	if err := system.UpdateStatus(evidence.ID, "ADM-001", StatusDeleted, "Expired"); err != nil {
+		t.Errorf("expected the administrator to delete, got %v", err)
+	}
+
+	denied := 0
+	for _, log := range system.GetAuditLogs("", "") {
+		if log.Action == "PERMISSION_DENIED" {
+			denied++
+		}
+	}
+	if denied != 3 {
+		t.Errorf("expected the 3 refusals to be audited, got %d", denied)
+	}
+}
+
+func TestUserManagement(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	if _, err := system.RegisterUser("DET-2001", "Jordan Lee", RoleDetective, "DET-2002"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected only administrators to register users, got %v", err)
+	}
+	if _, err := system.RegisterUser("DET-2001", "Jordan Lee", RoleDetective, "ADM-001"); err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+	if _, err := system.RegisterUser("DET-2001", "", RoleOfficer, "ADM-001"); err == nil {
+		t.Error("expected a duplicate user to be refused")
+	}
+	if _, err := system.RegisterUser("DET-2003", "", Role("CHIEF"), "ADM-001"); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Errorf("expected an unknown role to be refused, got %v", err)
+	}
+	if _, err := system.RegisterUser(systemUserID, "", RoleOfficer, "ADM-001"); ErrorCodeOf(err) != CodeInvalidArgument {
+		t.Errorf("expected the system's own ID to be refused, got %v", err)
+	}
+
+	if err := system.Authorize("DET-2001", PermTransfer); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected a detective not to transfer, got %v", err)
+	}
+	if err := system.SetUserRole("DET-2001", RoleEvidenceCustodian, "ADM-001"); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if err := system.Authorize("DET-2001", PermTransfer); err != nil {
+		t.Errorf("expected the new role to apply, got %v", err)
+	}
+	if err := system.DeactivateUser("DET-2001", "ADM-001", "Left the agency"); err != nil {
+		t.Fatalf("DeactivateUser failed: %v", err)
+	}
+	if err := system.Authorize("DET-2001", PermViewEvidence); err == nil || !strings.Contains(err.Error(), "deactivated") {
+		t.Errorf("expected a deactivated user to be refused, got %v", err)
+	}
+
+	reopened, err := NewBWCSystem(tmpDir)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	user, err := reopened.GetUser("DET-2001")
+	if err != nil || user.Role != RoleEvidenceCustodian || user.Active || user.DeactivatedBy != "ADM-001" {
+		t.Errorf("expected the users to persist, got %+v %v", user, err)
+	}
+}
+
+func TestAccessControlDisabledAllowsEveryone(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	if err := system.Authorize("ANYONE-1", PermDeleteEvidence); err != nil {
+		t.Errorf("expected every permission while access control is off, got %v", err)
+	}
+	if !RoleAdmin.Allows(PermDeleteEvidence) || RoleEvidenceCustodian.Allows(PermDeleteEvidence) || RoleOfficer.Allows(PermTransfer) {
+		t.Error("unexpected role permissions")
+	}
+
+	cfg := DefaultConfig()
+	cfg.AccessControl.Enabled = true
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "access_control.admins") {
+		t.Errorf("expected an administrator to be required, got %v", err)
+	}
+}
+
+func TestServerPermissionDenied(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-RBAC-API", "OFF-2101", "", "", nil)
+	enableAccessControl(system)
+
+	for _, path := range []string{"/api/evidence", "/api/evidence/" + evidence.ID, "/api/evidence/" + evidence.ID + "/custody", "/api/ingests", "/api/ingests/interrupted"} {
+		resp := authGet(t, server, path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected an unregistered user to get 403 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	system.config.AccessControl.Admins = []string{"CUS-001"}
+	resp := authPostJSON(t, server, "/api/users", `{"id":"OFF-2101","name":"Sam Ortiz","role":"officer"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected the user to be registered, got %d", resp.StatusCode)
+	}
+	if user, err := system.GetUser("OFF-2101"); err != nil || user.Role != RoleOfficer || user.CreatedBy != "CUS-001" {
+		t.Errorf("unexpected user %+v %v", user, err)
+	}
+}
+
+func TestServerAuditReadsNeedReviewPermission(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-RBAC-AUD", "OFF-2201", "", "", nil)
+	enableAccessControl(system)
+	if _, err := system.RegisterUser("CUS-001", "", RoleEvidenceCustodian, "ADM-001"); err != nil {
+		t.Fatalf("RegisterUser failed: %v", err)
+	}
+
+	for _, path := range []string{"/api/audit?evidence_id=" + evidence.ID, "/api/stream/evidence", "/api/stream/events"} {
+		resp := authGet(t, server, path)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("expected 403 for %s without review_audit, got %d", path, resp.StatusCode)
+		}
+	}
+	resp := authPostJSON(t, server, "/api/graphql", `{"query": "query Q($id: ID!) { evidence(id: $id) { auditLog { action } } }", "variables": {"id": "`+evidence.ID+`"}}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), string(CodePermissionDenied)) {
+		t.Errorf("expected the auditLog field to be refused, got %s", body)
+	}
+
+	ctx := ContextWithPrincipal(context.Background(), &Principal{UserID: "CUS-001", Method: AuthAPIKey})
+	if _, err := system.GetAuditLogsContext(ctx, evidence.ID, ""); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected GetAuditLogsContext to be refused, got %v", err)
+	}
+	if err := system.SetUserRole("CUS-001", RoleAuditor, "ADM-001"); err != nil {
+		t.Fatalf("SetUserRole failed: %v", err)
+	}
+	if logs, err := system.GetAuditLogsContext(ctx, evidence.ID, ""); err != nil || len(logs) == 0 {
+		t.Errorf("expected an auditor to read the log, got %d entries, %v", len(logs), err)
+	}
+	resp = authGet(t, server, "/api/audit?evidence_id="+evidence.ID)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected an auditor to list audit entries, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,39 @@
+package bwc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// auditReads records that the principal ctx carries read each of results,
+// when audit.log_all_access is on. Each record gets its own entry, so
+// GetAuditLogs for one item shows everyone who has seen it.
+func (bwc *BWCSystem) auditReads(ctx context.Context, action string, results []*Evidence, details string) {
+	if !bwc.config.Audit.LogAllAccess {
+		return
+	}
+	principal := PrincipalFromContext(ctx)
+	userID := readerFromContext(ctx)
+	for _, evidence := range results {
+		bwc.logAuditAs(principal.authentication(), userID, action, evidence.ID, details, "")
+	}
+}
+
+// searchCriteria describes a search for the audit log
+func searchCriteria(caseNumber, officerID string, status EvidenceStatus) string {
+	var criteria []string
+	if caseNumber != "" {
+		criteria = append(criteria, "case "+caseNumber)
+	}
+	if officerID != "" {
+		criteria = append(criteria, "officer "+officerID)
+	}
+	if status != "" {
+		criteria = append(criteria, "status "+string(status))
+	}
+	if len(criteria) == 0 {
+		return "Returned by a search of all evidence"
+	}
+	return fmt.Sprintf("Returned by a search on %s", strings.Join(criteria, ", "))
+}
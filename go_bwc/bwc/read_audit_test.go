@@ -0,0 +1,58 @@
+package bwc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadAccessAuditing(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	first, _ := system.IngestEvidence(testFile, "CASE-READ-001", "OFF-7001", "", "", nil)
+	second, _ := system.IngestEvidence(testFile, "CASE-READ-001", "OFF-7002", "", "", nil)
+
+	// Off by default
+	system.GetEvidence(first.ID)
+	if actions := strings.Join(auditActions(system, first.ID), " "); strings.Contains(actions, "VIEW_EVIDENCE") {
+		t.Errorf("expected reads not to be audited by default, got %s", actions)
+	}
+
+	system.config.Audit.LogAllAccess = true
+	ctx := ContextWithPrincipal(context.Background(), &Principal{UserID: "DET-7001", Method: AuthAPIKey, AuthenticatedAt: time.Now()})
+
+	if _, err := system.GetEvidenceContext(ctx, first.ID); err != nil {
+		t.Fatalf("GetEvidenceContext failed: %v", err)
+	}
+	if _, err := system.GetChainOfCustodyContext(ctx, first.ID); err != nil {
+		t.Fatalf("GetChainOfCustodyContext failed: %v", err)
+	}
+	if results, err := system.SearchEvidenceContext(ctx, "CASE-READ-001", "", ""); err != nil || len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d: %v", len(results), err)
+	}
+
+	logs := system.GetAuditLogs(first.ID, "DET-7001")
+	var actions []string
+	for _, log := range logs {
+		actions = append(actions, log.Action)
+		if log.Authentication == nil || log.Authentication.Method != AuthAPIKey {
+			t.Errorf("expected %s to record how the reader authenticated, got %+v", log.Action, log.Authentication)
+		}
+	}
+	if got := strings.Join(actions, " "); got != "VIEW_EVIDENCE VIEW_CUSTODY SEARCH_EVIDENCE" {
+		t.Errorf("unexpected read audit for %s: %s", first.ID, got)
+	}
+	if logs := system.GetAuditLogs(second.ID, "DET-7001"); len(logs) != 1 || !strings.Contains(logs[0].Details, "case CASE-READ-001") {
+		t.Errorf("expected the search to be audited against every result, got %+v", logs)
+	}
+
+	// Searches the system makes for itself are not reads by anyone
+	before := len(system.GetAuditLogs("", ""))
+	system.BuildDashboard("", 30*24*time.Hour)
+	if after := len(system.GetAuditLogs("", "")); after != before {
+		t.Errorf("expected the dashboard not to be audited as reads, got %d new entries", after-before)
+	}
+}
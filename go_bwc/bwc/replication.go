@@ -0,0 +1,403 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReplicaInfo records where an evidence file's replica copy is kept and, for
+// an S3 replica with Object Lock, how it is protected from deletion
+type ReplicaInfo struct {
+	Type         string    `json:"type"`
+	Location     string    `json:"location"`
+	Key          string    `json:"key"`
+	ReplicatedAt time.Time `json:"replicated_at"`
+	LockMode     string    `json:"lock_mode,omitempty"`
+	RetainUntil  time.Time `json:"retain_until,omitempty"`
+	LegalHold    bool      `json:"legal_hold,omitempty"`
+}
+
+// ReplicaRepairRequest is a pending restoration of a damaged evidence file from
+// its replica; the replica is only fetched once a second person approves it
+type ReplicaRepairRequest struct {
+	ID            string               `json:"id"`
+	EvidenceID    string               `json:"evidence_id"`
+	RequestedBy   string               `json:"requested_by"`
+	Reason        string               `json:"reason"`
+	DamagedSHA256 string               `json:"damaged_sha256"`
+	Status        CustodyRequestStatus `json:"status"`
+	RequestedAt   time.Time            `json:"requested_at"`
+	ResolvedBy    string               `json:"resolved_by"`
+	ResolvedAt    time.Time            `json:"resolved_at"`
+	Resolution    string               `json:"resolution"`
+}
+
+var errNoReplica = errors.New("evidence has no replica copy")
+
+// replicaHTTPClient transfers recordings to and from an S3 replica
+var replicaHTTPClient = &http.Client{Timeout: 30 * time.Minute}
+
+// replicateLocked copies the evidence file to the configured replica and
+// records it on the evidence; the caller must hold bwc.mu
+func (bwc *BWCSystem) replicateLocked(evidence *Evidence) error {
+	dest := bwc.config.Storage.Replica
+	if dest == nil {
+		return errors.New("no replica is configured")
+	}
+
+	path, done, err := bwc.localStoredFile(evidence)
+	if err != nil {
+		return err
+	}
+	defer done()
+	name := filepath.Base(bwc.storagePathFor(evidence))
+	info := &ReplicaInfo{Type: dest.Type, ReplicatedAt: time.Now()}
+	switch dest.Type {
+	case "directory":
+		if err := os.MkdirAll(dest.Path, 0700); err != nil {
+			return fmt.Errorf("failed to create replica directory: %w", err)
+		}
+		info.Key = name
+		info.Location = filepath.Join(dest.Path, name)
+		if err := copyFile(path, info.Location); err != nil {
+			os.Remove(info.Location)
+			return fmt.Errorf("failed to copy to replica: %w", err)
+		}
+	case "s3":
+		info.Key = dest.Prefix + name
+		info.Location = "s3://" + dest.Bucket + "/" + info.Key
+		var headers map[string]string
+		if dest.ObjectLock {
+			// Lock the copy for the evidence's retention period so it cannot
+			// be deleted early, even with the bucket owner's credentials
+			if expiry, _, ok := bwc.retentionExpiry(evidence); ok {
+				info.LockMode = "COMPLIANCE"
+				info.RetainUntil = expiry.UTC().Add(time.Second - 1).Truncate(time.Second)
+			} else {
+				info.LegalHold = true
+			}
+			if headers, err = objectLockHeaders(path, info.RetainUntil); err != nil {
+				return err
+			}
+		}
+		if err := putS3File(replicaHTTPClient, *dest, info.Key, path, storedHash(evidence), headers); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported replica type %q", dest.Type)
+	}
+
+	evidence.Replica = info
+	return nil
+}
+
+// describe names the replica copy and any Object Lock protecting it
+func (r *ReplicaInfo) describe() string {
+	switch {
+	case r.LegalHold:
+		return r.Location + " under legal hold"
+	case r.LockMode != "":
+		return fmt.Sprintf("%s locked in %s mode until %s", r.Location, strings.ToLower(r.LockMode), r.RetainUntil.Format("2006-01-02"))
+	}
+	return r.Location
+}
+
+// fetchReplica downloads the evidence file's replica copy to dst
+func (bwc *BWCSystem) fetchReplica(evidence *Evidence, dst string) error {
+	file, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	switch evidence.Replica.Type {
+	case "directory":
+		src, err := os.Open(evidence.Replica.Location)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if _, err := file.ReadFrom(src); err != nil {
+			return err
+		}
+	case "s3":
+		dest := bwc.config.Storage.Replica
+		if dest == nil || dest.Type != "s3" {
+			return errors.New("the S3 replica is no longer configured")
+		}
+		if err := getS3Object(replicaHTTPClient, *dest, evidence.Replica.Key, file); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported replica type %q", evidence.Replica.Type)
+	}
+	return file.Sync()
+}
+
+// damagedFileHash returns the SHA-256 of an evidence file that no longer
+// matches its recorded hash, or "missing" if the file is gone
+func (bwc *BWCSystem) damagedFileHash(evidence *Evidence) (string, error) {
+	hash, err := bwc.hashStoredFile(evidence)
+	if errors.Is(err, os.ErrNotExist) {
+		return "missing", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+	if hash == storedHash(evidence) {
+		return "", errors.New("evidence is intact; nothing to repair")
+	}
+	return hash, nil
+}
+
+// ReplicateEvidence copies evidence ingested before a replica was configured,
+// or whose replication at ingest failed, to the replica
+func (bwc *BWCSystem) ReplicateEvidence(evidenceID, userID string) (*ReplicaInfo, error) {
+	if err := bwc.authorize(userID, PermAdminister, "Replication", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+
+	// Never replicate a damaged file over a good copy
+	hash, err := bwc.hashStoredFile(evidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+	if hash != storedHash(evidence) {
+		return nil, errors.New("evidence file does not match its hash; it cannot be replicated")
+	}
+
+	before := copyEvidence(evidence)
+	if err := bwc.replicateLocked(evidence); err != nil {
+		bwc.logAudit(userID, "REPLICATION_FAILED", evidenceID, err.Error(), "")
+		return nil, err
+	}
+	markModified(evidence, time.Now())
+	if err := bwc.saveLocked(evidence); err != nil {
+		*evidence = before
+		return nil, err
+	}
+
+	bwc.logAudit(userID, "REPLICATE_EVIDENCE", evidenceID, "Replicated to "+evidence.Replica.describe(), "")
+
+	r := *evidence.Replica
+	return &r, nil
+}
+
+// RequestReplicaRepair asks to restore damaged evidence from its replica.
+// VerifyIntegrity opens these requests itself when a check fails.
+func (bwc *BWCSystem) RequestReplicaRepair(evidenceID, requestedBy, reason string) (*ReplicaRepairRequest, error) {
+	if err := bwc.authorize(requestedBy, PermAdminister, "Replica repair request", evidenceID); err != nil {
+		return nil, err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	damagedHash, err := bwc.damagedFileHash(evidence)
+	if err != nil {
+		return nil, err
+	}
+	return bwc.requestReplicaRepairLocked(evidence, requestedBy, reason, damagedHash)
+}
+
+// requestReplicaRepairLocked records a replica repair request; the caller must hold bwc.mu
+func (bwc *BWCSystem) requestReplicaRepairLocked(evidence *Evidence, requestedBy, reason, damagedHash string) (*ReplicaRepairRequest, error) {
+	if evidence.Replica == nil {
+		return nil, errNoReplica
+	}
+	for _, req := range bwc.replicaRepairs {
+		if req.EvidenceID == evidence.ID && req.Status == RequestPending {
+			return nil, fmt.Errorf("replica repair %s is already pending for this evidence", req.ID)
+		}
+	}
+
+	bwc.replicaRepairSeq++
+	req := &ReplicaRepairRequest{
+		ID:            fmt.Sprintf("RRP-%06d", bwc.replicaRepairSeq),
+		EvidenceID:    evidence.ID,
+		RequestedBy:   requestedBy,
+		Reason:        reason,
+		DamagedSHA256: damagedHash,
+		Status:        RequestPending,
+		RequestedAt:   time.Now(),
+	}
+	bwc.replicaRepairs[req.ID] = req
+
+	bwc.logAudit(requestedBy, "REQUEST_REPLICA_REPAIR", evidence.ID,
+		fmt.Sprintf("Repair %s from %s requested - %s", req.ID, evidence.Replica.Location, reason), "")
+
+	r := *req
+	return &r, nil
+}
+
+// ApproveReplicaRepair fetches the replica for a pending request and, if it
+// matches the hash recorded at ingest, puts it in place of the damaged file.
+// The approver must not be the requester. A failed attempt leaves the local
+// file and the request as they were.
+func (bwc *BWCSystem) ApproveReplicaRepair(requestID, approverID string) error {
+	if err := bwc.authorize(approverID, PermAdminister, "Replica repair approval", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	req, err := bwc.pendingReplicaRepairFor(requestID, approverID)
+	if err != nil {
+		return err
+	}
+	evidence := bwc.evidenceDB.Get(req.EvidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, approverID, "Replica repair"); err != nil {
+		return err
+	}
+	if evidence.Replica == nil {
+		return errNoReplica
+	}
+
+	damagedHash, err := bwc.damagedFileHash(evidence)
+	if err != nil {
+		return err
+	}
+
+	local := bwc.storagePathFor(evidence)
+	tmp := filepath.Join(filepath.Dir(local), "."+filepath.Base(local)+".replica")
+	err = bwc.fetchReplica(evidence, tmp)
+	if err == nil {
+		var replicaHash string
+		replicaHash, err = calculateFileHash(tmp)
+		if err == nil && replicaHash != storedHash(evidence) {
+			err = fmt.Errorf("replica copy sha256 %s does not match the evidence hash", replicaHash)
+		}
+	}
+	if err == nil {
+		err = bwc.replaceStoredFileLocked(evidence, tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		bwc.logAudit(approverID, "REPLICA_REPAIR_FAILED", evidence.ID,
+			fmt.Sprintf("Repair %s from %s failed: %v", req.ID, evidence.Replica.Location, err), "")
+		return fmt.Errorf("replica repair failed: %w", err)
+	}
+
+	now := time.Now()
+	details := fmt.Sprintf("Restored from replica %s under %s (requested by %s, approved by %s): damaged sha256 %s",
+		evidence.Replica.Location, req.ID, req.RequestedBy, approverID, damagedHash)
+	evidence.IntegrityChecks = append(evidence.IntegrityChecks, IntegrityCheck{
+		Timestamp: now,
+		CheckedBy: approverID,
+		HashValue: evidence.FileHash,
+		IsValid:   true,
+		Notes:     details,
+	})
+	entry := CustodyEntry{
+		Timestamp:    now,
+		FromOfficer:  approverID,
+		ToOfficer:    approverID,
+		Action:       "RESTORED",
+		Purpose:      details,
+		VerifiedHash: evidence.FileHash,
+	}
+	entry.EntryHash, err = custodyEntryHash(entry)
+	if err != nil {
+		return err
+	}
+	evidence.ChainOfCustody = append(evidence.ChainOfCustody, entry)
+	markModified(evidence, now)
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	req.Status = RequestAccepted
+	req.ResolvedBy = approverID
+	req.ResolvedAt = now
+	req.Resolution = details
+
+	bwc.logAudit(approverID, "REPAIR_FROM_REPLICA", evidence.ID, details, "")
+
+	return nil
+}
+
+// DeclineReplicaRepair rejects a pending replica repair; the file is left as it is
+func (bwc *BWCSystem) DeclineReplicaRepair(requestID, approverID, reason string) error {
+	if err := bwc.authorize(approverID, PermAdminister, "Replica repair decline", ""); err != nil {
+		return err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	req, err := bwc.pendingReplicaRepairFor(requestID, approverID)
+	if err != nil {
+		return err
+	}
+
+	req.Status = RequestDeclined
+	req.ResolvedBy = approverID
+	req.ResolvedAt = time.Now()
+	req.Resolution = reason
+
+	bwc.logAudit(approverID, "DECLINE_REPLICA_REPAIR", req.EvidenceID,
+		fmt.Sprintf("Repair %s requested by %s declined - %s", req.ID, req.RequestedBy, reason), "")
+
+	return nil
+}
+
+// PendingReplicaRepairs lists replica repairs awaiting approval
+func (bwc *BWCSystem) PendingReplicaRepairs() []ReplicaRepairRequest {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	results := make([]ReplicaRepairRequest, 0)
+	for _, req := range bwc.replicaRepairs {
+		if req.Status == RequestPending {
+			results = append(results, *req)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// pendingReplicaRepairFor looks up a pending replica repair that approverID may
+// resolve; the caller must hold bwc.mu
+func (bwc *BWCSystem) pendingReplicaRepairFor(requestID, approverID string) (*ReplicaRepairRequest, error) {
+	req, exists := bwc.replicaRepairs[requestID]
+	if !exists {
+		return nil, errors.New("replica repair request not found")
+	}
+	if req.Status != RequestPending {
+		return nil, fmt.Errorf("replica repair request is already %s", req.Status)
+	}
+	if approverID == "" || approverID == req.RequestedBy {
+		return nil, errors.New("a replica repair must be approved by someone other than the requester")
+	}
+	return req, nil
+}
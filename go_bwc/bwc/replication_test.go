@@ -0,0 +1,313 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBucket is an in-memory S3 bucket
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	hashes  map[string]string
+	headers map[string]http.Header
+}
+
+func newFakeBucket() (*fakeBucket, *httptest.Server) {
+	b := &fakeBucket{objects: make(map[string][]byte), hashes: make(map[string]string), headers: make(map[string]http.Header)}
+	return b, httptest.NewServer(b)
+}
+
+func (b *fakeBucket) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		b.objects[r.URL.Path] = body
+		b.hashes[r.URL.Path] = r.Header.Get("X-Amz-Content-Sha256")
+		b.headers[r.URL.Path] = r.Header.Clone()
+	case http.MethodGet, http.MethodHead:
+		body, ok := b.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	case http.MethodDelete:
+		// Locked objects cannot be deleted, as with Object Lock
+		if h := b.headers[r.URL.Path]; h.Get("X-Amz-Object-Lock-Mode") != "" || h.Get("X-Amz-Object-Lock-Legal-Hold") == "ON" {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		delete(b.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestReplicaRepairFromDirectory(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.Replica = &ReportDestination{Type: "directory", Path: filepath.Join(tmpDir, "replica")}
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-001", "OFF-911", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if evidence.Replica == nil || evidence.Replica.Location != filepath.Join(tmpDir, "replica", filepath.Base(evidence.FilePath)) {
+		t.Fatalf("Expected the recording replicated at ingest, got %+v", evidence.Replica)
+	}
+	original, _ := os.ReadFile(evidence.FilePath)
+
+	os.WriteFile(evidence.FilePath, []byte("overwritten"), 0600)
+	if valid, _ := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); valid {
+		t.Fatal("Expected damaged evidence to fail verification")
+	}
+
+	pending := system.PendingReplicaRepairs()
+	if len(pending) != 1 || pending[0].EvidenceID != evidence.ID || pending[0].RequestedBy != "SYSTEM" {
+		t.Fatalf("Expected a repair request opened by the failed check, got %+v", pending)
+	}
+	if data, _ := os.ReadFile(evidence.FilePath); string(data) != "overwritten" {
+		t.Fatal("Expected the file untouched until the repair is approved")
+	}
+	if _, err := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); err != nil || len(system.PendingReplicaRepairs()) != 1 {
+		t.Errorf("Expected a repeated failure not to open a second request: %v", err)
+	}
+
+	if err := system.ApproveReplicaRepair(pending[0].ID, "SUP-1"); err != nil {
+		t.Fatalf("ApproveReplicaRepair failed: %v", err)
+	}
+	if data, _ := os.ReadFile(evidence.FilePath); !bytes.Equal(data, original) {
+		t.Error("Expected the original recording restored")
+	}
+	if valid, err := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Errorf("Expected restored evidence to verify: %v", err)
+	}
+	if len(system.PendingReplicaRepairs()) != 0 {
+		t.Error("Expected the request resolved")
+	}
+
+	restored := system.evidenceDB.Get(evidence.ID).ChainOfCustody
+	var entry *CustodyEntry
+	for i := range restored {
+		if restored[i].Action == "RESTORED" {
+			entry = &restored[i]
+		}
+	}
+	if entry == nil || entry.FromOfficer != "SUP-1" || !strings.Contains(entry.Purpose, pending[0].ID) ||
+		!strings.Contains(entry.Purpose, evidence.Replica.Location) {
+		t.Errorf("Expected a RESTORED custody entry naming the request and replica, got %+v", restored)
+	}
+	if failed, _ := system.VerifyCustodySignatures(evidence.ID); len(failed) != 0 {
+		t.Errorf("Custody entries failed verification: %v", failed)
+	}
+
+	var actions []string
+	for _, log := range system.GetAuditLogs(evidence.ID, "") {
+		actions = append(actions, log.Action)
+	}
+	joined := strings.Join(actions, " ")
+	if !strings.Contains(joined, "REQUEST_REPLICA_REPAIR") || !strings.Contains(joined, "REPAIR_FROM_REPLICA") {
+		t.Errorf("Expected request and repair audit entries, got %v", actions)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(system.storagePath, ".*.replica")); len(matches) != 0 {
+		t.Errorf("Temporary files left behind: %v", matches)
+	}
+}
+
+func TestReplicaRepairRequiresApproval(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-002", "OFF-912", "Officer Test", "Test Location", nil)
+	original, _ := os.ReadFile(evidence.FilePath)
+	os.WriteFile(evidence.FilePath, []byte("overwritten"), 0600)
+	if _, err := system.RequestReplicaRepair(evidence.ID, "TECH-1", "Bad sectors"); !errors.Is(err, errNoReplica) {
+		t.Fatalf("Expected errNoReplica without a replica, got %v", err)
+	}
+
+	system.config.Storage.Replica = &ReportDestination{Type: "directory", Path: filepath.Join(tmpDir, "replica")}
+	if _, err := system.ReplicateEvidence(evidence.ID, "TECH-1"); err == nil {
+		t.Fatal("Expected a damaged file to be refused for replication")
+	}
+	os.WriteFile(evidence.FilePath, original, 0600)
+	if _, err := system.ReplicateEvidence(evidence.ID, "TECH-1"); err != nil {
+		t.Fatalf("ReplicateEvidence failed: %v", err)
+	}
+	if _, err := system.RequestReplicaRepair(evidence.ID, "TECH-1", "Bad sectors"); err == nil {
+		t.Fatal("Expected intact evidence to need no repair")
+	}
+
+	os.WriteFile(evidence.FilePath, []byte("overwritten"), 0600)
+	req, err := system.RequestReplicaRepair(evidence.ID, "TECH-1", "Bad sectors")
+	if err != nil {
+		t.Fatalf("RequestReplicaRepair failed: %v", err)
+	}
+	if err := system.ApproveReplicaRepair(req.ID, "TECH-1"); err == nil {
+		t.Error("Expected the requester to be unable to approve")
+	}
+
+	// A replica that no longer matches is never put in place
+	os.WriteFile(evidence.Replica.Location, []byte("also damaged"), 0600)
+	if err := system.ApproveReplicaRepair(req.ID, "SUP-1"); err == nil {
+		t.Fatal("Expected a mismatched replica to be refused")
+	}
+	if data, _ := os.ReadFile(evidence.FilePath); string(data) != "overwritten" {
+		t.Error("Expected the local file untouched after a failed repair")
+	}
+	logs := system.GetAuditLogs(evidence.ID, "")
+	if last := logs[len(logs)-1]; last.Action != "REPLICA_REPAIR_FAILED" {
+		t.Errorf("Expected REPLICA_REPAIR_FAILED audit entry, got %+v", last)
+	}
+
+	if err := system.DeclineReplicaRepair(req.ID, "SUP-1", "Replica damaged too"); err != nil {
+		t.Fatalf("DeclineReplicaRepair failed: %v", err)
+	}
+	if err := system.ApproveReplicaRepair(req.ID, "SUP-2"); err == nil {
+		t.Error("Expected a declined request to stay declined")
+	}
+	for _, entry := range system.evidenceDB.Get(evidence.ID).ChainOfCustody {
+		if entry.Action == "RESTORED" {
+			t.Error("Expected no RESTORED custody entry")
+		}
+	}
+}
+
+func TestReplicaRepairFromS3(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	_, server := newFakeBucket()
+	dest := &ReportDestination{Type: "s3", Bucket: "evidence", Prefix: "bwc/", Region: "us-east-1", Endpoint: server.URL,
+		AccessKeyID: "AKID", SecretAccessKey: "secret"}
+
+	// Ingest goes ahead while the bucket is unreachable
+	server.Close()
+	system.config.Storage.Replica = dest
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-003", "OFF-913", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "")
+	if evidence.Replica != nil || logs[len(logs)-1].Action != "REPLICATION_FAILED" {
+		t.Fatalf("Expected replication failure audited, got %+v", logs[len(logs)-1])
+	}
+
+	bucket, server := newFakeBucket()
+	defer server.Close()
+	dest.Endpoint = server.URL
+	info, err := system.ReplicateEvidence(evidence.ID, "TECH-1")
+	if err != nil {
+		t.Fatalf("ReplicateEvidence failed: %v", err)
+	}
+	key := "/evidence/bwc/" + filepath.Base(evidence.FilePath)
+	if info.Location != "s3://evidence/bwc/"+filepath.Base(evidence.FilePath) || bucket.hashes[key] != evidence.FileHash {
+		t.Fatalf("Expected an upload signed with the evidence hash, got %+v %v", info, bucket.hashes)
+	}
+
+	os.WriteFile(evidence.FilePath, []byte("overwritten"), 0600)
+	system.VerifyIntegrity(evidence.ID, "AUDITOR-1")
+	pending := system.PendingReplicaRepairs()
+	if len(pending) != 1 {
+		t.Fatalf("Expected one pending repair, got %+v", pending)
+	}
+	if err := system.ApproveReplicaRepair(pending[0].ID, "SUP-1"); err != nil {
+		t.Fatalf("ApproveReplicaRepair failed: %v", err)
+	}
+	if valid, _ := system.VerifyIntegrity(evidence.ID, "AUDITOR-1"); !valid {
+		t.Error("Expected evidence restored from S3 to verify")
+	}
+}
+
+func TestReplicaConfigValidation(t *testing.T) {
+	tests := []struct {
+		replica ReportDestination
+		valid   bool
+	}{
+		{ReportDestination{Type: "directory", Path: "/srv/replica"}, true},
+		{ReportDestination{Type: "s3", Bucket: "evidence", Region: "us-east-1"}, true},
+		{ReportDestination{Type: "directory"}, false},
+		{ReportDestination{Type: "directory", Path: "./bwc_storage/"}, false},
+		{ReportDestination{Type: "s3", Bucket: "evidence"}, false},
+		{ReportDestination{Type: "s3", Bucket: "evidence", Endpoint: "minio:9000"}, false},
+		{ReportDestination{Type: "email", Recipients: []string{"a@example.gov"}}, false},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.Storage.Path = "./bwc_storage"
+		replica := tt.replica
+		cfg.Storage.Replica = &replica
+		if err := cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%+v: expected valid=%v, got %v", tt.replica, tt.valid, err)
+		}
+	}
+}
+
+func TestReplicaObjectLock(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 365
+	system.config.Storage.RetentionRules = []RetentionRule{{Tag: "homicide", Indefinite: true}}
+
+	bucket, server := newFakeBucket()
+	defer server.Close()
+	system.config.Storage.Replica = &ReportDestination{Type: "s3", Bucket: "evidence", Region: "us-east-1", Endpoint: server.URL,
+		AccessKeyID: "AKID", SecretAccessKey: "secret", ObjectLock: true}
+
+	testFile := createTestFile(t, tmpDir)
+	routine, _ := system.IngestEvidence(testFile, "CASE-LCK-001", "OFF-914", "Officer Test", "Test Location", nil)
+	held, _ := system.IngestEvidence(testFile, "CASE-LCK-002", "OFF-915", "Officer Test", "Test Location", []string{"homicide"})
+	if routine.Replica == nil || held.Replica == nil {
+		t.Fatal("Expected both recordings replicated")
+	}
+
+	data, _ := os.ReadFile(routine.FilePath)
+	sum := md5.Sum(data)
+	wantUntil := routine.CreatedAt.AddDate(0, 0, 365)
+
+	h := bucket.headers["/evidence/"+filepath.Base(routine.FilePath)]
+	if h.Get("X-Amz-Object-Lock-Mode") != "COMPLIANCE" || h.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+		t.Errorf("Expected a compliance lock with Content-MD5, got %v", h)
+	}
+	until, err := time.Parse(time.RFC3339, h.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	if err != nil || until.Before(wantUntil) || until.Sub(wantUntil) >= time.Second {
+		t.Errorf("Expected retention until %s, got %s", wantUntil, h.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	}
+	if routine.Replica.LockMode != "COMPLIANCE" || !routine.Replica.RetainUntil.Equal(until) {
+		t.Errorf("Expected the lock recorded on the evidence, got %+v", routine.Replica)
+	}
+	if !strings.Contains(h.Get("Authorization"), "x-amz-object-lock-mode") {
+		t.Error("Expected the lock headers to be signed")
+	}
+
+	h = bucket.headers["/evidence/"+filepath.Base(held.FilePath)]
+	if h.Get("X-Amz-Object-Lock-Legal-Hold") != "ON" || h.Get("X-Amz-Object-Lock-Mode") != "" || !held.Replica.LegalHold {
+		t.Errorf("Expected indefinitely retained evidence under legal hold, got %v", h)
+	}
+}
+
+func TestReplicaObjectLockConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.Replica = &ReportDestination{Type: "directory", Path: "/srv/replica", ObjectLock: true}
+	cfg.Reports.Schedules = []ReportSchedule{{Name: "audit", Type: ScheduledAuditExport, Frequency: "weekly",
+		Destinations: []ReportDestination{{Type: "s3", Bucket: "reports", Region: "us-east-1", ObjectLock: true}}}}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "storage.replica.object_lock requires an s3 replica") ||
+		!strings.Contains(err.Error(), "object_lock is only supported for storage.replica") {
+		t.Errorf("Expected object lock problems, got %v", err)
+	}
+}
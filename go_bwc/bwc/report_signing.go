@@ -0,0 +1,385 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// errReportSigningDisabled is returned when no agency signing certificate is configured
+var errReportSigningDisabled = errors.New("report signing is not configured")
+
+// CMS object identifiers (RFC 5652) for detached SignedData over SHA-256
+var (
+	oidData            = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidAttrContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrDigest      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidAttrSigningTime = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256          = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSAEncryption   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidSHA256WithRSA   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+)
+
+const (
+	asn1TagSet          = 17
+	asn1ClassContextTag = 2
+)
+
+// Context-specific fields are tagged by hand: encoding/asn1 ignores struct
+// tags when marshalling a RawValue
+type cmsContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type cmsSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo cmsEncapsulatedContent
+	Certificates     asn1.RawValue
+	SignerInfos      []cmsSignerInfo `asn1:"set"`
+}
+
+// cmsEncapsulatedContent has no content: the signature is detached
+type cmsEncapsulatedContent struct {
+	ContentType asn1.ObjectIdentifier
+}
+
+type cmsIssuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type cmsSignerInfo struct {
+	Version            int
+	SID                cmsIssuerAndSerial
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+type cmsAttribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// reportSigner signs documents with the agency's X.509 certificate. Chain
+// holds the certificate followed by any intermediates, all embedded in each
+// signature so it can be checked against the agency's root alone.
+type reportSigner struct {
+	cert  *x509.Certificate
+	chain []*x509.Certificate
+	key   crypto.Signer
+}
+
+// loadReportSigner reads a PEM certificate chain, the agency certificate
+// first, and the PEM private key that belongs to it. RSA and ECDSA keys are
+// supported.
+func loadReportSigner(certFile, keyFile string) (*reportSigner, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load report signing certificate: %w", err)
+	}
+	return newReportSigner(pair)
+}
+
+func newReportSigner(pair tls.Certificate) (*reportSigner, error) {
+	signer := &reportSigner{}
+	for _, der := range pair.Certificate {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report signing certificate: %w", err)
+		}
+		signer.chain = append(signer.chain, cert)
+	}
+	signer.cert = signer.chain[0]
+	// The key may be a handle to one in a hardware security module, so it is
+	// told apart by its public half
+	key, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("report signing key must be RSA or ECDSA")
+	}
+	switch key.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		signer.key = key
+	default:
+		return nil, errors.New("report signing key must be RSA or ECDSA")
+	}
+	return signer, nil
+}
+
+// signatureAlgorithm is the CMS signature algorithm for the signer's key
+func (s *reportSigner) signatureAlgorithm() pkix.AlgorithmIdentifier {
+	if _, ok := s.key.Public().(*ecdsa.PublicKey); ok {
+		return pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256}
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidRSAEncryption, Parameters: asn1.NullRawValue}
+}
+
+// sign returns a DER-encoded PKCS#7 (CMS) detached signature over content,
+// verifiable with `openssl cms -verify -binary -inform DER`
+func (s *reportSigner) sign(content []byte, signingTime time.Time) ([]byte, error) {
+	digest := sha256.Sum256(content)
+	attrs, err := cmsSignedAttributes(digest[:], signingTime)
+	if err != nil {
+		return nil, err
+	}
+	// The signature covers the attributes encoded as a SET, not as the
+	// implicitly tagged field they are stored in
+	attrsDigest := sha256.Sum256(attrs.FullBytes)
+	signature, err := s.key.Sign(rand.Reader, attrsDigest[:], crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign report: %w", err)
+	}
+
+	var certs []byte
+	for _, cert := range s.chain {
+		certs = append(certs, cert.Raw...)
+	}
+	sha256Algorithm := pkix.AlgorithmIdentifier{Algorithm: oidSHA256}
+	signedData, err := asn1.Marshal(cmsSignedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{sha256Algorithm},
+		EncapContentInfo: cmsEncapsulatedContent{ContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: certs},
+		SignerInfos: []cmsSignerInfo{{
+			Version:            1,
+			SID:                cmsIssuerAndSerial{Issuer: asn1.RawValue{FullBytes: s.cert.RawIssuer}, Serial: s.cert.SerialNumber},
+			DigestAlgorithm:    sha256Algorithm,
+			SignedAttrs:        asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: attrs.Bytes},
+			SignatureAlgorithm: s.signatureAlgorithm(),
+			Signature:          signature,
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signature: %w", err)
+	}
+	return asn1.Marshal(cmsContentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: signedData},
+	})
+}
+
+// cmsSignedAttributes encodes the content type, message digest and signing
+// time attributes as a DER SET, sorted as DER requires
+func cmsSignedAttributes(digest []byte, signingTime time.Time) (asn1.RawValue, error) {
+	values := []struct {
+		oid   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{oidAttrContentType, oidData},
+		{oidAttrDigest, digest},
+		{oidAttrSigningTime, signingTime.UTC()},
+	}
+	encoded := make([][]byte, 0, len(values))
+	for _, v := range values {
+		value, err := asn1.Marshal(v.value)
+		if err != nil {
+			return asn1.RawValue{}, fmt.Errorf("failed to encode signed attributes: %w", err)
+		}
+		attr, err := asn1.Marshal(cmsAttribute{
+			Type:   v.oid,
+			Values: asn1.RawValue{Tag: asn1TagSet, IsCompound: true, Bytes: value},
+		})
+		if err != nil {
+			return asn1.RawValue{}, fmt.Errorf("failed to encode signed attributes: %w", err)
+		}
+		encoded = append(encoded, attr)
+	}
+	sort.Slice(encoded, func(i, j int) bool { return bytes.Compare(encoded[i], encoded[j]) < 0 })
+
+	set, err := asn1.Marshal(asn1.RawValue{Tag: asn1TagSet, IsCompound: true, Bytes: bytes.Join(encoded, nil)})
+	if err != nil {
+		return asn1.RawValue{}, fmt.Errorf("failed to encode signed attributes: %w", err)
+	}
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(set, &raw); err != nil {
+		return asn1.RawValue{}, err
+	}
+	return raw, nil
+}
+
+// ReportSignature describes a verified detached report signature
+type ReportSignature struct {
+	Signer      string            `json:"signer"`
+	Certificate *x509.Certificate `json:"-"`
+	SignedAt    time.Time         `json:"signed_at"`
+	// Fingerprint is the SHA-256 of the signing certificate
+	Fingerprint string `json:"fingerprint"`
+}
+
+// VerifyReportSignature checks a detached PKCS#7 signature made by SignDocument
+// against content. When roots is non-nil the signing certificate must chain
+// to one of them as of the signing time, so a report stays verifiable after
+// the certificate expires.
+func VerifyReportSignature(content, signature []byte, roots *x509.CertPool) (*ReportSignature, error) {
+	var info cmsContentInfo
+	if rest, err := asn1.Unmarshal(signature, &info); err != nil || len(rest) > 0 {
+		return nil, errors.New("signature is not a DER-encoded PKCS#7 structure")
+	}
+	if !info.ContentType.Equal(oidSignedData) || info.Content.Class != asn1ClassContextTag || info.Content.Tag != 0 {
+		return nil, errors.New("signature is not PKCS#7 signed data")
+	}
+	var sd cmsSignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("invalid signed data: %w", err)
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("expected one signer, found %d", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+	if !si.DigestAlgorithm.Algorithm.Equal(oidSHA256) {
+		return nil, errors.New("only SHA-256 signatures are supported")
+	}
+
+	if sd.Certificates.Class != asn1ClassContextTag || si.SignedAttrs.Class != asn1ClassContextTag {
+		return nil, errors.New("signature must include its certificate and signed attributes")
+	}
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedded certificate: %w", err)
+	}
+	var cert *x509.Certificate
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, si.SID.Issuer.FullBytes) && c.SerialNumber.Cmp(si.SID.Serial) == 0 {
+			cert = c
+			break
+		}
+	}
+	if cert == nil {
+		return nil, errors.New("signing certificate is not included in the signature")
+	}
+
+	// Re-encode the implicitly tagged attributes as the SET that was signed
+	attrs, err := asn1.Marshal(asn1.RawValue{Tag: asn1TagSet, IsCompound: true, Bytes: si.SignedAttrs.Bytes})
+	if err != nil {
+		return nil, err
+	}
+	var digest []byte
+	var signedAt time.Time
+	for rest := si.SignedAttrs.Bytes; len(rest) > 0; {
+		var attr cmsAttribute
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return nil, fmt.Errorf("invalid signed attributes: %w", err)
+		}
+		switch {
+		case attr.Type.Equal(oidAttrDigest):
+			_, err = asn1.Unmarshal(attr.Values.Bytes, &digest)
+		case attr.Type.Equal(oidAttrSigningTime):
+			_, err = asn1.Unmarshal(attr.Values.Bytes, &signedAt)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid signed attribute %s: %w", attr.Type, err)
+		}
+	}
+	sum := sha256.Sum256(content)
+	if digest == nil || !bytes.Equal(digest, sum[:]) {
+		return nil, errors.New("document does not match its signature")
+	}
+
+	var algorithm x509.SignatureAlgorithm
+	switch {
+	case si.SignatureAlgorithm.Algorithm.Equal(oidRSAEncryption), si.SignatureAlgorithm.Algorithm.Equal(oidSHA256WithRSA):
+		algorithm = x509.SHA256WithRSA
+	case si.SignatureAlgorithm.Algorithm.Equal(oidECDSAWithSHA256):
+		algorithm = x509.ECDSAWithSHA256
+	default:
+		return nil, fmt.Errorf("unsupported signature algorithm %s", si.SignatureAlgorithm.Algorithm)
+	}
+	if err := cert.CheckSignature(algorithm, attrs, si.Signature); err != nil {
+		return nil, fmt.Errorf("signature is invalid: %w", err)
+	}
+
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs {
+			if c != cert {
+				intermediates.AddCert(c)
+			}
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   signedAt,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		if _, err := cert.Verify(opts); err != nil {
+			return nil, fmt.Errorf("signing certificate is not trusted: %w", err)
+		}
+	}
+
+	fingerprint := sha256.Sum256(cert.Raw)
+	return &ReportSignature{
+		Signer:      cert.Subject.String(),
+		Certificate: cert,
+		SignedAt:    signedAt,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+	}, nil
+}
+
+// ReportSigningCertificate returns the agency certificate that signs reports,
+// or nil when report signing is not configured
+func (bwc *BWCSystem) ReportSigningCertificate() *x509.Certificate {
+	if bwc.reportSigner == nil {
+		return nil
+	}
+	return bwc.reportSigner.cert
+}
+
+// ReportSigningChainPEM returns the agency certificate and its intermediates
+// as PEM, to be handed out alongside signed documents
+func (bwc *BWCSystem) ReportSigningChainPEM() ([]byte, error) {
+	if bwc.reportSigner == nil {
+		return nil, errReportSigningDisabled
+	}
+	var b bytes.Buffer
+	for _, cert := range bwc.reportSigner.chain {
+		pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return b.Bytes(), nil
+}
+
+// SignDocument returns a detached PKCS#7 signature over a generated report or
+// certificate, made with the agency's X.509 certificate. name identifies the
+// document in the audit log along with its SHA-256, so the agency can later
+// confirm what it signed.
+func (bwc *BWCSystem) SignDocument(name string, content []byte, userID string) ([]byte, error) {
+	return bwc.SignDocumentContext(context.Background(), name, content, userID)
+}
+
+// SignDocumentContext signs like SignDocument, recording the principal ctx
+// carries as the signer when it has one
+func (bwc *BWCSystem) SignDocumentContext(ctx context.Context, name string, content []byte, userID string) ([]byte, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermExport, "Document signing", ""); err != nil {
+		return nil, err
+	}
+	if bwc.reportSigner == nil {
+		return nil, errReportSigningDisabled
+	}
+	signature, err := bwc.reportSigner.sign(content, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(content)
+	bwc.logAuditAs(auth, userID, "SIGN_DOCUMENT", "", fmt.Sprintf("Signed %s (SHA-256 %s) as %s", name, hex.EncodeToString(sum[:]), bwc.reportSigner.cert.Subject), "")
+	return signature, nil
+}
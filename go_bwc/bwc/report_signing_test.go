@@ -0,0 +1,244 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestAgencyCertificate issues an agency signing certificate for key
+// from a throwaway root, writes the certificate and key as PEM files to dir
+// and returns their paths with a pool holding the root
+func writeTestAgencyCertificate(t *testing.T, dir string, key crypto.Signer) (string, string, *x509.CertPool) {
+	t.Helper()
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate root key: %v", err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Agency Root CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, rootKey.Public(), rootKey)
+	if err != nil {
+		t.Fatalf("failed to create root certificate: %v", err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "Springfield PD Evidence Unit", Organization: []string{"Springfield PD"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(12 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, root, key.Public(), rootKey)
+	if err != nil {
+		t.Fatalf("failed to create agency certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "agency.pem")
+	keyFile := filepath.Join(dir, "agency.key")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	return certFile, keyFile, roots
+}
+
+func TestReportSignatureRoundTrip(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+
+	for name, key := range map[string]crypto.Signer{"rsa": rsaKey, "ecdsa": ecKey} {
+		t.Run(name, func(t *testing.T) {
+			certFile, keyFile, roots := writeTestAgencyCertificate(t, t.TempDir(), key)
+			signer, err := loadReportSigner(certFile, keyFile)
+			if err != nil {
+				t.Fatalf("loadReportSigner failed: %v", err)
+			}
+
+			report := []byte("CHAIN OF CUSTODY REPORT\nCase: CASE-SIG-1\n")
+			signedAt := time.Now().Truncate(time.Second)
+			signature, err := signer.sign(report, signedAt)
+			if err != nil {
+				t.Fatalf("sign failed: %v", err)
+			}
+
+			verified, err := VerifyReportSignature(report, signature, roots)
+			if err != nil {
+				t.Fatalf("VerifyReportSignature failed: %v", err)
+			}
+			if !strings.Contains(verified.Signer, "Springfield PD Evidence Unit") || !verified.SignedAt.Equal(signedAt) {
+				t.Errorf("unexpected signature details: %+v", verified)
+			}
+
+			altered := bytes.Replace(report, []byte("SIG-1"), []byte("SIG-2"), 1)
+			if _, err := VerifyReportSignature(altered, signature, roots); err == nil {
+				t.Error("expected an altered report to fail verification")
+			}
+			if _, err := VerifyReportSignature(report, signature, x509.NewCertPool()); err == nil {
+				t.Error("expected an untrusted certificate to be refused")
+			}
+			if _, err := VerifyReportSignature(report, signature, nil); err != nil {
+				t.Errorf("expected the signature to verify without roots: %v", err)
+			}
+			if _, err := VerifyReportSignature(report, []byte("not a signature"), roots); err == nil {
+				t.Error("expected garbage to be refused")
+			}
+		})
+	}
+}
+
+func TestSignedReportDownload(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	if _, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-SIG-1", "OFF-1117", "", "", nil); err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	resp := authGet(t, server, "/api/reports/CASE-SIG-1?signed=true")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Errorf("expected 501 without a signing certificate, got %d", resp.StatusCode)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	certFile, keyFile, roots := writeTestAgencyCertificate(t, t.TempDir(), key)
+	if system.reportSigner, err = loadReportSigner(certFile, keyFile); err != nil {
+		t.Fatalf("loadReportSigner failed: %v", err)
+	}
+
+	resp = authGet(t, server, "/api/reports/CASE-SIG-1?signed=true&format=html")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/zip" {
+		t.Fatalf("expected a zip, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	report, signature := files["report-CASE-SIG-1.html"], files["report-CASE-SIG-1.html.p7s"]
+	if report == nil || signature == nil || files["agency-certificate.pem"] == nil {
+		t.Fatalf("expected the report, its signature and the certificate, got %d files", len(files))
+	}
+	if _, err := VerifyReportSignature(report, signature, roots); err != nil {
+		t.Errorf("signed report did not verify: %v", err)
+	}
+
+	signed := 0
+	for _, log := range system.GetAuditLogs("", "CUS-001") {
+		if log.Action == "SIGN_DOCUMENT" && strings.Contains(log.Details, "report-CASE-SIG-1.html") {
+			signed++
+		}
+	}
+	if signed != 1 {
+		t.Errorf("expected 1 SIGN_DOCUMENT audit entry, got %d", signed)
+	}
+}
+
+func TestSignedTestimonyPackage(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	certFile, keyFile, _ := writeTestAgencyCertificate(t, t.TempDir(), key)
+	if system.reportSigner, err = loadReportSigner(certFile, keyFile); err != nil {
+		t.Fatalf("loadReportSigner failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-SIG-2", "OFF-1118", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	manifest, err := system.ExportTestimonyPackage(ev.ID, filepath.Join(t.TempDir(), "testimony.zip"), "OFF-1118", "Pat Officer", nil)
+	if err != nil {
+		t.Fatalf("ExportTestimonyPackage failed: %v", err)
+	}
+	found := false
+	for _, f := range manifest.Files {
+		found = found || f.Name == "custody-certificate.pdf.p7s"
+	}
+	if !found {
+		t.Error("expected the custody certificate to be signed")
+	}
+}
+
+func TestReportSigningConfig(t *testing.T) {
+	dir := t.TempDir()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	certFile, keyFile, _ := writeTestAgencyCertificate(t, dir, key)
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(dir, "storage")
+	cfg.Security.ReportSigningCertFile = certFile
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "must be set together") {
+		t.Errorf("expected a certificate without a key to be refused, got %v", err)
+	}
+
+	cfg.Security.ReportSigningKeyFile = keyFile
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	if cert := system.ReportSigningCertificate(); cert == nil || cert.Subject.CommonName != "Springfield PD Evidence Unit" {
+		t.Errorf("expected the agency certificate to be loaded, got %v", cert)
+	}
+
+	// A key that does not belong to the certificate is refused
+	other, _, _ := writeTestAgencyCertificate(t, t.TempDir(), key)
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, mismatched, _ := writeTestAgencyCertificate(t, t.TempDir(), otherKey)
+	cfg.Security.ReportSigningCertFile, cfg.Security.ReportSigningKeyFile = other, mismatched
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil {
+		t.Error("expected a mismatched key to be refused")
+	}
+}
@@ -0,0 +1,298 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReportProfile controls which sections a case report includes for its audience
+type ReportProfile string
+
+const (
+	ReportProfileInternal ReportProfile = "internal"
+	ReportProfileCourt    ReportProfile = "court"
+	ReportProfilePublic   ReportProfile = "public"
+)
+
+// reportSections lists the optional sections of a case report
+type reportSections struct {
+	FilePaths      bool
+	OfficerDetails bool
+	Notes          bool
+	Reviews        bool
+}
+
+// reportProfiles maps each profile to its sections. Each profile is a strict
+// subset of the one before it: internal > court > public.
+var reportProfiles = map[ReportProfile]reportSections{
+	ReportProfileInternal: {FilePaths: true, OfficerDetails: true, Notes: true, Reviews: true},
+	ReportProfileCourt:    {OfficerDetails: true, Notes: true},
+	ReportProfilePublic:   {},
+}
+
+// reportProfileRank orders profiles from least to most disclosure
+var reportProfileRank = map[ReportProfile]int{
+	ReportProfilePublic:   0,
+	ReportProfileCourt:    1,
+	ReportProfileInternal: 2,
+}
+
+// ParseReportProfile converts a profile name to a ReportProfile
+func ParseReportProfile(name string) (ReportProfile, error) {
+	profile := ReportProfile(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := reportProfiles[profile]; !ok {
+		return "", fmt.Errorf("unknown report profile %q (expected internal, court or public)", name)
+	}
+	return profile, nil
+}
+
+// Permits reports whether a holder of profile p may request a report with profile other
+func (p ReportProfile) Permits(other ReportProfile) bool {
+	rank, ok := reportProfileRank[p]
+	if !ok {
+		return false
+	}
+	otherRank, ok := reportProfileRank[other]
+	return ok && otherRank <= rank
+}
+
+// caseEvidence returns copies of the evidence in a case that the principal
+// ctx carries may see, oldest first, so that reports can be rendered without
+// holding the system lock. Restricted evidence left out is audited.
+func (bwc *BWCSystem) caseEvidence(ctx context.Context, caseNumber string) ([]Evidence, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	reader := readerFromContext(ctx)
+	evidence := make([]Evidence, 0)
+	for _, ev := range bwc.evidenceDB.Search(nil) {
+		if ev.CaseNumber == caseNumber && bwc.rejectIfRestrictedLocked(ev, reader, "Case report") == nil {
+			evidence = append(evidence, copyEvidence(ev))
+		}
+	}
+	if len(evidence) == 0 {
+		return nil, errors.New("no evidence found for case")
+	}
+	sort.Slice(evidence, func(i, j int) bool {
+		if evidence[i].Timestamp.Equal(evidence[j].Timestamp) {
+			return evidence[i].ID < evidence[j].ID
+		}
+		return evidence[i].Timestamp.Before(evidence[j].Timestamp)
+	})
+
+	return evidence, nil
+}
+
+// copyEvidence copies an evidence record including its history slices
+func copyEvidence(ev *Evidence) Evidence {
+	c := *ev
+	c.Tags = append([]string(nil), ev.Tags...)
+	c.ChainOfCustody = append([]CustodyEntry(nil), ev.ChainOfCustody...)
+	c.IntegrityChecks = append([]IntegrityCheck(nil), ev.IntegrityChecks...)
+	c.SealHistory = append([]SealEvent(nil), ev.SealHistory...)
+	c.SealedChecks = append([]IntegrityCheck(nil), ev.SealedChecks...)
+	c.Reviews = copyReviews(ev.Reviews)
+	if ev.Place != nil {
+		place := *ev.Place
+		c.Place = &place
+	}
+	if ev.Video != nil {
+		video := *ev.Video
+		c.Video = &video
+	}
+	if ev.Audio != nil {
+		audio := *ev.Audio
+		audio.Waveform = append([]float64(nil), ev.Audio.Waveform...)
+		c.Audio = &audio
+	}
+	if ev.Photo != nil {
+		photo := *ev.Photo
+		c.Photo = &photo
+	}
+	if ev.Document != nil {
+		document := *ev.Document
+		c.Document = &document
+	}
+	if ev.Encryption != nil {
+		enc := *ev.Encryption
+		c.Encryption = &enc
+	}
+	if ev.Seal != nil {
+		seal := *ev.Seal
+		c.Seal = &seal
+	}
+	if ev.Digests != nil {
+		c.Digests = make(map[HashAlgorithm]string, len(ev.Digests))
+		for alg, sum := range ev.Digests {
+			c.Digests[alg] = sum
+		}
+	}
+	if ev.ACL != nil {
+		acl := *ev.ACL
+		acl.Users = append([]string(nil), ev.ACL.Users...)
+		c.ACL = &acl
+	}
+	if ev.Forensics != nil {
+		forensics := *ev.Forensics
+		forensics.Tools = append([]ForensicTool(nil), ev.Forensics.Tools...)
+		if ev.Forensics.OriginalMedia != nil {
+			media := *ev.Forensics.OriginalMedia
+			forensics.OriginalMedia = &media
+		}
+		c.Forensics = &forensics
+	}
+	if ev.Clock != nil {
+		clock := *ev.Clock
+		c.Clock = &clock
+	}
+	return c
+}
+
+// ReportOptions selects the audience and language of a report. An empty
+// Profile selects the internal profile and an empty Locale selects DefaultLocale.
+type ReportOptions struct {
+	Profile ReportProfile
+	Locale  Locale
+}
+
+// resolve returns the sections and translator the options select
+func (o ReportOptions) resolve() (ReportProfile, reportSections, *translator, error) {
+	profile := o.Profile
+	if profile == "" {
+		profile = ReportProfileInternal
+	}
+	sections, ok := reportProfiles[profile]
+	if !ok {
+		return "", reportSections{}, nil, fmt.Errorf("unknown report profile %q", profile)
+	}
+
+	locale := o.Locale
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	if _, ok := reportMessages[locale]; !ok {
+		return "", reportSections{}, nil, fmt.Errorf("unsupported locale %q", locale)
+	}
+
+	return profile, sections, newTranslator(locale), nil
+}
+
+// GenerateProfiledReport generates an English case report containing only the
+// sections the given profile allows
+func (bwc *BWCSystem) GenerateProfiledReport(caseNumber string, profile ReportProfile) (string, error) {
+	if profile == "" {
+		return "", errors.New("report profile is required")
+	}
+	return bwc.GenerateCaseReport(caseNumber, ReportOptions{Profile: profile})
+}
+
+// GenerateCaseReport generates a plain-text case report for the given profile and locale
+func (bwc *BWCSystem) GenerateCaseReport(caseNumber string, opts ReportOptions) (string, error) {
+	return bwc.GenerateCaseReportContext(systemContext(context.Background()), caseNumber, opts)
+}
+
+// GenerateCaseReportContext generates a plain-text case report of the
+// evidence the principal ctx carries may see
+func (bwc *BWCSystem) GenerateCaseReportContext(ctx context.Context, caseNumber string, opts ReportOptions) (string, error) {
+	profile, sections, tr, err := opts.resolve()
+	if err != nil {
+		return "", err
+	}
+
+	evidence, err := bwc.caseEvidence(ctx, caseNumber)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString(tr.T("report.title") + "\n")
+	fmt.Fprintf(&b, "%s: %s\n", tr.T("report.case_number"), caseNumber)
+	fmt.Fprintf(&b, "%s: %s\n", tr.T("report.profile"), profile)
+	fmt.Fprintf(&b, "%s: %s\n", tr.T("report.generated"), time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "%s: %d\n\n", tr.T("report.total_items"), len(evidence))
+
+	for _, ev := range evidence {
+		fmt.Fprintf(&b, "%s: %s", tr.T("report.evidence_id"), ev.ID)
+		if ev.Seal != nil {
+			fmt.Fprintf(&b, " [%s]", tr.T("report.sealed"))
+		}
+		b.WriteString("\n")
+		if ev.Seal != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.sealed"),
+				tr.T("report.sealed_detail", ev.Seal.Authority, ev.Seal.SealedAt.Format(time.RFC3339)))
+		}
+		if sections.OfficerDetails {
+			fmt.Fprintf(&b, "  %s: %s (%s)\n", tr.T("report.officer"), ev.OfficerName, ev.OfficerID)
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.timestamp"), ev.Timestamp.Format(time.RFC3339))
+		fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.location"), ev.Location)
+		fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.media_type"), tr.MediaType(mediaTypeOf(&ev)))
+		if ev.Video != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.video"), tr.Video(ev.Video))
+		}
+		if ev.Audio != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.audio"), tr.Audio(ev.Audio))
+		}
+		if ev.Photo != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.photo"), tr.Photo(ev.Photo))
+			if ev.Photo.TimeDiscrepancy != "" {
+				fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.time_discrepancy"), ev.Photo.TimeDiscrepancy)
+			}
+		}
+		if ev.Document != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.document"), tr.Document(ev.Document))
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.status"), tr.Status(ev.Status))
+		if sections.FilePaths {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.file_path"), ev.FilePath)
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.file_hash"), ev.FileHash)
+		fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.file_size"), tr.T("report.bytes", ev.FileSize))
+		if f := ev.Forensics; f != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.acquisition"), tr.Acquisition(f, sections.OfficerDetails))
+			if f.OriginalMedia != nil {
+				fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.original_media"), tr.OriginalMedia(f.OriginalMedia))
+			}
+			if f.WriteBlocker != "" {
+				fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.write_blocker"), f.WriteBlocker)
+			}
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.tools"), tr.Tools(f.Tools))
+		}
+		if ev.Clock != nil {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.clock"), tr.Clock(ev.Clock))
+		}
+		fmt.Fprintf(&b, "  %s: %d\n", tr.T("report.integrity"), len(ev.IntegrityChecks))
+		fmt.Fprintf(&b, "  %s: %d\n", tr.T("report.custody_entries"), len(ev.ChainOfCustody))
+		if len(ev.SealHistory) > 0 {
+			fmt.Fprintf(&b, "  %s:\n", tr.T("report.seal_history", len(ev.SealHistory)))
+			for _, event := range ev.SealHistory {
+				fmt.Fprintf(&b, "    %s %s - %s\n", event.Timestamp.Format(time.RFC3339), tr.Action(event.Action), event.Authority)
+			}
+		}
+		if sections.Notes && ev.Notes != "" {
+			fmt.Fprintf(&b, "  %s: %s\n", tr.T("report.notes"), ev.Notes)
+		}
+		if sections.Reviews && len(ev.Reviews) > 0 {
+			fmt.Fprintf(&b, "  %s:\n", tr.T("report.reviews", len(ev.Reviews)))
+			for _, review := range ev.Reviews {
+				fmt.Fprintf(&b, "    %s %s - %s", review.ID, tr.ReviewReason(review.Reason), tr.ReviewState(review.State))
+				if review.Supervisor != "" {
+					fmt.Fprintf(&b, " (%s)", review.Supervisor)
+				}
+				b.WriteString("\n")
+				if review.State == ReviewClosed {
+					fmt.Fprintf(&b, "      %s: %s - %s\n", tr.T("report.outcome"), tr.ReviewOutcome(review.Outcome), review.Findings)
+				}
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "%s\n%s\n", tr.T("report.certificate_heading"), tr.T("report.certificate", caseNumber))
+
+	return b.String(), nil
+}
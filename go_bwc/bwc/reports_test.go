@@ -0,0 +1,59 @@
+package bwc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateProfiledReport(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-PROFILE-001", "OFF-123", "Officer Test", "Test Location", nil)
+	system.UpdateStatus(evidence.ID, "OFF-123", StatusProcessing, "Witness statement pending")
+
+	tests := []struct {
+		profile ReportProfile
+		present []string
+		absent  []string
+	}{
+		{ReportProfileInternal, []string{evidence.FilePath, "Officer Test", "Witness statement pending"}, nil},
+		{ReportProfileCourt, []string{"Officer Test", "OFF-123", "Witness statement pending"}, []string{evidence.FilePath}},
+		{ReportProfilePublic, []string{evidence.FileHash}, []string{evidence.FilePath, "Officer:", "Officer Test", "Witness statement pending"}},
+	}
+
+	for _, tt := range tests {
+		report, err := system.GenerateProfiledReport("CASE-PROFILE-001", tt.profile)
+		if err != nil {
+			t.Fatalf("GenerateProfiledReport(%s) failed: %v", tt.profile, err)
+		}
+		for _, s := range tt.present {
+			if !strings.Contains(report, s) {
+				t.Errorf("%s report should contain %q", tt.profile, s)
+			}
+		}
+		for _, s := range tt.absent {
+			if strings.Contains(report, s) {
+				t.Errorf("%s report should not contain %q", tt.profile, s)
+			}
+		}
+	}
+
+	if _, err := system.GenerateProfiledReport("CASE-PROFILE-001", "press"); err == nil {
+		t.Error("Expected error for unknown profile")
+	}
+}
+
+func TestReportProfilePermits(t *testing.T) {
+	if !ReportProfileInternal.Permits(ReportProfilePublic) || !ReportProfileCourt.Permits(ReportProfileCourt) {
+		t.Error("Expected profiles to permit equal or less detailed profiles")
+	}
+	if ReportProfilePublic.Permits(ReportProfileCourt) || ReportProfileCourt.Permits(ReportProfileInternal) {
+		t.Error("Expected profiles to refuse more detailed profiles")
+	}
+
+	if profile, err := ParseReportProfile(" Court "); err != nil || profile != ReportProfileCourt {
+		t.Errorf("Expected court profile, got %q (%v)", profile, err)
+	}
+}
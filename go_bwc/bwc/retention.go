@@ -0,0 +1,190 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// RetentionItem describes when an evidence item reaches the end of its retention period
+type RetentionItem struct {
+	EvidenceID    string    `json:"evidence_id"`
+	CaseNumber    string    `json:"case_number"`
+	OfficerID     string    `json:"officer_id"`
+	Status        string    `json:"status"`
+	RetentionDays int       `json:"retention_days"`
+	ExpiresAt     time.Time `json:"expires_at"`
+	DaysRemaining int       `json:"days_remaining"`
+}
+
+// retentionExpiry returns the retention deadline for evidence, or false if it is kept indefinitely
+func (bwc *BWCSystem) retentionExpiry(evidence *Evidence) (time.Time, int, bool) {
+	return retentionExpiryUnder(bwc.config.RetentionPolicy(), evidence)
+}
+
+// retentionExpiryUnder returns the retention deadline for evidence under
+// policy, or false if it is kept indefinitely
+func retentionExpiryUnder(policy RetentionPolicy, evidence *Evidence) (time.Time, int, bool) {
+	days, indefinite := policy.RetentionFor(evidence.Tags)
+	if indefinite {
+		return time.Time{}, 0, false
+	}
+
+	return evidence.CreatedAt.AddDate(0, 0, days), days, true
+}
+
+// RetentionDue lists evidence whose retention period ends within the given window of now,
+// including items already past expiry. Deleted and indefinitely retained items are skipped.
+func (bwc *BWCSystem) RetentionDue(now time.Time, within time.Duration) []RetentionItem {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	cutoff := now.Add(within)
+	items := make([]RetentionItem, 0)
+
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted {
+			continue
+		}
+
+		expiresAt, days, ok := bwc.retentionExpiry(evidence)
+		if !ok || expiresAt.After(cutoff) {
+			continue
+		}
+
+		items = append(items, RetentionItem{
+			EvidenceID:    evidence.ID,
+			CaseNumber:    evidence.CaseNumber,
+			OfficerID:     evidence.OfficerID,
+			Status:        string(evidence.Status),
+			RetentionDays: days,
+			ExpiresAt:     expiresAt,
+			DaysRemaining: int(expiresAt.Sub(now).Hours() / 24),
+		})
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].ExpiresAt.Before(items[j].ExpiresAt)
+	})
+
+	return items
+}
+
+// PurgeExpired deletes the stored files of evidence whose retention period
+// ended before now and marks it DELETED with a PURGED custody entry. Sealed
+// and checked-out evidence is skipped. The record itself, its custody chain
+// and any replica copy are kept. With dryRun set nothing is changed and the
+// plan shows what would be. Under the two-person rule only a dry run is
+// allowed; RequestPurge queues the purge for a second user's approval.
+func (bwc *BWCSystem) PurgeExpired(now time.Time, userID string, dryRun bool) (*ChangePlan, error) {
+	if err := bwc.authorize(userID, PermDeleteEvidence, "Retention purge", ""); err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := bwc.requireNoApproval(userID, "Retention purge", ""); err != nil {
+			return nil, err
+		}
+		if err := bwc.beginOperation(opMutation); err != nil {
+			return nil, err
+		}
+		defer bwc.endOperation()
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	return bwc.purgeExpiredLocked(now, userID, dryRun, nil)
+}
+
+// purgeExpiredLocked purges as PurgeExpired does, limited to the items in
+// only when it is not nil. The caller must hold bwc.mu.
+func (bwc *BWCSystem) purgeExpiredLocked(now time.Time, userID string, dryRun bool, only map[string]bool) (*ChangePlan, error) {
+	expired := make([]*Evidence, 0)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted || (only != nil && !only[evidence.ID]) {
+			continue
+		}
+		if expiresAt, _, ok := bwc.retentionExpiry(evidence); ok && !expiresAt.After(now) {
+			expired = append(expired, evidence)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ID < expired[j].ID
+	})
+
+	plan := &ChangePlan{Operation: "retention purge", DryRun: dryRun, Items: make([]PlannedChange, 0)}
+	for _, evidence := range expired {
+		if reason := bwc.sealedSkipLocked(evidence, userID, "Retention purge", dryRun); reason != "" {
+			plan.skip(evidence.ID, reason)
+			continue
+		}
+		if checkout, out := bwc.checkouts[evidence.ID]; out {
+			plan.skip(evidence.ID, "checked out to "+checkout.CheckedOutTo)
+			continue
+		}
+
+		expiresAt, days, _ := bwc.retentionExpiry(evidence)
+		files, size := bwc.storedFiles(evidence)
+		plan.add(PlannedChange{
+			EvidenceID:    evidence.ID,
+			CaseNumber:    evidence.CaseNumber,
+			FromStatus:    evidence.Status,
+			ToStatus:      StatusDeleted,
+			Files:         files,
+			Bytes:         size,
+			CustodyAction: "PURGED",
+			Reason:        fmt.Sprintf("%d-day retention ended %s", days, expiresAt.Format("2006-01-02")),
+		})
+	}
+	if dryRun {
+		return plan, nil
+	}
+
+	var errs []error
+	purged := 0
+	for _, change := range plan.Items {
+		if err := bwc.purgeLocked(bwc.evidenceDB.Get(change.EvidenceID), userID, change); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", change.EvidenceID, err))
+			continue
+		}
+		purged++
+	}
+	bwc.logAudit(userID, "RETENTION_PURGE", "",
+		fmt.Sprintf("%d items purged (%d bytes), %d skipped, %d failed", purged, plan.TotalBytes, len(plan.Skipped), len(errs)), "")
+
+	return plan, errors.Join(errs...)
+}
+
+// purgeLocked removes evidence files and records the purge; the caller must hold bwc.mu
+func (bwc *BWCSystem) purgeLocked(evidence *Evidence, userID string, change PlannedChange) error {
+	for _, path := range change.Files {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	os.RemoveAll(bwc.derivedDir(evidence.ID))
+	bwc.textIndex.remove(evidence.ID)
+
+	entry := CustodyEntry{
+		Timestamp:    time.Now(),
+		FromOfficer:  userID,
+		ToOfficer:    userID,
+		Action:       "PURGED",
+		Purpose:      fmt.Sprintf("Retention purge: %s; %d files, %d bytes removed", change.Reason, len(change.Files), change.Bytes),
+		VerifiedHash: evidence.FileHash,
+	}
+	var err error
+	entry.EntryHash, err = custodyEntryHash(entry)
+	if err != nil {
+		return err
+	}
+	evidence.ChainOfCustody = append(evidence.ChainOfCustody, entry)
+
+	if err := bwc.markDeletedLocked(evidence, userID, entry.Purpose, nil); err != nil {
+		return err
+	}
+	bwc.logAudit(userID, "PURGE_EVIDENCE", evidence.ID, entry.Purpose, "")
+	return nil
+}
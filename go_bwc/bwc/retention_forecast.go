@@ -0,0 +1,161 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionForecast lists evidence whose retention ends within the next Days
+// days, by case and then by current custodian, so each custodian can decide
+// on disposition before the items are purged
+type RetentionForecast struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	Days        int            `json:"days"`
+	Through     time.Time      `json:"through"`
+	Items       int            `json:"items"`
+	Cases       []ForecastCase `json:"cases"`
+}
+
+// ForecastCase is one case's expiring evidence, by custodian
+type ForecastCase struct {
+	CaseNumber     string              `json:"case_number"`
+	EarliestExpiry time.Time           `json:"earliest_expiry"`
+	Custodians     []ForecastCustodian `json:"custodians"`
+}
+
+// ForecastCustodian is the expiring evidence one custodian holds in a case
+type ForecastCustodian struct {
+	Custodian string         `json:"custodian"`
+	Items     []ForecastItem `json:"items"`
+}
+
+// ForecastItem is an expiring item. Hold says why a purge would skip it.
+type ForecastItem struct {
+	RetentionItem
+	Hold string `json:"hold,omitempty"`
+}
+
+// retentionHoldLocked says why a purge would skip evidence, or returns "";
+// the caller must hold bwc.mu
+func (bwc *BWCSystem) retentionHoldLocked(evidence *Evidence) string {
+	if evidence.Seal != nil {
+		return "sealed under " + evidence.Seal.Authority
+	}
+	if checkout, out := bwc.checkouts[evidence.ID]; out {
+		return "checked out to " + checkout.CheckedOutTo
+	}
+	return ""
+}
+
+// RetentionForecast returns the evidence whose retention ends within days of
+// now, including items already past expiry, earliest case first
+func (bwc *BWCSystem) RetentionForecast(now time.Time, days int) (*RetentionForecast, error) {
+	if days < 1 {
+		return nil, errors.New("forecast days must be at least 1")
+	}
+	due := bwc.RetentionDue(now, time.Duration(days)*24*time.Hour)
+
+	forecast := &RetentionForecast{
+		GeneratedAt: now,
+		Days:        days,
+		Through:     now.AddDate(0, 0, days),
+		Items:       len(due),
+		Cases:       make([]ForecastCase, 0),
+	}
+
+	bwc.mu.RLock()
+	cases := make(map[string]*ForecastCase)
+	holders := make(map[string]map[string]*ForecastCustodian)
+	for _, item := range due {
+		evidence := bwc.evidenceDB.Get(item.EvidenceID)
+		if evidence == nil {
+			continue
+		}
+		fi := ForecastItem{RetentionItem: item, Hold: bwc.retentionHoldLocked(evidence)}
+
+		c := cases[item.CaseNumber]
+		if c == nil {
+			// Items arrive earliest first, so the first one sets the case's expiry
+			c = &ForecastCase{CaseNumber: item.CaseNumber, EarliestExpiry: item.ExpiresAt}
+			cases[item.CaseNumber] = c
+			holders[item.CaseNumber] = make(map[string]*ForecastCustodian)
+		}
+		custodian := currentCustodian(evidence)
+		h := holders[item.CaseNumber][custodian]
+		if h == nil {
+			h = &ForecastCustodian{Custodian: custodian}
+			holders[item.CaseNumber][custodian] = h
+		}
+		h.Items = append(h.Items, fi)
+	}
+	bwc.mu.RUnlock()
+
+	for caseNumber, c := range cases {
+		for _, h := range holders[caseNumber] {
+			c.Custodians = append(c.Custodians, *h)
+		}
+		sort.Slice(c.Custodians, func(i, j int) bool {
+			return c.Custodians[i].Custodian < c.Custodians[j].Custodian
+		})
+		forecast.Cases = append(forecast.Cases, *c)
+	}
+	sort.Slice(forecast.Cases, func(i, j int) bool {
+		a, b := forecast.Cases[i], forecast.Cases[j]
+		if !a.EarliestExpiry.Equal(b.EarliestExpiry) {
+			return a.EarliestExpiry.Before(b.EarliestExpiry)
+		}
+		return a.CaseNumber < b.CaseNumber
+	})
+	return forecast, nil
+}
+
+// Text renders the forecast as a plain-text report
+func (f *RetentionForecast) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RETENTION EXPIRY FORECAST\n")
+	fmt.Fprintf(&b, "Generated: %s\n", f.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Window: %d days, through %s\n", f.Days, f.Through.Format("2006-01-02"))
+	fmt.Fprintf(&b, "Items: %d in %d case(s)\n", f.Items, len(f.Cases))
+
+	for _, c := range f.Cases {
+		fmt.Fprintf(&b, "\nCase %s (earliest %s)\n", c.CaseNumber, c.EarliestExpiry.Format("2006-01-02"))
+		for _, h := range c.Custodians {
+			fmt.Fprintf(&b, "  Custodian %s\n", h.Custodian)
+			for _, item := range h.Items {
+				when := fmt.Sprintf("expires %s (%d days)", item.ExpiresAt.Format("2006-01-02"), item.DaysRemaining)
+				if item.ExpiresAt.Before(f.GeneratedAt) {
+					when = fmt.Sprintf("expired %s", item.ExpiresAt.Format("2006-01-02"))
+				}
+				fmt.Fprintf(&b, "    %s  %s  %s", item.EvidenceID, item.Status, when)
+				if item.Hold != "" {
+					fmt.Fprintf(&b, "  [hold: %s]", item.Hold)
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+	return b.String()
+}
+
+// CSV renders the forecast with one row per item
+func (f *RetentionForecast) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"case_number", "custodian", "evidence_id", "officer_id", "status", "retention_days", "expires_at", "days_remaining", "hold"})
+	for _, c := range f.Cases {
+		for _, h := range c.Custodians {
+			for _, item := range h.Items {
+				w.Write([]string{c.CaseNumber, h.Custodian, item.EvidenceID, item.OfficerID, item.Status,
+					strconv.Itoa(item.RetentionDays), item.ExpiresAt.Format(time.RFC3339), strconv.Itoa(item.DaysRemaining), item.Hold})
+			}
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
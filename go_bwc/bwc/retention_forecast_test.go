@@ -0,0 +1,134 @@
+package bwc
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionForecast(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 30
+	system.config.Storage.RetentionRules = []RetentionRule{
+		{Tag: "felony", RetentionDays: 365},
+		{Tag: "homicide", Indefinite: true},
+	}
+
+	testFile := createTestFile(t, tmpDir)
+	ingest := func(caseNumber, officer string, createdDaysAgo int, tags []string) *Evidence {
+		ev, err := system.IngestEvidence(testFile, caseNumber, officer, "Officer Test", "Test Location", tags)
+		if err != nil {
+			t.Fatalf("Ingest failed: %v", err)
+		}
+		ev.CreatedAt = ev.CreatedAt.AddDate(0, 0, -createdDaysAgo)
+		return ev
+	}
+	soonest := ingest("CASE-RF-002", "OFF-1070", 28, nil)
+	held := ingest("CASE-RF-002", "OFF-1071", 25, nil)
+	other := ingest("CASE-RF-001", "OFF-1072", 20, nil)
+	overdue := ingest("CASE-RF-003", "OFF-1073", 40, nil)
+	ingest("CASE-RF-004", "OFF-1074", 0, nil)
+	ingest("CASE-RF-005", "OFF-1075", 29, []string{"homicide"})
+	ingest("CASE-RF-006", "OFF-1076", 29, []string{"felony"})
+
+	if _, err := system.CheckOutEvidence(held.ID, "OFF-1071", "LAB-001", "Forensic enhancement"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+
+	if _, err := system.RetentionForecast(time.Now(), 0); err == nil {
+		t.Error("Expected a zero-day forecast to be rejected")
+	}
+
+	forecast, err := system.RetentionForecast(time.Now(), 15)
+	if err != nil {
+		t.Fatalf("RetentionForecast failed: %v", err)
+	}
+	if forecast.Items != 4 || len(forecast.Cases) != 3 {
+		t.Fatalf("Expected 4 items in 3 cases, got %d in %+v", forecast.Items, forecast.Cases)
+	}
+	if forecast.Cases[0].CaseNumber != "CASE-RF-003" || forecast.Cases[0].Custodians[0].Items[0].EvidenceID != overdue.ID {
+		t.Errorf("Expected the overdue case first, got %+v", forecast.Cases[0])
+	}
+
+	c := forecast.Cases[1]
+	if c.CaseNumber != "CASE-RF-002" || len(c.Custodians) != 2 {
+		t.Fatalf("Expected CASE-RF-002 split across two custodians, got %+v", c)
+	}
+	if !c.EarliestExpiry.Equal(soonest.CreatedAt.AddDate(0, 0, 30)) {
+		t.Errorf("Expected the case's earliest expiry from %s, got %s", soonest.ID, c.EarliestExpiry)
+	}
+	if c.Custodians[0].Custodian != "LAB-001" || c.Custodians[0].Items[0].Hold != "checked out to LAB-001" {
+		t.Errorf("Expected the checked-out item held by LAB-001, got %+v", c.Custodians[0])
+	}
+	if c.Custodians[1].Custodian != "OFF-1070" || c.Custodians[1].Items[0].Hold != "" {
+		t.Errorf("Unexpected custodian: %+v", c.Custodians[1])
+	}
+	if forecast.Cases[2].Custodians[0].Items[0].EvidenceID != other.ID {
+		t.Errorf("Expected %s last, got %+v", other.ID, forecast.Cases[2])
+	}
+
+	text := forecast.Text()
+	for _, want := range []string{"Items: 4 in 3 case(s)", "Case CASE-RF-002", "Custodian LAB-001", "[hold: checked out to LAB-001]", "expired "} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in forecast:\n%s", want, text)
+		}
+	}
+	data, err := forecast.CSV()
+	if err != nil || strings.Count(string(data), "\n") != 5 || !strings.Contains(string(data), "CASE-RF-002,LAB-001,"+held.ID) {
+		t.Errorf("Unexpected CSV (%v):\n%s", err, data)
+	}
+}
+
+func TestScheduledRetentionForecast(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 30
+
+	testFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(testFile, "CASE-RF-010", "OFF-1077", "Officer Test", "Test Location", nil)
+
+	end := time.Now().Add(24 * time.Hour)
+	artifacts, err := system.GenerateScheduledReport(ReportSchedule{Name: "forecast", Type: ScheduledRetentionForecast}, end.AddDate(0, 0, -30), end)
+	if err != nil {
+		t.Fatalf("Scheduled forecast failed: %v", err)
+	}
+	if len(artifacts) != 1 || !strings.HasSuffix(artifacts[0].Name, ".txt") || !strings.Contains(string(artifacts[0].Data), "Window: 30 days") {
+		t.Errorf("Unexpected artifacts: %+v", artifacts)
+	}
+}
+
+func TestRetentionForecastEndpoint(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 30
+
+	testFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(testFile, "CASE-RF-020", "OFF-1078", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/retention/forecast?days=0")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for days=0, got %d", resp.StatusCode)
+	}
+
+	resp = authGet(t, server, "/api/retention/forecast?days=31")
+	var forecast RetentionForecast
+	json.NewDecoder(resp.Body).Decode(&forecast)
+	resp.Body.Close()
+	if forecast.Items != 1 || forecast.Cases[0].Custodians[0].Custodian != "OFF-1078" {
+		t.Errorf("Unexpected forecast: %+v", forecast)
+	}
+
+	resp = authGet(t, server, "/api/retention/forecast?format=csv")
+	resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Expected CSV, got %s", ct)
+	}
+
+	if logs := system.GetAuditLogs("", "CUS-001"); len(logs) != 2 || logs[0].Action != "RETENTION_FORECAST" {
+		t.Errorf("Expected RETENTION_FORECAST audit entries, got %+v", logs)
+	}
+}
@@ -0,0 +1,165 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimulateRetention(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 365
+	system.config.Storage.RetentionRules = nil
+
+	testFile := createTestFile(t, tmpDir)
+	ingest := func(caseNumber, officer string, createdDaysAgo int, tags []string) *Evidence {
+		ev, err := system.IngestEvidence(testFile, caseNumber, officer, "Officer Test", "Test Location", tags)
+		if err != nil {
+			t.Fatalf("Ingest failed: %v", err)
+		}
+		ev.CreatedAt = ev.CreatedAt.AddDate(0, 0, -createdDaysAgo)
+		return ev
+	}
+	expiredNow := ingest("CASE-RS-001", "OFF-1108", 400, nil)
+	shortened := ingest("CASE-RS-002", "OFF-1109", 100, nil)
+	kept := ingest("CASE-RS-003", "OFF-1110", 100, []string{"felony"})
+	held := ingest("CASE-RS-004", "OFF-1111", 100, nil)
+	soon := ingest("CASE-RS-005", "OFF-1112", 85, nil)
+	ingest("CASE-RS-006", "OFF-1113", 10, nil)
+
+	if _, err := system.CheckOutEvidence(held.ID, "OFF-1111", "LAB-001", "Enhancement"); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+
+	policy := RetentionPolicy{
+		RetentionDays:  90,
+		RetentionRules: []RetentionRule{{Tag: "felony", RetentionDays: 730}},
+	}
+	now := time.Now()
+	sim, err := system.SimulateRetention(policy, now, 0)
+	if err != nil {
+		t.Fatalf("SimulateRetention failed: %v", err)
+	}
+
+	ids := func(items []SimulatedItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.EvidenceID)
+		}
+		return out
+	}
+	if got := ids(sim.Purged); len(got) != 2 || got[0] != expiredNow.ID || got[1] != shortened.ID {
+		t.Errorf("expected %s and %s purged, oldest expiry first, got %v", expiredNow.ID, shortened.ID, got)
+	}
+	if len(sim.Protected) != 1 || sim.Protected[0].EvidenceID != held.ID || !strings.Contains(sim.Protected[0].Hold, "checked out to LAB-001") {
+		t.Errorf("expected %s protected by its checkout, got %+v", held.ID, sim.Protected)
+	}
+	if sim.ReclaimedBytes != 2*expiredNow.FileSize || sim.ProtectedBytes != held.FileSize {
+		t.Errorf("unexpected bytes: %d reclaimed, %d protected", sim.ReclaimedBytes, sim.ProtectedBytes)
+	}
+	if sim.Current.Purged != 1 || sim.Current.ReclaimedBytes != expiredNow.FileSize {
+		t.Errorf("expected the policy in force to purge one item, got %+v", sim.Current)
+	}
+	if len(sim.NewlyPurged) != 1 || sim.NewlyPurged[0] != shortened.ID || len(sim.NoLongerPurged) != 0 {
+		t.Errorf("unexpected comparison: newly %v, no longer %v", sim.NewlyPurged, sim.NoLongerPurged)
+	}
+	for _, item := range sim.Purged {
+		if item.EvidenceID == kept.ID {
+			t.Error("expected the felony rule to keep its item")
+		}
+	}
+
+	// A purge run ten days out also reaches the item expiring in five
+	later, err := system.SimulateRetention(policy, now, 10)
+	if err != nil {
+		t.Fatalf("SimulateRetention failed: %v", err)
+	}
+	if got := ids(later.Purged); len(got) != 3 || got[2] != soon.ID {
+		t.Errorf("expected %s to be purged ten days out, got %v", soon.ID, got)
+	}
+
+	// Nothing was changed
+	for _, ev := range []*Evidence{expiredNow, shortened} {
+		if ev.Status == StatusDeleted {
+			t.Errorf("%s was deleted by a simulation", ev.ID)
+		}
+		if _, err := os.Stat(ev.FilePath); err != nil {
+			t.Errorf("%s file removed by a simulation: %v", ev.ID, err)
+		}
+	}
+
+	if _, err := system.SimulateRetention(RetentionPolicy{RetentionDays: 0}, now, 0); err == nil || !strings.Contains(err.Error(), "retention_days must be positive") {
+		t.Errorf("expected an invalid policy to be refused, got %v", err)
+	}
+	if _, err := system.SimulateRetention(policy, now, -1); err == nil {
+		t.Error("expected negative days to be refused")
+	}
+
+	text := sim.Text()
+	for _, want := range []string{"Would purge: 2 items", "Protected by holds: 1 items", "(checked out to LAB-001)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in the text report:\n%s", want, text)
+		}
+	}
+}
+
+func TestRetentionSimulationAPI(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-RS-010", "OFF-1114", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	ev.CreatedAt = ev.CreatedAt.AddDate(0, 0, -60)
+
+	post := func(query, body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/retention/simulate"+query, bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", "Bearer "+testAPIToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post("", `{"retention_days": 30}`)
+	var sim RetentionSimulation
+	json.NewDecoder(resp.Body).Decode(&sim)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || len(sim.Purged) != 1 || sim.Purged[0].EvidenceID != ev.ID {
+		t.Errorf("expected %s in the simulated purge, got status %d and %+v", ev.ID, resp.StatusCode, sim.Purged)
+	}
+
+	resp = post("?format=text&days=5", `{"retention_days": 30}`)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected a text report, got %s", ct)
+	}
+	resp.Body.Close()
+
+	for query, body := range map[string]string{"": `{"retention_days": -1}`, "?days=x": `{"retention_days": 30}`, "?format=pdf": `{"retention_days": 30}`} {
+		resp := post(query, body)
+		resp.Body.Close()
+		if resp.StatusCode != 400 {
+			t.Errorf("%s %s: expected 400, got %d", query, body, resp.StatusCode)
+		}
+	}
+
+	if system.evidenceDB.Get(ev.ID).Status == StatusDeleted {
+		t.Error("the simulation purged evidence")
+	}
+	simulations := 0
+	for _, log := range system.GetAuditLogs("", "CUS-001") {
+		if log.Action == "RETENTION_SIMULATION" {
+			simulations++
+		}
+	}
+	if simulations != 2 {
+		t.Errorf("expected 2 RETENTION_SIMULATION audit entries, got %d", simulations)
+	}
+}
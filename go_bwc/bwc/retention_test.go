@@ -0,0 +1,122 @@
+package bwc
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetentionDue(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.config.Storage.RetentionDays = 365
+	system.config.Storage.RetentionRules = []RetentionRule{
+		{Tag: "homicide", Indefinite: true},
+	}
+
+	testFile := createTestFile(t, tmpDir)
+
+	expiring, _ := system.IngestEvidence(testFile, "CASE-RET-001", "OFF-001", "Officer A", "Location A", []string{"traffic-stop"})
+	recent, _ := system.IngestEvidence(testFile, "CASE-RET-002", "OFF-002", "Officer B", "Location B", []string{"traffic-stop"})
+	kept, _ := system.IngestEvidence(testFile, "CASE-RET-003", "OFF-003", "Officer C", "Location C", []string{"homicide"})
+
+	now := time.Now()
+	expiring.CreatedAt = now.AddDate(0, 0, -360)
+	kept.CreatedAt = now.AddDate(-5, 0, 0)
+
+	items := system.RetentionDue(now, 30*24*time.Hour)
+	if len(items) != 1 {
+		t.Fatalf("Expected 1 retention item, got %d", len(items))
+	}
+
+	if items[0].EvidenceID != expiring.ID {
+		t.Errorf("Expected %s, got %s", expiring.ID, items[0].EvidenceID)
+	}
+
+	if items[0].DaysRemaining != 4 && items[0].DaysRemaining != 5 {
+		t.Errorf("Expected about 5 days remaining, got %d", items[0].DaysRemaining)
+	}
+
+	// Deleted evidence is no longer subject to retention
+	if err := system.UpdateStatus(expiring.ID, "ADM-001", StatusArchived, "Archived"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if err := system.UpdateStatus(expiring.ID, "ADM-001", StatusDeleted, "Purged"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if items := system.RetentionDue(now, 30*24*time.Hour); len(items) != 0 {
+		t.Errorf("Expected no retention items after deletion, got %d", len(items))
+	}
+
+	_ = recent
+}
+
+func TestPurgeExpiredDryRun(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 365
+	enableTestParity(system)
+
+	testFile := createTestFile(t, tmpDir)
+	expired, _ := system.IngestEvidence(testFile, "CASE-PRG-001", "OFF-951", "Officer A", "Location A", nil)
+	current, _ := system.IngestEvidence(testFile, "CASE-PRG-002", "OFF-952", "Officer B", "Location B", nil)
+	sealed, _ := system.IngestEvidence(testFile, "CASE-PRG-003", "OFF-953", "Officer C", "Location C", nil)
+	now := time.Now()
+	expired.CreatedAt = now.AddDate(-2, 0, 0)
+	sealed.CreatedAt = now.AddDate(-2, 0, 0)
+	system.SealEvidence(sealed.ID, "Court order 24-202")
+
+	auditCount := len(system.GetAuditLogs("", ""))
+	preview, err := system.PurgeExpired(now, "RECORDS-1", true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(preview.Items) != 1 || len(preview.Skipped) != 1 || preview.Skipped[0].EvidenceID != sealed.ID {
+		t.Fatalf("Unexpected plan %+v", preview)
+	}
+	item := preview.Items[0]
+	recording, _ := os.Stat(expired.FilePath)
+	parity, _ := os.Stat(expired.Parity.Path)
+	if item.EvidenceID != expired.ID || item.CustodyAction != "PURGED" || len(item.Files) != 2 ||
+		item.Bytes != recording.Size()+parity.Size() || preview.TotalBytes != item.Bytes {
+		t.Errorf("Unexpected planned purge %+v", item)
+	}
+	if _, err := os.Stat(expired.FilePath); err != nil || expired.Status == StatusDeleted {
+		t.Error("Expected a dry run to delete nothing")
+	}
+	if n := len(system.GetAuditLogs("", "")); n != auditCount {
+		t.Errorf("Expected a dry run to write no audit entries, got %d new", n-auditCount)
+	}
+
+	plan, err := system.PurgeExpired(now, "RECORDS-1", false)
+	if err != nil {
+		t.Fatalf("PurgeExpired failed: %v", err)
+	}
+	if !reflect.DeepEqual(plan.Items, preview.Items) {
+		t.Errorf("Expected the purge to match its dry run:\n%+v\n%+v", plan.Items, preview.Items)
+	}
+	for _, path := range item.Files {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s removed", path)
+		}
+	}
+	if expired.Status != StatusDeleted || expired.ChainOfCustody[len(expired.ChainOfCustody)-1].Action != "PURGED" {
+		t.Errorf("Expected the record kept as DELETED with a PURGED entry, got %s", expired.Status)
+	}
+	if failed, _ := system.VerifyCustodySignatures(expired.ID); len(failed) != 0 {
+		t.Errorf("Custody entries failed verification: %v", failed)
+	}
+	if _, err := os.Stat(current.FilePath); err != nil || current.Status == StatusDeleted {
+		t.Error("Expected evidence within retention untouched")
+	}
+	if _, err := os.Stat(sealed.FilePath); err != nil {
+		t.Error("Expected sealed evidence untouched")
+	}
+
+	again, _ := system.PurgeExpired(now, "RECORDS-1", true)
+	if len(again.Items) != 0 {
+		t.Errorf("Expected nothing left to purge, got %+v", again.Items)
+	}
+}
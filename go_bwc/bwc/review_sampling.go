@@ -0,0 +1,239 @@
+package bwc
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// AuditSampleOptions describes a random audit draw. Percent of each
+// officer's footage recorded in [From, To) is selected, at least one item
+// for any officer with eligible footage.
+type AuditSampleOptions struct {
+	Percent float64
+	From    time.Time
+	To      time.Time
+	// Officers limits the draw to these officers; empty means all
+	Officers []string
+}
+
+// AuditSample records one random audit draw and the review tasks it created
+type AuditSample struct {
+	ID          string    `json:"id"`
+	Percent     float64   `json:"percent"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	RequestedBy string    `json:"requested_by"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Eligible counts each officer's footage in the period
+	Eligible map[string]int `json:"eligible"`
+	// Reviews lists the review IDs created for each officer
+	Reviews map[string][]string `json:"reviews"`
+}
+
+// SampleCompletion reports how far the reviews of one officer's sample have got
+type SampleCompletion struct {
+	OfficerID string  `json:"officer_id"`
+	Eligible  int     `json:"eligible"`
+	Sampled   int     `json:"sampled"`
+	Closed    int     `json:"closed"`
+	Rate      float64 `json:"completion_rate"`
+}
+
+// AuditSampleReport is the completion of a sample, per officer and overall
+type AuditSampleReport struct {
+	Sample   AuditSample        `json:"sample"`
+	Officers []SampleCompletion `json:"officers"`
+	Total    SampleCompletion   `json:"total"`
+}
+
+// sampleSize is how many of n items a draw of percent selects
+func sampleSize(n int, percent float64) int {
+	if n == 0 {
+		return 0
+	}
+	k := int(math.Ceil(float64(n) * percent / 100))
+	if k > n {
+		return n
+	}
+	return k
+}
+
+// randomSelection picks k of items uniformly at random with a partial
+// Fisher-Yates shuffle. The randomness comes from crypto/rand so that the
+// selection cannot be predicted.
+func randomSelection(items []*Evidence, k int) ([]*Evidence, error) {
+	pool := append([]*Evidence(nil), items...)
+	for i := 0; i < k; i++ {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(len(pool)-i)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to draw sample: %w", err)
+		}
+		swap := i + int(j.Int64())
+		pool[i], pool[swap] = pool[swap], pool[i]
+	}
+	return pool[:k], nil
+}
+
+// DrawAuditSample selects a random share of each officer's footage for the
+// period and opens a RANDOM_AUDIT review on each selected item. Deleted and
+// sealed footage, and footage with a random audit already open, are not
+// eligible.
+func (bwc *BWCSystem) DrawAuditSample(opts AuditSampleOptions, userID string) (*AuditSample, error) {
+	if err := bwc.authorize(userID, PermReviewAudit, "Audit sample", ""); err != nil {
+		return nil, err
+	}
+	if opts.Percent <= 0 || opts.Percent > 100 {
+		return nil, errors.New("sample percent must be greater than 0 and at most 100")
+	}
+	if opts.From.IsZero() || opts.To.IsZero() || !opts.From.Before(opts.To) {
+		return nil, errors.New("sample period must have a start before its end")
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	officers := make(map[string]bool, len(opts.Officers))
+	for _, officer := range opts.Officers {
+		officers[officer] = true
+	}
+
+	eligible := make(map[string][]*Evidence)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Timestamp.Before(opts.From) || !evidence.Timestamp.Before(opts.To) {
+			continue
+		}
+		if len(officers) > 0 && !officers[evidence.OfficerID] {
+			continue
+		}
+		if evidence.Status == StatusDeleted || evidence.Seal != nil || openReview(evidence, ReviewRandomAudit) != nil {
+			continue
+		}
+		eligible[evidence.OfficerID] = append(eligible[evidence.OfficerID], evidence)
+	}
+
+	now := time.Now()
+	bwc.auditSampleSeq++
+	sample := &AuditSample{
+		ID:          fmt.Sprintf("SMP-%06d", bwc.auditSampleSeq),
+		Percent:     opts.Percent,
+		From:        opts.From,
+		To:          opts.To,
+		RequestedBy: userID,
+		CreatedAt:   now,
+		Eligible:    make(map[string]int),
+		Reviews:     make(map[string][]string),
+	}
+
+	officerIDs := make([]string, 0, len(eligible))
+	for officer := range eligible {
+		officerIDs = append(officerIDs, officer)
+	}
+	sort.Strings(officerIDs)
+
+	summary := fmt.Sprintf("Random audit %s (%g%% of %s to %s)", sample.ID, opts.Percent,
+		opts.From.Format("2006-01-02"), opts.To.Format("2006-01-02"))
+	for _, officer := range officerIDs {
+		items := eligible[officer]
+		// Sort so that the draw depends on the shuffle alone, not map order
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+		selected, err := randomSelection(items, sampleSize(len(items), opts.Percent))
+		if err != nil {
+			return nil, err
+		}
+		sample.Eligible[officer] = len(items)
+		for _, evidence := range selected {
+			review, err := bwc.flagForReviewLocked(evidence, ReviewRandomAudit, userID, summary, now)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", evidence.ID, err)
+			}
+			sample.Reviews[officer] = append(sample.Reviews[officer], review.ID)
+		}
+	}
+	bwc.auditSamples[sample.ID] = sample
+
+	sampled := 0
+	for _, ids := range sample.Reviews {
+		sampled += len(ids)
+	}
+	bwc.logAudit(userID, "DRAW_AUDIT_SAMPLE", "",
+		fmt.Sprintf("%s: %d items for %d officers", summary, sampled, len(officerIDs)), "")
+
+	c := copyAuditSample(sample)
+	return &c, nil
+}
+
+// copyAuditSample copies a sample including its maps
+func copyAuditSample(sample *AuditSample) AuditSample {
+	c := *sample
+	c.Eligible = make(map[string]int, len(sample.Eligible))
+	for officer, n := range sample.Eligible {
+		c.Eligible[officer] = n
+	}
+	c.Reviews = make(map[string][]string, len(sample.Reviews))
+	for officer, ids := range sample.Reviews {
+		c.Reviews[officer] = append([]string(nil), ids...)
+	}
+	return c
+}
+
+// AuditSamples returns copies of all samples, oldest first
+func (bwc *BWCSystem) AuditSamples() []AuditSample {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	samples := make([]AuditSample, 0, len(bwc.auditSamples))
+	for _, sample := range bwc.auditSamples {
+		samples = append(samples, copyAuditSample(sample))
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].ID < samples[j].ID })
+	return samples
+}
+
+// AuditSampleCompletion reports how many of a sample's reviews are closed,
+// per officer and overall
+func (bwc *BWCSystem) AuditSampleCompletion(sampleID string) (*AuditSampleReport, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	sample, exists := bwc.auditSamples[sampleID]
+	if !exists {
+		return nil, errors.New("audit sample not found")
+	}
+
+	report := &AuditSampleReport{Sample: copyAuditSample(sample), Officers: make([]SampleCompletion, 0, len(sample.Eligible))}
+	for officer, eligible := range sample.Eligible {
+		completion := SampleCompletion{OfficerID: officer, Eligible: eligible}
+		for _, id := range sample.Reviews[officer] {
+			completion.Sampled++
+			if _, review, err := bwc.reviewLocked(id); err == nil && review.State == ReviewClosed {
+				completion.Closed++
+			}
+		}
+		completion.Rate = completionRate(completion.Closed, completion.Sampled)
+		report.Officers = append(report.Officers, completion)
+
+		report.Total.Eligible += completion.Eligible
+		report.Total.Sampled += completion.Sampled
+		report.Total.Closed += completion.Closed
+	}
+	sort.Slice(report.Officers, func(i, j int) bool { return report.Officers[i].OfficerID < report.Officers[j].OfficerID })
+	report.Total.Rate = completionRate(report.Total.Closed, report.Total.Sampled)
+	return report, nil
+}
+
+// completionRate is closed as a fraction of sampled, or 1 when nothing was sampled
+func completionRate(closed, sampled int) float64 {
+	if sampled == 0 {
+		return 1
+	}
+	return float64(closed) / float64(sampled)
+}
@@ -0,0 +1,136 @@
+package bwc
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSampleSize(t *testing.T) {
+	cases := []struct {
+		n       int
+		percent float64
+		want    int
+	}{
+		{0, 10, 0},
+		{1, 10, 1},
+		{10, 10, 1},
+		{11, 10, 2},
+		{40, 2.5, 1},
+		{5, 100, 5},
+	}
+	for _, c := range cases {
+		if got := sampleSize(c.n, c.percent); got != c.want {
+			t.Errorf("sampleSize(%d, %g) = %d, want %d", c.n, c.percent, got, c.want)
+		}
+	}
+}
+
+func TestRandomSelectionIsUniform(t *testing.T) {
+	items := make([]*Evidence, 4)
+	for i := range items {
+		items[i] = &Evidence{ID: fmt.Sprintf("EV-%d", i)}
+	}
+
+	counts := map[string]int{}
+	const draws = 4000
+	for i := 0; i < draws; i++ {
+		selected, err := randomSelection(items, 2)
+		if err != nil {
+			t.Fatalf("randomSelection failed: %v", err)
+		}
+		if selected[0] == selected[1] {
+			t.Fatal("the same item was selected twice")
+		}
+		for _, ev := range selected {
+			counts[ev.ID]++
+		}
+	}
+	// Each item is expected in half of the draws; allow a wide margin
+	for _, ev := range items {
+		if n := counts[ev.ID]; n < draws/2-300 || n > draws/2+300 {
+			t.Errorf("%s selected %d times in %d draws, expected about %d", ev.ID, n, draws, draws/2)
+		}
+	}
+}
+
+func TestDrawAuditSample(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	file := createTestFile(t, tmpDir)
+
+	for i := 0; i < 4; i++ {
+		if _, err := system.IngestEvidence(file, fmt.Sprintf("CASE-SMP-%d", i), "OFF-1104", "", "", nil); err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+	}
+	if _, err := system.IngestEvidence(file, "CASE-SMP-9", "OFF-1105", "", "", nil); err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	from, to := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	if _, err := system.DrawAuditSample(AuditSampleOptions{Percent: 0, From: from, To: to}, "IA-1"); err == nil {
+		t.Error("expected a zero percent sample to be refused")
+	}
+	if _, err := system.DrawAuditSample(AuditSampleOptions{Percent: 10, From: to, To: from}, "IA-1"); err == nil {
+		t.Error("expected a reversed period to be refused")
+	}
+
+	sample, err := system.DrawAuditSample(AuditSampleOptions{Percent: 50, From: from, To: to}, "IA-1")
+	if err != nil {
+		t.Fatalf("DrawAuditSample failed: %v", err)
+	}
+	if sample.Eligible["OFF-1104"] != 4 || len(sample.Reviews["OFF-1104"]) != 2 {
+		t.Errorf("expected 2 of 4 items for OFF-1104, got %d of %d", len(sample.Reviews["OFF-1104"]), sample.Eligible["OFF-1104"])
+	}
+	if len(sample.Reviews["OFF-1105"]) != 1 {
+		t.Errorf("expected at least one item for OFF-1105, got %d", len(sample.Reviews["OFF-1105"]))
+	}
+	if open := system.Reviews(ReviewFilter{Reason: ReviewRandomAudit, Open: true}); len(open) != 3 {
+		t.Errorf("expected 3 random audit reviews, got %d", len(open))
+	}
+
+	// Items already under a random audit are not drawn again
+	second, err := system.DrawAuditSample(AuditSampleOptions{Percent: 100, From: from, To: to, Officers: []string{"OFF-1104"}}, "IA-1")
+	if err != nil {
+		t.Fatalf("DrawAuditSample failed: %v", err)
+	}
+	if second.Eligible["OFF-1104"] != 2 || len(second.Reviews) != 1 {
+		t.Errorf("expected only the 2 unsampled OFF-1104 items, got %+v", second)
+	}
+
+	reviewID := sample.Reviews["OFF-1104"][0]
+	system.AssignReview(reviewID, "SGT-4", "LT-1")
+	system.StartReview(reviewID, "SGT-4")
+	if err := system.CloseReview(reviewID, "SGT-4", OutcomeWithinPolicy, "No issues"); err != nil {
+		t.Fatalf("CloseReview failed: %v", err)
+	}
+
+	report, err := system.AuditSampleCompletion(sample.ID)
+	if err != nil {
+		t.Fatalf("AuditSampleCompletion failed: %v", err)
+	}
+	if len(report.Officers) != 2 || report.Officers[0].OfficerID != "OFF-1104" || report.Officers[0].Rate != 0.5 {
+		t.Errorf("unexpected per-officer completion %+v", report.Officers)
+	}
+	if report.Total.Sampled != 3 || report.Total.Closed != 1 || report.Total.Eligible != 5 {
+		t.Errorf("unexpected total completion %+v", report.Total)
+	}
+
+	if _, err := system.AuditSampleCompletion("SMP-999999"); err == nil {
+		t.Error("expected an unknown sample to be reported")
+	}
+	if n := len(system.AuditSamples()); n != 2 {
+		t.Errorf("expected 2 samples, got %d", n)
+	}
+
+	draws := 0
+	for _, log := range system.GetAuditLogs("", "IA-1") {
+		if log.Action == "DRAW_AUDIT_SAMPLE" {
+			draws++
+		}
+	}
+	if draws != 2 {
+		t.Errorf("expected 2 DRAW_AUDIT_SAMPLE audit entries, got %d", draws)
+	}
+}
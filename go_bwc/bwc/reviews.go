@@ -0,0 +1,378 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ReviewReason is why footage was flagged for supervisor review
+type ReviewReason string
+
+const (
+	ReviewUseOfForce ReviewReason = "USE_OF_FORCE"
+	ReviewComplaint  ReviewReason = "COMPLAINT"
+	ReviewPursuit    ReviewReason = "PURSUIT"
+	// ReviewRandomAudit marks footage drawn by the audit sampler
+	ReviewRandomAudit ReviewReason = "RANDOM_AUDIT"
+)
+
+// ParseReviewReason converts a reason name such as use-of-force to a ReviewReason
+func ParseReviewReason(name string) (ReviewReason, error) {
+	reason := ReviewReason(strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(name), "-", "_")))
+	switch reason {
+	case ReviewUseOfForce, ReviewComplaint, ReviewPursuit, ReviewRandomAudit:
+		return reason, nil
+	}
+	return "", fmt.Errorf("unknown review reason %q (expected use-of-force, complaint, pursuit or random-audit)", name)
+}
+
+// ReviewState is where a review stands. Reviews move from FLAGGED to
+// ASSIGNED when a supervisor is named, to IN_REVIEW when the supervisor
+// starts, and to CLOSED with findings.
+type ReviewState string
+
+const (
+	ReviewFlagged  ReviewState = "FLAGGED"
+	ReviewAssigned ReviewState = "ASSIGNED"
+	ReviewInReview ReviewState = "IN_REVIEW"
+	ReviewClosed   ReviewState = "CLOSED"
+)
+
+// ReviewOutcome is the supervisor's conclusion when closing a review
+type ReviewOutcome string
+
+const (
+	OutcomeWithinPolicy    ReviewOutcome = "WITHIN_POLICY"
+	OutcomePolicyViolation ReviewOutcome = "POLICY_VIOLATION"
+	OutcomeTrainingNeeded  ReviewOutcome = "TRAINING_NEEDED"
+	OutcomeUnfounded       ReviewOutcome = "UNFOUNDED"
+)
+
+// ParseReviewOutcome converts an outcome name such as within-policy to a ReviewOutcome
+func ParseReviewOutcome(name string) (ReviewOutcome, error) {
+	outcome := ReviewOutcome(strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(name), "-", "_")))
+	switch outcome {
+	case OutcomeWithinPolicy, OutcomePolicyViolation, OutcomeTrainingNeeded, OutcomeUnfounded:
+		return outcome, nil
+	}
+	return "", fmt.Errorf("unknown review outcome %q (expected within-policy, policy-violation, training-needed or unfounded)", name)
+}
+
+// ReviewEvent is one step in a review's history
+type ReviewEvent struct {
+	Timestamp time.Time   `json:"timestamp"`
+	UserID    string      `json:"user_id"`
+	State     ReviewState `json:"state"`
+	Notes     string      `json:"notes,omitempty"`
+}
+
+// FootageReview is a supervisor review of flagged footage. Reviews are kept
+// on the evidence record, so they appear in its exports and reports.
+type FootageReview struct {
+	ID         string        `json:"id"`
+	EvidenceID string        `json:"evidence_id"`
+	Reason     ReviewReason  `json:"reason"`
+	Summary    string        `json:"summary"`
+	State      ReviewState   `json:"state"`
+	FlaggedBy  string        `json:"flagged_by"`
+	FlaggedAt  time.Time     `json:"flagged_at"`
+	Supervisor string        `json:"supervisor,omitempty"`
+	Outcome    ReviewOutcome `json:"outcome,omitempty"`
+	Findings   string        `json:"findings,omitempty"`
+	ClosedAt   time.Time     `json:"closed_at,omitempty"`
+	History    []ReviewEvent `json:"history"`
+}
+
+// copyReviews copies reviews including their histories
+func copyReviews(reviews []FootageReview) []FootageReview {
+	if reviews == nil {
+		return nil
+	}
+	c := make([]FootageReview, len(reviews))
+	for i, review := range reviews {
+		c[i] = review
+		c[i].History = append([]ReviewEvent(nil), review.History...)
+	}
+	return c
+}
+
+// reviewLocked finds a review by ID; the caller must hold bwc.mu
+func (bwc *BWCSystem) reviewLocked(reviewID string) (*Evidence, *FootageReview, error) {
+	evidence := bwc.evidenceDB.Get(bwc.reviewIndex[reviewID])
+	if evidence == nil {
+		return nil, nil, errors.New("review not found")
+	}
+	for i := range evidence.Reviews {
+		if evidence.Reviews[i].ID == reviewID {
+			return evidence, &evidence.Reviews[i], nil
+		}
+	}
+	return nil, nil, errors.New("review not found")
+}
+
+// advanceReviewLocked moves a review to state, recording the step, and saves
+// evidence. If it cannot be saved, evidence is put back as it was before the
+// change began. The caller must hold bwc.mu.
+func (bwc *BWCSystem) advanceReviewLocked(evidence *Evidence, before Evidence, review *FootageReview, userID string, state ReviewState, notes string, at time.Time) error {
+	review.State = state
+	review.History = append(review.History, ReviewEvent{Timestamp: at, UserID: userID, State: state, Notes: notes})
+	markModified(evidence, at)
+	if err := bwc.saveLocked(evidence); err != nil {
+		*evidence = before
+		return err
+	}
+	return nil
+}
+
+// FlagForReview flags evidence for supervisor review. An item may carry one
+// open review per reason.
+func (bwc *BWCSystem) FlagForReview(evidenceID string, reason ReviewReason, userID, summary string) (*FootageReview, error) {
+	if err := bwc.authorize(userID, PermViewEvidence, "Review flag", evidenceID); err != nil {
+		return nil, err
+	}
+	if _, err := ParseReviewReason(string(reason)); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Review flag"); err != nil {
+		return nil, err
+	}
+	if open := openReview(evidence, reason); open != nil {
+		return nil, fmt.Errorf("review %s for %s is already open on this evidence", open.ID, reason)
+	}
+
+	review, err := bwc.flagForReviewLocked(evidence, reason, userID, summary, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	c := copyReviews([]FootageReview{*review})[0]
+	return &c, nil
+}
+
+// openReview returns the evidence's open review for reason, if any
+func openReview(evidence *Evidence, reason ReviewReason) *FootageReview {
+	for i := range evidence.Reviews {
+		if evidence.Reviews[i].Reason == reason && evidence.Reviews[i].State != ReviewClosed {
+			return &evidence.Reviews[i]
+		}
+	}
+	return nil
+}
+
+// flagForReviewLocked opens a review on evidence; the caller must hold bwc.mu
+func (bwc *BWCSystem) flagForReviewLocked(evidence *Evidence, reason ReviewReason, userID, summary string, now time.Time) (*FootageReview, error) {
+	before := copyEvidence(evidence)
+	bwc.reviewSeq++
+	evidence.Reviews = append(evidence.Reviews, FootageReview{
+		ID:         fmt.Sprintf("REV-%06d", bwc.reviewSeq),
+		EvidenceID: evidence.ID,
+		Reason:     reason,
+		Summary:    summary,
+		FlaggedBy:  userID,
+		FlaggedAt:  now,
+	})
+	review := &evidence.Reviews[len(evidence.Reviews)-1]
+	if err := bwc.advanceReviewLocked(evidence, before, review, userID, ReviewFlagged, summary, now); err != nil {
+		bwc.reviewSeq--
+		return nil, err
+	}
+	bwc.reviewIndex[review.ID] = evidence.ID
+
+	bwc.logAudit(userID, "FLAG_FOR_REVIEW", evidence.ID,
+		fmt.Sprintf("Review %s opened for %s - %s", review.ID, reason, summary), "")
+	return review, nil
+}
+
+// AssignReview names the supervisor who reviews the footage. An open review
+// may be reassigned; one already started returns to ASSIGNED.
+func (bwc *BWCSystem) AssignReview(reviewID, supervisorID, assignedBy string) error {
+	if err := bwc.authorize(assignedBy, PermReviewAudit, "Review assignment", ""); err != nil {
+		return err
+	}
+	if err := ValidateOfficerID(supervisorID); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence, review, err := bwc.reviewLocked(reviewID)
+	if err != nil {
+		return err
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, assignedBy, "Review assignment"); err != nil {
+		return err
+	}
+	if review.State == ReviewClosed {
+		return fmt.Errorf("review %s is closed", reviewID)
+	}
+	if supervisorID == evidence.OfficerID {
+		return errors.New("a review cannot be assigned to the officer who recorded the footage")
+	}
+
+	before := copyEvidence(evidence)
+	previous := review.Supervisor
+	review.Supervisor = supervisorID
+	if err := bwc.advanceReviewLocked(evidence, before, review, assignedBy, ReviewAssigned, "Assigned to "+supervisorID, time.Now()); err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("Review %s assigned to %s", reviewID, supervisorID)
+	if previous != "" {
+		details += " (previously " + previous + ")"
+	}
+	bwc.logAudit(assignedBy, "ASSIGN_REVIEW", evidence.ID, details, "")
+	return nil
+}
+
+// StartReview records that the assigned supervisor has begun the review
+func (bwc *BWCSystem) StartReview(reviewID, supervisorID string) error {
+	if err := bwc.authorize(supervisorID, PermReviewAudit, "Review start", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence, review, err := bwc.assignedReviewLocked(reviewID, supervisorID, "Review start")
+	if err != nil {
+		return err
+	}
+	if review.State != ReviewAssigned {
+		return fmt.Errorf("review %s is %s, not ASSIGNED", reviewID, review.State)
+	}
+
+	if err := bwc.advanceReviewLocked(evidence, copyEvidence(evidence), review, supervisorID, ReviewInReview, "", time.Now()); err != nil {
+		return err
+	}
+	bwc.logAudit(supervisorID, "START_REVIEW", evidence.ID, fmt.Sprintf("Review %s started", reviewID), "")
+	return nil
+}
+
+// CloseReview records the assigned supervisor's outcome and findings
+func (bwc *BWCSystem) CloseReview(reviewID, supervisorID string, outcome ReviewOutcome, findings string) error {
+	if err := bwc.authorize(supervisorID, PermReviewAudit, "Review close", ""); err != nil {
+		return err
+	}
+	if _, err := ParseReviewOutcome(string(outcome)); err != nil {
+		return err
+	}
+	if strings.TrimSpace(findings) == "" {
+		return errors.New("findings are required to close a review")
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence, review, err := bwc.assignedReviewLocked(reviewID, supervisorID, "Review closure")
+	if err != nil {
+		return err
+	}
+	if review.State != ReviewInReview {
+		return fmt.Errorf("review %s is %s, not IN_REVIEW", reviewID, review.State)
+	}
+
+	before := copyEvidence(evidence)
+	now := time.Now()
+	review.Outcome = outcome
+	review.Findings = findings
+	review.ClosedAt = now
+	if err := bwc.advanceReviewLocked(evidence, before, review, supervisorID, ReviewClosed, findings, now); err != nil {
+		return err
+	}
+
+	bwc.logAudit(supervisorID, "CLOSE_REVIEW", evidence.ID,
+		fmt.Sprintf("Review %s closed: %s - %s", reviewID, outcome, findings), "")
+	return nil
+}
+
+// assignedReviewLocked finds a review that supervisorID is assigned to; the
+// caller must hold bwc.mu
+func (bwc *BWCSystem) assignedReviewLocked(reviewID, supervisorID, operation string) (*Evidence, *FootageReview, error) {
+	evidence, review, err := bwc.reviewLocked(reviewID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, supervisorID, operation); err != nil {
+		return nil, nil, err
+	}
+	if review.Supervisor != supervisorID {
+		return nil, nil, fmt.Errorf("review %s is not assigned to %s", reviewID, supervisorID)
+	}
+	return evidence, review, nil
+}
+
+// ReviewFilter selects reviews; empty fields match everything
+type ReviewFilter struct {
+	EvidenceID string
+	Reason     ReviewReason
+	State      ReviewState
+	Supervisor string
+	// Open selects reviews that are not closed
+	Open bool
+}
+
+// Reviews returns copies of the reviews matching filter, oldest first
+func (bwc *BWCSystem) Reviews(filter ReviewFilter) []FootageReview {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	results := make([]FootageReview, 0)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if filter.EvidenceID != "" && evidence.ID != filter.EvidenceID {
+			continue
+		}
+		for _, review := range evidence.Reviews {
+			switch {
+			case filter.Reason != "" && review.Reason != filter.Reason,
+				filter.State != "" && review.State != filter.State,
+				filter.Supervisor != "" && review.Supervisor != filter.Supervisor,
+				filter.Open && review.State == ReviewClosed:
+				continue
+			}
+			results = append(results, review)
+		}
+	}
+	results = copyReviews(results)
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	return results
+}
+
+// GetReview returns a copy of one review
+func (bwc *BWCSystem) GetReview(reviewID string) (*FootageReview, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	_, review, err := bwc.reviewLocked(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	c := copyReviews([]FootageReview{*review})[0]
+	return &c, nil
+}
@@ -0,0 +1,152 @@
+package bwc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReviewWorkflow(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-1", "OFF-1101", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	review, err := system.FlagForReview(ev.ID, ReviewUseOfForce, "IA-1", "Taser deployed during arrest")
+	if err != nil {
+		t.Fatalf("FlagForReview failed: %v", err)
+	}
+	if review.ID != "REV-000001" || review.State != ReviewFlagged {
+		t.Fatalf("unexpected review %+v", review)
+	}
+	if _, err := system.FlagForReview(ev.ID, ReviewUseOfForce, "IA-1", "Again"); err == nil {
+		t.Error("expected a second open review for the same reason to be refused")
+	}
+	if _, err := system.FlagForReview(ev.ID, ReviewComplaint, "IA-1", "Citizen complaint"); err != nil {
+		t.Errorf("expected a review for another reason to be allowed, got %v", err)
+	}
+
+	if err := system.StartReview(review.ID, "SGT-1"); err == nil {
+		t.Error("expected an unassigned review not to start")
+	}
+	if err := system.AssignReview(review.ID, "OFF-1101", "LT-1"); err == nil {
+		t.Error("expected assignment to the recording officer to be refused")
+	}
+	if err := system.AssignReview(review.ID, "SGT-1", "LT-1"); err != nil {
+		t.Fatalf("AssignReview failed: %v", err)
+	}
+	if err := system.StartReview(review.ID, "SGT-2"); err == nil {
+		t.Error("expected only the assigned supervisor to start the review")
+	}
+	if err := system.StartReview(review.ID, "SGT-1"); err != nil {
+		t.Fatalf("StartReview failed: %v", err)
+	}
+	if err := system.CloseReview(review.ID, "SGT-1", OutcomeWithinPolicy, "  "); err == nil {
+		t.Error("expected findings to be required")
+	}
+	if err := system.CloseReview(review.ID, "SGT-1", OutcomeWithinPolicy, "Force proportionate to resistance"); err != nil {
+		t.Fatalf("CloseReview failed: %v", err)
+	}
+	if err := system.AssignReview(review.ID, "SGT-2", "LT-1"); err == nil {
+		t.Error("expected a closed review not to be reassigned")
+	}
+
+	closed, err := system.GetReview(review.ID)
+	if err != nil {
+		t.Fatalf("GetReview failed: %v", err)
+	}
+	if closed.State != ReviewClosed || closed.Outcome != OutcomeWithinPolicy || len(closed.History) != 4 {
+		t.Errorf("unexpected closed review %+v", closed)
+	}
+	closed.History[0].UserID = "changed"
+	if again, _ := system.GetReview(review.ID); again.History[0].UserID != "IA-1" {
+		t.Error("GetReview returned shared history")
+	}
+
+	if open := system.Reviews(ReviewFilter{Open: true}); len(open) != 1 || open[0].Reason != ReviewComplaint {
+		t.Errorf("expected the complaint review to be the only open one, got %+v", open)
+	}
+	if mine := system.Reviews(ReviewFilter{Supervisor: "SGT-1"}); len(mine) != 1 {
+		t.Errorf("expected one review for SGT-1, got %d", len(mine))
+	}
+
+	// A closed review no longer blocks a new one for the same reason
+	if _, err := system.FlagForReview(ev.ID, ReviewUseOfForce, "IA-1", "New allegation"); err != nil {
+		t.Errorf("expected a new review after closure, got %v", err)
+	}
+
+	actions := map[string]int{}
+	for _, log := range system.GetAuditLogs(ev.ID, "") {
+		actions[log.Action]++
+	}
+	for action, want := range map[string]int{"FLAG_FOR_REVIEW": 3, "ASSIGN_REVIEW": 1, "START_REVIEW": 1, "CLOSE_REVIEW": 1} {
+		if actions[action] != want {
+			t.Errorf("expected %d %s audit entries, got %d", want, action, actions[action])
+		}
+	}
+}
+
+func TestReviewRefusedOnSealedEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-2", "OFF-1102", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if _, err := system.SealEvidence(ev.ID, "Court order 24-117"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if _, err := system.FlagForReview(ev.ID, ReviewPursuit, "IA-1", "Vehicle pursuit"); err == nil {
+		t.Error("expected sealed evidence to refuse a review flag")
+	}
+	if _, err := system.FlagForReview(ev.ID, ReviewReason("TRAFFIC"), "IA-1", ""); err == nil {
+		t.Error("expected an unknown reason to be refused")
+	}
+}
+
+func TestReviewsInReports(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-3", "OFF-1103", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	review, err := system.FlagForReview(ev.ID, ReviewPursuit, "IA-1", "Vehicle pursuit")
+	if err != nil {
+		t.Fatalf("FlagForReview failed: %v", err)
+	}
+	system.AssignReview(review.ID, "SGT-3", "LT-1")
+	system.StartReview(review.ID, "SGT-3")
+	if err := system.CloseReview(review.ID, "SGT-3", OutcomeTrainingNeeded, "Pursuit policy refresher"); err != nil {
+		t.Fatalf("CloseReview failed: %v", err)
+	}
+
+	internal, err := system.GenerateCaseReport("CASE-REV-3", ReportOptions{Profile: ReportProfileInternal})
+	if err != nil {
+		t.Fatalf("GenerateCaseReport failed: %v", err)
+	}
+	for _, want := range []string{"Supervisor reviews (1)", review.ID + " Pursuit - Closed (SGT-3)", "Outcome: Training needed - Pursuit policy refresher"} {
+		if !strings.Contains(internal, want) {
+			t.Errorf("expected %q in the internal report:\n%s", want, internal)
+		}
+	}
+
+	court, _ := system.GenerateCaseReport("CASE-REV-3", ReportOptions{Profile: ReportProfileCourt})
+	if strings.Contains(court, review.ID) {
+		t.Error("expected reviews to be left out of the court report")
+	}
+
+	html, err := system.GenerateHTMLReport("CASE-REV-3", ReportOptions{Profile: ReportProfileInternal, Locale: LocaleSpanish})
+	if err != nil {
+		t.Fatalf("GenerateHTMLReport failed: %v", err)
+	}
+	for _, want := range []string{"Revisiones de supervisión (1)", "Persecución", "Requiere formación"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in the HTML report", want)
+		}
+	}
+}
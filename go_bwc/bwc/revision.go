@@ -0,0 +1,136 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RevisionConflictError is returned when a change is made against a stale
+// revision of evidence, i.e. someone else changed the record since the
+// caller read it. The caller should re-read the record and reapply its edit.
+type RevisionConflictError struct {
+	EvidenceID string
+	Expected   int64
+	Current    int64
+}
+
+func (e *RevisionConflictError) Error() string {
+	return fmt.Sprintf("evidence %s was changed by someone else: revision %d is stale, current revision is %d",
+		e.EvidenceID, e.Expected, e.Current)
+}
+
+// markModified records a change to evidence, bumping its revision
+func markModified(evidence *Evidence, at time.Time) {
+	evidence.LastModified = at
+	evidence.Revision++
+}
+
+// checkRevisionLocked refuses a change made against a stale revision; a
+// revision of 0 skips the check. The caller must hold bwc.mu.
+func (bwc *BWCSystem) checkRevisionLocked(evidence *Evidence, userID string, revision int64) error {
+	if revision == 0 || revision == evidence.Revision {
+		return nil
+	}
+	bwc.logAudit(userID, "REVISION_CONFLICT", evidence.ID,
+		fmt.Sprintf("Change against revision %d refused; current revision is %d", revision, evidence.Revision), "")
+	return &RevisionConflictError{EvidenceID: evidence.ID, Expected: revision, Current: evidence.Revision}
+}
+
+// MetadataUpdate is an edit of the descriptive fields of evidence. Nil
+// fields are left unchanged.
+type MetadataUpdate struct {
+	Location    *string `json:"location,omitempty"`
+	OfficerName *string `json:"officer_name,omitempty"`
+	Notes       *string `json:"notes,omitempty"`
+
+	// Forensic acquisition details, recorded in Evidence.Forensics
+	AcquisitionMethod *AcquisitionMethod `json:"acquisition_method,omitempty"`
+	Examiner          *string            `json:"examiner,omitempty"`
+	ExaminerName      *string            `json:"examiner_name,omitempty"`
+	OriginalMedia     *OriginalMedia     `json:"original_media,omitempty"`
+	WriteBlocker      *string            `json:"write_blocker,omitempty"`
+}
+
+// UpdateMetadata edits the descriptive fields of evidence. revision is the
+// revision the caller read; if the record has changed since, nothing is
+// written and a *RevisionConflictError carries the current revision.
+func (bwc *BWCSystem) UpdateMetadata(evidenceID, userID string, revision int64, update MetadataUpdate) (*Evidence, error) {
+	return bwc.UpdateMetadataContext(context.Background(), evidenceID, userID, revision, update)
+}
+
+// UpdateMetadataContext edits like UpdateMetadata as the principal ctx
+// carries, when it has one, whoever userID names
+func (bwc *BWCSystem) UpdateMetadataContext(ctx context.Context, evidenceID, userID string, revision int64, update MetadataUpdate) (*Evidence, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermEditMetadata, "Metadata update", evidenceID); err != nil {
+		return nil, err
+	}
+	if revision <= 0 {
+		return nil, errors.New("the revision being edited is required")
+	}
+	if err := validateForensicUpdate(update); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, userID, "Metadata update"); err != nil {
+		return nil, err
+	}
+	if err := bwc.checkRevisionLocked(evidence, userID, revision); err != nil {
+		return nil, err
+	}
+
+	before := copyEvidence(evidence)
+	var changed []string
+	if update.Location != nil && *update.Location != evidence.Location {
+		evidence.Location = *update.Location
+		changed = append(changed, "location")
+	}
+	if update.OfficerName != nil && *update.OfficerName != evidence.OfficerName {
+		evidence.OfficerName = *update.OfficerName
+		changed = append(changed, "officer name")
+	}
+	if update.Notes != nil && *update.Notes != evidence.Notes {
+		evidence.Notes = *update.Notes
+		changed = append(changed, "notes")
+	}
+	forensic := applyForensicUpdate(evidence, update)
+	changed = append(changed, forensic...)
+	if len(changed) == 0 {
+		copied := copyEvidence(evidence)
+		return &copied, nil
+	}
+	markModified(evidence, time.Now())
+	// Identifying the camera may bring a known clock offset with it
+	var drift string
+	for _, field := range forensic {
+		if field == "original media" {
+			drift = bwc.recheckClockLocked(evidence)
+		}
+	}
+	if err := bwc.saveLocked(evidence); err != nil {
+		*evidence = before
+		return nil, err
+	}
+
+	bwc.logAuditAs(auth, userID, "UPDATE_METADATA", evidenceID,
+		fmt.Sprintf("Updated %s at revision %d", strings.Join(changed, ", "), evidence.Revision), "")
+	if drift != "" {
+		bwc.logAuditAs(auth, userID, "CLOCK_DRIFT_DETECTED", evidenceID, drift, "")
+	}
+	copied := copyEvidence(evidence)
+	return &copied, nil
+}
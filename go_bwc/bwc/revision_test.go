@@ -0,0 +1,144 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRevisionBumpsOnMutation(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-001", "OFF-1031", "Officer Test", "Main St", nil)
+	if evidence.Revision != 1 {
+		t.Fatalf("Expected a new record at revision 1, got %d", evidence.Revision)
+	}
+
+	system.TransferCustody(evidence.ID, "OFF-1031", "DET-1", "Investigation")
+	system.UpdateStatus(evidence.ID, "DET-1", StatusProcessing, "")
+	system.SetPlace(evidence.ID, "DET-1", Place{Latitude: 39.78, Longitude: -89.65})
+	if evidence.Revision != 4 {
+		t.Errorf("Expected each change to bump the revision, got %d", evidence.Revision)
+	}
+
+	err := system.UpdateStatusIfRevision(evidence.ID, "DET-2", StatusAnalyzed, "", 2)
+	var conflict *RevisionConflictError
+	if !errors.As(err, &conflict) || conflict.Current != 4 || conflict.Expected != 2 {
+		t.Fatalf("Expected a revision conflict, got %v", err)
+	}
+	if evidence.Status != StatusProcessing {
+		t.Error("Expected a stale change not to be applied")
+	}
+	if err := system.SetPlaceIfRevision(evidence.ID, "DET-2", Place{Latitude: 1}, 3); !errors.As(err, &conflict) {
+		t.Errorf("Expected a revision conflict, got %v", err)
+	}
+	if err := system.UpdateStatusIfRevision(evidence.ID, "DET-2", StatusAnalyzed, "", 4); err != nil {
+		t.Errorf("Expected a current revision to be accepted: %v", err)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "DET-2")
+	if len(logs) != 3 || logs[0].Action != "REVISION_CONFLICT" || logs[1].Action != "REVISION_CONFLICT" {
+		t.Errorf("Expected REVISION_CONFLICT audit entries, got %v", logs)
+	}
+}
+
+func TestUpdateMetadata(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-002", "OFF-1032", "Officer Test", "Main St", nil)
+
+	location, notes := "123 Main St", "Suspect vehicle visible at 00:41"
+	updated, err := system.UpdateMetadata(evidence.ID, "DET-1", 1, MetadataUpdate{Location: &location, Notes: &notes})
+	if err != nil {
+		t.Fatalf("UpdateMetadata failed: %v", err)
+	}
+	if updated.Location != location || updated.Notes != notes || updated.OfficerName != "Officer Test" || updated.Revision != 2 {
+		t.Errorf("Unexpected record %+v", updated)
+	}
+
+	updated.Notes = "Edited by the caller"
+	if evidence.Notes != notes {
+		t.Error("Expected UpdateMetadata to return a copy of the record")
+	}
+
+	// A second detective who read revision 1 does not clobber the first edit
+	other := "Side entrance"
+	_, err = system.UpdateMetadata(evidence.ID, "DET-2", 1, MetadataUpdate{Location: &other})
+	var conflict *RevisionConflictError
+	if !errors.As(err, &conflict) || conflict.Current != 2 || evidence.Location != location {
+		t.Fatalf("Expected a revision conflict, got %v", err)
+	}
+
+	if _, err := system.UpdateMetadata(evidence.ID, "DET-1", 2, MetadataUpdate{Notes: &notes}); err != nil || evidence.Revision != 2 {
+		t.Errorf("Expected an edit without changes to keep the revision, got %d, %v", evidence.Revision, err)
+	}
+	if _, err := system.UpdateMetadata(evidence.ID, "DET-1", 0, MetadataUpdate{Notes: &notes}); err == nil {
+		t.Error("Expected an edit without a revision to be refused")
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "DET-1")
+	if len(logs) != 1 || logs[0].Action != "UPDATE_METADATA" || !strings.Contains(logs[0].Details, "location, notes") {
+		t.Errorf("Expected UPDATE_METADATA audit entry, got %v", logs)
+	}
+}
+
+func TestUpdateMetadataFailedSave(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	store := newRecordingStore()
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-004", "OFF-1034", "Officer Test", "Main St", nil)
+	store.fail = errors.New("disk full")
+	location := "123 Main St"
+	if _, err := system.UpdateMetadata(evidence.ID, "DET-1", 1, MetadataUpdate{Location: &location}); err == nil {
+		t.Fatal("Expected a failed write to fail the update")
+	}
+	if evidence.Location != "Main St" || evidence.Revision != 1 {
+		t.Errorf("Expected the record to be left as it was, got %q at revision %d", evidence.Location, evidence.Revision)
+	}
+	if logs := system.GetAuditLogs(evidence.ID, "DET-1"); len(logs) != 0 {
+		t.Errorf("Expected nothing audited for an unsaved update, got %v", logs)
+	}
+}
+
+func TestServerUpdateMetadata(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-REV-003", "OFF-1033", "Officer Test", "Main St", nil)
+
+	patch := func(body string) (*http.Response, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPatch, server.URL+"/api/evidence/"+evidence.ID, bytes.NewReader([]byte(body)))
+		req.Header.Set("Authorization", "Bearer "+testAPIToken)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PATCH failed: %v", err)
+		}
+		defer resp.Body.Close()
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	resp, out := patch(`{"revision":1,"notes":"Reviewed"}`)
+	if resp.StatusCode != http.StatusOK || out["notes"] != "Reviewed" || out["revision"] != float64(2) {
+		t.Fatalf("Expected the edit applied, got %d %v", resp.StatusCode, out)
+	}
+	resp, out = patch(`{"revision":1,"notes":"Stale"}`)
+	if resp.StatusCode != http.StatusConflict || out["current_revision"] != float64(2) {
+		t.Errorf("Expected 409 with the current revision, got %d %v", resp.StatusCode, out)
+	}
+	resp, _ = patch(`{"notes":"No revision"}`)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a revision, got %d", resp.StatusCode)
+	}
+}
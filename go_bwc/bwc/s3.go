@@ -0,0 +1,244 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Credentials authenticate S3 requests
+type s3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// s3CredentialsFor returns the destination's credentials, falling back to the
+// standard AWS_* environment variables when none are configured
+func s3CredentialsFor(dest ReportDestination) s3Credentials {
+	if dest.AccessKeyID != "" {
+		return s3Credentials{AccessKeyID: dest.AccessKeyID, SecretAccessKey: dest.SecretAccessKey, SessionToken: dest.SessionToken}
+	}
+	return s3Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// s3ObjectURL returns the URL of key in the destination bucket. A configured
+// endpoint (e.g. MinIO) is addressed path-style; AWS is addressed virtual-host style.
+func s3ObjectURL(dest ReportDestination, key string) string {
+	escaped := (&url.URL{Path: "/" + key}).EscapedPath()
+	if dest.Endpoint != "" {
+		return strings.TrimRight(dest.Endpoint, "/") + "/" + dest.Bucket + escaped
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com%s", dest.Bucket, dest.Region, escaped)
+}
+
+// putS3Object uploads body to the destination bucket under key
+func putS3Object(client *http.Client, dest ReportDestination, key, contentType string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPut, s3ObjectURL(dest, key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	// Reports are written once and kept; ask the bucket to encrypt them at rest
+	req.Header.Set("X-Amz-Server-Side-Encryption", "AES256")
+
+	payloadHash := sha256.Sum256(body)
+	signS3Request(req, hex.EncodeToString(payloadHash[:]), dest.Region, s3CredentialsFor(dest), time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 upload of %s returned %s: %s", key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// putS3File uploads the file at path under key. sha256Hex is the file's
+// SHA-256, which S3 checks against the body it receives. headers are added to
+// the request and signed with it.
+func putS3File(client *http.Client, dest ReportDestination, key, path, sha256Hex string, headers map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s3ObjectURL(dest, key), file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Amz-Server-Side-Encryption", "AES256")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	signS3Request(req, sha256Hex, dest.Region, s3CredentialsFor(dest), time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 upload of %s returned %s: %s", key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+// objectLockHeaders returns the S3 headers that lock an upload in compliance
+// mode until retainUntil, or place it under legal hold when retainUntil is
+// zero. S3 requires Content-MD5 on uploads that set Object Lock.
+func objectLockHeaders(path string, retainUntil time.Time) (map[string]string, error) {
+	sum, err := fileMD5(path)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := map[string]string{"Content-MD5": sum}
+	if retainUntil.IsZero() {
+		headers["X-Amz-Object-Lock-Legal-Hold"] = "ON"
+	} else {
+		headers["X-Amz-Object-Lock-Mode"] = "COMPLIANCE"
+		headers["X-Amz-Object-Lock-Retain-Until-Date"] = retainUntil.UTC().Format(time.RFC3339)
+	}
+	return headers, nil
+}
+
+// getS3Object downloads key from the destination bucket into w
+func getS3Object(client *http.Client, dest ReportDestination, key string, w io.Writer) error {
+	req, err := http.NewRequest(http.MethodGet, s3ObjectURL(dest, key), nil)
+	if err != nil {
+		return err
+	}
+	emptyHash := sha256.Sum256(nil)
+	signS3Request(req, hex.EncodeToString(emptyHash[:]), dest.Region, s3CredentialsFor(dest), time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("S3 download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("S3 download of %s returned %s: %s", key, resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("S3 download of %s failed: %w", key, err)
+	}
+	return nil
+}
+
+// sendS3Request sends a request with no body for key and returns the
+// response, which the caller must close. A missing object is an error
+// wrapping os.ErrNotExist, and any other status but want an error too.
+func sendS3Request(client *http.Client, dest ReportDestination, method, key string, want int) (*http.Response, error) {
+	req, err := http.NewRequest(method, s3ObjectURL(dest, key), nil)
+	if err != nil {
+		return nil, err
+	}
+	emptyHash := sha256.Sum256(nil)
+	signS3Request(req, hex.EncodeToString(emptyHash[:]), dest.Region, s3CredentialsFor(dest), time.Now())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s of %s failed: %w", method, key, err)
+	}
+	if resp.StatusCode == want {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("S3 object %s: %w", key, os.ErrNotExist)
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("S3 %s of %s returned %s: %s", method, key, resp.Status, strings.TrimSpace(string(detail)))
+}
+
+// signS3Request adds AWS Signature Version 4 headers for the s3 service to req,
+// signing every header already set on it
+func signS3Request(req *http.Request, payloadHash, region string, creds s3Credentials, now time.Time) {
+	signAWSRequest(req, payloadHash, region, "s3", creds, now)
+}
+
+// signAWSRequest adds AWS Signature Version 4 headers for service to req,
+// signing every header already set on it
+func signAWSRequest(req *http.Request, payloadHash, region, service string, creds s3Credentials, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s,SignedHeaders=%s,Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,119 @@
+package bwc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// s3BlobStore keeps evidence files as objects in an S3 bucket, under the
+// destination's prefix. With ObjectLock set, every object is locked in
+// compliance mode until its retention period ends, or put under legal hold
+// when it is retained indefinitely, so no one can change or delete it early.
+type s3BlobStore struct {
+	client *http.Client
+	dest   ReportDestination
+}
+
+func newS3BlobStore(dest ReportDestination) *s3BlobStore {
+	return &s3BlobStore{client: replicaHTTPClient, dest: dest}
+}
+
+func (s *s3BlobStore) key(key string) (string, error) {
+	if err := checkBlobKey(key); err != nil {
+		return "", err
+	}
+	return s.dest.Prefix + key, nil
+}
+
+// Put stores r under key. With Object Lock it is put under legal hold, as
+// no retention period is known.
+func (s *s3BlobStore) Put(key string, r io.Reader) (BlobInfo, error) {
+	return s.PutRetained(key, r, time.Time{})
+}
+
+// PutRetained stores r under key, locked until retainUntil when the bucket
+// uses Object Lock, or under legal hold when retainUntil is zero. S3 needs
+// the length and hashes of an object before it is sent, so r is spooled to a
+// temporary file first.
+func (s *s3BlobStore) PutRetained(key string, r io.Reader, retainUntil time.Time) (BlobInfo, error) {
+	objectKey, err := s.key(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	path, size, sum, err := spoolBlob(key, r)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	defer os.Remove(path)
+
+	var headers map[string]string
+	if s.dest.ObjectLock {
+		if headers, err = objectLockHeaders(path, retainUntil); err != nil {
+			return BlobInfo{}, err
+		}
+	}
+	// S3 refuses the upload if the body does not match sum
+	if err := putS3File(s.client, s.dest, objectKey, path, sum, headers); err != nil {
+		return BlobInfo{}, err
+	}
+	return BlobInfo{Key: key, Size: size, SHA256: sum, Modified: time.Now()}, nil
+}
+
+func (s *s3BlobStore) Get(key string) (io.ReadCloser, error) {
+	objectKey, err := s.key(key)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sendS3Request(s.client, s.dest, http.MethodGet, objectKey, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object, which S3 refuses while it is locked
+func (s *s3BlobStore) Delete(key string) error {
+	objectKey, err := s.key(key)
+	if err != nil {
+		return err
+	}
+	resp, err := sendS3Request(s.client, s.dest, http.MethodDelete, objectKey, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (s *s3BlobStore) Stat(key string) (BlobInfo, error) {
+	objectKey, err := s.key(key)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp, err := sendS3Request(s.client, s.dest, http.MethodHead, objectKey, http.StatusOK)
+	if err != nil {
+		return BlobInfo{}, err
+	}
+	resp.Body.Close()
+	info := BlobInfo{Key: key, Size: resp.ContentLength}
+	info.Modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	return info, nil
+}
+
+// HashReader streams the object through SHA-256; nothing is written to disk
+func (s *s3BlobStore) HashReader(key string) (string, error) {
+	body, err := s.Get(key)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", fmt.Errorf("S3 download of %s failed: %w", key, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,83 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestS3BlobStoreWithObjectLock(t *testing.T) {
+	bucket, server := newFakeBucket()
+	defer server.Close()
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Storage.Blobs = &ReportDestination{Type: "s3", Bucket: "evidence", Prefix: "media/", Region: "us-east-1",
+		Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret", ObjectLock: true}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-S3B-1", "OFF-1152", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	path := "/evidence/media/" + ev.BlobKey
+	headers := bucket.headers[path]
+	if ev.FilePath != "" || bucket.hashes[path] != ev.FileHash {
+		t.Fatalf("expected an upload signed with the evidence hash, got %v", bucket.hashes)
+	}
+	retainUntil, err := time.Parse(time.RFC3339, headers.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	if headers.Get("X-Amz-Object-Lock-Mode") != "COMPLIANCE" || err != nil {
+		t.Fatalf("expected the object locked in compliance mode, got %v", headers)
+	}
+	if want := time.Now().AddDate(0, 0, cfg.Storage.RetentionDays); retainUntil.Sub(want) > time.Minute || want.Sub(retainUntil) > time.Minute {
+		t.Errorf("expected the lock to last the retention period, got %s", retainUntil)
+	}
+
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Fatalf("expected the streamed object to verify, got %v, %v", valid, err)
+	}
+	if entries, _ := os.ReadDir(cfg.Storage.Path); len(entries) != 1 || entries[0].Name() != "staging" {
+		t.Errorf("expected nothing but the staging directory on local disk, got %v", entries)
+	}
+
+	store := system.blobs.(*s3BlobStore)
+	if info, err := store.Stat(ev.BlobKey); err != nil || info.Size != ev.FileSize {
+		t.Errorf("expected the object's size, got %+v, %v", info, err)
+	}
+	if err := store.Delete(ev.BlobKey); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected a locked object not to be deleted, got %v", err)
+	}
+	if _, err := store.Stat("BWC-missing.mp4"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a missing object to wrap os.ErrNotExist, got %v", err)
+	}
+
+	bucket.objects[path] = []byte("tampered")
+	if valid, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || valid {
+		t.Errorf("expected the tampered object to fail verification, got %v, %v", valid, err)
+	}
+}
+
+func TestS3BlobStoreLegalHold(t *testing.T) {
+	bucket, server := newFakeBucket()
+	defer server.Close()
+	store := newS3BlobStore(ReportDestination{Type: "s3", Bucket: "evidence", Region: "us-east-1", Endpoint: server.URL, ObjectLock: true})
+
+	info, err := store.Put("BWC-CASE-S3B-2-OFF-1153-1700000000.mp4", strings.NewReader("footage"))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if h := bucket.headers["/evidence/"+info.Key]; h.Get("X-Amz-Object-Lock-Legal-Hold") != "ON" || h.Get("Content-MD5") == "" {
+		t.Errorf("expected a legal hold with Content-MD5, got %v", h)
+	}
+	if hash, err := store.HashReader(info.Key); err != nil || hash != info.SHA256 {
+		t.Errorf("expected the stored hash, got %s, %v", hash, err)
+	}
+}
@@ -0,0 +1,113 @@
+package bwc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// scanClient sends scanned label codes to a running server's /api/scan endpoint
+type scanClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newScanClient(baseURL, token string) *scanClient {
+	return &scanClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Scan submits one code with the given action and returns the resulting custody state
+func (c *scanClient) Scan(req scanRequest) (*ScanResult, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequest(http.MethodPost, c.baseURL+"/api/scan", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&apiErr)
+		if apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return nil, errors.New(apiErr.Error)
+	}
+
+	var result ScanResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &result, nil
+}
+
+// runScanLoop reads one code per line, as keyboard-wedge barcode scanners type
+// them, and applies template's action to each. It returns the number of failures.
+func runScanLoop(client *scanClient, template scanRequest, in io.Reader, out io.Writer, prompt bool) int {
+	scanner := bufio.NewScanner(in)
+	failures := 0
+
+	for {
+		if prompt {
+			fmt.Fprint(out, "scan> ")
+		}
+		if !scanner.Scan() {
+			return failures
+		}
+
+		code := strings.TrimSpace(scanner.Text())
+		if code == "" {
+			continue
+		}
+
+		req := template
+		req.Code = code
+		result, err := client.Scan(req)
+		if err != nil {
+			fmt.Fprintf(out, "Error: %s: %v\n", code, err)
+			failures++
+			continue
+		}
+		printScanResult(out, result)
+	}
+}
+
+func printScanResult(out io.Writer, result *ScanResult) {
+	ev := result.Evidence
+	fmt.Fprintf(out, "%s  case %s  status %s\n", ev.ID, ev.CaseNumber, ev.Status)
+	fmt.Fprintf(out, "  custodian: %s\n", result.CurrentCustodian)
+	if s := ev.Seal; s != nil {
+		fmt.Fprintf(out, "  SEALED under %s since %s\n", s.Authority, s.SealedAt.Format("2006-01-02 15:04"))
+	}
+	if c := result.Checkout; c != nil {
+		fmt.Fprintf(out, "  checked out to %s since %s (%s)\n", c.CheckedOutTo,
+			c.CheckedOutAt.Format("2006-01-02 15:04"), c.Purpose)
+	}
+	if r := result.PendingRequest; r != nil {
+		fmt.Fprintf(out, "  pending transfer %s to %s (%s)\n", r.ID, r.ToOfficer, r.Purpose)
+	}
+}
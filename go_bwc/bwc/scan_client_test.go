@@ -0,0 +1,84 @@
+package bwc
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestScanLoopAgainstServer(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SCAN-010", "OFF-123", "Officer Test", "Test Location", nil)
+
+	client := newScanClient(server.URL, testAPIToken)
+
+	var out bytes.Buffer
+	in := strings.NewReader(evidence.ID + "\n\nBWC-UNKNOWN\n")
+	failures := runScanLoop(client, scanRequest{Action: "checkout", To: "COURT-7", Purpose: "Trial exhibit"}, in, &out, false)
+
+	if failures != 1 {
+		t.Errorf("Expected 1 failure, got %d: %s", failures, out.String())
+	}
+	if !strings.Contains(out.String(), "checked out to COURT-7") {
+		t.Errorf("Expected checkout in output: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "Error: BWC-UNKNOWN") {
+		t.Errorf("Expected error for unknown code: %s", out.String())
+	}
+
+	result, err := client.Scan(scanRequest{Code: evidence.ID, Action: "checkin"})
+	if err != nil {
+		t.Fatalf("Check-in scan failed: %v", err)
+	}
+	if result.Checkout != nil || result.CurrentCustodian != "CUS-001" {
+		t.Errorf("Expected evidence back with CUS-001, got %+v", result)
+	}
+
+	result, err = client.Scan(scanRequest{Code: evidence.ID, Action: "transfer", To: "DET-456", Purpose: "Analysis"})
+	if err != nil {
+		t.Fatalf("Transfer scan failed: %v", err)
+	}
+	if result.PendingRequest == nil || result.PendingRequest.ToOfficer != "DET-456" {
+		t.Errorf("Expected pending transfer request, got %+v", result)
+	}
+
+	if _, err := client.Scan(scanRequest{Code: evidence.ID, Action: "shred"}); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
+
+func TestScanRequiresJSON(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/scan", strings.NewReader("code=BWC-1"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/scan failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for form body, got %d", resp.StatusCode)
+	}
+}
+
+func TestScanCommandRequiresToken(t *testing.T) {
+	t.Setenv(EnvPrefix+"API_TOKEN", "")
+	var stdout, stderr bytes.Buffer
+
+	code := runScanCommand([]string{"-action", "lookup"}, strings.NewReader(""), &stdout, &stderr)
+	if code != 2 || !strings.Contains(stderr.String(), "API_TOKEN") {
+		t.Errorf("Expected token error, got %d: %s", code, stderr.String())
+	}
+
+	code = runScanCommand([]string{"-action", "checkout"}, strings.NewReader(""), &stdout, &stderr)
+	if code != 2 {
+		t.Errorf("Expected usage error for checkout without -to, got %d", code)
+	}
+}
@@ -0,0 +1,415 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScheduledReportType selects what a scheduled report contains
+type ScheduledReportType string
+
+const (
+	// ScheduledCaseSummary renders the case report for each case active in the period
+	ScheduledCaseSummary ScheduledReportType = "case_summary"
+	// ScheduledAuditExport exports the audit log entries recorded in the period
+	ScheduledAuditExport ScheduledReportType = "audit_export"
+	// ScheduledRetentionPreview lists evidence whose retention ends within the next period
+	ScheduledRetentionPreview ScheduledReportType = "retention_preview"
+	// ScheduledRetentionForecast groups that evidence by case and custodian
+	ScheduledRetentionForecast ScheduledReportType = "retention_forecast"
+	// ScheduledOfficerAccountability shows how much of each officer's evidence
+	// uploaded in the period is categorized
+	ScheduledOfficerAccountability ScheduledReportType = "officer_accountability"
+)
+
+// scheduleFormats lists the formats each report type supports; the first is the default
+var scheduleFormats = map[ScheduledReportType][]string{
+	ScheduledCaseSummary:           {"text", "html"},
+	ScheduledAuditExport:           {"json", "csv"},
+	ScheduledRetentionPreview:      {"csv", "json"},
+	ScheduledRetentionForecast:     {"text", "csv", "json"},
+	ScheduledOfficerAccountability: {"text", "csv", "json"},
+}
+
+// validScheduleFormat reports whether format is supported for reportType; empty selects the default
+func validScheduleFormat(reportType ScheduledReportType, format string) bool {
+	if format == "" {
+		return true
+	}
+	for _, f := range scheduleFormats[reportType] {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportArtifact is one generated file of a scheduled report. CaseNumber is
+// set for case summaries, whose deliveries are registered as copies.
+type ReportArtifact struct {
+	Name        string
+	ContentType string
+	Data        []byte
+	CaseNumber  string
+}
+
+// unsafeFileChars matches characters not allowed in artifact file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// schedulePeriod returns the period a schedule covers when run on day and
+// whether it falls due that day. The period ends at midnight starting day.
+func schedulePeriod(frequency string, day time.Time) (time.Time, time.Time, bool) {
+	end := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+
+	switch frequency {
+	case "weekly":
+		return end.AddDate(0, 0, -7), end, end.Weekday() == time.Monday
+	case "monthly":
+		return end.AddDate(0, -1, 0), end, end.Day() == 1
+	case "quarterly":
+		return end.AddDate(0, -3, 0), end, end.Day() == 1 && (end.Month()-1)%3 == 0
+	}
+	return end, end, false
+}
+
+// GenerateScheduledReport renders the artifacts of schedule for the period
+// [start, end). A case summary with no active cases has no artifacts.
+func (bwc *BWCSystem) GenerateScheduledReport(schedule ReportSchedule, start, end time.Time) ([]ReportArtifact, error) {
+	format := schedule.Format
+	if format == "" {
+		format = scheduleFormats[schedule.Type][0]
+		if schedule.Type == ScheduledAuditExport && validScheduleFormat(schedule.Type, bwc.config.Audit.ExportFormat) {
+			format = bwc.config.Audit.ExportFormat
+		}
+	}
+	if !validScheduleFormat(schedule.Type, format) {
+		return nil, fmt.Errorf("format %q is not supported for %s", format, schedule.Type)
+	}
+
+	baseName := unsafeFileChars.ReplaceAllString(schedule.Name+"_"+start.Format("2006-01-02"), "_")
+
+	switch schedule.Type {
+	case ScheduledCaseSummary:
+		return bwc.scheduledCaseSummaries(schedule, format, baseName, start, end)
+	case ScheduledAuditExport:
+		data, err := bwc.auditExport(format, start, end)
+		if err != nil {
+			return nil, err
+		}
+		return []ReportArtifact{{Name: baseName + "." + format, ContentType: artifactContentType(format), Data: data}}, nil
+	case ScheduledRetentionPreview:
+		data, err := bwc.retentionPreview(format, end, end.Sub(start))
+		if err != nil {
+			return nil, err
+		}
+		return []ReportArtifact{{Name: baseName + "." + format, ContentType: artifactContentType(format), Data: data}}, nil
+	case ScheduledRetentionForecast, ScheduledOfficerAccountability:
+		var data []byte
+		var err error
+		if schedule.Type == ScheduledRetentionForecast {
+			data, err = bwc.retentionForecastReport(format, end, int(end.Sub(start).Hours()/24))
+		} else {
+			data, err = bwc.officerAccountabilityReport(format, start, end, end)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ext := format
+		if format == "text" {
+			ext = "txt"
+		}
+		return []ReportArtifact{{Name: baseName + "." + ext, ContentType: artifactContentType(format), Data: data}}, nil
+	}
+	return nil, fmt.Errorf("unknown scheduled report type %q", schedule.Type)
+}
+
+func artifactContentType(format string) string {
+	switch format {
+	case "html":
+		return "text/html; charset=utf-8"
+	case "json":
+		return "application/json"
+	case "csv":
+		return "text/csv; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// scheduledCaseSummaries renders one case report per case in the schedule, or
+// per case with evidence ingested or modified in the period
+func (bwc *BWCSystem) scheduledCaseSummaries(schedule ReportSchedule, format, baseName string, start, end time.Time) ([]ReportArtifact, error) {
+	opts := ReportOptions{Profile: ReportProfile(schedule.Profile), Locale: Locale(schedule.Locale)}
+
+	cases := schedule.Cases
+	if len(cases) == 0 {
+		cases = bwc.casesActiveBetween(start, end)
+	}
+
+	artifacts := make([]ReportArtifact, 0, len(cases))
+	for _, caseNumber := range cases {
+		var report string
+		var err error
+		if format == "html" {
+			report, err = bwc.GenerateHTMLReport(caseNumber, opts)
+		} else {
+			report, err = bwc.GenerateCaseReport(caseNumber, opts)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("case %s: %w", caseNumber, err)
+		}
+
+		ext := "txt"
+		if format == "html" {
+			ext = "html"
+		}
+		artifacts = append(artifacts, ReportArtifact{
+			Name:        baseName + "_" + unsafeFileChars.ReplaceAllString(caseNumber, "_") + "." + ext,
+			ContentType: artifactContentType(format),
+			Data:        []byte(report),
+			CaseNumber:  caseNumber,
+		})
+	}
+	return artifacts, nil
+}
+
+// casesActiveBetween returns the sorted case numbers with evidence created or modified in [start, end)
+func (bwc *BWCSystem) casesActiveBetween(start, end time.Time) []string {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	inPeriod := func(t time.Time) bool { return !t.Before(start) && t.Before(end) }
+	seen := make(map[string]bool)
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if inPeriod(evidence.CreatedAt) || inPeriod(evidence.LastModified) {
+			seen[evidence.CaseNumber] = true
+		}
+	}
+
+	cases := make([]string, 0, len(seen))
+	for caseNumber := range seen {
+		cases = append(cases, caseNumber)
+	}
+	sort.Strings(cases)
+	return cases
+}
+
+// auditExport renders the audit log entries recorded in [start, end)
+func (bwc *BWCSystem) auditExport(format string, start, end time.Time) ([]byte, error) {
+	logs := make([]AuditLog, 0)
+	for _, log := range bwc.GetAuditLogs("", "") {
+		if !log.Timestamp.Before(start) && log.Timestamp.Before(end) {
+			logs = append(logs, log)
+		}
+	}
+
+	if format == "json" {
+		return json.MarshalIndent(logs, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"timestamp", "user_id", "action", "evidence_id", "details", "ip_address"})
+	for _, log := range logs {
+		w.Write([]string{log.Timestamp.Format(time.RFC3339), log.UserID, log.Action, log.EvidenceID, log.Details, log.IPAddress})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// retentionPreview renders evidence whose retention ends before from+window
+func (bwc *BWCSystem) retentionPreview(format string, from time.Time, window time.Duration) ([]byte, error) {
+	items := bwc.RetentionDue(from, window)
+
+	if format == "json" {
+		return json.MarshalIndent(items, "", "  ")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"evidence_id", "case_number", "officer_id", "status", "retention_days", "expires_at", "days_remaining"})
+	for _, item := range items {
+		w.Write([]string{item.EvidenceID, item.CaseNumber, item.OfficerID, item.Status,
+			strconv.Itoa(item.RetentionDays), item.ExpiresAt.Format(time.RFC3339), strconv.Itoa(item.DaysRemaining)})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// retentionForecastReport renders the retention forecast for days from from
+func (bwc *BWCSystem) retentionForecastReport(format string, from time.Time, days int) ([]byte, error) {
+	forecast, err := bwc.RetentionForecast(from, days)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "json":
+		return json.MarshalIndent(forecast, "", "  ")
+	case "csv":
+		return forecast.CSV()
+	}
+	return []byte(forecast.Text()), nil
+}
+
+// officerAccountabilityReport renders the officer accountability report for
+// evidence uploaded in [from, to), with deadlines judged as of now
+func (bwc *BWCSystem) officerAccountabilityReport(format string, from, to, now time.Time) ([]byte, error) {
+	report, err := bwc.OfficerAccountability(from, to, now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "json":
+		return json.MarshalIndent(report, "", "  ")
+	case "csv":
+		return report.CSV()
+	}
+	return []byte(report.Text()), nil
+}
+
+// reportScheduler generates scheduled reports when they fall due and delivers them
+type reportScheduler struct {
+	system *BWCSystem
+	mail   mailer
+	http   *http.Client
+}
+
+func newReportScheduler(system *BWCSystem) *reportScheduler {
+	return &reportScheduler{
+		system: system,
+		mail:   newSMTPMailer(system.config.Notifications),
+		http:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// RunDue runs every schedule that falls due on day's date. Failures do not
+// stop other schedules and are returned together.
+func (s *reportScheduler) RunDue(day time.Time) error {
+	var errs []error
+	for _, schedule := range s.system.config.Reports.Schedules {
+		start, end, due := schedulePeriod(schedule.Frequency, day)
+		if !due {
+			continue
+		}
+		if err := s.RunSchedule(schedule, start, end); err != nil {
+			errs = append(errs, fmt.Errorf("scheduled report %s: %w", schedule.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunSchedule generates schedule for [start, end) and delivers it to every destination
+func (s *reportScheduler) RunSchedule(schedule ReportSchedule, start, end time.Time) error {
+	artifacts, err := s.system.GenerateScheduledReport(schedule, start, end)
+	if err != nil {
+		return err
+	}
+	if len(artifacts) == 0 {
+		s.system.logAudit("SYSTEM", "SCHEDULED_REPORT", "",
+			fmt.Sprintf("%s for %s had nothing to report", schedule.Name, start.Format("2006-01-02")), "")
+		return nil
+	}
+
+	var errs []error
+	var delivered []string
+	for _, dest := range schedule.Destinations {
+		if err := s.deliver(schedule, start, end, dest, artifacts); err != nil {
+			errs = append(errs, fmt.Errorf("%s destination: %w", dest.Type, err))
+			continue
+		}
+		delivered = append(delivered, dest.Type)
+		for _, a := range artifacts {
+			if a.CaseNumber != "" {
+				s.system.registerCaseCopy(a.CaseNumber, CopyReport, "SYSTEM", describeDestination(dest),
+					"Scheduled report "+schedule.Name, a.Data)
+			}
+		}
+	}
+
+	if len(delivered) > 0 {
+		s.system.logAudit("SYSTEM", "SCHEDULED_REPORT", "",
+			fmt.Sprintf("%s for %s (%d files) delivered to %s", schedule.Name, start.Format("2006-01-02"),
+				len(artifacts), strings.Join(delivered, ", ")), "")
+	}
+	return errors.Join(errs...)
+}
+
+// deliver sends the artifacts to one destination
+func (s *reportScheduler) deliver(schedule ReportSchedule, start, end time.Time, dest ReportDestination, artifacts []ReportArtifact) error {
+	switch dest.Type {
+	case "directory":
+		if err := os.MkdirAll(dest.Path, 0700); err != nil {
+			return fmt.Errorf("failed to create report directory: %w", err)
+		}
+		for _, a := range artifacts {
+			if err := os.WriteFile(filepath.Join(dest.Path, a.Name), a.Data, 0600); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+		return nil
+
+	case "email":
+		attachments := make([]mailAttachment, 0, len(artifacts))
+		names := make([]string, 0, len(artifacts))
+		for _, a := range artifacts {
+			attachments = append(attachments, mailAttachment{Name: a.Name, ContentType: a.ContentType, Data: a.Data})
+			names = append(names, "  "+a.Name)
+		}
+		subject := fmt.Sprintf("[%s] %s report for %s to %s", s.system.config.System.Name, schedule.Name,
+			start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+		body := fmt.Sprintf("Scheduled report %s is attached:\n%s\n", schedule.Name, strings.Join(names, "\n"))
+		return s.mail.SendMail(dest.Recipients, subject, body, attachments...)
+
+	case "s3":
+		for _, a := range artifacts {
+			if err := putS3Object(s.http, dest, dest.Prefix+a.Name, a.ContentType, a.Data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown destination type %q", dest.Type)
+}
+
+// describeDestination names a destination for the copy registry
+func describeDestination(dest ReportDestination) string {
+	switch dest.Type {
+	case "directory":
+		return dest.Path
+	case "email":
+		return "email to " + strings.Join(dest.Recipients, ", ")
+	case "s3":
+		return "s3://" + dest.Bucket + "/" + dest.Prefix
+	}
+	return dest.Type
+}
+
+// Run checks for due reports every day at reports.hour local time until stop is closed
+func (s *reportScheduler) Run(stop <-chan struct{}, logf func(format string, args ...interface{})) {
+	for {
+		next := nextDailyRun(time.Now(), s.system.config.Reports.Hour)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.RunDue(next); err != nil {
+			logf("Scheduled report delivery failed: %v\n", err)
+		}
+	}
+}
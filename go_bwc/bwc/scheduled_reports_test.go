@@ -0,0 +1,167 @@
+package bwc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedulePeriod(t *testing.T) {
+	monday := time.Date(2025, 3, 10, 6, 0, 0, 0, time.UTC)
+	start, end, due := schedulePeriod("weekly", monday)
+	if !due || !start.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected weekly period %v - %v due=%v", start, end, due)
+	}
+	if _, _, due := schedulePeriod("weekly", monday.AddDate(0, 0, 1)); due {
+		t.Error("Weekly report should not be due on Tuesday")
+	}
+
+	first := time.Date(2025, 4, 1, 6, 0, 0, 0, time.UTC)
+	start, _, due = schedulePeriod("monthly", first)
+	if !due || !start.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected monthly period start %v due=%v", start, due)
+	}
+	start, _, due = schedulePeriod("quarterly", first)
+	if !due || !start.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected quarterly period start %v due=%v", start, due)
+	}
+	if _, _, due := schedulePeriod("quarterly", time.Date(2025, 5, 1, 6, 0, 0, 0, time.UTC)); due {
+		t.Error("Quarterly report should not be due on May 1st")
+	}
+}
+
+func TestGenerateScheduledReport(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Storage.RetentionDays = 30
+
+	testFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(testFile, "CASE SCH-001", "OFF-201", "Officer Test", "Test Location", nil)
+
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	summaries, err := system.GenerateScheduledReport(ReportSchedule{Name: "weekly cases", Type: ScheduledCaseSummary, Format: "html"}, start, end)
+	if err != nil {
+		t.Fatalf("Case summary failed: %v", err)
+	}
+	if len(summaries) != 1 || !strings.HasSuffix(summaries[0].Name, "_CASE_SCH-001.html") || !strings.HasPrefix(summaries[0].Name, "weekly_cases_") {
+		t.Fatalf("Unexpected case summary artifacts: %+v", summaries)
+	}
+	if !strings.Contains(string(summaries[0].Data), "<html") {
+		t.Error("Expected an HTML case report")
+	}
+
+	audit, err := system.GenerateScheduledReport(ReportSchedule{Name: "audit", Type: ScheduledAuditExport, Format: "csv"}, start, end)
+	if err != nil {
+		t.Fatalf("Audit export failed: %v", err)
+	}
+	if !strings.HasPrefix(string(audit[0].Data), "timestamp,user_id,action") || !strings.Contains(string(audit[0].Data), "INGEST_EVIDENCE") {
+		t.Errorf("Unexpected audit export:\n%s", audit[0].Data)
+	}
+
+	// A 30-day window after end covers the 30-day retention of the new item
+	preview, err := system.GenerateScheduledReport(ReportSchedule{Name: "retention", Type: ScheduledRetentionPreview}, end.AddDate(0, 0, -30), end)
+	if err != nil {
+		t.Fatalf("Retention preview failed: %v", err)
+	}
+	if !strings.HasSuffix(preview[0].Name, ".csv") || !strings.Contains(string(preview[0].Data), "CASE SCH-001") {
+		t.Errorf("Unexpected retention preview:\n%s", preview[0].Data)
+	}
+
+	idle, err := system.GenerateScheduledReport(ReportSchedule{Name: "idle", Type: ScheduledCaseSummary}, end, end.Add(time.Hour))
+	if err != nil || len(idle) != 0 {
+		t.Errorf("Expected no artifacts for an idle period, got %d (%v)", len(idle), err)
+	}
+
+	if _, err := system.GenerateScheduledReport(ReportSchedule{Name: "bad", Type: ScheduledAuditExport, Format: "html"}, start, end); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestRunScheduleDelivers(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(testFile, "CASE-SCH-010", "OFF-202", "Officer Test", "Test Location", nil)
+
+	var uploaded []string
+	bucket := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploaded = append(uploaded, r.URL.Path)
+	}))
+	defer bucket.Close()
+
+	outDir := filepath.Join(tmpDir, "reports")
+	schedule := ReportSchedule{
+		Name: "audit",
+		Type: ScheduledAuditExport,
+		Destinations: []ReportDestination{
+			{Type: "directory", Path: outDir},
+			{Type: "email", Recipients: []string{"records@example.gov"}},
+			{Type: "s3", Bucket: "reports", Prefix: "bwc/", Endpoint: bucket.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		},
+	}
+
+	mail := &fakeMailer{}
+	scheduler := newReportScheduler(system)
+	scheduler.mail = mail
+	scheduler.http = bucket.Client()
+
+	start := time.Now().Add(-time.Hour)
+	if err := scheduler.RunSchedule(schedule, start, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("RunSchedule failed: %v", err)
+	}
+
+	name := "audit_" + start.Format("2006-01-02") + ".json"
+	if info, err := os.Stat(filepath.Join(outDir, name)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected %s written with mode 0600: %v", name, err)
+	}
+	if len(mail.sent) != 1 || len(mail.sent[0].attachments) != 1 || mail.sent[0].attachments[0].Name != name {
+		t.Errorf("Unexpected mail: %+v", mail.sent)
+	}
+	if len(uploaded) != 1 || uploaded[0] != "/reports/bwc/"+name {
+		t.Errorf("Unexpected uploads: %v", uploaded)
+	}
+
+	found := false
+	for _, log := range system.GetAuditLogs("", "SYSTEM") {
+		if log.Action == "SCHEDULED_REPORT" && strings.Contains(log.Details, "directory, email, s3") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected SCHEDULED_REPORT audit entry")
+	}
+}
+
+func TestReportScheduleValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Notifications.SMTPHost = ""
+	cfg.Reports.Hour = -1
+	cfg.Reports.Schedules = []ReportSchedule{
+		{Name: "a", Type: "bogus", Frequency: "daily"},
+		{Name: "a", Type: ScheduledAuditExport, Frequency: "monthly", Format: "html", Destinations: []ReportDestination{
+			{Type: "email", Recipients: []string{"x@example.gov"}},
+			{Type: "s3"},
+			{Type: "ftp"},
+		}},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, want := range []string{
+		"reports.hour", "schedules[0].type", "schedules[0].frequency", "schedules[0].destinations must not be empty",
+		"schedules[1] duplicates", "schedules[1].format", "smtp_host", "destinations[1] requires bucket", "destinations[2].type",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected problem mentioning %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package bwc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scrubSampleChunks is how many chunks of each recording a sampled scrub hashes
+const scrubSampleChunks = 4
+
+// scrubTemporaryAge is how old an unclaimed upload, staging or temporary file
+// must be before a scrub reports it; younger ones may belong to work under way
+const scrubTemporaryAge = 24 * time.Hour
+
+// ScrubFindingKind is what a scrub found wrong with a file
+type ScrubFindingKind string
+
+const (
+	ScrubOrphanFile     ScrubFindingKind = "ORPHAN_FILE"
+	ScrubStaleTemporary ScrubFindingKind = "STALE_TEMPORARY"
+	ScrubMissingFile    ScrubFindingKind = "MISSING_FILE"
+	ScrubUnreadableFile ScrubFindingKind = "UNREADABLE_FILE"
+	ScrubSizeMismatch   ScrubFindingKind = "SIZE_MISMATCH"
+	ScrubHashMismatch   ScrubFindingKind = "HASH_MISMATCH"
+)
+
+// ScrubAction is the remediation a scrub suggests for a finding
+type ScrubAction string
+
+const (
+	ScrubRepairFromParity     ScrubAction = "REPAIR_FROM_PARITY"
+	ScrubRequestReplicaRepair ScrubAction = "REQUEST_REPLICA_REPAIR"
+	ScrubRegenerateParity     ScrubAction = "REGENERATE_PARITY"
+	ScrubReprocess            ScrubAction = "REPROCESS"
+	ScrubRemoveFile           ScrubAction = "REMOVE_FILE"
+	ScrubReviewOrphan         ScrubAction = "REVIEW_ORPHAN"
+	ScrubInvestigate          ScrubAction = "INVESTIGATE"
+)
+
+// ScrubFileRole is what a file in storage holds
+type ScrubFileRole string
+
+const (
+	ScrubRoleEvidence  ScrubFileRole = "EVIDENCE"
+	ScrubRoleParity    ScrubFileRole = "PARITY"
+	ScrubRoleDerived   ScrubFileRole = "DERIVED"
+	ScrubRoleTemporary ScrubFileRole = "TEMPORARY"
+)
+
+// ScrubFinding is one problem found by a scrub, with the suggested fix
+type ScrubFinding struct {
+	Kind          ScrubFindingKind `json:"kind"`
+	Path          string           `json:"path"`
+	Role          ScrubFileRole    `json:"role,omitempty"`
+	EvidenceID    string           `json:"evidence_id,omitempty"`
+	ExpectedSize  int64            `json:"expected_size,omitempty"`
+	ActualSize    int64            `json:"actual_size,omitempty"`
+	ExpectedHash  string           `json:"expected_hash,omitempty"`
+	ActualHash    string           `json:"actual_hash,omitempty"`
+	CorruptRanges []ByteRange      `json:"corrupt_ranges,omitempty"`
+	Detail        string           `json:"detail"`
+	Action        ScrubAction      `json:"action"`
+}
+
+// ScrubReport is the outcome of a storage scrub. It is meant to be read by
+// remediation tooling as well as people.
+type ScrubReport struct {
+	StoragePath string    `json:"storage_path"`
+	Full        bool      `json:"full"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+
+	// RecordsChecked counts evidence records whose files were checked, and
+	// FilesScanned the files found walking storage
+	RecordsChecked int   `json:"records_checked"`
+	FilesScanned   int   `json:"files_scanned"`
+	BytesScanned   int64 `json:"bytes_scanned"`
+	BytesHashed    int64 `json:"bytes_hashed"`
+	// FilesHashed counts files whose hash, or a sample of whose chunks, was
+	// checked; FilesNotHashed those only checked for size
+	FilesHashed    int `json:"files_hashed"`
+	FilesNotHashed int `json:"files_not_hashed"`
+
+	Findings []ScrubFinding `json:"findings"`
+}
+
+// Clean reports whether the scrub found nothing wrong
+func (r *ScrubReport) Clean() bool {
+	return len(r.Findings) == 0
+}
+
+// Counts tallies the findings by kind
+func (r *ScrubReport) Counts() map[ScrubFindingKind]int {
+	counts := make(map[ScrubFindingKind]int)
+	for _, f := range r.Findings {
+		counts[f.Kind]++
+	}
+	return counts
+}
+
+// Text renders the report for people: a summary, then each finding with its
+// suggested remediation
+func (r *ScrubReport) Text() string {
+	var b strings.Builder
+	mode := "sampled"
+	if r.Full {
+		mode = "full"
+	}
+	fmt.Fprintf(&b, "STORAGE SCRUB (%s)\nStorage: %s\nStarted: %s\nFinished: %s\n", mode, r.StoragePath,
+		r.StartedAt.Format(time.RFC3339), r.FinishedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Records checked: %d; files scanned: %d (%d bytes)\n", r.RecordsChecked, r.FilesScanned, r.BytesScanned)
+	fmt.Fprintf(&b, "Files hashed: %d (%d bytes); size only: %d\n", r.FilesHashed, r.BytesHashed, r.FilesNotHashed)
+	if r.Clean() {
+		b.WriteString("\nNo problems found\n")
+		return b.String()
+	}
+
+	counts := r.Counts()
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+	fmt.Fprintf(&b, "\nFindings: %d\n", len(r.Findings))
+	for _, kind := range kinds {
+		fmt.Fprintf(&b, "  %-16s %d\n", kind, counts[ScrubFindingKind(kind)])
+	}
+	b.WriteString("\n")
+	for _, f := range r.Findings {
+		fmt.Fprintf(&b, "%s  %s\n", f.Kind, f.Path)
+		if f.EvidenceID != "" {
+			fmt.Fprintf(&b, "  evidence %s\n", f.EvidenceID)
+		}
+		fmt.Fprintf(&b, "  %s\n  action: %s\n", f.Detail, f.Action)
+	}
+	return b.String()
+}
+
+// scrubTarget is a file a record says should be in storage
+type scrubTarget struct {
+	path       string
+	role       ScrubFileRole
+	evidenceID string
+	size       int64 // -1 when the record does not say
+	hash       string
+	manifest   *ChunkManifest
+	// parity and replica say what a damaged recording can be restored from
+	parity  bool
+	replica bool
+}
+
+// scrubClaims is what the records say storage holds: the files to check,
+// files left over from purged evidence, and staged copies of interrupted
+// ingests
+type scrubClaims struct {
+	targets map[string]*scrubTarget
+	purged  map[string]string
+	staged  map[string]bool
+}
+
+// scrubClaimsLocked snapshots the files records claim; the caller must hold bwc.mu
+func (bwc *BWCSystem) scrubClaimsLocked() *scrubClaims {
+	claims := &scrubClaims{
+		targets: make(map[string]*scrubTarget),
+		purged:  make(map[string]string),
+		staged:  make(map[string]bool),
+	}
+	for _, evidence := range bwc.evidenceDB.Search(nil) {
+		if evidence.Status == StatusDeleted {
+			files, _ := bwc.storedFiles(evidence)
+			for _, path := range files {
+				claims.purged[filepath.Clean(path)] = evidence.ID
+			}
+			continue
+		}
+		target := &scrubTarget{
+			path:       evidence.FilePath,
+			role:       ScrubRoleEvidence,
+			evidenceID: evidence.ID,
+			size:       storedSize(evidence),
+			hash:       storedHash(evidence),
+			manifest:   evidence.ChunkManifest,
+			parity:     evidence.Parity != nil,
+			replica:    evidence.Replica != nil,
+		}
+		if evidence.Encryption != nil {
+			// Chunk hashes are of the recording, not of the encrypted file
+			target.manifest = nil
+		}
+		claims.targets[filepath.Clean(evidence.FilePath)] = target
+		if evidence.Parity != nil {
+			claims.targets[filepath.Clean(evidence.Parity.Path)] = &scrubTarget{
+				path: evidence.Parity.Path, role: ScrubRoleParity, evidenceID: evidence.ID, size: -1, hash: evidence.Parity.SHA256,
+			}
+		}
+		derived := make([]*DerivedFile, 0)
+		if evidence.Photo != nil && evidence.Photo.Thumbnail != nil {
+			derived = append(derived, evidence.Photo.Thumbnail)
+		}
+		if evidence.Document != nil && evidence.Document.Text != nil {
+			derived = append(derived, evidence.Document.Text)
+		}
+		for _, result := range evidence.Processing {
+			for i := range result.Outputs {
+				derived = append(derived, &result.Outputs[i])
+			}
+		}
+		for _, d := range derived {
+			claims.targets[filepath.Clean(d.Path)] = &scrubTarget{
+				path: d.Path, role: ScrubRoleDerived, evidenceID: evidence.ID, size: d.Size, hash: d.SHA256,
+			}
+		}
+	}
+	for _, stage := range bwc.stagedIngests {
+		claims.staged[filepath.Clean(bwc.stagedPartialPath(stage))] = true
+		claims.staged[filepath.Clean(bwc.stagedJournalPath(stage))] = true
+	}
+	return claims
+}
+
+// Scrub walks storage and checks it against the evidence records. Every file
+// a record names must exist at its recorded size; every file in storage must
+// belong to a record. A full scrub hashes every file. A sampled scrub hashes
+// a few random chunks of each recording that has chunk hashes and checks only
+// sizes otherwise. Scrub changes nothing: each finding carries the
+// remediation to apply.
+func (bwc *BWCSystem) Scrub(userID string, full bool) (*ScrubReport, error) {
+	return bwc.ScrubContext(context.Background(), userID, full)
+}
+
+// ScrubContext scrubs like Scrub, recording the findings against the principal
+// ctx carries when it has one
+func (bwc *BWCSystem) ScrubContext(ctx context.Context, userID string, full bool) (*ScrubReport, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermAdminister, "Storage scrub", ""); err != nil {
+		return nil, err
+	}
+	report := &ScrubReport{StoragePath: bwc.storagePath, Full: full, StartedAt: time.Now(), Findings: make([]ScrubFinding, 0)}
+
+	// Files are read without holding the lock, so a long scrub does not stall
+	// the system; findings are checked against the records again at the end
+	bwc.mu.RLock()
+	claims := bwc.scrubClaimsLocked()
+	bwc.mu.RUnlock()
+
+	records := make(map[string]bool)
+	paths := make([]string, 0, len(claims.targets))
+	for path, target := range claims.targets {
+		paths = append(paths, path)
+		if target.role == ScrubRoleEvidence {
+			records[target.evidenceID] = true
+		}
+	}
+	sort.Strings(paths)
+	report.RecordsChecked = len(records)
+
+	var findings []ScrubFinding
+	for _, path := range paths {
+		if f := bwc.scrubTarget(claims.targets[path], full, report); f != nil {
+			findings = append(findings, *f)
+		}
+	}
+
+	err := filepath.WalkDir(bwc.storagePath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			findings = append(findings, ScrubFinding{Kind: ScrubUnreadableFile, Path: path, Detail: err.Error(), Action: ScrubInvestigate})
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		report.FilesScanned++
+		report.BytesScanned += info.Size()
+
+		clean := filepath.Clean(path)
+		if claims.targets[clean] != nil || claims.staged[clean] {
+			return nil
+		}
+		if f := bwc.scrubUnclaimed(clean, info, claims); f != nil {
+			findings = append(findings, *f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk storage: %w", err)
+	}
+
+	// Drop findings the records have since caught up with: files ingested,
+	// purged, repaired or reprocessed while the scrub ran
+	bwc.mu.RLock()
+	current := bwc.scrubClaimsLocked()
+	bwc.mu.RUnlock()
+	for _, f := range findings {
+		clean := filepath.Clean(f.Path)
+		switch f.Kind {
+		case ScrubOrphanFile, ScrubStaleTemporary:
+			if current.targets[clean] != nil || current.staged[clean] {
+				continue
+			}
+		case ScrubUnreadableFile:
+		default:
+			was, now := claims.targets[clean], current.targets[clean]
+			if now == nil || now.hash != was.hash || now.size != was.size {
+				continue
+			}
+		}
+		report.Findings = append(report.Findings, f)
+	}
+	report.FinishedAt = time.Now()
+
+	mode := "Sampled"
+	if full {
+		mode = "Full"
+	}
+	for _, f := range report.Findings {
+		if f.EvidenceID != "" && f.Kind != ScrubOrphanFile {
+			bwc.logAuditAs(auth, userID, "SCRUB_FINDING", f.EvidenceID, fmt.Sprintf("%s %s: %s", f.Kind, f.Path, f.Detail), "")
+		}
+	}
+	bwc.logAuditAs(auth, userID, "SCRUB_STORAGE", "",
+		fmt.Sprintf("%s scrub of %d records and %d files (%d bytes hashed): %d findings",
+			mode, report.RecordsChecked, report.FilesScanned, report.BytesHashed, len(report.Findings)), "")
+	return report, nil
+}
+
+// scrubTarget checks one file a record names, returning a finding when it is
+// missing, the wrong size or does not hash as recorded
+func (bwc *BWCSystem) scrubTarget(t *scrubTarget, full bool, report *ScrubReport) *ScrubFinding {
+	finding := &ScrubFinding{Path: t.path, Role: t.role, EvidenceID: t.evidenceID, Action: t.remediation(false)}
+
+	info, err := os.Stat(t.path)
+	if os.IsNotExist(err) {
+		finding.Kind, finding.Action = ScrubMissingFile, t.remediation(true)
+		if t.size >= 0 {
+			finding.ExpectedSize = t.size
+		}
+		finding.Detail = fmt.Sprintf("%s file is missing", strings.ToLower(string(t.role)))
+		return finding
+	}
+	if err != nil {
+		finding.Kind, finding.Detail, finding.Action = ScrubUnreadableFile, err.Error(), ScrubInvestigate
+		return finding
+	}
+	if t.size >= 0 && info.Size() != t.size {
+		finding.Kind = ScrubSizeMismatch
+		finding.ExpectedSize, finding.ActualSize = t.size, info.Size()
+		finding.Detail = fmt.Sprintf("file is %d bytes, recorded as %d", info.Size(), t.size)
+		return finding
+	}
+
+	// A sampled scrub learns only which chunks differ, not the file's hash
+	var hash string
+	var corrupt []ByteRange
+	switch {
+	case full && t.manifest != nil:
+		hash, corrupt, err = t.manifest.corruptRanges(t.path, t.size)
+		report.BytesHashed += info.Size()
+	case full:
+		hash, err = calculateFileHash(t.path)
+		report.BytesHashed += info.Size()
+	case t.manifest != nil:
+		var hashed int64
+		corrupt, hashed, err = t.manifest.sampleChunks(t.path, t.size, scrubSampleChunks)
+		report.BytesHashed += hashed
+	default:
+		report.FilesNotHashed++
+		return nil
+	}
+	if err != nil {
+		finding.Kind, finding.Detail, finding.Action = ScrubUnreadableFile, err.Error(), ScrubInvestigate
+		return finding
+	}
+	report.FilesHashed++
+	if (full && hash == t.hash) || (!full && len(corrupt) == 0) {
+		return nil
+	}
+
+	finding.Kind = ScrubHashMismatch
+	finding.ExpectedHash, finding.ActualHash = t.hash, hash
+	finding.CorruptRanges = corrupt
+	finding.Detail = "file does not hash as recorded"
+	if len(corrupt) > 0 {
+		finding.Detail += "; corrupted " + formatByteRanges(corrupt)
+	}
+	return finding
+}
+
+// remediation picks the fix for a damaged or missing file. A damaged
+// recording is rebuilt from its parity file when it has one, else restored
+// from its replica; parity and derived files are regenerated from the
+// recording.
+func (t *scrubTarget) remediation(missing bool) ScrubAction {
+	switch t.role {
+	case ScrubRoleParity:
+		return ScrubRegenerateParity
+	case ScrubRoleDerived:
+		return ScrubReprocess
+	}
+	switch {
+	case t.parity && !missing:
+		return ScrubRepairFromParity
+	case t.replica:
+		return ScrubRequestReplicaRepair
+	}
+	return ScrubInvestigate
+}
+
+// scrubUnclaimed classifies a file in storage that no record names. Uploads,
+// staging files and dot-files are work in progress until they grow stale.
+func (bwc *BWCSystem) scrubUnclaimed(path string, info fs.FileInfo, claims *scrubClaims) *ScrubFinding {
+	finding := &ScrubFinding{Path: path, ActualSize: info.Size()}
+	if id, ok := claims.purged[path]; ok {
+		finding.Kind, finding.EvidenceID, finding.Action = ScrubOrphanFile, id, ScrubRemoveFile
+		finding.Detail = "file of purged evidence was not removed"
+		return finding
+	}
+
+	rel, err := filepath.Rel(filepath.Clean(bwc.storagePath), path)
+	if err != nil {
+		rel = path
+	}
+	top := strings.SplitN(filepath.ToSlash(rel), "/", 2)[0]
+	if top == "uploads" || top == "staging" || strings.HasPrefix(info.Name(), ".") {
+		if time.Since(info.ModTime()) < scrubTemporaryAge {
+			return nil
+		}
+		finding.Kind, finding.Role, finding.Action = ScrubStaleTemporary, ScrubRoleTemporary, ScrubRemoveFile
+		finding.Detail = fmt.Sprintf("temporary file untouched since %s", info.ModTime().UTC().Format(time.RFC3339))
+		return finding
+	}
+
+	finding.Kind, finding.Action = ScrubOrphanFile, ScrubReviewOrphan
+	finding.Detail = "no evidence record refers to this file"
+	if top == "derived" {
+		if parts := strings.Split(filepath.ToSlash(rel), "/"); len(parts) > 1 {
+			finding.EvidenceID = parts[1]
+		}
+		finding.Role = ScrubRoleDerived
+	}
+	return finding
+}
+
+// sampleChunks hashes n randomly chosen chunks of the file at path, returning
+// the byte ranges of those that differ from the manifest and how many bytes
+// were read
+func (m *ChunkManifest) sampleChunks(path string, size int64, n int) ([]ByteRange, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	indexes, err := sampleIndexes(len(m.Hashes), n)
+	if err != nil {
+		return nil, 0, err
+	}
+	var corrupt []ByteRange
+	var hashed int64
+	for _, i := range indexes {
+		start := int64(i) * m.ChunkSize
+		h := sha256.New()
+		read, err := io.Copy(h, io.NewSectionReader(file, start, m.ChunkSize))
+		if err != nil {
+			return nil, hashed, err
+		}
+		hashed += read
+		if hex.EncodeToString(h.Sum(nil)) != m.Hashes[i] {
+			end := start + m.ChunkSize - 1
+			if end >= size {
+				end = size - 1
+			}
+			corrupt = append(corrupt, ByteRange{Start: start, End: end})
+		}
+	}
+	return corrupt, hashed, nil
+}
+
+// sampleIndexes picks up to n distinct indexes below total, in order
+func sampleIndexes(total, n int) ([]int, error) {
+	pool := make([]int, total)
+	for i := range pool {
+		pool[i] = i
+	}
+	if n > total {
+		n = total
+	}
+	for i := 0; i < n; i++ {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(total-i)))
+		if err != nil {
+			return nil, err
+		}
+		k := i + int(j.Int64())
+		pool[i], pool[k] = pool[k], pool[i]
+	}
+	picked := pool[:n]
+	sort.Ints(picked)
+	return picked, nil
+}
@@ -0,0 +1,43 @@
+package bwc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// requestScrub asks a running server to scrub its storage. It returns the
+// report as the server sent it, for saving, and decoded. There is no timeout:
+// a full scrub reads every file in storage.
+func requestScrub(baseURL, token string, full bool) ([]byte, *ScrubReport, error) {
+	url := strings.TrimRight(baseURL, "/") + "/api/storage/scrub"
+	if full {
+		url += "?full=true"
+	}
+	req, err := http.NewRequest(http.MethodPost, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, apiError(resp)
+	}
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var report ScrubReport
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return nil, nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return raw, &report, nil
+}
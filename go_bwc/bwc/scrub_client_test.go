@@ -0,0 +1,55 @@
+package bwc
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunScrubCommand(t *testing.T) {
+	_, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	t.Setenv(EnvPrefix+"API_TOKEN", testAPIToken)
+
+	var stdout, stderr bytes.Buffer
+	if code := runScrubCommand([]string{"-server", server.URL}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected a clean scrub, got %d: %s", code, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "No problems found") {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	orphan := filepath.Join(tmpDir, "unknown.mp4")
+	os.WriteFile(orphan, []byte("stray"), 0600)
+	reportFile := filepath.Join(t.TempDir(), "scrub.json")
+	stdout.Reset()
+	if code := runScrubCommand([]string{"-server", server.URL, "-full", "-report", reportFile}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected findings to fail the command, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "ORPHAN_FILE  "+orphan) {
+		t.Errorf("expected the orphan in the summary:\n%s", stdout.String())
+	}
+
+	data, err := os.ReadFile(reportFile)
+	if err != nil {
+		t.Fatalf("expected the report to be saved: %v", err)
+	}
+	var report ScrubReport
+	if err := json.Unmarshal(data, &report); err != nil || !report.Full || len(report.Findings) != 1 || report.Findings[0].Action != ScrubReviewOrphan {
+		t.Errorf("unexpected saved report %s", data)
+	}
+
+	stdout.Reset()
+	runScrubCommand([]string{"-server", server.URL, "-report", "-"}, &stdout, &stderr)
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil || report.Full {
+		t.Errorf("expected the JSON report on stdout, got %s", stdout.String())
+	}
+
+	t.Setenv(EnvPrefix+"API_TOKEN", "wrong-token")
+	if code := runScrubCommand([]string{"-server", server.URL}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected a rejected token to fail, got %d", code)
+	}
+}
@@ -0,0 +1,184 @@
+package bwc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scrubFinding returns the finding for path, failing the test when there is none
+func scrubFinding(t *testing.T, report *ScrubReport, path string) ScrubFinding {
+	t.Helper()
+	for _, f := range report.Findings {
+		if f.Path == path {
+			return f
+		}
+	}
+	t.Fatalf("expected a finding for %s, got %+v", path, report.Findings)
+	return ScrubFinding{}
+}
+
+func TestScrubCleanStorage(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.ChunkSizeMB = 1
+
+	if _, err := system.IngestEvidence(writeRecording(t, t.TempDir()), "CASE-SCR-1", "OFF-1122", "", "", nil); err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+
+	for _, full := range []bool{false, true} {
+		report, err := system.Scrub("CUS-001", full)
+		if err != nil {
+			t.Fatalf("Scrub failed: %v", err)
+		}
+		if !report.Clean() || report.RecordsChecked != 1 || report.FilesScanned != 1 || report.FilesHashed != 1 {
+			t.Errorf("expected a clean scrub of one file, got %+v", report)
+		}
+		if full && report.BytesHashed != 3<<20 {
+			t.Errorf("expected a full scrub to hash the whole file, got %d bytes", report.BytesHashed)
+		}
+		if !strings.Contains(report.Text(), "No problems found") {
+			t.Errorf("unexpected report text:\n%s", report.Text())
+		}
+	}
+	if logs := system.GetAuditLogs("", "CUS-001"); len(logs) != 2 || logs[1].Action != "SCRUB_STORAGE" {
+		t.Errorf("expected each scrub to be audited, got %+v", logs)
+	}
+}
+
+func TestScrubFindings(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.ChunkSizeMB = 1
+
+	sources := t.TempDir()
+	ingest := func(caseNumber string) *Evidence {
+		ev, err := system.IngestEvidence(writeRecording(t, sources), caseNumber, "OFF-1122", "", "", nil)
+		if err != nil {
+			t.Fatalf("IngestEvidence failed: %v", err)
+		}
+		return ev
+	}
+	corrupted, truncated, missing := ingest("CASE-SCR-2"), ingest("CASE-SCR-3"), ingest("CASE-SCR-4")
+	checks := len(corrupted.IntegrityChecks)
+
+	// One byte in the second chunk flips
+	f, _ := os.OpenFile(corrupted.FilePath, os.O_WRONLY, 0)
+	f.WriteAt([]byte{0xff}, 3<<19)
+	f.Close()
+	os.Truncate(truncated.FilePath, 1000)
+	os.Remove(missing.FilePath)
+
+	orphan := filepath.Join(tmpDir, "unknown.mp4")
+	os.WriteFile(orphan, []byte("stray"), 0600)
+	os.MkdirAll(filepath.Join(tmpDir, "uploads"), 0700)
+	stale := filepath.Join(tmpDir, "uploads", "upload-1.mp4")
+	fresh := filepath.Join(tmpDir, "uploads", "upload-2.mp4")
+	os.WriteFile(stale, []byte("old"), 0600)
+	os.WriteFile(fresh, []byte("new"), 0600)
+	old := time.Now().Add(-2 * scrubTemporaryAge)
+	os.Chtimes(stale, old, old)
+
+	for _, full := range []bool{false, true} {
+		report, err := system.Scrub("CUS-001", full)
+		if err != nil {
+			t.Fatalf("Scrub failed: %v", err)
+		}
+		if len(report.Findings) != 5 {
+			t.Errorf("expected 5 findings, got %+v", report.Findings)
+		}
+
+		bad := scrubFinding(t, report, corrupted.FilePath)
+		if bad.Kind != ScrubHashMismatch || bad.EvidenceID != corrupted.ID || bad.Action != ScrubInvestigate {
+			t.Errorf("expected a hash mismatch, got %+v", bad)
+		}
+		if len(bad.CorruptRanges) != 1 || bad.CorruptRanges[0].Start != 1<<20 {
+			t.Errorf("expected the second chunk to be located, got %+v", bad.CorruptRanges)
+		}
+		if short := scrubFinding(t, report, truncated.FilePath); short.Kind != ScrubSizeMismatch || short.ActualSize != 1000 || short.ExpectedSize != 3<<20 {
+			t.Errorf("expected a size mismatch, got %+v", short)
+		}
+		if gone := scrubFinding(t, report, missing.FilePath); gone.Kind != ScrubMissingFile || gone.EvidenceID != missing.ID {
+			t.Errorf("expected a missing file, got %+v", gone)
+		}
+		if stray := scrubFinding(t, report, orphan); stray.Kind != ScrubOrphanFile || stray.Action != ScrubReviewOrphan {
+			t.Errorf("expected an orphan, got %+v", stray)
+		}
+		if temp := scrubFinding(t, report, stale); temp.Kind != ScrubStaleTemporary || temp.Action != ScrubRemoveFile {
+			t.Errorf("expected a stale upload, got %+v", temp)
+		}
+		if counts := report.Counts(); counts[ScrubOrphanFile] != 1 || counts[ScrubHashMismatch] != 1 {
+			t.Errorf("unexpected counts %v", counts)
+		}
+	}
+
+	// The report is for remediation tooling; the scrub itself changes nothing
+	ev, _ := system.GetEvidence(corrupted.ID)
+	if len(ev.IntegrityChecks) != checks {
+		t.Error("expected the scrub not to record integrity checks")
+	}
+	if logs := system.GetAuditLogs(corrupted.ID, "CUS-001"); len(logs) != 2 || logs[0].Action != "SCRUB_FINDING" {
+		t.Errorf("expected each finding to be audited against the evidence, got %+v", logs)
+	}
+}
+
+func TestScrubRemediation(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+	system.config.Integrity.Parity = ParityConfig{Enabled: true, BlockSizeKB: 4, DataBlocks: 4, ParityBlocks: 2}
+
+	ev, err := system.IngestEvidence(writeRecording(t, t.TempDir()), "CASE-SCR-5", "OFF-1123", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if ev.Parity == nil {
+		t.Fatal("expected a parity file")
+	}
+	f, _ := os.OpenFile(ev.FilePath, os.O_WRONLY, 0)
+	f.WriteAt([]byte{0xff}, 100)
+	f.Close()
+	os.Truncate(ev.Parity.Path, 10)
+
+	purged, err := system.IngestEvidence(writeRecording(t, t.TempDir()), "CASE-SCR-6", "OFF-1123", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	system.mu.Lock()
+	purged.Status = StatusDeleted
+	system.mu.Unlock()
+
+	// A sampled scrub checks only sizes without chunk hashes
+	report, err := system.Scrub("CUS-001", false)
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	if report.FilesNotHashed != 2 {
+		t.Errorf("expected the recording and parity file to go unhashed, got %d", report.FilesNotHashed)
+	}
+
+	report, err = system.Scrub("CUS-001", true)
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	if bad := scrubFinding(t, report, ev.FilePath); bad.Action != ScrubRepairFromParity || bad.ActualHash == "" {
+		t.Errorf("expected a parity repair, got %+v", bad)
+	}
+	if parity := scrubFinding(t, report, ev.Parity.Path); parity.Kind != ScrubHashMismatch || parity.Action != ScrubRegenerateParity {
+		t.Errorf("expected the parity file to be regenerated, got %+v", parity)
+	}
+	if left := scrubFinding(t, report, purged.FilePath); left.Kind != ScrubOrphanFile || left.EvidenceID != purged.ID || left.Action != ScrubRemoveFile {
+		t.Errorf("expected the purged file to be reported, got %+v", left)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil || !strings.Contains(string(data), `"action":"REPAIR_FROM_PARITY"`) {
+		t.Errorf("expected a machine-readable report, got %s", data)
+	}
+	if !strings.Contains(report.Text(), "action: REGENERATE_PARITY") {
+		t.Errorf("unexpected report text:\n%s", report.Text())
+	}
+}
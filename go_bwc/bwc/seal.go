@@ -0,0 +1,450 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// errEvidenceSealed is returned for any attempt to modify sealed evidence
+var errEvidenceSealed = errors.New("evidence is sealed")
+
+// sealVersion is the seal format written by SealEvidence. Version 2 hashes
+// the canonical serialization of the record and signs the sealer; seals
+// without a version were made by older releases and are still verified.
+const sealVersion = 2
+
+// Seal records that evidence was sealed under a legal authority. Signature is an
+// Ed25519 signature over the seal fields and StateHash, the SHA-256 of the
+// evidence record as it stood when sealed.
+type Seal struct {
+	Version   int       `json:"version,omitempty"`
+	Authority string    `json:"authority"`
+	SealedBy  string    `json:"sealed_by,omitempty"`
+	SealedAt  time.Time `json:"sealed_at"`
+	StateHash string    `json:"state_hash"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"`
+}
+
+// SealEvent is a permanent, signed entry in an evidence record's seal history
+type SealEvent struct {
+	Action      string    `json:"action"` // SEALED or UNSEALED
+	Authority   string    `json:"authority"`
+	SealedBy    string    `json:"sealed_by,omitempty"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	ApprovedBy  string    `json:"approved_by,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	StateHash   string    `json:"state_hash"`
+	KeyID       string    `json:"key_id"`
+	Signature   string    `json:"signature"`
+}
+
+// sealSigner signs sealed evidence state
+type sealSigner struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+func newSealSigner(seed []byte) *sealSigner {
+	key := ed25519.NewKeyFromSeed(seed)
+	sum := sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return &sealSigner{key: key, keyID: hex.EncodeToString(sum[:8])}
+}
+
+// generateSealSigner creates a signer with a random key, used when no sealing
+// key is configured. Its seals cannot be verified after a restart.
+func generateSealSigner() (*sealSigner, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate sealing key: %w", err)
+	}
+	return newSealSigner(seed), nil
+}
+
+// sealingKeyFile is where a system with a persistent store keeps the sealing
+// key it generated, when security.sealing_key_file names none
+const sealingKeyFile = "sealing.key"
+
+// loadOrCreateSealSigner reads the sealing key at path, first generating and
+// writing one if there is none, so seals still verify after a restart
+func loadOrCreateSealSigner(path string) (*sealSigner, error) {
+	if _, err := os.Stat(path); err == nil {
+		return loadSealSigner(path)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read sealing key: %w", err)
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate sealing key: %w", err)
+	}
+	if err := replaceFile(path, []byte(hex.EncodeToString(seed)+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write sealing key: %w", err)
+	}
+	return newSealSigner(seed), nil
+}
+
+// loadSealSigner reads a hex-encoded Ed25519 seed from path
+func loadSealSigner(path string) (*sealSigner, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sealing key: %w", err)
+	}
+	seed, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("sealing key %s must contain a hex-encoded %d-byte Ed25519 seed", path, ed25519.SeedSize)
+	}
+	return newSealSigner(seed), nil
+}
+
+// sealPayload is the message a seal signature covers
+func sealPayload(evidenceID string, seal *Seal) []byte {
+	if seal.Version < sealVersion {
+		return []byte(strings.Join([]string{
+			"BWC-SEAL-v1",
+			evidenceID,
+			seal.Authority,
+			seal.SealedAt.UTC().Format(time.RFC3339Nano),
+			seal.StateHash,
+		}, "\n"))
+	}
+	return []byte(strings.Join([]string{
+		"BWC-SEAL-v2",
+		evidenceID,
+		seal.Authority,
+		seal.SealedBy,
+		seal.SealedAt.UTC().Format(time.RFC3339Nano),
+		seal.StateHash,
+	}, "\n"))
+}
+
+// sign returns the base64 signature of payload
+func (s *sealSigner) sign(payload []byte) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload))
+}
+
+// sealedState is the part of the record a seal covers: everything but its
+// seal or seal history, whose entries are signed individually, the checks
+// made while sealed, the wrapping of its data key, which key rotation
+// changes, and its access list
+func sealedState(evidence *Evidence) *Evidence {
+	state := copyEvidence(evidence)
+	state.Seal = nil
+	state.SealHistory = nil
+	state.SealedChecks = nil
+	state.Encryption = nil
+	state.ACL = nil
+	return &state
+}
+
+// CanonicalEvidenceJSON is the serialization a version 2 seal hashes: the
+// record's JSON in the JSON Canonicalization Scheme of RFC 8785, so an
+// outside party can reproduce it from an export with any JCS library. The
+// seal, seal history, key wrapping and access list are left out.
+func CanonicalEvidenceJSON(evidence *Evidence) ([]byte, error) {
+	data, err := json.Marshal(sealedState(evidence))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal evidence state: %w", err)
+	}
+	return canonicalJSON(data)
+}
+
+// canonicalJSON re-encodes a JSON document under RFC 8785: object keys sorted
+// by their UTF-16 code units, no insignificant whitespace, strings escaped
+// only where JSON requires it and numbers written as ECMAScript writes a double
+func canonicalJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode evidence state: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCanonical writes one decoded JSON value in its RFC 8785 form
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return fmt.Errorf("number %s cannot be canonicalized: %w", v, err)
+		}
+		buf.WriteString(canonicalNumber(f))
+	case string:
+		writeCanonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, key)
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value %T", value)
+	}
+	return nil
+}
+
+// lessUTF16 orders strings by their UTF-16 code units, which differs from
+// Go's byte order for characters outside the Basic Multilingual Plane
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// writeCanonicalString quotes s, escaping only quotes, backslashes and
+// control characters, the last with the short forms JSON has for them
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// canonicalNumber writes f as ECMAScript's Number.prototype.toString does,
+// which RFC 8785 adopts: the shortest digits that round-trip, in plain
+// notation from 1e-6 up to 1e21 and in exponent notation outside it
+func canonicalNumber(f float64) string {
+	if f == 0 {
+		return "0" // -0 too
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// Shortest digits d.ddd and exponent, e.g. "3.3333333333333331e+08"
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	e, _ := strconv.Atoi(exp)
+	k, n := len(digits), e+1 // f is 0.digits × 10^n
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	exponent := fmt.Sprintf("e%+d", n-1)
+	if k == 1 {
+		return sign + digits + exponent
+	}
+	return sign + digits[:1] + "." + digits[1:] + exponent
+}
+
+// sealStateHash hashes the evidence record as a seal of the given version
+// covers it
+func sealStateHash(evidence *Evidence, version int) (string, error) {
+	var data []byte
+	var err error
+	if version < sealVersion {
+		data, err = json.Marshal(sealedState(evidence))
+	} else {
+		data, err = CanonicalEvidenceJSON(evidence)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal evidence state: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SealPublicKey returns the key that verifies seal signatures
+func (bwc *BWCSystem) SealPublicKey() ed25519.PublicKey {
+	return bwc.sealer.key.Public().(ed25519.PublicKey)
+}
+
+// SealEvidence seals evidence under authority, e.g. a court order reference,
+// as the system; SealEvidenceAs names the person sealing
+func (bwc *BWCSystem) SealEvidence(evidenceID, authority string) (*Seal, error) {
+	return bwc.SealEvidenceAs(evidenceID, systemUserID, authority)
+}
+
+// SealEvidenceAs has sealer seal evidence under authority. The file's
+// integrity is verified first, then the canonical serialization of the record
+// is hashed and signed. Afterwards the record is read-only and every attempt
+// to modify it is rejected and audited until a second person approves an
+// unseal.
+func (bwc *BWCSystem) SealEvidenceAs(evidenceID, sealer, authority string) (*Seal, error) {
+	if err := bwc.authorize(sealer, PermSeal, "Seal", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	authority = strings.TrimSpace(authority)
+	if authority == "" {
+		return nil, errors.New("sealing authority is required")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if evidence.Seal != nil {
+		return nil, fmt.Errorf("evidence is already sealed under %s", evidence.Seal.Authority)
+	}
+	if checkout, out := bwc.checkouts[evidenceID]; out {
+		return nil, fmt.Errorf("evidence is checked out to %s and must be checked in before sealing", checkout.CheckedOutTo)
+	}
+
+	currentHash, err := bwc.hashEvidence(evidence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate file hash: %w", err)
+	}
+	if currentHash != evidence.FileHash {
+		return nil, errors.New("integrity check failed: hash mismatch")
+	}
+
+	stateHash, err := sealStateHash(evidence, sealVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	seal := &Seal{
+		Version:   sealVersion,
+		Authority: authority,
+		SealedBy:  sealer,
+		SealedAt:  time.Now().UTC(),
+		StateHash: stateHash,
+		KeyID:     bwc.sealer.keyID,
+	}
+	seal.Signature = bwc.sealer.sign(sealPayload(evidenceID, seal))
+	evidence.Seal = seal
+	evidence.SealHistory = append(evidence.SealHistory, SealEvent{
+		Action:    "SEALED",
+		Authority: seal.Authority,
+		SealedBy:  seal.SealedBy,
+		Timestamp: seal.SealedAt,
+		StateHash: seal.StateHash,
+		KeyID:     seal.KeyID,
+		Signature: seal.Signature,
+	})
+	if err := bwc.saveLocked(evidence); err != nil {
+		return nil, err
+	}
+
+	bwc.logAudit(sealer, "SEAL_EVIDENCE", evidenceID,
+		fmt.Sprintf("Evidence sealed under %s (state %s, key %s)", authority, stateHash, seal.KeyID), "")
+
+	s := *seal
+	return &s, nil
+}
+
+// VerifySeal checks that sealed evidence still matches its signed state. It
+// does not modify the record.
+func (bwc *BWCSystem) VerifySeal(evidenceID string) (bool, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return false, ErrEvidenceNotFound
+	}
+	seal := evidence.Seal
+	if seal == nil {
+		return false, errors.New("evidence is not sealed")
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(seal.Signature)
+	if err != nil {
+		return false, nil
+	}
+	if !ed25519.Verify(bwc.SealPublicKey(), sealPayload(evidenceID, seal), signature) {
+		return false, nil
+	}
+
+	stateHash, err := sealStateHash(evidence, seal.Version)
+	if err != nil {
+		return false, err
+	}
+	return stateHash == seal.StateHash, nil
+}
+
+// rejectIfSealedLocked audits and refuses operation on sealed evidence.
+// Callers must hold bwc.mu.
+func (bwc *BWCSystem) rejectIfSealedLocked(evidence *Evidence, userID, operation string) error {
+	if evidence.Seal == nil {
+		return nil
+	}
+
+	bwc.logAudit(userID, "SEALED_ACCESS_DENIED", evidence.ID,
+		fmt.Sprintf("%s rejected: sealed under %s", operation, evidence.Seal.Authority), "")
+
+	return fmt.Errorf("%w under %s", errEvidenceSealed, evidence.Seal.Authority)
+}
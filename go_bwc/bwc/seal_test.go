@@ -0,0 +1,388 @@
+package bwc
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSealEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	if _, err := system.SealEvidenceAs(evidence.ID, "SUP-SEAL", " "); err == nil {
+		t.Error("Expected error sealing without an authority")
+	}
+
+	seal, err := system.SealEvidenceAs(evidence.ID, "SUP-SEAL", "Court Order 2024-CR-0042")
+	if err != nil {
+		t.Fatalf("SealEvidenceAs failed: %v", err)
+	}
+	if seal.Authority != "Court Order 2024-CR-0042" || seal.SealedBy != "SUP-SEAL" || seal.Signature == "" || seal.StateHash == "" {
+		t.Errorf("Unexpected seal: %+v", seal)
+	}
+
+	valid, err := system.VerifySeal(evidence.ID)
+	if err != nil || !valid {
+		t.Errorf("Expected valid seal, got %v, %v", valid, err)
+	}
+
+	if _, err := system.SealEvidenceAs(evidence.ID, "SUP-SEAL", "Another Order"); err == nil {
+		t.Error("Expected error sealing twice")
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "SUP-SEAL")
+	if len(logs) != 1 || logs[0].Action != "SEAL_EVIDENCE" {
+		t.Errorf("Expected SEAL_EVIDENCE audit entry, got %v", logs)
+	}
+}
+
+func TestSealCoversCanonicalRecord(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-006", "OFF-128", "Officer Test", "Test Location", nil)
+	seal, err := system.SealEvidenceAs(evidence.ID, "SUP-SEAL", "Court Order 2024-CR-0047")
+	if err != nil {
+		t.Fatalf("SealEvidenceAs failed: %v", err)
+	}
+
+	// The seal can be checked from an exported record and the public key alone
+	got, _ := system.GetEvidence(evidence.ID)
+	canonical, err := CanonicalEvidenceJSON(got)
+	if err != nil {
+		t.Fatalf("CanonicalEvidenceJSON failed: %v", err)
+	}
+	if strings.Contains(string(canonical), "\"seal\"") || strings.Contains(string(canonical), ": ") {
+		t.Errorf("Expected a compact record without its seal, got %s", canonical)
+	}
+	sum := sha256.Sum256(canonical)
+	if hex.EncodeToString(sum[:]) != seal.StateHash {
+		t.Error("Expected the state hash to cover the canonical serialization")
+	}
+	signature, _ := base64.StdEncoding.DecodeString(seal.Signature)
+	if !ed25519.Verify(system.SealPublicKey(), sealPayload(evidence.ID, seal), signature) {
+		t.Error("Expected the seal signature to verify")
+	}
+
+	// The serialization does not depend on key order in the source document
+	reordered, err := canonicalJSON([]byte(`{"b": [1.50, {"z": 1, "a": 2}], "a": "x"}`))
+	if err != nil || string(reordered) != `{"a":"x","b":[1.5,{"a":2,"z":1}]}` {
+		t.Errorf("Unexpected canonical form %s: %v", reordered, err)
+	}
+}
+
+func TestCanonicalJSONFollowsRFC8785(t *testing.T) {
+	// The examples of RFC 8785 sections 3.2.2 and 3.2.3
+	cases := []struct{ in, want string }{
+		{
+			`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			  "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			`{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh",
+			  "1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control",
+			  "\u00f6": "Latin Small Letter O With Diaeresis"}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\"," +
+				"\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\"," +
+				"\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		// Characters encoding/json would escape are written as they are
+		{`"<a href=\"x\">&\u2028"`, "\"<a href=\\\"x\\\">&\u2028\""},
+	}
+	for _, c := range cases {
+		got, err := canonicalJSON([]byte(c.in))
+		if err != nil || string(got) != c.want {
+			t.Errorf("canonicalJSON(%s) = %s, %v; want %s", c.in, got, err, c.want)
+		}
+	}
+
+	// The IEEE 754 vectors of RFC 8785 appendix B
+	numbers := []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	}
+	for _, n := range numbers {
+		if got := canonicalNumber(math.Float64frombits(n.bits)); got != n.want {
+			t.Errorf("canonicalNumber(%#016x) = %s, want %s", n.bits, got, n.want)
+		}
+	}
+}
+
+func TestSealNeedsSealPermission(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	enableAccessControl(system)
+	system.RegisterUser("OFF-129", "", RoleOfficer, "ADM-001")
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, err := system.IngestEvidence(testFile, "CASE-SEAL-007", "OFF-129", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if _, err := system.SealEvidenceAs(evidence.ID, "OFF-129", "Court Order 2024-CR-0048"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("Expected an officer to be refused, got %v", err)
+	}
+	if _, err := system.SealEvidenceAs(evidence.ID, "ADM-001", "Court Order 2024-CR-0048"); err != nil {
+		t.Errorf("Expected the administrator to seal, got %v", err)
+	}
+}
+
+func TestSealEvidenceSealsAsSystem(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-010", "OFF-131", "Officer Test", "Test Location", nil)
+	seal, err := system.SealEvidence(evidence.ID, "Court Order 2024-CR-0049")
+	if err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if seal.SealedBy != systemUserID || seal.Version != sealVersion {
+		t.Errorf("Expected a version %d seal by the system, got %+v", sealVersion, seal)
+	}
+	if valid, err := system.VerifySeal(evidence.ID); err != nil || !valid {
+		t.Errorf("Expected valid seal, got %v, %v", valid, err)
+	}
+}
+
+func TestLegacySealStillVerifies(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-008", "OFF-130", "Officer Test", "Test Location", nil)
+
+	// A seal written before versioning hashed the plain JSON encoding
+	system.mu.Lock()
+	stored := system.evidenceDB.Get(evidence.ID)
+	stateHash, _ := sealStateHash(stored, 0)
+	seal := &Seal{Authority: "Court Order 2023-CR-0001", SealedAt: time.Now().UTC(), StateHash: stateHash, KeyID: system.sealer.keyID}
+	seal.Signature = system.sealer.sign(sealPayload(evidence.ID, seal))
+	stored.Seal = seal
+	system.mu.Unlock()
+
+	if valid, err := system.VerifySeal(evidence.ID); err != nil || !valid {
+		t.Errorf("Expected a legacy seal to verify, got %v, %v", valid, err)
+	}
+	req, err := system.RequestUnseal(evidence.ID, "SUP-001", "Court Order 2024-CR-0049")
+	if err != nil {
+		t.Fatalf("RequestUnseal failed: %v", err)
+	}
+	if err := system.ApproveUnseal(req.ID, "SUP-002"); err != nil {
+		t.Errorf("Expected a legacy seal to be lifted, got %v", err)
+	}
+}
+
+func TestSealedEvidenceRejectsModification(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-002", "OFF-124", "Officer Test", "Test Location", nil)
+	if _, err := system.SealEvidence(evidence.ID, "Court Order 2024-CR-0043"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+
+	attempts := map[string]error{
+		"transfer": system.TransferCustody(evidence.ID, "OFF-124", "DET-456", "Analysis"),
+		"status":   system.UpdateStatus(evidence.ID, "OFF-124", StatusArchived, "Archive"),
+		"export":   system.ExportEvidence(evidence.ID, filepath.Join(tmpDir, "export.json")),
+	}
+	_, attempts["checkout"] = system.CheckOutEvidence(evidence.ID, "OFF-124", "COURT-7", "Trial exhibit")
+	_, attempts["request"] = system.RequestCustodyTransfer(evidence.ID, "OFF-124", "DET-456", "Analysis")
+
+	for name, err := range attempts {
+		if !errors.Is(err, errEvidenceSealed) {
+			t.Errorf("%s: expected sealed error, got %v", name, err)
+		}
+	}
+
+	denied := 0
+	for _, log := range system.GetAuditLogs(evidence.ID, "") {
+		if log.Action == "SEALED_ACCESS_DENIED" {
+			denied++
+		}
+	}
+	if denied != len(attempts) {
+		t.Errorf("Expected %d SEALED_ACCESS_DENIED entries, got %d", len(attempts), denied)
+	}
+
+	// The file is still checked, beside the sealed state
+	if ok, err := system.VerifyIntegrity(evidence.ID, "OFF-124"); err != nil || !ok {
+		t.Errorf("Expected sealed evidence to be verified, got %v, %v", ok, err)
+	}
+
+	got, _ := system.GetEvidence(evidence.ID)
+	if got.Status != StatusCollected || len(got.ChainOfCustody) != 1 || len(got.IntegrityChecks) != 1 || got.Revision != evidence.Revision {
+		t.Errorf("Sealed record was modified: %+v", got)
+	}
+	if len(got.SealedChecks) != 1 || !got.SealedChecks[0].IsValid || got.SealedChecks[0].CheckedBy != "OFF-124" {
+		t.Errorf("Expected the check kept beside the seal, got %+v", got.SealedChecks)
+	}
+	if valid, err := system.VerifySeal(evidence.ID); err != nil || !valid {
+		t.Errorf("Expected seal to remain valid, got %v, %v", valid, err)
+	}
+
+	if _, err := system.GenerateReport("CASE-SEAL-002"); err != nil {
+		t.Errorf("Expected sealed evidence to remain readable: %v", err)
+	}
+}
+
+func TestSealRefusesCheckedOutEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-003", "OFF-125", "Officer Test", "Test Location", nil)
+	system.CheckOutEvidence(evidence.ID, "OFF-125", "COURT-7", "Trial exhibit")
+
+	if _, err := system.SealEvidence(evidence.ID, "Court Order 2024-CR-0044"); err == nil {
+		t.Error("Expected error sealing checked-out evidence")
+	}
+}
+
+func TestVerifySealDetectsTampering(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-004", "OFF-126", "Officer Test", "Test Location", nil)
+	system.SealEvidence(evidence.ID, "Court Order 2024-CR-0045")
+
+	system.mu.Lock()
+	system.evidenceDB.Get(evidence.ID).Notes = "altered"
+	system.mu.Unlock()
+
+	if valid, err := system.VerifySeal(evidence.ID); err != nil || valid {
+		t.Errorf("Expected altered record to fail seal verification, got %v, %v", valid, err)
+	}
+
+	if _, err := system.VerifySeal("BWC-UNKNOWN"); err == nil {
+		t.Error("Expected error for unknown evidence")
+	}
+}
+
+func TestSealingKeyFromConfig(t *testing.T) {
+	tmpDir := t.TempDir()
+	seed := strings.Repeat("ab", ed25519.SeedSize)
+	keyFile := filepath.Join(tmpDir, "sealing.key")
+	if err := os.WriteFile(keyFile, []byte(seed+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(tmpDir, "storage")
+	cfg.Security.SealingKeyFile = keyFile
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+
+	raw, _ := hex.DecodeString(seed)
+	want := ed25519.NewKeyFromSeed(raw).Public().(ed25519.PublicKey)
+	if !want.Equal(system.SealPublicKey()) {
+		t.Error("Expected sealing key to be loaded from configuration")
+	}
+
+	os.WriteFile(keyFile, []byte("not-a-key"), 0600)
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil {
+		t.Error("Expected error for malformed sealing key")
+	}
+}
+
+func TestGeneratedSealingKeyOutlivesRestart(t *testing.T) {
+	tmpDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(tmpDir, "storage")
+	cfg.Database.Type = "json"
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-SEAL-011", "OFF-132", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if _, err := system.SealEvidence(evidence.ID, "Court Order 2024-CR-0050"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if info, err := os.Stat(filepath.Join(cfg.Storage.Path, sealingKeyFile)); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected the generated key to be kept, got %v", err)
+	}
+
+	restarted, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	if !restarted.SealPublicKey().Equal(system.SealPublicKey()) {
+		t.Error("Expected the same sealing key after a restart")
+	}
+	if valid, err := restarted.VerifySeal(evidence.ID); err != nil || !valid {
+		t.Errorf("Expected the seal to verify after a restart, got %v, %v", valid, err)
+	}
+}
+
+func TestReportsMarkSealedEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SEAL-005", "OFF-127", "Officer Test", "Test Location", nil)
+	system.SealEvidence(evidence.ID, "Court Order 2024-CR-0046")
+
+	text, err := system.GenerateReport("CASE-SEAL-005")
+	if err != nil {
+		t.Fatalf("GenerateReport failed: %v", err)
+	}
+	if !strings.Contains(text, evidence.ID+" [SEALED]") || !strings.Contains(text, "Court Order 2024-CR-0046") {
+		t.Errorf("Expected text report to mark sealed evidence:\n%s", text)
+	}
+
+	html, err := system.GenerateHTMLReport("CASE-SEAL-005", ReportOptions{Locale: LocaleFrench})
+	if err != nil {
+		t.Fatalf("GenerateHTMLReport failed: %v", err)
+	}
+	if !strings.Contains(html, `<span class="sealed">SCELLÉE</span>`) {
+		t.Error("Expected HTML report to mark sealed evidence")
+	}
+}
@@ -0,0 +1,2172 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// sessionCookieName is the cookie carrying a web UI session
+const sessionCookieName = "bwc_session"
+
+// webSession is an authenticated browser session
+type webSession struct {
+	principal *Principal
+	expires   time.Time
+}
+
+// apiServer serves the HTTP API and the embedded web review UI
+type apiServer struct {
+	system   *BWCSystem
+	config   *Config
+	mux      *http.ServeMux
+	sessions map[string]webSession
+	sessMu   sync.Mutex
+}
+
+// NewAPIHandler returns the REST API and web review UI for the system, as
+// served by "bwc-system serve", for mounting in another program's server
+func NewAPIHandler(system *BWCSystem) http.Handler {
+	return newAPIServer(system)
+}
+
+// newAPIServer creates the HTTP handler for the system
+func newAPIServer(system *BWCSystem) *apiServer {
+	s := &apiServer{
+		system:   system,
+		config:   system.config,
+		mux:      http.NewServeMux(),
+		sessions: make(map[string]webSession),
+	}
+
+	static, _ := fs.Sub(webFiles, "web")
+	s.mux.Handle("/", http.FileServer(http.FS(static)))
+
+	s.mux.HandleFunc("/login", s.handleLogin)
+	s.mux.HandleFunc("/logout", s.handleLogout)
+	s.mux.HandleFunc("/verify/", s.handleVerifyLink)
+	s.mux.HandleFunc("/api/session", s.allowGrantOnly(s.handleSession))
+	s.mux.HandleFunc("/api/evidence", s.requireAuth(s.handleEvidenceCollection))
+	s.mux.HandleFunc("/api/evidence/", s.allowGrantOnly(s.handleEvidence))
+	s.mux.HandleFunc("/api/ingests", s.requireAuth(s.handleActiveIngests))
+	s.mux.HandleFunc("/api/ingests/interrupted", s.requireAuth(s.handleInterruptedIngests))
+	s.mux.HandleFunc("/api/ingests/interrupted/", s.requireAuth(s.handleInterruptedIngests))
+	s.mux.HandleFunc("/api/storage/scrub", s.requireAuth(s.handleScrub))
+	s.mux.HandleFunc("/api/anchors", s.requireAuth(s.handleAnchors))
+	s.mux.HandleFunc("/api/deletions", s.requireAuth(s.handleDeletions))
+	s.mux.HandleFunc("/api/deletions/", s.requireAuth(s.handleDeletions))
+	s.mux.HandleFunc("/api/anchors/", s.requireAuth(s.handleAnchors))
+	s.mux.HandleFunc("/api/keys/rotate", s.requireAuth(s.handleRotateKeys))
+	s.mux.HandleFunc("/api/officers/", s.requireAuth(s.handleOfficerKeys))
+	s.mux.HandleFunc("/api/users", s.requireAuth(s.handleUsers))
+	s.mux.HandleFunc("/api/users/", s.requireAuth(s.handleUsers))
+	s.mux.HandleFunc("/api/replication", s.requireAuth(s.handleReplication))
+	s.mux.HandleFunc("/api/replication/", s.requireAuth(s.handleReplication))
+	s.mux.HandleFunc("/api/grants", s.allowGrantOnly(s.handleGrants))
+	s.mux.HandleFunc("/api/audit", s.requireAuth(s.handleAuditLogs))
+	s.mux.HandleFunc("/api/graphql", s.requireAuth(s.handleGraphQL))
+	s.mux.HandleFunc("/api/audit/baselines", s.requireAuth(s.handleActivityBaselines))
+	s.mux.HandleFunc("/api/audit/review", s.requireAuth(s.handleActivityReview))
+	s.mux.HandleFunc("/api/anomalies", s.requireAuth(s.handleAnomalies))
+	s.mux.HandleFunc("/api/reports/", s.requireAuth(s.handleReport))
+	s.mux.HandleFunc("/api/exchange/niem/", s.requireAuth(s.handleNIEMExchange))
+	s.mux.HandleFunc("/api/retention/forecast", s.requireAuth(s.handleRetentionForecast))
+	s.mux.HandleFunc("/api/retention/officers", s.requireAuth(s.handleOfficerAccountability))
+	s.mux.HandleFunc("/api/retention/simulate", s.requireAuth(s.handleRetentionSimulation))
+	s.mux.HandleFunc("/api/analytics/", s.requireAuth(s.handleAnalytics))
+	s.mux.HandleFunc("/api/scan", s.requireAuth(s.handleScan))
+	s.mux.HandleFunc("/api/stream/events", s.requireAuth(s.handleEventStream))
+	s.mux.HandleFunc("/api/stream/evidence", s.requireAuth(s.handleEvidenceStream))
+
+	return s
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'")
+	s.mux.ServeHTTP(w, r)
+}
+
+// authenticateToken returns the principal a raw API token belongs to: a
+// token in api.credentials, or an API key issued to a registered user
+func (s *apiServer) authenticateToken(token string) (*Principal, bool) {
+	if token == "" {
+		return nil, false
+	}
+
+	sum := sha256.Sum256([]byte(token))
+	digest := hex.EncodeToString(sum[:])
+
+	for _, cred := range s.config.API.Credentials {
+		if subtle.ConstantTimeCompare([]byte(digest), []byte(strings.ToLower(cred.TokenSHA256))) == 1 {
+			principal := &Principal{UserID: cred.UserID, Method: AuthConfigToken, AuthenticatedAt: time.Now()}
+			if user, err := s.system.GetUser(cred.UserID); err == nil {
+				principal.Role = user.Role
+			}
+			return principal, true
+		}
+	}
+
+	principal, err := s.system.AuthenticateAPIKey(token)
+	return principal, err == nil
+}
+
+// authenticate resolves the caller from a bearer token or a session cookie
+func (s *apiServer) authenticate(r *http.Request) (*Principal, bool) {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return s.authenticateToken(strings.TrimPrefix(auth, "Bearer "))
+	}
+
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		return nil, false
+	}
+
+	s.sessMu.Lock()
+	defer s.sessMu.Unlock()
+
+	sess, ok := s.sessions[cookie.Value]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(sess.expires) {
+		delete(s.sessions, cookie.Value)
+		return nil, false
+	}
+
+	return sess.principal, true
+}
+
+// requireAuth rejects unauthenticated and grant-only requests and passes the caller's user ID to next
+func (s *apiServer) requireAuth(next func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return s.allowGrantOnly(func(w http.ResponseWriter, r *http.Request, userID string) {
+		if s.grantOnly(userID) {
+			writeError(w, http.StatusForbidden, "access is limited to granted evidence")
+			return
+		}
+		next(w, r, userID)
+	})
+}
+
+// allowGrantOnly rejects unauthenticated requests and passes the caller's user
+// ID to next, which must itself restrict grant-only users. The request's
+// context carries the caller as its principal.
+func (s *apiServer) allowGrantOnly(next func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := s.authenticate(r)
+		if !ok {
+			if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				s.system.logAudit("UNKNOWN", "AUTH_FAILED", "", "API token rejected for "+r.URL.Path, clientIP(r))
+			}
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		next(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)), principal.UserID)
+	}
+}
+
+// grantOnly reports whether userID may only view evidence under access grants
+func (s *apiServer) grantOnly(userID string) bool {
+	for _, cred := range s.config.API.Credentials {
+		if cred.UserID == userID {
+			return cred.GrantOnly
+		}
+	}
+	return false
+}
+
+func (s *apiServer) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Registered users sign in with their password, others with a token
+	var principal *Principal
+	var ok bool
+	if userID := r.FormValue("user_id"); userID != "" {
+		var err error
+		principal, err = s.system.AuthenticatePassword(userID, r.FormValue("password"), clientIP(r))
+		ok = err == nil
+	} else {
+		principal, ok = s.authenticateToken(r.FormValue("token"))
+	}
+	if !ok {
+		s.system.logAudit("UNKNOWN", "LOGIN_FAILED", "", "Web UI login rejected", clientIP(r))
+		writeError(w, http.StatusUnauthorized, "invalid credentials")
+		return
+	}
+	userID := principal.UserID
+
+	id, err := newSessionID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to create session")
+		return
+	}
+
+	expires := time.Now().Add(s.config.SessionTimeout())
+	s.sessMu.Lock()
+	s.sessions[id] = webSession{principal: principal, expires: expires}
+	s.sessMu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    id,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   s.config.API.EnableTLS,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	s.auditRequest(r, userID, "LOGIN", "", "Web UI session started")
+	writeJSON(w, http.StatusOK, map[string]string{"user_id": userID})
+}
+
+func (s *apiServer) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		s.sessMu.Lock()
+		delete(s.sessions, cookie.Value)
+		s.sessMu.Unlock()
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *apiServer) handleSession(w http.ResponseWriter, r *http.Request, userID string) {
+	writeJSON(w, http.StatusOK, map[string]string{"user_id": userID})
+}
+
+// handleEvidenceCollection serves /api/evidence: GET searches and POST ingests
+func (s *apiServer) handleEvidenceCollection(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method == http.MethodPost {
+		s.handleIngest(w, r, userID)
+		return
+	}
+	if err := s.system.Authorize(userID, PermViewEvidence); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+	s.handleSearchEvidence(w, r, userID)
+}
+
+// handleIngest ingests the file in the request body. The metadata is given
+// as query parameters, with filename supplying the extension. A request with
+// an Idempotency-Key header that repeats an earlier one returns the original
+// evidence with 200 and Idempotent-Replayed: true instead of ingesting again.
+func (s *apiServer) handleIngest(w http.ResponseWriter, r *http.Request, userID string) {
+	q := r.URL.Query()
+	name := filepath.Base(q.Get("filename"))
+	if q.Get("filename") == "" || name == "." || name == string(filepath.Separator) {
+		writeError(w, http.StatusBadRequest, "filename is required")
+		return
+	}
+	var tags []string
+	if t := q.Get("tags"); t != "" {
+		tags = strings.Split(t, ",")
+	}
+
+	body := r.Body
+	if limit := s.config.Storage.MaxFileSizeMB; limit > 0 {
+		body = http.MaxBytesReader(w, r.Body, limit<<20)
+	}
+	path, err := s.system.receiveUpload(body, filepath.Ext(name))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "file exceeds storage.max_file_size_mb")
+			return
+		}
+		writeSystemError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.Remove(path)
+
+	officerID := q.Get("officer")
+	if officerID == "" {
+		officerID = userID
+	}
+	var evidence *Evidence
+	replayed := false
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		evidence, replayed, err = s.system.IngestEvidenceIdempotentContext(r.Context(), key, path, q.Get("case"), officerID, q.Get("officer_name"), q.Get("location"), tags)
+	} else {
+		evidence, err = s.system.IngestEvidenceContext(r.Context(), path, q.Get("case"), officerID, q.Get("officer_name"), q.Get("location"), tags)
+	}
+	switch {
+	case errors.Is(err, errIngestInProgress):
+		writeSystemError(w, http.StatusConflict, err)
+	case errors.Is(err, errIdempotencyKeyReused):
+		writeSystemError(w, http.StatusUnprocessableEntity, err)
+	case err != nil:
+		writeSystemError(w, http.StatusBadRequest, err)
+	case replayed:
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeJSON(w, http.StatusOK, evidence)
+	default:
+		writeJSON(w, http.StatusCreated, evidence)
+	}
+}
+
+// handleActiveIngests serves the progress of every ingest under way, so long
+// transfers can be watched from another client
+func (s *apiServer) handleActiveIngests(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := s.system.Authorize(userID, PermViewEvidence); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.system.ActiveIngests())
+}
+
+// handleScrub checks storage against the evidence records and returns the
+// report; ?full=true hashes every file rather than a sample
+func (s *apiServer) handleScrub(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	report, err := s.system.ScrubContext(r.Context(), userID, r.URL.Query().Get("full") == "true")
+	if err != nil {
+		writeSystemError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleAnchors serves GET /api/anchors, the stored Merkle anchors, POST to
+// anchor the archive now, and GET /api/anchors/{id}/verify
+func (s *apiServer) handleAnchors(w http.ResponseWriter, r *http.Request, userID string) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/anchors"), "/")
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		if err := s.system.Authorize(userID, PermVerify); err != nil {
+			writeSystemError(w, http.StatusForbidden, err)
+			return
+		}
+		anchors, err := s.system.Anchors()
+		if err != nil {
+			writeSystemError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, anchors)
+	case rest == "" && r.Method == http.MethodPost:
+		anchor, err := s.system.AnchorEvidenceContext(r.Context(), userID)
+		if err != nil {
+			writeSystemError(w, http.StatusInternalServerError, err)
+			return
+		}
+		anchor.Leaves = nil
+		writeJSON(w, http.StatusCreated, anchor)
+	case strings.HasSuffix(rest, "/verify") && r.Method == http.MethodGet:
+		result, err := s.system.VerifyAnchorContext(r.Context(), strings.TrimSuffix(rest, "/verify"), userID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	case rest == "" || strings.HasSuffix(rest, "/verify"):
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// deletionRequest is the body of POST /api/deletions and of a decline
+type deletionRequest struct {
+	EvidenceID string `json:"evidence_id"`
+	Purge      bool   `json:"purge"`
+	Reason     string `json:"reason"`
+}
+
+// handleDeletions serves the two-person deletion queue: GET /api/deletions
+// lists pending requests, POST requests the deletion of evidence_id or, with
+// purge, a retention purge, and POST /api/deletions/{id}/approve or
+// /decline resolves one
+func (s *apiServer) handleDeletions(w http.ResponseWriter, r *http.Request, userID string) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/deletions"), "/")
+	if rest == "" && r.Method == http.MethodGet {
+		if err := s.system.Authorize(userID, PermDeleteEvidence); err != nil {
+			writeSystemError(w, http.StatusForbidden, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.system.PendingDeletionRequests())
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body deletionRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	parts := strings.Split(rest, "/")
+	switch {
+	case rest == "":
+		var req *DeletionRequest
+		var err error
+		if body.Purge {
+			req, err = s.system.RequestPurgeContext(r.Context(), body.Reason, userID)
+		} else {
+			req, err = s.system.RequestDeletionContext(r.Context(), body.EvidenceID, body.Reason, userID)
+		}
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, req)
+	case len(parts) == 2 && parts[1] == "approve":
+		req, err := s.system.ApproveDeletionContext(r.Context(), parts[0], userID)
+		if err != nil {
+			writeSystemError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	case len(parts) == 2 && parts[1] == "decline":
+		if err := s.system.DeclineDeletionContext(r.Context(), parts[0], userID, body.Reason); err != nil {
+			writeSystemError(w, http.StatusConflict, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleRotateKeys re-wraps every data key under the key provider's current
+// key and returns the outcome
+func (s *apiServer) handleRotateKeys(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	rotation, err := s.system.RotateDataKeysContext(r.Context(), userID)
+	if err != nil {
+		writeSystemError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rotation)
+}
+
+// officerKeyRequest is the body of POST /api/officers/{id}/keys
+type officerKeyRequest struct {
+	PublicKey []byte `json:"public_key"`
+}
+
+// handleOfficerKeys serves GET /api/officers/{id}/keys, the officer's signing
+// keys, POST to enroll a new one (base64 public_key) and DELETE
+// /api/officers/{id}/keys/{key} to revoke one, with the ?reason=
+func (s *apiServer) handleOfficerKeys(w http.ResponseWriter, r *http.Request, userID string) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/officers/"), "/")
+	if len(parts) < 2 || parts[1] != "keys" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	officerID := parts[0]
+	if err := ValidateOfficerID(officerID); err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.system.OfficerKeys(officerID))
+	case len(parts) == 2 && r.Method == http.MethodPost:
+		var req officerKeyRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		key, err := s.system.EnrollOfficerKeyContext(r.Context(), officerID, req.PublicKey, userID)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, key)
+	case len(parts) == 3 && r.Method == http.MethodDelete:
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			writeError(w, http.StatusBadRequest, "reason is required")
+			return
+		}
+		for _, key := range s.system.OfficerKeys(officerID) {
+			if key.KeyID == parts[2] {
+				if err := s.system.RevokeOfficerKeyContext(r.Context(), key.KeyID, userID, reason); err != nil {
+					writeSystemError(w, http.StatusConflict, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "officer key not found")
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// userRequest is the body of POST /api/users and PUT /api/users/{id}
+type userRequest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// handleUsers serves GET /api/users, the registered users, POST to register
+// one, PUT /api/users/{id} to change their role and DELETE /api/users/{id}
+// to deactivate them, with the ?reason=. Their credentials are under
+// /api/users/{id}/password and /api/users/{id}/keys.
+func (s *apiServer) handleUsers(w http.ResponseWriter, r *http.Request, userID string) {
+	target := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/users"), "/")
+	if i := strings.Index(target, "/"); i >= 0 {
+		s.handleUserCredentials(w, r, userID, target[:i], target[i+1:])
+		return
+	}
+	switch {
+	case target == "" && r.Method == http.MethodGet:
+		if err := s.system.Authorize(userID, PermManageUsers); err != nil {
+			writeSystemError(w, http.StatusForbidden, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.system.Users())
+	case target == "" && r.Method == http.MethodPost, target != "" && r.Method == http.MethodPut:
+		var req userRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		role, err := ParseRole(req.Role)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		if target != "" {
+			if err := s.system.SetUserRoleContext(r.Context(), target, role, userID); err != nil {
+				writeSystemError(w, http.StatusBadRequest, err)
+				return
+			}
+			user, _ := s.system.GetUser(target)
+			writeJSON(w, http.StatusOK, user)
+			return
+		}
+		user, err := s.system.RegisterUserContext(r.Context(), req.ID, req.Name, role, userID)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, user)
+	case target != "" && r.Method == http.MethodDelete:
+		reason := r.URL.Query().Get("reason")
+		if reason == "" {
+			writeError(w, http.StatusBadRequest, "reason is required")
+			return
+		}
+		if err := s.system.DeactivateUserContext(r.Context(), target, userID, reason); err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// apiKeyRequest is the body of POST /api/users/{id}/keys
+type apiKeyRequest struct {
+	Name string `json:"name"`
+}
+
+// issuedAPIKey is the response to POST /api/users/{id}/keys, the only one
+// that carries the token
+type issuedAPIKey struct {
+	*APIKey
+	Token string `json:"token"`
+}
+
+// handleUserCredentials serves PUT /api/users/{id}/password ({"password"},
+// and "current_password" to change one's own), GET /api/users/{id}/keys,
+// POST to issue an API key ({"name"}) and DELETE /api/users/{id}/keys/{key}
+// to revoke one
+func (s *apiServer) handleUserCredentials(w http.ResponseWriter, r *http.Request, userID, target, rest string) {
+	switch {
+	case rest == "password" && r.Method == http.MethodPut:
+		var req struct {
+			CurrentPassword string `json:"current_password"`
+			Password        string `json:"password"`
+		}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := s.system.SetPasswordContext(r.Context(), target, req.CurrentPassword, req.Password, userID); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, ErrUnauthenticated) {
+				status = http.StatusForbidden
+			}
+			writeSystemError(w, status, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case rest == "keys" && r.Method == http.MethodGet:
+		if target != userID {
+			if err := s.system.Authorize(userID, PermManageUsers); err != nil {
+				writeSystemError(w, http.StatusForbidden, err)
+				return
+			}
+		}
+		writeJSON(w, http.StatusOK, s.system.APIKeys(target))
+	case rest == "keys" && r.Method == http.MethodPost:
+		var req apiKeyRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		token, key, err := s.system.IssueAPIKeyContext(r.Context(), target, req.Name, userID)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, issuedAPIKey{APIKey: key, Token: token})
+	case strings.HasPrefix(rest, "keys/") && r.Method == http.MethodDelete:
+		keyID := strings.TrimPrefix(rest, "keys/")
+		for _, key := range s.system.APIKeys(target) {
+			if key.ID == keyID {
+				if err := s.system.RevokeAPIKeyContext(r.Context(), keyID, userID); err != nil {
+					writeSystemError(w, http.StatusConflict, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "API key not found")
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleReplication serves GET /api/replication, this system's part in site
+// replication, and on a standby the primary's POST /api/replication/changes
+// (a JSON batch) and PUT /api/replication/files/{id} (a recording)
+func (s *apiServer) handleReplication(w http.ResponseWriter, r *http.Request, userID string) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/replication"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		status, err := s.system.ReplicationStatus()
+		if err != nil {
+			writeSystemError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+		return
+	}
+
+	cfg := s.config.Replication
+	if cfg.Role != "standby" || userID != cfg.PrimaryUser {
+		writeError(w, http.StatusForbidden, "only the primary may replicate to this system")
+		return
+	}
+	switch {
+	case rest == "changes" && r.Method == http.MethodPost:
+		var batch ReplicationBatch
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReplicationBody)).Decode(&batch); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid replication batch")
+			return
+		}
+		status, err := s.system.ApplyReplication(&batch)
+		if err != nil {
+			writeSystemError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	case strings.HasPrefix(rest, "files/") && r.Method == http.MethodPut:
+		body := r.Body
+		if limit := s.config.Storage.MaxFileSizeMB; limit > 0 {
+			body = http.MaxBytesReader(w, r.Body, limit<<20)
+		}
+		err := s.system.ReceiveReplicaFile(strings.TrimPrefix(rest, "files/"), body)
+		switch {
+		case err == nil:
+			w.WriteHeader(http.StatusNoContent)
+		case errors.Is(err, errNoReplicatedRecord):
+			writeSystemError(w, http.StatusNotFound, err)
+		default:
+			writeSystemError(w, http.StatusUnprocessableEntity, err)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// handleInterruptedIngests serves GET /api/ingests/interrupted, the ingests
+// whose copy into storage was cut short, and POST
+// /api/ingests/interrupted/{id}/resume or /discard (with a JSON reason)
+func (s *apiServer) handleInterruptedIngests(w http.ResponseWriter, r *http.Request, userID string) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/ingests/interrupted"), "/")
+	if rest == "" {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		if err := s.system.Authorize(userID, PermViewEvidence); err != nil {
+			writeSystemError(w, http.StatusForbidden, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.system.InterruptedIngests())
+		return
+	}
+
+	parts := strings.Split(rest, "/")
+	if len(parts) != 2 || (parts[1] != "resume" && parts[1] != "discard") {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	evidenceID := parts[0]
+
+	if parts[1] == "resume" {
+		evidence, err := s.system.ResumeIngestContext(r.Context(), evidenceID, userID)
+		switch {
+		case errors.Is(err, errNoInterruptedIngest):
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		case err != nil:
+			writeSystemError(w, http.StatusConflict, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, evidence)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := s.system.DiscardIngestContext(r.Context(), evidenceID, userID, req.Reason); errors.Is(err, errNoInterruptedIngest) {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *apiServer) handleSearchEvidence(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	if near := q.Get("near"); near != "" {
+		lat, lon, radius, err := parseNearQuery(near, q.Get("radius"))
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.system.SearchNearContext(r.Context(), lat, lon, radius))
+		return
+	}
+	if text := q.Get("q"); text != "" {
+		results, err := s.system.SearchTextContext(r.Context(), text)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+		return
+	}
+	opts, err := queryOptions(q)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter := EvidenceFilter{
+		CaseNumber: q.Get("case"),
+		OfficerID:  q.Get("officer"),
+		Status:     EvidenceStatus(q.Get("status")),
+		MediaType:  MediaType(strings.ToUpper(q.Get("media"))),
+	}
+	results, err := s.system.SearchEvidencePage(r.Context(), filter, opts)
+	switch {
+	case ErrorCodeOf(err) == CodeInvalidArgument:
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		writeSystemError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writePageHeaders(w, results.Total, results.NextCursor)
+	writeJSON(w, http.StatusOK, results.Evidence)
+}
+
+// queryOptions reads ?limit=, ?offset=, ?cursor=, ?sort= and ?order=asc|desc
+func queryOptions(q url.Values) (QueryOptions, error) {
+	opts := QueryOptions{Cursor: q.Get("cursor"), SortBy: SortField(q.Get("sort"))}
+	for name, dest := range map[string]*int{"limit": &opts.Limit, "offset": &opts.Offset} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return opts, &ValidationError{Field: name, Value: v, Reason: "must be a number"}
+			}
+			*dest = n
+		}
+	}
+	switch order := q.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, &ValidationError{Field: "order", Value: order, Reason: "must be asc or desc"}
+	}
+	return opts, nil
+}
+
+// writePageHeaders reports the size of a paged result and where the next
+// page starts, leaving the body a plain list
+func writePageHeaders(w http.ResponseWriter, total int, next string) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+}
+
+// handleEvidence serves /api/evidence/{id}, /api/evidence/{id}/custody
+// (POST transfers custody), /api/evidence/{id}/custody/signatures (the
+// custody entries that no longer verify), /api/evidence/{id}/timestamps
+// (the integrity checks' RFC 3161 timestamps, verified), /api/evidence/{id}/status and /verify (POST only),
+// /api/evidence/{id}/label (SVG, or the bare QR code with ?format=png),
+// /api/evidence/{id}/affidavit (custody affidavit PDF sworn by the caller, named by ?name=,
+// signed by the agency with ?signed=true),
+// /api/evidence/{id}/waveform (SVG preview of audio), /api/evidence/{id}/views, /api/evidence/{id}/copies,
+// /api/evidence/{id}/acl (PUT restricts, DELETE lifts) and the playback
+// endpoints in handlePlayback
+func (s *apiServer) handleEvidence(w http.ResponseWriter, r *http.Request, userID string) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/evidence/"), "/")
+	evidenceID := parts[0]
+	if err := ValidateEvidenceID(evidenceID); err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if r.Method == http.MethodPatch && len(parts) == 1 {
+		if s.grantOnly(userID) {
+			writeError(w, http.StatusForbidden, "access is limited to granted evidence")
+			return
+		}
+		s.handleUpdateMetadata(w, r, evidenceID, userID)
+		return
+	}
+
+	if r.Method == http.MethodPost && len(parts) == 2 && (parts[1] == "custody" || parts[1] == "status" || parts[1] == "verify") {
+		if s.grantOnly(userID) {
+			writeError(w, http.StatusForbidden, "access is limited to granted evidence")
+			return
+		}
+		s.handleEvidenceAction(w, r, evidenceID, parts[1], userID)
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "acl" && (r.Method == http.MethodPut || r.Method == http.MethodDelete) {
+		s.handleEvidenceACL(w, r, evidenceID, userID)
+		return
+	}
+
+	// Restricted evidence hides everything about it, not only the record
+	if len(parts) > 1 && !s.grantOnly(userID) {
+		if _, err := s.system.readableEvidence(r.Context(), evidenceID); err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+	}
+
+	if len(parts) == 2 && (parts[1] == "view" || parts[1] == "stream") {
+		s.handlePlayback(w, r, evidenceID, parts[1], userID)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if s.grantOnly(userID) {
+		s.serveGrantedEvidence(w, r, evidenceID, userID, len(parts) == 1)
+		return
+	}
+	if err := s.system.Authorize(userID, PermViewEvidence); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		evidence, err := s.system.GetEvidenceContext(r.Context(), evidenceID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, evidence)
+	case len(parts) == 2 && parts[1] == "custody":
+		custody, err := s.system.GetChainOfCustodyContext(r.Context(), evidenceID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, custody)
+	case len(parts) == 3 && parts[1] == "custody" && parts[2] == "signatures":
+		failed, err := s.system.VerifyCustodySignatures(evidenceID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"evidence_id": evidenceID, "valid": len(failed) == 0, "failed_entries": failed})
+	case len(parts) == 2 && parts[1] == "timestamps":
+		results, err := s.system.VerifyTimestamps(evidenceID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, results)
+	case len(parts) == 2 && parts[1] == "inclusion-proof":
+		proof, err := s.system.InclusionProof(evidenceID, r.URL.Query().Get("anchor"))
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, proof)
+	case len(parts) == 2 && parts[1] == "label":
+		s.serveLabel(w, r, evidenceID, userID)
+	case len(parts) == 2 && parts[1] == "affidavit":
+		data, err := s.system.GenerateCustodyAffidavitPDF(evidenceID, userID, r.URL.Query().Get("name"))
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		if r.URL.Query().Get("signed") == "true" {
+			s.writeSignedBundle(w, r, "affidavit-"+evidenceID+".pdf", data, userID)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "affidavit-"+evidenceID+".pdf"))
+		w.Write(data)
+	case len(parts) == 2 && parts[1] == "waveform":
+		data, err := s.system.GenerateWaveformSVG(evidenceID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(data)
+	case len(parts) == 2 && parts[1] == "timeline":
+		timeline, err := s.system.GetTimelineContext(r.Context(), evidenceID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, timeline)
+	case len(parts) == 2 && parts[1] == "views":
+		writeJSON(w, http.StatusOK, s.system.ViewSessions(evidenceID))
+	case len(parts) == 2 && parts[1] == "copies":
+		writeJSON(w, http.StatusOK, s.system.CopiesOf(evidenceID))
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// aclRequest is the body of PUT /api/evidence/{id}/acl
+type aclRequest struct {
+	Users  []string `json:"users"`
+	Reason string   `json:"reason"`
+}
+
+// handleEvidenceACL serves PUT /api/evidence/{id}/acl, restricting the
+// evidence to the caller and the users listed, and DELETE, with the
+// ?reason=, lifting the restriction
+func (s *apiServer) handleEvidenceACL(w http.ResponseWriter, r *http.Request, evidenceID, userID string) {
+	if r.Method == http.MethodDelete {
+		if err := s.system.LiftRestrictionContext(r.Context(), evidenceID, r.URL.Query().Get("reason"), userID); err != nil {
+			writeSystemError(w, http.StatusConflict, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req aclRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if err := s.system.RestrictEvidenceContext(r.Context(), evidenceID, req.Users, req.Reason, userID); err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	evidence, err := s.system.GetEvidenceContext(r.Context(), evidenceID)
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, evidence.ACL)
+}
+
+// serveGrantedEvidence serves evidence to a grant-only user, who may view the
+// evidence record itself and nothing else
+func (s *apiServer) serveGrantedEvidence(w http.ResponseWriter, r *http.Request, evidenceID, userID string, record bool) {
+	if !record {
+		writeError(w, http.StatusForbidden, "access is limited to granted evidence")
+		return
+	}
+
+	evidence, err := s.system.AccessEvidenceContext(r.Context(), evidenceID, userID, clientIP(r))
+	switch {
+	case errors.Is(err, errNoAccessGrant):
+		writeSystemError(w, http.StatusForbidden, err)
+	case err != nil:
+		writeSystemError(w, http.StatusNotFound, err)
+	default:
+		writeJSON(w, http.StatusOK, evidence)
+	}
+}
+
+// handlePlayback serves POST /api/evidence/{id}/view, which opens a view
+// session, and GET /api/evidence/{id}/stream?session=, which streams the
+// evidence file with byte-range support within that session
+func (s *apiServer) handlePlayback(w http.ResponseWriter, r *http.Request, evidenceID, endpoint, userID string) {
+	if s.grantOnly(userID) && !s.system.HasAccessGrant(evidenceID, userID) {
+		writeError(w, http.StatusForbidden, errNoAccessGrant.Error())
+		return
+	}
+
+	if endpoint == "view" {
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		// A JSON body cannot be sent cross-site without a CORS preflight
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+			return
+		}
+		session, err := s.system.StartViewSessionContext(r.Context(), evidenceID, userID, clientIP(r))
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, session)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	sessionID := r.URL.Query().Get("session")
+	file, evidence, err := s.system.OpenEvidenceStream(sessionID, evidenceID, userID)
+	switch {
+	case errors.Is(err, errViewSessionInvalid):
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	case err != nil:
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+	defer file.Close()
+
+	// Only single ranges are served so each request maps to one audited span;
+	// a server may ignore Range and send the whole file instead
+	if strings.Contains(r.Header.Get("Range"), ",") {
+		r.Header.Del("Range")
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Header().Set("ETag", `"`+evidence.FileHash+`"`)
+	rec := &rangeRecorder{ResponseWriter: w}
+	http.ServeContent(rec, r, filepath.Base(evidence.FilePath), time.Time{}, file)
+
+	if r.Method == http.MethodGet && rec.written > 0 && (rec.status == 0 || rec.status == http.StatusOK || rec.status == http.StatusPartialContent) {
+		start := rec.rangeStart()
+		s.system.RecordServedRange(sessionID, start, start+rec.written-1, clientIP(r))
+		// A single response carrying the whole file is a download, not playback
+		if start == 0 && rec.written == evidence.FileSize {
+			s.system.RegisterCopyContext(r.Context(), evidenceID, CopyDownload, userID, "API download to "+clientIP(r),
+				"Full file streamed in view session "+sessionID, evidence.FileHash, evidence.FileSize)
+		}
+	}
+}
+
+// rangeRecorder counts the body bytes written so the range actually
+// delivered, rather than the range requested, is audited
+type rangeRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (rr *rangeRecorder) WriteHeader(status int) {
+	rr.status = status
+	rr.ResponseWriter.WriteHeader(status)
+}
+
+func (rr *rangeRecorder) Write(p []byte) (int, error) {
+	n, err := rr.ResponseWriter.Write(p)
+	rr.written += int64(n)
+	return n, err
+}
+
+// rangeStart returns the first byte offset of the response from its
+// Content-Range header, or 0 for a full-file response
+func (rr *rangeRecorder) rangeStart() int64 {
+	var start, end, size int64
+	if _, err := fmt.Sscanf(rr.Header().Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &size); err != nil {
+		return 0
+	}
+	return start
+}
+
+// grantRequest is the body of POST /api/grants
+type grantRequest struct {
+	EvidenceID string `json:"evidence_id"`
+	UserID     string `json:"user_id"`
+	Duration   string `json:"duration"`
+	Reason     string `json:"reason"`
+}
+
+// handleGrants lists active access grants (GET, filtered by evidence_id and
+// user_id) and creates them (POST). Grant-only users see just their own grants.
+func (s *apiServer) handleGrants(w http.ResponseWriter, r *http.Request, userID string) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		filterUser := q.Get("user_id")
+		if s.grantOnly(userID) {
+			filterUser = userID
+		}
+		writeJSON(w, http.StatusOK, s.system.ActiveAccessGrants(q.Get("evidence_id"), filterUser))
+
+	case http.MethodPost:
+		if s.grantOnly(userID) {
+			writeError(w, http.StatusForbidden, "access is limited to granted evidence")
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+			return
+		}
+
+		var req grantRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		duration, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "duration must be a Go duration such as 72h")
+			return
+		}
+
+		grant, err := s.system.GrantAccessByContext(r.Context(), req.EvidenceID, req.UserID, userID, duration, req.Reason)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, grant)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// metadataRequest is the body of PATCH /api/evidence/{id}
+type metadataRequest struct {
+	Revision int64 `json:"revision"`
+	MetadataUpdate
+}
+
+// handleUpdateMetadata edits evidence metadata against the revision the
+// client read. A stale revision gets 409 with the current revision, so the
+// client can re-read the record and reapply its edit.
+func (s *apiServer) handleUpdateMetadata(w http.ResponseWriter, r *http.Request, evidenceID, userID string) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+	var req metadataRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	evidence, err := s.system.UpdateMetadataContext(r.Context(), evidenceID, userID, req.Revision, req.MetadataUpdate)
+	var conflict *RevisionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":            err.Error(),
+			"code":             CodeRevisionConflict,
+			"current_revision": conflict.Current,
+		})
+	case err != nil:
+		writeSystemError(w, http.StatusBadRequest, err)
+	default:
+		writeJSON(w, http.StatusOK, evidence)
+	}
+}
+
+// evidenceActionRequest is the body of POST /api/evidence/{id}/custody and
+// /status. Custody is handed over by the caller, so From may only name the
+// caller; a status change with a revision is refused if the record has
+// changed since.
+type evidenceActionRequest struct {
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	Purpose  string         `json:"purpose"`
+	Status   EvidenceStatus `json:"status"`
+	Notes    string         `json:"notes"`
+	Revision int64          `json:"revision"`
+	// Signature is attached to the custody entry of a transfer
+	Signature *CustodySignature `json:"signature,omitempty"`
+}
+
+// handleEvidenceAction transfers custody of evidence, changes its status or
+// verifies its integrity, returning the record as it now stands or, for
+// verify, the outcome of the check
+func (s *apiServer) handleEvidenceAction(w http.ResponseWriter, r *http.Request, evidenceID, action, userID string) {
+	if action == "verify" {
+		valid, err := s.system.VerifyIntegrityContext(r.Context(), evidenceID, userID)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"evidence_id": evidenceID, "valid": valid})
+		return
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+	var req evidenceActionRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var err error
+	if action == "custody" {
+		if req.From != "" && req.From != userID {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("%s may not transfer custody on behalf of %s", userID, req.From))
+			return
+		}
+		if req.To == "" {
+			writeError(w, http.StatusBadRequest, "to is required")
+			return
+		}
+		err = s.system.TransferCustodySignedContext(r.Context(), evidenceID, userID, req.To, req.Purpose, req.Signature)
+	} else {
+		switch req.Status {
+		case StatusCollected, StatusProcessing, StatusAnalyzed, StatusArchived:
+		default:
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("status %q is not one of COLLECTED, PROCESSING, ANALYZED, ARCHIVED", req.Status))
+			return
+		}
+		err = s.system.UpdateStatusContext(r.Context(), evidenceID, userID, req.Status, req.Notes, req.Revision)
+	}
+	var conflict *RevisionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		writeJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":            err.Error(),
+			"code":             CodeRevisionConflict,
+			"current_revision": conflict.Current,
+		})
+		return
+	case errors.Is(err, ErrEvidenceNotFound):
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	case err != nil:
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	evidence, err := s.system.GetEvidenceContext(r.Context(), evidenceID)
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, evidence)
+}
+
+// labelQRScale is the pixel size of one QR module in PNG label codes
+const labelQRScale = 8
+
+func (s *apiServer) serveLabel(w http.ResponseWriter, r *http.Request, evidenceID, userID string) {
+	if r.URL.Query().Get("format") == "png" {
+		data, err := s.system.GenerateLabelQRPNG(evidenceID, labelQRScale)
+		if err != nil {
+			writeSystemError(w, http.StatusNotFound, err)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+		return
+	}
+
+	data, err := s.system.GenerateLabelSVG(evidenceID, userID)
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(data)
+}
+
+// handleVerifyLink sends scanned label URLs (/verify/{id}) to the evidence in the review UI
+func (s *apiServer) handleVerifyLink(w http.ResponseWriter, r *http.Request) {
+	evidenceID, err := ParseLabelCode(strings.TrimPrefix(r.URL.Path, "/verify/"))
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+	http.Redirect(w, r, "/?evidence="+url.QueryEscape(evidenceID), http.StatusFound)
+}
+
+// scanRequest is the body of POST /api/scan
+type scanRequest struct {
+	Code    string `json:"code"`
+	Action  string `json:"action"`
+	To      string `json:"to"`
+	Purpose string `json:"purpose"`
+	// Signature is attached to the custody entry for checkout and checkin
+	Signature *CustodySignature `json:"signature,omitempty"`
+}
+
+// handleScan looks up a scanned label code and optionally acts on the evidence:
+// action is lookup (default), checkout, checkin or transfer. The response is the
+// evidence's custody state after the action.
+func (s *apiServer) handleScan(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	// A JSON body cannot be sent cross-site without a CORS preflight
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+		return
+	}
+
+	var req scanRequest
+	// Room for a base64 signature image
+	if err := json.NewDecoder(io.LimitReader(r.Body, 256*1024)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	evidenceID, err := ParseLabelCode(req.Code)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	if _, err := s.system.ScanLabelContext(r.Context(), evidenceID, userID); err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+
+	switch req.Action {
+	case "", "lookup":
+	case "checkout":
+		_, err = s.system.CheckOutEvidenceSignedContext(r.Context(), evidenceID, userID, req.To, req.Purpose, req.Signature)
+	case "checkin":
+		err = s.system.CheckInEvidenceSignedContext(r.Context(), evidenceID, userID, req.Signature)
+	case "transfer":
+		_, err = s.system.RequestCustodyTransferContext(r.Context(), evidenceID, userID, req.To, req.Purpose)
+	default:
+		writeError(w, http.StatusBadRequest, "action must be lookup, checkout, checkin or transfer")
+		return
+	}
+	if err != nil {
+		writeSystemError(w, http.StatusConflict, err)
+		return
+	}
+
+	result, err := s.system.scanState(evidenceID, userID)
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// maxGraphQLBody bounds a GraphQL request
+const maxGraphQLBody = 1 << 20
+
+// handleGraphQL runs read-only GraphQL queries, posted as
+// {"query", "operationName", "variables"} or given as GET parameters, and
+// serves the schema as SDL to a GET without a query
+func (s *apiServer) handleGraphQL(w http.ResponseWriter, r *http.Request, userID string) {
+	var body struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		if q.Get("query") == "" {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			io.WriteString(w, graphQLSchema.sdl())
+			return
+		}
+		body.Query, body.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &body.Variables); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid variables: "+err.Error())
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLBody)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	req := &gqlRequest{ctx: r.Context(), system: s.system, userID: userID}
+	resp, ok := graphQLSchema.execute(req, body.Query, body.OperationName, body.Variables)
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, resp)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *apiServer) handleAuditLogs(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	opts, err := queryOptions(q)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	filter := AuditFilter{EvidenceID: q.Get("evidence_id"), UserID: q.Get("user_id"), Action: q.Get("action")}
+	logs, err := s.system.GetAuditLogsPageContext(r.Context(), filter, opts)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	writePageHeaders(w, logs.Total, logs.NextCursor)
+	writeJSON(w, http.StatusOK, logs.Entries)
+}
+
+// reviewDay parses ?date= as a review queue day, defaulting to today
+func reviewDay(r *http.Request) (time.Time, error) {
+	v := r.URL.Query().Get("date")
+	if v == "" {
+		return time.Now(), nil
+	}
+	day, err := time.ParseInLocation(reviewDayLayout, v, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("date must be YYYY-MM-DD")
+	}
+	return day, nil
+}
+
+// handleActivityBaselines serves the activity baselines in effect on ?date=
+func (s *apiServer) handleActivityBaselines(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if err := s.system.Authorize(userID, PermReviewAudit); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+	day, err := reviewDay(r)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.system.ActivityBaselines(day))
+}
+
+// activityReviewRequest is the body of POST /api/audit/review
+type activityReviewRequest struct {
+	UserID string `json:"user_id"`
+	Date   string `json:"date"`
+	Notes  string `json:"notes"`
+}
+
+// handleActivityReview serves the review queue for ?date= and records an
+// auditor's review on POST
+func (s *apiServer) handleActivityReview(w http.ResponseWriter, r *http.Request, userID string) {
+	if err := s.system.Authorize(userID, PermReviewAudit); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		day, err := reviewDay(r)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.system.ActivityReviewQueue(day))
+
+	case http.MethodPost:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+			return
+		}
+
+		var req activityReviewRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		day, err := time.ParseInLocation(reviewDayLayout, req.Date, time.Local)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "date must be YYYY-MM-DD")
+			return
+		}
+		if err := ValidateOfficerID(req.UserID); err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.system.ReviewActivityContext(r.Context(), req.UserID, day, userID, req.Notes); err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// anomalyReview is the body of POST /api/anomalies
+type anomalyReview struct {
+	UserID string `json:"user_id"`
+	Notes  string `json:"notes"`
+}
+
+// handleAnomalies lists access alerts raised since ?since= (RFC 3339) with
+// the accounts awaiting review, and clears an account's flag on POST
+func (s *apiServer) handleAnomalies(w http.ResponseWriter, r *http.Request, userID string) {
+	switch r.Method {
+	case http.MethodGet:
+		var since time.Time
+		if v := r.URL.Query().Get("since"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+				return
+			}
+			since = t
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"alerts":           s.system.AccessAlerts(since),
+			"flagged_accounts": s.system.FlaggedAccounts(),
+		})
+
+	case http.MethodPost:
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, "content type must be application/json")
+			return
+		}
+
+		var req anomalyReview
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := ValidateOfficerID(req.UserID); err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := s.system.ReviewFlaggedAccountContext(r.Context(), req.UserID, userID, req.Notes); err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// reportProfileFor returns the most detailed report profile userID may request
+func (s *apiServer) reportProfileFor(userID string) ReportProfile {
+	for _, cred := range s.config.API.Credentials {
+		if cred.UserID != userID {
+			continue
+		}
+		if cred.ReportProfile == "" {
+			return ReportProfileInternal
+		}
+		if profile, err := ParseReportProfile(cred.ReportProfile); err == nil {
+			return profile
+		}
+	}
+	return ReportProfilePublic
+}
+
+// handleReport serves /api/reports/{case} as a downloadable text or HTML (?format=html) report
+// in the locale selected by ?lang=. The optional profile parameter narrows the report below
+// the caller's own profile. With ?signed=true the report comes in a zip with the agency's
+// detached signature.
+func (s *apiServer) handleReport(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	allowed := s.reportProfileFor(userID)
+	profile := allowed
+	if name := r.URL.Query().Get("profile"); name != "" {
+		requested, err := ParseReportProfile(name)
+		if err != nil {
+			writeSystemError(w, http.StatusBadRequest, err)
+			return
+		}
+		if !allowed.Permits(requested) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("report profile %s is not permitted", requested))
+			return
+		}
+		profile = requested
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "text" && format != "html" {
+		writeError(w, http.StatusBadRequest, "format must be text or html")
+		return
+	}
+
+	locale, err := ParseLocale(r.URL.Query().Get("lang"))
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	caseNumber := strings.TrimPrefix(r.URL.Path, "/api/reports/")
+	opts := ReportOptions{Profile: profile, Locale: locale}
+	var report string
+	if format == "html" {
+		report, err = s.system.GenerateHTMLReportContext(r.Context(), caseNumber, opts)
+	} else {
+		report, err = s.system.GenerateCaseReportContext(r.Context(), caseNumber, opts)
+	}
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+
+	s.auditRequest(r, userID, "DOWNLOAD_REPORT", "", fmt.Sprintf("Report downloaded for case %s (profile: %s, locale: %s)", caseNumber, profile, locale))
+	s.system.registerCaseCopy(caseNumber, CopyReport, userID, "API download to "+clientIP(r),
+		fmt.Sprintf("%s report", profile), []byte(report))
+
+	if r.URL.Query().Get("signed") == "true" {
+		name := "report-" + caseNumber + ".txt"
+		if format == "html" {
+			name = "report-" + caseNumber + ".html"
+		}
+		s.writeSignedBundle(w, r, name, []byte(report), userID)
+		return
+	}
+
+	if format == "html" {
+		// The report is self-contained: inline styles and data: thumbnails only
+		w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:")
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "report-"+caseNumber+".html"))
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "report-"+caseNumber+".txt"))
+	}
+	w.Write([]byte(report))
+}
+
+// writeSignedBundle serves a generated document as a zip holding the document,
+// its detached PKCS#7 signature (name.p7s) and the agency certificate chain
+func (s *apiServer) writeSignedBundle(w http.ResponseWriter, r *http.Request, name string, data []byte, userID string) {
+	signature, err := s.system.SignDocumentContext(r.Context(), name, data, userID)
+	if err != nil {
+		writeSystemError(w, http.StatusNotImplemented, err)
+		return
+	}
+	chain, err := s.system.ReportSigningChainPEM()
+	if err != nil {
+		writeSystemError(w, http.StatusNotImplemented, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, part := range []struct {
+		name string
+		data []byte
+	}{{name, data}, {name + ".p7s", signature}, {"agency-certificate.pem", chain}} {
+		fw, err := zw.Create(part.name)
+		if err == nil {
+			_, err = fw.Write(part.data)
+		}
+		if err != nil {
+			writeSystemError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	if err := zw.Close(); err != nil {
+		writeSystemError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", strings.TrimSuffix(name, filepath.Ext(name))+"-signed.zip"))
+	w.Write(buf.Bytes())
+}
+
+// handleNIEMExchange serves the case named in the path as a NIEM XML exchange
+// document
+func (s *apiServer) handleNIEMExchange(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	caseNumber := strings.TrimPrefix(r.URL.Path, "/api/exchange/niem/")
+	if caseNumber == "" {
+		writeError(w, http.StatusBadRequest, "case number is required")
+		return
+	}
+	data, err := s.system.ExportCaseNIEM(caseNumber, userID, "API download to "+clientIP(r))
+	if errors.Is(err, errEvidenceSealed) {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		writeSystemError(w, http.StatusNotFound, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "niem-"+unsafeFileChars.ReplaceAllString(caseNumber, "_")+".xml"))
+	w.Write(data)
+}
+
+// defaultForecastDays is the retention forecast window when ?days= is not given
+const defaultForecastDays = 90
+
+// handleRetentionForecast serves the evidence whose retention ends within
+// ?days= (default 90) as json (default), csv or text (?format=)
+func (s *apiServer) handleRetentionForecast(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	days := defaultForecastDays
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		days = n
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if !validScheduleFormat(ScheduledRetentionForecast, format) {
+		writeError(w, http.StatusBadRequest, "format must be json, csv or text")
+		return
+	}
+
+	data, err := s.system.retentionForecastReport(format, time.Now(), days)
+	if err != nil {
+		writeSystemError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.auditRequest(r, userID, "RETENTION_FORECAST", "", fmt.Sprintf("Retention forecast for the next %d days downloaded", days))
+
+	w.Header().Set("Content-Type", artifactContentType(format))
+	w.Write(data)
+}
+
+// defaultAccountabilityDays is the officer accountability period when ?days= is not given
+const defaultAccountabilityDays = 30
+
+// handleOfficerAccountability serves, per officer, the evidence uploaded in
+// the last ?days= (default 30) and how much of it is categorized, as json
+// (default), csv or text (?format=)
+func (s *apiServer) handleOfficerAccountability(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	days := defaultAccountabilityDays
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "days must be a positive number")
+			return
+		}
+		days = n
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if !validScheduleFormat(ScheduledOfficerAccountability, format) {
+		writeError(w, http.StatusBadRequest, "format must be json, csv or text")
+		return
+	}
+
+	now := time.Now()
+	data, err := s.system.officerAccountabilityReport(format, now.AddDate(0, 0, -days), now, now)
+	if err != nil {
+		writeSystemError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.auditRequest(r, userID, "OFFICER_ACCOUNTABILITY_REPORT", "", fmt.Sprintf("Officer accountability report for the last %d days downloaded", days))
+
+	w.Header().Set("Content-Type", artifactContentType(format))
+	w.Write(data)
+}
+
+// handleRetentionSimulation evaluates the retention policy in the request
+// body against stored evidence without purging anything. Query parameters:
+// days (default 0, a purge run today) and format (json or text).
+func (s *apiServer) handleRetentionSimulation(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	q := r.URL.Query()
+	days := 0
+	if v := q.Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "days must be a number of at least 0")
+			return
+		}
+		days = n
+	}
+	format := q.Get("format")
+	if format != "" && format != "json" && format != "text" {
+		writeError(w, http.StatusBadRequest, "format must be json or text")
+		return
+	}
+
+	var policy RetentionPolicy
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&policy); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	sim, err := s.system.SimulateRetention(policy, time.Now(), days)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.auditRequest(r, userID, "RETENTION_SIMULATION", "",
+		fmt.Sprintf("Retention policy of %d days and %d rules simulated: %d items would be purged (%d bytes), %d protected",
+			policy.RetentionDays, len(policy.RetentionRules), len(sim.Purged), sim.ReclaimedBytes, len(sim.Protected)))
+
+	if format == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(sim.Text()))
+		return
+	}
+	writeJSON(w, http.StatusOK, sim)
+}
+
+// handleAnalytics serves a daily time series computed from system data.
+// Query parameters: days (default 30).
+func (s *apiServer) handleAnalytics(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	days := defaultAnalyticsDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "days must be a number")
+			return
+		}
+		days = n
+	}
+
+	metric := strings.TrimPrefix(r.URL.Path, "/api/analytics/")
+	if _, known := analyticsMetrics[metric]; !known {
+		writeError(w, http.StatusNotFound, "unknown metric "+strconv.Quote(metric))
+		return
+	}
+	series, err := s.system.Analytics(metric, time.Now(), days)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, series)
+}
+
+// eventPingInterval keeps idle event streams alive through proxies
+const eventPingInterval = 30 * time.Second
+
+// handleEventStream pushes audit events and integrity alerts over a WebSocket,
+// leaving out those about restricted evidence the caller may not see.
+// Query parameters: types (comma-separated), evidence_id, case, user_id.
+func (s *apiServer) handleEventStream(w http.ResponseWriter, r *http.Request, userID string) {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			writeError(w, http.StatusForbidden, "cross-origin event subscriptions are not allowed")
+			return
+		}
+	}
+
+	if err := s.system.Authorize(userID, PermReviewAudit); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+	filter := parseEventFilter(r.URL.Query())
+
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeSystemError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	sub := s.system.Subscribe(filter)
+	defer sub.Close()
+
+	s.auditRequest(r, userID, "SUBSCRIBE_EVENTS", filter.EvidenceID,
+		fmt.Sprintf("Event stream opened (types: %v)", filter.Types))
+
+	done := make(chan struct{})
+	go func() {
+		ws.readLoop()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(eventPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-sub.C:
+			if event.EvidenceID != "" && !s.system.canRead(r.Context(), event.EvidenceID) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if err := ws.WriteText(data); err != nil {
+				ws.conn.Close()
+				return
+			}
+		case <-ticker.C:
+			if err := ws.Ping(); err != nil {
+				ws.conn.Close()
+				return
+			}
+		case <-done:
+			ws.conn.Close()
+			return
+		}
+	}
+}
+
+// handleEvidenceStream emits events as server-sent events, the evidence
+// lifecycle changes unless types names others, leaving out those about
+// restricted evidence the caller may not see.
+// Query parameters: types (comma-separated), evidence_id, case, user_id.
+func (s *apiServer) handleEvidenceStream(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if err := s.system.Authorize(userID, PermReviewAudit); err != nil {
+		writeSystemError(w, http.StatusForbidden, err)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := parseEventFilter(q)
+	if len(filter.Types) == 0 {
+		filter.Types = lifecycleEventTypes
+	}
+	sub := s.system.Subscribe(filter)
+	defer sub.Close()
+
+	s.auditRequest(r, userID, "SUBSCRIBE_EVENTS", q.Get("evidence_id"),
+		fmt.Sprintf("Evidence change stream opened (case: %q, types: %v)", q.Get("case"), filter.Types))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event := <-sub.C:
+			if event.EvidenceID != "" && !s.system.canRead(r.Context(), event.EvidenceID) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		case <-ticker.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// parseEventFilter builds an event filter from query parameters
+func parseEventFilter(q url.Values) EventFilter {
+	filter := EventFilter{
+		EvidenceID: q.Get("evidence_id"),
+		CaseNumber: q.Get("case"),
+		UserID:     q.Get("user_id"),
+	}
+	for _, t := range strings.Split(q.Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, EventType(strings.ToUpper(t)))
+		}
+	}
+	return filter
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// writeSystemError writes err with status, adding its code when the system
+// classifies it. Permission refusals are always 403.
+func writeSystemError(w http.ResponseWriter, status int, err error) {
+	body := map[string]string{"error": err.Error()}
+	if code := ErrorCodeOf(err); code != "" {
+		body["code"] = string(code)
+	}
+	// A refusal is the same whichever operation was refused
+	if errors.Is(err, ErrPermissionDenied) {
+		status = http.StatusForbidden
+	}
+	writeJSON(w, status, body)
+}
+
+// auditRequest records an entry for userID's request r, with how its
+// principal authenticated
+func (s *apiServer) auditRequest(r *http.Request, userID, action, evidenceID, details string) {
+	s.system.logAuditAs(PrincipalFromContext(r.Context()).authentication(), userID, action, evidenceID, details, clientIP(r))
+}
+
+// clientIP returns the remote address of a request without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// newAPIToken generates a random API token and the digest to store in configuration
+func newAPIToken() (token, digest string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = hex.EncodeToString(b)
+	sum := sha256.Sum256([]byte(token))
+	return token, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,639 @@
+package bwc
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+const testAPIToken = "test-token-for-cus-001"
+
+// setupTestServer creates a BWC system with one API credential and an httptest server for it
+func setupTestServer(t *testing.T) (*BWCSystem, *httptest.Server, string, func()) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+
+	sum := sha256.Sum256([]byte(testAPIToken))
+	system.config.API.Credentials = []APICredential{
+		{UserID: "CUS-001", TokenSHA256: hex.EncodeToString(sum[:])},
+	}
+
+	server := httptest.NewServer(newAPIServer(system))
+
+	return system, server, tmpDir, func() {
+		server.Close()
+		cleanup()
+	}
+}
+
+// authGet performs an authenticated GET request against the test server
+func authGet(t *testing.T, server *httptest.Server, path string) *http.Response {
+	req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	return resp
+}
+
+func TestServerRequiresAuthentication(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for _, path := range []string{"/api/evidence", "/api/audit", "/api/reports/CASE-1", "/api/session"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/evidence", nil)
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	resp, _ := http.DefaultClient.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong token, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerServesEmbeddedUI(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected 200 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestServerEvidenceEndpoints(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WEB-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/evidence?case=CASE-WEB-001")
+	var results []Evidence
+	json.NewDecoder(resp.Body).Decode(&results)
+	resp.Body.Close()
+	if len(results) != 1 || results[0].ID != evidence.ID {
+		t.Errorf("Expected search to return %s, got %v", evidence.ID, results)
+	}
+
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID+"/custody")
+	var custody []CustodyEntry
+	json.NewDecoder(resp.Body).Decode(&custody)
+	resp.Body.Close()
+	if len(custody) != 1 {
+		t.Errorf("Expected 1 custody entry, got %d", len(custody))
+	}
+
+	resp = authGet(t, server, "/api/evidence/INVALID-ID")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown evidence, got %d", resp.StatusCode)
+	}
+
+	resp = authGet(t, server, "/api/reports/CASE-WEB-001")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for report, got %d", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+		t.Errorf("Expected report to be served as an attachment, got %q", resp.Header.Get("Content-Disposition"))
+	}
+
+	logs := system.GetAuditLogs("", "CUS-001")
+	if len(logs) != 1 || logs[0].Action != "DOWNLOAD_REPORT" || logs[0].IPAddress == "" {
+		t.Errorf("Expected report download to be audited with client IP, got %v", logs)
+	}
+}
+
+// authPostJSON performs an authenticated JSON POST request against the test server
+func authPostJSON(t *testing.T, server *httptest.Server, path, body string) *http.Response {
+	req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", path, err)
+	}
+	return resp
+}
+
+func TestServerEvidenceActions(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-WEB-002", "OFF-124", "Officer Test", "Test Location", nil)
+	base := "/api/evidence/" + evidence.ID
+
+	resp := authPostJSON(t, server, base+"/custody", `{"from": "OFF-124", "to": "CUS-001", "purpose": "Property room"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a transfer on behalf of another officer, got %d", resp.StatusCode)
+	}
+	resp = authPostJSON(t, server, base+"/custody", `{"to": "DET-001", "purpose": "Property room"}`)
+	var updated Evidence
+	json.NewDecoder(resp.Body).Decode(&updated)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(updated.ChainOfCustody) != 2 || updated.ChainOfCustody[1].FromOfficer != "CUS-001" || updated.ChainOfCustody[1].ToOfficer != "DET-001" {
+		t.Fatalf("Expected the custody transfer in the returned record, got %d %+v", resp.StatusCode, updated.ChainOfCustody)
+	}
+
+	resp = authPostJSON(t, server, base+"/status", fmt.Sprintf(`{"status": "ANALYZED", "notes": "Reviewed", "revision": %d}`, updated.Revision))
+	json.NewDecoder(resp.Body).Decode(&updated)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || updated.Status != StatusAnalyzed {
+		t.Errorf("Expected the status changed, got %d %s", resp.StatusCode, updated.Status)
+	}
+	resp = authPostJSON(t, server, base+"/status", `{"status": "ARCHIVED", "revision": 1}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for a stale revision, got %d", resp.StatusCode)
+	}
+	resp = authPostJSON(t, server, base+"/status", `{"status": "LOST"}`)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown status, got %d", resp.StatusCode)
+	}
+
+	resp = authPostJSON(t, server, base+"/verify", "")
+	var result struct {
+		Valid bool `json:"valid"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !result.Valid {
+		t.Errorf("Expected the evidence to verify, got %d %+v", resp.StatusCode, result)
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "CUS-001")
+	if len(logs) != 4 || logs[0].Action != "TRANSFER_CUSTODY" || logs[1].Action != "UPDATE_STATUS" || logs[2].Action != "REVISION_CONFLICT" || logs[3].Action != "VERIFY_INTEGRITY" {
+		t.Errorf("Expected the transfer, status changes and check audited as the caller, got %v", logs)
+	}
+}
+
+func TestServerReportProfiles(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	const courtToken = "test-token-for-pros-001"
+	sum := sha256.Sum256([]byte(courtToken))
+	system.config.API.Credentials = append(system.config.API.Credentials,
+		APICredential{UserID: "PROS-001", TokenSHA256: hex.EncodeToString(sum[:]), ReportProfile: "court"})
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WEB-002", "OFF-123", "Officer Test", "Test Location", nil)
+
+	get := func(path string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+courtToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := get("/api/reports/CASE-WEB-002")
+	if code != http.StatusOK || !strings.Contains(body, "Report Profile: court") || strings.Contains(body, evidence.FilePath) {
+		t.Errorf("Expected court report without file paths, got %d: %s", code, body)
+	}
+
+	if code, _ := get("/api/reports/CASE-WEB-002?profile=internal"); code != http.StatusForbidden {
+		t.Errorf("Expected 403 when requesting a broader profile, got %d", code)
+	}
+
+	code, body = get("/api/reports/CASE-WEB-002?profile=public")
+	if code != http.StatusOK || strings.Contains(body, "Officer Test") {
+		t.Errorf("Expected public report without officer details, got %d: %s", code, body)
+	}
+
+	code, body = get("/api/reports/CASE-WEB-002?format=html")
+	if code != http.StatusOK || !strings.HasPrefix(body, "<!DOCTYPE html>") {
+		t.Errorf("Expected HTML report, got %d", code)
+	}
+}
+
+func TestServerEvidenceLabel(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WEB-003", "OFF-123", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/evidence/"+evidence.ID+"/label")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("Expected SVG label, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID+"/label?format=png")
+	resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "image/png" {
+		t.Errorf("Expected PNG QR code, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(server.URL + "/verify/" + url.PathEscape(evidence.ID))
+	if err != nil {
+		t.Fatalf("GET /verify failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || resp.Header.Get("Location") != "/?evidence="+url.QueryEscape(evidence.ID) {
+		t.Errorf("Expected redirect to review UI, got %d %s", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestServerLoginSession(t *testing.T) {
+	system, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	resp, err := http.PostForm(server.URL+"/login", url.Values{"token": {"wrong"}})
+	if err != nil {
+		t.Fatalf("POST /login failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for bad login, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.PostForm(server.URL+"/login", url.Values{"token": {testAPIToken}})
+	if err != nil {
+		t.Fatalf("POST /login failed: %v", err)
+	}
+	resp.Body.Close()
+
+	var session *http.Cookie
+	for _, c := range resp.Cookies() {
+		if c.Name == sessionCookieName {
+			session = c
+		}
+	}
+	if session == nil || !session.HttpOnly {
+		t.Fatal("Expected an HttpOnly session cookie")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/session", nil)
+	req.AddCookie(session)
+	resp, _ = http.DefaultClient.Do(req)
+	var who map[string]string
+	json.NewDecoder(resp.Body).Decode(&who)
+	resp.Body.Close()
+	if who["user_id"] != "CUS-001" {
+		t.Errorf("Expected session for CUS-001, got %v", who)
+	}
+
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/logout", nil)
+	req.AddCookie(session)
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/session", nil)
+	req.AddCookie(session)
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected session to be invalid after logout, got %d", resp.StatusCode)
+	}
+
+	failed := system.GetAuditLogs("", "UNKNOWN")
+	if len(failed) != 1 || failed[0].Action != "LOGIN_FAILED" {
+		t.Errorf("Expected failed login to be audited, got %v", failed)
+	}
+}
+
+func TestEvidenceStreamSSE(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	resp := authGet(t, server, "/api/stream/evidence?case=CASE-SSE-001")
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %s", ct)
+	}
+
+	// The connected comment confirms the subscription is registered
+	br := bufio.NewReader(resp.Body)
+	if line, _ := br.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("Unexpected first line %q", line)
+	}
+	br.ReadString('\n')
+
+	otherFile := createTestFile(t, tmpDir)
+	system.IngestEvidence(otherFile, "CASE-SSE-002", "OFF-789", "Officer Other", "Test Location", nil)
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SSE-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	eventLine, _ := br.ReadString('\n')
+	dataLine, _ := br.ReadString('\n')
+	if eventLine != "event: EVIDENCE_INGESTED\n" {
+		t.Errorf("Unexpected event line %q", eventLine)
+	}
+
+	var event Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &event); err != nil {
+		t.Fatalf("Invalid event data %q: %v", dataLine, err)
+	}
+	if event.EvidenceID != evidence.ID || event.CaseNumber != "CASE-SSE-001" {
+		t.Errorf("Expected event for %s, got %+v", evidence.ID, event)
+	}
+}
+
+func TestEvidenceStreamSelectsTypes(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-SSE-003", "OFF-123", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/stream/evidence?types=integrity_alert&evidence_id="+evidence.ID)
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	br.ReadString('\n')
+	br.ReadString('\n')
+
+	system.UpdateStatus(evidence.ID, "OFF-123", StatusAnalyzed, "Reviewed")
+	os.WriteFile(evidence.FilePath, []byte("TAMPERED"), 0600)
+	system.VerifyIntegrity(evidence.ID, "AUDITOR-1")
+
+	if eventLine, _ := br.ReadString('\n'); eventLine != "event: INTEGRITY_ALERT\n" {
+		t.Errorf("Expected only the integrity alert, got %q", eventLine)
+	}
+}
+
+func TestServerGrantOnlyAccess(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	const detectiveToken = "test-token-for-det-ext"
+	sum := sha256.Sum256([]byte(detectiveToken))
+	system.config.API.Credentials = append(system.config.API.Credentials,
+		APICredential{UserID: "DET-EXT", TokenSHA256: hex.EncodeToString(sum[:]), GrantOnly: true})
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WEB-AGR", "OFF-303", "Officer Test", "Test Location", nil)
+
+	detectiveGet := func(path string) int {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("Authorization", "Bearer "+detectiveToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := detectiveGet("/api/evidence/" + evidence.ID); code != http.StatusForbidden {
+		t.Errorf("Expected 403 before a grant, got %d", code)
+	}
+
+	body := strings.NewReader(`{"evidence_id":"` + evidence.ID + `","user_id":"DET-EXT","duration":"48h","reason":"Related case"}`)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/grants", body)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /api/grants failed: %v", err)
+	}
+	var grant AccessGrant
+	json.NewDecoder(resp.Body).Decode(&grant)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || grant.GrantedBy != "CUS-001" {
+		t.Fatalf("Expected grant issued by CUS-001, got %d %+v", resp.StatusCode, grant)
+	}
+
+	if code := detectiveGet("/api/evidence/" + evidence.ID); code != http.StatusOK {
+		t.Errorf("Expected 200 under grant, got %d", code)
+	}
+	for _, path := range []string{"/api/evidence/" + evidence.ID + "/custody", "/api/evidence?case=CASE-WEB-AGR", "/api/audit", "/api/reports/CASE-WEB-AGR"} {
+		if code := detectiveGet(path); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for grant-only user on %s, got %d", path, code)
+		}
+	}
+
+	resp = authGet(t, server, "/api/grants?user_id=DET-EXT")
+	var grants []AccessGrant
+	json.NewDecoder(resp.Body).Decode(&grants)
+	resp.Body.Close()
+	if len(grants) != 1 || grants[0].AccessCount != 1 {
+		t.Errorf("Expected one active grant with one access, got %+v", grants)
+	}
+}
+
+func TestServerRangeStreaming(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WEB-VS", "OFF-402", "Officer Test", "Test Location", nil)
+	base := server.URL + "/api/evidence/" + evidence.ID
+
+	do := func(method, url string, header map[string]string) *http.Response {
+		req, _ := http.NewRequest(method, url, strings.NewReader("{}"))
+		req.Header.Set("Authorization", "Bearer "+testAPIToken)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s failed: %v", method, url, err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodGet, base+"/stream", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 without a view session, got %d", resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, base+"/view", map[string]string{"Content-Type": "application/json"})
+	var session ViewSession
+	json.NewDecoder(resp.Body).Decode(&session)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || session.ID == "" {
+		t.Fatalf("Expected view session, got %d %+v", resp.StatusCode, session)
+	}
+	stream := base + "/stream?session=" + url.QueryEscape(session.ID)
+
+	resp = do(http.MethodGet, stream, map[string]string{"Range": "bytes=0-9"})
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "This is te" {
+		t.Errorf("Expected first 10 bytes as 206, got %d %q", resp.StatusCode, body)
+	}
+	if resp.Header.Get("Content-Range") != "bytes 0-9/49" || resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Errorf("Unexpected range headers: %v", resp.Header)
+	}
+
+	resp = do(http.MethodGet, stream, map[string]string{"Range": "bytes=39-"})
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || len(body) != 10 {
+		t.Errorf("Expected last 10 bytes, got %d %q", resp.StatusCode, body)
+	}
+
+	resp = do(http.MethodGet, stream, map[string]string{"Range": "bytes=0-1,5-6"})
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(body) != 49 {
+		t.Errorf("Expected multi-range request to get the whole file, got %d (%d bytes)", resp.StatusCode, len(body))
+	}
+
+	resp = do(http.MethodGet, stream, map[string]string{"Range": "bytes=900-999"})
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("Expected 416 for out-of-range request, got %d", resp.StatusCode)
+	}
+
+	sessions := system.ViewSessions(evidence.ID)
+	if len(sessions) != 1 || len(sessions[0].Ranges) != 3 || sessions[0].BytesServed != 69 {
+		t.Fatalf("Unexpected recorded ranges: %+v", sessions)
+	}
+	if r := sessions[0].Ranges[1]; r.Start != 39 || r.End != 48 {
+		t.Errorf("Expected second range 39-48, got %+v", r)
+	}
+
+	ranges := 0
+	for _, log := range system.GetAuditLogs(evidence.ID, "CUS-001") {
+		if log.Action == "STREAM_RANGE" && log.IPAddress != "" {
+			ranges++
+		}
+	}
+	if ranges != 3 {
+		t.Errorf("Expected 3 audited ranges, got %d", ranges)
+	}
+}
+
+func TestServerDownloadsRegisterCopies(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WEB-CPY", "OFF-504", "Officer Test", "Test Location", nil)
+
+	resp := authGet(t, server, "/api/reports/CASE-WEB-CPY")
+	resp.Body.Close()
+
+	session, _ := system.StartViewSession(evidence.ID, "CUS-001", "")
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID+"/stream?session="+url.QueryEscape(session.ID))
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID+"/copies")
+	var copies []CopyRecord
+	json.NewDecoder(resp.Body).Decode(&copies)
+	resp.Body.Close()
+
+	if len(copies) != 2 || copies[0].Kind != CopyReport || copies[1].Kind != CopyDownload || copies[1].SHA256 != evidence.FileHash {
+		t.Errorf("Expected report and download copies, got %+v", copies)
+	}
+}
+
+func TestServerSignedCustodyTransfer(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-SRV-SIG", "CUS-001", "", "", nil)
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	body, _ := json.Marshal(map[string][]byte{"public_key": pub})
+	resp := authPostJSON(t, server, "/api/officers/CUS-001/keys", string(body))
+	var key OfficerKey
+	json.NewDecoder(resp.Body).Decode(&key)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated || key.KeyID != officerKeyID(pub) {
+		t.Fatalf("expected the key to be enrolled, got %d %+v", resp.StatusCode, key)
+	}
+
+	payload, _ := system.CustodySigningPayload(evidence.ID, "CUS-001", "DET-9", "TRANSFERRED", "Lab")
+	body, _ = json.Marshal(evidenceActionRequest{To: "DET-9", Purpose: "Lab",
+		Signature: &CustodySignature{Type: SignatureEd25519, SignerID: "CUS-001", Value: ed25519.Sign(priv, payload)}})
+	resp = authPostJSON(t, server, "/api/evidence/"+evidence.ID+"/custody", string(body))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the signed transfer to succeed, got %d", resp.StatusCode)
+	}
+
+	resp = authGet(t, server, "/api/evidence/"+evidence.ID+"/custody/signatures")
+	var result struct {
+		Valid bool `json:"valid"`
+	}
+	json.NewDecoder(resp.Body).Decode(&result)
+	resp.Body.Close()
+	if !result.Valid {
+		t.Error("expected the custody signatures to verify")
+	}
+}
+
+func TestServerSignedCustodyTransferNeedsPermission(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+	enableAccessControl(system)
+
+	const officerToken = "test-token-for-off-001"
+	sum := sha256.Sum256([]byte(officerToken))
+	system.config.API.Credentials = append(system.config.API.Credentials,
+		APICredential{UserID: "OFF-001", TokenSHA256: hex.EncodeToString(sum[:])})
+	for id, role := range map[string]Role{"OFF-001": RoleOfficer, "CUS-001": RoleEvidenceCustodian} {
+		if _, err := system.RegisterUser(id, "", role, "ADM-001"); err != nil {
+			t.Fatalf("RegisterUser %s failed: %v", id, err)
+		}
+	}
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-SRV-SIG", "CUS-001", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	payload, _ := system.CustodySigningPayload(evidence.ID, "SYSTEM", "OFF-001", "TRANSFERRED", "Lab")
+	sig := &CustodySignature{Type: SignatureEd25519, SignerID: "OFF-001", Value: ed25519.Sign(priv, payload)}
+
+	post := func(req evidenceActionRequest) int {
+		body, _ := json.Marshal(req)
+		r, _ := http.NewRequest(http.MethodPost, server.URL+"/api/evidence/"+evidence.ID+"/custody", strings.NewReader(string(body)))
+		r.Header.Set("Authorization", "Bearer "+officerToken)
+		r.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post(evidenceActionRequest{From: "SYSTEM", To: "OFF-001", Purpose: "Lab", Signature: sig}); code != http.StatusForbidden {
+		t.Errorf("expected a signed transfer on behalf of SYSTEM to be refused, got %d", code)
+	}
+	if code := post(evidenceActionRequest{To: "OFF-001", Purpose: "Lab", Signature: sig}); code != http.StatusForbidden {
+		t.Errorf("expected a signed transfer by an officer without the permission to be refused, got %d", code)
+	}
+	if chain, _ := system.GetChainOfCustody(evidence.ID); len(chain) != 1 {
+		t.Errorf("expected the custody chain unchanged, got %+v", chain)
+	}
+}
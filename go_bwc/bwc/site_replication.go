@@ -0,0 +1,694 @@
+package bwc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	replicationJournalFile = "replication.journal"
+	replicationStateFile   = "replication.state"
+)
+
+// errNoReplicatedRecord is returned for a recording whose record has not
+// been replicated
+var errNoReplicatedRecord = &BWCError{Code: CodeNotFound, Message: "evidence not found"}
+
+// replicationBatchSize bounds the journal entries shipped in one request
+const replicationBatchSize = 100
+
+// maxReplicationBody bounds a batch a standby accepts; replicationBatchSize
+// entries, each at most a full evidence record, fit well within it
+const maxReplicationBody = 64 << 20
+
+// ReplicationConfig makes this system the primary or the standby of a pair
+// of sites. A primary streams every change and recording to the standby at
+// StandbyURL over its HTTPS API, authenticating with StandbyToken; the
+// standby accepts them only from the API user PrimaryUser.
+type ReplicationConfig struct {
+	Role            string `json:"role,omitempty"`
+	StandbyURL      string `json:"standby_url,omitempty"`
+	StandbyToken    string `json:"standby_token,omitempty"`
+	PrimaryUser     string `json:"primary_user,omitempty"`
+	IntervalSeconds int    `json:"interval_seconds,omitempty"`
+}
+
+func (c *Config) validateReplication(problems []string) []string {
+	r := c.Replication
+	switch r.Role {
+	case "":
+	case "primary":
+		if !isHTTPURL(r.StandbyURL) {
+			problems = append(problems, "replication.standby_url must be an absolute http or https URL")
+		}
+		if r.StandbyToken == "" {
+			problems = append(problems, "replication.standby_token is required for a primary")
+		}
+	case "standby":
+		if r.PrimaryUser == "" {
+			problems = append(problems, "replication.primary_user is required for a standby")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("replication.role %q is not one of primary, standby", r.Role))
+	}
+	if r.IntervalSeconds < 0 {
+		problems = append(problems, "replication.interval_seconds must not be negative")
+	}
+	return problems
+}
+
+// replicationEntry is one line of the replication journal: a record as a
+// change left it, or an audit entry
+type replicationEntry struct {
+	Seq        int64           `json:"seq"`
+	Timestamp  time.Time       `json:"timestamp"`
+	EvidenceID string          `json:"evidence_id,omitempty"`
+	Record     json.RawMessage `json:"record,omitempty"`
+	Audit      *AuditLog       `json:"audit,omitempty"`
+}
+
+// replicationHeader is the first line of the journal. Epoch names the
+// journal, so a standby that applied another journal's entries is sent
+// every record again; Base is the sequence number entries follow on from.
+type replicationHeader struct {
+	Epoch string `json:"epoch"`
+	Base  int64  `json:"base"`
+}
+
+// ReplicationBatch is what a primary sends a standby: journal entries in
+// order, or with Resync every record the primary holds
+type ReplicationBatch struct {
+	Epoch   string             `json:"epoch"`
+	Resync  bool               `json:"resync,omitempty"`
+	Entries []replicationEntry `json:"entries"`
+}
+
+// ReplicationStatus describes a system's part in site replication. A primary
+// reports its journal and how far the standby has acknowledged it; a standby
+// reports the journal it follows, how far it has applied it and the
+// recordings it is still waiting for.
+type ReplicationStatus struct {
+	Role         string    `json:"role"`
+	Epoch        string    `json:"epoch,omitempty"`
+	JournalSeq   int64     `json:"journal_seq,omitempty"`
+	AppliedSeq   int64     `json:"applied_seq"`
+	Pending      int       `json:"pending,omitempty"`
+	NeedFiles    []string  `json:"need_files,omitempty"`
+	LastShipped  time.Time `json:"last_shipped,omitempty"`
+	LastApplied  time.Time `json:"last_applied,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	StandbyURL   string    `json:"standby_url,omitempty"`
+	ResyncNeeded bool      `json:"resync_needed,omitempty"`
+}
+
+// replicationJournal is the primary's append-only file of changes not yet
+// acknowledged by the standby. Unlike the write-ahead log it is not synced
+// per entry: a change lost in a crash is caught up by a full resync, which a
+// damaged journal forces.
+type replicationJournal struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	header  replicationHeader
+	entries []replicationEntry
+	seq     int64
+	acked   int64
+	// resync is set when a change could not be journaled
+	resync bool
+	// notify wakes the shipper when an entry is added
+	notify chan struct{}
+
+	lastShipped time.Time
+	lastError   string
+}
+
+// openReplicationJournal loads the journal at path, or starts a new one
+func openReplicationJournal(path string) (*replicationJournal, error) {
+	j := &replicationJournal{path: path, notify: make(chan struct{}, 1)}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read the replication journal: %w", err)
+	}
+	reader := bufio.NewReader(bytes.NewReader(data))
+	if line, err := reader.ReadBytes('\n'); err == nil && json.Unmarshal(line, &j.header) == nil && j.header.Epoch != "" {
+		j.seq, j.acked = j.header.Base, j.header.Base
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				// A line without its newline was being written when the
+				// process died
+				break
+			}
+			var entry replicationEntry
+			if err := json.Unmarshal(line, &entry); err != nil || entry.Seq != j.seq+1 {
+				j.resync = true
+				break
+			}
+			j.entries = append(j.entries, entry)
+			j.seq = entry.Seq
+		}
+	} else {
+		epoch := make([]byte, 8)
+		if _, err := rand.Read(epoch); err != nil {
+			return nil, err
+		}
+		j.header = replicationHeader{Epoch: hex.EncodeToString(epoch)}
+	}
+	if err := j.rewriteLocked(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// rewriteLocked replaces the journal file with the header and the entries
+// still held, and reopens it for appending. The caller must hold j.mu.
+func (j *replicationJournal) rewriteLocked() error {
+	if len(j.entries) > 0 {
+		j.header.Base = j.entries[0].Seq - 1
+	} else {
+		j.header.Base = j.seq
+	}
+	var buf bytes.Buffer
+	for _, v := range append([]interface{}{j.header}, entriesAsValues(j.entries)...) {
+		line, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(append(line, '\n'))
+	}
+	if err := replaceFile(j.path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write the replication journal: %w", err)
+	}
+	if j.file != nil {
+		j.file.Close()
+	}
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the replication journal: %w", err)
+	}
+	j.file = file
+	return nil
+}
+
+func entriesAsValues(entries []replicationEntry) []interface{} {
+	values := make([]interface{}, len(entries))
+	for i := range entries {
+		values[i] = entries[i]
+	}
+	return values
+}
+
+// add journals one change. A change that cannot be written calls for a full
+// resync instead.
+func (j *replicationJournal) add(entry replicationEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry.Seq, entry.Timestamp = j.seq+1, time.Now()
+	line, err := json.Marshal(entry)
+	if err == nil {
+		_, err = j.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		j.resync = true
+		j.lastError = fmt.Sprintf("failed to journal a change: %v", err)
+	} else {
+		j.entries = append(j.entries, entry)
+	}
+	// The sequence advances either way, so the standby cannot mistake a
+	// later entry for the one that was lost
+	j.seq = entry.Seq
+	select {
+	case j.notify <- struct{}{}:
+	default:
+	}
+}
+
+// acknowledge drops the entries the standby has applied, rewriting the
+// journal once enough have been dropped
+func (j *replicationJournal) acknowledge(seq int64) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.acked = seq
+	n := sort.Search(len(j.entries), func(i int) bool { return j.entries[i].Seq > seq })
+	if n == 0 {
+		return nil
+	}
+	j.entries = append([]replicationEntry(nil), j.entries[n:]...)
+	if n < replicationBatchSize && len(j.entries) > 0 {
+		return nil
+	}
+	return j.rewriteLocked()
+}
+
+// since returns up to limit entries after seq, or false when the journal no
+// longer holds them all and the standby must be resynced
+func (j *replicationJournal) since(seq int64, limit int) ([]replicationEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	first := j.seq + 1
+	if len(j.entries) > 0 {
+		first = j.entries[0].Seq
+	}
+	if j.resync || seq+1 < first || seq > j.seq {
+		return nil, false
+	}
+	n := sort.Search(len(j.entries), func(i int) bool { return j.entries[i].Seq > seq })
+	end := n + limit
+	if end > len(j.entries) {
+		end = len(j.entries)
+	}
+	return append([]replicationEntry(nil), j.entries[n:end]...), true
+}
+
+// journalRecordLocked journals a changed record for the standby. The caller
+// must hold bwc.mu.
+func (bwc *BWCSystem) journalRecordLocked(evidence *Evidence) {
+	if bwc.replication == nil {
+		return
+	}
+	record, err := json.Marshal(evidence)
+	if err != nil {
+		bwc.replication.mu.Lock()
+		bwc.replication.resync = true
+		bwc.replication.mu.Unlock()
+		return
+	}
+	bwc.replication.add(replicationEntry{EvidenceID: evidence.ID, Record: record})
+}
+
+// journalAudit journals an audit entry for the standby
+func (bwc *BWCSystem) journalAudit(log AuditLog) {
+	if bwc.replication != nil {
+		bwc.replication.add(replicationEntry{EvidenceID: log.EvidenceID, Audit: &log})
+	}
+}
+
+// replicationClient carries batches and recordings to the standby
+var replicationClient = &http.Client{Timeout: 30 * time.Minute}
+
+// ShipReplication sends the standby everything it has not yet applied: the
+// journal from where it left off, or every record when it follows another
+// journal, has fallen behind what the journal holds, or a change could not be
+// journaled. Recordings the standby is missing are uploaded after each batch.
+// It returns the number of entries the standby applied.
+func (bwc *BWCSystem) ShipReplication() (int, error) {
+	j := bwc.replication
+	if j == nil {
+		return 0, errors.New("this system is not a replication primary")
+	}
+	shipped, err := bwc.shipReplication(j)
+	j.mu.Lock()
+	if err != nil {
+		j.lastError = err.Error()
+	} else {
+		j.lastError, j.lastShipped = "", time.Now()
+	}
+	j.mu.Unlock()
+	return shipped, err
+}
+
+func (bwc *BWCSystem) shipReplication(j *replicationJournal) (int, error) {
+	var status ReplicationStatus
+	if err := bwc.replicationRequest(http.MethodGet, "/api/replication", nil, &status); err != nil {
+		return 0, err
+	}
+	shipped := 0
+	var err error
+	for {
+		j.mu.Lock()
+		epoch := j.header.Epoch
+		j.mu.Unlock()
+
+		batch := &ReplicationBatch{Epoch: epoch}
+		entries, ok := j.since(status.AppliedSeq, replicationBatchSize)
+		if status.Epoch != epoch || !ok {
+			batch.Resync = true
+			if batch.Entries, err = bwc.resyncEntries(j); err != nil {
+				return shipped, err
+			}
+		} else {
+			batch.Entries = entries
+		}
+		if len(batch.Entries) == 0 && len(status.NeedFiles) == 0 {
+			return shipped, j.acknowledge(status.AppliedSeq)
+		}
+
+		if len(batch.Entries) > 0 {
+			applied := status.AppliedSeq
+			if err = bwc.replicationRequest(http.MethodPost, "/api/replication/changes", batch, &status); err != nil {
+				return shipped, err
+			}
+			if !batch.Resync {
+				shipped += int(status.AppliedSeq - applied)
+			}
+		}
+		if err := bwc.shipReplicaFiles(status.NeedFiles); err != nil {
+			return shipped, err
+		}
+		status.NeedFiles = nil
+		if err := j.acknowledge(status.AppliedSeq); err != nil {
+			return shipped, err
+		}
+	}
+}
+
+// resyncEntries returns every record held, stamped with the journal's current
+// sequence number, and empties the journal, which they supersede
+func (bwc *BWCSystem) resyncEntries(j *replicationJournal) ([]replicationEntry, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	records := bwc.evidenceDB.Search(nil)
+	sort.Slice(records, func(a, b int) bool { return records[a].ID < records[b].ID })
+	entries := make([]replicationEntry, 0, len(records)+1)
+	for _, ev := range records {
+		record, err := json.Marshal(ev)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, replicationEntry{Seq: j.seq, Timestamp: time.Now(), EvidenceID: ev.ID, Record: record})
+	}
+	if len(entries) == 0 {
+		// An empty resync still tells the standby which journal to follow
+		entries = append(entries, replicationEntry{Seq: j.seq, Timestamp: time.Now()})
+	}
+	j.entries, j.resync = nil, false
+	return entries, j.rewriteLocked()
+}
+
+// shipReplicaFiles uploads the recordings of the records with ids
+func (bwc *BWCSystem) shipReplicaFiles(ids []string) error {
+	for _, id := range ids {
+		bwc.mu.RLock()
+		evidence := bwc.evidenceDB.Get(id)
+		var snapshot Evidence
+		if evidence != nil {
+			snapshot = *evidence
+		}
+		bwc.mu.RUnlock()
+		if evidence == nil {
+			continue
+		}
+		if err := bwc.shipReplicaFile(&snapshot); err != nil {
+			return fmt.Errorf("failed to send the recording of %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// shipReplicaFile sends the recording of evidence as it is stored, so one
+// encrypted at rest is sent encrypted
+func (bwc *BWCSystem) shipReplicaFile(evidence *Evidence) error {
+	src, err := bwc.openStoredFile(evidence)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	path := "/api/replication/files/" + url.PathEscape(evidence.ID) + "?sha256=" + storedHash(evidence)
+	return bwc.replicationRequest(http.MethodPut, path, src, nil)
+}
+
+// replicationRequest sends a request to the standby. A body that is not an
+// io.Reader is sent as JSON; a JSON response is decoded into out.
+func (bwc *BWCSystem) replicationRequest(method, path string, body interface{}, out interface{}) error {
+	cfg := bwc.config.Replication
+	var reader io.Reader
+	contentType := "application/octet-stream"
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(cfg.StandbyURL, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+cfg.StandbyToken)
+	if reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := replicationClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("standby unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("standby returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// replicaState is the standby's record of the journal it follows
+type replicaState struct {
+	Epoch       string    `json:"epoch"`
+	AppliedSeq  int64     `json:"applied_seq"`
+	NeedFiles   []string  `json:"need_files,omitempty"`
+	LastApplied time.Time `json:"last_applied,omitempty"`
+}
+
+// loadReplicaStateLocked reads the standby's state. The caller must hold bwc.mu.
+func (bwc *BWCSystem) loadReplicaStateLocked() (*replicaState, error) {
+	var state replicaState
+	data, err := os.ReadFile(filepath.Join(bwc.storagePath, replicationStateFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return &state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("%s: %w", replicationStateFile, err)
+	}
+	return &state, nil
+}
+
+func (bwc *BWCSystem) saveReplicaStateLocked(state *replicaState) error {
+	return writeSnapshot(filepath.Join(bwc.storagePath, replicationStateFile), state)
+}
+
+// ApplyReplication applies a batch from the primary on a standby. Entries it
+// has already applied are skipped, so a batch resent after an outage is
+// harmless. Records are stored as the primary left them, pointing at this
+// system's copy of the recording; a record whose recording this system does
+// not hold yet is listed in NeedFiles until ReceiveReplicaFile stores it.
+func (bwc *BWCSystem) ApplyReplication(batch *ReplicationBatch) (*ReplicationStatus, error) {
+	if bwc.config.Replication.Role != "standby" {
+		return nil, errors.New("this system is not a replication standby")
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	state, err := bwc.loadReplicaStateLocked()
+	if err != nil {
+		return nil, err
+	}
+	if batch.Resync {
+		state.Epoch, state.AppliedSeq = batch.Epoch, 0
+		bwc.logAudit("SYSTEM", "REPLICATION_RESYNC", "", fmt.Sprintf("Primary journal %s sent every record again", batch.Epoch), "")
+	} else if batch.Epoch != state.Epoch {
+		return nil, fmt.Errorf("the batch is from journal %s, but this standby follows %q", batch.Epoch, state.Epoch)
+	}
+
+	need := make(map[string]bool)
+	for _, id := range state.NeedFiles {
+		need[id] = true
+	}
+	applyErr := func() error {
+		for _, entry := range batch.Entries {
+			if !batch.Resync && entry.Seq <= state.AppliedSeq {
+				continue
+			}
+			if !batch.Resync && entry.Seq != state.AppliedSeq+1 {
+				return fmt.Errorf("entry %d does not follow %d", entry.Seq, state.AppliedSeq)
+			}
+			switch {
+			case entry.Audit != nil:
+				if err := bwc.appendAuditEntries([]AuditLog{*entry.Audit}); err != nil {
+					return err
+				}
+			case entry.Record != nil:
+				missing, err := bwc.applyReplicaRecordLocked(entry.Record)
+				if err != nil {
+					return fmt.Errorf("entry %d: %w", entry.Seq, err)
+				}
+				if missing != "" {
+					need[missing] = true
+				}
+			}
+			state.AppliedSeq = entry.Seq
+		}
+		return nil
+	}()
+
+	state.NeedFiles = state.NeedFiles[:0]
+	for id := range need {
+		state.NeedFiles = append(state.NeedFiles, id)
+	}
+	sort.Strings(state.NeedFiles)
+	state.LastApplied = time.Now()
+	if err := bwc.saveReplicaStateLocked(state); err != nil {
+		return nil, err
+	}
+	if applyErr != nil {
+		return nil, applyErr
+	}
+	return &ReplicationStatus{Role: "standby", Epoch: state.Epoch, AppliedSeq: state.AppliedSeq, NeedFiles: state.NeedFiles, LastApplied: state.LastApplied}, nil
+}
+
+// applyReplicaRecordLocked stores a record from the primary unless this
+// system holds a later revision. It returns the record's ID when the
+// recording it describes is not held here yet. The caller must hold bwc.mu.
+func (bwc *BWCSystem) applyReplicaRecordLocked(data json.RawMessage) (string, error) {
+	var evidence Evidence
+	if err := json.Unmarshal(data, &evidence); err != nil {
+		return "", err
+	}
+	if err := ValidateEvidenceID(evidence.ID); err != nil {
+		return "", err
+	}
+	current := bwc.evidenceDB.Get(evidence.ID)
+	if current != nil && current.Revision > evidence.Revision {
+		return "", nil
+	}
+
+	missing := ""
+	if current != nil && current.FileHash == evidence.FileHash && current.BlobKey != "" {
+		evidence.BlobKey, evidence.FilePath = current.BlobKey, current.FilePath
+	} else {
+		// The recording follows; until it does the record points at where
+		// it will be kept
+		if evidence.BlobKey == "" {
+			evidence.BlobKey = evidence.ID + filepath.Ext(evidence.FilePath)
+		}
+		evidence.FilePath = ""
+		if local, ok := bwc.blobs.(localBlobStore); ok {
+			evidence.FilePath = local.Path(evidence.BlobKey)
+		}
+		missing = evidence.ID
+	}
+	return missing, bwc.saveLocked(&evidence)
+}
+
+// ReceiveReplicaFile stores the recording of a replicated record on a
+// standby, refusing one that does not match the record's hash
+func (bwc *BWCSystem) ReceiveReplicaFile(evidenceID string, src io.Reader) error {
+	if bwc.config.Replication.Role != "standby" {
+		return errors.New("this system is not a replication standby")
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return errNoReplicatedRecord
+	}
+	state, err := bwc.loadReplicaStateLocked()
+	if err != nil {
+		return err
+	}
+	if err := bwc.storeReceivedFileLocked(src, evidence.BlobKey, evidence); err != nil {
+		bwc.logAudit("SYSTEM", "REPLICATION_REJECTED", evidenceID, err.Error(), "")
+		return err
+	}
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+	for i, id := range state.NeedFiles {
+		if id == evidenceID {
+			state.NeedFiles = append(state.NeedFiles[:i], state.NeedFiles[i+1:]...)
+			break
+		}
+	}
+	return bwc.saveReplicaStateLocked(state)
+}
+
+// ReplicationStatus reports this system's part in site replication
+func (bwc *BWCSystem) ReplicationStatus() (*ReplicationStatus, error) {
+	switch bwc.config.Replication.Role {
+	case "primary":
+		j := bwc.replication
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		return &ReplicationStatus{
+			Role: "primary", Epoch: j.header.Epoch, JournalSeq: j.seq, AppliedSeq: j.acked,
+			Pending: len(j.entries), LastShipped: j.lastShipped, LastError: j.lastError,
+			StandbyURL: bwc.config.Replication.StandbyURL, ResyncNeeded: j.resync,
+		}, nil
+	case "standby":
+		bwc.mu.RLock()
+		defer bwc.mu.RUnlock()
+		state, err := bwc.loadReplicaStateLocked()
+		if err != nil {
+			return nil, err
+		}
+		return &ReplicationStatus{Role: "standby", Epoch: state.Epoch, AppliedSeq: state.AppliedSeq, NeedFiles: state.NeedFiles, LastApplied: state.LastApplied}, nil
+	}
+	return &ReplicationStatus{Role: "none"}, nil
+}
+
+// replicationShipper ships journaled changes to the standby as they are made,
+// retrying on an interval while the standby is unreachable
+type replicationShipper struct {
+	system   *BWCSystem
+	interval time.Duration
+}
+
+func newReplicationShipper(system *BWCSystem) *replicationShipper {
+	interval := time.Duration(system.config.Replication.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &replicationShipper{system: system, interval: interval}
+}
+
+// Run ships changes until stop is closed, reporting only when the standby
+// becomes unreachable or catches up again
+func (s *replicationShipper) Run(stop <-chan struct{}, logf func(format string, args ...interface{})) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		_, err := s.system.ShipReplication()
+		switch {
+		case err != nil && !failing:
+			logf("Replication to the standby failed, retrying: %v\n", err)
+		case err == nil && failing:
+			logf("Replication to the standby caught up\n")
+		}
+		failing = err != nil
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-s.system.replication.notify:
+		}
+	}
+}
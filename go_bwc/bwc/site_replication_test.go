@@ -0,0 +1,182 @@
+package bwc
+
+import (
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupReplicationPair returns a primary replicating to a standby served over
+// the API, which accepts the primary as CUS-001
+func setupReplicationPair(t *testing.T) (primary, standby *BWCSystem, tmpDir string, cleanup func()) {
+	standby, server, _, cleanupStandby := setupTestServer(t)
+	standby.config.Replication = ReplicationConfig{Role: "standby", PrimaryUser: "CUS-001"}
+
+	primary, tmpDir, cleanupPrimary := setupTestSystem(t)
+	primary.config.Replication = ReplicationConfig{Role: "primary", StandbyURL: server.URL, StandbyToken: testAPIToken}
+	journal, err := openReplicationJournal(filepath.Join(tmpDir, replicationJournalFile))
+	if err != nil {
+		t.Fatalf("openReplicationJournal failed: %v", err)
+	}
+	primary.replication = journal
+
+	return primary, standby, tmpDir, func() {
+		cleanupPrimary()
+		cleanupStandby()
+	}
+}
+
+func TestReplicationStreamsChangesAndRecordings(t *testing.T) {
+	primary, standby, tmpDir, cleanup := setupReplicationPair(t)
+	defer cleanup()
+
+	ev, err := primary.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-001", "OFF-1170", "Officer Test", "Test Location", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	// The standby has never followed this journal, so it is sent every record
+	if _, err := primary.ShipReplication(); err != nil {
+		t.Fatalf("ShipReplication failed: %v", err)
+	}
+	replica, err := standby.GetEvidence(ev.ID)
+	if err != nil || replica.FileHash != ev.FileHash {
+		t.Fatalf("expected the record on the standby, got %+v, %v", replica, err)
+	}
+	if !strings.HasPrefix(replica.FilePath, standby.storagePath) {
+		t.Errorf("expected the recording in the standby's storage, got %s", replica.FilePath)
+	}
+	if valid, err := standby.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Fatalf("expected the replicated recording to verify, got %v, %v", valid, err)
+	}
+
+	if err := primary.TransferCustody(ev.ID, "OFF-1170", "DET-1", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	shipped, err := primary.ShipReplication()
+	if err != nil || shipped == 0 {
+		t.Fatalf("expected the transfer shipped, got %d, %v", shipped, err)
+	}
+	replica, _ = standby.GetEvidence(ev.ID)
+	if len(replica.ChainOfCustody) != len(primary.evidenceDB.Get(ev.ID).ChainOfCustody) {
+		t.Errorf("expected the custody chain replicated, got %+v", replica.ChainOfCustody)
+	}
+	found := false
+	for _, log := range standby.GetAuditLogs(ev.ID, "") {
+		found = found || log.Action == "TRANSFER_CUSTODY"
+	}
+	if !found {
+		t.Error("expected the primary's audit entries on the standby")
+	}
+
+	status, err := primary.ReplicationStatus()
+	if err != nil || status.Pending != 0 || status.LastError != "" {
+		t.Errorf("expected nothing pending, got %+v, %v", status, err)
+	}
+}
+
+func TestReplicationCatchesUpAfterOutage(t *testing.T) {
+	primary, standby, tmpDir, cleanup := setupReplicationPair(t)
+	defer cleanup()
+
+	ev, _ := primary.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-002", "OFF-1171", "Officer Test", "Test Location", nil)
+	if _, err := primary.ShipReplication(); err != nil {
+		t.Fatalf("ShipReplication failed: %v", err)
+	}
+
+	standbyURL := primary.config.Replication.StandbyURL
+	primary.config.Replication.StandbyURL = "http://127.0.0.1:1"
+	primary.TransferCustody(ev.ID, "OFF-1171", "DET-2", "Investigation")
+	second, _ := primary.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-003", "OFF-1171", "Officer Test", "Test Location", nil)
+	if _, err := primary.ShipReplication(); err == nil {
+		t.Fatal("expected shipping to an unreachable standby to fail")
+	}
+	status, _ := primary.ReplicationStatus()
+	if status.Pending == 0 || !strings.Contains(status.LastError, "unreachable") {
+		t.Errorf("expected changes pending behind the outage, got %+v", status)
+	}
+
+	// The journal survives a restart of the primary
+	journal, err := openReplicationJournal(filepath.Join(tmpDir, replicationJournalFile))
+	if err != nil || journal.seq != primary.replication.seq || len(journal.entries) != status.Pending {
+		t.Fatalf("expected the journal reloaded with %d pending entries, got %d, %v", status.Pending, len(journal.entries), err)
+	}
+	primary.replication = journal
+
+	primary.config.Replication.StandbyURL = standbyURL
+	if _, err := primary.ShipReplication(); err != nil {
+		t.Fatalf("ShipReplication after the outage failed: %v", err)
+	}
+	if valid, err := standby.VerifyIntegrity(second.ID, "AUDITOR-1"); err != nil || !valid {
+		t.Errorf("expected the recording ingested during the outage to verify, got %v, %v", valid, err)
+	}
+	replica, _ := standby.GetEvidence(ev.ID)
+	if last := replica.ChainOfCustody[len(replica.ChainOfCustody)-1]; last.ToOfficer != "DET-2" {
+		t.Errorf("expected the transfer made during the outage, got %+v", last)
+	}
+	standbyStatus, _ := standby.ReplicationStatus()
+	if primaryStatus, _ := primary.ReplicationStatus(); standbyStatus.AppliedSeq != primaryStatus.JournalSeq || len(standbyStatus.NeedFiles) != 0 {
+		t.Errorf("expected the standby caught up with the journal, got %+v and %+v", standbyStatus, primaryStatus)
+	}
+}
+
+func TestReplicationRejectsForeignWrites(t *testing.T) {
+	primary, standby, tmpDir, cleanup := setupReplicationPair(t)
+	defer cleanup()
+
+	ev, _ := primary.IngestEvidence(createTestFile(t, tmpDir), "CASE-REP-004", "OFF-1172", "Officer Test", "Test Location", nil)
+	entries, err := primary.resyncEntries(primary.replication)
+	if err != nil {
+		t.Fatalf("resyncEntries failed: %v", err)
+	}
+	batch := &ReplicationBatch{Epoch: "journal-1", Resync: true, Entries: entries}
+	status, err := standby.ApplyReplication(batch)
+	if err != nil || len(status.NeedFiles) != 1 {
+		t.Fatalf("expected the record applied awaiting its recording, got %+v, %v", status, err)
+	}
+	if err := standby.ReceiveReplicaFile(ev.ID, strings.NewReader("not the recording")); err == nil || !strings.Contains(err.Error(), "recorded hash") {
+		t.Errorf("expected a recording that does not match its hash to be refused, got %v", err)
+	}
+	if _, err := standby.ApplyReplication(&ReplicationBatch{Epoch: "journal-2", Entries: batch.Entries}); err == nil {
+		t.Error("expected a batch from another journal to be refused")
+	}
+	if _, err := primary.ApplyReplication(batch); err == nil {
+		t.Error("expected a primary to refuse a batch")
+	}
+
+	standby.config.Replication.PrimaryUser = "OTHER"
+	if _, err := primary.ShipReplication(); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected the standby to refuse writes from another user, got %v", err)
+	}
+}
+
+func TestServerReplicationBoundsBatchSize(t *testing.T) {
+	system, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+	system.config.Replication = ReplicationConfig{Role: "standby", PrimaryUser: "CUS-001"}
+
+	// A well-formed batch past the bound is refused before it is decoded
+	body := io.MultiReader(strings.NewReader(`{"epoch":"`), io.LimitReader(repeatReader('a'), maxReplicationBody), strings.NewReader(`"}`))
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/api/replication/changes", body)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an oversized batch to be refused, got %d", resp.StatusCode)
+	}
+}
+
+// repeatReader yields the same byte forever
+type repeatReader byte
+
+func (r repeatReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,236 @@
+package bwc
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// sqlDialect is what differs between the SQL databases evidence can be kept in
+type sqlDialect struct {
+	// name is the database.type that selects the dialect
+	name string
+	// driver is the database/sql driver name; the driver is linked in with
+	// the build tag of the same name as name
+	driver string
+	// placeholder returns the marker of the nth parameter, counting from 1
+	placeholder func(n int) string
+	// shared is set when other systems write the same table, so records are
+	// read from the database rather than from what was loaded
+	shared bool
+}
+
+var sqliteDialect = sqlDialect{name: "sqlite", driver: "sqlite", placeholder: func(int) string { return "?" }}
+
+var postgresDialect = sqlDialect{
+	name:        "postgres",
+	driver:      "pgx",
+	placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	shared:      true,
+}
+
+const sqlSchema = `CREATE TABLE IF NOT EXISTS evidence (
+	id TEXT PRIMARY KEY,
+	case_number TEXT NOT NULL,
+	revision INTEGER NOT NULL,
+	record TEXT NOT NULL
+)`
+
+// sqlStore is an EvidenceStore in a SQL database. Each record is a row of
+// JSON, with the case number and revision as columns so the table can be
+// inspected without decoding it. Records are loaded when the store is opened
+// and written through on Put and Delete.
+//
+// Writes are checked against the revision last read: a row someone else
+// changed in the meantime is not overwritten. Put then reloads the record and
+// returns a *RevisionConflictError, and the operation fails.
+type sqlStore struct {
+	*memoryStore
+	db      *sql.DB
+	dialect sqlDialect
+
+	// revisions are the revisions of the rows as last read or written
+	revisionsMu sync.Mutex
+	revisions   map[string]int64
+}
+
+// openSQLStore connects to dsn, creates the evidence table if needed and loads
+// the records in it
+func openSQLStore(dialect sqlDialect, dsn string) (*sqlStore, error) {
+	db, err := sql.Open(dialect.driver, dsn)
+	if err != nil {
+		if strings.Contains(err.Error(), "unknown driver") {
+			return nil, fmt.Errorf("database.type %s needs a build with -tags %s: %w", dialect.name, dialect.name, err)
+		}
+		return nil, err
+	}
+	s := &sqlStore{memoryStore: newMemoryStore(), db: db, dialect: dialect, revisions: make(map[string]int64)}
+	if _, err := db.Exec(sqlSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create the evidence table: %w", err)
+	}
+	if err := s.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads every row, keeping the records already held that have not
+// changed, and drops records whose rows are gone
+func (s *sqlStore) load() error {
+	rows, err := s.db.Query("SELECT id, revision, record FROM evidence")
+	if err != nil {
+		return fmt.Errorf("failed to read evidence: %w", err)
+	}
+	defer rows.Close()
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id, record string
+		var revision int64
+		if err := rows.Scan(&id, &revision, &record); err != nil {
+			return fmt.Errorf("failed to read evidence: %w", err)
+		}
+		seen[id] = true
+		if err := s.cache(id, revision, record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read evidence: %w", err)
+	}
+	for _, evidence := range s.memoryStore.Search(func(ev *Evidence) bool { return !seen[ev.ID] }) {
+		s.forget(evidence.ID)
+	}
+	return nil
+}
+
+// reload reads the row of id again, dropping the record if the row is gone
+func (s *sqlStore) reload(id string) error {
+	var record string
+	var revision int64
+	err := s.db.QueryRow("SELECT revision, record FROM evidence WHERE id = "+s.dialect.placeholder(1), id).Scan(&revision, &record)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.forget(id)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read evidence %s: %w", id, err)
+	}
+	return s.cache(id, revision, record)
+}
+
+// cache holds the record of a row, decoding it only when its revision is not
+// the one already held
+func (s *sqlStore) cache(id string, revision int64, record string) error {
+	// Every change bumps the revision, so a held record at revision is as
+	// the row has it, unless a failed write changed it in place
+	s.revisionsMu.Lock()
+	held, ok := s.revisions[id]
+	s.revisionsMu.Unlock()
+	if cached := s.memoryStore.Get(id); ok && held == revision && cached != nil && cached.Revision == revision {
+		return nil
+	}
+	var evidence Evidence
+	if err := json.Unmarshal([]byte(record), &evidence); err != nil {
+		return fmt.Errorf("evidence %s: invalid record: %w", id, err)
+	}
+	s.setRevision(id, revision)
+	return s.memoryStore.Put(&evidence)
+}
+
+func (s *sqlStore) forget(id string) {
+	s.revisionsMu.Lock()
+	delete(s.revisions, id)
+	s.revisionsMu.Unlock()
+	s.memoryStore.Delete(id)
+}
+
+func (s *sqlStore) setRevision(id string, revision int64) {
+	s.revisionsMu.Lock()
+	s.revisions[id] = revision
+	s.revisionsMu.Unlock()
+}
+
+func (s *sqlStore) Get(id string) *Evidence {
+	if s.dialect.shared {
+		// An unreachable database leaves the record as last read
+		s.reload(id)
+	}
+	return s.memoryStore.Get(id)
+}
+
+func (s *sqlStore) Search(match func(*Evidence) bool) []*Evidence {
+	if s.dialect.shared {
+		s.load()
+	}
+	return s.memoryStore.Search(match)
+}
+
+func (s *sqlStore) Put(evidence *Evidence) error {
+	record, err := json.Marshal(evidence)
+	if err != nil {
+		return err
+	}
+	s.revisionsMu.Lock()
+	read, exists := s.revisions[evidence.ID]
+	s.revisionsMu.Unlock()
+
+	p := s.dialect.placeholder
+	if exists {
+		err = s.update(evidence, record, read)
+	} else {
+		_, err = s.db.Exec(fmt.Sprintf("INSERT INTO evidence (id, case_number, revision, record) VALUES (%s, %s, %s, %s)",
+			p(1), p(2), p(3), p(4)), evidence.ID, evidence.CaseNumber, evidence.Revision, string(record))
+	}
+	var conflict *RevisionConflictError
+	if errors.As(err, &conflict) {
+		return err
+	}
+	if err != nil {
+		// The record was changed in place; put back what the database holds
+		s.reload(evidence.ID)
+		return fmt.Errorf("failed to write evidence %s: %w", evidence.ID, err)
+	}
+	s.setRevision(evidence.ID, evidence.Revision)
+	return s.memoryStore.Put(evidence)
+}
+
+// update replaces the row of evidence if it is still at revision read
+func (s *sqlStore) update(evidence *Evidence, record []byte, read int64) error {
+	p := s.dialect.placeholder
+	result, err := s.db.Exec(fmt.Sprintf("UPDATE evidence SET case_number = %s, revision = %s, record = %s WHERE id = %s AND revision = %s",
+		p(1), p(2), p(3), p(4), p(5)), evidence.CaseNumber, evidence.Revision, string(record), evidence.ID, read)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	return s.conflict(evidence.ID, read)
+}
+
+// conflict reloads a record another system changed since it was read at
+// revision, and reports it
+func (s *sqlStore) conflict(id string, revision int64) error {
+	if err := s.reload(id); err != nil {
+		return err
+	}
+	current := int64(0)
+	if evidence := s.memoryStore.Get(id); evidence != nil {
+		current = evidence.Revision
+	}
+	return &RevisionConflictError{EvidenceID: id, Expected: revision, Current: current}
+}
+
+func (s *sqlStore) Delete(id string) error {
+	if _, err := s.db.Exec("DELETE FROM evidence WHERE id = "+s.dialect.placeholder(1), id); err != nil {
+		return fmt.Errorf("failed to delete evidence %s: %w", id, err)
+	}
+	s.forget(id)
+	return nil
+}
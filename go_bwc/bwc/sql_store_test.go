@@ -0,0 +1,277 @@
+package bwc
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSQL is a database/sql driver that understands the statements sqlStore
+// issues. Databases are kept by DSN, so reopening one sees what was written.
+type fakeSQL struct {
+	mu        sync.Mutex
+	databases map[string]map[string]fakeSQLRow
+}
+
+type fakeSQLRow struct {
+	revision int64
+	record   string
+}
+
+var fakeSQLDriver = &fakeSQL{databases: make(map[string]map[string]fakeSQLRow)}
+
+var fakeSQLDialect = sqlDialect{name: "fakesql", driver: "fakesql", placeholder: func(int) string { return "?" }}
+
+func init() {
+	sql.Register("fakesql", fakeSQLDriver)
+}
+
+func (d *fakeSQL) Open(dsn string) (driver.Conn, error) {
+	return &fakeSQLConn{driver: d, dsn: dsn}, nil
+}
+
+type fakeSQLConn struct {
+	driver *fakeSQL
+	dsn    string
+}
+
+func (c *fakeSQLConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeSQLStmt{conn: c, query: query}, nil
+}
+func (c *fakeSQLConn) Close() error { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeSQLStmt struct {
+	conn  *fakeSQLConn
+	query string
+}
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return -1 }
+
+func (s *fakeSQLStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.conn.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	table := d.databases[s.conn.dsn]
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		if table == nil {
+			d.databases[s.conn.dsn] = make(map[string]fakeSQLRow)
+		}
+	case strings.HasPrefix(s.query, "INSERT INTO evidence"):
+		id := args[0].(string)
+		if _, exists := table[id]; exists {
+			return nil, errors.New("duplicate key value violates unique constraint")
+		}
+		table[id] = fakeSQLRow{revision: args[2].(int64), record: args[3].(string)}
+	case strings.HasPrefix(s.query, "UPDATE evidence"):
+		id := args[3].(string)
+		if row, exists := table[id]; !exists || row.revision != args[4].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		table[id] = fakeSQLRow{revision: args[1].(int64), record: args[2].(string)}
+	case strings.HasPrefix(s.query, "DELETE FROM evidence"):
+		delete(table, args[0].(string))
+	default:
+		return nil, errors.New("unexpected statement: " + s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeSQLStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.conn.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	table := d.databases[s.conn.dsn]
+	switch {
+	case strings.HasPrefix(s.query, "SELECT id, revision, record FROM evidence"):
+		rows := &fakeSQLRows{columns: []string{"id", "revision", "record"}}
+		for id, row := range table {
+			rows.rows = append(rows.rows, []driver.Value{id, row.revision, row.record})
+		}
+		sort.Slice(rows.rows, func(i, j int) bool { return rows.rows[i][0].(string) < rows.rows[j][0].(string) })
+		return rows, nil
+	case strings.HasPrefix(s.query, "SELECT revision, record FROM evidence WHERE id"):
+		rows := &fakeSQLRows{columns: []string{"revision", "record"}}
+		if row, exists := table[args[0].(string)]; exists {
+			rows.rows = append(rows.rows, []driver.Value{row.revision, row.record})
+		}
+		return rows, nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+type fakeSQLRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQLStoreSurvivesRestart(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	store, err := openSQLStore(fakeSQLDialect, tmpDir)
+	if err != nil {
+		t.Fatalf("openSQLStore failed: %v", err)
+	}
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-SQL-1", "OFF-1141", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1141", "DET-004", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	if _, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil {
+		t.Fatalf("VerifyIntegrity failed: %v", err)
+	}
+
+	restarted, err := NewBWCSystem(tmpDir)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	reopened, err := openSQLStore(fakeSQLDialect, tmpDir)
+	if err != nil {
+		t.Fatalf("openSQLStore failed: %v", err)
+	}
+	if err := restarted.SetEvidenceStore(reopened); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+	got, err := restarted.GetEvidence(ev.ID)
+	if err != nil {
+		t.Fatalf("expected the record to survive a restart: %v", err)
+	}
+	if got.FileHash != ev.FileHash || len(got.ChainOfCustody) != 2 || len(got.IntegrityChecks) != 2 {
+		t.Errorf("expected hash, custody and integrity checks to survive, got %+v", got)
+	}
+	if _, err := restarted.VerifyIntegrity(ev.ID, "AUDITOR-2"); err != nil {
+		t.Errorf("expected the reloaded record to verify, got %v", err)
+	}
+
+	if err := reopened.Delete(ev.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	again, err := openSQLStore(fakeSQLDialect, tmpDir)
+	if err != nil {
+		t.Fatalf("openSQLStore failed: %v", err)
+	}
+	if again.Get(ev.ID) != nil {
+		t.Error("expected the deleted record to stay deleted")
+	}
+}
+
+func TestSQLiteNeedsBuildTag(t *testing.T) {
+	for _, name := range sql.Drivers() {
+		if name == "sqlite" {
+			t.Skip("built with the sqlite driver")
+		}
+	}
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Database.Type = "sqlite"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected database.type sqlite to be accepted: %v", err)
+	}
+	_, err := NewBWCSystemFromConfig(cfg)
+	if err == nil || !strings.Contains(err.Error(), "-tags sqlite") {
+		t.Errorf("expected a hint to build with -tags sqlite, got %v", err)
+	}
+
+	cfg.Database.Type = "oracle"
+	if _, err := openEvidenceStore(cfg); err == nil {
+		t.Error("expected an unsupported database type to be rejected")
+	}
+}
+
+func TestSharedSQLStoreRefusesStaleWrites(t *testing.T) {
+	first, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	second, err := NewBWCSystem(tmpDir)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+
+	shared := fakeSQLDialect
+	shared.shared = true
+	for _, system := range []*BWCSystem{first, second} {
+		store, err := openSQLStore(shared, "shared-"+tmpDir)
+		if err != nil {
+			t.Fatalf("openSQLStore failed: %v", err)
+		}
+		if err := system.SetEvidenceStore(store); err != nil {
+			t.Fatalf("SetEvidenceStore failed: %v", err)
+		}
+	}
+
+	ev, err := first.IngestEvidence(createTestFile(t, tmpDir), "CASE-SQL-2", "OFF-1144", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := second.TransferCustody(ev.ID, "OFF-1144", "DET-006", "Investigation"); err != nil {
+		t.Fatalf("expected the second workstation to see the new record: %v", err)
+	}
+	if err := first.UpdateStatus(ev.ID, "OFF-1144", StatusProcessing, "Review"); err != nil {
+		t.Fatalf("expected the first workstation to read the transfer first: %v", err)
+	}
+	got, err := second.GetEvidence(ev.ID)
+	if err != nil || got.Status != StatusProcessing || len(got.ChainOfCustody) != 2 {
+		t.Fatalf("expected both changes to be kept, got %+v, %v", got, err)
+	}
+
+	// A write racing one from elsewhere is refused rather than clobbering it
+	store := first.evidenceDB.(*sqlStore)
+	stale := copyEvidence(store.memoryStore.Get(ev.ID))
+	read := stale.Revision
+	if err := second.UpdateStatus(ev.ID, "DET-006", StatusAnalyzed, "Done"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	markModified(&stale, stale.LastModified)
+	err = store.Put(&stale)
+	var conflict *RevisionConflictError
+	if !errors.As(err, &conflict) || conflict.Expected != read || conflict.Current != read+1 {
+		t.Fatalf("expected a revision conflict, got %v", err)
+	}
+	if held := store.memoryStore.Get(ev.ID); held.Status != StatusAnalyzed {
+		t.Errorf("expected the newer record to be reloaded, got %s", held.Status)
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	db := DatabaseConfig{Host: "db", Port: 5432, Name: "bwc", User: "bwc_admin", Password: "it's", SSLMode: "require", ConnectionTimeoutSeconds: 30}
+	want := `host='db' port='5432' dbname='bwc' user='bwc_admin' password='it\'s' sslmode='require' connect_timeout='30'`
+	if got := postgresDSN(db); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	db.DSN = "postgres://bwc@db/bwc"
+	if got := postgresDSN(db); got != db.DSN {
+		t.Errorf("expected database.dsn as it is, got %s", got)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Database.Type = "postgres"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected postgres without a host or DSN to be refused")
+	}
+}
@@ -0,0 +1,7 @@
+//go:build sqlite
+
+package bwc
+
+// Builds with -tags sqlite link in a pure-Go SQLite driver for database.type
+// sqlite
+import _ "modernc.org/sqlite"
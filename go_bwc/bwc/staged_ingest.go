@@ -0,0 +1,370 @@
+package bwc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stagingCheckpointSize is how much of a file is copied between journal
+// checkpoints. An interrupted copy resumes from its last checkpoint.
+const stagingCheckpointSize = 64 << 20
+
+// errStagedFileChanged is returned when a staged copy does not hash to the
+// value taken when the ingest began; the copy cannot be resumed
+var errStagedFileChanged = errors.New("file changed while it was being ingested")
+
+// errNoInterruptedIngest is returned for an evidence ID with no interrupted ingest
+var errNoInterruptedIngest = errors.New("no interrupted ingest for evidence")
+
+// StagedIngest is the journal of an ingest whose file is being copied into
+// storage. It holds everything needed to finish the ingest after a restart.
+type StagedIngest struct {
+	EvidenceID    string         `json:"evidence_id"`
+	SourcePath    string         `json:"source_path"`
+	CaseNumber    string         `json:"case_number"`
+	OfficerID     string         `json:"officer_id"`
+	OfficerName   string         `json:"officer_name,omitempty"`
+	Location      string         `json:"location,omitempty"`
+	Tags          []string       `json:"tags,omitempty"`
+	IncidentTime  time.Time      `json:"incident_time,omitempty"`
+	FileHash      string         `json:"file_hash"`
+	FileSize      int64          `json:"file_size"`
+	ChunkManifest *ChunkManifest `json:"chunk_manifest,omitempty"`
+	// Digests are the file's digests besides FileHash
+	Digests   map[HashAlgorithm]string `json:"digests,omitempty"`
+	StartedAt time.Time                `json:"started_at"`
+	// Encryption is set when the copy is encrypted at rest; the journal
+	// holds its data key only wrapped
+	Encryption *AtRestEncryption `json:"encryption,omitempty"`
+	// Authentication is who started the ingest, when they were authenticated
+	Authentication *Authentication `json:"authentication,omitempty"`
+
+	// Offset is how many bytes have been copied and synced to disk, and
+	// PrefixHash is their SHA-256. Anything past Offset is discarded on
+	// resume. Both count the recording, not what it is encrypted to.
+	Offset     int64     `json:"offset"`
+	PrefixHash string    `json:"prefix_hash,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// stagingDir holds partial copies and their journals
+func (bwc *BWCSystem) stagingDir() string {
+	return filepath.Join(bwc.storagePath, "staging")
+}
+
+func (bwc *BWCSystem) stagedPartialPath(stage *StagedIngest) string {
+	return filepath.Join(bwc.stagingDir(), stage.EvidenceID+filepath.Ext(stage.SourcePath)+".partial")
+}
+
+// stageBlobKey is the key the file of stage is stored under
+func stageBlobKey(stage *StagedIngest) string {
+	return stage.EvidenceID + filepath.Ext(stage.SourcePath)
+}
+
+func (bwc *BWCSystem) stagedJournalPath(stage *StagedIngest) string {
+	return filepath.Join(bwc.stagingDir(), stage.EvidenceID+".json")
+}
+
+// writeStageJournal replaces stage's journal, so a crash mid-write leaves the
+// previous checkpoint in place
+func (bwc *BWCSystem) writeStageJournal(stage *StagedIngest) error {
+	data, err := json.MarshalIndent(stage, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal ingest journal: %w", err)
+	}
+	path := bwc.stagedJournalPath(stage)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write ingest journal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write ingest journal: %w", err)
+	}
+	return nil
+}
+
+// removeStaged deletes stage's partial copy and journal
+func (bwc *BWCSystem) removeStaged(stage *StagedIngest) {
+	os.Remove(bwc.stagedPartialPath(stage))
+	os.Remove(bwc.stagedJournalPath(stage))
+}
+
+// stageIngestLocked copies stage's source into the blob store and returns
+// where it landed on local disk, or "" when the store is not local. The copy
+// goes to a partial file in the staging directory, synced and journaled at
+// checkpoints, and is moved into the store once its hash matches. A copy that
+// fails is left staged for ResumeIngest.
+func (bwc *BWCSystem) stageIngestLocked(stage *StagedIngest, tracker *ingestTracker) (string, error) {
+	err := bwc.copyStaged(stage, tracker)
+	if errors.Is(err, errStagedFileChanged) {
+		bwc.removeStaged(stage)
+		delete(bwc.stagedIngests, stage.EvidenceID)
+		return "", err
+	}
+	if err != nil {
+		bwc.stagedIngests[stage.EvidenceID] = stage
+		bwc.logAudit("SYSTEM", "INGEST_INTERRUPTED", stage.EvidenceID,
+			fmt.Sprintf("Copy of %s stopped at byte %d of %d: %v", stage.SourcePath, stage.Offset, stage.FileSize, err), "")
+		return "", fmt.Errorf("failed to copy file to secure storage (resume with ResumeIngest %s): %w", stage.EvidenceID, err)
+	}
+
+	// The file is retained for the period its tags give it from today
+	var retainUntil time.Time
+	if expiry, _, ok := retentionExpiryUnder(bwc.config.RetentionPolicy(), &Evidence{Tags: stage.Tags, CreatedAt: time.Now()}); ok {
+		retainUntil = expiry.UTC().Add(time.Second - 1).Truncate(time.Second)
+	}
+	storedHash := stage.FileHash
+	if stage.Encryption != nil {
+		if storedHash, err = calculateFileHash(bwc.stagedPartialPath(stage)); err != nil {
+			return "", fmt.Errorf("failed to hash encrypted copy: %w", err)
+		}
+		stage.Encryption.StoredSHA256 = storedHash
+	}
+	destPath, err := bwc.putBlobLocked(stageBlobKey(stage), bwc.stagedPartialPath(stage), storedHash, retainUntil)
+	if err != nil {
+		return "", fmt.Errorf("failed to move file into secure storage: %w", err)
+	}
+	os.Remove(bwc.stagedJournalPath(stage))
+	delete(bwc.stagedIngests, stage.EvidenceID)
+	return destPath, nil
+}
+
+// copyStaged copies the source into the partial file from stage.Offset,
+// first re-hashing what is already staged. A staged prefix that no longer
+// matches its journal is copied again from the start.
+func (bwc *BWCSystem) copyStaged(stage *StagedIngest, tracker *ingestTracker) error {
+	if err := os.MkdirAll(bwc.stagingDir(), 0700); err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	// The journal is written before the partial file, so a crash never leaves
+	// a copy that cannot be traced
+	if err := bwc.writeStageJournal(stage); err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(bwc.stagedPartialPath(stage), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	// An encrypted copy is sealed as it is written, so the prefix is opened
+	// to be checked
+	var c *segmentCipher
+	var staged io.Reader = dst
+	if stage.Encryption != nil {
+		if c, err = bwc.atRestCipher(stage.Encryption, stage.EvidenceID, stage.FileSize); err != nil {
+			return err
+		}
+		staged = newSegmentReader(c, dst, false)
+	}
+	h := sha256.New()
+	if stage.Offset > 0 {
+		if _, err := io.CopyN(h, staged, stage.Offset); err != nil || hex.EncodeToString(h.Sum(nil)) != stage.PrefixHash {
+			h.Reset()
+			stage.Offset, stage.PrefixHash = 0, ""
+		}
+	}
+	end := stage.Offset
+	if c != nil {
+		end = sealedSize(stage.Offset, c.segSize)
+		if stage.Offset == 0 {
+			end = 0
+		}
+	}
+	if err := dst.Truncate(end); err != nil {
+		return err
+	}
+	if _, err := dst.Seek(end, io.SeekStart); err != nil {
+		return err
+	}
+
+	src, err := os.Open(stage.SourcePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if _, err := src.Seek(stage.Offset, io.SeekStart); err != nil {
+		return err
+	}
+	tracker.skip(IngestCopying, stage.Offset)
+
+	r := tracker.reader(IngestCopying)(src)
+	var out io.Writer = dst
+	var sealer *segmentWriter
+	if c != nil {
+		sealer = newSegmentWriter(c, dst, stage.Offset)
+		out = sealer
+	}
+	w := io.MultiWriter(out, h)
+	for {
+		n, err := io.CopyN(w, r, stagingCheckpointSize)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		eof := errors.Is(err, io.EOF)
+		if eof && sealer != nil {
+			if err := sealer.Close(); err != nil {
+				return err
+			}
+		}
+		if n > 0 || (eof && sealer != nil) {
+			if err := dst.Sync(); err != nil {
+				return err
+			}
+			stage.Offset += n
+			stage.PrefixHash = hex.EncodeToString(h.Sum(nil))
+			stage.UpdatedAt = time.Now()
+			if err := bwc.writeStageJournal(stage); err != nil {
+				return err
+			}
+		}
+		if eof {
+			break
+		}
+	}
+
+	if stage.Offset != stage.FileSize || hex.EncodeToString(h.Sum(nil)) != stage.FileHash {
+		return errStagedFileChanged
+	}
+	return nil
+}
+
+// loadStagedIngests finds the journals of copies cut short by a crash. Each
+// is audited and listed by InterruptedIngests until it is resumed or
+// discarded.
+func (bwc *BWCSystem) loadStagedIngests() error {
+	paths, err := filepath.Glob(filepath.Join(bwc.stagingDir(), "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read ingest journal: %w", err)
+		}
+		var stage StagedIngest
+		if err := json.Unmarshal(data, &stage); err != nil || stage.EvidenceID+".json" != filepath.Base(path) {
+			bwc.logAudit("SYSTEM", "INGEST_INTERRUPTED", "", fmt.Sprintf("Unreadable ingest journal %s", path), "")
+			continue
+		}
+		bwc.stagedIngests[stage.EvidenceID] = &stage
+		bwc.logAudit("SYSTEM", "INGEST_INTERRUPTED", stage.EvidenceID,
+			fmt.Sprintf("Interrupted ingest of %s for case %s found at byte %d of %d", stage.SourcePath, stage.CaseNumber, stage.Offset, stage.FileSize), "")
+	}
+	return nil
+}
+
+// InterruptedIngests lists ingests whose copy into storage was cut short,
+// oldest first
+func (bwc *BWCSystem) InterruptedIngests() []StagedIngest {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+	stages := make([]StagedIngest, 0, len(bwc.stagedIngests))
+	for _, stage := range bwc.stagedIngests {
+		stages = append(stages, *stage)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i].StartedAt.Before(stages[j].StartedAt) })
+	return stages
+}
+
+// ResumeIngest finishes an interrupted ingest, copying from its last verified
+// checkpoint rather than from the start, and records the evidence as the
+// original ingest would have. The source file must still be where it was.
+func (bwc *BWCSystem) ResumeIngest(evidenceID, userID string) (*Evidence, error) {
+	return bwc.ResumeIngestContext(context.Background(), evidenceID, userID)
+}
+
+// ResumeIngestContext resumes like ResumeIngest, as the principal ctx carries
+// when it has one
+func (bwc *BWCSystem) ResumeIngestContext(ctx context.Context, evidenceID, userID string) (*Evidence, error) {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermIngest, "Resume ingest", evidenceID); err != nil {
+		return nil, err
+	}
+	if err := bwc.beginOperation(opIngest); err != nil {
+		return nil, err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.RLock()
+	stage, exists := bwc.stagedIngests[evidenceID]
+	bwc.mu.RUnlock()
+	if !exists {
+		return nil, errNoInterruptedIngest
+	}
+	lookups := bwc.lookupIngest(stage.SourcePath, stage.Location)
+	evidence, err := bwc.recordResumedIngest(stage, userID, auth, lookups)
+	if err != nil {
+		return nil, err
+	}
+	return bwc.timestampIngest(evidence), nil
+}
+
+// recordResumedIngest finishes copying a staged ingest and records the
+// evidence, all under bwc.mu
+func (bwc *BWCSystem) recordResumedIngest(stage *StagedIngest, userID string, auth *Authentication, lookups ingestLookups) (*Evidence, error) {
+	evidenceID := stage.EvidenceID
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	if bwc.stagedIngests[evidenceID] != stage {
+		return nil, errors.New("ingest was resumed or discarded by another request")
+	}
+	if bwc.evidenceDB.Get(evidenceID) != nil {
+		return nil, errors.New("evidence ID already exists")
+	}
+
+	tracker := bwc.trackIngest(context.Background(), stage.SourcePath, stage.CaseNumber, stage.OfficerID, stage.FileSize, nil)
+	defer bwc.untrackIngest(tracker)
+	tracker.skip(IngestHashing, stage.FileSize)
+
+	bwc.logAuditAs(auth, userID, "RESUME_INGEST", evidenceID, fmt.Sprintf("Ingest resumed from byte %d of %d", stage.Offset, stage.FileSize), "")
+	destPath, err := bwc.stageIngestLocked(stage, tracker)
+	if err != nil {
+		return nil, err
+	}
+	return bwc.completeIngestLocked(stage, destPath, lookups)
+}
+
+// DiscardIngest abandons an interrupted ingest and deletes its partial copy
+func (bwc *BWCSystem) DiscardIngest(evidenceID, userID, reason string) error {
+	return bwc.DiscardIngestContext(context.Background(), evidenceID, userID, reason)
+}
+
+// DiscardIngestContext discards like DiscardIngest, as the principal ctx
+// carries when it has one
+func (bwc *BWCSystem) DiscardIngestContext(ctx context.Context, evidenceID, userID, reason string) error {
+	userID, auth := actingUser(ctx, userID)
+	if err := bwc.authorize(userID, PermIngest, "Discard ingest", evidenceID); err != nil {
+		return err
+	}
+	if strings.TrimSpace(reason) == "" {
+		return errors.New("a reason is required to discard an ingest")
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	stage, exists := bwc.stagedIngests[evidenceID]
+	if !exists {
+		return errNoInterruptedIngest
+	}
+	bwc.removeStaged(stage)
+	delete(bwc.stagedIngests, evidenceID)
+	bwc.logAuditAs(auth, userID, "DISCARD_INGEST", evidenceID,
+		fmt.Sprintf("Interrupted ingest of %s discarded at byte %d of %d: %s", stage.SourcePath, stage.Offset, stage.FileSize, reason), "")
+	return nil
+}
@@ -0,0 +1,201 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stageInterruptedIngest leaves storage as a crash mid-copy would: a journal
+// checkpointed at offset and a partial file holding those bytes followed by
+// an unsynced tail. With corrupt set the staged prefix no longer matches.
+func stageInterruptedIngest(t *testing.T, storage, source, evidenceID string, offset int64, corrupt bool) *StagedIngest {
+	t.Helper()
+	data, err := os.ReadFile(source)
+	if err != nil {
+		t.Fatalf("failed to read source: %v", err)
+	}
+	hash, chunks, err := hashFileChunks(source, 0)
+	if err != nil {
+		t.Fatalf("failed to hash source: %v", err)
+	}
+	prefix := sha256.Sum256(data[:offset])
+	stage := &StagedIngest{
+		EvidenceID:    evidenceID,
+		SourcePath:    source,
+		CaseNumber:    "CASE-RSM-1",
+		OfficerID:     "OFF-1120",
+		Tags:          []string{"traffic"},
+		FileHash:      hash,
+		FileSize:      int64(len(data)),
+		ChunkManifest: chunks,
+		StartedAt:     time.Now().Add(-time.Hour),
+		Offset:        offset,
+		PrefixHash:    hex.EncodeToString(prefix[:]),
+	}
+
+	dir := filepath.Join(storage, "staging")
+	os.MkdirAll(dir, 0700)
+	journal, _ := json.Marshal(stage)
+	os.WriteFile(filepath.Join(dir, evidenceID+".json"), journal, 0600)
+	partial := append([]byte{}, data[:offset]...)
+	if corrupt {
+		partial[0] ^= 0xff
+	}
+	partial = append(partial, bytes.Repeat([]byte{0xee}, 1000)...)
+	os.WriteFile(filepath.Join(dir, evidenceID+".mp4.partial"), partial, 0600)
+	return stage
+}
+
+func writeRecording(t *testing.T, dir string) string {
+	t.Helper()
+	data := make([]byte, 3<<20)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	path := filepath.Join(dir, "recording.mp4")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write recording: %v", err)
+	}
+	return path
+}
+
+func TestResumeInterruptedIngest(t *testing.T) {
+	for name, corrupt := range map[string]bool{"verified prefix": false, "corrupted prefix": true} {
+		t.Run(name, func(t *testing.T) {
+			storage := t.TempDir()
+			source := writeRecording(t, t.TempDir())
+			id := "BWC-CASE-RSM-1-OFF-1120-1700000000"
+			stageInterruptedIngest(t, storage, source, id, 1<<20, corrupt)
+
+			system, err := NewBWCSystem(storage)
+			if err != nil {
+				t.Fatalf("NewBWCSystem failed: %v", err)
+			}
+			interrupted := system.InterruptedIngests()
+			if len(interrupted) != 1 || interrupted[0].EvidenceID != id || interrupted[0].Offset != 1<<20 {
+				t.Fatalf("expected the interrupted ingest to be found, got %+v", interrupted)
+			}
+			if logs := system.GetAuditLogs(id, "SYSTEM"); len(logs) != 1 || logs[0].Action != "INGEST_INTERRUPTED" {
+				t.Errorf("expected the interrupted ingest to be audited, got %+v", logs)
+			}
+
+			ev, err := system.ResumeIngest(id, "CUS-001")
+			if err != nil {
+				t.Fatalf("ResumeIngest failed: %v", err)
+			}
+			original, _ := os.ReadFile(source)
+			stored, _ := os.ReadFile(ev.FilePath)
+			if !bytes.Equal(original, stored) || ev.FileSize != int64(len(original)) {
+				t.Error("expected the stored file to match the source")
+			}
+			if ev.CaseNumber != "CASE-RSM-1" || ev.OfficerID != "OFF-1120" || len(ev.Tags) != 1 {
+				t.Errorf("expected the journaled metadata, got %+v", ev)
+			}
+			if valid, err := system.VerifyIntegrity(id, "CUS-001"); err != nil || !valid {
+				t.Errorf("expected the resumed evidence to verify, got %v, %v", valid, err)
+			}
+			if entries, _ := os.ReadDir(filepath.Join(storage, "staging")); len(entries) != 0 {
+				t.Errorf("expected staging to be empty, found %d entries", len(entries))
+			}
+			if len(system.InterruptedIngests()) != 0 {
+				t.Error("expected no interrupted ingests left")
+			}
+			if _, err := system.ResumeIngest(id, "CUS-001"); !errors.Is(err, errNoInterruptedIngest) {
+				t.Errorf("expected a second resume to find nothing, got %v", err)
+			}
+		})
+	}
+}
+
+func TestResumeIngestOfChangedSource(t *testing.T) {
+	storage := t.TempDir()
+	source := writeRecording(t, t.TempDir())
+	id := "BWC-CASE-RSM-1-OFF-1120-1700000001"
+	stageInterruptedIngest(t, storage, source, id, 1<<20, false)
+
+	// The tail of the source changes after the crash
+	f, _ := os.OpenFile(source, os.O_WRONLY, 0)
+	f.WriteAt([]byte("edited"), 2<<20)
+	f.Close()
+
+	system, err := NewBWCSystem(storage)
+	if err != nil {
+		t.Fatalf("NewBWCSystem failed: %v", err)
+	}
+	if _, err := system.ResumeIngest(id, "CUS-001"); !errors.Is(err, errStagedFileChanged) {
+		t.Fatalf("expected the changed source to be refused, got %v", err)
+	}
+	if _, err := system.GetEvidence(id); err == nil {
+		t.Error("expected no evidence to be recorded")
+	}
+	if entries, _ := os.ReadDir(filepath.Join(storage, "staging")); len(entries) != 0 {
+		t.Errorf("expected the staged copy to be removed, found %d entries", len(entries))
+	}
+}
+
+func TestIngestLeavesNothingStaged(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(writeRecording(t, t.TempDir()), "CASE-RSM-2", "OFF-1121", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if filepath.Dir(ev.FilePath) != tmpDir {
+		t.Errorf("expected the file in storage, got %s", ev.FilePath)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(tmpDir, "staging")); len(entries) != 0 {
+		t.Errorf("expected staging to be empty, found %d entries", len(entries))
+	}
+}
+
+func TestInterruptedIngestsAPI(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	id := "BWC-CASE-RSM-1-OFF-1120-1700000002"
+	stageInterruptedIngest(t, tmpDir, writeRecording(t, t.TempDir()), id, 1<<20, false)
+	if err := system.loadStagedIngests(); err != nil {
+		t.Fatalf("loadStagedIngests failed: %v", err)
+	}
+
+	resp := authGet(t, server, "/api/ingests/interrupted")
+	var listed []StagedIngest
+	json.NewDecoder(resp.Body).Decode(&listed)
+	resp.Body.Close()
+	if len(listed) != 1 || listed[0].EvidenceID != id {
+		t.Errorf("expected the interrupted ingest to be listed, got %+v", listed)
+	}
+
+	post := func(path, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+testAPIToken)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := post("/api/ingests/interrupted/"+id+"/discard", `{}`); code != http.StatusBadRequest {
+		t.Errorf("expected a discard without a reason to be refused, got %d", code)
+	}
+	if code := post("/api/ingests/interrupted/"+id+"/discard", `{"reason": "Duplicate upload"}`); code != http.StatusNoContent {
+		t.Errorf("expected the discard to succeed, got %d", code)
+	}
+	if code := post("/api/ingests/interrupted/"+id+"/resume", ``); code != http.StatusNotFound {
+		t.Errorf("expected a discarded ingest not to resume, got %d", code)
+	}
+	if logs := system.GetAuditLogs(id, "CUS-001"); len(logs) != 1 || logs[0].Action != "DISCARD_INGEST" {
+		t.Errorf("expected the discard to be audited, got %+v", logs)
+	}
+}
@@ -0,0 +1,235 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EvidenceStore holds the evidence records of a BWCSystem. The system calls it
+// with bwc.mu held, changes the records Get and Search return in place, and
+// then writes each changed record back with Put. Reads cannot fail, so a store
+// that persists records loads them when it is opened and writes through on Put
+// and Delete.
+type EvidenceStore interface {
+	// Get returns the record with id, or nil
+	Get(id string) *Evidence
+	// Put adds a record or replaces the one with the same ID
+	Put(evidence *Evidence) error
+	// Delete removes the record with id
+	Delete(id string) error
+	// Search returns the records match accepts in no particular order; a nil
+	// match accepts every record
+	Search(match func(*Evidence) bool) []*Evidence
+}
+
+// AuditStore is implemented by evidence stores that persist the audit log as
+// well. SetEvidenceStore loads the entries already there and then appends each
+// new one.
+type AuditStore interface {
+	// AppendAudit adds an entry to the end of the log
+	AppendAudit(log AuditLog) error
+	// AuditLogs returns the whole log, oldest first
+	AuditLogs() ([]AuditLog, error)
+}
+
+// memoryStore is the default EvidenceStore. Its records last as long as the
+// process.
+type memoryStore struct {
+	mu      sync.RWMutex
+	records map[string]*Evidence
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{records: make(map[string]*Evidence)}
+}
+
+func (s *memoryStore) Get(id string) *Evidence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.records[id]
+}
+
+func (s *memoryStore) Put(evidence *Evidence) error {
+	if evidence == nil || evidence.ID == "" {
+		return errors.New("evidence ID is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[evidence.ID] = evidence
+	return nil
+}
+
+func (s *memoryStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, id)
+	return nil
+}
+
+func (s *memoryStore) Search(match func(*Evidence) bool) []*Evidence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	results := make([]*Evidence, 0, len(s.records))
+	for _, evidence := range s.records {
+		if match == nil || match(evidence) {
+			results = append(results, evidence)
+		}
+	}
+	return results
+}
+
+// SetEvidenceStore replaces the in-memory store with store. It must be
+// called before any evidence is ingested; records already in store are used
+// as they are. When store is an AuditStore, its log is loaded ahead of the
+// entries made so far, which are then written to it.
+func (bwc *BWCSystem) SetEvidenceStore(store EvidenceStore) error {
+	if store == nil {
+		return errors.New("an evidence store is required")
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	if len(bwc.evidenceDB.Search(nil)) > 0 {
+		return errors.New("the evidence store cannot be changed once evidence is held")
+	}
+	if audit, ok := store.(AuditStore); ok {
+		if err := bwc.attachAuditStore(audit); err != nil {
+			return err
+		}
+	}
+	bwc.evidenceDB = store
+	for _, evidence := range store.Search(nil) {
+		bwc.indexLoadedLocked(evidence)
+	}
+	return nil
+}
+
+// indexLoadedLocked indexes a record the system did not create in this run.
+// The caller must hold bwc.mu.
+func (bwc *BWCSystem) indexLoadedLocked(evidence *Evidence) {
+	bwc.indexDocumentLocked(evidence)
+	for _, review := range evidence.Reviews {
+		bwc.reviewIndex[review.ID] = evidence.ID
+		// Review IDs carry on from the highest loaded
+		if n, err := strconv.Atoi(strings.TrimPrefix(review.ID, "REV-")); err == nil && n > bwc.reviewSeq {
+			bwc.reviewSeq = n
+		}
+	}
+}
+
+// attachAuditStore loads the log in audit and sends later entries to it
+func (bwc *BWCSystem) attachAuditStore(audit AuditStore) error {
+	bwc.auditMu.Lock()
+	defer bwc.auditMu.Unlock()
+
+	loaded, err := audit.AuditLogs()
+	if err != nil {
+		return fmt.Errorf("failed to load the audit log: %w", err)
+	}
+	for _, log := range bwc.auditLogs {
+		if err := audit.AppendAudit(log); err != nil {
+			return fmt.Errorf("failed to write the audit log: %w", err)
+		}
+	}
+	bwc.auditLogs = append(loaded, bwc.auditLogs...)
+	bwc.auditStore = audit
+	return nil
+}
+
+// openBoltStore opens a bbolt file; it is set by builds with -tags bbolt
+var openBoltStore func(path string) (EvidenceStore, error)
+
+// postgresDSN is database.dsn, or else a connection string made from the
+// other database settings
+func postgresDSN(db DatabaseConfig) string {
+	if db.DSN != "" {
+		return db.DSN
+	}
+	var params []string
+	add := func(key, value string) {
+		if value != "" {
+			value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+			params = append(params, key+"='"+value+"'")
+		}
+	}
+	add("host", db.Host)
+	if db.Port > 0 {
+		add("port", strconv.Itoa(db.Port))
+	}
+	add("dbname", db.Name)
+	add("user", db.User)
+	add("password", db.Password)
+	add("sslmode", db.SSLMode)
+	if db.ConnectionTimeoutSeconds > 0 {
+		add("connect_timeout", strconv.Itoa(db.ConnectionTimeoutSeconds))
+	}
+	return strings.Join(params, " ")
+}
+
+// openEvidenceStore opens the store cfg.Database selects, or returns nil for
+// the in-memory default
+func openEvidenceStore(cfg *Config) (EvidenceStore, error) {
+	switch cfg.Database.Type {
+	case "sqlite":
+		dsn := cfg.Database.DSN
+		if dsn == "" {
+			dsn = filepath.Join(cfg.Storage.Path, "evidence.db")
+		}
+		return openSQLStore(sqliteDialect, dsn)
+	case "postgres":
+		store, err := openSQLStore(postgresDialect, postgresDSN(cfg.Database))
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Database.MaxConnections > 0 {
+			store.db.SetMaxOpenConns(cfg.Database.MaxConnections)
+		}
+		return store, nil
+	case "bbolt":
+		if openBoltStore == nil {
+			return nil, errors.New("database.type bbolt needs a build with -tags bbolt")
+		}
+		path := cfg.Database.DSN
+		if path == "" {
+			path = filepath.Join(cfg.Storage.Path, "evidence.bolt")
+		}
+		return openBoltStore(path)
+	case "json":
+		dir := cfg.Database.DSN
+		if dir == "" {
+			dir = cfg.Storage.Path
+		}
+		return openJSONStore(dir)
+	case "memory":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("database.type %q is not supported", cfg.Database.Type)
+}
+
+// saveLocked writes a changed record back to the store, logging it to the
+// write-ahead log first when there is one. A failed write is audited as well
+// as returned, for callers that cannot report it. The caller must hold bwc.mu.
+func (bwc *BWCSystem) saveLocked(evidence *Evidence) error {
+	var seq int64
+	if bwc.wal != nil {
+		var err error
+		if seq, err = bwc.wal.append(evidence); err != nil {
+			bwc.logAudit("SYSTEM", "WAL_WRITE_FAILED", evidence.ID, err.Error(), "")
+			return err
+		}
+	}
+	if err := bwc.evidenceDB.Put(evidence); err != nil {
+		if seq > 0 {
+			// The store refused it, so it must not be replayed either
+			bwc.wal.abort(seq, evidence.ID)
+		}
+		bwc.logAudit("SYSTEM", "STORE_WRITE_FAILED", evidence.ID, err.Error(), "")
+		return err
+	}
+	bwc.journalRecordLocked(evidence)
+	return nil
+}
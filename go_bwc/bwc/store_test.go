@@ -0,0 +1,165 @@
+package bwc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// recordingStore is a memoryStore that counts writes and can refuse them,
+// all of them or only those of failID
+type recordingStore struct {
+	*memoryStore
+	puts   map[string]int
+	fail   error
+	failID string
+}
+
+func newRecordingStore() *recordingStore {
+	return &recordingStore{memoryStore: newMemoryStore(), puts: make(map[string]int)}
+}
+
+func (s *recordingStore) Put(evidence *Evidence) error {
+	if s.fail != nil && (s.failID == "" || s.failID == evidence.ID) {
+		return s.fail
+	}
+	s.puts[evidence.ID]++
+	return s.memoryStore.Put(evidence)
+}
+
+func TestEvidenceStoreWriteBack(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	store := newRecordingStore()
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-STO-1", "OFF-1139", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if store.puts[ev.ID] != 1 || store.Get(ev.ID) != ev {
+		t.Fatalf("expected the ingested record to be written once, got %d", store.puts[ev.ID])
+	}
+
+	if err := system.TransferCustody(ev.ID, "OFF-1139", "DET-003", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	if err := system.UpdateStatus(ev.ID, "DET-003", StatusProcessing, "Under review"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if _, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil {
+		t.Fatalf("VerifyIntegrity failed: %v", err)
+	}
+	if _, err := system.SealEvidence(ev.ID, "Court order 3"); err != nil {
+		t.Fatalf("SealEvidence failed: %v", err)
+	}
+	if store.puts[ev.ID] != 5 {
+		t.Errorf("expected every change to be written back, got %d writes", store.puts[ev.ID])
+	}
+	if got := system.SearchEvidence("CASE-STO-1", "", ""); len(got) != 1 || got[0].ID != ev.ID {
+		t.Errorf("expected search to read the store, got %v", got)
+	}
+
+	store.fail = errors.New("disk full")
+	if _, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-STO-2", "OFF-1139", "Officer Test", "Patrol", nil); err == nil {
+		t.Error("expected a failed write to fail the ingest")
+	}
+	if len(system.SearchEvidence("CASE-STO-2", "", "")) != 0 {
+		t.Error("expected the refused record not to be held")
+	}
+	logs := system.GetAuditLogs("", "SYSTEM")
+	if len(logs) == 0 || logs[len(logs)-1].Action != "STORE_WRITE_FAILED" || logs[len(logs)-1].Details != "disk full" {
+		t.Errorf("expected STORE_WRITE_FAILED, got %+v", logs)
+	}
+
+	if err := system.SetEvidenceStore(newMemoryStore()); err == nil {
+		t.Error("expected the store not to be replaced once evidence is held")
+	}
+}
+
+func TestSetEvidenceStoreLoadsRecords(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	store := newMemoryStore()
+	store.Put(&Evidence{ID: "BWC-CASE-STO-3-OFF-1140-1700000000", CaseNumber: "CASE-STO-3", Status: StatusCollected})
+	if err := system.SetEvidenceStore(nil); err == nil {
+		t.Error("expected a nil store to be rejected")
+	}
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+	if ev, err := system.GetEvidence("BWC-CASE-STO-3-OFF-1140-1700000000"); err != nil || ev.CaseNumber != "CASE-STO-3" {
+		t.Errorf("expected the stored record, got %+v, %v", ev, err)
+	}
+}
+
+// auditingStore is a memoryStore that keeps the audit log too
+type auditingStore struct {
+	*memoryStore
+	logs []AuditLog
+	fail error
+}
+
+func (s *auditingStore) AppendAudit(log AuditLog) error {
+	if s.fail != nil {
+		return s.fail
+	}
+	s.logs = append(s.logs, log)
+	return nil
+}
+
+func (s *auditingStore) AuditLogs() ([]AuditLog, error) {
+	return append([]AuditLog(nil), s.logs...), nil
+}
+
+func TestAuditStorePersistsLog(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	store := &auditingStore{memoryStore: newMemoryStore(), logs: []AuditLog{{UserID: "OFF-1142", Action: "EARLIER_RUN"}}}
+	system.logAudit("SYSTEM", "BEFORE_STORE", "", "", "")
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+	logs := system.GetAuditLogs("", "")
+	if len(logs) < 2 || logs[0].Action != "EARLIER_RUN" || logs[len(logs)-1].Action != "BEFORE_STORE" {
+		t.Fatalf("expected the loaded log ahead of this run's, got %+v", logs)
+	}
+	if store.logs[len(store.logs)-1].Action != "BEFORE_STORE" {
+		t.Errorf("expected this run's entries to be written to the store, got %+v", store.logs)
+	}
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-STO-4", "OFF-1142", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if last := store.logs[len(store.logs)-1]; last.Action != "INGEST_EVIDENCE" || last.EvidenceID != ev.ID {
+		t.Errorf("expected the ingest to be written to the store, got %+v", last)
+	}
+
+	store.fail = errors.New("disk full")
+	system.logAudit("OFF-1142", "VIEW_EVIDENCE", ev.ID, "", "")
+	logs = system.GetAuditLogs(ev.ID, "SYSTEM")
+	if len(logs) == 0 || logs[len(logs)-1].Action != "AUDIT_WRITE_FAILED" || logs[len(logs)-1].Details != "VIEW_EVIDENCE: disk full" {
+		t.Errorf("expected AUDIT_WRITE_FAILED, got %+v", logs)
+	}
+}
+
+func TestBoltNeedsBuildTag(t *testing.T) {
+	if openBoltStore != nil {
+		t.Skip("built with the bbolt driver")
+	}
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Database.Type = "bbolt"
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected database.type bbolt to be accepted: %v", err)
+	}
+	if _, err := NewBWCSystemFromConfig(cfg); err == nil || !strings.Contains(err.Error(), "-tags bbolt") {
+		t.Errorf("expected a hint to build with -tags bbolt, got %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// AddTags adds tags to every selected item. Items that are missing, sealed,
+// restricted from userID or already carry all the tags are skipped. Each changed item is audited on its
+// own. Tags decide retention, so re-tagging can move an item's expiry.
+func (bwc *BWCSystem) AddTags(selection EvidenceSelection, tags []string, userID string, dryRun bool) (*ChangePlan, error) {
+	return bwc.changeTags(selection, tags, userID, true, dryRun)
+}
+
+// RemoveTags removes tags from every selected item, skipping items that are
+// missing, sealed, restricted from userID or carry none of the tags
+func (bwc *BWCSystem) RemoveTags(selection EvidenceSelection, tags []string, userID string, dryRun bool) (*ChangePlan, error) {
+	return bwc.changeTags(selection, tags, userID, false, dryRun)
+}
+
+func (bwc *BWCSystem) changeTags(selection EvidenceSelection, tags []string, userID string, add, dryRun bool) (*ChangePlan, error) {
+	if err := bwc.authorize(userID, PermEditMetadata, "Tag change", ""); err != nil {
+		return nil, err
+	}
+	tags, err := cleanTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if add {
+		if tags, err = bwc.config.Tags.Normalize(tags); err != nil {
+			return nil, err
+		}
+	}
+	evidenceIDs, err := bwc.resolveSelection(selection, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		if err := bwc.beginOperation(opMutation); err != nil {
+			return nil, err
+		}
+		defer bwc.endOperation()
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	operation, action, verb := "add tags", "ADD_TAGS", "Added"
+	if !add {
+		operation, action, verb = "remove tags", "REMOVE_TAGS", "Removed"
+	}
+	plan := &ChangePlan{Operation: operation, DryRun: dryRun, Items: make([]PlannedChange, 0)}
+	seen := make(map[string]bool)
+	for _, id := range evidenceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		evidence := bwc.evidenceDB.Get(id)
+		if evidence == nil {
+			plan.skip(id, "evidence not found")
+			continue
+		}
+		if bwc.rejectIfRestrictedLocked(evidence, userID, "Tag change") != nil {
+			plan.skip(id, "restricted")
+			continue
+		}
+		changed := tagChanges(evidence.Tags, tags, add)
+		if len(changed) == 0 {
+			plan.skip(id, "no tags to change")
+			continue
+		}
+		if reason := bwc.sealedSkipLocked(evidence, userID, "Tag change", dryRun); reason != "" {
+			plan.skip(id, reason)
+			continue
+		}
+		plan.add(PlannedChange{EvidenceID: id, CaseNumber: evidence.CaseNumber, Tags: changed})
+
+		if dryRun {
+			continue
+		}
+		before := copyEvidence(evidence)
+		if add {
+			evidence.Tags = append(append([]string(nil), evidence.Tags...), changed...)
+		} else {
+			evidence.Tags = withoutTags(evidence.Tags, changed)
+		}
+		markModified(evidence, time.Now())
+		if err := bwc.saveLocked(evidence); err != nil {
+			*evidence = before
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		bwc.logAudit(userID, action, id, fmt.Sprintf("%s tags: %s", verb, strings.Join(changed, ", ")), "")
+	}
+
+	if !dryRun {
+		bwc.logAudit(userID, "BULK_"+action, "",
+			fmt.Sprintf("%s %s on %d items, %d skipped", verb, strings.Join(tags, ", "), len(plan.Items), len(plan.Skipped)), "")
+	}
+	return plan, nil
+}
+
+// MergeTags replaces the from tags with into on all evidence, e.g. to fold
+// "UOF" and "Use of Force" into "use-of-force" after a vocabulary change.
+// Tags are matched ignoring case and separators, so differently written copies
+// of into are merged as well. Sealed items, and restricted items userID is not
+// on the access list of, are skipped.
+func (bwc *BWCSystem) MergeTags(from []string, into, userID string, dryRun bool) (*ChangePlan, error) {
+	if err := bwc.authorize(userID, PermEditMetadata, "Tag merge", ""); err != nil {
+		return nil, err
+	}
+	from, err := cleanTags(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := bwc.config.Tags.Normalize([]string{into})
+	if err != nil {
+		return nil, err
+	}
+	if len(target) == 0 {
+		return nil, errors.New("a tag to merge into is required")
+	}
+	into = target[0]
+	if !dryRun {
+		if err := bwc.beginOperation(opMutation); err != nil {
+			return nil, err
+		}
+		defer bwc.endOperation()
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	keys := map[string]bool{tagKey(into): true}
+	for _, tag := range from {
+		keys[tagKey(tag)] = true
+	}
+	records := bwc.evidenceDB.Search(nil)
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	plan := &ChangePlan{Operation: "merge tags", DryRun: dryRun, Items: make([]PlannedChange, 0)}
+	for _, evidence := range records {
+		id := evidence.ID
+		merged := make([]string, 0)
+		for _, tag := range evidence.Tags {
+			if keys[tagKey(tag)] && tag != into {
+				merged = append(merged, tag)
+			}
+		}
+		if len(merged) == 0 {
+			continue
+		}
+		if bwc.rejectIfRestrictedLocked(evidence, userID, "Tag merge") != nil {
+			plan.skip(id, "restricted")
+			continue
+		}
+		if reason := bwc.sealedSkipLocked(evidence, userID, "Tag merge", dryRun); reason != "" {
+			plan.skip(id, reason)
+			continue
+		}
+		plan.add(PlannedChange{EvidenceID: id, CaseNumber: evidence.CaseNumber, Tags: merged})
+
+		if dryRun {
+			continue
+		}
+		before := copyEvidence(evidence)
+		evidence.Tags = withoutTags(evidence.Tags, merged)
+		if len(tagChanges(evidence.Tags, []string{into}, true)) > 0 {
+			evidence.Tags = append(evidence.Tags, into)
+		}
+		markModified(evidence, time.Now())
+		if err := bwc.saveLocked(evidence); err != nil {
+			*evidence = before
+			return nil, fmt.Errorf("%s: %w", id, err)
+		}
+		bwc.logAudit(userID, "MERGE_TAGS", id, fmt.Sprintf("Merged tags %s into %s", strings.Join(merged, ", "), into), "")
+	}
+
+	if !dryRun {
+		bwc.logAudit(userID, "TAG_MERGE", "",
+			fmt.Sprintf("Merged %s into %s on %d items, %d skipped", strings.Join(from, ", "), into, len(plan.Items), len(plan.Skipped)), "")
+	}
+	return plan, nil
+}
+
+// tagKey is the form tags are compared in: lower case, with runs of spaces,
+// hyphens and underscores folded into one hyphen
+func tagKey(tag string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return unicode.IsSpace(r) || r == '-' || r == '_'
+	}), "-")
+}
+
+// lookup finds the vocabulary entry a tag names or is a synonym of
+func (c *TagsConfig) lookup(tag string) *TagDefinition {
+	key := tagKey(tag)
+	for i := range c.Vocabulary {
+		def := &c.Vocabulary[i]
+		if tagKey(def.Name) == key {
+			return def
+		}
+		for _, synonym := range def.Synonyms {
+			if tagKey(synonym) == key {
+				return def
+			}
+		}
+	}
+	return nil
+}
+
+// Normalize maps tags onto the vocabulary: synonyms become their tag and
+// deprecated tags their replacement. Unlisted tags and deprecated tags without
+// a replacement are refused. Without a vocabulary tags are returned unchanged.
+func (c *TagsConfig) Normalize(tags []string) ([]string, error) {
+	if len(c.Vocabulary) == 0 {
+		return tags, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		name := tag
+		def := c.lookup(tag)
+		switch {
+		case def == nil && !c.AllowUnlisted:
+			return nil, fmt.Errorf("tag %q is not in the tag vocabulary", tag)
+		case def == nil:
+		case def.Deprecated && def.ReplacedBy == "":
+			return nil, fmt.Errorf("tag %q is deprecated", tag)
+		case def.Deprecated:
+			name = c.lookup(def.ReplacedBy).Name
+		default:
+			name = def.Name
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized, nil
+}
+
+// cleanTags trims tags and drops duplicates, refusing an empty list
+func cleanTags(tags []string) ([]string, error) {
+	cleaned := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		cleaned = append(cleaned, tag)
+	}
+	if len(cleaned) == 0 {
+		return nil, errors.New("no tags given")
+	}
+	return cleaned, nil
+}
+
+// tagChanges returns the tags that adding or removing would actually change
+func tagChanges(current, tags []string, add bool) []string {
+	has := make(map[string]bool, len(current))
+	for _, tag := range current {
+		has[tag] = true
+	}
+	changed := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if has[tag] != add {
+			changed = append(changed, tag)
+		}
+	}
+	return changed
+}
+
+func withoutTags(current, remove []string) []string {
+	drop := make(map[string]bool, len(remove))
+	for _, tag := range remove {
+		drop[tag] = true
+	}
+	kept := make([]string, 0, len(current))
+	for _, tag := range current {
+		if !drop[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
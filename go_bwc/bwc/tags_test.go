@@ -0,0 +1,165 @@
+package bwc
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddAndRemoveTags(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	a, _ := system.IngestEvidence(testFile, "CASE-TAG-001", "OFF-961", "Officer Test", "Test Location", []string{"UOF"})
+	b, _ := system.IngestEvidence(testFile, "CASE-TAG-001", "OFF-962", "Officer Test", "Test Location", []string{"traffic"})
+	sealed, _ := system.IngestEvidence(testFile, "CASE-TAG-001", "OFF-963", "Officer Test", "Test Location", nil)
+	system.SealEvidence(sealed.ID, "Court order 24-211")
+
+	preview, err := system.AddTags(EvidenceSelection{CaseNumber: "CASE-TAG-001"}, []string{"use-of-force", " ", "use-of-force"}, "RECORDS-1", true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(preview.Items) != 2 || len(preview.Skipped) != 1 || len(a.Tags) != 1 {
+		t.Fatalf("Unexpected dry run %+v", preview)
+	}
+
+	plan, err := system.AddTags(EvidenceSelection{CaseNumber: "CASE-TAG-001"}, []string{"use-of-force"}, "RECORDS-1", false)
+	if err != nil {
+		t.Fatalf("AddTags failed: %v", err)
+	}
+	if len(plan.Items) != 2 || len(a.Tags) != 2 || len(b.Tags) != 2 || len(sealed.Tags) != 0 {
+		t.Fatalf("Expected the unsealed items tagged, got %+v", plan)
+	}
+
+	plan, err = system.RemoveTags(EvidenceSelection{EvidenceIDs: []string{a.ID, b.ID}}, []string{"UOF"}, "RECORDS-1", false)
+	if err != nil {
+		t.Fatalf("RemoveTags failed: %v", err)
+	}
+	if len(plan.Items) != 1 || plan.Items[0].EvidenceID != a.ID || plan.Skipped[0].Reason != "no tags to change" {
+		t.Errorf("Expected only %s changed, got %+v", a.ID, plan)
+	}
+	if len(a.Tags) != 1 || a.Tags[0] != "use-of-force" {
+		t.Errorf("Unexpected tags %v", a.Tags)
+	}
+
+	actions := make(map[string]int)
+	for _, log := range system.GetAuditLogs("", "RECORDS-1") {
+		actions[log.Action]++
+	}
+	if actions["ADD_TAGS"] != 2 || actions["REMOVE_TAGS"] != 1 || actions["BULK_ADD_TAGS"] != 1 ||
+		actions["BULK_REMOVE_TAGS"] != 1 || actions["SEALED_ACCESS_DENIED"] != 1 {
+		t.Errorf("Unexpected audit entries %v", actions)
+	}
+
+	if _, err := system.AddTags(EvidenceSelection{EvidenceIDs: []string{a.ID}}, []string{""}, "RECORDS-1", false); err == nil {
+		t.Error("Expected an empty tag list to be refused")
+	}
+}
+
+func TestAddTagsFailedSave(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	store := newRecordingStore()
+	if err := system.SetEvidenceStore(store); err != nil {
+		t.Fatalf("SetEvidenceStore failed: %v", err)
+	}
+
+	evidence, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TAG-005", "OFF-964", "Officer Test", "Test Location", []string{"traffic"})
+	store.fail = errors.New("disk full")
+	if _, err := system.AddTags(EvidenceSelection{CaseNumber: "CASE-TAG-005"}, []string{"use-of-force"}, "RECORDS-1", false); err == nil {
+		t.Fatal("Expected a failed write to fail the tag change")
+	}
+	if len(evidence.Tags) != 1 || evidence.Revision != 1 {
+		t.Errorf("Expected the tags to be left as they were, got %v at revision %d", evidence.Tags, evidence.Revision)
+	}
+	if logs := system.GetAuditLogs(evidence.ID, "RECORDS-1"); len(logs) != 0 {
+		t.Errorf("Expected nothing audited for an unsaved change, got %v", logs)
+	}
+}
+
+func TestTagVocabulary(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.config.Tags = TagsConfig{Vocabulary: []TagDefinition{
+		{Name: "use-of-force", Synonyms: []string{"UOF"}},
+		{Name: "traffic"},
+		{Name: "pursuit", Deprecated: true, ReplacedBy: "traffic"},
+		{Name: "misc", Deprecated: true},
+	}}
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, err := system.IngestEvidence(testFile, "CASE-TAG-002", "OFF-964", "Officer Test", "Test Location", []string{"uof", "Use of Force", "Pursuit"})
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if strings.Join(evidence.Tags, ",") != "use-of-force,traffic" {
+		t.Errorf("Expected tags mapped onto the vocabulary, got %v", evidence.Tags)
+	}
+
+	for _, tags := range [][]string{{"burglary"}, {"misc"}} {
+		if _, err := system.IngestEvidence(testFile, "CASE-TAG-002", "OFF-965", "Officer Test", "Test Location", tags); err == nil {
+			t.Errorf("Expected ingest with %v to be refused", tags)
+		}
+		if _, err := system.AddTags(EvidenceSelection{EvidenceIDs: []string{evidence.ID}}, tags, "RECORDS-1", false); err == nil {
+			t.Errorf("Expected tagging with %v to be refused", tags)
+		}
+	}
+
+	system.config.Tags.AllowUnlisted = true
+	if plan, err := system.AddTags(EvidenceSelection{EvidenceIDs: []string{evidence.ID}}, []string{"burglary", "UOF"}, "RECORDS-1", false); err != nil || len(plan.Items) != 1 || len(plan.Items[0].Tags) != 1 {
+		t.Errorf("Expected only the unlisted tag added, got %+v, %v", plan, err)
+	}
+}
+
+func TestMergeTags(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	a, _ := system.IngestEvidence(testFile, "CASE-TAG-003", "OFF-966", "Officer Test", "Test Location", []string{"UOF", "Use of Force", "night"})
+	b, _ := system.IngestEvidence(testFile, "CASE-TAG-003", "OFF-967", "Officer Test", "Test Location", []string{"use-of-force"})
+	sealed, _ := system.IngestEvidence(testFile, "CASE-TAG-003", "OFF-968", "Officer Test", "Test Location", []string{"UOF"})
+	system.SealEvidence(sealed.ID, "Court order 24-214")
+
+	plan, err := system.MergeTags([]string{"UOF"}, "use-of-force", "RECORDS-1", true)
+	if err != nil || len(plan.Items) != 1 || len(plan.Skipped) != 1 || len(a.Tags) != 3 {
+		t.Fatalf("Unexpected dry run %+v, %v", plan, err)
+	}
+
+	input := "mt use-of-force UOF\nq\n"
+	var out bytes.Buffer
+	if err := runTUI(system, "RECORDS-1", 30*24*time.Hour, strings.NewReader(input), &out, false); err != nil {
+		t.Fatalf("runTUI failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "Merged tags into use-of-force on 1 items, 1 sealed items skipped") {
+		t.Errorf("Unexpected TUI output:\n%s", out.String())
+	}
+	if strings.Join(a.Tags, ",") != "night,use-of-force" || strings.Join(b.Tags, ",") != "use-of-force" || sealed.Tags[0] != "UOF" {
+		t.Errorf("Unexpected tags after merge: %v %v %v", a.Tags, b.Tags, sealed.Tags)
+	}
+
+	logs := system.GetAuditLogs(a.ID, "RECORDS-1")
+	if len(logs) != 1 || logs[0].Action != "MERGE_TAGS" || !strings.Contains(logs[0].Details, "UOF, Use of Force into use-of-force") {
+		t.Errorf("Expected MERGE_TAGS audit entry, got %v", logs)
+	}
+}
+
+func TestTagVocabularyValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tags.Vocabulary = []TagDefinition{
+		{Name: "use-of-force", Synonyms: []string{"UOF"}},
+		{Name: "Use Of Force"},
+		{Name: "traffic", Synonyms: []string{"uof"}, ReplacedBy: "use-of-force"},
+		{Name: "pursuit", Deprecated: true, ReplacedBy: "UOF"},
+		{Name: " "},
+	}
+	err := cfg.Validate()
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || len(cfgErr.Problems) != 5 {
+		t.Fatalf("Expected 5 tag problems, got %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// testimonyPackageFormat identifies the layout of a testimony package
+const testimonyPackageFormat = "bwc-testimony-package/v1"
+
+// TestimonyPackageFile lists one file in a testimony package
+type TestimonyPackageFile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SHA256      string `json:"sha256"`
+	Size        int64  `json:"size"`
+}
+
+// TestimonyPackageManifest is manifest.json at the root of a testimony
+// package: a zip of everything an officer needs on the stand for one item,
+// signed like a case package in manifest.sig. The recording itself is not
+// included.
+type TestimonyPackageManifest struct {
+	Format       string                 `json:"format"`
+	SourceSystem string                 `json:"source_system"`
+	EvidenceID   string                 `json:"evidence_id"`
+	CaseNumber   string                 `json:"case_number"`
+	FileSHA256   string                 `json:"file_sha256"`
+	PreparedAt   time.Time              `json:"prepared_at"`
+	PreparedFor  string                 `json:"prepared_for"`
+	Files        []TestimonyPackageFile `json:"files"`
+}
+
+// ExportTestimonyPackage writes a testimony preparation package for evidence
+// to path, encrypted with enc when it is set. It holds the custody
+// certificate (the affidavit) sworn by officerID, the integrity history, the
+// viewing log and audit trail, derivatives such as thumbnails, extracted text
+// and processing outputs, and a description of how to verify the recording.
+// Derivatives are re-hashed as they are packaged. When report signing is
+// configured the custody certificate carries the agency's detached signature. The package is registered as
+// a copy; sealed evidence cannot be packaged.
+func (bwc *BWCSystem) ExportTestimonyPackage(evidenceID, path, officerID, officerName string, enc *ExportEncryption) (*TestimonyPackageManifest, error) {
+	if err := bwc.authorize(officerID, PermExport, "Testimony package export", evidenceID); err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		if err := enc.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	bwc.mu.Lock()
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		bwc.mu.Unlock()
+		return nil, ErrEvidenceNotFound
+	}
+	if err := bwc.rejectIfSealedLocked(evidence, officerID, "Testimony package export"); err != nil {
+		bwc.mu.Unlock()
+		return nil, err
+	}
+	if err := bwc.rejectIfRestrictedLocked(evidence, officerID, "Testimony package export"); err != nil {
+		bwc.mu.Unlock()
+		return nil, err
+	}
+	if err := bwc.checkExportPathLocked(path, evidenceID, officerID); err != nil {
+		bwc.mu.Unlock()
+		return nil, err
+	}
+	bwc.mu.Unlock()
+
+	affidavit, err := bwc.BuildCustodyAffidavit(evidenceID, officerID, officerName)
+	if err != nil {
+		return nil, err
+	}
+	ev := affidavit.Evidence
+	record, err := json.MarshalIndent(ev, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal evidence: %w", err)
+	}
+	viewing, err := viewingLogCSV(bwc.ViewSessions(evidenceID))
+	if err != nil {
+		return nil, err
+	}
+	audit, err := auditLogCSV(bwc.GetAuditLogs(evidenceID, ""))
+	if err != nil {
+		return nil, err
+	}
+	integrity, err := integrityHistoryCSV(ev.IntegrityChecks)
+	if err != nil {
+		return nil, err
+	}
+	procedure, err := verificationProcedure(&ev, bwc.config.System.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &TestimonyPackageManifest{
+		Format:       testimonyPackageFormat,
+		SourceSystem: bwc.config.System.Name,
+		EvidenceID:   ev.ID,
+		CaseNumber:   ev.CaseNumber,
+		FileSHA256:   ev.FileHash,
+		PreparedAt:   time.Now().UTC(),
+		PreparedFor:  officerID,
+	}
+
+	hash, size, err := writeExportFile(path, enc, func(w io.Writer) error {
+		zw := zip.NewWriter(w)
+		add := func(name, description string, data []byte) error {
+			sum := sha256.Sum256(data)
+			fw, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			if _, err := fw.Write(data); err != nil {
+				return err
+			}
+			manifest.Files = append(manifest.Files, TestimonyPackageFile{
+				Name: name, Description: description, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(data)),
+			})
+			return nil
+		}
+
+		certificate := affidavit.PDF()
+		parts := []struct {
+			name, description string
+			data              []byte
+		}{
+			{"custody-certificate.pdf", "Chain-of-custody affidavit to sign", certificate},
+			{"evidence.json", "Evidence record", record},
+			{"integrity-history.csv", "Every integrity check of the recording", integrity},
+			{"viewing-log.csv", "Every playback session", viewing},
+			{"audit-log.csv", "Every audited action on the item", audit},
+			{"verification-procedure.txt", "How the recording is verified", procedure},
+		}
+		for _, part := range parts {
+			if err := add(part.name, part.description, part.data); err != nil {
+				return err
+			}
+		}
+		if bwc.reportSigner != nil {
+			signature, err := bwc.SignDocument("custody-certificate.pdf for "+ev.ID, certificate, officerID)
+			if err != nil {
+				return err
+			}
+			if err := add("custody-certificate.pdf.p7s", "Agency signature over the custody certificate", signature); err != nil {
+				return err
+			}
+		}
+		for _, d := range testimonyDerivatives(&ev) {
+			data, err := os.ReadFile(d.file.Path)
+			if err != nil {
+				return fmt.Errorf("failed to read derivative %s: %w", d.file.Name, err)
+			}
+			if sum := sha256.Sum256(data); d.file.SHA256 != "" && hex.EncodeToString(sum[:]) != d.file.SHA256 {
+				return fmt.Errorf("derivative %s no longer matches its recorded hash", d.file.Name)
+			}
+			if err := add("derivatives/"+d.name, d.description, data); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.MarshalIndent(manifest, "", "  ")
+		if err != nil {
+			return err
+		}
+		mw, err := zw.Create("manifest.json")
+		if err != nil {
+			return err
+		}
+		if _, err := mw.Write(data); err != nil {
+			return err
+		}
+		sig, err := json.MarshalIndent(PackageSignature{
+			KeyID:     bwc.sealer.keyID,
+			PublicKey: hex.EncodeToString(bwc.SealPublicKey()),
+			Signature: bwc.sealer.sign(packageSigningPayload(data)),
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		sw, err := zw.Create(casePackageSignatureFile)
+		if err != nil {
+			return err
+		}
+		if _, err := sw.Write(sig); err != nil {
+			return err
+		}
+		return zw.Close()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write testimony package: %w", err)
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+	copyRecord := bwc.registerCopyLocked(evidenceID, CopyReport, officerID, path, "Testimony preparation", hash, size)
+	copyRecord.Encryption = enc.Describe()
+	bwc.logAudit(officerID, "EXPORT_TESTIMONY_PACKAGE", evidenceID, copyDetails(copyRecord), "")
+
+	return manifest, nil
+}
+
+// testimonyDerivative is a derived file to include in a testimony package
+type testimonyDerivative struct {
+	name        string
+	description string
+	file        DerivedFile
+}
+
+// testimonyDerivatives lists the files derived from evidence, named by where they came from
+func testimonyDerivatives(ev *Evidence) []testimonyDerivative {
+	var derivatives []testimonyDerivative
+	if ev.Photo != nil && ev.Photo.Thumbnail != nil {
+		derivatives = append(derivatives, testimonyDerivative{
+			"thumbnail" + filepath.Ext(ev.Photo.Thumbnail.Path), "Photo thumbnail", *ev.Photo.Thumbnail})
+	}
+	if ev.Document != nil && ev.Document.Text != nil {
+		derivatives = append(derivatives, testimonyDerivative{
+			"text.txt", "Text extracted by " + ev.Document.Extractor, *ev.Document.Text})
+	}
+	for _, result := range ev.Processing {
+		for _, out := range result.Outputs {
+			derivatives = append(derivatives, testimonyDerivative{
+				result.Processor + "/" + out.Name,
+				fmt.Sprintf("Output of %s (job %s)", result.Processor, result.JobID), out})
+		}
+	}
+	return derivatives
+}
+
+// integrityHistoryCSV renders integrity checks one per row
+func integrityHistoryCSV(checks []IntegrityCheck) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"timestamp", "checked_by", "result", "hash_value", "notes"})
+	for _, check := range checks {
+		result := "PASSED"
+		if !check.IsValid {
+			result = "FAILED"
+		}
+		w.Write([]string{check.Timestamp.Format(time.RFC3339), check.CheckedBy, result, check.HashValue, check.Notes})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// viewingLogCSV renders playback sessions one per row
+func viewingLogCSV(sessions []ViewSession) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"session_id", "user_id", "ip_address", "started_at", "last_activity", "bytes_served", "ranges"})
+	for _, s := range sessions {
+		w.Write([]string{s.ID, s.UserID, s.IPAddress, s.StartedAt.Format(time.RFC3339), s.LastActivity.Format(time.RFC3339),
+			strconv.FormatInt(s.BytesServed, 10), strconv.Itoa(len(s.Ranges))})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// auditLogCSV renders audit entries one per row
+func auditLogCSV(logs []AuditLog) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"timestamp", "user_id", "action", "details", "ip_address"})
+	for _, log := range logs {
+		w.Write([]string{log.Timestamp.Format(time.RFC3339), log.UserID, log.Action, log.Details, log.IPAddress})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// verificationProcedureTemplate describes, for the officer and the court, how
+// the recording's integrity is established
+var verificationProcedureTemplate = template.Must(template.New("procedure").Parse(`VERIFICATION PROCEDURE
+Evidence {{.ID}}, case {{.CaseNumber}}{{if .System}}, {{.System}}{{end}}
+
+1. On ingest the system computed the SHA-256 digest of the recording, a
+   fingerprint that changes completely if even one bit of the file changes:
+
+   {{.FileHash}}  ({{.FileSize}} bytes)
+
+2. Every custody transfer re-computes the digest before the transfer is
+   recorded and stores it in the custody entry's verified hash. Each custody
+   entry is itself hashed, and signed entries carry the signer's signature,
+   so a later change to an entry is detected.
+
+3. Integrity checks re-compute the digest of the stored file and compare it
+   with the digest recorded on ingest. Each check and its result is listed in
+   integrity-history.csv and cannot be removed.{{if .Chunked}}
+
+   The recording also has per-chunk digests, so a failed check reports which
+   byte ranges changed.{{end}}
+
+4. Anyone holding a copy of the recording can verify it independently with a
+   standard tool, and must obtain the digest above:
+
+     sha256sum <recording>                        (Linux)
+     shasum -a 256 <recording>                    (macOS)
+     certutil -hashfile <recording> SHA256        (Windows)
+
+5. This package is signed. manifest.json lists the SHA-256 of every file in
+   the package, and manifest.sig holds the system's Ed25519 signature over
+   manifest.json with the public key it can be checked against.
+`))
+
+// verificationProcedure renders the verification procedure for evidence
+func verificationProcedure(ev *Evidence, system string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := verificationProcedureTemplate.Execute(&buf, struct {
+		*Evidence
+		System  string
+		Chunked bool
+	}{ev, system, ev.ChunkManifest != nil})
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimLeft(buf.String(), "\n")), nil
+}
@@ -0,0 +1,150 @@
+package bwc
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readTestimonyPackage opens a testimony package, checks its manifest
+// signature and file hashes, and returns its files by name
+func readTestimonyPackage(t *testing.T, path string) (*TestimonyPackageManifest, map[string][]byte) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read package: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Package is not a zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	var manifest TestimonyPackageManifest
+	if err := json.Unmarshal(files["manifest.json"], &manifest); err != nil {
+		t.Fatalf("Invalid manifest: %v", err)
+	}
+	var sig PackageSignature
+	json.Unmarshal(files[casePackageSignatureFile], &sig)
+	key, _ := hex.DecodeString(sig.PublicKey)
+	value, _ := base64.StdEncoding.DecodeString(sig.Signature)
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, packageSigningPayload(files["manifest.json"]), value) {
+		t.Error("Manifest signature does not verify")
+	}
+	for _, f := range manifest.Files {
+		sum := sha256.Sum256(files[f.Name])
+		if hex.EncodeToString(sum[:]) != f.SHA256 {
+			t.Errorf("%s does not match the manifest", f.Name)
+		}
+	}
+	return &manifest, files
+}
+
+func TestExportTestimonyPackage(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := writeTestAudio(t, tmpDir, "scene.jpg", testJPEG(640, 480, testExif("2024:03:09 21:15:02", "-05:00")))
+	evidence, err := system.IngestEvidence(path, "CASE-TST-001", "OFF-1090", "Officer Test", "Scene", nil)
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	system.TransferCustody(evidence.ID, "OFF-1090", "DET-1091", "Analysis")
+	if _, err := system.StartViewSession(evidence.ID, "DA-1092", "10.0.0.5"); err != nil {
+		t.Fatalf("StartViewSession failed: %v", err)
+	}
+
+	out := filepath.Join(tmpDir, "testimony.zip")
+	manifest, err := system.ExportTestimonyPackage(evidence.ID, out, "OFF-1090", "Pat Officer", nil)
+	if err != nil {
+		t.Fatalf("ExportTestimonyPackage failed: %v", err)
+	}
+	if manifest.Format != testimonyPackageFormat || manifest.FileSHA256 != evidence.FileHash || manifest.PreparedFor != "OFF-1090" {
+		t.Errorf("Unexpected manifest: %+v", manifest)
+	}
+
+	read, files := readTestimonyPackage(t, out)
+	if len(read.Files) != 7 {
+		t.Fatalf("Expected 6 parts and a thumbnail, got %+v", read.Files)
+	}
+	if !bytes.HasPrefix(files["custody-certificate.pdf"], []byte("%PDF-")) ||
+		!bytes.Contains(files["custody-certificate.pdf"], []byte("I, Pat Officer \\(ID OFF-1090\\)")) {
+		t.Error("Expected the custody certificate sworn by the officer")
+	}
+	if !bytes.Equal(files["derivatives/thumbnail.jpg"], mustReadFile(t, evidence.Photo.Thumbnail.Path)) {
+		t.Error("Expected the photo thumbnail")
+	}
+	if !strings.Contains(string(files["viewing-log.csv"]), "DA-1092,10.0.0.5") {
+		t.Errorf("Unexpected viewing log:\n%s", files["viewing-log.csv"])
+	}
+	if !strings.Contains(string(files["audit-log.csv"]), "TRANSFER_CUSTODY") {
+		t.Errorf("Unexpected audit log:\n%s", files["audit-log.csv"])
+	}
+	if lines := strings.Count(string(files["integrity-history.csv"]), "\n"); lines != 2 {
+		t.Errorf("Expected the ingest check and header, got:\n%s", files["integrity-history.csv"])
+	}
+	if !strings.Contains(string(files["verification-procedure.txt"]), evidence.FileHash) {
+		t.Error("Expected the recorded hash in the verification procedure")
+	}
+	if _, included := files["files/"+evidence.ID+".jpg"]; included {
+		t.Error("Expected the recording itself to be left out")
+	}
+
+	copies := system.CopiesOf(evidence.ID)
+	if len(copies) != 1 || copies[0].Kind != CopyReport || copies[0].Destination != out {
+		t.Errorf("Expected the package registered as a copy, got %+v", copies)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "OFF-1090")
+	if len(logs) == 0 || logs[len(logs)-1].Action != "EXPORT_TESTIMONY_PACKAGE" {
+		t.Errorf("Expected EXPORT_TESTIMONY_PACKAGE audit entry, got %+v", logs)
+	}
+}
+
+func TestExportTestimonyPackageChecksDerivatives(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := writeTestAudio(t, tmpDir, "scene.jpg", testJPEG(320, 240, nil))
+	evidence, _ := system.IngestEvidence(path, "CASE-TST-002", "OFF-1093", "Officer Test", "Scene", nil)
+	os.WriteFile(evidence.Photo.Thumbnail.Path, []byte("replaced"), 0600)
+
+	out := filepath.Join(tmpDir, "testimony.zip")
+	if _, err := system.ExportTestimonyPackage(evidence.ID, out, "OFF-1093", "", nil); err == nil || !strings.Contains(err.Error(), "no longer matches") {
+		t.Errorf("Expected a changed derivative to fail the export, got %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("Expected no package to be left behind")
+	}
+
+	if _, err := system.SealEvidence(evidence.ID, "Order 26-0500"); err == nil {
+		if _, err := system.ExportTestimonyPackage(evidence.ID, out, "OFF-1093", "", nil); err == nil {
+			t.Error("Expected sealed evidence to be refused")
+		}
+	}
+	if _, err := system.ExportTestimonyPackage("EV-MISSING", out, "OFF-1093", "", nil); err == nil {
+		t.Error("Expected an error for missing evidence")
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return data
+}
@@ -0,0 +1,121 @@
+package bwc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minTermLength is the shortest word kept in the full-text index
+const minTermLength = 2
+
+// textIndex is an in-memory inverted index from words to the evidence whose
+// text contains them
+type textIndex struct {
+	postings map[string]map[string]bool
+	terms    map[string][]string
+}
+
+func newTextIndex() *textIndex {
+	return &textIndex{
+		postings: make(map[string]map[string]bool),
+		terms:    make(map[string][]string),
+	}
+}
+
+// indexTerms splits text into lowercase words, dropping punctuation and
+// words shorter than minTermLength
+func indexTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) >= minTermLength {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// add indexes text for evidenceID, replacing whatever was indexed for it
+func (x *textIndex) add(evidenceID, text string) {
+	x.remove(evidenceID)
+	seen := make(map[string]bool)
+	for _, term := range indexTerms(text) {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		if x.postings[term] == nil {
+			x.postings[term] = make(map[string]bool)
+		}
+		x.postings[term][evidenceID] = true
+		x.terms[evidenceID] = append(x.terms[evidenceID], term)
+	}
+}
+
+// remove drops evidenceID from the index
+func (x *textIndex) remove(evidenceID string) {
+	for _, term := range x.terms[evidenceID] {
+		delete(x.postings[term], evidenceID)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	delete(x.terms, evidenceID)
+}
+
+// search returns the sorted IDs of evidence containing every word of query
+func (x *textIndex) search(query string) []string {
+	terms := indexTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+	var ids []string
+	for id := range x.postings[terms[0]] {
+		match := true
+		for _, term := range terms[1:] {
+			if !x.postings[term][id] {
+				match = false
+				break
+			}
+		}
+		if match {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SearchText returns the evidence whose indexed text contains every word of
+// query, in ID order. Document evidence is indexed by its extracted text.
+func (bwc *BWCSystem) SearchText(query string) ([]*Evidence, error) {
+	return bwc.SearchTextContext(systemContext(context.Background()), query)
+}
+
+// SearchTextContext searches like SearchText, leaving out restricted evidence
+// the principal ctx carries is not on the access list of. With
+// audit.log_all_access on, each record returned is audited as read.
+func (bwc *BWCSystem) SearchTextContext(ctx context.Context, query string) ([]*Evidence, error) {
+	if len(indexTerms(query)) == 0 {
+		return nil, errors.New("search query has no words to match")
+	}
+
+	bwc.mu.RLock()
+	reader := readerFromContext(ctx)
+	results := make([]*Evidence, 0)
+	for _, id := range bwc.textIndex.search(query) {
+		if evidence := bwc.evidenceDB.Get(id); evidence != nil && evidence.ACL.allows(reader) {
+			results = append(results, evidence)
+		}
+	}
+	bwc.mu.RUnlock()
+
+	bwc.auditReads(ctx, "SEARCH_EVIDENCE", results, fmt.Sprintf("Returned by a text search for %q", query))
+	return results, nil
+}
@@ -0,0 +1,42 @@
+package bwc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTextIndex(t *testing.T) {
+	x := newTextIndex()
+	x.add("EV-1", "CONSENT TO SEARCH. I, John Doe, consent to a search of my vehicle.")
+	x.add("EV-2", "Vehicle tow report: a search was not performed.")
+	x.add("EV-3", "Use-of-force report, Ofc. Diaz")
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"search", []string{"EV-1", "EV-2"}},
+		{"Vehicle SEARCH consent", []string{"EV-1"}},
+		{"use of force", []string{"EV-3"}},
+		{"doe, john", []string{"EV-1"}},
+		{"warrant", nil},
+		{"a", nil},
+	}
+	for _, tt := range tests {
+		if got := x.search(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	x.add("EV-1", "Property receipt")
+	if got := x.search("consent"); got != nil {
+		t.Errorf("Expected replaced text to be unindexed, got %v", got)
+	}
+	x.remove("EV-2")
+	if got := x.search("search"); got != nil {
+		t.Errorf("Expected removed evidence to be unindexed, got %v", got)
+	}
+	if _, ok := x.postings["tow"]; ok {
+		t.Error("Expected empty postings to be dropped")
+	}
+}
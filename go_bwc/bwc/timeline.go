@@ -0,0 +1,127 @@
+package bwc
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TimelineSource is the record a timeline event was drawn from
+type TimelineSource string
+
+const (
+	TimelineCustody   TimelineSource = "CUSTODY"
+	TimelineIntegrity TimelineSource = "INTEGRITY"
+	TimelineStatus    TimelineSource = "STATUS"
+	TimelineViewing   TimelineSource = "VIEWING"
+	TimelineAudit     TimelineSource = "AUDIT"
+)
+
+// TimelineEvent is one moment in the life of an evidence item. Exactly one of
+// Custody, Integrity, View and Audit holds the record it was drawn from.
+type TimelineEvent struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Source    TimelineSource `json:"source"`
+	Action    string         `json:"action"`
+	Actor     string         `json:"actor,omitempty"`
+	Summary   string         `json:"summary"`
+	IPAddress string         `json:"ip_address,omitempty"`
+
+	Custody   *CustodyEntry   `json:"custody,omitempty"`
+	Integrity *IntegrityCheck `json:"integrity,omitempty"`
+	View      *ViewSession    `json:"view,omitempty"`
+	Audit     *AuditLog       `json:"audit,omitempty"`
+}
+
+// Timeline is everything that has happened to an evidence item, oldest first
+type Timeline struct {
+	EvidenceID  string          `json:"evidence_id"`
+	CaseNumber  string          `json:"case_number"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Events      []TimelineEvent `json:"events"`
+}
+
+// timelineFoldedActions are the audit actions whose events the timeline
+// already draws from the evidence record or its view sessions
+var timelineFoldedActions = map[string]bool{
+	"INGEST_EVIDENCE":    true,
+	"TRANSFER_CUSTODY":   true,
+	"VERIFY_INTEGRITY":   true,
+	"VIEW_SESSION_START": true,
+	"STREAM_RANGE":       true,
+}
+
+// GetTimeline merges the chain of custody, integrity checks, status changes,
+// view sessions and audit log of evidenceID into one chronological timeline.
+// Events at the same instant keep that order.
+func (bwc *BWCSystem) GetTimeline(evidenceID string) (*Timeline, error) {
+	return bwc.GetTimelineContext(systemContext(context.Background()), evidenceID)
+}
+
+// GetTimelineContext builds the timeline of evidenceID for the principal ctx
+// carries, refusing restricted evidence whose access list does not name them
+func (bwc *BWCSystem) GetTimelineContext(ctx context.Context, evidenceID string) (*Timeline, error) {
+	evidence, err := bwc.readableEvidence(ctx, evidenceID)
+	if err != nil {
+		return nil, err
+	}
+	bwc.mu.RLock()
+	ev := copyEvidence(evidence)
+	bwc.mu.RUnlock()
+
+	timeline := &Timeline{EvidenceID: ev.ID, CaseNumber: ev.CaseNumber, GeneratedAt: time.Now()}
+	for i := range ev.ChainOfCustody {
+		entry := ev.ChainOfCustody[i]
+		summary := fmt.Sprintf("%s to %s", entry.FromOfficer, entry.ToOfficer)
+		if entry.Purpose != "" {
+			summary += ": " + entry.Purpose
+		}
+		timeline.Events = append(timeline.Events, TimelineEvent{
+			Timestamp: entry.Timestamp, Source: TimelineCustody, Action: entry.Action,
+			Actor: entry.FromOfficer, Summary: summary, Custody: &entry,
+		})
+	}
+	for i := range ev.IntegrityChecks {
+		check := ev.IntegrityChecks[i]
+		action, summary := "INTEGRITY_VERIFIED", "Hash "+check.HashValue+" matches"
+		if !check.IsValid {
+			action, summary = "INTEGRITY_FAILED", "Hash "+check.HashValue+" does not match"
+		}
+		if check.Notes != "" {
+			summary += ": " + check.Notes
+		}
+		timeline.Events = append(timeline.Events, TimelineEvent{
+			Timestamp: check.Timestamp, Source: TimelineIntegrity, Action: action,
+			Actor: check.CheckedBy, Summary: summary, Integrity: &check,
+		})
+	}
+	for _, session := range bwc.ViewSessions(evidenceID) {
+		session := session
+		timeline.Events = append(timeline.Events, TimelineEvent{
+			Timestamp: session.StartedAt, Source: TimelineViewing, Action: "VIEW_SESSION",
+			Actor: session.UserID, IPAddress: session.IPAddress, View: &session,
+			Summary: fmt.Sprintf("View session %s: %d bytes in %d ranges, last activity %s",
+				session.ID, session.BytesServed, len(session.Ranges), session.LastActivity.Format(time.RFC3339)),
+		})
+	}
+	for _, log := range bwc.GetAuditLogs(evidenceID, "") {
+		if timelineFoldedActions[log.Action] {
+			continue
+		}
+		log := log
+		source := TimelineAudit
+		if log.Action == "UPDATE_STATUS" {
+			source = TimelineStatus
+		}
+		timeline.Events = append(timeline.Events, TimelineEvent{
+			Timestamp: log.Timestamp, Source: source, Action: log.Action,
+			Actor: log.UserID, Summary: log.Details, IPAddress: log.IPAddress, Audit: &log,
+		})
+	}
+
+	sort.SliceStable(timeline.Events, func(i, j int) bool {
+		return timeline.Events[i].Timestamp.Before(timeline.Events[j].Timestamp)
+	})
+	return timeline, nil
+}
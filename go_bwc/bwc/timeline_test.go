@@ -0,0 +1,107 @@
+package bwc
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGetTimeline(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TL-1", "OFF-1137", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1137", "DET-002", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	if err := system.UpdateStatus(ev.ID, "DET-002", StatusProcessing, "Under review"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	session, err := system.StartViewSession(ev.ID, "DET-002", "10.0.0.5")
+	if err != nil {
+		t.Fatalf("StartViewSession failed: %v", err)
+	}
+	if err := system.RecordServedRange(session.ID, 0, 9, "10.0.0.5"); err != nil {
+		t.Fatalf("RecordServedRange failed: %v", err)
+	}
+	if _, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil {
+		t.Fatalf("VerifyIntegrity failed: %v", err)
+	}
+	if err := system.ExportEvidence(ev.ID, filepath.Join(t.TempDir(), "export.json")); err != nil {
+		t.Fatalf("ExportEvidence failed: %v", err)
+	}
+
+	timeline, err := system.GetTimeline(ev.ID)
+	if err != nil {
+		t.Fatalf("GetTimeline failed: %v", err)
+	}
+	if timeline.EvidenceID != ev.ID || timeline.CaseNumber != "CASE-TL-1" {
+		t.Errorf("unexpected timeline header %+v", timeline)
+	}
+
+	var got []string
+	for i, e := range timeline.Events {
+		if i > 0 && e.Timestamp.Before(timeline.Events[i-1].Timestamp) {
+			t.Errorf("event %d is out of order", i)
+		}
+		got = append(got, string(e.Source)+" "+e.Action)
+	}
+	want := []string{
+		"CUSTODY INGESTED",
+		"INTEGRITY INTEGRITY_VERIFIED",
+		"CUSTODY TRANSFERRED",
+		"STATUS UPDATE_STATUS",
+		"VIEWING VIEW_SESSION",
+		"INTEGRITY INTEGRITY_VERIFIED",
+		"AUDIT EXPORT_EVIDENCE",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events\n%v\ngot\n%v", want, got)
+	}
+
+	transfer := timeline.Events[2]
+	if transfer.Custody == nil || transfer.Custody.ToOfficer != "DET-002" || transfer.Summary != "OFF-1137 to DET-002: Investigation" {
+		t.Errorf("unexpected custody event %+v", transfer)
+	}
+	status := timeline.Events[3]
+	if status.Audit == nil || status.Actor != "DET-002" || !strings.Contains(status.Summary, "COLLECTED to PROCESSING") {
+		t.Errorf("unexpected status event %+v", status)
+	}
+	view := timeline.Events[4]
+	if view.View == nil || view.View.BytesServed != 10 || view.IPAddress != "10.0.0.5" || !strings.Contains(view.Summary, "10 bytes in 1 ranges") {
+		t.Errorf("unexpected view event %+v", view)
+	}
+	if check := timeline.Events[5]; check.Integrity == nil || check.Actor != "AUDITOR-1" {
+		t.Errorf("unexpected integrity event %+v", check)
+	}
+
+	if _, err := system.GetTimeline("BWC-NONE"); err == nil {
+		t.Error("expected unknown evidence to be rejected")
+	}
+}
+
+func TestTimelineEndpoint(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	ev, _ := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TL-2", "OFF-1138", "Officer Test", "Patrol", nil)
+
+	resp := authGet(t, server, "/api/evidence/"+ev.ID+"/timeline")
+	var timeline Timeline
+	json.NewDecoder(resp.Body).Decode(&timeline)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || timeline.EvidenceID != ev.ID || len(timeline.Events) != 2 {
+		t.Errorf("unexpected timeline %d %+v", resp.StatusCode, timeline)
+	}
+
+	resp = authGet(t, server, "/api/evidence/BWC-NONE/timeline")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown evidence, got %d", resp.StatusCode)
+	}
+}
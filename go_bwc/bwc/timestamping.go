@@ -0,0 +1,474 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultTSATimeout bounds a timestamp request when timestamping.timeout_seconds is unset
+const defaultTSATimeout = 10 * time.Second
+
+// RFC 3161 object identifiers
+var (
+	oidTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidSHA384  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512  = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+)
+
+// HashTimestamp is an RFC 3161 timestamp token from a time-stamping
+// authority, proving a hash existed by Time on the authority's clock rather
+// than this system's
+type HashTimestamp struct {
+	Time         time.Time `json:"time"`
+	TSA          string    `json:"tsa"`
+	SerialNumber string    `json:"serial_number"`
+	// Token is the DER TimeStampToken, which openssl ts -verify also checks
+	Token []byte `json:"token"`
+}
+
+type tsaMessageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type tsaRequest struct {
+	Version        int
+	MessageImprint tsaMessageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+}
+
+type tsaStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional,utf8"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type tsaResponse struct {
+	Status tsaStatusInfo
+	Token  asn1.RawValue `asn1:"optional"`
+}
+
+type tsaAccuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+// tstInfo is the content a TSA signs; later optional fields are not read
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint tsaMessageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time   `asn1:"generalized"`
+	Accuracy       tsaAccuracy `asn1:"optional"`
+	Ordering       bool        `asn1:"optional,default:false"`
+	Nonce          *big.Int    `asn1:"optional"`
+}
+
+// tsaSignedData is CMS SignedData with its content encapsulated, as in a
+// timestamp token
+type tsaSignedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo tsaEncapsulatedContent
+	Certificates     asn1.RawValue   `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue   `asn1:"optional,tag:1"`
+	SignerInfos      []tsaSignerInfo `asn1:"set"`
+}
+
+type tsaEncapsulatedContent struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte `asn1:"explicit,tag:0"`
+}
+
+// tsaSignerInfo identifies its certificate by issuer and serial or, in
+// version 3, by subject key identifier
+type tsaSignerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+}
+
+// tsaDigests are the digest algorithms a token may be signed with
+var tsaDigests = []struct {
+	oid   asn1.ObjectIdentifier
+	hash  crypto.Hash
+	rsa   x509.SignatureAlgorithm
+	ecdsa x509.SignatureAlgorithm
+}{
+	{oidSHA256, crypto.SHA256, x509.SHA256WithRSA, x509.ECDSAWithSHA256},
+	{oidSHA384, crypto.SHA384, x509.SHA384WithRSA, x509.ECDSAWithSHA384},
+	{oidSHA512, crypto.SHA512, x509.SHA512WithRSA, x509.ECDSAWithSHA512},
+}
+
+// timestampAuthority requests RFC 3161 timestamps from a TSA over HTTP
+type timestampAuthority struct {
+	url    string
+	policy asn1.ObjectIdentifier
+	roots  *x509.CertPool
+	client *http.Client
+}
+
+// newTimestampAuthority returns a client for the TSA cfg names
+func newTimestampAuthority(cfg TimestampingConfig) (*timestampAuthority, error) {
+	tsa := &timestampAuthority{url: cfg.URL, client: &http.Client{Timeout: defaultTSATimeout}}
+	if cfg.TimeoutSeconds > 0 {
+		tsa.client.Timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+	if cfg.Policy != "" {
+		policy, err := parseOID(cfg.Policy)
+		if err != nil {
+			return nil, fmt.Errorf("timestamping.policy: %w", err)
+		}
+		tsa.policy = policy
+	}
+	if cfg.RootsFile != "" {
+		data, err := os.ReadFile(cfg.RootsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TSA roots: %w", err)
+		}
+		tsa.roots = x509.NewCertPool()
+		if !tsa.roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.RootsFile)
+		}
+	}
+	return tsa, nil
+}
+
+// parseOID parses a dotted object identifier such as 1.2.3.4
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	var oid asn1.ObjectIdentifier
+	for _, part := range strings.Split(s, ".") {
+		var n int
+		if _, err := fmt.Sscanf(part, "%d", &n); err != nil || n < 0 || fmt.Sprint(n) != part {
+			return nil, fmt.Errorf("%q is not a dotted object identifier", s)
+		}
+		oid = append(oid, n)
+	}
+	if len(oid) < 2 {
+		return nil, fmt.Errorf("%q is not a dotted object identifier", s)
+	}
+	return oid, nil
+}
+
+// timestamp asks the TSA to timestamp a hex SHA-256 hash and checks the
+// token it returns
+func (tsa *timestampAuthority) timestamp(hash string) (*HashTimestamp, error) {
+	digest, err := hex.DecodeString(hash)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hash: %w", err)
+	}
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return nil, err
+	}
+	body, err := asn1.Marshal(tsaRequest{
+		Version:        1,
+		MessageImprint: tsaMessageImprint{HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSHA256}, HashedMessage: digest},
+		ReqPolicy:      tsa.policy,
+		Nonce:          nonce,
+		CertReq:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tsa.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/timestamp-query")
+	resp, err := tsa.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("TSA request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA returned %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TSA response: %w", err)
+	}
+
+	var tsr tsaResponse
+	if _, err := asn1.Unmarshal(data, &tsr); err != nil {
+		return nil, fmt.Errorf("invalid TSA response: %w", err)
+	}
+	// 0 is granted and 1 granted with modifications
+	if tsr.Status.Status > 1 || len(tsr.Token.FullBytes) == 0 {
+		return nil, fmt.Errorf("TSA refused the request (status %d): %s", tsr.Status.Status, strings.Join(tsr.Status.StatusString, "; "))
+	}
+	stamp, info, err := verifyTimestampToken(tsr.Token.FullBytes, hash, tsa.roots)
+	if err != nil {
+		return nil, err
+	}
+	if info.Nonce == nil || info.Nonce.Cmp(nonce) != 0 {
+		return nil, errors.New("TSA response does not answer this request")
+	}
+	return stamp, nil
+}
+
+// VerifyHashTimestamp checks an RFC 3161 timestamp token over a hex SHA-256
+// hash: that the TSA signed it, for that hash, with a certificate for
+// timestamping. When roots is non-nil the certificate must chain to one of
+// them as of the time stamped.
+func VerifyHashTimestamp(token []byte, hash string, roots *x509.CertPool) (*HashTimestamp, error) {
+	stamp, _, err := verifyTimestampToken(token, hash, roots)
+	return stamp, err
+}
+
+func verifyTimestampToken(token []byte, hash string, roots *x509.CertPool) (*HashTimestamp, *tstInfo, error) {
+	var ci cmsContentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil || len(rest) > 0 {
+		return nil, nil, errors.New("timestamp token is not a DER-encoded CMS structure")
+	}
+	if !ci.ContentType.Equal(oidSignedData) || ci.Content.Class != asn1ClassContextTag || ci.Content.Tag != 0 {
+		return nil, nil, errors.New("timestamp token is not CMS signed data")
+	}
+	var sd tsaSignedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, nil, fmt.Errorf("invalid timestamp token: %w", err)
+	}
+	if !sd.EncapContentInfo.ContentType.Equal(oidTSTInfo) || len(sd.SignerInfos) != 1 {
+		return nil, nil, errors.New("timestamp token does not hold one signed TSTInfo")
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.Content, &info); err != nil {
+		return nil, nil, fmt.Errorf("invalid TSTInfo: %w", err)
+	}
+	digest, err := hex.DecodeString(hash)
+	if err != nil || !info.MessageImprint.HashAlgorithm.Algorithm.Equal(oidSHA256) || !bytes.Equal(info.MessageImprint.HashedMessage, digest) {
+		return nil, nil, errors.New("timestamp token is for a different hash")
+	}
+
+	si := sd.SignerInfos[0]
+	certs, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil || len(certs) == 0 {
+		return nil, nil, errors.New("timestamp token does not include the TSA certificate")
+	}
+	cert := tsaSignerCertificate(si.SID, certs)
+	if cert == nil {
+		return nil, nil, errors.New("TSA certificate is not included in the timestamp token")
+	}
+	if err := checkTSASignature(si, cert, sd.EncapContentInfo.Content); err != nil {
+		return nil, nil, err
+	}
+
+	stamping := false
+	for _, usage := range cert.ExtKeyUsage {
+		stamping = stamping || usage == x509.ExtKeyUsageTimeStamping
+	}
+	if !stamping {
+		return nil, nil, errors.New("TSA certificate is not for timestamping")
+	}
+	if roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, c := range certs {
+			if c != cert {
+				intermediates.AddCert(c)
+			}
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: intermediates,
+			CurrentTime:   info.GenTime,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		}
+		if _, err := cert.Verify(opts); err != nil {
+			return nil, nil, fmt.Errorf("TSA certificate is not trusted: %w", err)
+		}
+	}
+
+	return &HashTimestamp{
+		Time:         info.GenTime.UTC(),
+		TSA:          cert.Subject.String(),
+		SerialNumber: info.SerialNumber.String(),
+		Token:        append([]byte(nil), token...),
+	}, &info, nil
+}
+
+// tsaSignerCertificate finds the certificate a signer identifier names
+func tsaSignerCertificate(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	if sid.Class == asn1ClassContextTag && sid.Tag == 0 {
+		for _, c := range certs {
+			if bytes.Equal(c.SubjectKeyId, sid.Bytes) {
+				return c
+			}
+		}
+		return nil
+	}
+	var ias cmsIssuerAndSerial
+	if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+		return nil
+	}
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, ias.Issuer.FullBytes) && c.SerialNumber.Cmp(ias.Serial) == 0 {
+			return c
+		}
+	}
+	return nil
+}
+
+// checkTSASignature checks that the signed attributes carry the digest of
+// content and that cert signed them
+func checkTSASignature(si tsaSignerInfo, cert *x509.Certificate, content []byte) error {
+	var algorithm x509.SignatureAlgorithm
+	var hash crypto.Hash
+	for _, d := range tsaDigests {
+		if si.DigestAlgorithm.Algorithm.Equal(d.oid) {
+			hash = d.hash
+			switch cert.PublicKey.(type) {
+			case *rsa.PublicKey:
+				algorithm = d.rsa
+			case *ecdsa.PublicKey:
+				algorithm = d.ecdsa
+			}
+		}
+	}
+	if hash == 0 || algorithm == x509.UnknownSignatureAlgorithm {
+		return errors.New("timestamp token uses an unsupported signature algorithm")
+	}
+
+	var digest []byte
+	for rest := si.SignedAttrs.Bytes; len(rest) > 0; {
+		var attr cmsAttribute
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &attr); err != nil {
+			return fmt.Errorf("invalid signed attributes: %w", err)
+		}
+		if attr.Type.Equal(oidAttrDigest) {
+			if _, err := asn1.Unmarshal(attr.Values.Bytes, &digest); err != nil {
+				return fmt.Errorf("invalid message digest: %w", err)
+			}
+		}
+	}
+	h := hash.New()
+	h.Write(content)
+	if digest == nil || !bytes.Equal(digest, h.Sum(nil)) {
+		return errors.New("timestamp token content does not match its signature")
+	}
+
+	// The signature covers the attributes encoded as a SET
+	attrs, err := asn1.Marshal(asn1.RawValue{Tag: asn1TagSet, IsCompound: true, Bytes: si.SignedAttrs.Bytes})
+	if err != nil {
+		return err
+	}
+	if err := cert.CheckSignature(algorithm, attrs, si.Signature); err != nil {
+		return fmt.Errorf("timestamp token signature is invalid: %w", err)
+	}
+	return nil
+}
+
+// timestampCheck asks the TSA, when one is configured, to timestamp the hash
+// an integrity check found, then attaches the token to the check as recorded.
+// The TSA is asked without bwc.mu held, as geocoders and OCR are, so the
+// caller must not hold it. A TSA that fails does not hold up the check; the
+// failure is audited. It returns the evidence with the token attached, or nil
+// when none was.
+func (bwc *BWCSystem) timestampCheck(evidenceID string, check IntegrityCheck) *Evidence {
+	if bwc.tsa == nil {
+		return nil
+	}
+	stamp, err := bwc.tsa.timestamp(check.HashValue)
+	if err != nil {
+		bwc.logAudit("SYSTEM", "TIMESTAMP_FAILED", evidenceID, err.Error(), "")
+		return nil
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	// The record may have moved on while the TSA was asked; a check that is
+	// gone, or is inside a seal made since, is left as it is
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil
+	}
+	checks := evidence.IntegrityChecks
+	if evidence.Seal != nil {
+		checks = evidence.SealedChecks
+	}
+	for i := range checks {
+		recorded := &checks[i]
+		if !recorded.Timestamp.Equal(check.Timestamp) || recorded.HashValue != check.HashValue || recorded.TrustedTimestamp != nil {
+			continue
+		}
+		// The token completes the check rather than changing the record, so
+		// the revision a caller was handed still holds
+		previous := copyEvidence(evidence)
+		recorded.TrustedTimestamp = stamp
+		if err := bwc.saveLocked(evidence); err != nil {
+			*evidence = previous
+			bwc.logAudit("SYSTEM", "TIMESTAMP_FAILED", evidenceID, err.Error(), "")
+			return nil
+		}
+		return evidence
+	}
+	return nil
+}
+
+// TimestampVerification is the outcome of checking one integrity check's
+// timestamp token
+type TimestampVerification struct {
+	// Check indexes the record's integrity checks
+	Check     int       `json:"check"`
+	HashValue string    `json:"hash_value"`
+	Time      time.Time `json:"time"`
+	TSA       string    `json:"tsa"`
+	Valid     bool      `json:"valid"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// VerifyTimestamps checks the timestamp token of every integrity check of
+// the evidence that has one, against the configured TSA roots
+func (bwc *BWCSystem) VerifyTimestamps(evidenceID string) ([]TimestampVerification, error) {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	var roots *x509.CertPool
+	if bwc.tsa != nil {
+		roots = bwc.tsa.roots
+	}
+
+	results := make([]TimestampVerification, 0)
+	for i, check := range evidence.IntegrityChecks {
+		if check.TrustedTimestamp == nil {
+			continue
+		}
+		result := TimestampVerification{Check: i, HashValue: check.HashValue}
+		stamp, err := VerifyHashTimestamp(check.TrustedTimestamp.Token, check.HashValue, roots)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Valid, result.Time, result.TSA = true, stamp.Time, stamp.TSA
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,218 @@
+package bwc
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTSA answers RFC 3161 requests with tokens signed by a self-signed
+// timestamping certificate, whose pool it returns
+func fakeTSA(t *testing.T, genTime time.Time) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(7),
+		Subject:      pkix.Name{CommonName: "Test TSA"},
+		NotBefore:    genTime.Add(-time.Hour),
+		NotAfter:     genTime.Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}
+	der, _ := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	cert, _ := x509.ParseCertificate(der)
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var req tsaRequest
+		if _, err := asn1.Unmarshal(body, &req); err != nil || r.Header.Get("Content-Type") != "application/timestamp-query" {
+			t.Errorf("invalid timestamp request: %v", err)
+		}
+		info, _ := asn1.Marshal(tstInfo{
+			Version:        1,
+			Policy:         asn1.ObjectIdentifier{1, 2, 3, 4},
+			MessageImprint: req.MessageImprint,
+			SerialNumber:   big.NewInt(42),
+			GenTime:        genTime,
+			Nonce:          req.Nonce,
+		})
+
+		infoDigest := sha256.Sum256(info)
+		var attrs [][]byte
+		for _, a := range []struct {
+			oid   asn1.ObjectIdentifier
+			value interface{}
+		}{{oidAttrContentType, oidTSTInfo}, {oidAttrDigest, infoDigest[:]}} {
+			value, _ := asn1.Marshal(a.value)
+			attr, _ := asn1.Marshal(cmsAttribute{Type: a.oid, Values: asn1.RawValue{Tag: asn1TagSet, IsCompound: true, Bytes: value}})
+			attrs = append(attrs, attr)
+		}
+		sort.Slice(attrs, func(i, j int) bool { return bytes.Compare(attrs[i], attrs[j]) < 0 })
+		signedAttrs := bytes.Join(attrs, nil)
+		set, _ := asn1.Marshal(asn1.RawValue{Tag: asn1TagSet, IsCompound: true, Bytes: signedAttrs})
+		setDigest := sha256.Sum256(set)
+		signature, _ := ecdsa.SignASN1(rand.Reader, key, setDigest[:])
+
+		content, _ := asn1.Marshal(info)
+		sid, _ := asn1.Marshal(cmsIssuerAndSerial{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, Serial: cert.SerialNumber})
+		signedData, _ := asn1.Marshal(struct {
+			Version          int
+			DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+			EncapContentInfo struct {
+				ContentType asn1.ObjectIdentifier
+				Content     asn1.RawValue
+			}
+			Certificates asn1.RawValue
+			SignerInfos  []tsaSignerInfo `asn1:"set"`
+		}{
+			Version:          3,
+			DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidSHA256}},
+			EncapContentInfo: struct {
+				ContentType asn1.ObjectIdentifier
+				Content     asn1.RawValue
+			}{oidTSTInfo, asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: content}},
+			Certificates: asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: der},
+			SignerInfos: []tsaSignerInfo{{
+				Version:            1,
+				SID:                asn1.RawValue{FullBytes: sid},
+				DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: oidSHA256},
+				SignedAttrs:        asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: signedAttrs},
+				SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidECDSAWithSHA256},
+				Signature:          signature,
+			}},
+		})
+		token, _ := asn1.Marshal(cmsContentInfo{
+			ContentType: oidSignedData,
+			Content:     asn1.RawValue{Class: asn1ClassContextTag, Tag: 0, IsCompound: true, Bytes: signedData},
+		})
+		resp, _ := asn1.Marshal(tsaResponse{Status: tsaStatusInfo{Status: 0}, Token: asn1.RawValue{FullBytes: token}})
+		w.Header().Set("Content-Type", "application/timestamp-reply")
+		w.Write(resp)
+	}))
+	return server, roots
+}
+
+func TestIntegrityChecksAreTimestamped(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	genTime := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	server, roots := fakeTSA(t, genTime)
+	defer server.Close()
+	system.tsa, _ = newTimestampAuthority(TimestampingConfig{URL: server.URL})
+	system.tsa.roots = roots
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TSA-001", "OFF-1500", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	stamp := evidence.IntegrityChecks[0].TrustedTimestamp
+	if stamp == nil || !stamp.Time.Equal(genTime) || stamp.TSA != "CN=Test TSA" || stamp.SerialNumber != "42" {
+		t.Fatalf("expected the ingest hash to be timestamped, got %+v", stamp)
+	}
+	if _, err := VerifyHashTimestamp(stamp.Token, evidence.FileHash, roots); err != nil {
+		t.Errorf("expected the token to verify: %v", err)
+	}
+	if _, err := VerifyHashTimestamp(stamp.Token, strings.Repeat("0", 64), roots); err == nil {
+		t.Error("expected the token not to verify for another hash")
+	}
+	if _, err := VerifyHashTimestamp(stamp.Token, evidence.FileHash, x509.NewCertPool()); err == nil {
+		t.Error("expected a TSA outside the roots to be refused")
+	}
+	tampered := append([]byte(nil), stamp.Token...)
+	tampered[bytes.Index(tampered, []byte{0x18, 0x0f})+5] ^= 1 // a digit of genTime
+	if _, err := VerifyHashTimestamp(tampered, evidence.FileHash, roots); err == nil {
+		t.Error("expected an altered token to be refused")
+	}
+
+	if ok, err := system.VerifyIntegrity(evidence.ID, "AUDITOR"); err != nil || !ok {
+		t.Fatalf("VerifyIntegrity failed: %v %v", ok, err)
+	}
+	results, err := system.VerifyTimestamps(evidence.ID)
+	if err != nil || len(results) != 2 || !results[0].Valid || !results[1].Valid || results[1].Check != 1 {
+		t.Errorf("expected both checks' timestamps to verify, got %+v %v", results, err)
+	}
+}
+
+func TestTimestampFailureDoesNotBlockIngest(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	system.tsa, _ = newTimestampAuthority(TimestampingConfig{URL: server.URL})
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TSA-002", "OFF-1501", "", "", nil)
+	if err != nil {
+		t.Fatalf("expected ingest to succeed without the TSA, got %v", err)
+	}
+	if evidence.IntegrityChecks[0].TrustedTimestamp != nil {
+		t.Error("expected no timestamp")
+	}
+	failed := false
+	for _, log := range system.GetAuditLogs(evidence.ID, "SYSTEM") {
+		failed = failed || (log.Action == "TIMESTAMP_FAILED" && strings.Contains(log.Details, "503"))
+	}
+	if !failed {
+		t.Error("expected the TSA failure to be audited")
+	}
+}
+
+func TestTimestampAuthorityIsAskedWithoutTheLock(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	server, roots := fakeTSA(t, time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC))
+	defer server.Close()
+	stamp := server.Config.Handler
+	requests, locked := 0, 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if system.mu.TryLock() {
+			system.mu.Unlock()
+		} else {
+			locked++
+		}
+		stamp.ServeHTTP(w, r)
+	})
+	system.tsa, _ = newTimestampAuthority(TimestampingConfig{URL: server.URL})
+	system.tsa.roots = roots
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-TSA-003", "OFF-1502", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if ok, err := system.VerifyIntegrity(evidence.ID, "AUDITOR"); err != nil || !ok {
+		t.Fatalf("VerifyIntegrity failed: %v %v", ok, err)
+	}
+	if requests != 2 || locked != 0 {
+		t.Errorf("expected both requests to be made with the lock free, got %d request(s), %d under the lock", requests, locked)
+	}
+	got, _ := system.GetEvidence(evidence.ID)
+	if got.IntegrityChecks[0].TrustedTimestamp == nil || got.IntegrityChecks[1].TrustedTimestamp == nil {
+		t.Errorf("expected both checks to keep their tokens, got %+v", got.IntegrityChecks)
+	}
+}
+
+func TestTimestampingConfigValidation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Timestamping = TimestampingConfig{URL: "tsa.example.com", Policy: "1.x.3"}
+	err := cfg.Validate()
+	if err == nil || !strings.Contains(err.Error(), "timestamping.url") || !strings.Contains(err.Error(), "timestamping.policy") {
+		t.Errorf("expected the URL and policy to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package bwc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	ansiClear = "\033[H\033[2J"
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiReset = "\033[0m"
+)
+
+// tuiViews are the panes selectable by number
+var tuiViews = []string{"overview", "queues", "pending", "alerts", "retention"}
+
+// custodianTUI is a keyboard-driven terminal interface for property-room staff
+type custodianTUI struct {
+	system          *BWCSystem
+	officerID       string
+	retentionWindow time.Duration
+	in              *bufio.Scanner
+	out             io.Writer
+	ansi            bool
+
+	view    string
+	detail  string
+	message string
+}
+
+// runTUI runs the custodian interface until the user quits or input ends
+func runTUI(system *BWCSystem, officerID string, retentionWindow time.Duration, in io.Reader, out io.Writer, ansi bool) error {
+	t := &custodianTUI{
+		system:          system,
+		officerID:       officerID,
+		retentionWindow: retentionWindow,
+		in:              bufio.NewScanner(in),
+		out:             out,
+		ansi:            ansi,
+		view:            "overview",
+	}
+
+	for {
+		t.render()
+
+		if !t.in.Scan() {
+			return t.in.Err()
+		}
+
+		if quit := t.handle(strings.Fields(t.in.Text())); quit {
+			return nil
+		}
+	}
+}
+
+// handle executes one command line and reports whether the user asked to quit
+func (t *custodianTUI) handle(fields []string) bool {
+	t.message = ""
+	if len(fields) == 0 {
+		return false
+	}
+
+	cmd, args := fields[0], fields[1:]
+	switch cmd {
+	case "q", "quit":
+		return true
+	case "r", "refresh":
+	case "o":
+		t.view = "overview"
+	case "1", "2", "3", "4":
+		t.view = tuiViews[int(cmd[0]-'0')]
+	case "i":
+		if len(args) != 1 {
+			t.message = "usage: i <evidence-id>"
+			break
+		}
+		if _, err := t.system.GetEvidence(args[0]); err != nil {
+			t.message = "Error: " + err.Error()
+			break
+		}
+		t.view = "detail"
+		t.detail = args[0]
+	case "a":
+		if len(args) != 1 {
+			t.message = "usage: a <request-id>"
+			break
+		}
+		if err := t.system.AcceptCustodyTransfer(args[0], t.officerID); err != nil {
+			t.message = "Error: " + err.Error()
+			break
+		}
+		t.message = fmt.Sprintf("Accepted custody transfer %s", args[0])
+	case "d":
+		if len(args) < 2 {
+			t.message = "usage: d <request-id> <reason>"
+			break
+		}
+		if err := t.system.DeclineCustodyTransfer(args[0], t.officerID, strings.Join(args[1:], " ")); err != nil {
+			t.message = "Error: " + err.Error()
+			break
+		}
+		t.message = fmt.Sprintf("Declined custody transfer %s", args[0])
+	case "v":
+		if len(args) != 1 {
+			t.message = "usage: v <evidence-id>"
+			break
+		}
+		valid, err := t.system.VerifyIntegrity(args[0], t.officerID)
+		if err != nil {
+			t.message = "Error: " + err.Error()
+			break
+		}
+		if valid {
+			t.message = fmt.Sprintf("Integrity check PASSED for %s", args[0])
+		} else {
+			t.message = fmt.Sprintf("Integrity check FAILED for %s", args[0])
+		}
+	case "s":
+		if len(args) < 2 {
+			t.message = "usage: s <evidence-id> <STATUS> [notes]"
+			break
+		}
+		status := EvidenceStatus(strings.ToUpper(args[1]))
+		if err := t.system.UpdateStatus(args[0], t.officerID, status, strings.Join(args[2:], " ")); err != nil {
+			t.message = "Error: " + err.Error()
+			break
+		}
+		t.message = fmt.Sprintf("Status of %s set to %s", args[0], status)
+	case "mt":
+		if len(args) < 2 {
+			t.message = "usage: mt <into-tag> <tag>..."
+			break
+		}
+		plan, err := t.system.MergeTags(args[1:], args[0], t.officerID, false)
+		if err != nil {
+			t.message = "Error: " + err.Error()
+			break
+		}
+		t.message = fmt.Sprintf("Merged tags into %s on %d items, %d sealed items skipped", args[0], len(plan.Items), len(plan.Skipped))
+	case "?", "h", "help":
+		t.view = "help"
+	default:
+		t.message = fmt.Sprintf("Unknown command %q - type ? for help", cmd)
+	}
+
+	return false
+}
+
+func (t *custodianTUI) render() {
+	if t.ansi {
+		fmt.Fprint(t.out, ansiClear)
+	}
+
+	d := t.system.BuildDashboard(t.officerID, t.retentionWindow)
+
+	t.heading(fmt.Sprintf("BWC Evidence Custodian - %s - %s", t.officerID, d.GeneratedAt.Format("2006-01-02 15:04:05")))
+	fmt.Fprintf(t.out, "[1] Queues  [2] Pending acceptances (%d)  [3] Integrity alerts (%d)  [4] Retention (%d)\n\n",
+		len(d.PendingRequests), len(d.IntegrityAlerts), len(d.RetentionItems))
+
+	switch t.view {
+	case "queues":
+		t.renderQueues(d, 0)
+	case "pending":
+		t.renderPending(d)
+	case "alerts":
+		t.renderAlerts(d)
+	case "retention":
+		t.renderRetention(d)
+	case "detail":
+		t.renderDetail()
+	case "help":
+		t.renderHelp()
+	default:
+		t.renderQueues(d, 3)
+		t.renderPending(d)
+		t.renderAlerts(d)
+		t.renderRetention(d)
+	}
+
+	if t.message != "" {
+		fmt.Fprintf(t.out, "\n%s\n", t.message)
+	}
+	fmt.Fprint(t.out, "\n> ")
+}
+
+func (t *custodianTUI) heading(text string) {
+	if t.ansi {
+		fmt.Fprintf(t.out, "%s%s%s\n", ansiBold, text, ansiReset)
+		return
+	}
+	fmt.Fprintln(t.out, text)
+}
+
+// renderQueues lists evidence per status; limit caps items per queue when positive
+func (t *custodianTUI) renderQueues(d *Dashboard, limit int) {
+	t.heading("Evidence Queues")
+	for _, q := range d.Queues {
+		fmt.Fprintf(t.out, "  %-11s %d\n", q.Status, len(q.Items))
+		for i, ev := range q.Items {
+			if limit > 0 && i >= limit {
+				fmt.Fprintf(t.out, "      ... %d more\n", len(q.Items)-limit)
+				break
+			}
+			fmt.Fprintf(t.out, "      %s  %s  %s\n", ev.ID, ev.CaseNumber, ev.OfficerName)
+		}
+	}
+	fmt.Fprintln(t.out)
+}
+
+func (t *custodianTUI) renderPending(d *Dashboard) {
+	t.heading("Pending Custody Acceptances")
+	if len(d.PendingRequests) == 0 {
+		fmt.Fprintln(t.out, "  none")
+	}
+	for _, req := range d.PendingRequests {
+		fmt.Fprintf(t.out, "  %s  %s  from %s  %s  (%s)\n", req.ID, req.EvidenceID, req.FromOfficer,
+			req.Purpose, req.RequestedAt.Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintln(t.out)
+}
+
+func (t *custodianTUI) renderAlerts(d *Dashboard) {
+	t.heading("Integrity Alerts")
+	if len(d.IntegrityAlerts) == 0 {
+		fmt.Fprintln(t.out, "  none")
+	}
+	for _, alert := range d.IntegrityAlerts {
+		line := fmt.Sprintf("  %s  %s  checked by %s at %s", alert.EvidenceID, alert.CaseNumber,
+			alert.CheckedBy, alert.CheckedAt.Format("2006-01-02 15:04"))
+		if t.ansi {
+			line = ansiRed + line + ansiReset
+		}
+		fmt.Fprintln(t.out, line)
+	}
+	fmt.Fprintln(t.out)
+}
+
+func (t *custodianTUI) renderRetention(d *Dashboard) {
+	t.heading(fmt.Sprintf("Retention Due (next %d days)", int(t.retentionWindow.Hours()/24)))
+	if len(d.RetentionItems) == 0 {
+		fmt.Fprintln(t.out, "  none")
+	}
+	for _, item := range d.RetentionItems {
+		fmt.Fprintf(t.out, "  %s  %s  expires %s (%d days)\n", item.EvidenceID, item.CaseNumber,
+			item.ExpiresAt.Format("2006-01-02"), item.DaysRemaining)
+	}
+	fmt.Fprintln(t.out)
+}
+
+func (t *custodianTUI) renderDetail() {
+	evidence, err := t.system.GetEvidence(t.detail)
+	if err != nil {
+		fmt.Fprintf(t.out, "Error: %v\n", err)
+		return
+	}
+
+	t.heading("Evidence " + evidence.ID)
+	fmt.Fprintf(t.out, "  Case:     %s\n", evidence.CaseNumber)
+	fmt.Fprintf(t.out, "  Officer:  %s (%s)\n", evidence.OfficerName, evidence.OfficerID)
+	fmt.Fprintf(t.out, "  Location: %s\n", evidence.Location)
+	fmt.Fprintf(t.out, "  Status:   %s\n", evidence.Status)
+	if evidence.Seal != nil {
+		fmt.Fprintf(t.out, "  SEALED:   %s (%s)\n", evidence.Seal.Authority, evidence.Seal.SealedAt.Format("2006-01-02 15:04"))
+	}
+	fmt.Fprintf(t.out, "  Hash:     %s\n", evidence.FileHash)
+	fmt.Fprintf(t.out, "  Tags:     %s\n\n", strings.Join(evidence.Tags, ", "))
+
+	t.heading("Chain of Custody")
+	for i, entry := range evidence.ChainOfCustody {
+		fmt.Fprintf(t.out, "  [%d] %s: %s -> %s (%s) %s\n", i+1, entry.Timestamp.Format("2006-01-02 15:04:05"),
+			entry.FromOfficer, entry.ToOfficer, entry.Action, entry.Purpose)
+	}
+	fmt.Fprintln(t.out)
+}
+
+func (t *custodianTUI) renderHelp() {
+	t.heading("Commands")
+	fmt.Fprintln(t.out, "  o | 1-4              overview or pane")
+	fmt.Fprintln(t.out, "  i <evidence-id>      show evidence detail and custody chain")
+	fmt.Fprintln(t.out, "  a <request-id>       accept a custody transfer")
+	fmt.Fprintln(t.out, "  d <request-id> why   decline a custody transfer")
+	fmt.Fprintln(t.out, "  v <evidence-id>      verify integrity")
+	fmt.Fprintln(t.out, "  s <evidence-id> ST   update status (COLLECTED, PROCESSING, ANALYZED, ARCHIVED)")
+	fmt.Fprintln(t.out, "  mt <into> <tag>...   merge tags into one across all evidence")
+	fmt.Fprintln(t.out, "  r                    refresh")
+	fmt.Fprintln(t.out, "  q                    quit")
+	fmt.Fprintln(t.out)
+}
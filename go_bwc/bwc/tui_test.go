@@ -0,0 +1,73 @@
+package bwc
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTUIAcceptTransfer(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+
+	evidence, _ := system.IngestEvidence(testFile, "CASE-TUI-001", "OFF-123", "Officer Test", "Test Location", nil)
+	req, _ := system.RequestCustodyTransfer(evidence.ID, "OFF-123", "CUS-001", "Property room intake")
+
+	input := strings.Join([]string{
+		"2",
+		"a " + req.ID,
+		"v " + evidence.ID,
+		"i " + evidence.ID,
+		"q",
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := runTUI(system, "CUS-001", 30*24*time.Hour, strings.NewReader(input), &out, false); err != nil {
+		t.Fatalf("runTUI failed: %v", err)
+	}
+
+	output := out.String()
+
+	for _, want := range []string{
+		"Pending Custody Acceptances",
+		req.ID,
+		"Accepted custody transfer " + req.ID,
+		"Integrity check PASSED",
+		"Chain of Custody",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q", want)
+		}
+	}
+
+	if strings.Contains(output, ansiClear) {
+		t.Error("Expected no ANSI escape codes in plain mode")
+	}
+
+	custody, _ := system.GetChainOfCustody(evidence.ID)
+	if custody[len(custody)-1].ToOfficer != "CUS-001" {
+		t.Error("Expected custody to be transferred through the TUI")
+	}
+}
+
+func TestTUIReportsErrors(t *testing.T) {
+	system, _, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	var out bytes.Buffer
+	input := "a CTR-404\nbogus\n"
+	if err := runTUI(system, "CUS-001", 0, strings.NewReader(input), &out, false); err != nil {
+		t.Fatalf("runTUI failed: %v", err)
+	}
+
+	if !strings.Contains(out.String(), "custody request not found") {
+		t.Error("Expected error message for unknown request")
+	}
+
+	if !strings.Contains(out.String(), `Unknown command "bogus"`) {
+		t.Error("Expected unknown command message")
+	}
+}
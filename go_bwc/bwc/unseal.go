@@ -0,0 +1,200 @@
+package bwc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UnsealRequest asks to lift a seal; it takes effect once a second person approves it
+type UnsealRequest struct {
+	ID          string               `json:"id"`
+	EvidenceID  string               `json:"evidence_id"`
+	RequestedBy string               `json:"requested_by"`
+	Authority   string               `json:"authority"`
+	Status      CustodyRequestStatus `json:"status"`
+	RequestedAt time.Time            `json:"requested_at"`
+	ResolvedBy  string               `json:"resolved_by"`
+	ResolvedAt  time.Time            `json:"resolved_at"`
+	Resolution  string               `json:"resolution"`
+}
+
+// unsealPayload is the message an UNSEALED history entry's signature covers
+func unsealPayload(evidenceID string, event *SealEvent) []byte {
+	return []byte(strings.Join([]string{
+		"BWC-UNSEAL-v1",
+		evidenceID,
+		event.Authority,
+		event.RequestedBy,
+		event.ApprovedBy,
+		event.Timestamp.UTC().Format(time.RFC3339Nano),
+		event.StateHash,
+	}, "\n"))
+}
+
+// RequestUnseal records a request to unseal evidence under authority, the legal
+// reference (e.g. a court order) that permits it
+func (bwc *BWCSystem) RequestUnseal(evidenceID, requestedBy, authority string) (*UnsealRequest, error) {
+	if err := bwc.authorize(requestedBy, PermSeal, "Unseal request", evidenceID); err != nil {
+		return nil, err
+	}
+	authority = strings.TrimSpace(authority)
+	if authority == "" {
+		return nil, errors.New("legal authority reference is required to unseal evidence")
+	}
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	evidence := bwc.evidenceDB.Get(evidenceID)
+	if evidence == nil {
+		return nil, ErrEvidenceNotFound
+	}
+	if evidence.Seal == nil {
+		return nil, errors.New("evidence is not sealed")
+	}
+
+	for _, req := range bwc.unsealRequests {
+		if req.EvidenceID == evidenceID && req.Status == RequestPending {
+			return nil, fmt.Errorf("unseal request %s is already pending for this evidence", req.ID)
+		}
+	}
+
+	bwc.unsealRequestSeq++
+	req := &UnsealRequest{
+		ID:          fmt.Sprintf("USR-%06d", bwc.unsealRequestSeq),
+		EvidenceID:  evidenceID,
+		RequestedBy: requestedBy,
+		Authority:   authority,
+		Status:      RequestPending,
+		RequestedAt: time.Now(),
+	}
+	bwc.unsealRequests[req.ID] = req
+
+	bwc.logAudit(requestedBy, "REQUEST_UNSEAL", evidenceID,
+		fmt.Sprintf("Unseal %s requested under %s", req.ID, authority), "")
+
+	r := *req
+	return &r, nil
+}
+
+// ApproveUnseal lifts the seal for a pending request. The approver must not be
+// the requester. The unseal is signed and kept in the evidence's seal history.
+func (bwc *BWCSystem) ApproveUnseal(requestID, approverID string) error {
+	if err := bwc.authorize(approverID, PermSeal, "Unseal approval", ""); err != nil {
+		return err
+	}
+	if err := bwc.beginOperation(opMutation); err != nil {
+		return err
+	}
+	defer bwc.endOperation()
+
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	req, err := bwc.pendingUnsealFor(requestID, approverID)
+	if err != nil {
+		return err
+	}
+
+	evidence := bwc.evidenceDB.Get(req.EvidenceID)
+	if evidence == nil {
+		return ErrEvidenceNotFound
+	}
+	if evidence.Seal == nil {
+		return errors.New("evidence is not sealed")
+	}
+
+	stateHash, err := sealStateHash(evidence, evidence.Seal.Version)
+	if err != nil {
+		return err
+	}
+	if stateHash != evidence.Seal.StateHash {
+		return errors.New("sealed record does not match its signed state")
+	}
+
+	event := SealEvent{
+		Action:      "UNSEALED",
+		Authority:   req.Authority,
+		RequestedBy: req.RequestedBy,
+		ApprovedBy:  approverID,
+		Timestamp:   time.Now().UTC(),
+		StateHash:   stateHash,
+		KeyID:       bwc.sealer.keyID,
+	}
+	event.Signature = bwc.sealer.sign(unsealPayload(evidence.ID, &event))
+
+	evidence.SealHistory = append(evidence.SealHistory, event)
+	evidence.Seal = nil
+	if err := bwc.saveLocked(evidence); err != nil {
+		return err
+	}
+
+	req.Status = RequestAccepted
+	req.ResolvedBy = approverID
+	req.ResolvedAt = event.Timestamp
+
+	bwc.logAudit(approverID, "UNSEAL_EVIDENCE", evidence.ID,
+		fmt.Sprintf("Unseal %s requested by %s approved under %s", req.ID, req.RequestedBy, req.Authority), "")
+
+	return nil
+}
+
+// DeclineUnseal rejects a pending request; the evidence stays sealed
+func (bwc *BWCSystem) DeclineUnseal(requestID, approverID, reason string) error {
+	if err := bwc.authorize(approverID, PermSeal, "Unseal decline", ""); err != nil {
+		return err
+	}
+	bwc.mu.Lock()
+	defer bwc.mu.Unlock()
+
+	req, err := bwc.pendingUnsealFor(requestID, approverID)
+	if err != nil {
+		return err
+	}
+
+	req.Status = RequestDeclined
+	req.ResolvedBy = approverID
+	req.ResolvedAt = time.Now()
+	req.Resolution = reason
+
+	bwc.logAudit(approverID, "DECLINE_UNSEAL", req.EvidenceID,
+		fmt.Sprintf("Unseal %s requested by %s declined - %s", req.ID, req.RequestedBy, reason), "")
+
+	return nil
+}
+
+// PendingUnsealRequests lists unseal requests awaiting a second approver
+func (bwc *BWCSystem) PendingUnsealRequests() []UnsealRequest {
+	bwc.mu.RLock()
+	defer bwc.mu.RUnlock()
+
+	results := make([]UnsealRequest, 0)
+	for _, req := range bwc.unsealRequests {
+		if req.Status == RequestPending {
+			results = append(results, *req)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].ID < results[j].ID
+	})
+	return results
+}
+
+// pendingUnsealFor looks up a pending unseal request that approverID may resolve;
+// the caller must hold bwc.mu
+func (bwc *BWCSystem) pendingUnsealFor(requestID, approverID string) (*UnsealRequest, error) {
+	req, exists := bwc.unsealRequests[requestID]
+	if !exists {
+		return nil, errors.New("unseal request not found")
+	}
+	if req.Status != RequestPending {
+		return nil, fmt.Errorf("unseal request is already %s", req.Status)
+	}
+	if approverID == "" || approverID == req.RequestedBy {
+		return nil, errors.New("an unseal request must be resolved by a second approver")
+	}
+	return req, nil
+}
@@ -0,0 +1,120 @@
+package bwc
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestUnsealRequiresSecondApprover(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-UNSEAL-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	if _, err := system.RequestUnseal(evidence.ID, "SUP-001", "Court Order 2024-CR-0100"); err == nil {
+		t.Error("Expected error requesting unseal of unsealed evidence")
+	}
+
+	system.SealEvidence(evidence.ID, "Court Order 2024-CR-0042")
+
+	if _, err := system.RequestUnseal(evidence.ID, "SUP-001", ""); err == nil {
+		t.Error("Expected error requesting unseal without an authority reference")
+	}
+
+	req, err := system.RequestUnseal(evidence.ID, "SUP-001", "Court Order 2024-CR-0100")
+	if err != nil {
+		t.Fatalf("RequestUnseal failed: %v", err)
+	}
+	if _, err := system.RequestUnseal(evidence.ID, "SUP-002", "Court Order 2024-CR-0100"); err == nil {
+		t.Error("Expected error for second pending unseal request")
+	}
+	if pending := system.PendingUnsealRequests(); len(pending) != 1 || pending[0].ID != req.ID {
+		t.Errorf("Expected one pending unseal request, got %v", pending)
+	}
+
+	if err := system.ApproveUnseal(req.ID, "SUP-001"); err == nil {
+		t.Error("Expected requester to be refused as approver")
+	}
+	if err := system.ApproveUnseal(req.ID, "SUP-002"); err != nil {
+		t.Fatalf("ApproveUnseal failed: %v", err)
+	}
+	if err := system.ApproveUnseal(req.ID, "SUP-003"); err == nil {
+		t.Error("Expected error approving a resolved request")
+	}
+
+	if err := system.UpdateStatus(evidence.ID, "OFF-123", StatusProcessing, "Resumed"); err != nil {
+		t.Errorf("Expected normal operations after unseal: %v", err)
+	}
+
+	got, _ := system.GetEvidence(evidence.ID)
+	if got.Seal != nil {
+		t.Error("Expected seal to be lifted")
+	}
+	if len(got.SealHistory) != 2 || got.SealHistory[0].Action != "SEALED" || got.SealHistory[1].Action != "UNSEALED" {
+		t.Fatalf("Unexpected seal history: %+v", got.SealHistory)
+	}
+	unseal := got.SealHistory[1]
+	if unseal.Authority != "Court Order 2024-CR-0100" || unseal.RequestedBy != "SUP-001" || unseal.ApprovedBy != "SUP-002" {
+		t.Errorf("Unexpected unseal entry: %+v", unseal)
+	}
+	signature, _ := base64.StdEncoding.DecodeString(unseal.Signature)
+	if !ed25519.Verify(system.SealPublicKey(), unsealPayload(evidence.ID, &unseal), signature) {
+		t.Error("Expected unseal entry to carry a valid signature")
+	}
+
+	logs := system.GetAuditLogs(evidence.ID, "SUP-002")
+	if len(logs) != 1 || logs[0].Action != "UNSEAL_EVIDENCE" {
+		t.Errorf("Expected UNSEAL_EVIDENCE audit entry, got %v", logs)
+	}
+}
+
+func TestSealHistoryIsKeptAcrossReseal(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-UNSEAL-002", "OFF-124", "Officer Test", "Test Location", nil)
+
+	system.SealEvidence(evidence.ID, "Court Order 2024-CR-0043")
+	req, _ := system.RequestUnseal(evidence.ID, "SUP-001", "Court Order 2024-CR-0101")
+	system.ApproveUnseal(req.ID, "SUP-002")
+	if _, err := system.SealEvidence(evidence.ID, "Court Order 2024-CR-0102"); err != nil {
+		t.Fatalf("Reseal failed: %v", err)
+	}
+
+	if valid, err := system.VerifySeal(evidence.ID); err != nil || !valid {
+		t.Errorf("Expected valid seal after reseal, got %v, %v", valid, err)
+	}
+
+	report, _ := system.GenerateReport("CASE-UNSEAL-002")
+	if !strings.Contains(report, "Seal history (3)") || strings.Count(report, "Court Order 2024-CR-01") != 3 {
+		t.Errorf("Expected full seal history in report:\n%s", report)
+	}
+}
+
+func TestDeclineUnseal(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-UNSEAL-003", "OFF-125", "Officer Test", "Test Location", nil)
+	system.SealEvidence(evidence.ID, "Court Order 2024-CR-0044")
+	req, _ := system.RequestUnseal(evidence.ID, "SUP-001", "Court Order 2024-CR-0103")
+
+	if err := system.DeclineUnseal(req.ID, "SUP-001", "Self review"); err == nil {
+		t.Error("Expected requester to be refused as approver")
+	}
+	if err := system.DeclineUnseal(req.ID, "SUP-002", "Order not yet filed"); err != nil {
+		t.Fatalf("DeclineUnseal failed: %v", err)
+	}
+
+	if valid, err := system.VerifySeal(evidence.ID); err != nil || !valid {
+		t.Errorf("Expected evidence to remain sealed, got %v, %v", valid, err)
+	}
+	if pending := system.PendingUnsealRequests(); len(pending) != 0 {
+		t.Errorf("Expected no pending unseal requests, got %v", pending)
+	}
+}
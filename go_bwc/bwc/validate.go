@@ -0,0 +1,177 @@
+package bwc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits on externally supplied identifiers. Case numbers and officer IDs
+// are embedded in evidence IDs, which name the stored files.
+const (
+	maxIdentifierLength = 64
+	maxEvidenceIDLength = 200
+)
+
+// ValidationError reports an externally supplied value that was refused
+type ValidationError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	value := e.Value
+	if len(value) > maxIdentifierLength {
+		value = value[:maxIdentifierLength] + "..."
+	}
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, value, e.Reason)
+}
+
+// validateIdentifier checks a name that ends up in evidence IDs, audit
+// entries and file names: it must be present, printable, free of path
+// separators and at most max bytes
+func validateIdentifier(field, value string, max int) error {
+	fail := func(reason string) error {
+		return &ValidationError{Field: field, Value: value, Reason: reason}
+	}
+	switch {
+	case value == "":
+		return fail("is required")
+	case len(value) > max:
+		return fail(fmt.Sprintf("is longer than %d bytes", max))
+	case !utf8.ValidString(value):
+		return fail("is not valid UTF-8")
+	case strings.TrimSpace(value) != value:
+		return fail("has leading or trailing spaces")
+	case strings.ContainsAny(value, `/\`):
+		return fail("contains a path separator")
+	}
+	for _, r := range value {
+		if !unicode.IsPrint(r) {
+			return fail("contains a non-printable character")
+		}
+	}
+	return nil
+}
+
+// ValidateCaseNumber checks a case number supplied at ingest
+func ValidateCaseNumber(caseNumber string) error {
+	return validateIdentifier("case number", caseNumber, maxIdentifierLength)
+}
+
+// ValidateOfficerID checks an officer or user ID supplied at ingest or custody
+// transfer. SYSTEM is refused: it is who the system's own jobs act as, and
+// is never refused a permission.
+func ValidateOfficerID(officerID string) error {
+	if officerID == systemUserID {
+		return &ValidationError{Field: "officer ID", Value: officerID, Reason: "is reserved for the system"}
+	}
+	return validateIdentifier("officer ID", officerID, maxIdentifierLength)
+}
+
+// ValidateEvidenceID checks an evidence ID taken from a request before it is looked up
+func ValidateEvidenceID(evidenceID string) error {
+	if evidenceID == "." || evidenceID == ".." {
+		return &ValidationError{Field: "evidence ID", Value: evidenceID, Reason: "is not an evidence ID"}
+	}
+	return validateIdentifier("evidence ID", evidenceID, maxEvidenceIDLength)
+}
+
+// validatePath refuses paths that are empty, contain NUL bytes or climb out
+// of a directory with "..". Either separator counts, as a path may be
+// handed on to another system.
+func validatePath(field, path string) error {
+	fail := func(reason string) error {
+		return &ValidationError{Field: field, Value: path, Reason: reason}
+	}
+	switch {
+	case path == "":
+		return fail("is required")
+	case strings.ContainsRune(path, 0):
+		return fail("contains a NUL byte")
+	}
+	for _, elem := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if elem == ".." {
+			return fail(`contains a ".." element`)
+		}
+	}
+	return nil
+}
+
+// ValidateIngestPath checks the path of a file to be ingested
+func ValidateIngestPath(path string) error {
+	return validatePath("ingest path", path)
+}
+
+// resolvePath returns path as an absolute path with symbolic links resolved,
+// as far as they exist, so two spellings of one file compare equal
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		return resolved, nil
+	}
+	// The file does not exist yet; resolve the directory it will be created in
+	if dir, err := filepath.EvalSymlinks(filepath.Dir(abs)); err == nil {
+		return filepath.Join(dir, filepath.Base(abs)), nil
+	}
+	return abs, nil
+}
+
+// pathWithin reports whether path is root or lies below it
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkExportPathLocked refuses an export to path that climbs directories,
+// would overwrite a stored evidence file or something other than a regular
+// file, or lies outside storage.export_roots when they are configured. The
+// refusal is audited. The caller must hold bwc.mu.
+func (bwc *BWCSystem) checkExportPathLocked(path, evidenceID, userID string) error {
+	err := validatePath("export path", path)
+	if err == nil {
+		err = bwc.exportPathProblemLocked(path)
+	}
+	if err != nil {
+		bwc.logAudit(userID, "EXPORT_PATH_REFUSED", evidenceID, err.Error(), "")
+	}
+	return err
+}
+
+func (bwc *BWCSystem) exportPathProblemLocked(path string) error {
+	fail := func(reason string) error {
+		return &ValidationError{Field: "export path", Value: path, Reason: reason}
+	}
+
+	target, err := resolvePath(path)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if info, err := os.Lstat(target); err == nil && !info.Mode().IsRegular() {
+		return fail("is not a regular file")
+	}
+
+	for _, ev := range bwc.evidenceDB.Search(nil) {
+		if stored, err := resolvePath(ev.FilePath); err == nil && stored == target {
+			return fail("would overwrite stored evidence")
+		}
+	}
+
+	roots := bwc.config.Storage.ExportRoots
+	if len(roots) == 0 {
+		return nil
+	}
+	for _, root := range roots {
+		if resolved, err := resolvePath(root); err == nil && pathWithin(resolved, target) {
+			return nil
+		}
+	}
+	return fail("is outside storage.export_roots")
+}
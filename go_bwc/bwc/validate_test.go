@@ -0,0 +1,183 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestValidateIdentifiers(t *testing.T) {
+	valid := []string{"CASE-2024-001", "CASE SCH-001", "CASE-<LBL>&002", "OFF-1098", "Ünit-7"}
+	for _, v := range valid {
+		if err := ValidateCaseNumber(v); err != nil {
+			t.Errorf("expected %q to be valid, got %v", v, err)
+		}
+	}
+
+	invalid := map[string]string{
+		"":                      "is required",
+		"../../etc":             "path separator",
+		`CASE\01`:               "path separator",
+		" CASE-1":               "leading or trailing",
+		"CASE\x00":              "non-printable",
+		"CASE\n1":               "non-printable",
+		"\xff\xfe":              "UTF-8",
+		strings.Repeat("x", 65): "longer than 64",
+		"SYSTEM":                "reserved",
+	}
+	for v, reason := range invalid {
+		err := ValidateOfficerID(v)
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !strings.Contains(err.Error(), reason) {
+			t.Errorf("expected %q to be refused with %q, got %v", v, reason, err)
+		}
+	}
+
+	if err := ValidateEvidenceID(".."); err == nil {
+		t.Error("expected .. to be refused as an evidence ID")
+	}
+	if err := ValidateEvidenceID("BWC-CASE-1-OFF-1-1700000000"); err != nil {
+		t.Errorf("expected a generated ID to be valid, got %v", err)
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	for _, p := range []string{"/tmp/export.json", "exports/a.zip", "C:\\exports\\a.zip", "a..b/c"} {
+		if err := validatePath("export path", p); err != nil {
+			t.Errorf("expected %q to be valid, got %v", p, err)
+		}
+	}
+	for _, p := range []string{"", "../etc/passwd", "/srv/exports/../../etc/cron.d/x", `exports\..\..\x`, "a\x00b"} {
+		if err := validatePath("export path", p); err == nil {
+			t.Errorf("expected %q to be refused", p)
+		}
+	}
+}
+
+func TestIngestRejectsInvalidInput(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+	file := createTestFile(t, tmpDir)
+
+	if _, err := system.IngestEvidence(file, "../../escape", "OFF-1098", "", "", nil); err == nil {
+		t.Error("expected a case number with path separators to be refused")
+	}
+	if _, err := system.IngestEvidence(file, "CASE-VAL-1", "", "", "", nil); err == nil {
+		t.Error("expected a missing officer ID to be refused")
+	}
+	if _, err := system.IngestEvidence(tmpDir, "CASE-VAL-1", "OFF-1098", "", "", nil); err == nil {
+		t.Error("expected a directory to be refused")
+	}
+	if _, err := system.IngestEvidence(tmpDir+"/../"+filepath.Base(tmpDir)+"/test_video.mp4", "CASE-VAL-1", "OFF-1098", "", "", nil); err == nil {
+		t.Error("expected a path with .. to be refused")
+	}
+	if n := len(system.SearchEvidence("", "", "")); n != 0 {
+		t.Errorf("expected nothing ingested, got %d", n)
+	}
+
+	ev, err := system.IngestEvidence(file, "CASE-VAL-1", "OFF-1098", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1098", "LAB/1", "Analysis"); err == nil {
+		t.Error("expected a transfer to an invalid officer ID to be refused")
+	}
+}
+
+func TestExportPathChecks(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	ev, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-VAL-2", "OFF-1099", "", "", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	original, _ := os.ReadFile(ev.FilePath)
+
+	refused := []string{
+		ev.FilePath,
+		tmpDir + "/sub/../" + filepath.Base(ev.FilePath),
+		tmpDir,
+	}
+	for _, p := range refused {
+		if err := system.ExportEvidenceFor(ev.ID, p, "DA-CLERK-1", ""); err == nil {
+			t.Errorf("expected export to %q to be refused", p)
+		}
+	}
+	if data, _ := os.ReadFile(ev.FilePath); string(data) != string(original) {
+		t.Fatal("stored evidence was overwritten")
+	}
+	if _, err := system.ExportCasePackage("CASE-VAL-2", ev.FilePath, "DA-CLERK-1", "", nil); err == nil {
+		t.Error("expected a case package over stored evidence to be refused")
+	}
+
+	refusals := 0
+	for _, log := range system.GetAuditLogs("", "DA-CLERK-1") {
+		if log.Action == "EXPORT_PATH_REFUSED" {
+			refusals++
+		}
+	}
+	if refusals != len(refused)+1 {
+		t.Errorf("expected %d audited refusals, got %d", len(refused)+1, refusals)
+	}
+
+	exports := t.TempDir()
+	system.config.Storage.ExportRoots = []string{exports}
+	if err := system.ExportEvidenceFor(ev.ID, filepath.Join(tmpDir, "outside.json"), "DA-CLERK-1", ""); err == nil || !strings.Contains(err.Error(), "export_roots") {
+		t.Errorf("expected an export outside the roots to be refused, got %v", err)
+	}
+	if err := system.ExportEvidenceFor(ev.ID, filepath.Join(exports, "inside.json"), "DA-CLERK-1", ""); err != nil {
+		t.Errorf("expected an export inside the roots to succeed, got %v", err)
+	}
+}
+
+func TestServerRejectsInvalidEvidenceID(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	resp := authGet(t, server, "/api/evidence/..%5c..%5cetc")
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Errorf("expected 400 for an invalid evidence ID, got %d", resp.StatusCode)
+	}
+}
+
+func FuzzValidateIdentifier(f *testing.F) {
+	for _, seed := range []string{"CASE-2024-001", "CASE SCH-001", "../x", "a\x00b", " x", "\xff"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		if ValidateCaseNumber(s) != nil {
+			return
+		}
+		// Anything accepted must be safe to embed in a stored file name
+		if s == "" || len(s) > maxIdentifierLength || !utf8.ValidString(s) || strings.ContainsAny(s, "/\\\x00") {
+			t.Fatalf("accepted unsafe identifier %q", s)
+		}
+		id := generateEvidenceID(s, "OFF-1")
+		if filepath.Base(filepath.Join("/storage", id+".mp4")) != id+".mp4" {
+			t.Fatalf("identifier %q escapes the storage directory", s)
+		}
+	})
+}
+
+func FuzzValidatePath(f *testing.F) {
+	for _, seed := range []string{"/tmp/export.json", "../etc/passwd", `a\..\b`, "a/./b", "..", "a\x00"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, p string) {
+		if validatePath("export path", p) != nil {
+			return
+		}
+		// A relative path that is accepted never resolves above its base
+		if !filepath.IsAbs(p) && !pathWithin("/base", filepath.Join("/base", p)) {
+			t.Fatalf("accepted path %q escapes its base directory", p)
+		}
+		if strings.ContainsRune(p, 0) {
+			t.Fatalf("accepted path %q with a NUL byte", p)
+		}
+	})
+}
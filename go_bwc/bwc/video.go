@@ -0,0 +1,136 @@
+package bwc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultProbeTimeout bounds an ffprobe run when none is configured
+const defaultProbeTimeout = 30 * time.Second
+
+// VideoInfo is the metadata of a video recording. Only the format is known
+// unless video_processing.extract_metadata is on. It is then read with
+// ffprobe when ffprobe_path is set, and otherwise from MP4 and QuickTime
+// files directly.
+type VideoInfo struct {
+	Format          string  `json:"format"`
+	Codec           string  `json:"codec,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	FrameRate       float64 `json:"frame_rate,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	// HasAudio is set when the recording carries an audio track
+	HasAudio bool `json:"has_audio,omitempty"`
+	// RecordedAt is the creation time the camera wrote into the container
+	RecordedAt *time.Time `json:"recorded_at,omitempty"`
+}
+
+// ffprobeOutput is the part of ffprobe's JSON output that is read
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		Tags     struct {
+			CreationTime string `json:"creation_time"`
+		} `json:"tags"`
+	} `json:"format"`
+}
+
+// probeVideo reads the metadata of a video file with ffprobe, or without it
+// for the formats readMP4 understands
+func (bwc *BWCSystem) probeVideo(path string) (*VideoInfo, error) {
+	info := &VideoInfo{Format: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")}
+	cfg := bwc.config.VideoProcessing
+	if !cfg.ExtractMetadata {
+		return info, nil
+	}
+	if cfg.FFprobePath == "" {
+		if !mp4Extensions[info.Format] {
+			return info, nil
+		}
+		probed, err := probeMP4(path, info.Format)
+		if err != nil {
+			return info, err
+		}
+		return probed, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultProbeTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, cfg.FFprobePath, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &stdout, limit: execOutputLimit}
+	cmd.Stderr = &limitedBuffer{buf: &stderr, limit: 1024}
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return info, errors.New("ffprobe timed out")
+		}
+		if detail := strings.TrimSpace(stderr.String()); detail != "" {
+			return info, fmt.Errorf("ffprobe: %v: %s", err, detail)
+		}
+		return info, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	var out ffprobeOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return info, fmt.Errorf("ffprobe: invalid output: %w", err)
+	}
+	found := false
+	for _, s := range out.Streams {
+		switch s.CodecType {
+		case "video":
+			if found {
+				continue
+			}
+			found = true
+			info.Codec = s.CodecName
+			info.Width, info.Height = s.Width, s.Height
+			info.FrameRate = parseFrameRate(s.AvgFrameRate)
+		case "audio":
+			info.HasAudio = true
+		}
+	}
+	if !found {
+		return info, errors.New("ffprobe: no video stream")
+	}
+	if d, err := strconv.ParseFloat(out.Format.Duration, 64); err == nil && d > 0 {
+		info.DurationSeconds = math.Round(d*1000) / 1000
+	}
+	if t, err := time.Parse(time.RFC3339Nano, out.Format.Tags.CreationTime); err == nil {
+		info.RecordedAt = &t
+	}
+	return info, nil
+}
+
+// parseFrameRate parses an ffprobe rate such as "30000/1001"
+func parseFrameRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !ok {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return math.Round(n/d*100) / 100
+}
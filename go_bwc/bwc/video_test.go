@@ -0,0 +1,85 @@
+package bwc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeFFprobe writes a script that prints output as ffprobe would
+func fakeFFprobe(t *testing.T, dir, output string) string {
+	t.Helper()
+	return writeProcessorScript(t, dir, "ffprobe", "cat <<'JSON'\n"+output+"\nJSON\n")
+}
+
+const ffprobeBodycam = `{"streams":[
+ {"codec_type":"video","codec_name":"h264","width":1920,"height":1080,"avg_frame_rate":"30000/1001"},
+ {"codec_type":"audio","codec_name":"aac"}],
+ "format":{"duration":"754.321000"}}`
+
+func TestIngestVideoMetadata(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	system.config.VideoProcessing.ExtractMetadata = true
+	system.config.VideoProcessing.FFprobePath = fakeFFprobe(t, tmpDir, ffprobeBodycam)
+
+	evidence, err := system.IngestEvidence(createTestFile(t, tmpDir), "CASE-VID-001", "OFF-1011", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	want := VideoInfo{Format: "mp4", Codec: "h264", Width: 1920, Height: 1080, FrameRate: 29.97, DurationSeconds: 754.321, HasAudio: true}
+	if evidence.MediaType != MediaVideo || evidence.Video == nil || *evidence.Video != want || evidence.Duration != 754 {
+		t.Fatalf("Unexpected video metadata %+v", evidence.Video)
+	}
+
+	report, _ := system.GenerateReport("CASE-VID-001")
+	if !strings.Contains(report, "Type: Video") || !strings.Contains(report, "Video: mp4, h264 1920x1080, 29.97 fps, 754.3 s") {
+		t.Errorf("Expected the video details in the report:\n%s", report)
+	}
+
+	system.config.VideoProcessing.FFprobePath = fakeFFprobe(t, tmpDir, `{"streams":[{"codec_type":"audio"}],"format":{}}`)
+	evidence, err = system.IngestEvidence(createTestFile(t, tmpDir), "CASE-VID-001", "OFF-1012", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("Expected a failed probe not to block ingest: %v", err)
+	}
+	if evidence.Video == nil || evidence.Video.Format != "mp4" || evidence.Video.Codec != "" {
+		t.Errorf("Expected a video recorded by format only, got %+v", evidence.Video)
+	}
+	logs := system.GetAuditLogs(evidence.ID, "SYSTEM")
+	if len(logs) != 1 || logs[0].Action != "MEDIA_PROBE_FAILED" || !strings.Contains(logs[0].Details, "no video stream") {
+		t.Errorf("Expected MEDIA_PROBE_FAILED audit entry, got %v", logs)
+	}
+}
+
+func TestIngestOtherEvidence(t *testing.T) {
+	system, tmpDir, cleanup := setupTestSystem(t)
+	defer cleanup()
+
+	path := filepath.Join(tmpDir, "phone-extraction.zip")
+	if err := os.WriteFile(path, []byte("PK extraction"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	evidence, err := system.IngestEvidence(path, "CASE-VID-002", "OFF-1013", "Officer Test", "Lab", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if evidence.MediaType != MediaOther || evidence.Video != nil || evidence.Audio != nil || evidence.Photo != nil || evidence.Document != nil {
+		t.Errorf("Expected no type-specific metadata, got %+v", evidence)
+	}
+
+	report, _ := system.GenerateReport("CASE-VID-002")
+	if !strings.Contains(report, "Type: Other") || strings.Contains(report, "Video:") {
+		t.Errorf("Expected an untyped item in the report:\n%s", report)
+	}
+}
+
+func TestParseFrameRate(t *testing.T) {
+	tests := map[string]float64{"30000/1001": 29.97, "25/1": 25, "30": 30, "0/0": 0, "": 0}
+	for rate, want := range tests {
+		if got := parseFrameRate(rate); got != want {
+			t.Errorf("parseFrameRate(%q) = %v, want %v", rate, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package bwc
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+const walFile = "evidence.wal"
+
+// walEntry is one line of the write-ahead log: a record as a change left it,
+// or the withdrawal of an earlier entry the store then refused
+type walEntry struct {
+	Seq        int64           `json:"seq"`
+	Timestamp  time.Time       `json:"timestamp"`
+	EvidenceID string          `json:"evidence_id"`
+	Revision   int64           `json:"revision,omitempty"`
+	Record     json.RawMessage `json:"record,omitempty"`
+	// Checksum is the SHA-256 of Record, so a damaged entry is not replayed
+	Checksum string `json:"checksum,omitempty"`
+	// Aborts is the sequence number of the entry this one withdraws
+	Aborts int64 `json:"aborts,omitempty"`
+}
+
+// writeAheadLog is an append-only file of changed records. Each entry is
+// synced to disk before the record is stored, so a record the process dies
+// while storing is replayed on the next start.
+type writeAheadLog struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+}
+
+// append logs evidence and returns the entry's sequence number
+func (w *writeAheadLog) append(evidence *Evidence) (int64, error) {
+	record, err := json.Marshal(evidence)
+	if err != nil {
+		return 0, err
+	}
+	sum := sha256.Sum256(record)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entry := walEntry{
+		Seq: w.seq + 1, Timestamp: time.Now(), EvidenceID: evidence.ID, Revision: evidence.Revision,
+		Record: record, Checksum: hex.EncodeToString(sum[:]),
+	}
+	if err := w.writeLocked(entry); err != nil {
+		return 0, err
+	}
+	return entry.Seq, nil
+}
+
+// abort withdraws the entry seq, which the store refused
+func (w *writeAheadLog) abort(seq int64, evidenceID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked(walEntry{Seq: w.seq + 1, Timestamp: time.Now(), EvidenceID: evidenceID, Aborts: seq})
+}
+
+func (w *writeAheadLog) writeLocked(entry walEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the write-ahead log: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync the write-ahead log: %w", err)
+	}
+	w.seq = entry.Seq
+	return nil
+}
+
+// readWAL returns the entries in the log at path, each record's latest
+// entry that was not withdrawn. A last line cut short by a crash is ignored;
+// damage anywhere else is an error.
+func readWAL(path string) ([]walEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the write-ahead log: %w", err)
+	}
+	latest := make(map[string]walEntry)
+	aborted := make(map[int64]bool)
+	reader := bufio.NewReader(bytes.NewReader(data))
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without its newline was being written when the
+			// process died
+			break
+		}
+		var entry walEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("write-ahead log line %d: %w", lineNo, err)
+		}
+		if entry.Aborts > 0 {
+			aborted[entry.Aborts] = true
+			continue
+		}
+		sum := sha256.Sum256(entry.Record)
+		if hex.EncodeToString(sum[:]) != entry.Checksum {
+			return nil, fmt.Errorf("write-ahead log line %d: checksum mismatch", lineNo)
+		}
+		if prev, ok := latest[entry.EvidenceID]; !ok || entry.Seq > prev.Seq {
+			latest[entry.EvidenceID] = entry
+		}
+	}
+	entries := make([]walEntry, 0, len(latest))
+	for _, entry := range latest {
+		if !aborted[entry.Seq] {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries, nil
+}
+
+// openWriteAheadLogLocked replays the log at path into the evidence store,
+// rewrites it with one entry per record held and starts logging changes to
+// it. The caller must hold bwc.mu.
+func (bwc *BWCSystem) openWriteAheadLogLocked(path string) error {
+	entries, err := readWAL(path)
+	if err != nil {
+		return err
+	}
+	replayed := 0
+	for _, entry := range entries {
+		if current := bwc.evidenceDB.Get(entry.EvidenceID); current != nil && current.Revision >= entry.Revision {
+			continue
+		}
+		var evidence Evidence
+		if err := json.Unmarshal(entry.Record, &evidence); err != nil {
+			return fmt.Errorf("write-ahead log entry %d: %w", entry.Seq, err)
+		}
+		if err := bwc.evidenceDB.Put(&evidence); err != nil {
+			return fmt.Errorf("failed to replay write-ahead log entry %d: %w", entry.Seq, err)
+		}
+		bwc.indexLoadedLocked(&evidence)
+		replayed++
+	}
+
+	// Compact the log so it does not grow without end. The memory store
+	// keeps nothing across restarts, so every record held is logged again.
+	tmp := path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to compact the write-ahead log: %w", err)
+	}
+	wal := &writeAheadLog{file: file}
+	records := bwc.evidenceDB.Search(nil)
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	for _, evidence := range records {
+		if _, err := wal.append(evidence); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact the write-ahead log: %w", err)
+	}
+	bwc.wal = wal
+
+	if replayed > 0 {
+		bwc.logAudit("SYSTEM", "WAL_REPLAYED", "",
+			fmt.Sprintf("%d records restored from the write-ahead log", replayed), "")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package bwc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func walTestConfig(t *testing.T) *Config {
+	cfg := DefaultConfig()
+	cfg.Storage.Path = t.TempDir()
+	cfg.Storage.WriteAheadLog = true
+	return cfg
+}
+
+func TestWriteAheadLogReplay(t *testing.T) {
+	cfg := walTestConfig(t)
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	ev, err := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-WAL-1", "OFF-1147", "Officer Test", "Patrol", nil)
+	if err != nil {
+		t.Fatalf("IngestEvidence failed: %v", err)
+	}
+	if err := system.TransferCustody(ev.ID, "OFF-1147", "DET-008", "Investigation"); err != nil {
+		t.Fatalf("TransferCustody failed: %v", err)
+	}
+	if err := system.UpdateStatus(ev.ID, "DET-008", StatusProcessing, "Review"); err != nil {
+		t.Fatalf("UpdateStatus failed: %v", err)
+	}
+	if _, err := system.VerifyIntegrity(ev.ID, "AUDITOR-1"); err != nil {
+		t.Fatalf("VerifyIntegrity failed: %v", err)
+	}
+
+	// The process dies partway through writing the next entry
+	path := filepath.Join(cfg.Storage.Path, walFile)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq": 99, "evidence_id": "` + ev.ID)
+	f.Close()
+
+	restarted, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("expected the log to replay past a torn last entry: %v", err)
+	}
+	got, err := restarted.GetEvidence(ev.ID)
+	if err != nil {
+		t.Fatalf("expected the record to be replayed: %v", err)
+	}
+	if len(got.ChainOfCustody) != 2 || got.Status != StatusProcessing || len(got.IntegrityChecks) != 2 {
+		t.Errorf("expected custody, status and integrity checks to be replayed, got %+v", got)
+	}
+	logs := restarted.GetAuditLogs("", "SYSTEM")
+	if len(logs) == 0 || logs[len(logs)-1].Action != "WAL_REPLAYED" {
+		t.Errorf("expected WAL_REPLAYED, got %+v", logs)
+	}
+
+	// The log was compacted to one entry per record, and replays again
+	entries, err := readWAL(path)
+	if err != nil || len(entries) != 1 || entries[0].Revision != got.Revision {
+		t.Fatalf("expected one compacted entry, got %+v, %v", entries, err)
+	}
+	if _, err := NewBWCSystemFromConfig(cfg); err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+}
+
+func TestWriteAheadLogSkipsRefusedWrites(t *testing.T) {
+	cfg := walTestConfig(t)
+	system, err := NewBWCSystemFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("NewBWCSystemFromConfig failed: %v", err)
+	}
+	store := newRecordingStore()
+	store.fail = errors.New("disk full")
+	system.evidenceDB = store
+
+	if _, err := system.IngestEvidence(createTestFile(t, t.TempDir()), "CASE-WAL-2", "OFF-1148", "Officer Test", "Patrol", nil); err == nil {
+		t.Fatal("expected the refused write to fail the ingest")
+	}
+	entries, err := readWAL(filepath.Join(cfg.Storage.Path, walFile))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected the refused record to be withdrawn from the log, got %+v, %v", entries, err)
+	}
+}
+
+func TestWriteAheadLogDamage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), walFile)
+	line := `{"seq":1,"evidence_id":"BWC-CASE-WAL-3-OFF-1149-1700000000","revision":1,"record":{"id":"x"},"checksum":"00"}` + "\n"
+	if err := os.WriteFile(path, []byte(line), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readWAL(path); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a damaged entry to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+'use strict';
+
+const $ = (sel) => document.querySelector(sel);
+
+async function api(path, options) {
+  const res = await fetch(path, Object.assign({ credentials: 'same-origin' }, options));
+  if (res.status === 401) {
+    showLogin();
+    throw new Error('authentication required');
+  }
+  if (!res.ok) {
+    const body = await res.json().catch(() => ({}));
+    throw new Error(body.error || res.statusText);
+  }
+  return res.status === 204 ? null : res.json();
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : '';
+}
+
+function cell(text, className) {
+  const td = document.createElement('td');
+  td.textContent = text == null ? '' : String(text);
+  if (className) {
+    td.className = className;
+  }
+  return td;
+}
+
+function fillTable(table, rows, render) {
+  const body = table.querySelector('tbody');
+  body.replaceChildren();
+  for (const row of rows) {
+    body.appendChild(render(row));
+  }
+}
+
+function showLogin() {
+  $('#app-view').hidden = true;
+  $('#session').hidden = true;
+  $('#login-view').hidden = false;
+}
+
+function showApp(userID) {
+  $('#login-view').hidden = true;
+  $('#app-view').hidden = false;
+  $('#session').hidden = false;
+  $('#session-user').textContent = userID;
+}
+
+function statusCell(ev) {
+  const td = cell(ev.status);
+  if (ev.seal) {
+    const badge = document.createElement('span');
+    badge.className = 'badge sealed';
+    badge.textContent = 'SEALED';
+    badge.title = 'Sealed under ' + ev.seal.authority;
+    td.append(' ', badge);
+  }
+  return td;
+}
+
+function resultRow(ev) {
+  const tr = document.createElement('tr');
+  tr.className = 'selectable';
+  tr.append(cell(ev.id), cell(ev.case_number), cell(ev.officer_name + ' (' + ev.officer_id + ')'),
+    cell(formatTime(ev.timestamp)), statusCell(ev));
+  tr.addEventListener('click', () => showDetail(ev.id));
+  return tr;
+}
+
+async function search(form) {
+  const params = new URLSearchParams(new FormData(form));
+  const results = await api('/api/evidence?' + params.toString());
+  results.sort((a, b) => (a.timestamp < b.timestamp ? 1 : -1));
+
+  fillTable($('#results'), results, resultRow);
+
+  const caseNumber = form.elements['case'].value.trim();
+  const link = $('#report-link');
+  link.hidden = caseNumber === '';
+  link.href = '/api/reports/' + encodeURIComponent(caseNumber);
+  const htmlLink = $('#report-html-link');
+  htmlLink.hidden = caseNumber === '';
+  htmlLink.href = link.href + '?format=html';
+  $('#detail').hidden = true;
+  $('#player').pause();
+  currentDetail = null;
+  watchEvidence(caseNumber);
+}
+
+let evidenceStream = null;
+let currentDetail = null;
+let refreshTimer = null;
+
+// watchEvidence keeps the result list and open detail live via server-sent events
+function watchEvidence(caseNumber) {
+  if (evidenceStream) {
+    evidenceStream.close();
+  }
+  const query = caseNumber ? '?case=' + encodeURIComponent(caseNumber) : '';
+  evidenceStream = new EventSource('/api/stream/evidence' + query);
+
+  const onChange = (e) => {
+    const event = JSON.parse(e.data);
+    clearTimeout(refreshTimer);
+    refreshTimer = setTimeout(() => {
+      const detailID = currentDetail;
+      refreshResults().then(() => {
+        if (detailID) {
+          showDetail(detailID);
+        }
+      }).catch(() => {});
+    }, 250);
+    $('#live-status').textContent = 'Updated ' + new Date().toLocaleTimeString() + ': ' +
+      event.type.replace(/_/g, ' ').toLowerCase() + ' ' + event.evidence_id;
+  };
+  for (const type of ['EVIDENCE_INGESTED', 'STATUS_CHANGED', 'CUSTODY_TRANSFERRED']) {
+    evidenceStream.addEventListener(type, onChange);
+  }
+}
+
+async function refreshResults() {
+  const params = new URLSearchParams(new FormData($('#search-form')));
+  const results = await api('/api/evidence?' + params.toString());
+  results.sort((a, b) => (a.timestamp < b.timestamp ? 1 : -1));
+  fillTable($('#results'), results, resultRow);
+}
+
+async function showDetail(id) {
+  const ev = await api('/api/evidence/' + encodeURIComponent(id));
+  $('#detail-title').textContent = 'Evidence ' + ev.id;
+
+  const fields = $('#detail-fields');
+  fields.replaceChildren();
+  const entries = [
+    ['Case', ev.case_number],
+    ['Officer', ev.officer_name + ' (' + ev.officer_id + ')'],
+    ['Recorded', formatTime(ev.timestamp)],
+    ['Location', ev.location],
+    ['Status', ev.status],
+    ['Sealed', ev.seal ? ev.seal.authority + ' (' + formatTime(ev.seal.sealed_at) + ')' : ''],
+    ['File Hash', ev.file_hash],
+    ['File Size', ev.file_size + ' bytes'],
+    ['Tags', (ev.tags || []).join(', ')],
+    ['Notes', ev.notes],
+  ];
+  for (const [label, value] of entries) {
+    const dt = document.createElement('dt');
+    dt.textContent = label;
+    const dd = document.createElement('dd');
+    dd.textContent = value == null ? '' : String(value);
+    fields.append(dt, dd);
+  }
+
+  fillTable($('#custody'), ev.chain_of_custody || [], (c) => {
+    const tr = document.createElement('tr');
+    tr.append(cell(formatTime(c.timestamp)), cell(c.from_officer), cell(c.to_officer),
+      cell(c.action), cell(c.purpose), cell(c.verified_hash, 'hash'));
+    return tr;
+  });
+
+  fillTable($('#checks'), ev.integrity_checks || [], (c) => {
+    const tr = document.createElement('tr');
+    tr.append(cell(formatTime(c.timestamp)), cell(c.checked_by),
+      cell(c.is_valid ? 'PASSED' : 'FAILED', c.is_valid ? '' : 'fail'), cell(c.notes));
+    return tr;
+  });
+
+  $('#label-link').href = '/api/evidence/' + encodeURIComponent(ev.id) + '/label';
+  $('#detail').hidden = false;
+  if (currentDetail !== ev.id) {
+    openPlayer(ev.id).catch(() => {});
+  }
+  currentDetail = ev.id;
+}
+
+// openPlayer starts an audited view session and streams the recording into the player
+async function openPlayer(id) {
+  const player = $('#player');
+  player.pause();
+  player.removeAttribute('src');
+  const path = '/api/evidence/' + encodeURIComponent(id);
+  const session = await api(path + '/view', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: '{}',
+  });
+  player.src = path + '/stream?session=' + encodeURIComponent(session.id);
+}
+
+async function loadAudit(form) {
+  const params = new URLSearchParams(new FormData(form));
+  const logs = await api('/api/audit?' + params.toString());
+  logs.reverse();
+
+  fillTable($('#audit'), logs, (log) => {
+    const tr = document.createElement('tr');
+    tr.append(cell(formatTime(log.timestamp)), cell(log.user_id), cell(log.action),
+      cell(log.evidence_id), cell(log.details), cell(log.ip_address));
+    return tr;
+  });
+}
+
+function selectTab(id) {
+  document.querySelectorAll('.tab').forEach((tab) => { tab.hidden = tab.id !== id; });
+  document.querySelectorAll('nav button').forEach((b) => {
+    b.classList.toggle('active', b.dataset.tab === id);
+  });
+  if (id === 'audit-tab') {
+    loadAudit($('#audit-form')).catch(() => {});
+  }
+}
+
+$('#login-form').addEventListener('submit', async (e) => {
+  e.preventDefault();
+  $('#login-error').textContent = '';
+  try {
+    const session = await api('/login', { method: 'POST', body: new URLSearchParams(new FormData(e.target)) });
+    e.target.reset();
+    showApp(session.user_id);
+    await search($('#search-form'));
+    await openLinkedEvidence();
+  } catch (err) {
+    $('#login-error').textContent = 'Sign in failed: ' + err.message;
+  }
+});
+
+$('#logout').addEventListener('click', async () => {
+  if (evidenceStream) {
+    evidenceStream.close();
+    evidenceStream = null;
+  }
+  await api('/logout', { method: 'POST' }).catch(() => {});
+  showLogin();
+});
+
+$('#search-form').addEventListener('submit', (e) => {
+  e.preventDefault();
+  search(e.target).catch(() => {});
+});
+
+$('#audit-form').addEventListener('submit', (e) => {
+  e.preventDefault();
+  loadAudit(e.target).catch(() => {});
+});
+
+document.querySelectorAll('nav button').forEach((b) => {
+  b.addEventListener('click', () => selectTab(b.dataset.tab));
+});
+
+// openLinkedEvidence shows the evidence named by ?evidence= (from a scanned label link)
+async function openLinkedEvidence() {
+  const id = new URLSearchParams(window.location.search).get('evidence');
+  if (id) {
+    await showDetail(id);
+  }
+}
+
+api('/api/session')
+  .then((session) => {
+    showApp(session.user_id);
+    return search($('#search-form'));
+  })
+  .then(openLinkedEvidence)
+  .catch(() => {});
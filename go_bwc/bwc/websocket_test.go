@@ -0,0 +1,158 @@
+package bwc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialTestWebSocket performs a raw WebSocket handshake against the test server
+func dialTestWebSocket(t *testing.T, serverURL, path string) (net.Conn, *bufio.Reader) {
+	u, _ := url.Parse(serverURL)
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+
+	req := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"Authorization: Bearer " + testAPIToken + "\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"
+	conn.Write([]byte(req))
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatalf("Failed to read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101, got %d", resp.StatusCode)
+	}
+	// Accept value from the RFC 6455 example handshake
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Unexpected Sec-WebSocket-Accept: %s", resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	return conn, br
+}
+
+// readServerFrame reads one unmasked frame sent by the server
+func readServerFrame(t *testing.T, conn net.Conn, br *bufio.Reader) (byte, []byte) {
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatalf("Failed to read frame: %v", err)
+	}
+	length := int(head[1] & 0x7F)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(br, ext[:])
+		length = int(ext[0])<<8 | int(ext[1])
+	}
+	payload := make([]byte, length)
+	io.ReadFull(br, payload)
+	return head[0] & 0x0F, payload
+}
+
+// writeClientFrame sends a masked frame as a browser would
+func writeClientFrame(conn net.Conn, opcode byte, payload []byte) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode, 0x80 | byte(len(payload))}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	conn.Write(frame)
+}
+
+func TestEventStreamWebSocket(t *testing.T) {
+	system, server, tmpDir, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	conn, br := dialTestWebSocket(t, server.URL, "/api/stream/events?types=audit&user_id=OFF-123")
+	defer conn.Close()
+
+	// Wait for the subscription to be registered
+	deadline := time.Now().Add(time.Second)
+	for len(system.GetAuditLogs("", "CUS-001")) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	testFile := createTestFile(t, tmpDir)
+	evidence, _ := system.IngestEvidence(testFile, "CASE-WS-001", "OFF-123", "Officer Test", "Test Location", nil)
+
+	opcode, payload := readServerFrame(t, conn, br)
+	if opcode != wsOpText {
+		t.Fatalf("Expected text frame, got opcode %d", opcode)
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("Invalid event JSON: %v", err)
+	}
+	if event.Type != EventAudit || event.EvidenceID != evidence.ID {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	writeClientFrame(conn, wsOpPing, []byte("hi"))
+	opcode, payload = readServerFrame(t, conn, br)
+	if opcode != wsOpPong || string(payload) != "hi" {
+		t.Errorf("Expected pong echoing ping payload, got opcode %d payload %q", opcode, payload)
+	}
+
+	writeClientFrame(conn, wsOpClose, []byte{0x03, 0xE8})
+	opcode, _ = readServerFrame(t, conn, br)
+	if opcode != wsOpClose {
+		t.Errorf("Expected close frame, got opcode %d", opcode)
+	}
+}
+
+func TestEventStreamRejectsCrossOrigin(t *testing.T) {
+	_, server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/stream/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for cross-origin subscription, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/api/stream/events", nil)
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+	resp, _ = http.DefaultClient.Do(req)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 without upgrade headers, got %d", resp.StatusCode)
+	}
+}
+
+func TestParseEventFilter(t *testing.T) {
+	q, _ := url.ParseQuery("types=audit,%20integrity_alert&case=CASE-1&user_id=OFF-1")
+	f := parseEventFilter(q)
+
+	if len(f.Types) != 2 || f.Types[1] != EventIntegrityAlert {
+		t.Errorf("Unexpected types: %v", f.Types)
+	}
+	if f.CaseNumber != "CASE-1" || f.UserID != "OFF-1" {
+		t.Errorf("Unexpected filter: %+v", f)
+	}
+	if !strings.EqualFold(string(f.Types[0]), "AUDIT") {
+		t.Errorf("Expected AUDIT, got %s", f.Types[0])
+	}
+}
@@ -0,0 +1,656 @@
+// Package client is the Go client for the BWC evidence server's HTTP API.
+// Its methods mirror the BWCSystem operations the server exposes; requests
+// that are safe to repeat are retried on transient failures.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls a BWC server with an API token
+type Client struct {
+	baseURL string
+	token   string
+
+	// HTTP is the underlying client; its Timeout bounds each attempt
+	HTTP *http.Client
+	// Retries is how many times a retryable request is repeated after the first attempt
+	Retries int
+	// Backoff is the wait before the first retry; it doubles with each retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// New returns a client for the server at baseURL, e.g. https://bwc.example.org
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		HTTP:       &http.Client{Timeout: 5 * time.Minute},
+		Retries:    3,
+		Backoff:    500 * time.Millisecond,
+		MaxBackoff: 10 * time.Second,
+	}
+}
+
+// Error is a non-success response from the server
+type Error struct {
+	StatusCode int
+	Message    string
+	// Code classifies the failure, such as NOT_FOUND, INTEGRITY_FAILURE or
+	// INVALID_TRANSITION, when the server gives one
+	Code string
+	// CurrentRevision is set on revision conflicts (409) from UpdateMetadata
+	CurrentRevision int64
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bwc: %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the server
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is a 409 from the server: a stale revision
+// on UpdateMetadata, or an ingest with the same idempotency key still running
+func IsConflict(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+// request describes one API call
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// body is read once per attempt; a request with a body that cannot be
+	// rewound is sent once
+	body        io.Reader
+	contentType string
+	// idempotent requests are retried; GET always is
+	idempotent bool
+	header     http.Header
+}
+
+// retryable reports whether a response status is worth retrying
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// do sends req, retrying transient failures, and returns the successful
+// response. The caller closes its body.
+func (c *Client) do(ctx context.Context, req request) (*http.Response, error) {
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	seeker, rewindable := req.body.(io.Seeker)
+	attempts := 1
+	if (req.method == http.MethodGet || req.idempotent) && (req.body == nil || rewindable) {
+		attempts += c.Retries
+	}
+	backoff := c.Backoff
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && rewindable {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+		}
+
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, u, req.body)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range req.header {
+			httpReq.Header[k] = v
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+		if req.contentType != "" {
+			httpReq.Header.Set("Content-Type", req.contentType)
+		}
+		if req.body != nil && rewindable {
+			// Keep the transport from closing a file the next attempt rereads
+			httpReq.Body = io.NopCloser(req.body)
+		}
+
+		resp, err := c.HTTP.Do(httpReq)
+		var wait time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || attempt >= attempts {
+				return nil, err
+			}
+			wait = backoff
+		case resp.StatusCode < 300:
+			return resp, nil
+		case retryable(resp.StatusCode) && attempt < attempts:
+			wait = backoff
+			if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+				wait = time.Duration(secs) * time.Second
+			}
+			resp.Body.Close()
+		default:
+			defer resp.Body.Close()
+			return nil, responseError(resp)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > c.MaxBackoff {
+			backoff = c.MaxBackoff
+		}
+	}
+}
+
+// responseError decodes the server's {"error": ...} body
+func responseError(resp *http.Response) error {
+	var body struct {
+		Error           string         `json:"error"`
+		Code            string         `json:"code"`
+		CurrentRevision int64          `json:"current_revision"`
+		Errors          []GraphQLError `json:"errors"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&body)
+	if body.Error == "" && len(body.Errors) > 0 {
+		// A GraphQL query the server could not run
+		body.Error = body.Errors[0].Message
+	}
+	if body.Error == "" {
+		body.Error = resp.Status
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: body.Error, Code: body.Code, CurrentRevision: body.CurrentRevision}
+}
+
+// getJSON decodes the response of a GET into out
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out interface{}) error {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, query: query})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// getBytes returns the body of a GET, for the endpoints that serve documents
+func (c *Client) getBytes(ctx context.Context, path string, query url.Values) ([]byte, error) {
+	resp, err := c.do(ctx, request{method: http.MethodGet, path: path, query: query})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// sendJSON sends in as a JSON body and decodes the response into out, if given
+func (c *Client) sendJSON(ctx context.Context, method, path string, in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(ctx, request{method: method, path: path, body: bytes.NewReader(data), contentType: "application/json"})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return decode(resp, out)
+}
+
+func decode(resp *http.Response, out interface{}) error {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+// Session returns the user the client's token authenticates as
+func (c *Client) Session(ctx context.Context) (string, error) {
+	var session struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.getJSON(ctx, "/api/session", nil, &session); err != nil {
+		return "", err
+	}
+	return session.UserID, nil
+}
+
+// IngestOptions is the metadata of a file being ingested
+type IngestOptions struct {
+	// Filename supplies the extension, which selects the media type
+	Filename    string
+	CaseNumber  string
+	OfficerID   string
+	OfficerName string
+	Location    string
+	Tags        []string
+	// IdempotencyKey makes retries safe: the server returns the original
+	// evidence for a key it has seen. Ingest generates one when it is empty.
+	IdempotencyKey string
+}
+
+// IngestResult is the evidence an ingest created, or found already created
+// under the same idempotency key
+type IngestResult struct {
+	Evidence *Evidence
+	Replayed bool
+}
+
+// Ingest streams r to the server as new evidence. When r is an io.Seeker,
+// such as an *os.File, the upload is retried on transient failures; the
+// idempotency key keeps a retry from creating a second record.
+func (c *Client) Ingest(ctx context.Context, r io.Reader, opts IngestOptions) (*IngestResult, error) {
+	if opts.Filename == "" {
+		return nil, errors.New("filename is required")
+	}
+	key := opts.IdempotencyKey
+	if key == "" {
+		var err error
+		if key, err = newIdempotencyKey(); err != nil {
+			return nil, err
+		}
+	}
+
+	query := url.Values{"filename": {opts.Filename}}
+	for name, value := range map[string]string{
+		"case":         opts.CaseNumber,
+		"officer":      opts.OfficerID,
+		"officer_name": opts.OfficerName,
+		"location":     opts.Location,
+		"tags":         strings.Join(opts.Tags, ","),
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+
+	resp, err := c.do(ctx, request{
+		method:      http.MethodPost,
+		path:        "/api/evidence",
+		query:       query,
+		body:        r,
+		contentType: "application/octet-stream",
+		idempotent:  true,
+		header:      http.Header{"Idempotency-Key": {key}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result := &IngestResult{Replayed: resp.Header.Get("Idempotent-Replayed") == "true"}
+	if err := decode(resp, &result.Evidence); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// IngestFile uploads the file at path; opts.Filename defaults to its name
+func (c *Client) IngestFile(ctx context.Context, path string, opts IngestOptions) (*IngestResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if opts.Filename == "" {
+		opts.Filename = filepath.Base(path)
+	}
+	return c.Ingest(ctx, f, opts)
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// GetEvidence returns one evidence record
+func (c *Client) GetEvidence(ctx context.Context, id string) (*Evidence, error) {
+	var ev Evidence
+	if err := c.getJSON(ctx, "/api/evidence/"+url.PathEscape(id), nil, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// SearchQuery filters SearchEvidence. Text and Near select full-text and
+// location search; otherwise the remaining fields filter the evidence list.
+type SearchQuery struct {
+	CaseNumber string
+	OfficerID  string
+	Status     string
+	MediaType  string
+	Text       string
+	// Near is "lat,lon", with RadiusMeters around it
+	Near         string
+	RadiusMeters float64
+	// Limit and Offset page the results of case, officer, status and media
+	// searches, ordered by SortBy (timestamp, case_number or status)
+	Limit      int
+	Offset     int
+	SortBy     string
+	Descending bool
+}
+
+// SearchEvidence returns the evidence matching q
+func (c *Client) SearchEvidence(ctx context.Context, q SearchQuery) ([]*Evidence, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{
+		"case":    q.CaseNumber,
+		"officer": q.OfficerID,
+		"status":  q.Status,
+		"media":   q.MediaType,
+		"q":       q.Text,
+		"near":    q.Near,
+	} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	if q.RadiusMeters > 0 {
+		query.Set("radius", strconv.FormatFloat(q.RadiusMeters, 'f', -1, 64))
+	}
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Offset > 0 {
+		query.Set("offset", strconv.Itoa(q.Offset))
+	}
+	if q.SortBy != "" {
+		query.Set("sort", q.SortBy)
+	}
+	if q.Descending {
+		query.Set("order", "desc")
+	}
+
+	var results []*Evidence
+	if err := c.getJSON(ctx, "/api/evidence", query, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// UpdateMetadata edits evidence metadata against the revision the caller
+// read. On a stale revision it returns an *Error for which IsConflict is
+// true, carrying the current revision.
+func (c *Client) UpdateMetadata(ctx context.Context, id string, revision int64, update MetadataUpdate) (*Evidence, error) {
+	body := struct {
+		Revision int64 `json:"revision"`
+		MetadataUpdate
+	}{revision, update}
+
+	var ev Evidence
+	if err := c.sendJSON(ctx, http.MethodPatch, "/api/evidence/"+url.PathEscape(id), body, &ev); err != nil {
+		return nil, err
+	}
+	return &ev, nil
+}
+
+// ChainOfCustody returns the custody entries of an evidence record
+func (c *Client) ChainOfCustody(ctx context.Context, id string) ([]CustodyEntry, error) {
+	var entries []CustodyEntry
+	if err := c.getJSON(ctx, "/api/evidence/"+url.PathEscape(id)+"/custody", nil, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CustodyAffidavit returns the custody affidavit PDF for an evidence record,
+// sworn by the client's user under affiantName
+func (c *Client) CustodyAffidavit(ctx context.Context, id, affiantName string) ([]byte, error) {
+	return c.getBytes(ctx, "/api/evidence/"+url.PathEscape(id)+"/affidavit", url.Values{"name": {affiantName}})
+}
+
+// AccessGrants returns the active access grants, filtered by evidence and user when given
+func (c *Client) AccessGrants(ctx context.Context, evidenceID, userID string) ([]*AccessGrant, error) {
+	query := url.Values{}
+	if evidenceID != "" {
+		query.Set("evidence_id", evidenceID)
+	}
+	if userID != "" {
+		query.Set("user_id", userID)
+	}
+	var grants []*AccessGrant
+	if err := c.getJSON(ctx, "/api/grants", query, &grants); err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// GrantAccess allows userID to view evidenceID for duration
+func (c *Client) GrantAccess(ctx context.Context, evidenceID, userID string, duration time.Duration, reason string) (*AccessGrant, error) {
+	body := map[string]string{
+		"evidence_id": evidenceID,
+		"user_id":     userID,
+		"duration":    duration.String(),
+		"reason":      reason,
+	}
+	var grant AccessGrant
+	if err := c.sendJSON(ctx, http.MethodPost, "/api/grants", body, &grant); err != nil {
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// AuditLogs returns the audit trail, filtered by evidence and user when given
+func (c *Client) AuditLogs(ctx context.Context, evidenceID, userID string) ([]AuditLog, error) {
+	query := url.Values{}
+	if evidenceID != "" {
+		query.Set("evidence_id", evidenceID)
+	}
+	if userID != "" {
+		query.Set("user_id", userID)
+	}
+	var logs []AuditLog
+	if err := c.getJSON(ctx, "/api/audit", query, &logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// Anomalies returns the access alerts raised since since (all when zero)
+// and the accounts awaiting review
+func (c *Client) Anomalies(ctx context.Context, since time.Time) (*Anomalies, error) {
+	query := url.Values{}
+	if !since.IsZero() {
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	var anomalies Anomalies
+	if err := c.getJSON(ctx, "/api/anomalies", query, &anomalies); err != nil {
+		return nil, err
+	}
+	return &anomalies, nil
+}
+
+// ReviewFlaggedAccount clears the flag on userID's account
+func (c *Client) ReviewFlaggedAccount(ctx context.Context, userID, notes string) error {
+	return c.sendJSON(ctx, http.MethodPost, "/api/anomalies", map[string]string{"user_id": userID, "notes": notes}, nil)
+}
+
+// Scan looks up a scanned label code and applies action (lookup, checkout,
+// checkin or transfer) to the evidence
+func (c *Client) Scan(ctx context.Context, code, action, to, purpose string) (*ScanResult, error) {
+	body := map[string]string{"code": code, "action": action, "to": to, "purpose": purpose}
+	var result ScanResult
+	if err := c.sendJSON(ctx, http.MethodPost, "/api/scan", body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CaseReport returns the case report as text, or HTML when format is "html".
+// profile and lang may be empty for the caller's default.
+func (c *Client) CaseReport(ctx context.Context, caseNumber, format, profile, lang string) ([]byte, error) {
+	query := url.Values{}
+	for name, value := range map[string]string{"format": format, "profile": profile, "lang": lang} {
+		if value != "" {
+			query.Set(name, value)
+		}
+	}
+	return c.getBytes(ctx, "/api/reports/"+url.PathEscape(caseNumber), query)
+}
+
+// RetentionForecast returns the evidence whose retention ends within days,
+// formatted as json, csv or text
+func (c *Client) RetentionForecast(ctx context.Context, days int, format string) ([]byte, error) {
+	query := url.Values{"days": {strconv.Itoa(days)}}
+	if format != "" {
+		query.Set("format", format)
+	}
+	return c.getBytes(ctx, "/api/retention/forecast", query)
+}
+
+// EventQuery selects the events Events delivers; empty fields match
+// everything
+type EventQuery struct {
+	// Types defaults to the evidence lifecycle events: ingests, status
+	// changes and custody transfers
+	Types      []string
+	EvidenceID string
+	CaseNumber string
+	UserID     string
+}
+
+// Events subscribes to the server's event stream and calls handle with each
+// event as it happens. It returns when ctx ends, when handle returns an
+// error, which it passes back, or with nil when the server closes the
+// stream. The subscription is not retried; callers that need to stay
+// connected call Events again.
+func (c *Client) Events(ctx context.Context, q EventQuery, handle func(Event) error) error {
+	query := url.Values{}
+	if len(q.Types) > 0 {
+		query.Set("types", strings.Join(q.Types, ","))
+	}
+	for name, v := range map[string]string{"evidence_id": q.EvidenceID, "case": q.CaseNumber, "user_id": q.UserID} {
+		if v != "" {
+			query.Set(name, v)
+		}
+	}
+	u := c.baseURL + "/api/stream/evidence"
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "text/event-stream")
+
+	// The stream outlives any per-request timeout
+	httpClient := *c.HTTP
+	httpClient.Timeout = 0
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var data []byte
+	for scanner.Scan() {
+		// Keepalive comments and event names are skipped; the data carries
+		// the type
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+			if len(data) == 0 {
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal(data, &event); err != nil {
+				return fmt.Errorf("bwc: bad event: %w", err)
+			}
+			data = data[:0]
+			if err := handle(event); err != nil {
+				return err
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
+
+// GraphQLError is one error reported by a GraphQL query
+type GraphQLError struct {
+	Message string `json:"message"`
+	// Path names the field that failed, as keys and list indexes
+	Path []interface{} `json:"path,omitempty"`
+	// Extensions carries the error code, such as NOT_FOUND, when there is one
+	Extensions map[string]string `json:"extensions,omitempty"`
+}
+
+// GraphQLErrors is returned by GraphQL when some fields failed; the fields
+// that resolved are still decoded
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Message
+	}
+	return "bwc: graphql: " + strings.Join(msgs, "; ")
+}
+
+// GraphQL runs a read-only query against /api/graphql and decodes its data
+// into out. A query the server refuses outright is an *Error; one where
+// only some fields failed is GraphQLErrors.
+func (c *Client) GraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}
+	in := map[string]interface{}{"query": query, "variables": variables}
+	if err := c.sendJSON(ctx, http.MethodPost, "/api/graphql", in, &resp); err != nil {
+		return err
+	}
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return resp.Errors
+	}
+	return nil
+}
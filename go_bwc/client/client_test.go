@@ -0,0 +1,261 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	c := New(srv.URL+"/", "secret")
+	c.Backoff = time.Millisecond
+	c.MaxBackoff = 5 * time.Millisecond
+	return c
+}
+
+func TestGetEvidenceSendsTokenAndDecodes(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/api/evidence/EVD-1" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": "EVD-1", "case_number": "CASE-1", "revision": 3})
+	})
+
+	ev, err := c.GetEvidence(context.Background(), "EVD-1")
+	if err != nil {
+		t.Fatalf("GetEvidence failed: %v", err)
+	}
+	if ev.ID != "EVD-1" || ev.CaseNumber != "CASE-1" || ev.Revision != 3 {
+		t.Errorf("unexpected evidence %+v", ev)
+	}
+}
+
+func TestErrorsCarryStatusAndMessage(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "evidence not found"})
+		case http.MethodPatch:
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": "stale", "current_revision": 7})
+		}
+	})
+
+	_, err := c.GetEvidence(context.Background(), "EVD-X")
+	if !IsNotFound(err) || !strings.Contains(err.Error(), "evidence not found") {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	notes := "x"
+	_, err = c.UpdateMetadata(context.Background(), "EVD-1", 2, MetadataUpdate{Notes: &notes})
+	var apiErr *Error
+	if !IsConflict(err) || !errors.As(err, &apiErr) || apiErr.CurrentRevision != 7 {
+		t.Errorf("expected a conflict with current revision 7, got %v", err)
+	}
+}
+
+func TestGetRetriesTransientFailures(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"user_id": "OFF-1"})
+	})
+
+	user, err := c.Session(context.Background())
+	if err != nil || user != "OFF-1" {
+		t.Fatalf("expected OFF-1 after retries, got %q, %v", user, err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 3 {
+		t.Errorf("expected 3 attempts, got %d", n)
+	}
+
+	c.Retries = 0
+	atomic.StoreInt32(&calls, 0)
+	if _, err := c.Session(context.Background()); err == nil {
+		t.Error("expected an error with retries disabled")
+	}
+}
+
+func TestNonIdempotentPostIsNotRetried(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	if _, err := c.GrantAccess(context.Background(), "EVD-1", "DET-1", 72*time.Hour, "review"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected one attempt for a grant, got %d", n)
+	}
+}
+
+func TestIngestFileRetriesWithSameKey(t *testing.T) {
+	var mu sync.Mutex
+	var keys, bodies []string
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		bodies = append(bodies, string(data))
+		first := len(keys) == 1
+		mu.Unlock()
+		if first {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("filename") != "clip.mp4" || q.Get("case") != "CASE-9" || q.Get("tags") != "a,b" {
+			t.Errorf("unexpected query %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		json.NewEncoder(w).Encode(map[string]string{"id": "EVD-9"})
+	})
+
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, []byte("video bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := c.IngestFile(context.Background(), path, IngestOptions{CaseNumber: "CASE-9", Tags: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("IngestFile failed: %v", err)
+	}
+	if result.Evidence.ID != "EVD-9" || !result.Replayed {
+		t.Errorf("unexpected result %+v", result)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) != 2 || keys[0] == "" || keys[0] != keys[1] {
+		t.Errorf("expected the same idempotency key on both attempts, got %v", keys)
+	}
+	if bodies[0] != "video bytes" || bodies[1] != "video bytes" {
+		t.Errorf("expected the full file on each attempt, got %q", bodies)
+	}
+}
+
+func TestIngestStreamIsSentOnce(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("streamed"))
+		pw.Close()
+	}()
+	if _, err := c.Ingest(context.Background(), pr, IngestOptions{Filename: "a.wav"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("expected a non-rewindable upload to be sent once, got %d", n)
+	}
+
+	if _, err := c.Ingest(context.Background(), strings.NewReader("x"), IngestOptions{}); err == nil {
+		t.Error("expected an error without a filename")
+	}
+}
+
+func TestRetryStopsWhenContextEnds(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c.Backoff = time.Hour
+	c.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.AuditLogs(ctx, "", ""); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline error, got %v", err)
+	}
+}
+
+func TestEventsDeliversStreamedEvents(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/stream/evidence" || r.URL.Query().Get("types") != "INTEGRITY_ALERT,STATUS_CHANGED" || r.URL.Query().Get("case") != "CASE-1" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, ": connected\n\n")
+		io.WriteString(w, "event: INTEGRITY_ALERT\ndata: {\"type\": \"INTEGRITY_ALERT\", \"evidence_id\": \"EVD-1\", \"alert\": {\"notes\": \"mismatch\"}}\n\n")
+		io.WriteString(w, ": keepalive\n\n")
+		io.WriteString(w, "event: STATUS_CHANGED\ndata: {\"type\": \"STATUS_CHANGED\", \"change\": {\"status\": \"ANALYZED\"}}\n\n")
+	})
+
+	query := EventQuery{Types: []string{EventIntegrityAlert, EventStatusChanged}, CaseNumber: "CASE-1"}
+	var events []Event
+	err := c.Events(context.Background(), query, func(e Event) error {
+		events = append(events, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Events failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Alert == nil || events[0].Alert.Notes != "mismatch" || events[1].Change == nil || events[1].Change.Status != "ANALYZED" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	stop := errors.New("stop")
+	if err := c.Events(context.Background(), query, func(Event) error { return stop }); err != stop {
+		t.Errorf("expected the handler's error back, got %v", err)
+	}
+}
+
+func TestGraphQLDecodesDataAndErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.Contains(body.Query, "broken"):
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"errors": [{"message": "Cannot query field \"broken\" on type \"Query\""}]}`)
+		case body.Variables["id"] == "EVD-1":
+			io.WriteString(w, `{"data": {"evidence": {"caseNumber": "CASE-1"}, "case": null}, "errors": [{"message": "no evidence found for case CASE-X", "path": ["case"], "extensions": {"code": "NOT_FOUND"}}]}`)
+		}
+	})
+
+	var out struct {
+		Evidence struct {
+			CaseNumber string `json:"caseNumber"`
+		} `json:"evidence"`
+	}
+	err := c.GraphQL(context.Background(), `query($id: ID!) { evidence(id: $id) { caseNumber } case(number: "CASE-X") { number } }`, map[string]interface{}{"id": "EVD-1"}, &out)
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || gqlErrs[0].Extensions["code"] != "NOT_FOUND" || out.Evidence.CaseNumber != "CASE-1" {
+		t.Errorf("expected partial data with a NOT_FOUND error, got %+v, %v", out, err)
+	}
+
+	var apiErr *Error
+	if err := c.GraphQL(context.Background(), `{ broken }`, nil, nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || !strings.Contains(apiErr.Message, "broken") {
+		t.Errorf("expected a 400 naming the field, got %v", err)
+	}
+}
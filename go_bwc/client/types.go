@@ -0,0 +1,256 @@
+package client
+
+import "time"
+
+// The types below are the JSON the server sends and accepts. They carry the
+// fields integrations rely on; fields the server adds later are ignored when
+// decoding. client_bindings_test.go in the server package checks every json
+// tag here against the server's own types, so the two cannot drift apart.
+
+// Evidence is an evidence record as returned by /api/evidence
+type Evidence struct {
+	ID              string           `json:"id"`
+	CaseNumber      string           `json:"case_number"`
+	OfficerID       string           `json:"officer_id"`
+	OfficerName     string           `json:"officer_name"`
+	Timestamp       time.Time        `json:"timestamp"`
+	Duration        int              `json:"duration_seconds"`
+	MediaType       string           `json:"media_type,omitempty"`
+	IncidentTime    *time.Time       `json:"incident_time,omitempty"`
+	Severity        string           `json:"severity,omitempty"`
+	CourtDate       *time.Time       `json:"court_date,omitempty"`
+	Location        string           `json:"location"`
+	FileHash        string           `json:"file_hash"`
+	FileSize        int64            `json:"file_size"`
+	Status          string           `json:"status"`
+	Tags            []string         `json:"tags"`
+	Notes           string           `json:"notes"`
+	ChainOfCustody  []CustodyEntry   `json:"chain_of_custody"`
+	CreatedAt       time.Time        `json:"created_at"`
+	LastModified    time.Time        `json:"last_modified"`
+	Revision        int64            `json:"revision"`
+	IntegrityChecks []IntegrityCheck `json:"integrity_checks"`
+	Seal            *Seal            `json:"seal,omitempty"`
+	Forensics       *Forensics       `json:"forensics,omitempty"`
+	Clock           *ClockCheck      `json:"clock,omitempty"`
+}
+
+// Forensics documents how evidence was acquired and the tools that verified it
+type Forensics struct {
+	AcquisitionMethod string         `json:"acquisition_method"`
+	AcquiredAt        time.Time      `json:"acquired_at"`
+	Examiner          string         `json:"examiner"`
+	ExaminerName      string         `json:"examiner_name,omitempty"`
+	OriginalFileName  string         `json:"original_file_name"`
+	OriginalMedia     *OriginalMedia `json:"original_media,omitempty"`
+	WriteBlocker      string         `json:"write_blocker,omitempty"`
+	HashAlgorithm     string         `json:"hash_algorithm"`
+	Tools             []ForensicTool `json:"tools"`
+}
+
+// OriginalMedia identifies the device and media evidence came from
+type OriginalMedia struct {
+	DeviceMake   string `json:"device_make,omitempty"`
+	DeviceModel  string `json:"device_model,omitempty"`
+	DeviceSerial string `json:"device_serial,omitempty"`
+	MediaSerial  string `json:"media_serial,omitempty"`
+}
+
+// ForensicTool is a tool, and its version, that handled evidence
+type ForensicTool struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Purpose string `json:"purpose"`
+}
+
+// ClockCheck compares the time a camera embedded in a recording with when it
+// was uploaded. DriftSeconds is positive when the camera clock ran ahead.
+type ClockCheck struct {
+	RecordedAt    time.Time `json:"recorded_at"`
+	Device        string    `json:"device,omitempty"`
+	OffsetSeconds int       `json:"offset_seconds,omitempty"`
+	CorrectedAt   time.Time `json:"corrected_at"`
+	IngestedAt    time.Time `json:"ingested_at"`
+	Plausible     bool      `json:"plausible"`
+	DriftSeconds  int64     `json:"drift_seconds,omitempty"`
+	Reason        string    `json:"reason,omitempty"`
+}
+
+// CustodyEntry is one hand-off in an evidence record's chain of custody
+type CustodyEntry struct {
+	Timestamp    time.Time         `json:"timestamp"`
+	FromOfficer  string            `json:"from_officer"`
+	ToOfficer    string            `json:"to_officer"`
+	Action       string            `json:"action"`
+	Purpose      string            `json:"purpose"`
+	VerifiedHash string            `json:"verified_hash"`
+	Signature    *CustodySignature `json:"signature,omitempty"`
+	EntryHash    string            `json:"entry_hash,omitempty"`
+}
+
+// CustodySignature is the officer's signature on a custody entry
+type CustodySignature struct {
+	Type           string    `json:"type"`
+	SignerID       string    `json:"signer_id"`
+	SignedAt       time.Time `json:"signed_at"`
+	Acknowledgment string    `json:"acknowledgment,omitempty"`
+	Image          []byte    `json:"image,omitempty"`
+	Certificate    []byte    `json:"certificate,omitempty"`
+	Value          []byte    `json:"value,omitempty"`
+}
+
+// IntegrityCheck is one verification of the evidence file's hash
+type IntegrityCheck struct {
+	Timestamp time.Time `json:"timestamp"`
+	CheckedBy string    `json:"checked_by"`
+	HashValue string    `json:"hash_value"`
+	IsValid   bool      `json:"is_valid"`
+	Notes     string    `json:"notes"`
+}
+
+// Seal is present on evidence sealed by court order
+type Seal struct {
+	Authority string    `json:"authority"`
+	SealedAt  time.Time `json:"sealed_at"`
+	StateHash string    `json:"state_hash"`
+	KeyID     string    `json:"key_id"`
+	Signature string    `json:"signature"`
+}
+
+// MetadataUpdate holds the descriptive fields to change; nil fields are left alone
+type MetadataUpdate struct {
+	Location    *string `json:"location,omitempty"`
+	OfficerName *string `json:"officer_name,omitempty"`
+	Notes       *string `json:"notes,omitempty"`
+
+	AcquisitionMethod *string        `json:"acquisition_method,omitempty"`
+	Examiner          *string        `json:"examiner,omitempty"`
+	ExaminerName      *string        `json:"examiner_name,omitempty"`
+	OriginalMedia     *OriginalMedia `json:"original_media,omitempty"`
+	WriteBlocker      *string        `json:"write_blocker,omitempty"`
+}
+
+// AuditLog is one entry of the audit trail
+type AuditLog struct {
+	Timestamp  time.Time `json:"timestamp"`
+	UserID     string    `json:"user_id"`
+	Action     string    `json:"action"`
+	EvidenceID string    `json:"evidence_id"`
+	Details    string    `json:"details"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+// AccessGrant allows a user to view one evidence record until it expires
+type AccessGrant struct {
+	ID             string    `json:"id"`
+	EvidenceID     string    `json:"evidence_id"`
+	UserID         string    `json:"user_id"`
+	GrantedBy      string    `json:"granted_by"`
+	Reason         string    `json:"reason"`
+	GrantedAt      time.Time `json:"granted_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	RevokedBy      string    `json:"revoked_by,omitempty"`
+	RevokedAt      time.Time `json:"revoked_at,omitempty"`
+	AccessCount    int       `json:"access_count"`
+	LastAccessedAt time.Time `json:"last_accessed_at,omitempty"`
+}
+
+// Checkout is evidence currently checked out of the evidence room
+type Checkout struct {
+	EvidenceID   string    `json:"evidence_id"`
+	CheckedOutBy string    `json:"checked_out_by"`
+	CheckedOutTo string    `json:"checked_out_to"`
+	Purpose      string    `json:"purpose"`
+	CheckedOutAt time.Time `json:"checked_out_at"`
+}
+
+// CustodyRequest is a custody transfer awaiting the receiving officer
+type CustodyRequest struct {
+	ID          string    `json:"id"`
+	EvidenceID  string    `json:"evidence_id"`
+	FromOfficer string    `json:"from_officer"`
+	ToOfficer   string    `json:"to_officer"`
+	Purpose     string    `json:"purpose"`
+	Status      string    `json:"status"`
+	RequestedAt time.Time `json:"requested_at"`
+	ResolvedAt  time.Time `json:"resolved_at"`
+	Resolution  string    `json:"resolution"`
+}
+
+// ScanResult is the custody state of scanned evidence after the scan's action
+type ScanResult struct {
+	Evidence         *Evidence       `json:"evidence"`
+	CurrentCustodian string          `json:"current_custodian"`
+	Checkout         *Checkout       `json:"checkout,omitempty"`
+	PendingRequest   *CustodyRequest `json:"pending_request,omitempty"`
+}
+
+// AccessAlert is an access anomaly raised against an account
+type AccessAlert struct {
+	ID       string    `json:"id"`
+	Rule     string    `json:"rule"`
+	UserID   string    `json:"user_id"`
+	RaisedAt time.Time `json:"raised_at"`
+	Count    int       `json:"count"`
+	Details  string    `json:"details"`
+}
+
+// FlaggedAccount is an account with access alerts awaiting review
+type FlaggedAccount struct {
+	UserID     string    `json:"user_id"`
+	FlaggedAt  time.Time `json:"flagged_at"`
+	AlertIDs   []string  `json:"alert_ids"`
+	Reviewed   bool      `json:"reviewed"`
+	ReviewedBy string    `json:"reviewed_by,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty"`
+	Notes      string    `json:"notes,omitempty"`
+}
+
+// Anomalies is the response of GET /api/anomalies
+type Anomalies struct {
+	Alerts          []AccessAlert    `json:"alerts"`
+	FlaggedAccounts []FlaggedAccount `json:"flagged_accounts"`
+}
+
+// Event types delivered by Events. IntegrityAlert events report failed
+// integrity checks and custody transfers refused for a hash mismatch.
+const (
+	EventEvidenceIngested   = "EVIDENCE_INGESTED"
+	EventStatusChanged      = "STATUS_CHANGED"
+	EventCustodyTransferred = "CUSTODY_TRANSFERRED"
+	EventIntegrityAlert     = "INTEGRITY_ALERT"
+	EventAudit              = "AUDIT"
+	EventAccessAnomaly      = "ACCESS_ANOMALY"
+)
+
+// Event is one real-time notification; which of Audit, Alert, Change and
+// Anomaly is set depends on Type
+type Event struct {
+	Type       string          `json:"type"`
+	Timestamp  time.Time       `json:"timestamp"`
+	EvidenceID string          `json:"evidence_id,omitempty"`
+	CaseNumber string          `json:"case_number,omitempty"`
+	UserID     string          `json:"user_id,omitempty"`
+	Audit      *AuditLog       `json:"audit,omitempty"`
+	Alert      *IntegrityAlert `json:"alert,omitempty"`
+	Change     *EvidenceChange `json:"change,omitempty"`
+	Anomaly    *AccessAlert    `json:"anomaly,omitempty"`
+}
+
+// IntegrityAlert is a failed integrity check
+type IntegrityAlert struct {
+	EvidenceID string    `json:"evidence_id"`
+	CaseNumber string    `json:"case_number"`
+	CheckedAt  time.Time `json:"checked_at"`
+	CheckedBy  string    `json:"checked_by"`
+	Notes      string    `json:"notes"`
+}
+
+// EvidenceChange describes an ingest, status change or custody transfer
+type EvidenceChange struct {
+	Status         string `json:"status"`
+	PreviousStatus string `json:"previous_status,omitempty"`
+	FromOfficer    string `json:"from_officer,omitempty"`
+	ToOfficer      string `json:"to_officer,omitempty"`
+	Purpose        string `json:"purpose,omitempty"`
+}
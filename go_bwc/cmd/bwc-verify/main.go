@@ -0,0 +1,131 @@
+// Command bwc-verify checks a case package exported by the BWC evidence
+// system without the system itself. Prosecutors, defence counsel and other
+// agencies can confirm that a package is complete, unaltered and signed by
+// the agency that sent it.
+//
+//	bwc-verify -key 3b6a27bc... case.zip
+//	bwc-verify -trusted agencies.json -json case.zip
+//
+// Encrypted packages must be decrypted with age or gpg first. The exit
+// status is 0 when the package verifies, 1 when any check fails, 2 on a
+// usage error and 3 when every check passes but no trusted key was given to
+// confirm who signed it.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run verifies the package named in args and returns the exit status
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("bwc-verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var trusted []TrustedKey
+	flags.Func("key", "hex Ed25519 public key the package must be signed with (repeatable)", func(v string) error {
+		trusted = append(trusted, TrustedKey{Name: "key " + shortKey(v), PublicKey: v})
+		return nil
+	})
+	trustedFile := flags.String("trusted", "", "JSON file of trusted keys, as in chain_of_custody.trusted_sources")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: bwc-verify [-key hex] [-trusted file] [-json] package.zip")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	if *trustedFile != "" {
+		keys, err := loadTrustedKeys(*trustedFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 2
+		}
+		trusted = append(trusted, keys...)
+	}
+
+	report, err := Verify(flags.Arg(0), trusted)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Fprintln(stdout, string(data))
+	} else {
+		printReport(stdout, report)
+	}
+
+	switch report.Status {
+	case StatusVerified:
+		return 0
+	case StatusUnconfirmedSigner:
+		return 3
+	}
+	return 1
+}
+
+// loadTrustedKeys reads a JSON array of {"name", "public_key"} objects
+func loadTrustedKeys(path string) ([]TrustedKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trusted keys: %w", err)
+	}
+	var keys []TrustedKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("malformed trusted keys: %w", err)
+	}
+	return keys, nil
+}
+
+func shortKey(hexKey string) string {
+	hexKey = strings.TrimSpace(hexKey)
+	if len(hexKey) > 16 {
+		return hexKey[:16] + "..."
+	}
+	return hexKey
+}
+
+// printReport writes the report for people, one line per check
+func printReport(w io.Writer, r *Report) {
+	fmt.Fprintf(w, "Package:  %s\n", r.Package)
+	if r.CaseNumber != "" {
+		fmt.Fprintf(w, "Case:     %s (%d items)\n", r.CaseNumber, r.Evidence)
+		fmt.Fprintf(w, "Exported: %s by %s from %s\n", r.ExportedAt.Format("2006-01-02 15:04:05 MST"), r.ExportedBy, r.SourceSystem)
+	}
+	if r.KeyID != "" {
+		fmt.Fprintf(w, "Key:      %s (%s)\n", r.KeyID, r.PublicKey)
+	}
+	fmt.Fprintln(w)
+	for _, c := range r.Checks {
+		mark := "OK  "
+		if !c.OK {
+			mark = "FAIL"
+		}
+		fmt.Fprintf(w, "  %s  %-40s %-10s %s\n", mark, c.Subject, c.Name, c.Detail)
+	}
+	fmt.Fprintln(w)
+
+	switch r.Status {
+	case StatusVerified:
+		fmt.Fprintf(w, "VERIFIED: signed by %s; every record and recording matches\n", r.Signer)
+	case StatusUnconfirmedSigner:
+		fmt.Fprintln(w, "SIGNER NOT CONFIRMED: the contents match the signed manifest, but the signing key")
+		fmt.Fprintln(w, "was not checked. Obtain the agency's public key separately and pass it with -key.")
+	default:
+		fmt.Fprintf(w, "FAILED: %d of %d checks failed\n", r.Failures(), len(r.Checks))
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	p := newTestPackage(t)
+	path := writeZip(t, p.build(t))
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-key", p.publicKey(), path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("expected the package to verify, got %d: %s%s", code, stdout.String(), stderr.String())
+	}
+	if out := stdout.String(); !strings.Contains(out, "VERIFIED: signed by key ") || !strings.Contains(out, "Case:     CASE-VFY-1 (2 items)") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	stdout.Reset()
+	if code := run([]string{path}, &stdout, &stderr); code != 3 {
+		t.Errorf("expected exit 3 without a trusted key, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "SIGNER NOT CONFIRMED") {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	trusted := filepath.Join(t.TempDir(), "trusted.json")
+	os.WriteFile(trusted, []byte(`[{"name": "County Sheriff", "public_key": "`+p.publicKey()+`"}]`), 0600)
+	stdout.Reset()
+	if code := run([]string{"-trusted", trusted, "-json", path}, &stdout, &stderr); code != 0 {
+		t.Errorf("expected the trusted file to confirm the signer, got %d", code)
+	}
+	var report Report
+	if err := json.Unmarshal(stdout.Bytes(), &report); err != nil || report.Signer != "County Sheriff" || report.Status != StatusVerified {
+		t.Errorf("expected a JSON report, got %s", stdout.String())
+	}
+
+	entries := p.build(t)
+	entries["files/extra.mp4"] = []byte("extra")
+	stdout.Reset()
+	if code := run([]string{"-key", p.publicKey(), writeZip(t, entries)}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected a tampered package to fail, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "FAILED: 1 of") {
+		t.Errorf("unexpected output:\n%s", stdout.String())
+	}
+
+	if code := run(nil, &stdout, &stderr); code != 2 {
+		t.Errorf("expected a usage error, got %d", code)
+	}
+}
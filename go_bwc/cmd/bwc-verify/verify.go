@@ -0,0 +1,417 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The types below mirror the case package format written by the BWC
+// system's ExportCasePackage. They are copied rather than imported so the
+// verifier builds on its own.
+
+// packageFormat identifies the layout of a case package
+const packageFormat = "bwc-case-package/v1"
+
+// maxMetadataBytes bounds the manifest, signature and each record read from a package
+const maxMetadataBytes = 16 << 20
+
+type packageItem struct {
+	EvidenceID   string `json:"evidence_id"`
+	Record       string `json:"record"`
+	RecordSHA256 string `json:"record_sha256"`
+	File         string `json:"file"`
+	FileSHA256   string `json:"file_sha256"`
+	FileSize     int64  `json:"file_size"`
+}
+
+type packageManifest struct {
+	Format       string        `json:"format"`
+	SourceSystem string        `json:"source_system"`
+	CaseNumber   string        `json:"case_number"`
+	ExportedAt   time.Time     `json:"exported_at"`
+	ExportedBy   string        `json:"exported_by"`
+	Purpose      string        `json:"purpose,omitempty"`
+	Evidence     []packageItem `json:"evidence"`
+}
+
+type packageSignature struct {
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+	Signature string `json:"signature"`
+}
+
+// evidenceRecord holds the fields of an evidence record the verifier checks
+type evidenceRecord struct {
+	ID             string         `json:"id"`
+	CaseNumber     string         `json:"case_number"`
+	FileHash       string         `json:"file_hash"`
+	FileSize       int64          `json:"file_size"`
+	ChainOfCustody []custodyEntry `json:"chain_of_custody"`
+}
+
+// custodyEntry, custodySignature and authentication must keep the system's
+// field order and tags: an entry's hash is taken over its JSON encoding
+type custodyEntry struct {
+	Timestamp      time.Time         `json:"timestamp"`
+	FromOfficer    string            `json:"from_officer"`
+	ToOfficer      string            `json:"to_officer"`
+	Action         string            `json:"action"`
+	Purpose        string            `json:"purpose"`
+	VerifiedHash   string            `json:"verified_hash"`
+	Signature      *custodySignature `json:"signature,omitempty"`
+	Authentication *authentication   `json:"authentication,omitempty"`
+	EntryHash      string            `json:"entry_hash,omitempty"`
+}
+
+type authentication struct {
+	UserID       string `json:"user_id"`
+	Method       string `json:"method"`
+	CredentialID string `json:"credential_id,omitempty"`
+}
+
+type custodySignature struct {
+	Type           string    `json:"type"`
+	SignerID       string    `json:"signer_id"`
+	SignedAt       time.Time `json:"signed_at"`
+	Acknowledgment string    `json:"acknowledgment,omitempty"`
+	Image          []byte    `json:"image,omitempty"`
+	Certificate    []byte    `json:"certificate,omitempty"`
+	Value          []byte    `json:"value,omitempty"`
+	KeyID          string    `json:"key_id,omitempty"`
+}
+
+// TrustedKey is a package signing key the recipient obtained from the
+// exporting agency by some other channel
+type TrustedKey struct {
+	Name string `json:"name"`
+	// PublicKey is the hex-encoded Ed25519 key, as the system's
+	// SealPublicKey() and trusted_sources show it
+	PublicKey string `json:"public_key"`
+}
+
+// Status is the overall outcome of a verification
+type Status string
+
+const (
+	StatusVerified Status = "VERIFIED"
+	StatusFailed   Status = "FAILED"
+	// StatusUnconfirmedSigner means every check passed but no trusted key was
+	// given, so the package may have been re-signed by anyone
+	StatusUnconfirmedSigner Status = "SIGNER_NOT_CONFIRMED"
+)
+
+// Check is one verification step and its outcome
+type Check struct {
+	Subject string `json:"subject"`
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// Report is the outcome of verifying a case package
+type Report struct {
+	Package      string    `json:"package"`
+	Format       string    `json:"format,omitempty"`
+	CaseNumber   string    `json:"case_number,omitempty"`
+	SourceSystem string    `json:"source_system,omitempty"`
+	ExportedBy   string    `json:"exported_by,omitempty"`
+	ExportedAt   time.Time `json:"exported_at,omitempty"`
+	KeyID        string    `json:"key_id,omitempty"`
+	PublicKey    string    `json:"public_key,omitempty"`
+	// Signer names the trusted key that signed the package, if any
+	Signer   string  `json:"signer,omitempty"`
+	Evidence int     `json:"evidence"`
+	Checks   []Check `json:"checks"`
+	Status   Status  `json:"status"`
+}
+
+func (r *Report) pass(subject, name, detail string) {
+	r.Checks = append(r.Checks, Check{Subject: subject, Name: name, OK: true, Detail: detail})
+}
+
+func (r *Report) fail(subject, name, detail string) {
+	r.Checks = append(r.Checks, Check{Subject: subject, Name: name, Detail: detail})
+}
+
+// Failures counts the checks that failed
+func (r *Report) Failures() int {
+	n := 0
+	for _, c := range r.Checks {
+		if !c.OK {
+			n++
+		}
+	}
+	return n
+}
+
+// Verify checks the case package at packagePath: the manifest signature and who
+// made it, that the manifest and the zip agree entry for entry, every record
+// and recording against the hashes the manifest pins, and each record's
+// chain of custody. It checks as much as it can rather than stopping at the
+// first problem.
+func Verify(packagePath string, trusted []TrustedKey) (*Report, error) {
+	zr, err := zip.OpenReader(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("not a case package (decrypt it first if it is encrypted): %w", err)
+	}
+	defer zr.Close()
+
+	report := &Report{Package: packagePath, Checks: make([]Check, 0)}
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		switch {
+		case entries[f.Name] != nil:
+			report.fail(f.Name, "entry", "listed twice in the zip")
+		case path.IsAbs(f.Name) || strings.Contains(f.Name, "..") || strings.Contains(f.Name, `\`):
+			report.fail(f.Name, "entry", "unsafe entry name")
+		}
+		entries[f.Name] = f
+	}
+
+	manifestData, err := readEntry(entries, "manifest.json")
+	if err != nil {
+		report.fail("manifest.json", "present", err.Error())
+		return report.finish(), nil
+	}
+	verifySignature(report, entries, manifestData, trusted)
+
+	var manifest packageManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		report.fail("manifest.json", "format", fmt.Sprintf("malformed manifest: %v", err))
+		return report.finish(), nil
+	}
+	report.Format, report.CaseNumber, report.SourceSystem = manifest.Format, manifest.CaseNumber, manifest.SourceSystem
+	report.ExportedBy, report.ExportedAt, report.Evidence = manifest.ExportedBy, manifest.ExportedAt, len(manifest.Evidence)
+	switch {
+	case manifest.Format != packageFormat:
+		report.fail("manifest.json", "format", fmt.Sprintf("unsupported format %q", manifest.Format))
+		return report.finish(), nil
+	case len(manifest.Evidence) == 0:
+		report.fail("manifest.json", "format", "package contains no evidence")
+	default:
+		report.pass("manifest.json", "format", fmt.Sprintf("%s, %d items", manifest.Format, len(manifest.Evidence)))
+	}
+
+	listed := map[string]bool{"manifest.json": true, "manifest.sig": true}
+	seen := make(map[string]bool)
+	for _, item := range manifest.Evidence {
+		if seen[item.EvidenceID] {
+			report.fail(item.EvidenceID, "manifest", "listed twice")
+			continue
+		}
+		seen[item.EvidenceID] = true
+		listed[item.Record], listed[item.File] = true, true
+		verifyItem(report, entries, &manifest, item)
+	}
+
+	unlisted := make([]string, 0)
+	for name := range entries {
+		if !listed[name] && !strings.HasSuffix(name, "/") {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+	for _, name := range unlisted {
+		report.fail(name, "entry", "not listed in the manifest")
+	}
+	return report.finish(), nil
+}
+
+// finish sets the overall status from the checks
+func (r *Report) finish() *Report {
+	switch {
+	case r.Failures() > 0:
+		r.Status = StatusFailed
+	case r.Signer == "":
+		r.Status = StatusUnconfirmedSigner
+	default:
+		r.Status = StatusVerified
+	}
+	return r
+}
+
+// verifySignature checks manifest.sig over the manifest and whether a
+// trusted key made it
+func verifySignature(report *Report, entries map[string]*zip.File, manifest []byte, trusted []TrustedKey) {
+	sigData, err := readEntry(entries, "manifest.sig")
+	if err != nil {
+		report.fail("manifest.sig", "signature", err.Error())
+		return
+	}
+	var sig packageSignature
+	if err := json.Unmarshal(sigData, &sig); err != nil {
+		report.fail("manifest.sig", "signature", fmt.Sprintf("malformed signature: %v", err))
+		return
+	}
+	key, err := hex.DecodeString(sig.PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		report.fail("manifest.sig", "signature", "malformed signing key")
+		return
+	}
+	keySum := sha256.Sum256(key)
+	report.KeyID, report.PublicKey = hex.EncodeToString(keySum[:8]), sig.PublicKey
+
+	signature, err := base64.StdEncoding.DecodeString(sig.Signature)
+	payload := append([]byte("BWC-PACKAGE-v1\n"), manifest...)
+	if err != nil || !ed25519.Verify(key, payload, signature) {
+		report.fail("manifest.sig", "signature", "signature does not verify against the manifest")
+		return
+	}
+	report.pass("manifest.sig", "signature", "Ed25519 signature by key "+report.KeyID)
+
+	if len(trusted) == 0 {
+		return
+	}
+	for _, t := range trusted {
+		want, err := hex.DecodeString(strings.TrimSpace(t.PublicKey))
+		if err == nil && bytes.Equal(want, key) {
+			report.Signer = t.Name
+			report.pass("manifest.sig", "signer", "signed by "+t.Name)
+			return
+		}
+	}
+	report.fail("manifest.sig", "signer", fmt.Sprintf("key %s is not one of the trusted keys", report.KeyID))
+}
+
+// verifyItem checks one evidence record and its recording against the manifest
+func verifyItem(report *Report, entries map[string]*zip.File, manifest *packageManifest, item packageItem) {
+	id := item.EvidenceID
+	if id == "" || path.Base(id) != id || strings.HasPrefix(id, ".") {
+		report.fail(id, "manifest", "invalid evidence ID")
+		return
+	}
+	ext := strings.TrimPrefix(item.File, "files/"+id)
+	if item.Record != "evidence/"+id+".json" || !strings.HasPrefix(item.File, "files/"+id) || (ext != "" && ext != path.Ext(item.File)) {
+		report.fail(id, "manifest", "unexpected entry names")
+		return
+	}
+
+	if record, err := readEntry(entries, item.Record); err != nil {
+		report.fail(id, "record", err.Error())
+	} else if sum := sha256.Sum256(record); hex.EncodeToString(sum[:]) != item.RecordSHA256 {
+		report.fail(id, "record", "record does not match the manifest hash")
+	} else {
+		report.pass(id, "record", "sha256 "+item.RecordSHA256)
+		var ev evidenceRecord
+		if err := json.Unmarshal(record, &ev); err != nil {
+			report.fail(id, "record", fmt.Sprintf("malformed record: %v", err))
+		} else if ev.ID != id || ev.CaseNumber != manifest.CaseNumber || ev.FileHash != item.FileSHA256 || ev.FileSize != item.FileSize {
+			report.fail(id, "record", "record does not agree with the manifest")
+		} else {
+			verifyCustody(report, &ev)
+		}
+	}
+
+	f := entries[item.File]
+	if f == nil {
+		report.fail(id, "recording", "missing from the package")
+		return
+	}
+	rc, err := f.Open()
+	if err != nil {
+		report.fail(id, "recording", err.Error())
+		return
+	}
+	defer rc.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, rc)
+	switch {
+	case err != nil:
+		report.fail(id, "recording", err.Error())
+	case n != item.FileSize:
+		report.fail(id, "recording", fmt.Sprintf("%d bytes, manifest lists %d", n, item.FileSize))
+	case hex.EncodeToString(h.Sum(nil)) != item.FileSHA256:
+		report.fail(id, "recording", "recording does not match the manifest hash")
+	default:
+		report.pass(id, "recording", fmt.Sprintf("sha256 %s, %d bytes", item.FileSHA256, n))
+	}
+}
+
+// verifyCustody recomputes each custody entry hash and re-verifies PIV
+// signatures over the hand-off they record
+func verifyCustody(report *Report, ev *evidenceRecord) {
+	hashed, signed := 0, 0
+	for i, entry := range ev.ChainOfCustody {
+		if entry.EntryHash == "" {
+			continue
+		}
+		want := entry.EntryHash
+		entry.EntryHash = ""
+		data, err := json.Marshal(entry)
+		if sum := sha256.Sum256(data); err != nil || hex.EncodeToString(sum[:]) != want {
+			report.fail(ev.ID, "custody", fmt.Sprintf("entry %d (%s) does not match its hash", i+1, entry.Action))
+			return
+		}
+		hashed++
+
+		if sig := entry.Signature; sig != nil && sig.Type == "PIV" {
+			payload := strings.Join([]string{"BWC-CUSTODY-v1", ev.ID, entry.FromOfficer, entry.ToOfficer, entry.Action, entry.Purpose, ev.FileHash}, "\n")
+			if err := checkPIVSignature(sig, []byte(payload)); err != nil {
+				report.fail(ev.ID, "custody", fmt.Sprintf("entry %d (%s): %v", i+1, entry.Action, err))
+				return
+			}
+			signed++
+		}
+	}
+	detail := fmt.Sprintf("%d entries, %d hashed", len(ev.ChainOfCustody), hashed)
+	if signed > 0 {
+		detail += fmt.Sprintf(", %d PIV signatures", signed)
+	}
+	report.pass(ev.ID, "custody", detail)
+}
+
+// checkPIVSignature checks a SHA-256 card signature over payload. The
+// certificate may have lapsed since the hand-off was signed.
+func checkPIVSignature(sig *custodySignature, payload []byte) error {
+	cert, err := x509.ParseCertificate(sig.Certificate)
+	if err != nil {
+		return fmt.Errorf("invalid PIV certificate: %w", err)
+	}
+	algorithm := x509.UnknownSignatureAlgorithm
+	switch cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	}
+	if err := cert.CheckSignature(algorithm, payload, sig.Value); err != nil {
+		return errors.New("PIV signature does not verify")
+	}
+	return nil
+}
+
+// readEntry reads a bounded metadata entry from the package
+func readEntry(entries map[string]*zip.File, name string) ([]byte, error) {
+	f := entries[name]
+	if f == nil {
+		return nil, errors.New("missing from the package")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxMetadataBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxMetadataBytes {
+		return nil, errors.New("entry is too large")
+	}
+	return data, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPackage is a case package under construction, so tests can tamper
+// with it before it is written
+type testPackage struct {
+	key      ed25519.PrivateKey
+	manifest packageManifest
+	records  map[string]*evidenceRecord
+	files    map[string][]byte
+}
+
+func newTestPackage(t *testing.T) *testPackage {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	p := &testPackage{
+		key:      key,
+		manifest: packageManifest{Format: packageFormat, SourceSystem: "County BWC", CaseNumber: "CASE-VFY-1", ExportedAt: time.Now().UTC(), ExportedBy: "CLERK-1"},
+		records:  make(map[string]*evidenceRecord),
+		files:    make(map[string][]byte),
+	}
+	for _, id := range []string{"BWC-CASE-VFY-1-OFF-1-1700000000", "BWC-CASE-VFY-1-OFF-2-1700000001"} {
+		data := []byte("recording of " + id)
+		sum := sha256.Sum256(data)
+		record := &evidenceRecord{ID: id, CaseNumber: "CASE-VFY-1", FileHash: hex.EncodeToString(sum[:]), FileSize: int64(len(data))}
+		for _, action := range []string{"COLLECTED", "TRANSFERRED"} {
+			entry := custodyEntry{Timestamp: time.Now(), FromOfficer: "OFF-1", ToOfficer: "OFF-2", Action: action, Purpose: "Review", VerifiedHash: record.FileHash}
+			entry.EntryHash = entryHash(t, entry)
+			record.ChainOfCustody = append(record.ChainOfCustody, entry)
+		}
+		p.records[id] = record
+		p.files["files/"+id+".mp4"] = data
+	}
+	return p
+}
+
+func entryHash(t *testing.T, entry custodyEntry) string {
+	t.Helper()
+	entry.EntryHash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatalf("failed to marshal entry: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// build lays out the zip entries, hashing records and recordings into the
+// manifest and signing it
+func (p *testPackage) build(t *testing.T) map[string][]byte {
+	t.Helper()
+	entries := make(map[string][]byte)
+	ids := make([]string, 0, len(p.records))
+	for id := range p.records {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	p.manifest.Evidence = nil
+	for _, id := range ids {
+		record, _ := json.MarshalIndent(p.records[id], "", "  ")
+		recordSum := sha256.Sum256(record)
+		name := "files/" + id + ".mp4"
+		file := p.files[name]
+		fileSum := sha256.Sum256(file)
+		item := packageItem{
+			EvidenceID: id, Record: "evidence/" + id + ".json", RecordSHA256: hex.EncodeToString(recordSum[:]),
+			File: name, FileSHA256: hex.EncodeToString(fileSum[:]), FileSize: int64(len(file)),
+		}
+		p.manifest.Evidence = append(p.manifest.Evidence, item)
+		entries[item.Record] = record
+		entries[item.File] = file
+	}
+	manifest, _ := json.MarshalIndent(p.manifest, "", "  ")
+	entries["manifest.json"] = manifest
+	entries["manifest.sig"] = p.signature(manifest)
+	return entries
+}
+
+func (p *testPackage) signature(manifest []byte) []byte {
+	sig, _ := json.MarshalIndent(packageSignature{
+		KeyID:     "test",
+		PublicKey: p.publicKey(),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(p.key, append([]byte("BWC-PACKAGE-v1\n"), manifest...))),
+	}, "", "  ")
+	return sig
+}
+
+func (p *testPackage) publicKey() string {
+	return hex.EncodeToString(p.key.Public().(ed25519.PublicKey))
+}
+
+// writeZip writes entries to a zip file in a temporary directory
+func writeZip(t *testing.T, entries map[string][]byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "case.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create package: %v", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, _ := zw.Create(name)
+		w.Write(entries[name])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to write package: %v", err)
+	}
+	return path
+}
+
+// failedChecks lists "subject name" for every failed check
+func failedChecks(r *Report) []string {
+	failed := make([]string, 0)
+	for _, c := range r.Checks {
+		if !c.OK {
+			failed = append(failed, c.Subject+" "+c.Name)
+		}
+	}
+	return failed
+}
+
+func TestVerifyPackage(t *testing.T) {
+	p := newTestPackage(t)
+	path := writeZip(t, p.build(t))
+
+	report, err := Verify(path, []TrustedKey{{Name: "County Sheriff", PublicKey: p.publicKey()}})
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if report.Status != StatusVerified || report.Signer != "County Sheriff" || report.Failures() != 0 {
+		t.Errorf("expected the package to verify, got %+v", report)
+	}
+	if report.CaseNumber != "CASE-VFY-1" || report.Evidence != 2 || len(report.Checks) != 9 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	report, _ = Verify(path, nil)
+	if report.Status != StatusUnconfirmedSigner || report.Failures() != 0 {
+		t.Errorf("expected an unconfirmed signer without trusted keys, got %s %v", report.Status, failedChecks(report))
+	}
+
+	_, other, _ := ed25519.GenerateKey(nil)
+	report, _ = Verify(path, []TrustedKey{{Name: "Other", PublicKey: hex.EncodeToString(other.Public().(ed25519.PublicKey))}})
+	if report.Status != StatusFailed || strings.Join(failedChecks(report), ",") != "manifest.sig signer" {
+		t.Errorf("expected an untrusted signer to fail, got %s %v", report.Status, failedChecks(report))
+	}
+
+	if _, err := Verify(filepath.Join(t.TempDir(), "missing.zip"), nil); err == nil {
+		t.Error("expected a missing package to be an error")
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	first := "BWC-CASE-VFY-1-OFF-1-1700000000"
+	tests := []struct {
+		name   string
+		tamper func(p *testPackage, entries map[string][]byte)
+		want   string
+	}{
+		{"recording altered", func(p *testPackage, entries map[string][]byte) {
+			entries["files/"+first+".mp4"] = []byte("recording of something else")
+		}, first + " recording"},
+		{"recording removed", func(p *testPackage, entries map[string][]byte) {
+			delete(entries, "files/"+first+".mp4")
+		}, first + " recording"},
+		{"record altered", func(p *testPackage, entries map[string][]byte) {
+			entries["evidence/"+first+".json"] = append(entries["evidence/"+first+".json"], ' ')
+		}, first + " record"},
+		{"manifest altered", func(p *testPackage, entries map[string][]byte) {
+			entries["manifest.json"] = []byte(strings.Replace(string(entries["manifest.json"]), "CLERK-1", "CLERK-2", 1))
+		}, "manifest.sig signature"},
+		{"entry added", func(p *testPackage, entries map[string][]byte) {
+			entries["files/extra.mp4"] = []byte("extra")
+		}, "files/extra.mp4 entry"},
+		{"custody entry rewritten and re-signed", func(p *testPackage, entries map[string][]byte) {
+			p.records[first].ChainOfCustody[1].Purpose = "Destroyed"
+			for name, data := range p.build(t) {
+				entries[name] = data
+			}
+		}, first + " custody"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPackage(t)
+			entries := p.build(t)
+			tt.tamper(p, entries)
+			report, err := Verify(writeZip(t, entries), []TrustedKey{{Name: "County Sheriff", PublicKey: p.publicKey()}})
+			if err != nil {
+				t.Fatalf("Verify failed: %v", err)
+			}
+			if failed := failedChecks(report); report.Status != StatusFailed || len(failed) != 1 || failed[0] != tt.want {
+				t.Errorf("expected only %q to fail, got %s %v", tt.want, report.Status, failed)
+			}
+		})
+	}
+}
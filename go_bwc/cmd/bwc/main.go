@@ -0,0 +1,18 @@
+// Command bwc-system runs the forensic body-worn camera evidence system: the
+// API server, the custodian console and the administrative commands. Run it
+// with "help" for the list of commands.
+package main
+
+import (
+	"os"
+
+	"github.com/gtgspot/go_bwc/bwc"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		bwc.PrintUsage(os.Stderr)
+		os.Exit(2)
+	}
+	os.Exit(bwc.RunCommand(os.Args[1:], os.Stdout, os.Stderr))
+}
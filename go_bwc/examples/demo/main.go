@@ -0,0 +1,120 @@
+// Command demo walks through the evidence lifecycle using the bwc package as a
+// library: it ingests a test recording, verifies it, transfers custody,
+// updates its status and prints the report and audit log.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gtgspot/go_bwc/bwc"
+)
+
+func main() {
+	// Initialize the BWC system
+	system, err := bwc.NewBWCSystem("./bwc_storage")
+	if err != nil {
+		fmt.Printf("Error initializing system: %v\n", err)
+		return
+	}
+
+	fmt.Println("Forensic Body-Worn Camera System Initialized")
+	fmt.Println("============================================")
+	fmt.Println()
+
+	// Example: Create a test video file
+	testVideoPath := "./test_video.mp4"
+	testFile, err := os.Create(testVideoPath)
+	if err != nil {
+		fmt.Printf("Error creating test file: %v\n", err)
+		return
+	}
+	testFile.WriteString("This is test video content for demonstration")
+	testFile.Close()
+
+	// Ingest evidence
+	fmt.Println("1. Ingesting Evidence...")
+	evidence, err := system.IngestEvidence(
+		testVideoPath,
+		"CASE-2025-001",
+		"OFF-12345",
+		"Officer John Smith",
+		"123 Main St, City",
+		[]string{"traffic-stop", "incident"},
+	)
+	if err != nil {
+		fmt.Printf("Error ingesting evidence: %v\n", err)
+		return
+	}
+	fmt.Printf("   Evidence ID: %s\n", evidence.ID)
+	fmt.Printf("   File Hash: %s\n", evidence.FileHash)
+	fmt.Printf("   Status: %s\n\n", evidence.Status)
+
+	// Verify integrity
+	fmt.Println("2. Verifying Evidence Integrity...")
+	isValid, err := system.VerifyIntegrity(evidence.ID, "OFF-12345")
+	if err != nil {
+		fmt.Printf("Error verifying integrity: %v\n", err)
+		return
+	}
+	fmt.Printf("   Integrity Check: %v\n\n", isValid)
+
+	// Transfer custody
+	fmt.Println("3. Transferring Custody...")
+	err = system.TransferCustody(evidence.ID, "OFF-12345", "DET-67890", "Evidence analysis")
+	if err != nil {
+		fmt.Printf("Error transferring custody: %v\n", err)
+		return
+	}
+	fmt.Printf("   Custody transferred successfully\n\n")
+
+	// Update status
+	fmt.Println("4. Updating Evidence Status...")
+	err = system.UpdateStatus(evidence.ID, "DET-67890", bwc.StatusAnalyzed, "Analysis completed")
+	if err != nil {
+		fmt.Printf("Error updating status: %v\n", err)
+		return
+	}
+	fmt.Printf("   Status updated to: %s\n\n", bwc.StatusAnalyzed)
+
+	// Get chain of custody
+	fmt.Println("5. Chain of Custody:")
+	custody, err := system.GetChainOfCustody(evidence.ID)
+	if err != nil {
+		fmt.Printf("Error getting chain of custody: %v\n", err)
+		return
+	}
+	for i, entry := range custody {
+		fmt.Printf("   [%d] %s: %s -> %s (%s)\n", i+1, entry.Timestamp.Format("2006-01-02 15:04:05"),
+			entry.FromOfficer, entry.ToOfficer, entry.Action)
+	}
+	fmt.Println()
+
+	// Generate report
+	fmt.Println("6. Generating Case Report...")
+	report, err := system.GenerateReport("CASE-2025-001")
+	if err != nil {
+		fmt.Printf("Error generating report: %v\n", err)
+		return
+	}
+	fmt.Println(report)
+
+	// Get audit logs
+	fmt.Println("7. Audit Logs:")
+	logs := system.GetAuditLogs(evidence.ID, "")
+	for i, log := range logs {
+		fmt.Printf("   [%d] %s: %s by %s - %s\n", i+1, log.Timestamp.Format("2006-01-02 15:04:05"),
+			log.Action, log.UserID, log.Details)
+	}
+
+	// Export evidence record
+	fmt.Println("\n8. Exporting Evidence Record...")
+	err = system.ExportEvidence(evidence.ID, "./evidence_export.json")
+	if err != nil {
+		fmt.Printf("Error exporting evidence: %v\n", err)
+		return
+	}
+	fmt.Printf("   Evidence exported to: ./evidence_export.json\n")
+
+	fmt.Println("\nDemo completed successfully!")
+}